@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/rbaccheck"
+)
+
+// AdminCmd groups operator commands that run against the proxy's
+// configuration and cluster
+var AdminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administrative commands for agentapi-proxy operators",
+}
+
+// rbac-check command flags
+var (
+	rbacConfig                  string
+	rbacNamespace               string
+	rbacManifest                bool
+	rbacName                    string
+	rbacServiceAccount          string
+	rbacServiceAccountNamespace string
+)
+
+var rbacCheckCmd = &cobra.Command{
+	Use:   "rbac-check",
+	Short: "Check the proxy's Kubernetes permissions and generate a least-privilege Role",
+	Long: `List the Kubernetes permissions the proxy uses with the given configuration
+and check them against the current credentials with SelfSubjectAccessReviews.
+Run it in the proxy Pod (or with the proxy's ServiceAccount) to verify a
+deployment. The command exits with an error when a permission is missing.
+
+Optional features add permissions only when they are enabled:
+  - pods/exec                 kubernetes_session.exec_enabled
+  - endpointslices            kubernetes_session.routing=endpoints
+  - jobs                      kubernetes_session.oneshot_job_enabled
+  - leases                    schedule_worker, slackbot_cleanup_worker or git_sync.sync_interval
+  - namespaces (ClusterRole)  kubernetes_session.preview_deploy_hook_url
+
+With --manifest, print a Role and RoleBinding (and a ClusterRole and
+ClusterRoleBinding when needed) granting exactly these permissions instead.
+No cluster access is needed for --manifest.
+
+Examples:
+  # Check the permissions of the current ServiceAccount
+  agentapi-proxy admin rbac-check --config config.json
+
+  # Generate a minimal Role for the configured features
+  agentapi-proxy admin rbac-check --config config.json --manifest \
+    --namespace agentapi --service-account agentapi-proxy > rbac.yaml`,
+	RunE: runRBACCheck,
+}
+
+func init() {
+	rbacCheckCmd.Flags().StringVarP(&rbacConfig, "config", "c", "config.json",
+		"Configuration file path (environment variables are used when it cannot be read)")
+	rbacCheckCmd.Flags().StringVar(&rbacNamespace, "namespace", "",
+		"Session namespace (default: kubernetes_session.namespace, then the in-cluster namespace)")
+	rbacCheckCmd.Flags().BoolVar(&rbacManifest, "manifest", false,
+		"Print a least-privilege RBAC manifest instead of checking permissions")
+	rbacCheckCmd.Flags().StringVar(&rbacName, "name", "agentapi-proxy",
+		"Name of the generated Role, ClusterRole and bindings")
+	rbacCheckCmd.Flags().StringVar(&rbacServiceAccount, "service-account", "agentapi-proxy",
+		"ServiceAccount bound by the generated manifest")
+	rbacCheckCmd.Flags().StringVar(&rbacServiceAccountNamespace, "service-account-namespace", "",
+		"Namespace of the ServiceAccount (default: the session namespace)")
+
+	AdminCmd.AddCommand(rbacCheckCmd)
+}
+
+func runRBACCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(rbacConfig)
+	if err != nil {
+		cfg, err = config.LoadConfig("")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	reqs := rbaccheck.Required(cfg, rbacCheckNamespace(cfg))
+
+	if rbacManifest {
+		manifest, err := rbaccheck.Manifest(reqs, rbaccheck.ManifestOptions{
+			Name:                    rbacName,
+			ServiceAccount:          rbacServiceAccount,
+			ServiceAccountNamespace: rbacServiceAccountNamespace,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(manifest)
+		return err
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get Kubernetes config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	fmt.Printf("Checking permissions in namespace: %s\n", reqs.Namespace)
+	result, err := rbaccheck.Check(context.Background(), client, reqs)
+	if err != nil {
+		return err
+	}
+
+	missing := make(map[string][]string)
+	for _, m := range result.Missing {
+		missing[m.Rule.Name()] = append(missing[m.Rule.Name()], m.Verb)
+	}
+	printRules := func(rules []rbaccheck.Rule, scope string) {
+		for _, r := range rules {
+			status := "OK"
+			if verbs := missing[r.Name()]; len(verbs) > 0 {
+				status = "MISSING " + strings.Join(verbs, ",")
+			}
+			fmt.Printf("  [%s] %s %s: %s (%s)\n", status, scope, r.Name(), strings.Join(r.Verbs, ","), r.Reason)
+		}
+	}
+	printRules(reqs.Namespaced, "namespace")
+	printRules(reqs.Cluster, "cluster")
+
+	if len(result.Missing) > 0 {
+		fmt.Printf("\n%d of %d permission(s) missing.\n", len(result.Missing), result.Checked)
+		fmt.Println("Run with --manifest to generate a Role granting them.")
+		return fmt.Errorf("%d permission(s) missing", len(result.Missing))
+	}
+	fmt.Printf("\nAll %d required permission(s) granted.\n", result.Checked)
+	return nil
+}
+
+// rbacCheckNamespace resolves the session namespace like the session
+// manager does
+func rbacCheckNamespace(cfg *config.Config) string {
+	for _, candidate := range []string{rbacNamespace, cfg.KubernetesSession.Namespace} {
+		if namespace := strings.TrimSpace(candidate); namespace != "" {
+			return namespace
+		}
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return "default"
+}
@@ -30,6 +30,7 @@ var AgentProvisionerCmd = &cobra.Command{
 Endpoints:
   GET  /healthz   – liveness/readiness probe (always 200)
   GET  /status    – current provisioning state as JSON
+  POST /complete  – end a oneshot Job session (from inside the Pod only)
 
 Provision requests are pulled from the proxy internal provisioner API.`,
 	RunE: runAgentProvisioner,
@@ -65,6 +66,7 @@ func runAgentProvisioner(cmd *cobra.Command, args []string) error {
 	}()
 
 	srv := provisioner.New(port, settingsFile)
+	srv.SetWorkspaceToken(os.Getenv("PROVISIONER_TOKEN"))
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- srv.Start(ctx)
@@ -77,6 +79,7 @@ func runAgentProvisioner(cmd *cobra.Command, args []string) error {
 			Token:             os.Getenv("PROVISIONER_TOKEN"),
 			UpstreamAuthToken: os.Getenv("PROVISIONER_UPSTREAM_AUTH_TOKEN"),
 			SessionID:         os.Getenv("AGENTAPI_SESSION_ID"),
+			SessionToken:      os.Getenv("PROVISIONER_SESSION_TOKEN"),
 			PodName:           os.Getenv("POD_NAME"),
 			Namespace:         os.Getenv("POD_NAMESPACE"),
 			CAFile:            os.Getenv("NODE_EXTRA_CA_CERTS"),
@@ -88,6 +91,9 @@ func runAgentProvisioner(cmd *cobra.Command, args []string) error {
 		return err
 	case err := <-pullErrCh:
 		return err
+	case <-srv.Done():
+		// Oneshot Job sessions end here so the Job completes successfully.
+		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -18,6 +19,12 @@ var (
 	endpoint      string
 	sessionID     string
 	confirmDelete bool
+	// clientConfigPath is the client config file; empty reads the default
+	// file if it exists
+	clientConfigPath string
+	// provisionerURL is the local agent-provisioner used by complete-session
+	// and checkpoint-session
+	provisionerURL string
 )
 
 // annotate-session command flags
@@ -94,22 +101,43 @@ var (
 	cycleMaxCount int
 )
 
-// resolveClient creates a client using flags if provided, otherwise falling back
-// to environment variables (AGENTAPI_PROXY_SERVICE_HOST, AGENTAPI_PROXY_SERVICE_PORT_HTTP,
-// AGENTAPI_SESSION_ID, AGENTAPI_KEY).
-// Returns the client and the resolved session ID.
-func resolveClient() (*client.Client, string, error) {
+// resolveConnection returns the endpoint and API key of the proxy. The
+// endpoint comes from --endpoint, else the environment variables, else the
+// client config file; the API key from AGENTAPI_KEY, else the config file.
+func resolveConnection() (string, string, error) {
 	resolvedEndpoint := endpoint
-	resolvedSessionID := sessionID
+	apiKey := os.Getenv("AGENTAPI_KEY")
+	if resolvedEndpoint != "" && apiKey != "" {
+		return resolvedEndpoint, apiKey, nil
+	}
 
+	fileConfig, err := client.LoadFileConfig(clientConfigPath)
+	if err != nil {
+		return "", "", err
+	}
+	if apiKey == "" {
+		apiKey = fileConfig.APIKey
+	}
 	if resolvedEndpoint == "" {
 		envEndpoint, err := client.EndpointFromEnv()
-		if err != nil {
-			return nil, "", fmt.Errorf("--endpoint not specified and %w", err)
+		switch {
+		case err == nil:
+			resolvedEndpoint = envEndpoint
+		case fileConfig.Endpoint != "":
+			resolvedEndpoint = fileConfig.Endpoint
+		default:
+			return "", "", fmt.Errorf("--endpoint not specified and %w", err)
 		}
-		resolvedEndpoint = envEndpoint
 	}
+	return resolvedEndpoint, apiKey, nil
+}
 
+// resolveClient creates a client using flags if provided, otherwise falling back
+// to environment variables (AGENTAPI_PROXY_SERVICE_HOST, AGENTAPI_PROXY_SERVICE_PORT_HTTP,
+// AGENTAPI_SESSION_ID, AGENTAPI_KEY) and the client config file.
+// Returns the client and the resolved session ID.
+func resolveClient() (*client.Client, string, error) {
+	resolvedSessionID := sessionID
 	if resolvedSessionID == "" {
 		resolvedSessionID = os.Getenv("AGENTAPI_SESSION_ID")
 		if resolvedSessionID == "" {
@@ -117,25 +145,17 @@ func resolveClient() (*client.Client, string, error) {
 		}
 	}
 
-	apiKey := os.Getenv("AGENTAPI_KEY")
-	c := client.NewClient(resolvedEndpoint, client.WithAPIKeyAuth(apiKey))
+	c, err := resolveBaseClient()
+	if err != nil {
+		return nil, "", err
+	}
 	return c, resolvedSessionID, nil
 }
 
 // resolveMemoryClient creates a client for memory operations using flags or env vars.
 // Unlike resolveClient, session-id is not required for memory operations.
 func resolveMemoryClient() (*client.Client, error) {
-	resolvedEndpoint := endpoint
-	if resolvedEndpoint == "" {
-		envEndpoint, err := client.EndpointFromEnv()
-		if err != nil {
-			return nil, fmt.Errorf("--endpoint not specified and %w", err)
-		}
-		resolvedEndpoint = envEndpoint
-	}
-
-	apiKey := os.Getenv("AGENTAPI_KEY")
-	return client.NewClient(resolvedEndpoint, client.WithAPIKeyAuth(apiKey)), nil
+	return resolveBaseClient()
 }
 
 // parseKeyValueFlags parses a slice of "key=value" strings into a map.
@@ -237,6 +257,36 @@ Examples:
 	Run: runDeleteSession,
 }
 
+var completeSessionCmd = &cobra.Command{
+	Use:   "complete-session",
+	Short: "Complete the current oneshot Job session",
+	Long: `Tell the agent-provisioner of this Pod that the session is complete.
+
+The provisioner exits successfully, so the Job of a oneshot session
+completes and the proxy collects its logs and result. This is run by the
+Stop hook of oneshot sessions that run as Jobs.
+
+Examples:
+  agentapi-proxy client complete-session`,
+	Run: runCompleteSession,
+}
+
+var checkpointSessionCmd = &cobra.Command{
+	Use:   "checkpoint-session",
+	Short: "Checkpoint the agent conversation of the current session",
+	Long: `Tell the agent-provisioner of this Pod to save the agent conversation
+to the workdir volume.
+
+When the session's Pod is recreated, for example after a node drain, the
+agent resumes the saved conversation with "claude -c" instead of starting
+over with the initial message. This is run by the preStop hook of session
+Pods when kubernetes_session.prestop_checkpoint is enabled.
+
+Examples:
+  agentapi-proxy client checkpoint-session`,
+	Run: runCheckpointSession,
+}
+
 var annotateSessionCmd = &cobra.Command{
 	Use:   "annotate-session",
 	Short: "Update current session info",
@@ -507,10 +557,17 @@ var taskDeleteCmd = &cobra.Command{
 func init() {
 	ClientCmd.PersistentFlags().StringVarP(&endpoint, "endpoint", "e", "", "AgentAPI endpoint URL (required for most commands)")
 	ClientCmd.PersistentFlags().StringVarP(&sessionID, "session-id", "s", "", "Session ID for the agent (required for most commands)")
+	ClientCmd.PersistentFlags().StringVar(&clientConfigPath, "config", "", "Client config file with the endpoint and API key (default $AGENTAPI_CLIENT_CONFIG or ~/.config/agentapi-proxy/client.yaml)")
 
 	// delete-session command flags
 	deleteSessionCmd.Flags().BoolVar(&confirmDelete, "confirm", false, "Skip confirmation prompt")
 
+	// complete-session command flags
+	completeSessionCmd.Flags().StringVar(&provisionerURL, "provisioner-url", "http://127.0.0.1:9001", "URL of the agent-provisioner in this Pod")
+
+	// checkpoint-session command flags
+	checkpointSessionCmd.Flags().StringVar(&provisionerURL, "provisioner-url", "http://127.0.0.1:9001", "URL of the agent-provisioner in this Pod")
+
 	// annotate-session command flags
 	annotateSessionCmd.Flags().StringVar(&annotationPRURL, "pr-url", "", "Pull request URL annotation")
 	annotateSessionCmd.Flags().StringVar(&annotationIssueURL, "issue-url", "", "Issue URL annotation")
@@ -619,6 +676,8 @@ func init() {
 	ClientCmd.AddCommand(statusCmd)
 	ClientCmd.AddCommand(eventsCmd)
 	ClientCmd.AddCommand(deleteSessionCmd)
+	ClientCmd.AddCommand(completeSessionCmd)
+	ClientCmd.AddCommand(checkpointSessionCmd)
 	ClientCmd.AddCommand(annotateSessionCmd)
 	ClientCmd.AddCommand(summarizeDraftsCmd)
 	ClientCmd.AddCommand(sendNotificationClientCmd)
@@ -1133,22 +1192,19 @@ const endpointHint = `
 Hint: configure the endpoint using one of the following methods:
   1. Flag:    --endpoint http://<host>:<port>
   2. Env vars: AGENTAPI_PROXY_SERVICE_HOST=<host> AGENTAPI_PROXY_SERVICE_PORT_HTTP=<port>
+  3. Config file (~/.config/agentapi-proxy/client.yaml or --config):
+       endpoint: https://<host>
 
 Optional authentication:
-  AGENTAPI_KEY=<api-key>`
+  AGENTAPI_KEY=<api-key>, or api_key in the config file`
 
-// resolveBaseClient creates a client using flags or environment variables.
-// Unlike resolveClient, no session-id is required.
+// resolveBaseClient creates a client using flags, environment variables or
+// the client config file. Unlike resolveClient, no session-id is required.
 func resolveBaseClient() (*client.Client, error) {
-	resolvedEndpoint := endpoint
-	if resolvedEndpoint == "" {
-		envEndpoint, err := client.EndpointFromEnv()
-		if err != nil {
-			return nil, fmt.Errorf("--endpoint not specified and %w", err)
-		}
-		resolvedEndpoint = envEndpoint
+	resolvedEndpoint, apiKey, err := resolveConnection()
+	if err != nil {
+		return nil, err
 	}
-	apiKey := os.Getenv("AGENTAPI_KEY")
 	return client.NewClient(resolvedEndpoint, client.WithAPIKeyAuth(apiKey)), nil
 }
 
@@ -1707,6 +1763,50 @@ func runDeleteSession(cmd *cobra.Command, args []string) {
 	fmt.Printf("Session ID: %s\n", resp.SessionID)
 }
 
+func runCompleteSession(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(provisionerURL, "/")+"/complete", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error completing session: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Error completing session: %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		os.Exit(1)
+	}
+	fmt.Println("Session completed")
+}
+
+func runCheckpointSession(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(provisionerURL, "/")+"/checkpoint", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checkpointing session: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Error checkpointing session: %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		os.Exit(1)
+	}
+	fmt.Println("Session checkpointed")
+}
+
 // notifyRateLimitFile is the file used to track the last notification send time.
 const notifyRateLimitFile = "/tmp/notify"
 
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takutakahashi/agentapi-proxy/pkg/client"
+)
+
+// list subcommand flags
+var (
+	listTags   []string
+	listStatus string
+	listQuery  string
+	listLimit  int
+	listOutput string
+)
+
+// tail and attach subcommand flags
+var tailLines int
+
+// maxListDescription bounds the descriptions shown by list
+const maxListDescription = 60
+
+var listSessionsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions",
+	Long: `List the sessions you can access, newest first.
+
+Filters (all optional):
+  --tag     tag in key=value format (can be specified multiple times, all must match)
+  --status  session status, e.g. "active"
+  --query   text in the description or initial message
+
+Examples:
+  agentapi-proxy client list
+  agentapi-proxy client list --tag env=dev --tag repo=myorg/myrepo
+  agentapi-proxy client list --status active -o json`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runListSessions,
+}
+
+var tailSessionCmd = &cobra.Command{
+	Use:   "tail <session-id>",
+	Short: "Stream the messages of a session",
+	Long: `Print the last messages of a session, then the new ones as the agent
+completes them, until interrupted with Ctrl+C.
+
+Examples:
+  agentapi-proxy client tail abc123
+  agentapi-proxy client tail abc123 -n 0`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runTailSession,
+}
+
+var attachSessionCmd = &cobra.Command{
+	Use:   "attach <session-id>",
+	Short: "Chat with a session interactively",
+	Long: `Stream the messages of a session like tail and send each line typed on
+stdin as a message to the agent. Ctrl+D or /detach detaches from the session,
+which keeps running.
+
+Example:
+  agentapi-proxy client attach abc123`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runAttachSession,
+}
+
+var deleteSessionsCmd = &cobra.Command{
+	Use:   "delete <session-id>...",
+	Short: "Delete sessions",
+	Long: `Delete one or more sessions by ID.
+
+Examples:
+  agentapi-proxy client delete abc123
+  agentapi-proxy client delete abc123 def456 --confirm`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runDeleteSessions,
+}
+
+func init() {
+	listSessionsCmd.Flags().StringArrayVar(&listTags, "tag", nil, "Tag filter in key=value format (can be specified multiple times)")
+	listSessionsCmd.Flags().StringVar(&listStatus, "status", "", "Filter by session status")
+	listSessionsCmd.Flags().StringVarP(&listQuery, "query", "q", "", "Filter by text in the description or initial message")
+	listSessionsCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of sessions (0 lists all)")
+	listSessionsCmd.Flags().StringVarP(&listOutput, "output", "o", "table", `Output format: "table" or "json"`)
+
+	tailSessionCmd.Flags().IntVarP(&tailLines, "lines", "n", 10, "Number of past messages to print first")
+	attachSessionCmd.Flags().IntVarP(&tailLines, "lines", "n", 10, "Number of past messages to print first")
+
+	deleteSessionsCmd.Flags().BoolVar(&confirmDelete, "confirm", false, "Skip confirmation prompt")
+
+	ClientCmd.AddCommand(listSessionsCmd)
+	ClientCmd.AddCommand(tailSessionCmd)
+	ClientCmd.AddCommand(attachSessionCmd)
+	ClientCmd.AddCommand(deleteSessionsCmd)
+}
+
+func runListSessions(cmd *cobra.Command, args []string) error {
+	if listOutput != "table" && listOutput != "json" {
+		return fmt.Errorf(`--output must be "table" or "json"`)
+	}
+	tags, err := parseKeyValueFlags(listTags)
+	if err != nil {
+		return err
+	}
+	c, err := resolveBaseClient()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, endpointHint)
+	}
+
+	resp, err := c.SearchWithOptions(cmd.Context(), client.SearchOptions{
+		Status: listStatus,
+		Tags:   tags,
+		Query:  listQuery,
+		Limit:  listLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if listOutput == "json" {
+		data, err := json.MarshalIndent(resp.Sessions, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	return writeSessionTable(out, resp.Sessions)
+}
+
+// writeSessionTable writes sessions as the table printed by list
+func writeSessionTable(out io.Writer, sessions []client.SessionInfo) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SESSION ID\tSTATUS\tSTARTED\tTAGS\tDESCRIPTION")
+	for _, s := range sessions {
+		started := "-"
+		if !s.StartedAt.IsZero() {
+			started = s.StartedAt.Local().Format(time.RFC3339)
+		}
+		description := s.Metadata.Description
+		if description == "" {
+			description = s.Annotations.Description
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.SessionID, s.Status, started, formatTags(s.Tags), truncateLine(description, maxListDescription))
+	}
+	return w.Flush()
+}
+
+// formatTags renders tags as sorted key=value pairs
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// truncateLine returns the first line of s, cut to n characters
+func truncateLine(s string, n int) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
+
+func runTailSession(cmd *cobra.Command, args []string) error {
+	c, err := resolveBaseClient()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, endpointHint)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	out := cmd.OutOrStdout()
+	afterID, err := printRecentMessages(ctx, c, args[0], tailLines, out)
+	if err != nil {
+		return err
+	}
+	return followSessionMessages(ctx, c, args[0], afterID, out)
+}
+
+func runAttachSession(cmd *cobra.Command, args []string) error {
+	c, err := resolveBaseClient()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, endpointHint)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	sessionID := args[0]
+	out := cmd.OutOrStdout()
+	afterID, err := printRecentMessages(ctx, c, sessionID, tailLines, out)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	followErr := make(chan error, 1)
+	go func() {
+		followErr <- followSessionMessages(ctx, c, sessionID, afterID, out)
+		cancel()
+	}()
+
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Attached to session %s. Type a message and press Enter to send it; Ctrl+D or /detach to detach.\n", sessionID)
+	if err := sendInputLines(ctx, c, sessionID, cmd.InOrStdin()); err != nil {
+		return err
+	}
+	cancel()
+	return <-followErr
+}
+
+// sendInputLines sends each non-empty line of in to the session until in
+// ends, a line is /detach or ctx is cancelled
+func sendInputLines(ctx context.Context, c *client.Client, sessionID string, in io.Reader) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok || strings.TrimSpace(line) == "/detach" {
+				return nil
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			resp, err := c.SendMessage(ctx, sessionID, &client.Message{Content: line, Type: "user"})
+			if err != nil {
+				return fmt.Errorf("failed to send message: %w", err)
+			}
+			if !resp.OK {
+				return fmt.Errorf("message was not sent successfully")
+			}
+		}
+	}
+}
+
+// printRecentMessages prints the last n complete messages of a session and
+// returns the ID of the last complete message, after which
+// followSessionMessages continues. The last message is left to follow while
+// the agent is still writing it.
+func printRecentMessages(ctx context.Context, c *client.Client, sessionID string, n int, out io.Writer) (int64, error) {
+	resp, err := c.GetMessages(ctx, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get messages: %w", err)
+	}
+	messages := resp.Messages
+	if len(messages) > 0 && messages[len(messages)-1].Role != "user" {
+		if status, err := c.GetStatus(ctx, sessionID); err == nil && status.Status == "running" {
+			messages = messages[:len(messages)-1]
+		}
+	}
+
+	afterID := int64(-1)
+	if len(messages) > 0 {
+		afterID = messageID(messages[len(messages)-1], len(messages)-1)
+	}
+	if n < len(messages) {
+		messages = messages[len(messages)-max(n, 0):]
+	}
+	for _, msg := range messages {
+		update := client.MessageUpdate{Role: msg.Role, Message: msg.Content}
+		if ts := msg.GetTimestamp(); ts != nil {
+			update.Time = *ts
+		}
+		printMessage(out, update)
+	}
+	return afterID, nil
+}
+
+// messageID returns the agentapi ID of a message, which is its index when
+// the ID is missing
+func messageID(msg client.Message, index int) int64 {
+	if id, err := strconv.ParseInt(string(msg.ID), 10, 64); err == nil {
+		return id
+	}
+	return int64(index)
+}
+
+// followSessionMessages prints the messages of a session after afterID as
+// they are completed, until ctx is cancelled or the stream ends
+func followSessionMessages(ctx context.Context, c *client.Client, sessionID string, afterID int64, out io.Writer) error {
+	messages, errs := c.FollowMessages(ctx, sessionID, afterID)
+	for msg := range messages {
+		printMessage(out, msg)
+	}
+	if err := <-errs; err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to stream messages: %w", err)
+	}
+	return nil
+}
+
+// printMessage prints a message like history does
+func printMessage(out io.Writer, msg client.MessageUpdate) {
+	ts := ""
+	if !msg.Time.IsZero() {
+		ts = msg.Time.Local().Format("15:04:05")
+	}
+	_, _ = fmt.Fprintf(out, "[%s] %s: %s\n", ts, msg.Role, strings.TrimSpace(msg.Message))
+}
+
+func runDeleteSessions(cmd *cobra.Command, args []string) error {
+	c, err := resolveBaseClient()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, endpointHint)
+	}
+
+	if !confirmDelete {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Are you sure you want to delete %d session(s) (%s)? [y/N]: ", len(args), strings.Join(args, ", "))
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		response := ""
+		if scanner.Scan() {
+			response = strings.ToLower(strings.TrimSpace(scanner.Text()))
+		}
+		if response != "y" && response != "yes" {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Deletion cancelled")
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, id := range args {
+		if _, err := c.DeleteSession(cmd.Context(), id); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Error deleting session %s: %v\n", id, err)
+			failed++
+			continue
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Deleted session %s\n", id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d session(s)", failed, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/client"
+)
+
+func TestListSessionsWithMockServer(t *testing.T) {
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.Query()
+		resp := client.SearchResponse{
+			Sessions: []client.SessionInfo{{
+				SessionID: "session-1",
+				Status:    "active",
+				StartedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Tags:      map[string]string{"repo": "org/repo", "env": "dev"},
+				Metadata:  client.SessionMetadata{Description: "Fix the login page\nmore details"},
+			}},
+			Total: 1,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	endpoint = server.URL
+	listTags = []string{"env=dev"}
+	listOutput = "table"
+	defer func() {
+		endpoint = ""
+		listTags = nil
+	}()
+
+	var out bytes.Buffer
+	listSessionsCmd.SetOut(&out)
+	listSessionsCmd.SetContext(context.Background())
+	defer listSessionsCmd.SetOut(nil)
+	require.NoError(t, runListSessions(listSessionsCmd, nil))
+
+	assert.Equal(t, []string{"dev"}, query["tag.env"])
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"SESSION", "ID", "STATUS", "STARTED", "TAGS", "DESCRIPTION"}, strings.Fields(lines[0]))
+	assert.Contains(t, lines[1], "session-1")
+	assert.Contains(t, lines[1], "env=dev,repo=org/repo")
+	assert.True(t, strings.HasSuffix(lines[1], "Fix the login page"))
+}
+
+func TestListSessionsRejectsInvalidTag(t *testing.T) {
+	listTags = []string{"env"}
+	defer func() { listTags = nil }()
+
+	assert.Error(t, runListSessions(listSessionsCmd, nil))
+}
+
+func TestTruncateLine(t *testing.T) {
+	assert.Equal(t, "first line", truncateLine("  first line\nsecond line", 20))
+	assert.Equal(t, "abcd…", truncateLine("abcdefgh", 5))
+}
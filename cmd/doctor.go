@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/app"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+)
+
+// ConfigCmd groups commands that work on the proxy configuration
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration commands",
+}
+
+// config validate and doctor command flags
+var (
+	validateConfigPath string
+	doctorConfigPath   string
+	doctorNamespace    string
+	doctorTimeout      time.Duration
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration without starting the server",
+	Long: `Parse the configuration and run the checks the server runs at startup:
+kubernetes_session settings, rate limits, RBAC, the session store, the memory
+backend and air-gapped mode. The files the configuration refers to
+(auth_config_file, auth.static.keys_file, kubernetes_session.config_file) are
+read as well; the server only logs a warning when they cannot be read.
+
+No cluster access is needed. The command exits with an error when a problem
+is found. Use "agentapi-proxy doctor" to check the cluster as well.
+
+Examples:
+  agentapi-proxy config validate --config config.yaml
+
+  # Validate a configuration given only by environment variables
+  agentapi-proxy config validate --config ""`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, results := validateConfig(validateConfigPath)
+		return printDiagnostics(results)
+	},
+}
+
+// DoctorCmd checks the configuration and the Kubernetes cluster before the
+// server is started
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration and the Kubernetes cluster before starting the server",
+	Long: `Run "config validate", then check the Kubernetes cluster with the current
+credentials:
+  kubernetes                the API server is reachable and the session namespace exists
+  kubernetes_rbac           the permissions the configuration needs are granted
+  kubernetes_secrets        the Secrets the configuration refers to exist and contain their keys
+  kubernetes_storage_class  the storage classes of session PVCs exist, or the cluster has a default one
+  redis                     redis.addr answers PING
+
+Run it in the proxy Pod (or with the proxy's ServiceAccount) before rolling
+out a new configuration. Every failed check prints how to fix it, and the
+command exits with an error when a check failed. A running server reports the
+same checks and more at GET /admin/diagnostics.
+
+Examples:
+  agentapi-proxy doctor --config config.yaml
+  kubectl exec deploy/agentapi-proxy -- agentapi-proxy doctor --config /etc/agentapi/config.yaml`,
+	SilenceUsage: true,
+	RunE:         runDoctor,
+}
+
+func init() {
+	configValidateCmd.Flags().StringVarP(&validateConfigPath, "config", "c", "config.json",
+		"Configuration file path (\"\" searches the default locations and uses environment variables)")
+	ConfigCmd.AddCommand(configValidateCmd)
+
+	DoctorCmd.Flags().StringVarP(&doctorConfigPath, "config", "c", "config.json",
+		"Configuration file path (\"\" searches the default locations and uses environment variables)")
+	DoctorCmd.Flags().StringVar(&doctorNamespace, "namespace", "",
+		"Session namespace (default: kubernetes_session.namespace, then the in-cluster namespace)")
+	DoctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", diagnostics.DefaultTimeout,
+		"Timeout of each cluster check")
+}
+
+// validateConfig loads the configuration at path and validates it. The
+// config is nil when it cannot be loaded.
+func validateConfig(path string) (*config.Config, []diagnostics.Result) {
+	name := "config"
+	if path != "" {
+		name = "config " + path
+	}
+	result := func(r diagnostics.Result) diagnostics.Result {
+		r.Name = name
+		return r
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, []diagnostics.Result{result(diagnostics.Fail(
+			"Fix the syntax of the file, or pass --config \"\" to use environment variables only",
+			"cannot be loaded: %v", err))}
+	}
+	var results []diagnostics.Result
+	for _, err := range config.CheckReferencedFiles(cfg) {
+		results = append(results, result(diagnostics.Fail(
+			"Fix or remove the file; the server ignores it and starts without its settings", "%v", err)))
+	}
+	for _, err := range app.ValidateConfig(cfg) {
+		results = append(results, result(diagnostics.Fail("Fix the setting; the server refuses to start", "%v", err)))
+	}
+	if !cfg.AirGap.Strict {
+		for _, finding := range cfg.ValidateAirGap() {
+			results = append(results, result(diagnostics.Warn(
+				"Mirror the dependency inside the network, or ignore this outside air-gapped deployments",
+				"air-gapped mode: %s", finding)))
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, result(diagnostics.Pass("valid")))
+	}
+	return cfg, results
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, results := validateConfig(doctorConfigPath)
+	if cfg == nil {
+		return printDiagnostics(results)
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		results = append(results, diagnostics.Result{Name: "kubernetes", Status: diagnostics.StatusFail,
+			Message:     fmt.Sprintf("no Kubernetes credentials: %v", err),
+			Remediation: "Run in the proxy Pod or point KUBECONFIG at the cluster"})
+		return printDiagnostics(results)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	namespace := resolveKubernetesNamespace(doctorNamespace, cfg.KubernetesSession.Namespace)
+	report := diagnostics.Run(context.Background(), app.DoctorChecks(cfg, client, namespace), doctorTimeout)
+	return printDiagnostics(append(results, report.Checks...))
+}
+
+// printDiagnostics prints results and returns an error when one failed
+func printDiagnostics(results []diagnostics.Result) error {
+	failed, warnings := 0, 0
+	for _, r := range results {
+		switch r.Status {
+		case diagnostics.StatusFail:
+			failed++
+		case diagnostics.StatusWarn:
+			warnings++
+		}
+		fmt.Printf("  [%s] %s: %s\n", r.Status, r.Name, r.Message)
+		if r.Remediation != "" {
+			fmt.Printf("         fix: %s\n", r.Remediation)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d check(s) failed, %d warning(s).\n", failed, len(results), warnings)
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	fmt.Printf("\nNo check failed, %d warning(s).\n", warnings)
+	return nil
+}
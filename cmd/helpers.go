@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"crypto/rand"
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +20,7 @@ import (
 	"github.com/takutakahashi/agentapi-proxy/pkg/github"
 	"github.com/takutakahashi/agentapi-proxy/pkg/mcp"
 	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/provisioner"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
 	"github.com/takutakahashi/agentapi-proxy/pkg/settings"
 	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
@@ -859,6 +863,8 @@ var (
 	setupNotificationSubscriptions string
 	setupNotificationsDir          string
 	setupRegisterMarketplaces      bool
+	setupPhases                    []string
+	setupProvisionerURL            string
 )
 
 var setupCmd = &cobra.Command{
@@ -875,6 +881,12 @@ Reads /session-settings/settings.yaml and performs:
 This replaces the old write-pem, clone-repo, merge-settings, sync-config, and setup-mcp
 init containers with a single unified step.
 
+Every phase can be re-run. --phase (write-pem, clone, compile-settings,
+marketplaces) runs only the given phases, e.g. to recover from a transient
+clone failure in a live Pod. With --provisioner-url the phases are run by the
+agent-provisioner of the Pod (POST /setup), which still has the settings of
+the session when /session-settings is not mounted.
+
 Examples:
   # Use defaults (reads /session-settings/settings.yaml)
   agentapi-proxy helpers setup
@@ -885,7 +897,10 @@ Examples:
     --credentials-file /credentials-config/credentials.json \
     --notification-subscriptions /notification-subscriptions-source \
     --notifications-dir /notifications \
-    --register-marketplaces`,
+    --register-marketplaces
+
+  # Retry the repository clone inside a running session Pod
+  agentapi-proxy helpers setup --phase clone --provisioner-url http://127.0.0.1:9001`,
 	RunE: runSetup,
 }
 
@@ -905,12 +920,23 @@ func init() {
 		"Destination directory for notification files (optional)")
 	setupCmd.Flags().BoolVar(&setupRegisterMarketplaces, "register-marketplaces", false,
 		"Register cloned marketplace repos via claude CLI")
+	setupCmd.Flags().StringSliceVar(&setupPhases, "phase", nil,
+		"Run only these phases ("+strings.Join(sessionsettings.SetupPhases, ", ")+"); repeatable, defaults to all")
+	setupCmd.Flags().StringVar(&setupProvisionerURL, "provisioner-url", "",
+		"Run the phases through the agent-provisioner at this URL instead of locally")
 
 	_ = compileDefaults // referenced via DefaultCompileOptions inside Setup()
 	HelpersCmd.AddCommand(setupCmd)
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
+	if err := sessionsettings.ValidateSetupPhases(setupPhases); err != nil {
+		return err
+	}
+	if setupProvisionerURL != "" {
+		return runSetupViaProvisioner(setupProvisionerURL, setupPhases)
+	}
+
 	opts := sessionsettings.SetupOptions{
 		InputPath:                 setupInputPath,
 		SettingsFile:              setupSettingsFile,
@@ -919,6 +945,7 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		NotificationSubscriptions: setupNotificationSubscriptions,
 		NotificationsDir:          setupNotificationsDir,
 		RegisterMarketplaces:      setupRegisterMarketplaces,
+		Phases:                    setupPhases,
 	}
 
 	if err := sessionsettings.Setup(opts); err != nil {
@@ -928,3 +955,30 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	fmt.Println("Session setup completed successfully!")
 	return nil
 }
+
+// runSetupViaProvisioner asks the agent-provisioner to re-run setup phases.
+func runSetupViaProvisioner(baseURL string, phases []string) error {
+	query := url.Values{"phase": phases}
+	// Setup may clone repositories, so allow it plenty of time.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/setup?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("setup failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result provisioner.SetupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("setup failed: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("setup failed: %s", result.Error)
+	}
+
+	fmt.Println("Session setup completed successfully!")
+	return nil
+}
@@ -217,19 +217,18 @@ func generateInstallationToken(config GitHubAppConfig) (string, error) {
 	}
 
 	// Set base URL if specified (for GitHub Enterprise)
-	if config.APIBase != "" && config.APIBase != "https://api.github.com" {
-		transport.BaseURL = config.APIBase
+	instance, err := github_pkg.NewInstance("", config.APIBase)
+	if err != nil {
+		return "", err
+	}
+	if instance.IsEnterprise() {
+		transport.BaseURL = instance.APIURL
 	}
 
 	// Create GitHub client
-	var client *github.Client
-	if config.APIBase == "" || strings.Contains(config.APIBase, "https://api.github.com") {
-		client = github.NewClient(&http.Client{Transport: transport})
-	} else {
-		client, err = github.NewClient(&http.Client{Transport: transport}).WithEnterpriseURLs(config.APIBase, config.APIBase)
-		if err != nil {
-			return "", fmt.Errorf("failed to create GitHub Enterprise client: %w", err)
-		}
+	client, err := instance.NewClient(&http.Client{Transport: transport})
+	if err != nil {
+		return "", err
 	}
 
 	// Create installation token
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// label-schema-migrate command flags
+var (
+	labelSchemaConfig    string
+	labelSchemaNamespace string
+	labelSchemaDryRun    bool
+)
+
+var labelSchemaMigrateCmd = &cobra.Command{
+	Use:   "label-schema-migrate",
+	Short: "Upgrade the labels of existing session Services and Deployments",
+	Long: `Relabel the session Services and Deployments created by older versions to
+the current label schema (scope, team-id-hash, agent-type), so that session
+lists filtered by user, scope or team do not miss them. The proxy does the same
+in the background every kubernetes_session.label_schema_migration_interval.
+
+Only object labels are changed; session Pods are not restarted. The command
+prints a JSON report of the relabeled objects.
+
+Examples:
+  # List the objects that would be relabeled
+  agentapi-proxy admin label-schema-migrate --config config.json --dry-run
+
+  # Relabel them
+  agentapi-proxy admin label-schema-migrate --config config.json`,
+	RunE: runLabelSchemaMigrate,
+}
+
+func init() {
+	labelSchemaMigrateCmd.Flags().StringVarP(&labelSchemaConfig, "config", "c", "config.json",
+		"Configuration file path (environment variables are used when it cannot be read)")
+	labelSchemaMigrateCmd.Flags().StringVar(&labelSchemaNamespace, "namespace", "",
+		"Session namespace (default: kubernetes_session.namespace, then the in-cluster namespace)")
+	labelSchemaMigrateCmd.Flags().BoolVar(&labelSchemaDryRun, "dry-run", false,
+		"Report the objects to relabel without changing them")
+
+	AdminCmd.AddCommand(labelSchemaMigrateCmd)
+}
+
+func runLabelSchemaMigrate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(labelSchemaConfig)
+	if err != nil {
+		cfg, err = config.LoadConfig("")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get Kubernetes config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	namespace := labelSchemaNamespace
+	if namespace == "" {
+		namespace = rbacCheckNamespace(cfg)
+	}
+	ctx := context.Background()
+	namespaces := services.SessionNamespaces(ctx, client, namespace, &cfg.KubernetesSession)
+	report := services.MigrateSessionLabelSchema(ctx, client, namespaces, labelSchemaDryRun)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d object(s) could not be relabeled", len(report.Errors))
+	}
+	return nil
+}
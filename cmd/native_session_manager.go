@@ -123,6 +123,7 @@ func runNativeSessionManager(command *cobra.Command, _ []string) error {
 	e.POST("/internal/session-provisioners/connect", provisionerController.Connect)
 	e.GET("/internal/session-provisioners/:sessionId/provision-requests", provisionerController.GetProvisionRequest)
 	e.POST("/internal/session-provisioners/:sessionId/provision-requests/:requestId/status", provisionerController.UpdateProvisionRequestStatus)
+	e.POST("/internal/session-provisioners/:sessionId/extensions", provisionerController.ReportExtensions)
 
 	handlers := sessionmanager.NewHandlers(manager, o.connectionToken)
 	if err := handlers.RegisterRoutes(e); err != nil {
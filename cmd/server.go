@@ -26,8 +26,11 @@ import (
 	"github.com/takutakahashi/agentapi-proxy/internal/modules/slackbot"
 	"github.com/takutakahashi/agentapi-proxy/internal/modules/webhook"
 	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
 	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/egressproxy"
 	githubsync "github.com/takutakahashi/agentapi-proxy/pkg/github_sync"
+	idlereaper "github.com/takutakahashi/agentapi-proxy/pkg/idle_reaper"
 	importexport "github.com/takutakahashi/agentapi-proxy/pkg/import"
 	slackbotcleanup "github.com/takutakahashi/agentapi-proxy/pkg/slackbot_cleanup"
 	stock_inventory "github.com/takutakahashi/agentapi-proxy/pkg/stock_inventory"
@@ -102,9 +105,37 @@ func runProxy(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if findings := configData.ValidateAirGap(); len(findings) > 0 {
+		for _, f := range findings {
+			log.Printf("[SERVER] Air-gapped mode: %s", f)
+		}
+		if configData.AirGap.Strict {
+			log.Fatalf("[SERVER] Air-gapped mode: %d external dependencies found, refusing to start", len(findings))
+		}
+	}
+
+	if err := configData.GitHubEnterprise.Validate(); err != nil {
+		log.Fatalf("[SERVER] %v", err)
+	}
+
+	// Honor the corporate egress proxy before any outbound HTTP request is
+	// made: net/http caches the proxy environment on first use.
+	if configData.EgressProxy.ApplyToProxy {
+		if err := egressproxy.ApplyToProcess(configData.EgressProxy.ProxySettings(""), configData.EgressProxy.CABundleFile); err != nil {
+			log.Printf("[SERVER] Warning: failed to apply egress proxy settings: %v", err)
+		} else if !configData.EgressProxy.ProxySettings("").IsZero() || configData.EgressProxy.CABundleFile != "" {
+			log.Printf("[SERVER] Egress proxy settings applied to proxy process")
+		}
+	}
+
 	proxyServer := app.NewServer(configData, verbose)
 	workerCtx, cancelWorkers := context.WithCancel(context.Background())
 
+	// Reload auth providers, rate limits and session images and limits from
+	// the config file on POST /admin/config/reload and, when watching, on
+	// file changes
+	proxyServer.EnableConfigReload(workerCtx, cfg)
+
 	// Run the idempotent legacy→multi API token migration and load all named
 	// tokens into the auth service. Any migration conflict or bootstrap load
 	// error is fatal here: serving traffic with a partially migrated or
@@ -124,6 +155,11 @@ func runProxy(cmd *cobra.Command, args []string) {
 	// Start session monitoring after proxy is initialized
 	proxyServer.StartMonitoring()
 
+	// Run the singleton background subsystems (showback, metering, archive
+	// sweeps, ...) on the replica holding the leader Lease. Cancelling
+	// workerCtx on shutdown releases the Lease to another replica at once.
+	proxyServer.StartLeaderElection(workerCtx)
+
 	// Start schedule worker if enabled
 	var scheduleWorker *schedule.LeaderWorker
 	if configData.ScheduleWorker.Enabled {
@@ -135,6 +171,11 @@ func runProxy(cmd *cobra.Command, args []string) {
 		startSlackbotCleanupWorker(configData, proxyServer)
 	}
 
+	// Start idle session reaper if enabled
+	if configData.IdleReaper.Enabled {
+		startIdleReaper(configData, proxyServer)
+	}
+
 	// Start stock inventory worker if enabled
 	if configData.StockInventoryWorker.Enabled {
 		startStockInventoryWorker(configData, proxyServer)
@@ -182,6 +223,19 @@ func runProxy(cmd *cobra.Command, args []string) {
 	<-quit
 
 	log.Println("Shutdown signal received, shutting down gracefully...")
+	drainDelay, gracePeriod := shutdownDurations(configData.Shutdown)
+
+	// Keep serving while load balancers notice the failing readiness check,
+	// refusing new sessions only
+	proxyServer.BeginShutdown()
+	if drainDelay > 0 {
+		log.Printf("Draining for %s before closing the listener", drainDelay)
+		select {
+		case <-time.After(drainDelay):
+		case <-quit:
+			log.Printf("Second shutdown signal received, skipping the drain delay")
+		}
+	}
 	cancelWorkers()
 
 	// Stop schedule worker if running
@@ -190,46 +244,36 @@ func runProxy(cmd *cobra.Command, args []string) {
 		scheduleWorker.Stop()
 	}
 
-	// Create a context with timeout for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Stop accepting connections and wait for in-flight requests; streams
+	// still open after the grace period are closed
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
 	defer cancel()
-
-	// Shutdown the proxy and all sessions
-	shutdownDone := make(chan error, 1)
-	go func() {
-		shutdownDone <- proxyServer.Shutdown(25 * time.Second)
-	}()
-
-	// Shutdown the HTTP server
-	serverShutdownDone := make(chan error, 1)
-	go func() {
-		serverShutdownDone <- proxyServer.GetEcho().Shutdown(ctx)
-	}()
-
-	// Wait for both shutdowns to complete
-	var proxyErr, serverErr error
-	for i := 0; i < 2; i++ {
-		select {
-		case err := <-shutdownDone:
-			proxyErr = err
-		case err := <-serverShutdownDone:
-			serverErr = err
-		case <-ctx.Done():
-			log.Printf("Shutdown timeout reached")
-			return
-		}
+	if err := proxyServer.GetEcho().Shutdown(ctx); err != nil {
+		log.Printf("Server shutdown error: %v; closing remaining connections", err)
+		_ = proxyServer.GetEcho().Close()
 	}
 
-	if proxyErr != nil {
-		log.Printf("Proxy shutdown error: %v", proxyErr)
-	}
-	if serverErr != nil {
-		log.Printf("Server shutdown error: %v", serverErr)
+	// Flush buffered events and usage, then shut the sessions down
+	if err := proxyServer.Shutdown(10 * time.Second); err != nil {
+		log.Printf("Proxy shutdown error: %v", err)
 	}
 
 	log.Printf("Server shutdown complete")
 }
 
+// shutdownDurations parses the drain delay and grace period of sc, falling
+// back to the defaults for unset or invalid values
+func shutdownDurations(sc config.ShutdownConfig) (drainDelay, gracePeriod time.Duration) {
+	drainDelay, gracePeriod = 5*time.Second, 20*time.Second
+	if d, err := time.ParseDuration(sc.DrainDelay); err == nil && d >= 0 {
+		drainDelay = d
+	}
+	if d, err := time.ParseDuration(sc.GracePeriod); err == nil && d > 0 {
+		gracePeriod = d
+	}
+	return drainDelay, gracePeriod
+}
+
 // registerScheduleHandlers registers schedule REST API handlers
 func registerScheduleHandlers(configData *config.Config, proxyServer *app.Server) {
 	log.Printf("[SCHEDULE_HANDLERS] Registering schedule handlers...")
@@ -261,7 +305,25 @@ func registerScheduleHandlers(configData *config.Config, proxyServer *app.Server
 
 	// Create and register schedule handlers
 	scheduleHandlers := schedule.NewHandlers(scheduleManager, proxyServer.GetSessionManager(), proxyServer.GetMemoryRepository(), proxyServer.GetSessionProfileRepository())
+	if parserCfg := configData.ScheduleParser; parserCfg.Enabled {
+		timeout, err := time.ParseDuration(parserCfg.Timeout)
+		if err != nil {
+			log.Printf("[SCHEDULE_HANDLERS] Invalid schedule_parser.timeout %q, using 30s: %v", parserCfg.Timeout, err)
+			timeout = 30 * time.Second
+		}
+		apiKey := os.Getenv(parserCfg.APIKeyEnv)
+		if apiKey == "" {
+			log.Printf("[SCHEDULE_HANDLERS] %s is not set, schedule parsing disabled", parserCfg.APIKeyEnv)
+		} else {
+			scheduleHandlers.WithDraftParser(schedule.NewAnthropicDraftParser(parserCfg.BaseURL, apiKey, parserCfg.Model, timeout))
+			log.Printf("[SCHEDULE_HANDLERS] Schedule parsing enabled with model %s", parserCfg.Model)
+		}
+	}
+	if authorizer := proxyServer.GetAuthorizer(); authorizer != nil {
+		scheduleHandlers.WithAuthorizer(authorizer)
+	}
 	proxyServer.AddCustomHandler(scheduleHandlers)
+	proxyServer.AddActivitySource("schedules", schedule.NewActivitySource(scheduleManager), activity.KindScheduleRun)
 
 	log.Printf("[SCHEDULE_HANDLERS] Schedule handlers registered successfully")
 }
@@ -438,6 +500,92 @@ func startSlackbotCleanupWorker(configData *config.Config, proxyServer *app.Serv
 	return leaderCleanupWorker
 }
 
+// startIdleReaper starts the idle session reaper with leader election.
+// It follows the same pattern as startSlackbotCleanupWorker.
+func startIdleReaper(configData *config.Config, proxyServer *app.Server) *idlereaper.LeaderReaper {
+	log.Printf("[IDLE_REAPER] Initializing idle session reaper...")
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		log.Printf("[IDLE_REAPER] Kubernetes config not available, idle reaper disabled: %v", err)
+		return nil
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Printf("[IDLE_REAPER] Failed to create Kubernetes client, idle reaper disabled: %v", err)
+		return nil
+	}
+
+	namespace := resolveKubernetesNamespace(configData.KubernetesSession.Namespace)
+
+	checkInterval, err := time.ParseDuration(configData.IdleReaper.CheckInterval)
+	if err != nil || checkInterval <= 0 {
+		log.Printf("[IDLE_REAPER] Invalid check_interval, using default 5m: %v", err)
+		checkInterval = 5 * time.Minute
+	}
+
+	idleTTL, err := time.ParseDuration(configData.IdleReaper.IdleTTL)
+	if err != nil || idleTTL <= 0 {
+		log.Printf("[IDLE_REAPER] Invalid idle_ttl, using default 24h: %v", err)
+		idleTTL = 24 * time.Hour
+	}
+
+	action := idlereaper.Action(configData.IdleReaper.Action)
+	switch action {
+	case idlereaper.ActionDelete, idlereaper.ActionScaleToZero:
+	default:
+		log.Printf("[IDLE_REAPER] Invalid action %q, using default %q", action, idlereaper.ActionDelete)
+		action = idlereaper.ActionDelete
+	}
+
+	reaperConfig := idlereaper.Config{
+		CheckInterval: checkInterval,
+		IdleTTL:       idleTTL,
+		Action:        action,
+		DryRun:        configData.IdleReaper.DryRun,
+	}
+
+	leaseDuration, err := time.ParseDuration(configData.IdleReaper.LeaseDuration)
+	if err != nil || leaseDuration <= 0 {
+		log.Printf("[IDLE_REAPER] Invalid lease_duration, using default 15s: %v", err)
+		leaseDuration = 15 * time.Second
+	}
+
+	renewDeadline, err := time.ParseDuration(configData.IdleReaper.RenewDeadline)
+	if err != nil || renewDeadline <= 0 {
+		log.Printf("[IDLE_REAPER] Invalid renew_deadline, using default 10s: %v", err)
+		renewDeadline = 10 * time.Second
+	}
+
+	retryPeriod, err := time.ParseDuration(configData.IdleReaper.RetryPeriod)
+	if err != nil || retryPeriod <= 0 {
+		log.Printf("[IDLE_REAPER] Invalid retry_period, using default 2s: %v", err)
+		retryPeriod = 2 * time.Second
+	}
+
+	electionConfig := schedule.LeaderElectionConfig{
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Namespace:     namespace,
+		// LeaseName is overridden inside NewLeaderReaper to "agentapi-idle-reaper"
+	}
+
+	leaderReaper := idlereaper.NewLeaderReaper(
+		proxyServer.GetSessionManager(),
+		client,
+		namespace,
+		reaperConfig,
+		electionConfig,
+	)
+
+	go leaderReaper.Run(context.Background())
+
+	log.Printf("[IDLE_REAPER] Idle session reaper started in namespace: %s (TTL: %v, action: %s)", namespace, idleTTL, action)
+	return leaderReaper
+}
+
 // startStockInventoryWorker starts the stock session inventory worker with leader election.
 // It ensures a configurable number of pre-warmed stock sessions are always available.
 func startStockInventoryWorker(configData *config.Config, proxyServer *app.Server) *stock_inventory.LeaderWorker {
@@ -567,13 +715,26 @@ func registerWebhookHandlers(configData *config.Config, proxyServer *app.Server)
 	webhookRepo := repositories.NewKubernetesWebhookRepository(client, namespace)
 
 	// Set default GitHub Enterprise host if configured
-	if configData.Webhook.GitHubEnterpriseHost != "" {
-		webhookRepo.SetDefaultGitHubEnterpriseHost(configData.Webhook.GitHubEnterpriseHost)
-		log.Printf("[WEBHOOK_HANDLERS] Default GitHub Enterprise host configured: %s", configData.Webhook.GitHubEnterpriseHost)
+	if host := configData.WebhookGitHubEnterpriseHost(); host != "" {
+		webhookRepo.SetDefaultGitHubEnterpriseHost(host)
+		log.Printf("[WEBHOOK_HANDLERS] Default GitHub Enterprise host configured: %s", host)
 	}
 
 	// Create and register webhook handlers with baseURL from config
 	webhookHandlers := webhook.NewHandlers(webhookRepo, proxyServer.GetSessionManager(), configData.Webhook.BaseURL, proxyServer.GetMemoryRepository(), proxyServer.GetSessionProfileRepository())
+	if gh := configData.Webhook.GitHub; gh.Enabled {
+		rulesController, err := webhook.NewWebhookGitHubRulesController(githubRulesConfig(gh), webhookRepo, proxyServer.GetSessionManager(), proxyServer.GetMemoryRepository(), proxyServer.GetSessionProfileRepository())
+		if err != nil {
+			log.Printf("[WEBHOOK_HANDLERS] GitHub rules receiver disabled: %v", err)
+		} else {
+			webhookHandlers.WithGitHubRules(rulesController)
+			log.Printf("[WEBHOOK_HANDLERS] GitHub rules receiver enabled at /webhooks/github (%d rules)", len(gh.Rules))
+		}
+	}
+	if reporter := proxyServer.GetGitHubReporter(); reporter != nil {
+		webhookHandlers.WithSessionReporter(reporter)
+		log.Printf("[WEBHOOK_HANDLERS] GitHub webhook sessions are reported as branches, check runs and pull requests")
+	}
 	proxyServer.AddCustomHandler(webhookHandlers)
 
 	if configData.Webhook.BaseURL != "" {
@@ -584,6 +745,38 @@ func registerWebhookHandlers(configData *config.Config, proxyServer *app.Server)
 	log.Printf("[WEBHOOK_HANDLERS] Webhook handlers registered successfully")
 }
 
+// githubRulesConfig converts the webhook.github config section for the webhook module
+func githubRulesConfig(gh config.GitHubWebhookRulesConfig) webhook.GitHubRulesConfig {
+	rules := make([]webhook.GitHubRule, 0, len(gh.Rules))
+	for _, r := range gh.Rules {
+		rules = append(rules, webhook.GitHubRule{
+			Name:                   r.Name,
+			Events:                 r.Events,
+			Actions:                r.Actions,
+			Command:                r.Command,
+			Labels:                 r.Labels,
+			Branches:               r.Branches,
+			BaseBranches:           r.BaseBranches,
+			Repositories:           r.Repositories,
+			Senders:                r.Senders,
+			InitialMessageTemplate: r.InitialMessageTemplate,
+			SessionProfileID:       r.SessionProfileID,
+			AgentType:              r.AgentType,
+			Tags:                   r.Tags,
+			Environment:            r.Environment,
+			ReuseSession:           r.ReuseSession,
+		})
+	}
+	return webhook.GitHubRulesConfig{
+		Secret:       gh.Secret,
+		UserID:       gh.UserID,
+		TeamID:       gh.TeamID,
+		Repositories: gh.Repositories,
+		MaxSessions:  gh.MaxSessions,
+		Rules:        rules,
+	}
+}
+
 // startSessionAllocator starts the leader-elected SessionAllocator. API requests
 // can land on any proxy replica, but only the elected leader consumes allocation
 // requests and creates/adopts session Pods.
@@ -711,8 +904,8 @@ func registerImportExportHandlers(configData *config.Config, proxyServer *app.Se
 	webhookRepo := repositories.NewKubernetesWebhookRepository(client, namespace)
 
 	// Set default GitHub Enterprise host if configured
-	if configData.Webhook.GitHubEnterpriseHost != "" {
-		webhookRepo.SetDefaultGitHubEnterpriseHost(configData.Webhook.GitHubEnterpriseHost)
+	if host := configData.WebhookGitHubEnterpriseHost(); host != "" {
+		webhookRepo.SetDefaultGitHubEnterpriseHost(host)
 	}
 
 	// Get settings repository from server
@@ -759,6 +952,20 @@ func registerSlackBotHandlers(configData *config.Config, proxyServer *app.Server
 
 	// Create and register SlackBot management handlers (no event reception - handled by Socket Mode)
 	slackbotHandlers := slackbot.NewHandlers(slackbotRepo)
+	if configData.Slack.SigningSecret != "" {
+		slackbotHandlers.WithCommandHandler(slackbot.NewSlackCommandHandler(
+			proxyServer.GetSessionManager(),
+			proxyServer.GetSettingsRepository(),
+			slackbot.NewSlackChannelResolver(client, namespace).WithDeliveryQueue(proxyServer.GetDeliveryQueue()),
+			configData.Slack.SigningSecret,
+			configData.KubernetesSession.SlackBotTokenSecretName,
+			configData.KubernetesSession.SlackBotTokenSecretKey,
+			configData.Webhook.BaseURL,
+			configData.Slack.DryRun,
+			proxyServer.GetMemoryRepository(),
+			proxyServer.GetSessionProfileRepository(),
+		))
+	}
 	proxyServer.AddCustomHandler(slackbotHandlers)
 
 	log.Printf("[SLACKBOT_HANDLERS] SlackBot management handlers registered successfully")
@@ -786,7 +993,7 @@ func startSlackSocketManager(configData *config.Config, proxyServer *app.Server)
 
 	// Create dependencies
 	slackbotRepo := repositories.NewKubernetesSlackBotRepository(client, namespace)
-	channelResolver := slackbot.NewSlackChannelResolver(client, namespace)
+	channelResolver := slackbot.NewSlackChannelResolver(client, namespace).WithDeliveryQueue(proxyServer.GetDeliveryQueue())
 
 	eventHandler := slackbot.NewSlackBotEventHandler(
 		slackbotRepo,
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/app"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// storage-migration command flags
+var (
+	storageMigrationConfig      string
+	storageMigrationStore       string
+	storageMigrationNamespace   string
+	storageMigrationDeleteExtra bool
+)
+
+var storageMigrationCmd = &cobra.Command{
+	Use:   "storage-migration",
+	Short: "Migrate a store to another backend",
+	Long: `Migrate memories (memory.backend: kubernetes <-> s3) or session records
+(session_store.backend: postgres <-> sqlite) to another backend without
+losing writes.
+
+  1. Set the target in the migration section of the store
+     (memory.migration.target_backend, or session_store.migration.target_backend
+     and target_dsn) and roll out the proxy. Every write now goes to both
+     backends; reads still come from the current backend.
+  2. Run "backfill" to copy the records that existed before the rollout.
+  3. Run "verify" until the record counts and checksums match.
+  4. Run "cutover". It verifies again and, only when both backends match,
+     switches reads to the target within 30 seconds. Writes still go to both
+     backends, so "rollback" switches reads back without losing data.
+  5. Make the target the backend of the store and remove the migration
+     section. The cutover state is ignored without a migration section.
+
+Examples:
+  agentapi-proxy admin storage-migration backfill --store memory --config config.json
+  agentapi-proxy admin storage-migration verify --store session_store
+  agentapi-proxy admin storage-migration cutover --store session_store`,
+}
+
+var storageMigrationVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare the record counts and checksums of both backends",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStorageMigration(func(ctx context.Context, m *app.StorageMigration) error {
+			report, err := m.Verify(ctx)
+			if err != nil {
+				return err
+			}
+			if err := printStorageMigrationReport(report); err != nil {
+				return err
+			}
+			if !report.OK() {
+				return fmt.Errorf("%s backends differ", m.Store)
+			}
+			return nil
+		})
+	},
+}
+
+var storageMigrationBackfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Copy missing and differing records from the source to the target",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStorageMigration(func(ctx context.Context, m *app.StorageMigration) error {
+			before, err := m.Backfill(ctx, storageMigrationDeleteExtra)
+			if before != nil {
+				fmt.Fprintf(os.Stderr, "Copied %d missing and %d differing record(s) from %s to %s\n",
+					len(before.Missing), len(before.Mismatched), m.Source, m.Target)
+			}
+			if err != nil {
+				return err
+			}
+			after, err := m.Verify(ctx)
+			if err != nil {
+				return err
+			}
+			return printStorageMigrationReport(after)
+		})
+	},
+}
+
+var storageMigrationCutoverCmd = &cobra.Command{
+	Use:   "cutover",
+	Short: "Verify both backends and switch reads to the target",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStorageMigration(func(ctx context.Context, m *app.StorageMigration) error {
+			report, err := m.Cutover(ctx)
+			if report != nil {
+				if printErr := printStorageMigrationReport(report); printErr != nil {
+					return printErr
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("cutover refused: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Reads of %s switched from %s to %s\n", m.Store, m.Source, m.Target)
+			return nil
+		})
+	},
+}
+
+var storageMigrationRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Switch reads back to the source",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStorageMigration(func(ctx context.Context, m *app.StorageMigration) error {
+			if err := m.Rollback(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Reads of %s switched back to %s\n", m.Store, m.Source)
+			return nil
+		})
+	},
+}
+
+func init() {
+	storageMigrationCmd.PersistentFlags().StringVarP(&storageMigrationConfig, "config", "c", "config.json",
+		"Configuration file path (environment variables are used when it cannot be read)")
+	storageMigrationCmd.PersistentFlags().StringVar(&storageMigrationStore, "store", "",
+		fmt.Sprintf("Store to migrate (%s or %s)", repositories.StorageMigrationMemory, repositories.StorageMigrationSessionStore))
+	storageMigrationCmd.PersistentFlags().StringVar(&storageMigrationNamespace, "namespace", "",
+		"Namespace of the proxy (default: kubernetes_session.namespace, then the in-cluster namespace)")
+	_ = storageMigrationCmd.MarkPersistentFlagRequired("store")
+	storageMigrationBackfillCmd.Flags().BoolVar(&storageMigrationDeleteExtra, "delete-extra", false,
+		"Also delete records that only the target has")
+
+	storageMigrationCmd.AddCommand(storageMigrationVerifyCmd)
+	storageMigrationCmd.AddCommand(storageMigrationBackfillCmd)
+	storageMigrationCmd.AddCommand(storageMigrationCutoverCmd)
+	storageMigrationCmd.AddCommand(storageMigrationRollbackCmd)
+	AdminCmd.AddCommand(storageMigrationCmd)
+}
+
+// runStorageMigration opens the migration of the selected store and runs fn.
+func runStorageMigration(fn func(ctx context.Context, m *app.StorageMigration) error) error {
+	cfg, err := config.LoadConfig(storageMigrationConfig)
+	if err != nil {
+		cfg, err = config.LoadConfig("")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get Kubernetes config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	namespace := storageMigrationNamespace
+	if namespace == "" {
+		namespace = rbacCheckNamespace(cfg)
+	}
+	ctx := context.Background()
+	migration, err := app.OpenStorageMigration(ctx, cfg, storageMigrationStore, client, namespace)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, migration)
+}
+
+func printStorageMigrationReport(report *repositories.StorageMigrationReport) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
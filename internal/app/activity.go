@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+)
+
+// activityNotificationLimit caps the notifications read for one feed
+const activityNotificationLimit = 100
+
+// buildActivityFeed registers the activity sources available in the proxy
+// itself. Schedule runs are added by the schedule module through
+// AddActivitySource.
+func (s *Server) buildActivityFeed() *activity.Feed {
+	feed := activity.NewFeed()
+	feed.Add("sessions", activity.SourceFunc(s.sessionActivity), activity.KindSession)
+	if s.auditRepo != nil || s.sessionArchive != nil {
+		feed.Add("deleted_sessions", deletedSessionActivity(s.auditRepo, s.sessionArchive), activity.KindSessionDeleted)
+	}
+	if s.auditRepo != nil {
+		feed.Add("approvals", approvalActivity(s.auditRepo), activity.KindApproval)
+	}
+	if s.notificationSvc != nil {
+		feed.Add("notifications", notificationActivity(s.notificationSvc), activity.KindNotification)
+	}
+	return feed
+}
+
+// AddActivitySource adds a source to the feed of GET /me/activity
+func (s *Server) AddActivitySource(name string, source activity.Source, kinds ...string) {
+	s.activityFeed.Add(name, source, kinds...)
+}
+
+// sessionActivity lists the sessions of the user at their latest activity:
+// the last message, or their start.
+func (s *Server) sessionActivity(ctx context.Context, q activity.Query) ([]activity.Item, error) {
+	sessions := s.sessionManager.ListSessions(entities.SessionFilter{UserID: q.UserID})
+	items := make([]activity.Item, 0, len(sessions))
+	for _, sess := range sessions {
+		at := sess.StartedAt()
+		if sess.LastMessageAt().After(at) {
+			at = sess.LastMessageAt()
+		}
+		items = append(items, activity.Item{
+			Kind:      activity.KindSession,
+			Timestamp: at,
+			SessionID: sess.ID(),
+			Title:     sess.Description(),
+			Status:    sess.Status(),
+		})
+	}
+	return items, nil
+}
+
+// deletedSessionActivity lists the recently deleted sessions of the user:
+// those archived with their logs, and those the user deleted themselves
+// according to the audit log.
+func deletedSessionActivity(auditRepo portrepos.AuditRepository, archive *sessionarchive.Archive) activity.Source {
+	return activity.SourceFunc(func(ctx context.Context, q activity.Query) ([]activity.Item, error) {
+		var items []activity.Item
+		seen := map[string]bool{}
+		if archive != nil {
+			manifests, err := archive.List(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list archived sessions: %w", err)
+			}
+			for _, m := range manifests {
+				if m.UserID != q.UserID || m.ArchivedAt.Before(q.Since) {
+					continue
+				}
+				seen[m.SessionID] = true
+				items = append(items, activity.Item{
+					Kind:      activity.KindSessionDeleted,
+					Timestamp: m.ArchivedAt,
+					SessionID: m.SessionID,
+					Title:     m.Tags["description"],
+					Status:    "archived",
+				})
+			}
+		}
+		if auditRepo != nil {
+			events, err := auditRepo.ListAuditEvents(ctx, q.Since, time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("failed to list audit events: %w", err)
+			}
+			for _, event := range events {
+				if event.Operation != entities.AuditOpSessionDelete || event.Actor != q.UserID {
+					continue
+				}
+				sessionID := strings.TrimPrefix(event.Resource, "/sessions/")
+				if seen[sessionID] {
+					continue
+				}
+				seen[sessionID] = true
+				items = append(items, activity.Item{
+					Kind:      activity.KindSessionDeleted,
+					Timestamp: event.Timestamp,
+					SessionID: sessionID,
+					Status:    "deleted",
+				})
+			}
+		}
+		return items, nil
+	})
+}
+
+// approvalActivity lists the capability requests of the user's sessions that
+// were denied for lack of approval in the team or base settings.
+func approvalActivity(auditRepo portrepos.AuditRepository) activity.Source {
+	return activity.SourceFunc(func(ctx context.Context, q activity.Query) ([]activity.Item, error) {
+		events, err := auditRepo.ListAuditEvents(ctx, q.Since, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit events: %w", err)
+		}
+		var items []activity.Item
+		for _, event := range events {
+			if event.Category != entities.AuditCategoryPolicyViolation || event.Action != "capability_denied" || event.Actor != q.UserID {
+				continue
+			}
+			items = append(items, activity.Item{
+				Kind:      activity.KindApproval,
+				Timestamp: event.Timestamp,
+				SessionID: strings.TrimPrefix(event.Resource, "session/"),
+				Title:     "Capabilities not approved",
+				Status:    event.Outcome,
+				Detail:    event.Detail,
+			})
+		}
+		return items, nil
+	})
+}
+
+// notificationActivity lists the notifications sent to the user
+func notificationActivity(svc *notification.Service) activity.Source {
+	return activity.SourceFunc(func(ctx context.Context, q activity.Query) ([]activity.Item, error) {
+		history, err := svc.GetNotificationHistory(q.UserID, activityNotificationLimit, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]activity.Item, 0, len(history.Notifications))
+		for _, n := range history.Notifications {
+			status := "sent"
+			if n.ErrorMessage != nil {
+				status = "failed"
+			} else if n.Clicked {
+				status = "clicked"
+			}
+			items = append(items, activity.Item{
+				Kind:      activity.KindNotification,
+				Timestamp: n.SentAt,
+				SessionID: n.SessionID,
+				Title:     n.Title,
+				Status:    status,
+				Detail:    n.Body,
+				Ref:       n.ID,
+			})
+		}
+		return items, nil
+	})
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// auditRecordTimeout bounds writing one audit event
+const auditRecordTimeout = 10 * time.Second
+
+// auditPurgeInterval is how often audit events past retention are purged
+const auditPurgeInterval = time.Hour
+
+// sessionProxyRoute is the catch-all route that proxies to session backends
+const sessionProxyRoute = "/:sessionId/*"
+
+// auditOperations names the operation of well-known mutating routes and of
+// the WebSocket upgrades that attach to a session container. GET routes are
+// only named, and so audited, when the request is a WebSocket upgrade.
+var auditOperations = map[string]string{
+	http.MethodPost + " /start":                                      entities.AuditOpSessionCreate,
+	http.MethodDelete + " /sessions/:sessionId":                      entities.AuditOpSessionDelete,
+	http.MethodPost + " /sessions/:sessionId/exec":                   entities.AuditOpSessionExec,
+	http.MethodGet + " /sessions/:sessionId/exec":                    entities.AuditOpSessionExec,
+	http.MethodGet + " /sessions/:sessionId/terminal":                entities.AuditOpTerminalAttach,
+	http.MethodGet + " /sessions/:sessionId/terminal/*":              entities.AuditOpTerminalAttach,
+	http.MethodGet + " /sessions/:sessionId/editor":                  entities.AuditOpEditorAttach,
+	http.MethodGet + " /sessions/:sessionId/editor/*":                entities.AuditOpEditorAttach,
+	http.MethodGet + " /sessions/:sessionId/browser":                 entities.AuditOpBrowserAttach,
+	http.MethodGet + " /sessions/:sessionId/browser/*":               entities.AuditOpBrowserAttach,
+	http.MethodPut + " /settings/:name":                              entities.AuditOpSettingsUpdate,
+	http.MethodDelete + " /settings/:name":                           entities.AuditOpSettingsDelete,
+	http.MethodDelete + " /settings/:name/sync":                      entities.AuditOpSettingsDelete,
+	http.MethodPut + " /credentials/:name":                           entities.AuditOpSecretUpdate,
+	http.MethodDelete + " /credentials/:name":                        entities.AuditOpSecretDelete,
+	http.MethodPost + " /users/me/api-key":                           entities.AuditOpSecretCreate,
+	http.MethodPost + " /api-tokens":                                 entities.AuditOpSecretCreate,
+	http.MethodDelete + " /api-tokens/:tokenId":                      entities.AuditOpSecretDelete,
+	http.MethodPost + " /external-session-managers/:id/rotate-token": entities.AuditOpSecretRotate,
+	http.MethodPost + " /schedules":                                  entities.AuditOpScheduleCreate,
+	http.MethodPut + " /schedules/:id":                               entities.AuditOpScheduleUpdate,
+	http.MethodDelete + " /schedules/:id":                            entities.AuditOpScheduleDelete,
+	http.MethodPost + " /resources/transfer":                         entities.AuditOpResourceTransfer,
+}
+
+// auditOperation names the operation of a request, or returns "". Of the
+// traffic proxied to session backends only sent messages are named.
+func auditOperation(c echo.Context) string {
+	if c.Path() == sessionProxyRoute {
+		if c.Request().Method == http.MethodPost && strings.Trim(c.Param("*"), "/") == "message" {
+			return entities.AuditOpMessageSend
+		}
+		return ""
+	}
+	if c.Request().Method == http.MethodGet && !c.IsWebSocket() {
+		return ""
+	}
+	return auditOperations[c.Request().Method+" "+c.Path()]
+}
+
+// auditMiddleware records security-relevant requests to the audit log. It
+// must run before AuthMiddleware so that rejected credentials are recorded.
+// Events are written in the background; writes tracks them so that shutdown
+// can wait for them.
+func auditMiddleware(repo portrepos.AuditRepository, writes *sync.WaitGroup) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now().UTC()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			if event, ok := auditEventFor(c, status, start); ok {
+				writes.Add(1)
+				go func() {
+					defer writes.Done()
+					ctx, cancel := context.WithTimeout(context.Background(), auditRecordTimeout)
+					defer cancel()
+					if err := repo.RecordAuditEvent(ctx, event); err != nil {
+						log.Printf("[AUDIT] Failed to record %s event %q: %v", event.Category, event.Action, err)
+					}
+				}()
+			}
+			return err
+		}
+	}
+}
+
+// auditEventFor classifies a finished request. Rejected credentials and
+// permissions are always recorded; successful reads other than WebSocket
+// attaches to session containers, and proxied agent traffic other than sent
+// messages, are not. Events are timestamped with the start of the request,
+// since a WebSocket only finishes when the connection closes; a hijacked
+// WebSocket leaves the status at 200.
+func auditEventFor(c echo.Context, status int, start time.Time) (entities.AuditEvent, bool) {
+	req := c.Request()
+	path := req.URL.Path
+	if req.Method == http.MethodOptions || path == "/health" || path == "/ready" || path == "/metrics" ||
+		strings.HasPrefix(path, "/public") || path == "/openapi.json" || path == "/docs" ||
+		strings.HasPrefix(path, "/internal/") {
+		return entities.AuditEvent{}, false
+	}
+
+	event := entities.AuditEvent{
+		Timestamp: start,
+		Action:    req.Method + " " + c.Path(),
+		Operation: auditOperation(c),
+		Actor:     "anonymous",
+		Resource:  path,
+		ClientIP:  c.RealIP(),
+	}
+	user := auth.GetUserFromContext(c)
+	if user != nil {
+		event.Actor = string(user.ID())
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		event.Category = entities.AuditCategoryAccess
+		event.Outcome = entities.AuditOutcomeDenied
+		event.Detail = strconv.Itoa(status) + " " + http.StatusText(status)
+		return event, true
+	case (status < 200 || status >= 300) && status != http.StatusSwitchingProtocols:
+		return entities.AuditEvent{}, false
+	case (req.Method == http.MethodGet || req.Method == http.MethodHead) && event.Operation == "":
+		return entities.AuditEvent{}, false
+	case c.Path() == sessionProxyRoute && event.Operation == "":
+		return entities.AuditEvent{}, false
+	case event.Operation == entities.AuditOpSecretRotate:
+		event.Category = entities.AuditCategorySecretRotation
+	case user != nil && user.IsAdmin():
+		event.Category = entities.AuditCategoryAdminAction
+	default:
+		event.Category = entities.AuditCategoryAccess
+	}
+	event.Outcome = entities.AuditOutcomeSuccess
+	return event, true
+}
+
+// purgeAuditEvents periodically deletes audit events older than the
+// retention period and records each purge for the compliance report, until
+// ctx is cancelled.
+func (s *Server) purgeAuditEvents(ctx context.Context, retentionDays int) {
+	s.purgeAuditEventsOnce(retentionDays)
+
+	ticker := time.NewTicker(auditPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.purgeAuditEventsOnce(retentionDays)
+		}
+	}
+}
+
+func (s *Server) purgeAuditEventsOnce(retentionDays int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	before := time.Now().UTC().AddDate(0, 0, -retentionDays)
+	purged, err := s.auditRepo.PurgeAuditEvents(ctx, before)
+	if err == nil && purged == 0 {
+		return
+	}
+
+	event := entities.AuditEvent{
+		Timestamp: time.Now().UTC(),
+		Category:  entities.AuditCategoryRetention,
+		Action:    "purge_audit_events",
+		Actor:     "system",
+		Resource:  "audit_events",
+		Outcome:   entities.AuditOutcomeSuccess,
+		Detail:    strconv.Itoa(purged),
+	}
+	if err != nil {
+		log.Printf("[AUDIT] Failed to purge audit events older than %s: %v", before.Format(time.RFC3339), err)
+		event.Outcome = entities.AuditOutcomeFailure
+	} else {
+		log.Printf("[AUDIT] Purged %d audit events older than %d days", purged, retentionDays)
+	}
+	if err := s.auditRepo.RecordAuditEvent(ctx, event); err != nil {
+		log.Printf("[AUDIT] Failed to record retention purge: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestAuditEventForWebSocketAttach(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		route         string
+		websocket     bool
+		status        int
+		wantRecorded  bool
+		wantOperation string
+	}{
+		{name: "interactive exec", method: http.MethodGet, route: "/sessions/:sessionId/exec", websocket: true, status: http.StatusOK, wantRecorded: true, wantOperation: entities.AuditOpSessionExec},
+		{name: "terminal upgrade", method: http.MethodGet, route: "/sessions/:sessionId/terminal/*", websocket: true, status: http.StatusSwitchingProtocols, wantRecorded: true, wantOperation: entities.AuditOpTerminalAttach},
+		{name: "editor upgrade", method: http.MethodGet, route: "/sessions/:sessionId/editor/*", websocket: true, status: http.StatusOK, wantRecorded: true, wantOperation: entities.AuditOpEditorAttach},
+		{name: "terminal page", method: http.MethodGet, route: "/sessions/:sessionId/terminal/*", status: http.StatusOK},
+		{name: "failed upgrade", method: http.MethodGet, route: "/sessions/:sessionId/exec", websocket: true, status: http.StatusBadRequest},
+		{name: "other read", method: http.MethodGet, route: "/sessions/:sessionId/events", websocket: true, status: http.StatusOK},
+		{name: "exec command", method: http.MethodPost, route: "/sessions/:sessionId/exec", status: http.StatusOK, wantRecorded: true, wantOperation: entities.AuditOpSessionExec},
+	}
+
+	e := echo.New()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/sessions/s1/exec", nil)
+			if tt.websocket {
+				req.Header.Set(echo.HeaderConnection, "Upgrade")
+				req.Header.Set(echo.HeaderUpgrade, "websocket")
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetPath(tt.route)
+
+			event, ok := auditEventFor(c, tt.status, start)
+			if ok != tt.wantRecorded {
+				t.Fatalf("recorded = %v, want %v", ok, tt.wantRecorded)
+			}
+			if !ok {
+				return
+			}
+			if event.Operation != tt.wantOperation {
+				t.Errorf("operation = %q, want %q", event.Operation, tt.wantOperation)
+			}
+			if event.Outcome != entities.AuditOutcomeSuccess || !event.Timestamp.Equal(start) {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
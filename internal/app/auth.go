@@ -138,7 +138,7 @@ func (s *Server) handleOAuthCallback(c echo.Context) error {
 	sessionID := uuid.New().String()
 	expiresAt := time.Now().Add(24 * time.Hour) // Token expires in 24 hours
 
-	// Store session information (in production, use a proper session store)
+	// Store session information (in Redis when configured, see oauthSessionStore)
 	s.oauthSessions.Store(sessionID, &OAuthSession{
 		ID:          sessionID,
 		UserContext: userContext,
@@ -214,6 +214,7 @@ func (s *Server) handleOAuthRefresh(c echo.Context) error {
 
 	// Extend session expiration
 	session.ExpiresAt = time.Now().Add(24 * time.Hour)
+	s.oauthSessions.Store(sessionID, session)
 
 	return c.JSON(http.StatusOK, OAuthTokenResponse{
 		AccessToken: session.UserContext.AccessToken,
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/pkg/capacity"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+)
+
+const defaultCapacitySampleInterval = 5 * time.Minute
+
+// capacityForecaster samples the sessions of the Kubernetes session manager
+// and forecasts the coming week from the recorded history
+type capacityForecaster struct {
+	recorder *capacity.Recorder
+	opts     capacity.ForecastOptions
+}
+
+// Forecast returns the forecast for the week following now
+func (f *capacityForecaster) Forecast(ctx context.Context, now time.Time) (*capacity.Report, error) {
+	buckets, err := f.recorder.Buckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return capacity.Forecast(buckets, now, f.opts), nil
+}
+
+// buildCapacityForecaster starts sampling the sessions of manager. Returns
+// nil when capacity forecasts are disabled or the store cannot be created.
+func buildCapacityForecaster(cfg *config.Config, manager *services.KubernetesSessionManager, singletons *leader.Runner) *capacityForecaster {
+	cc := cfg.CapacityForecast
+	if !cc.Enabled {
+		return nil
+	}
+
+	dir := cc.Dir
+	if dir == "" {
+		dir = filepath.Join(notification.GetBaseDir(), "capacity")
+	}
+	store, err := capacity.NewFileStore(dir)
+	if err != nil {
+		log.Printf("[CAPACITY] Failed to initialize store, capacity forecasts disabled: %v", err)
+		return nil
+	}
+
+	list := func() []capacity.Session {
+		sessions := manager.ListSessions(entities.SessionFilter{})
+		out := make([]capacity.Session, 0, len(sessions))
+		for _, s := range sessions {
+			out = append(out, capacity.Session{ID: s.ID(), StartedAt: s.StartedAt()})
+		}
+		return out
+	}
+	recorder := capacity.NewRecorder(store, list, time.Duration(cc.RetentionDays)*24*time.Hour)
+
+	interval := defaultCapacitySampleInterval
+	if d, err := time.ParseDuration(cc.SampleInterval); err == nil && d > 0 {
+		interval = d
+	}
+	singletons.Go("capacity sampler", func(ctx context.Context) { recorder.Run(ctx, interval) })
+
+	opts := capacity.ForecastOptions{
+		SessionCPUMillis:   quantityMillis(cfg.KubernetesSession.CPURequest),
+		SessionMemoryBytes: quantityValue(cfg.KubernetesSession.MemoryRequest),
+		NodeCPUMillis:      quantityMillis(cc.NodeCPU),
+		NodeMemoryBytes:    quantityValue(cc.NodeMemory),
+		Headroom:           cc.Headroom,
+	}
+	log.Printf("[CAPACITY] Sampling sessions every %s (store: %s)", interval, dir)
+	return &capacityForecaster{recorder: recorder, opts: opts}
+}
+
+// newCapacityController returns the forecast controller, or nil when
+// capacity forecasts are disabled
+func newCapacityController(f *capacityForecaster) *controllers.CapacityController {
+	if f == nil {
+		return nil
+	}
+	return controllers.NewCapacityController(f)
+}
+
+// quantityMillis parses a CPU quantity such as "500m", returning 0 when it
+// is empty or invalid
+func quantityMillis(s string) int64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.MilliValue()
+}
+
+// quantityValue parses a memory quantity such as "2Gi" in bytes, returning 0
+// when it is empty or invalid
+func quantityValue(s string) int64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/outboundwebhook"
+)
+
+// buildCompletionCallbacks creates the sender of session completion
+// callbacks. Sessions opt in with params.completion_callback_url, so the
+// sender is always created.
+func buildCompletionCallbacks(cfg *config.Config, manager *services.KubernetesSessionManager, queue *delivery.Queue) *outboundwebhook.Callbacks {
+	callbackURL := func(id string) string {
+		if ks, ok := manager.GetSession(id).(*services.KubernetesSession); ok {
+			return ks.CompletionCallbackURL()
+		}
+		return ""
+	}
+	callbacks := outboundwebhook.NewCallbacks(callbackURL, manager.GetSession, cfg.CompletionCallbacks.Secret)
+	if cfg.CompletionCallbacks.AllowPrivateTargets {
+		callbacks.AllowPrivateTargets()
+	}
+	if queue != nil {
+		callbacks.WithDeliveryQueue(queue)
+	}
+	return callbacks
+}
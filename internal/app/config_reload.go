@@ -0,0 +1,251 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portservices "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// defaultConfigReloadDebounce is the quiet period after a config file change
+// before reloading
+const defaultConfigReloadDebounce = 2 * time.Second
+
+// reloadableMiddleware runs the middleware built from the last applied
+// config, so that a config reload can replace it without re-registering
+// middleware.
+type reloadableMiddleware struct {
+	current atomic.Pointer[echo.MiddlewareFunc]
+}
+
+func newReloadableMiddleware(mw echo.MiddlewareFunc) *reloadableMiddleware {
+	m := &reloadableMiddleware{}
+	m.set(mw)
+	return m
+}
+
+func (m *reloadableMiddleware) set(mw echo.MiddlewareFunc) {
+	m.current.Store(&mw)
+}
+
+func (m *reloadableMiddleware) middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return (*m.current.Load())(next)(c)
+		}
+	}
+}
+
+// configReloader applies the safe-to-change settings of a reloaded config:
+// auth providers, rate limits and the images and resource limits of new
+// sessions. See config.ReloadableSettings.
+type configReloader struct {
+	mu sync.Mutex
+	// path is the config file; "" searches the default locations
+	path string
+	// applied is the startup config with the reloadable settings of the last
+	// successful reload
+	applied     *config.Config
+	authService portservices.AuthService
+	k8sManager  *services.KubernetesSessionManager
+	auth        *reloadableMiddleware
+	rateLimits  rateLimiting
+	rateLimit   *reloadableMiddleware
+}
+
+func newConfigReloader(cfg *config.Config, authService portservices.AuthService, k8sManager *services.KubernetesSessionManager) *configReloader {
+	r := &configReloader{
+		applied:     cfg,
+		authService: authService,
+		k8sManager:  k8sManager,
+	}
+	r.auth = newReloadableMiddleware(auth.AuthMiddleware(cfg, authService))
+	r.rateLimit = newReloadableMiddleware(r.rateLimits.middleware(cfg))
+	return r
+}
+
+// reload loads the config file, validates it and applies its reloadable
+// settings. Nothing is applied when loading or validation fails.
+func (r *configReloader) reload() (config.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.LoadConfig(r.path)
+	if err != nil {
+		return config.ReloadResult{}, fmt.Errorf("load config: %w", err)
+	}
+	if err := validateRateLimit(next.RateLimit); err != nil {
+		return config.ReloadResult{}, err
+	}
+	result := config.DiffReload(r.applied, next)
+	merged := config.MergeReload(r.applied, next)
+
+	// The session manager validates before applying, so it goes first
+	if r.k8sManager != nil {
+		live := merged.KubernetesSession
+		if err := r.k8sManager.ReloadConfig(&live); err != nil {
+			return config.ReloadResult{}, err
+		}
+	}
+	if simpleAuth, ok := r.authService.(*services.SimpleAuthService); ok {
+		simpleAuth.SetStaticAuthConfig(merged.Auth.Static)
+		simpleAuth.SetGitHubAuthConfig(merged.Auth.GitHub)
+	}
+	r.auth.set(auth.AuthMiddleware(merged, r.authService))
+	r.rateLimit.set(r.rateLimits.middleware(merged))
+	r.applied = merged
+	return result, nil
+}
+
+// watchedFiles returns the config file and the files it references
+func (r *configReloader) watchedFiles() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var files []string
+	for _, path := range []string{r.path, r.applied.AuthConfigFile, r.applied.KubernetesSession.ConfigFile} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	if r.applied.Auth.Static != nil && r.applied.Auth.Static.KeysFile != "" {
+		files = append(files, r.applied.Auth.Static.KeysFile)
+	}
+	return files
+}
+
+// ReloadConfig reloads the config file and applies its safe-to-change
+// settings. Every reload, successful or not, is recorded in the audit log
+// with actor as the actor.
+func (s *Server) ReloadConfig(ctx context.Context, actor string) (config.ReloadResult, error) {
+	result, err := s.configReloader.reload()
+
+	event := entities.AuditEvent{
+		Timestamp: time.Now().UTC(),
+		Category:  entities.AuditCategoryAdminAction,
+		Action:    "config_reload",
+		Operation: entities.AuditOpConfigReload,
+		Actor:     actor,
+		Resource:  "config",
+		Outcome:   entities.AuditOutcomeSuccess,
+	}
+	if err != nil {
+		log.Printf("[CONFIG_RELOAD] Reload requested by %s failed, keeping the current config: %v", actor, err)
+		event.Outcome = entities.AuditOutcomeFailure
+		event.Detail = err.Error()
+	} else {
+		log.Printf("[CONFIG_RELOAD] Reload requested by %s applied %v; restart required for %v", actor, result.Reloaded, result.RestartRequired)
+		event.Detail = "reloaded: " + strings.Join(result.Reloaded, ", ")
+		if len(result.RestartRequired) > 0 {
+			event.Detail += "; restart required: " + strings.Join(result.RestartRequired, ", ")
+		}
+	}
+	if s.auditRepo != nil {
+		if auditErr := s.auditRepo.RecordAuditEvent(ctx, event); auditErr != nil {
+			log.Printf("[AUDIT] Failed to record config reload: %v", auditErr)
+		}
+	}
+	return result, err
+}
+
+// EnableConfigReload sets the config file reloaded by ReloadConfig ("" searches
+// the default locations) and, with config_reload.watch, reloads whenever it
+// or a file it references changes until ctx is done.
+func (s *Server) EnableConfigReload(ctx context.Context, path string) {
+	s.configReloader.mu.Lock()
+	s.configReloader.path = path
+	s.configReloader.mu.Unlock()
+
+	if !s.config.ConfigReload.Watch {
+		return
+	}
+	debounce := defaultConfigReloadDebounce
+	if d, err := time.ParseDuration(s.config.ConfigReload.Debounce); err == nil && d > 0 {
+		debounce = d
+	}
+	files := s.configReloader.watchedFiles()
+	if len(files) == 0 {
+		log.Printf("[CONFIG_RELOAD] Warning: config_reload.watch is set but no config file is given, not watching")
+		return
+	}
+	if err := s.watchConfigFiles(ctx, files, debounce); err != nil {
+		log.Printf("[CONFIG_RELOAD] Warning: failed to watch config files: %v", err)
+	}
+}
+
+// watchConfigFiles reloads the config debounce after the last change of one
+// of files. Their directories are watched so that files replaced by rename,
+// as Kubernetes does for mounted ConfigMaps and Secrets, are noticed.
+func (s *Server) watchConfigFiles(ctx context.Context, files []string, debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	watched := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			abs = file
+		}
+		watched[abs] = true
+		dir := filepath.Dir(abs)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("watch %s: %w", dir, err)
+		}
+		dirs[dir] = true
+	}
+	log.Printf("[CONFIG_RELOAD] Watching %s", strings.Join(files, ", "))
+
+	go func() {
+		defer func() { _ = watcher.Close() }()
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// ConfigMap volumes swap the ..data symlink
+				if !watched[event.Name] && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(debounce, func() {
+					reloadCtx, cancel := context.WithTimeout(context.Background(), auditRecordTimeout)
+					defer cancel()
+					_, _ = s.ReloadConfig(reloadCtx, "system")
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("[CONFIG_RELOAD] Watch error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func writeReloadConfig(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeReloadConfig(t, path, `{"rate_limit": {"enabled": false}}`)
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditRepo := repositories.NewMemoryAuditRepository()
+	s := &Server{
+		config:         cfg,
+		auditRepo:      auditRepo,
+		configReloader: newConfigReloader(cfg, services.NewSimpleAuthService(), nil),
+	}
+	s.EnableConfigReload(context.Background(), path)
+
+	e := echo.New()
+	e.Use(s.configReloader.rateLimit.middleware())
+	e.GET("/sessions", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	get := func() int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sessions", nil))
+		return rec.Code
+	}
+	if get() != http.StatusOK || get() != http.StatusOK {
+		t.Fatal("rate limited before the reload")
+	}
+
+	writeReloadConfig(t, path, `{"rate_limit": {"enabled": true, "user": {"rps": 0.001, "burst": 1}}, "redis": {"addr": "redis:6379"}}`)
+	result, err := s.ReloadConfig(context.Background(), "admin")
+	if err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	if strings.Join(result.Reloaded, ",") != "rate_limit.enabled,rate_limit.user" || strings.Join(result.RestartRequired, ",") != "redis" {
+		t.Errorf("result = %+v", result)
+	}
+	if get() != http.StatusOK || get() != http.StatusTooManyRequests {
+		t.Error("reloaded rate limit not applied")
+	}
+
+	// An invalid config is rejected and the applied one is kept
+	writeReloadConfig(t, path, `{"rate_limit": {"enabled": true, "user": {"rps": -1}}}`)
+	if _, err := s.ReloadConfig(context.Background(), "admin"); err == nil {
+		t.Fatal("ReloadConfig accepted a negative rate")
+	}
+	if get() != http.StatusTooManyRequests {
+		t.Error("rejected reload replaced the rate limit")
+	}
+
+	events, err := auditRepo.ListAuditEvents(context.Background(), time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("audit events = %+v, want 2", events)
+	}
+	if events[0].Operation != entities.AuditOpConfigReload || events[0].Outcome != entities.AuditOutcomeSuccess || events[0].Actor != "admin" ||
+		events[0].Detail != "reloaded: rate_limit.enabled, rate_limit.user; restart required: redis" {
+		t.Errorf("success event = %+v", events[0])
+	}
+	if events[1].Outcome != entities.AuditOutcomeFailure || !strings.Contains(events[1].Detail, "rate_limit.user") {
+		t.Errorf("failure event = %+v", events[1])
+	}
+}
+
+func TestReloadConfigStaticKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	keysFile := filepath.Join(dir, "api_keys.json")
+	writeReloadConfig(t, path, `{"auth": {"static": {"enabled": true, "header_name": "X-API-Key", "keys_file": "`+keysFile+`"}}}`)
+	writeReloadConfig(t, keysFile, `{"api_keys": [{"key": "key-1", "user_id": "alice", "role": "user", "permissions": ["session:read"]}]}`)
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService := services.NewSimpleAuthService()
+	authService.SetStaticAuthConfig(cfg.Auth.Static)
+	s := &Server{
+		config:         cfg,
+		configReloader: newConfigReloader(cfg, authService, nil),
+	}
+	s.EnableConfigReload(context.Background(), path)
+
+	user, err := authService.ValidateAPIKey(context.Background(), "key-1")
+	if err != nil || user.ID() != "alice" {
+		t.Fatalf("ValidateAPIKey(key-1) = %v, %v before the reload", user, err)
+	}
+
+	writeReloadConfig(t, keysFile, `{"api_keys": [{"key": "key-2", "user_id": "bob", "role": "admin", "permissions": ["admin"]}]}`)
+	if _, err := s.ReloadConfig(context.Background(), "admin"); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	if _, err := authService.ValidateAPIKey(context.Background(), "key-1"); err == nil {
+		t.Error("removed key still accepted after the reload")
+	}
+	user, err = authService.ValidateAPIKey(context.Background(), "key-2")
+	if err != nil || user.ID() != "bob" || !user.IsAdmin() {
+		t.Errorf("ValidateAPIKey(key-2) = %v, %v after the reload", user, err)
+	}
+}
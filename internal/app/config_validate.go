@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// ValidateConfig returns the problems of cfg that keep the server from
+// starting, without connecting to Kubernetes or any storage backend. It runs
+// the same checks as NewServer.
+func ValidateConfig(cfg *config.Config) []error {
+	var errs []error
+	if err := services.ValidateKubernetesSessionConfig(&cfg.KubernetesSession); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateRateLimit(cfg.RateLimit); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateLeaderElection(cfg.LeaderElection); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateEventBus(cfg.EventBus); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateShutdown(cfg.Shutdown); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateUsage(cfg.Usage); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.GitHubEnterprise.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.RBAC.Enabled {
+		if _, err := buildAuthorizer(cfg.RBAC); err != nil {
+			errs = append(errs, fmt.Errorf("rbac: %w", err))
+		}
+	}
+
+	switch dialect := repositories.SQLDialect(cfg.SessionStore.Backend); dialect {
+	case repositories.SQLDialectPostgres, repositories.SQLDialectSQLite:
+		if cfg.SessionStore.DSN == "" {
+			errs = append(errs, fmt.Errorf("session_store.backend is %q but session_store.dsn is empty (set AGENTAPI_SESSION_STORE_DSN)", dialect))
+		}
+		if cfg.SessionStore.Migration.TargetBackend != "" {
+			if err := validateSessionStoreMigration(&cfg.SessionStore); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case "", "kubernetes":
+		if cfg.SessionStore.Migration.TargetBackend != "" {
+			errs = append(errs, fmt.Errorf("session store backend \"kubernetes\" cannot be migrated (supported: postgres, sqlite)"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("session_store.backend %q is unknown (supported: kubernetes, postgres, sqlite)", cfg.SessionStore.Backend))
+	}
+
+	switch cfg.Memory.Backend {
+	case "s3":
+		if cfg.Memory.S3 == nil {
+			errs = append(errs, fmt.Errorf("memory.backend is \"s3\" but memory.s3 is not set"))
+		}
+	case "external":
+		if cfg.Memory.External == nil || cfg.Memory.External.URL == "" {
+			errs = append(errs, fmt.Errorf("memory.backend is \"external\" but memory.external.url is empty (set AGENTAPI_MEMORY_EXTERNAL_URL)"))
+		}
+	}
+	if cfg.Memory.Migration.TargetBackend != "" {
+		if err := validateMemoryMigration(&cfg.Memory); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if cfg.AirGap.Strict {
+		for _, finding := range cfg.ValidateAirGap() {
+			errs = append(errs, fmt.Errorf("air_gap.strict: %s", finding))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateConfig(t *testing.T) {
+	if errs := ValidateConfig(config.DefaultConfig()); len(errs) != 0 {
+		t.Fatalf("ValidateConfig(default) = %v", errs)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.KubernetesSession.Routing = "carrier-pigeon"
+	cfg.SessionStore.Backend = "postgres"
+	cfg.Memory.Backend = "external"
+	cfg.LeaderElection.RenewDeadline = "20s"
+	cfg.EventBus = config.EventBusConfig{Enabled: true, Type: "kafka", URL: "localhost:8082"}
+	cfg.Shutdown.GracePeriod = "soon"
+	cfg.Usage.Budgets = []config.UsageBudgetConfig{{Team: "acme/dev"}}
+	errs := ValidateConfig(cfg)
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{"routing", "session_store.dsn", "memory.external.url", "leader_election.lease_duration", "event_bus.url", "shutdown.grace_period", "usage.budgets[0]"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("ValidateConfig() = %q, want a problem mentioning %q", joined, want)
+		}
+	}
+}
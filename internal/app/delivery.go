@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+)
+
+// buildDeliveryQueue creates the outbound delivery retry queue from config.
+// The redis backend falls back to the file backend when Redis is not
+// configured or unreachable at startup. Outbound webhooks always need the
+// queue for their retries. Returns nil when the queue is disabled or its
+// store cannot be created.
+func buildDeliveryQueue(cfg *config.Config) *delivery.Queue {
+	dc := cfg.Delivery
+	if !dc.Enabled && !cfg.OutboundWebhooks.Enabled {
+		return nil
+	}
+
+	var store delivery.Store
+	backend := "file"
+	if dc.Backend == "redis" {
+		if client := connectDeliveryRedis(cfg); client != nil {
+			store = delivery.NewRedisStore(client)
+			backend = "redis"
+		}
+	}
+	if store == nil {
+		dir := dc.Dir
+		if dir == "" {
+			dir = filepath.Join(notification.GetBaseDir(), "deliveries")
+		}
+		fileStore, err := delivery.NewFileStore(dir)
+		if err != nil {
+			log.Printf("[DELIVERY] Failed to initialize file store, delivery retries disabled: %v", err)
+			return nil
+		}
+		store = fileStore
+	}
+
+	opts := delivery.Options{MaxAttempts: dc.MaxAttempts}
+	if d, err := time.ParseDuration(dc.BaseBackoff); err == nil && d > 0 {
+		opts.BaseBackoff = d
+	}
+	if d, err := time.ParseDuration(dc.MaxBackoff); err == nil && d > 0 {
+		opts.MaxBackoff = d
+	}
+
+	log.Printf("[DELIVERY] Enabled with %s backend (max_attempts=%d)", backend, dc.MaxAttempts)
+	return delivery.NewQueue(store, opts)
+}
+
+// deliveryPollInterval returns how often due deliveries are attempted
+func deliveryPollInterval(cfg *config.Config) time.Duration {
+	if d, err := time.ParseDuration(cfg.Delivery.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return delivery.DefaultPollInterval
+}
+
+// connectDeliveryRedis returns a connected Redis client, or nil when Redis is unavailable.
+func connectDeliveryRedis(cfg *config.Config) *redis.Client {
+	if cfg.Redis.Addr == "" {
+		log.Printf("[DELIVERY] Warning: redis backend requested but redis.addr is empty – using file store")
+		return nil
+	}
+
+	client := redis.NewClient(redisOptions(cfg))
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pingCancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		log.Printf("[DELIVERY] Warning: Redis ping failed (%s) – using file store: %v", cfg.Redis.Addr, err)
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/slack-go/slack"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/rbaccheck"
+)
+
+// pinger is implemented by storage backends that can check their connection
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// replicaPinger is implemented by SQL stores that can serve reads from a replica
+type replicaPinger interface {
+	PingReplica(ctx context.Context) error
+}
+
+// buildDiagnostics returns the live checks behind GET /admin/diagnostics.
+// sessionStore and memoryStore are checked when they implement Ping.
+func buildDiagnostics(cfg *config.Config, manager *services.KubernetesSessionManager, sessionStore, memoryStore interface{}, singletons *leader.Runner) []diagnostics.Check {
+	return []diagnostics.Check{
+		kubernetesCheck(manager.GetClient(), manager.GetNamespace()),
+		kubernetesRBACCheck(cfg, manager.GetClient(), manager.GetNamespace()),
+		labelSchemaCheck(manager),
+		kubernetesSecretsCheck(cfg, manager.GetClient(), manager.GetNamespace()),
+		storageClassCheck(cfg, manager.GetClient()),
+		storeCheck("session_store", cfg.SessionStore.Backend, sessionStore,
+			"Check AGENTAPI_SESSION_STORE_DSN and that the database accepts connections from the proxy Pod"),
+		sessionStoreReplicaCheck(cfg, sessionStore),
+		storeCheck("memory_store", cfg.Memory.Backend, memoryStore,
+			"Check the memory.s3 bucket and credentials, or memory.external url and admin_token"),
+		redisCheck(cfg),
+		leaderElectionCheck(cfg, singletons),
+		diagnostics.Func("github_app",
+			"Check GITHUB_APP_ID, GITHUB_APP_PEM and GITHUB_INSTALLATION_ID in the GitHub Secret; an 'Issued at' error means the clock is skewed",
+			func(ctx context.Context) (string, error) {
+				message, err := manager.VerifyGitHubApp(ctx)
+				if errors.Is(err, services.ErrGitHubTokenUnavailable) {
+					return "no GitHub App credentials in the GitHub Secret", diagnostics.ErrNotConfigured
+				}
+				return message, err
+			}),
+		slackCheck(cfg, manager),
+		diagnostics.ClockSkew(githubAPIBase(cfg), nil, diagnostics.DefaultMaxClockSkew),
+	}
+}
+
+// githubAPIBase returns the API URL of the default github_enterprise
+// instance, falling back to GITHUB_API of the proxy environment
+func githubAPIBase(cfg *config.Config) string {
+	if instance, _, ok := cfg.GitHubEnterprise.Instance(""); ok {
+		return instance.APIURL
+	}
+	return github_pkg.GetAPIBase()
+}
+
+func kubernetesCheck(client kubernetes.Interface, namespace string) diagnostics.Check {
+	return diagnostics.Func("kubernetes",
+		"Check the in-cluster service account token or KUBECONFIG, and that the API server is reachable from the proxy Pod",
+		func(ctx context.Context) (string, error) {
+			version, err := client.Discovery().ServerVersion()
+			if err != nil {
+				return "", fmt.Errorf("failed to reach the API server: %w", err)
+			}
+			if _, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+				if k8serrors.IsNotFound(err) {
+					return "", fmt.Errorf("namespace %s does not exist", namespace)
+				}
+				return "", fmt.Errorf("failed to list services in namespace %s: %w", namespace, err)
+			}
+			return fmt.Sprintf("connected to Kubernetes %s, namespace %s", version.GitVersion, namespace), nil
+		})
+}
+
+// kubernetesRBACCheck asks the API server whether the proxy may use each verb
+// it needs with SelfSubjectAccessReviews
+func kubernetesRBACCheck(cfg *config.Config, client kubernetes.Interface, namespace string) diagnostics.Check {
+	return diagnostics.Check{Name: "kubernetes_rbac", Run: func(ctx context.Context) diagnostics.Result {
+		result, err := rbaccheck.Check(ctx, client, rbaccheck.Required(cfg, namespace))
+		if err != nil {
+			return diagnostics.Fail("Check that the API server is reachable; SelfSubjectAccessReviews are allowed for every authenticated user by default",
+				"failed to review access: %v", err)
+		}
+		if len(result.Missing) > 0 {
+			missing := make([]string, 0, len(result.Missing))
+			for _, m := range result.Missing {
+				missing = append(missing, m.String())
+			}
+			return diagnostics.Fail(
+				fmt.Sprintf("Grant these verbs to the proxy service account in namespace %s; 'agentapi-proxy admin rbac-check --manifest' prints a matching Role", namespace),
+				"missing permissions: %s", strings.Join(missing, ", "))
+		}
+		return diagnostics.Pass("all %d required permissions granted in namespace %s", result.Checked, namespace)
+	}}
+}
+
+// secretRef is a Secret of the session namespace a setting refers to, and
+// the key it must contain ("" for any)
+type secretRef struct {
+	setting string
+	name    string
+	key     string
+}
+
+// referencedSecrets returns the Secrets of the session namespace cfg refers to
+func referencedSecrets(cfg *config.Config) []secretRef {
+	k8s := cfg.KubernetesSession
+	botTokenKey := k8s.SlackBotTokenSecretKey
+	if botTokenKey == "" {
+		botTokenKey = "bot-token"
+	}
+	appTokenKey := cfg.Slack.AppTokenSecretKey
+	if appTokenKey == "" {
+		appTokenKey = "app-token"
+	}
+	var refs []secretRef
+	for _, ref := range []secretRef{
+		{setting: "kubernetes_session.github_secret_name", name: k8s.GitHubSecretName},
+		{setting: "kubernetes_session.github_config_secret_name", name: k8s.GitHubConfigSecretName},
+		{setting: "kubernetes_session.settings_base_secret", name: k8s.SettingsBaseSecret},
+		{setting: "kubernetes_session.slack_bot_token_secret_name", name: k8s.SlackBotTokenSecretName, key: botTokenKey},
+		{setting: "slack.app_token_secret_name", name: cfg.Slack.AppTokenSecretName, key: appTokenKey},
+	} {
+		if ref.name != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// kubernetesSecretsCheck verifies that the Secrets the config refers to
+// exist in the session namespace and contain their keys
+func kubernetesSecretsCheck(cfg *config.Config, client kubernetes.Interface, namespace string) diagnostics.Check {
+	return diagnostics.Func("kubernetes_secrets",
+		fmt.Sprintf("Create the Secrets in namespace %s or clear the settings referring to them", namespace),
+		func(ctx context.Context) (string, error) {
+			refs := referencedSecrets(cfg)
+			if len(refs) == 0 {
+				return "no Secrets referenced", diagnostics.ErrNotConfigured
+			}
+			var problems []string
+			for _, ref := range refs {
+				secret, err := client.CoreV1().Secrets(namespace).Get(ctx, ref.name, metav1.GetOptions{})
+				switch {
+				case k8serrors.IsNotFound(err):
+					problems = append(problems, fmt.Sprintf("secret %s (%s) does not exist", ref.name, ref.setting))
+				case err != nil:
+					problems = append(problems, fmt.Sprintf("failed to read secret %s (%s): %v", ref.name, ref.setting, err))
+				case ref.key != "" && len(secret.Data[ref.key]) == 0:
+					problems = append(problems, fmt.Sprintf("secret %s (%s) has no key %s", ref.name, ref.setting, ref.key))
+				}
+			}
+			if len(problems) > 0 {
+				return "", errors.New(strings.Join(problems, "; "))
+			}
+			return fmt.Sprintf("all %d referenced Secrets exist in namespace %s", len(refs), namespace), nil
+		})
+}
+
+// storageClassCheck verifies that the storage classes of session PVCs exist,
+// or that the cluster has a default one when none is set. Reading storage
+// classes needs a ClusterRole the proxy usually lacks, so the check is
+// skipped when it is forbidden.
+func storageClassCheck(cfg *config.Config, client kubernetes.Interface) diagnostics.Check {
+	return diagnostics.Func("kubernetes_storage_class",
+		"Set the storage class settings to one of 'kubectl get storageclass', or mark a default storage class",
+		func(ctx context.Context) (string, error) {
+			k8s := cfg.KubernetesSession
+			settings := map[string]string{}
+			if k8s.PVCEnabled == nil || *k8s.PVCEnabled {
+				settings["kubernetes_session.pvc_storage_class"] = k8s.PVCStorageClass
+			}
+			if k8s.RuntimeCacheEnabled {
+				settings["kubernetes_session.runtime_cache_storage_class"] = k8s.RuntimeCacheStorageClass
+			}
+			if len(settings) == 0 {
+				return "no persistent volumes", diagnostics.ErrNotConfigured
+			}
+
+			classes, err := client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+			if k8serrors.IsForbidden(err) {
+				return "storage classes cannot be read with the current credentials", diagnostics.ErrNotConfigured
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to list storage classes: %w", err)
+			}
+			exists := make(map[string]bool, len(classes.Items))
+			defaultClass := ""
+			for _, class := range classes.Items {
+				exists[class.Name] = true
+				if class.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
+					defaultClass = class.Name
+				}
+			}
+
+			var used, problems []string
+			for _, setting := range slices.Sorted(maps.Keys(settings)) {
+				switch class := settings[setting]; {
+				case class == "" && defaultClass == "":
+					problems = append(problems, setting+" is empty and the cluster has no default storage class")
+				case class == "":
+					used = append(used, fmt.Sprintf("%s (default, %s)", defaultClass, setting))
+				case !exists[class]:
+					problems = append(problems, fmt.Sprintf("storage class %s (%s) does not exist", class, setting))
+				default:
+					used = append(used, fmt.Sprintf("%s (%s)", class, setting))
+				}
+			}
+			if len(problems) > 0 {
+				return "", errors.New(strings.Join(problems, "; "))
+			}
+			return "storage classes exist: " + strings.Join(used, ", "), nil
+		})
+}
+
+// labelSchemaCheck looks for session objects whose labels predate the
+// current label schema, which label-filtered session lists miss
+func labelSchemaCheck(manager *services.KubernetesSessionManager) diagnostics.Check {
+	return diagnostics.Check{Name: "kubernetes_label_schema", Run: func(ctx context.Context) diagnostics.Result {
+		report := manager.MigrateLabelSchema(ctx, true)
+		if len(report.Errors) > 0 {
+			return diagnostics.Fail("Check that the proxy may list and patch Services and Deployments in the session namespaces",
+				"failed to check session labels: %s", strings.Join(report.Errors, "; "))
+		}
+		if n := len(report.Migrated); n > 0 {
+			return diagnostics.Warn("Run 'agentapi-proxy admin label-schema-migrate', or wait for the next run of kubernetes_session.label_schema_migration_interval",
+				"%d session object(s) use an outdated label schema", n)
+		}
+		return diagnostics.Pass("labels of all %d session(s) match the current schema", report.Checked)
+	}}
+}
+
+func storeCheck(name, backend string, store interface{}, remediation string) diagnostics.Check {
+	if backend == "" {
+		backend = "kubernetes"
+	}
+	return diagnostics.Func(name, remediation, func(ctx context.Context) (string, error) {
+		p, ok := store.(pinger)
+		if !ok {
+			// ConfigMap and Secret backed stores are covered by the Kubernetes checks
+			return fmt.Sprintf("backend %s is checked by the kubernetes checks", backend), diagnostics.ErrNotConfigured
+		}
+		if err := p.Ping(ctx); err != nil {
+			return "", fmt.Errorf("backend %s is not reachable: %w", backend, err)
+		}
+		return fmt.Sprintf("backend %s is reachable", backend), nil
+	})
+}
+
+// sessionStoreReplicaCheck checks the read replica serving bulk reads of
+// the SQL session store
+func sessionStoreReplicaCheck(cfg *config.Config, store interface{}) diagnostics.Check {
+	return diagnostics.Func("session_store_replica",
+		"Check AGENTAPI_SESSION_STORE_READ_REPLICA_DSN and that the replica has the agentapi_sessions table; until it is reachable reports read from the primary",
+		func(ctx context.Context) (string, error) {
+			repo, ok := store.(replicaPinger)
+			if !ok || cfg.SessionStore.ReadReplicaDSN == "" {
+				return "session_store.read_replica_dsn is not set", diagnostics.ErrNotConfigured
+			}
+			if err := repo.PingReplica(ctx); err != nil {
+				return "", fmt.Errorf("read replica is not reachable: %w", err)
+			}
+			return "read replica is reachable", nil
+		})
+}
+
+func redisCheck(cfg *config.Config) diagnostics.Check {
+	return diagnostics.Func("redis",
+		"Check redis.addr, redis.password and redis.tls_enabled; without Redis, session status is not shared between replicas",
+		func(ctx context.Context) (string, error) {
+			if cfg.Redis.Addr == "" {
+				return "redis.addr is not set", diagnostics.ErrNotConfigured
+			}
+			client := redis.NewClient(redisOptions(cfg))
+			defer func() {
+				_ = client.Close()
+			}()
+			if err := client.Ping(ctx).Err(); err != nil {
+				return "", fmt.Errorf("failed to ping %s: %w", cfg.Redis.Addr, err)
+			}
+			return fmt.Sprintf("connected to %s", cfg.Redis.Addr), nil
+		})
+}
+
+// slackCheck verifies the bot token used for notification DMs and the
+// default Slack bot token Secret with auth.test
+func slackCheck(cfg *config.Config, manager *services.KubernetesSessionManager) diagnostics.Check {
+	return diagnostics.Func("slack",
+		"Reinstall the Slack App or rotate the bot token (xoxb-...) in SLACK_BOT_TOKEN or the Slack bot token Secret",
+		func(ctx context.Context) (string, error) {
+			type botToken struct{ source, token string }
+			var tokens []botToken
+			if token := os.Getenv("SLACK_BOT_TOKEN"); token != "" {
+				tokens = append(tokens, botToken{"SLACK_BOT_TOKEN", token})
+			}
+			if name := cfg.KubernetesSession.SlackBotTokenSecretName; name != "" {
+				key := cfg.KubernetesSession.SlackBotTokenSecretKey
+				if key == "" {
+					key = "bot-token"
+				}
+				secret, err := manager.GetClient().CoreV1().Secrets(manager.GetNamespace()).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return "", fmt.Errorf("failed to read Slack bot token secret %s: %w", name, err)
+				}
+				token := string(secret.Data[key])
+				if token == "" {
+					return "", fmt.Errorf("secret %s has no key %s", name, key)
+				}
+				tokens = append(tokens, botToken{"secret " + name, token})
+			}
+			if len(tokens) == 0 {
+				return "no Slack bot token configured", diagnostics.ErrNotConfigured
+			}
+
+			var verified []string
+			for _, t := range tokens {
+				resp, err := slack.New(t.token).AuthTestContext(ctx)
+				if err != nil {
+					return "", fmt.Errorf("%s was rejected by Slack: %w", t.source, err)
+				}
+				verified = append(verified, fmt.Sprintf("%s (%s in %s)", t.source, resp.User, resp.Team))
+			}
+			return "authenticated " + strings.Join(verified, ", "), nil
+		})
+}
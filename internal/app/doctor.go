@@ -0,0 +1,21 @@
+package app
+
+import (
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+)
+
+// DoctorChecks returns the checks "agentapi-proxy doctor" runs before the
+// server is started: the checks of GET /admin/diagnostics that need no
+// running server, against client with sessions in namespace.
+func DoctorChecks(cfg *config.Config, client kubernetes.Interface, namespace string) []diagnostics.Check {
+	return []diagnostics.Check{
+		kubernetesCheck(client, namespace),
+		kubernetesRBACCheck(cfg, client, namespace),
+		kubernetesSecretsCheck(cfg, client, namespace),
+		storageClassCheck(cfg, client),
+		redisCheck(cfg),
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+)
+
+func doctorTestClient(denied func(*authorizationv1.ResourceAttributes) bool, objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = !denied(review.Spec.ResourceAttributes)
+		return true, review, nil
+	})
+	return client
+}
+
+func doctorResult(t *testing.T, report diagnostics.Report, name string) diagnostics.Result {
+	t.Helper()
+	for _, r := range report.Checks {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no check %q in %+v", name, report.Checks)
+	return diagnostics.Result{}
+}
+
+func TestDoctorChecksHealthyCluster(t *testing.T) {
+	client := doctorTestClient(func(*authorizationv1.ResourceAttributes) bool { return false },
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "agentapi"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "agentapi"},
+			Data:       map[string][]byte{"bot-token": []byte("xoxb")},
+		},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{
+			Name:        "standard",
+			Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"},
+		}},
+	)
+	cfg := &config.Config{}
+	cfg.KubernetesSession.GitHubSecretName = "github"
+	cfg.KubernetesSession.SlackBotTokenSecretName = "slack"
+
+	report := diagnostics.Run(context.Background(), DoctorChecks(cfg, client, "agentapi"), 0)
+	if report.Status != diagnostics.StatusPass {
+		t.Fatalf("status = %s, checks %+v", report.Status, report.Checks)
+	}
+	if r := doctorResult(t, report, "kubernetes_storage_class"); !strings.Contains(r.Message, "standard (default") {
+		t.Errorf("kubernetes_storage_class = %+v", r)
+	}
+	if r := doctorResult(t, report, "redis"); r.Status != diagnostics.StatusSkip {
+		t.Errorf("redis = %+v, want skipped", r)
+	}
+}
+
+func TestDoctorChecksReportProblems(t *testing.T) {
+	client := doctorTestClient(func(attrs *authorizationv1.ResourceAttributes) bool {
+		return attrs.Resource == "deployments" && attrs.Verb == "create"
+	}, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "agentapi"}})
+	cfg := &config.Config{}
+	cfg.KubernetesSession.GitHubSecretName = "github"
+	cfg.KubernetesSession.SlackBotTokenSecretName = "slack"
+	cfg.KubernetesSession.PVCStorageClass = "fast"
+
+	report := diagnostics.Run(context.Background(), DoctorChecks(cfg, client, "agentapi"), 0)
+	for name, wants := range map[string][]string{
+		"kubernetes_rbac":          {"create deployments"},
+		"kubernetes_secrets":       {"secret github (kubernetes_session.github_secret_name) does not exist", "secret slack (kubernetes_session.slack_bot_token_secret_name) has no key bot-token"},
+		"kubernetes_storage_class": {"storage class fast (kubernetes_session.pvc_storage_class) does not exist"},
+	} {
+		r := doctorResult(t, report, name)
+		if r.Status != diagnostics.StatusFail || r.Remediation == "" {
+			t.Errorf("%s = %+v, want a failure with remediation", name, r)
+		}
+		for _, want := range wants {
+			if !strings.Contains(r.Message, want) {
+				t.Errorf("%s message = %q, want %q", name, r.Message, want)
+			}
+		}
+	}
+}
+
+func TestStorageClassCheckWithoutDefault(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	result := storageClassCheck(&config.Config{}, client).Run(context.Background())
+	if result.Status != diagnostics.StatusFail || !strings.Contains(result.Message, "no default storage class") {
+		t.Errorf("storageClassCheck = %+v, want a failure", result)
+	}
+
+	disabled := false
+	cfg := &config.Config{}
+	cfg.KubernetesSession.PVCEnabled = &disabled
+	if result := storageClassCheck(cfg, client).Run(context.Background()); result.Status != diagnostics.StatusSkip {
+		t.Errorf("storageClassCheck without PVCs = %+v, want skipped", result)
+	}
+}
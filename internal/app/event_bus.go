@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/eventbus"
+)
+
+// validateEventBus checks the publisher settings of event_bus
+func validateEventBus(ec config.EventBusConfig) error {
+	if !ec.Enabled {
+		return nil
+	}
+	_, err := eventbus.NewPublisher(ec)
+	return err
+}
+
+// buildEventBus creates the event bus publisher from config, feeds it the
+// agent status changes and capability decisions of manager and starts
+// publishing. Session timeline events reach it once its Recorder wraps the
+// session event recorder, and policy violations once it is an audit sink.
+// Every replica publishes the events it observes. Returns nil when the event
+// bus is disabled.
+func buildEventBus(cfg *config.Config, manager *services.KubernetesSessionManager) *eventbus.Bus {
+	ec := cfg.EventBus
+	if !ec.Enabled {
+		return nil
+	}
+	publisher, err := eventbus.NewPublisher(ec)
+	if err != nil {
+		log.Fatalf("[SERVER] Invalid event bus configuration: %v", err)
+	}
+	bus := eventbus.New(publisher, manager.GetSession, eventbus.Options{
+		Source:     ec.Source,
+		Events:     ec.Events,
+		BufferSize: ec.BufferSize,
+	})
+
+	statusEvents, _ := manager.SubscribeStatusEvents()
+	go func() {
+		for evt := range statusEvents {
+			bus.ObserveStatus(evt.SessionID, evt.Status)
+		}
+	}()
+	manager.SetCapabilityObserver(bus.CapabilityDecision)
+
+	go bus.Run(context.Background())
+	log.Printf("[EVENT_BUS] Publishing session events to %s (topic: %s)", ec.Type, ec.Topic)
+	return bus
+}
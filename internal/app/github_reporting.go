@@ -0,0 +1,24 @@
+package app
+
+import (
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/githubreport"
+)
+
+// buildGitHubReporter creates the reporter of sessions created from GitHub
+// webhooks, or returns nil when github_reporting is disabled
+func buildGitHubReporter(cfg *config.Config, manager *services.KubernetesSessionManager) *githubreport.Reporter {
+	rc := cfg.GitHubReporting
+	if !rc.Enabled {
+		return nil
+	}
+	log.Printf("[GITHUB_REPORT] Reporting GitHub webhook sessions as branches %s<session-id> with check %q", rc.BranchPrefix, rc.CheckName)
+	return githubreport.NewReporter(githubreport.Config{
+		BranchPrefix: rc.BranchPrefix,
+		CheckName:    rc.CheckName,
+		DetailsURL:   rc.DetailsURL,
+	}, manager)
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
+)
+
+// userLocale returns the locale selected in a user's settings, or "" if the
+// user has not selected a supported one.
+func (s *Server) userLocale(ctx context.Context, userID string) i18n.Locale {
+	if s.settingsRepo == nil || userID == "" {
+		return ""
+	}
+	settings, err := s.settingsRepo.FindByName(ctx, userID)
+	if err != nil || settings == nil {
+		return ""
+	}
+	locale, _ := i18n.Parse(settings.Locale())
+	return locale
+}
+
+// notificationLocale resolves the locale of a notification recipient.
+func (s *Server) notificationLocale(userID string) i18n.Locale {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.userLocale(ctx, userID)
+}
+
+// requestLocale returns the locale for responses to a request: the
+// authenticated user's settings first, then the Accept-Language header.
+func (s *Server) requestLocale(c echo.Context) (i18n.Locale, bool) {
+	if user := auth.GetUserFromContext(c); user != nil {
+		if locale := s.userLocale(c.Request().Context(), string(user.ID())); locale != "" {
+			return locale, true
+		}
+	}
+	return i18n.FromAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+}
+
+// localizedHTTPErrorHandler translates the message of HTTP errors into the
+// request locale before rendering them with Echo's default handler.
+func (s *Server) localizedHTTPErrorHandler(err error, c echo.Context) {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		if msg, ok := he.Message.(string); ok {
+			if locale, ok := s.requestLocale(c); ok {
+				// Copy the error: sentinel errors such as echo.ErrNotFound are shared.
+				localized := *he
+				localized.Message = i18n.T(locale, msg)
+				err = &localized
+				c.Response().Header().Set("Content-Language", string(locale))
+			}
+		}
+	}
+	s.echo.DefaultHTTPErrorHandler(err, c)
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestLocalizedHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	s := &Server{echo: e}
+	e.HTTPErrorHandler = s.localizedHTTPErrorHandler
+	e.GET("/sessions/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptLanguage string
+		wantBody       string
+		wantLanguage   string
+	}{
+		{"japanese", "/sessions/1", "ja-JP,ja;q=0.9,en;q=0.8", "セッションが見つかりません", "ja"},
+		{"english", "/sessions/1", "en-US", "Session not found", "en"},
+		{"unsupported language", "/sessions/1", "fr-FR", "Session not found", ""},
+		{"no header", "/sessions/1", "", "Session not found", ""},
+		{"echo sentinel error", "/unknown", "ja", "見つかりません", "ja"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want message %q", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+		})
+	}
+
+	if echo.ErrNotFound.Message != "Not Found" {
+		t.Errorf("echo.ErrNotFound was modified: %v", echo.ErrNotFound.Message)
+	}
+}
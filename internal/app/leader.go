@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+)
+
+// leaderElectionConfig returns the Lease settings of leader_election
+func leaderElectionConfig(le config.LeaderElectionConfig, namespace string) (leader.Config, error) {
+	lc := leader.Config{
+		Namespace:     namespace,
+		LeaseName:     le.LeaseName,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+	if strings.TrimSpace(le.Namespace) != "" {
+		lc.Namespace = strings.TrimSpace(le.Namespace)
+	}
+	if lc.LeaseName == "" {
+		lc.LeaseName = "agentapi-proxy-leader"
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"lease_duration", le.LeaseDuration, &lc.LeaseDuration},
+		{"renew_deadline", le.RenewDeadline, &lc.RenewDeadline},
+		{"retry_period", le.RetryPeriod, &lc.RetryPeriod},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return lc, fmt.Errorf("leader_election.%s %q is not a positive duration", d.name, d.value)
+		}
+		*d.into = parsed
+	}
+	// The limits of client-go's leader election, which panics otherwise. The
+	// Lease records its duration in whole seconds.
+	if lc.LeaseDuration < time.Second {
+		return lc, fmt.Errorf("leader_election.lease_duration (%s) must be at least 1s", lc.LeaseDuration)
+	}
+	if lc.LeaseDuration <= lc.RenewDeadline {
+		return lc, fmt.Errorf("leader_election.lease_duration (%s) must be greater than renew_deadline (%s)", lc.LeaseDuration, lc.RenewDeadline)
+	}
+	if float64(lc.RenewDeadline) <= 1.2*float64(lc.RetryPeriod) {
+		return lc, fmt.Errorf("leader_election.renew_deadline (%s) must be greater than 1.2 times retry_period (%s)", lc.RenewDeadline, lc.RetryPeriod)
+	}
+	return lc, nil
+}
+
+// validateLeaderElection checks the Lease settings of leader_election
+func validateLeaderElection(le config.LeaderElectionConfig) error {
+	if !le.Enabled {
+		return nil
+	}
+	_, err := leaderElectionConfig(le, "")
+	return err
+}
+
+// buildLeaderRunner returns the runner of the singleton background
+// subsystems. Every replica runs them when leader election is disabled.
+func buildLeaderRunner(cfg *config.Config, manager *services.KubernetesSessionManager) *leader.Runner {
+	if !cfg.LeaderElection.Enabled || manager.GetClient() == nil {
+		return leader.NewRunner(nil, leader.Config{})
+	}
+	lc, err := leaderElectionConfig(cfg.LeaderElection, manager.GetNamespace())
+	if err != nil {
+		log.Fatalf("[SERVER] Invalid leader election configuration: %v", err)
+	}
+	return leader.NewRunner(manager.GetClient(), lc)
+}
+
+// StartLeaderElection takes part in the election of the replica that runs
+// the singleton background subsystems until ctx is cancelled. The subsystems
+// do not run before it is called. It returns immediately.
+func (s *Server) StartLeaderElection(ctx context.Context) {
+	go s.singletons.Run(ctx)
+}
+
+// leaderElectionCheck reports which replica runs the singleton subsystems
+func leaderElectionCheck(cfg *config.Config, runner *leader.Runner) diagnostics.Check {
+	return diagnostics.Check{Name: "leader_election", Run: func(ctx context.Context) diagnostics.Result {
+		if !cfg.LeaderElection.Enabled {
+			return diagnostics.Skip("leader_election.enabled is false; every replica runs the singleton subsystems")
+		}
+		switch current := runner.Leader(); {
+		case runner.IsLeader():
+			return diagnostics.Pass("this replica (%s) is the leader and runs: %s", runner.Identity(), strings.Join(runner.Tasks(), ", "))
+		case current != "":
+			return diagnostics.Pass("replica %s is the leader; this replica (%s) is standing by", current, runner.Identity())
+		default:
+			return diagnostics.Warn("Check that the proxy service account may get, create and update Leases in the leader_election namespace",
+				"no leader has been elected yet; the singleton subsystems are not running")
+		}
+	}}
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/metering"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+)
+
+const defaultMeteringInterval = time.Hour
+
+// startMetering exports the usage metered by sb to the configured billing
+// system in the background. Nothing is exported when metering or showback is
+// disabled or the configuration is invalid.
+func startMetering(cfg *config.Config, sb *showbackService, singletons *leader.Runner) {
+	mc := cfg.Metering
+	if !mc.Enabled {
+		return
+	}
+	if sb == nil {
+		log.Printf("[METERING] showback.enabled is required, usage export disabled")
+		return
+	}
+
+	sink, metrics, err := buildMeteringSink(mc)
+	if err != nil {
+		log.Printf("[METERING] Invalid configuration, usage export disabled: %v", err)
+		return
+	}
+
+	dir := mc.Dir
+	if dir == "" {
+		dir = filepath.Join(notification.GetBaseDir(), "metering")
+	}
+	exporter, err := metering.NewExporter(sb.meter, sink, metrics, dir)
+	if err != nil {
+		log.Printf("[METERING] Failed to initialize exporter, usage export disabled: %v", err)
+		return
+	}
+
+	interval := defaultMeteringInterval
+	if d, err := time.ParseDuration(mc.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	singletons.Go("metering export", func(ctx context.Context) { exporter.Run(ctx, interval) })
+	log.Printf("[METERING] Exporting %d metric(s) to %s every %s", len(metrics), mc.Provider, interval)
+}
+
+// buildMeteringSink returns the sink of the configured provider and the
+// metrics exported to it
+func buildMeteringSink(mc config.MeteringConfig) (metering.Sink, []string, error) {
+	switch mc.Provider {
+	case "stripe":
+		apiKey := os.Getenv(mc.Stripe.APIKeyEnv)
+		if apiKey == "" {
+			return nil, nil, fmt.Errorf("%s is not set", mc.Stripe.APIKeyEnv)
+		}
+		customers := make(map[string]string, len(mc.Stripe.Customers))
+		for _, c := range mc.Stripe.Customers {
+			customers[c.TeamID] = c.CustomerID
+		}
+		sink := metering.NewStripeSink(apiKey, mc.Stripe.Events, customers)
+		if mc.Stripe.BaseURL != "" {
+			sink.WithBaseURL(mc.Stripe.BaseURL)
+		}
+		metrics := sink.Metrics()
+		for metric := range mc.Stripe.Events {
+			if err := metering.ValidateMetric(metric); err != nil {
+				return nil, nil, err
+			}
+		}
+		if len(metrics) == 0 {
+			return nil, nil, fmt.Errorf("metering.stripe.events maps no metric to a meter event")
+		}
+		return sink, metrics, nil
+	case "webhook":
+		if mc.Webhook.URL == "" {
+			return nil, nil, fmt.Errorf("metering.webhook.url is required")
+		}
+		return metering.NewWebhookSink(mc.Webhook.URL, mc.Webhook.Secret), metering.Metrics, nil
+	}
+	return nil, nil, fmt.Errorf("unknown metering provider %q (valid: stripe, webhook)", mc.Provider)
+}
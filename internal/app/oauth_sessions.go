@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+// redisOAuthSessionPrefix is the prefix of the Redis keys of OAuth sessions
+const redisOAuthSessionPrefix = "agentapi:oauth:session:"
+
+// oauthSessionStore keeps the sessions created by the OAuth callback. It has
+// the methods of sync.Map and keeps sessions in memory until useRedis is
+// called; with Redis every replica accepts the sessions created by the
+// others, without sticky routing. Redis keys expire with their session and
+// hold the session encrypted, as it contains the GitHub access token.
+type oauthSessionStore struct {
+	local      sync.Map
+	redis      *redis.Client
+	encryption *services.EncryptionServiceRegistry
+}
+
+// useRedis keeps the sessions in Redis from now on
+func (s *oauthSessionStore) useRedis(client *redis.Client, encryption *services.EncryptionServiceRegistry) {
+	s.redis = client
+	s.encryption = encryption
+}
+
+// redisOAuthSession is the stored form of an OAuth session
+type redisOAuthSession struct {
+	Value    string                            `json:"value"`
+	Metadata domainservices.EncryptionMetadata `json:"metadata"`
+}
+
+// Store saves value, an *OAuthSession, under key, its ID
+func (s *oauthSessionStore) Store(key, value any) {
+	if s.redis == nil {
+		s.local.Store(key, value)
+		return
+	}
+	id, session := key.(string), value.(*OAuthSession)
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	plaintext, err := json.Marshal(session)
+	if err != nil {
+		log.Printf("[OAUTH] Failed to encode session %s: %v", id, err)
+		return
+	}
+	encrypted, err := s.encryption.GetForEncryption().Encrypt(ctx, string(plaintext))
+	if err != nil {
+		log.Printf("[OAUTH] Failed to encrypt session %s: %v", id, err)
+		return
+	}
+	payload, err := json.Marshal(redisOAuthSession{Value: encrypted.EncryptedValue, Metadata: encrypted.Metadata})
+	if err != nil {
+		log.Printf("[OAUTH] Failed to encode session %s: %v", id, err)
+		return
+	}
+	if err := s.redis.Set(ctx, redisOAuthSessionPrefix+id, payload, ttl).Err(); err != nil {
+		log.Printf("[OAUTH] Failed to store session %s in Redis: %v", id, err)
+	}
+}
+
+// Load returns the *OAuthSession stored under key
+func (s *oauthSessionStore) Load(key any) (any, bool) {
+	if s.redis == nil {
+		return s.local.Load(key)
+	}
+	id := key.(string)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	payload, err := s.redis.Get(ctx, redisOAuthSessionPrefix+id).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("[OAUTH] Failed to load session %s from Redis: %v", id, err)
+		}
+		return nil, false
+	}
+	var stored redisOAuthSession
+	if err := json.Unmarshal(payload, &stored); err != nil {
+		log.Printf("[OAUTH] Failed to decode session %s: %v", id, err)
+		return nil, false
+	}
+	encrypted := &domainservices.EncryptedData{EncryptedValue: stored.Value, Metadata: stored.Metadata}
+	plaintext, err := s.encryption.GetForDecryption(stored.Metadata).Decrypt(ctx, encrypted)
+	if err != nil {
+		log.Printf("[OAUTH] Failed to decrypt session %s: %v", id, err)
+		return nil, false
+	}
+	var session OAuthSession
+	if err := json.Unmarshal([]byte(plaintext), &session); err != nil {
+		log.Printf("[OAUTH] Failed to decode session %s: %v", id, err)
+		return nil, false
+	}
+	return &session, true
+}
+
+// Delete removes the session stored under key
+func (s *oauthSessionStore) Delete(key any) {
+	if s.redis == nil {
+		s.local.Delete(key)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.redis.Del(ctx, redisOAuthSessionPrefix+key.(string)).Err(); err != nil {
+		log.Printf("[OAUTH] Failed to delete session %v from Redis: %v", key, err)
+	}
+}
+
+// Range calls f for the sessions kept in memory. Sessions in Redis are not
+// listed; their keys expire on their own.
+func (s *oauthSessionStore) Range(f func(key, value any) bool) {
+	s.local.Range(f)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// observerOwnRoutes are session routes that change only the caller's own
+// organization of the session, which observers may use
+var observerOwnRoutes = map[string]bool{
+	"/sessions/:sessionId/favorite": true,
+	"/sessions/:sessionId/folder":   true,
+}
+
+// observerInteractiveRoutes are session routes that control the session
+// although they are opened with GET (WebSocket upgrades and editors)
+var observerInteractiveRoutes = []string{
+	"/sessions/:sessionId/terminal",
+	"/sessions/:sessionId/editor",
+	"/sessions/:sessionId/browser",
+	"/sessions/:sessionId/exec",
+}
+
+// observerModeReader reads the observer mode of a session from its store
+type observerModeReader interface {
+	SessionObserverMode(ctx context.Context, sessionID string) bool
+}
+
+// observerBlocks reports whether observer mode blocks the route path when
+// requested with method: every session route that changes the session or
+// controls it, while reading and streaming its messages and events stays
+// allowed.
+func observerBlocks(method, path string) bool {
+	if path != "/:sessionId/*" && path != "/sessions/:sessionId" && !strings.HasPrefix(path, "/sessions/:sessionId/") {
+		return false
+	}
+	if observerOwnRoutes[path] {
+		return false
+	}
+	for _, route := range observerInteractiveRoutes {
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return true
+		}
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// observerModeMiddleware makes sessions in observer mode read-only for every
+// user but their owner (and admins). It must run after AuthMiddleware and
+// SessionSlugMiddleware.
+func (s *Server) observerModeMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.sessionManager == nil || !observerBlocks(c.Request().Method, c.Path()) {
+				return next(c)
+			}
+			user := auth.GetUserFromContext(c)
+			if user == nil {
+				// Handlers reject unauthenticated requests themselves
+				return next(c)
+			}
+			reader, ok := s.sessionManager.(observerModeReader)
+			if !ok {
+				return next(c)
+			}
+			session := s.sessionManager.GetSession(c.Param("sessionId"))
+			if session == nil || user.CanAccessSession(entities.UserID(session.UserID())) {
+				return next(c)
+			}
+			if !reader.SessionObserverMode(c.Request().Context(), session.ID()) {
+				return next(c)
+			}
+			log.Printf("[OBSERVER] Blocked %s %s on session %s for user %s", c.Request().Method, c.Path(), session.ID(), user.ID())
+			return echo.NewHTTPError(http.StatusForbidden, "The session is in observer mode; only its owner can change it")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestObserverBlocks(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/sessions/:sessionId/messages", false},
+		{http.MethodGet, "/sessions/:sessionId/events", false},
+		{http.MethodGet, "/:sessionId/*", false},
+		{http.MethodPost, "/:sessionId/*", true},
+		{http.MethodPost, "/sessions/:sessionId/messages", true},
+		{http.MethodPut, "/sessions/:sessionId/files", true},
+		{http.MethodPatch, "/sessions/:sessionId", true},
+		{http.MethodDelete, "/sessions/:sessionId", true},
+		{http.MethodGet, "/sessions/:sessionId/terminal", true},
+		{http.MethodGet, "/sessions/:sessionId/editor/*", true},
+		{http.MethodGet, "/sessions/:sessionId/terminal-recordings", false},
+		{http.MethodPut, "/sessions/:sessionId/favorite", false},
+		{http.MethodPost, "/start", false},
+		{http.MethodPost, "/internal/session-provisioners/:sessionId/extensions", false},
+	}
+	for _, tt := range tests {
+		if got := observerBlocks(tt.method, tt.path); got != tt.want {
+			t.Errorf("observerBlocks(%s, %s) = %t, want %t", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
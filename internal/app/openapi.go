@@ -0,0 +1,41 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/spec"
+)
+
+// openAPIDocument is the document served at /openapi.json, merged once
+var openAPIDocument = sync.OnceValues(spec.Document)
+
+// serveOpenAPIDocument handles GET /openapi.json
+func serveOpenAPIDocument(c echo.Context) error {
+	doc, err := openAPIDocument()
+	if err != nil {
+		log.Printf("[ROUTES] Failed to build the OpenAPI document: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "OpenAPI document unavailable")
+	}
+	return c.JSONBlob(http.StatusOK, doc)
+}
+
+// swaggerUIHandler handles GET /docs. The page loads the Swagger UI assets
+// from air_gap.swagger_ui_url, or from unpkg.com by default.
+func swaggerUIHandler(cfg *config.Config) echo.HandlerFunc {
+	assetsURL := config.DefaultSwaggerUIURL
+	if cfg != nil && cfg.AirGap.SwaggerUIURL != "" {
+		assetsURL = cfg.AirGap.SwaggerUIURL
+	}
+	page, err := spec.SwaggerUI(assetsURL)
+	return func(c echo.Context) error {
+		if err != nil {
+			log.Printf("[ROUTES] Failed to render Swagger UI: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Swagger UI unavailable")
+		}
+		return c.HTMLBlob(http.StatusOK, page)
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestOpenAPIDocumentationIsPublic(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Auth.Static.Enabled = true
+	cfg.Auth.Static.APIKeys = []config.APIKey{{Key: "secret", UserID: "alice", Role: "user", Permissions: []string{"*"}}}
+	server := NewServer(cfg, false)
+
+	rec := httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json"))
+	var doc struct {
+		OpenAPI    string         `json:"openapi"`
+		Paths      map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/start")
+	assert.Contains(t, doc.Components.Schemas, "SessionStats")
+
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"SessionStats"`)
+
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `url: "openapi.json"`)
+	assert.Contains(t, rec.Body.String(), `src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"`)
+
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestSwaggerUIAssetsURL(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AirGap.SwaggerUIURL = "https://mirror.corp/swagger-ui-dist/"
+	server := NewServer(cfg, false)
+
+	rec := httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `href="https://mirror.corp/swagger-ui-dist/swagger-ui.css"`)
+	assert.Contains(t, rec.Body.String(), `src="https://mirror.corp/swagger-ui-dist/swagger-ui-bundle.js"`)
+	assert.NotContains(t, rec.Body.String(), "unpkg.com")
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/outboundwebhook"
+)
+
+// outboundWebhooks is the dispatcher of session lifecycle webhooks together
+// with the store behind their management API
+type outboundWebhooks struct {
+	*outboundwebhook.Dispatcher
+	store outboundwebhook.Store
+}
+
+// buildOutboundWebhooks creates the outbound webhook dispatcher from config
+// and feeds it the agent status changes of manager. Session timeline events
+// reach it once its Recorder wraps the session event recorder. Returns nil
+// when outbound webhooks are disabled or their store cannot be created.
+func buildOutboundWebhooks(cfg *config.Config, manager *services.KubernetesSessionManager, queue *delivery.Queue) *outboundWebhooks {
+	oc := cfg.OutboundWebhooks
+	if !oc.Enabled {
+		return nil
+	}
+
+	dir := oc.Dir
+	if dir == "" {
+		dir = filepath.Join(notification.GetBaseDir(), "outbound-webhooks")
+	}
+	store, err := outboundwebhook.NewFileStore(dir)
+	if err != nil {
+		log.Printf("[OUTBOUND_WEBHOOK] Failed to initialize store, outbound webhooks disabled: %v", err)
+		return nil
+	}
+
+	dispatcher := outboundwebhook.NewDispatcher(store, manager.GetSession)
+	if oc.AllowPrivateTargets {
+		dispatcher.AllowPrivateTargets()
+	}
+	if queue != nil {
+		dispatcher.WithDeliveryQueue(queue)
+	} else {
+		log.Printf("[OUTBOUND_WEBHOOK] Warning: delivery queue unavailable, failed deliveries will not be retried")
+	}
+
+	// Agent status changes are only observed to detect completed runs
+	statusEvents, _ := manager.SubscribeStatusEvents()
+	go func() {
+		for evt := range statusEvents {
+			dispatcher.ObserveStatus(evt.SessionID, evt.Status)
+		}
+	}()
+
+	log.Printf("[OUTBOUND_WEBHOOK] Enabled (store: %s)", dir)
+	return &outboundWebhooks{Dispatcher: dispatcher, store: store}
+}
+
+// newOutboundWebhookController returns the management API controller, or nil
+// when outbound webhooks are disabled
+func newOutboundWebhookController(w *outboundWebhooks) *controllers.OutboundWebhookController {
+	if w == nil {
+		return nil
+	}
+	return controllers.NewOutboundWebhookController(w.store, w.Dispatcher)
+}
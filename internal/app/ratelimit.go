@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/ratelimit"
+)
+
+// rateLimiting builds the rate limiting middleware on startup and on config
+// reloads. The limiter is created when rate limiting is first enabled and is
+// kept across reloads, so buckets survive them and the backend can only be
+// changed with a restart.
+type rateLimiting struct {
+	limiter ratelimit.Limiter
+	backend string
+}
+
+// middleware builds the rate limiting middleware from config, or a no-op
+// when rate limiting is disabled. The redis backend falls back to the
+// in-memory limiter when Redis is not configured or unreachable.
+func (r *rateLimiting) middleware(cfg *config.Config) echo.MiddlewareFunc {
+	rl := cfg.RateLimit
+	if !rl.Enabled {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	if r.limiter == nil {
+		r.limiter = ratelimit.NewMemoryLimiter()
+		r.backend = "memory"
+		if rl.Backend == "redis" {
+			if client := connectRateLimitRedis(cfg); client != nil {
+				r.limiter = ratelimit.NewRedisLimiter(client)
+				r.backend = "redis"
+			}
+		}
+	}
+
+	routes := make([]ratelimit.RouteGroup, 0, len(rl.Routes))
+	for _, route := range rl.Routes {
+		routes = append(routes, ratelimit.RouteGroup{
+			Name:       route.Name,
+			PathPrefix: route.PathPrefix,
+			Rule:       ratelimit.Rule{RPS: route.RPS, Burst: route.Burst},
+		})
+	}
+
+	log.Printf("[RATE_LIMIT] Enabled with %s backend (user=%.2f/s, api_key=%.2f/s, team=%.2f/s, %d route groups)",
+		r.backend, rl.User.RPS, rl.APIKey.RPS, rl.Team.RPS, len(routes))
+
+	return ratelimit.Middleware(r.limiter, ratelimit.MiddlewareConfig{
+		User:     ratelimit.Rule{RPS: rl.User.RPS, Burst: rl.User.Burst},
+		APIKey:   ratelimit.Rule{RPS: rl.APIKey.RPS, Burst: rl.APIKey.Burst},
+		Team:     ratelimit.Rule{RPS: rl.Team.RPS, Burst: rl.Team.Burst},
+		Routes:   routes,
+		Identify: rateLimitIdentity,
+		Skipper:  skipRateLimit,
+	})
+}
+
+// validateRateLimit rejects negative rates and route groups without a path
+// prefix.
+func validateRateLimit(rl config.RateLimitConfig) error {
+	for _, r := range []struct {
+		name string
+		rule config.RateLimitRule
+	}{{"user", rl.User}, {"api_key", rl.APIKey}, {"team", rl.Team}} {
+		if r.rule.RPS < 0 || r.rule.Burst < 0 {
+			return fmt.Errorf("rate_limit.%s: rps and burst must not be negative", r.name)
+		}
+	}
+	for i, route := range rl.Routes {
+		if route.PathPrefix == "" {
+			return fmt.Errorf("rate_limit.routes[%d]: path_prefix is required", i)
+		}
+		if route.RPS < 0 || route.Burst < 0 {
+			return fmt.Errorf("rate_limit.routes[%d]: rps and burst must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// connectRateLimitRedis returns a connected Redis client, or nil when Redis is unavailable.
+func connectRateLimitRedis(cfg *config.Config) *redis.Client {
+	if cfg.Redis.Addr == "" {
+		log.Printf("[RATE_LIMIT] Warning: redis backend requested but redis.addr is empty – using in-memory limiter")
+		return nil
+	}
+
+	client := redis.NewClient(redisOptions(cfg))
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pingCancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		log.Printf("[RATE_LIMIT] Warning: Redis ping failed (%s) – using in-memory limiter: %v", cfg.Redis.Addr, err)
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
+
+// rateLimitIdentity charges authenticated requests to the user and their
+// teams, and anonymous requests to the client IP.
+func rateLimitIdentity(c echo.Context) ratelimit.Identity {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return ratelimit.Identity{Principal: "ip:" + c.RealIP()}
+	}
+
+	id := ratelimit.Identity{
+		Principal: "user:" + string(user.ID()),
+		APIKey:    auth.GetAPIKeyFingerprint(c),
+	}
+	if authzCtx := auth.GetAuthorizationContext(c); authzCtx != nil {
+		id.Teams = authzCtx.TeamScope.Teams
+	}
+	return id
+}
+
+// skipRateLimit exempts health checks, metrics, static files, the API
+// documentation and session Pod callbacks.
+func skipRateLimit(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return path == "/health" || path == "/ready" || path == "/metrics" ||
+		strings.HasPrefix(path, "/public") || path == "/openapi.json" || path == "/docs" ||
+		strings.HasPrefix(path, "/internal/")
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/urlutil"
+)
+
+// maxRBACPeekBody bounds how much of a request body is read to find its scope
+const maxRBACPeekBody = 1 << 20
+
+// rbacResolver returns the resource a request acts on. ok is false when the
+// request does not need a role check, e.g. because the resource does not
+// exist and the handler will answer 404.
+type rbacResolver func(s *Server, c echo.Context) (resource entities.AuthzResource, ok bool)
+
+// rbacRule maps a route to the action it performs
+type rbacRule struct {
+	action  entities.Action
+	resolve rbacResolver
+}
+
+// rbacRoutes maps "METHOD path" to the action the route performs. Routes
+// registered with Any use "* path". Schedule routes are checked by the
+// schedule handlers, which load the schedule first.
+var rbacRoutes = map[string]rbacRule{
+	http.MethodPost + " /start":                            {entities.ActionSessionCreate, bodyScopeResource},
+	http.MethodDelete + " /sessions/:sessionId":            {entities.ActionSessionDelete, sessionResource},
+	http.MethodGet + " /search":                            {entities.ActionSessionList, queryScopeResource},
+	http.MethodGet + " /sessions/:sessionId/logs":          {entities.ActionSessionLogs, sessionResource},
+	http.MethodGet + " /sessions/:sessionId/exec":          {entities.ActionSessionExec, sessionResource},
+	http.MethodPost + " /sessions/:sessionId/exec":         {entities.ActionSessionExec, sessionResource},
+	"* /sessions/:sessionId/terminal":                      {entities.ActionSessionExec, sessionResource},
+	"* /sessions/:sessionId/terminal/*":                    {entities.ActionSessionExec, sessionResource},
+	http.MethodPut + " /settings/:name":                    {entities.ActionTeamConfigManage, teamSettingsResource},
+	http.MethodDelete + " /settings/:name":                 {entities.ActionTeamConfigManage, teamSettingsResource},
+	http.MethodDelete + " /settings/:name/sync":            {entities.ActionTeamConfigManage, teamSettingsResource},
+	http.MethodGet + " /teams/:id/config":                  {entities.ActionSessionList, teamConfigResource},
+	http.MethodPut + " /teams/:id/config":                  {entities.ActionTeamConfigManage, teamConfigResource},
+	http.MethodGet + " /sessions/:sessionId/events":        {entities.ActionSessionLogs, sessionResource},
+	http.MethodGet + " /sessions/:sessionId/events/stream": {entities.ActionSessionLogs, sessionResource},
+	http.MethodGet + " /events":                            {entities.ActionSessionList, queryScopeResource},
+}
+
+// buildAuthorizer creates the role policy authorizer from config
+func buildAuthorizer(cfg config.RBACConfig) (*services.PolicyAuthorizer, error) {
+	base, err := rbacPolicyFromConfig(cfg.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("rbac.roles: %w", err)
+	}
+	base = entities.DefaultRBACPolicy().Override(base)
+
+	teams := make(map[string]*entities.RBACPolicy, len(cfg.Teams))
+	for _, t := range cfg.Teams {
+		if t.TeamID == "" {
+			return nil, errors.New("rbac.teams: team_id is required")
+		}
+		policy, err := rbacPolicyFromConfig(t.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("rbac.teams[%s]: %w", t.TeamID, err)
+		}
+		teams[t.TeamID] = policy
+	}
+	return services.NewPolicyAuthorizer(base, teams), nil
+}
+
+func rbacPolicyFromConfig(roles map[string][]string) (*entities.RBACPolicy, error) {
+	assignments := make(map[entities.Role][]entities.Action, len(roles))
+	for role, actions := range roles {
+		if !slices.Contains(entities.RBACRoles, entities.Role(role)) {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		granted := make([]entities.Action, 0, len(actions))
+		for _, a := range actions {
+			granted = append(granted, entities.Action(a))
+		}
+		assignments[entities.Role(role)] = granted
+	}
+	policy := entities.NewRBACPolicy(assignments)
+	return policy, policy.Validate()
+}
+
+// rbacMiddleware checks the routes in rbacRoutes against the authorizer of
+// the server. It must run after AuthMiddleware; requests pass unchecked
+// while no authorizer is set.
+func (s *Server) rbacMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authorizer := s.authorizer
+			if authorizer == nil || c.Request().Method == http.MethodOptions {
+				return next(c)
+			}
+			rule, ok := rbacRoutes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				rule, ok = rbacRoutes["* "+c.Path()]
+			}
+			if !ok {
+				return next(c)
+			}
+			user := auth.GetUserFromContext(c)
+			if user == nil {
+				// Handlers reject unauthenticated requests themselves
+				return next(c)
+			}
+
+			resource, ok := rule.resolve(s, c)
+			if !ok {
+				return next(c)
+			}
+			if err := authorizer.Authorize(c.Request().Context(), user, rule.action, resource); err != nil {
+				log.Printf("[RBAC] Denied %s %s for user %s: %v", c.Request().Method, c.Path(), user.ID(), err)
+				return echo.NewHTTPError(http.StatusForbidden, "Your role does not allow this action")
+			}
+			return next(c)
+		}
+	}
+}
+
+// sessionResource resolves the session of the :sessionId parameter
+func sessionResource(s *Server, c echo.Context) (entities.AuthzResource, bool) {
+	if s.sessionManager == nil {
+		return entities.AuthzResource{}, false
+	}
+	session := s.sessionManager.GetSession(c.Param("sessionId"))
+	if session == nil {
+		return entities.AuthzResource{}, false
+	}
+	return entities.AuthzResource{
+		Scope:   session.Scope(),
+		TeamID:  session.TeamID(),
+		OwnerID: entities.UserID(session.UserID()),
+	}, true
+}
+
+// queryScopeResource resolves the scope and team_id query parameters
+func queryScopeResource(_ *Server, c echo.Context) (entities.AuthzResource, bool) {
+	return scopeResource(c, c.QueryParam("scope"), c.QueryParam("team_id")), true
+}
+
+// bodyScopeResource resolves the scope and team_id of a JSON request body
+// and restores the body for the handler
+func bodyScopeResource(_ *Server, c echo.Context) (entities.AuthzResource, bool) {
+	req := c.Request()
+	var peek struct {
+		Scope  string `json:"scope"`
+		TeamID string `json:"team_id"`
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxRBACPeekBody))
+		if err != nil {
+			return entities.AuthzResource{}, false
+		}
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		// Invalid bodies are rejected by the handler
+		_ = json.Unmarshal(body, &peek)
+	}
+	return scopeResource(c, peek.Scope, peek.TeamID), true
+}
+
+// teamSettingsResource resolves team settings; other settings are governed
+// by the settings controller alone
+func teamSettingsResource(_ *Server, c echo.Context) (entities.AuthzResource, bool) {
+	name := urlutil.DecodeSlashParam(c.Param("name"))
+	if !strings.Contains(name, "/") {
+		return entities.AuthzResource{}, false
+	}
+	return entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: name}, true
+}
+
+// teamConfigResource resolves the team of the :id parameter. Malformed team
+// IDs are rejected by the team config controller.
+func teamConfigResource(_ *Server, c echo.Context) (entities.AuthzResource, bool) {
+	teamID := urlutil.DecodeSlashParam(c.Param("id"))
+	if !strings.Contains(teamID, "/") {
+		return entities.AuthzResource{}, false
+	}
+	return entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: teamID}, true
+}
+
+// scopeResource builds the resource of a requested scope, routing service
+// accounts to their team like the handlers do
+func scopeResource(c echo.Context, scope, teamID string) entities.AuthzResource {
+	user := auth.GetUserFromContext(c)
+	scope, teamID = auth.ResolveUserScope(user, scope, teamID)
+	if scope == string(entities.ScopeTeam) && teamID != "" {
+		return entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: teamID}
+	}
+	return entities.AuthzResource{Scope: entities.ScopeUser, OwnerID: user.ID()}
+}
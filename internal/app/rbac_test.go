@@ -0,0 +1,61 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+func TestRBACMiddleware_TeamConfig(t *testing.T) {
+	s := &Server{authorizer: services.NewPolicyAuthorizer(entities.DefaultRBACPolicy(), nil)}
+	e := echo.New()
+	handler := s.rbacMiddleware()(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	user := entities.NewUser("alice", entities.UserTypeGitHub, "alice")
+	user.SetGitHubInfo(entities.NewGitHubUserInfo(1, "alice", "", "", "", "", ""), []entities.GitHubTeamMembership{
+		{Organization: "org", TeamSlug: "dev", Role: "member"},
+		{Organization: "org", TeamSlug: "ops", Role: "maintainer"},
+	})
+
+	tests := []struct {
+		method string
+		teamID string
+		want   int
+	}{
+		{http.MethodGet, "org/dev", http.StatusOK},
+		{http.MethodPut, "org/dev", http.StatusForbidden},
+		{http.MethodGet, "org/ops", http.StatusOK},
+		{http.MethodPut, "org/ops", http.StatusOK},
+		{http.MethodGet, "org/other", http.StatusForbidden},
+		{http.MethodPut, "org/other", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.teamID, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(tt.method, "/teams/x/config", nil), rec)
+			c.SetPath("/teams/:id/config")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.teamID)
+			c.Set("internal_user", user)
+
+			code := http.StatusOK
+			if err := handler(c); err != nil {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				code = httpErr.Code
+			}
+			if code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
 	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
 	apitokenuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/api_token"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/compliance"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/personal_api_key"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/resource_transfer"
+	serviceaccountuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/service_account"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/proxy"
 	"github.com/takutakahashi/agentapi-proxy/spec"
 )
 
@@ -36,6 +40,8 @@ type HandlerRegistry struct {
 	userController             *controllers.UserController
 	shareController            *controllers.ShareController
 	personalAPIKeyController   *controllers.PersonalAPIKeyController
+	teamConfigController       *controllers.TeamConfigController
+	teamSyncController         *controllers.TeamSyncController
 	apiTokenController         *controllers.APITokenController
 	memoryController           *controllers.MemoryController
 	sandboxPolicyController    *controllers.SandboxPolicyController
@@ -46,6 +52,28 @@ type HandlerRegistry struct {
 	assetController            *controllers.AssetController
 	sessionProfileController   *controllers.SessionProfileController
 	provisionerController      *controllers.ProvisionerController
+	llmProxyController         *controllers.LLMProxyController
+	workspaceController        *controllers.WorkspaceController
+	editorController           *controllers.EditorController
+	browserController          *controllers.BrowserController
+	terminalController         *controllers.TerminalController
+	previewController          *controllers.PreviewController
+	postSessionHookController  *controllers.PostSessionHookController
+	transcriptController       *controllers.TranscriptController
+	sessionArchiveController   *controllers.SessionArchiveController
+	activityController         *controllers.ActivityController
+	sessionJobController       *controllers.SessionJobController
+	complianceController       *controllers.ComplianceController
+	deliveryController         *controllers.DeliveryController
+	outboundWebhookController  *controllers.OutboundWebhookController
+	diagnosticsController      *controllers.DiagnosticsController
+	configReloadController     *controllers.ConfigReloadController
+	capacityController         *controllers.CapacityController
+	statsController            *controllers.StatsController
+	imageRolloutController     *controllers.ImageRolloutController
+	sessionSnapshotController  *controllers.SessionSnapshotController
+	billingController          *controllers.BillingController
+	usageController            *controllers.UsageController
 	customHandlers             []CustomHandler
 }
 
@@ -59,9 +87,17 @@ type CustomHandler interface {
 func NewRouter(e *echo.Echo, server *Server) *Router {
 	// Create settings controller
 	var gitSyncKMSKeyARN, gitSyncAWSRegion string
+	var autoResume, terminalRecording bool
+	var proxyOptions proxy.Options
+	var auditRetentionDays int
 	if cfg := server.GetConfig(); cfg != nil {
+		auditRetentionDays = cfg.Audit.RetentionDays
 		gitSyncKMSKeyARN = cfg.GitSync.Encryption.KMSKeyARN
 		gitSyncAWSRegion = cfg.GitSync.Encryption.AWSRegion
+		autoResume = cfg.KubernetesSession.AutoResume
+		terminalRecording = cfg.KubernetesSession.TerminalRecording
+		proxyOptions = cfg.Streaming.ProxyOptions()
+		cfg.BackendProxy.ApplyTo(&proxyOptions)
 	}
 	settingsController := controllers.NewSettingsController(server.settingsRepo, server.notificationSvc, gitSyncKMSKeyARN, gitSyncAWSRegion)
 
@@ -71,6 +107,16 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 			k8sManager.GetClient(),
 			k8sManager.GetNamespace(),
 		)
+		if server.secretsProvider != nil {
+			apiKeyRepo.SetSecretsProvider(server.secretsProvider)
+		}
+	}
+
+	var imageRolloutController *controllers.ImageRolloutController
+	var sessionSnapshotController *controllers.SessionSnapshotController
+	if k8sManager, ok := server.sessionManager.(*services.KubernetesSessionManager); ok {
+		imageRolloutController = controllers.NewImageRolloutController(k8sManager)
+		sessionSnapshotController = controllers.NewSessionSnapshotController(k8sManager)
 	}
 
 	var googleOAuthController *controllers.GoogleOAuthController
@@ -109,6 +155,8 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 		controllers.WithSessionRouteRepository(server.GetSessionRouteRepository()),
 		controllers.WithSettingsRepository(server.settingsRepo),
 		controllers.WithSessionProfileRepository(server.sessionProfileRepo),
+		controllers.WithAutoResume(autoResume),
+		controllers.WithProxyOptions(proxyOptions),
 	)
 
 	// Create share controller if share repository is available
@@ -152,6 +200,24 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 		log.Printf("[ROUTER] API token controller initialized")
 	}
 
+	// Create team config controller when team configs are stored (Kubernetes mode only)
+	var teamConfigController *controllers.TeamConfigController
+	if server.teamConfigRepo != nil && server.settingsRepo != nil {
+		var ensurer controllers.TeamServiceAccountEnsurer
+		if simpleAuth, ok := server.container.AuthService.(*services.SimpleAuthService); ok {
+			ensurer = serviceaccountuc.NewGetOrCreateServiceAccountUseCase(server.teamConfigRepo, simpleAuth)
+		}
+		teamConfigController = controllers.NewTeamConfigController(server.teamConfigRepo, settingsController, ensurer)
+		log.Printf("[ROUTER] Team config controller initialized")
+	}
+
+	// Create the team sync webhook receiver when the sync and its webhook secret are configured
+	var teamSyncController *controllers.TeamSyncController
+	if cfg := server.GetConfig(); server.teamSync != nil && cfg != nil && cfg.Auth.GitHub.TeamSync.WebhookSecret != "" {
+		teamSyncController = controllers.NewTeamSyncController(server.teamSync, cfg.Auth.GitHub.TeamSync.WebhookSecret)
+		log.Printf("[ROUTER] Team sync webhook controller initialized")
+	}
+
 	// Create memory controller if memory repository is available
 	var memoryController *controllers.MemoryController
 	if server.memoryRepo != nil {
@@ -211,6 +277,17 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 		log.Printf("[ROUTER] Asset controller initialized")
 	}
 
+	// Terminal sessions are recorded into the artifact store when the asset
+	// backend supports artifacts.
+	var artifacts, terminalRecordings services.ArtifactStore
+	if artifactStore, ok := server.assetStore.(services.ArtifactStore); ok {
+		artifacts = artifactStore
+		if terminalRecording {
+			terminalRecordings = artifactStore
+			log.Printf("[ROUTER] Terminal recording enabled")
+		}
+	}
+
 	// Create session profile controller if session profile repository is available
 	var sessionProfileController *controllers.SessionProfileController
 	if server.sessionProfileRepo != nil {
@@ -224,6 +301,12 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 		log.Printf("[ROUTER] Provisioner controller initialized")
 	}
 
+	var llmProxyController *controllers.LLMProxyController
+	if server.llmProxy != nil {
+		llmProxyController = controllers.NewLLMProxyController(server, server.llmProxy)
+		log.Printf("[ROUTER] LLM proxy controller initialized")
+	}
+
 	acpController := controllers.NewACPController(server, server, server.GetSessionRouteRepository())
 
 	return &Router{
@@ -231,7 +314,7 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 		server: server,
 		handlers: &HandlerRegistry{
 			notificationHandlers:       controllers.NewNotificationHandlers(server.notificationSvc, server.sessionManager),
-			healthController:           controllers.NewHealthController(),
+			healthController:           controllers.NewHealthController(server.Draining),
 			sessionController:          sessionController,
 			acpController:              acpController,
 			settingsController:         settingsController,
@@ -241,6 +324,8 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 			userController:             controllers.NewUserController(),
 			shareController:            shareController,
 			personalAPIKeyController:   personalAPIKeyController,
+			teamConfigController:       teamConfigController,
+			teamSyncController:         teamSyncController,
 			apiTokenController:         apiTokenController,
 			memoryController:           memoryController,
 			sandboxPolicyController:    sandboxPolicyController,
@@ -251,6 +336,28 @@ func NewRouter(e *echo.Echo, server *Server) *Router {
 			assetController:            assetController,
 			sessionProfileController:   sessionProfileController,
 			provisionerController:      provisionerController,
+			llmProxyController:         llmProxyController,
+			workspaceController:        controllers.NewWorkspaceController(server),
+			editorController:           controllers.NewEditorController(server),
+			browserController:          controllers.NewBrowserController(server),
+			terminalController:         controllers.NewTerminalController(server, terminalRecordings),
+			previewController:          controllers.NewPreviewController(server),
+			postSessionHookController:  controllers.NewPostSessionHookController(artifacts),
+			transcriptController:       controllers.NewTranscriptController(server, artifacts),
+			sessionArchiveController:   controllers.NewSessionArchiveController(server.sessionArchive),
+			activityController:         controllers.NewActivityController(server.activityFeed),
+			sessionJobController:       controllers.NewSessionJobController(artifacts),
+			complianceController:       controllers.NewComplianceController(compliance.NewReportUseCase(server.auditRepo, auditRetentionDays), compliance.NewEventsUseCase(server.auditRepo)),
+			deliveryController:         controllers.NewDeliveryController(server.deliveryQueue),
+			outboundWebhookController:  newOutboundWebhookController(server.outboundWebhooks),
+			diagnosticsController:      controllers.NewDiagnosticsController(server.diagnostics),
+			configReloadController:     controllers.NewConfigReloadController(server),
+			capacityController:         newCapacityController(server.capacityForecaster),
+			statsController:            controllers.NewStatsController(server.sessionStats),
+			imageRolloutController:     imageRolloutController,
+			sessionSnapshotController:  sessionSnapshotController,
+			billingController:          newBillingController(server.showback),
+			usageController:            newUsageController(server.usage),
 			customHandlers:             make([]CustomHandler, 0),
 		},
 	}
@@ -288,6 +395,10 @@ func (r *Router) RegisterRoutes() error {
 func (r *Router) registerCoreRoutes() error {
 	// Health check endpoint
 	r.echo.GET("/health", r.handlers.healthController.HealthCheck)
+	r.echo.GET("/ready", r.handlers.healthController.ReadinessCheck)
+
+	// Prometheus metrics endpoint (no authentication required)
+	r.echo.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 
 	// Static file serving for /public/* (no authentication required)
 	// Embedded from spec/openapi.json - independent of working directory
@@ -295,6 +406,13 @@ func (r *Router) registerCoreRoutes() error {
 	r.echo.StaticFS("/public", spec.FS())
 	log.Printf("[ROUTES] Static file serving registered at /public/*")
 
+	// OpenAPI document and Swagger UI (no authentication required). The
+	// document includes the generated schemas, so /public/openapi.json
+	// serves it as well.
+	r.echo.GET("/openapi.json", serveOpenAPIDocument)
+	r.echo.GET("/public/openapi.json", serveOpenAPIDocument)
+	r.echo.GET("/docs", swaggerUIHandler(r.server.GetConfig()))
+
 	// ACP (Agent Client Protocol) JSON-RPC 2.0 endpoints
 	log.Printf("[ROUTES] Registering ACP endpoints...")
 	r.echo.POST("/acp", r.handlers.acpController.HandleRPC, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
@@ -311,17 +429,147 @@ func (r *Router) registerCoreRoutes() error {
 	log.Printf("[ROUTES] Registering session management endpoints...")
 	r.echo.POST("/start", r.handlers.sessionController.StartSession)
 	r.echo.GET("/search", r.handlers.sessionController.SearchSessions)
+	r.echo.GET("/archived-sessions", r.handlers.sessionArchiveController.ListArchivedSessions,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Named session filters of the caller, usable as /search?saved=<name>
+	r.echo.GET("/saved-searches", r.handlers.sessionController.ListSavedSearches,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.PUT("/saved-searches/:name", r.handlers.sessionController.PutSavedSearch,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.DELETE("/saved-searches/:name", r.handlers.sessionController.DeleteSavedSearch,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Merged feed of the caller's sessions, deleted sessions, approvals, schedule runs and notifications
+	r.echo.GET("/me/activity", r.handlers.activityController.GetActivity,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 	r.echo.PATCH("/sessions/:sessionId/annotations", r.handlers.sessionController.UpdateSessionAnnotations)
+	// Tags and description of an existing session
+	r.echo.PATCH("/sessions/:sessionId", r.handlers.sessionController.UpdateSession)
+	// Pinned sessions and folders of the caller, stored in their settings (must be before /:sessionId/* catch-all)
+	r.echo.PUT("/sessions/:sessionId/favorite", r.handlers.sessionController.FavoriteSession,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.DELETE("/sessions/:sessionId/favorite", r.handlers.sessionController.UnfavoriteSession,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.PUT("/sessions/:sessionId/folder", r.handlers.sessionController.SetSessionFolder,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 	r.echo.DELETE("/sessions/:sessionId", r.handlers.sessionController.DeleteSession)
+	// Sessions that joined a group with the group field of /start
+	r.echo.GET("/session-groups/:group", r.handlers.sessionController.GetSessionGroup,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.POST("/session-groups/:group/messages", r.handlers.sessionController.SendSessionGroupMessage,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.DELETE("/session-groups/:group", r.handlers.sessionController.DeleteSessionGroup,
+		auth.RequirePermission(entities.PermissionSessionDelete, r.server.container.AuthService))
 
 	// Proxy-wide session status push endpoints (registered before /:sessionId/* catch-all)
 	r.echo.GET("/sessions/status/stream", r.handlers.sessionController.StreamSessionsStatus)
 	r.echo.GET("/sessions/status/wait", r.handlers.sessionController.WaitSessionsStatus)
+	// Created, status-changed and deleted events of the caller's sessions
+	r.echo.GET("/events", r.handlers.sessionController.StreamSessionLifecycle,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Long-poll until a session is stable or stopped (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/wait", r.handlers.sessionController.WaitSession,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 	// Per-session message update long-poll endpoint (must be before /:sessionId/* catch-all)
 	r.echo.GET("/sessions/:sessionId/messages/wait", r.handlers.sessionController.WaitSessionMessages)
+	// Conversation history and message sending for every session manager backend (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/messages", r.handlers.sessionController.GetSessionMessages,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.POST("/sessions/:sessionId/messages", r.handlers.sessionController.SendSessionMessage,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
 	// Sandbox domain viewer (must be before /:sessionId/* catch-all)
 	r.echo.GET("/sessions/:sessionId/sandbox-domains", r.handlers.sessionController.GetSessionSandboxDomains,
 		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Workspace file browser (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/workspace", r.handlers.workspaceController.ServeUI,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/workspace/files", r.handlers.workspaceController.ListFiles,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/workspace/raw", r.handlers.workspaceController.GetFileContent,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Workdir file download and upload (must be before /:sessionId/* catch-all).
+	// Files are listed with /workspace/files.
+	r.echo.GET("/sessions/:sessionId/files/download", r.handlers.workspaceController.DownloadFile,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.PUT("/sessions/:sessionId/files", r.handlers.workspaceController.UploadFile,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	// Uncommitted repository changes (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/changes", r.handlers.workspaceController.GetChanges,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Pause/resume by scaling the session workload (must be before /:sessionId/* catch-all)
+	r.echo.POST("/sessions/:sessionId/pause", r.handlers.sessionController.PauseSession,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.POST("/sessions/:sessionId/resume", r.handlers.sessionController.ResumeSession,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	// Clones from and restores of workdir snapshots (must be before /:sessionId/* catch-all)
+	if r.handlers.sessionSnapshotController != nil {
+		r.echo.POST("/sessions/:sessionId/clone", r.handlers.sessionSnapshotController.CloneSession,
+			auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		r.echo.GET("/snapshots", r.handlers.sessionSnapshotController.ListSnapshots,
+			auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.POST("/snapshots/:name/restore", r.handlers.sessionSnapshotController.RestoreSnapshot,
+			auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		log.Printf("[ROUTES] Session snapshot endpoints registered")
+	}
+	// In-browser editor sidecar (must be before /:sessionId/* catch-all)
+	r.echo.Any("/sessions/:sessionId/editor", r.handlers.editorController.ProxyEditor,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.Any("/sessions/:sessionId/editor/*", r.handlers.editorController.ProxyEditor,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	// Headless browser display (must be before /:sessionId/* catch-all)
+	r.echo.Any("/sessions/:sessionId/browser", r.handlers.browserController.ProxyBrowser,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.Any("/sessions/:sessionId/browser/*", r.handlers.browserController.ProxyBrowser,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	// Web terminal and its recordings (must be before /:sessionId/* catch-all)
+	r.echo.Any("/sessions/:sessionId/terminal", r.handlers.terminalController.ProxyTerminal,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.Any("/sessions/:sessionId/terminal/*", r.handlers.terminalController.ProxyTerminal,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/terminal-recordings", r.handlers.terminalController.ListRecordings,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/terminal-recordings/:name", r.handlers.terminalController.GetRecording,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Post-session hook output of oneshot sessions, also after deletion (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/post-session-hooks", r.handlers.postSessionHookController.GetReport,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/post-session-hooks/:name", r.handlers.postSessionHookController.GetHookLog,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Downloadable conversation transcript, also of deleted sessions when archived (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/export", r.handlers.transcriptController.ExportSession,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Logs and artifacts archived to object storage on deletion (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/archive", r.handlers.sessionArchiveController.GetArchive,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/archive/:name", r.handlers.sessionArchiveController.GetArchivedFile,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Completion and container logs of oneshot Job sessions, also after deletion (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/job-result", r.handlers.sessionJobController.GetResult,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/job-result/logs/:name", r.handlers.sessionJobController.GetLog,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Container logs of the session Pod (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/logs", r.handlers.sessionController.StreamSessionLogs,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Marketplaces and plugins registered for the session agent (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/extensions", r.handlers.sessionController.GetSessionExtensions,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Lifecycle event timeline of the session (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/events", r.handlers.sessionController.GetSessionEvents,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/events/stream", r.handlers.sessionController.StreamSessionEvents,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	// Exec into session containers, interactive over WebSocket (must be before /:sessionId/* catch-all)
+	r.echo.GET("/sessions/:sessionId/exec", r.handlers.sessionController.ExecSession,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.POST("/sessions/:sessionId/exec", r.handlers.sessionController.ExecSession,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	// Preview environment deployed from the session branch (must be before /:sessionId/* catch-all)
+	r.echo.POST("/sessions/:sessionId/preview", r.handlers.previewController.CreatePreview,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
+	r.echo.GET("/sessions/:sessionId/preview", r.handlers.previewController.GetPreview,
+		auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+	r.echo.DELETE("/sessions/:sessionId/preview", r.handlers.previewController.DeletePreview,
+		auth.RequirePermission(entities.PermissionSessionUpdate, r.server.container.AuthService))
 	log.Printf("[ROUTES] Session status/message push endpoints registered (SSE + long-poll)")
 
 	if r.handlers.resourceTransferController != nil {
@@ -340,6 +588,8 @@ func (r *Router) registerCoreRoutes() error {
 		r.echo.POST("/internal/session-provisioners/connect", r.handlers.provisionerController.Connect)
 		r.echo.GET("/internal/session-provisioners/:sessionId/provision-requests", r.handlers.provisionerController.GetProvisionRequest)
 		r.echo.POST("/internal/session-provisioners/:sessionId/provision-requests/:requestId/status", r.handlers.provisionerController.UpdateProvisionRequestStatus)
+		r.echo.POST("/internal/session-provisioners/:sessionId/extensions", r.handlers.provisionerController.ReportExtensions)
+		r.echo.GET("/internal/session-provisioners/:sessionId/github-token", r.handlers.provisionerController.GetGitHubToken)
 		r.echo.GET("/internal/session-allocations/next", r.handlers.provisionerController.GetNextSessionAllocation)
 		r.echo.POST("/internal/session-allocations/:sessionId/result", r.handlers.provisionerController.CompleteSessionAllocation)
 		r.echo.GET("/internal/external-session-manager/allocations/next", r.handlers.provisionerController.GetNextExternalSessionAllocation)
@@ -375,7 +625,7 @@ func (r *Router) registerCoreRoutes() error {
 		log.Printf("[ROUTES] Session sharing endpoints registered")
 	}
 
-	// Add explicit OPTIONS handler for DELETE endpoint to ensure CORS preflight works
+	// Add explicit OPTIONS handler for the PATCH and DELETE endpoints to ensure CORS preflight works
 	r.echo.OPTIONS("/sessions/:sessionId", func(c echo.Context) error {
 		return c.NoContent(http.StatusNoContent)
 	})
@@ -409,18 +659,86 @@ func (r *Router) registerConditionalRoutes() error {
 	r.echo.GET("/user/info", r.handlers.userController.GetUserInfo, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 	log.Printf("[ROUTES] User info endpoint registered")
 
+	// Audit log queries and compliance reports (admins only)
+	if r.server.auditRepo != nil {
+		r.echo.GET("/admin/audit-events", r.handlers.complianceController.ListAuditEvents, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.GET("/admin/reports/compliance", r.handlers.complianceController.GetComplianceReport, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		log.Printf("[ROUTES] Audit log and compliance report endpoints registered")
+	}
+
+	// Live checks of the proxy's dependencies (admins only)
+	r.echo.GET("/admin/diagnostics", r.handlers.diagnosticsController.RunDiagnostics, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+	log.Printf("[ROUTES] Diagnostics endpoint registered")
+
+	// Reload the safe-to-change settings of the config file (admins only)
+	r.echo.POST("/admin/config/reload", r.handlers.configReloadController.ReloadConfig, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+	log.Printf("[ROUTES] Config reload endpoint registered")
+
+	// Aggregate session statistics for dashboards (admins only)
+	r.echo.GET("/admin/stats", r.handlers.statsController.GetStats, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+	log.Printf("[ROUTES] Session stats endpoint registered")
+
+	// Rollout of updated images to running sessions (admins only)
+	if r.handlers.imageRolloutController != nil {
+		r.echo.POST("/admin/image-rollout", r.handlers.imageRolloutController.StartImageRollout, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.GET("/admin/image-rollout", r.handlers.imageRolloutController.GetImageRollout, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.DELETE("/admin/image-rollout", r.handlers.imageRolloutController.CancelImageRollout, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		log.Printf("[ROUTES] Image rollout endpoints registered")
+	}
+
+	// Session capacity forecast (admins only)
+	if r.handlers.capacityController != nil {
+		r.echo.GET("/admin/capacity/forecast", r.handlers.capacityController.GetForecast, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		log.Printf("[ROUTES] Capacity forecast endpoint registered")
+	}
+
+	// Team showback statements (admins only)
+	if r.handlers.billingController != nil {
+		r.echo.GET("/admin/billing", r.handlers.billingController.GetBilling, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.GET("/admin/billing/statement", r.handlers.billingController.GetStatement, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		log.Printf("[ROUTES] Billing endpoints registered")
+	}
+
+	// Session token usage and team budgets
+	if r.handlers.usageController != nil {
+		r.echo.GET("/usage", r.handlers.usageController.GetUsage, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		log.Printf("[ROUTES] Usage endpoint registered")
+	}
+
+	// Dead-lettered outbound deliveries and manual redelivery (admins only)
+	if r.server.deliveryQueue != nil {
+		r.echo.GET("/admin/deliveries/dead", r.handlers.deliveryController.ListDeadLetters, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.POST("/admin/deliveries/:id/redeliver", r.handlers.deliveryController.Redeliver, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		log.Printf("[ROUTES] Delivery dead-letter endpoints registered")
+	}
+
+	// Webhooks that receive session lifecycle events
+	if r.handlers.outboundWebhookController != nil {
+		r.echo.POST("/outbound-webhooks", r.handlers.outboundWebhookController.CreateWebhook, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.GET("/outbound-webhooks", r.handlers.outboundWebhookController.ListWebhooks, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.GET("/outbound-webhooks/:id", r.handlers.outboundWebhookController.GetWebhook, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.PUT("/outbound-webhooks/:id", r.handlers.outboundWebhookController.UpdateWebhook, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.DELETE("/outbound-webhooks/:id", r.handlers.outboundWebhookController.DeleteWebhook, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.GET("/outbound-webhooks/:id/deliveries", r.handlers.outboundWebhookController.ListDeliveries, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.POST("/outbound-webhooks/:id/test", r.handlers.outboundWebhookController.TestWebhook, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		log.Printf("[ROUTES] Outbound webhook endpoints registered")
+	}
+
 	// Add notification routes if service is available
 	if r.server.notificationSvc != nil {
 		log.Printf("[ROUTES] Registering notification endpoints...")
 		// UI-compatible routes (proxied from agentapi-ui)
 		r.echo.POST("/notification/subscribe", r.handlers.notificationHandlers.Subscribe, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 		r.echo.GET("/notification/subscribe", r.handlers.notificationHandlers.GetSubscriptions, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.PATCH("/notification/subscribe", r.handlers.notificationHandlers.UpdateSubscription, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 		r.echo.DELETE("/notification/subscribe", r.handlers.notificationHandlers.DeleteSubscription, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 
 		// Internal routes
 		r.echo.POST("/notifications/webhook", r.handlers.notificationHandlers.Webhook)
 		r.echo.GET("/notifications/history", r.handlers.notificationHandlers.GetHistory, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 		r.echo.POST("/notifications/send", r.handlers.notificationHandlers.SendNotification, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.POST("/notifications/templates/preview", r.handlers.notificationHandlers.PreviewTemplates, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.POST("/notifications/test", r.handlers.notificationHandlers.SendTestNotification, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 		log.Printf("[ROUTES] Notification endpoints registered")
 	} else {
 		log.Printf("[ROUTES] Notification service not available, skipping notification routes")
@@ -441,6 +759,10 @@ func (r *Router) registerConditionalRoutes() error {
 		r.echo.PUT("/settings/:name", r.handlers.settingsController.UpdateSettings, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
 		r.echo.DELETE("/settings/:name", r.handlers.settingsController.DeleteSettings, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
 		r.echo.DELETE("/settings/:name/sync", r.handlers.settingsController.DeleteGitSync, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		r.echo.GET("/settings/:name/mcp-servers", r.handlers.settingsController.ListMCPServers, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.GET("/settings/:name/mcp-servers/:server", r.handlers.settingsController.GetMCPServer, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.PUT("/settings/:name/mcp-servers/:server", r.handlers.settingsController.PutMCPServer, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		r.echo.DELETE("/settings/:name/mcp-servers/:server", r.handlers.settingsController.DeleteMCPServer, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
 		log.Printf("[ROUTES] Settings endpoints registered")
 	} else {
 		log.Printf("[ROUTES] Settings repository not available, skipping settings routes")
@@ -475,6 +797,20 @@ func (r *Router) registerConditionalRoutes() error {
 		log.Printf("[ROUTES] Codex device auth endpoints registered")
 	}
 
+	// Add team config routes if controller is available (Kubernetes mode only)
+	if r.handlers.teamConfigController != nil {
+		log.Printf("[ROUTES] Registering team config endpoints...")
+		r.echo.GET("/teams/:id/config", r.handlers.teamConfigController.GetTeamConfig, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.PUT("/teams/:id/config", r.handlers.teamConfigController.UpdateTeamConfig, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		log.Printf("[ROUTES] Team config endpoints registered")
+	}
+
+	// GitHub team membership events, verified with the team sync webhook secret
+	if r.handlers.teamSyncController != nil {
+		r.echo.POST("/webhooks/github/team-sync", r.handlers.teamSyncController.Webhook)
+		log.Printf("[ROUTES] Team sync webhook endpoint registered")
+	}
+
 	// Add personal API key routes if controller is available (Kubernetes mode only)
 	if r.handlers.personalAPIKeyController != nil {
 		log.Printf("[ROUTES] Registering personal API key endpoints...")
@@ -582,11 +918,25 @@ func (r *Router) registerConditionalRoutes() error {
 		r.echo.GET("/session-profiles/:id", r.handlers.sessionProfileController.GetSessionProfile, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
 		r.echo.PUT("/session-profiles/:id", r.handlers.sessionProfileController.UpdateSessionProfile, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
 		r.echo.DELETE("/session-profiles/:id", r.handlers.sessionProfileController.DeleteSessionProfile, auth.RequirePermission(entities.PermissionSessionCreate, r.server.container.AuthService))
+		// Team default profiles apply beneath the profiles of a team's sessions (admins only)
+		r.echo.GET("/admin/team-default-profiles", r.handlers.sessionProfileController.ListTeamDefaultProfiles, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.PUT("/admin/team-default-profiles", r.handlers.sessionProfileController.PutTeamDefaultProfile, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
+		r.echo.DELETE("/admin/team-default-profiles", r.handlers.sessionProfileController.DeleteTeamDefaultProfile, auth.RequirePermission(entities.PermissionAdmin, r.server.container.AuthService))
 		log.Printf("[ROUTES] Session profile endpoints registered")
 	} else {
 		log.Printf("[ROUTES] Session profile repository not available, skipping session profile routes")
 	}
 
+	// Add LLM egress proxy routes if enabled (must be before /:sessionId/* catch-all)
+	if r.handlers.llmProxyController != nil {
+		log.Printf("[ROUTES] Registering LLM proxy endpoints...")
+		r.echo.Any("/llm-proxy/:sessionId/:provider/*", r.handlers.llmProxyController.ProxyRequest, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		r.echo.GET("/sessions/:sessionId/llm-usage", r.handlers.llmProxyController.GetSessionUsage, auth.RequirePermission(entities.PermissionSessionRead, r.server.container.AuthService))
+		log.Printf("[ROUTES] LLM proxy endpoints registered")
+	} else {
+		log.Printf("[ROUTES] LLM proxy not enabled, skipping LLM proxy routes")
+	}
+
 	return nil
 }
 
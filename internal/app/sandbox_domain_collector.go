@@ -133,7 +133,7 @@ type sandboxDomainsResponse struct {
 }
 
 func (c *SandboxDomainCollector) fetchDomains(ks *services.KubernetesSession) (*sandboxDomainsResponse, error) {
-	url := fmt.Sprintf("http://%s:%d/sandbox-domains", ks.ServiceDNS(), services.ProvisionerPort)
+	url := fmt.Sprintf("http://%s:%d/sandbox-domains", ks.Host(), services.ProvisionerPort)
 	resp, err := c.httpClient.Get(url) //nolint:noctx
 	if err != nil {
 		return nil, err
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// routeSessionNotification reports whether the user wants notifications
+// about the session: when they route notifications by a saved search, the
+// session must match it. Sessions that are unknown to this replica, and
+// users whose settings cannot be read, are notified as before.
+func (s *Server) routeSessionNotification(userID, sessionID string) bool {
+	if s.settingsRepo == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	settings, err := s.settingsRepo.FindByName(ctx, userID)
+	if err != nil || settings == nil || settings.NotificationSavedSearch() == "" {
+		return true
+	}
+	search := settings.SavedSearch(settings.NotificationSavedSearch())
+	if search == nil {
+		return true
+	}
+	session := s.sessionManager.GetSession(sessionID)
+	if session == nil {
+		return true
+	}
+	return search.Matches(session)
+}
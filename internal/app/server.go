@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -24,16 +26,29 @@ import (
 	sessionallocation "github.com/takutakahashi/agentapi-proxy/internal/core/sessionallocation"
 	"github.com/takutakahashi/agentapi-proxy/internal/di"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
 	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	portservices "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/services"
 	serviceaccountuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/service_account"
 	sessionuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/session"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
 	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+	"github.com/takutakahashi/agentapi-proxy/pkg/eventbus"
+	"github.com/takutakahashi/agentapi-proxy/pkg/githubreport"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/llmproxy"
 	"github.com/takutakahashi/agentapi-proxy/pkg/logger"
 	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionstats"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 	"github.com/takutakahashi/agentapi-proxy/pkg/urlutil"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -45,8 +60,18 @@ type Server struct {
 	verbose            bool
 	logger             *logger.Logger
 	oauthProvider      *auth.GitHubOAuthProvider
-	oauthSessions      sync.Map // sessionID -> OAuthSession
+	teamSync           *auth.TeamSync    // Background GitHub team membership sync (nil when disabled)
+	oauthSessions      oauthSessionStore // sessionID -> OAuthSession
 	notificationSvc    *notification.Service
+	deliveryQueue      *delivery.Queue                                 // Outbound delivery retry queue; nil when disabled
+	outboundWebhooks   *outboundWebhooks                               // Session lifecycle webhooks; nil when disabled
+	diagnostics        []diagnostics.Check                             // Live checks behind GET /admin/diagnostics
+	capacityForecaster *capacityForecaster                             // Session history and forecasts; nil when disabled
+	sessionStats       *sessionstats.Aggregator                        // Aggregate session statistics for the admin dashboard
+	showback           *showbackService                                // Team usage metering and statements; nil when disabled
+	usage              *usageService                                   // Session token usage and team budgets; nil when disabled
+	sessionArchive     *sessionarchive.Archive                         // Logs and artifacts of deleted sessions; nil when disabled
+	activityFeed       *activity.Feed                                  // Sources of GET /me/activity
 	container          *di.Container                                   // Internal DI container
 	sessionManager     portrepos.SessionManager                        // Session lifecycle manager
 	settingsRepo       portrepos.SettingsRepository                    // Settings repository
@@ -64,7 +89,18 @@ type Server struct {
 	apiTokenRepo       portrepos.APITokenRepository                    // Named API token repository
 	apiTokenDeps       *apiTokenInitDeps                               // Wiring for migration/bootstrap/reconcile
 	assetStore         services.AssetStore                             // Static asset storage backend
+	auditRepo          portrepos.AuditRepository                       // Audit event log for compliance reports
+	auditWrites        sync.WaitGroup                                  // Audit events of requests being written
+	authorizer         portservices.Authorizer                         // Role policy authorizer; nil when RBAC is disabled
+	llmProxy           *llmproxy.Proxy                                 // Egress proxy for session model API traffic
+	tracer             *tracing.Tracer                                 // Trace exporter; nil when tracing is disabled
 	router             *Router                                         // Router for custom handler registration
+	secretsProvider    domainservices.SecretsProvider                  // External secrets backend; nil keeps secrets in Kubernetes Secrets
+	configReloader     *configReloader                                 // Applies reloaded auth, rate limit and session settings
+	singletons         *leader.Runner                                  // Background subsystems run by the leader replica only
+	eventBus           *eventbus.Bus                                   // Session event publisher; nil when disabled
+	githubReporter     *githubreport.Reporter                          // Branches, Check Runs and PRs of GitHub webhook sessions; nil when disabled
+	draining           atomic.Bool                                     // Set once shutdown begins; fails GET /ready
 }
 
 // NewServer creates a new server instance
@@ -181,48 +217,90 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		k8sSessionManager.SetSessionListCacheRepository(listCacheRepo)
 	}
 
+	// Failed outbound deliveries are persisted and retried in the background
+	deliveryQueue := buildDeliveryQueue(cfg)
+	if deliveryQueue != nil {
+		go deliveryQueue.Run(context.Background(), deliveryPollInterval(cfg))
+	}
+
+	// Session lifecycle events are kept in a ConfigMap per session so that the
+	// timeline survives proxy restarts, and are sent to outbound webhooks,
+	// completion callbacks, GitHub reports and the event bus. Session
+	// summaries wrap the recorder once the leader runner exists.
+	var eventRecorder portrepos.EventRecorder = repositories.NewKubernetesEventRecorder(k8sSessionManager.GetClient(), k8sSessionManager.GetNamespace())
+	eventRecorder = buildCompletionCallbacks(cfg, k8sSessionManager, deliveryQueue).Recorder(eventRecorder)
+	outboundWebhooks := buildOutboundWebhooks(cfg, k8sSessionManager, deliveryQueue)
+	if outboundWebhooks != nil {
+		eventRecorder = outboundWebhooks.Recorder(eventRecorder)
+	}
+	githubReporter := buildGitHubReporter(cfg, k8sSessionManager)
+	if githubReporter != nil {
+		eventRecorder = githubReporter.Recorder(eventRecorder)
+	}
+	eventBus := buildEventBus(cfg, k8sSessionManager)
+	if eventBus != nil {
+		eventRecorder = eventBus.Recorder(eventRecorder)
+	}
+	k8sSessionManager.SetEventRecorder(eventRecorder)
+
 	// Initialize encryption service registry
-	// The registry manages multiple encryption services and selects the appropriate one
-	// based on encryption metadata when decrypting
-	encryptionFactory := services.NewEncryptionServiceFactory("AGENTAPI_ENCRYPTION")
-	primaryService, err := encryptionFactory.Create()
+	encryptionRegistry, err := newEncryptionRegistry()
 	if err != nil {
 		log.Fatalf("Failed to create primary encryption service: %v", err)
 	}
 
-	// Create registry with primary service (used for encryption)
-	encryptionRegistry := services.NewEncryptionServiceRegistry(primaryService)
-
-	// Register Noop service for backward compatibility with plaintext data
-	// This allows reading old unencrypted data
-	noopService := services.NewNoopEncryptionService()
-	encryptionRegistry.Register(noopService)
-
-	// Try to register additional services for migration scenarios
-	// These are optional and will be used for decryption if data was encrypted with them
-
-	// Try to create a local encryption service (if different from primary)
-	localFactory := services.NewEncryptionServiceFactory("AGENTAPI_DECRYPTION")
-	if localService, err := localFactory.Create(); err == nil {
-		// Only register if it's different from primary
-		if localService.Algorithm() != primaryService.Algorithm() || localService.KeyID() != primaryService.KeyID() {
-			encryptionRegistry.Register(localService)
-		}
+	// Initialize the external secrets provider (Vault / AWS Secrets Manager).
+	// When none is configured, secrets stay in Kubernetes Secrets.
+	secretsProvider, err := services.NewSecretsProviderFactory("AGENTAPI_SECRETS").Create()
+	if err != nil {
+		log.Fatalf("Failed to create secrets provider: %v", err)
 	}
 
-	log.Printf("[SERVER] Encryption registry initialized with primary: %s (keyID: %s)",
-		primaryService.Algorithm(), primaryService.KeyID())
-
 	// Initialize settings repository
-	settingsRepo = repositories.NewKubernetesSettingsRepository(
+	k8sSettingsRepo := repositories.NewKubernetesSettingsRepository(
 		k8sSessionManager.GetClient(),
 		k8sSessionManager.GetNamespace(),
 		encryptionRegistry,
 	)
+	if secretsProvider != nil {
+		k8sSettingsRepo.SetSecretsProvider(secretsProvider)
+	}
+	settingsRepo = k8sSettingsRepo
 	// Set settings repository in session manager for Bedrock integration
 	k8sSessionManager.SetSettingsRepository(settingsRepo)
 	log.Printf("[SERVER] Settings repository initialized")
 
+	// Initialize session store based on backend configuration.
+	// Supported backends: "kubernetes" (default), "postgres", "sqlite".
+	var sessionRepo portrepos.SessionRepository
+	switch dialect := repositories.SQLDialect(cfg.SessionStore.Backend); dialect {
+	case repositories.SQLDialectPostgres, repositories.SQLDialectSQLite:
+		if cfg.SessionStore.DSN == "" {
+			log.Fatalf("[SERVER] Session store backend is '%s' but no DSN provided (set AGENTAPI_SESSION_STORE_DSN)", dialect)
+		}
+		sqlRepo, storeErr := newSQLSessionRepository(context.Background(), dialect, cfg.SessionStore.DSN, cfg.SessionStore.ReadReplicaDSN, encryptionRegistry)
+		if storeErr != nil {
+			log.Fatalf("[SERVER] Failed to initialize session store: %v", storeErr)
+		}
+		sessionRepo, storeErr = dualWriteSessionRepository(context.Background(), cfg, sqlRepo, encryptionRegistry,
+			k8sSessionManager.GetClient(), k8sSessionManager.GetNamespace())
+		if storeErr != nil {
+			log.Fatalf("[SERVER] Failed to initialize session store migration: %v", storeErr)
+		}
+		k8sSessionManager.SetSessionRepository(sessionRepo)
+		log.Printf("[SERVER] Session store initialized (backend: %s)", dialect)
+		if target := cfg.SessionStore.Migration.TargetBackend; target != "" {
+			log.Printf("[SERVER] Session store migration to %s enabled (dual write)", target)
+		}
+	case "", "kubernetes":
+		if cfg.SessionStore.Migration.TargetBackend != "" {
+			log.Fatalf("[SERVER] Session store backend 'kubernetes' cannot be migrated (supported: postgres, sqlite)")
+		}
+		log.Printf("[SERVER] Session store initialized (backend: kubernetes)")
+	default:
+		log.Fatalf("[SERVER] Unknown session store backend: %s", cfg.SessionStore.Backend)
+	}
+
 	// Initialize credentials repository
 	credentialsRepo := portrepos.CredentialsRepository(repositories.NewKubernetesCredentialsRepository(
 		k8sSessionManager.GetClient(),
@@ -240,6 +318,7 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 	teamConfigRepo := repositories.NewKubernetesTeamConfigRepository(
 		k8sSessionManager.GetClient(),
 		k8sSessionManager.GetNamespace(),
+		encryptionRegistry,
 	)
 	// Set team config repository in session manager for service account integration
 	k8sSessionManager.SetTeamConfigRepository(teamConfigRepo)
@@ -250,6 +329,9 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		k8sSessionManager.GetClient(),
 		k8sSessionManager.GetNamespace(),
 	)
+	if secretsProvider != nil {
+		personalAPIKeyRepo.SetSecretsProvider(secretsProvider)
+	}
 	// Set personal API key repository in session manager
 	k8sSessionManager.SetPersonalAPIKeyRepository(personalAPIKeyRepo)
 	log.Printf("[SERVER] Personal API key repository initialized")
@@ -289,6 +371,14 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		)
 		log.Printf("[SERVER] Memory repository initialized (backend: kubernetes)")
 	}
+	if target := cfg.Memory.Migration.TargetBackend; target != "" {
+		memoryRepo, err = dualWriteMemoryRepository(context.Background(), cfg, memoryRepo,
+			k8sSessionManager.GetClient(), k8sSessionManager.GetNamespace())
+		if err != nil {
+			log.Fatalf("[SERVER] Failed to initialize memory migration: %v", err)
+		}
+		log.Printf("[SERVER] Memory migration to %s enabled (dual write)", target)
+	}
 
 	// Initialize sandbox policy repository (Kubernetes ConfigMap-backed)
 	sandboxPolicyRepo := portrepos.SandboxPolicyRepository(repositories.NewKubernetesSandboxPolicyRepository(
@@ -345,6 +435,50 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		log.Fatalf("[SERVER] Failed to initialize asset store: %v", err)
 	}
 	log.Printf("[SERVER] Asset store initialized (backend: %s)", cfg.Asset.Backend)
+	// Post-session hook output of oneshot sessions is kept as artifacts.
+	if artifactStore, ok := assetStore.(services.ArtifactStore); ok {
+		k8sSessionManager.SetArtifactStore(artifactStore)
+	}
+
+	// Initialize audit event log (ConfigMap-backed so every replica shares it)
+	var auditRepo portrepos.AuditRepository = repositories.NewMemoryAuditRepository()
+	if k8sSessionManager.GetClient() != nil {
+		auditRepo = repositories.NewKubernetesAuditRepository(
+			k8sSessionManager.GetClient(),
+			k8sSessionManager.GetNamespace(),
+		)
+	}
+	auditSinks, err := services.NewAuditSinks(context.Background(), cfg.Audit.Sinks)
+	if err != nil {
+		log.Fatalf("[SERVER] Failed to initialize audit sinks: %v", err)
+	}
+	if eventBus != nil {
+		auditSinks = append(auditSinks, eventBus)
+	}
+	if len(auditSinks) > 0 {
+		auditRepo = services.NewTeeAuditRepository(auditRepo, auditSinks)
+		log.Printf("[SERVER] Audit events are forwarded to %d sink(s)", len(auditSinks))
+	}
+	k8sSessionManager.SetAuditRepository(auditRepo)
+	log.Printf("[SERVER] Audit repository initialized (retention: %d days)", cfg.Audit.RetentionDays)
+
+	tracer, err := cfg.Tracing.Setup()
+	if err != nil {
+		log.Printf("[SERVER] Tracing disabled: %v", err)
+	} else if tracer != nil {
+		log.Printf("[SERVER] Tracing enabled (exporter: %s)", cfg.Tracing.Exporter)
+	}
+
+	// Singleton background subsystems run only on the replica holding the
+	// leader Lease; they start with StartLeaderElection
+	singletons := buildLeaderRunner(cfg, k8sSessionManager)
+	singletons.Go("label schema migration", k8sSessionManager.RunLabelSchemaMigrator)
+	singletons.Go("image rollout", k8sSessionManager.RunImageRollouts)
+	singletons.Go("session snapshots", k8sSessionManager.RunSessionSnapshots)
+	if summaries := buildSessionSummary(cfg, k8sSessionManager, singletons); summaries != nil {
+		eventRecorder = summaries.Recorder(eventRecorder)
+		k8sSessionManager.SetEventRecorder(eventRecorder)
+	}
 
 	s := &Server{
 		config:             cfg,
@@ -367,7 +501,21 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		sessionProfileRepo: sessionProfileRepo,
 		apiTokenRepo:       apiTokenRepo,
 		assetStore:         assetStore,
-	}
+		auditRepo:          auditRepo,
+		tracer:             tracer,
+		secretsProvider:    secretsProvider,
+		deliveryQueue:      deliveryQueue,
+		outboundWebhooks:   outboundWebhooks,
+		diagnostics:        buildDiagnostics(cfg, k8sSessionManager, sessionRepo, memoryRepo, singletons),
+		capacityForecaster: buildCapacityForecaster(cfg, k8sSessionManager, singletons),
+		sessionStats:       buildSessionStats(k8sSessionManager),
+		singletons:         singletons,
+		eventBus:           eventBus,
+		githubReporter:     githubReporter,
+	}
+
+	// Render error messages in the user's locale
+	e.HTTPErrorHandler = s.localizedHTTPErrorHandler
 
 	// Add logging middleware if verbose
 	if verbose {
@@ -390,6 +538,7 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		)
 		githubAuthProvider.SetTeamMappingRepo(teamMappingRepo)
 		log.Printf("[AUTH_INIT] GitHub auth provider initialized with ConfigMap team mapping cache")
+		s.teamSync = startTeamSync(cfg.Auth.GitHub.TeamSync, githubAuthProvider, teamMappingRepo, k8sSessionManager, singletons)
 
 		// Inject the shared provider into SimpleAuthService.
 		if simpleAuth, ok := container.AuthService.(*services.SimpleAuthService); ok {
@@ -399,8 +548,40 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		}
 	}
 
+	// Load the static API keys into the internal auth service; config
+	// reloads replace them
+	if simpleAuth, ok := container.AuthService.(*services.SimpleAuthService); ok {
+		simpleAuth.SetStaticAuthConfig(cfg.Auth.Static)
+	}
+
+	// Audit logging wraps authentication so rejected credentials are recorded
+	e.Use(auditMiddleware(s.auditRepo, &s.auditWrites))
+
+	// Authentication and rate limiting are rebuilt on config reloads
+	s.configReloader = newConfigReloader(cfg, container.AuthService, k8sSessionManager)
+
 	// Add authentication middleware using internal auth service
-	e.Use(auth.AuthMiddleware(cfg, container.AuthService))
+	e.Use(s.configReloader.auth.middleware())
+
+	// Resolve session slugs in :sessionId to session IDs before authorization
+	e.Use(controllers.SessionSlugMiddleware(s, s.sessionRouteRepo))
+
+	// Rate limiting runs after authentication so buckets are keyed by user
+	e.Use(s.configReloader.rateLimit.middleware())
+
+	// Role-based authorization; the authorizer can be replaced with SetAuthorizer
+	if cfg.RBAC.Enabled {
+		authorizer, err := buildAuthorizer(cfg.RBAC)
+		if err != nil {
+			log.Fatalf("[RBAC] Invalid configuration: %v", err)
+		}
+		s.authorizer = authorizer
+		log.Printf("[RBAC] Role-based authorization enabled (%d team overrides)", len(cfg.RBAC.Teams))
+	}
+	e.Use(s.rbacMiddleware())
+
+	// Sessions in observer mode are read-only for everyone but their owner
+	e.Use(s.observerModeMiddleware())
 
 	// Initialize OAuth provider if configured.
 	// Reuses the shared githubAuthProvider so OAuth-authenticated users benefit from
@@ -419,6 +600,11 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 			s.oauthProvider.SetStateStore(oauthStateRepo)
 			log.Printf("[OAUTH_INIT] ConfigMap-backed OAuth state store injected (namespace: %s)", k8sSessionManager.GetNamespace())
 		}
+		// Share OAuth sessions between replicas when Redis is available
+		if redisRepo, ok := statusEventRepo.(*repositories.RedisStatusRepository); ok {
+			s.oauthSessions.useRedis(redisRepo.Client(), encryptionRegistry)
+			log.Printf("[OAUTH_INIT] OAuth sessions are kept in Redis")
+		}
 		log.Printf("[OAUTH_INIT] OAuth provider initialized successfully")
 		// Start cleanup goroutine for expired OAuth sessions
 		go s.cleanupExpiredOAuthSessions()
@@ -433,6 +619,12 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		log.Printf("Failed to initialize notification service: %v", err)
 	} else {
 		s.notificationSvc = notificationSvc
+		notificationSvc.SetLocaleResolver(s.notificationLocale)
+		notificationSvc.SetSessionRouter(s.routeSessionNotification)
+		notificationSvc.SetTypePreference(s.notificationTypes)
+		if s.deliveryQueue != nil {
+			notificationSvc.SetDeliveryQueue(s.deliveryQueue)
+		}
 		log.Printf("Notification service initialized successfully")
 
 		// Set up subscription secret syncer if Kubernetes mode is enabled
@@ -454,6 +646,12 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 			notificationSvc.SetSubscriptionWriter(syncer)
 			log.Printf("Subscription secret syncer configured for Kubernetes mode (read+write)")
 		}
+
+		// The notification service is created after the session event
+		// recorder, so the recorder is wrapped again here
+		if notifier := buildSessionNotifier(cfg, k8sSessionManager, notificationSvc, s.singletons); notifier != nil {
+			k8sSessionManager.SetEventRecorder(notifier.Recorder(eventRecorder))
+		}
 	}
 
 	// Start cleanup goroutine for defunct processes
@@ -462,13 +660,20 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 	// Start sandbox domain collector (Kubernetes mode only)
 	if k8sMgr, ok := s.sessionManager.(*services.KubernetesSessionManager); ok && s.sandboxDomainRepo != nil {
 		collector := newSandboxDomainCollector(k8sMgr, s.sandboxDomainRepo, 60*time.Second)
-		go collector.start(context.Background())
-		log.Printf("[SERVER] Sandbox domain collector started (interval: 60s)")
+		s.singletons.Go("sandbox domain collector", collector.start)
+		log.Printf("[SERVER] Sandbox domain collector registered (interval: 60s)")
+	}
+
+	// Start purge goroutine for audit events past retention
+	if cfg.Audit.RetentionDays > 0 {
+		s.singletons.Go("audit event purge", func(ctx context.Context) {
+			s.purgeAuditEvents(ctx, cfg.Audit.RetentionDays)
+		})
 	}
 
 	// Start cleanup goroutine for expired shares
 	if s.shareRepo != nil {
-		go s.cleanupExpiredShares()
+		s.singletons.Go("expired share cleanup", s.cleanupExpiredShares)
 	}
 
 	// Bootstrap service accounts from team configs
@@ -537,6 +742,13 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		log.Printf("[SERVER] Memory dump handler registered for session deletion")
 	}
 
+	// Archive the conversation of deleted sessions for GET /sessions/:id/export
+	if k8sManager, ok := sessionManager.(*services.KubernetesSessionManager); ok {
+		artifactStore, _ := assetStore.(services.ArtifactStore)
+		registerTranscriptArchive(cfg, k8sManager, artifactStore)
+		s.sessionArchive = buildSessionArchive(cfg, k8sManager, s.singletons)
+	}
+
 	// Local allocation may expose a stable public session ID while running the
 	// workload under an adopted stock-session ID.  A oneshot workload deletes
 	// itself by that runtime ID, so remove the public alias at the same time.
@@ -549,11 +761,82 @@ func NewServer(cfg *config.Config, verbose bool) *Server {
 		log.Printf("[SERVER] Local session route cleanup handler registered")
 	}
 
+	// Unpin and unfile deleted sessions so that favorites and folders in the
+	// owner's settings do not accumulate stale session IDs.
+	if k8sManager, ok := sessionManager.(*services.KubernetesSessionManager); ok && settingsRepo != nil {
+		k8sManager.AddSessionDeletedHandler(func(ctx context.Context, sess entities.Session) {
+			forgetSessionOrganization(ctx, settingsRepo, sess)
+		})
+	}
+
+	// Account the token usage of sessions and enforce team budgets. Showback
+	// and metering read token usage from this ledger.
+	if k8sManager, ok := sessionManager.(*services.KubernetesSessionManager); ok {
+		s.usage = buildUsage(cfg, k8sManager, s)
+	}
+	// Meter team usage for showback statements. Registered before the LLM
+	// proxy so that deleted sessions are metered before their usage is cleared.
+	if k8sManager, ok := sessionManager.(*services.KubernetesSessionManager); ok {
+		s.showback = buildShowback(cfg, k8sManager, s)
+		if s.showback != nil && s.notificationSvc != nil {
+			s.notificationSvc.SetSessionDeliveredHook(func(sessionID string) {
+				s.showback.meterNotification(k8sManager, sessionID)
+			})
+		}
+	}
+	// Export the metered usage to Stripe or a metering webhook
+	startMetering(cfg, s.showback, s.singletons)
+
+	// Initialize the LLM egress proxy if enabled
+	if cfg.LLMProxy.Enabled {
+		llmProxy, err := newLLMProxy(cfg.LLMProxy)
+		if err != nil {
+			log.Printf("[SERVER] Warning: failed to initialize LLM proxy: %v", err)
+		} else {
+			s.llmProxy = llmProxy
+			log.Printf("[SERVER] LLM proxy initialized with %d provider(s)", len(cfg.LLMProxy.Providers))
+			if k8sManager, ok := sessionManager.(*services.KubernetesSessionManager); ok {
+				k8sManager.AddSessionDeletedHandler(func(ctx context.Context, sess entities.Session) {
+					llmProxy.Usage().Delete(sess.ID())
+				})
+			}
+		}
+	}
+
+	s.activityFeed = s.buildActivityFeed()
+
 	s.setupRoutes()
 
 	return s
 }
 
+// newLLMProxy builds the LLM egress proxy from configuration. Provider API keys
+// are read from the environment variables named by each provider.
+func newLLMProxy(cfg config.LLMProxyConfig) (*llmproxy.Proxy, error) {
+	providers := make([]llmproxy.Provider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		apiKey := os.Getenv(p.APIKeyEnv)
+		if apiKey == "" {
+			log.Printf("[SERVER] Warning: LLM proxy provider %s skipped: %s is not set", p.Name, p.APIKeyEnv)
+			continue
+		}
+		providers = append(providers, llmproxy.Provider{
+			Name:    p.Name,
+			Type:    llmproxy.ProviderType(p.Type),
+			BaseURL: p.BaseURL,
+			APIKey:  apiKey,
+		})
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no usable providers configured")
+	}
+	return llmproxy.New(llmproxy.Options{
+		Providers:          providers,
+		LogBodies:          cfg.LogBodies,
+		MaxLoggedBodyBytes: cfg.MaxLoggedBodyBytes,
+	})
+}
+
 func cleanupLocalSessionRoutes(ctx context.Context, repo portrepos.SessionRouteRepository, runtimeSessionID string) {
 	routes, err := repo.List(ctx, "")
 	if err != nil {
@@ -740,10 +1023,14 @@ func (s *Server) GetSessionRouteRepository() portrepos.SessionRouteRepository {
 }
 
 // CreateSession creates a new agent session
-func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error) {
+func (s *Server) CreateSession(ctx context.Context, sessionID string, startReq entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error) {
+	// Keep the trace of the request but not its cancellation: session
+	// creation must finish even if the client goes away.
+	ctx = context.WithoutCancel(ctx)
+
 	// If ManagerID is set, forward session creation to an external session manager (External Session Manager)
 	if startReq.Params != nil && startReq.Params.ManagerID != "" {
-		return s.createRemoteSession(context.Background(), sessionID, startReq, userID, teams)
+		return s.createRemoteSession(ctx, sessionID, startReq, userID, teams)
 	}
 
 	// If no ManagerID is specified, check for a default external session manager.
@@ -756,7 +1043,7 @@ func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest,
 		return nil, fmt.Errorf("allocator.* routing does not support sandbox or Docker-in-Docker")
 	}
 	if !sandboxRequested && !dindRequested {
-		selectedESM, err := s.findDefaultESM(context.Background(), userID, teams, startReq.Tags)
+		selectedESM, err := s.findDefaultESM(ctx, userID, teams, startReq.Tags)
 		if err != nil {
 			return nil, fmt.Errorf("select external session manager: %w", err)
 		}
@@ -766,7 +1053,7 @@ func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest,
 				startReq.Params = &entities.SessionParams{}
 			}
 			startReq.Params.ManagerID = selectedESM.ID
-			return s.createRemoteSession(context.Background(), sessionID, startReq, userID, teams)
+			return s.createRemoteSession(ctx, sessionID, startReq, userID, teams)
 		}
 		if hasAllocatorSelector {
 			return nil, fmt.Errorf("no external session manager matches allocator.* tags")
@@ -855,6 +1142,24 @@ func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest,
 		docker = startReq.Params.Docker
 	}
 
+	// Determine editor params from Params.Editor
+	var editor *entities.EditorParams
+	if startReq.Params != nil && startReq.Params.Editor != nil {
+		editor = startReq.Params.Editor
+	}
+
+	// Determine browser params from Params.Browser
+	var browser *entities.BrowserParams
+	if startReq.Params != nil && startReq.Params.Browser != nil {
+		browser = startReq.Params.Browser
+	}
+
+	// Determine terminal params from Params.Terminal
+	var terminal *entities.TerminalParams
+	if startReq.Params != nil && startReq.Params.Terminal != nil {
+		terminal = startReq.Params.Terminal
+	}
+
 	// Determine auth proxy params from Params.AuthProxy
 	var authProxy *bool
 	if startReq.Params != nil && startReq.Params.AuthProxy != nil {
@@ -869,16 +1174,27 @@ func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest,
 
 	var unsyncedFilePaths []string
 	var credentialSource string
+	var setupHooks, postSessionHooks []entities.SetupHook
+	var replicas int
+	var accelerator *entities.AcceleratorParams
+	var image string
+	var completionCallbackURL string
 	if startReq.Params != nil && len(startReq.Params.UnsyncedFilePaths) > 0 {
 		unsyncedFilePaths = append([]string(nil), startReq.Params.UnsyncedFilePaths...)
 	}
 	if startReq.Params != nil {
 		credentialSource = startReq.Params.CredentialSource
+		setupHooks = startReq.Params.SetupHooks
+		postSessionHooks = startReq.Params.PostSessionHooks
+		replicas = startReq.Params.Replicas
+		accelerator = startReq.Params.Accelerator
+		image = startReq.Params.Image
+		completionCallbackURL = startReq.Params.CompletionCallbackURL
 	}
 
 	launcher := sessionuc.NewLaunchUseCase(s.sessionManager).
 		WithMemoryRepository(s.memoryRepo)
-	result, err := launcher.Launch(context.Background(), sessionID, sessionuc.LaunchRequest{
+	result, err := launcher.Launch(ctx, sessionID, sessionuc.LaunchRequest{
 		UserID:                   userID,
 		Environment:              startReq.Environment,
 		Tags:                     startReq.Tags,
@@ -897,11 +1213,22 @@ func (s *Server) CreateSession(sessionID string, startReq entities.StartRequest,
 		CycleMaxCount:            cycleMaxCount,
 		Sandbox:                  sandbox,
 		Docker:                   docker,
+		Editor:                   editor,
+		Browser:                  browser,
+		Terminal:                 terminal,
 		AuthProxy:                authProxy,
 		SessionTTL:               sessionTTL,
 		UnsyncedFilePaths:        unsyncedFilePaths,
 		CredentialSource:         credentialSource,
 		ProfileMCPServers:        startReq.ProfileMCPServers,
+		TeamProfileMCPServers:    startReq.TeamProfileMCPServers,
+		RequestMCPServers:        startReq.RequestMCPServers,
+		SetupHooks:               setupHooks,
+		PostSessionHooks:         postSessionHooks,
+		Replicas:                 replicas,
+		Accelerator:              accelerator,
+		Image:                    image,
+		CompletionCallbackURL:    completionCallbackURL,
 	})
 	if err != nil {
 		return nil, err
@@ -929,6 +1256,7 @@ func (s *Server) createRemoteSession(ctx context.Context, sessionID string, star
 	var authProxy *bool
 	var unsyncedFilePaths []string
 	var credentialSource string
+	var setupHooks, postSessionHooks []entities.SetupHook
 	if startReq.Params != nil {
 		initialMessage = startReq.Params.Message
 		agentType = startReq.Params.AgentType
@@ -936,30 +1264,40 @@ func (s *Server) createRemoteSession(ctx context.Context, sessionID string, star
 		authProxy = startReq.Params.AuthProxy
 		unsyncedFilePaths = append([]string(nil), startReq.Params.UnsyncedFilePaths...)
 		credentialSource = startReq.Params.CredentialSource
+		setupHooks = startReq.Params.SetupHooks
+		postSessionHooks = startReq.Params.PostSessionHooks
 	}
 	runReq := &entities.RunServerRequest{
-		UserID:            userID,
-		Teams:             teams,
-		Scope:             startReq.Scope,
-		TeamID:            startReq.TeamID,
-		AgentType:         agentType,
-		Oneshot:           oneshot,
-		Environment:       startReq.Environment,
-		Tags:              startReq.Tags,
-		MemoryKey:         startReq.MemoryKey,
-		InitialMessage:    initialMessage,
-		RepoInfo:          s.extractRepositoryInfo(sessionID, startReq.Tags),
-		AuthProxy:         authProxy,
-		UnsyncedFilePaths: unsyncedFilePaths,
-		CredentialSource:  credentialSource,
-		ProfileMCPServers: startReq.ProfileMCPServers,
+		UserID:                userID,
+		Teams:                 teams,
+		Scope:                 startReq.Scope,
+		TeamID:                startReq.TeamID,
+		AgentType:             agentType,
+		Oneshot:               oneshot,
+		Environment:           startReq.Environment,
+		Tags:                  startReq.Tags,
+		MemoryKey:             startReq.MemoryKey,
+		InitialMessage:        initialMessage,
+		RepoInfo:              s.extractRepositoryInfo(sessionID, startReq.Tags),
+		AuthProxy:             authProxy,
+		UnsyncedFilePaths:     unsyncedFilePaths,
+		CredentialSource:      credentialSource,
+		ProfileMCPServers:     startReq.ProfileMCPServers,
+		TeamProfileMCPServers: startReq.TeamProfileMCPServers,
+		RequestMCPServers:     startReq.RequestMCPServers,
+		SetupHooks:            setupHooks,
+		PostSessionHooks:      postSessionHooks,
 	}
 
 	// Try to build fully-resolved settings (env vars, Bedrock, MCP servers, OAuth token, etc.)
 	// by delegating to the session manager which has access to the settings resolution logic.
 	var settings *sessionsettings.SessionSettings
 	if builder, ok := s.sessionManager.(portrepos.RemoteProvisionSettingsBuilder); ok {
-		if builtSettings, buildErr := builder.BuildRemoteProvisionSettings(ctx, sessionID, runReq); buildErr == nil {
+		builtSettings, buildErr := builder.BuildRemoteProvisionSettings(ctx, sessionID, runReq)
+		if errors.Is(buildErr, entities.ErrMCPServerNotAllowed) {
+			return nil, buildErr
+		}
+		if buildErr == nil {
 			settings = builtSettings
 			log.Printf("[REMOTE_SESSION] Built full provision settings for session %s (env vars: %d)", sessionID, len(settings.Env))
 		} else {
@@ -982,6 +1320,8 @@ func (s *Server) createRemoteSession(ctx context.Context, sessionID string, star
 			Env:               startReq.Environment,
 			InitialMessage:    initialMessage,
 			UnsyncedFilePaths: unsyncedFilePaths,
+			SetupHooks:        services.SetupHookSettings(setupHooks),
+			PostSessionHooks:  services.SetupHookSettings(postSessionHooks),
 		}
 	}
 
@@ -1290,9 +1630,19 @@ func buildIntegrationPrompt(memTagFlags, memKeyFlags, scope, draftMemoryID strin
 重要: ステップ5のドラフトメモリ削除は必ず実行してください。すべての作業が完了したら、その旨を報告してください。`, draftMemoryID, draftMemoryID, memTagFlags, scope, memKeyFlags, scope, draftMemoryID, draftMemoryID)
 }
 
-// Shutdown gracefully stops all running sessions and waits for them to terminate
+// Shutdown flushes the buffered audit events, bus events and usage, then
+// gracefully stops all running sessions and waits for them to terminate
 func (s *Server) Shutdown(timeout time.Duration) error {
-	return s.sessionManager.Shutdown(timeout)
+	s.flushBuffers(timeout)
+	err := s.sessionManager.Shutdown(timeout)
+	if s.tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if flushErr := s.tracer.Shutdown(ctx); flushErr != nil {
+			log.Printf("[SERVER] Failed to flush traces: %v", flushErr)
+		}
+	}
+	return err
 }
 
 // GetEcho returns the Echo instance for external access
@@ -1300,11 +1650,39 @@ func (s *Server) GetEcho() *echo.Echo {
 	return s.echo
 }
 
+// GetLLMProxy returns the LLM egress proxy, or nil when it is disabled
+func (s *Server) GetLLMProxy() *llmproxy.Proxy {
+	return s.llmProxy
+}
+
 // GetConfig returns the server configuration
 func (s *Server) GetConfig() *config.Config {
 	return s.config
 }
 
+// GetAuthorizer returns the role policy authorizer, or nil when RBAC is disabled
+func (s *Server) GetAuthorizer() portservices.Authorizer {
+	return s.authorizer
+}
+
+// SetAuthorizer replaces the authorizer used for role-based authorization,
+// e.g. with an external policy engine. It must be called before custom
+// handlers that take the authorizer are registered.
+func (s *Server) SetAuthorizer(authorizer portservices.Authorizer) {
+	s.authorizer = authorizer
+}
+
+// GetGitHubReporter returns the reporter of sessions created from GitHub
+// webhooks, or nil when github_reporting is disabled
+func (s *Server) GetGitHubReporter() *githubreport.Reporter {
+	return s.githubReporter
+}
+
+// GetDeliveryQueue returns the outbound delivery retry queue, or nil when it is disabled
+func (s *Server) GetDeliveryQueue() *delivery.Queue {
+	return s.deliveryQueue
+}
+
 // GetNotificationService returns the notification service
 func (s *Server) GetNotificationService() *notification.Service {
 	return s.notificationSvc
@@ -1440,12 +1818,18 @@ func (s *Server) cleanupDefunctProcessesOnce() {
 	}
 }
 
-// cleanupExpiredShares periodically removes expired session shares
-func (s *Server) cleanupExpiredShares() {
+// cleanupExpiredShares periodically removes expired session shares until ctx
+// is cancelled
+func (s *Server) cleanupExpiredShares(ctx context.Context) {
 	ticker := time.NewTicker(15 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 		if s.shareRepo != nil {
 			count, err := s.shareRepo.CleanupExpired()
 			if err != nil {
@@ -1457,17 +1841,8 @@ func (s *Server) cleanupExpiredShares() {
 	}
 }
 
-// buildStatusEventRepository constructs the appropriate StatusEventRepository
-// based on the config:
-//   - When cfg.Redis.Addr is non-empty a real RedisStatusRepository is returned.
-//   - Otherwise a NoopStatusRepository is returned so existing single-pod
-//     behaviour is preserved without any code changes in the callers.
-func buildStatusEventRepository(cfg *config.Config) portrepos.StatusEventRepository {
-	if cfg.Redis.Addr == "" {
-		log.Printf("[SERVER] Redis not configured – using noop status event repository")
-		return repositories.NewNoopStatusRepository()
-	}
-
+// redisOptions builds go-redis client options from the redis config section.
+func redisOptions(cfg *config.Config) *redis.Options {
 	opts := &redis.Options{
 		Addr:     cfg.Redis.Addr,
 		Password: cfg.Redis.Password,
@@ -1486,8 +1861,21 @@ func buildStatusEventRepository(cfg *config.Config) portrepos.StatusEventReposit
 	if cfg.Redis.TLSEnabled {
 		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
 	}
+	return opts
+}
+
+// buildStatusEventRepository constructs the appropriate StatusEventRepository
+// based on the config:
+//   - When cfg.Redis.Addr is non-empty a real RedisStatusRepository is returned.
+//   - Otherwise a NoopStatusRepository is returned so existing single-pod
+//     behaviour is preserved without any code changes in the callers.
+func buildStatusEventRepository(cfg *config.Config) portrepos.StatusEventRepository {
+	if cfg.Redis.Addr == "" {
+		log.Printf("[SERVER] Redis not configured – using noop status event repository")
+		return repositories.NewNoopStatusRepository()
+	}
 
-	client := redis.NewClient(opts)
+	client := redis.NewClient(redisOptions(cfg))
 
 	// Verify connectivity at startup (non-fatal: a misconfigured Redis falls
 	// back to noop so the proxy can still serve requests).
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+)
+
+const sessionArchiveSweepInterval = time.Hour
+
+// buildSessionArchive archives the logs and artifacts of each session deleted
+// by manager into the configured bucket and removes expired archives in the
+// background. It returns nil when archiving is disabled or misconfigured.
+func buildSessionArchive(cfg *config.Config, manager *services.KubernetesSessionManager, singletons *leader.Runner) *sessionarchive.Archive {
+	ac := cfg.SessionArchive
+	if !ac.Enabled {
+		return nil
+	}
+	store, err := sessionarchive.NewS3Store(context.Background(), sessionarchive.S3Options{
+		Provider: ac.Provider,
+		Bucket:   ac.Bucket,
+		Region:   ac.Region,
+		Prefix:   ac.Prefix,
+		Endpoint: ac.Endpoint,
+	})
+	if err != nil {
+		log.Printf("[SESSION_ARCHIVE] Invalid configuration, archiving disabled: %v", err)
+		return nil
+	}
+
+	archive := sessionarchive.New(store, time.Duration(ac.RetentionDays)*24*time.Hour)
+	manager.SetSessionArchive(archive, ac.Artifacts)
+	singletons.Go("session archive sweep", func(ctx context.Context) { archive.Run(ctx, sessionArchiveSweepInterval) })
+	log.Printf("[SESSION_ARCHIVE] Archiving deleted sessions to %s bucket %s (retention: %d days)", ac.Provider, ac.Bucket, ac.RetentionDays)
+	return archive
+}
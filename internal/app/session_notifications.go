@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+)
+
+// buildSessionNotifier creates the sender of push notifications on session
+// lifecycle events. Timeline events reach it once its Recorder wraps the
+// session event recorder; agent status changes, which every replica receives
+// with Redis, are observed by the leader only so that a completed run
+// notifies once. Returns nil when session notifications are disabled.
+func buildSessionNotifier(cfg *config.Config, manager *services.KubernetesSessionManager, service *notification.Service, singletons *leader.Runner) *notification.SessionNotifier {
+	if !cfg.SessionNotifications.Enabled || service == nil {
+		return nil
+	}
+	notifier := notification.NewSessionNotifier(service, manager.GetSession)
+
+	singletons.Go("session notifications", func(ctx context.Context) {
+		statusEvents, cancel := manager.SubscribeStatusEvents()
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-statusEvents:
+				if !ok {
+					return
+				}
+				notifier.ObserveStatus(evt.SessionID, evt.Status)
+			}
+		}
+	})
+
+	log.Printf("[NOTIFICATION_SERVICE] Sending notifications on session lifecycle events")
+	return notifier
+}
+
+// notificationTypes returns the notification types the user has chosen to
+// receive; nil (all types) when they have not chosen or their settings
+// cannot be read.
+func (s *Server) notificationTypes(userID string) []string {
+	if s.settingsRepo == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	settings, err := s.settingsRepo.FindByName(ctx, userID)
+	if err != nil || settings == nil {
+		return nil
+	}
+	return settings.NotificationTypes()
+}
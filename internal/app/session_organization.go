@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// forgetSessionOrganization removes a deleted session from the favorites and
+// folders of its owner. Other users who pinned a shared session keep the
+// stale ID until they unpin it; it no longer appears in their listings.
+func forgetSessionOrganization(ctx context.Context, repo portrepos.SettingsRepository, sess entities.Session) {
+	userID := sess.UserID()
+	if userID == "" {
+		return
+	}
+	settings, err := repo.FindByName(ctx, userID)
+	if err != nil || settings == nil {
+		return
+	}
+	org := settings.SessionOrganization()
+	if !org.Forget(sess.ID()) {
+		return
+	}
+	settings.SetSessionOrganization(org)
+	if err := repo.Save(ctx, settings); err != nil {
+		log.Printf("[SERVER] Failed to remove deleted session %s from the organization of user %s: %v", sess.ID(), userID, err)
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionstats"
+)
+
+// sessionStatsRefreshInterval is how often the current sessions are counted
+const sessionStatsRefreshInterval = 15 * time.Second
+
+// buildSessionStats aggregates the sessions of manager for GET /admin/stats.
+// Every replica aggregates the lifecycle events it receives: its own, and
+// with Redis those of all replicas.
+func buildSessionStats(manager *services.KubernetesSessionManager) *sessionstats.Aggregator {
+	describe := func(sess entities.Session) sessionstats.Session {
+		session := sessionstats.Session{ID: sess.ID(), UserID: sess.UserID(), Status: sess.Status()}
+		if sess.Scope() == entities.ScopeTeam {
+			session.TeamID = sess.TeamID()
+		}
+		if ks, ok := sess.(*services.KubernetesSession); ok && ks.Request() != nil {
+			session.AgentType = ks.Request().AgentType
+		}
+		return session
+	}
+	stats := sessionstats.NewAggregator(func() []sessionstats.Session {
+		sessions := manager.ListSessions(entities.SessionFilter{})
+		out := make([]sessionstats.Session, 0, len(sessions))
+		for _, sess := range sessions {
+			out = append(out, describe(sess))
+		}
+		return out
+	})
+
+	events, _ := manager.SubscribeSessionEvents()
+	go func() {
+		for evt := range events {
+			switch evt.Type {
+			case entities.SessionEventCreated:
+				// Sessions created by another replica may not be known here
+				// yet; they are counted without their user
+				session := sessionstats.Session{ID: evt.SessionID}
+				if sess := manager.GetSession(evt.SessionID); sess != nil {
+					session = describe(sess)
+				}
+				stats.Created(session, evt.Timestamp)
+			case entities.SessionEventProvisioned:
+				stats.Started(evt.SessionID, evt.Timestamp)
+			case entities.SessionEventProvisionFailed, entities.SessionEventStartupTimeout, entities.SessionEventCrashed:
+				stats.Failed(evt.SessionID, evt.Timestamp)
+			}
+		}
+	}()
+	go stats.Run(context.Background(), sessionStatsRefreshInterval)
+
+	log.Printf("[SESSION_STATS] Aggregating session statistics")
+	return stats
+}
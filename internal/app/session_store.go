@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+// sqlDriverNames maps a session store dialect to its database/sql driver.
+// The postgres driver is always linked in; the sqlite driver needs cgo and
+// the "sqlite" build tag.
+var sqlDriverNames = map[repositories.SQLDialect]string{
+	repositories.SQLDialectPostgres: "pgx",
+	repositories.SQLDialectSQLite:   "sqlite3",
+}
+
+// newSQLSessionRepository opens the session store database and prepares its schema.
+// When replicaDSN is set, bulk reads are served by that database instead; a
+// replica that cannot be reached is logged and the primary serves them.
+func newSQLSessionRepository(ctx context.Context, dialect repositories.SQLDialect, dsn, replicaDSN string, reg *services.EncryptionServiceRegistry) (*repositories.SQLSessionRepository, error) {
+	db, err := openSQLSessionStore(ctx, dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := repositories.NewSQLSessionRepository(ctx, db, dialect, reg)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if replicaDSN != "" {
+		replica, err := openSQLSessionStore(ctx, dialect, replicaDSN)
+		if err != nil {
+			log.Printf("[SERVER] Session store read replica unavailable, reading from primary: %v", err)
+		} else {
+			repo.SetReadReplica(replica)
+			log.Printf("[SERVER] Session store read replica enabled")
+		}
+	}
+	return repo, nil
+}
+
+// openSQLSessionStore opens and pings a session store database.
+func openSQLSessionStore(ctx context.Context, dialect repositories.SQLDialect, dsn string) (*sql.DB, error) {
+	driver := sqlDriverNames[dialect]
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("SQL driver %q is not compiled in; rebuild with CGO_ENABLED=1 -tags %s", driver, dialect)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s session store: %w", dialect, err)
+	}
+	if dialect == repositories.SQLDialectSQLite {
+		// SQLite allows a single writer; serialize access instead of failing
+		// with SQLITE_BUSY under concurrent session creation.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to connect to %s session store: %w", dialect, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,5 @@
+package app
+
+// Registers the "pgx" database/sql driver for the postgres session store.
+// It is pure Go, so it is always linked in.
+import _ "github.com/jackc/pgx/v5/stdlib"
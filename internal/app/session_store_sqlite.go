@@ -0,0 +1,8 @@
+//go:build sqlite && cgo
+
+package app
+
+// Registers the "sqlite3" database/sql driver for the sqlite session store.
+// It needs cgo, so it is only linked in by builds with CGO_ENABLED=1 and
+// -tags sqlite.
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsummary"
+)
+
+// buildSessionSummary creates the summarizer of session conversations.
+// Completed sessions are summarized once its Recorder wraps the session event
+// recorder; running sessions are summarized by the leader every interval.
+// Returns nil when session summaries are disabled or the API key is not set.
+func buildSessionSummary(cfg *config.Config, manager *services.KubernetesSessionManager, singletons *leader.Runner) *sessionsummary.Service {
+	sc := cfg.SessionSummary
+	if !sc.Enabled {
+		return nil
+	}
+	apiKey := os.Getenv(sc.APIKeyEnv)
+	if apiKey == "" {
+		log.Printf("[SESSION_SUMMARY] %s is not set, session summaries disabled", sc.APIKeyEnv)
+		return nil
+	}
+	timeout, err := time.ParseDuration(sc.Timeout)
+	if err != nil || timeout <= 0 {
+		log.Printf("[SESSION_SUMMARY] Invalid session_summary.timeout %q, using 30s", sc.Timeout)
+		timeout = 30 * time.Second
+	}
+	interval, err := time.ParseDuration(sc.Interval)
+	if err != nil || interval <= 0 {
+		log.Printf("[SESSION_SUMMARY] Invalid session_summary.interval %q, using 10m", sc.Interval)
+		interval = 10 * time.Minute
+	}
+
+	service := sessionsummary.NewService(sessionsummary.NewAnthropicSummarizer(sc.BaseURL, apiKey, sc.Model, timeout), manager)
+	singletons.Go("session summaries", func(ctx context.Context) {
+		service.Run(ctx, interval)
+	})
+
+	log.Printf("[SESSION_SUMMARY] Summarizing sessions with model %s every %s", sc.Model, interval)
+	return service
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+)
+
+const (
+	defaultShowbackSampleInterval = 5 * time.Minute
+	// showbackDigestInterval is how often the digest checks whether the
+	// statements of the previous month are due
+	showbackDigestInterval = time.Hour
+)
+
+// showbackService meters team usage and prices it into statements
+type showbackService struct {
+	meter *showback.Meter
+	rates showback.Rates
+}
+
+// buildShowback starts metering the team sessions of manager. Token usage is
+// read from the usage ledger of the server, so showback needs usage
+// accounting for token counts. LLM requests are read from the server's LLM
+// proxy, which is looked up on each sample because it is created later.
+// Returns nil when showback is disabled or the store cannot be created.
+func buildShowback(cfg *config.Config, manager *services.KubernetesSessionManager, s *Server) *showbackService {
+	sc := cfg.Showback
+	if !sc.Enabled {
+		return nil
+	}
+
+	dir := sc.Dir
+	if dir == "" {
+		dir = filepath.Join(notification.GetBaseDir(), "showback")
+	}
+	store, err := showback.NewFileStore(dir)
+	if err != nil {
+		log.Printf("[SHOWBACK] Failed to initialize store, showback disabled: %v", err)
+		return nil
+	}
+	meter := showback.NewMeter(store)
+	if s.usage != nil {
+		meter.SetTokenSource(ledgerTokenSource(s.usage.ledger))
+	} else {
+		log.Printf("[SHOWBACK] usage.enabled is off, statements carry no token usage")
+	}
+
+	pvcEnabled := cfg.KubernetesSession.PVCEnabled == nil || *cfg.KubernetesSession.PVCEnabled
+	describe := func(ks *services.KubernetesSession) showback.Session {
+		session := showback.Session{
+			ID:        ks.ID(),
+			TeamID:    ks.TeamID(),
+			StartedAt: ks.StartedAt(),
+			Paused:    ks.Status() == "paused",
+			Storage:   pvcEnabled && ks.Replicas() <= 1,
+		}
+		if s.llmProxy != nil {
+			if usage, ok := s.llmProxy.Usage().Get(ks.ID()); ok {
+				session.LLMRequests = usage.Requests
+			}
+		}
+		return session
+	}
+	list := func() []showback.Session {
+		sessions := manager.ListSessions(entities.SessionFilter{Scope: entities.ScopeTeam})
+		out := make([]showback.Session, 0, len(sessions))
+		for _, sess := range sessions {
+			if ks, ok := sess.(*services.KubernetesSession); ok && ks.Scope() == entities.ScopeTeam {
+				out = append(out, describe(ks))
+			}
+		}
+		return out
+	}
+
+	interval := defaultShowbackSampleInterval
+	if d, err := time.ParseDuration(sc.SampleInterval); err == nil && d > 0 {
+		interval = d
+	}
+	sampler := showback.NewSampler(meter, list, 2*interval)
+	s.singletons.Go("showback sampler", func(ctx context.Context) { sampler.Run(ctx, interval) })
+
+	// Meter deleted sessions up to their deletion. This handler is registered
+	// before the LLM proxy clears the usage of deleted sessions.
+	manager.AddSessionDeletedHandler(func(ctx context.Context, sess entities.Session) {
+		ks, ok := sess.(*services.KubernetesSession)
+		if !ok || ks.Scope() != entities.ScopeTeam {
+			return
+		}
+		if err := sampler.Forget(ctx, describe(ks), time.Now()); err != nil {
+			log.Printf("[SHOWBACK] Failed to meter deleted session %s: %v", ks.ID(), err)
+		}
+	})
+
+	rates := showbackRates(sc.Rates)
+	if sc.DigestSlackChannel != "" {
+		slackSvc, err := notification.NewSlackService()
+		if err != nil {
+			log.Printf("[SHOWBACK] Slack digest disabled: %v", err)
+		} else {
+			digest := showback.NewDigest(meter, rates, slackShowbackPublisher(slackSvc, sc.DigestSlackChannel))
+			s.singletons.Go("showback digest", func(ctx context.Context) { digest.Run(ctx, showbackDigestInterval) })
+		}
+	}
+
+	log.Printf("[SHOWBACK] Metering team sessions every %s (store: %s)", interval, dir)
+	return &showbackService{meter: meter, rates: rates}
+}
+
+// ledgerTokenSource reads the token usage of teams from the usage ledger
+func ledgerTokenSource(ledger *usage.Ledger) showback.TokenSource {
+	return func(ctx context.Context, month string) (map[string]showback.Tokens, error) {
+		start, err := showback.ParseMonth(month)
+		if err != nil {
+			return nil, err
+		}
+		teams, err := ledger.TeamsMonth(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make(map[string]showback.Tokens, len(teams))
+		for teamID, c := range teams {
+			tokens[teamID] = showback.Tokens{
+				InputTokens:  c.InputTokens + c.CacheReadTokens + c.CacheCreationTokens,
+				OutputTokens: c.OutputTokens,
+			}
+		}
+		return tokens, nil
+	}
+}
+
+// meterNotification records a notification delivered for a session
+func (sb *showbackService) meterNotification(manager *services.KubernetesSessionManager, sessionID string) {
+	sess := manager.GetSession(sessionID)
+	if sess == nil || sess.Scope() != entities.ScopeTeam {
+		return
+	}
+	if err := sb.meter.AddNotification(context.Background(), sess.TeamID(), time.Now()); err != nil {
+		log.Printf("[SHOWBACK] Failed to meter notification of session %s: %v", sessionID, err)
+	}
+}
+
+// slackShowbackPublisher posts a summary of the statements to channel,
+// followed by the PDF statement of each team
+func slackShowbackPublisher(slackSvc *notification.SlackService, channel string) showback.Publisher {
+	return func(_ context.Context, month string, statements []*showback.Statement) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Showback statements for %s\n", month)
+		for _, st := range statements {
+			fmt.Fprintf(&b, "• %s: %.2f %s (%d sessions, %.1f session hours)\n",
+				st.TeamID, st.Total, st.Currency, st.Usage.Sessions, st.Usage.SessionHours)
+		}
+		if err := slackSvc.PostToChannel(channel, b.String()); err != nil {
+			return err
+		}
+		for _, st := range statements {
+			var pdf bytes.Buffer
+			if err := showback.WritePDF(&pdf, st); err != nil {
+				return err
+			}
+			filename := fmt.Sprintf("showback-%s-%s.pdf", strings.ReplaceAll(st.TeamID, "/", "-"), month)
+			if err := slackSvc.UploadToChannel(channel, filename, "Showback "+st.TeamID+" "+month, pdf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// showbackRates converts the configured rates
+func showbackRates(rc config.ShowbackRatesConfig) showback.Rates {
+	return showback.Rates{
+		Currency:     rc.Currency,
+		Session:      rc.Session,
+		SessionHour:  rc.SessionHour,
+		StorageHour:  rc.StorageHour,
+		InputTokens:  rc.InputTokens,
+		OutputTokens: rc.OutputTokens,
+		Notification: rc.Notification,
+	}
+}
+
+// newBillingController returns the billing controller, or nil when showback
+// is disabled
+func newBillingController(sb *showbackService) *controllers.BillingController {
+	if sb == nil {
+		return nil
+	}
+	return controllers.NewBillingController(sb.meter, sb.rates)
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+)
+
+func TestLedgerTokenSource(t *testing.T) {
+	ctx := context.Background()
+	ledger := usage.NewLedger(usage.NewConfigMapStore(fake.NewSimpleClientset(), "agentapi"))
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ledger.Add(usage.Session{ID: "s1", UserID: "alice", TeamID: "acme/dev"}, at,
+		usage.Counters{InputTokens: 100, OutputTokens: 20, CacheReadTokens: 1000, CacheCreationTokens: 50, CostUSD: 1})
+	ledger.Add(usage.Session{ID: "s2", UserID: "bob"}, at, usage.Counters{InputTokens: 5})
+	ledger.Add(usage.Session{ID: "s3", UserID: "alice", TeamID: "acme/dev"}, at.AddDate(0, -1, 0), usage.Counters{InputTokens: 7})
+
+	tokens, err := ledgerTokenSource(ledger)(ctx, "2026-10")
+	if err != nil {
+		t.Fatalf("token source error = %v", err)
+	}
+	want := map[string]showback.Tokens{"acme/dev": {InputTokens: 1150, OutputTokens: 20}}
+	if len(tokens) != len(want) || tokens["acme/dev"] != want["acme/dev"] {
+		t.Errorf("tokens = %+v, want %+v", tokens, want)
+	}
+
+	if _, err := ledgerTokenSource(ledger)(ctx, "October"); err == nil {
+		t.Error("an invalid month should fail")
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// validateShutdown checks the durations of shutdown; empty ones use the defaults
+func validateShutdown(sc config.ShutdownConfig) error {
+	for _, d := range []struct{ name, value string }{
+		{"shutdown.drain_delay", sc.DrainDelay},
+		{"shutdown.grace_period", sc.GracePeriod},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(d.value); err != nil || parsed < 0 {
+			return fmt.Errorf("%s %q is not a valid duration", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// BeginShutdown starts draining: GET /ready fails so that load balancers
+// stop routing to this replica, and new sessions are refused with 503 so
+// that clients retry against another replica. Requests to existing sessions
+// are still served.
+func (s *Server) BeginShutdown() {
+	if !s.draining.CompareAndSwap(false, true) {
+		return
+	}
+	if k8sManager, ok := s.sessionManager.(*services.KubernetesSessionManager); ok {
+		k8sManager.StopAcceptingSessions()
+	}
+	log.Printf("[SERVER] Draining: readiness check fails and new sessions are refused")
+}
+
+// Draining reports whether BeginShutdown was called
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// flushBuffers waits up to timeout for the audit events still being written
+// and the events queued for the event bus, and saves the metered usage not
+// yet saved.
+func (s *Server) flushBuffers(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	auditDone := make(chan struct{})
+	go func() {
+		s.auditWrites.Wait()
+		close(auditDone)
+	}()
+	select {
+	case <-auditDone:
+	case <-ctx.Done():
+		log.Printf("[SERVER] Timed out waiting for audit events to be written")
+	}
+
+	if s.eventBus != nil {
+		if err := s.eventBus.Flush(ctx); err != nil {
+			log.Printf("[SERVER] Failed to flush event bus: %v", err)
+		}
+	}
+	if s.showback != nil {
+		if err := s.showback.meter.Flush(ctx); err != nil {
+			log.Printf("[SERVER] Failed to save metered usage: %v", err)
+		}
+	}
+	if s.usage != nil {
+		if err := s.usage.ledger.Flush(ctx); err != nil {
+			log.Printf("[SERVER] Failed to save session usage: %v", err)
+		}
+	}
+}
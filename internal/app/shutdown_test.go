@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestBeginShutdownFailsReadinessAndRefusesSessions(t *testing.T) {
+	server := NewServer(config.DefaultConfig(), false)
+
+	rec := httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /ready before shutdown = %d, want 200", rec.Code)
+	}
+
+	server.BeginShutdown()
+
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /ready while draining = %d, want 503", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.GetEcho().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /health while draining = %d, want 200", rec.Code)
+	}
+
+	_, err := server.sessionManager.CreateSession(context.Background(), "s1", &entities.RunServerRequest{UserID: "alice"}, nil)
+	if !errors.Is(err, entities.ErrShuttingDown) {
+		t.Errorf("CreateSession() while draining = %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestValidateShutdown(t *testing.T) {
+	for _, sc := range []config.ShutdownConfig{{}, {DrainDelay: "0s", GracePeriod: "30s"}} {
+		if err := validateShutdown(sc); err != nil {
+			t.Errorf("validateShutdown(%+v) = %v", sc, err)
+		}
+	}
+	for _, sc := range []config.ShutdownConfig{{DrainDelay: "-1s"}, {GracePeriod: "soon"}} {
+		if err := validateShutdown(sc); err == nil {
+			t.Errorf("validateShutdown(%+v) succeeded", sc)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// newEncryptionRegistry creates the registry of encryption services. The
+// primary service (AGENTAPI_ENCRYPTION_*) encrypts; the registry selects
+// the service to decrypt with from the encryption metadata of the data.
+func newEncryptionRegistry() (*services.EncryptionServiceRegistry, error) {
+	primaryService, err := services.NewEncryptionServiceFactory("AGENTAPI_ENCRYPTION").Create()
+	if err != nil {
+		return nil, err
+	}
+	registry := services.NewEncryptionServiceRegistry(primaryService)
+
+	// Register Noop service for backward compatibility with plaintext data
+	registry.Register(services.NewNoopEncryptionService())
+
+	// Data encrypted with an earlier key can still be decrypted
+	// (AGENTAPI_DECRYPTION_*) when it differs from the primary service.
+	if localService, err := services.NewEncryptionServiceFactory("AGENTAPI_DECRYPTION").Create(); err == nil {
+		if localService.Algorithm() != primaryService.Algorithm() || localService.KeyID() != primaryService.KeyID() {
+			registry.Register(localService)
+		}
+	}
+
+	log.Printf("[SERVER] Encryption registry initialized with primary: %s (keyID: %s)",
+		primaryService.Algorithm(), primaryService.KeyID())
+	return registry, nil
+}
+
+// memoryBackendName returns the name of a memory backend, "" meaning kubernetes.
+func memoryBackendName(backend string) string {
+	if backend == "" {
+		return "kubernetes"
+	}
+	return backend
+}
+
+// newMigratableMemoryRepository creates a memory repository of a backend
+// that can be migrated, i.e. whose repository lists every memory.
+func newMigratableMemoryRepository(ctx context.Context, backend string, cfg *config.MemoryConfig, client kubernetes.Interface, namespace string) (portrepos.MemoryRepository, error) {
+	switch memoryBackendName(backend) {
+	case "kubernetes":
+		return repositories.NewKubernetesMemoryRepository(client, namespace), nil
+	case "s3":
+		if cfg.S3 == nil {
+			return nil, fmt.Errorf("memory backend 's3' has no S3 configuration")
+		}
+		return repositories.NewS3MemoryRepository(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("memory backend %q cannot be migrated (supported: kubernetes, s3)", backend)
+	}
+}
+
+// validateMemoryMigration checks the memory migration of cfg.
+func validateMemoryMigration(cfg *config.MemoryConfig) error {
+	source := memoryBackendName(cfg.Backend)
+	target := cfg.Migration.TargetBackend
+	if source == target {
+		return fmt.Errorf("memory.migration.target_backend is the current backend %q", target)
+	}
+	for _, backend := range []string{source, target} {
+		if backend != "kubernetes" && backend != "s3" {
+			return fmt.Errorf("memory backend %q cannot be migrated (supported: kubernetes, s3)", backend)
+		}
+	}
+	return nil
+}
+
+// validateSessionStoreMigration checks the session store migration of cfg.
+// Only SQL session stores keep full session records that can be copied.
+func validateSessionStoreMigration(cfg *config.SessionStoreConfig) error {
+	for _, backend := range []string{cfg.Backend, cfg.Migration.TargetBackend} {
+		switch repositories.SQLDialect(backend) {
+		case repositories.SQLDialectPostgres, repositories.SQLDialectSQLite:
+		default:
+			return fmt.Errorf("session store backend %q cannot be migrated (supported: postgres, sqlite)", backend)
+		}
+	}
+	if cfg.Migration.TargetDSN == "" {
+		return fmt.Errorf("session_store.migration.target_dsn is required")
+	}
+	if cfg.Backend == cfg.Migration.TargetBackend && cfg.DSN == cfg.Migration.TargetDSN {
+		return fmt.Errorf("session_store.migration targets the current session store")
+	}
+	return nil
+}
+
+// dualWriteMemoryRepository wraps source in a dual-write repository when a
+// memory migration is configured.
+func dualWriteMemoryRepository(ctx context.Context, cfg *config.Config, source portrepos.MemoryRepository, client kubernetes.Interface, namespace string) (portrepos.MemoryRepository, error) {
+	if cfg.Memory.Migration.TargetBackend == "" {
+		return source, nil
+	}
+	if err := validateMemoryMigration(&cfg.Memory); err != nil {
+		return nil, err
+	}
+	target, err := newMigratableMemoryRepository(ctx, cfg.Memory.Migration.TargetBackend, &cfg.Memory, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return repositories.NewDualWriteMemoryRepository(source, target,
+		memoryBackendName(cfg.Memory.Backend), cfg.Memory.Migration.TargetBackend,
+		repositories.NewKubernetesStorageMigrationStateRepository(client, namespace)), nil
+}
+
+// dualWriteSessionRepository wraps source in a dual-write repository when a
+// session store migration is configured.
+func dualWriteSessionRepository(ctx context.Context, cfg *config.Config, source portrepos.SessionRepository, reg *services.EncryptionServiceRegistry, client kubernetes.Interface, namespace string) (portrepos.SessionRepository, error) {
+	migration := cfg.SessionStore.Migration
+	if migration.TargetBackend == "" {
+		return source, nil
+	}
+	if err := validateSessionStoreMigration(&cfg.SessionStore); err != nil {
+		return nil, err
+	}
+	target, err := newSQLSessionRepository(ctx, repositories.SQLDialect(migration.TargetBackend), migration.TargetDSN, "", reg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration target: %w", err)
+	}
+	return repositories.NewDualWriteSessionRepository(source, target,
+		cfg.SessionStore.Backend, migration.TargetBackend,
+		repositories.NewKubernetesStorageMigrationStateRepository(client, namespace)), nil
+}
+
+// StorageMigration is the migration of a store to the target backend
+// configured in its migration section. It is used by the
+// "agentapi-proxy admin storage-migration" command.
+type StorageMigration struct {
+	Store  string
+	Source string
+	Target string
+
+	states   *repositories.KubernetesStorageMigrationStateRepository
+	verify   func(ctx context.Context) (*repositories.StorageMigrationReport, error)
+	backfill func(ctx context.Context, deleteExtra bool) (*repositories.StorageMigrationReport, error)
+}
+
+// OpenStorageMigration opens the source and target backends of the
+// migration of store ("memory" or "session_store").
+func OpenStorageMigration(ctx context.Context, cfg *config.Config, store string, client kubernetes.Interface, namespace string) (*StorageMigration, error) {
+	m := &StorageMigration{
+		Store:  store,
+		states: repositories.NewKubernetesStorageMigrationStateRepository(client, namespace),
+	}
+	switch store {
+	case repositories.StorageMigrationMemory:
+		if cfg.Memory.Migration.TargetBackend == "" {
+			return nil, fmt.Errorf("memory.migration.target_backend is not set")
+		}
+		if err := validateMemoryMigration(&cfg.Memory); err != nil {
+			return nil, err
+		}
+		m.Source, m.Target = memoryBackendName(cfg.Memory.Backend), cfg.Memory.Migration.TargetBackend
+		source, err := newMigratableMemoryRepository(ctx, m.Source, &cfg.Memory, client, namespace)
+		if err != nil {
+			return nil, err
+		}
+		target, err := newMigratableMemoryRepository(ctx, m.Target, &cfg.Memory, client, namespace)
+		if err != nil {
+			return nil, err
+		}
+		m.verify = func(ctx context.Context) (*repositories.StorageMigrationReport, error) {
+			return repositories.VerifyMemoryMigration(ctx, source, target)
+		}
+		m.backfill = func(ctx context.Context, deleteExtra bool) (*repositories.StorageMigrationReport, error) {
+			return repositories.BackfillMemoryMigration(ctx, source, target, deleteExtra)
+		}
+	case repositories.StorageMigrationSessionStore:
+		migration := cfg.SessionStore.Migration
+		if migration.TargetBackend == "" {
+			return nil, fmt.Errorf("session_store.migration.target_backend is not set")
+		}
+		if err := validateSessionStoreMigration(&cfg.SessionStore); err != nil {
+			return nil, err
+		}
+		m.Source, m.Target = cfg.SessionStore.Backend, migration.TargetBackend
+		reg, err := newEncryptionRegistry()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encryption service: %w", err)
+		}
+		source, err := newSQLSessionRepository(ctx, repositories.SQLDialect(m.Source), cfg.SessionStore.DSN, "", reg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open migration source: %w", err)
+		}
+		target, err := newSQLSessionRepository(ctx, repositories.SQLDialect(m.Target), migration.TargetDSN, "", reg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open migration target: %w", err)
+		}
+		m.verify = func(ctx context.Context) (*repositories.StorageMigrationReport, error) {
+			return repositories.VerifySessionMigration(ctx, source, target)
+		}
+		m.backfill = func(ctx context.Context, deleteExtra bool) (*repositories.StorageMigrationReport, error) {
+			return repositories.BackfillSessionMigration(ctx, source, target, deleteExtra)
+		}
+	default:
+		return nil, fmt.Errorf("unknown store %q (supported: %s, %s)", store,
+			repositories.StorageMigrationMemory, repositories.StorageMigrationSessionStore)
+	}
+	return m, nil
+}
+
+// Verify compares the record counts and checksums of both backends.
+func (m *StorageMigration) Verify(ctx context.Context) (*repositories.StorageMigrationReport, error) {
+	return m.verify(ctx)
+}
+
+// Backfill copies the records the target lacks or holds differently, and
+// returns the report of the state before the copy.
+func (m *StorageMigration) Backfill(ctx context.Context, deleteExtra bool) (*repositories.StorageMigrationReport, error) {
+	return m.backfill(ctx, deleteExtra)
+}
+
+// Cutover switches reads to the target. It refuses to when the target does
+// not hold exactly the records of the source.
+func (m *StorageMigration) Cutover(ctx context.Context) (*repositories.StorageMigrationReport, error) {
+	report, err := m.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !report.OK() {
+		return report, fmt.Errorf("target does not match source: %d missing, %d mismatched, %d extra record(s)",
+			len(report.Missing), len(report.Mismatched), len(report.Extra))
+	}
+	return report, m.states.Set(ctx, m.Store, &repositories.StorageMigrationState{
+		Source:    m.Source,
+		Target:    m.Target,
+		CutOverAt: time.Now().UTC(),
+		Verified:  report,
+	})
+}
+
+// Rollback switches reads back to the source.
+func (m *StorageMigration) Rollback(ctx context.Context) error {
+	return m.states.Set(ctx, m.Store, nil)
+}
+
+// State returns the cutover state of the store, or nil before the cutover.
+func (m *StorageMigration) State(ctx context.Context) (*repositories.StorageMigrationState, error) {
+	return m.states.Get(ctx, m.Store)
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/repositories"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/leader"
+)
+
+const defaultTeamSyncInterval = 10 * time.Minute
+
+// startTeamSync syncs the GitHub team memberships of the configured teams
+// into repo in the background. Synced entries stay valid for three
+// intervals. It returns nil when the sync is disabled.
+func startTeamSync(tc config.GitHubTeamSyncConfig, provider *auth.GitHubAuthProvider, repo *repositories.KubernetesUserTeamMappingRepository, manager *services.KubernetesSessionManager, singletons *leader.Runner) *auth.TeamSync {
+	if !tc.Enabled {
+		return nil
+	}
+
+	interval := defaultTeamSyncInterval
+	if d, err := time.ParseDuration(tc.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	repo.SetSyncedTTL(3 * interval)
+
+	directory := github_pkg.NewAppTeamDirectory(manager.GitHubAppCredentials)
+	teamSync := auth.NewTeamSync(provider, directory, repo)
+	singletons.Go("github team sync", func(ctx context.Context) { teamSync.Run(ctx, interval) })
+	log.Printf("[TEAM_SYNC] Syncing GitHub team memberships every %s", interval)
+	return teamSync
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/transcript"
+)
+
+// registerTranscriptArchive archives the transcript of each session deleted
+// by manager into store. Deletion handlers run while the session agent is
+// still reachable, so the full conversation is captured.
+func registerTranscriptArchive(cfg *config.Config, manager *services.KubernetesSessionManager, store services.ArtifactStore) {
+	if !cfg.TranscriptArchive.Enabled {
+		return
+	}
+	if store == nil {
+		log.Printf("[TRANSCRIPT] The asset backend has no artifact support, transcript archiving disabled")
+		return
+	}
+	manager.AddSessionDeletedHandler(func(ctx context.Context, sess entities.Session) {
+		messages, err := manager.GetMessages(ctx, sess.ID())
+		if err != nil {
+			log.Printf("[TRANSCRIPT] Failed to get messages of deleted session %s, transcript not archived: %v", sess.ID(), err)
+			return
+		}
+		var body bytes.Buffer
+		if err := transcript.FromSession(sess, messages, time.Now()).Write(&body, transcript.FormatJSON); err != nil {
+			log.Printf("[TRANSCRIPT] Failed to render transcript of session %s: %v", sess.ID(), err)
+			return
+		}
+		if err := store.PutArtifact(ctx, transcript.ArchiveKey(sess.ID()), transcript.FormatJSON.ContentType(), &body); err != nil {
+			log.Printf("[TRANSCRIPT] Failed to archive transcript of session %s: %v", sess.ID(), err)
+			return
+		}
+		log.Printf("[TRANSCRIPT] Archived transcript of session %s (%d messages)", sess.ID(), len(messages))
+	})
+	log.Printf("[TRANSCRIPT] Transcripts of deleted sessions are archived")
+}
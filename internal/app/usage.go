@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+)
+
+const defaultUsageCollectInterval = time.Minute
+
+// usageService accounts the token usage and cost of sessions
+type usageService struct {
+	ledger  *usage.Ledger
+	budgets usage.Budgets
+}
+
+// validateUsage checks the collect interval and the budgets of usage
+func validateUsage(uc config.UsageConfig) error {
+	if uc.CollectInterval != "" {
+		if d, err := time.ParseDuration(uc.CollectInterval); err != nil || d <= 0 {
+			return fmt.Errorf("usage.collect_interval %q is not a valid duration", uc.CollectInterval)
+		}
+	}
+	seen := make(map[string]bool, len(uc.Budgets))
+	for i, b := range uc.Budgets {
+		switch {
+		case b.Team == "":
+			return fmt.Errorf("usage.budgets[%d].team is required", i)
+		case seen[b.Team]:
+			return fmt.Errorf("usage.budgets[%d]: team %q has more than one budget", i, b.Team)
+		case b.MonthlyTokens < 0 || b.MonthlyCostUSD < 0:
+			return fmt.Errorf("usage.budgets[%d]: limits must not be negative", i)
+		case b.MonthlyTokens == 0 && b.MonthlyCostUSD == 0:
+			return fmt.Errorf("usage.budgets[%d]: monthly_tokens or monthly_cost_usd is required", i)
+		}
+		seen[b.Team] = true
+	}
+	return nil
+}
+
+// buildUsage starts collecting the usage of the sessions of manager on the
+// leader and enforces the team budgets on new sessions. Returns nil when
+// usage accounting is disabled or the OpenTelemetry Collector of sessions,
+// which exports the usage, is not enabled.
+func buildUsage(cfg *config.Config, manager *services.KubernetesSessionManager, s *Server) *usageService {
+	uc := cfg.Usage
+	if !uc.Enabled {
+		return nil
+	}
+	if !cfg.KubernetesSession.OtelCollectorEnabled {
+		log.Printf("[USAGE] kubernetes_session.otel_collector_enabled is required, usage accounting disabled")
+		return nil
+	}
+
+	ledger := usage.NewLedger(usage.NewConfigMapStore(manager.GetClient(), manager.GetNamespace()))
+	list := func() []usage.Session {
+		sessions := manager.ListSessions(entities.SessionFilter{})
+		out := make([]usage.Session, 0, len(sessions))
+		for _, sess := range sessions {
+			ks, ok := sess.(*services.KubernetesSession)
+			if !ok || ks.Status() == "paused" {
+				continue
+			}
+			session := usage.Session{ID: ks.ID(), UserID: ks.UserID()}
+			if ks.Scope() == entities.ScopeTeam {
+				session.TeamID = ks.TeamID()
+			}
+			out = append(out, session)
+		}
+		return out
+	}
+	collector := usage.NewCollector(ledger, list, usage.NewHTTPScraper(manager.SessionMetricsURL).Scrape)
+
+	interval := defaultUsageCollectInterval
+	if d, err := time.ParseDuration(uc.CollectInterval); err == nil && d > 0 {
+		interval = d
+	}
+	s.singletons.Go("usage collector", func(ctx context.Context) { collector.Run(ctx, interval) })
+
+	svc := &usageService{ledger: ledger, budgets: make(usage.Budgets, len(uc.Budgets))}
+	for _, b := range uc.Budgets {
+		svc.budgets[b.Team] = usage.Budget{MonthlyTokens: b.MonthlyTokens, MonthlyCostUSD: b.MonthlyCostUSD}
+	}
+	if len(svc.budgets) > 0 {
+		manager.SetBudgetCheck(svc.checkBudget)
+	}
+
+	log.Printf("[USAGE] Collecting session usage every %s (%d team budget(s))", interval, len(svc.budgets))
+	return svc
+}
+
+// checkBudget refuses team sessions once their team reached a monthly
+// limit. Usage that cannot be read does not block sessions.
+func (u *usageService) checkBudget(ctx context.Context, req *entities.RunServerRequest) error {
+	if req.Scope != entities.ScopeTeam || req.TeamID == "" {
+		return nil
+	}
+	budget, ok := u.budgets.For(req.TeamID)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	used, err := u.ledger.TeamMonth(ctx, req.TeamID, now)
+	if err != nil {
+		log.Printf("[USAGE] Failed to read usage of team %s, budget not enforced: %v", req.TeamID, err)
+		return nil
+	}
+	if status := budget.Status(used, now); status.Exceeded {
+		return fmt.Errorf("%w: team %s: %s", entities.ErrBudgetExceeded, req.TeamID, status.Reason)
+	}
+	return nil
+}
+
+// newUsageController returns the usage endpoint, or nil when usage
+// accounting is disabled
+func newUsageController(u *usageService) *controllers.UsageController {
+	if u == nil {
+		return nil
+	}
+	return controllers.NewUsageController(u.ledger, u.budgets)
+}
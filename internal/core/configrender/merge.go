@@ -29,6 +29,12 @@ func MergeSessionConfigs(base, override *entities.WebhookSessionConfig) *entitie
 		result.SetParams(base.Params())
 	}
 
+	// Override mappings are applied after the base ones, so they win for the same target.
+	mappings := make([]entities.WebhookPayloadMapping, 0, len(base.PayloadMappings())+len(override.PayloadMappings()))
+	mappings = append(mappings, base.PayloadMappings()...)
+	mappings = append(mappings, override.PayloadMappings()...)
+	result.SetPayloadMappings(mappings)
+
 	result.SetReuseSession(base.ReuseSession() || override.ReuseSession())
 	result.SetMountPayload(base.MountPayload() || override.MountPayload())
 
@@ -0,0 +1,222 @@
+package configrender
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// MappedValues holds the session fields extracted from a payload by
+// WebhookPayloadMappings.
+type MappedValues struct {
+	Tags        map[string]string
+	Environment map[string]string
+	Vars        map[string]string
+	Repository  string
+	Branch      string
+}
+
+// ApplyPayloadMappings evaluates the JSONPath expression of every mapping
+// against the payload. Later mappings override earlier ones with the same
+// target. Mappings whose path does not match fall back to their default and
+// are skipped without one, unless they are required.
+func ApplyPayloadMappings(mappings []entities.WebhookPayloadMapping, payload map[string]interface{}) (*MappedValues, error) {
+	result := &MappedValues{
+		Tags:        make(map[string]string),
+		Environment: make(map[string]string),
+		Vars:        make(map[string]string),
+	}
+	for _, m := range mappings {
+		value, found, err := LookupJSONPath(payload, m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("payload mapping %q: %w", m.Path, err)
+		}
+		text := m.Default
+		if found {
+			text = stringifyPayloadValue(value)
+		} else if m.Default == "" {
+			if m.Required {
+				return nil, fmt.Errorf("payload mapping %q: required value not found in payload", m.Path)
+			}
+			continue
+		}
+
+		switch {
+		case m.Target == entities.PayloadMappingTargetRepository:
+			result.Repository = text
+		case m.Target == entities.PayloadMappingTargetBranch:
+			result.Branch = text
+		case strings.HasPrefix(m.Target, entities.PayloadMappingTargetTagPrefix):
+			result.Tags[strings.TrimPrefix(m.Target, entities.PayloadMappingTargetTagPrefix)] = text
+		case strings.HasPrefix(m.Target, entities.PayloadMappingTargetEnvPrefix):
+			result.Environment[strings.TrimPrefix(m.Target, entities.PayloadMappingTargetEnvPrefix)] = text
+		case strings.HasPrefix(m.Target, entities.PayloadMappingTargetVarPrefix):
+			result.Vars[strings.TrimPrefix(m.Target, entities.PayloadMappingTargetVarPrefix)] = text
+		default:
+			return nil, fmt.Errorf("payload mapping %q: unsupported target %q", m.Path, m.Target)
+		}
+	}
+	return result, nil
+}
+
+// TemplateData returns the data templates are rendered with: the payload
+// plus the mapped variables under "vars". The payload itself is not modified.
+func (v *MappedValues) TemplateData(payload map[string]interface{}) map[string]interface{} {
+	if v == nil || len(v.Vars) == 0 {
+		return payload
+	}
+	data := make(map[string]interface{}, len(payload)+1)
+	for k, val := range payload {
+		data[k] = val
+	}
+	vars := make(map[string]interface{}, len(v.Vars))
+	for k, val := range v.Vars {
+		vars[k] = val
+	}
+	data["vars"] = vars
+	return data
+}
+
+// MergeInto writes the mapped tags and environment variables over the given
+// ones. The repository and branch are stored as the "repository" and "branch"
+// tags, which select the repository a webhook session clones.
+func (v *MappedValues) MergeInto(tags, env map[string]string) {
+	if v == nil {
+		return
+	}
+	for k, val := range v.Tags {
+		tags[k] = val
+	}
+	for k, val := range v.Environment {
+		env[k] = val
+	}
+	if v.Repository != "" {
+		tags["repository"] = v.Repository
+	}
+	if v.Branch != "" {
+		tags["branch"] = v.Branch
+	}
+}
+
+// LookupJSONPath returns the value at a JSONPath expression. The supported
+// subset is the root "$", dot children ("$.a.b"), quoted bracket children
+// ("$['a-b']") and array indexes ("$.items[0]", negative indexes count from
+// the end). found is false when the path does not exist in the payload.
+func LookupJSONPath(payload map[string]interface{}, path string) (value interface{}, found bool, err error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var current interface{} = payload
+	for _, seg := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if seg.isIndex {
+				return nil, false, nil
+			}
+			next, ok := node[seg.key]
+			if !ok {
+				return nil, false, nil
+			}
+			current = next
+		case []interface{}:
+			if !seg.isIndex {
+				return nil, false, nil
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(node)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, false, nil
+			}
+			current = node[idx]
+		default:
+			return nil, false, nil
+		}
+	}
+	return current, true, nil
+}
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// ValidateJSONPath reports whether path is a JSONPath expression supported
+// by LookupJSONPath.
+func ValidateJSONPath(path string) error {
+	_, err := parseJSONPath(path)
+	return err
+}
+
+// parseJSONPath splits a JSONPath expression into its segments.
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath must start with $")
+	}
+	var segments []jsonPathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty member name in JSONPath %q", path)
+			}
+			segments = append(segments, jsonPathSegment{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket in JSONPath %q", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, jsonPathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported bracket expression [%s] in JSONPath %q", inner, path)
+			}
+			segments = append(segments, jsonPathSegment{index: idx, isIndex: true})
+		default:
+			return nil, fmt.Errorf("unexpected character %q in JSONPath %q", rest[0], path)
+		}
+	}
+	return segments, nil
+}
+
+// stringifyPayloadValue converts a decoded JSON value into the string stored
+// in a session field. Objects and arrays are JSON-encoded.
+func stringifyPayloadValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,122 @@
+package configrender
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func testPayload(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"alert": {"id": 4021, "firing": true, "labels": {"service": "billing", "team-name": "payments"}},
+		"source": {"repo": "acme/billing", "ref": "main"},
+		"items": [{"name": "first"}, {"name": "last"}]
+	}`), &payload)
+	if err != nil {
+		t.Fatalf("failed to parse payload: %v", err)
+	}
+	return payload
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	payload := testPayload(t)
+	tests := []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{path: "$.alert.labels.service", want: "billing", found: true},
+		{path: "$.alert.labels['team-name']", want: "payments", found: true},
+		{path: `$["source"]["repo"]`, want: "acme/billing", found: true},
+		{path: "$.items[0].name", want: "first", found: true},
+		{path: "$.items[-1].name", want: "last", found: true},
+		{path: "$.alert.id", want: "4021", found: true},
+		{path: "$.alert.firing", want: "true", found: true},
+		{path: "$.alert.labels", want: `{"service":"billing","team-name":"payments"}`, found: true},
+		{path: "$.items[5].name", found: false},
+		{path: "$.alert.missing", found: false},
+		{path: "$.alert.id.nested", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, found, err := LookupJSONPath(payload, tt.path)
+			if err != nil {
+				t.Fatalf("LookupJSONPath() error = %v", err)
+			}
+			if found != tt.found {
+				t.Fatalf("LookupJSONPath() found = %v, want %v", found, tt.found)
+			}
+			if found && stringifyPayloadValue(value) != tt.want {
+				t.Errorf("LookupJSONPath() = %q, want %q", stringifyPayloadValue(value), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJSONPath(t *testing.T) {
+	for _, path := range []string{"alert.id", "$.", "$.items[", "$.items[*]", "$..name"} {
+		if err := ValidateJSONPath(path); err == nil {
+			t.Errorf("ValidateJSONPath(%q) expected error", path)
+		}
+	}
+	if err := ValidateJSONPath("$"); err != nil {
+		t.Errorf("ValidateJSONPath($) error = %v", err)
+	}
+}
+
+func TestApplyPayloadMappings(t *testing.T) {
+	payload := testPayload(t)
+	mapped, err := ApplyPayloadMappings([]entities.WebhookPayloadMapping{
+		{Path: "$.alert.labels.service", Target: "tags.service"},
+		{Path: "$.alert.id", Target: "env.ALERT_ID"},
+		{Path: "$.alert.labels['team-name']", Target: "vars.team"},
+		{Path: "$.source.repo", Target: "repository"},
+		{Path: "$.source.ref", Target: "branch"},
+		{Path: "$.alert.severity", Target: "tags.severity", Default: "unknown"},
+		{Path: "$.alert.runbook", Target: "env.RUNBOOK"},
+	}, payload)
+	if err != nil {
+		t.Fatalf("ApplyPayloadMappings() error = %v", err)
+	}
+	if mapped.Tags["service"] != "billing" || mapped.Tags["severity"] != "unknown" {
+		t.Errorf("unexpected tags %v", mapped.Tags)
+	}
+	if mapped.Environment["ALERT_ID"] != "4021" {
+		t.Errorf("unexpected environment %v", mapped.Environment)
+	}
+	if _, ok := mapped.Environment["RUNBOOK"]; ok {
+		t.Errorf("missing optional value must be skipped, got %v", mapped.Environment)
+	}
+	if mapped.Repository != "acme/billing" || mapped.Branch != "main" {
+		t.Errorf("unexpected repository %q / branch %q", mapped.Repository, mapped.Branch)
+	}
+
+	rendered, err := RenderTemplate("Investigate {{.vars.team}} alert {{.alert.id}}", mapped.TemplateData(payload))
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if rendered != "Investigate payments alert 4021" {
+		t.Errorf("unexpected rendered message %q", rendered)
+	}
+	if _, ok := payload["vars"]; ok {
+		t.Error("TemplateData must not modify the payload")
+	}
+
+	tags, env := map[string]string{"repository": "old/repo"}, map[string]string{}
+	mapped.MergeInto(tags, env)
+	if tags["repository"] != "acme/billing" || tags["branch"] != "main" || env["ALERT_ID"] != "4021" {
+		t.Errorf("unexpected merged tags %v / env %v", tags, env)
+	}
+}
+
+func TestApplyPayloadMappings_RequiredValueMissing(t *testing.T) {
+	_, err := ApplyPayloadMappings([]entities.WebhookPayloadMapping{
+		{Path: "$.alert.runbook", Target: "env.RUNBOOK", Required: true},
+	}, testPayload(t))
+	if err == nil {
+		t.Fatal("expected error for missing required value")
+	}
+}
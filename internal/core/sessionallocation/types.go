@@ -27,7 +27,10 @@ type AllocationRequest struct {
 	Message            string                           `json:"message,omitempty"`
 	AllocatedSessionID string                           `json:"allocated_session_id,omitempty"`
 	Requirements       Requirements                     `json:"requirements"`
-	UpdatedAt          time.Time                        `json:"updated_at"`
+	// TraceParent links the allocation to the trace of the request that
+	// submitted it, so the allocator continues the same trace.
+	TraceParent string    `json:"trace_parent,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type AllocationResult struct {
@@ -0,0 +1,28 @@
+package entities
+
+import "errors"
+
+// AcceleratorParams requests accelerators such as GPUs for the session Pod.
+type AcceleratorParams struct {
+	// Resources are extended resource limits of the agent container, e.g.
+	// {"nvidia.com/gpu": "1"}.
+	Resources map[string]string `json:"resources,omitempty"`
+	// RuntimeClassName is the RuntimeClass of the session Pod, e.g. "nvidia".
+	RuntimeClassName string `json:"runtime_class_name,omitempty"`
+	// NodeAffinity requires nodes with these labels, each with one of the
+	// values. An empty list requires the label with any value.
+	NodeAffinity map[string][]string `json:"node_affinity,omitempty"`
+}
+
+// IsEmpty reports whether p requests nothing.
+func (p *AcceleratorParams) IsEmpty() bool {
+	return p == nil || (len(p.Resources) == 0 && p.RuntimeClassName == "" && len(p.NodeAffinity) == 0)
+}
+
+// ErrInvalidAccelerator is returned when a session requests a malformed
+// accelerator, e.g. a resource quantity that is not a positive integer.
+var ErrInvalidAccelerator = errors.New("invalid session accelerator")
+
+// ErrAcceleratorNotAllowed is returned when a session requests an
+// accelerator beyond the allowance of its team.
+var ErrAcceleratorNotAllowed = errors.New("session accelerator not allowed")
@@ -0,0 +1,76 @@
+package entities
+
+import "time"
+
+// AuditCategory groups audit events for compliance reporting.
+type AuditCategory string
+
+const (
+	// AuditCategoryAccess covers API calls that changed state and requests
+	// rejected by authentication or authorization.
+	AuditCategoryAccess AuditCategory = "access"
+	// AuditCategoryAdminAction covers state-changing calls made by administrators.
+	AuditCategoryAdminAction AuditCategory = "admin_action"
+	// AuditCategoryPolicyViolation covers requests rejected by a policy, such as
+	// unapproved session capabilities.
+	AuditCategoryPolicyViolation AuditCategory = "policy_violation"
+	// AuditCategorySecretRotation covers rotated tokens and keys.
+	AuditCategorySecretRotation AuditCategory = "secret_rotation"
+	// AuditCategoryRetention covers retention purges of audit data.
+	AuditCategoryRetention AuditCategory = "retention"
+)
+
+// AuditCategories lists all categories in report order.
+var AuditCategories = []AuditCategory{
+	AuditCategoryAccess,
+	AuditCategoryAdminAction,
+	AuditCategoryPolicyViolation,
+	AuditCategorySecretRotation,
+	AuditCategoryRetention,
+}
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeDenied  = "denied"
+	AuditOutcomeFailure = "failure"
+)
+
+// Operations of well-known mutating requests, recorded in AuditEvent.Operation
+const (
+	AuditOpSessionCreate    = "session.create"
+	AuditOpSessionDelete    = "session.delete"
+	AuditOpSessionExec      = "session.exec"
+	AuditOpTerminalAttach   = "session.terminal"
+	AuditOpEditorAttach     = "session.editor"
+	AuditOpBrowserAttach    = "session.browser"
+	AuditOpMessageSend      = "message.send"
+	AuditOpSettingsUpdate   = "settings.update"
+	AuditOpSettingsDelete   = "settings.delete"
+	AuditOpSecretCreate     = "secret.create"
+	AuditOpSecretUpdate     = "secret.update"
+	AuditOpSecretDelete     = "secret.delete"
+	AuditOpSecretRotate     = "secret.rotate"
+	AuditOpScheduleCreate   = "schedule.create"
+	AuditOpScheduleUpdate   = "schedule.update"
+	AuditOpScheduleDelete   = "schedule.delete"
+	AuditOpResourceTransfer = "resource.transfer"
+	AuditOpConfigReload     = "config.reload"
+	AuditOpSessionPreempt   = "session.preempt"
+)
+
+// AuditEvent is a security-relevant action kept for compliance audits.
+type AuditEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Category  AuditCategory `json:"category"`
+	// Action is the route (e.g. "DELETE /sessions/:sessionId") or a named
+	// operation (e.g. "capability_denied").
+	Action string `json:"action"`
+	// Operation names well-known requests independently of their route,
+	// e.g. "session.create".
+	Operation string `json:"operation,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	Resource  string `json:"resource,omitempty"`
+	Outcome   string `json:"outcome"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
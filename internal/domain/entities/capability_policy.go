@@ -0,0 +1,163 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionCapability names a risky session capability that must be approved
+// by a team admin before it can be requested.
+type SessionCapability string
+
+const (
+	// CapabilityTerminal is the web terminal (ttyd) sidecar
+	CapabilityTerminal SessionCapability = "terminal"
+	// CapabilityEditor is the in-browser editor (code-server) sidecar
+	CapabilityEditor SessionCapability = "editor"
+	// CapabilityDocker is the Docker-in-Docker sidecar
+	CapabilityDocker SessionCapability = "docker"
+	// CapabilityExec is exec into the session containers. It is not part of
+	// the launch request; sessions get it when their policy allows it.
+	CapabilityExec SessionCapability = "exec"
+)
+
+// GatedCapabilities lists every capability governed by CapabilityPolicy, in
+// the order they are reported.
+var GatedCapabilities = []SessionCapability{CapabilityDocker, CapabilityEditor, CapabilityExec, CapabilityTerminal}
+
+// ErrCapabilityNotAllowed is returned when a session requests a capability
+// its team's CapabilityPolicy does not allow.
+var ErrCapabilityNotAllowed = errors.New("session capability not allowed by policy")
+
+// IsGatedCapability reports whether c is governed by CapabilityPolicy
+func IsGatedCapability(c SessionCapability) bool {
+	for _, gated := range GatedCapabilities {
+		if c == gated {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestedCapabilities returns the gated capabilities enabled by req.
+// CapabilityExec is never requested.
+func RequestedCapabilities(req *RunServerRequest) []SessionCapability {
+	if req == nil {
+		return nil
+	}
+	var caps []SessionCapability
+	if req.Docker != nil && req.Docker.Enabled {
+		caps = append(caps, CapabilityDocker)
+	}
+	if req.Editor != nil && req.Editor.Enabled {
+		caps = append(caps, CapabilityEditor)
+	}
+	if req.Terminal != nil && req.Terminal.Enabled {
+		caps = append(caps, CapabilityTerminal)
+	}
+	return caps
+}
+
+// JoinCapabilities formats capabilities as a comma-separated list
+func JoinCapabilities(caps []SessionCapability) string {
+	names := make([]string, len(caps))
+	for i, c := range caps {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ",")
+}
+
+// CapabilityPolicy is the set of risky capabilities a team admin has
+// approved for sessions of a team (or, on the base settings, for
+// user-scoped sessions).
+type CapabilityPolicy struct {
+	allowed   []SessionCapability
+	updatedBy string
+	updatedAt time.Time
+}
+
+// NewCapabilityPolicy creates a CapabilityPolicy allowing the given
+// capabilities. Duplicates are dropped and the result is kept in
+// GatedCapabilities order.
+func NewCapabilityPolicy(allowed []SessionCapability) *CapabilityPolicy {
+	p := &CapabilityPolicy{updatedAt: time.Now()}
+	seen := make(map[SessionCapability]bool, len(allowed))
+	for _, c := range allowed {
+		seen[c] = true
+	}
+	for _, c := range GatedCapabilities {
+		if seen[c] {
+			p.allowed = append(p.allowed, c)
+			delete(seen, c)
+		}
+	}
+	// Keep unknown entries so that Validate can report them
+	for _, c := range allowed {
+		if seen[c] {
+			p.allowed = append(p.allowed, c)
+			delete(seen, c)
+		}
+	}
+	return p
+}
+
+// Allowed returns the approved capabilities
+func (p *CapabilityPolicy) Allowed() []SessionCapability {
+	return p.allowed
+}
+
+// Allows reports whether c is approved. A nil policy approves nothing.
+func (p *CapabilityPolicy) Allows(c SessionCapability) bool {
+	if p == nil {
+		return false
+	}
+	for _, allowed := range p.allowed {
+		if allowed == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Denied returns the capabilities in requested that the policy does not approve
+func (p *CapabilityPolicy) Denied(requested []SessionCapability) []SessionCapability {
+	var denied []SessionCapability
+	for _, c := range requested {
+		if !p.Allows(c) {
+			denied = append(denied, c)
+		}
+	}
+	return denied
+}
+
+// UpdatedBy returns the ID of the user who last changed the policy
+func (p *CapabilityPolicy) UpdatedBy() string {
+	return p.updatedBy
+}
+
+// SetUpdatedBy sets the ID of the user who last changed the policy
+func (p *CapabilityPolicy) SetUpdatedBy(userID string) {
+	p.updatedBy = userID
+}
+
+// UpdatedAt returns when the policy was last changed
+func (p *CapabilityPolicy) UpdatedAt() time.Time {
+	return p.updatedAt
+}
+
+// SetUpdatedAt sets when the policy was last changed
+func (p *CapabilityPolicy) SetUpdatedAt(t time.Time) {
+	p.updatedAt = t
+}
+
+// Validate validates the policy
+func (p *CapabilityPolicy) Validate() error {
+	for _, c := range p.allowed {
+		if !IsGatedCapability(c) {
+			return fmt.Errorf("unknown session capability %q", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequestedCapabilities(t *testing.T) {
+	req := &RunServerRequest{
+		Terminal: &TerminalParams{Enabled: true},
+		Editor:   &EditorParams{Enabled: false},
+		Docker:   &DockerParams{Enabled: true},
+		Browser:  &BrowserParams{Enabled: true},
+	}
+	got := RequestedCapabilities(req)
+	want := []SessionCapability{CapabilityDocker, CapabilityTerminal}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RequestedCapabilities() = %v, want %v", got, want)
+	}
+	if got := JoinCapabilities(got); got != "docker,terminal" {
+		t.Errorf("JoinCapabilities() = %q", got)
+	}
+	if got := RequestedCapabilities(nil); got != nil {
+		t.Errorf("RequestedCapabilities(nil) = %v, want nil", got)
+	}
+}
+
+func TestCapabilityPolicy(t *testing.T) {
+	p := NewCapabilityPolicy([]SessionCapability{CapabilityTerminal, CapabilityEditor, CapabilityTerminal})
+	if want := []SessionCapability{CapabilityEditor, CapabilityTerminal}; !reflect.DeepEqual(p.Allowed(), want) {
+		t.Errorf("Allowed() = %v, want %v", p.Allowed(), want)
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	denied := p.Denied([]SessionCapability{CapabilityDocker, CapabilityEditor})
+	if want := []SessionCapability{CapabilityDocker}; !reflect.DeepEqual(denied, want) {
+		t.Errorf("Denied() = %v, want %v", denied, want)
+	}
+
+	var nilPolicy *CapabilityPolicy
+	if nilPolicy.Allows(CapabilityEditor) {
+		t.Error("nil policy must not allow any capability")
+	}
+	if got := nilPolicy.Denied([]SessionCapability{CapabilityEditor}); len(got) != 1 {
+		t.Errorf("nil policy Denied() = %v", got)
+	}
+
+	if err := NewCapabilityPolicy([]SessionCapability{"sudo"}).Validate(); err == nil {
+		t.Error("Validate() should reject unknown capabilities")
+	}
+	if err := NewCapabilityPolicy([]SessionCapability{CapabilityExec}).Validate(); err != nil {
+		t.Errorf("Validate() with exec error = %v", err)
+	}
+
+	s := NewSettings("org/team")
+	s.SetCapabilityPolicy(NewCapabilityPolicy([]SessionCapability{"bogus"}))
+	if err := s.Validate(); err == nil {
+		t.Error("Settings.Validate() should reject an invalid capability policy")
+	}
+}
@@ -0,0 +1,37 @@
+package entities
+
+import "time"
+
+// Statuses of a GitHubReport
+const (
+	GitHubReportInProgress = "in_progress"
+	GitHubReportCompleted  = "completed"
+)
+
+// GitHubReport tracks the work of a session created from a GitHub webhook on
+// its repository: the working branch created for the session, the Check Run
+// that reports its progress and the pull request opened once the agent has
+// finished.
+type GitHubReport struct {
+	// Repository is the full name of the repository ("owner/repo")
+	Repository string `json:"repository"`
+	// BaseBranch is the branch the working branch was created from and the
+	// pull request targets
+	BaseBranch string `json:"base_branch"`
+	// Branch is the working branch of the session
+	Branch string `json:"branch"`
+	// HeadSHA is the commit the Check Run reports on
+	HeadSHA    string `json:"head_sha"`
+	CheckRunID int64  `json:"check_run_id,omitempty"`
+	// Title is the title of the pull request
+	Title string `json:"title,omitempty"`
+	// Status is in_progress until the session has finished, then completed
+	Status string `json:"status"`
+	// Conclusion is the conclusion of the Check Run: success, neutral (no
+	// changes), failure or cancelled
+	Conclusion        string     `json:"conclusion,omitempty"`
+	PullRequestNumber int        `json:"pull_request_number,omitempty"`
+	PullRequestURL    string     `json:"pull_request_url,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
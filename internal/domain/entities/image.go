@@ -0,0 +1,11 @@
+package entities
+
+import "errors"
+
+// ErrInvalidImage is returned when a session requests a malformed container
+// image reference.
+var ErrInvalidImage = errors.New("invalid session image")
+
+// ErrImageNotAllowed is returned when a session requests a container image
+// that is not on the image allowlist.
+var ErrImageNotAllowed = errors.New("session image not allowed")
@@ -0,0 +1,100 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// ImageRolloutState is the state of an image rollout.
+type ImageRolloutState string
+
+const (
+	// ImageRolloutRunning means outdated sessions are still being upgraded.
+	ImageRolloutRunning ImageRolloutState = "running"
+	// ImageRolloutCompleted means no session is left to upgrade.
+	ImageRolloutCompleted ImageRolloutState = "completed"
+	// ImageRolloutCancelled means an administrator stopped the rollout.
+	ImageRolloutCancelled ImageRolloutState = "cancelled"
+)
+
+// ImageRolloutSessionState is the state of one session in an image rollout.
+type ImageRolloutSessionState string
+
+const (
+	// ImageRolloutSessionWaiting means the session is busy or not idle long
+	// enough yet; Reason says which.
+	ImageRolloutSessionWaiting ImageRolloutSessionState = "waiting"
+	// ImageRolloutSessionUpgrading means the new Pod of the session is
+	// starting.
+	ImageRolloutSessionUpgrading ImageRolloutSessionState = "upgrading"
+	// ImageRolloutSessionUpgraded means the session runs the new image.
+	ImageRolloutSessionUpgraded ImageRolloutSessionState = "upgraded"
+	// ImageRolloutSessionFailed means the upgrade failed; Reason has why.
+	ImageRolloutSessionFailed ImageRolloutSessionState = "failed"
+	// ImageRolloutSessionSkipped means the session keeps its image, e.g.
+	// because its team is pinned.
+	ImageRolloutSessionSkipped ImageRolloutSessionState = "skipped"
+)
+
+var (
+	// ErrImageRolloutInProgress is returned when a rollout is started while
+	// another one is running.
+	ErrImageRolloutInProgress = errors.New("an image rollout is already running")
+	// ErrInvalidImageRollout is returned for a malformed rollout request.
+	ErrInvalidImageRollout = errors.New("invalid image rollout")
+	// ErrImageRolloutNotFound is returned when no rollout was ever started.
+	ErrImageRolloutNotFound = errors.New("image rollout not found")
+	// ErrImageRolloutUnsupported is returned when sessions do not run a
+	// Deployment whose image could be changed.
+	ErrImageRolloutUnsupported = errors.New("image rollouts need kubernetes_session.pvc_enabled")
+)
+
+// ImageRolloutRequest starts an image rollout. Empty fields default to
+// kubernetes_session.image_rollout.
+type ImageRolloutRequest struct {
+	// MaxConcurrent is the number of sessions upgraded at a time
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// MinIdle is how long a session must have been idle to be upgraded
+	MinIdle string `json:"min_idle,omitempty"`
+	// PinnedTeams keep their sessions on their image in this rollout, in
+	// addition to the teams pinned in kubernetes_session.team_images
+	PinnedTeams []string `json:"pinned_teams,omitempty"`
+}
+
+// ImageRollout moves running sessions to the image the config currently
+// gives them.
+type ImageRollout struct {
+	ID            string            `json:"id"`
+	State         ImageRolloutState `json:"state"`
+	StartedBy     string            `json:"started_by"`
+	StartedAt     time.Time         `json:"started_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+	MaxConcurrent int               `json:"max_concurrent"`
+	MinIdle       string            `json:"min_idle"`
+	PinnedTeams   []string          `json:"pinned_teams,omitempty"`
+	// Sessions are the sessions that ran an outdated image
+	Sessions []ImageRolloutSession `json:"sessions"`
+	// Counts are the number of sessions in each state
+	Counts map[ImageRolloutSessionState]int `json:"counts"`
+}
+
+// ImageRolloutSession is a session in an image rollout.
+type ImageRolloutSession struct {
+	SessionID string                   `json:"session_id"`
+	UserID    string                   `json:"user_id,omitempty"`
+	TeamID    string                   `json:"team_id,omitempty"`
+	FromImage string                   `json:"from_image"`
+	ToImage   string                   `json:"to_image"`
+	State     ImageRolloutSessionState `json:"state"`
+	Reason    string                   `json:"reason,omitempty"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// Count recomputes Counts from Sessions.
+func (r *ImageRollout) Count() {
+	r.Counts = make(map[ImageRolloutSessionState]int)
+	for _, s := range r.Sessions {
+		r.Counts[s.State]++
+	}
+}
@@ -2,9 +2,19 @@ package entities
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
 	"sort"
 )
 
+// mcpServerNamePattern is the form of MCP server names: they become keys of
+// the agent's MCP configuration and appear in tool names
+var mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// envVarNamePattern is the form of environment variable names
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // MCPServer represents a single MCP server configuration
 type MCPServer struct {
 	name    string
@@ -14,6 +24,7 @@ type MCPServer struct {
 	args    []string          // for stdio
 	env     map[string]string // environment variables (may contain secrets)
 	headers map[string]string // for http/sse (may contain secrets)
+	enabled bool              // disabled servers are kept but not given to sessions
 }
 
 // NewMCPServer creates a new MCPServer
@@ -24,6 +35,7 @@ func NewMCPServer(name string, serverType string) *MCPServer {
 		args:    []string{},
 		env:     make(map[string]string),
 		headers: make(map[string]string),
+		enabled: true,
 	}
 }
 
@@ -62,6 +74,16 @@ func (s *MCPServer) Headers() map[string]string {
 	return s.headers
 }
 
+// Enabled returns whether sessions get the server
+func (s *MCPServer) Enabled() bool {
+	return s.enabled
+}
+
+// SetEnabled sets whether sessions get the server
+func (s *MCPServer) SetEnabled(enabled bool) {
+	s.enabled = enabled
+}
+
 // SetURL sets the URL
 func (s *MCPServer) SetURL(url string) {
 	s.url = url
@@ -114,12 +136,30 @@ func (s *MCPServer) Validate() error {
 		if s.url == "" {
 			return errors.New("url is required for http/sse server")
 		}
+		if u, err := url.Parse(s.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("url must be an http or https URL")
+		}
 	case "":
 		return errors.New("server type is required")
 	default:
 		return errors.New("invalid server type: must be stdio, http, or sse")
 	}
 
+	for key := range s.env {
+		if !envVarNamePattern.MatchString(key) {
+			return fmt.Errorf("invalid environment variable name: %q", key)
+		}
+	}
+
+	return nil
+}
+
+// ValidateMCPServerName checks that name can be used as the name of a new
+// MCP server
+func ValidateMCPServerName(name string) error {
+	if !mcpServerNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid server name %q: use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
 	return nil
 }
 
@@ -149,6 +189,49 @@ func (s *MCPServer) HeaderKeys() []string {
 	return keys
 }
 
+// ErrMCPServerNotAllowed is returned when a session creation request
+// attaches an MCP server that is not on the allowlist of the proxy
+var ErrMCPServerNotAllowed = errors.New("MCP server not allowed")
+
+// RequestMCPServer is an MCP server attached to a single session by the
+// mcp_servers block of its creation request
+type RequestMCPServer struct {
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// RequestMCPServersSettings validates the MCP servers of a creation request
+// and converts them. Returns nil when there are none.
+func RequestMCPServersSettings(servers map[string]*RequestMCPServer) (*MCPServersSettings, error) {
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	result := NewMCPServersSettings()
+	for name, item := range servers {
+		if item == nil {
+			return nil, fmt.Errorf("mcp_servers.%s: server configuration is required", name)
+		}
+		if err := ValidateMCPServerName(name); err != nil {
+			return nil, fmt.Errorf("mcp_servers: %w", err)
+		}
+		server := NewMCPServer(name, item.Type)
+		server.SetURL(item.URL)
+		server.SetCommand(item.Command)
+		server.SetArgs(item.Args)
+		server.SetEnv(item.Env)
+		server.SetHeaders(item.Headers)
+		if err := server.Validate(); err != nil {
+			return nil, fmt.Errorf("mcp_servers.%s: %w", name, err)
+		}
+		result.SetServer(name, server)
+	}
+	return result, nil
+}
+
 // MCPServersSettings represents MCP servers configuration
 type MCPServersSettings struct {
 	servers map[string]*MCPServer
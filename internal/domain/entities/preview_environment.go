@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// PreviewStatus is the lifecycle state of a preview environment.
+type PreviewStatus string
+
+const (
+	// PreviewStatusDeploying means the deploy hook has not finished yet.
+	PreviewStatusDeploying PreviewStatus = "deploying"
+	// PreviewStatusReady means the branch is deployed and URL is reachable.
+	PreviewStatusReady PreviewStatus = "ready"
+	// PreviewStatusFailed means the deploy hook failed; Message has the reason.
+	PreviewStatusFailed PreviewStatus = "failed"
+)
+
+var (
+	// ErrPreviewDisabled is returned when no deploy hook is configured.
+	ErrPreviewDisabled = errors.New("preview environments are not enabled")
+	// ErrPreviewNotFound is returned when the session has no preview environment.
+	ErrPreviewNotFound = errors.New("preview environment not found")
+	// ErrPreviewDeploying is returned when a deploy of the preview
+	// environment is still running.
+	ErrPreviewDeploying = errors.New("preview environment is still deploying")
+	// ErrPreviewBranchRequired is returned when neither the request nor the
+	// session repository names a branch to deploy.
+	ErrPreviewBranchRequired = errors.New("branch is required")
+)
+
+// PreviewEnvironment is an ephemeral environment deployed from the branch of
+// a session into a namespace of its own. It is deleted with the session.
+type PreviewEnvironment struct {
+	Namespace  string        `json:"namespace"`
+	Repository string        `json:"repository,omitempty"`
+	Branch     string        `json:"branch"`
+	Status     PreviewStatus `json:"status"`
+	URL        string        `json:"url,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// CreatePreviewRequest requests a preview environment for a session.
+// Branch defaults to the branch of the session repository.
+type CreatePreviewRequest struct {
+	Branch string `json:"branch,omitempty"`
+}
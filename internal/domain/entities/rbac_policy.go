@@ -0,0 +1,178 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Action names an operation governed by an RBACPolicy
+type Action string
+
+const (
+	ActionSessionCreate    Action = "session:create"
+	ActionSessionDelete    Action = "session:delete"
+	ActionSessionList      Action = "session:list"
+	ActionSessionLogs      Action = "session:logs"
+	ActionSessionExec      Action = "session:exec"
+	ActionTeamConfigManage Action = "team_config:manage"
+	ActionScheduleManage   Action = "schedule:manage"
+
+	// ActionAll grants every action
+	ActionAll Action = "*"
+)
+
+// Actions lists every action governed by an RBACPolicy
+var Actions = []Action{
+	ActionSessionCreate,
+	ActionSessionDelete,
+	ActionSessionList,
+	ActionSessionLogs,
+	ActionSessionExec,
+	ActionTeamConfigManage,
+	ActionScheduleManage,
+}
+
+// RBACRoles lists the roles an RBACPolicy assigns actions to, from the most
+// to the least privileged.
+var RBACRoles = []Role{RoleAdmin, RoleTeamAdmin, RoleMember, RoleViewer}
+
+// ErrActionNotAllowed is returned when the role of a user does not allow an action
+var ErrActionNotAllowed = errors.New("action not allowed by role policy")
+
+// IsAction reports whether a is a known action or ActionAll
+func IsAction(a Action) bool {
+	if a == ActionAll {
+		return true
+	}
+	for _, known := range Actions {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthzResource describes what an action is performed on
+type AuthzResource struct {
+	Scope   ResourceScope
+	TeamID  string
+	OwnerID UserID
+}
+
+// RBACPolicy maps roles to the actions they may perform
+type RBACPolicy struct {
+	roles map[Role][]Action
+}
+
+// NewRBACPolicy creates an RBACPolicy from role to action assignments
+func NewRBACPolicy(roles map[Role][]Action) *RBACPolicy {
+	p := &RBACPolicy{roles: make(map[Role][]Action, len(roles))}
+	for role, actions := range roles {
+		p.roles[role] = append([]Action(nil), actions...)
+	}
+	return p
+}
+
+// DefaultRBACPolicy returns the built-in policy: admins may do everything,
+// team admins additionally manage team configuration, members work with
+// sessions and schedules, and viewers only list sessions and read logs.
+func DefaultRBACPolicy() *RBACPolicy {
+	member := []Action{
+		ActionSessionCreate,
+		ActionSessionDelete,
+		ActionSessionList,
+		ActionSessionLogs,
+		ActionSessionExec,
+		ActionScheduleManage,
+	}
+	return NewRBACPolicy(map[Role][]Action{
+		RoleAdmin:     {ActionAll},
+		RoleTeamAdmin: append(append([]Action(nil), member...), ActionTeamConfigManage),
+		RoleMember:    member,
+		RoleViewer:    {ActionSessionList, ActionSessionLogs},
+	})
+}
+
+// Validate checks that the policy only names known actions
+func (p *RBACPolicy) Validate() error {
+	for role, actions := range p.roles {
+		for _, a := range actions {
+			if !IsAction(a) {
+				return fmt.Errorf("role %q: unknown action %q", role, a)
+			}
+		}
+	}
+	return nil
+}
+
+// Actions returns the actions assigned to role
+func (p *RBACPolicy) Actions(role Role) []Action {
+	if p == nil {
+		return nil
+	}
+	return p.roles[role]
+}
+
+// Allows reports whether role may perform action. A nil policy allows nothing.
+func (p *RBACPolicy) Allows(role Role, action Action) bool {
+	for _, a := range p.Actions(role) {
+		if a == action || a == ActionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Override returns a copy of p in which the roles defined by o replace those
+// of p. Roles o does not define keep their actions from p.
+func (p *RBACPolicy) Override(o *RBACPolicy) *RBACPolicy {
+	merged := NewRBACPolicy(nil)
+	if p != nil {
+		for role, actions := range p.roles {
+			merged.roles[role] = actions
+		}
+	}
+	if o != nil {
+		for role, actions := range o.roles {
+			merged.roles[role] = actions
+		}
+	}
+	return merged
+}
+
+// RoleFor returns the role the user holds for resources of teamID, or for
+// personal resources when teamID is empty. Global admins are admins
+// everywhere; a "viewer" or "readonly" role caps the user at viewer. In a
+// team, GitHub maintainers and users with the "team-admin" role are team
+// admins and other members are members; non-members hold no role, which
+// is returned as an empty Role.
+func (u *User) RoleFor(teamID string) Role {
+	if u.IsAdmin() {
+		return RoleAdmin
+	}
+	viewer, teamAdmin := false, false
+	for _, role := range u.roles {
+		switch role {
+		case RoleViewer, RoleReadOnly:
+			viewer = true
+		case RoleTeamAdmin:
+			teamAdmin = true
+		}
+	}
+
+	if teamID != "" {
+		switch {
+		case !u.IsMemberOfTeam(teamID):
+			return ""
+		case viewer:
+			return RoleViewer
+		case teamAdmin || u.IsTeamAdmin(teamID):
+			return RoleTeamAdmin
+		}
+		return RoleMember
+	}
+	if viewer {
+		return RoleViewer
+	}
+	return RoleMember
+}
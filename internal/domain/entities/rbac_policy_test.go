@@ -0,0 +1,87 @@
+package entities
+
+import "testing"
+
+func TestRBACPolicy_Allows(t *testing.T) {
+	p := DefaultRBACPolicy()
+	tests := []struct {
+		role   Role
+		action Action
+		want   bool
+	}{
+		{RoleAdmin, ActionTeamConfigManage, true},
+		{RoleTeamAdmin, ActionTeamConfigManage, true},
+		{RoleMember, ActionTeamConfigManage, false},
+		{RoleMember, ActionSessionExec, true},
+		{RoleViewer, ActionSessionLogs, true},
+		{RoleViewer, ActionSessionCreate, false},
+		{"", ActionSessionList, false},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(tt.role, tt.action); got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.role, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestRBACPolicy_Override(t *testing.T) {
+	base := DefaultRBACPolicy()
+	merged := base.Override(NewRBACPolicy(map[Role][]Action{
+		RoleMember: {ActionSessionList},
+	}))
+	if merged.Allows(RoleMember, ActionSessionCreate) {
+		t.Error("override should replace the member actions")
+	}
+	if !merged.Allows(RoleViewer, ActionSessionLogs) {
+		t.Error("roles not overridden should keep their actions")
+	}
+	if !base.Allows(RoleMember, ActionSessionCreate) {
+		t.Error("Override must not modify the base policy")
+	}
+
+	if err := NewRBACPolicy(map[Role][]Action{RoleMember: {"session:fly"}}).Validate(); err == nil {
+		t.Error("expected unknown action to fail validation")
+	}
+}
+
+func TestUser_RoleFor(t *testing.T) {
+	member := NewUser("alice", UserTypeGitHub, "alice")
+	member.SetGitHubInfo(NewGitHubUserInfo(1, "alice", "", "", "", "", ""), []GitHubTeamMembership{
+		{Organization: "org", TeamSlug: "dev", Role: "member"},
+		{Organization: "org", TeamSlug: "ops", Role: "maintainer"},
+	})
+	viewer := NewUser("bob", UserTypeGitHub, "bob")
+	viewer.SetGitHubInfo(NewGitHubUserInfo(2, "bob", "", "", "", "", ""), []GitHubTeamMembership{
+		{Organization: "org", TeamSlug: "ops", Role: "maintainer"},
+	})
+	if err := viewer.SetRoles([]Role{RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+	admin := NewUser("root", UserTypeGitHub, "root")
+	if err := admin.SetRoles([]Role{RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		user   *User
+		teamID string
+		want   Role
+	}{
+		{"personal member", member, "", RoleMember},
+		{"team member", member, "org/dev", RoleMember},
+		{"team maintainer", member, "org/ops", RoleTeamAdmin},
+		{"non member", member, "org/other", ""},
+		{"viewer caps maintainer", viewer, "org/ops", RoleViewer},
+		{"viewer personal", viewer, "", RoleViewer},
+		{"admin", admin, "org/other", RoleAdmin},
+		{"service account", NewServiceAccountUser("sa", "org/dev", nil), "org/dev", RoleMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.RoleFor(tt.teamID); got != tt.want {
+				t.Errorf("RoleFor(%q) = %q, want %q", tt.teamID, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaxSavedSearches caps the number of saved searches of a user
+const MaxSavedSearches = 50
+
+var savedSearchNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// SavedSearch is a named session filter of a user. It can be referenced by
+// name in GET /search and in the user's notification routing.
+type SavedSearch struct {
+	Name string `json:"name"`
+	// Status matches the session status
+	Status string `json:"status,omitempty"`
+	// Scope matches "user" or "team" sessions
+	Scope ResourceScope `json:"scope,omitempty"`
+	// TeamIDs matches sessions of any of these teams
+	TeamIDs []string `json:"team_ids,omitempty"`
+	// Tags matches sessions having all of these tags
+	Tags map[string]string `json:"tags,omitempty"`
+	// Query matches sessions whose description contains it, ignoring case
+	Query string `json:"query,omitempty"`
+}
+
+// Validate checks the name and filters of the saved search
+func (s *SavedSearch) Validate() error {
+	if !savedSearchNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("name must be 1-64 letters, digits, '_', '.' or '-' and start with a letter or digit")
+	}
+	switch s.Scope {
+	case "", ScopeUser, ScopeTeam:
+	default:
+		return fmt.Errorf("scope must be %q or %q", ScopeUser, ScopeTeam)
+	}
+	if s.Scope == ScopeUser && len(s.TeamIDs) > 0 {
+		return fmt.Errorf("team_ids cannot be combined with scope %q", ScopeUser)
+	}
+	return nil
+}
+
+// EffectiveScope returns the scope the search applies to: team when it
+// filters by team, user by default
+func (s *SavedSearch) EffectiveScope() ResourceScope {
+	if s.Scope != "" {
+		return s.Scope
+	}
+	if len(s.TeamIDs) > 0 {
+		return ScopeTeam
+	}
+	return ScopeUser
+}
+
+// Matches reports whether the session passes all filters of the search
+func (s *SavedSearch) Matches(session Session) bool {
+	if s.Status != "" && session.Status() != s.Status {
+		return false
+	}
+	if session.Scope() != s.EffectiveScope() && !(session.Scope() == "" && s.EffectiveScope() == ScopeUser) {
+		return false
+	}
+	if len(s.TeamIDs) > 0 && !slices.Contains(s.TeamIDs, session.TeamID()) {
+		return false
+	}
+	tags := session.Tags()
+	for k, v := range s.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+	if s.Query != "" && !strings.Contains(strings.ToLower(session.Description()), strings.ToLower(s.Query)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package entities
+
+import "testing"
+
+type savedSearchSession struct {
+	Session
+	status      string
+	scope       ResourceScope
+	teamID      string
+	tags        map[string]string
+	description string
+}
+
+func (s savedSearchSession) Status() string          { return s.status }
+func (s savedSearchSession) Scope() ResourceScope    { return s.scope }
+func (s savedSearchSession) TeamID() string          { return s.teamID }
+func (s savedSearchSession) Tags() map[string]string { return s.tags }
+func (s savedSearchSession) Description() string     { return s.description }
+
+func TestSavedSearchValidate(t *testing.T) {
+	for _, s := range []SavedSearch{
+		{Name: "mine"},
+		{Name: "team.infra-1", TeamIDs: []string{"org/infra"}},
+		{Name: "active", Scope: ScopeTeam, Status: "active"},
+	} {
+		if err := s.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", s, err)
+		}
+	}
+	for _, s := range []SavedSearch{
+		{Name: ""},
+		{Name: "-leading"},
+		{Name: "with space"},
+		{Name: "x", Scope: "org"},
+		{Name: "x", Scope: ScopeUser, TeamIDs: []string{"org/infra"}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", s)
+		}
+	}
+}
+
+func TestSavedSearchMatches(t *testing.T) {
+	session := savedSearchSession{
+		status:      "active",
+		scope:       ScopeTeam,
+		teamID:      "org/infra",
+		tags:        map[string]string{"env": "prod", "repo": "api"},
+		description: "Fix Login flow",
+	}
+	for _, tc := range []struct {
+		search SavedSearch
+		want   bool
+	}{
+		{SavedSearch{TeamIDs: []string{"org/infra"}, Status: "active", Tags: map[string]string{"env": "prod"}, Query: "login"}, true},
+		{SavedSearch{Scope: ScopeTeam}, true},
+		{SavedSearch{}, false},
+		{SavedSearch{TeamIDs: []string{"org/web"}}, false},
+		{SavedSearch{Scope: ScopeTeam, Status: "stopped"}, false},
+		{SavedSearch{Scope: ScopeTeam, Tags: map[string]string{"env": "dev"}}, false},
+		{SavedSearch{Scope: ScopeTeam, Query: "logout"}, false},
+	} {
+		if got := tc.search.Matches(session); got != tc.want {
+			t.Errorf("%+v.Matches() = %v, want %v", tc.search, got, tc.want)
+		}
+	}
+
+	if !(&SavedSearch{}).Matches(savedSearchSession{}) {
+		t.Error("a session without scope must match a user search")
+	}
+}
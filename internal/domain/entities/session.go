@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
@@ -50,14 +51,40 @@ type SandboxParams struct {
 	CountMode bool `json:"count_mode,omitempty"`
 }
 
+// Docker sidecar modes selectable through DockerParams.Mode.
+const (
+	// DockerModeDinD runs a privileged Docker daemon sidecar (the default)
+	DockerModeDinD = "dind"
+	// DockerModeBuildKit runs a rootless BuildKit daemon sidecar
+	DockerModeBuildKit = "buildkit"
+)
+
 // DockerParams holds Docker-in-Docker (DinD) configuration for session creation.
 type DockerParams struct {
 	// Enabled activates the DinD sidecar for this session
 	Enabled bool `json:"enabled,omitempty"`
+	// Mode selects the sidecar: "dind" (default) or "buildkit". BuildKit runs
+	// rootless and can build images but not run containers.
+	Mode string `json:"mode,omitempty"`
 	// Registries specifies authenticated container registries
 	Registries []DockerRegistry `json:"registries,omitempty"`
 }
 
+// BuildKit reports whether the session uses the rootless BuildKit sidecar
+func (p *DockerParams) BuildKit() bool {
+	return p != nil && p.Enabled && p.Mode == DockerModeBuildKit
+}
+
+// Validate validates the Docker parameters
+func (p *DockerParams) Validate() error {
+	switch p.Mode {
+	case "", DockerModeDinD, DockerModeBuildKit:
+		return nil
+	default:
+		return fmt.Errorf("invalid docker mode %q: must be %q or %q", p.Mode, DockerModeDinD, DockerModeBuildKit)
+	}
+}
+
 // DockerRegistry holds authentication for a container registry.
 type DockerRegistry struct {
 	// Server is the registry server address (e.g., "ghcr.io", "registry.example.com")
@@ -73,6 +100,29 @@ type DockerRegistry struct {
 	Insecure bool `json:"insecure,omitempty"`
 }
 
+// EditorParams holds configuration for the in-browser editor (code-server) sidecar.
+type EditorParams struct {
+	// Enabled runs a code-server sidecar bound to the session workdir,
+	// reachable through /sessions/{id}/editor/.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// BrowserParams holds configuration for the headless browser sidecar used by
+// agents that perform browser automation.
+type BrowserParams struct {
+	// Enabled runs a headless browser sidecar whose display is reachable
+	// through /sessions/{id}/browser/ and whose downloads land in the
+	// workdir downloads directory.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// TerminalParams holds configuration for the web terminal (ttyd) sidecar.
+type TerminalParams struct {
+	// Enabled runs a terminal sidecar in the session workdir that is
+	// reachable through /sessions/{id}/terminal/.
+	Enabled bool `json:"enabled,omitempty"`
+}
+
 // SessionParams represents session parameters for agentapi server
 type SessionParams struct {
 	// Message is the initial message to send to the agent after session starts
@@ -109,6 +159,12 @@ type SessionParams struct {
 	Sandbox *SandboxParams `json:"sandbox,omitempty"`
 	// Docker configures Docker-in-Docker (DinD) for the session.
 	Docker *DockerParams `json:"docker,omitempty"`
+	// Editor configures the in-browser editor (code-server) sidecar for the session.
+	Editor *EditorParams `json:"editor,omitempty"`
+	// Browser configures the headless browser (noVNC) sidecar for the session.
+	Browser *BrowserParams `json:"browser,omitempty"`
+	// Terminal configures the web terminal (ttyd) sidecar for the session.
+	Terminal *TerminalParams `json:"terminal,omitempty"`
 	// AuthProxy controls whether the session auth proxy sidecar is injected.
 	// nil means use the global server configuration.
 	AuthProxy *bool `json:"auth_proxy,omitempty"`
@@ -123,6 +179,27 @@ type SessionParams struct {
 	// Valid values are "session_user", "team", and "none". Empty preserves the
 	// legacy behavior (session user for user scope, none for team scope).
 	CredentialSource string `json:"credential_source,omitempty"`
+	// SetupHooks are run in order after the repository is cloned and before the
+	// agent starts. Session profiles use them to bootstrap their repositories.
+	SetupHooks []SetupHook `json:"setup_hooks,omitempty"`
+	// PostSessionHooks are run in the session container when a oneshot session
+	// is deleted, before its resources are removed. Their output is kept as
+	// artifacts. OnFailure is ignored: a failing hook never blocks deletion.
+	PostSessionHooks []SetupHook `json:"post_session_hooks,omitempty"`
+	// Replicas runs the session as that many interchangeable Pods behind one
+	// logical session. Values above 1 are only allowed for agent types
+	// configured as stateless; requests are pinned to a replica by an
+	// affinity key. 0 means 1.
+	Replicas int `json:"replicas,omitempty"`
+	// Accelerator requests extended resources such as GPUs, a RuntimeClass
+	// and node labels for the session Pod, within the allowance of the team.
+	Accelerator *AcceleratorParams `json:"accelerator,omitempty"`
+	// Image overrides the container image of the agent. It must be on the
+	// image allowlist of the proxy or of the team of the session.
+	Image string `json:"image,omitempty"`
+	// CompletionCallbackURL receives a POST once the agent has finished
+	// processing the initial message, and when a oneshot Job ends.
+	CompletionCallbackURL string `json:"completion_callback_url,omitempty"`
 }
 
 // SessionAnnotations contains user-managed annotations attached to a session.
@@ -131,6 +208,26 @@ type SessionAnnotations struct {
 	IssueURL    string `json:"issue_url,omitempty"`
 	Description string `json:"description,omitempty"`
 	RunningTask string `json:"running_task,omitempty"`
+	// Summary is the generated one-line summary of what the session did. It
+	// is not user-managed.
+	Summary string `json:"summary,omitempty"`
+	// ObserverMode makes the session read-only for everyone but its owner:
+	// other users who can access it may stream its messages and events but
+	// not send messages or use its terminal, editor or browser.
+	ObserverMode bool `json:"observer_mode,omitempty"`
+}
+
+// DisplayDescription returns the description shown for a session: the
+// user-managed description, else the generated summary, else fallback (the
+// initial message)
+func (a SessionAnnotations) DisplayDescription(fallback string) string {
+	if a.Description != "" {
+		return a.Description
+	}
+	if a.Summary != "" {
+		return a.Summary
+	}
+	return fallback
 }
 
 // UpdateSessionAnnotationsRequest partially updates user-managed session annotations.
@@ -159,8 +256,28 @@ type StartRequest struct {
 	// SessionProfileID is an optional reference to a SessionProfile.
 	// When set, the profile's config is used as a base; explicit fields override it.
 	SessionProfileID string `json:"session_profile_id,omitempty"`
+	// Slug is an optional human-friendly name of the session, unique among
+	// the sessions of the user. It is stored as the "slug" tag and can be
+	// used instead of the session ID in URLs.
+	Slug string `json:"slug,omitempty"`
+	// SlugPrefix generates the slug from this prefix and four random digits
+	// when Slug is empty.
+	SlugPrefix string `json:"slug_prefix,omitempty"`
+	// Group is an optional group the session joins, e.g.
+	// "release-42-verification". It is stored as the "group" tag; the
+	// sessions of a group can be messaged and deleted together.
+	Group string `json:"group,omitempty"`
 	// ProfileMCPServers is resolved from SessionProfileID and is never accepted from the API.
 	ProfileMCPServers *MCPServersSettings `json:"-"`
+	// TeamProfileMCPServers is resolved from the default profiles of the
+	// session's teams and is never accepted from the API.
+	TeamProfileMCPServers *MCPServersSettings `json:"-"`
+	// MCPServers are task-specific MCP servers of this session only. They
+	// override all settings layers and must be on the allowlist of the proxy.
+	MCPServers map[string]*RequestMCPServer `json:"mcp_servers,omitempty"`
+	// RequestMCPServers is validated from MCPServers and is never accepted
+	// from the API.
+	RequestMCPServers *MCPServersSettings `json:"-"`
 }
 
 // RepositoryInfo contains repository information extracted from tags
@@ -193,6 +310,12 @@ type RunServerRequest struct {
 	Sandbox *SandboxParams
 	// Docker configures Docker-in-Docker (DinD) for the session.
 	Docker *DockerParams
+	// Editor configures the in-browser editor (code-server) sidecar.
+	Editor *EditorParams
+	// Browser configures the headless browser (noVNC) sidecar.
+	Browser *BrowserParams
+	// Terminal configures the web terminal (ttyd) sidecar.
+	Terminal *TerminalParams
 	// AuthProxy controls whether the auth proxy sidecar is injected.
 	// nil means use the global server configuration.
 	AuthProxy *bool
@@ -209,6 +332,31 @@ type RunServerRequest struct {
 	CredentialSource string
 	// ProfileMCPServers is applied as a settings layer above user/team settings.
 	ProfileMCPServers *MCPServersSettings
+	// TeamProfileMCPServers, from the default profiles of the session's
+	// teams, is applied as a settings layer between team and user settings.
+	TeamProfileMCPServers *MCPServersSettings
+	// RequestMCPServers, from the creation request, are applied as the
+	// highest-priority settings layer.
+	RequestMCPServers *MCPServersSettings
+	// SetupHooks are run by the provisioner before the agent starts.
+	SetupHooks []SetupHook
+	// PostSessionHooks are run before a oneshot session is deleted.
+	PostSessionHooks []SetupHook
+	// Replicas is the number of session Pods (0 means 1).
+	Replicas int
+	// Accelerator requests accelerators for the session Pod.
+	Accelerator *AcceleratorParams
+	// Image is the requested container image of the agent ("" selects the
+	// configured image of the agent type).
+	Image string
+	// CompletionCallbackURL is notified when the session completes.
+	CompletionCallbackURL string
+	// GitHubReport is the working branch and Check Run created for a
+	// session started from a GitHub webhook.
+	GitHubReport *GitHubReport
+	// RestoreSnapshot is the VolumeSnapshot the workdir PVC of the session
+	// is created from, in the namespace the session is placed in.
+	RestoreSnapshot string
 }
 
 // Session represents a running agentapi session
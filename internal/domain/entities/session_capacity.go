@@ -0,0 +1,8 @@
+package entities
+
+import "errors"
+
+// ErrSessionCapacityExhausted is returned when no session slot is free for
+// a new session: the unreserved slots are in use and the team of the session
+// has no free reserved slot.
+var ErrSessionCapacityExhausted = errors.New("session capacity exhausted")
@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Statuses of finished sessions. Oneshot sessions run as Jobs end as
+// completed or failed; other sessions with an initial message are completed
+// once the agent has finished processing it.
+const (
+	SessionStatusCompleted = "completed"
+	SessionStatusFailed    = "failed"
+)
+
+// SessionCompletion records how a session ended its initial run: how the
+// Job of a oneshot session ended, or when the agent finished the initial
+// message of any other session. Like
+// PostSessionReport it keeps the session owner so that it can be authorized
+// after the session itself is gone.
+type SessionCompletion struct {
+	SessionID string        `json:"session_id"`
+	UserID    string        `json:"user_id"`
+	Scope     ResourceScope `json:"scope"`
+	TeamID    string        `json:"team_id,omitempty"`
+	// Succeeded is true when the agent completed the session
+	Succeeded bool `json:"succeeded"`
+	// Reason is the reason of the Job failure, e.g. "DeadlineExceeded" or
+	// "BackoffLimitExceeded"
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ExitCode is the exit code of the agent container of the last Pod
+	ExitCode   *int32    `json:"exit_code,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Logs lists the names of the captured container logs
+	Logs []string `json:"logs,omitempty"`
+}
+
+// ValidateCompletionCallbackURL checks that a completion callback URL is an
+// absolute http or https URL. An empty URL is valid.
+func ValidateCompletionCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("completion_callback_url must be an absolute http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package entities
+
+import "time"
+
+// SessionEventType is a step in the lifecycle of a session.
+type SessionEventType string
+
+const (
+	SessionEventCreated         SessionEventType = "created"
+	SessionEventSecretCreated   SessionEventType = "secret-created"
+	SessionEventDeploymentReady SessionEventType = "deployment-ready"
+	SessionEventProvisioned     SessionEventType = "provisioned"
+	SessionEventProvisionFailed SessionEventType = "provision-failed"
+	SessionEventStartupTimeout  SessionEventType = "startup-timeout"
+	SessionEventCrashed         SessionEventType = "crashed"
+	SessionEventEvicted         SessionEventType = "evicted"
+	SessionEventRestarted       SessionEventType = "restarted"
+	SessionEventUnhealthy       SessionEventType = "unhealthy"
+	SessionEventRecovered       SessionEventType = "recovered"
+	SessionEventImageUpgraded   SessionEventType = "image-upgraded"
+	SessionEventSnapshotted     SessionEventType = "snapshotted"
+	SessionEventRestored        SessionEventType = "restored"
+	SessionEventMessageSent     SessionEventType = "message-sent"
+	SessionEventCompleted       SessionEventType = "completed"
+	SessionEventJobCompleted    SessionEventType = "job-completed"
+	SessionEventJobFailed       SessionEventType = "job-failed"
+	SessionEventDeleted         SessionEventType = "deleted"
+)
+
+// SessionEvent is an entry of the event timeline of a session, used to show
+// why a session is stuck or what happened to it.
+type SessionEvent struct {
+	SessionID string           `json:"session_id"`
+	Type      SessionEventType `json:"type"`
+	Message   string           `json:"message,omitempty"`
+	Timestamp time.Time        `json:"timestamp"`
+}
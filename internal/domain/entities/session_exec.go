@@ -0,0 +1,25 @@
+package entities
+
+import "errors"
+
+var (
+	// ErrExecDisabled is returned when exec into session containers is
+	// disabled by configuration.
+	ErrExecDisabled = errors.New("exec into sessions is disabled")
+)
+
+// SessionExecRequest describes a command run in a container of the session Pod.
+type SessionExecRequest struct {
+	// Container defaults to the main agentapi container.
+	Container string `json:"container,omitempty"`
+	// Command defaults to an interactive shell.
+	Command []string `json:"command,omitempty"`
+	// TTY allocates a terminal. Interactive WebSocket execs use one by default.
+	TTY bool `json:"tty,omitempty"`
+}
+
+// TerminalSize is the size of the terminal of an interactive exec.
+type TerminalSize struct {
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SessionGroupTag is the session tag holding the name of the group a session
+// joined at creation. The sessions of a group can be messaged, inspected and
+// deleted together.
+const SessionGroupTag = "group"
+
+var sessionGroupPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,61}[a-z0-9]$`)
+
+// ValidateSessionGroup checks that group is 2-63 lower-case letters, digits,
+// '.', '_' and '-' and starts and ends with a letter or digit
+func ValidateSessionGroup(group string) error {
+	if !sessionGroupPattern.MatchString(group) {
+		return fmt.Errorf("group must be 2-63 lower-case letters, digits, '.', '_' or '-' and start and end with a letter or digit")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSessionGroup(t *testing.T) {
+	for _, group := range []string{"release-42-verification", "v1.2_checks", strings.Repeat("x", 63)} {
+		if err := ValidateSessionGroup(group); err != nil {
+			t.Errorf("ValidateSessionGroup(%q) = %v", group, err)
+		}
+	}
+	for _, group := range []string{"", "a", "-lead", "trail.", "Upper", "with space", strings.Repeat("x", 64)} {
+		if err := ValidateSessionGroup(group); err == nil {
+			t.Errorf("ValidateSessionGroup(%q) succeeded", group)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package entities
+
+import "time"
+
+// Reasons of a failed health probe of a session
+const (
+	// HealthReasonUnreachable means the agentapi server refused the
+	// connection or could not be resolved while the Pod was ready
+	HealthReasonUnreachable = "unreachable"
+	// HealthReasonTimeout means the agentapi server did not answer in time
+	HealthReasonTimeout = "timeout"
+	// HealthReasonHTTPError means the agentapi server answered with an
+	// unexpected HTTP status
+	HealthReasonHTTPError = "http_error"
+	// HealthReasonInvalidResponse means the answer was not an agentapi
+	// status
+	HealthReasonInvalidResponse = "invalid_response"
+)
+
+// SessionHealth is the result of probing the agentapi server of a session
+type SessionHealth struct {
+	// Healthy is false once FailureThreshold consecutive probes failed
+	Healthy bool `json:"healthy"`
+	// AgentStatus is the agent status reported by the last successful probe,
+	// e.g. "running" or "stable"
+	AgentStatus string `json:"agent_status,omitempty"`
+	// Reason and Message describe the last failed probe
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ConsecutiveFailures counts the failed probes since the last success
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastProbeAt         time.Time  `json:"last_probe_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	// Restarts counts the restarts triggered by failed probes
+	Restarts      int        `json:"restarts,omitempty"`
+	LastRestartAt *time.Time `json:"last_restart_at,omitempty"`
+}
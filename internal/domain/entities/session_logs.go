@@ -0,0 +1,27 @@
+package entities
+
+import "errors"
+
+var (
+	// ErrSessionPodUnavailable is returned when the session has no Pod to
+	// read logs from or exec into, e.g. while it is paused.
+	ErrSessionPodUnavailable = errors.New("session has no running pod")
+	// ErrUnknownContainer is returned when the requested container is not
+	// part of the session Pod.
+	ErrUnknownContainer = errors.New("unknown container")
+)
+
+// SessionLogOptions selects the logs streamed from a session Pod.
+type SessionLogOptions struct {
+	// Container defaults to the main agentapi container. Sidecars and init
+	// containers of the session Pod can be selected by name.
+	Container string
+	// Follow keeps the stream open and sends new lines as they are written.
+	Follow bool
+	// Previous returns the logs of the previous (crashed) container instance.
+	Previous bool
+	// TailLines limits the output to the last lines. nil means all lines.
+	TailLines *int64
+	// SinceSeconds limits the output to lines newer than this. nil means all lines.
+	SinceSeconds *int64
+}
@@ -0,0 +1,96 @@
+package entities
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSessionFolderNameLength caps the length of a session folder name
+const MaxSessionFolderNameLength = 64
+
+// SessionOrganization holds how a user organizes their sessions: pinned
+// favorites and simple, flat folders. It is stored in the user's settings and
+// only affects how sessions are listed for that user.
+type SessionOrganization struct {
+	// Favorites lists the IDs of pinned sessions
+	Favorites []string `json:"favorites,omitempty"`
+	// Folders maps a session ID to the folder it was filed into
+	Folders map[string]string `json:"folders,omitempty"`
+}
+
+// IsFavorite reports whether the session is pinned
+func (o *SessionOrganization) IsFavorite(sessionID string) bool {
+	return o != nil && slices.Contains(o.Favorites, sessionID)
+}
+
+// SetFavorite pins or unpins the session
+func (o *SessionOrganization) SetFavorite(sessionID string, favorite bool) {
+	if favorite {
+		if !o.IsFavorite(sessionID) {
+			o.Favorites = append(o.Favorites, sessionID)
+		}
+		return
+	}
+	o.Favorites = slices.DeleteFunc(o.Favorites, func(id string) bool { return id == sessionID })
+}
+
+// Folder returns the folder of the session; empty when it is not filed
+func (o *SessionOrganization) Folder(sessionID string) string {
+	if o == nil {
+		return ""
+	}
+	return o.Folders[sessionID]
+}
+
+// SetFolder files the session into folder; an empty folder removes it from
+// its folder
+func (o *SessionOrganization) SetFolder(sessionID, folder string) {
+	if folder == "" {
+		delete(o.Folders, sessionID)
+		return
+	}
+	if o.Folders == nil {
+		o.Folders = make(map[string]string)
+	}
+	o.Folders[sessionID] = folder
+}
+
+// FolderNames returns the folders in use, sorted by name
+func (o *SessionOrganization) FolderNames() []string {
+	if o == nil {
+		return nil
+	}
+	names := make([]string, 0, len(o.Folders))
+	for _, folder := range o.Folders {
+		if !slices.Contains(names, folder) {
+			names = append(names, folder)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Forget removes the session from favorites and folders, e.g. after it was
+// deleted. It reports whether anything changed.
+func (o *SessionOrganization) Forget(sessionID string) bool {
+	if o == nil || (!o.IsFavorite(sessionID) && o.Folder(sessionID) == "") {
+		return false
+	}
+	o.SetFavorite(sessionID, false)
+	o.SetFolder(sessionID, "")
+	return true
+}
+
+// ValidateSessionFolderName checks a folder name. Names are trimmed by the
+// caller; empty names are handled as "no folder" and are not passed here.
+func ValidateSessionFolderName(folder string) error {
+	if utf8.RuneCountInString(folder) > MaxSessionFolderNameLength {
+		return fmt.Errorf("folder name must be at most %d characters", MaxSessionFolderNameLength)
+	}
+	if strings.ContainsAny(folder, "\n\r\t") {
+		return fmt.Errorf("folder name must not contain control characters")
+	}
+	return nil
+}
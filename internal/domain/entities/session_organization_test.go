@@ -0,0 +1,48 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionOrganization(t *testing.T) {
+	var none *SessionOrganization
+	if none.IsFavorite("a") || none.Folder("a") != "" || none.FolderNames() != nil {
+		t.Error("nil organization must be empty")
+	}
+
+	org := &SessionOrganization{}
+	org.SetFavorite("a", true)
+	org.SetFavorite("a", true)
+	org.SetFolder("a", "infra")
+	org.SetFolder("b", "docs")
+	org.SetFolder("c", "infra")
+	if len(org.Favorites) != 1 || !org.IsFavorite("a") {
+		t.Errorf("Favorites = %v", org.Favorites)
+	}
+	if got := org.FolderNames(); len(got) != 2 || got[0] != "docs" || got[1] != "infra" {
+		t.Errorf("FolderNames() = %v", got)
+	}
+
+	if !org.Forget("a") || org.IsFavorite("a") || org.Folder("a") != "" {
+		t.Errorf("Forget(a) left %+v", org)
+	}
+	if org.Forget("a") {
+		t.Error("Forget of an unknown session must report no change")
+	}
+	org.SetFolder("b", "")
+	if org.Folder("b") != "" {
+		t.Error("empty folder must unfile the session")
+	}
+}
+
+func TestValidateSessionFolderName(t *testing.T) {
+	if err := ValidateSessionFolderName("Release 1.2 / hotfixes"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, name := range []string{strings.Repeat("x", MaxSessionFolderNameLength+1), "two\nlines"} {
+		if err := ValidateSessionFolderName(name); err == nil {
+			t.Errorf("ValidateSessionFolderName(%q) = nil", name)
+		}
+	}
+}
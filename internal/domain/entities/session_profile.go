@@ -13,6 +13,7 @@ type SessionProfile struct {
 	scope        ResourceScope
 	teamID       string
 	isDefault    bool
+	teamDefault  bool
 	selectorTags map[string]string
 	config       SessionProfileConfig
 	createdAt    time.Time
@@ -120,6 +121,18 @@ func (p *SessionProfile) SetIsDefault(isDefault bool) {
 	p.updatedAt = time.Now()
 }
 
+// IsTeamDefault returns whether this team-scoped profile is the default of
+// its team. A team default is not selected like other profiles; it is
+// applied beneath the profile of every session of the team and of its
+// members, so that the profile and the request override it.
+func (p *SessionProfile) IsTeamDefault() bool { return p.teamDefault }
+
+// SetIsTeamDefault sets whether this profile is the default of its team
+func (p *SessionProfile) SetIsTeamDefault(teamDefault bool) {
+	p.teamDefault = teamDefault
+	p.updatedAt = time.Now()
+}
+
 // SelectorTags returns the tag selector used to choose this profile at launch time.
 func (p *SessionProfile) SelectorTags() map[string]string {
 	return copyStringMap(p.selectorTags)
@@ -0,0 +1,7 @@
+package entities
+
+import "errors"
+
+// ErrInvalidReplicas is returned when a session requests a replica count
+// that its agent type or the server configuration does not allow.
+var ErrInvalidReplicas = errors.New("invalid session replicas")
@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetupHookFailurePolicy decides what happens to a session when a setup hook fails.
+type SetupHookFailurePolicy string
+
+const (
+	// SetupHookFailSession marks the session as failed and does not start the agent.
+	SetupHookFailSession SetupHookFailurePolicy = "fail"
+	// SetupHookContinue logs the failure and starts the agent anyway.
+	SetupHookContinue SetupHookFailurePolicy = "continue"
+
+	// DefaultSetupHookTimeoutSeconds is used when a hook sets no timeout.
+	DefaultSetupHookTimeoutSeconds = 600
+	// MaxSetupHookTimeoutSeconds bounds the timeout of a single hook.
+	MaxSetupHookTimeoutSeconds = 3600
+)
+
+// SetupHook is a shell command run by the provisioner after the repository is
+// cloned and before the agent starts (e.g. "make bootstrap" or "npm ci").
+type SetupHook struct {
+	// Name identifies the hook in logs. Defaults to its position.
+	Name string `json:"name,omitempty"`
+	// Command is run with sh -c in the repository directory.
+	Command string `json:"command"`
+	// TimeoutSeconds defaults to 600 and is capped at 3600.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// OnFailure is "fail" (default) or "continue".
+	OnFailure SetupHookFailurePolicy `json:"on_failure,omitempty"`
+}
+
+// Validate validates the setup hook
+func (h SetupHook) Validate() error {
+	if h.Command == "" {
+		return fmt.Errorf("setup hook %q: command is required", h.Name)
+	}
+	if h.TimeoutSeconds < 0 || h.TimeoutSeconds > MaxSetupHookTimeoutSeconds {
+		return fmt.Errorf("setup hook %q: timeout_seconds must be between 0 and %d", h.Name, MaxSetupHookTimeoutSeconds)
+	}
+	switch h.OnFailure {
+	case "", SetupHookFailSession, SetupHookContinue:
+		return nil
+	default:
+		return fmt.Errorf("setup hook %q: invalid on_failure %q: must be %q or %q", h.Name, h.OnFailure, SetupHookFailSession, SetupHookContinue)
+	}
+}
+
+// ValidateSetupHooks validates every hook in order.
+func ValidateSetupHooks(hooks []SetupHook) error {
+	for _, h := range hooks {
+		if err := h.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostSessionHookResult is the outcome of one post-session hook.
+type PostSessionHookResult struct {
+	Name       string `json:"name"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	// Error is set when the hook could not be run or timed out.
+	Error string `json:"error,omitempty"`
+	// Log is the artifact name of the combined stdout and stderr of the hook.
+	Log string `json:"log"`
+}
+
+// PostSessionReport records the post-session hooks of a deleted session. It
+// keeps the session owner so that the report can be authorized after the
+// session itself is gone.
+type PostSessionReport struct {
+	SessionID string                  `json:"session_id"`
+	UserID    string                  `json:"user_id"`
+	Scope     ResourceScope           `json:"scope"`
+	TeamID    string                  `json:"team_id,omitempty"`
+	StartedAt time.Time               `json:"started_at"`
+	Hooks     []PostSessionHookResult `json:"hooks"`
+}
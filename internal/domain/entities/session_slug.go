@@ -0,0 +1,71 @@
+package entities
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// SessionSlugTag is the session tag holding the human-friendly slug of a
+// session. A slug can be used instead of the session ID in URLs.
+const SessionSlugTag = "slug"
+
+var sessionSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,61}[a-z0-9]$`)
+
+// reservedSessionSlugs are path segments that routes of the proxy use. The
+// session proxy route /:sessionId/* shares their namespace, so a session
+// cannot be called like them.
+var reservedSessionSlugs = []string{
+	"acp", "admin", "api", "api-tokens", "archived-sessions", "assets", "auth",
+	"codex", "credentials", "default", "docs", "events", "external-session-managers",
+	"files", "github", "health", "healthz", "hooks", "integrations", "internal",
+	"llm-proxy", "login", "logout", "manage", "mcp", "me", "memories", "messages",
+	"metrics", "new", "notification", "notifications", "oauth", "openapi",
+	"outbound-webhooks", "resources", "rpc", "s", "sandbox-policies",
+	"saved-searches", "schedules", "search", "session", "session-groups", "session-profiles",
+	"sessions", "settings", "slack", "slackbots", "sse", "start", "static",
+	"status", "task-groups", "tasks", "user", "users", "webhooks", "ws",
+}
+
+// ErrSessionSlugTaken is returned when the owner of a session already has
+// another session with the same slug
+var ErrSessionSlugTaken = errors.New("session slug is already used by another session")
+
+// ValidateSessionSlug checks that slug is 2-63 lower-case letters, digits
+// and '-', does not look like a session ID and is not reserved
+func ValidateSessionSlug(slug string) error {
+	if !sessionSlugPattern.MatchString(slug) {
+		return fmt.Errorf("slug must be 2-63 lower-case letters, digits or '-' and start and end with a letter or digit")
+	}
+	if _, err := uuid.Parse(slug); err == nil {
+		return fmt.Errorf("slug must not be a session ID")
+	}
+	if slices.Contains(reservedSessionSlugs, slug) {
+		return fmt.Errorf("slug %q is reserved", slug)
+	}
+	return nil
+}
+
+// GenerateSessionSlug returns prefix followed by '-' and four random digits,
+// e.g. "pr-review-1234"
+func GenerateSessionSlug(prefix string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", err
+	}
+	slug := fmt.Sprintf("%s-%04d", prefix, n.Int64())
+	if err := ValidateSessionSlug(slug); err != nil {
+		return "", fmt.Errorf("invalid slug_prefix: %w", err)
+	}
+	return slug, nil
+}
+
+// IsSessionSlug reports whether id could be a slug rather than a session ID
+func IsSessionSlug(id string) bool {
+	return ValidateSessionSlug(id) == nil
+}
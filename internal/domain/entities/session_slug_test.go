@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSessionSlug(t *testing.T) {
+	for _, slug := range []string{"my-pr-review-1234", "a1", strings.Repeat("x", 63)} {
+		if err := ValidateSessionSlug(slug); err != nil {
+			t.Errorf("ValidateSessionSlug(%q) = %v", slug, err)
+		}
+	}
+	for _, slug := range []string{
+		"", "a", "-lead", "trail-", "Upper", "with_underscore", strings.Repeat("x", 64),
+		"search", "sessions", "start",
+		"0b7c1f7e-8f9a-4a55-9d7e-5f0a7c9d1e01",
+	} {
+		if err := ValidateSessionSlug(slug); err == nil {
+			t.Errorf("ValidateSessionSlug(%q) succeeded", slug)
+		}
+	}
+}
+
+func TestGenerateSessionSlug(t *testing.T) {
+	slug, err := GenerateSessionSlug("pr-review")
+	if err != nil {
+		t.Fatalf("GenerateSessionSlug() error = %v", err)
+	}
+	if !strings.HasPrefix(slug, "pr-review-") || len(slug) != len("pr-review-1234") {
+		t.Errorf("GenerateSessionSlug() = %q", slug)
+	}
+	if _, err := GenerateSessionSlug("Bad Prefix"); err == nil {
+		t.Error("GenerateSessionSlug() accepted an invalid prefix")
+	}
+}
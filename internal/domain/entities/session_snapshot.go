@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// SessionSnapshotKind is why a snapshot of a session workdir was taken.
+type SessionSnapshotKind string
+
+const (
+	// SessionSnapshotClone is taken to clone a session.
+	SessionSnapshotClone SessionSnapshotKind = "clone"
+	// SessionSnapshotScheduled is taken periodically to restore the session
+	// if it is deleted by accident.
+	SessionSnapshotScheduled SessionSnapshotKind = "scheduled"
+)
+
+var (
+	// ErrSnapshotsDisabled is returned when kubernetes_session.snapshots is
+	// off or sessions have no workdir PVC to snapshot.
+	ErrSnapshotsDisabled = errors.New("session snapshots need kubernetes_session.snapshots.enabled and pvc_enabled")
+	// ErrSessionNotSnapshottable is returned for a session without a
+	// workdir PVC, e.g. a multi-replica session.
+	ErrSessionNotSnapshottable = errors.New("session has no workdir volume to snapshot")
+	// ErrSessionSnapshotNotFound is returned for an unknown snapshot.
+	ErrSessionSnapshotNotFound = errors.New("session snapshot not found")
+	// ErrSessionSnapshotNotReady is returned when a snapshot failed and
+	// cannot be restored.
+	ErrSessionSnapshotNotReady = errors.New("session snapshot is not usable")
+)
+
+// SessionSnapshot is a VolumeSnapshot of the workdir of a session.
+type SessionSnapshot struct {
+	Name      string              `json:"name"`
+	SessionID string              `json:"session_id"`
+	UserID    string              `json:"user_id"`
+	Scope     ResourceScope       `json:"scope"`
+	TeamID    string              `json:"team_id,omitempty"`
+	Kind      SessionSnapshotKind `json:"kind"`
+	// ReadyToUse is true once the storage finished taking the snapshot
+	ReadyToUse bool `json:"ready_to_use"`
+	// Error is set when taking the snapshot failed
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CloneSessionRequest creates a session from a snapshot of another session
+// or from a stored snapshot.
+type CloneSessionRequest struct {
+	// InitialMessage is sent to the new session once it is ready
+	InitialMessage string `json:"initial_message,omitempty"`
+}
@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSessionUpdate is returned when an UpdateSessionRequest cannot be
+// applied to a session
+var ErrInvalidSessionUpdate = errors.New("invalid session update")
+
+// UpdateSessionRequest updates the tags, description and observer mode of
+// an existing session. Tags replaces all tags when non-nil; AddTags and
+// RemoveTags are applied after it. A nil Description leaves the description
+// unchanged and an empty one clears it; a nil ObserverMode leaves the mode
+// unchanged.
+type UpdateSessionRequest struct {
+	// Tags replaces all tags of the session
+	Tags map[string]string `json:"tags,omitempty"`
+	// AddTags adds or overwrites tags
+	AddTags map[string]string `json:"add_tags,omitempty"`
+	// RemoveTags removes tags by key
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// Description sets the description; an empty string clears it
+	Description *string `json:"description,omitempty"`
+	// ObserverMode makes the session read-only for everyone but its owner.
+	// Only the owner can change it.
+	ObserverMode *bool `json:"observer_mode,omitempty"`
+}
+
+// Validate checks that tag keys are not empty, that no tag is both added and
+// removed and that the slug tag is not changed
+func (r UpdateSessionRequest) Validate() error {
+	for k := range r.Tags {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("%w: tag keys must not be empty", ErrInvalidSessionUpdate)
+		}
+	}
+	for k := range r.AddTags {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("%w: tag keys must not be empty", ErrInvalidSessionUpdate)
+		}
+		if k == SessionSlugTag {
+			return fmt.Errorf("%w: the %q tag cannot be changed", ErrInvalidSessionUpdate, SessionSlugTag)
+		}
+	}
+	for _, k := range r.RemoveTags {
+		if _, ok := r.AddTags[k]; ok {
+			return fmt.Errorf("%w: tag %q is both added and removed", ErrInvalidSessionUpdate, k)
+		}
+		if k == SessionSlugTag {
+			return fmt.Errorf("%w: the %q tag cannot be changed", ErrInvalidSessionUpdate, SessionSlugTag)
+		}
+	}
+	return nil
+}
+
+// HasTagChanges reports whether the request changes the tags of the session
+func (r UpdateSessionRequest) HasTagChanges() bool {
+	return r.Tags != nil || len(r.AddTags) > 0 || len(r.RemoveTags) > 0
+}
+
+// ApplyTags returns the tags of a session with current tags after the
+// request. The slug tag is kept when Tags replaces all tags.
+func (r UpdateSessionRequest) ApplyTags(current map[string]string) map[string]string {
+	tags := make(map[string]string, len(current)+len(r.AddTags))
+	if r.Tags != nil {
+		for k, v := range r.Tags {
+			tags[k] = v
+		}
+		if slug, ok := current[SessionSlugTag]; ok {
+			tags[SessionSlugTag] = slug
+		} else {
+			delete(tags, SessionSlugTag)
+		}
+	} else {
+		for k, v := range current {
+			tags[k] = v
+		}
+	}
+	for k, v := range r.AddTags {
+		tags[k] = v
+	}
+	for _, k := range r.RemoveTags {
+		delete(tags, k)
+	}
+	return tags
+}
@@ -0,0 +1,65 @@
+package entities
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateSessionRequestApplyTags(t *testing.T) {
+	current := map[string]string{"env": "dev", "owner": "alice", SessionSlugTag: "triage"}
+	tests := []struct {
+		name string
+		req  UpdateSessionRequest
+		want map[string]string
+	}{
+		{
+			name: "add and remove",
+			req:  UpdateSessionRequest{AddTags: map[string]string{"env": "prod", "team": "core"}, RemoveTags: []string{"owner", "missing"}},
+			want: map[string]string{"env": "prod", "team": "core", SessionSlugTag: "triage"},
+		},
+		{
+			name: "replace keeps the slug",
+			req:  UpdateSessionRequest{Tags: map[string]string{"priority": "high", SessionSlugTag: "other"}},
+			want: map[string]string{"priority": "high", SessionSlugTag: "triage"},
+		},
+		{
+			name: "replace then add",
+			req:  UpdateSessionRequest{Tags: map[string]string{}, AddTags: map[string]string{"env": "prod"}},
+			want: map[string]string{"env": "prod", SessionSlugTag: "triage"},
+		},
+		{
+			name: "no tag changes",
+			req:  UpdateSessionRequest{},
+			want: current,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.ApplyTags(current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if current["owner"] != "alice" {
+		t.Error("ApplyTags() modified the current tags")
+	}
+}
+
+func TestUpdateSessionRequestValidate(t *testing.T) {
+	invalid := []UpdateSessionRequest{
+		{Tags: map[string]string{" ": "x"}},
+		{AddTags: map[string]string{"": "x"}},
+		{AddTags: map[string]string{"env": "prod"}, RemoveTags: []string{"env"}},
+		{AddTags: map[string]string{SessionSlugTag: "other"}},
+		{RemoveTags: []string{SessionSlugTag}},
+	}
+	for _, req := range invalid {
+		if err := req.Validate(); !errors.Is(err, ErrInvalidSessionUpdate) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidSessionUpdate", req, err)
+		}
+	}
+	if err := (UpdateSessionRequest{AddTags: map[string]string{"env": "prod"}, RemoveTags: []string{"owner"}}).Validate(); err != nil {
+		t.Errorf("Validate() = %v for a valid request", err)
+	}
+}
@@ -140,9 +140,15 @@ type Settings struct {
 	preferredTeamID         string            // "org/team-slug" format; if set, only this team's settings are used
 	slackUserID             string            // Slack DM notification user ID
 	notificationChannels    []string          // Active notification channels (e.g. "web", "slack")
+	locale                  string            // Language of messages and notifications (e.g. "en", "ja")
 	externalSessionManagers []ExternalSessionManagerEntry
 	gitSync                 *GitSyncConfig
 	defaultSessionProfileID string // ID of the default session profile for this tenant
+	capabilityPolicy        *CapabilityPolicy
+	sessionOrganization     *SessionOrganization // Pinned sessions and folders of a user
+	savedSearches           []SavedSearch        // Named session filters of a user
+	notificationSearch      string               // Saved search that sessions must match to notify the user
+	notificationTypes       []string             // Notification types the user receives; empty for all
 	createdAt               time.Time
 	updatedAt               time.Time
 }
@@ -328,6 +334,17 @@ func (s *Settings) SetNotificationChannels(channels []string) {
 	s.updatedAt = time.Now()
 }
 
+// Locale returns the language of messages and notifications; empty means the server default
+func (s *Settings) Locale() string {
+	return s.locale
+}
+
+// SetLocale sets the language of messages and notifications
+func (s *Settings) SetLocale(locale string) {
+	s.locale = locale
+	s.updatedAt = time.Now()
+}
+
 // ExternalSessionManagers returns the list of registered external session managers
 func (s *Settings) ExternalSessionManagers() []ExternalSessionManagerEntry {
 	return s.externalSessionManagers
@@ -362,6 +379,12 @@ func (s *Settings) Validate() error {
 		}
 	}
 
+	if s.capabilityPolicy != nil {
+		if err := s.capabilityPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -386,3 +409,70 @@ func (s *Settings) SetDefaultSessionProfileID(id string) {
 	s.defaultSessionProfileID = id
 	s.updatedAt = time.Now()
 }
+
+// CapabilityPolicy returns the risky session capabilities approved for this tenant
+func (s *Settings) CapabilityPolicy() *CapabilityPolicy {
+	return s.capabilityPolicy
+}
+
+// SetCapabilityPolicy sets the risky session capabilities approved for this tenant
+func (s *Settings) SetCapabilityPolicy(p *CapabilityPolicy) {
+	s.capabilityPolicy = p
+	s.updatedAt = time.Now()
+}
+
+// SessionOrganization returns the pinned sessions and folders of the user; nil when none
+func (s *Settings) SessionOrganization() *SessionOrganization {
+	return s.sessionOrganization
+}
+
+// SetSessionOrganization sets the pinned sessions and folders of the user
+func (s *Settings) SetSessionOrganization(o *SessionOrganization) {
+	s.sessionOrganization = o
+	s.updatedAt = time.Now()
+}
+
+// SavedSearches returns the named session filters of the user
+func (s *Settings) SavedSearches() []SavedSearch {
+	return s.savedSearches
+}
+
+// SetSavedSearches sets the named session filters of the user
+func (s *Settings) SetSavedSearches(searches []SavedSearch) {
+	s.savedSearches = searches
+	s.updatedAt = time.Now()
+}
+
+// SavedSearch returns the saved search called name; nil when there is none
+func (s *Settings) SavedSearch(name string) *SavedSearch {
+	for i := range s.savedSearches {
+		if s.savedSearches[i].Name == name {
+			return &s.savedSearches[i]
+		}
+	}
+	return nil
+}
+
+// NotificationSavedSearch returns the saved search that sessions must match
+// for the user to be notified about them; empty when all sessions notify
+func (s *Settings) NotificationSavedSearch() string {
+	return s.notificationSearch
+}
+
+// SetNotificationSavedSearch sets the saved search that routes notifications
+func (s *Settings) SetNotificationSavedSearch(name string) {
+	s.notificationSearch = name
+	s.updatedAt = time.Now()
+}
+
+// NotificationTypes returns the notification types the user receives
+// (e.g. "run_completed"); empty when the user receives all types
+func (s *Settings) NotificationTypes() []string {
+	return s.notificationTypes
+}
+
+// SetNotificationTypes sets the notification types the user receives
+func (s *Settings) SetNotificationTypes(types []string) {
+	s.notificationTypes = types
+	s.updatedAt = time.Now()
+}
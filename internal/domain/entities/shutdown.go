@@ -0,0 +1,7 @@
+package entities
+
+import "errors"
+
+// ErrShuttingDown is returned for sessions requested from a proxy that is
+// shutting down; the client can retry against another replica.
+var ErrShuttingDown = errors.New("proxy is shutting down")
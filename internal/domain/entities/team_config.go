@@ -2,6 +2,7 @@ package entities
 
 import (
 	"errors"
+	"fmt"
 )
 
 // TeamConfig represents a team configuration domain entity
@@ -74,5 +75,11 @@ func (tc *TeamConfig) Validate() error {
 		}
 	}
 
+	for key := range tc.envVars {
+		if !envVarNamePattern.MatchString(key) {
+			return fmt.Errorf("invalid env var name %q", key)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,7 @@
+package entities
+
+import "errors"
+
+// ErrBudgetExceeded is returned for sessions of a team that has used up its
+// monthly token or cost budget.
+var ErrBudgetExceeded = errors.New("monthly usage budget exceeded")
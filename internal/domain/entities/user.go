@@ -51,6 +51,8 @@ const (
 	RoleMember    Role = "member"
 	RoleDeveloper Role = "developer"
 	RoleReadOnly  Role = "readonly"
+	RoleTeamAdmin Role = "team-admin"
+	RoleViewer    Role = "viewer"
 )
 
 // User represents a user domain entity
@@ -321,7 +323,7 @@ func (u *User) SetEmail(email string) {
 
 // SetRoles sets the user roles
 func (u *User) SetRoles(roles []Role) error {
-	validRoles := []Role{RoleAdmin, RoleUser, RoleMember, RoleDeveloper, RoleReadOnly}
+	validRoles := []Role{RoleAdmin, RoleUser, RoleMember, RoleDeveloper, RoleReadOnly, RoleTeamAdmin, RoleViewer}
 	for _, role := range roles {
 		valid := false
 		for _, validRole := range validRoles {
@@ -461,6 +463,30 @@ func (u *User) IsMemberOfTeam(teamID string) bool {
 	return false
 }
 
+// IsTeamAdmin checks if the user may administer the specified team.
+// Global admins qualify for every team; otherwise the user's GitHub
+// membership of the team must carry the "maintainer" or "admin" role.
+// teamID must be in the format "org/team-slug" (slash-separated)
+func (u *User) IsTeamAdmin(teamID string) bool {
+	if u.IsAdmin() {
+		return true
+	}
+	if u.userType == UserTypeServiceAccount || u.githubInfo == nil {
+		return false
+	}
+
+	for _, team := range u.githubInfo.Teams() {
+		if fmt.Sprintf("%s/%s", team.Organization, team.TeamSlug) != teamID {
+			continue
+		}
+		switch team.Role {
+		case "maintainer", "admin":
+			return true
+		}
+	}
+	return false
+}
+
 // CanAccessResource checks if the user can access a resource based on its scope
 // For team-scoped resources, admin or any team member can access
 // For user-scoped resources, only the owner can access (admin privileges do not apply)
@@ -539,7 +565,7 @@ func (u *User) Validate() error {
 	}
 
 	// Validate roles
-	validRoles := []Role{RoleAdmin, RoleUser, RoleMember, RoleDeveloper, RoleReadOnly}
+	validRoles := []Role{RoleAdmin, RoleUser, RoleMember, RoleDeveloper, RoleReadOnly, RoleTeamAdmin, RoleViewer}
 	for _, role := range u.roles {
 		roleValid := false
 		for _, validRole := range validRoles {
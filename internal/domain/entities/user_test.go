@@ -127,3 +127,54 @@ func TestCanAccessResource_ServiceAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestIsTeamAdmin(t *testing.T) {
+	admin := makeGitHubUser(nil)
+	admin.roles = []Role{RoleAdmin}
+
+	tests := []struct {
+		name     string
+		user     *User
+		teamID   string
+		expected bool
+	}{
+		{
+			name:     "global admin",
+			user:     admin,
+			teamID:   "org/any",
+			expected: true,
+		},
+		{
+			name:     "team maintainer",
+			user:     makeGitHubUser([]GitHubTeamMembership{{Organization: "org", TeamSlug: "team", Role: "maintainer"}}),
+			teamID:   "org/team",
+			expected: true,
+		},
+		{
+			name:     "plain team member",
+			user:     makeGitHubUser([]GitHubTeamMembership{{Organization: "org", TeamSlug: "team", Role: "member"}}),
+			teamID:   "org/team",
+			expected: false,
+		},
+		{
+			name:     "maintainer of another team",
+			user:     makeGitHubUser([]GitHubTeamMembership{{Organization: "org", TeamSlug: "other", Role: "admin"}}),
+			teamID:   "org/team",
+			expected: false,
+		},
+		{
+			name:     "service account",
+			user:     NewServiceAccountUser("sa-org-team", "org/team", nil),
+			teamID:   "org/team",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.IsTeamAdmin(tt.teamID); got != tt.expected {
+				t.Errorf("IsTeamAdmin(%q) = %v, want %v", tt.teamID, got, tt.expected)
+			}
+		})
+	}
+}
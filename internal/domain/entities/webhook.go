@@ -1,6 +1,9 @@
 package entities
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -32,8 +35,18 @@ const (
 	WebhookSignatureTypeHMAC WebhookSignatureType = "hmac"
 	// WebhookSignatureTypeStatic indicates static token comparison
 	WebhookSignatureTypeStatic WebhookSignatureType = "static"
+	// WebhookSignatureTypeJWT indicates an HMAC-signed JWT (HS256/HS384/HS512)
+	// verified with the webhook secret
+	WebhookSignatureTypeJWT WebhookSignatureType = "jwt"
 )
 
+// DefaultGenericWebhookMaxPayloadBytes is the payload size limit applied to
+// /webhooks/generic/:source deliveries when the webhook does not set one.
+const DefaultGenericWebhookMaxPayloadBytes int64 = 1 << 20
+
+// webhookSourcePattern restricts source names to values usable in a URL path.
+var webhookSourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
 // DeliveryStatus defines the status of a webhook delivery
 type DeliveryStatus string
 
@@ -68,6 +81,10 @@ type Webhook struct {
 	triggers        []WebhookTrigger
 	sessionConfig   *WebhookSessionConfig
 	maxSessions     int
+	// source is the name custom webhooks are reachable under at
+	// /webhooks/generic/:source. Empty means the webhook is only reachable by ID.
+	source          string
+	maxPayloadBytes int64
 	createdAt       time.Time
 	updatedAt       time.Time
 	lastDelivery    *WebhookDeliveryRecord
@@ -274,6 +291,25 @@ func (w *Webhook) SetMaxSessions(max int) {
 	w.updatedAt = time.Now()
 }
 
+// Source returns the generic source name of the webhook
+func (w *Webhook) Source() string { return w.source }
+
+// SetSource sets the generic source name of the webhook
+func (w *Webhook) SetSource(source string) {
+	w.source = source
+	w.updatedAt = time.Now()
+}
+
+// MaxPayloadBytes returns the maximum accepted payload size in bytes.
+// Returns 0 when no limit is configured.
+func (w *Webhook) MaxPayloadBytes() int64 { return w.maxPayloadBytes }
+
+// SetMaxPayloadBytes sets the maximum accepted payload size in bytes
+func (w *Webhook) SetMaxPayloadBytes(max int64) {
+	w.maxPayloadBytes = max
+	w.updatedAt = time.Now()
+}
+
 // CreatedAt returns the creation time
 func (w *Webhook) CreatedAt() time.Time { return w.createdAt }
 
@@ -321,6 +357,17 @@ func (w *Webhook) Validate() error {
 	if len(w.triggers) == 0 {
 		return ErrInvalidWebhook{Field: "triggers", Message: "at least one trigger is required"}
 	}
+	if w.source != "" {
+		if w.webhookType != WebhookTypeCustom {
+			return ErrInvalidWebhook{Field: "source", Message: "source is only supported for custom webhooks"}
+		}
+		if !webhookSourcePattern.MatchString(w.source) {
+			return ErrInvalidWebhook{Field: "source", Message: "source must be 1-63 lowercase letters, digits, '-' or '_'"}
+		}
+	}
+	if w.maxPayloadBytes < 0 {
+		return ErrInvalidWebhook{Field: "max_payload_bytes", Message: "max_payload_bytes must not be negative"}
+	}
 	return nil
 }
 
@@ -524,6 +571,50 @@ type WebhookSessionConfig struct {
 	// sessionProfileID is an optional reference to a SessionProfile.
 	// When set, the profile's config is used as a base; explicit fields override it.
 	sessionProfileID string
+	// payloadMappings copy values selected by JSONPath from the payload into
+	// session fields.
+	payloadMappings []WebhookPayloadMapping
+}
+
+// Payload mapping targets. Targets with a prefix take the field name after
+// the dot, e.g. "tags.service" or "env.ALERT_ID".
+const (
+	PayloadMappingTargetTagPrefix  = "tags."
+	PayloadMappingTargetEnvPrefix  = "env."
+	PayloadMappingTargetVarPrefix  = "vars."
+	PayloadMappingTargetRepository = "repository"
+	PayloadMappingTargetBranch     = "branch"
+)
+
+// WebhookPayloadMapping maps the value at a JSONPath expression of the
+// webhook payload into a session field. Mapped variables ("vars.<name>") are
+// available to all templates of the session config as {{.vars.<name>}}.
+type WebhookPayloadMapping struct {
+	// Path is a JSONPath expression such as "$.alert.labels.service" or
+	// "$.items[0]['name']".
+	Path string `json:"path"`
+	// Target is the session field the value is written to.
+	Target string `json:"target"`
+	// Default is used when Path does not match the payload.
+	Default string `json:"default,omitempty"`
+	// Required rejects the delivery when Path does not match and no Default is set.
+	Required bool `json:"required,omitempty"`
+}
+
+// Validate checks that the mapping has a path and a known target.
+func (m WebhookPayloadMapping) Validate() error {
+	if m.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	switch {
+	case m.Target == PayloadMappingTargetRepository, m.Target == PayloadMappingTargetBranch:
+		return nil
+	case strings.HasPrefix(m.Target, PayloadMappingTargetTagPrefix) && len(m.Target) > len(PayloadMappingTargetTagPrefix),
+		strings.HasPrefix(m.Target, PayloadMappingTargetEnvPrefix) && len(m.Target) > len(PayloadMappingTargetEnvPrefix),
+		strings.HasPrefix(m.Target, PayloadMappingTargetVarPrefix) && len(m.Target) > len(PayloadMappingTargetVarPrefix):
+		return nil
+	}
+	return fmt.Errorf("unsupported target %q: use tags.<key>, env.<NAME>, vars.<name>, repository or branch", m.Target)
 }
 
 // NewWebhookSessionConfig creates a new session config
@@ -592,6 +683,14 @@ func (c *WebhookSessionConfig) SessionProfileID() string { return c.sessionProfi
 // SetSessionProfileID sets the optional session profile ID reference
 func (c *WebhookSessionConfig) SetSessionProfileID(id string) { c.sessionProfileID = id }
 
+// PayloadMappings returns the JSONPath payload mappings
+func (c *WebhookSessionConfig) PayloadMappings() []WebhookPayloadMapping { return c.payloadMappings }
+
+// SetPayloadMappings sets the JSONPath payload mappings
+func (c *WebhookSessionConfig) SetPayloadMappings(mappings []WebhookPayloadMapping) {
+	c.payloadMappings = mappings
+}
+
 // WebhookDeliveryRecord represents a single webhook delivery
 type WebhookDeliveryRecord struct {
 	id             string
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSecretNotFound is returned by SecretsProvider.Get when no secret is
+// stored under the requested key.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsProvider stores secret values (agent-env variables, GitHub tokens,
+// personal API keys) in an external secret manager such as HashiCorp Vault or
+// AWS Secrets Manager instead of Kubernetes Secrets.
+//
+// Values are read from the provider on every access and never copied into
+// Kubernetes, so a secret rotated in the backend takes effect on the next read.
+type SecretsProvider interface {
+	// Get returns the key/value pairs stored under key
+	Get(ctx context.Context, key string) (map[string]string, error)
+
+	// Put replaces the key/value pairs stored under key
+	Put(ctx context.Context, key string, data map[string]string) error
+
+	// Delete removes the secret stored under key. Deleting a missing secret is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// Name returns the name of the backend ("vault", "aws-secrets-manager")
+	Name() string
+}
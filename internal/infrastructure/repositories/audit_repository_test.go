@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+func TestAuditRepositories(t *testing.T) {
+	repos := map[string]portrepos.AuditRepository{
+		"memory":     NewMemoryAuditRepository(),
+		"kubernetes": NewKubernetesAuditRepository(fake.NewSimpleClientset(), "test-ns"),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			day1 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+			day2 := day1.AddDate(0, 0, 1)
+			for _, ts := range []time.Time{day1, day1.Add(2 * time.Hour), day2, day2.Add(time.Hour)} {
+				event := entities.AuditEvent{
+					Timestamp: ts,
+					Category:  entities.AuditCategoryAccess,
+					Action:    "POST /start",
+					Outcome:   entities.AuditOutcomeSuccess,
+				}
+				if err := repo.RecordAuditEvent(ctx, event); err != nil {
+					t.Fatalf("RecordAuditEvent() error = %v", err)
+				}
+			}
+
+			events, err := repo.ListAuditEvents(ctx, day1.Add(time.Hour), day2.Add(30*time.Minute))
+			if err != nil {
+				t.Fatalf("ListAuditEvents() error = %v", err)
+			}
+			if len(events) != 2 || !events[0].Timestamp.Equal(day1.Add(2*time.Hour)) || !events[1].Timestamp.Equal(day2) {
+				t.Errorf("ListAuditEvents() = %+v, want the second event of day 1 and the first of day 2", events)
+			}
+
+			oldest, err := repo.OldestAuditEvent(ctx)
+			if err != nil || !oldest.Equal(day1) {
+				t.Errorf("OldestAuditEvent() = %v, %v, want %v", oldest, err, day1)
+			}
+
+			// Removes all of day 1 and the first event of day 2
+			purged, err := repo.PurgeAuditEvents(ctx, day2.Add(30*time.Minute))
+			if err != nil {
+				t.Fatalf("PurgeAuditEvents() error = %v", err)
+			}
+			if purged != 3 {
+				t.Errorf("purged = %d, want 3", purged)
+			}
+			oldest, err = repo.OldestAuditEvent(ctx)
+			if err != nil || !oldest.Equal(day2.Add(time.Hour)) {
+				t.Errorf("OldestAuditEvent() after purge = %v, %v, want %v", oldest, err, day2.Add(time.Hour))
+			}
+		})
+	}
+}
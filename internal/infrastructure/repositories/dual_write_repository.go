@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// DualWriteMemoryRepository writes memories to both the source and the
+// target backend of a storage migration. Reads come from the source until
+// the migration is cut over, and from the target after that. Writes go to
+// the backend that serves reads first and fail with it; a failed write to
+// the other backend is logged and repaired by the next backfill.
+type DualWriteMemoryRepository struct {
+	source, target portrepos.MemoryRepository
+	reads          *storageMigrationReadSwitch
+}
+
+// NewDualWriteMemoryRepository creates a DualWriteMemoryRepository. The
+// cutover state of the "memory" store is read from states.
+func NewDualWriteMemoryRepository(source, target portrepos.MemoryRepository, sourceBackend, targetBackend string, states *KubernetesStorageMigrationStateRepository) *DualWriteMemoryRepository {
+	return &DualWriteMemoryRepository{
+		source: source,
+		target: target,
+		reads: &storageMigrationReadSwitch{
+			states: states,
+			store:  StorageMigrationMemory,
+			source: sourceBackend,
+			target: targetBackend,
+		},
+	}
+}
+
+// primary returns the backend that serves reads and the other one.
+func (r *DualWriteMemoryRepository) primary(ctx context.Context) (portrepos.MemoryRepository, portrepos.MemoryRepository) {
+	if r.reads.readsTarget(ctx) {
+		return r.target, r.source
+	}
+	return r.source, r.target
+}
+
+func (r *DualWriteMemoryRepository) Create(ctx context.Context, memory *entities.Memory) error {
+	primary, secondary := r.primary(ctx)
+	if err := primary.Create(ctx, memory); err != nil {
+		return err
+	}
+	if err := secondary.Create(ctx, memory); err != nil {
+		log.Printf("[STORAGE_MIGRATION] Failed to write memory %s to the other backend: %v", memory.ID(), err)
+	}
+	return nil
+}
+
+func (r *DualWriteMemoryRepository) GetByID(ctx context.Context, id string) (*entities.Memory, error) {
+	primary, _ := r.primary(ctx)
+	return primary.GetByID(ctx, id)
+}
+
+func (r *DualWriteMemoryRepository) List(ctx context.Context, filter portrepos.MemoryFilter) ([]*entities.Memory, error) {
+	primary, _ := r.primary(ctx)
+	return primary.List(ctx, filter)
+}
+
+func (r *DualWriteMemoryRepository) Update(ctx context.Context, memory *entities.Memory) error {
+	primary, secondary := r.primary(ctx)
+	if err := primary.Update(ctx, memory); err != nil {
+		return err
+	}
+	if err := secondary.Update(ctx, memory); err != nil {
+		// The memory may predate the migration and not be backfilled yet.
+		if createErr := secondary.Create(ctx, memory); createErr != nil {
+			log.Printf("[STORAGE_MIGRATION] Failed to write memory %s to the other backend: %v", memory.ID(), err)
+		}
+	}
+	return nil
+}
+
+func (r *DualWriteMemoryRepository) Delete(ctx context.Context, id string) error {
+	primary, secondary := r.primary(ctx)
+	if err := primary.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := secondary.Delete(ctx, id); err != nil {
+		log.Printf("[STORAGE_MIGRATION] Failed to delete memory %s from the other backend: %v", id, err)
+	}
+	return nil
+}
+
+// DualWriteSessionRepository is the session store counterpart of
+// DualWriteMemoryRepository.
+type DualWriteSessionRepository struct {
+	source, target portrepos.SessionRepository
+	reads          *storageMigrationReadSwitch
+}
+
+// NewDualWriteSessionRepository creates a DualWriteSessionRepository. The
+// cutover state of the "session_store" store is read from states.
+func NewDualWriteSessionRepository(source, target portrepos.SessionRepository, sourceBackend, targetBackend string, states *KubernetesStorageMigrationStateRepository) *DualWriteSessionRepository {
+	return &DualWriteSessionRepository{
+		source: source,
+		target: target,
+		reads: &storageMigrationReadSwitch{
+			states: states,
+			store:  StorageMigrationSessionStore,
+			source: sourceBackend,
+			target: targetBackend,
+		},
+	}
+}
+
+func (r *DualWriteSessionRepository) primary(ctx context.Context) (portrepos.SessionRepository, portrepos.SessionRepository) {
+	if r.reads.readsTarget(ctx) {
+		return r.target, r.source
+	}
+	return r.source, r.target
+}
+
+func (r *DualWriteSessionRepository) Save(ctx context.Context, record *portrepos.SessionRecord) error {
+	primary, secondary := r.primary(ctx)
+	if err := primary.Save(ctx, record); err != nil {
+		return err
+	}
+	if err := secondary.Save(ctx, record); err != nil {
+		log.Printf("[STORAGE_MIGRATION] Failed to write session %s to the other backend: %v", record.ID, err)
+	}
+	return nil
+}
+
+func (r *DualWriteSessionRepository) Get(ctx context.Context, sessionID string) (*portrepos.SessionRecord, error) {
+	primary, _ := r.primary(ctx)
+	return primary.Get(ctx, sessionID)
+}
+
+func (r *DualWriteSessionRepository) List(ctx context.Context, userID string) ([]*portrepos.SessionRecord, error) {
+	primary, _ := r.primary(ctx)
+	return primary.List(ctx, userID)
+}
+
+func (r *DualWriteSessionRepository) Delete(ctx context.Context, sessionID string) error {
+	primary, secondary := r.primary(ctx)
+	if err := primary.Delete(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := secondary.Delete(ctx, sessionID); err != nil {
+		log.Printf("[STORAGE_MIGRATION] Failed to delete session %s from the other backend: %v", sessionID, err)
+	}
+	return nil
+}
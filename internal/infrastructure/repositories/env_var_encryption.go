@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+// encryptedEnvVarJSON is the JSON representation of a single encrypted env var value.
+// It embeds the encryption metadata needed to select the correct decryptor.
+type encryptedEnvVarJSON struct {
+	EncryptedValue string    `json:"v"`
+	Algorithm      string    `json:"alg"`
+	KeyID          string    `json:"kid"`
+	EncryptedAt    time.Time `json:"at"`
+	Version        string    `json:"ver,omitempty"`
+}
+
+// encryptEnvVars encrypts env var values with the primary service of
+// registry. It returns nil when they are stored as plain text: without a
+// registry or when the primary algorithm is "noop".
+func encryptEnvVars(ctx context.Context, registry *infraservices.EncryptionServiceRegistry, envVars map[string]string) (map[string]encryptedEnvVarJSON, error) {
+	if registry == nil {
+		return nil, nil
+	}
+	enc := registry.GetForEncryption()
+	if enc == nil || enc.Algorithm() == "noop" {
+		return nil, nil
+	}
+	encryptedVars := make(map[string]encryptedEnvVarJSON, len(envVars))
+	for k, v := range envVars {
+		encrypted, err := enc.Encrypt(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt env var %q: %w", k, err)
+		}
+		encryptedVars[k] = encryptedEnvVarJSON{
+			EncryptedValue: encrypted.EncryptedValue,
+			Algorithm:      encrypted.Metadata.Algorithm,
+			KeyID:          encrypted.Metadata.KeyID,
+			EncryptedAt:    encrypted.Metadata.EncryptedAt,
+			Version:        encrypted.Metadata.Version,
+		}
+	}
+	return encryptedVars, nil
+}
+
+// decryptEnvVars decrypts env var values into merged. Values without a
+// matching decryption service or that fail to decrypt are logged under
+// logPrefix and skipped.
+func decryptEnvVars(ctx context.Context, registry *infraservices.EncryptionServiceRegistry, encryptedVars map[string]encryptedEnvVarJSON, merged map[string]string, logPrefix string) {
+	for k, ev := range encryptedVars {
+		metadata := domainservices.EncryptionMetadata{
+			Algorithm:   ev.Algorithm,
+			KeyID:       ev.KeyID,
+			EncryptedAt: ev.EncryptedAt,
+			Version:     ev.Version,
+		}
+		var decSvc domainservices.EncryptionService
+		if registry != nil {
+			decSvc = registry.GetForDecryption(metadata)
+		}
+		if decSvc == nil {
+			log.Printf("[%s] No decryption service for env var %q (alg=%s kid=%s), skipping", logPrefix, k, ev.Algorithm, ev.KeyID)
+			continue
+		}
+		plaintext, err := decSvc.Decrypt(ctx, &domainservices.EncryptedData{
+			EncryptedValue: ev.EncryptedValue,
+			Metadata:       metadata,
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to decrypt env var %q: %v, skipping", logPrefix, k, err)
+			continue
+		}
+		merged[k] = plaintext
+	}
+}
@@ -99,6 +99,18 @@ func resolveUserID(scope entities.ResourceScope, ownerID, teamID string) string
 // errUserNotFound is returned by getUser when the user does not exist (HTTP 404).
 var errUserNotFound = fmt.Errorf("user not found")
 
+// diagnosticsUserID is looked up by Ping. It is never created.
+const diagnosticsUserID = "agentapi-proxy-diagnostics"
+
+// Ping checks that memory-server is reachable and accepts the admin token.
+func (r *ExternalMemoryRepository) Ping(ctx context.Context) error {
+	_, err := r.getUser(ctx, diagnosticsUserID)
+	if errors.Is(err, errUserNotFound) {
+		return nil
+	}
+	return err
+}
+
 // ensureUser gets or creates a user in memory-server and caches their token.
 // It only calls createUser when the user provably does not exist (HTTP 404).
 // Any other error from getUser is propagated without attempting creation, to
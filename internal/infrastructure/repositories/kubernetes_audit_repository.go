@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+const (
+	AuditConfigMapPrefix = "agentapi-audit-"
+	AuditConfigMapKey    = "events.json"
+	LabelAuditEvents     = "agentapi.proxy/audit-events"
+	LabelAuditDay        = "agentapi.proxy/audit-day"
+
+	// maxAuditDetailLength keeps a day of events within the ConfigMap size limit.
+	maxAuditDetailLength = 256
+)
+
+var _ portrepos.AuditRepository = (*KubernetesAuditRepository)(nil)
+
+// KubernetesAuditRepository stores audit events as JSON in one ConfigMap per
+// UTC day, so every proxy replica writes to and reports from the same log.
+type KubernetesAuditRepository struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewKubernetesAuditRepository creates a new KubernetesAuditRepository
+func NewKubernetesAuditRepository(client kubernetes.Interface, namespace string) *KubernetesAuditRepository {
+	return &KubernetesAuditRepository{client: client, namespace: namespace}
+}
+
+// RecordAuditEvent appends event to the ConfigMap of its day, creating it on
+// the first event of the day.
+func (r *KubernetesAuditRepository) RecordAuditEvent(ctx context.Context, event entities.AuditEvent) error {
+	if len(event.Detail) > maxAuditDetailLength {
+		event.Detail = event.Detail[:maxAuditDetailLength]
+	}
+	day := auditDay(event.Timestamp)
+	name := AuditConfigMapPrefix + day
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			data, err := json.Marshal([]entities.AuditEvent{event})
+			if err != nil {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: r.namespace,
+					Labels: map[string]string{
+						LabelAuditEvents: "true",
+						LabelAuditDay:    day,
+					},
+				},
+				Data: map[string]string{AuditConfigMapKey: string(data)},
+			}
+			_, err = r.client.CoreV1().ConfigMaps(r.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// Lost the race with another replica; retry as an update.
+				return errors.NewConflict(corev1.Resource("configmaps"), name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get audit configmap: %w", err)
+		}
+
+		events, err := decodeAuditEvents(cm)
+		if err != nil {
+			return err
+		}
+		return r.update(ctx, cm, appendAuditEvent(events, event))
+	})
+}
+
+// ListAuditEvents returns events with from <= timestamp < to, oldest first.
+func (r *KubernetesAuditRepository) ListAuditEvents(ctx context.Context, from, to time.Time) ([]entities.AuditEvent, error) {
+	cms, err := r.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var buckets [][]entities.AuditEvent
+	for i := range cms {
+		if !auditDayInRange(cms[i].Labels[LabelAuditDay], from, to) {
+			continue
+		}
+		events, err := decodeAuditEvents(&cms[i])
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, events)
+	}
+	return filterAuditEvents(buckets, from, to), nil
+}
+
+// OldestAuditEvent returns the timestamp of the oldest stored event.
+func (r *KubernetesAuditRepository) OldestAuditEvent(ctx context.Context) (time.Time, error) {
+	cms, err := r.list(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for i := range cms {
+		events, err := decodeAuditEvents(&cms[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Days are sorted, so the first non-empty day holds the oldest event.
+		var oldest time.Time
+		for _, e := range events {
+			if oldest.IsZero() || e.Timestamp.Before(oldest) {
+				oldest = e.Timestamp
+			}
+		}
+		if !oldest.IsZero() {
+			return oldest, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// PurgeAuditEvents deletes the ConfigMaps of days entirely before before and
+// trims the day that contains it.
+func (r *KubernetesAuditRepository) PurgeAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	cms, err := r.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range cms {
+		cm := &cms[i]
+		start, err := time.Parse("20060102", cm.Labels[LabelAuditDay])
+		if err != nil || !start.Before(before) {
+			continue
+		}
+		events, err := decodeAuditEvents(cm)
+		if err != nil {
+			return purged, err
+		}
+		kept := []entities.AuditEvent{}
+		for _, e := range events {
+			if !e.Timestamp.Before(before) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == len(events) {
+			continue
+		}
+		if len(kept) == 0 {
+			err = r.client.CoreV1().ConfigMaps(r.namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return purged, fmt.Errorf("failed to delete audit configmap %s: %w", cm.Name, err)
+			}
+		} else if err := r.update(ctx, cm, kept); err != nil {
+			return purged, err
+		}
+		purged += len(events) - len(kept)
+	}
+	return purged, nil
+}
+
+// list returns the audit ConfigMaps ordered by day, oldest first.
+func (r *KubernetesAuditRepository) list(ctx context.Context) ([]corev1.ConfigMap, error) {
+	list, err := r.client.CoreV1().ConfigMaps(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: LabelAuditEvents + "=true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit configmaps: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Labels[LabelAuditDay] < list.Items[j].Labels[LabelAuditDay]
+	})
+	return list.Items, nil
+}
+
+func (r *KubernetesAuditRepository) update(ctx context.Context, cm *corev1.ConfigMap, events []entities.AuditEvent) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[AuditConfigMapKey] = string(data)
+	_, err = r.client.CoreV1().ConfigMaps(r.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+func decodeAuditEvents(cm *corev1.ConfigMap) ([]entities.AuditEvent, error) {
+	events := []entities.AuditEvent{}
+	raw := cm.Data[AuditConfigMapKey]
+	if raw == "" {
+		return events, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &events); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit events: %w", err)
+	}
+	return events, nil
+}
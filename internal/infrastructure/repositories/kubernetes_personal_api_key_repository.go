@@ -12,6 +12,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
 )
 
 const (
@@ -27,15 +28,17 @@ const (
 
 // personalAPIKeyJSON is the JSON representation of personal API key metadata
 type personalAPIKeyJSON struct {
-	UserID    string    `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID          string    `json:"user_id"`
+	ExternalSecrets string    `json:"external_secrets,omitempty"` // Secrets provider holding the API key
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // KubernetesPersonalAPIKeyRepository implements PersonalAPIKeyRepository using Kubernetes Secrets
 type KubernetesPersonalAPIKeyRepository struct {
-	client    kubernetes.Interface
-	namespace string
+	client          kubernetes.Interface
+	namespace       string
+	secretsProvider domainservices.SecretsProvider // optional; nil = keep the API key in the Secret
 }
 
 // NewKubernetesPersonalAPIKeyRepository creates a new KubernetesPersonalAPIKeyRepository
@@ -46,6 +49,12 @@ func NewKubernetesPersonalAPIKeyRepository(client kubernetes.Interface, namespac
 	}
 }
 
+// SetSecretsProvider stores API keys in an external secrets provider; the
+// Kubernetes Secret then only holds metadata.
+func (r *KubernetesPersonalAPIKeyRepository) SetSecretsProvider(provider domainservices.SecretsProvider) {
+	r.secretsProvider = provider
+}
+
 // Save persists a personal API key
 func (r *KubernetesPersonalAPIKeyRepository) Save(ctx context.Context, apiKey *entities.PersonalAPIKey) error {
 	if err := apiKey.Validate(); err != nil {
@@ -62,10 +71,23 @@ func (r *KubernetesPersonalAPIKeyRepository) Save(ctx context.Context, apiKey *e
 		UpdatedAt: apiKey.UpdatedAt(),
 	}
 
+	stringData := map[string]string{
+		SecretKeyPersonalAPIKey: apiKey.APIKey(),
+	}
+	if r.secretsProvider != nil {
+		data := map[string]string{SecretKeyPersonalAPIKey: apiKey.APIKey()}
+		if err := r.secretsProvider.Put(ctx, r.externalSecretsKey(string(apiKey.UserID())), data); err != nil {
+			return fmt.Errorf("failed to store personal API key in %s: %w", r.secretsProvider.Name(), err)
+		}
+		metadata.ExternalSecrets = r.secretsProvider.Name()
+		delete(stringData, SecretKeyPersonalAPIKey)
+	}
+
 	metadataBytes, err := json.Marshal(metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
+	stringData["metadata.json"] = string(metadataBytes)
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -76,11 +98,8 @@ func (r *KubernetesPersonalAPIKeyRepository) Save(ctx context.Context, apiKey *e
 				LabelUserID:         labelValue,
 			},
 		},
-		Type: corev1.SecretTypeOpaque,
-		StringData: map[string]string{
-			SecretKeyPersonalAPIKey: apiKey.APIKey(),
-			"metadata.json":         string(metadataBytes),
-		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: stringData,
 	}
 
 	// Try to create first
@@ -112,7 +131,7 @@ func (r *KubernetesPersonalAPIKeyRepository) FindByUserID(ctx context.Context, u
 		return nil, fmt.Errorf("failed to get personal API key secret: %w", err)
 	}
 
-	return r.fromSecret(secret)
+	return r.fromSecret(ctx, secret)
 }
 
 // Delete removes a personal API key
@@ -127,6 +146,12 @@ func (r *KubernetesPersonalAPIKeyRepository) Delete(ctx context.Context, userID
 		return fmt.Errorf("failed to delete personal API key secret: %w", err)
 	}
 
+	if r.secretsProvider != nil {
+		if err := r.secretsProvider.Delete(ctx, r.externalSecretsKey(string(userID))); err != nil {
+			return fmt.Errorf("failed to delete personal API key from %s: %w", r.secretsProvider.Name(), err)
+		}
+	}
+
 	return nil
 }
 
@@ -145,7 +170,7 @@ func (r *KubernetesPersonalAPIKeyRepository) List(ctx context.Context) ([]*entit
 	apiKeys := make([]*entities.PersonalAPIKey, 0, len(secretList.Items))
 
 	for _, secret := range secretList.Items {
-		apiKey, err := r.fromSecret(&secret)
+		apiKey, err := r.fromSecret(ctx, &secret)
 		if err != nil {
 			// Log error but continue with other secrets
 			fmt.Printf("Warning: failed to parse personal API key from secret %s: %v\n", secret.Name, err)
@@ -162,14 +187,13 @@ func (r *KubernetesPersonalAPIKeyRepository) secretName(userID string) string {
 	return PersonalAPIKeySecretPrefix + sanitizeLabelValue(userID)
 }
 
-// fromSecret converts a Kubernetes Secret to PersonalAPIKey entity
-func (r *KubernetesPersonalAPIKeyRepository) fromSecret(secret *corev1.Secret) (*entities.PersonalAPIKey, error) {
-	// Extract API key from Secret
-	apiKeyBytes, ok := secret.Data[SecretKeyPersonalAPIKey]
-	if !ok {
-		return nil, fmt.Errorf("api_key not found in secret")
-	}
+// externalSecretsKey returns the secrets provider key for the given user ID
+func (r *KubernetesPersonalAPIKeyRepository) externalSecretsKey(userID string) string {
+	return "personal-api-keys/" + sanitizeLabelValue(userID)
+}
 
+// fromSecret converts a Kubernetes Secret to PersonalAPIKey entity
+func (r *KubernetesPersonalAPIKeyRepository) fromSecret(ctx context.Context, secret *corev1.Secret) (*entities.PersonalAPIKey, error) {
 	// Extract metadata
 	metadataBytes, ok := secret.Data["metadata.json"]
 	if !ok {
@@ -181,7 +205,28 @@ func (r *KubernetesPersonalAPIKeyRepository) fromSecret(secret *corev1.Secret) (
 		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 	}
 
-	apiKey := entities.NewPersonalAPIKey(entities.UserID(metadata.UserID), string(apiKeyBytes))
+	// Extract API key from the secrets provider or the Secret
+	var key string
+	if metadata.ExternalSecrets != "" {
+		if r.secretsProvider == nil {
+			return nil, fmt.Errorf("api_key is stored in %s but no secrets provider is configured", metadata.ExternalSecrets)
+		}
+		data, err := r.secretsProvider.Get(ctx, r.externalSecretsKey(metadata.UserID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get api_key from %s: %w", r.secretsProvider.Name(), err)
+		}
+		if key, ok = data[SecretKeyPersonalAPIKey]; !ok {
+			return nil, fmt.Errorf("api_key not found in %s", r.secretsProvider.Name())
+		}
+	} else {
+		apiKeyBytes, ok := secret.Data[SecretKeyPersonalAPIKey]
+		if !ok {
+			return nil, fmt.Errorf("api_key not found in secret")
+		}
+		key = string(apiKeyBytes)
+	}
+
+	apiKey := entities.NewPersonalAPIKey(entities.UserID(metadata.UserID), key)
 	apiKey.SetCreatedAt(metadata.CreatedAt)
 	apiKey.SetUpdatedAt(metadata.UpdatedAt)
 
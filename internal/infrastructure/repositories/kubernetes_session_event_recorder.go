@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+const (
+	SessionEventsConfigMapPrefix = "agentapi-session-events-"
+	SessionEventsConfigMapKey    = "events.json"
+	LabelSessionEvents           = "agentapi.proxy/session-events"
+)
+
+var _ portrepos.EventRecorder = (*KubernetesEventRecorder)(nil)
+
+// KubernetesEventRecorder stores the timeline of each session as JSON in a
+// ConfigMap, so every proxy replica sees the same timeline.
+type KubernetesEventRecorder struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewKubernetesEventRecorder creates a new KubernetesEventRecorder
+func NewKubernetesEventRecorder(client kubernetes.Interface, namespace string) *KubernetesEventRecorder {
+	return &KubernetesEventRecorder{client: client, namespace: namespace}
+}
+
+func (r *KubernetesEventRecorder) configMapName(sessionID string) string {
+	name := SessionEventsConfigMapPrefix + sessionID
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+// RecordSessionEvent appends event to the timeline ConfigMap of its session,
+// creating it on the first event.
+func (r *KubernetesEventRecorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	name := r.configMapName(event.SessionID)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			data, err := json.Marshal([]entities.SessionEvent{event})
+			if err != nil {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: r.namespace,
+					Labels: map[string]string{
+						LabelSessionEvents:          "true",
+						"agentapi.proxy/session-id": event.SessionID,
+					},
+				},
+				Data: map[string]string{SessionEventsConfigMapKey: string(data)},
+			}
+			_, err = r.client.CoreV1().ConfigMaps(r.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// Lost the race with another replica; retry as an update.
+				return errors.NewConflict(corev1.Resource("configmaps"), name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get session events configmap: %w", err)
+		}
+
+		events, err := decodeSessionEvents(cm)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(appendSessionEvent(events, event))
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[SessionEventsConfigMapKey] = string(data)
+		_, err = r.client.CoreV1().ConfigMaps(r.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// ListSessionEvents returns the timeline of a session, oldest first.
+func (r *KubernetesEventRecorder) ListSessionEvents(ctx context.Context, sessionID string) ([]entities.SessionEvent, error) {
+	cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, r.configMapName(sessionID), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []entities.SessionEvent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session events configmap: %w", err)
+	}
+	return decodeSessionEvents(cm)
+}
+
+// DeleteSessionEvents deletes the timeline ConfigMap of a session.
+func (r *KubernetesEventRecorder) DeleteSessionEvents(ctx context.Context, sessionID string) error {
+	err := r.client.CoreV1().ConfigMaps(r.namespace).Delete(ctx, r.configMapName(sessionID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete session events configmap: %w", err)
+	}
+	return nil
+}
+
+func decodeSessionEvents(cm *corev1.ConfigMap) ([]entities.SessionEvent, error) {
+	events := []entities.SessionEvent{}
+	raw := cm.Data[SessionEventsConfigMapKey]
+	if raw == "" {
+		return events, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &events); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session events: %w", err)
+	}
+	return events, nil
+}
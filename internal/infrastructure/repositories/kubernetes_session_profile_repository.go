@@ -41,6 +41,7 @@ type sessionProfileJSON struct {
 	Scope        entities.ResourceScope   `json:"scope,omitempty"`
 	TeamID       string                   `json:"team_id,omitempty"`
 	IsDefault    bool                     `json:"is_default,omitempty"`
+	TeamDefault  bool                     `json:"team_default,omitempty"`
 	SelectorTags map[string]string        `json:"selector_tags,omitempty"`
 	Config       sessionProfileConfigJSON `json:"config"`
 	CreatedAt    time.Time                `json:"created_at"`
@@ -309,6 +310,7 @@ func (r *KubernetesSessionProfileRepository) jsonToEntity(pj *sessionProfileJSON
 	profile.SetScope(pj.Scope)
 	profile.SetTeamID(pj.TeamID)
 	profile.SetIsDefault(pj.IsDefault)
+	profile.SetIsTeamDefault(pj.TeamDefault)
 	profile.SetSelectorTags(pj.SelectorTags)
 	profile.SetCreatedAt(pj.CreatedAt)
 	profile.SetUpdatedAt(pj.UpdatedAt)
@@ -361,6 +363,7 @@ func (r *KubernetesSessionProfileRepository) entityToJSON(profile *entities.Sess
 		Scope:        profile.Scope(),
 		TeamID:       profile.TeamID(),
 		IsDefault:    profile.IsDefault(),
+		TeamDefault:  profile.IsTeamDefault(),
 		SelectorTags: profile.SelectorTags(),
 		Config: sessionProfileConfigJSON{
 			Environment:            cfg.Environment(),
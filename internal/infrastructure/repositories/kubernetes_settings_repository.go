@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -28,17 +29,12 @@ const (
 	SecretKeySettings = "settings.json"
 	// SettingsSecretPrefix is the prefix for settings Secret names
 	SettingsSecretPrefix = "agentapi-settings-"
-)
 
-// encryptedEnvVarJSON is the JSON representation of a single encrypted env var value.
-// It embeds the encryption metadata needed to select the correct decryptor.
-type encryptedEnvVarJSON struct {
-	EncryptedValue string    `json:"v"`
-	Algorithm      string    `json:"alg"`
-	KeyID          string    `json:"kid"`
-	EncryptedAt    time.Time `json:"at"`
-	Version        string    `json:"ver,omitempty"`
-}
+	// externalEnvVarPrefix prefixes env var keys in the secrets provider
+	externalEnvVarPrefix = "env."
+	// externalGitHubTokenKey is the GitHub sync token key in the secrets provider
+	externalGitHubTokenKey = "git_sync.github_token"
+)
 
 // settingsJSON is the JSON representation of settings stored in Secret
 type settingsJSON struct {
@@ -54,9 +50,16 @@ type settingsJSON struct {
 	PreferredTeamID         string                                 `json:"preferred_team_id,omitempty"`         // "org/team-slug" format
 	SlackUserID             string                                 `json:"slack_user_id,omitempty"`             // Slack DM notification user ID
 	NotificationChannels    []string                               `json:"notification_channels,omitempty"`     // Active notification channels
+	Locale                  string                                 `json:"locale,omitempty"`                    // Language of messages and notifications
+	ExternalSecrets         string                                 `json:"external_secrets,omitempty"`          // Secrets provider holding env vars and the GitHub sync token
 	ExternalSessionManagers []entities.ExternalSessionManagerEntry `json:"external_session_managers,omitempty"` // Registered external session managers
 	GitSync                 *gitSyncJSON                           `json:"git_sync,omitempty"`
 	DefaultSessionProfileID string                                 `json:"default_session_profile_id,omitempty"`
+	CapabilityPolicy        *capabilityPolicyJSON                  `json:"capability_policy,omitempty"`
+	SessionOrganization     *entities.SessionOrganization          `json:"session_organization,omitempty"` // Pinned sessions and folders
+	SavedSearches           []entities.SavedSearch                 `json:"saved_searches,omitempty"`       // Named session filters
+	NotificationSavedSearch string                                 `json:"notification_saved_search,omitempty"`
+	NotificationTypes       []string                               `json:"notification_types,omitempty"` // Notification types the user receives
 	CreatedAt               time.Time                              `json:"created_at"`
 	UpdatedAt               time.Time                              `json:"updated_at"`
 }
@@ -79,6 +82,8 @@ type mcpServerJSON struct {
 	Args    []string          `json:"args,omitempty"`
 	Env     map[string]string `json:"env,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	// Enabled is only stored for disabled servers; absent means enabled
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 // marketplaceJSON is the JSON representation of a single marketplace
@@ -106,11 +111,19 @@ type gitSyncJSON struct {
 	LastPushedAt *time.Time               `json:"last_pushed_at,omitempty"`
 }
 
+// capabilityPolicyJSON is the JSON representation of a capability policy
+type capabilityPolicyJSON struct {
+	AllowedCapabilities []string  `json:"allowed_capabilities"`
+	UpdatedBy           string    `json:"updated_by,omitempty"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
 // KubernetesSettingsRepository implements SettingsRepository using Kubernetes Secrets
 type KubernetesSettingsRepository struct {
 	client             kubernetes.Interface
 	namespace          string
 	encryptionRegistry *infraservices.EncryptionServiceRegistry // optional; nil = store env_vars as plain text
+	secretsProvider    domainservices.SecretsProvider           // optional; nil = keep secrets in the settings Secret
 }
 
 // NewKubernetesSettingsRepository creates a new KubernetesSettingsRepository.
@@ -129,6 +142,13 @@ func NewKubernetesSettingsRepository(client kubernetes.Interface, namespace stri
 	}
 }
 
+// SetSecretsProvider stores env vars and GitHub sync tokens in an external
+// secrets provider instead of the settings Secret. Settings saved before
+// the provider was configured are moved to it on their next save.
+func (r *KubernetesSettingsRepository) SetSecretsProvider(provider domainservices.SecretsProvider) {
+	r.secretsProvider = provider
+}
+
 // Save persists settings (creates or updates)
 func (r *KubernetesSettingsRepository) Save(ctx context.Context, settings *entities.Settings) error {
 	if err := settings.Validate(); err != nil {
@@ -213,6 +233,12 @@ func (r *KubernetesSettingsRepository) Delete(ctx context.Context, name string)
 		return fmt.Errorf("failed to delete settings secret: %w", err)
 	}
 
+	if r.secretsProvider != nil {
+		if err := r.secretsProvider.Delete(ctx, r.externalSecretsKey(name)); err != nil {
+			log.Printf("[SETTINGS] Failed to delete external secrets of %s: %v", name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -258,22 +284,6 @@ func (r *KubernetesSettingsRepository) secretName(name string) string {
 	return SettingsSecretPrefix + sanitizeSecretName(name)
 }
 
-// encryptionSvc returns the primary encryption service, or nil if no registry is configured.
-func (r *KubernetesSettingsRepository) encryptionSvc() domainservices.EncryptionService {
-	if r.encryptionRegistry == nil {
-		return nil
-	}
-	return r.encryptionRegistry.GetForEncryption()
-}
-
-// decryptionSvc returns the appropriate decryption service for the given metadata, or nil if no registry is configured.
-func (r *KubernetesSettingsRepository) decryptionSvc(metadata domainservices.EncryptionMetadata) domainservices.EncryptionService {
-	if r.encryptionRegistry == nil {
-		return nil
-	}
-	return r.encryptionRegistry.GetForDecryption(metadata)
-}
-
 // toJSON converts Settings entity to JSON bytes, encrypting env_vars when a non-noop
 // EncryptionServiceRegistry is configured.
 func (r *KubernetesSettingsRepository) toJSON(ctx context.Context, settings *entities.Settings) ([]byte, error) {
@@ -307,6 +317,10 @@ func (r *KubernetesSettingsRepository) toJSON(ctx context.Context, settings *ent
 				Env:     server.Env(),
 				Headers: server.Headers(),
 			}
+			if !server.Enabled() {
+				disabled := false
+				sj.MCPServers[name].Enabled = &disabled
+			}
 		}
 	}
 
@@ -323,22 +337,13 @@ func (r *KubernetesSettingsRepository) toJSON(ctx context.Context, settings *ent
 		sj.EnabledPlugins = plugins
 	}
 
-	if envVars := settings.EnvVars(); len(envVars) > 0 {
-		if enc := r.encryptionSvc(); enc != nil && enc.Algorithm() != "noop" {
-			sj.EncryptedEnvVars = make(map[string]encryptedEnvVarJSON, len(envVars))
-			for k, v := range envVars {
-				encrypted, err := enc.Encrypt(ctx, v)
-				if err != nil {
-					return nil, fmt.Errorf("failed to encrypt env var %q: %w", k, err)
-				}
-				sj.EncryptedEnvVars[k] = encryptedEnvVarJSON{
-					EncryptedValue: encrypted.EncryptedValue,
-					Algorithm:      encrypted.Metadata.Algorithm,
-					KeyID:          encrypted.Metadata.KeyID,
-					EncryptedAt:    encrypted.Metadata.EncryptedAt,
-					Version:        encrypted.Metadata.Version,
-				}
-			}
+	if envVars := settings.EnvVars(); len(envVars) > 0 && r.secretsProvider == nil {
+		encryptedVars, err := encryptEnvVars(ctx, r.encryptionRegistry, envVars)
+		if err != nil {
+			return nil, err
+		}
+		if encryptedVars != nil {
+			sj.EncryptedEnvVars = encryptedVars
 		} else {
 			sj.EnvVars = envVars
 		}
@@ -356,10 +361,24 @@ func (r *KubernetesSettingsRepository) toJSON(ctx context.Context, settings *ent
 		sj.NotificationChannels = channels
 	}
 
+	if locale := settings.Locale(); locale != "" {
+		sj.Locale = locale
+	}
+
 	if managers := settings.ExternalSessionManagers(); len(managers) > 0 {
 		sj.ExternalSessionManagers = managers
 	}
 
+	if org := settings.SessionOrganization(); org != nil && (len(org.Favorites) > 0 || len(org.Folders) > 0) {
+		sj.SessionOrganization = org
+	}
+
+	if searches := settings.SavedSearches(); len(searches) > 0 {
+		sj.SavedSearches = searches
+	}
+	sj.NotificationSavedSearch = settings.NotificationSavedSearch()
+	sj.NotificationTypes = settings.NotificationTypes()
+
 	if gitSync := settings.GitSync(); gitSync != nil {
 		j := &gitSyncJSON{
 			Enabled:      gitSync.Enabled,
@@ -385,9 +404,85 @@ func (r *KubernetesSettingsRepository) toJSON(ctx context.Context, settings *ent
 		sj.DefaultSessionProfileID = id
 	}
 
+	if policy := settings.CapabilityPolicy(); policy != nil {
+		allowed := make([]string, 0, len(policy.Allowed()))
+		for _, c := range policy.Allowed() {
+			allowed = append(allowed, string(c))
+		}
+		sj.CapabilityPolicy = &capabilityPolicyJSON{
+			AllowedCapabilities: allowed,
+			UpdatedBy:           policy.UpdatedBy(),
+			UpdatedAt:           policy.UpdatedAt(),
+		}
+	}
+
+	if r.secretsProvider != nil {
+		if err := r.storeExternalSecrets(ctx, settings, sj); err != nil {
+			return nil, err
+		}
+	}
+
 	return json.Marshal(sj)
 }
 
+// externalSecretsKey returns the secrets provider key for the given settings name
+func (r *KubernetesSettingsRepository) externalSecretsKey(name string) string {
+	return "settings/" + sanitizeSecretName(name)
+}
+
+// storeExternalSecrets writes env vars and the GitHub sync token to the
+// secrets provider and leaves only a reference to it in sj.
+func (r *KubernetesSettingsRepository) storeExternalSecrets(ctx context.Context, settings *entities.Settings, sj *settingsJSON) error {
+	data := make(map[string]string)
+	for k, v := range settings.EnvVars() {
+		data[externalEnvVarPrefix+k] = v
+	}
+	if sj.GitSync != nil && sj.GitSync.GitHubToken != "" {
+		data[externalGitHubTokenKey] = sj.GitSync.GitHubToken
+		sj.GitSync.GitHubToken = ""
+	}
+
+	key := r.externalSecretsKey(settings.Name())
+	if len(data) == 0 {
+		return r.secretsProvider.Delete(ctx, key)
+	}
+	if err := r.secretsProvider.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("failed to store secrets in %s: %w", r.secretsProvider.Name(), err)
+	}
+	sj.ExternalSecrets = r.secretsProvider.Name()
+	return nil
+}
+
+// loadExternalSecrets reads env vars and the GitHub sync token from the secrets provider.
+func (r *KubernetesSettingsRepository) loadExternalSecrets(ctx context.Context, settings *entities.Settings, sj *settingsJSON) error {
+	if r.secretsProvider == nil {
+		return fmt.Errorf("settings %s keep secrets in %s but no secrets provider is configured", settings.Name(), sj.ExternalSecrets)
+	}
+	data, err := r.secretsProvider.Get(ctx, r.externalSecretsKey(settings.Name()))
+	if err != nil {
+		if stderrors.Is(err, domainservices.ErrSecretNotFound) {
+			log.Printf("[SETTINGS] External secrets of %s not found in %s", settings.Name(), r.secretsProvider.Name())
+			return nil
+		}
+		return fmt.Errorf("failed to load secrets from %s: %w", r.secretsProvider.Name(), err)
+	}
+
+	envVars := make(map[string]string)
+	for k, v := range data {
+		if name, ok := strings.CutPrefix(k, externalEnvVarPrefix); ok {
+			envVars[name] = v
+		}
+	}
+	if len(envVars) > 0 {
+		settings.SetEnvVars(envVars)
+	}
+	if gs := settings.GitSync(); gs != nil {
+		gs.GitHubToken = data[externalGitHubTokenKey]
+	}
+	settings.SetUpdatedAt(sj.UpdatedAt)
+	return nil
+}
+
 // fromSecret converts a Kubernetes Secret to Settings entity, decrypting env_vars as needed.
 func (r *KubernetesSettingsRepository) fromSecret(ctx context.Context, secret *corev1.Secret) (*entities.Settings, error) {
 	data, ok := secret.Data[SecretKeySettings]
@@ -431,6 +526,9 @@ func (r *KubernetesSettingsRepository) fromSecret(ctx context.Context, secret *c
 			server.SetArgs(serverJSON.Args)
 			server.SetEnv(serverJSON.Env)
 			server.SetHeaders(serverJSON.Headers)
+			if serverJSON.Enabled != nil {
+				server.SetEnabled(*serverJSON.Enabled)
+			}
 
 			mcpServers.SetServer(name, server)
 		}
@@ -474,34 +572,7 @@ func (r *KubernetesSettingsRepository) fromSecret(ctx context.Context, secret *c
 	{
 		merged := make(map[string]string)
 		// 1. Decrypt encrypted_env_vars
-		if len(sj.EncryptedEnvVars) > 0 {
-			for k, ev := range sj.EncryptedEnvVars {
-				decSvc := r.decryptionSvc(domainservices.EncryptionMetadata{
-					Algorithm:   ev.Algorithm,
-					KeyID:       ev.KeyID,
-					EncryptedAt: ev.EncryptedAt,
-					Version:     ev.Version,
-				})
-				if decSvc == nil {
-					log.Printf("[SETTINGS] No decryption service for env var %q (alg=%s kid=%s), skipping", k, ev.Algorithm, ev.KeyID)
-					continue
-				}
-				plaintext, err := decSvc.Decrypt(ctx, &domainservices.EncryptedData{
-					EncryptedValue: ev.EncryptedValue,
-					Metadata: domainservices.EncryptionMetadata{
-						Algorithm:   ev.Algorithm,
-						KeyID:       ev.KeyID,
-						EncryptedAt: ev.EncryptedAt,
-						Version:     ev.Version,
-					},
-				})
-				if err != nil {
-					log.Printf("[SETTINGS] Failed to decrypt env var %q: %v, skipping", k, err)
-					continue
-				}
-				merged[k] = plaintext
-			}
-		}
+		decryptEnvVars(ctx, r.encryptionRegistry, sj.EncryptedEnvVars, merged, "SETTINGS")
 		// 2. Merge plain env_vars (backward compat; don't overwrite already-decrypted keys)
 		for k, v := range sj.EnvVars {
 			if _, exists := merged[k]; !exists {
@@ -532,11 +603,32 @@ func (r *KubernetesSettingsRepository) fromSecret(ctx context.Context, secret *c
 		settings.SetUpdatedAt(sj.UpdatedAt)
 	}
 
+	if sj.Locale != "" {
+		settings.SetLocale(sj.Locale)
+		settings.SetUpdatedAt(sj.UpdatedAt)
+	}
+
 	if len(sj.ExternalSessionManagers) > 0 {
 		settings.SetExternalSessionManagers(sj.ExternalSessionManagers)
 		settings.SetUpdatedAt(sj.UpdatedAt)
 	}
 
+	if sj.SessionOrganization != nil {
+		settings.SetSessionOrganization(sj.SessionOrganization)
+		settings.SetUpdatedAt(sj.UpdatedAt)
+	}
+
+	if len(sj.SavedSearches) > 0 || sj.NotificationSavedSearch != "" {
+		settings.SetSavedSearches(sj.SavedSearches)
+		settings.SetNotificationSavedSearch(sj.NotificationSavedSearch)
+		settings.SetUpdatedAt(sj.UpdatedAt)
+	}
+
+	if len(sj.NotificationTypes) > 0 {
+		settings.SetNotificationTypes(sj.NotificationTypes)
+		settings.SetUpdatedAt(sj.UpdatedAt)
+	}
+
 	if sj.GitSync != nil {
 		gs := &entities.GitSyncConfig{
 			Enabled:      sj.GitSync.Enabled,
@@ -563,6 +655,24 @@ func (r *KubernetesSettingsRepository) fromSecret(ctx context.Context, secret *c
 		settings.SetDefaultSessionProfileID(sj.DefaultSessionProfileID)
 	}
 
+	if sj.CapabilityPolicy != nil {
+		allowed := make([]entities.SessionCapability, 0, len(sj.CapabilityPolicy.AllowedCapabilities))
+		for _, c := range sj.CapabilityPolicy.AllowedCapabilities {
+			allowed = append(allowed, entities.SessionCapability(c))
+		}
+		policy := entities.NewCapabilityPolicy(allowed)
+		policy.SetUpdatedBy(sj.CapabilityPolicy.UpdatedBy)
+		policy.SetUpdatedAt(sj.CapabilityPolicy.UpdatedAt)
+		settings.SetCapabilityPolicy(policy)
+		settings.SetUpdatedAt(sj.UpdatedAt)
+	}
+
+	if sj.ExternalSecrets != "" {
+		if err := r.loadExternalSecrets(ctx, settings, &sj); err != nil {
+			return nil, err
+		}
+	}
+
 	return settings, nil
 }
 
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+// memorySecretsProvider is an in-memory SecretsProvider for tests
+type memorySecretsProvider struct {
+	secrets map[string]map[string]string
+}
+
+func (p *memorySecretsProvider) Get(_ context.Context, key string) (map[string]string, error) {
+	data, ok := p.secrets[key]
+	if !ok {
+		return nil, domainservices.ErrSecretNotFound
+	}
+	return data, nil
+}
+
+func (p *memorySecretsProvider) Put(_ context.Context, key string, data map[string]string) error {
+	p.secrets[key] = data
+	return nil
+}
+
+func (p *memorySecretsProvider) Delete(_ context.Context, key string) error {
+	delete(p.secrets, key)
+	return nil
+}
+
+func (p *memorySecretsProvider) Name() string { return "memory" }
+
+func TestKubernetesSettingsRepository_ExternalSecrets(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &memorySecretsProvider{secrets: map[string]map[string]string{}}
+	repo := NewKubernetesSettingsRepository(client, "default")
+	repo.SetSecretsProvider(provider)
+	ctx := context.Background()
+
+	settings := entities.NewSettings("myorg/backend")
+	settings.SetEnvVars(map[string]string{"API_TOKEN": "s3cret"})
+	settings.SetGitSync(&entities.GitSyncConfig{Enabled: true, RepoFullName: "myorg/settings", GitHubToken: "ghp_token"})
+	require.NoError(t, repo.Save(ctx, settings))
+
+	// The Kubernetes Secret only references the provider
+	secret, err := client.CoreV1().Secrets("default").Get(ctx, "agentapi-settings-myorg-backend", metav1.GetOptions{})
+	require.NoError(t, err)
+	raw := string(secret.Data[SecretKeySettings])
+	assert.NotContains(t, raw, "s3cret")
+	assert.NotContains(t, raw, "ghp_token")
+	assert.Contains(t, raw, `"external_secrets":"memory"`)
+	assert.Equal(t, map[string]string{
+		"env.API_TOKEN":         "s3cret",
+		"git_sync.github_token": "ghp_token",
+	}, provider.secrets["settings/myorg-backend"])
+
+	// A value rotated in the provider is returned on the next read
+	provider.secrets["settings/myorg-backend"]["env.API_TOKEN"] = "rotated"
+	loaded, err := repo.FindByName(ctx, "myorg/backend")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_TOKEN": "rotated"}, loaded.EnvVars())
+	require.NotNil(t, loaded.GitSync())
+	assert.Equal(t, "ghp_token", loaded.GitSync().GitHubToken)
+
+	// Reading requires the provider once secrets were moved to it
+	_, err = NewKubernetesSettingsRepository(client, "default").FindByName(ctx, "myorg/backend")
+	assert.Error(t, err)
+
+	require.NoError(t, repo.Delete(ctx, "myorg/backend"))
+	assert.Empty(t, provider.secrets)
+}
@@ -377,6 +377,35 @@ func TestKubernetesSettingsRepository_FindByName(t *testing.T) {
 	}
 }
 
+func TestKubernetesSettingsRepository_CapabilityPolicy(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	repo := NewKubernetesSettingsRepository(client, "default")
+	ctx := context.Background()
+
+	settings := entities.NewSettings("org/team")
+	policy := entities.NewCapabilityPolicy([]entities.SessionCapability{entities.CapabilityTerminal, entities.CapabilityDocker})
+	policy.SetUpdatedBy("maintainer")
+	settings.SetCapabilityPolicy(policy)
+	if err := repo.Save(ctx, settings); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+
+	loaded, err := repo.FindByName(ctx, "org/team")
+	if err != nil {
+		t.Fatalf("Failed to find settings: %v", err)
+	}
+	got := loaded.CapabilityPolicy()
+	if got == nil {
+		t.Fatal("Expected capability policy to be set")
+	}
+	if !got.Allows(entities.CapabilityTerminal) || !got.Allows(entities.CapabilityDocker) || got.Allows(entities.CapabilityEditor) {
+		t.Errorf("Unexpected allowed capabilities: %v", got.Allowed())
+	}
+	if got.UpdatedBy() != "maintainer" {
+		t.Errorf("Expected updated_by 'maintainer', got %q", got.UpdatedBy())
+	}
+}
+
 func TestKubernetesSettingsRepository_FindByName_NotFound(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	repo := NewKubernetesSettingsRepository(client, "default")
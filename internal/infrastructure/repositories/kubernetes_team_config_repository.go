@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
 )
 
 const (
@@ -32,6 +33,9 @@ type teamConfigJSON struct {
 	TeamID         string              `json:"team_id"`
 	ServiceAccount *serviceAccountJSON `json:"service_account,omitempty"`
 	EnvVars        map[string]string   `json:"env_vars,omitempty"`
+	// EncryptedEnvVars holds the env vars when an encryption service is
+	// configured; plain EnvVars are read for configs saved before
+	EncryptedEnvVars map[string]encryptedEnvVarJSON `json:"encrypted_env_vars,omitempty"`
 }
 
 // serviceAccountJSON is the JSON representation of service account
@@ -45,15 +49,23 @@ type serviceAccountJSON struct {
 
 // KubernetesTeamConfigRepository implements TeamConfigRepository using Kubernetes Secrets
 type KubernetesTeamConfigRepository struct {
-	client    kubernetes.Interface
-	namespace string
+	client             kubernetes.Interface
+	namespace          string
+	encryptionRegistry *infraservices.EncryptionServiceRegistry // optional; nil = store env_vars as plain text
 }
 
-// NewKubernetesTeamConfigRepository creates a new KubernetesTeamConfigRepository
-func NewKubernetesTeamConfigRepository(client kubernetes.Interface, namespace string) *KubernetesTeamConfigRepository {
+// NewKubernetesTeamConfigRepository creates a new KubernetesTeamConfigRepository.
+// As with settings, an optional EncryptionServiceRegistry enables at-rest
+// encryption of env_vars.
+func NewKubernetesTeamConfigRepository(client kubernetes.Interface, namespace string, registry ...*infraservices.EncryptionServiceRegistry) *KubernetesTeamConfigRepository {
+	var reg *infraservices.EncryptionServiceRegistry
+	if len(registry) > 0 {
+		reg = registry[0]
+	}
 	return &KubernetesTeamConfigRepository{
-		client:    client,
-		namespace: namespace,
+		client:             client,
+		namespace:          namespace,
+		encryptionRegistry: reg,
 	}
 }
 
@@ -67,7 +79,7 @@ func (r *KubernetesTeamConfigRepository) Save(ctx context.Context, config *entit
 	labelValue := sanitizeTeamIDForLabel(config.TeamID())
 
 	// Convert to JSON
-	data, err := r.toJSON(config)
+	data, err := r.toJSON(ctx, config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal team config: %w", err)
 	}
@@ -124,7 +136,7 @@ func (r *KubernetesTeamConfigRepository) FindByTeamID(ctx context.Context, teamI
 		return nil, fmt.Errorf("failed to get team config secret: %w", err)
 	}
 
-	config, err := r.fromSecret(secret)
+	config, err := r.fromSecret(ctx, secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse team config: %w", err)
 	}
@@ -176,7 +188,7 @@ func (r *KubernetesTeamConfigRepository) List(ctx context.Context) ([]*entities.
 
 	configs := make([]*entities.TeamConfig, 0, len(secretList.Items))
 	for i := range secretList.Items {
-		config, err := r.fromSecret(&secretList.Items[i])
+		config, err := r.fromSecret(ctx, &secretList.Items[i])
 		if err != nil {
 			// Log error but continue with other configs
 			fmt.Printf("Warning: failed to parse team config from secret %s: %v\n", secretList.Items[i].Name, err)
@@ -199,11 +211,22 @@ func (r *KubernetesTeamConfigRepository) secretName(teamID string) string {
 	return TeamConfigSecretPrefix + sanitized
 }
 
-// toJSON converts team config to JSON bytes
-func (r *KubernetesTeamConfigRepository) toJSON(config *entities.TeamConfig) ([]byte, error) {
+// toJSON converts team config to JSON bytes, encrypting env_vars when a
+// non-noop EncryptionServiceRegistry is configured
+func (r *KubernetesTeamConfigRepository) toJSON(ctx context.Context, config *entities.TeamConfig) ([]byte, error) {
 	jsonData := &teamConfigJSON{
-		TeamID:  config.TeamID(),
-		EnvVars: config.EnvVars(),
+		TeamID: config.TeamID(),
+	}
+	if envVars := config.EnvVars(); len(envVars) > 0 {
+		encryptedVars, err := encryptEnvVars(ctx, r.encryptionRegistry, envVars)
+		if err != nil {
+			return nil, err
+		}
+		if encryptedVars != nil {
+			jsonData.EncryptedEnvVars = encryptedVars
+		} else {
+			jsonData.EnvVars = envVars
+		}
 	}
 
 	// Convert service account if present
@@ -226,7 +249,7 @@ func (r *KubernetesTeamConfigRepository) toJSON(config *entities.TeamConfig) ([]
 }
 
 // fromSecret converts Kubernetes Secret to TeamConfig entity
-func (r *KubernetesTeamConfigRepository) fromSecret(secret *corev1.Secret) (*entities.TeamConfig, error) {
+func (r *KubernetesTeamConfigRepository) fromSecret(ctx context.Context, secret *corev1.Secret) (*entities.TeamConfig, error) {
 	data, ok := secret.Data[SecretKeyConfig]
 	if !ok {
 		return nil, fmt.Errorf("secret %s does not contain %s key", secret.Name, SecretKeyConfig)
@@ -270,7 +293,16 @@ func (r *KubernetesTeamConfigRepository) fromSecret(secret *corev1.Secret) (*ent
 		serviceAccount.SetUpdatedAt(updatedAt)
 	}
 
-	return entities.NewTeamConfig(jsonData.TeamID, serviceAccount, jsonData.EnvVars), nil
+	// encrypted_env_vars take precedence over plain env_vars of older saves
+	envVars := make(map[string]string, len(jsonData.EnvVars)+len(jsonData.EncryptedEnvVars))
+	decryptEnvVars(ctx, r.encryptionRegistry, jsonData.EncryptedEnvVars, envVars, "TEAM_CONFIG")
+	for k, v := range jsonData.EnvVars {
+		if _, exists := envVars[k]; !exists {
+			envVars[k] = v
+		}
+	}
+
+	return entities.NewTeamConfig(jsonData.TeamID, serviceAccount, envVars), nil
 }
 
 // sanitizeTeamIDForLabel converts team ID to a valid Kubernetes label value
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+func newTestEncryptionRegistry(t *testing.T) *infraservices.EncryptionServiceRegistry {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "encryption.key")
+	require.NoError(t, os.WriteFile(keyPath, key, 0600))
+	svc, err := infraservices.NewLocalEncryptionService(keyPath, "")
+	require.NoError(t, err)
+	return infraservices.NewEncryptionServiceRegistry(svc)
+}
+
+func TestKubernetesTeamConfigRepository_EncryptsEnvVars(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	repo := NewKubernetesTeamConfigRepository(client, "default", newTestEncryptionRegistry(t))
+	ctx := context.Background()
+
+	config := entities.NewTeamConfig("acme/ml", nil, map[string]string{"DB_PASSWORD": "hunter2"})
+	require.NoError(t, repo.Save(ctx, config))
+
+	secret, err := client.CoreV1().Secrets("default").Get(ctx, repo.secretName("acme/ml"), metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(secret.Data[SecretKeyConfig]), "hunter2")
+	var stored teamConfigJSON
+	require.NoError(t, json.Unmarshal(secret.Data[SecretKeyConfig], &stored))
+	assert.Empty(t, stored.EnvVars)
+	assert.Equal(t, "aes-256-gcm", stored.EncryptedEnvVars["DB_PASSWORD"].Algorithm)
+
+	loaded, err := repo.FindByTeamID(ctx, "acme/ml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_PASSWORD": "hunter2"}, loaded.EnvVars())
+}
+
+func TestKubernetesTeamConfigRepository_ReadsPlainEnvVars(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	// Configs saved without encryption stay readable once it is enabled
+	require.NoError(t, NewKubernetesTeamConfigRepository(client, "default").Save(ctx,
+		entities.NewTeamConfig("acme/ml", nil, map[string]string{"REGION": "us-east-1"})))
+
+	loaded, err := NewKubernetesTeamConfigRepository(client, "default", newTestEncryptionRegistry(t)).FindByTeamID(ctx, "acme/ml")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", loaded.EnvVars()["REGION"])
+}
+
+func TestKubernetesTeamConfigRepository_RejectsInvalidEnvVarNames(t *testing.T) {
+	repo := NewKubernetesTeamConfigRepository(fake.NewSimpleClientset(), "default")
+	err := repo.Save(context.Background(), entities.NewTeamConfig("acme/ml", nil, map[string]string{"BAD-NAME": "x"}))
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +29,8 @@ const (
 type userTeamMappingEntry struct {
 	Teams     []auth.GitHubTeamMembership `json:"teams"`
 	UpdatedAt time.Time                   `json:"updated_at"`
+	// Synced marks entries written by the team membership sync
+	Synced bool `json:"synced,omitempty"`
 }
 
 // KubernetesUserTeamMappingRepository implements auth.TeamMappingRepository using a single
@@ -40,11 +43,16 @@ type userTeamMappingEntry struct {
 //	Namespace: <same as other resources>
 //	Labels:    agentapi.proxy/type: user-team-mapping
 //	Data:
-//	  {username}: {"teams":[...], "updated_at":"RFC3339"}
+//	  {username}: {"teams":[...], "updated_at":"RFC3339", "synced":true}
+//
+// Entries cached from a user's token expire after 5 minutes. Entries of the
+// team membership sync (SetSynced) expire after the synced TTL instead, which
+// is set above the sync interval.
 type KubernetesUserTeamMappingRepository struct {
 	client    kubernetes.Interface
 	namespace string
 	ttl       time.Duration
+	syncedTTL time.Duration
 }
 
 // NewKubernetesUserTeamMappingRepository creates a new KubernetesUserTeamMappingRepository
@@ -53,9 +61,16 @@ func NewKubernetesUserTeamMappingRepository(client kubernetes.Interface, namespa
 		client:    client,
 		namespace: namespace,
 		ttl:       5 * time.Minute,
+		syncedTTL: time.Hour,
 	}
 }
 
+// SetSyncedTTL sets how long synced entries stay valid. It should outlast a
+// few sync intervals so a missed sync does not fall back to token lookups.
+func (r *KubernetesUserTeamMappingRepository) SetSyncedTTL(ttl time.Duration) {
+	r.syncedTTL = ttl
+}
+
 // Get retrieves the team memberships for a given username from the ConfigMap.
 // Returns (teams, true, nil) if found, (nil, false, nil) if not found, or (nil, false, err) on error.
 func (r *KubernetesUserTeamMappingRepository) Get(ctx context.Context, username string) ([]auth.GitHubTeamMembership, bool, error) {
@@ -77,7 +92,11 @@ func (r *KubernetesUserTeamMappingRepository) Get(ctx context.Context, username
 		return nil, false, fmt.Errorf("failed to unmarshal team mapping for user %s: %w", username, err)
 	}
 
-	if r.ttl > 0 && time.Since(entry.UpdatedAt) > r.ttl {
+	ttl := r.ttl
+	if entry.Synced {
+		ttl = r.syncedTTL
+	}
+	if ttl > 0 && time.Since(entry.UpdatedAt) > ttl {
 		return nil, false, nil
 	}
 
@@ -88,11 +107,44 @@ func (r *KubernetesUserTeamMappingRepository) Get(ctx context.Context, username
 // Uses merge-patch to avoid resourceVersion conflicts under concurrent writes.
 // Falls back to Create when the ConfigMap doesn't yet exist.
 func (r *KubernetesUserTeamMappingRepository) Set(ctx context.Context, username string, teams []auth.GitHubTeamMembership) error {
-	entry := userTeamMappingEntry{
+	return r.set(ctx, username, userTeamMappingEntry{
 		Teams:     teams,
 		UpdatedAt: time.Now().UTC(),
+	})
+}
+
+// SetSynced stores the team memberships of a user found by the team
+// membership sync
+func (r *KubernetesUserTeamMappingRepository) SetSynced(ctx context.Context, username string, teams []auth.GitHubTeamMembership) error {
+	return r.set(ctx, username, userTeamMappingEntry{
+		Teams:     teams,
+		UpdatedAt: time.Now().UTC(),
+		Synced:    true,
+	})
+}
+
+// SyncedUsernames returns the users with an entry of the team membership sync
+func (r *KubernetesUserTeamMappingRepository) SyncedUsernames(ctx context.Context) ([]string, error) {
+	cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, UserTeamMappingConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user-team mapping ConfigMap: %w", err)
 	}
 
+	var usernames []string
+	for username, rawJSON := range cm.Data {
+		var entry userTeamMappingEntry
+		if err := json.Unmarshal([]byte(rawJSON), &entry); err == nil && entry.Synced {
+			usernames = append(usernames, username)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames, nil
+}
+
+func (r *KubernetesUserTeamMappingRepository) set(ctx context.Context, username string, entry userTeamMappingEntry) error {
 	rawJSON, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("failed to marshal team mapping for user %s: %w", username, err)
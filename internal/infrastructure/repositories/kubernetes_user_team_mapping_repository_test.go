@@ -119,3 +119,44 @@ func TestKubernetesUserTeamMappingRepository_Set_Concurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestKubernetesUserTeamMappingRepository_Synced(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	repo := NewKubernetesUserTeamMappingRepository(client, "default")
+	ctx := context.Background()
+
+	// A synced entry older than the cache TTL stays valid, even when empty
+	entries := map[string]userTeamMappingEntry{
+		"carol": {Teams: []auth.GitHubTeamMembership{}, UpdatedAt: time.Now().Add(-10 * time.Minute), Synced: true},
+		"dave":  {Teams: []auth.GitHubTeamMembership{}, UpdatedAt: time.Now().Add(-2 * time.Hour), Synced: true},
+	}
+	data := make(map[string]string)
+	for username, entry := range entries {
+		raw, _ := json.Marshal(entry)
+		data[username] = string(raw)
+	}
+	if _, err := client.CoreV1().ConfigMaps("default").Create(ctx, repo.buildConfigMap(data), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("seed ConfigMap failed: %v", err)
+	}
+	if err := repo.Set(ctx, "alice", nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := repo.SetSynced(ctx, "bob", []auth.GitHubTeamMembership{{Organization: "org", TeamSlug: "team", Role: "maintainer"}}); err != nil {
+		t.Fatalf("SetSynced failed: %v", err)
+	}
+
+	if teams, found, err := repo.Get(ctx, "carol"); err != nil || !found || len(teams) != 0 {
+		t.Fatalf("expected empty synced entry for carol, got %v %v %v", teams, found, err)
+	}
+	if _, found, _ := repo.Get(ctx, "dave"); found {
+		t.Fatal("expected synced entry past the synced TTL to expire")
+	}
+
+	usernames, err := repo.SyncedUsernames(ctx)
+	if err != nil {
+		t.Fatalf("SyncedUsernames failed: %v", err)
+	}
+	if want := []string{"bob", "carol", "dave"}; len(usernames) != len(want) || usernames[0] != want[0] || usernames[1] != want[1] || usernames[2] != want[2] {
+		t.Fatalf("unexpected synced usernames: %v", usernames)
+	}
+}
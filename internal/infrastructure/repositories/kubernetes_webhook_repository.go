@@ -60,6 +60,8 @@ type webhookJSON struct {
 	Triggers        []webhookTriggerJSON          `json:"triggers"`
 	SessionConfig   *webhookSessionConfigJSON     `json:"session_config,omitempty"`
 	MaxSessions     int                           `json:"max_sessions,omitempty"`
+	Source          string                        `json:"source,omitempty"`
+	MaxPayloadBytes int64                         `json:"max_payload_bytes,omitempty"`
 	CreatedAt       time.Time                     `json:"created_at"`
 	UpdatedAt       time.Time                     `json:"updated_at"`
 	LastDelivery    *webhookDeliveryRecordJSON    `json:"last_delivery,omitempty"`
@@ -100,15 +102,16 @@ type webhookGitHubConditionsJSON struct {
 }
 
 type webhookSessionConfigJSON struct {
-	Environment            map[string]string       `json:"environment,omitempty"`
-	Tags                   map[string]string       `json:"tags,omitempty"`
-	InitialMessageTemplate string                  `json:"initial_message_template,omitempty"`
-	ReuseMessageTemplate   string                  `json:"reuse_message_template,omitempty"`
-	Params                 *entities.SessionParams `json:"params,omitempty"`
-	ReuseSession           bool                    `json:"reuse_session,omitempty"`
-	MountPayload           bool                    `json:"mount_payload,omitempty"`
-	MemoryKey              map[string]string       `json:"memory_key,omitempty"`
-	SessionProfileID       string                  `json:"session_profile_id,omitempty"`
+	Environment            map[string]string                `json:"environment,omitempty"`
+	Tags                   map[string]string                `json:"tags,omitempty"`
+	InitialMessageTemplate string                           `json:"initial_message_template,omitempty"`
+	ReuseMessageTemplate   string                           `json:"reuse_message_template,omitempty"`
+	Params                 *entities.SessionParams          `json:"params,omitempty"`
+	ReuseSession           bool                             `json:"reuse_session,omitempty"`
+	MountPayload           bool                             `json:"mount_payload,omitempty"`
+	MemoryKey              map[string]string                `json:"memory_key,omitempty"`
+	SessionProfileID       string                           `json:"session_profile_id,omitempty"`
+	PayloadMappings        []entities.WebhookPayloadMapping `json:"payload_mappings,omitempty"`
 }
 
 type webhookDeliveryRecordJSON struct {
@@ -218,6 +221,9 @@ func (r *KubernetesWebhookRepository) List(ctx context.Context, filter repositor
 		if filter.TeamID != "" && w.TeamID() != filter.TeamID {
 			continue
 		}
+		if filter.Source != "" && w.Source() != filter.Source {
+			continue
+		}
 		if len(filter.TeamIDs) > 0 && w.Scope() == entities.ScopeTeam {
 			teamMatch := false
 			for _, teamID := range filter.TeamIDs {
@@ -515,6 +521,12 @@ func (r *KubernetesWebhookRepository) jsonToEntity(wj *webhookJSON) *entities.We
 	if wj.MaxSessions > 0 {
 		webhook.SetMaxSessions(wj.MaxSessions)
 	}
+	if wj.Source != "" {
+		webhook.SetSource(wj.Source)
+	}
+	if wj.MaxPayloadBytes > 0 {
+		webhook.SetMaxPayloadBytes(wj.MaxPayloadBytes)
+	}
 
 	// GitHub config
 	if wj.GitHub != nil {
@@ -595,6 +607,8 @@ func (r *KubernetesWebhookRepository) entityToJSON(w *entities.Webhook) *webhook
 		SignatureType:   w.SignatureType(),
 		SignaturePrefix: w.SignaturePrefix(),
 		MaxSessions:     w.MaxSessions(),
+		Source:          w.Source(),
+		MaxPayloadBytes: w.MaxPayloadBytes(),
 		CreatedAt:       w.CreatedAt(),
 		UpdatedAt:       w.UpdatedAt(),
 		DeliveryCount:   w.DeliveryCount(),
@@ -686,6 +700,7 @@ func (r *KubernetesWebhookRepository) sessionConfigJSONToEntity(scj *webhookSess
 	if scj.SessionProfileID != "" {
 		sc.SetSessionProfileID(scj.SessionProfileID)
 	}
+	sc.SetPayloadMappings(scj.PayloadMappings)
 	return sc
 }
 
@@ -699,6 +714,7 @@ func (r *KubernetesWebhookRepository) sessionConfigEntityToJSON(sc *entities.Web
 		MountPayload:           sc.MountPayload(),
 		MemoryKey:              sc.MemoryKey(),
 		SessionProfileID:       sc.SessionProfileID(),
+		PayloadMappings:        sc.PayloadMappings(),
 	}
 	if params := sc.Params(); params != nil {
 		// Use entities.SessionParams directly for JSON - no need to copy fields
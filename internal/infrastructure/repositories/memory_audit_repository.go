@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+var _ portrepos.AuditRepository = (*MemoryAuditRepository)(nil)
+
+// MemoryAuditRepository keeps audit events in process memory, bucketed by UTC
+// day. Events are lost on restart and not shared between proxy replicas.
+type MemoryAuditRepository struct {
+	mu   sync.RWMutex
+	days map[string][]entities.AuditEvent
+}
+
+// NewMemoryAuditRepository creates a new MemoryAuditRepository.
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{days: make(map[string][]entities.AuditEvent)}
+}
+
+// RecordAuditEvent appends event to the bucket of its day.
+func (r *MemoryAuditRepository) RecordAuditEvent(_ context.Context, event entities.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	day := auditDay(event.Timestamp)
+	r.days[day] = appendAuditEvent(r.days[day], event)
+	return nil
+}
+
+// ListAuditEvents returns events with from <= timestamp < to, oldest first.
+func (r *MemoryAuditRepository) ListAuditEvents(_ context.Context, from, to time.Time) ([]entities.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var buckets [][]entities.AuditEvent
+	for day, events := range r.days {
+		if auditDayInRange(day, from, to) {
+			buckets = append(buckets, events)
+		}
+	}
+	return filterAuditEvents(buckets, from, to), nil
+}
+
+// OldestAuditEvent returns the timestamp of the oldest stored event.
+func (r *MemoryAuditRepository) OldestAuditEvent(_ context.Context) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var oldest time.Time
+	for _, events := range r.days {
+		for _, e := range events {
+			if oldest.IsZero() || e.Timestamp.Before(oldest) {
+				oldest = e.Timestamp
+			}
+		}
+	}
+	return oldest, nil
+}
+
+// PurgeAuditEvents deletes events older than before.
+func (r *MemoryAuditRepository) PurgeAuditEvents(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	purged := 0
+	for day, events := range r.days {
+		kept := events[:0]
+		for _, e := range events {
+			if e.Timestamp.Before(before) {
+				purged++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		if len(kept) == 0 {
+			delete(r.days, day)
+		} else {
+			r.days[day] = kept
+		}
+	}
+	return purged, nil
+}
+
+// auditDay returns the UTC day bucket of t, e.g. "20260102".
+func auditDay(t time.Time) string {
+	return t.UTC().Format("20060102")
+}
+
+// auditDayInRange reports whether the day bucket may hold events in [from, to).
+func auditDayInRange(day string, from, to time.Time) bool {
+	start, err := time.Parse("20060102", day)
+	if err != nil {
+		return false
+	}
+	end := start.AddDate(0, 0, 1)
+	return end.After(from) && start.Before(to)
+}
+
+// appendAuditEvent appends event and keeps the newest MaxAuditEventsPerDay.
+func appendAuditEvent(events []entities.AuditEvent, event entities.AuditEvent) []entities.AuditEvent {
+	events = append(events, event)
+	if len(events) > portrepos.MaxAuditEventsPerDay {
+		events = append([]entities.AuditEvent{}, events[len(events)-portrepos.MaxAuditEventsPerDay:]...)
+	}
+	return events
+}
+
+// filterAuditEvents merges day buckets and returns events in [from, to), oldest first.
+func filterAuditEvents(buckets [][]entities.AuditEvent, from, to time.Time) []entities.AuditEvent {
+	result := []entities.AuditEvent{}
+	for _, events := range buckets {
+		for _, e := range events {
+			if !e.Timestamp.Before(from) && e.Timestamp.Before(to) {
+				result = append(result, e)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result
+}
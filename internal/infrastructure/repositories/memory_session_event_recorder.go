@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+var _ portrepos.EventRecorder = (*MemoryEventRecorder)(nil)
+
+// MemoryEventRecorder keeps session timelines in process memory. Timelines
+// are lost on restart and not shared between proxy replicas.
+type MemoryEventRecorder struct {
+	mu     sync.RWMutex
+	events map[string][]entities.SessionEvent
+}
+
+// NewMemoryEventRecorder creates a new MemoryEventRecorder.
+func NewMemoryEventRecorder() *MemoryEventRecorder {
+	return &MemoryEventRecorder{events: make(map[string][]entities.SessionEvent)}
+}
+
+// RecordSessionEvent appends event to the timeline of its session.
+func (r *MemoryEventRecorder) RecordSessionEvent(_ context.Context, event entities.SessionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[event.SessionID] = appendSessionEvent(r.events[event.SessionID], event)
+	return nil
+}
+
+// ListSessionEvents returns a copy of the timeline of a session.
+func (r *MemoryEventRecorder) ListSessionEvents(_ context.Context, sessionID string) ([]entities.SessionEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]entities.SessionEvent{}, r.events[sessionID]...), nil
+}
+
+// DeleteSessionEvents removes the timeline of a session.
+func (r *MemoryEventRecorder) DeleteSessionEvents(_ context.Context, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.events, sessionID)
+	return nil
+}
+
+// appendSessionEvent appends event and keeps the newest MaxSessionEvents.
+func appendSessionEvent(events []entities.SessionEvent, event entities.SessionEvent) []entities.SessionEvent {
+	events = append(events, event)
+	if len(events) > portrepos.MaxSessionEvents {
+		events = append([]entities.SessionEvent{}, events[len(events)-portrepos.MaxSessionEvents:]...)
+	}
+	return events
+}
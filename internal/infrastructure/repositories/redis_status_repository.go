@@ -16,6 +16,8 @@ const (
 	redisStatusKeyPrefix = "agentapi:session:status:"
 	// redisGlobalChannel is the Pub/Sub channel name for all status change events.
 	redisGlobalChannel = "agentapi:session:events:global"
+	// redisLifecycleChannel is the Pub/Sub channel name for session timeline events.
+	redisLifecycleChannel = "agentapi:session:events:lifecycle"
 	// redisStatusTTL is the TTL applied to session status keys.
 	// It is intentionally longer than any realistic session lifetime.
 	redisStatusTTL = 48 * time.Hour
@@ -50,6 +52,12 @@ func NewRedisStatusRepository(client *redis.Client, podID string) *RedisStatusRe
 	}
 }
 
+// Client returns the Redis client, so that other shared state can use the
+// same connection pool.
+func (r *RedisStatusRepository) Client() *redis.Client {
+	return r.client
+}
+
 func statusKey(sessionID string) string {
 	return redisStatusKeyPrefix + sessionID
 }
@@ -165,6 +173,67 @@ func (r *RedisStatusRepository) DeleteStatus(ctx context.Context, sessionID stri
 	return nil
 }
 
+// --------------------------------------------------------------------------
+// SessionEventBroadcaster implementation
+// --------------------------------------------------------------------------
+
+// PublishSessionEvent serialises event to JSON and publishes it to the
+// lifecycle Pub/Sub channel.
+func (r *RedisStatusRepository) PublishSessionEvent(ctx context.Context, event portrepos.SharedSessionEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("redis PublishSessionEvent marshal: %w", err)
+	}
+	if err := r.client.Publish(ctx, redisLifecycleChannel, payload).Err(); err != nil {
+		return fmt.Errorf("redis PublishSessionEvent publish: %w", err)
+	}
+	return nil
+}
+
+// SubscribeSessionEvents subscribes to the lifecycle channel and returns a Go
+// channel that receives deserialized SharedSessionEvent values.
+// The returned channel is closed when ctx is cancelled.
+// Slow consumers will drop messages rather than block the publisher.
+func (r *RedisStatusRepository) SubscribeSessionEvents(ctx context.Context) (<-chan portrepos.SharedSessionEvent, error) {
+	pubsub := r.client.Subscribe(ctx, redisLifecycleChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("redis SubscribeSessionEvents subscribe: %w", err)
+	}
+
+	ch := make(chan portrepos.SharedSessionEvent, 256)
+	go func() {
+		defer func() {
+			_ = pubsub.Close()
+			close(ch)
+		}()
+		msgCh := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgCh:
+				if !ok {
+					return
+				}
+				var event portrepos.SharedSessionEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					log.Printf("[REDIS_STATUS] failed to unmarshal session event: %v", err)
+					continue
+				}
+				select {
+				case ch <- event:
+				default:
+					log.Printf("[REDIS_STATUS] session event subscriber channel full, dropping event session=%s type=%s",
+						event.SessionID, event.Type)
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
 // --------------------------------------------------------------------------
 // SessionListCacheRepository implementation
 // --------------------------------------------------------------------------
@@ -123,6 +123,16 @@ func (r *S3MemoryRepository) teamKey(teamID, id string) string {
 	return fmt.Sprintf("%steam/%s/%s.json", r.prefix, hashID(teamID), id)
 }
 
+// Ping checks that the bucket can be listed under the memory prefix.
+func (r *S3MemoryRepository) Ping(ctx context.Context) error {
+	_, err := r.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(r.bucket),
+		Prefix:  aws.String(r.prefix),
+		MaxKeys: aws.Int32(1),
+	})
+	return err
+}
+
 // Create persists a new memory entry to S3.
 // Returns an error if an entry with the same ID already exists.
 func (r *S3MemoryRepository) Create(ctx context.Context, memory *entities.Memory) error {
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+func TestSessionEventRecorders(t *testing.T) {
+	recorders := map[string]portrepos.EventRecorder{
+		"memory":     NewMemoryEventRecorder(),
+		"kubernetes": NewKubernetesEventRecorder(fake.NewSimpleClientset(), "test-ns"),
+	}
+	for name, recorder := range recorders {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i := 0; i < portrepos.MaxSessionEvents+5; i++ {
+				event := entities.SessionEvent{
+					SessionID: "s1",
+					Type:      entities.SessionEventMessageSent,
+					Message:   fmt.Sprintf("message %d", i),
+					Timestamp: start.Add(time.Duration(i) * time.Second),
+				}
+				if err := recorder.RecordSessionEvent(ctx, event); err != nil {
+					t.Fatalf("RecordSessionEvent() error = %v", err)
+				}
+			}
+			if err := recorder.RecordSessionEvent(ctx, entities.SessionEvent{SessionID: "s2", Type: entities.SessionEventCreated, Timestamp: start}); err != nil {
+				t.Fatalf("RecordSessionEvent() error = %v", err)
+			}
+
+			events, err := recorder.ListSessionEvents(ctx, "s1")
+			if err != nil {
+				t.Fatalf("ListSessionEvents() error = %v", err)
+			}
+			if len(events) != portrepos.MaxSessionEvents {
+				t.Fatalf("len(events) = %d, want %d", len(events), portrepos.MaxSessionEvents)
+			}
+			if events[0].Message != "message 5" || !events[0].Timestamp.Equal(start.Add(5*time.Second)) {
+				t.Errorf("oldest kept event = %+v, want message 5", events[0])
+			}
+
+			if err := recorder.DeleteSessionEvents(ctx, "s1"); err != nil {
+				t.Fatalf("DeleteSessionEvents() error = %v", err)
+			}
+			if events, _ := recorder.ListSessionEvents(ctx, "s1"); len(events) != 0 {
+				t.Errorf("events after delete = %v", events)
+			}
+			if events, _ := recorder.ListSessionEvents(ctx, "s2"); len(events) != 1 {
+				t.Errorf("events of s2 = %v, want 1 event", events)
+			}
+			if err := recorder.DeleteSessionEvents(ctx, "missing"); err != nil {
+				t.Errorf("DeleteSessionEvents() of missing session error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// SQLDialect selects the SQL flavour spoken by SQLSessionRepository.
+type SQLDialect string
+
+const (
+	// SQLDialectPostgres is used with the "pgx" database/sql driver.
+	SQLDialectPostgres SQLDialect = "postgres"
+	// SQLDialectSQLite is used with the "sqlite3" database/sql driver.
+	SQLDialectSQLite SQLDialect = "sqlite"
+)
+
+// sessionsTable is the table holding session records.
+const sessionsTable = "agentapi_sessions"
+
+// sessionStateJSON is the JSON document stored in the state column.
+type sessionStateJSON struct {
+	Request        *entities.RunServerRequest `json:"request"`
+	WebhookPayload []byte                     `json:"webhook_payload,omitempty"`
+}
+
+// SQLSessionRepository implements SessionRepository on top of database/sql.
+// The launch request and webhook payload are stored as one JSON document,
+// encrypted at rest when a non-noop EncryptionServiceRegistry is configured.
+type SQLSessionRepository struct {
+	db                 *sql.DB
+	replica            *sql.DB // optional; serves List so bulk reads stay off the primary
+	dialect            SQLDialect
+	encryptionRegistry *infraservices.EncryptionServiceRegistry // optional; nil = store state as plain text
+}
+
+// NewSQLSessionRepository creates a SQLSessionRepository and creates its
+// table if it does not exist yet.
+func NewSQLSessionRepository(ctx context.Context, db *sql.DB, dialect SQLDialect, reg *infraservices.EncryptionServiceRegistry) (*SQLSessionRepository, error) {
+	switch dialect {
+	case SQLDialectPostgres, SQLDialectSQLite:
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	r := &SQLSessionRepository{db: db, dialect: dialect, encryptionRegistry: reg}
+	if err := r.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLSessionRepository) migrate(ctx context.Context) error {
+	timestampType := "TIMESTAMPTZ"
+	if r.dialect == SQLDialectSQLite {
+		timestampType = "DATETIME"
+	}
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + sessionsTable + ` (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			team_id TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL,
+			enc_algorithm TEXT NOT NULL DEFAULT '',
+			enc_key_id TEXT NOT NULL DEFAULT '',
+			enc_version TEXT NOT NULL DEFAULT '',
+			created_at ` + timestampType + ` NOT NULL,
+			updated_at ` + timestampType + ` NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS ` + sessionsTable + `_user_id_idx ON ` + sessionsTable + ` (user_id)`,
+	}
+	for _, stmt := range statements {
+		if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to migrate session store: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetReadReplica routes List, the bulk query used by reports, to db, a read
+// replica or analytical copy of the primary database. Get, Save and Delete
+// stay on the primary so that session restore reads its own writes. The
+// replica schema is managed by the primary and is not migrated here.
+func (r *SQLSessionRepository) SetReadReplica(db *sql.DB) {
+	r.replica = db
+}
+
+// PingReplica checks that the read replica is reachable and the sessions
+// table can be read on it. It returns nil when no replica is configured.
+func (r *SQLSessionRepository) PingReplica(ctx context.Context) error {
+	if r.replica == nil {
+		return nil
+	}
+	if err := r.replica.PingContext(ctx); err != nil {
+		return err
+	}
+	var n int
+	return r.replica.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+sessionsTable).Scan(&n)
+}
+
+// Ping checks that the database is reachable and the sessions table can be
+// read.
+func (r *SQLSessionRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return err
+	}
+	var n int
+	return r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+sessionsTable).Scan(&n)
+}
+
+// rebind rewrites ? placeholders into the dialect's placeholder syntax.
+func (r *SQLSessionRepository) rebind(query string) string {
+	if r.dialect != SQLDialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Save creates or updates a session record
+func (r *SQLSessionRepository) Save(ctx context.Context, record *portrepos.SessionRecord) error {
+	if record == nil || record.ID == "" {
+		return fmt.Errorf("session record ID is required")
+	}
+	if record.Request == nil {
+		return fmt.Errorf("session record %s has no request", record.ID)
+	}
+	now := time.Now().UTC()
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	state, meta, err := r.encodeState(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	query := r.rebind(`INSERT INTO ` + sessionsTable + `
+		(id, user_id, scope, team_id, state, enc_algorithm, enc_key_id, enc_version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = excluded.user_id,
+			scope = excluded.scope,
+			team_id = excluded.team_id,
+			state = excluded.state,
+			enc_algorithm = excluded.enc_algorithm,
+			enc_key_id = excluded.enc_key_id,
+			enc_version = excluded.enc_version,
+			updated_at = excluded.updated_at`)
+	_, err = r.db.ExecContext(ctx, query,
+		record.ID, record.Request.UserID, string(record.Request.Scope), record.Request.TeamID,
+		state, meta.Algorithm, meta.KeyID, meta.Version, createdAt.UTC(), now)
+	if err != nil {
+		return fmt.Errorf("failed to save session %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+// Get retrieves the record for the given session ID; returns nil, nil if not found
+func (r *SQLSessionRepository) Get(ctx context.Context, sessionID string) (*portrepos.SessionRecord, error) {
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT id, state, enc_algorithm, enc_key_id, enc_version, created_at, updated_at
+		FROM `+sessionsTable+` WHERE id = ?`), sessionID)
+	record, err := r.scanRecord(ctx, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	return record, nil
+}
+
+// List retrieves all session records; if userID is non-empty, only records for that user are returned.
+// It reads from the read replica when one is set and falls back to the
+// primary if the replica fails.
+func (r *SQLSessionRepository) List(ctx context.Context, userID string) ([]*portrepos.SessionRecord, error) {
+	if r.replica != nil {
+		records, err := r.list(ctx, r.replica, userID)
+		if err == nil {
+			return records, nil
+		}
+		log.Printf("[SESSION_STORE] Read replica query failed, falling back to primary: %v", err)
+	}
+	return r.list(ctx, r.db, userID)
+}
+
+func (r *SQLSessionRepository) list(ctx context.Context, db *sql.DB, userID string) ([]*portrepos.SessionRecord, error) {
+	query := `SELECT id, state, enc_algorithm, enc_key_id, enc_version, created_at, updated_at FROM ` + sessionsTable
+	var args []interface{}
+	if userID != "" {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at`
+
+	rows, err := db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var records []*portrepos.SessionRecord
+	for rows.Next() {
+		record, err := r.scanRecord(ctx, rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session record: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return records, nil
+}
+
+// Delete removes the record for the given session ID
+func (r *SQLSessionRepository) Delete(ctx context.Context, sessionID string) error {
+	if _, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM `+sessionsTable+` WHERE id = ?`), sessionID); err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *SQLSessionRepository) scanRecord(ctx context.Context, row rowScanner) (*portrepos.SessionRecord, error) {
+	var (
+		record portrepos.SessionRecord
+		state  string
+		meta   domainservices.EncryptionMetadata
+	)
+	if err := row.Scan(&record.ID, &state, &meta.Algorithm, &meta.KeyID, &meta.Version, &record.CreatedAt, &record.UpdatedAt); err != nil {
+		return nil, err
+	}
+	decoded, err := r.decodeState(ctx, state, meta)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", record.ID, err)
+	}
+	record.Request = decoded.Request
+	record.WebhookPayload = decoded.WebhookPayload
+	return &record, nil
+}
+
+// encodeState serializes the record state, encrypting it when a non-noop
+// encryption service is configured.
+func (r *SQLSessionRepository) encodeState(ctx context.Context, record *portrepos.SessionRecord) (string, domainservices.EncryptionMetadata, error) {
+	data, err := json.Marshal(sessionStateJSON{Request: record.Request, WebhookPayload: record.WebhookPayload})
+	if err != nil {
+		return "", domainservices.EncryptionMetadata{}, fmt.Errorf("failed to marshal session %s: %w", record.ID, err)
+	}
+	if r.encryptionRegistry == nil {
+		return string(data), domainservices.EncryptionMetadata{}, nil
+	}
+	enc := r.encryptionRegistry.GetForEncryption()
+	if enc == nil || enc.Algorithm() == "noop" {
+		return string(data), domainservices.EncryptionMetadata{}, nil
+	}
+	encrypted, err := enc.Encrypt(ctx, string(data))
+	if err != nil {
+		return "", domainservices.EncryptionMetadata{}, fmt.Errorf("failed to encrypt session %s: %w", record.ID, err)
+	}
+	return encrypted.EncryptedValue, encrypted.Metadata, nil
+}
+
+func (r *SQLSessionRepository) decodeState(ctx context.Context, state string, meta domainservices.EncryptionMetadata) (*sessionStateJSON, error) {
+	plaintext := state
+	if meta.Algorithm != "" && meta.Algorithm != "noop" {
+		if r.encryptionRegistry == nil {
+			return nil, fmt.Errorf("state is encrypted with %s but no encryption service is configured", meta.Algorithm)
+		}
+		dec := r.encryptionRegistry.GetForDecryption(meta)
+		if dec == nil {
+			return nil, fmt.Errorf("no decryption service for %s (keyID: %s)", meta.Algorithm, meta.KeyID)
+		}
+		var err error
+		plaintext, err = dec.Decrypt(ctx, &domainservices.EncryptedData{EncryptedValue: state, Metadata: meta})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt state: %w", err)
+		}
+	}
+	var decoded sessionStateJSON
+	if err := json.Unmarshal([]byte(plaintext), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if decoded.Request == nil {
+		decoded.Request = &entities.RunServerRequest{}
+	}
+	return &decoded, nil
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// openSQLiteSessionRepository opens a file-backed SQLite database and skips
+// the test when the driver is unavailable (CGO_ENABLED=0).
+func openSQLiteSessionRepository(t *testing.T, dsn string, reg *infraservices.EncryptionServiceRegistry) *SQLSessionRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Skipf("sqlite3 driver unavailable: %v", err)
+	}
+
+	repo, err := NewSQLSessionRepository(context.Background(), db, SQLDialectSQLite, reg)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestSQLSessionRepository_SQLiteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "sessions.db")
+	reg := infraservices.NewEncryptionServiceRegistry(base64EncryptionService{})
+	repo := openSQLiteSessionRepository(t, dsn, reg)
+
+	alice := &portrepos.SessionRecord{
+		ID: "session-1",
+		Request: &entities.RunServerRequest{
+			UserID:      "alice",
+			Environment: map[string]string{"FOO": "bar"},
+		},
+		WebhookPayload: []byte(`{"action":"opened"}`),
+	}
+	bob := &portrepos.SessionRecord{
+		ID:      "session-2",
+		Request: &entities.RunServerRequest{UserID: "bob"},
+	}
+	require.NoError(t, repo.Ping(ctx))
+	require.NoError(t, repo.Save(ctx, alice))
+	require.NoError(t, repo.Save(ctx, bob))
+
+	got, err := repo.Get(ctx, "session-1")
+	require.NoError(t, err)
+	assert.Equal(t, alice.Request, got.Request)
+	assert.Equal(t, alice.WebhookPayload, got.WebhookPayload)
+
+	// Saving again updates the record in place.
+	alice.Request.Environment["FOO"] = "baz"
+	require.NoError(t, repo.Save(ctx, alice))
+	got, err = repo.Get(ctx, "session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "baz", got.Request.Environment["FOO"])
+
+	all, err := repo.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	mine, err := repo.List(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Equal(t, "session-2", mine[0].ID)
+
+	require.NoError(t, repo.Delete(ctx, "session-2"))
+	got, err = repo.Get(ctx, "session-2")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	// A second repository on the same file sees the persisted state, which is
+	// how sessions survive a proxy restart.
+	reopened := openSQLiteSessionRepository(t, dsn, reg)
+	all, err = reopened.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "session-1", all[0].ID)
+	assert.Equal(t, "baz", all[0].Request.Environment["FOO"])
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	domainservices "github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+	infraservices "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// base64EncryptionService is a reversible stand-in for a real encryption service.
+type base64EncryptionService struct{}
+
+func (base64EncryptionService) Encrypt(_ context.Context, plaintext string) (*domainservices.EncryptedData, error) {
+	return &domainservices.EncryptedData{
+		EncryptedValue: base64.StdEncoding.EncodeToString([]byte(plaintext)),
+		Metadata:       domainservices.EncryptionMetadata{Algorithm: "base64", KeyID: "test"},
+	}, nil
+}
+
+func (base64EncryptionService) Decrypt(_ context.Context, encrypted *domainservices.EncryptedData) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(encrypted.EncryptedValue)
+	return string(b), err
+}
+
+func (base64EncryptionService) Algorithm() string { return "base64" }
+func (base64EncryptionService) KeyID() string     { return "test" }
+
+func TestSQLSessionRepository_Rebind(t *testing.T) {
+	query := "SELECT id FROM t WHERE a = ? AND b = ?"
+
+	pg := &SQLSessionRepository{dialect: SQLDialectPostgres}
+	assert.Equal(t, "SELECT id FROM t WHERE a = $1 AND b = $2", pg.rebind(query))
+
+	lite := &SQLSessionRepository{dialect: SQLDialectSQLite}
+	assert.Equal(t, query, lite.rebind(query))
+}
+
+func TestSQLSessionRepository_StateRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	record := &portrepos.SessionRecord{
+		ID: "session-1",
+		Request: &entities.RunServerRequest{
+			UserID:      "alice",
+			Environment: map[string]string{"FOO": "bar"},
+		},
+		WebhookPayload: []byte(`{"action":"opened"}`),
+	}
+
+	tests := []struct {
+		name      string
+		registry  *infraservices.EncryptionServiceRegistry
+		encrypted bool
+	}{
+		{"no registry", nil, false},
+		{"noop", infraservices.NewEncryptionServiceRegistry(infraservices.NewNoopEncryptionService()), false},
+		{"encrypted", infraservices.NewEncryptionServiceRegistry(base64EncryptionService{}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &SQLSessionRepository{dialect: SQLDialectSQLite, encryptionRegistry: tt.registry}
+
+			state, meta, err := r.encodeState(ctx, record)
+			require.NoError(t, err)
+			if tt.encrypted {
+				assert.Equal(t, "base64", meta.Algorithm)
+				assert.NotContains(t, state, "alice")
+			} else {
+				assert.Empty(t, meta.Algorithm)
+				assert.Contains(t, state, "alice")
+			}
+
+			decoded, err := r.decodeState(ctx, state, meta)
+			require.NoError(t, err)
+			assert.Equal(t, record.Request, decoded.Request)
+			assert.Equal(t, record.WebhookPayload, decoded.WebhookPayload)
+		})
+	}
+}
+
+func TestSQLSessionRepository_DecodeEncryptedWithoutRegistry(t *testing.T) {
+	r := &SQLSessionRepository{dialect: SQLDialectSQLite}
+	_, err := r.decodeState(context.Background(), "c2VjcmV0", domainservices.EncryptionMetadata{Algorithm: "base64"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,382 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// Stores that can be migrated to another backend.
+const (
+	StorageMigrationMemory       = "memory"
+	StorageMigrationSessionStore = "session_store"
+)
+
+const (
+	storageMigrationConfigMapName = "agentapi-storage-migration"
+	// storageMigrationStateTTL is how long the dual-write repositories cache
+	// the migration state, i.e. how long a cutover takes to reach all replicas.
+	storageMigrationStateTTL = 30 * time.Second
+)
+
+// StorageMigrationState records that reads of a store have been cut over
+// from the source to the target backend.
+type StorageMigrationState struct {
+	Source    string                  `json:"source"`
+	Target    string                  `json:"target"`
+	CutOverAt time.Time               `json:"cut_over_at"`
+	Verified  *StorageMigrationReport `json:"verified,omitempty"`
+}
+
+// KubernetesStorageMigrationStateRepository keeps the state of storage
+// migrations in one ConfigMap, with one key per store.
+type KubernetesStorageMigrationStateRepository struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+func NewKubernetesStorageMigrationStateRepository(client kubernetes.Interface, namespace string) *KubernetesStorageMigrationStateRepository {
+	return &KubernetesStorageMigrationStateRepository{client: client, namespace: namespace}
+}
+
+// Get returns the state of store, or nil when it has not been cut over.
+func (r *KubernetesStorageMigrationStateRepository) Get(ctx context.Context, store string) (*StorageMigrationState, error) {
+	cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, storageMigrationConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get storage migration configmap: %w", err)
+	}
+	raw, ok := cm.Data[store]
+	if !ok {
+		return nil, nil
+	}
+	var state StorageMigrationState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return nil, fmt.Errorf("unmarshal storage migration state of %s: %w", store, err)
+	}
+	return &state, nil
+}
+
+// Set records the state of store; a nil state rolls the cutover back.
+func (r *KubernetesStorageMigrationStateRepository) Set(ctx context.Context, store string, state *StorageMigrationState) error {
+	configMaps := r.client.CoreV1().ConfigMaps(r.namespace)
+	existing, err := configMaps.Get(ctx, storageMigrationConfigMapName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("get storage migration configmap: %w", err)
+	}
+	create := k8serrors.IsNotFound(err)
+	if create {
+		if state == nil {
+			return nil
+		}
+		existing = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      storageMigrationConfigMapName,
+				Namespace: r.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+			},
+		}
+	}
+	if existing.Data == nil {
+		existing.Data = make(map[string]string)
+	}
+	if state == nil {
+		delete(existing.Data, store)
+	} else {
+		raw, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("marshal storage migration state: %w", err)
+		}
+		existing.Data[store] = string(raw)
+	}
+	if create {
+		_, err = configMaps.Create(ctx, existing, metav1.CreateOptions{})
+	} else {
+		_, err = configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// storageMigrationReadSwitch tells the dual-write repositories of a store
+// whether reads have been cut over to the target. The state is cached so
+// that requests do not hit the Kubernetes API.
+type storageMigrationReadSwitch struct {
+	states         *KubernetesStorageMigrationStateRepository
+	store          string
+	source, target string
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	cutOver   bool
+}
+
+func (s *storageMigrationReadSwitch) readsTarget(ctx context.Context) bool {
+	if s == nil || s.states == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) < storageMigrationStateTTL {
+		return s.cutOver
+	}
+	state, err := s.states.Get(ctx, s.store)
+	if err != nil {
+		// Keep the last known state; retry on the next read.
+		return s.cutOver
+	}
+	s.checkedAt = time.Now()
+	s.cutOver = state != nil && state.Source == s.source && state.Target == s.target
+	return s.cutOver
+}
+
+// StorageMigrationReport compares the records of the source and target
+// backends of a store.
+type StorageMigrationReport struct {
+	Store          string `json:"store"`
+	SourceCount    int    `json:"source_count"`
+	TargetCount    int    `json:"target_count"`
+	SourceChecksum string `json:"source_checksum"`
+	TargetChecksum string `json:"target_checksum"`
+	// Missing are records of the source that the target lacks
+	Missing []string `json:"missing,omitempty"`
+	// Mismatched are records whose content differs
+	Mismatched []string `json:"mismatched,omitempty"`
+	// Extra are records of the target that the source lacks
+	Extra []string `json:"extra,omitempty"`
+}
+
+// OK reports whether the target holds exactly the records of the source.
+func (r *StorageMigrationReport) OK() bool {
+	return r.SourceCount == r.TargetCount && r.SourceChecksum == r.TargetChecksum &&
+		len(r.Missing) == 0 && len(r.Mismatched) == 0 && len(r.Extra) == 0
+}
+
+// compareChecksums builds the report of two sets of per-record checksums.
+func compareChecksums(store string, source, target map[string]string) *StorageMigrationReport {
+	report := &StorageMigrationReport{
+		Store:          store,
+		SourceCount:    len(source),
+		TargetCount:    len(target),
+		SourceChecksum: combinedChecksum(source),
+		TargetChecksum: combinedChecksum(target),
+	}
+	for id, sum := range source {
+		targetSum, ok := target[id]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, id)
+		case targetSum != sum:
+			report.Mismatched = append(report.Mismatched, id)
+		}
+	}
+	for id := range target {
+		if _, ok := source[id]; !ok {
+			report.Extra = append(report.Extra, id)
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Mismatched)
+	sort.Strings(report.Extra)
+	return report
+}
+
+// combinedChecksum hashes the checksums of all records in ID order.
+func combinedChecksum(sums map[string]string) string {
+	ids := make([]string, 0, len(sums))
+	for id := range sums {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	h := sha256.New()
+	for _, id := range ids {
+		_, _ = fmt.Fprintf(h, "%s:%s\n", id, sums[id])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checksumJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// memoryChecksum hashes the content of a memory. Timestamps are compared at
+// second precision, which every backend keeps.
+func memoryChecksum(m *entities.Memory) (string, error) {
+	return checksumJSON(struct {
+		Title     string            `json:"title"`
+		Content   string            `json:"content"`
+		Tags      map[string]string `json:"tags"`
+		Scope     string            `json:"scope"`
+		OwnerID   string            `json:"owner_id"`
+		TeamID    string            `json:"team_id"`
+		CreatedAt int64             `json:"created_at"`
+		UpdatedAt int64             `json:"updated_at"`
+	}{
+		Title:     m.Title(),
+		Content:   m.Content(),
+		Tags:      m.Tags(),
+		Scope:     string(m.Scope()),
+		OwnerID:   m.OwnerID(),
+		TeamID:    m.TeamID(),
+		CreatedAt: m.CreatedAt().Unix(),
+		UpdatedAt: m.UpdatedAt().Unix(),
+	})
+}
+
+// sessionRecordChecksum hashes a session record. UpdatedAt is left out
+// because the SQL backends set it to the time of each write.
+func sessionRecordChecksum(record *portrepos.SessionRecord) (string, error) {
+	return checksumJSON(struct {
+		Request        *entities.RunServerRequest `json:"request"`
+		WebhookPayload []byte                     `json:"webhook_payload"`
+		CreatedAt      int64                      `json:"created_at"`
+	}{
+		Request:        record.Request,
+		WebhookPayload: record.WebhookPayload,
+		CreatedAt:      record.CreatedAt.Unix(),
+	})
+}
+
+func memoryChecksums(ctx context.Context, repo portrepos.MemoryRepository) (map[string]*entities.Memory, map[string]string, error) {
+	memories, err := repo.List(ctx, portrepos.MemoryFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*entities.Memory, len(memories))
+	sums := make(map[string]string, len(memories))
+	for _, m := range memories {
+		sum, err := memoryChecksum(m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("checksum of memory %s: %w", m.ID(), err)
+		}
+		byID[m.ID()] = m
+		sums[m.ID()] = sum
+	}
+	return byID, sums, nil
+}
+
+func sessionRecordChecksums(ctx context.Context, repo portrepos.SessionRepository) (map[string]*portrepos.SessionRecord, map[string]string, error) {
+	records, err := repo.List(ctx, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*portrepos.SessionRecord, len(records))
+	sums := make(map[string]string, len(records))
+	for _, record := range records {
+		sum, err := sessionRecordChecksum(record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("checksum of session %s: %w", record.ID, err)
+		}
+		byID[record.ID] = record
+		sums[record.ID] = sum
+	}
+	return byID, sums, nil
+}
+
+// VerifyMemoryMigration compares the memories of source and target.
+func VerifyMemoryMigration(ctx context.Context, source, target portrepos.MemoryRepository) (*StorageMigrationReport, error) {
+	_, sourceSums, err := memoryChecksums(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source memories: %w", err)
+	}
+	_, targetSums, err := memoryChecksums(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target memories: %w", err)
+	}
+	return compareChecksums(StorageMigrationMemory, sourceSums, targetSums), nil
+}
+
+// BackfillMemoryMigration copies the memories that are missing or differ
+// from source to target. With deleteExtra, memories only the target has
+// are deleted. It returns the report of the state before the backfill.
+func BackfillMemoryMigration(ctx context.Context, source, target portrepos.MemoryRepository, deleteExtra bool) (*StorageMigrationReport, error) {
+	sourceMemories, sourceSums, err := memoryChecksums(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source memories: %w", err)
+	}
+	_, targetSums, err := memoryChecksums(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target memories: %w", err)
+	}
+	report := compareChecksums(StorageMigrationMemory, sourceSums, targetSums)
+	for _, id := range report.Missing {
+		if err := target.Create(ctx, sourceMemories[id]); err != nil {
+			return report, fmt.Errorf("failed to copy memory %s: %w", id, err)
+		}
+	}
+	for _, id := range report.Mismatched {
+		if err := target.Update(ctx, sourceMemories[id]); err != nil {
+			return report, fmt.Errorf("failed to update memory %s: %w", id, err)
+		}
+	}
+	if deleteExtra {
+		for _, id := range report.Extra {
+			var notFound entities.ErrMemoryNotFound
+			if err := target.Delete(ctx, id); err != nil && !errors.As(err, &notFound) {
+				return report, fmt.Errorf("failed to delete memory %s: %w", id, err)
+			}
+		}
+	}
+	return report, nil
+}
+
+// VerifySessionMigration compares the session records of source and target.
+func VerifySessionMigration(ctx context.Context, source, target portrepos.SessionRepository) (*StorageMigrationReport, error) {
+	_, sourceSums, err := sessionRecordChecksums(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source sessions: %w", err)
+	}
+	_, targetSums, err := sessionRecordChecksums(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target sessions: %w", err)
+	}
+	return compareChecksums(StorageMigrationSessionStore, sourceSums, targetSums), nil
+}
+
+// BackfillSessionMigration copies the session records that are missing or
+// differ from source to target. With deleteExtra, records only the target
+// has are deleted. It returns the report of the state before the backfill.
+func BackfillSessionMigration(ctx context.Context, source, target portrepos.SessionRepository, deleteExtra bool) (*StorageMigrationReport, error) {
+	sourceRecords, sourceSums, err := sessionRecordChecksums(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source sessions: %w", err)
+	}
+	_, targetSums, err := sessionRecordChecksums(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target sessions: %w", err)
+	}
+	report := compareChecksums(StorageMigrationSessionStore, sourceSums, targetSums)
+	for _, id := range append(append([]string(nil), report.Missing...), report.Mismatched...) {
+		if err := target.Save(ctx, sourceRecords[id]); err != nil {
+			return report, fmt.Errorf("failed to copy session %s: %w", id, err)
+		}
+	}
+	if deleteExtra {
+		for _, id := range report.Extra {
+			if err := target.Delete(ctx, id); err != nil {
+				return report, fmt.Errorf("failed to delete session %s: %w", id, err)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// mapSessionRepository is an in-memory SessionRepository.
+type mapSessionRepository struct {
+	mu      sync.Mutex
+	records map[string]*portrepos.SessionRecord
+}
+
+func newMapSessionRepository() *mapSessionRepository {
+	return &mapSessionRepository{records: make(map[string]*portrepos.SessionRecord)}
+}
+
+func (r *mapSessionRepository) Save(_ context.Context, record *portrepos.SessionRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *record
+	copied.UpdatedAt = time.Now()
+	r.records[record.ID] = &copied
+	return nil
+}
+
+func (r *mapSessionRepository) Get(_ context.Context, sessionID string) (*portrepos.SessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.records[sessionID], nil
+}
+
+func (r *mapSessionRepository) List(_ context.Context, _ string) ([]*portrepos.SessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []*portrepos.SessionRecord
+	for _, record := range r.records {
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (r *mapSessionRepository) Delete(_ context.Context, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, sessionID)
+	return nil
+}
+
+func TestMemoryMigrationBackfillAndCutover(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	source := NewKubernetesMemoryRepository(client, "source")
+	target := NewKubernetesMemoryRepository(client, "target")
+	states := NewKubernetesStorageMigrationStateRepository(client, "default")
+
+	for _, m := range []*entities.Memory{
+		newTestMemory("m-1", "one", "first", entities.ScopeUser, "alice", ""),
+		newTestMemory("m-2", "two", "second", entities.ScopeTeam, "alice", "org/team"),
+	} {
+		if err := source.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := newTestMemory("m-2", "two", "outdated", entities.ScopeTeam, "alice", "org/team")
+	if err := target.Create(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Create(ctx, newTestMemory("m-3", "three", "deleted", entities.ScopeUser, "bob", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := VerifyMemoryMigration(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() || len(report.Missing) != 1 || len(report.Mismatched) != 1 || len(report.Extra) != 1 {
+		t.Fatalf("report = %+v, want one missing, mismatched and extra memory", report)
+	}
+
+	if _, err := BackfillMemoryMigration(ctx, source, target, true); err != nil {
+		t.Fatalf("BackfillMemoryMigration: %v", err)
+	}
+	report, err = VerifyMemoryMigration(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.SourceCount != 2 {
+		t.Fatalf("report after backfill = %+v", report)
+	}
+
+	// Writes go to both backends, reads come from the source until the cutover.
+	repo := NewDualWriteMemoryRepository(source, target, "kubernetes", "s3", states)
+	if err := repo.Create(ctx, newTestMemory("m-4", "four", "dual", entities.ScopeUser, "alice", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.GetByID(ctx, "m-4"); err != nil {
+		t.Fatalf("dual write missed the target: %v", err)
+	}
+	if err := source.Delete(ctx, "m-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, "m-1"); err == nil {
+		t.Fatal("read came from the target before the cutover")
+	}
+
+	if err := states.Set(ctx, StorageMigrationMemory, &StorageMigrationState{Source: "kubernetes", Target: "s3"}); err != nil {
+		t.Fatal(err)
+	}
+	repo = NewDualWriteMemoryRepository(source, target, "kubernetes", "s3", states)
+	if _, err := repo.GetByID(ctx, "m-1"); err != nil {
+		t.Fatalf("read did not switch to the target after the cutover: %v", err)
+	}
+
+	// A cutover of another migration does not switch reads.
+	repo = NewDualWriteMemoryRepository(source, target, "s3", "kubernetes", states)
+	if _, err := repo.GetByID(ctx, "m-1"); err == nil {
+		t.Fatal("read switched by the cutover of another migration")
+	}
+
+	if err := states.Set(ctx, StorageMigrationMemory, nil); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := states.Get(ctx, StorageMigrationMemory); err != nil || state != nil {
+		t.Fatalf("state after rollback = %+v, %v", state, err)
+	}
+}
+
+func TestSessionMigrationIgnoresUpdatedAt(t *testing.T) {
+	ctx := context.Background()
+	source := newMapSessionRepository()
+	target := newMapSessionRepository()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := &portrepos.SessionRecord{
+		ID:        "session-1",
+		Request:   &entities.RunServerRequest{UserID: "alice", Environment: map[string]string{"FOO": "bar"}},
+		CreatedAt: created,
+	}
+	if err := source.Save(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := BackfillSessionMigration(ctx, source, target, false)
+	if err != nil {
+		t.Fatalf("BackfillSessionMigration: %v", err)
+	}
+	if len(before.Missing) != 1 {
+		t.Fatalf("report before backfill = %+v", before)
+	}
+	// Saving sets UpdatedAt again, which does not make the records differ.
+	report, err := VerifySessionMigration(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Fatalf("report after backfill = %+v", report)
+	}
+
+	changed := *record
+	changed.Request = &entities.RunServerRequest{UserID: "alice", Environment: map[string]string{"FOO": "baz"}}
+	if err := target.Save(ctx, &changed); err != nil {
+		t.Fatal(err)
+	}
+	report, err = VerifySessionMigration(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() || len(report.Mismatched) != 1 || report.SourceChecksum == report.TargetChecksum {
+		t.Fatalf("changed environment not detected: %+v", report)
+	}
+}
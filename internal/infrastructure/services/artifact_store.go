@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// artifactsDir is the key prefix of artifacts inside the asset storage. It is
+// outside of assets/, so artifacts are not served publicly.
+const artifactsDir = "artifacts"
+
+// ErrArtifactNotFound is returned by OpenArtifact for unknown keys.
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Artifact describes a stored artifact.
+type Artifact struct {
+	Key       string
+	Size      int64
+	UpdatedAt time.Time
+}
+
+// ArtifactStore stores private files produced by sessions, such as terminal
+// recordings. Unlike assets, artifacts have no public URL and are only read
+// back through the proxy.
+type ArtifactStore interface {
+	PutArtifact(ctx context.Context, key, contentType string, body io.Reader) error
+	ListArtifacts(ctx context.Context, prefix string) ([]Artifact, error)
+	OpenArtifact(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// validateArtifactKey rejects keys that could escape the artifacts directory.
+func validateArtifactKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	return nil
+}
+
+// PutArtifact writes body to <root>/artifacts/<key>.
+func (s *FilesystemAssetStore) PutArtifact(ctx context.Context, key, contentType string, body io.Reader) error {
+	_ = ctx
+	_ = contentType
+	if err := validateArtifactKey(key); err != nil {
+		return err
+	}
+	p := filepath.Join(s.root, artifactsDir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see partial artifacts.
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".artifact-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// ListArtifacts returns the artifacts whose key starts with prefix, sorted by key.
+func (s *FilesystemAssetStore) ListArtifacts(ctx context.Context, prefix string) ([]Artifact, error) {
+	_ = ctx
+	if strings.Contains(prefix, "..") {
+		return nil, fmt.Errorf("invalid artifact prefix %q", prefix)
+	}
+	base := filepath.Join(s.root, artifactsDir)
+	dir := filepath.Join(base, filepath.FromSlash(path.Dir(prefix+"x")))
+	var artifacts []Artifact
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".artifact-") {
+			return nil
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{Key: key, Size: info.Size(), UpdatedAt: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Key < artifacts[j].Key })
+	return artifacts, nil
+}
+
+// OpenArtifact opens the artifact stored under key.
+func (s *FilesystemAssetStore) OpenArtifact(ctx context.Context, key string) (io.ReadCloser, error) {
+	_ = ctx
+	if err := validateArtifactKey(key); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(s.root, artifactsDir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrArtifactNotFound
+	}
+	return f, err
+}
+
+func (s *S3AssetStore) artifactObjectKey(key string) string {
+	objectKey := artifactsDir + "/" + key
+	if s.prefix != "" {
+		objectKey = s.prefix + "/" + objectKey
+	}
+	return objectKey
+}
+
+// PutArtifact uploads body as <prefix>/artifacts/<key>.
+func (s *S3AssetStore) PutArtifact(ctx context.Context, key, contentType string, body io.Reader) error {
+	if err := validateArtifactKey(key); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.artifactObjectKey(key)),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// ListArtifacts returns the artifacts whose key starts with prefix, sorted by key.
+func (s *S3AssetStore) ListArtifacts(ctx context.Context, prefix string) ([]Artifact, error) {
+	base := s.artifactObjectKey("")
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(base + prefix),
+	})
+	var artifacts []Artifact
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+			artifact := Artifact{Key: strings.TrimPrefix(*obj.Key, base)}
+			if obj.Size != nil {
+				artifact.Size = *obj.Size
+			}
+			if obj.LastModified != nil {
+				artifact.UpdatedAt = *obj.LastModified
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts, nil
+}
+
+// OpenArtifact downloads the artifact stored under key.
+func (s *S3AssetStore) OpenArtifact(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateArtifactKey(key); err != nil {
+		return nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.artifactObjectKey(key)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrArtifactNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -43,3 +44,45 @@ func TestJoinURL(t *testing.T) {
 		t.Fatalf("joinURL() = %q, want %q", got, want)
 	}
 }
+
+func TestFilesystemArtifactStore(t *testing.T) {
+	root := t.TempDir()
+	store := NewFilesystemAssetStore(root, "")
+	ctx := context.Background()
+
+	for _, key := range []string{"recordings/sess-1/b.cast", "recordings/sess-1/a.cast", "recordings/sess-2/c.cast"} {
+		if err := store.PutArtifact(ctx, key, "application/x-asciicast", strings.NewReader(key)); err != nil {
+			t.Fatalf("PutArtifact(%s) returned error: %v", key, err)
+		}
+	}
+	if err := store.PutArtifact(ctx, "../escape", "text/plain", strings.NewReader("x")); err == nil {
+		t.Fatal("expected error for key outside the artifacts directory")
+	}
+	if _, err := os.Stat(filepath.Join(root, "assets")); !os.IsNotExist(err) {
+		t.Fatalf("artifacts must not be written to the public assets directory: %v", err)
+	}
+
+	artifacts, err := store.ListArtifacts(ctx, "recordings/sess-1/")
+	if err != nil {
+		t.Fatalf("ListArtifacts returned error: %v", err)
+	}
+	if len(artifacts) != 2 || artifacts[0].Key != "recordings/sess-1/a.cast" || artifacts[1].Key != "recordings/sess-1/b.cast" {
+		t.Fatalf("unexpected artifacts: %+v", artifacts)
+	}
+	if missing, err := store.ListArtifacts(ctx, "recordings/none/"); err != nil || len(missing) != 0 {
+		t.Fatalf("ListArtifacts(missing) = %+v, %v", missing, err)
+	}
+
+	rc, err := store.OpenArtifact(ctx, "recordings/sess-2/c.cast")
+	if err != nil {
+		t.Fatalf("OpenArtifact returned error: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(data) != "recordings/sess-2/c.cast" {
+		t.Fatalf("unexpected artifact content: %q", data)
+	}
+	if _, err := store.OpenArtifact(ctx, "recordings/sess-2/missing.cast"); err != ErrArtifactNotFound {
+		t.Fatalf("OpenArtifact(missing) error = %v, want ErrArtifactNotFound", err)
+	}
+}
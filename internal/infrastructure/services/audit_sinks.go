@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// Audit sink types of config.AuditSinkConfig
+const (
+	AuditSinkFile    = "file"
+	AuditSinkStdout  = "stdout"
+	AuditSinkS3      = "s3"
+	AuditSinkWebhook = "webhook"
+)
+
+// auditWebhookTimeout bounds one webhook delivery
+const auditWebhookTimeout = 10 * time.Second
+
+// AuditSink receives audit events in addition to the audit log, e.g. to
+// forward them to a SIEM.
+type AuditSink interface {
+	WriteAuditEvent(ctx context.Context, event entities.AuditEvent) error
+}
+
+// NewAuditSinks creates the configured audit sinks
+func NewAuditSinks(ctx context.Context, cfgs []config.AuditSinkConfig) ([]AuditSink, error) {
+	sinks := make([]AuditSink, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		var (
+			sink AuditSink
+			err  error
+		)
+		switch cfg.Type {
+		case AuditSinkFile:
+			sink, err = NewFileAuditSink(cfg.Path)
+		case AuditSinkStdout:
+			sink = NewWriterAuditSink(os.Stdout)
+		case AuditSinkS3:
+			sink, err = NewS3AuditSink(ctx, cfg.S3)
+		case AuditSinkWebhook:
+			sink, err = NewWebhookAuditSink(cfg.URL, cfg.Headers)
+		default:
+			err = fmt.Errorf("unknown type %q", cfg.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("audit.sinks[%d]: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// WriterAuditSink writes audit events as JSON Lines to a writer
+type WriterAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterAuditSink creates a WriterAuditSink
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+// WriteAuditEvent writes event as one JSON line
+func (s *WriterAuditSink) WriteAuditEvent(_ context.Context, event entities.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// NewFileAuditSink creates a sink appending JSON Lines to the file at path
+func NewFileAuditSink(path string) (*WriterAuditSink, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterAuditSink(f), nil
+}
+
+// S3AuditSink stores each audit event as a JSON object under
+// <prefix>/YYYY/MM/DD/ in S3 or S3-compatible storage.
+type S3AuditSink struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3AuditSink creates an S3AuditSink
+func NewS3AuditSink(ctx context.Context, cfg *config.AssetS3Config) (*S3AuditSink, error) {
+	if cfg == nil || cfg.Bucket == "" {
+		return nil, errors.New("s3.bucket is required")
+	}
+	loadOpts := []func(*awsconfig.LoadOptions) error{}
+	if cfg.Region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3AuditSink{client: client, bucket: cfg.Bucket, prefix: strings.Trim(cfg.Prefix, "/")}, nil
+}
+
+// WriteAuditEvent uploads event as a new object
+func (s *S3AuditSink) WriteAuditEvent(ctx context.Context, event entities.AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key := event.Timestamp.UTC().Format("2006/01/02/150405.000000000") + "-" + uuid.New().String() + ".json"
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// WebhookAuditSink POSTs each audit event as JSON to a URL
+type WebhookAuditSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookAuditSink creates a WebhookAuditSink. headers are added to every
+// request, e.g. for authentication.
+func NewWebhookAuditSink(url string, headers map[string]string) (*WebhookAuditSink, error) {
+	if url == "" {
+		return nil, errors.New("url is required")
+	}
+	return &WebhookAuditSink{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: auditWebhookTimeout},
+	}, nil
+}
+
+// WriteAuditEvent delivers event; non-2xx responses are errors
+func (s *WebhookAuditSink) WriteAuditEvent(ctx context.Context, event entities.AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// TeeAuditRepository is an AuditRepository that also writes every recorded
+// event to audit sinks.
+type TeeAuditRepository struct {
+	portrepos.AuditRepository
+	sinks []AuditSink
+}
+
+// NewTeeAuditRepository wraps repo so recorded events also go to sinks
+func NewTeeAuditRepository(repo portrepos.AuditRepository, sinks []AuditSink) *TeeAuditRepository {
+	return &TeeAuditRepository{AuditRepository: repo, sinks: sinks}
+}
+
+// RecordAuditEvent stores event and writes it to every sink. A failing sink
+// does not keep the event from the others.
+func (r *TeeAuditRepository) RecordAuditEvent(ctx context.Context, event entities.AuditEvent) error {
+	errs := []error{r.AuditRepository.RecordAuditEvent(ctx, event)}
+	for _, sink := range r.sinks {
+		if err := sink.WriteAuditEvent(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("sink %T: %w", sink, err))
+		}
+	}
+	return errors.Join(errs...)
+}
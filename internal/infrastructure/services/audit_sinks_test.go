@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func testAuditEvent() entities.AuditEvent {
+	return entities.AuditEvent{
+		Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Category:  entities.AuditCategoryAccess,
+		Action:    "POST /start",
+		Operation: entities.AuditOpSessionCreate,
+		Actor:     "alice",
+		Outcome:   entities.AuditOutcomeSuccess,
+	}
+}
+
+func TestFileAuditSinkAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "events.jsonl")
+	sinks, err := NewAuditSinks(context.Background(), []config.AuditSinkConfig{{Type: AuditSinkFile, Path: path}})
+	if err != nil {
+		t.Fatalf("NewAuditSinks() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := sinks[0].WriteAuditEvent(context.Background(), testAuditEvent()); err != nil {
+			t.Fatalf("WriteAuditEvent() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read audit file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var got entities.AuditEvent
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("unmarshal line: %v", err)
+	}
+	if got.Operation != entities.AuditOpSessionCreate || got.Actor != "alice" {
+		t.Errorf("event = %+v", got)
+	}
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	var received entities.AuditEvent
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink, err := NewWebhookAuditSink(srv.URL, map[string]string{"Authorization": "Bearer secret"})
+	if err != nil {
+		t.Fatalf("NewWebhookAuditSink() error = %v", err)
+	}
+	if err := sink.WriteAuditEvent(context.Background(), testAuditEvent()); err != nil {
+		t.Fatalf("WriteAuditEvent() error = %v", err)
+	}
+	if received.Action != "POST /start" || token != "Bearer secret" {
+		t.Errorf("received %+v with token %q", received, token)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	sink, _ = NewWebhookAuditSink(failing.URL, nil)
+	if err := sink.WriteAuditEvent(context.Background(), testAuditEvent()); err == nil {
+		t.Error("WriteAuditEvent() to failing webhook succeeded, want error")
+	}
+}
+
+type recordingAuditRepository struct {
+	portrepos.AuditRepository
+	events []entities.AuditEvent
+}
+
+func (r *recordingAuditRepository) RecordAuditEvent(_ context.Context, event entities.AuditEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type failingAuditSink struct{}
+
+func (failingAuditSink) WriteAuditEvent(context.Context, entities.AuditEvent) error {
+	return errors.New("unavailable")
+}
+
+func TestTeeAuditRepositoryWritesAllSinks(t *testing.T) {
+	repo := &recordingAuditRepository{}
+	var buf bytes.Buffer
+	tee := NewTeeAuditRepository(repo, []AuditSink{failingAuditSink{}, NewWriterAuditSink(&buf)})
+
+	event := testAuditEvent()
+	if err := tee.RecordAuditEvent(context.Background(), event); err == nil {
+		t.Error("RecordAuditEvent() error = nil, want the failing sink's error")
+	}
+	if !strings.Contains(buf.String(), `"operation":"session.create"`) {
+		t.Errorf("writer sink got %q", buf.String())
+	}
+	if len(repo.events) != 1 {
+		t.Errorf("stored %d events, want 1", len(repo.events))
+	}
+}
+
+func TestNewAuditSinksRejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.AuditSinkConfig{
+		{Type: "syslog"},
+		{Type: AuditSinkFile},
+		{Type: AuditSinkWebhook},
+		{Type: AuditSinkS3},
+	} {
+		if _, err := NewAuditSinks(context.Background(), []config.AuditSinkConfig{cfg}); err == nil {
+			t.Errorf("NewAuditSinks(%+v) succeeded, want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+// AWSSecretsManagerProvider stores secrets in AWS Secrets Manager. Each key is
+// stored as one secret whose SecretString is a JSON object.
+//
+// Reads return the AWSCURRENT version, so values rotated by a Secrets Manager
+// rotation function take effect on the next read.
+type AWSSecretsManagerProvider struct {
+	region      string
+	prefix      string
+	endpoint    string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	httpClient  *http.Client
+}
+
+// awsSecretsManagerError is the error body of the Secrets Manager JSON protocol
+type awsSecretsManagerError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *awsSecretsManagerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// NewAWSSecretsManagerProvider creates a new AWSSecretsManagerProvider.
+// Secrets are named "<prefix>/<key>"; prefix defaults to "agentapi".
+func NewAWSSecretsManagerProvider(region, prefix string) (*AWSSecretsManagerProvider, error) {
+	if region == "" {
+		return nil, fmt.Errorf("AWS region is required")
+	}
+	if prefix == "" {
+		prefix = "agentapi"
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &AWSSecretsManagerProvider{
+		region:      region,
+		prefix:      strings.Trim(prefix, "/"),
+		endpoint:    fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region),
+		credentials: cfg.Credentials,
+		signer:      v4.NewSigner(),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Name returns "aws-secrets-manager"
+func (p *AWSSecretsManagerProvider) Name() string {
+	return "aws-secrets-manager"
+}
+
+// Get returns the current version of the secret stored under key
+func (p *AWSSecretsManagerProvider) Get(ctx context.Context, key string) (map[string]string, error) {
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	err := p.call(ctx, "GetSecretValue", map[string]interface{}{"SecretId": p.secretName(key)}, &out)
+	if isAWSSecretNotFound(err) {
+		return nil, services.ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS secret %s: %w", key, err)
+	}
+
+	data := map[string]string{}
+	if err := json.Unmarshal([]byte(out.SecretString), &data); err != nil {
+		return nil, fmt.Errorf("AWS secret %s is not a JSON object: %w", key, err)
+	}
+	return data, nil
+}
+
+// Put stores data as a new version of the secret, creating the secret if needed
+func (p *AWSSecretsManagerProvider) Put(ctx context.Context, key string, data map[string]string) error {
+	secretString, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	err = p.call(ctx, "PutSecretValue", map[string]interface{}{
+		"SecretId":     p.secretName(key),
+		"SecretString": string(secretString),
+	}, nil)
+	if isAWSSecretNotFound(err) {
+		err = p.call(ctx, "CreateSecret", map[string]interface{}{
+			"Name":         p.secretName(key),
+			"SecretString": string(secretString),
+		}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put AWS secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored under key without a recovery window
+func (p *AWSSecretsManagerProvider) Delete(ctx context.Context, key string) error {
+	err := p.call(ctx, "DeleteSecret", map[string]interface{}{
+		"SecretId":                   p.secretName(key),
+		"ForceDeleteWithoutRecovery": true,
+	}, nil)
+	if err != nil && !isAWSSecretNotFound(err) {
+		return fmt.Errorf("failed to delete AWS secret %s: %w", key, err)
+	}
+	return nil
+}
+
+func (p *AWSSecretsManagerProvider) secretName(key string) string {
+	return p.prefix + "/" + strings.TrimLeft(key, "/")
+}
+
+// call invokes a Secrets Manager API action with a SigV4-signed JSON request
+func (p *AWSSecretsManagerProvider) call(ctx context.Context, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager."+action)
+
+	creds, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "secretsmanager", p.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := &awsSecretsManagerError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Type == "" {
+			return fmt.Errorf("secrets manager returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		}
+		// __type may be qualified, e.g. "com.amazonaws.secretsmanager#ResourceNotFoundException"
+		if i := strings.LastIndex(apiErr.Type, "#"); i >= 0 {
+			apiErr.Type = apiErr.Type[i+1:]
+		}
+		return apiErr
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode secrets manager response: %w", err)
+		}
+	}
+	return nil
+}
+
+func isAWSSecretNotFound(err error) bool {
+	apiErr, ok := err.(*awsSecretsManagerError)
+	return ok && apiErr.Type == "ResourceNotFoundException"
+}
+
+var _ services.SecretsProvider = (*AWSSecretsManagerProvider)(nil)
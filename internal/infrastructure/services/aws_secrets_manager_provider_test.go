@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	secrets := map[string]string{}
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			t.Errorf("request is not SigV4 signed: %q", r.Header.Get("Authorization"))
+		}
+		target := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager.")
+		targets = append(targets, target)
+
+		var in map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		id, _ := in["SecretId"].(string)
+		notFound := func() {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		}
+		switch target {
+		case "GetSecretValue":
+			value, ok := secrets[id]
+			if !ok {
+				notFound()
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": value})
+		case "PutSecretValue":
+			if _, ok := secrets[id]; !ok {
+				notFound()
+				return
+			}
+			secrets[id] = in["SecretString"].(string)
+			_, _ = w.Write([]byte(`{}`))
+		case "CreateSecret":
+			secrets[in["Name"].(string)] = in["SecretString"].(string)
+			_, _ = w.Write([]byte(`{}`))
+		case "DeleteSecret":
+			if _, ok := secrets[id]; !ok {
+				notFound()
+				return
+			}
+			delete(secrets, id)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := &AWSSecretsManagerProvider{
+		region:   "us-east-1",
+		prefix:   "agentapi",
+		endpoint: server.URL,
+		credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		signer:     v4.NewSigner(),
+		httpClient: server.Client(),
+	}
+	ctx := context.Background()
+
+	if _, err := provider.Get(ctx, "personal-api-keys/alice"); !errors.Is(err, services.ErrSecretNotFound) {
+		t.Fatalf("Get() of a missing secret error = %v, want ErrSecretNotFound", err)
+	}
+	if err := provider.Put(ctx, "personal-api-keys/alice", map[string]string{"api_key": "k1"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := provider.Put(ctx, "personal-api-keys/alice", map[string]string{"api_key": "k2"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := provider.Get(ctx, "personal-api-keys/alice")
+	if err != nil || data["api_key"] != "k2" {
+		t.Fatalf("Get() = %v, %v; want api_key=k2", data, err)
+	}
+	if err := provider.Delete(ctx, "personal-api-keys/alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := provider.Delete(ctx, "personal-api-keys/alice"); err != nil {
+		t.Fatalf("Delete() of a missing secret error = %v", err)
+	}
+
+	want := "GetSecretValue,PutSecretValue,CreateSecret,PutSecretValue,GetSecretValue,DeleteSecret,DeleteSecret"
+	if got := strings.Join(targets, ","); got != want {
+		t.Errorf("actions = %s, want %s", got, want)
+	}
+}
@@ -13,12 +13,14 @@ import (
 // pod's in-memory map.  It allows ListSessions to serve results from the Redis
 // cache without hitting the Kubernetes API.
 type cachedSession struct {
-	dto portrepos.CachedSessionDTO
+	dto           portrepos.CachedSessionDTO
+	clusterDomain string
 }
 
-// newCachedSession wraps a CachedSessionDTO as an entities.Session.
-func newCachedSession(dto portrepos.CachedSessionDTO) *cachedSession {
-	return &cachedSession{dto: dto}
+// newCachedSession wraps a CachedSessionDTO as an entities.Session. The session
+// is addressed by its Service DNS name in clusterDomain.
+func newCachedSession(dto portrepos.CachedSessionDTO, clusterDomain string) *cachedSession {
+	return &cachedSession{dto: dto, clusterDomain: clusterDomain}
 }
 
 // Compile-time check.
@@ -29,8 +31,8 @@ func (s *cachedSession) ID() string {
 }
 
 func (s *cachedSession) Addr() string {
-	return fmt.Sprintf("%s.%s.svc.cluster.local:%d",
-		s.dto.ServiceName, s.dto.Namespace, s.dto.ServicePort)
+	return fmt.Sprintf("%s:%d",
+		serviceDNSName(s.dto.ServiceName, s.dto.Namespace, s.clusterDomain), s.dto.ServicePort)
 }
 
 func (s *cachedSession) UserID() string {
@@ -31,6 +31,16 @@ type KubernetesSession struct {
 	resolvedAPIKey    string                           // API key resolved during session creation, used by memory-sync sidecar
 	provisionSettings *sessionsettings.SessionSettings // Settings used for provisioning (stored after successful provisioning)
 	isStock           bool                             // Whether this is a pre-warmed stock session
+	capabilities      []entities.SessionCapability     // Risky capabilities granted at creation
+	preview           *entities.PreviewEnvironment     // Preview environment deployed from the session branch
+	clusterDomain     string                           // DNS suffix of the cluster, "cluster.local" when empty
+	routeHost         string                           // Endpoint address used instead of the Service DNS name
+	endpoints         []string                         // Ready endpoint addresses of multi-replica sessions
+	runsAsJob         bool                             // Whether the workload is a oneshot Job
+	completion        *entities.SessionCompletion      // How the Job ended, once it has finished
+	health            *entities.SessionHealth          // Result of the health probes, once probed
+	githubReport      *entities.GitHubReport           // Working branch, Check Run and pull request on GitHub
+	summaryMessages   int                              // Number of messages the summary was generated from
 
 	// statusChangeCallback is called by SetStatus when the status changes.
 	// Set by KubernetesSessionManager at session creation to enable proxy-wide pub/sub.
@@ -47,6 +57,10 @@ func NewKubernetesSession(
 	webhookPayload []byte,
 ) *KubernetesSession {
 	now := time.Now()
+	var githubReport *entities.GitHubReport
+	if request != nil {
+		githubReport = request.GitHubReport
+	}
 	return &KubernetesSession{
 		id:             id,
 		request:        request,
@@ -61,6 +75,8 @@ func NewKubernetesSession(
 		status:         "creating",
 		cancelFunc:     cancelFunc,
 		webhookPayload: webhookPayload,
+		capabilities:   entities.RequestedCapabilities(request),
+		githubReport:   githubReport,
 	}
 }
 
@@ -70,9 +86,9 @@ func (s *KubernetesSession) ID() string {
 }
 
 // Addr returns the address (host:port) the session is running on
-// For Kubernetes sessions, this returns the Service DNS name with port
+// For Kubernetes sessions, this returns the routed host with the Service port
 func (s *KubernetesSession) Addr() string {
-	return fmt.Sprintf("%s:%d", s.ServiceDNS(), s.servicePort)
+	return fmt.Sprintf("%s:%d", s.Host(), s.servicePort)
 }
 
 // UserID returns the user ID that owns this session
@@ -112,9 +128,19 @@ func (s *KubernetesSession) TeamID() string {
 
 // Tags returns the session tags
 func (s *KubernetesSession) Tags() map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return s.request.Tags
 }
 
+// setTags replaces the session tags. The map is replaced rather than
+// modified so that maps returned by Tags are never written to.
+func (s *KubernetesSession) setTags(tags map[string]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.request.Tags = tags
+}
+
 // Status returns the current status of the session
 func (s *KubernetesSession) Status() string {
 	s.mutex.RLock()
@@ -247,7 +273,28 @@ func (s *KubernetesSession) SetLastMessageAt(t time.Time) {
 
 // ServiceDNS returns the Kubernetes Service DNS name for this session
 func (s *KubernetesSession) ServiceDNS() string {
-	return s.serviceName + "." + s.namespace + ".svc.cluster.local"
+	return serviceDNSName(s.serviceName, s.namespace, s.clusterDomain)
+}
+
+// Host returns the host the proxy uses to reach the session: the endpoint
+// address when routing by endpoints, otherwise the Service DNS name.
+func (s *KubernetesSession) Host() string {
+	if host := s.routeHostValue(); host != "" {
+		return host
+	}
+	return s.ServiceDNS()
+}
+
+func (s *KubernetesSession) routeHostValue() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.routeHost
+}
+
+func (s *KubernetesSession) setRouteHost(host string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.routeHost = host
 }
 
 // DeploymentName returns the Kubernetes Deployment name
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// acceleratorAnnotation records the accelerator request of a session so that
+// a recreated Pod gets the same accelerators after a proxy restart.
+const acceleratorAnnotation = "agentapi.proxy/accelerator"
+
+// anyTeamAllowance is the TeamID of the accelerator allowance that applies to
+// sessions without an allowance of their own.
+const anyTeamAllowance = "*"
+
+// extendedResourceQuantity parses the quantity of an extended resource, which
+// Kubernetes only accepts as a positive whole number.
+func extendedResourceQuantity(name, value string) (resource.Quantity, error) {
+	if !strings.Contains(name, "/") {
+		return resource.Quantity{}, fmt.Errorf("%q is not an extended resource name (e.g. nvidia.com/gpu)", name)
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid quantity %q of %s: %v", value, name, err)
+	}
+	if _, ok := quantity.AsInt64(); !ok || quantity.Sign() <= 0 {
+		return resource.Quantity{}, fmt.Errorf("quantity %q of %s must be a positive whole number", value, name)
+	}
+	return quantity, nil
+}
+
+// validateAcceleratorConfig rejects malformed kubernetes_session extended
+// resources and accelerator allowances.
+func validateAcceleratorConfig(k8sConfig *config.KubernetesSessionConfig) error {
+	for name, value := range k8sConfig.ExtendedResources {
+		if _, err := extendedResourceQuantity(name, value); err != nil {
+			return fmt.Errorf("kubernetes_session.extended_resources: %w", err)
+		}
+	}
+	for i, allowance := range k8sConfig.AcceleratorAllowances {
+		if allowance.TeamID == "" {
+			return fmt.Errorf("kubernetes_session.accelerator_allowances[%d]: team_id is required", i)
+		}
+		for name, value := range allowance.ExtendedResources {
+			if _, err := extendedResourceQuantity(name, value); err != nil {
+				return fmt.Errorf("kubernetes_session.accelerator_allowances[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// acceleratorAllowance returns the allowance of the team of a team-scoped
+// session, or the "*" allowance.
+func (m *KubernetesSessionManager) acceleratorAllowance(req *entities.RunServerRequest) *config.AcceleratorAllowance {
+	if m.k8sConfig == nil {
+		return nil
+	}
+	var fallback *config.AcceleratorAllowance
+	for i := range m.k8sConfig.AcceleratorAllowances {
+		allowance := &m.k8sConfig.AcceleratorAllowances[i]
+		if req.Scope == entities.ScopeTeam && req.TeamID != "" && allowance.TeamID == req.TeamID {
+			return allowance
+		}
+		if allowance.TeamID == anyTeamAllowance && fallback == nil {
+			fallback = allowance
+		}
+	}
+	return fallback
+}
+
+// checkAccelerator rejects malformed accelerator requests and requests beyond
+// the allowance of the team of the session.
+func (m *KubernetesSessionManager) checkAccelerator(req *entities.RunServerRequest) error {
+	accelerator := req.Accelerator
+	if accelerator.IsEmpty() {
+		return nil
+	}
+	for name, value := range accelerator.Resources {
+		if _, err := extendedResourceQuantity(name, value); err != nil {
+			return fmt.Errorf("%w: %v", entities.ErrInvalidAccelerator, err)
+		}
+	}
+	for key := range accelerator.NodeAffinity {
+		if key == "" {
+			return fmt.Errorf("%w: empty node label key", entities.ErrInvalidAccelerator)
+		}
+	}
+
+	allowance := m.acceleratorAllowance(req)
+	if allowance == nil {
+		return fmt.Errorf("%w: no accelerator allowance for this session", entities.ErrAcceleratorNotAllowed)
+	}
+	for name, value := range accelerator.Resources {
+		requested, _ := extendedResourceQuantity(name, value)
+		limit, ok := allowance.ExtendedResources[name]
+		if !ok {
+			return fmt.Errorf("%w: %s is not allowed", entities.ErrAcceleratorNotAllowed, name)
+		}
+		if allowed, err := resource.ParseQuantity(limit); err != nil || requested.Cmp(allowed) > 0 {
+			return fmt.Errorf("%w: %s %s exceeds the allowance of %s", entities.ErrAcceleratorNotAllowed, value, name, limit)
+		}
+	}
+	if name := accelerator.RuntimeClassName; name != "" && !slices.Contains(allowance.RuntimeClassNames, name) {
+		return fmt.Errorf("%w: runtime class %q is not allowed", entities.ErrAcceleratorNotAllowed, name)
+	}
+	for key, values := range accelerator.NodeAffinity {
+		allowed, ok := allowance.NodeLabels[key]
+		if !ok {
+			return fmt.Errorf("%w: node label %s is not allowed", entities.ErrAcceleratorNotAllowed, key)
+		}
+		if len(allowed) == 0 {
+			continue
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("%w: node label %s requires one of %s", entities.ErrAcceleratorNotAllowed, key, strings.Join(allowed, ", "))
+		}
+		for _, value := range values {
+			if !slices.Contains(allowed, value) {
+				return fmt.Errorf("%w: node label %s=%s is not allowed", entities.ErrAcceleratorNotAllowed, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// applyAccelerator adds the configured and requested extended resources to
+// the agent container and sets the RuntimeClass and node affinity of the Pod.
+// Requested node labels are required in addition to every configured
+// required node selector term.
+func (m *KubernetesSessionManager) applyAccelerator(req *entities.RunServerRequest, spec *corev1.PodSpec) {
+	var accelerator entities.AcceleratorParams
+	if req != nil && req.Accelerator != nil {
+		accelerator = *req.Accelerator
+	}
+
+	resources := make(map[string]string, len(m.k8sConfig.ExtendedResources)+len(accelerator.Resources))
+	for name, value := range m.k8sConfig.ExtendedResources {
+		resources[name] = value
+	}
+	for name, value := range accelerator.Resources {
+		resources[name] = value
+	}
+	if len(resources) > 0 {
+		for i := range spec.Containers {
+			container := &spec.Containers[i]
+			if container.Name != mainContainerName {
+				continue
+			}
+			if container.Resources.Limits == nil {
+				container.Resources.Limits = corev1.ResourceList{}
+			}
+			for name, value := range resources {
+				// Quantities were validated by checkAccelerator and
+				// validateAcceleratorConfig.
+				quantity, err := extendedResourceQuantity(name, value)
+				if err != nil {
+					continue
+				}
+				container.Resources.Limits[corev1.ResourceName(name)] = quantity
+			}
+		}
+	}
+
+	if accelerator.RuntimeClassName != "" {
+		spec.RuntimeClassName = &accelerator.RuntimeClassName
+	} else if m.k8sConfig.RuntimeClassName != "" {
+		runtimeClassName := m.k8sConfig.RuntimeClassName
+		spec.RuntimeClassName = &runtimeClassName
+	}
+
+	if len(accelerator.NodeAffinity) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(accelerator.NodeAffinity))
+	for key := range accelerator.NodeAffinity {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	requirements := make([]corev1.NodeSelectorRequirement, 0, len(keys))
+	for _, key := range keys {
+		values := accelerator.NodeAffinity[key]
+		if len(values) == 0 {
+			requirements = append(requirements, corev1.NodeSelectorRequirement{Key: key, Operator: corev1.NodeSelectorOpExists})
+			continue
+		}
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   append([]string(nil), values...),
+		})
+	}
+
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	required := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: requirements}},
+		}
+		return
+	}
+	// Terms are ORed, so the requirements are added to each of them.
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchExpressions = append(required.NodeSelectorTerms[i].MatchExpressions, requirements...)
+	}
+}
+
+// acceleratorAnnotationValue encodes the accelerator request of a session.
+func acceleratorAnnotationValue(accelerator *entities.AcceleratorParams) string {
+	if accelerator.IsEmpty() {
+		return ""
+	}
+	data, err := json.Marshal(accelerator)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// restoreAcceleratorFromService reads the accelerator request of a restored session.
+func restoreAcceleratorFromService(svc *corev1.Service) *entities.AcceleratorParams {
+	value := svc.Annotations[acceleratorAnnotation]
+	if value == "" {
+		return nil
+	}
+	var accelerator entities.AcceleratorParams
+	if err := json.Unmarshal([]byte(value), &accelerator); err != nil {
+		return nil
+	}
+	return &accelerator
+}
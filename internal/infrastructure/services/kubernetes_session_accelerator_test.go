@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateAcceleratorConfig(t *testing.T) {
+	valid := &config.KubernetesSessionConfig{
+		ExtendedResources: map[string]string{"nvidia.com/gpu": "1"},
+		AcceleratorAllowances: []config.AcceleratorAllowance{
+			{TeamID: "org/ml", ExtendedResources: map[string]string{"nvidia.com/gpu": "4"}},
+		},
+	}
+	if err := validateAcceleratorConfig(valid); err != nil {
+		t.Fatalf("validateAcceleratorConfig = %v", err)
+	}
+	for name, cfg := range map[string]*config.KubernetesSessionConfig{
+		"fractional":   {ExtendedResources: map[string]string{"nvidia.com/gpu": "500m"}},
+		"cpu":          {ExtendedResources: map[string]string{"cpu": "1"}},
+		"missing team": {AcceleratorAllowances: []config.AcceleratorAllowance{{}}},
+	} {
+		if err := validateAcceleratorConfig(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestCheckAccelerator(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.AcceleratorAllowances = []config.AcceleratorAllowance{
+		{
+			TeamID:            "org/ml",
+			ExtendedResources: map[string]string{"nvidia.com/gpu": "2"},
+			RuntimeClassNames: []string{"nvidia"},
+			NodeLabels:        map[string][]string{"gpu-type": {"a100", "l4"}, "gpu-node": nil},
+		},
+		{TeamID: "*", ExtendedResources: map[string]string{"nvidia.com/gpu": "1"}},
+	}
+	team := func(accelerator *entities.AcceleratorParams) *entities.RunServerRequest {
+		return &entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "org/ml", Accelerator: accelerator}
+	}
+
+	tests := []struct {
+		name string
+		req  *entities.RunServerRequest
+		want error
+	}{
+		{"no accelerator", &entities.RunServerRequest{}, nil},
+		{"team within allowance", team(&entities.AcceleratorParams{
+			Resources:        map[string]string{"nvidia.com/gpu": "2"},
+			RuntimeClassName: "nvidia",
+			NodeAffinity:     map[string][]string{"gpu-type": {"a100"}, "gpu-node": nil},
+		}), nil},
+		{"team above allowance", team(&entities.AcceleratorParams{Resources: map[string]string{"nvidia.com/gpu": "3"}}), entities.ErrAcceleratorNotAllowed},
+		{"unknown resource", team(&entities.AcceleratorParams{Resources: map[string]string{"amd.com/gpu": "1"}}), entities.ErrAcceleratorNotAllowed},
+		{"unknown runtime class", team(&entities.AcceleratorParams{RuntimeClassName: "kata"}), entities.ErrAcceleratorNotAllowed},
+		{"node label value", team(&entities.AcceleratorParams{NodeAffinity: map[string][]string{"gpu-type": {"h100"}}}), entities.ErrAcceleratorNotAllowed},
+		{"node label without value", team(&entities.AcceleratorParams{NodeAffinity: map[string][]string{"gpu-type": nil}}), entities.ErrAcceleratorNotAllowed},
+		{"fractional", team(&entities.AcceleratorParams{Resources: map[string]string{"nvidia.com/gpu": "0.5"}}), entities.ErrInvalidAccelerator},
+		{"user within wildcard", &entities.RunServerRequest{Accelerator: &entities.AcceleratorParams{Resources: map[string]string{"nvidia.com/gpu": "1"}}}, nil},
+		{"user above wildcard", &entities.RunServerRequest{Accelerator: &entities.AcceleratorParams{Resources: map[string]string{"nvidia.com/gpu": "2"}}}, entities.ErrAcceleratorNotAllowed},
+	}
+	for _, tt := range tests {
+		err := manager.checkAccelerator(tt.req)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkAccelerator = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	manager.k8sConfig.AcceleratorAllowances = nil
+	if err := manager.checkAccelerator(team(&entities.AcceleratorParams{RuntimeClassName: "nvidia"})); !errors.Is(err, entities.ErrAcceleratorNotAllowed) {
+		t.Errorf("without allowances: checkAccelerator = %v", err)
+	}
+}
+
+func TestBuildDeploymentAppliesAccelerator(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	manager.k8sConfig.ExtendedResources = map[string]string{"example.com/fpga": "1"}
+	manager.k8sConfig.RuntimeClassName = "default-runtime"
+	manager.k8sConfig.Affinity = map[string]interface{}{
+		"nodeAffinity": map[string]interface{}{
+			"requiredDuringSchedulingIgnoredDuringExecution": map[string]interface{}{
+				"nodeSelectorTerms": []interface{}{
+					map[string]interface{}{"matchExpressions": []interface{}{
+						map[string]interface{}{"key": "pool", "operator": "In", "values": []interface{}{"agents"}},
+					}},
+				},
+			},
+		},
+	}
+	session := newWorkloadTestSession()
+	req := &entities.RunServerRequest{
+		UserID: "test-user",
+		Accelerator: &entities.AcceleratorParams{
+			Resources:        map[string]string{"nvidia.com/gpu": "2"},
+			RuntimeClassName: "nvidia",
+			NodeAffinity:     map[string][]string{"gpu-type": {"a100"}},
+		},
+	}
+
+	deployment, err := manager.buildDeployment(context.Background(), session, req)
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	var main *corev1.Container
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == mainContainerName {
+			main = &spec.Containers[i]
+		}
+	}
+	if main == nil {
+		t.Fatal("agent container not found")
+	}
+	for name, want := range map[corev1.ResourceName]int64{"nvidia.com/gpu": 2, "example.com/fpga": 1} {
+		if got := main.Resources.Limits[name]; got.Value() != want {
+			t.Errorf("limit %s = %s, want %d", name, got.String(), want)
+		}
+	}
+	if main.Resources.Limits.Cpu().IsZero() {
+		t.Error("cpu limit dropped")
+	}
+	if spec.RuntimeClassName == nil || *spec.RuntimeClassName != "nvidia" {
+		t.Errorf("runtimeClassName = %v, want nvidia", spec.RuntimeClassName)
+	}
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) != 2 || terms[0].MatchExpressions[1].Key != "gpu-type" {
+		t.Fatalf("node selector terms = %+v, want the pool and gpu-type requirements", terms)
+	}
+
+	// Without a request, the configured defaults apply.
+	deployment, err = manager.buildDeployment(context.Background(), session, &entities.RunServerRequest{UserID: "test-user"})
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	if got := deployment.Spec.Template.Spec.RuntimeClassName; got == nil || *got != "default-runtime" {
+		t.Errorf("default runtimeClassName = %v, want default-runtime", got)
+	}
+}
+
+func TestAcceleratorAnnotationRoundTrip(t *testing.T) {
+	accelerator := &entities.AcceleratorParams{Resources: map[string]string{"nvidia.com/gpu": "1"}}
+	svc := &corev1.Service{}
+	svc.Annotations = map[string]string{acceleratorAnnotation: acceleratorAnnotationValue(accelerator)}
+	restored := restoreAcceleratorFromService(svc)
+	if restored == nil || restored.Resources["nvidia.com/gpu"] != "1" {
+		t.Fatalf("restored accelerator = %+v", restored)
+	}
+	if acceleratorAnnotationValue(&entities.AcceleratorParams{}) != "" {
+		t.Error("empty accelerator annotated")
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// lastActivityAtAnnotation records the last time a request was proxied to
+	// the session or the agent produced a message. Read by the idle reaper.
+	lastActivityAtAnnotation = "agentapi.proxy/last-activity-at"
+
+	// activityPatchInterval bounds how often the last-activity-at annotation is
+	// written for one session, so busy sessions don't flood the API server.
+	activityPatchInterval = time.Minute
+)
+
+// RecordActivity marks the session as active now. The timestamp is persisted
+// on the session Service asynchronously and at most once per
+// activityPatchInterval.
+func (m *KubernetesSessionManager) RecordActivity(sessionID string) {
+	m.mutex.RLock()
+	session, exists := m.sessions[sessionID]
+	m.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	now := time.Now()
+	m.activityMu.Lock()
+	if m.activityPatchedAt == nil {
+		m.activityPatchedAt = make(map[string]time.Time)
+	}
+	if last, ok := m.activityPatchedAt[sessionID]; ok && now.Sub(last) < activityPatchInterval {
+		m.activityMu.Unlock()
+		return
+	}
+	m.activityPatchedAt[sessionID] = now
+	m.activityMu.Unlock()
+
+	namespace, svcName := session.Namespace(), session.ServiceName()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := m.patchServiceAnnotations(ctx, namespace, svcName, map[string]interface{}{
+			lastActivityAtAnnotation: now.UTC().Format(time.RFC3339),
+		}); err != nil {
+			log.Printf("[K8S_SESSION] Failed to record activity for session %s: %v", sessionID, err)
+		}
+	}()
+}
+
+// forgetActivity drops the activity throttle state of a removed session.
+func (m *KubernetesSessionManager) forgetActivity(sessionID string) {
+	m.activityMu.Lock()
+	delete(m.activityPatchedAt, sessionID)
+	m.activityMu.Unlock()
+}
+
+// patchServiceAnnotations merges the given annotations into a session Service.
+// A nil value removes the annotation.
+func (m *KubernetesSessionManager) patchServiceAnnotations(ctx context.Context, namespace, svcName string, annotations map[string]interface{}) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	_, err = m.client.CoreV1().Services(namespace).Patch(
+		ctx, svcName, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
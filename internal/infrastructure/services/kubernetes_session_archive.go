@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+)
+
+const (
+	// sessionHistoryPath is the agent output history written by the ACP bridge.
+	sessionHistoryPath = "/opt/acp-posts/history.jsonl"
+	// sessionWorkdir is the working directory of the agent in session Pods.
+	sessionWorkdir = "/home/agentapi/workdir"
+	// maxArchivedLogBytes caps the pod log and history kept per session.
+	maxArchivedLogBytes = 16 << 20
+	// maxArchivedArtifactBytes caps the artifact tarball kept per session.
+	maxArchivedArtifactBytes = 64 << 20
+	// sessionArchiveTimeout bounds the time spent collecting and uploading.
+	sessionArchiveTimeout = 2 * time.Minute
+)
+
+// SetSessionArchive enables archiving the pod logs, history.jsonl and the
+// workdir files matching artifacts of each session before it is deleted.
+// artifacts are shell glob patterns relative to the workdir.
+func (m *KubernetesSessionManager) SetSessionArchive(archive *sessionarchive.Archive, artifacts []string) {
+	m.sessionArchive = archive
+	m.sessionArchiveArtifacts = artifacts
+}
+
+// archiveSessionBeforeDelete archives the session while its Pod still
+// exists. Failures are logged and never prevent the deletion.
+func (m *KubernetesSessionManager) archiveSessionBeforeDelete(session *KubernetesSession) {
+	if m.sessionArchive == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionArchiveTimeout)
+	defer cancel()
+	logs := func(ctx context.Context) (io.ReadCloser, error) {
+		pod, err := m.sessionPod(ctx, session)
+		if err != nil {
+			return nil, err
+		}
+		return m.client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: mainContainerName}).Stream(ctx)
+	}
+	entries := collectSessionArchive(ctx, session, m.execInSession, logs, m.sessionArchiveArtifacts)
+	manifest := sessionarchive.NewManifest(session, time.Now())
+	if err := m.sessionArchive.Save(ctx, manifest, entries); err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to archive session %s: %v", session.ID(), err)
+		return
+	}
+	log.Printf("[SESSION_ARCHIVE] Archived %d file(s) of session %s under %s", len(manifest.Files), session.ID(), manifest.Prefix())
+}
+
+// collectSessionArchive reads the files to archive from the session Pod.
+// Files that cannot be read, e.g. because a Job Pod has already exited, are
+// left out.
+func collectSessionArchive(ctx context.Context, session entities.Session, exec sessionExecFunc, logs func(context.Context) (io.ReadCloser, error), artifacts []string) []sessionarchive.Entry {
+	var entries []sessionarchive.Entry
+
+	if stream, err := logs(ctx); err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to read pod logs of session %s: %v", session.ID(), err)
+	} else {
+		out := &cappedBuffer{limit: maxArchivedLogBytes}
+		_, err := io.Copy(out, stream)
+		_ = stream.Close()
+		if err != nil {
+			log.Printf("[SESSION_ARCHIVE] Pod logs of session %s are incomplete: %v", session.ID(), err)
+		}
+		entries = append(entries, archiveEntry("pod.log", "text/plain; charset=utf-8", out))
+	}
+
+	if out, ok := execForArchive(ctx, session, exec, maxArchivedLogBytes, "cat "+sessionHistoryPath); ok {
+		entries = append(entries, archiveEntry("history.jsonl", "application/x-ndjson", out))
+	}
+
+	if len(artifacts) > 0 {
+		// Patterns are expanded by the shell. Nothing is archived when none
+		// matches; tar skips the ones that match nothing otherwise.
+		patterns := strings.Join(artifacts, " ")
+		script := fmt.Sprintf("cd %s && [ -n \"$(ls -d %s 2>/dev/null)\" ] || exit 1; tar czf - %s 2>/dev/null; exit 0",
+			sessionWorkdir, patterns, patterns)
+		if out, ok := execForArchive(ctx, session, exec, maxArchivedArtifactBytes, script); ok {
+			entries = append(entries, archiveEntry("artifacts.tar.gz", "application/gzip", out))
+		}
+	}
+	return entries
+}
+
+// execForArchive runs script in the session Pod and returns its output if it
+// succeeded and produced any.
+func execForArchive(ctx context.Context, session entities.Session, exec sessionExecFunc, limit int, script string) (*cappedBuffer, bool) {
+	out := &cappedBuffer{limit: limit}
+	exitCode, err := exec(ctx, session.ID(), entities.SessionExecRequest{
+		Command: []string{"sh", "-c", script},
+	}, ExecStreams{Stdout: out, Stderr: io.Discard})
+	if err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to read files of session %s: %v", session.ID(), err)
+		return nil, false
+	}
+	return out, exitCode == 0 && out.Len() > 0
+}
+
+func archiveEntry(name, contentType string, out *cappedBuffer) sessionarchive.Entry {
+	return sessionarchive.Entry{
+		File: sessionarchive.File{Name: name, ContentType: contentType, Truncated: out.Len() >= out.limit},
+		Data: out.Bytes(),
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestCollectSessionArchive(t *testing.T) {
+	session := newWorkloadTestSession()
+	exec := func(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error) {
+		script := req.Command[2]
+		switch {
+		case strings.HasPrefix(script, "cat "):
+			_, _ = fmt.Fprint(streams.Stdout, `{"type":"post"}`+"\n")
+			return 0, nil
+		case strings.Contains(script, "tar czf - coverage.out dist/*"):
+			_, _ = fmt.Fprint(streams.Stdout, "tarball")
+			return 0, nil
+		}
+		return 1, nil
+	}
+	logs := func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("agent started\n")), nil
+	}
+
+	entries := collectSessionArchive(context.Background(), session, exec, logs, []string{"coverage.out", "dist/*"})
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, want := range []string{"pod.log", "history.jsonl", "artifacts.tar.gz"} {
+		if entries[i].Name != want || len(entries[i].Data) == 0 || entries[i].Truncated {
+			t.Errorf("entries[%d] = %s (%d bytes), want %s", i, entries[i].Name, len(entries[i].Data), want)
+		}
+	}
+}
+
+func TestCollectSessionArchiveSkipsUnreadableFiles(t *testing.T) {
+	session := newWorkloadTestSession()
+	exec := func(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error) {
+		return 0, errors.New("pod has exited")
+	}
+	logs := func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("job done\n")), nil
+	}
+
+	entries := collectSessionArchive(context.Background(), session, exec, logs, []string{"out/"})
+	if len(entries) != 1 || entries[0].Name != "pod.log" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	// BrowserVNCPort is the noVNC port of the browser sidecar. The proxy
+	// reaches it through the session Service.
+	BrowserVNCPort = 7900
+
+	// browserWebDriverPort is the WebDriver endpoint of the browser sidecar.
+	// It is only reachable from inside the Pod.
+	browserWebDriverPort = 4444
+
+	// browserDownloadsDir is where downloads land, relative to the workdir.
+	browserDownloadsDir = "downloads"
+
+	// browserCapabilityLabel marks session Services whose Pod runs the browser sidecar.
+	browserCapabilityLabel = "agentapi.proxy/capability-browser"
+
+	// seleniumUID is the UID of the seluser account in the Selenium images.
+	seleniumUID = 1200
+)
+
+func browserEnabled(req *entities.RunServerRequest) bool {
+	return req != nil && req.Browser != nil && req.Browser.Enabled
+}
+
+// restoreBrowserFromService rebuilds the browser params of a restored session.
+func restoreBrowserFromService(svc *corev1.Service) *entities.BrowserParams {
+	if svc.Labels[browserCapabilityLabel] != "true" {
+		return nil
+	}
+	return &entities.BrowserParams{Enabled: true}
+}
+
+// BrowserEnabled reports whether the session Pod runs the browser sidecar.
+func (s *KubernetesSession) BrowserEnabled() bool {
+	return browserEnabled(s.Request())
+}
+
+// applyBrowserSidecar returns the headless browser sidecar and its volumes when
+// the session requests one, and points the agent container at it. The browser
+// display is served by noVNC without a password: access control is enforced
+// by the proxy, which is the only intended client of the VNC Service port.
+// Downloads are written to the downloads directory of the shared workdir.
+func (m *KubernetesSessionManager) applyBrowserSidecar(req *entities.RunServerRequest, container *corev1.Container) (*corev1.Container, []corev1.Volume) {
+	if !browserEnabled(req) {
+		return nil, nil
+	}
+
+	image := m.k8sConfig.BrowserImage
+	if image == "" {
+		image = config.DefaultBrowserImage
+	}
+	falseVal := false
+	uid := int64(seleniumUID)
+	shmSize := resource.MustParse("1Gi")
+
+	sidecar := corev1.Container{
+		Name:            "browser",
+		Image:           image,
+		ImagePullPolicy: corev1.PullPolicy(m.k8sConfig.ImagePullPolicy),
+		Env: []corev1.EnvVar{
+			{Name: "SE_VNC_NO_PASSWORD", Value: "true"},
+			{Name: "SE_NO_VNC_PORT", Value: fmt.Sprintf("%d", BrowserVNCPort)},
+			{Name: "SE_SESSION_REQUEST_TIMEOUT", Value: "300"},
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "browser-vnc", ContainerPort: BrowserVNCPort, Protocol: corev1.ProtocolTCP},
+			{Name: "webdriver", ContainerPort: browserWebDriverPort, Protocol: corev1.ProtocolTCP},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/status", Port: intstr.FromInt(browserWebDriverPort)},
+			},
+			PeriodSeconds: 10,
+		},
+		SecurityContext: &corev1.SecurityContext{
+			// The Selenium images expect to run as seluser. Files it writes to
+			// the workdir stay group-writable through the Pod fsGroup.
+			RunAsUser:                &uid,
+			AllowPrivilegeEscalation: &falseVal,
+		},
+		Resources: buildResourceRequirements(
+			defaultIfEmpty(m.k8sConfig.BrowserCPURequest, "250m"),
+			defaultIfEmpty(m.k8sConfig.BrowserCPULimit, "2"),
+			defaultIfEmpty(m.k8sConfig.BrowserMemoryRequest, "512Mi"),
+			defaultIfEmpty(m.k8sConfig.BrowserMemoryLimit, "2Gi"),
+		),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "workdir", MountPath: "/home/seluser/Downloads", SubPath: browserDownloadsDir},
+			{Name: "browser-shm", MountPath: "/dev/shm"},
+		},
+	}
+
+	container.Env = append(container.Env,
+		// Picked up by Playwright and Selenium clients to drive the sidecar browser.
+		corev1.EnvVar{Name: "SELENIUM_REMOTE_URL", Value: fmt.Sprintf("http://127.0.0.1:%d", browserWebDriverPort)},
+		corev1.EnvVar{Name: "AGENTAPI_BROWSER_DOWNLOADS_DIR", Value: "/home/agentapi/workdir/" + browserDownloadsDir},
+	)
+
+	volumes := []corev1.Volume{
+		{
+			// Chrome needs more shared memory than the container default.
+			Name: "browser-shm",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &shmSize},
+			},
+		},
+	}
+	return &sidecar, volumes
+}
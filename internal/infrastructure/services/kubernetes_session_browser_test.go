@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestCreateSessionWorkloadWithBrowserSidecar(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	session := newWorkloadTestSession()
+	session.Request().Browser = &entities.BrowserParams{Enabled: true}
+
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	deployment, err := manager.client.AppsV1().Deployments("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected deployment to be created: %v", err)
+	}
+
+	var agent, browser *corev1.Container
+	for i := range deployment.Spec.Template.Spec.Containers {
+		switch c := &deployment.Spec.Template.Spec.Containers[i]; c.Name {
+		case "browser":
+			browser = c
+		case "agentapi":
+			agent = c
+		}
+	}
+	if browser == nil {
+		t.Fatal("Expected browser sidecar container")
+	}
+	if browser.Image != config.DefaultBrowserImage {
+		t.Errorf("Expected default browser image, got %q", browser.Image)
+	}
+	mountsDownloads := false
+	for _, vm := range browser.VolumeMounts {
+		if vm.Name == "workdir" && vm.SubPath == browserDownloadsDir {
+			mountsDownloads = true
+		}
+	}
+	if !mountsDownloads {
+		t.Errorf("Expected browser downloads to be mapped into the workdir, got %+v", browser.VolumeMounts)
+	}
+
+	if agent == nil {
+		t.Fatal("Expected agentapi container")
+	}
+	hasRemoteURL := false
+	for _, env := range agent.Env {
+		if env.Name == "SELENIUM_REMOTE_URL" {
+			hasRemoteURL = true
+		}
+	}
+	if !hasRemoteURL {
+		t.Error("Expected agent container to be pointed at the browser sidecar")
+	}
+
+	hasPort := false
+	for _, p := range manager.buildServicePorts(session) {
+		if p.Name == "browser-vnc" && p.Port == BrowserVNCPort {
+			hasPort = true
+		}
+	}
+	if !hasPort {
+		t.Error("Expected noVNC port on the session Service")
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: manager.buildLabels(session)}}
+	if restored := restoreBrowserFromService(svc); restored == nil || !restored.Enabled {
+		t.Error("Expected browser params to be restored from Service labels")
+	}
+}
+
+func TestCreateSessionWorkloadWithoutBrowser(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	pod, err := manager.client.CoreV1().Pods("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected pod to be created: %v", err)
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == "browser" {
+			t.Fatal("Browser sidecar must be opt-in")
+		}
+	}
+	if _, ok := manager.buildLabels(session)[browserCapabilityLabel]; ok {
+		t.Error("Browser capability label must only be set for browser sessions")
+	}
+}
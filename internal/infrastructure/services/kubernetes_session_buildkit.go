@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// buildKitPort is the loopback TCP port the BuildKit daemon listens on.
+const buildKitPort = 1234
+
+// buildKitUID is the user the rootless BuildKit image runs as.
+const buildKitUID = 1000
+
+// imageCacheDir is where DinD and BuildKit keep their image cache when
+// kubernetes_session.docker_image_cache_on_pvc is set. It lives on the
+// session PVC, inside the workdir mount every Docker sidecar already has.
+const imageCacheDir = "/home/agentapi/workdir/.image-cache"
+
+// buildKitRegistryConfigPath is where a registry Secret is mounted in the
+// main container of BuildKit sessions. The provisioner merges it into the
+// docker config read by buildctl.
+const buildKitRegistryConfigPath = "/etc/agentapi/docker-registry/config.json"
+
+// imageCacheOnPVC reports whether Docker sidecars keep their image cache on
+// the session PVC.
+func (m *KubernetesSessionManager) imageCacheOnPVC() bool {
+	return m.k8sConfig.DockerImageCacheOnPVC && m.isPVCEnabled()
+}
+
+// buildKitConfig renders buildkitd.toml for the insecure registries of docker.
+func buildKitConfig(docker *entities.DockerParams) string {
+	var b strings.Builder
+	for _, reg := range docker.Registries {
+		if reg.Insecure && reg.Server != "" {
+			fmt.Fprintf(&b, "[registry.%s]\n  http = true\n  insecure = true\n", strconv.Quote(reg.Server))
+		}
+	}
+	return b.String()
+}
+
+// applyBuildKitSidecar returns the rootless BuildKit sidecar and its volumes
+// when the session requests docker.mode=buildkit, and points the main
+// container at it through BUILDKIT_HOST. Unlike DinD the sidecar is not
+// privileged; rootlesskit only needs seccomp and AppArmor to be unconfined.
+func (m *KubernetesSessionManager) applyBuildKitSidecar(req *entities.RunServerRequest, container *corev1.Container) (*corev1.Container, []corev1.Volume) {
+	if !req.Docker.BuildKit() {
+		return nil, nil
+	}
+
+	image := m.k8sConfig.BuildKitImage
+	if image == "" {
+		image = config.DefaultBuildKitImage
+	}
+	uid := int64(buildKitUID)
+
+	stateDir := "/home/user/.local/share/buildkit"
+	if m.imageCacheOnPVC() {
+		stateDir = imageCacheDir + "/buildkit"
+	}
+	// The config is passed through the environment so registry names never
+	// end up in the shell script.
+	script := `mkdir -p "$HOME/.config/buildkit" && printf '%s' "$BUILDKITD_TOML" > "$HOME/.config/buildkit/buildkitd.toml" && ` +
+		`exec rootlesskit buildkitd --config "$HOME/.config/buildkit/buildkitd.toml" --oci-worker-no-process-sandbox ` +
+		fmt.Sprintf("--addr tcp://127.0.0.1:%d --root %s", buildKitPort, stateDir)
+
+	sidecar := corev1.Container{
+		Name:            "buildkitd",
+		Image:           image,
+		ImagePullPolicy: corev1.PullPolicy(m.k8sConfig.ImagePullPolicy),
+		Command:         []string{"sh", "-c", script},
+		Env: []corev1.EnvVar{
+			{Name: "BUILDKITD_TOML", Value: buildKitConfig(req.Docker)},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:       &uid,
+			RunAsGroup:      &uid,
+			SeccompProfile:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+			AppArmorProfile: &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeUnconfined},
+		},
+		Resources: buildResourceRequirements(
+			defaultIfEmpty(m.k8sConfig.BuildKitCPURequest, "2"),
+			defaultIfEmpty(m.k8sConfig.BuildKitCPULimit, "2"),
+			defaultIfEmpty(m.k8sConfig.BuildKitMemoryRequest, "2Gi"),
+			defaultIfEmpty(m.k8sConfig.BuildKitMemoryLimit, "2Gi"),
+		),
+		// The workdir is shared so that build contexts can also be sent by
+		// path, and it holds the image cache when that is kept on the PVC.
+		// Files BuildKit creates there stay group-writable through the Pod fsGroup.
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "workdir", MountPath: "/home/agentapi/workdir"},
+		},
+	}
+
+	var volumes []corev1.Volume
+	if !m.imageCacheOnPVC() {
+		sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+			Name:      "buildkit-state",
+			MountPath: stateDir,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name:         "buildkit-state",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+
+	// BuildKit takes registry credentials from the client, so a registry
+	// Secret is mounted into the main container instead of the sidecar.
+	for _, reg := range req.Docker.Registries {
+		if reg.SecretName == "" {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "docker-registry-config",
+			MountPath: buildKitRegistryConfigPath,
+			SubPath:   "config.json",
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "docker-registry-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: reg.SecretName,
+					Optional:   boolPtr(true),
+				},
+			},
+		})
+		break
+	}
+
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "BUILDKIT_HOST",
+		Value: fmt.Sprintf("tcp://127.0.0.1:%d", buildKitPort),
+	})
+
+	return &sidecar, volumes
+}
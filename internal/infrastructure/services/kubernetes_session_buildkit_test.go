@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func hasVolume(deployment *appsv1.Deployment, name string) bool {
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func createTestWorkload(t *testing.T, manager *KubernetesSessionManager, docker *entities.DockerParams) *appsv1.Deployment {
+	t.Helper()
+	session := newWorkloadTestSession()
+	session.Request().Docker = docker
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	deployment, err := manager.client.AppsV1().Deployments("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected deployment to be created: %v", err)
+	}
+	return deployment
+}
+
+func TestCreateSessionWorkloadWithBuildKitSidecar(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	deployment := createTestWorkload(t, manager, &entities.DockerParams{
+		Enabled: true,
+		Mode:    entities.DockerModeBuildKit,
+		Registries: []entities.DockerRegistry{
+			{Server: "registry.internal:5000", Insecure: true},
+			{Server: "ghcr.io", SecretName: "ghcr-auth"},
+		},
+	})
+
+	if findContainerByName(deployment.Spec.Template.Spec.Containers, "docker-dind") != nil {
+		t.Error("BuildKit mode must not add the privileged DinD sidecar")
+	}
+	buildkit := findContainerByName(deployment.Spec.Template.Spec.Containers, "buildkitd")
+	if buildkit == nil {
+		t.Fatal("Expected buildkitd sidecar container")
+	}
+	if buildkit.Image != config.DefaultBuildKitImage {
+		t.Errorf("Expected default BuildKit image, got %q", buildkit.Image)
+	}
+	sc := buildkit.SecurityContext
+	if sc == nil || sc.Privileged != nil || sc.RunAsUser == nil || *sc.RunAsUser != buildKitUID {
+		t.Errorf("Expected an unprivileged rootless security context, got %+v", sc)
+	}
+	if !strings.Contains(buildkit.Command[2], "--root /home/user/.local/share/buildkit") {
+		t.Errorf("Expected EmptyDir state without the PVC cache, got %q", buildkit.Command[2])
+	}
+	if !hasVolume(deployment, "buildkit-state") {
+		t.Error("Expected buildkit-state volume")
+	}
+	if toml := buildkit.Env[0].Value; !strings.Contains(toml, `[registry."registry.internal:5000"]`) || !strings.Contains(toml, "http = true") {
+		t.Errorf("Expected insecure registry in buildkitd.toml, got %q", toml)
+	}
+
+	main := findContainerByName(deployment.Spec.Template.Spec.Containers, "agentapi")
+	if main == nil {
+		t.Fatal("Expected agentapi container")
+	}
+	hasHost := false
+	for _, env := range main.Env {
+		if env.Name == "BUILDKIT_HOST" && env.Value == "tcp://127.0.0.1:1234" {
+			hasHost = true
+		}
+	}
+	if !hasHost {
+		t.Error("Expected BUILDKIT_HOST in the main container")
+	}
+	mountsSecret := false
+	for _, vm := range main.VolumeMounts {
+		if vm.Name == "docker-registry-config" && vm.MountPath == buildKitRegistryConfigPath {
+			mountsSecret = true
+		}
+	}
+	if !mountsSecret {
+		t.Error("Expected the registry Secret to be mounted in the main container")
+	}
+}
+
+func TestDockerImageCacheOnPVC(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	manager.k8sConfig.DockerImageCacheOnPVC = true
+
+	deployment := createTestWorkload(t, manager, &entities.DockerParams{Enabled: true})
+	dind := findContainerByName(deployment.Spec.Template.Spec.Containers, "docker-dind")
+	if dind == nil {
+		t.Fatal("Expected docker-dind sidecar container")
+	}
+	wantArg := "--data-root=" + imageCacheDir + "/docker"
+	hasArg := false
+	for _, arg := range dind.Args {
+		if arg == wantArg {
+			hasArg = true
+		}
+	}
+	if !hasArg {
+		t.Errorf("Expected %s in DinD args, got %v", wantArg, dind.Args)
+	}
+	if hasVolume(deployment, "docker-storage") {
+		t.Error("Expected no docker-storage EmptyDir when the cache is on the PVC")
+	}
+
+	// Without a PVC the cache setting has no effect.
+	// Sessions without a PVC run as bare Pods, so inspect the built spec.
+	noPVC := newWorkloadTestManager(t, false)
+	noPVC.k8sConfig.DockerImageCacheOnPVC = true
+	session := newWorkloadTestSession()
+	session.Request().Docker = &entities.DockerParams{Enabled: true}
+	deployment, err := noPVC.buildDeployment(context.Background(), session, session.Request())
+	if err != nil {
+		t.Fatalf("Failed to build workload: %v", err)
+	}
+	if !hasVolume(deployment, "docker-storage") {
+		t.Error("Expected docker-storage EmptyDir when PVCs are disabled")
+	}
+}
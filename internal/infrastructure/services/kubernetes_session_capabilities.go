@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// capabilitiesAnnotation records the risky capabilities granted to a session
+// (comma-separated, see entities.GatedCapabilities).
+const capabilitiesAnnotation = "agentapi.proxy/capabilities"
+
+// capabilityPolicySettingsName is the settings entry whose capability policy
+// governs req: the team settings for team-scoped sessions, the base settings
+// otherwise.
+func capabilityPolicySettingsName(req *entities.RunServerRequest) string {
+	if req.Scope == entities.ScopeTeam && req.TeamID != "" {
+		return req.TeamID
+	}
+	return "base"
+}
+
+// SetAuditRepository configures where denied capability requests are recorded
+// as policy violations.
+func (m *KubernetesSessionManager) SetAuditRepository(repo portrepos.AuditRepository) {
+	m.auditRepo = repo
+}
+
+// CapabilityObserver is told whether the risky capabilities requested by a
+// session were granted, and why.
+type CapabilityObserver func(sessionID string, granted bool, capabilities []entities.SessionCapability, reason string)
+
+// SetCapabilityObserver configures the observer of capability policy
+// decisions, e.g. to publish them as approval events.
+func (m *KubernetesSessionManager) SetCapabilityObserver(observer CapabilityObserver) {
+	m.capabilityObserver = observer
+}
+
+// checkCapabilityPolicy rejects requests for risky capabilities that a team
+// admin has not approved. It only runs when
+// kubernetes_session.require_capability_approval is set; every decision is
+// written to the audit log.
+func (m *KubernetesSessionManager) checkCapabilityPolicy(ctx context.Context, id string, req *entities.RunServerRequest) error {
+	requested := entities.RequestedCapabilities(req)
+	if len(requested) == 0 {
+		return nil
+	}
+	if m.k8sConfig == nil || !m.k8sConfig.RequireCapabilityApproval {
+		m.auditCapabilities(id, req, "granted", requested, "approval not required")
+		return nil
+	}
+
+	settingsName := capabilityPolicySettingsName(req)
+	policy := m.capabilityPolicy(ctx, settingsName)
+	if denied := policy.Denied(requested); len(denied) > 0 {
+		m.auditCapabilities(id, req, "denied", denied, "not approved in "+settingsName+" settings")
+		m.recordPolicyViolation(ctx, id, req, denied, settingsName)
+		return fmt.Errorf("%w: %s not approved in %q settings", entities.ErrCapabilityNotAllowed, entities.JoinCapabilities(denied), settingsName)
+	}
+	m.auditCapabilities(id, req, "granted", requested, "approved in "+settingsName+" settings")
+	return nil
+}
+
+// capabilityPolicy returns the capability policy of the named settings, or
+// nil (which approves nothing) when it cannot be read.
+func (m *KubernetesSessionManager) capabilityPolicy(ctx context.Context, settingsName string) *entities.CapabilityPolicy {
+	if m.settingsRepo == nil {
+		return nil
+	}
+	settings, err := m.settingsRepo.FindByName(ctx, settingsName)
+	if err != nil {
+		log.Printf("[K8S_SESSION] Capability policy for %q unavailable, denying risky capabilities: %v", settingsName, err)
+		return nil
+	}
+	return settings.CapabilityPolicy()
+}
+
+// grantExecCapability records CapabilityExec on a new session when exec is
+// approved for it: always when capability approval is not required,
+// otherwise when the capability policy of its team (or the base settings)
+// allows it.
+func (m *KubernetesSessionManager) grantExecCapability(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest) {
+	if m.k8sConfig != nil && m.k8sConfig.RequireCapabilityApproval {
+		if !m.capabilityPolicy(ctx, capabilityPolicySettingsName(req)).Allows(entities.CapabilityExec) {
+			return
+		}
+	}
+	session.SetCapabilities(append(session.Capabilities(), entities.CapabilityExec))
+}
+
+// checkExecCapability rejects execs into sessions that were not granted
+// CapabilityExec. Sessions are only gated when
+// kubernetes_session.require_capability_approval is set.
+func (m *KubernetesSessionManager) checkExecCapability(ks *KubernetesSession) error {
+	if m.k8sConfig == nil || !m.k8sConfig.RequireCapabilityApproval {
+		return nil
+	}
+	if slices.Contains(ks.Capabilities(), entities.CapabilityExec) {
+		return nil
+	}
+	req := ks.Request()
+	settingsName := "base"
+	if req != nil {
+		settingsName = capabilityPolicySettingsName(req)
+	}
+	return fmt.Errorf("%w: exec not approved in %q settings", entities.ErrCapabilityNotAllowed, settingsName)
+}
+
+func (m *KubernetesSessionManager) auditCapabilities(id string, req *entities.RunServerRequest, decision string, caps []entities.SessionCapability, reason string) {
+	log.Printf("[AUDIT] session_capabilities session=%s user=%s scope=%s team=%s decision=%s capabilities=%q reason=%q",
+		id, req.UserID, req.Scope, req.TeamID, decision, entities.JoinCapabilities(caps), reason)
+	if m.capabilityObserver != nil {
+		m.capabilityObserver(id, decision == "granted", caps, reason)
+	}
+}
+
+// recordPolicyViolation writes a denied capability request to the audit log.
+func (m *KubernetesSessionManager) recordPolicyViolation(ctx context.Context, id string, req *entities.RunServerRequest, denied []entities.SessionCapability, settingsName string) {
+	if m.auditRepo == nil {
+		return
+	}
+	err := m.auditRepo.RecordAuditEvent(ctx, entities.AuditEvent{
+		Timestamp: time.Now().UTC(),
+		Category:  entities.AuditCategoryPolicyViolation,
+		Action:    "capability_denied",
+		Actor:     req.UserID,
+		Resource:  "session/" + id,
+		Outcome:   entities.AuditOutcomeDenied,
+		Detail:    entities.JoinCapabilities(denied) + " not approved in " + settingsName + " settings",
+	})
+	if err != nil {
+		log.Printf("[AUDIT] Failed to record capability policy violation for session %s: %v", id, err)
+	}
+}
+
+// restoreCapabilitiesFromService reads the granted capabilities of a restored session.
+func restoreCapabilitiesFromService(svc *corev1.Service) []entities.SessionCapability {
+	value := svc.Annotations[capabilitiesAnnotation]
+	if value == "" {
+		return nil
+	}
+	var caps []entities.SessionCapability
+	for _, name := range strings.Split(value, ",") {
+		if c := entities.SessionCapability(strings.TrimSpace(name)); entities.IsGatedCapability(c) {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// Capabilities returns the risky capabilities granted to the session.
+func (s *KubernetesSession) Capabilities() []entities.SessionCapability {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.capabilities
+}
+
+// SetCapabilities sets the risky capabilities granted to the session.
+func (s *KubernetesSession) SetCapabilities(caps []entities.SessionCapability) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capabilities = caps
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestCheckCapabilityPolicy(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+
+	teamReq := &entities.RunServerRequest{
+		UserID:   "test-user",
+		Scope:    entities.ScopeTeam,
+		TeamID:   "org/team",
+		Terminal: &entities.TerminalParams{Enabled: true},
+		Docker:   &entities.DockerParams{Enabled: true},
+	}
+
+	// Approval not required: everything passes.
+	if err := manager.checkCapabilityPolicy(ctx, "s1", teamReq); err != nil {
+		t.Fatalf("checkCapabilityPolicy() without enforcement error = %v", err)
+	}
+
+	manager.k8sConfig.RequireCapabilityApproval = true
+
+	// No settings repository or policy: risky capabilities are denied.
+	if err := manager.checkCapabilityPolicy(ctx, "s2", teamReq); !errors.Is(err, entities.ErrCapabilityNotAllowed) {
+		t.Fatalf("checkCapabilityPolicy() without policy error = %v, want ErrCapabilityNotAllowed", err)
+	}
+
+	teamSettings := entities.NewSettings("org/team")
+	teamSettings.SetCapabilityPolicy(entities.NewCapabilityPolicy([]entities.SessionCapability{entities.CapabilityTerminal}))
+	manager.SetSettingsRepository(&fakeSettingsRepository{
+		settings: map[string]*entities.Settings{"org/team": teamSettings},
+	})
+
+	// Docker is not approved for the team.
+	err := manager.checkCapabilityPolicy(ctx, "s3", teamReq)
+	if !errors.Is(err, entities.ErrCapabilityNotAllowed) {
+		t.Fatalf("checkCapabilityPolicy() error = %v, want ErrCapabilityNotAllowed", err)
+	}
+
+	teamReq.Docker = nil
+	if err := manager.checkCapabilityPolicy(ctx, "s4", teamReq); err != nil {
+		t.Fatalf("checkCapabilityPolicy() with approved terminal error = %v", err)
+	}
+
+	// User-scoped sessions are governed by the base settings, which have no policy.
+	userReq := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeUser, Terminal: &entities.TerminalParams{Enabled: true}}
+	if err := manager.checkCapabilityPolicy(ctx, "s5", userReq); !errors.Is(err, entities.ErrCapabilityNotAllowed) {
+		t.Fatalf("checkCapabilityPolicy() for user scope error = %v, want ErrCapabilityNotAllowed", err)
+	}
+
+	// Sessions without risky capabilities are never gated.
+	if err := manager.checkCapabilityPolicy(ctx, "s6", &entities.RunServerRequest{UserID: "test-user"}); err != nil {
+		t.Fatalf("checkCapabilityPolicy() for plain session error = %v", err)
+	}
+}
+
+func TestSessionCapabilitiesRecordedOnService(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	session.request.Editor = &entities.EditorParams{Enabled: true}
+	session.request.Docker = &entities.DockerParams{Enabled: true}
+	session.SetCapabilities(entities.RequestedCapabilities(session.request))
+
+	if err := manager.createService(context.Background(), session); err != nil {
+		t.Fatalf("createService() error = %v", err)
+	}
+	svc, err := manager.client.CoreV1().Services(manager.namespace).Get(context.Background(), session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := svc.Annotations[capabilitiesAnnotation]; got != "docker,editor" {
+		t.Errorf("capabilities annotation = %q, want %q", got, "docker,editor")
+	}
+
+	restored := restoreCapabilitiesFromService(svc)
+	if want := []entities.SessionCapability{entities.CapabilityDocker, entities.CapabilityEditor}; !reflect.DeepEqual(restored, want) {
+		t.Errorf("restoreCapabilitiesFromService() = %v, want %v", restored, want)
+	}
+
+	if got := restoreCapabilitiesFromService(&corev1.Service{}); got != nil {
+		t.Errorf("restoreCapabilitiesFromService() without annotation = %v, want nil", got)
+	}
+}
+
+func TestExecCapability(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	manager.k8sConfig.ExecEnabled = true
+	req := entities.SessionExecRequest{Command: []string{"id"}}
+
+	// Approval not required: sessions get exec.
+	session := newWorkloadTestSession()
+	manager.grantExecCapability(ctx, session, session.Request())
+	if want := []entities.SessionCapability{entities.CapabilityExec}; !reflect.DeepEqual(session.Capabilities(), want) {
+		t.Fatalf("Capabilities() = %v, want %v", session.Capabilities(), want)
+	}
+
+	manager.k8sConfig.RequireCapabilityApproval = true
+	teamReq := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeTeam, TeamID: "org/team"}
+	teamSettings := entities.NewSettings("org/team")
+	teamSettings.SetCapabilityPolicy(entities.NewCapabilityPolicy([]entities.SessionCapability{entities.CapabilityTerminal}))
+	manager.SetSettingsRepository(&fakeSettingsRepository{
+		settings: map[string]*entities.Settings{"org/team": teamSettings},
+	})
+
+	// The team policy does not allow exec.
+	session = newWorkloadTestSession()
+	session.request = teamReq
+	manager.grantExecCapability(ctx, session, teamReq)
+	if len(session.Capabilities()) != 0 {
+		t.Fatalf("Capabilities() = %v, want none", session.Capabilities())
+	}
+	manager.sessions[session.ID()] = session
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); !errors.Is(err, entities.ErrCapabilityNotAllowed) {
+		t.Fatalf("ExecInSession() without exec capability error = %v, want ErrCapabilityNotAllowed", err)
+	}
+
+	teamSettings.SetCapabilityPolicy(entities.NewCapabilityPolicy([]entities.SessionCapability{entities.CapabilityExec}))
+	manager.grantExecCapability(ctx, session, teamReq)
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); errors.Is(err, entities.ErrCapabilityNotAllowed) {
+		t.Fatalf("ExecInSession() with exec capability error = %v", err)
+	}
+
+	// The grant survives a restart through the capabilities annotation.
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		capabilitiesAnnotation: entities.JoinCapabilities(session.Capabilities()),
+	}}}
+	if got := restoreCapabilitiesFromService(svc); !reflect.DeepEqual(got, []entities.SessionCapability{entities.CapabilityExec}) {
+		t.Errorf("restoreCapabilitiesFromService() = %v, want [exec]", got)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// PodDisruptionBudget modes of session Pods.
+const (
+	// DisruptionBudgetSession creates one PodDisruptionBudget per session.
+	DisruptionBudgetSession = "session"
+	// DisruptionBudgetShared creates one PodDisruptionBudget per namespace
+	// that selects all session Pods.
+	DisruptionBudgetShared = "shared"
+)
+
+// sharedDisruptionBudgetName is the PodDisruptionBudget of the shared mode.
+const sharedDisruptionBudgetName = "agentapi-sessions"
+
+// evictedPodReason is the Pod status reason the kubelet sets when it evicts
+// a Pod because of node pressure.
+const evictedPodReason = "Evicted"
+
+// validateDisruptionBudget rejects unknown kubernetes_session.disruption_budget values.
+func validateDisruptionBudget(mode string, maxUnavailable int) error {
+	switch mode {
+	case "", DisruptionBudgetSession, DisruptionBudgetShared:
+	default:
+		return fmt.Errorf("unknown kubernetes_session.disruption_budget %q: must be %q or %q",
+			mode, DisruptionBudgetSession, DisruptionBudgetShared)
+	}
+	if maxUnavailable < 0 {
+		return fmt.Errorf("kubernetes_session.disruption_budget_max_unavailable must not be negative")
+	}
+	return nil
+}
+
+// ensureSessionDisruptionBudget protects the Pod of the session from
+// voluntary disruptions such as node drains. Failures are logged only: a
+// session without a budget still works.
+func (m *KubernetesSessionManager) ensureSessionDisruptionBudget(ctx context.Context, session *KubernetesSession) {
+	if session.RunsAsJob() {
+		return
+	}
+	maxUnavailable := intstr.FromInt32(int32(m.k8sConfig.DisruptionBudgetMaxUnavailable))
+	var pdb *policyv1.PodDisruptionBudget
+	switch m.k8sConfig.DisruptionBudget {
+	case DisruptionBudgetSession:
+		pdb = &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      session.DeploymentName(),
+				Namespace: session.Namespace(),
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "agentapi-proxy",
+					"agentapi.proxy/session-id":    session.id,
+				},
+				OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"agentapi.proxy/session-id": session.id},
+				},
+			},
+		}
+	case DisruptionBudgetShared:
+		pdb = &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      sharedDisruptionBudgetName,
+				Namespace: session.Namespace(),
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app.kubernetes.io/name":       "agentapi-session",
+						"app.kubernetes.io/managed-by": "agentapi-proxy",
+					},
+				},
+			},
+		}
+	default:
+		return
+	}
+	_, err := m.client.PolicyV1().PodDisruptionBudgets(session.Namespace()).Create(ctx, pdb, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		log.Printf("[K8S_SESSION] Warning: failed to create PodDisruptionBudget %s for session %s: %v", pdb.Name, session.id, err)
+	}
+}
+
+// deleteSessionDisruptionBudget deletes the PodDisruptionBudget of the
+// session. The shared budget is kept for the other sessions.
+func (m *KubernetesSessionManager) deleteSessionDisruptionBudget(ctx context.Context, session *KubernetesSession) error {
+	if m.k8sConfig == nil || m.k8sConfig.DisruptionBudget != DisruptionBudgetSession {
+		return nil
+	}
+	err := m.client.PolicyV1().PodDisruptionBudgets(session.Namespace()).Delete(ctx, session.DeploymentName(), metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// applyPreStopCheckpoint lets the agent checkpoint its conversation to the
+// session PVC before the container stops, so that the next Pod resumes it.
+// Without a PVC the checkpoint would be lost with the Pod.
+func (m *KubernetesSessionManager) applyPreStopCheckpoint(container *corev1.Container) {
+	if !m.k8sConfig.PreStopCheckpoint || !m.isPVCEnabled() {
+		return
+	}
+	container.Lifecycle = &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"agentapi-proxy", "client", "checkpoint-session"},
+			},
+		},
+	}
+}
+
+// podEviction reports whether the Pod was evicted, by the kubelet because of
+// node pressure or through the Eviction API (e.g. by a node drain), and why.
+func podEviction(pod *corev1.Pod) (bool, string) {
+	if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == evictedPodReason {
+		return true, pod.Status.Message
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return true, fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+		}
+	}
+	return false, ""
+}
+
+// checkPodEviction records an evicted event once for each evicted Pod of the
+// session and reports whether a Pod of the session is being or was evicted.
+// All Pods are checked because a Deployment replaces an evicted Pod while it
+// is still terminating.
+func (m *KubernetesSessionManager) checkPodEviction(ctx context.Context, session *KubernetesSession, health *podHealth) bool {
+	pods, err := m.client.CoreV1().Pods(session.Namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("agentapi.proxy/session-id=%s", session.id),
+	})
+	if err != nil {
+		return false
+	}
+	evicted := false
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		ok, reason := podEviction(pod)
+		if !ok {
+			continue
+		}
+		evicted = true
+		if health.evictedPod != pod.Name {
+			health.evictedPod = pod.Name
+			m.recordEvent(session.id, entities.SessionEventEvicted, "Pod %s was evicted: %s", pod.Name, reason)
+		}
+	}
+	return evicted
+}
+
+// recreateEvictedPod brings back the Pod of an evicted session that has no
+// Deployment to do so. The evicted Pod is deleted first; the new one is
+// created once it is gone. It reports whether the session is being
+// recreated, so that watching it should go on.
+func (m *KubernetesSessionManager) recreateEvictedPod(ctx context.Context, session *KubernetesSession) bool {
+	if !m.k8sConfig.RecreateEvictedSessions || m.isPVCEnabled() || session.RunsAsJob() {
+		return false
+	}
+	pods := m.client.CoreV1().Pods(session.Namespace())
+	pod, err := pods.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	switch {
+	case err == nil && pod.DeletionTimestamp == nil:
+		// Pods evicted by the kubelet stay Failed until they are deleted.
+		if err := pods.Delete(ctx, session.DeploymentName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			log.Printf("[K8S_SESSION] Failed to delete evicted pod of session %s: %v", session.id, err)
+		}
+		return true
+	case err == nil:
+		return true
+	case !errors.IsNotFound(err):
+		return true
+	}
+
+	if err := m.createPod(ctx, session, session.Request()); err != nil {
+		log.Printf("[K8S_SESSION] Failed to recreate evicted pod of session %s: %v", session.id, err)
+		return false
+	}
+	log.Printf("[K8S_SESSION] Recreated evicted pod of session %s", session.id)
+	m.recordEvent(session.id, entities.SessionEventRestarted, "Pod recreated after eviction")
+	return true
+}
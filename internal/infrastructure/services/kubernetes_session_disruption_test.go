@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestValidateDisruptionBudget(t *testing.T) {
+	for _, mode := range []string{"", DisruptionBudgetSession, DisruptionBudgetShared} {
+		if err := validateDisruptionBudget(mode, 1); err != nil {
+			t.Errorf("validateDisruptionBudget(%q) = %v", mode, err)
+		}
+	}
+	if err := validateDisruptionBudget("node", 0); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	if err := validateDisruptionBudget(DisruptionBudgetSession, -1); err == nil {
+		t.Error("negative maxUnavailable accepted")
+	}
+}
+
+func TestSessionDisruptionBudget(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.DisruptionBudget = DisruptionBudgetSession
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+	pdbs := manager.client.PolicyV1().PodDisruptionBudgets("test-ns")
+
+	manager.ensureSessionDisruptionBudget(ctx, session)
+	pdb, err := pdbs.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("PodDisruptionBudget not created: %v", err)
+	}
+	if got := pdb.Spec.Selector.MatchLabels["agentapi.proxy/session-id"]; got != session.ID() {
+		t.Errorf("selector session-id = %q, want %q", got, session.ID())
+	}
+	if pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntValue() != 0 {
+		t.Errorf("maxUnavailable = %v, want 0", pdb.Spec.MaxUnavailable)
+	}
+
+	if err := manager.deleteSessionDisruptionBudget(ctx, session); err != nil {
+		t.Fatalf("deleteSessionDisruptionBudget: %v", err)
+	}
+	if _, err := pdbs.Get(ctx, session.DeploymentName(), metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Fatalf("PodDisruptionBudget not deleted: %v", err)
+	}
+}
+
+func TestSharedDisruptionBudget(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.DisruptionBudget = DisruptionBudgetShared
+	manager.k8sConfig.DisruptionBudgetMaxUnavailable = 2
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+
+	// Every session ensures the same budget.
+	manager.ensureSessionDisruptionBudget(ctx, session)
+	manager.ensureSessionDisruptionBudget(ctx, session)
+	list, err := manager.client.PolicyV1().PodDisruptionBudgets("test-ns").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != sharedDisruptionBudgetName {
+		t.Fatalf("budgets = %+v, want only %s", list.Items, sharedDisruptionBudgetName)
+	}
+	if got := list.Items[0].Spec.MaxUnavailable.IntValue(); got != 2 {
+		t.Errorf("maxUnavailable = %d, want 2", got)
+	}
+
+	// The shared budget outlives the session.
+	if err := manager.deleteSessionDisruptionBudget(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.client.PolicyV1().PodDisruptionBudgets("test-ns").Get(ctx, sharedDisruptionBudgetName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("shared budget deleted: %v", err)
+	}
+}
+
+func TestApplyPreStopCheckpointRequiresPVC(t *testing.T) {
+	for _, pvc := range []bool{false, true} {
+		manager := newWorkloadTestManager(t, pvc)
+		manager.k8sConfig.PreStopCheckpoint = true
+		var container corev1.Container
+		manager.applyPreStopCheckpoint(&container)
+		if got := container.Lifecycle != nil; got != pvc {
+			t.Errorf("pvc=%v: preStop hook set = %v, want %v", pvc, got, pvc)
+		}
+	}
+}
+
+func TestCheckPodEvictionRecordsEventOnce(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	recorder := &fakeEventRecorder{}
+	manager.SetEventRecorder(recorder)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      session.DeploymentName(),
+			Namespace: "test-ns",
+			Labels:    map[string]string{"agentapi.proxy/session-id": session.ID()},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	pods := manager.client.CoreV1().Pods("test-ns")
+	if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var health podHealth
+	if manager.checkPodEviction(ctx, session, &health) {
+		t.Fatal("running pod reported as evicted")
+	}
+
+	pod.Status.Conditions = []corev1.PodCondition{{
+		Type:    corev1.DisruptionTarget,
+		Status:  corev1.ConditionTrue,
+		Reason:  "EvictionByEvictionAPI",
+		Message: "Eviction API: evicting",
+	}}
+	if _, err := pods.UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if !manager.checkPodEviction(ctx, session, &health) {
+			t.Fatal("evicted pod not detected")
+		}
+	}
+	events, _ := recorder.ListSessionEvents(ctx, session.ID())
+	if len(events) != 1 || events[0].Type != entities.SessionEventEvicted {
+		t.Fatalf("events = %+v, want one evicted event", events)
+	}
+}
+
+func TestRecreateEvictedPod(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+	if err := manager.createSessionWorkload(ctx, session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	pods := manager.client.CoreV1().Pods("test-ns")
+	pod, err := pods.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod.Status = corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "The node was low on resource: memory."}
+	if _, err := pods.UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if evicted, _ := podEviction(pod); !evicted {
+		t.Fatal("pod evicted by the kubelet not detected")
+	}
+
+	if manager.recreateEvictedPod(ctx, session) {
+		t.Fatal("pod recreated without recreate_evicted_sessions")
+	}
+
+	manager.k8sConfig.RecreateEvictedSessions = true
+	// The failed Pod is deleted first, then recreated.
+	if !manager.recreateEvictedPod(ctx, session) {
+		t.Fatal("recreation not started")
+	}
+	if _, err := pods.Get(ctx, session.DeploymentName(), metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Fatalf("evicted pod not deleted: %v", err)
+	}
+	if !manager.recreateEvictedPod(ctx, session) {
+		t.Fatal("pod not recreated")
+	}
+	recreated, err := pods.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("pod not recreated: %v", err)
+	}
+	if recreated.Status.Phase == corev1.PodFailed {
+		t.Fatal("recreated pod still failed")
+	}
+}
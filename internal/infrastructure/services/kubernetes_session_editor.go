@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// EditorPort is the port code-server listens on in sessions with the editor
+// sidecar. The proxy reaches it through the session Service.
+const EditorPort = 13337
+
+// editorCapabilityLabel marks session Services whose Pod runs the editor sidecar.
+const editorCapabilityLabel = "agentapi.proxy/capability-editor"
+
+func editorEnabled(req *entities.RunServerRequest) bool {
+	return req != nil && req.Editor != nil && req.Editor.Enabled
+}
+
+// restoreEditorFromService rebuilds the editor params of a restored session.
+func restoreEditorFromService(svc *corev1.Service) *entities.EditorParams {
+	if svc.Labels[editorCapabilityLabel] != "true" {
+		return nil
+	}
+	return &entities.EditorParams{Enabled: true}
+}
+
+// EditorEnabled reports whether the session Pod runs the editor sidecar.
+func (s *KubernetesSession) EditorEnabled() bool {
+	return editorEnabled(s.Request())
+}
+
+// buildEditorContainer returns the code-server sidecar and its volumes. It
+// shares the workdir volume with the agent container and runs without its own
+// authentication: access control is enforced by the proxy, which is the only
+// intended client of the editor Service port.
+func (m *KubernetesSessionManager) buildEditorContainer() (*corev1.Container, []corev1.Volume) {
+	image := m.k8sConfig.EditorImage
+	if image == "" {
+		image = config.DefaultEditorImage
+	}
+	falseVal := false
+
+	sidecar := corev1.Container{
+		Name:            "editor",
+		Image:           image,
+		ImagePullPolicy: corev1.PullPolicy(m.k8sConfig.ImagePullPolicy),
+		Args: []string{
+			"--bind-addr", fmt.Sprintf("0.0.0.0:%d", EditorPort),
+			"--auth", "none",
+			"--disable-telemetry",
+			"--disable-update-check",
+			"--disable-workspace-trust",
+			"/home/agentapi/workdir",
+		},
+		Env: []corev1.EnvVar{
+			// The Pod runs as UID 999, which has no home directory in the image.
+			{Name: "HOME", Value: "/home/coder"},
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "editor", ContainerPort: EditorPort, Protocol: corev1.ProtocolTCP},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(EditorPort)},
+			},
+			PeriodSeconds: 10,
+		},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &falseVal,
+		},
+		Resources: buildResourceRequirements(
+			defaultIfEmpty(m.k8sConfig.EditorCPURequest, "100m"),
+			defaultIfEmpty(m.k8sConfig.EditorCPULimit, "1"),
+			defaultIfEmpty(m.k8sConfig.EditorMemoryRequest, "256Mi"),
+			defaultIfEmpty(m.k8sConfig.EditorMemoryLimit, "1Gi"),
+		),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "workdir", MountPath: "/home/agentapi/workdir"},
+			{Name: "editor-home", MountPath: "/home/coder"},
+		},
+	}
+
+	volumes := []corev1.Volume{
+		{
+			Name:         "editor-home",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	return &sidecar, volumes
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestCreateSessionWorkloadWithEditorSidecar(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	session.Request().Editor = &entities.EditorParams{Enabled: true}
+
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	pod, err := manager.client.CoreV1().Pods("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected pod to be created: %v", err)
+	}
+
+	var editor *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == "editor" {
+			editor = &pod.Spec.Containers[i]
+		}
+	}
+	if editor == nil {
+		t.Fatal("Expected editor sidecar container")
+	}
+	if editor.Image != config.DefaultEditorImage {
+		t.Errorf("Expected default editor image, got %q", editor.Image)
+	}
+	mountsWorkdir := false
+	for _, vm := range editor.VolumeMounts {
+		if vm.Name == "workdir" && vm.MountPath == "/home/agentapi/workdir" {
+			mountsWorkdir = true
+		}
+	}
+	if !mountsWorkdir {
+		t.Errorf("Expected editor to mount the session workdir, got %+v", editor.VolumeMounts)
+	}
+
+	hasPort := false
+	for _, p := range manager.buildServicePorts(session) {
+		if p.Name == "editor" && p.Port == EditorPort {
+			hasPort = true
+		}
+	}
+	if !hasPort {
+		t.Error("Expected editor port on the session Service")
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: manager.buildLabels(session)}}
+	if restored := restoreEditorFromService(svc); restored == nil || !restored.Enabled {
+		t.Error("Expected editor params to be restored from Service labels")
+	}
+}
+
+func TestCreateSessionWorkloadWithoutEditor(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	pod, err := manager.client.CoreV1().Pods("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected pod to be created: %v", err)
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == "editor" {
+			t.Fatal("Editor sidecar must be opt-in")
+		}
+	}
+	if _, ok := manager.buildLabels(session)[editorCapabilityLabel]; ok {
+		t.Error("Editor capability label must only be set for editor sessions")
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// podHealth is what watchDeploymentStatus last saw of the session Pod. It is
+// only touched by the watch goroutine of the session.
+type podHealth struct {
+	observed     bool
+	restarts     int32
+	crashLooping bool
+	// evictedPod is the last Pod an evicted event was recorded for
+	evictedPod string
+}
+
+// SetEventRecorder configures where session lifecycle events are recorded.
+// Without a recorder events are not kept.
+func (m *KubernetesSessionManager) SetEventRecorder(recorder portrepos.EventRecorder) {
+	m.eventRecorder = recorder
+}
+
+// recordEvent records a lifecycle event of a session. Recording is best
+// effort and never fails the operation that caused the event.
+// The event is also passed to the lifecycle subscribers of every pod.
+func (m *KubernetesSessionManager) recordEvent(sessionID string, eventType entities.SessionEventType, format string, args ...interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event := entities.SessionEvent{
+		SessionID: sessionID,
+		Type:      eventType,
+		Message:   fmt.Sprintf(format, args...),
+		Timestamp: time.Now().UTC(),
+	}
+	if m.eventRecorder != nil {
+		if err := m.eventRecorder.RecordSessionEvent(ctx, event); err != nil {
+			log.Printf("[K8S_SESSION] Failed to record %s event for session %s: %v", eventType, sessionID, err)
+		}
+	}
+	m.broadcastSessionEventLocal(event)
+	if m.sessionEventBroadcaster != nil {
+		shared := portrepos.SharedSessionEvent{SessionEvent: event, PodID: m.podID}
+		if err := m.sessionEventBroadcaster.PublishSessionEvent(ctx, shared); err != nil {
+			log.Printf("[K8S_SESSION] Failed to share %s event for session %s: %v", eventType, sessionID, err)
+		}
+	}
+}
+
+// SubscribeSessionEvents registers a subscriber for the timeline events of
+// all sessions, recorded by this pod or (with Redis) any other pod. It
+// returns the channel of events and a cancel func that must be called to
+// unsubscribe (closes the channel). Slow subscribers miss events.
+func (m *KubernetesSessionManager) SubscribeSessionEvents() (<-chan entities.SessionEvent, func()) {
+	m.sessionEventSubsMu.Lock()
+	defer m.sessionEventSubsMu.Unlock()
+	if m.sessionEventSubs == nil {
+		m.sessionEventSubs = make(map[uint64]chan entities.SessionEvent)
+	}
+	id := m.nextSessionEventSub
+	m.nextSessionEventSub++
+	ch := make(chan entities.SessionEvent, 32)
+	m.sessionEventSubs[id] = ch
+	cancel := func() {
+		m.sessionEventSubsMu.Lock()
+		defer m.sessionEventSubsMu.Unlock()
+		if ch, ok := m.sessionEventSubs[id]; ok {
+			close(ch)
+			delete(m.sessionEventSubs, id)
+		}
+	}
+	return ch, cancel
+}
+
+// broadcastSessionEventLocal passes event to the subscribers of this pod
+func (m *KubernetesSessionManager) broadcastSessionEventLocal(event entities.SessionEvent) {
+	m.sessionEventSubsMu.Lock()
+	defer m.sessionEventSubsMu.Unlock()
+	for _, ch := range m.sessionEventSubs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// runSessionEventSubscriber receives the timeline events shared by the other
+// pods until ctx is cancelled, reconnecting when the subscription is lost.
+func (m *KubernetesSessionManager) runSessionEventSubscriber(ctx context.Context) {
+	for {
+		ch, err := m.sessionEventBroadcaster.SubscribeSessionEvents(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[K8S_SESSION] Failed to subscribe to shared session events, retrying in 5s: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		log.Printf("[K8S_SESSION] Cross-pod session event subscriber connected")
+		for evt := range ch {
+			m.handleSharedSessionEvent(evt)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[K8S_SESSION] Session event subscriber channel closed, reconnecting in 3s")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// handleSharedSessionEvent applies a timeline event recorded by another pod.
+// A session deleted elsewhere is dropped from memory so that this pod stops
+// watching it and no longer serves it from its map; its Kubernetes resources
+// are left to the pod that deletes them.
+func (m *KubernetesSessionManager) handleSharedSessionEvent(evt portrepos.SharedSessionEvent) {
+	if evt.PodID == m.podID {
+		return
+	}
+	if evt.Type == entities.SessionEventDeleted {
+		m.mutex.RLock()
+		session, exists := m.sessions[evt.SessionID]
+		m.mutex.RUnlock()
+		if exists {
+			log.Printf("[K8S_SESSION] Session %s deleted by pod %s, dropping it from memory", evt.SessionID, evt.PodID)
+			session.Cancel()
+			m.cleanupSession(evt.SessionID)
+		}
+	}
+	m.broadcastSessionEventLocal(evt.SessionEvent)
+}
+
+// RecordMessageSent records that a message was sent to the agent of a session.
+func (m *KubernetesSessionManager) RecordMessageSent(sessionID string) {
+	m.recordEvent(sessionID, entities.SessionEventMessageSent, "Message sent to the agent")
+}
+
+// ListSessionEvents returns the lifecycle event timeline of a session.
+func (m *KubernetesSessionManager) ListSessionEvents(ctx context.Context, sessionID string) ([]entities.SessionEvent, error) {
+	if m.eventRecorder == nil {
+		return []entities.SessionEvent{}, nil
+	}
+	return m.eventRecorder.ListSessionEvents(ctx, sessionID)
+}
+
+// deleteSessionEvents removes the timeline of a deleted session.
+func (m *KubernetesSessionManager) deleteSessionEvents(ctx context.Context, sessionID string) error {
+	if m.eventRecorder == nil {
+		return nil
+	}
+	return m.eventRecorder.DeleteSessionEvents(ctx, sessionID)
+}
+
+// checkPodRestarts records restarted and crashed events for the main
+// container of the session Pod. Restarts that happened before the first
+// check (e.g. before a proxy restart) are not reported.
+func (m *KubernetesSessionManager) checkPodRestarts(ctx context.Context, session *KubernetesSession, health *podHealth) {
+	pod, err := m.sessionPod(ctx, session)
+	if err != nil {
+		return
+	}
+	var status *corev1.ContainerStatus
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == mainContainerName {
+			status = &pod.Status.ContainerStatuses[i]
+		}
+	}
+	if status == nil {
+		return
+	}
+
+	if health.observed && status.RestartCount > health.restarts {
+		reason := "unknown reason"
+		if t := status.LastTerminationState.Terminated; t != nil {
+			reason = fmt.Sprintf("%s (exit code %d)", t.Reason, t.ExitCode)
+		}
+		m.recordEvent(session.id, entities.SessionEventRestarted, "Container %s restarted (%d restarts): %s", status.Name, status.RestartCount, reason)
+	}
+	crashLooping := status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff"
+	if crashLooping && !health.crashLooping {
+		m.recordEvent(session.id, entities.SessionEventCrashed, "Container %s is crash looping: %s", status.Name, status.State.Waiting.Message)
+	}
+	health.observed = true
+	health.restarts = status.RestartCount
+	health.crashLooping = crashLooping
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+type fakeEventRecorder struct {
+	mu     sync.Mutex
+	events []entities.SessionEvent
+}
+
+func (r *fakeEventRecorder) RecordSessionEvent(_ context.Context, event entities.SessionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeEventRecorder) ListSessionEvents(_ context.Context, sessionID string) ([]entities.SessionEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []entities.SessionEvent
+	for _, e := range r.events {
+		if e.SessionID == sessionID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeEventRecorder) DeleteSessionEvents(context.Context, string) error { return nil }
+
+func TestCheckPodRestartsRecordsEvents(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	recorder := &fakeEventRecorder{}
+	manager.SetEventRecorder(recorder)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "session-pod",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"agentapi.proxy/session-id": session.ID()},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: mainContainerName, RestartCount: 2}},
+		},
+	}
+	pods := manager.client.CoreV1().Pods("test-ns")
+	if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+
+	// Restarts before the first check are not reported.
+	var health podHealth
+	manager.checkPodRestarts(ctx, session, &health)
+	if len(recorder.events) != 0 {
+		t.Fatalf("events after first check = %+v, want none", recorder.events)
+	}
+
+	pod.Status.ContainerStatuses[0] = corev1.ContainerStatus{
+		Name:         mainContainerName,
+		RestartCount: 3,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+			Reason: "CrashLoopBackOff", Message: "back-off 10s",
+		}},
+		LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			Reason: "OOMKilled", ExitCode: 137,
+		}},
+	}
+	if _, err := pods.UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update pod: %v", err)
+	}
+	manager.checkPodRestarts(ctx, session, &health)
+	// A Pod still crash looping at the next check is not reported again.
+	manager.checkPodRestarts(ctx, session, &health)
+
+	events, _ := recorder.ListSessionEvents(ctx, session.ID())
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want restarted and crashed", events)
+	}
+	if events[0].Type != entities.SessionEventRestarted || events[0].Message != "Container agentapi restarted (3 restarts): OOMKilled (exit code 137)" {
+		t.Errorf("restarted event = %+v", events[0])
+	}
+	if events[1].Type != entities.SessionEventCrashed {
+		t.Errorf("crashed event = %+v", events[1])
+	}
+}
+
+// fakeSessionEventBroadcaster is a status event repository that shares
+// session events through in-process channels
+type fakeSessionEventBroadcaster struct {
+	mu        sync.Mutex
+	published []portrepos.SharedSessionEvent
+	incoming  chan portrepos.SharedSessionEvent
+}
+
+func (b *fakeSessionEventBroadcaster) SetStatus(context.Context, string, string, string) error {
+	return nil
+}
+
+func (b *fakeSessionEventBroadcaster) GetStatus(context.Context, string) (string, time.Time, error) {
+	return "", time.Time{}, nil
+}
+
+func (b *fakeSessionEventBroadcaster) PublishStatusChange(context.Context, portrepos.StatusChangeEvent) error {
+	return nil
+}
+
+func (b *fakeSessionEventBroadcaster) SubscribeGlobal(ctx context.Context) (<-chan portrepos.StatusChangeEvent, error) {
+	ch := make(chan portrepos.StatusChangeEvent)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (b *fakeSessionEventBroadcaster) DeleteStatus(context.Context, string) error { return nil }
+
+func (b *fakeSessionEventBroadcaster) PublishSessionEvent(_ context.Context, event portrepos.SharedSessionEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, event)
+	return nil
+}
+
+func (b *fakeSessionEventBroadcaster) SubscribeSessionEvents(context.Context) (<-chan portrepos.SharedSessionEvent, error) {
+	return b.incoming, nil
+}
+
+func receiveSessionEvent(t *testing.T, ch <-chan entities.SessionEvent) entities.SessionEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no session event received")
+		return entities.SessionEvent{}
+	}
+}
+
+func TestSessionEventsAreSharedBetweenPods(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	defer manager.StopStatusSubscriber()
+	broadcaster := &fakeSessionEventBroadcaster{incoming: make(chan portrepos.SharedSessionEvent, 4)}
+	manager.SetStatusEventRepository(broadcaster)
+	events, cancel := manager.SubscribeSessionEvents()
+	defer cancel()
+
+	// Events recorded here reach local subscribers and the other pods
+	manager.RecordMessageSent("local-session")
+	if got := receiveSessionEvent(t, events); got.SessionID != "local-session" || got.Type != entities.SessionEventMessageSent {
+		t.Errorf("local event = %+v", got)
+	}
+	broadcaster.mu.Lock()
+	if len(broadcaster.published) != 1 || broadcaster.published[0].PodID != manager.podID {
+		t.Errorf("published = %+v, want one event from pod %s", broadcaster.published, manager.podID)
+	}
+	broadcaster.mu.Unlock()
+
+	// A session deleted by another pod is dropped from memory; the echo of
+	// our own events is ignored
+	session := newWorkloadTestSession()
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+	broadcaster.incoming <- portrepos.SharedSessionEvent{
+		SessionEvent: entities.SessionEvent{SessionID: "local-session", Type: entities.SessionEventMessageSent},
+		PodID:        manager.podID,
+	}
+	broadcaster.incoming <- portrepos.SharedSessionEvent{
+		SessionEvent: entities.SessionEvent{SessionID: session.ID(), Type: entities.SessionEventDeleted},
+		PodID:        "other-pod",
+	}
+	if got := receiveSessionEvent(t, events); got.SessionID != session.ID() || got.Type != entities.SessionEventDeleted {
+		t.Errorf("shared event = %+v, want the deletion from the other pod", got)
+	}
+	manager.mutex.RLock()
+	_, exists := manager.sessions[session.ID()]
+	manager.mutex.RUnlock()
+	if exists {
+		t.Error("session deleted by another pod is still in memory")
+	}
+}
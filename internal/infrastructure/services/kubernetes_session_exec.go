@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// defaultExecCommand starts bash when the image has it and sh otherwise.
+var defaultExecCommand = []string{"/bin/sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
+// ExecStreams connects an exec to the caller. Nil streams are not attached.
+type ExecStreams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Resize delivers terminal size changes of a TTY exec until it is closed.
+	Resize <-chan entities.TerminalSize
+}
+
+// terminalSizeQueue adapts ExecStreams.Resize to remotecommand.
+type terminalSizeQueue <-chan entities.TerminalSize
+
+func (q terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Width: size.Cols, Height: size.Rows}
+}
+
+// sessionExecFunc runs a command in a session container; see ExecInSession.
+type sessionExecFunc func(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error)
+
+// ExecInSession runs req in a container of the session Pod and returns the
+// exit code of the command. It returns once the command exits or ctx is
+// cancelled. Sessions that were not granted CapabilityExec are rejected with
+// ErrCapabilityNotAllowed.
+func (m *KubernetesSessionManager) ExecInSession(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error) {
+	if !m.k8sConfig.ExecEnabled {
+		return 0, entities.ErrExecDisabled
+	}
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.checkExecCapability(ks); err != nil {
+		return 0, err
+	}
+	return m.execInSession(ctx, sessionID, req, streams)
+}
+
+// execInSession is ExecInSession without the ExecEnabled check, for execs
+// started by the proxy itself rather than by users.
+func (m *KubernetesSessionManager) execInSession(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error) {
+	if m.restConfig == nil {
+		return 0, fmt.Errorf("exec requires a Kubernetes client configuration")
+	}
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	pod, err := m.sessionPod(ctx, ks)
+	if err != nil {
+		return 0, err
+	}
+
+	container := defaultIfEmpty(req.Container, mainContainerName)
+	names := make([]string, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	if !slices.Contains(names, container) {
+		return 0, fmt.Errorf("%w %q (available: %s)", entities.ErrUnknownContainer, container, strings.Join(names, ", "))
+	}
+	command := req.Command
+	if len(command) == 0 {
+		command = defaultExecCommand
+	}
+
+	execReq := m.client.CoreV1().RESTClient().Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     streams.Stdin != nil,
+			Stdout:    streams.Stdout != nil,
+			Stderr:    streams.Stderr != nil && !req.TTY,
+			TTY:       req.TTY,
+		}, scheme.ParameterCodec)
+
+	// Prefer the WebSocket protocol and fall back to SPDY for API servers
+	// older than Kubernetes 1.30.
+	wsExec, err := remotecommand.NewWebSocketExecutor(m.restConfig, "GET", execReq.URL().String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	spdyExec, err := remotecommand.NewSPDYExecutor(m.restConfig, "POST", execReq.URL())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	executor, err := remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	opts := remotecommand.StreamOptions{
+		Stdin:  streams.Stdin,
+		Stdout: streams.Stdout,
+		Tty:    req.TTY,
+	}
+	if !req.TTY {
+		opts.Stderr = streams.Stderr
+	}
+	if req.TTY && streams.Resize != nil {
+		opts.TerminalSizeQueue = terminalSizeQueue(streams.Resize)
+	}
+	err = executor.StreamWithContext(ctx, opts)
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("exec in pod %s failed: %w", pod.Name, err)
+	}
+	return 0, nil
+}
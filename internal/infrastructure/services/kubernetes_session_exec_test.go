@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestExecInSessionValidation(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+	manager.sessions[session.ID()] = session
+	req := entities.SessionExecRequest{Command: []string{"id"}}
+
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); !errors.Is(err, entities.ErrExecDisabled) {
+		t.Fatalf("ExecInSession() with exec disabled error = %v, want ErrExecDisabled", err)
+	}
+	manager.k8sConfig.ExecEnabled = true
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); err == nil {
+		t.Fatal("ExecInSession() without a REST config should fail")
+	}
+
+	manager.restConfig = &rest.Config{Host: "http://127.0.0.1:1"}
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); !errors.Is(err, entities.ErrSessionPodUnavailable) {
+		t.Fatalf("ExecInSession() without pod error = %v, want ErrSessionPodUnavailable", err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      session.DeploymentName(),
+			Namespace: "test-ns",
+			Labels:    map[string]string{"agentapi.proxy/session-id": session.ID()},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "network-filter-setup"}},
+			Containers:     []corev1.Container{{Name: "agentapi"}},
+		},
+	}
+	if _, err := manager.client.CoreV1().Pods("test-ns").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	// Init containers have exited and cannot be exec'd into.
+	req.Container = "network-filter-setup"
+	if _, err := manager.ExecInSession(ctx, session.ID(), req, ExecStreams{}); !errors.Is(err, entities.ErrUnknownContainer) {
+		t.Fatalf("ExecInSession() into init container error = %v, want ErrUnknownContainer", err)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v57/github"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// githubReportAnnotation records the GitHub report of a session as JSON so
+// that restored sessions still finish their Check Run and pull request.
+const githubReportAnnotation = "agentapi.proxy/github-report"
+
+// GitHubReport returns the working branch, Check Run and pull request of a
+// session started from a GitHub webhook, or nil.
+func (s *KubernetesSession) GitHubReport() *entities.GitHubReport {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.githubReport == nil {
+		return nil
+	}
+	report := *s.githubReport
+	return &report
+}
+
+func (s *KubernetesSession) setGitHubReport(report *entities.GitHubReport) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.githubReport = report
+}
+
+// SetGitHubReport stores the GitHub report of a session in memory and on its
+// Service.
+func (m *KubernetesSessionManager) SetGitHubReport(ctx context.Context, sessionID string, report *entities.GitHubReport) error {
+	session, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || session == nil {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	session.setGitHubReport(report)
+	return m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+		githubReportAnnotation: githubReportAnnotationValue(report),
+	})
+}
+
+// GitHubRepositoryClient returns a REST API client authenticated as the
+// GitHub App installation of a repository, on the GitHub instance of teamID.
+// ErrGitHubTokenUnavailable is returned when the GitHub Secret holds no
+// GitHub App credentials.
+func (m *KubernetesSessionManager) GitHubRepositoryClient(ctx context.Context, teamID, repoFullName string) (*github.Client, error) {
+	creds, err := m.githubAppCredentials(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return m.githubTokenSource().Client(ctx, *creds, repoFullName)
+}
+
+func githubReportAnnotationValue(report *entities.GitHubReport) string {
+	if report == nil {
+		return ""
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func restoreGitHubReportFromService(svc *corev1.Service) *entities.GitHubReport {
+	raw := svc.Annotations[githubReportAnnotation]
+	if raw == "" {
+		return nil
+	}
+	var report entities.GitHubReport
+	if err := json.Unmarshal([]byte(raw), &report); err != nil {
+		log.Printf("[K8S_SESSION] Warning: invalid %s annotation on service %s: %v", githubReportAnnotation, svc.Name, err)
+		return nil
+	}
+	return &report
+}
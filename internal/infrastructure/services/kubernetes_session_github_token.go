@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+)
+
+// defaultGitHubTokenRefreshInterval is how often cached installation tokens
+// are checked for upcoming expiry when no interval is configured.
+const defaultGitHubTokenRefreshInterval = 5 * time.Minute
+
+// ErrGitHubTokenUnavailable is returned when a session does not authenticate
+// with the GitHub App of the proxy, for example because it was started with
+// params.github_token or the GitHub Secret holds a personal access token.
+var ErrGitHubTokenUnavailable = errors.New("github app installation token is not available for this session")
+
+// SessionGitHubToken returns a GitHub App installation token for the session,
+// scoped to its repository when REPOSITORY_RESTRICTION is enabled. Tokens are
+// minted from the credentials in the GitHub Secret and refreshed in the
+// background before they expire, so long-running sessions can fetch a valid
+// token at any time.
+func (m *KubernetesSessionManager) SessionGitHubToken(ctx context.Context, sessionID string) (*github_pkg.InstallationToken, error) {
+	session, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || session == nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	req := session.Request()
+	if req != nil && req.GithubToken != "" {
+		return nil, ErrGitHubTokenUnavailable
+	}
+
+	teamID := ""
+	if req != nil {
+		teamID = egressTeamID(req)
+	}
+	creds, err := m.githubAppCredentials(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	repoFullName := ""
+	if req != nil && req.RepoInfo != nil {
+		repoFullName = req.RepoInfo.FullName
+	}
+	return m.githubTokenSource().Token(ctx, *creds, repoFullName)
+}
+
+// githubTokenSource lazily creates the installation token source and starts
+// its refresh loop for the lifetime of the manager.
+func (m *KubernetesSessionManager) githubTokenSource() *github_pkg.InstallationTokenSource {
+	m.githubTokensOnce.Do(func() {
+		m.githubTokens = github_pkg.NewInstallationTokenSource(nil)
+		interval := defaultGitHubTokenRefreshInterval
+		if raw := strings.TrimSpace(m.k8sConfig.GitHubTokenRefreshInterval); raw != "" {
+			if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+				interval = d
+			} else {
+				log.Printf("[K8S_SESSION] Warning: invalid github_token_refresh_interval %q, using %s", raw, interval)
+			}
+		}
+		go m.githubTokens.Run(m.statusSubCtx, interval)
+	})
+	return m.githubTokens
+}
+
+// VerifyGitHubApp checks the GitHub App credentials in the GitHub Secret
+// against the GitHub API and describes the App. ErrGitHubTokenUnavailable is
+// returned when the Secret holds no GitHub App credentials.
+func (m *KubernetesSessionManager) VerifyGitHubApp(ctx context.Context) (string, error) {
+	creds, err := m.githubAppCredentials(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	slug, account, err := github_pkg.VerifyApp(ctx, *creds)
+	if err != nil {
+		return "", err
+	}
+	if account == "" {
+		return fmt.Sprintf("authenticated as GitHub App %s (installation discovered per repository)", slug), nil
+	}
+	return fmt.Sprintf("authenticated as GitHub App %s, installed on %s", slug, account), nil
+}
+
+// GitHubAppCredentials returns the GitHub App credentials of the GitHub
+// Secret. ErrGitHubTokenUnavailable is returned when it holds none.
+func (m *KubernetesSessionManager) GitHubAppCredentials(ctx context.Context) (*github_pkg.AppCredentials, error) {
+	return m.githubAppCredentials(ctx, "")
+}
+
+// githubInstance returns the GitHub instance configured for the sessions of
+// teamID and the GitHub Secret that replaces the default one, if any. ok is
+// false when github_enterprise is not configured.
+func (m *KubernetesSessionManager) githubInstance(teamID string) (instance github_pkg.Instance, secretName string, ok bool) {
+	if m.config == nil {
+		return github_pkg.DefaultInstance(), "", false
+	}
+	return m.config.GitHubEnterprise.Instance(teamID)
+}
+
+// githubSecretName returns the GitHub Secret of the sessions of req: the one
+// of its team's GitHub instance, or kubernetes_session.github_secret_name.
+func (m *KubernetesSessionManager) githubSecretName(req *entities.RunServerRequest) string {
+	if _, secretName, ok := m.githubInstance(egressTeamID(req)); ok && secretName != "" {
+		return secretName
+	}
+	return m.k8sConfig.GitHubSecretName
+}
+
+// githubAppCredentials reads the GitHub App credentials from the GitHub
+// Secret of teamID. The API URL comes from github_enterprise when configured,
+// else from GITHUB_API of the GitHub config Secret or the GitHub Secret.
+func (m *KubernetesSessionManager) githubAppCredentials(ctx context.Context, teamID string) (*github_pkg.AppCredentials, error) {
+	secretName := m.k8sConfig.GitHubSecretName
+	instance, instanceSecretName, configured := m.githubInstance(teamID)
+	if instanceSecretName != "" {
+		secretName = instanceSecretName
+	}
+	if secretName == "" {
+		return nil, ErrGitHubTokenUnavailable
+	}
+	data, err := m.readSecretData(ctx, secretName)
+	if err != nil {
+		return nil, err
+	}
+	appID := strings.TrimSpace(string(data["GITHUB_APP_ID"]))
+	pem := data["GITHUB_APP_PEM"]
+	if appID == "" || len(pem) == 0 {
+		return nil, ErrGitHubTokenUnavailable
+	}
+
+	creds := &github_pkg.AppCredentials{PEM: pem}
+	if creds.AppID, err = strconv.ParseInt(appID, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid GITHUB_APP_ID in secret %s: %w", secretName, err)
+	}
+	if raw := strings.TrimSpace(string(data["GITHUB_INSTALLATION_ID"])); raw != "" {
+		if creds.InstallationID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_INSTALLATION_ID in secret %s: %w", secretName, err)
+		}
+	}
+	creds.RestrictToRepository = strings.EqualFold(strings.TrimSpace(string(data["REPOSITORY_RESTRICTION"])), "true")
+	if configured {
+		creds.APIBase = instance.APIURL
+		return creds, nil
+	}
+	creds.APIBase = strings.TrimSpace(string(data["GITHUB_API"]))
+	if m.k8sConfig.GitHubConfigSecretName != "" {
+		configData, err := m.readSecretData(ctx, m.k8sConfig.GitHubConfigSecretName)
+		if err != nil {
+			return nil, err
+		}
+		if api := strings.TrimSpace(string(configData["GITHUB_API"])); api != "" {
+			creds.APIBase = api
+		}
+	}
+	return creds, nil
+}
+
+// readSecretData returns the data of a Secret in the session namespace. A
+// missing Secret yields empty data.
+func (m *KubernetesSessionManager) readSecretData(ctx context.Context, name string) (map[string][]byte, error) {
+	secret, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return map[string][]byte{}, nil
+		}
+		return nil, fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return secret.Data, nil
+}
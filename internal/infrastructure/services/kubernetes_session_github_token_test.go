@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/logger"
+)
+
+func newGitHubTokenTestManager(t *testing.T, mint github_pkg.MintFunc) *KubernetesSessionManager {
+	t.Helper()
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.GitHubSecretName = "github-session"
+	_, err := manager.client.CoreV1().Secrets("test-ns").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-session", Namespace: "test-ns"},
+		Data: map[string][]byte{
+			"GITHUB_APP_ID":          []byte("12"),
+			"GITHUB_INSTALLATION_ID": []byte("34"),
+			"GITHUB_APP_PEM":         []byte("pem"),
+			"REPOSITORY_RESTRICTION": []byte("true"),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	manager.githubTokensOnce.Do(func() {
+		manager.githubTokens = github_pkg.NewInstallationTokenSource(mint)
+	})
+	return manager
+}
+
+func TestSessionGitHubToken_MintsFromGitHubSecret(t *testing.T) {
+	var gotCreds github_pkg.AppCredentials
+	var gotRepo string
+	manager := newGitHubTokenTestManager(t, func(ctx context.Context, creds github_pkg.AppCredentials, repoFullName string) (*github_pkg.InstallationToken, error) {
+		gotCreds, gotRepo = creds, repoFullName
+		return &github_pkg.InstallationToken{Token: "ghs_fresh", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	session := NewKubernetesSession("gh-session", &entities.RunServerRequest{
+		UserID:   "test-user",
+		RepoInfo: &entities.RepositoryInfo{FullName: "org/repo"},
+	}, "dep", "svc", "pvc", "test-ns", 9000, nil, nil)
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	token, err := manager.SessionGitHubToken(context.Background(), session.ID())
+	if err != nil {
+		t.Fatalf("SessionGitHubToken() error = %v", err)
+	}
+	if token.Token != "ghs_fresh" {
+		t.Fatalf("Expected minted token, got %q", token.Token)
+	}
+	if gotCreds.AppID != 12 || gotCreds.InstallationID != 34 || string(gotCreds.PEM) != "pem" || !gotCreds.RestrictToRepository {
+		t.Fatalf("Unexpected credentials: %+v", gotCreds)
+	}
+	if gotRepo != "org/repo" {
+		t.Fatalf("Expected token for org/repo, got %q", gotRepo)
+	}
+}
+
+func TestSessionGitHubToken_ExplicitTokenIsNotRefreshed(t *testing.T) {
+	manager := newGitHubTokenTestManager(t, func(ctx context.Context, creds github_pkg.AppCredentials, repoFullName string) (*github_pkg.InstallationToken, error) {
+		t.Fatal("mint must not be called for sessions with an explicit token")
+		return nil, nil
+	})
+	session := NewKubernetesSession("pat-session", &entities.RunServerRequest{
+		UserID:      "test-user",
+		GithubToken: "ghp_explicit",
+	}, "dep", "svc", "pvc", "test-ns", 9000, nil, nil)
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	if _, err := manager.SessionGitHubToken(context.Background(), session.ID()); !errors.Is(err, ErrGitHubTokenUnavailable) {
+		t.Fatalf("Expected ErrGitHubTokenUnavailable, got %v", err)
+	}
+}
+
+func TestSessionGitHubToken_TeamGitHubInstance(t *testing.T) {
+	var gotCreds github_pkg.AppCredentials
+	manager := newGitHubTokenTestManager(t, func(ctx context.Context, creds github_pkg.AppCredentials, repoFullName string) (*github_pkg.InstallationToken, error) {
+		gotCreds = creds
+		return &github_pkg.InstallationToken{Token: "ghs_eu", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	manager.config.GitHubEnterprise = config.GitHubEnterpriseConfig{
+		URL:   "https://github.example.com",
+		Teams: []config.GitHubEnterpriseTeamConfig{{TeamID: "acme/eu", URL: "https://github.eu.example.com", SecretName: "github-eu"}},
+	}
+	_, err := manager.client.CoreV1().Secrets("test-ns").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-eu", Namespace: "test-ns"},
+		Data:       map[string][]byte{"GITHUB_APP_ID": []byte("56"), "GITHUB_APP_PEM": []byte("pem-eu")},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	req := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeTeam, TeamID: "acme/eu"}
+	session := NewKubernetesSession("eu-session", req, "dep", "svc", "pvc", "test-ns", 9000, nil, nil)
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	if _, err := manager.SessionGitHubToken(context.Background(), session.ID()); err != nil {
+		t.Fatalf("SessionGitHubToken() error = %v", err)
+	}
+	if gotCreds.AppID != 56 || gotCreds.APIBase != "https://github.eu.example.com/api/v3" {
+		t.Fatalf("Expected credentials of the team instance, got %+v", gotCreds)
+	}
+	if got := manager.githubSecretName(req); got != "github-eu" {
+		t.Fatalf("Expected team GitHub Secret, got %q", got)
+	}
+
+	env := map[string]string{}
+	for _, e := range manager.buildEnvVars(session, req) {
+		env[e.Name] = e.Value
+	}
+	if env["GITHUB_URL"] != "https://github.eu.example.com" || env["GITHUB_API"] != "https://github.eu.example.com/api/v3" {
+		t.Fatalf("Expected GITHUB_URL/GITHUB_API of the team instance, got %q / %q", env["GITHUB_URL"], env["GITHUB_API"])
+	}
+
+	// Other sessions use the default instance and GitHub Secret
+	creds, err := manager.GitHubAppCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GitHubAppCredentials() error = %v", err)
+	}
+	if creds.AppID != 12 || creds.APIBase != "https://github.example.com/api/v3" {
+		t.Fatalf("Expected credentials of the default instance, got %+v", creds)
+	}
+}
+
+func TestSessionToken_BoundToSession(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	req := &entities.RunServerRequest{UserID: "test-user"}
+	session := NewKubernetesSession("session-a", req, "dep", "svc", "pvc", "test-ns", 9000, nil, nil)
+
+	env := map[string]string{}
+	for _, e := range manager.buildEnvVars(session, req) {
+		env[e.Name] = e.Value
+	}
+	token := env["PROVISIONER_SESSION_TOKEN"]
+	if token == "" || token == env["PROVISIONER_TOKEN"] {
+		t.Fatalf("Expected a session token distinct from the provisioner token, got %q", token)
+	}
+	if !manager.ValidateSessionToken("session-a", token) {
+		t.Fatal("Expected the session token to be valid for its session")
+	}
+	if manager.ValidateSessionToken("session-b", token) {
+		t.Fatal("Expected the session token to be rejected for another session")
+	}
+	if manager.ValidateSessionToken("session-a", env["PROVISIONER_TOKEN"]) || manager.ValidateSessionToken("session-a", "") {
+		t.Fatal("Expected the provisioner token and an empty token to be rejected")
+	}
+
+	// The key is kept in a Secret, so a restarted proxy accepts the tokens
+	// of running sessions
+	restarted, err := NewKubernetesSessionManagerWithClient(manager.config, false, logger.NewLogger(), manager.client)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if !restarted.ValidateSessionToken("session-a", token) {
+		t.Fatal("Expected the session token to survive a proxy restart")
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	defaultHealthProbeInterval         = 30 * time.Second
+	defaultHealthProbeTimeout          = 5 * time.Second
+	defaultHealthProbeFailureThreshold = 3
+)
+
+// validateHealthProbe checks kubernetes_session.health_probe
+func validateHealthProbe(probe *config.SessionHealthProbeConfig) error {
+	for _, d := range []struct{ name, value string }{
+		{"interval", probe.Interval},
+		{"timeout", probe.Timeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(d.value); err != nil || parsed <= 0 {
+			return fmt.Errorf("kubernetes_session.health_probe.%s: invalid duration %q", d.name, d.value)
+		}
+	}
+	if probe.FailureThreshold < 0 || probe.RestartThreshold < 0 {
+		return fmt.Errorf("kubernetes_session.health_probe: thresholds must not be negative")
+	}
+	return nil
+}
+
+// healthProbeSettings returns the probe interval, timeout and failure
+// threshold with their defaults
+func healthProbeSettings(probe *config.SessionHealthProbeConfig) (interval, timeout time.Duration, threshold int) {
+	interval, timeout, threshold = defaultHealthProbeInterval, defaultHealthProbeTimeout, defaultHealthProbeFailureThreshold
+	if d, err := time.ParseDuration(probe.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	if d, err := time.ParseDuration(probe.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+	if probe.FailureThreshold > 0 {
+		threshold = probe.FailureThreshold
+	}
+	return interval, timeout, threshold
+}
+
+// Health returns the result of the health probes of the session, or nil when
+// it was not probed
+func (s *KubernetesSession) Health() *entities.SessionHealth {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.health == nil {
+		return nil
+	}
+	health := *s.health
+	return &health
+}
+
+// setHealth replaces the health of the session
+func (s *KubernetesSession) setHealth(health entities.SessionHealth) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.health = &health
+}
+
+// notProbedStatuses are the statuses of sessions whose Pod is not expected
+// to serve; their probes would only repeat what the Pod status says.
+var notProbedStatuses = map[string]bool{
+	"creating": true, "starting": true, "unhealthy": true, "paused": true, "resuming": true,
+	"evicted": true, "stopped": true, "error": true, "timeout": true,
+}
+
+// watchSessionHealth probes the agentapi server of the session until ctx is
+// done. Consecutive failures mark the session unhealthy and, with a restart
+// threshold, restart its Deployment.
+func (m *KubernetesSessionManager) watchSessionHealth(ctx context.Context, session *KubernetesSession) {
+	probe := &m.k8sConfig.HealthProbe
+	if !probe.Enabled {
+		return
+	}
+	interval, timeout, threshold := healthProbeSettings(probe)
+	client := &http.Client{Timeout: timeout}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if notProbedStatuses[session.Status()] {
+			continue
+		}
+		m.probeSessionHealth(ctx, client, session, threshold, probe.RestartThreshold)
+	}
+}
+
+// probeSessionHealth probes the session once and records the result
+func (m *KubernetesSessionManager) probeSessionHealth(ctx context.Context, client *http.Client, session *KubernetesSession, threshold, restartThreshold int) {
+	health := entities.SessionHealth{Healthy: true}
+	if previous := session.Health(); previous != nil {
+		health = *previous
+	}
+	now := time.Now().UTC()
+	health.LastProbeAt = now
+
+	agentStatus, reason, message := probeAgentAPIStatus(ctx, client, session.Addr())
+	if reason == "" {
+		if !health.Healthy {
+			m.recordEvent(session.id, entities.SessionEventRecovered, "Agent answers health probes again")
+		}
+		health.Healthy = true
+		health.AgentStatus = agentStatus
+		health.Reason, health.Message = "", ""
+		health.ConsecutiveFailures = 0
+		health.LastSuccessAt = &now
+		session.setHealth(health)
+		return
+	}
+
+	health.Reason, health.Message = reason, message
+	health.ConsecutiveFailures++
+	if health.Healthy && health.ConsecutiveFailures >= threshold {
+		health.Healthy = false
+		log.Printf("[K8S_SESSION] Session %s is unhealthy: %d failed health probes (%s: %s)", session.id, health.ConsecutiveFailures, reason, message)
+		m.recordEvent(session.id, entities.SessionEventUnhealthy, "%d consecutive health probes failed: %s: %s", health.ConsecutiveFailures, reason, message)
+	}
+	if restartThreshold > 0 && health.ConsecutiveFailures >= restartThreshold {
+		if !m.canRestartSession(session) {
+			if health.ConsecutiveFailures == restartThreshold {
+				log.Printf("[K8S_SESSION] Unhealthy session %s is not restarted: only sessions running a Deployment (kubernetes_session.pvc_enabled) can be restarted", session.id)
+			}
+		} else if err := m.restartSessionWorkload(ctx, session); err != nil {
+			log.Printf("[K8S_SESSION] Failed to restart unhealthy session %s: %v", session.id, err)
+		} else {
+			log.Printf("[K8S_SESSION] Restarted session %s after %d failed health probes", session.id, health.ConsecutiveFailures)
+			m.recordEvent(session.id, entities.SessionEventRestarted, "Restarted after %d failed health probes: %s", health.ConsecutiveFailures, reason)
+			health.Restarts++
+			health.LastRestartAt = &now
+			health.ConsecutiveFailures = 0
+		}
+	}
+	session.setHealth(health)
+}
+
+// probeAgentAPIStatus gets the status of the agentapi server at addr. A
+// failed probe returns one of the entities.HealthReason* reasons.
+func probeAgentAPIStatus(ctx context.Context, client *http.Client, addr string) (status, reason, message string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/status", addr), nil)
+	if err != nil {
+		return "", entities.HealthReasonUnreachable, err.Error()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return "", entities.HealthReasonTimeout, err.Error()
+		}
+		return "", entities.HealthReasonUnreachable, err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", entities.HealthReasonHTTPError, fmt.Sprintf("GET /status returned %d", resp.StatusCode)
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Status == "" {
+		return "", entities.HealthReasonInvalidResponse, "GET /status did not return an agent status"
+	}
+	return body.Status, "", ""
+}
+
+// canRestartSession reports whether the session runs a Deployment. Bare
+// Pods and Jobs are not recreated once their Pod is gone.
+func (m *KubernetesSessionManager) canRestartSession(session *KubernetesSession) bool {
+	return !session.RunsAsJob() && m.isPVCEnabled()
+}
+
+// restartSessionWorkload restarts the Pods of the session's Deployment like
+// "kubectl rollout restart"
+func (m *KubernetesSessionManager) restartSessionWorkload(ctx context.Context, session *KubernetesSession) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`, time.Now().UTC().Format(time.RFC3339))
+	_, err := m.client.AppsV1().Deployments(session.Namespace()).Patch(ctx, session.DeploymentName(), types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
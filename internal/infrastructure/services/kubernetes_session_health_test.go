@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateHealthProbe(t *testing.T) {
+	valid := []config.SessionHealthProbeConfig{
+		{},
+		{Enabled: true, Interval: "10s", Timeout: "2s", FailureThreshold: 2, RestartThreshold: 5},
+	}
+	for _, probe := range valid {
+		if err := validateHealthProbe(&probe); err != nil {
+			t.Errorf("validateHealthProbe(%+v) = %v", probe, err)
+		}
+	}
+	invalid := []config.SessionHealthProbeConfig{
+		{Interval: "soon"},
+		{Timeout: "0s"},
+		{RestartThreshold: -1},
+	}
+	for _, probe := range invalid {
+		if err := validateHealthProbe(&probe); err == nil {
+			t.Errorf("validateHealthProbe(%+v) accepted", probe)
+		}
+	}
+}
+
+// newHealthTestSession returns a session served by handler
+func newHealthTestSession(t *testing.T, handler http.HandlerFunc) *KubernetesSession {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	session := NewKubernetesSession("test-session", &entities.RunServerRequest{UserID: "test-user"},
+		"agentapi-session-test-session", "agentapi-session-test-session-svc", "agentapi-session-test-session-pvc",
+		"test-ns", port, nil, nil)
+	session.setRouteHost(u.Hostname())
+	return session
+}
+
+func TestProbeSessionHealthClassifiesFailures(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	ctx := context.Background()
+
+	var mode atomic.Value
+	mode.Store("ok")
+	session := newHealthTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		switch mode.Load() {
+		case "ok":
+			_, _ = w.Write([]byte(`{"status":"stable"}`))
+		case "error":
+			w.WriteHeader(http.StatusBadGateway)
+		case "garbage":
+			_, _ = w.Write([]byte(`<html>`))
+		case "slow":
+			time.Sleep(300 * time.Millisecond)
+		}
+	})
+
+	manager.probeSessionHealth(ctx, client, session, 2, 0)
+	health := session.Health()
+	if health == nil || !health.Healthy || health.AgentStatus != "stable" || health.LastSuccessAt == nil {
+		t.Fatalf("health after success = %+v", health)
+	}
+
+	for _, tc := range []struct{ mode, reason string }{
+		{"error", entities.HealthReasonHTTPError},
+		{"garbage", entities.HealthReasonInvalidResponse},
+		{"slow", entities.HealthReasonTimeout},
+	} {
+		mode.Store(tc.mode)
+		manager.probeSessionHealth(ctx, client, session, 2, 0)
+		if got := session.Health().Reason; got != tc.reason {
+			t.Errorf("reason of %s = %q, want %q", tc.mode, got, tc.reason)
+		}
+	}
+	health = session.Health()
+	if health.Healthy || health.ConsecutiveFailures != 3 {
+		t.Errorf("health after 3 failures = %+v", health)
+	}
+
+	mode.Store("ok")
+	manager.probeSessionHealth(ctx, client, session, 2, 0)
+	if health := session.Health(); !health.Healthy || health.ConsecutiveFailures != 0 || health.Reason != "" {
+		t.Errorf("health after recovery = %+v", health)
+	}
+
+	unreachable := NewKubernetesSession("gone", &entities.RunServerRequest{}, "d", "s", "p", "test-ns", 1, nil, nil)
+	unreachable.setRouteHost("127.0.0.1")
+	manager.probeSessionHealth(ctx, client, unreachable, 1, 0)
+	if health := unreachable.Health(); health.Healthy || health.Reason != entities.HealthReasonUnreachable {
+		t.Errorf("health of unreachable session = %+v", health)
+	}
+}
+
+func TestProbeSessionHealthRestartsDeployment(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	client := &http.Client{Timeout: time.Second}
+	ctx := context.Background()
+	session := newHealthTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	deployments := manager.client.AppsV1().Deployments("test-ns")
+	if _, err := deployments.Create(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: session.DeploymentName(), Namespace: "test-ns"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager.probeSessionHealth(ctx, client, session, 1, 2)
+	deployment, _ := deployments.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if _, ok := deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"]; ok {
+		t.Fatal("restarted before the restart threshold")
+	}
+
+	manager.probeSessionHealth(ctx, client, session, 1, 2)
+	deployment, _ = deployments.Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if _, ok := deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"]; !ok {
+		t.Fatal("not restarted at the restart threshold")
+	}
+	health := session.Health()
+	if health.Restarts != 1 || health.LastRestartAt == nil || health.ConsecutiveFailures != 0 || health.Healthy {
+		t.Errorf("health after restart = %+v", health)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	// imageAnnotation records the image requested with params.image so that
+	// a recreated Pod runs the same image after a proxy restart.
+	imageAnnotation = "agentapi.proxy/image"
+	// defaultAgentImageKey is the agent_images entry of sessions without an
+	// agent type.
+	defaultAgentImageKey = "claude-agentapi"
+	// maxImageLength bounds params.image.
+	maxImageLength = 512
+)
+
+// imageReferencePattern accepts registry/repository[:tag][@digest] image
+// references without whitespace or shell metacharacters.
+var imageReferencePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`)
+
+// validateImageConfig rejects malformed kubernetes_session team images.
+func validateImageConfig(k8sConfig *config.KubernetesSessionConfig) error {
+	for agentType, image := range k8sConfig.AgentImages {
+		if image == "" {
+			return fmt.Errorf("kubernetes_session.agent_images.%s: image is empty", agentType)
+		}
+	}
+	seen := make(map[string]bool, len(k8sConfig.TeamImages))
+	for i, team := range k8sConfig.TeamImages {
+		if team.TeamID == "" {
+			return fmt.Errorf("kubernetes_session.team_images[%d]: team_id is required", i)
+		}
+		if seen[team.TeamID] {
+			return fmt.Errorf("kubernetes_session.team_images[%d]: duplicate team_id %q", i, team.TeamID)
+		}
+		seen[team.TeamID] = true
+		for agentType, image := range team.AgentImages {
+			if image == "" {
+				return fmt.Errorf("kubernetes_session.team_images[%d].agent_images.%s: image is empty", i, agentType)
+			}
+		}
+	}
+	return nil
+}
+
+// teamImage returns the image override of the team of a team-scoped
+// session, or nil.
+func (m *KubernetesSessionManager) teamImage(req *entities.RunServerRequest) *config.TeamImage {
+	live := m.liveConfig()
+	if live == nil || req == nil || req.Scope != entities.ScopeTeam || req.TeamID == "" {
+		return nil
+	}
+	for i := range live.TeamImages {
+		if live.TeamImages[i].TeamID == req.TeamID {
+			return &live.TeamImages[i]
+		}
+	}
+	return nil
+}
+
+// sessionImage returns the image of the agent container of a session: the
+// requested image, then the image of the team for the agent type, the image
+// of the team, the image of the agent type and finally the default image.
+func (m *KubernetesSessionManager) sessionImage(req *entities.RunServerRequest) string {
+	if req == nil {
+		return m.k8sConfig.Image
+	}
+	if req.Image != "" {
+		return req.Image
+	}
+	agentType := req.AgentType
+	if agentType == "" {
+		agentType = defaultAgentImageKey
+	}
+	if team := m.teamImage(req); team != nil {
+		if image := team.AgentImages[agentType]; image != "" {
+			return image
+		}
+		if team.Image != "" {
+			return team.Image
+		}
+	}
+	if image := m.liveConfig().AgentImages[agentType]; image != "" {
+		return image
+	}
+	return m.k8sConfig.Image
+}
+
+// imageAllowed reports whether image matches an allowlist entry. An entry
+// ending in "*" matches every image starting with the rest.
+func imageAllowed(image string, allowlist []string) bool {
+	for _, entry := range allowlist {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if prefix != "" && strings.HasPrefix(image, prefix) {
+				return true
+			}
+			continue
+		}
+		if image == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImage rejects malformed image requests and images on neither the
+// allowlist of the proxy nor that of the team of the session.
+func (m *KubernetesSessionManager) checkImage(req *entities.RunServerRequest) error {
+	if req.Image == "" {
+		return nil
+	}
+	if len(req.Image) > maxImageLength || !imageReferencePattern.MatchString(req.Image) {
+		return fmt.Errorf("%w: %q is not an image reference", entities.ErrInvalidImage, req.Image)
+	}
+	if imageAllowed(req.Image, m.liveConfig().AllowedImages) {
+		return nil
+	}
+	if team := m.teamImage(req); team != nil && imageAllowed(req.Image, team.AllowedImages) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not on the image allowlist", entities.ErrImageNotAllowed, req.Image)
+}
+
+// usesStockImage reports whether a session runs the image of stock sessions.
+func (m *KubernetesSessionManager) usesStockImage(req *entities.RunServerRequest) bool {
+	return m.sessionImage(req) == m.k8sConfig.Image
+}
+
+// restoreImageFromService reads the requested image of a restored session.
+func restoreImageFromService(svc *corev1.Service) string {
+	return svc.Annotations[imageAnnotation]
+}
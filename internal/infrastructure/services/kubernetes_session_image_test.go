@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateImageConfig(t *testing.T) {
+	valid := &config.KubernetesSessionConfig{
+		AgentImages: map[string]string{"claude": "ghcr.io/example/claude:1"},
+		TeamImages:  []config.TeamImage{{TeamID: "org/ml", Image: "ghcr.io/example/ml:1"}},
+	}
+	if err := validateImageConfig(valid); err != nil {
+		t.Fatalf("validateImageConfig = %v", err)
+	}
+	for name, cfg := range map[string]*config.KubernetesSessionConfig{
+		"empty agent image": {AgentImages: map[string]string{"claude": ""}},
+		"missing team":      {TeamImages: []config.TeamImage{{Image: "x"}}},
+		"duplicate team":    {TeamImages: []config.TeamImage{{TeamID: "a"}, {TeamID: "a"}}},
+		"empty team image":  {TeamImages: []config.TeamImage{{TeamID: "a", AgentImages: map[string]string{"claude": ""}}}},
+	} {
+		if err := validateImageConfig(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestSessionImage(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.Image = "ghcr.io/example/default:1"
+	manager.k8sConfig.AgentImages = map[string]string{
+		"claude":          "ghcr.io/example/claude:1",
+		"claude-agentapi": "ghcr.io/example/default:1",
+	}
+	manager.k8sConfig.TeamImages = []config.TeamImage{
+		{TeamID: "org/ml", Image: "ghcr.io/example/ml:1", AgentImages: map[string]string{"claude": "ghcr.io/example/ml-claude:1"}},
+	}
+
+	tests := []struct {
+		name string
+		req  *entities.RunServerRequest
+		want string
+	}{
+		{"default", &entities.RunServerRequest{}, "ghcr.io/example/default:1"},
+		{"agent type", &entities.RunServerRequest{AgentType: "claude"}, "ghcr.io/example/claude:1"},
+		{"unknown agent type", &entities.RunServerRequest{AgentType: "codex"}, "ghcr.io/example/default:1"},
+		{"team agent type", &entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "org/ml", AgentType: "claude"}, "ghcr.io/example/ml-claude:1"},
+		{"team", &entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "org/ml"}, "ghcr.io/example/ml:1"},
+		{"user of the team", &entities.RunServerRequest{TeamID: "org/ml", AgentType: "claude"}, "ghcr.io/example/claude:1"},
+		{"request", &entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "org/ml", Image: "ghcr.io/example/custom:2"}, "ghcr.io/example/custom:2"},
+	}
+	for _, tt := range tests {
+		if got := manager.sessionImage(tt.req); got != tt.want {
+			t.Errorf("%s: sessionImage = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if !manager.usesStockImage(&entities.RunServerRequest{}) || manager.usesStockImage(&entities.RunServerRequest{AgentType: "claude"}) {
+		t.Error("usesStockImage does not follow the resolved image")
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.AllowedImages = []string{"ghcr.io/example/agent:1", "ghcr.io/example/agents/*"}
+	manager.k8sConfig.TeamImages = []config.TeamImage{{TeamID: "org/ml", AllowedImages: []string{"ghcr.io/ml/*"}}}
+	team := func(image string) *entities.RunServerRequest {
+		return &entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "org/ml", Image: image}
+	}
+
+	tests := []struct {
+		name string
+		req  *entities.RunServerRequest
+		want error
+	}{
+		{"no image", &entities.RunServerRequest{}, nil},
+		{"exact", &entities.RunServerRequest{Image: "ghcr.io/example/agent:1"}, nil},
+		{"other tag", &entities.RunServerRequest{Image: "ghcr.io/example/agent:2"}, entities.ErrImageNotAllowed},
+		{"prefix", &entities.RunServerRequest{Image: "ghcr.io/example/agents/codex@sha256:abc"}, nil},
+		{"team allowlist", team("ghcr.io/ml/trainer:3"), nil},
+		{"team allowlist for a user", &entities.RunServerRequest{TeamID: "org/ml", Image: "ghcr.io/ml/trainer:3"}, entities.ErrImageNotAllowed},
+		{"whitespace", &entities.RunServerRequest{Image: "ghcr.io/example/agent:1 --privileged"}, entities.ErrInvalidImage},
+		{"too long", &entities.RunServerRequest{Image: "ghcr.io/example/agents/" + strings.Repeat("a", maxImageLength)}, entities.ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		err := manager.checkImage(tt.req)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkImage = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	manager.k8sConfig.AllowedImages = nil
+	if err := manager.checkImage(&entities.RunServerRequest{Image: "ghcr.io/example/agent:1"}); !errors.Is(err, entities.ErrImageNotAllowed) {
+		t.Errorf("without an allowlist: checkImage = %v", err)
+	}
+}
+
+func TestBuildDeploymentUsesSessionImage(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.AgentImages = map[string]string{"claude": "ghcr.io/example/claude:1"}
+	session := newWorkloadTestSession()
+
+	deployment, err := manager.buildDeployment(context.Background(), session, &entities.RunServerRequest{UserID: "test-user", AgentType: "claude"})
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	main := findContainerByName(deployment.Spec.Template.Spec.Containers, mainContainerName)
+	if main == nil || main.Image != "ghcr.io/example/claude:1" {
+		t.Fatalf("agent container = %+v, want the claude image", main)
+	}
+
+	// Stock sessions always run the default image.
+	session.isStock = true
+	deployment, err = manager.buildDeployment(context.Background(), session, &entities.RunServerRequest{UserID: "test-user", AgentType: "claude"})
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	if main := findContainerByName(deployment.Spec.Template.Spec.Containers, mainContainerName); main.Image != manager.k8sConfig.Image {
+		t.Errorf("stock image = %q, want %q", main.Image, manager.k8sConfig.Image)
+	}
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+const (
+	// workloadAnnotation records the workload kind of sessions that do not
+	// use the configured default, so restored sessions are watched correctly.
+	workloadAnnotation = "agentapi.proxy/workload"
+	workloadJob        = "job"
+	// completionAnnotation stores the SessionCompletion of a finished Job
+	// session as JSON until the session is deleted.
+	completionAnnotation = "agentapi.proxy/completion"
+
+	// SessionCompletionName is the artifact name of the completion record.
+	SessionCompletionName = "completion.json"
+	// maxJobLogBytes caps the stored log of one container.
+	maxJobLogBytes = 4 << 20
+	// oneshotJobStopHookCommand ends the Job of a oneshot session when the
+	// agent stops, instead of deleting the session from inside the Pod.
+	oneshotJobStopHookCommand = "agentapi-proxy client complete-session"
+)
+
+// SessionJobPrefix is the artifact key prefix of the completion record and
+// container logs of a oneshot Job session.
+func SessionJobPrefix(sessionID string) string {
+	return "session-jobs/" + sessionID + "/"
+}
+
+// oneshotJobEnabled reports whether oneshot sessions run as Jobs.
+func (m *KubernetesSessionManager) oneshotJobEnabled() bool {
+	return m.k8sConfig != nil && m.k8sConfig.OneshotJobEnabled
+}
+
+// oneshotJobTTL is how long a finished Job session is kept.
+func (m *KubernetesSessionManager) oneshotJobTTL() time.Duration {
+	return time.Duration(max(m.k8sConfig.OneshotJobTTLSecondsAfterFinished, 0)) * time.Second
+}
+
+// RunsAsJob reports whether the session workload is a Job.
+func (s *KubernetesSession) RunsAsJob() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.runsAsJob
+}
+
+func (s *KubernetesSession) setRunsAsJob(job bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.runsAsJob = job
+}
+
+// Completion returns a copy of the completion of a finished Job session, or nil.
+func (s *KubernetesSession) Completion() *entities.SessionCompletion {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.completion == nil {
+		return nil
+	}
+	completion := *s.completion
+	return &completion
+}
+
+func (s *KubernetesSession) setCompletion(completion *entities.SessionCompletion) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.completion = completion
+}
+
+// restoreJobFromService restores the workload kind, completion and status
+// of a restored Job session.
+func (m *KubernetesSessionManager) restoreJobFromService(ctx context.Context, session *KubernetesSession, svc *corev1.Service) {
+	if svc.Annotations[workloadAnnotation] != workloadJob {
+		return
+	}
+	session.setRunsAsJob(true)
+	restoreCompletionFromService(session, svc)
+	session.SetStatus(m.jobSessionStatus(ctx, session))
+}
+
+// restoreCompletionFromService picks up the completion recorded by the
+// replica that watched the Job finish.
+func restoreCompletionFromService(session *KubernetesSession, svc *corev1.Service) {
+	value := svc.Annotations[completionAnnotation]
+	if value == "" || session.Completion() != nil {
+		return
+	}
+	var completion entities.SessionCompletion
+	if err := json.Unmarshal([]byte(value), &completion); err != nil {
+		log.Printf("[K8S_SESSION] Ignoring invalid completion annotation on Service %s: %v", svc.Name, err)
+		return
+	}
+	session.setCompletion(&completion)
+	session.SetStatus(completionStatus(&completion))
+}
+
+func completionStatus(completion *entities.SessionCompletion) string {
+	if completion.Succeeded {
+		return entities.SessionStatusCompleted
+	}
+	return entities.SessionStatusFailed
+}
+
+// createJob creates the Job of a oneshot session.
+func (m *KubernetesSessionManager) createJob(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest) error {
+	job, err := m.buildJob(ctx, session, req)
+	if err != nil {
+		return err
+	}
+	if err := m.mirrorPodSpecDependencies(ctx, session.Namespace(), &job.Spec.Template.Spec); err != nil {
+		return err
+	}
+	_, err = m.client.BatchV1().Jobs(session.Namespace()).Create(ctx, job, metav1.CreateOptions{})
+	return err
+}
+
+// buildJob builds the Job of a oneshot session from the session Pod
+// template. Sidecars run as native sidecars (init containers that keep
+// running), so the Pod, and with it the Job, finishes when the agent
+// container exits.
+func (m *KubernetesSessionManager) buildJob(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest) (*batchv1.Job, error) {
+	deployment, err := m.buildDeployment(ctx, session, req)
+	if err != nil {
+		return nil, err
+	}
+	template := deployment.Spec.Template
+	spec := template.Spec
+	spec.RestartPolicy = corev1.RestartPolicyNever
+	always := corev1.ContainerRestartPolicyAlways
+	for _, sidecar := range spec.Containers[1:] {
+		sidecar.RestartPolicy = &always
+		spec.InitContainers = append(spec.InitContainers, sidecar)
+	}
+	spec.Containers = spec.Containers[:1]
+	template.Spec = spec
+
+	backoffLimit := int32(max(m.k8sConfig.OneshotJobBackoffLimit, 0))
+	ttl := int32(m.oneshotJobTTL() / time.Second)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            session.DeploymentName(),
+			Namespace:       session.Namespace(),
+			Labels:          deployment.Labels,
+			Annotations:     deployment.Annotations,
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template:                template,
+		},
+	}
+	if deadline := int64(m.k8sConfig.OneshotJobActiveDeadlineSeconds); deadline > 0 {
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	return job, nil
+}
+
+// isJobReady reports whether the Pod of a Job session is ready.
+func (m *KubernetesSessionManager) isJobReady(ctx context.Context, session *KubernetesSession) (bool, error) {
+	job, err := m.client.BatchV1().Jobs(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return job.Status.Ready != nil && *job.Status.Ready > 0, nil
+}
+
+// watchJobStatus follows a running Job session until its Job finishes.
+func (m *KubernetesSessionManager) watchJobStatus(ctx context.Context, session *KubernetesSession) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		if m.finishJobSessionIfDone(ctx, session) {
+			return
+		}
+		m.refreshRouteHost(ctx, session)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishJobSessionIfDone completes the session once its Job has finished:
+// it captures the container logs, records the completion and deletes the
+// session after oneshot_job_ttl_seconds_after_finished. It reports whether
+// the Job has finished.
+func (m *KubernetesSessionManager) finishJobSessionIfDone(ctx context.Context, session *KubernetesSession) bool {
+	completion := session.Completion()
+	if completion == nil {
+		job, err := m.client.BatchV1().Jobs(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			// Removed behind our back, e.g. by its TTL while no proxy was running
+			completion = &entities.SessionCompletion{Reason: "JobNotFound", Message: "the session Job no longer exists"}
+		case err != nil:
+			log.Printf("[K8S_SESSION] Failed to get job of session %s: %v", session.id, err)
+			return false
+		default:
+			if completion = jobCompletion(job); completion == nil {
+				return false
+			}
+		}
+		m.completeJobSession(ctx, session, completion)
+	}
+
+	wait := time.Until(completion.FinishedAt.Add(m.oneshotJobTTL()))
+	select {
+	case <-ctx.Done():
+		return true
+	case <-time.After(wait):
+	}
+	log.Printf("[K8S_SESSION] Deleting finished job session %s", session.id)
+	if err := m.DeleteSession(session.id); err != nil {
+		log.Printf("[K8S_SESSION] Failed to delete finished job session %s: %v", session.id, err)
+	}
+	return true
+}
+
+// jobCompletion returns the completion of a finished Job, or nil while it runs.
+func jobCompletion(job *batchv1.Job) *entities.SessionCompletion {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return &entities.SessionCompletion{Succeeded: true, FinishedAt: cond.LastTransitionTime.UTC()}
+		case batchv1.JobFailed:
+			return &entities.SessionCompletion{Reason: cond.Reason, Message: cond.Message, FinishedAt: cond.LastTransitionTime.UTC()}
+		}
+	}
+	return nil
+}
+
+// completeJobSession fills in and records the completion of a session.
+func (m *KubernetesSessionManager) completeJobSession(ctx context.Context, session *KubernetesSession, completion *entities.SessionCompletion) {
+	// Finish recording even when the watcher is shutting down; the logs are
+	// gone once the Job is removed.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+
+	completion.SessionID = session.id
+	completion.UserID = session.UserID()
+	completion.Scope = session.Scope()
+	completion.TeamID = session.TeamID()
+	if completion.FinishedAt.IsZero() {
+		completion.FinishedAt = time.Now().UTC()
+	}
+
+	if pod, err := m.sessionPod(ctx, session); err == nil {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == mainContainerName && status.State.Terminated != nil {
+				exitCode := status.State.Terminated.ExitCode
+				completion.ExitCode = &exitCode
+			}
+		}
+		if m.artifactStore != nil {
+			completion.Logs = m.captureJobLogs(ctx, session, pod)
+		}
+	}
+
+	if m.artifactStore != nil {
+		data, err := json.Marshal(completion)
+		if err == nil {
+			err = m.artifactStore.PutArtifact(ctx, SessionJobPrefix(session.id)+SessionCompletionName, "application/json", bytes.NewReader(data))
+		}
+		if err != nil {
+			log.Printf("[K8S_SESSION] Failed to store completion of session %s: %v", session.id, err)
+		}
+	}
+	if data, err := json.Marshal(completion); err == nil {
+		if err := m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+			completionAnnotation: string(data),
+		}); err != nil {
+			log.Printf("[K8S_SESSION] Failed to record completion of session %s: %v", session.id, err)
+		}
+	}
+
+	session.setCompletion(completion)
+	session.SetStatus(completionStatus(completion))
+	if completion.Succeeded {
+		m.recordEvent(session.id, entities.SessionEventJobCompleted, "Job completed")
+	} else {
+		m.recordEvent(session.id, entities.SessionEventJobFailed, "Job failed: %s %s", completion.Reason, completion.Message)
+	}
+	log.Printf("[K8S_SESSION] Job session %s finished (succeeded=%t reason=%q)", session.id, completion.Succeeded, completion.Reason)
+}
+
+// captureJobLogs stores the logs of every container of pod as artifacts and
+// returns their names.
+func (m *KubernetesSessionManager) captureJobLogs(ctx context.Context, session *KubernetesSession, pod *corev1.Pod) []string {
+	var names []string
+	for _, container := range podContainerNames(pod) {
+		stream, err := m.client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container}).Stream(ctx)
+		if err != nil {
+			log.Printf("[K8S_SESSION] Failed to read %s logs of session %s: %v", container, session.id, err)
+			continue
+		}
+		out, err := io.ReadAll(io.LimitReader(stream, maxJobLogBytes))
+		_ = stream.Close()
+		if err != nil {
+			log.Printf("[K8S_SESSION] Failed to read %s logs of session %s: %v", container, session.id, err)
+		}
+		name := container + ".log"
+		if err := m.artifactStore.PutArtifact(ctx, SessionJobPrefix(session.id)+name, "text/plain; charset=utf-8", bytes.NewReader(out)); err != nil {
+			log.Printf("[K8S_SESSION] Failed to store %s logs of session %s: %v", container, session.id, err)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// jobSessionStatus returns the status of a restored Job session.
+func (m *KubernetesSessionManager) jobSessionStatus(ctx context.Context, session *KubernetesSession) string {
+	if completion := session.Completion(); completion != nil {
+		return completionStatus(completion)
+	}
+	job, err := m.client.BatchV1().Jobs(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "stopped"
+		}
+		return "unknown"
+	}
+	if completion := jobCompletion(job); completion != nil {
+		return completionStatus(completion)
+	}
+	if job.Status.Ready != nil && *job.Status.Ready > 0 {
+		return "active"
+	}
+	return "starting"
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func newJobTestManager(t *testing.T) *KubernetesSessionManager {
+	t.Helper()
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.OneshotJobEnabled = true
+	manager.k8sConfig.OneshotJobBackoffLimit = 1
+	manager.k8sConfig.OneshotJobActiveDeadlineSeconds = 3600
+	manager.k8sConfig.OneshotJobTTLSecondsAfterFinished = 600
+	return manager
+}
+
+func newJobTestSession() *KubernetesSession {
+	session := newWorkloadTestSession()
+	session.Request().Oneshot = true
+	session.Request().Browser = &entities.BrowserParams{Enabled: true}
+	session.runsAsJob = true
+	return session
+}
+
+func TestBuildJobRunsSidecarsNatively(t *testing.T) {
+	manager := newJobTestManager(t)
+	session := newJobTestSession()
+
+	job, err := manager.buildJob(context.Background(), session, session.Request())
+	if err != nil {
+		t.Fatalf("buildJob: %v", err)
+	}
+	if job.Name != session.DeploymentName() {
+		t.Errorf("name = %q, want %q", job.Name, session.DeploymentName())
+	}
+	if *job.Spec.BackoffLimit != 1 || *job.Spec.ActiveDeadlineSeconds != 3600 || *job.Spec.TTLSecondsAfterFinished != 600 {
+		t.Errorf("backoffLimit/activeDeadlineSeconds/ttlSecondsAfterFinished = %d/%d/%d, want 1/3600/600",
+			*job.Spec.BackoffLimit, *job.Spec.ActiveDeadlineSeconds, *job.Spec.TTLSecondsAfterFinished)
+	}
+
+	spec := job.Spec.Template.Spec
+	if spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("restartPolicy = %q, want Never", spec.RestartPolicy)
+	}
+	if len(spec.Containers) != 1 || spec.Containers[0].Name != mainContainerName {
+		t.Fatalf("containers = %v, want only %s", podContainerNames(&corev1.Pod{Spec: corev1.PodSpec{Containers: spec.Containers}}), mainContainerName)
+	}
+	sidecars := 0
+	for _, c := range spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars++
+		}
+	}
+	if sidecars == 0 {
+		t.Errorf("init containers = %+v, want the browser as a native sidecar", spec.InitContainers)
+	}
+}
+
+func TestFinishJobSessionRecordsCompletion(t *testing.T) {
+	manager := newJobTestManager(t)
+	manager.SetArtifactStore(NewFilesystemAssetStore(t.TempDir(), ""))
+	session := newJobTestSession()
+	ctx := context.Background()
+
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService: %v", err)
+	}
+	if err := manager.createJob(ctx, session, session.Request()); err != nil {
+		t.Fatalf("createJob: %v", err)
+	}
+
+	// Still running
+	if manager.finishJobSessionIfDone(ctx, session) {
+		t.Fatal("finishJobSessionIfDone() = true for a running Job")
+	}
+
+	job, err := manager.client.BatchV1().Jobs("test-ns").Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded"}}
+	if _, err := manager.client.BatchV1().Jobs("test-ns").UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update job status: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      session.DeploymentName() + "-abcde",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"agentapi.proxy/session-id": session.ID()},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: mainContainerName}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  mainContainerName,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137}},
+		}}},
+	}
+	if _, err := manager.client.CoreV1().Pods("test-ns").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create pod: %v", err)
+	}
+
+	// A cancelled context stops the wait before the session is deleted
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if !manager.finishJobSessionIfDone(cancelled, session) {
+		t.Fatal("finishJobSessionIfDone() = false for a failed Job")
+	}
+
+	completion := session.Completion()
+	if completion == nil || completion.Succeeded || completion.Reason != "DeadlineExceeded" {
+		t.Fatalf("completion = %+v, want a DeadlineExceeded failure", completion)
+	}
+	if completion.ExitCode == nil || *completion.ExitCode != 137 {
+		t.Errorf("exit code = %v, want 137", completion.ExitCode)
+	}
+	if !slices.Equal(completion.Logs, []string{"agentapi.log"}) {
+		t.Errorf("logs = %v, want [agentapi.log]", completion.Logs)
+	}
+	if session.Status() != entities.SessionStatusFailed {
+		t.Errorf("status = %q, want %q", session.Status(), entities.SessionStatusFailed)
+	}
+
+	body, err := manager.artifactStore.OpenArtifact(ctx, SessionJobPrefix(session.ID())+SessionCompletionName)
+	if err != nil {
+		t.Fatalf("open completion: %v", err)
+	}
+	defer func() { _ = body.Close() }()
+	data, _ := io.ReadAll(body)
+	var stored entities.SessionCompletion
+	if err := json.Unmarshal(data, &stored); err != nil || stored.UserID != "test-user" {
+		t.Errorf("stored completion = %s (%v), want the session owner", data, err)
+	}
+
+	// Other replicas pick the completion up from the Service
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	restored := newWorkloadTestSession()
+	manager.restoreJobFromService(ctx, restored, svc)
+	if !restored.RunsAsJob() || restored.Completion() == nil || restored.Status() != entities.SessionStatusFailed {
+		t.Errorf("restored session runsAsJob=%t completion=%+v status=%q", restored.RunsAsJob(), restored.Completion(), restored.Status())
+	}
+}
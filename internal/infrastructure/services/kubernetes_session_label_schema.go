@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+const (
+	// defaultLabelSchemaMigrationInterval is how often session objects are
+	// relabeled when no interval is configured.
+	defaultLabelSchemaMigrationInterval = time.Hour
+	// labelSchemaMigrationDelay postpones the first run after startup, so
+	// that it does not compete with the restore of sessions.
+	labelSchemaMigrationDelay = time.Minute
+	// labelSchemaSelector selects the Services of all sessions, whatever
+	// labels of the current schema they lack. Stock sessions are relabeled
+	// when they are adopted.
+	labelSchemaSelector = "app.kubernetes.io/managed-by=agentapi-proxy,agentapi.proxy/session-id,!agentapi.proxy/stock"
+)
+
+// LabelSchemaMigration is a session object relabeled to the current label
+// schema.
+type LabelSchemaMigration struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Labels are the labels that were added or corrected
+	Labels map[string]string `json:"labels"`
+}
+
+// LabelSchemaReport is the result of a run of the label schema migration.
+type LabelSchemaReport struct {
+	StartedAt time.Time `json:"started_at"`
+	DryRun    bool      `json:"dry_run"`
+	// Checked is the number of session Services checked
+	Checked  int                    `json:"checked"`
+	Migrated []LabelSchemaMigration `json:"migrated"`
+	Errors   []string               `json:"errors,omitempty"`
+}
+
+// sessionSchemaLabels returns the labels of the current schema that can be
+// derived from the labels and annotations of a session Service. Selectors
+// of session lists rely on them:
+//   - app.kubernetes.io/name, for the base selector of all sessions
+//   - agentapi.proxy/scope, for team session lists; sessions created
+//     before scopes existed are user sessions
+//   - agentapi.proxy/team-id-hash, for lists of a team, from the team-id
+//     annotation
+//   - agentapi.proxy/agent-type, from the agent-type annotation
+func sessionSchemaLabels(labels, annotations map[string]string) map[string]string {
+	schema := map[string]string{
+		"app.kubernetes.io/name": "agentapi-session",
+		"agentapi.proxy/scope":   string(entities.ScopeUser),
+	}
+	if scope := labels["agentapi.proxy/scope"]; scope != "" {
+		schema["agentapi.proxy/scope"] = scope
+	}
+	if teamID := annotations["agentapi.proxy/team-id"]; teamID != "" {
+		schema["agentapi.proxy/team-id-hash"] = hashTeamID(teamID)
+	}
+	if agentType := annotations["agentapi.proxy/agent-type"]; agentType != "" {
+		schema["agentapi.proxy/agent-type"] = sanitizeLabelValue(agentType)
+	}
+	return schema
+}
+
+// staleLabels returns the labels of schema that labels lacks or holds
+// with a different value.
+func staleLabels(labels, schema map[string]string) map[string]string {
+	stale := make(map[string]string)
+	for key, value := range schema {
+		if labels[key] != value {
+			stale[key] = value
+		}
+	}
+	return stale
+}
+
+// labelsPatch builds a merge patch that sets labels on an object.
+func labelsPatch(labels map[string]string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": labels},
+	})
+}
+
+// MigrateSessionLabelSchema relabels the session Services in namespaces,
+// and their Deployments, to the current label schema, so that session
+// lists filtered by label find sessions created by older versions. Only
+// object labels are changed: the Pod template of a Deployment is left
+// alone so that no session Pod is restarted. With dryRun, the report lists
+// the objects that would be relabeled without changing them.
+func MigrateSessionLabelSchema(ctx context.Context, client kubernetes.Interface, namespaces []string, dryRun bool) *LabelSchemaReport {
+	report := &LabelSchemaReport{StartedAt: time.Now().UTC(), DryRun: dryRun, Migrated: []LabelSchemaMigration{}}
+	for _, namespace := range namespaces {
+		svcs, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSchemaSelector})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("list services in %s: %v", namespace, err))
+			continue
+		}
+		for i := range svcs.Items {
+			svc := &svcs.Items[i]
+			if svc.DeletionTimestamp != nil {
+				continue
+			}
+			report.Checked++
+			schema := sessionSchemaLabels(svc.Labels, svc.Annotations)
+
+			if stale := staleLabels(svc.Labels, schema); len(stale) > 0 {
+				if err := patchLabels(dryRun, stale, func(patch []byte) error {
+					_, err := client.CoreV1().Services(namespace).Patch(ctx, svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+					return err
+				}); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("relabel service %s/%s: %v", namespace, svc.Name, err))
+				} else {
+					report.Migrated = append(report.Migrated, LabelSchemaMigration{Kind: "Service", Namespace: namespace, Name: svc.Name, Labels: stale})
+				}
+			}
+
+			deploymentName := "agentapi-session-" + svc.Labels["agentapi.proxy/session-id"]
+			deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+			if err != nil {
+				if !k8serrors.IsNotFound(err) {
+					report.Errors = append(report.Errors, fmt.Sprintf("get deployment %s/%s: %v", namespace, deploymentName, err))
+				}
+				continue
+			}
+			if stale := staleLabels(deployment.Labels, schema); len(stale) > 0 {
+				if err := patchLabels(dryRun, stale, func(patch []byte) error {
+					_, err := client.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.MergePatchType, patch, metav1.PatchOptions{})
+					return err
+				}); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("relabel deployment %s/%s: %v", namespace, deploymentName, err))
+				} else {
+					report.Migrated = append(report.Migrated, LabelSchemaMigration{Kind: "Deployment", Namespace: namespace, Name: deploymentName, Labels: stale})
+				}
+			}
+		}
+	}
+	sort.Slice(report.Migrated, func(i, j int) bool {
+		a, b := report.Migrated[i], report.Migrated[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Kind < b.Kind
+	})
+	return report
+}
+
+// patchLabels sets labels with apply unless dryRun.
+func patchLabels(dryRun bool, labels map[string]string, apply func(patch []byte) error) error {
+	if dryRun {
+		return nil
+	}
+	patch, err := labelsPatch(labels)
+	if err != nil {
+		return err
+	}
+	return apply(patch)
+}
+
+// labelSchemaMigrator holds the report of the last label schema migration.
+type labelSchemaMigrator struct {
+	mu   sync.Mutex
+	last *LabelSchemaReport
+}
+
+// MigrateLabelSchema relabels the session objects in all session namespaces
+// to the current label schema and keeps the report for
+// LastLabelSchemaReport.
+func (m *KubernetesSessionManager) MigrateLabelSchema(ctx context.Context, dryRun bool) *LabelSchemaReport {
+	report := MigrateSessionLabelSchema(ctx, m.client, m.sessionNamespaces(ctx), dryRun)
+	if !dryRun {
+		m.labelSchema.mu.Lock()
+		m.labelSchema.last = report
+		m.labelSchema.mu.Unlock()
+	}
+	return report
+}
+
+// LastLabelSchemaReport returns the report of the last label schema
+// migration of this replica, or nil before the first one.
+func (m *KubernetesSessionManager) LastLabelSchemaReport() *LabelSchemaReport {
+	m.labelSchema.mu.Lock()
+	defer m.labelSchema.mu.Unlock()
+	return m.labelSchema.last
+}
+
+// labelSchemaMigrationInterval returns the configured migration interval;
+// zero disables the migration.
+func (m *KubernetesSessionManager) labelSchemaMigrationInterval() time.Duration {
+	raw := strings.TrimSpace(m.k8sConfig.LabelSchemaMigrationInterval)
+	if raw == "" {
+		return defaultLabelSchemaMigrationInterval
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval < 0 {
+		log.Printf("[K8S_SESSION] Warning: invalid label_schema_migration_interval %q, using %s", raw, defaultLabelSchemaMigrationInterval)
+		return defaultLabelSchemaMigrationInterval
+	}
+	return interval
+}
+
+// RunLabelSchemaMigrator relabels session objects created by older versions
+// to the current label schema every label_schema_migration_interval until
+// ctx is cancelled. It returns at once when the migration is disabled.
+// Relabeling is idempotent, but only the leader replica needs to run it.
+func (m *KubernetesSessionManager) RunLabelSchemaMigrator(ctx context.Context) {
+	interval := m.labelSchemaMigrationInterval()
+	if interval <= 0 {
+		return
+	}
+	timer := time.NewTimer(labelSchemaMigrationDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		report := m.MigrateLabelSchema(ctx, false)
+		for _, migrated := range report.Migrated {
+			log.Printf("[LABEL_SCHEMA] Relabeled %s %s/%s: %v", migrated.Kind, migrated.Namespace, migrated.Name, migrated.Labels)
+		}
+		for _, failure := range report.Errors {
+			log.Printf("[LABEL_SCHEMA] %s", failure)
+		}
+		if len(report.Migrated) > 0 || len(report.Errors) > 0 {
+			log.Printf("[LABEL_SCHEMA] Checked %d session(s), relabeled %d object(s), %d error(s)",
+				report.Checked, len(report.Migrated), len(report.Errors))
+		}
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMigrateSessionLabelSchema(t *testing.T) {
+	ctx := context.Background()
+	// A team session created before the scope, team-id-hash and agent-type
+	// labels existed.
+	client := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      "agentapi-session-old-svc",
+			Namespace: "test-ns",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+				"agentapi.proxy/session-id":    "old",
+				"agentapi.proxy/user-id":       "alice",
+			},
+			Annotations: map[string]string{
+				"agentapi.proxy/team-id":    "org/team",
+				"agentapi.proxy/agent-type": "claude-agentapi",
+			},
+		}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:      "agentapi-session-old",
+			Namespace: "test-ns",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+				"agentapi.proxy/session-id":    "old",
+				"agentapi.proxy/scope":         "team",
+			},
+		}},
+	)
+
+	report := MigrateSessionLabelSchema(ctx, client, []string{"test-ns"}, true)
+	if report.Checked != 1 || len(report.Migrated) != 2 {
+		t.Fatalf("dry run report = %+v, want the Service and Deployment", report)
+	}
+	svc, _ := client.CoreV1().Services("test-ns").Get(ctx, "agentapi-session-old-svc", metav1.GetOptions{})
+	if _, ok := svc.Labels["agentapi.proxy/scope"]; ok {
+		t.Fatal("dry run relabeled the Service")
+	}
+
+	report = MigrateSessionLabelSchema(ctx, client, []string{"test-ns"}, false)
+	if len(report.Errors) > 0 || len(report.Migrated) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	svc, _ = client.CoreV1().Services("test-ns").Get(ctx, "agentapi-session-old-svc", metav1.GetOptions{})
+	deployment, _ := client.AppsV1().Deployments("test-ns").Get(ctx, "agentapi-session-old", metav1.GetOptions{})
+	for _, labels := range []map[string]string{svc.Labels, deployment.Labels} {
+		if labels["agentapi.proxy/team-id-hash"] != hashTeamID("org/team") {
+			t.Errorf("team-id-hash = %q", labels["agentapi.proxy/team-id-hash"])
+		}
+		if labels["agentapi.proxy/agent-type"] != "claude-agentapi" || labels["app.kubernetes.io/name"] != "agentapi-session" {
+			t.Errorf("labels = %v", labels)
+		}
+	}
+	// Sessions without a scope label are user sessions, and the Deployment
+	// follows its Service.
+	if svc.Labels["agentapi.proxy/scope"] != "user" || deployment.Labels["agentapi.proxy/scope"] != "user" {
+		t.Errorf("scope = %q / %q", svc.Labels["agentapi.proxy/scope"], deployment.Labels["agentapi.proxy/scope"])
+	}
+	if svc.Labels["agentapi.proxy/user-id"] != "alice" {
+		t.Error("relabeling dropped an existing label")
+	}
+
+	if report := MigrateSessionLabelSchema(ctx, client, []string{"test-ns"}, false); len(report.Migrated) != 0 {
+		t.Errorf("second run relabeled %+v", report.Migrated)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// mainContainerName is the name of the agentapi container in session Pods.
+const mainContainerName = "agentapi"
+
+// StreamSessionLogs streams the logs of a container of the session Pod. The
+// caller must close the returned reader; with opts.Follow it stays open until
+// ctx is cancelled or the container exits.
+func (m *KubernetesSessionManager) StreamSessionLogs(ctx context.Context, sessionID string, opts entities.SessionLogOptions) (io.ReadCloser, error) {
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	pod, err := m.sessionPod(ctx, ks)
+	if err != nil {
+		return nil, err
+	}
+
+	container := defaultIfEmpty(opts.Container, mainContainerName)
+	if names := podContainerNames(pod); !slices.Contains(names, container) {
+		return nil, fmt.Errorf("%w %q (available: %s)", entities.ErrUnknownContainer, container, strings.Join(names, ", "))
+	}
+
+	stream, err := m.client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:    container,
+		Follow:       opts.Follow,
+		Previous:     opts.Previous,
+		TailLines:    opts.TailLines,
+		SinceSeconds: opts.SinceSeconds,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream logs of pod %s: %w", pod.Name, err)
+	}
+	return stream, nil
+}
+
+// sessionPod returns the newest Pod of the session. Deployments (PVC-backed
+// sessions) may briefly run an old and a new Pod during a rollout.
+func (m *KubernetesSessionManager) sessionPod(ctx context.Context, ks *KubernetesSession) (*corev1.Pod, error) {
+	pods, err := m.client.CoreV1().Pods(ks.Namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("agentapi.proxy/session-id=%s", ks.ID()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of session %s: %w", ks.ID(), err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, entities.ErrSessionPodUnavailable
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	return &pods.Items[0], nil
+}
+
+// podContainerNames lists the init and regular containers of pod.
+func podContainerNames(pod *corev1.Pod) []string {
+	names := make([]string, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
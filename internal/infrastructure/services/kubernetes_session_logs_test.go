@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestStreamSessionLogs(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+	manager.sessions[session.ID()] = session
+
+	if _, err := manager.StreamSessionLogs(ctx, session.ID(), entities.SessionLogOptions{}); !errors.Is(err, entities.ErrSessionPodUnavailable) {
+		t.Fatalf("StreamSessionLogs() without pod error = %v, want ErrSessionPodUnavailable", err)
+	}
+
+	for i, name := range []string{"old-pod", "new-pod"} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "test-ns",
+				Labels:            map[string]string{"agentapi.proxy/session-id": session.ID()},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(time.Duration(i) * time.Minute)),
+			},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "network-filter-setup"}},
+				Containers:     []corev1.Container{{Name: "agentapi"}, {Name: "docker-dind"}},
+			},
+		}
+		if _, err := manager.client.CoreV1().Pods("test-ns").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("failed to create pod: %v", err)
+		}
+	}
+
+	pod, err := manager.sessionPod(ctx, session)
+	if err != nil || pod.Name != "new-pod" {
+		t.Fatalf("sessionPod() = %v, %v, want new-pod", pod, err)
+	}
+
+	tail := int64(10)
+	stream, err := manager.StreamSessionLogs(ctx, session.ID(), entities.SessionLogOptions{Container: "docker-dind", TailLines: &tail})
+	if err != nil {
+		t.Fatalf("StreamSessionLogs() error = %v", err)
+	}
+	data, _ := io.ReadAll(stream)
+	_ = stream.Close()
+	if len(data) == 0 {
+		t.Error("StreamSessionLogs() returned no logs")
+	}
+
+	if _, err := manager.StreamSessionLogs(ctx, session.ID(), entities.SessionLogOptions{Container: "unknown"}); !errors.Is(err, entities.ErrUnknownContainer) {
+		t.Errorf("StreamSessionLogs() with unknown container error = %v, want ErrUnknownContainer", err)
+	}
+}
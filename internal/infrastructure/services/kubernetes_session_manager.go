@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,7 +31,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/yaml"
 
@@ -38,10 +41,14 @@ import (
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	infrasessionallocation "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/sessionallocation"
 	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/agentadapter"
 	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
 	"github.com/takutakahashi/agentapi-proxy/pkg/logger"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
 	"github.com/takutakahashi/agentapi-proxy/pkg/settingspatch"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 )
 
 // provisionerPort is the TCP port on which agent-provisioner listens inside session Pods.
@@ -97,6 +104,8 @@ type SessionDeletedHandler func(ctx context.Context, session entities.Session)
 type KubernetesSessionManager struct {
 	config                *config.Config
 	k8sConfig             *config.KubernetesSessionConfig
+	reloadedConfig        atomic.Pointer[config.KubernetesSessionConfig] // Set by ReloadConfig; see liveConfig
+	shuttingDown          atomic.Bool                                    // Set by StopAcceptingSessions
 	client                kubernetes.Interface
 	verbose               bool
 	logger                *logger.Logger
@@ -138,16 +147,77 @@ type KubernetesSessionManager struct {
 	statusSubCtx    context.Context
 	statusSubCancel context.CancelFunc
 
+	// labelSchema keeps the report of the last label schema migration.
+	labelSchema labelSchemaMigrator
+
 	// sessionListCacheRepo is the short-lived session-list cache backend.
 	// When Redis is configured this reduces Kubernetes API calls for frequent
 	// ListSessions requests.
 	sessionListCacheRepo portrepos.SessionListCacheRepository
 
+	// sessionEventBroadcaster shares timeline events with the other pods when
+	// the status event repository supports it (Redis). nil keeps them local.
+	sessionEventBroadcaster portrepos.SessionEventBroadcaster
+	// sessionEventSubs maps subscriber ID → buffered channel of the timeline
+	// events recorded by this pod and the others.
+	sessionEventSubsMu  sync.Mutex
+	sessionEventSubs    map[uint64]chan entities.SessionEvent
+	nextSessionEventSub uint64
+
 	// sessionAllocatorEnabled routes CreateSession through the leader-elected
 	// SessionAllocator when the server has started that worker.
 	sessionAllocatorEnabled bool
 
 	sessionAllocationNotifier coreallocation.Notifier
+
+	// activityPatchedAt throttles last-activity-at annotation patches per
+	// session. Protected by activityMu.
+	activityMu        sync.Mutex
+	activityPatchedAt map[string]time.Time
+
+	// sessionRepo persists the full session state when a session store is
+	// configured. nil keeps Kubernetes object metadata as the only source.
+	sessionRepo portrepos.SessionRepository
+
+	// eventRecorder keeps the lifecycle event timeline of sessions. nil
+	// disables the timeline.
+	eventRecorder portrepos.EventRecorder
+	// artifactStore keeps the output of post-session hooks. nil disables them.
+	artifactStore ArtifactStore
+	// sessionArchive keeps logs, history and workdir artifacts of deleted
+	// sessions in object storage. nil disables archiving.
+	sessionArchive          *sessionarchive.Archive
+	sessionArchiveArtifacts []string
+	// watchers tracks the status watcher goroutines of each session.
+	watchers sessionWatchers
+	// auditRepo records denied capability requests as policy violations and
+	// preempted sessions for compliance reports. nil only logs them.
+	auditRepo portrepos.AuditRepository
+	// capabilityObserver is told about every capability policy decision
+	capabilityObserver CapabilityObserver
+	// budgetCheck refuses sessions of teams over their usage budget; nil
+	// when budgets are not enforced
+	budgetCheck BudgetCheck
+	// preemptMu serializes preemptions so that concurrent requests do not
+	// pause a session each for the same slot.
+	preemptMu sync.Mutex
+
+	// restConfig is used to exec into session Pods. It is nil when the
+	// manager was created with a custom client, which disables exec.
+	restConfig *rest.Config
+	// dynamicClient manages VolumeSnapshots of session workdirs. It is nil
+	// when the manager was created with a custom client, which disables
+	// snapshots.
+	dynamicClient dynamic.Interface
+
+	// githubTokens mints and refreshes GitHub App installation tokens for
+	// sessions. Created on first use by githubTokenSource.
+	githubTokensOnce sync.Once
+	githubTokens     *github_pkg.InstallationTokenSource
+
+	// sessionTokenKey signs the per-session credentials of session Pods.
+	// See SessionToken.
+	sessionTokenKey []byte
 }
 
 // NewKubernetesSessionManager creates a new KubernetesSessionManager
@@ -167,7 +237,48 @@ func NewKubernetesSessionManager(
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	return NewKubernetesSessionManagerWithClient(cfg, verbose, lgr, client)
+	manager, err := NewKubernetesSessionManagerWithClient(cfg, verbose, lgr, client)
+	if err != nil {
+		return nil, err
+	}
+	manager.restConfig = restConfig
+	if manager.dynamicClient, err = dynamic.NewForConfig(restConfig); err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes dynamic client: %w", err)
+	}
+	return manager, nil
+}
+
+// ValidateKubernetesSessionConfig returns the first error in k8sConfig that
+// keeps the session manager from starting.
+func ValidateKubernetesSessionConfig(k8sConfig *config.KubernetesSessionConfig) error {
+	if err := validateRoutingMode(k8sConfig.Routing); err != nil {
+		return err
+	}
+	if err := validateNamespacePlacement(k8sConfig.NamespacePlacement); err != nil {
+		return err
+	}
+	if err := validateDisruptionBudget(k8sConfig.DisruptionBudget, k8sConfig.DisruptionBudgetMaxUnavailable); err != nil {
+		return err
+	}
+	if err := validateAcceleratorConfig(k8sConfig); err != nil {
+		return err
+	}
+	if err := validateImageConfig(k8sConfig); err != nil {
+		return err
+	}
+	if err := validateReservations(k8sConfig); err != nil {
+		return err
+	}
+	if err := validateSessionSnapshots(&k8sConfig.Snapshots); err != nil {
+		return err
+	}
+	if err := validateHealthProbe(&k8sConfig.HealthProbe); err != nil {
+		return err
+	}
+	if err := validateImageRollout(&k8sConfig.ImageRollout); err != nil {
+		return err
+	}
+	return validatePreemption(&k8sConfig.Preemption)
 }
 
 // NewKubernetesSessionManagerWithClient creates a new KubernetesSessionManager with a custom client
@@ -179,6 +290,9 @@ func NewKubernetesSessionManagerWithClient(
 	client kubernetes.Interface,
 ) (*KubernetesSessionManager, error) {
 	k8sConfig := &cfg.KubernetesSession
+	if err := ValidateKubernetesSessionConfig(k8sConfig); err != nil {
+		return nil, err
+	}
 
 	// Determine namespace
 	namespace := resolveKubernetesNamespace(k8sConfig.Namespace)
@@ -207,6 +321,7 @@ func NewKubernetesSessionManagerWithClient(
 		statusSubCtx:              subCtx,
 		statusSubCancel:           subCancel,
 		sessionAllocationNotifier: infrasessionallocation.NewLocalNotifier(),
+		activityPatchedAt:         make(map[string]time.Time),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -214,6 +329,9 @@ func NewKubernetesSessionManagerWithClient(
 	if err := manager.ensureProvisionerToken(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ensure provisioner token: %w", err)
 	}
+	if err := manager.ensureSessionTokenKey(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure session token key: %w", err)
+	}
 
 	// Ensure OpenTelemetry Collector ConfigMap exists
 	if err := manager.ensureOtelcolConfigMap(ctx); err != nil {
@@ -251,6 +369,12 @@ func (m *KubernetesSessionManager) SetStatusEventRepository(repo portrepos.Statu
 	// Start the Redis subscriber goroutine that fans out cross-pod events to
 	// local SSE subscribers.
 	go m.runStatusSubscriber(m.statusSubCtx)
+
+	// Session timeline events are shared as well when the backend supports it.
+	if broadcaster, ok := repo.(portrepos.SessionEventBroadcaster); ok {
+		m.sessionEventBroadcaster = broadcaster
+		go m.runSessionEventSubscriber(m.statusSubCtx)
+	}
 }
 
 // SetSessionListCacheRepository injects a SessionListCacheRepository for
@@ -352,8 +476,19 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 	applySandboxDefaults(req)
 
 	// Attempt to adopt a stock session matching the requested pod capabilities
-	// before creating a new one.
-	if stockSvc, err := m.findStockSession(ctx, sessionRequirements(req)); err != nil {
+	// before creating a new one. Stock Pods never include the editor, browser,
+	// terminal or BuildKit sidecars or requested accelerators, run the default
+	// image and are never Jobs.
+	runsAsJob := req.Oneshot && m.oneshotJobEnabled()
+	// Stock sessions are pre-warmed in the proxy's namespace only.
+	namespace := m.placementNamespace(req)
+	if editorEnabled(req) || browserEnabled(req) || terminalEnabled(req) || req.Docker.BuildKit() || req.Replicas > 1 || !req.Accelerator.IsEmpty() || !m.usesStockImage(req) || runsAsJob {
+		log.Printf("[K8S_SESSION] Editor, browser, terminal, BuildKit, multiple replicas, accelerators, another image or a Job requested for session %s, skipping stock sessions", id)
+	} else if req.RestoreSnapshot != "" {
+		log.Printf("[K8S_SESSION] Session %s is restored from snapshot %s, skipping stock sessions", id, req.RestoreSnapshot)
+	} else if namespace != m.namespace {
+		log.Printf("[K8S_SESSION] Session %s is placed in team namespace %s, skipping stock sessions", id, namespace)
+	} else if stockSvc, err := m.findStockSession(ctx, sessionRequirements(req)); err != nil {
 		log.Printf("[K8S_SESSION] Warning: failed to search for stock sessions: %v", err)
 	} else if stockSvc != nil {
 		claimedSvc, claimErr := m.claimStockService(ctx, stockSvc)
@@ -361,6 +496,7 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 			log.Printf("[K8S_SESSION] Stock session claim failed (concurrent claim?), falling back to new session creation: %v", claimErr)
 		} else {
 			log.Printf("[K8S_SESSION] Found stock session %s, adopting for new request", claimedSvc.Labels["agentapi.proxy/session-id"])
+			tracing.SpanFromContext(ctx).SetAttributes(tracing.Bool("session.stock", true))
 			return m.adoptStockSession(ctx, req, webhookPayload, claimedSvc)
 		}
 	}
@@ -380,32 +516,45 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 		deploymentName,
 		serviceName,
 		pvcName,
-		m.namespace,
+		namespace,
 		m.k8sConfig.BasePort,
 		cancel,
 		webhookPayload,
 	)
+	m.grantExecCapability(ctx, session, req)
 	// Register proxy-wide status change broadcaster
 	session.statusChangeCallback = m.broadcastStatusChange
+	session.clusterDomain = m.clusterDomain()
+	session.runsAsJob = runsAsJob
 
 	// Store session
 	m.mutex.Lock()
 	m.sessions[id] = session
 	m.mutex.Unlock()
 
-	log.Printf("[K8S_SESSION] Creating session %s in namespace %s", id, m.namespace)
+	log.Printf("[K8S_SESSION] Creating session %s in namespace %s", id, namespace)
+
+	if err := m.ensureTeamNamespace(ctx, namespace, req.TeamID); err != nil {
+		m.cleanupSession(id)
+		return nil, err
+	}
 
 	// Create Service first. It is the canonical session resource and owns every
 	// other per-session Kubernetes resource through ownerReferences.
-	if err := m.createService(ctx, session); err != nil {
+	if err := tracing.Run(ctx, "kubernetes.CreateService", func(ctx context.Context) error {
+		return m.createService(ctx, session)
+	}, tracing.String("k8s.service.name", serviceName)); err != nil {
 		m.cleanupSession(id)
 		return nil, fmt.Errorf("failed to create Service: %w", err)
 	}
 	log.Printf("[K8S_SESSION] Created Service %s for session %s", serviceName, id)
+	m.recordEvent(id, entities.SessionEventCreated, "Session created")
 
-	// Create PVC if enabled
-	if m.isPVCEnabled() {
-		if err := m.createPVC(ctx, session); err != nil {
+	// Create PVC if enabled. Replicas of multi-replica sessions share no workdir.
+	if m.isPVCEnabled() && session.Replicas() <= 1 {
+		if err := tracing.Run(ctx, "kubernetes.CreatePVC", func(ctx context.Context) error {
+			return m.createPVC(ctx, session)
+		}, tracing.String("k8s.pvc.name", pvcName)); err != nil {
 			if delErr := m.deleteSessionResources(ctx, session); delErr != nil {
 				log.Printf("[K8S_SESSION] Failed to cleanup resources after PVC creation failure: %v", delErr)
 			}
@@ -414,7 +563,15 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 		}
 		log.Printf("[K8S_SESSION] Created PVC %s for session %s", pvcName, id)
 	} else {
-		log.Printf("[K8S_SESSION] PVC disabled, using EmptyDir for session %s", id)
+		log.Printf("[K8S_SESSION] PVC disabled or multiple replicas, using EmptyDir for session %s", id)
+	}
+
+	if err := m.ensureRuntimeCachePVC(ctx, session.Namespace(), req); err != nil {
+		if delErr := m.deleteSessionResources(ctx, session); delErr != nil {
+			log.Printf("[K8S_SESSION] Failed to cleanup resources after runtime cache PVC creation failure: %v", delErr)
+		}
+		m.cleanupSession(id)
+		return nil, fmt.Errorf("failed to create runtime cache PVC: %w", err)
 	}
 
 	// Cache initial message as description
@@ -424,7 +581,9 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 
 	// Create webhook payload Secret if webhook payload is provided
 	if len(webhookPayload) > 0 {
-		if err := m.createWebhookPayloadSecret(ctx, session, webhookPayload); err != nil {
+		if err := tracing.Run(ctx, "kubernetes.CreateWebhookPayloadSecret", func(ctx context.Context) error {
+			return m.createWebhookPayloadSecret(ctx, session, webhookPayload)
+		}); err != nil {
 			log.Printf("[K8S_SESSION] Warning: failed to create webhook payload secret: %v", err)
 			// Continue anyway - session will work without payload file
 		}
@@ -440,7 +599,9 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 
 	// Create oneshot settings Secret if oneshot is enabled
 	if req.Oneshot {
-		if err := m.createOneshotSettingsSecret(ctx, session); err != nil {
+		if err := tracing.Run(ctx, "kubernetes.CreateOneshotSettingsSecret", func(ctx context.Context) error {
+			return m.createOneshotSettingsSecret(ctx, session)
+		}); err != nil {
 			log.Printf("[K8S_SESSION] Warning: failed to create oneshot settings secret: %v", err)
 			// Continue anyway - session will work without oneshot hook
 		}
@@ -457,17 +618,22 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 	}
 
 	session.SetProvisionSettings(sessionSettings)
-	if err := m.CreateProvisionRequest(ctx, session); err != nil {
+	if err := tracing.Run(ctx, "kubernetes.CreateProvisionRequest", func(ctx context.Context) error {
+		return m.CreateProvisionRequest(ctx, session)
+	}); err != nil {
 		if delErr := m.deleteSessionResources(ctx, session); delErr != nil {
 			log.Printf("[K8S_SESSION] Failed to cleanup resources after provision request creation failure: %v", delErr)
 		}
 		m.cleanupSession(id)
 		return nil, fmt.Errorf("failed to create provision request: %w", err)
 	}
+	m.recordEvent(id, entities.SessionEventSecretCreated, "Provision request Secret created")
 
 	// Create workload. PVC-backed sessions use a Deployment for restart recovery;
 	// ephemeral EmptyDir sessions use a Pod with restartPolicy=Never.
-	if err := m.createSessionWorkload(ctx, session, req); err != nil {
+	if err := tracing.Run(ctx, "kubernetes.CreateWorkload", func(ctx context.Context) error {
+		return m.createSessionWorkload(ctx, session, req)
+	}, tracing.String("k8s.deployment.name", deploymentName)); err != nil {
 		if delErr := m.deleteSessionResources(ctx, session); delErr != nil {
 			log.Printf("[K8S_SESSION] Failed to cleanup resources after workload creation failure: %v", delErr)
 		}
@@ -475,9 +641,12 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 		return nil, fmt.Errorf("failed to create session workload: %w", err)
 	}
 	log.Printf("[K8S_SESSION] Created workload %s for session %s", deploymentName, id)
+	m.ensureSessionDisruptionBudget(ctx, session)
 
 	// Start watching session in background
-	go m.watchSession(sessionCtx, session)
+	m.watchers.start(sessionCtx, id, func(ctx context.Context) {
+		m.watchSession(ctx, session)
+	})
 
 	// Log session start
 	repository := ""
@@ -496,6 +665,8 @@ func (m *KubernetesSessionManager) allocateSessionDirect(ctx context.Context, id
 		}
 	}
 
+	m.saveSessionRecord(id, req, webhookPayload)
+
 	log.Printf("[K8S_SESSION] Session %s created successfully", id)
 	return session, nil
 }
@@ -524,6 +695,7 @@ func (m *KubernetesSessionManager) CreateStockSession(ctx context.Context, dind
 	session := NewKubernetesSession(id, minimalReq, deploymentName, serviceName, pvcName,
 		m.namespace, m.k8sConfig.BasePort, cancel, nil)
 	session.SetIsStock(true)
+	session.clusterDomain = m.clusterDomain()
 
 	// Create the Service first so stock resources can reference it as owner.
 	// Keep it out of the available stock pool until the workload is created.
@@ -811,6 +983,7 @@ func (m *KubernetesSessionManager) adoptStockSession(
 		cancel,
 		webhookPayload,
 	)
+	m.grantExecCapability(ctx, session, req)
 
 	// Cache initial message as description.
 	if req.InitialMessage != "" {
@@ -819,6 +992,7 @@ func (m *KubernetesSessionManager) adoptStockSession(
 
 	// Register proxy-wide status change broadcaster
 	session.statusChangeCallback = m.broadcastStatusChange
+	session.clusterDomain = m.clusterDomain()
 
 	// Register session in memory.
 	m.mutex.Lock()
@@ -828,7 +1002,7 @@ func (m *KubernetesSessionManager) adoptStockSession(
 	log.Printf("[K8S_SESSION] Adopting stock session %s in namespace %s", stockID, m.namespace)
 
 	effectiveSandbox := m.resolveSandboxParams(ctx, req)
-	if err := applySandboxPolicyToProvisioner(ctx, stockSvc, effectiveSandbox); err != nil {
+	if err := m.applySandboxPolicyToProvisioner(ctx, stockSvc, effectiveSandbox); err != nil {
 		if delErr := m.deleteSessionResources(ctx, session); delErr != nil {
 			log.Printf("[K8S_SESSION] Failed to cleanup stock session after sandbox policy error: %v", delErr)
 		}
@@ -843,7 +1017,7 @@ func (m *KubernetesSessionManager) adoptStockSession(
 	case pvcErr == nil:
 		log.Printf("[K8S_SESSION] Stock session %s has existing PVC %s, reusing it", stockID, pvcName)
 		if currentPVC, err := m.client.CoreV1().PersistentVolumeClaims(m.namespace).Get(ctx, pvcName, metav1.GetOptions{}); err == nil {
-			currentPVC.OwnerReferences = m.sessionServiceOwnerReferences(ctx, stockID)
+			currentPVC.OwnerReferences = m.sessionServiceOwnerReferences(ctx, m.namespace, stockID)
 			if _, err := m.client.CoreV1().PersistentVolumeClaims(m.namespace).Update(ctx, currentPVC, metav1.UpdateOptions{}); err != nil {
 				log.Printf("[K8S_SESSION] Warning: failed to update stock PVC owner reference for session %s: %v", stockID, err)
 			}
@@ -903,6 +1077,15 @@ func (m *KubernetesSessionManager) adoptStockSession(
 	if req.SessionTTL != "" {
 		annotations["agentapi.proxy/session-ttl"] = req.SessionTTL
 	}
+	if caps := session.Capabilities(); len(caps) > 0 {
+		annotations[capabilitiesAnnotation] = entities.JoinCapabilities(caps)
+	}
+	if req.CompletionCallbackURL != "" {
+		annotations[completionCallbackAnnotation] = req.CompletionCallbackURL
+	}
+	if report := githubReportAnnotationValue(req.GitHubReport); report != "" {
+		annotations[githubReportAnnotation] = report
+	}
 
 	currentSvc, err := m.client.CoreV1().Services(m.namespace).Get(ctx, stockSvc.Name, metav1.GetOptions{})
 	if err != nil {
@@ -930,7 +1113,7 @@ func (m *KubernetesSessionManager) adoptStockSession(
 			log.Printf("[K8S_SESSION] Warning: failed to get stock deployment for label update: %v", err)
 		} else {
 			currentDep.Labels = newLabels
-			currentDep.OwnerReferences = m.sessionServiceOwnerReferences(ctx, stockID)
+			currentDep.OwnerReferences = m.sessionServiceOwnerReferences(ctx, m.namespace, stockID)
 			if _, err := m.client.AppsV1().Deployments(m.namespace).Update(ctx, currentDep, metav1.UpdateOptions{}); err != nil {
 				log.Printf("[K8S_SESSION] Warning: failed to update stock deployment labels for session %s: %v", stockID, err)
 			}
@@ -941,15 +1124,19 @@ func (m *KubernetesSessionManager) adoptStockSession(
 			log.Printf("[K8S_SESSION] Warning: failed to get stock pod for label update: %v", err)
 		} else {
 			currentPod.Labels = newLabels
-			currentPod.OwnerReferences = m.sessionServiceOwnerReferences(ctx, stockID)
+			currentPod.OwnerReferences = m.sessionServiceOwnerReferences(ctx, m.namespace, stockID)
 			if _, err := m.client.CoreV1().Pods(m.namespace).Update(ctx, currentPod, metav1.UpdateOptions{}); err != nil {
 				log.Printf("[K8S_SESSION] Warning: failed to update stock pod labels for session %s: %v", stockID, err)
 			}
 		}
 	}
 
+	m.ensureSessionDisruptionBudget(ctx, session)
+
 	// Start background watch. The Pod is already running and will claim the provision request.
-	go m.watchStockSession(sessionCtx, session)
+	m.watchers.start(sessionCtx, stockID, func(ctx context.Context) {
+		m.watchStockSession(ctx, session)
+	})
 
 	// Log session start.
 	repository := ""
@@ -968,7 +1155,10 @@ func (m *KubernetesSessionManager) adoptStockSession(
 		}
 	}
 
+	m.saveSessionRecord(stockID, req, webhookPayload)
+
 	log.Printf("[K8S_SESSION] Stock session %s adopted successfully", stockID)
+	m.recordEvent(stockID, entities.SessionEventCreated, "Session created from a pre-warmed stock Pod")
 	return session, nil
 }
 
@@ -1009,6 +1199,7 @@ func (m *KubernetesSessionManager) watchStockSession(ctx context.Context, sessio
 	}
 	readyTicker.Stop()
 	log.Printf("[K8S_SESSION] Stock session %s: Pod is ready", session.id)
+	m.refreshRouteHost(ctx, session)
 
 	log.Printf("[K8S_SESSION] Waiting for pull provision request to become ready for stock session %s", session.id)
 	if err := m.waitForPullProvisioner(ctx, session); err != nil {
@@ -1057,8 +1248,7 @@ func (m *KubernetesSessionManager) GetSession(id string) entities.Session {
 
 	// Try to restore from Kubernetes Service
 	serviceName := fmt.Sprintf("agentapi-session-%s-svc", id)
-	svc, err := m.client.CoreV1().Services(m.namespace).Get(
-		context.Background(), serviceName, metav1.GetOptions{})
+	svc, err := m.getSessionService(context.Background(), id, serviceName)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			log.Printf("[K8S_SESSION] Failed to get service %s: %v", serviceName, err)
@@ -1111,7 +1301,8 @@ func (m *KubernetesSessionManager) GetSession(id string) entities.Session {
 // triggered (the broadcasting pod already did that).
 //
 // The "infrastructure" statuses (creating, starting, unhealthy, stopped,
-// unknown) are authoritative from Kubernetes and are never overridden by Redis.
+// evicted, paused, resuming, unknown) are authoritative from Kubernetes and
+// are never overridden by Redis.
 func runtimeStatusOverrideFromRedis(repo portrepos.StatusEventRepository, session entities.Session) {
 	ks, ok := session.(*KubernetesSession)
 	if !ok {
@@ -1120,7 +1311,7 @@ func runtimeStatusOverrideFromRedis(repo portrepos.StatusEventRepository, sessio
 	// Infrastructure statuses must not be overridden.
 	currentStatus := ks.Status()
 	switch currentStatus {
-	case "creating", "starting", "unhealthy", "stopped", "unknown":
+	case "creating", "starting", "unhealthy", "stopped", "evicted", "paused", "resuming", "unknown":
 		return
 	}
 
@@ -1281,19 +1472,35 @@ func (m *KubernetesSessionManager) buildSessionListCacheKey(labelSelector string
 // In-memory-only filters (status, teamIDs, tags) are NOT applied here so
 // the caller can cache the full result and reuse it across filter variants.
 func (m *KubernetesSessionManager) fetchSessionsFromK8s(ctx context.Context, labelSelector string, filter entities.SessionFilter) []entities.Session {
-	services, err := m.client.CoreV1().Services(m.namespace).List(
+	namespaces := m.sessionNamespaces(ctx)
+	result := m.fetchNamespaceSessionsFromK8s(ctx, namespaces[0], labelSelector, filter)
+	// Team namespaces are best-effort: a namespace that cannot be listed
+	// does not hide the sessions of the others.
+	for _, namespace := range namespaces[1:] {
+		result = append(result, m.fetchNamespaceSessionsFromK8s(ctx, namespace, labelSelector, filter)...)
+	}
+	if result == nil {
+		return []entities.Session{}
+	}
+	return result
+}
+
+// fetchNamespaceSessionsFromK8s lists the sessions of one namespace for
+// fetchSessionsFromK8s.
+func (m *KubernetesSessionManager) fetchNamespaceSessionsFromK8s(ctx context.Context, namespace, labelSelector string, filter entities.SessionFilter) []entities.Session {
+	services, err := m.client.CoreV1().Services(namespace).List(
 		ctx,
 		metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
-		log.Printf("[K8S_SESSION] Failed to list services: %v", err)
-		return []entities.Session{}
+		log.Printf("[K8S_SESSION] Failed to list services in namespace %s: %v", namespace, err)
+		return nil
 	}
 
 	deploymentMap := make(map[string]*appsv1.Deployment)
 	podMap := make(map[string]*corev1.Pod)
 	if m.isPVCEnabled() {
 		// Batch fetch deployments once to avoid N+1 API calls.
-		deployments, err := m.client.AppsV1().Deployments(m.namespace).List(
+		deployments, err := m.client.AppsV1().Deployments(namespace).List(
 			ctx,
 			metav1.ListOptions{LabelSelector: labelSelector})
 		if err != nil {
@@ -1309,7 +1516,7 @@ func (m *KubernetesSessionManager) fetchSessionsFromK8s(ctx context.Context, lab
 			}
 		}
 	} else {
-		pods, err := m.client.CoreV1().Pods(m.namespace).List(
+		pods, err := m.client.CoreV1().Pods(namespace).List(
 			ctx,
 			metav1.ListOptions{LabelSelector: labelSelector})
 		if err != nil {
@@ -1415,7 +1622,7 @@ func (m *KubernetesSessionManager) filterSessionsFromCache(dtos []portrepos.Cach
 			ls.SetAnnotations(dto.Annotations)
 			s = ls // use live in-memory session for current status
 		} else {
-			s = newCachedSession(dto)
+			s = newCachedSession(dto, m.clusterDomain())
 		}
 		sessions = append(sessions, s)
 	}
@@ -1487,6 +1694,12 @@ func (m *KubernetesSessionManager) getOrRestoreSessionWithWorkload(svc *corev1.S
 
 	if exists {
 		session.SetAnnotations(sessionAnnotationsFromMap(svc.Annotations))
+		if tags, ok := updatedTagsFromService(svc); ok {
+			session.setTags(tags)
+		}
+		session.SetCapabilities(restoreCapabilitiesFromService(svc))
+		session.SetPreview(restorePreviewFromService(svc))
+		restoreCompletionFromService(session, svc)
 		// If the in-memory session was cached when this Service was still a stock
 		// session (user-id was empty at restore time), and the Service now has a
 		// real owner, repair the user-id in-place so authorization checks pass.
@@ -1527,6 +1740,7 @@ func (m *KubernetesSessionManager) DeleteSession(id string) error {
 	}
 
 	log.Printf("[K8S_SESSION] Deleting session %s", id)
+	m.recordEvent(id, entities.SessionEventDeleted, "Session deletion requested")
 
 	// Invoke registered handlers BEFORE cancelling the context or removing Kubernetes resources.
 	// At this point the session's Service endpoint is still reachable (e.g. for GetMessages).
@@ -1543,6 +1757,11 @@ func (m *KubernetesSessionManager) DeleteSession(id string) error {
 		}
 	}
 
+	// Post-session hooks of oneshot sessions need the Pod, so they run before
+	// anything is cancelled or deleted.
+	m.runPostSessionHooksBeforeDelete(session)
+	m.archiveSessionBeforeDelete(session)
+
 	// Cancel context to trigger cleanup
 	if session != nil {
 		session.Cancel()
@@ -1587,6 +1806,13 @@ func (m *KubernetesSessionManager) DeleteSession(id string) error {
 	return nil
 }
 
+// StopAcceptingSessions makes CreateSession and CreateSessionDirect fail
+// with entities.ErrShuttingDown, so that new sessions are created by the
+// other replicas while this one drains. Existing sessions keep working.
+func (m *KubernetesSessionManager) StopAcceptingSessions() {
+	m.shuttingDown.Store(true)
+}
+
 // Shutdown gracefully stops all sessions
 // Note: This does NOT delete Kubernetes resources (Deployment, Service, PVC, Secret).
 // Resources are preserved so sessions can be restored when the proxy restarts.
@@ -1597,6 +1823,7 @@ func (m *KubernetesSessionManager) Shutdown(timeout time.Duration) error {
 	// Clear in-memory sessions (resources remain in Kubernetes)
 	m.sessions = make(map[string]*KubernetesSession)
 	m.mutex.Unlock()
+	m.watchers.stopAll()
 
 	log.Printf("[K8S_SESSION] Shutting down, preserving %d session(s) in Kubernetes for recovery", sessionCount)
 	return nil
@@ -1613,16 +1840,12 @@ func (m *KubernetesSessionManager) SendMessage(ctx context.Context, id string, m
 	}
 
 	status := session.Status()
-	if status != "active" && status != "starting" {
+	if status != "active" && status != "starting" && status != entities.SessionStatusCompleted {
 		return fmt.Errorf("session is not active: status=%s", status)
 	}
 
 	serviceName := fmt.Sprintf("agentapi-session-%s-svc", id)
-	baseURL := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d",
-		serviceName,
-		m.namespace,
-		m.k8sConfig.BasePort,
-	)
+	baseURL := fmt.Sprintf("http://%s:%d", m.sessionHost(id), m.k8sConfig.BasePort)
 
 	agentType := ""
 	if ks, ok := session.(*KubernetesSession); ok {
@@ -1631,7 +1854,7 @@ func (m *KubernetesSessionManager) SendMessage(ctx context.Context, id string, m
 		}
 	}
 	if agentType == "" {
-		agentType = m.getSessionAgentTypeFromService(ctx, serviceName)
+		agentType = m.getSessionAgentTypeFromService(ctx, m.namespaceOf(id), serviceName)
 	}
 
 	var jsonData []byte
@@ -1699,10 +1922,11 @@ func (m *KubernetesSessionManager) SendMessage(ctx context.Context, id string, m
 				ks.SetLastMessageAt(now)
 			}
 			svcName := fmt.Sprintf("agentapi-session-%s-svc", id)
-			if patchErr := m.patchLastMessageAt(context.Background(), svcName, now); patchErr != nil {
+			if patchErr := m.patchLastMessageAt(context.Background(), m.namespaceOf(id), svcName, now); patchErr != nil {
 				log.Printf("[K8S_SESSION] Failed to update last-message-at for session %s: %v", id, patchErr)
 			}
 			log.Printf("[K8S_SESSION] Successfully sent message to session %s (agentType=%q)", id, agentType)
+			m.RecordMessageSent(id)
 			return nil
 		}
 		if err != nil {
@@ -1755,17 +1979,13 @@ func (m *KubernetesSessionManager) StopAgent(ctx context.Context, id string) err
 
 	// Check session status
 	status := session.Status()
-	if status != "active" && status != "starting" {
+	if status != "active" && status != "starting" && status != entities.SessionStatusCompleted {
 		return fmt.Errorf("session is not active: status=%s", status)
 	}
 
 	// Build service name and endpoint URL for the agentapi /action endpoint
 	serviceName := fmt.Sprintf("agentapi-session-%s-svc", id)
-	url := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/action",
-		serviceName,
-		m.namespace,
-		m.k8sConfig.BasePort,
-	)
+	url := fmt.Sprintf("http://%s:%d/action", m.sessionHost(id), m.k8sConfig.BasePort)
 
 	agentType := ""
 	if ks, ok := session.(*KubernetesSession); ok {
@@ -1774,16 +1994,12 @@ func (m *KubernetesSessionManager) StopAgent(ctx context.Context, id string) err
 		}
 	}
 	if agentType == "" {
-		agentType = m.getSessionAgentTypeFromService(ctx, serviceName)
+		agentType = m.getSessionAgentTypeFromService(ctx, m.namespaceOf(id), serviceName)
 	}
 
 	var payload interface{}
 	if isACPAgentType(agentType) {
-		url = fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/rpc",
-			serviceName,
-			m.namespace,
-			m.k8sConfig.BasePort,
-		)
+		url = fmt.Sprintf("http://%s:%d/rpc", m.sessionHost(id), m.k8sConfig.BasePort)
 		payload = map[string]interface{}{
 			"jsonrpc": "2.0",
 			"method":  "session/cancel",
@@ -1827,16 +2043,11 @@ func (m *KubernetesSessionManager) StopAgent(ctx context.Context, id string) err
 }
 
 func isACPAgentType(agentType string) bool {
-	return agentType == "claude-acp" || agentType == "codex-acp" || agentType == "pi-ollama" || agentType == "cursor"
+	return agentadapter.IsACP(agentType)
 }
 
 func supportedAgentTypeOrDefault(agentType string) string {
-	switch agentType {
-	case "claude-acp", "codex-acp", "pi-ollama", "cursor":
-		return agentType
-	default:
-		return ""
-	}
+	return agentadapter.Normalize(agentType)
 }
 
 func restoreAgentTypeFromService(svc *corev1.Service) string {
@@ -1849,8 +2060,8 @@ func restoreAgentTypeFromService(svc *corev1.Service) string {
 	return svc.Labels["agentapi.proxy/agent-type"]
 }
 
-func (m *KubernetesSessionManager) getSessionAgentTypeFromService(ctx context.Context, serviceName string) string {
-	svc, err := m.client.CoreV1().Services(m.namespace).Get(ctx, serviceName, metav1.GetOptions{})
+func (m *KubernetesSessionManager) getSessionAgentTypeFromService(ctx context.Context, namespace, serviceName string) string {
+	svc, err := m.client.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			log.Printf("[K8S_SESSION] Failed to get service %s for agent type fallback: %v", serviceName, err)
@@ -1868,13 +2079,8 @@ func (m *KubernetesSessionManager) GetMessages(ctx context.Context, id string) (
 		return nil, fmt.Errorf("session not found: %s", id)
 	}
 
-	// Build service name and endpoint URL
-	serviceName := fmt.Sprintf("agentapi-session-%s-svc", id)
-	url := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/messages",
-		serviceName,
-		m.namespace,
-		m.k8sConfig.BasePort,
-	)
+	// Build endpoint URL
+	url := fmt.Sprintf("http://%s:%d/messages", m.sessionHost(id), m.k8sConfig.BasePort)
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -1914,9 +2120,9 @@ func (m *KubernetesSessionManager) createPVC(ctx context.Context, session *Kuber
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            session.PVCName(),
-			Namespace:       m.namespace,
+			Namespace:       session.Namespace(),
 			Labels:          m.buildLabels(session),
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
@@ -1935,15 +2141,26 @@ func (m *KubernetesSessionManager) createPVC(ctx context.Context, session *Kuber
 		pvc.Spec.StorageClassName = &m.k8sConfig.PVCStorageClass
 	}
 
-	_, err := m.client.CoreV1().PersistentVolumeClaims(m.namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	// Restore the workdir from a snapshot; the provisioner waits until the
+	// snapshot is ready
+	if snapshot := session.Request().RestoreSnapshot; snapshot != "" {
+		apiGroup := volumeSnapshotGVR.Group
+		pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{APIGroup: &apiGroup, Kind: "VolumeSnapshot", Name: snapshot}
+	}
+
+	_, err := m.client.CoreV1().PersistentVolumeClaims(session.Namespace()).Create(ctx, pvc, metav1.CreateOptions{})
 	return err
 }
 
 // createSessionWorkload creates the Kubernetes workload for the session.
 // PVC-backed sessions keep using a Deployment so they can recover from pod
 // restarts. Sessions without a PVC are intentionally ephemeral and run as a
-// single Pod with restartPolicy=Never.
+// single Pod with restartPolicy=Never. Oneshot sessions run as a Job when
+// kubernetes_session.oneshot_job_enabled is set.
 func (m *KubernetesSessionManager) createSessionWorkload(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest) error {
+	if session.RunsAsJob() {
+		return m.createJob(ctx, session, req)
+	}
 	if m.isPVCEnabled() {
 		return m.createDeployment(ctx, session, req)
 	}
@@ -1956,7 +2173,10 @@ func (m *KubernetesSessionManager) createDeployment(ctx context.Context, session
 	if err != nil {
 		return err
 	}
-	_, err = m.client.AppsV1().Deployments(m.namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	if err := m.mirrorPodSpecDependencies(ctx, session.Namespace(), &deployment.Spec.Template.Spec); err != nil {
+		return err
+	}
+	_, err = m.client.AppsV1().Deployments(session.Namespace()).Create(ctx, deployment, metav1.CreateOptions{})
 	return err
 }
 
@@ -1970,16 +2190,19 @@ func (m *KubernetesSessionManager) createPod(ctx context.Context, session *Kuber
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            session.DeploymentName(),
-			Namespace:       m.namespace,
+			Namespace:       session.Namespace(),
 			Labels:          podTemplate.Labels,
 			Annotations:     podTemplate.Annotations,
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 		},
 		Spec: podTemplate.Spec,
 	}
 	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	if err := m.mirrorPodSpecDependencies(ctx, session.Namespace(), &pod.Spec); err != nil {
+		return err
+	}
 
-	_, err = m.client.CoreV1().Pods(m.namespace).Create(ctx, pod, metav1.CreateOptions{})
+	_, err = m.client.CoreV1().Pods(session.Namespace()).Create(ctx, pod, metav1.CreateOptions{})
 	return err
 }
 
@@ -1988,13 +2211,14 @@ func (m *KubernetesSessionManager) createPod(ctx context.Context, session *Kuber
 func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest) (*appsv1.Deployment, error) {
 	labels := m.buildLabels(session)
 	envVars := m.buildEnvVars(session, req)
-	replicas := int32(1)
+	replicas := int32(session.Replicas())
 
 	// Parse resource requirements
-	cpuRequest := resource.MustParse(m.k8sConfig.CPURequest)
-	cpuLimit := resource.MustParse(m.k8sConfig.CPULimit)
-	memoryRequest := resource.MustParse(m.k8sConfig.MemoryRequest)
-	memoryLimit := resource.MustParse(m.k8sConfig.MemoryLimit)
+	live := m.liveConfig()
+	cpuRequest := resource.MustParse(live.CPURequest)
+	cpuLimit := resource.MustParse(live.CPULimit)
+	memoryRequest := resource.MustParse(live.MemoryRequest)
+	memoryLimit := resource.MustParse(live.MemoryLimit)
 
 	// Build init containers and sandbox sidecar.
 	// Sandbox (network filter) is now always enabled - it cannot be opted out.
@@ -2029,8 +2253,9 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 		sandboxEnvVars = provisionerSciaEnvVars(sciaEnvVars)
 	}
 
-	// Build DinD sidecar if Docker-in-Docker is enabled.
-	dindEnabled := req.Docker != nil && req.Docker.Enabled
+	// Build DinD sidecar if Docker-in-Docker is enabled. The BuildKit mode is
+	// handled once the main container exists.
+	dindEnabled := req.Docker != nil && req.Docker.Enabled && !req.Docker.BuildKit()
 	var dindSidecar *corev1.Container
 	var dindEnvVars []corev1.EnvVar
 	var dindVolumes []corev1.Volume
@@ -2049,6 +2274,7 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 			}
 			dockerConfig = &sessionsettings.DockerConfig{
 				Enabled:    true,
+				Mode:       req.Docker.Mode,
 				Registries: registries,
 			}
 		}
@@ -2081,14 +2307,14 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 			})
 			log.Printf("[K8S_SESSION] Mounting GitHub config Secret %s for session %s", m.k8sConfig.GitHubConfigSecretName, session.id)
 		}
-	} else if m.k8sConfig.GitHubSecretName != "" {
+	} else if githubSecretName := m.githubSecretName(req); githubSecretName != "" {
 		// When params.github_token is NOT provided:
-		// - Mount GitHubSecretName for full GitHub App authentication
+		// - Mount GitHubSecretName (or the Secret of the team's GitHub instance) for full GitHub App authentication
 		// - Also mount GitHubConfigSecretName (config values will override auth secret if same keys exist)
 		envFrom = append(envFrom, corev1.EnvFromSource{
 			SecretRef: &corev1.SecretEnvSource{
 				LocalObjectReference: corev1.LocalObjectReference{
-					Name: m.k8sConfig.GitHubSecretName,
+					Name: githubSecretName,
 				},
 				Optional: boolPtr(true),
 			},
@@ -2107,12 +2333,19 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 		}
 	}
 
+	// Stock sessions run the default image; others run the image of their
+	// agent type, team or request.
+	image := m.k8sConfig.Image
+	if !session.isStock {
+		image = m.sessionImage(req)
+	}
+
 	// Build container spec.
 	// The container runs agent-provisioner, which serves local health/status
 	// endpoints and pulls provision requests from the proxy internal API.
 	container := corev1.Container{
 		Name:            "agentapi",
-		Image:           m.k8sConfig.Image,
+		Image:           image,
 		ImagePullPolicy: corev1.PullPolicy(m.k8sConfig.ImagePullPolicy),
 		WorkingDir:      workingDir,
 		Ports: []corev1.ContainerPort{
@@ -2200,6 +2433,23 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 	if dindEnabled {
 		volumes = append(volumes, dindVolumes...)
 	}
+	// The BuildKit sidecar takes the DinD sidecar's place, including the
+	// corporate proxy settings applied below.
+	if req.Docker.BuildKit() {
+		var buildKitVolumes []corev1.Volume
+		dindSidecar, buildKitVolumes = m.applyBuildKitSidecar(req, &container)
+		volumes = append(volumes, buildKitVolumes...)
+	}
+
+	// Route outbound traffic through the corporate proxy and trust its CA.
+	volumes = append(volumes, m.applyEgressProxy(req, &container, sandboxSidecar, sciaSidecar, dindSidecar)...)
+
+	browserSidecar, browserVolumes := m.applyBrowserSidecar(req, &container)
+	m.applyPreStopCheckpoint(&container)
+	volumes = append(volumes, browserVolumes...)
+
+	// Share package manager caches between the sessions of a team.
+	volumes = append(volumes, m.applyRuntimeCache(req, &container)...)
 
 	// Build containers list.
 	// Note: credentials-sync is now handled as a goroutine inside agent-provisioner
@@ -2215,6 +2465,18 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 	if dindSidecar != nil {
 		containers = append(containers, *dindSidecar)
 	}
+	if editorEnabled(req) {
+		editorSidecar, editorVolumes := m.buildEditorContainer()
+		containers = append(containers, *editorSidecar)
+		volumes = append(volumes, editorVolumes...)
+	}
+	if browserSidecar != nil {
+		containers = append(containers, *browserSidecar)
+	}
+	if terminalSidecar, terminalVolumes := m.buildTerminalContainer(req); terminalSidecar != nil {
+		containers = append(containers, *terminalSidecar)
+		volumes = append(volumes, terminalVolumes...)
+	}
 
 	// Note: Initial message is now sent by agent-provisioner internally after agentapi
 	// becomes ready. The initial-message-sender sidecar has been removed.
@@ -2266,9 +2528,9 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            session.DeploymentName(),
-			Namespace:       m.namespace,
+			Namespace:       session.Namespace(),
 			Labels:          labels,
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -2300,6 +2562,8 @@ func (m *KubernetesSessionManager) buildDeployment(ctx context.Context, session
 			},
 		},
 	}
+	m.applyAccelerator(req, &deployment.Spec.Template.Spec)
+	m.applyGuaranteedQoS(req, &deployment.Spec.Template.Spec)
 	if err := m.applySessionPodTemplateFile(&deployment.Spec.Template); err != nil {
 		return nil, err
 	}
@@ -2494,8 +2758,8 @@ func (m *KubernetesSessionManager) createWebhookPayloadSecret(
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            secretName,
-			Namespace:       m.namespace,
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			Namespace:       session.Namespace(),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 			Labels: map[string]string{
 				"agentapi.proxy/session-id": session.id,
 				"agentapi.proxy/user-id":    sanitizeLabelValue(session.Request().UserID),
@@ -2508,7 +2772,7 @@ func (m *KubernetesSessionManager) createWebhookPayloadSecret(
 		},
 	}
 
-	_, err := m.client.CoreV1().Secrets(m.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	_, err := m.client.CoreV1().Secrets(session.Namespace()).Create(ctx, secret, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create webhook payload secret: %w", err)
 	}
@@ -2520,7 +2784,7 @@ func (m *KubernetesSessionManager) createWebhookPayloadSecret(
 // deleteWebhookPayloadSecret deletes the webhook payload Secret for a session
 func (m *KubernetesSessionManager) deleteWebhookPayloadSecret(ctx context.Context, session *KubernetesSession) error {
 	secretName := fmt.Sprintf("%s-webhook-payload", session.ServiceName())
-	err := m.client.CoreV1().Secrets(m.namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	err := m.client.CoreV1().Secrets(session.Namespace()).Delete(ctx, secretName, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete webhook payload secret: %w", err)
 	}
@@ -2530,9 +2794,9 @@ func (m *KubernetesSessionManager) deleteWebhookPayloadSecret(ctx context.Contex
 // getInitialMessageFromSecret retrieves the initial message from the session-settings Secret.
 // The initial message is stored as the "initial_message" field inside "settings.yaml".
 // serviceName follows the pattern "agentapi-session-{id}-svc"; the settings secret is "agentapi-session-{id}-settings".
-func (m *KubernetesSessionManager) getInitialMessageFromSecret(ctx context.Context, serviceName string) string {
+func (m *KubernetesSessionManager) getInitialMessageFromSecret(ctx context.Context, namespace, serviceName string) string {
 	settingsSecretName := strings.TrimSuffix(serviceName, "-svc") + "-settings"
-	secret, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, settingsSecretName, metav1.GetOptions{})
+	secret, err := m.client.CoreV1().Secrets(namespace).Get(ctx, settingsSecretName, metav1.GetOptions{})
 	if err != nil {
 		return ""
 	}
@@ -2549,9 +2813,9 @@ func (m *KubernetesSessionManager) getInitialMessageFromSecret(ctx context.Conte
 
 // getSessionMetaFromSecret reads the settings secret and returns the SessionMeta
 // (including MemoryKey, Teams, AgentType, Oneshot etc.) for session restore.
-func (m *KubernetesSessionManager) getSessionMetaFromSecret(ctx context.Context, serviceName string) *sessionsettings.SessionMeta {
+func (m *KubernetesSessionManager) getSessionMetaFromSecret(ctx context.Context, namespace, serviceName string) *sessionsettings.SessionMeta {
 	settingsSecretName := strings.TrimSuffix(serviceName, "-svc") + "-settings"
-	secret, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, settingsSecretName, metav1.GetOptions{})
+	secret, err := m.client.CoreV1().Secrets(namespace).Get(ctx, settingsSecretName, metav1.GetOptions{})
 	if err != nil {
 		return nil
 	}
@@ -2589,6 +2853,13 @@ func (m *KubernetesSessionManager) createOneshotSettingsSecret(
 ) error {
 	secretName := fmt.Sprintf("%s-oneshot-settings", session.ServiceName())
 
+	// Job sessions end their Job instead; the proxy deletes them after
+	// capturing the logs.
+	stopCommand := "agentapi-proxy client delete-session --confirm"
+	if session.RunsAsJob() {
+		stopCommand = oneshotJobStopHookCommand
+	}
+
 	// Create settings.json with Stop hook
 	settingsJSON := map[string]interface{}{
 		"hooks": map[string]interface{}{
@@ -2597,7 +2868,7 @@ func (m *KubernetesSessionManager) createOneshotSettingsSecret(
 					"hooks": []map[string]interface{}{
 						{
 							"type":    "command",
-							"command": "agentapi-proxy client delete-session --confirm",
+							"command": stopCommand,
 						},
 					},
 				},
@@ -2613,8 +2884,8 @@ func (m *KubernetesSessionManager) createOneshotSettingsSecret(
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            secretName,
-			Namespace:       m.namespace,
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			Namespace:       session.Namespace(),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 			Labels: map[string]string{
 				"agentapi.proxy/session-id": session.id,
 				"agentapi.proxy/user-id":    sanitizeLabelValue(session.Request().UserID),
@@ -2627,7 +2898,7 @@ func (m *KubernetesSessionManager) createOneshotSettingsSecret(
 		},
 	}
 
-	_, err = m.client.CoreV1().Secrets(m.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	_, err = m.client.CoreV1().Secrets(session.Namespace()).Create(ctx, secret, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create oneshot settings secret: %w", err)
 	}
@@ -2680,7 +2951,7 @@ func applySandboxDefaults(req *entities.RunServerRequest) {
 	}
 }
 
-func applySandboxPolicyToProvisioner(ctx context.Context, stockSvc *corev1.Service, sandbox *entities.SandboxParams) error {
+func (m *KubernetesSessionManager) applySandboxPolicyToProvisioner(ctx context.Context, stockSvc *corev1.Service, sandbox *entities.SandboxParams) error {
 	body, err := json.Marshal(struct {
 		Allowed   []string `json:"allowed,omitempty"`
 		Denied    []string `json:"denied,omitempty"`
@@ -2694,7 +2965,7 @@ func applySandboxPolicyToProvisioner(ctx context.Context, stockSvc *corev1.Servi
 		return fmt.Errorf("marshal sandbox policy: %w", err)
 	}
 
-	url := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/sandbox-policy", stockSvc.Name, stockSvc.Namespace, ProvisionerPort)
+	url := fmt.Sprintf("http://%s:%d/sandbox-policy", serviceDNSName(stockSvc.Name, stockSvc.Namespace, m.clusterDomain()), ProvisionerPort)
 	client := &http.Client{Timeout: 2 * time.Second}
 	var lastErr error
 	for attempt := 0; attempt < 10; attempt++ {
@@ -3091,6 +3362,9 @@ func (m *KubernetesSessionManager) buildDinDContainers(docker *sessionsettings.D
 	rootUID := int64(0)
 
 	dindArgs := []string{"dockerd", "--host=tcp://0.0.0.0:2375", "--tls=false"}
+	if m.imageCacheOnPVC() {
+		dindArgs = append(dindArgs, "--data-root="+imageCacheDir+"/docker")
+	}
 	if docker != nil {
 		for _, reg := range docker.Registries {
 			if reg.Insecure && reg.Server != "" {
@@ -3116,10 +3390,6 @@ func (m *KubernetesSessionManager) buildDinDContainers(docker *sessionsettings.D
 			defaultIfEmpty(m.k8sConfig.DinDMemoryLimit, "2Gi"),
 		),
 		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      "docker-storage",
-				MountPath: "/var/lib/docker",
-			},
 			{
 				Name:      "workdir",
 				MountPath: "/home/agentapi/workdir",
@@ -3127,13 +3397,20 @@ func (m *KubernetesSessionManager) buildDinDContainers(docker *sessionsettings.D
 		},
 	}
 
-	volumes := []corev1.Volume{
-		{
+	// Without the PVC image cache, images live in an EmptyDir and are lost
+	// whenever the Pod is recreated.
+	var volumes []corev1.Volume
+	if !m.imageCacheOnPVC() {
+		sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+			Name:      "docker-storage",
+			MountPath: "/var/lib/docker",
+		})
+		volumes = append(volumes, corev1.Volume{
 			Name: "docker-storage",
 			VolumeSource: corev1.VolumeSource{
 				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
-		},
+		})
 	}
 
 	// Resolve registry secret from per-session config.
@@ -3204,7 +3481,7 @@ func buildResourceRequirements(cpuReq, cpuLim, memReq, memLim string) corev1.Res
 func (m *KubernetesSessionManager) buildVolumes(session *KubernetesSession) []corev1.Volume {
 	// Build workdir volume - use PVC if enabled, otherwise EmptyDir
 	var workdirVolume corev1.Volume
-	if m.isPVCEnabled() {
+	if m.isPVCEnabled() && session.Replicas() <= 1 {
 		workdirVolume = corev1.Volume{
 			Name: "workdir",
 			VolumeSource: corev1.VolumeSource{
@@ -3315,11 +3592,32 @@ func (m *KubernetesSessionManager) createServiceWithLabels(ctx context.Context,
 	if session.Request().SessionTTL != "" {
 		annotations["agentapi.proxy/session-ttl"] = session.Request().SessionTTL
 	}
+	if caps := session.Capabilities(); len(caps) > 0 {
+		annotations[capabilitiesAnnotation] = entities.JoinCapabilities(caps)
+	}
+	if callbackURL := session.CompletionCallbackURL(); callbackURL != "" {
+		annotations[completionCallbackAnnotation] = callbackURL
+	}
+	if report := githubReportAnnotationValue(session.GitHubReport()); report != "" {
+		annotations[githubReportAnnotation] = report
+	}
+	if replicas := session.Replicas(); replicas > 1 {
+		annotations[replicasAnnotation] = strconv.Itoa(replicas)
+	}
+	if accelerator := acceleratorAnnotationValue(session.Request().Accelerator); accelerator != "" {
+		annotations[acceleratorAnnotation] = accelerator
+	}
+	if image := session.Request().Image; image != "" {
+		annotations[imageAnnotation] = image
+	}
+	if session.RunsAsJob() {
+		annotations[workloadAnnotation] = workloadJob
+	}
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        session.ServiceName(),
-			Namespace:   m.namespace,
+			Namespace:   session.Namespace(),
 			Labels:      labels,
 			Annotations: annotations,
 		},
@@ -3331,17 +3629,26 @@ func (m *KubernetesSessionManager) createServiceWithLabels(ctx context.Context,
 			Ports: m.buildServicePorts(session),
 		},
 	}
+	if m.routingMode() == RoutingHeadless {
+		// The Service DNS name resolves straight to the session Pod.
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+	}
 
-	_, err := m.client.CoreV1().Services(m.namespace).Create(ctx, service, metav1.CreateOptions{})
+	_, err := m.client.CoreV1().Services(session.Namespace()).Create(ctx, service, metav1.CreateOptions{})
 	return err
 }
 
-func (m *KubernetesSessionManager) sessionServiceOwnerReferences(ctx context.Context, sessionID string) []metav1.OwnerReference {
+func (m *KubernetesSessionManager) sessionServiceOwnerReferences(ctx context.Context, namespace, sessionID string) []metav1.OwnerReference {
 	if m == nil || m.client == nil {
 		return nil
 	}
+	// Owner references cannot cross namespaces; objects kept in the proxy's
+	// namespace for sessions placed elsewhere are cleaned up explicitly.
+	if namespace != m.namespaceOf(sessionID) {
+		return nil
+	}
 	svcName := fmt.Sprintf("agentapi-session-%s-svc", sessionID)
-	svc, err := m.client.CoreV1().Services(m.namespace).Get(ctx, svcName, metav1.GetOptions{})
+	svc, err := m.client.CoreV1().Services(namespace).Get(ctx, svcName, metav1.GetOptions{})
 	if err != nil {
 		log.Printf("[K8S_SESSION] Warning: failed to get Service %s for owner reference: %v", svcName, err)
 		return nil
@@ -3376,6 +3683,7 @@ func (m *KubernetesSessionManager) watchSession(ctx context.Context, session *Ku
 
 		case <-timeout:
 			log.Printf("[K8S_SESSION] Session %s startup timeout", session.id)
+			m.recordEvent(session.id, entities.SessionEventStartupTimeout, "Pod did not become ready within %ds", m.k8sConfig.PodStartTimeout)
 			session.SetStatus("timeout")
 			return
 
@@ -3390,14 +3698,22 @@ func (m *KubernetesSessionManager) watchSession(ctx context.Context, session *Ku
 				continue
 			}
 
+			// A Job may fail before its Pod ever becomes ready.
+			if session.RunsAsJob() && m.finishJobSessionIfDone(ctx, session) {
+				return
+			}
+
 			// Check workload status
 			if ready {
 				session.SetStatus("starting")
 				log.Printf("[K8S_SESSION] Session %s Pod is ready", session.id)
+				m.refreshRouteHost(ctx, session)
+				m.recordEvent(session.id, entities.SessionEventDeploymentReady, "Pod %s is ready", session.DeploymentName())
 
 				log.Printf("[K8S_SESSION] Waiting for pull provision request to become ready for session %s", session.id)
 				if err := m.waitForPullProvisioner(ctx, session); err != nil {
 					log.Printf("[K8S_SESSION] Pull provisioner error for session %s: %v", session.id, err)
+					m.recordEvent(session.id, entities.SessionEventProvisionFailed, "Provisioning failed: %v", err)
 					session.SetStatus("error")
 					return
 				}
@@ -3407,15 +3723,22 @@ func (m *KubernetesSessionManager) watchSession(ctx context.Context, session *Ku
 					if err := m.createSessionSettingsSecretFromSettings(ctx, session, session.Request(), ps); err != nil {
 						log.Printf("[K8S_SESSION] Warning: failed to create settings secret for session %s: %v", session.id, err)
 						// Non-fatal: session works without it, but Pod restart will require re-provisioning
+					} else {
+						m.recordEvent(session.id, entities.SessionEventSecretCreated, "Settings Secret created for Pod restart recovery")
 					}
 				}
 
 				session.SetStatus("active")
 				log.Printf("[K8S_SESSION] Session %s is now active", session.id)
+				m.recordEvent(session.id, entities.SessionEventProvisioned, "Agent provisioned")
 
 				// Continue watching deployment health and agentapi runtime status.
 				go m.watchAgentAPIStatus(ctx, session)
-				m.watchDeploymentStatus(ctx, session)
+				if session.RunsAsJob() {
+					m.watchJobStatus(ctx, session)
+				} else {
+					m.watchDeploymentStatus(ctx, session)
+				}
 				return
 			}
 
@@ -3428,6 +3751,10 @@ func (m *KubernetesSessionManager) watchSession(ctx context.Context, session *Ku
 func (m *KubernetesSessionManager) watchDeploymentStatus(ctx context.Context, session *KubernetesSession) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
+	var health podHealth
+	m.checkPodRestarts(ctx, session, &health)
+	m.refreshRouteHost(ctx, session)
+	go m.watchSessionHealth(ctx, session)
 
 	for {
 		select {
@@ -3438,21 +3765,51 @@ func (m *KubernetesSessionManager) watchDeploymentStatus(ctx context.Context, se
 			ready, err := m.isSessionWorkloadReady(context.Background(), session)
 			if err != nil {
 				if errors.IsNotFound(err) {
+					// An evicted Pod without a Deployment is gone for good
+					// unless it is recreated.
+					if health.evictedPod != "" {
+						if m.recreateEvictedPod(ctx, session) {
+							continue
+						}
+						session.SetStatus("evicted")
+						return
+					}
 					session.SetStatus("stopped")
 					return
 				}
 				continue
 			}
 
+			// Restarts take the Pod out of ready, so checking while it is
+			// not ready (and once it recovers) catches them.
+			if !ready || session.Status() == "unhealthy" {
+				m.checkPodRestarts(ctx, session, &health)
+			}
+
 			if !ready {
-				session.SetStatus("unhealthy")
+				// A paused Deployment has no ready replicas on purpose, and a
+				// resuming one is expected to take a while to become ready.
+				switch {
+				case session.Status() == "resuming":
+				case m.isSessionPaused(context.Background(), session):
+					session.SetStatus("paused")
+				case m.checkPodEviction(ctx, session, &health):
+					session.SetStatus("evicted")
+					m.recreateEvictedPod(ctx, session)
+				default:
+					session.SetStatus("unhealthy")
+				}
 			} else {
+				// The Pod IP changes when the Pod is recreated.
+				m.refreshRouteHost(ctx, session)
 				// Only recover to "active" from a bad state.
 				// Do not overwrite "running" (agentapi is processing a message).
 				current := session.Status()
-				if current == "unhealthy" || current == "stopped" || current == "error" || current == "timeout" {
+				if current == "unhealthy" || current == "stopped" || current == "error" || current == "timeout" ||
+					current == "paused" || current == "resuming" || current == "evicted" {
 					session.SetStatus("active")
 				}
+				health.evictedPod = ""
 			}
 		}
 	}
@@ -3468,25 +3825,35 @@ func (m *KubernetesSessionManager) deleteSessionResources(ctx context.Context, s
 	var errs []string
 
 	// Delete Service
-	err := m.client.CoreV1().Services(m.namespace).Delete(ctx, session.ServiceName(), deleteOptions)
+	err := m.client.CoreV1().Services(session.Namespace()).Delete(ctx, session.ServiceName(), deleteOptions)
 	if err != nil && !errors.IsNotFound(err) {
 		errs = append(errs, fmt.Sprintf("service: %v", err))
 	}
 
 	// Delete workload. Try both kinds so sessions created before a PVC setting
 	// change are cleaned up correctly.
-	err = m.client.AppsV1().Deployments(m.namespace).Delete(ctx, session.DeploymentName(), deleteOptions)
+	err = m.client.AppsV1().Deployments(session.Namespace()).Delete(ctx, session.DeploymentName(), deleteOptions)
 	if err != nil && !errors.IsNotFound(err) {
 		errs = append(errs, fmt.Sprintf("deployment: %v", err))
 	}
-	err = m.client.CoreV1().Pods(m.namespace).Delete(ctx, session.DeploymentName(), deleteOptions)
+	err = m.client.CoreV1().Pods(session.Namespace()).Delete(ctx, session.DeploymentName(), deleteOptions)
 	if err != nil && !errors.IsNotFound(err) {
 		errs = append(errs, fmt.Sprintf("pod: %v", err))
 	}
+	if session.RunsAsJob() {
+		err = m.client.BatchV1().Jobs(session.Namespace()).Delete(ctx, session.DeploymentName(), deleteOptions)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf("job: %v", err))
+		}
+	}
+
+	if err := m.deleteSessionDisruptionBudget(ctx, session); err != nil {
+		errs = append(errs, fmt.Sprintf("pdb: %v", err))
+	}
 
 	// Delete PVC if present. Do not depend on the current PVC setting because
 	// old sessions may predate the setting.
-	err = m.client.CoreV1().PersistentVolumeClaims(m.namespace).Delete(ctx, session.PVCName(), deleteOptions)
+	err = m.client.CoreV1().PersistentVolumeClaims(session.Namespace()).Delete(ctx, session.PVCName(), deleteOptions)
 	if err != nil && !errors.IsNotFound(err) {
 		errs = append(errs, fmt.Sprintf("pvc: %v", err))
 	}
@@ -3510,6 +3877,16 @@ func (m *KubernetesSessionManager) deleteSessionResources(ctx context.Context, s
 		errs = append(errs, fmt.Sprintf("provision-request-secret: %v", err))
 	}
 
+	if err := m.teardownPreview(ctx, session); err != nil {
+		errs = append(errs, fmt.Sprintf("preview-environment: %v", err))
+	}
+
+	if err := m.deleteSessionEvents(ctx, session.id); err != nil {
+		errs = append(errs, fmt.Sprintf("session-events: %v", err))
+	}
+
+	m.deleteSessionRecord(ctx, session.id)
+
 	if len(errs) > 0 {
 		return fmt.Errorf("failed to delete resources: %s", strings.Join(errs, ", "))
 	}
@@ -3522,6 +3899,8 @@ func (m *KubernetesSessionManager) cleanupSession(id string) {
 	m.mutex.Lock()
 	delete(m.sessions, id)
 	m.mutex.Unlock()
+	m.watchers.stop(id)
+	m.forgetActivity(id)
 	// Close all per-session message subscribers to unblock any waiting long-poll handlers.
 	// Called after releasing m.mutex to avoid holding two locks simultaneously.
 	m.cleanupMessageSubs(id)
@@ -3584,7 +3963,7 @@ func (m *KubernetesSessionManager) buildLabels(session *KubernetesSession) map[s
 
 	// Add tags as labels (sanitized for Kubernetes)
 	for k, v := range session.Request().Tags {
-		labelKey := fmt.Sprintf("agentapi.proxy/tag-%s", sanitizeLabelKey(k))
+		labelKey := sessionTagLabelPrefix + sanitizeLabelKey(k)
 		labels[labelKey] = sanitizeLabelValue(v)
 	}
 
@@ -3595,6 +3974,15 @@ func (m *KubernetesSessionManager) buildLabels(session *KubernetesSession) map[s
 	// Sandbox (network filter) is always enabled
 	labels["agentapi.proxy/capability-sandbox"] = "true"
 	labels["agentapi.proxy/capability-dind"] = fmt.Sprintf("%t", req.Docker != nil && req.Docker.Enabled)
+	if editorEnabled(req) {
+		labels[editorCapabilityLabel] = "true"
+	}
+	if browserEnabled(req) {
+		labels[browserCapabilityLabel] = "true"
+	}
+	if terminalEnabled(req) {
+		labels[terminalCapabilityLabel] = "true"
+	}
 	if req.AgentType != "" {
 		labels["agentapi.proxy/agent-type"] = sanitizeLabelValue(req.AgentType)
 	}
@@ -3626,18 +4014,24 @@ func (m *KubernetesSessionManager) buildEnvVars(session *KubernetesSession, req
 		envVars = append(envVars, corev1.EnvVar{Name: "AGENTAPI_TEAM_ID", Value: req.TeamID})
 	}
 
+	// Explicit env wins over the GITHUB_URL/GITHUB_API of the envFrom GitHub Secrets
+	if instance, _, ok := m.githubInstance(egressTeamID(req)); ok {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "GITHUB_URL", Value: instance.URL},
+			corev1.EnvVar{Name: "GITHUB_API", Value: instance.APIURL},
+		)
+	}
+
 	// Add Agent Type if specified
 	if req.AgentType != "" {
 		envVars = append(envVars, corev1.EnvVar{Name: "AGENTAPI_AGENT_TYPE", Value: req.AgentType})
 	}
 
-	proxyURL := m.k8sConfig.ProvisionerProxyURL
-	if proxyURL == "" {
-		proxyURL = fmt.Sprintf("http://agentapi-proxy.%s.svc.cluster.local:8080", m.namespace)
-	}
+	proxyURL := m.provisionerProxyURL()
 	envVars = append(envVars,
 		corev1.EnvVar{Name: "PROVISIONER_PROXY_URL", Value: proxyURL},
 		corev1.EnvVar{Name: "PROVISIONER_TOKEN", Value: m.k8sConfig.ProvisionerToken},
+		corev1.EnvVar{Name: "PROVISIONER_SESSION_TOKEN", Value: m.SessionToken(session.id)},
 		corev1.EnvVar{
 			Name: "POD_NAME",
 			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{
@@ -3704,44 +4098,7 @@ func (m *KubernetesSessionManager) buildEnvVars(session *KubernetesSession, req
 	return envVars
 }
 
-const (
-	piOllamaCommandPath      = "/home/agentapi/.session/pi-ollama-pi"
-	piOllamaInstallPreScript = `mkdir -p "$HOME/.pi/agent/npm"
-test -f "$HOME/.pi/agent/npm/package.json" || printf '{"private":true,"dependencies":{}}\n' > "$HOME/.pi/agent/npm/package.json"
-if [ -d "$HOME/.pi/agent/npm/node_modules/pi-ollama-cloud" ] && [ -d "$HOME/.pi/agent/npm/node_modules/pi-mcp-adapter" ]; then
-  echo "Pi extensions already installed, skipping install"
-else
-  NPM_SHIM_DIR="$(mktemp -d)"
-  trap 'rm -rf "$NPM_SHIM_DIR"' EXIT
-  cat > "$NPM_SHIM_DIR/npm" <<'EOF'
-#!/bin/sh
-set -e
-prefix=""
-packages=""
-while [ "$#" -gt 0 ]; do
-  case "$1" in
-    install) shift ;;
-    --prefix) prefix="$2"; shift 2 ;;
-    --legacy-peer-deps) shift ;;
-    *) packages="$packages $1"; shift ;;
-  esac
-done
-if [ -z "$prefix" ]; then prefix="$PWD"; fi
-mkdir -p "$prefix"
-test -f "$prefix/package.json" || printf '%s\n' '{"private":true,"dependencies":{}}' > "$prefix/package.json"
-exec bun add --cwd "$prefix" $packages
-EOF
-  chmod +x "$NPM_SHIM_DIR/npm"
-  if [ ! -d "$HOME/.pi/agent/npm/node_modules/pi-ollama-cloud" ]; then
-    PATH="$NPM_SHIM_DIR:$PATH" pi install npm:pi-ollama-cloud
-  fi
-  if [ ! -d "$HOME/.pi/agent/npm/node_modules/pi-mcp-adapter" ]; then
-    PATH="$NPM_SHIM_DIR:$PATH" pi install npm:pi-mcp-adapter
-  fi
-  rm -rf "$NPM_SHIM_DIR"
-  trap - EXIT
-fi`
-)
+const piOllamaCommandPath = "/home/agentapi/.session/pi-ollama-pi"
 
 func ensurePiOllamaPodEnv(envVars []corev1.EnvVar) []corev1.EnvVar {
 	values := make(map[string]string, len(envVars))
@@ -3769,7 +4126,7 @@ func ensurePiOllamaSettingsEnv(env map[string]string) {
 
 // sanitizeLabelKey sanitizes a string to be used as a Kubernetes label key
 // patchLastMessageAt applies a MergePatch to update the last-message-at annotation.
-func (m *KubernetesSessionManager) patchLastMessageAt(ctx context.Context, svcName string, t time.Time) error {
+func (m *KubernetesSessionManager) patchLastMessageAt(ctx context.Context, namespace, svcName string, t time.Time) error {
 	patch := map[string]interface{}{
 		"metadata": map[string]interface{}{
 			"annotations": map[string]string{
@@ -3781,7 +4138,7 @@ func (m *KubernetesSessionManager) patchLastMessageAt(ctx context.Context, svcNa
 	if err != nil {
 		return fmt.Errorf("failed to marshal patch: %w", err)
 	}
-	_, err = m.client.CoreV1().Services(m.namespace).Patch(
+	_, err = m.client.CoreV1().Services(namespace).Patch(
 		ctx, svcName, types.MergePatchType, patchBytes, metav1.PatchOptions{})
 	return err
 }
@@ -3969,6 +4326,7 @@ func (m *KubernetesSessionManager) broadcastMessageUpdate(sessionID string) {
 	m.mutex.RUnlock()
 	if exists {
 		session.SetLastMessageAt(now)
+		m.RecordActivity(sessionID)
 	}
 
 	evt := SessionMessageEvent{SessionID: sessionID, Timestamp: now}
@@ -4142,8 +4500,8 @@ func (m *KubernetesSessionManager) streamAgentAPIEvents(ctx context.Context, ses
 					session.SetStatus("running")
 					log.Printf("[AGENT_STATUS] Session %s is now running", session.id)
 				case "stable":
-					session.SetStatus("active")
-					log.Printf("[AGENT_STATUS] Session %s is now stable (active)", session.id)
+					m.agentStable(ctx, session)
+					log.Printf("[AGENT_STATUS] Session %s is now stable (%s)", session.id, session.Status())
 				}
 			case "message_update":
 				log.Printf("[AGENT_MSG] Session %s: message_update received", session.id)
@@ -4166,9 +4524,15 @@ func (m *KubernetesSessionManager) GetPersonalAPIKeyRepository() portrepos.Perso
 
 // getSessionStatusFromDeployment determines session status from Deployment state
 func (m *KubernetesSessionManager) getSessionStatusFromDeployment(sessionID string) string {
+	return m.getSessionStatusFromWorkload(m.namespaceOf(sessionID), sessionID)
+}
+
+// getSessionStatusFromWorkload determines the status of a session from its
+// Deployment or Pod in namespace.
+func (m *KubernetesSessionManager) getSessionStatusFromWorkload(namespace, sessionID string) string {
 	deploymentName := fmt.Sprintf("agentapi-session-%s", sessionID)
 	if !m.isPVCEnabled() {
-		pod, err := m.client.CoreV1().Pods(m.namespace).Get(
+		pod, err := m.client.CoreV1().Pods(namespace).Get(
 			context.Background(), deploymentName, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
@@ -4185,7 +4549,7 @@ func (m *KubernetesSessionManager) getSessionStatusFromDeployment(sessionID stri
 		return "starting"
 	}
 
-	deployment, err := m.client.AppsV1().Deployments(m.namespace).Get(
+	deployment, err := m.client.AppsV1().Deployments(namespace).Get(
 		context.Background(), deploymentName, metav1.GetOptions{})
 
 	if err != nil {
@@ -4195,6 +4559,9 @@ func (m *KubernetesSessionManager) getSessionStatusFromDeployment(sessionID stri
 		return "unknown"
 	}
 
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0 {
+		return "paused"
+	}
 	if deployment.Status.ReadyReplicas > 0 {
 		return "active"
 	}
@@ -4205,15 +4572,18 @@ func (m *KubernetesSessionManager) getSessionStatusFromDeployment(sessionID stri
 }
 
 func (m *KubernetesSessionManager) isSessionWorkloadReady(ctx context.Context, session *KubernetesSession) (bool, error) {
+	if session.RunsAsJob() {
+		return m.isJobReady(ctx, session)
+	}
 	if m.isPVCEnabled() {
-		deployment, err := m.client.AppsV1().Deployments(m.namespace).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+		deployment, err := m.client.AppsV1().Deployments(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
 		if err != nil {
 			return false, err
 		}
 		return deployment.Status.ReadyReplicas > 0, nil
 	}
 
-	pod, err := m.client.CoreV1().Pods(m.namespace).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	pod, err := m.client.CoreV1().Pods(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -4286,14 +4656,7 @@ func (m *KubernetesSessionManager) restoreSessionFromService(svc *corev1.Service
 	sessionID := svc.Labels["agentapi.proxy/session-id"]
 	userID := svc.Labels["agentapi.proxy/user-id"]
 
-	// Restore tags from labels
-	tags := make(map[string]string)
-	for k, v := range svc.Labels {
-		if strings.HasPrefix(k, "agentapi.proxy/tag-") {
-			tagKey := strings.TrimPrefix(k, "agentapi.proxy/tag-")
-			tags[tagKey] = v
-		}
-	}
+	tags := restoreTagsFromService(svc)
 
 	// Restore scope from labels
 	scope := entities.ResourceScope(svc.Labels["agentapi.proxy/scope"])
@@ -4310,9 +4673,9 @@ func (m *KubernetesSessionManager) restoreSessionFromService(svc *corev1.Service
 	restoreCtx := context.Background()
 	initialMessage := svc.Annotations["agentapi.proxy/initial-message"]
 	if initialMessage == "" {
-		initialMessage = m.getInitialMessageFromSecret(restoreCtx, svc.Name)
+		initialMessage = m.getInitialMessageFromSecret(restoreCtx, m.serviceNamespace(svc), svc.Name)
 	}
-	sessionMeta := m.getSessionMetaFromSecret(restoreCtx, svc.Name)
+	sessionMeta := m.getSessionMetaFromSecret(restoreCtx, m.serviceNamespace(svc), svc.Name)
 
 	// Extract MemoryKey, Teams, and Oneshot from session meta if available
 	var memoryKey map[string]string
@@ -4364,50 +4727,84 @@ func (m *KubernetesSessionManager) restoreSessionFromService(svc *corev1.Service
 	agentType := restoreAgentTypeFromService(svc)
 
 	// Create session using constructor
+	req, webhookPayload := m.applySessionRecord(sessionID, &entities.RunServerRequest{
+		UserID:                userID,
+		Tags:                  tags,
+		Scope:                 scope,
+		TeamID:                teamID,
+		InitialMessage:        initialMessage,
+		MemoryKey:             memoryKey,
+		Teams:                 teams,
+		Oneshot:               oneshot,
+		SessionTTL:            sessionTTL,
+		AgentType:             agentType,
+		Editor:                restoreEditorFromService(svc),
+		Browser:               restoreBrowserFromService(svc),
+		Terminal:              restoreTerminalFromService(svc),
+		Replicas:              restoreReplicasFromService(svc),
+		Accelerator:           restoreAcceleratorFromService(svc),
+		Image:                 restoreImageFromService(svc),
+		CompletionCallbackURL: svc.Annotations[completionCallbackAnnotation],
+		GitHubReport:          restoreGitHubReportFromService(svc),
+	})
 	session := NewKubernetesSession(
 		sessionID,
-		&entities.RunServerRequest{
-			UserID:         userID,
-			Tags:           tags,
-			Scope:          scope,
-			TeamID:         teamID,
-			InitialMessage: initialMessage,
-			MemoryKey:      memoryKey,
-			Teams:          teams,
-			Oneshot:        oneshot,
-			SessionTTL:     sessionTTL,
-			AgentType:      agentType,
-		},
+		req,
 		fmt.Sprintf("agentapi-session-%s", sessionID),
 		svc.Name,
 		fmt.Sprintf("agentapi-session-%s-pvc", sessionID),
-		m.namespace,
+		m.serviceNamespace(svc),
 		servicePort,
 		cancel,
-		nil, // No webhook payload for restored sessions
+		webhookPayload, // Only available when a session store is configured
 	)
 	// Set restored values
 	session.SetStartedAt(createdAt)
 	session.SetUpdatedAt(updatedAt)
 	session.SetLastMessageAt(lastMessageAt)
-	session.SetStatus(m.getSessionStatusFromDeployment(sessionID))
+	session.SetStatus(m.getSessionStatusFromWorkload(m.serviceNamespace(svc), sessionID))
 	session.SetDescription(initialMessage) // Cache initial message as description
 	session.SetAnnotations(sessionAnnotationsFromMap(svc.Annotations))
+	session.SetCapabilities(restoreCapabilitiesFromService(svc))
+	session.SetPreview(restorePreviewFromService(svc))
+	m.restoreJobFromService(context.Background(), session, svc)
+	restoreRunCompletionFromService(session, svc)
+	restoreSummaryFromService(session, svc)
 
 	// Register proxy-wide status change broadcaster
 	session.statusChangeCallback = m.broadcastStatusChange
+	session.clusterDomain = m.clusterDomain()
 
-	// Add to memory map
-	m.mutex.Lock()
-	m.sessions[sessionID] = session
-	m.mutex.Unlock()
+	if existing := m.adoptRestoredSession(ctx, session); existing != session {
+		return existing
+	}
+	log.Printf("[K8S_SESSION] Restored session %s from Service", sessionID)
 
-	// Start watching deployment health and agentapi runtime status.
-	go m.watchDeploymentStatus(ctx, session)
-	go m.watchAgentAPIStatus(ctx, session)
+	return session
+}
 
-	log.Printf("[K8S_SESSION] Restored session %s from Service", sessionID)
+// adoptRestoredSession stores a restored session and starts watching its
+// workload health and agentapi runtime status. When a concurrent restore
+// stored the session first, the duplicate is discarded and the stored
+// session is returned instead, so no second set of watchers is started.
+func (m *KubernetesSessionManager) adoptRestoredSession(ctx context.Context, session *KubernetesSession) *KubernetesSession {
+	m.mutex.Lock()
+	if existing, ok := m.sessions[session.id]; ok {
+		m.mutex.Unlock()
+		session.Cancel()
+		return existing
+	}
+	m.sessions[session.id] = session
+	m.mutex.Unlock()
 
+	m.watchers.start(ctx, session.id, func(ctx context.Context) {
+		go m.watchAgentAPIStatus(ctx, session)
+		if session.RunsAsJob() {
+			m.watchJobStatus(ctx, session)
+			return
+		}
+		m.watchDeploymentStatus(ctx, session)
+	})
 	return session
 }
 
@@ -4417,14 +4814,7 @@ func (m *KubernetesSessionManager) restoreSessionFromServiceWithWorkload(svc *co
 	sessionID := svc.Labels["agentapi.proxy/session-id"]
 	userID := svc.Labels["agentapi.proxy/user-id"]
 
-	// Restore tags from labels
-	tags := make(map[string]string)
-	for k, v := range svc.Labels {
-		if strings.HasPrefix(k, "agentapi.proxy/tag-") {
-			tagKey := strings.TrimPrefix(k, "agentapi.proxy/tag-")
-			tags[tagKey] = v
-		}
-	}
+	tags := restoreTagsFromService(svc)
 
 	// Restore scope from labels
 	scope := entities.ResourceScope(svc.Labels["agentapi.proxy/scope"])
@@ -4441,9 +4831,9 @@ func (m *KubernetesSessionManager) restoreSessionFromServiceWithWorkload(svc *co
 	restoreCtx := context.Background()
 	initialMessage := svc.Annotations["agentapi.proxy/initial-message"]
 	if initialMessage == "" {
-		initialMessage = m.getInitialMessageFromSecret(restoreCtx, svc.Name)
+		initialMessage = m.getInitialMessageFromSecret(restoreCtx, m.serviceNamespace(svc), svc.Name)
 	}
-	sessionMeta := m.getSessionMetaFromSecret(restoreCtx, svc.Name)
+	sessionMeta := m.getSessionMetaFromSecret(restoreCtx, m.serviceNamespace(svc), svc.Name)
 
 	// Extract MemoryKey, Teams, and Oneshot from session meta if available
 	var memoryKey map[string]string
@@ -4495,27 +4885,36 @@ func (m *KubernetesSessionManager) restoreSessionFromServiceWithWorkload(svc *co
 	agentType := restoreAgentTypeFromService(svc)
 
 	// Create session using constructor
+	req, webhookPayload := m.applySessionRecord(sessionID, &entities.RunServerRequest{
+		UserID:                userID,
+		Tags:                  tags,
+		Scope:                 scope,
+		TeamID:                teamID,
+		InitialMessage:        initialMessage,
+		MemoryKey:             memoryKey,
+		Teams:                 teams,
+		Oneshot:               oneshot,
+		SessionTTL:            sessionTTL,
+		AgentType:             agentType,
+		Editor:                restoreEditorFromService(svc),
+		Browser:               restoreBrowserFromService(svc),
+		Terminal:              restoreTerminalFromService(svc),
+		Replicas:              restoreReplicasFromService(svc),
+		Accelerator:           restoreAcceleratorFromService(svc),
+		Image:                 restoreImageFromService(svc),
+		CompletionCallbackURL: svc.Annotations[completionCallbackAnnotation],
+		GitHubReport:          restoreGitHubReportFromService(svc),
+	})
 	session := NewKubernetesSession(
 		sessionID,
-		&entities.RunServerRequest{
-			UserID:         userID,
-			Tags:           tags,
-			Scope:          scope,
-			TeamID:         teamID,
-			InitialMessage: initialMessage,
-			MemoryKey:      memoryKey,
-			Teams:          teams,
-			Oneshot:        oneshot,
-			SessionTTL:     sessionTTL,
-			AgentType:      agentType,
-		},
+		req,
 		fmt.Sprintf("agentapi-session-%s", sessionID),
 		svc.Name,
 		fmt.Sprintf("agentapi-session-%s-pvc", sessionID),
-		m.namespace,
+		m.serviceNamespace(svc),
 		servicePort,
 		cancel,
-		nil, // No webhook payload for restored sessions
+		webhookPayload, // Only available when a session store is configured
 	)
 	// Set restored values
 	session.SetStartedAt(createdAt)
@@ -4524,19 +4923,19 @@ func (m *KubernetesSessionManager) restoreSessionFromServiceWithWorkload(svc *co
 	session.SetStatus(m.getStatusFromWorkloadObject(deployment, pod))
 	session.SetDescription(initialMessage) // Cache initial message as description
 	session.SetAnnotations(sessionAnnotationsFromMap(svc.Annotations))
+	session.SetCapabilities(restoreCapabilitiesFromService(svc))
+	session.SetPreview(restorePreviewFromService(svc))
+	m.restoreJobFromService(context.Background(), session, svc)
+	restoreRunCompletionFromService(session, svc)
+	restoreSummaryFromService(session, svc)
 
 	// Register proxy-wide status change broadcaster
 	session.statusChangeCallback = m.broadcastStatusChange
+	session.clusterDomain = m.clusterDomain()
 
-	// Add to memory map
-	m.mutex.Lock()
-	m.sessions[sessionID] = session
-	m.mutex.Unlock()
-
-	// Start watching workload health and agentapi runtime status.
-	go m.watchDeploymentStatus(ctx, session)
-	go m.watchAgentAPIStatus(ctx, session)
-
+	if existing := m.adoptRestoredSession(ctx, session); existing != session {
+		return existing
+	}
 	log.Printf("[K8S_SESSION] Restored session %s from Service (with pre-fetched workload)", sessionID)
 
 	return session
@@ -4561,6 +4960,9 @@ func (m *KubernetesSessionManager) getStatusFromWorkloadObject(deployment *appsv
 		return "stopped"
 	}
 
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0 {
+		return "paused"
+	}
 	if deployment.Status.ReadyReplicas > 0 {
 		return "active"
 	}
@@ -4698,6 +5100,31 @@ func (m *KubernetesSessionManager) buildServicePorts(session *KubernetesSession)
 		},
 	}
 
+	if editorEnabled(session.Request()) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "editor",
+			Port:       EditorPort,
+			TargetPort: intstr.FromInt(EditorPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	if browserEnabled(session.Request()) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "browser-vnc",
+			Port:       BrowserVNCPort,
+			TargetPort: intstr.FromInt(BrowserVNCPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	if terminalEnabled(session.Request()) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "terminal",
+			Port:       TerminalPort,
+			TargetPort: intstr.FromInt(TerminalPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+
 	// Add metrics port if otelcol is enabled
 	if m.k8sConfig.OtelCollectorEnabled {
 		exporterPort := 9090
@@ -4720,6 +5147,8 @@ const (
 	sessionAnnotationIssueURL    = "agentapi.proxy/session-annotation-issue-url"
 	sessionAnnotationDescription = "agentapi.proxy/session-annotation-description"
 	sessionAnnotationRunningTask = "agentapi.proxy/session-annotation-running-task"
+	sessionAnnotationSummary     = "agentapi.proxy/session-annotation-summary"
+	sessionAnnotationObserver    = "agentapi.proxy/session-annotation-observer-mode"
 )
 
 func sessionAnnotationsFromMap(annotations map[string]string) entities.SessionAnnotations {
@@ -4727,10 +5156,12 @@ func sessionAnnotationsFromMap(annotations map[string]string) entities.SessionAn
 		return entities.SessionAnnotations{}
 	}
 	return entities.SessionAnnotations{
-		PRURL:       annotations[sessionAnnotationPRURL],
-		IssueURL:    annotations[sessionAnnotationIssueURL],
-		Description: annotations[sessionAnnotationDescription],
-		RunningTask: annotations[sessionAnnotationRunningTask],
+		PRURL:        annotations[sessionAnnotationPRURL],
+		IssueURL:     annotations[sessionAnnotationIssueURL],
+		Description:  annotations[sessionAnnotationDescription],
+		RunningTask:  annotations[sessionAnnotationRunningTask],
+		Summary:      annotations[sessionAnnotationSummary],
+		ObserverMode: annotations[sessionAnnotationObserver] == "true",
 	}
 }
 
@@ -4770,7 +5201,7 @@ func (m *KubernetesSessionManager) UpdateSessionAnnotations(ctx context.Context,
 		return entities.SessionAnnotations{}, fmt.Errorf("session is not a KubernetesSession")
 	}
 
-	svc, err := m.client.CoreV1().Services(m.namespace).Get(ctx, ks.ServiceName(), metav1.GetOptions{})
+	svc, err := m.client.CoreV1().Services(ks.Namespace()).Get(ctx, ks.ServiceName(), metav1.GetOptions{})
 	if err != nil {
 		return entities.SessionAnnotations{}, fmt.Errorf("failed to get service: %w", err)
 	}
@@ -4784,7 +5215,7 @@ func (m *KubernetesSessionManager) UpdateSessionAnnotations(ctx context.Context,
 	setSessionAnnotationValue(svc.Annotations, sessionAnnotationDescription, updated.Description)
 	setSessionAnnotationValue(svc.Annotations, sessionAnnotationRunningTask, updated.RunningTask)
 
-	if _, err := m.client.CoreV1().Services(m.namespace).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+	if _, err := m.client.CoreV1().Services(ks.Namespace()).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
 		return entities.SessionAnnotations{}, fmt.Errorf("failed to update service annotations: %w", err)
 	}
 
@@ -4812,7 +5243,7 @@ func (m *KubernetesSessionManager) UpdateServiceAnnotation(ctx context.Context,
 	serviceName := ks.ServiceName()
 
 	// Get the current Service
-	svc, err := m.client.CoreV1().Services(m.namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	svc, err := m.client.CoreV1().Services(ks.Namespace()).Get(ctx, serviceName, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to get service: %w", err)
 	}
@@ -4824,7 +5255,7 @@ func (m *KubernetesSessionManager) UpdateServiceAnnotation(ctx context.Context,
 	svc.Annotations[key] = value
 
 	// Update the Service
-	_, err = m.client.CoreV1().Services(m.namespace).Update(ctx, svc, metav1.UpdateOptions{})
+	_, err = m.client.CoreV1().Services(ks.Namespace()).Update(ctx, svc, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update service annotation: %w", err)
 	}
@@ -4834,7 +5265,7 @@ func (m *KubernetesSessionManager) UpdateServiceAnnotation(ctx context.Context,
 
 // GetInitialMessage retrieves the initial message from Secret for a given session
 func (m *KubernetesSessionManager) GetInitialMessage(ctx context.Context, session *KubernetesSession) string {
-	return m.getInitialMessageFromSecret(ctx, session.ServiceName())
+	return m.getInitialMessageFromSecret(ctx, session.Namespace(), session.ServiceName())
 }
 
 // generatePersonalAPIKey generates a random API key for personal use
@@ -4903,6 +5334,8 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 		MemoryKey: req.MemoryKey,
 	}
 	settings.UnsyncedFilePaths = append([]string(nil), req.UnsyncedFilePaths...)
+	settings.SetupHooks = SetupHookSettings(req.SetupHooks)
+	settings.PostSessionHooks = SetupHookSettings(req.PostSessionHooks)
 
 	// Build env vars (mirrors buildEnvVars logic from line 2695)
 	env := map[string]string{
@@ -4974,9 +5407,9 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 			secretNames = append(secretNames, m.k8sConfig.GitHubConfigSecretName)
 		}
 		env["GITHUB_TOKEN"] = req.GithubToken
-	} else if m.k8sConfig.GitHubSecretName != "" {
+	} else if githubSecretName := m.githubSecretName(req); githubSecretName != "" {
 		// When params.github_token is NOT provided
-		secretNames = append(secretNames, m.k8sConfig.GitHubSecretName)
+		secretNames = append(secretNames, githubSecretName)
 		if m.k8sConfig.GitHubConfigSecretName != "" {
 			secretNames = append(secretNames, m.k8sConfig.GitHubConfigSecretName)
 		}
@@ -5042,6 +5475,19 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 	}
 
 	m.injectSciaProxyEnv(env, req)
+	m.injectLLMProxyEnv(env, session.id)
+	m.injectEgressProxyEnv(env, req)
+	if m.config != nil {
+		for key, value := range m.config.AirGap.SessionEnv() {
+			env[key] = value
+		}
+	}
+	// The configured GitHub instance wins over GITHUB_URL/GITHUB_API of the
+	// GitHub Secrets and settings
+	if instance, _, ok := m.githubInstance(egressTeamID(req)); ok {
+		env["GITHUB_URL"] = instance.URL
+		env["GITHUB_API"] = instance.APIURL
+	}
 
 	// Memory integration: generate MEMORY_KEY_FLAGS and AGENTAPI_SCOPE for startup script
 	// and memory-sync sidecar. Flags are sorted for deterministic shell script expansion.
@@ -5180,26 +5626,16 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 			Token:            req.GithubToken,
 			ConfigSecretName: m.k8sConfig.GitHubConfigSecretName,
 		}
-	} else if m.k8sConfig.GitHubSecretName != "" {
+	} else if githubSecretName := m.githubSecretName(req); githubSecretName != "" {
 		settings.Github = &sessionsettings.GithubConfig{
-			SecretName:       m.k8sConfig.GitHubSecretName,
+			SecretName:       githubSecretName,
 			ConfigSecretName: m.k8sConfig.GitHubConfigSecretName,
 		}
 	}
 
-	// Startup command (simplified version for now - full command logic in pod)
-	switch req.AgentType {
-	case "claude-acp":
-		// acp-server bridges claude-agent-acp (ACP over stdio) to the agentapi HTTP interface.
-		settings.Startup = sessionsettings.StartupConfig{
-			Command: []string{"agentapi-proxy"},
-			Args: []string{
-				"acp-server",
-				"--port", fmt.Sprintf("%d", m.k8sConfig.BasePort),
-				"--",
-				"bunx", "@agentclientprotocol/claude-agent-acp",
-			},
-		}
+	// Startup command, rendered from the per-agent-type startup templates
+	settings.Startup = m.startupConfig(ctx, session, req, env)
+	if req.AgentType == "claude-acp" {
 		// Bypass permission prompts for claude-acp sessions so tool calls
 		// proceed without waiting for user approval.
 		if settingsJSON == nil {
@@ -5208,54 +5644,6 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 		settingsJSON["permissions"] = map[string]interface{}{
 			"defaultMode": "bypassPermissions",
 		}
-	case "codex-acp":
-		// acp-server bridges codex-acp (ACP adapter for OpenAI Codex) to the agentapi HTTP interface.
-		// https://github.com/agentclientprotocol/codex-acp
-		// --auto-approve bypasses the UI permission modal at the ACP bridge layer.
-		settings.Startup = sessionsettings.StartupConfig{
-			Command: []string{"agentapi-proxy"},
-			Args: []string{
-				"acp-server",
-				"--port", fmt.Sprintf("%d", m.k8sConfig.BasePort),
-				"--auto-approve",
-				"--",
-				"npx", "-y", "@agentclientprotocol/codex-acp",
-			},
-		}
-	case "pi-ollama":
-		// acp-server bridges pi-acp to Pi, which is configured with the pi-ollama-cloud provider.
-		// https://github.com/svkozak/pi-acp
-		settings.Startup = sessionsettings.StartupConfig{
-			Command: []string{"agentapi-proxy"},
-			Args: []string{
-				"acp-server",
-				"--port", fmt.Sprintf("%d", m.k8sConfig.BasePort),
-				"--auto-approve",
-				"--",
-				"npx", "-y", "pi-acp",
-			},
-			PreScript: piOllamaInstallPreScript,
-		}
-	case "cursor":
-		// acp-server bridges Cursor Agent CLI's native ACP server to the agentapi HTTP interface.
-		// https://cursor.com/docs/cli/acp
-		// --auto-approve bypasses the UI permission modal at the ACP bridge layer.
-		settings.Startup = sessionsettings.StartupConfig{
-			Command: []string{"agentapi-proxy"},
-			Args: []string{
-				"acp-server",
-				"--port", fmt.Sprintf("%d", m.k8sConfig.BasePort),
-				"--auto-approve",
-				"--raw-json-log",
-				"--",
-				"agent", "acp",
-			},
-		}
-	default:
-		settings.Startup = sessionsettings.StartupConfig{
-			Command: []string{"agentapi", "server"},
-			Args:    []string{"--allowed-hosts", "*", "--allowed-origins", "*", "--port", fmt.Sprintf("%d", m.k8sConfig.BasePort)},
-		}
 	}
 
 	// Slack integration: embed SlackParams so the provisioner can launch
@@ -5449,9 +5837,10 @@ func (m *KubernetesSessionManager) buildSessionSettings(
 		}
 		settings.Docker = &sessionsettings.DockerConfig{
 			Enabled:    true,
+			Mode:       req.Docker.Mode,
 			Registries: registries,
 		}
-		log.Printf("[K8S_SESSION] DinD enabled for session %s (registries: %d)", session.id, len(registries))
+		log.Printf("[K8S_SESSION] Docker enabled for session %s (mode: %s, registries: %d)", session.id, defaultIfEmpty(req.Docker.Mode, entities.DockerModeDinD), len(registries))
 	}
 
 	return settings
@@ -5636,6 +6025,186 @@ func mergeNoProxy(existing, extra string) string {
 	return strings.Join(values, ",")
 }
 
+const (
+	corporateCAVolumeName = "corporate-ca"
+	corporateCAMountPath  = "/etc/agentapi/corporate-ca"
+	corporateCABundlePath = "/tmp/agentapi-ca-bundle.pem"
+)
+
+// directEgressNoProxyEntries are bypassed in the default sandbox proxy
+// configuration. Behind a corporate proxy there is no direct egress, so they
+// must go through the proxy chain instead.
+var directEgressNoProxyEntries = map[string]bool{
+	"anthropic.com":   true,
+	"*.anthropic.com": true,
+}
+
+// egressTeamID returns the team used to resolve per-team egress overrides.
+func egressTeamID(req *entities.RunServerRequest) string {
+	if req.Scope == entities.ScopeTeam {
+		return req.TeamID
+	}
+	return ""
+}
+
+// corporateCAFile returns the in-Pod path of the corporate CA bundle, or ""
+// when no CA bundle is configured for the request's team.
+func (m *KubernetesSessionManager) corporateCAFile(req *entities.RunServerRequest) string {
+	if m.config == nil || m.config.EgressProxy.CABundleConfigMapFor(egressTeamID(req)) == "" {
+		return ""
+	}
+	return corporateCAMountPath + "/" + m.config.EgressProxy.CABundleKey
+}
+
+// applyEgressProxy configures the Pod for a corporate HTTP/SOCKS proxy.
+// The agent container keeps its local proxy chain (scia → network filter);
+// the containers that open connections to the internet themselves (network
+// filter, DinD) receive the corporate proxy settings. It returns the volumes
+// that must be added to the Pod.
+func (m *KubernetesSessionManager) applyEgressProxy(req *entities.RunServerRequest, main, networkFilter, scia, dind *corev1.Container) []corev1.Volume {
+	if m.config == nil {
+		return nil
+	}
+	proxy := m.config.EgressProxy.ProxySettings(egressTeamID(req))
+	if !proxy.IsZero() {
+		var proxyEnv []corev1.EnvVar
+		for _, v := range proxy.EnvVars() {
+			proxyEnv = append(proxyEnv, corev1.EnvVar{Name: v.Name, Value: v.Value})
+		}
+		for _, c := range []*corev1.Container{networkFilter, dind} {
+			if c != nil {
+				c.Env = append(c.Env, proxyEnv...)
+			}
+		}
+		for i := range main.Env {
+			if main.Env[i].Name == "NO_PROXY" || main.Env[i].Name == "no_proxy" {
+				main.Env[i].Value = egressNoProxy(main.Env[i].Value, proxy.NoProxy)
+			}
+		}
+	}
+
+	caConfigMap := m.config.EgressProxy.CABundleConfigMapFor(egressTeamID(req))
+	if caConfigMap == "" {
+		return nil
+	}
+	caFile := m.corporateCAFile(req)
+	mount := corev1.VolumeMount{Name: corporateCAVolumeName, MountPath: corporateCAMountPath, ReadOnly: true}
+	main.VolumeMounts = append(main.VolumeMounts, mount)
+	main.Env = append(main.Env, corev1.EnvVar{Name: "AGENTAPI_EXTRA_CA_FILE", Value: caFile})
+	if scia != nil {
+		// scia chains to the corporate proxy through the network filter; let
+		// Go's TLS stack load the corporate CA alongside the system store.
+		scia.VolumeMounts = append(scia.VolumeMounts, mount)
+		scia.Env = append(scia.Env, corev1.EnvVar{Name: "SSL_CERT_DIR", Value: "/etc/ssl/certs:" + corporateCAMountPath})
+	} else {
+		// The provisioner writes the combined system + corporate bundle to
+		// corporateCABundlePath before cloning the repository.
+		main.Env = append(main.Env,
+			corev1.EnvVar{Name: "SSL_CERT_FILE", Value: corporateCABundlePath},
+			corev1.EnvVar{Name: "REQUESTS_CA_BUNDLE", Value: corporateCABundlePath},
+			corev1.EnvVar{Name: "CURL_CA_BUNDLE", Value: corporateCABundlePath},
+			corev1.EnvVar{Name: "GIT_SSL_CAINFO", Value: corporateCABundlePath},
+			corev1.EnvVar{Name: "NODE_EXTRA_CA_CERTS", Value: caFile},
+		)
+	}
+
+	return []corev1.Volume{{
+		Name: corporateCAVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: caConfigMap},
+				Optional:             boolPtr(true),
+			},
+		},
+	}}
+}
+
+// injectEgressProxyEnv mirrors applyEgressProxy for the session settings env
+// that the provisioner hands to the agent process.
+func (m *KubernetesSessionManager) injectEgressProxyEnv(env map[string]string, req *entities.RunServerRequest) {
+	if m.config == nil {
+		return
+	}
+	proxy := m.config.EgressProxy.ProxySettings(egressTeamID(req))
+	if !proxy.IsZero() {
+		for _, key := range []string{"NO_PROXY", "no_proxy"} {
+			if value, ok := env[key]; ok {
+				env[key] = egressNoProxy(value, proxy.NoProxy)
+			}
+		}
+	}
+
+	caFile := m.corporateCAFile(req)
+	if caFile == "" {
+		return
+	}
+	env["AGENTAPI_EXTRA_CA_FILE"] = caFile
+	if !m.sciaSessionSidecarEnabled(req) {
+		env["SSL_CERT_FILE"] = corporateCABundlePath
+		env["REQUESTS_CA_BUNDLE"] = corporateCABundlePath
+		env["CURL_CA_BUNDLE"] = corporateCABundlePath
+		env["GIT_SSL_CAINFO"] = corporateCABundlePath
+		env["NODE_EXTRA_CA_CERTS"] = caFile
+	}
+}
+
+// egressNoProxy drops direct-egress bypasses from existing and appends the
+// corporate NO_PROXY entries.
+func egressNoProxy(existing, corporate string) string {
+	var kept []string
+	for _, part := range strings.Split(existing, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || directEgressNoProxyEntries[part] {
+			continue
+		}
+		kept = append(kept, part)
+	}
+	return mergeNoProxy(strings.Join(kept, ","), corporate)
+}
+
+// provisionerProxyURL returns the in-cluster URL session Pods use to reach agentapi-proxy.
+func (m *KubernetesSessionManager) provisionerProxyURL() string {
+	if m.k8sConfig.ProvisionerProxyURL != "" {
+		return m.k8sConfig.ProvisionerProxyURL
+	}
+	return fmt.Sprintf("http://%s:8080", m.serviceHost("agentapi-proxy"))
+}
+
+// injectLLMProxyEnv points the agent's model API clients at the /llm-proxy
+// endpoint. The session API key (AGENTAPI_KEY) is used as the provider key so
+// the Pod never sees upstream credentials; the proxy swaps it for the real key.
+func (m *KubernetesSessionManager) injectLLMProxyEnv(env map[string]string, sessionID string) {
+	if m.config == nil || !m.config.LLMProxy.Enabled {
+		return
+	}
+	apiKey := env["AGENTAPI_KEY"]
+	if apiKey == "" {
+		log.Printf("[K8S_SESSION] LLM proxy enabled but no session API key for %s, skipping LLM proxy env", sessionID)
+		return
+	}
+	if env["CLAUDE_CODE_USE_BEDROCK"] == "1" || env["CLAUDE_CODE_USE_VERTEX"] == "1" {
+		log.Printf("[K8S_SESSION] Session %s uses Bedrock/Vertex, skipping LLM proxy env", sessionID)
+		return
+	}
+
+	baseURL := strings.TrimRight(m.config.LLMProxy.PublicURL, "/")
+	if baseURL == "" {
+		baseURL = strings.TrimRight(m.provisionerProxyURL(), "/")
+	}
+	baseURL = fmt.Sprintf("%s/llm-proxy/%s", baseURL, sessionID)
+
+	if m.config.LLMProxy.HasProviderType("anthropic") {
+		env["ANTHROPIC_BASE_URL"] = baseURL + "/anthropic"
+		env["ANTHROPIC_API_KEY"] = apiKey
+		delete(env, "ANTHROPIC_AUTH_TOKEN")
+		delete(env, "CLAUDE_CODE_OAUTH_TOKEN")
+	}
+	if m.config.LLMProxy.HasProviderType("openai") {
+		env["OPENAI_BASE_URL"] = baseURL + "/openai/v1"
+		env["OPENAI_API_KEY"] = apiKey
+	}
+}
+
 // createSessionSettingsSecretFromSettings creates the unified session settings Secret
 // from a pre-built SessionSettings struct.
 // This Secret is used by agent-provisioner for auto-provisioning on Pod restart.
@@ -5655,8 +6224,8 @@ func (m *KubernetesSessionManager) createSessionSettingsSecretFromSettings(
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            secretName,
-			Namespace:       m.namespace,
-			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.id),
+			Namespace:       session.Namespace(),
+			OwnerReferences: m.sessionServiceOwnerReferences(ctx, session.Namespace(), session.id),
 			Labels: map[string]string{
 				"agentapi.proxy/session-id": session.id,
 				"agentapi.proxy/user-id":    sanitizeLabelValue(req.UserID),
@@ -5669,7 +6238,7 @@ func (m *KubernetesSessionManager) createSessionSettingsSecretFromSettings(
 		},
 	}
 
-	_, err = m.client.CoreV1().Secrets(m.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	_, err = m.client.CoreV1().Secrets(session.Namespace()).Create(ctx, secret, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create session settings secret: %w", err)
 	}
@@ -5681,7 +6250,7 @@ func (m *KubernetesSessionManager) createSessionSettingsSecretFromSettings(
 // deleteSessionSettingsSecret deletes the unified session settings Secret.
 func (m *KubernetesSessionManager) deleteSessionSettingsSecret(ctx context.Context, session *KubernetesSession) error {
 	secretName := fmt.Sprintf("agentapi-session-%s-settings", session.id)
-	err := m.client.CoreV1().Secrets(m.namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	err := m.client.CoreV1().Secrets(session.Namespace()).Delete(ctx, secretName, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete session settings secret: %w", err)
 	}
@@ -5692,7 +6261,7 @@ func (m *KubernetesSessionManager) deleteSessionSettingsSecret(ctx context.Conte
 // This fixes the existing bug where oneshot-settings secrets were not being cleaned up.
 func (m *KubernetesSessionManager) deleteOneshotSettingsSecret(ctx context.Context, session *KubernetesSession) error {
 	secretName := fmt.Sprintf("%s-oneshot-settings", session.ServiceName())
-	err := m.client.CoreV1().Secrets(m.namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	err := m.client.CoreV1().Secrets(session.Namespace()).Delete(ctx, secretName, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete oneshot settings secret: %w", err)
 	}
@@ -5778,6 +6347,10 @@ func settingsToPatch(settings *entities.Settings) settingspatch.SettingsPatch {
 				Env:     cloneStringMap(server.Env()),
 				Headers: cloneStringMap(server.Headers()),
 			}
+			if !server.Enabled() {
+				disabled := false
+				patch.MCPServers[name].Enabled = &disabled
+			}
 		}
 	}
 
@@ -5803,7 +6376,9 @@ func cloneStringMap(src map[string]string) map[string]string {
 }
 
 // resolveSettings reads settings patches from the relevant Kubernetes Secrets
-// (base → team[] → user → oneshot) and returns materialized session configuration.
+// (base → team[] → team default profiles → user → session profile → oneshot
+// → request)
+// and returns materialized session configuration.
 //
 // This is the single entry point for all settings merging. It replaces the previous
 // dual-path approach (readAgentapiSettingsSecret + expandSettingsToEnv for env vars,
@@ -5835,10 +6410,11 @@ func (m *KubernetesSessionManager) resolveSettings(
 		appendIfExists(m.k8sConfig.SettingsBaseSecret)
 	}
 
-	// 2. teams (in order)
+	// 2. teams (in order), then the MCP servers of their default profiles
 	if req.Scope == entities.ScopeTeam && req.TeamID != "" {
 		// Team-scoped session: always use the specified team only (preferred_team_id is ignored)
 		appendSettingsIfExists(req.TeamID)
+		appendTeamProfileMCPServers(&layers, req)
 	} else {
 		// User-scoped session: check if the user has a preferred team set
 		preferredTeamID := m.resolvePreferredTeamID(ctx, req)
@@ -5852,6 +6428,7 @@ func (m *KubernetesSessionManager) resolveSettings(
 				appendSettingsIfExists(team)
 			}
 		}
+		appendTeamProfileMCPServers(&layers, req)
 		// 3. user
 		if req.UserID != "" {
 			appendSettingsIfExists(req.UserID)
@@ -5863,11 +6440,16 @@ func (m *KubernetesSessionManager) resolveSettings(
 		layers = append(layers, settingsToMCPProfilePatch(req.ProfileMCPServers))
 	}
 
-	// 5. oneshot (highest priority)
+	// 5. oneshot
 	if req.Oneshot {
 		appendIfExists(fmt.Sprintf("%s-oneshot-settings", session.ServiceName()))
 	}
 
+	// 6. MCP servers of the creation request (highest priority)
+	if req.RequestMCPServers != nil && !req.RequestMCPServers.IsEmpty() {
+		layers = append(layers, settingsToMCPProfilePatch(req.RequestMCPServers))
+	}
+
 	resolved := settingspatch.Resolve(layers...)
 	materialized, err := settingspatch.Materialize(resolved)
 	if err != nil {
@@ -5876,6 +6458,14 @@ func (m *KubernetesSessionManager) resolveSettings(
 	return materialized
 }
 
+// appendTeamProfileMCPServers adds the MCP servers of the default profiles of
+// the session's teams as a settings layer
+func appendTeamProfileMCPServers(layers *[]settingspatch.SettingsPatch, req *entities.RunServerRequest) {
+	if req.TeamProfileMCPServers != nil && !req.TeamProfileMCPServers.IsEmpty() {
+		*layers = append(*layers, settingsToMCPProfilePatch(req.TeamProfileMCPServers))
+	}
+}
+
 func settingsToMCPProfilePatch(servers *entities.MCPServersSettings) settingspatch.SettingsPatch {
 	patch := settingspatch.SettingsPatch{MCPServers: make(map[string]*settingspatch.MCPServerPatch)}
 	for name, server := range servers.Servers() {
@@ -5933,6 +6523,9 @@ func (m *KubernetesSessionManager) BuildRemoteProvisionSettings(
 	sessionID string,
 	req *entities.RunServerRequest,
 ) (*sessionsettings.SessionSettings, error) {
+	if err := m.checkRequestMCPServers(req); err != nil {
+		return nil, err
+	}
 	// Create a temporary session with the provided ID to satisfy buildSessionSettings
 	tempSession := &KubernetesSession{
 		id:          sessionID,
@@ -244,6 +244,9 @@ func TestSupportedAgentTypeOrDefault(t *testing.T) {
 		{name: "codex acp", agentType: "codex-acp", want: "codex-acp"},
 		{name: "pi ollama", agentType: "pi-ollama", want: "pi-ollama"},
 		{name: "cursor", agentType: "cursor", want: "cursor"},
+		{name: "goose", agentType: "goose", want: "goose"},
+		{name: "codex cli", agentType: "codex", want: "codex"},
+		{name: "claude agentapi", agentType: "claude-agentapi", want: ""},
 		{name: "unknown", agentType: "unknown-agent", want: ""},
 	}
 
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// checkRequestMCPServers rejects MCP servers of a creation request that are
+// not on kubernetes_session.allowed_request_mcp_servers. http/sse servers
+// are matched by URL and stdio servers by command.
+func (m *KubernetesSessionManager) checkRequestMCPServers(req *entities.RunServerRequest) error {
+	if req.RequestMCPServers == nil {
+		return nil
+	}
+	allowlist := m.liveConfig().AllowedRequestMCPServers
+	for _, name := range req.RequestMCPServers.ServerNames() {
+		server := req.RequestMCPServers.GetServer(name)
+		target := server.URL()
+		if server.Type() == "stdio" {
+			target = server.Command()
+		}
+		if !imageAllowed(target, allowlist) {
+			return fmt.Errorf("%w: %s (%s) is not on the MCP server allowlist", entities.ErrMCPServerNotAllowed, name, target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestCheckRequestMCPServers(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	request := func(server *entities.MCPServer) *entities.RunServerRequest {
+		servers := entities.NewMCPServersSettings()
+		servers.SetServer(server.Name(), server)
+		return &entities.RunServerRequest{RequestMCPServers: servers}
+	}
+	httpServer := func(url string) *entities.MCPServer {
+		server := entities.NewMCPServer("task", "http")
+		server.SetURL(url)
+		return server
+	}
+	stdioServer := entities.NewMCPServer("task", "stdio")
+	stdioServer.SetCommand("/usr/local/bin/jira-mcp")
+
+	if err := manager.checkRequestMCPServers(request(httpServer("https://mcp.example.com/jira"))); !errors.Is(err, entities.ErrMCPServerNotAllowed) {
+		t.Errorf("without an allowlist: checkRequestMCPServers = %v", err)
+	}
+
+	manager.k8sConfig.AllowedRequestMCPServers = []string{"https://mcp.example.com/*", "/usr/local/bin/jira-mcp"}
+	tests := []struct {
+		name string
+		req  *entities.RunServerRequest
+		want error
+	}{
+		{"no servers", &entities.RunServerRequest{}, nil},
+		{"url prefix", request(httpServer("https://mcp.example.com/jira")), nil},
+		{"other host", request(httpServer("https://mcp.example.org/jira")), entities.ErrMCPServerNotAllowed},
+		{"command", request(stdioServer), nil},
+	}
+	for _, tt := range tests {
+		err := manager.checkRequestMCPServers(tt.req)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkRequestMCPServers = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestResolveSettingsAppliesRequestMCPServersLast(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	profile := entities.NewMCPServersSettings()
+	profileServer := entities.NewMCPServer("jira", "http")
+	profileServer.SetURL("https://mcp.example.com/profile")
+	profile.SetServer("jira", profileServer)
+	requested := entities.NewMCPServersSettings()
+	requestServer := entities.NewMCPServer("jira", "http")
+	requestServer.SetURL("https://mcp.example.com/task")
+	requested.SetServer("jira", requestServer)
+
+	materialized := manager.resolveSettings(context.Background(), nil, &entities.RunServerRequest{
+		ProfileMCPServers: profile,
+		RequestMCPServers: requested,
+	})
+	server, _ := materialized.MCPServers["jira"].(map[string]interface{})
+	if server["url"] != "https://mcp.example.com/task" {
+		t.Errorf("jira server = %v, want the server of the request", materialized.MCPServers["jira"])
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pausedAtAnnotation marks a session Service whose Deployment was scaled to
+// zero by PauseSession. It is removed again by ResumeSession.
+const pausedAtAnnotation = "agentapi.proxy/paused-at"
+
+// ErrSessionNotPausable is returned when a session runs as a bare Pod or a
+// Job. Its workdir is not backed by a PVC and would be lost, so it cannot be
+// paused.
+var ErrSessionNotPausable = errors.New("session cannot be paused without a persistent workdir")
+
+// PauseSession scales the session Deployment to zero replicas. The Service,
+// Secrets and PVC are kept, so ResumeSession restores the session with its
+// workdir intact. Pausing an already paused session is a no-op.
+func (m *KubernetesSessionManager) PauseSession(ctx context.Context, sessionID string) error {
+	session, err := m.pausableSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status() == "paused" {
+		return nil
+	}
+
+	if err := m.scaleSessionDeployment(ctx, session, 0); err != nil {
+		return err
+	}
+	if err := m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+		pausedAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to mark session %s as paused: %w", sessionID, err)
+	}
+	session.SetStatus("paused")
+	log.Printf("[K8S_SESSION] Paused session %s", sessionID)
+	return nil
+}
+
+// ResumeSession scales a paused session Deployment back to its replicas. The
+// session reports "resuming" until its Pod is ready again. Resuming also
+// counts as activity so the idle reaper does not pause the session again
+// right away.
+func (m *KubernetesSessionManager) ResumeSession(ctx context.Context, sessionID string) error {
+	session, err := m.pausableSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.scaleSessionDeployment(ctx, session, int32(session.Replicas())); err != nil {
+		return err
+	}
+	if err := m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+		pausedAtAnnotation:       nil,
+		preemptedByAnnotation:    nil,
+		lastActivityAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to clear paused mark of session %s: %w", sessionID, err)
+	}
+	session.SetStatus("resuming")
+	log.Printf("[K8S_SESSION] Resuming session %s", sessionID)
+	return nil
+}
+
+func (m *KubernetesSessionManager) pausableSession(sessionID string) (*KubernetesSession, error) {
+	session, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || session == nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if !m.isPVCEnabled() || session.RunsAsJob() {
+		return nil, ErrSessionNotPausable
+	}
+	return session, nil
+}
+
+func (m *KubernetesSessionManager) scaleSessionDeployment(ctx context.Context, session *KubernetesSession, replicas int32) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	if _, err := m.client.AppsV1().Deployments(session.Namespace()).Patch(
+		ctx, session.DeploymentName(), types.MergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to scale deployment %s to %d: %w", session.DeploymentName(), replicas, err)
+	}
+	return nil
+}
+
+// isSessionPaused reports whether the session Deployment is scaled to zero.
+func (m *KubernetesSessionManager) isSessionPaused(ctx context.Context, session *KubernetesSession) bool {
+	if !m.isPVCEnabled() {
+		return false
+	}
+	deployment, err := m.client.AppsV1().Deployments(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Printf("[K8S_SESSION] Failed to get deployment for session %s: %v", session.id, err)
+		}
+		return false
+	}
+	return deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPauseAndResumeSession(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	session := newWorkloadTestSession()
+	ctx := context.Background()
+
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := manager.createSessionWorkload(ctx, session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	if err := manager.PauseSession(ctx, session.ID()); err != nil {
+		t.Fatalf("PauseSession() error = %v", err)
+	}
+	deployment, err := manager.client.AppsV1().Deployments("test-ns").Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 0 {
+		t.Fatalf("Expected deployment to be scaled to 0, got %v", deployment.Spec.Replicas)
+	}
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if svc.Annotations[pausedAtAnnotation] == "" {
+		t.Fatal("Expected service to be annotated as paused")
+	}
+	if session.Status() != "paused" {
+		t.Fatalf("Expected status paused, got %s", session.Status())
+	}
+	if status := manager.getSessionStatusFromDeployment(session.ID()); status != "paused" {
+		t.Fatalf("Expected deployment status paused, got %s", status)
+	}
+
+	if err := manager.ResumeSession(ctx, session.ID()); err != nil {
+		t.Fatalf("ResumeSession() error = %v", err)
+	}
+	deployment, err = manager.client.AppsV1().Deployments("test-ns").Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 1 {
+		t.Fatalf("Expected deployment to be scaled to 1, got %v", deployment.Spec.Replicas)
+	}
+	svc, err = manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if _, ok := svc.Annotations[pausedAtAnnotation]; ok {
+		t.Fatal("Expected paused annotation to be removed on resume")
+	}
+	if svc.Annotations[lastActivityAtAnnotation] == "" {
+		t.Fatal("Expected resume to record activity")
+	}
+	if session.Status() != "resuming" {
+		t.Fatalf("Expected status resuming, got %s", session.Status())
+	}
+}
+
+func TestPauseSessionWithoutPVC(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	err := manager.PauseSession(context.Background(), session.ID())
+	if !errors.Is(err, ErrSessionNotPausable) {
+		t.Fatalf("Expected ErrSessionNotPausable, got %v", err)
+	}
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// Namespace placement strategies select the namespace of session workloads.
+const (
+	// PlacementSingle runs every session in the proxy's session namespace.
+	PlacementSingle = "single"
+	// PlacementTeam runs team-scoped sessions in a namespace of their team.
+	PlacementTeam = "team"
+)
+
+const (
+	// teamNamespaceLabel marks namespaces created for team placement. Its
+	// value is the session namespace of the proxy that placed them.
+	teamNamespaceLabel = "agentapi.proxy/team-namespace-of"
+	// mirroredFromLabel marks Secrets and ConfigMaps copied into a team
+	// namespace from the proxy's session namespace.
+	mirroredFromLabel = "agentapi.proxy/mirrored-from"
+
+	teamResourceQuotaName      = "agentapi-team-quota"
+	teamLimitRangeName         = "agentapi-team-limits"
+	teamNetworkPolicyName      = "agentapi-team-isolation"
+	defaultTeamNamespacePrefix = "agentapi-team-"
+)
+
+var invalidNamespaceChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// validateNamespacePlacement rejects unknown kubernetes_session.namespace_placement values.
+func validateNamespacePlacement(placement string) error {
+	switch placement {
+	case "", PlacementSingle, PlacementTeam:
+		return nil
+	}
+	return fmt.Errorf("unknown kubernetes_session.namespace_placement %q: must be %q or %q",
+		placement, PlacementSingle, PlacementTeam)
+}
+
+// teamNamespaceName derives a namespace name from the team ID, e.g.
+// "myorg/backend" becomes "agentapi-team-myorg-backend". Names that would
+// exceed the 63 character limit are shortened and suffixed with a hash of
+// the team ID so that they stay unique.
+func teamNamespaceName(prefix, teamID string) string {
+	name := prefix + strings.Trim(invalidNamespaceChars.ReplaceAllString(strings.ToLower(teamID), "-"), "-")
+	if len(name) <= 63 {
+		return strings.Trim(name, "-")
+	}
+	hash := sha256.Sum256([]byte(teamID))
+	suffix := hex.EncodeToString(hash[:])[:8]
+	return strings.TrimRight(name[:63-len(suffix)-1], "-") + "-" + suffix
+}
+
+// teamPlacementEnabled reports whether team-scoped sessions get namespaces of
+// their own.
+func (m *KubernetesSessionManager) teamPlacementEnabled() bool {
+	return m.k8sConfig != nil && m.k8sConfig.NamespacePlacement == PlacementTeam
+}
+
+// placementNamespace returns the namespace the workload of a new session is
+// placed in.
+func (m *KubernetesSessionManager) placementNamespace(req *entities.RunServerRequest) string {
+	if !m.teamPlacementEnabled() || req.Scope != entities.ScopeTeam || req.TeamID == "" {
+		return m.namespace
+	}
+	if namespace := m.k8sConfig.TeamNamespaces[req.TeamID]; namespace != "" {
+		return namespace
+	}
+	return teamNamespaceName(defaultIfEmpty(m.k8sConfig.TeamNamespacePrefix, defaultTeamNamespacePrefix), req.TeamID)
+}
+
+// namespaceOf returns the namespace of the workload of a session. Sessions
+// not held by this replica are assumed to be in the proxy's namespace.
+func (m *KubernetesSessionManager) namespaceOf(sessionID string) string {
+	m.mutex.RLock()
+	session, ok := m.sessions[sessionID]
+	m.mutex.RUnlock()
+	if ok && session.Namespace() != "" {
+		return session.Namespace()
+	}
+	return m.namespace
+}
+
+// serviceNamespace returns the namespace of a session Service, which is the
+// namespace of the whole session.
+func (m *KubernetesSessionManager) serviceNamespace(svc *corev1.Service) string {
+	return defaultIfEmpty(svc.Namespace, m.namespace)
+}
+
+// getSessionService gets the Service of a session. Sessions not held by
+// this replica are looked up in the team namespaces when they are not in the
+// proxy's namespace.
+func (m *KubernetesSessionManager) getSessionService(ctx context.Context, sessionID, serviceName string) (*corev1.Service, error) {
+	namespace := m.namespaceOf(sessionID)
+	svc, err := m.client.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err == nil || !errors.IsNotFound(err) || namespace != m.namespace || !m.teamPlacementEnabled() {
+		return svc, err
+	}
+	for _, other := range m.sessionNamespaces(ctx)[1:] {
+		if found, otherErr := m.client.CoreV1().Services(other).Get(ctx, serviceName, metav1.GetOptions{}); otherErr == nil {
+			return found, nil
+		}
+	}
+	return nil, err
+}
+
+// sessionNamespaces returns the namespaces session workloads may be placed
+// in: the proxy's namespace, followed by the team namespaces it created and
+// those configured in kubernetes_session.team_namespaces.
+func (m *KubernetesSessionManager) sessionNamespaces(ctx context.Context) []string {
+	return SessionNamespaces(ctx, m.client, m.namespace, m.k8sConfig)
+}
+
+// SessionNamespaces returns the namespaces the proxy running in namespace
+// with k8sConfig places session workloads in.
+func SessionNamespaces(ctx context.Context, client kubernetes.Interface, namespace string, k8sConfig *config.KubernetesSessionConfig) []string {
+	namespaces := []string{namespace}
+	if k8sConfig == nil || k8sConfig.NamespacePlacement != PlacementTeam {
+		return namespaces
+	}
+	add := func(other string) {
+		for _, existing := range namespaces {
+			if existing == other {
+				return
+			}
+		}
+		namespaces = append(namespaces, other)
+	}
+	for _, other := range k8sConfig.TeamNamespaces {
+		add(other)
+	}
+	list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: teamNamespaceLabel + "=" + namespace,
+	})
+	if err != nil {
+		log.Printf("[K8S_SESSION] Failed to list team namespaces: %v", err)
+		return namespaces
+	}
+	for _, ns := range list.Items {
+		add(ns.Name)
+	}
+	return namespaces
+}
+
+// ensureTeamNamespace creates the namespace of a team, or updates it, with
+// its ResourceQuota, LimitRange and NetworkPolicy. The proxy's own namespace
+// is left alone.
+func (m *KubernetesSessionManager) ensureTeamNamespace(ctx context.Context, namespace, teamID string) error {
+	if namespace == m.namespace {
+		return nil
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+				teamNamespaceLabel:             m.namespace,
+				"agentapi.proxy/team-id-hash":  hashTeamID(teamID),
+			},
+			Annotations: map[string]string{
+				"agentapi.proxy/team-id": teamID,
+			},
+		},
+	}
+	if _, err := m.client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create team namespace %s: %w", namespace, err)
+		}
+	} else {
+		log.Printf("[K8S_SESSION] Created namespace %s for team %s", namespace, teamID)
+	}
+
+	if err := m.applyTeamResourceQuota(ctx, namespace, teamID); err != nil {
+		return err
+	}
+	if err := m.applyTeamLimitRange(ctx, namespace); err != nil {
+		return err
+	}
+	if m.k8sConfig.TeamNamespaceNetworkPolicy {
+		if err := m.applyTeamNetworkPolicy(ctx, namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseResourceList parses a map of resource names to quantities.
+func parseResourceList(values map[string]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q for %s: %w", value, name, err)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+func (m *KubernetesSessionManager) applyTeamResourceQuota(ctx context.Context, namespace, teamID string) error {
+	if len(m.k8sConfig.TeamNamespaceQuota) == 0 {
+		return nil
+	}
+	hard, err := parseResourceList(m.k8sConfig.TeamNamespaceQuota)
+	if err != nil {
+		return fmt.Errorf("team namespace quota: %w", err)
+	}
+	if hard, err = m.reservedTeamQuota(teamID, hard); err != nil {
+		return err
+	}
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      teamResourceQuotaName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	quotas := m.client.CoreV1().ResourceQuotas(namespace)
+	existing, err := quotas.Get(ctx, teamResourceQuotaName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = quotas.Create(ctx, quota, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Spec = quota.Spec
+		_, err = quotas.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply ResourceQuota in %s: %w", namespace, err)
+	}
+	return nil
+}
+
+func (m *KubernetesSessionManager) applyTeamLimitRange(ctx context.Context, namespace string) error {
+	if len(m.k8sConfig.TeamNamespaceLimitDefault) == 0 && len(m.k8sConfig.TeamNamespaceLimitDefaultRequest) == 0 {
+		return nil
+	}
+	defaults, err := parseResourceList(m.k8sConfig.TeamNamespaceLimitDefault)
+	if err != nil {
+		return fmt.Errorf("team namespace limit default: %w", err)
+	}
+	defaultRequests, err := parseResourceList(m.k8sConfig.TeamNamespaceLimitDefaultRequest)
+	if err != nil {
+		return fmt.Errorf("team namespace limit default request: %w", err)
+	}
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      teamLimitRangeName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+		},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			Default:        defaults,
+			DefaultRequest: defaultRequests,
+		}}},
+	}
+	limitRanges := m.client.CoreV1().LimitRanges(namespace)
+	existing, err := limitRanges.Get(ctx, teamLimitRangeName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = limitRanges.Create(ctx, limitRange, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Spec = limitRange.Spec
+		_, err = limitRanges.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply LimitRange in %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// applyTeamNetworkPolicy admits ingress into the team namespace only from
+// the namespace itself and from the proxy's namespace, so that sessions of
+// other teams cannot reach it.
+func (m *KubernetesSessionManager) applyTeamNetworkPolicy(ctx context.Context, namespace string) error {
+	fromNamespace := func(name string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{corev1.LabelMetadataName: name},
+		}}
+	}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      teamNetworkPolicyName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{fromNamespace(namespace), fromNamespace(m.namespace)},
+			}},
+		},
+	}
+	policies := m.client.NetworkingV1().NetworkPolicies(namespace)
+	existing, err := policies.Get(ctx, teamNetworkPolicyName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = policies.Create(ctx, policy, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Spec = policy.Spec
+		_, err = policies.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply NetworkPolicy in %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// mirrorPodSpecDependencies copies the Secrets and ConfigMaps a session Pod
+// references from the proxy's namespace into the namespace the Pod is placed
+// in, since Pods can only mount objects of their own namespace, and creates
+// the service account the Pod runs as there. Objects that only exist in the
+// target namespace, such as per-session Secrets, are left alone; earlier
+// copies are refreshed.
+func (m *KubernetesSessionManager) mirrorPodSpecDependencies(ctx context.Context, namespace string, spec *corev1.PodSpec) error {
+	if namespace == m.namespace {
+		return nil
+	}
+	if spec.ServiceAccountName != "" {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{
+			Name:      spec.ServiceAccountName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+		}}
+		if _, err := m.client.CoreV1().ServiceAccounts(namespace).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create service account %s in %s: %w", spec.ServiceAccountName, namespace, err)
+		}
+	}
+	secrets, configMaps := podSpecReferences(spec)
+	for _, name := range secrets {
+		if err := m.mirrorSecret(ctx, namespace, name); err != nil {
+			return err
+		}
+	}
+	for _, name := range configMaps {
+		if err := m.mirrorConfigMap(ctx, namespace, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *KubernetesSessionManager) mirrorSecret(ctx context.Context, namespace, name string) error {
+	source, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secret %s to mirror: %w", name, err)
+	}
+	secrets := m.client.CoreV1().Secrets(namespace)
+	existing, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy", mirroredFromLabel: m.namespace},
+			},
+			Type: source.Type,
+			Data: source.Data,
+		}, metav1.CreateOptions{})
+	case err == nil && existing.Labels[mirroredFromLabel] == m.namespace:
+		existing.Data = source.Data
+		_, err = secrets.Update(ctx, existing, metav1.UpdateOptions{})
+	case err == nil:
+		// Created in the team namespace by someone else; keep theirs.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mirror secret %s into %s: %w", name, namespace, err)
+	}
+	return nil
+}
+
+func (m *KubernetesSessionManager) mirrorConfigMap(ctx context.Context, namespace, name string) error {
+	source, err := m.client.CoreV1().ConfigMaps(m.namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read configmap %s to mirror: %w", name, err)
+	}
+	configMaps := m.client.CoreV1().ConfigMaps(namespace)
+	existing, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy", mirroredFromLabel: m.namespace},
+			},
+			Data:       source.Data,
+			BinaryData: source.BinaryData,
+		}, metav1.CreateOptions{})
+	case err == nil && existing.Labels[mirroredFromLabel] == m.namespace:
+		existing.Data = source.Data
+		existing.BinaryData = source.BinaryData
+		_, err = configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+	case err == nil:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mirror configmap %s into %s: %w", name, namespace, err)
+	}
+	return nil
+}
+
+// podSpecReferences returns the names of the Secrets and ConfigMaps a Pod
+// spec references through volumes, environment variables and image pull
+// secrets.
+func podSpecReferences(spec *corev1.PodSpec) (secrets, configMaps []string) {
+	seen := map[string]bool{}
+	addSecret := func(name string) {
+		if name != "" && !seen["secret/"+name] {
+			seen["secret/"+name] = true
+			secrets = append(secrets, name)
+		}
+	}
+	addConfigMap := func(name string) {
+		if name != "" && !seen["configmap/"+name] {
+			seen["configmap/"+name] = true
+			configMaps = append(configMaps, name)
+		}
+	}
+	for _, ref := range spec.ImagePullSecrets {
+		addSecret(ref.Name)
+	}
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			addSecret(volume.Secret.SecretName)
+		}
+		if volume.ConfigMap != nil {
+			addConfigMap(volume.ConfigMap.Name)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					addSecret(source.Secret.Name)
+				}
+				if source.ConfigMap != nil {
+					addConfigMap(source.ConfigMap.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, from := range container.EnvFrom {
+			if from.SecretRef != nil {
+				addSecret(from.SecretRef.Name)
+			}
+			if from.ConfigMapRef != nil {
+				addConfigMap(from.ConfigMapRef.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				addSecret(env.ValueFrom.SecretKeyRef.Name)
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				addConfigMap(env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+	return secrets, configMaps
+}
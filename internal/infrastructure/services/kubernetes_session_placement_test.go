@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestTeamNamespaceName(t *testing.T) {
+	tests := []struct {
+		teamID string
+		want   string
+	}{
+		{teamID: "myorg/backend", want: "agentapi-team-myorg-backend"},
+		{teamID: "MyOrg/Front_End", want: "agentapi-team-myorg-front-end"},
+		{teamID: "-org/team-", want: "agentapi-team-org-team"},
+	}
+	for _, tt := range tests {
+		if got := teamNamespaceName("agentapi-team-", tt.teamID); got != tt.want {
+			t.Errorf("teamNamespaceName(%q) = %q, want %q", tt.teamID, got, tt.want)
+		}
+	}
+
+	long := teamNamespaceName("agentapi-team-", "myorg/"+strings.Repeat("a", 80))
+	if len(long) > 63 {
+		t.Fatalf("teamNamespaceName() returned %d characters, want at most 63", len(long))
+	}
+	if other := teamNamespaceName("agentapi-team-", "myorg/"+strings.Repeat("a", 81)); other == long {
+		t.Fatalf("long team IDs with a shared prefix map to the same namespace %q", long)
+	}
+}
+
+func TestPlacementNamespace(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	teamReq := &entities.RunServerRequest{UserID: "u", Scope: entities.ScopeTeam, TeamID: "myorg/backend"}
+
+	if got := manager.placementNamespace(teamReq); got != "test-ns" {
+		t.Fatalf("placementNamespace() with single placement = %q, want test-ns", got)
+	}
+
+	manager.k8sConfig.NamespacePlacement = PlacementTeam
+	manager.k8sConfig.TeamNamespaces = map[string]string{"myorg/platform": "platform"}
+	if got := manager.placementNamespace(teamReq); got != "agentapi-team-myorg-backend" {
+		t.Errorf("placementNamespace(team) = %q, want agentapi-team-myorg-backend", got)
+	}
+	if got := manager.placementNamespace(&entities.RunServerRequest{Scope: entities.ScopeTeam, TeamID: "myorg/platform"}); got != "platform" {
+		t.Errorf("placementNamespace(configured team) = %q, want platform", got)
+	}
+	if got := manager.placementNamespace(&entities.RunServerRequest{UserID: "u", Scope: entities.ScopeUser}); got != "test-ns" {
+		t.Errorf("placementNamespace(user) = %q, want test-ns", got)
+	}
+}
+
+func TestEnsureTeamNamespace(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.NamespacePlacement = PlacementTeam
+	manager.k8sConfig.TeamNamespaceQuota = map[string]string{"requests.cpu": "8", "pods": "20"}
+	manager.k8sConfig.TeamNamespaceLimitDefault = map[string]string{"cpu": "1"}
+	manager.k8sConfig.TeamNamespaceNetworkPolicy = true
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := manager.ensureTeamNamespace(ctx, "agentapi-team-myorg-backend", "myorg/backend"); err != nil {
+			t.Fatalf("ensureTeamNamespace() call %d error = %v", i+1, err)
+		}
+	}
+
+	ns, err := manager.client.CoreV1().Namespaces().Get(ctx, "agentapi-team-myorg-backend", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected team namespace to be created: %v", err)
+	}
+	if got := ns.Labels[teamNamespaceLabel]; got != "test-ns" {
+		t.Errorf("namespace label %s = %q, want test-ns", teamNamespaceLabel, got)
+	}
+
+	quota, err := manager.client.CoreV1().ResourceQuotas(ns.Name).Get(ctx, teamResourceQuotaName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected ResourceQuota to be created: %v", err)
+	}
+	if got := quota.Spec.Hard[corev1.ResourcePods]; got.String() != "20" {
+		t.Errorf("quota pods = %s, want 20", got.String())
+	}
+	if _, err := manager.client.CoreV1().LimitRanges(ns.Name).Get(ctx, teamLimitRangeName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected LimitRange to be created: %v", err)
+	}
+	if _, err := manager.client.NetworkingV1().NetworkPolicies(ns.Name).Get(ctx, teamNetworkPolicyName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected NetworkPolicy to be created: %v", err)
+	}
+
+	namespaces := manager.sessionNamespaces(ctx)
+	if len(namespaces) != 2 || namespaces[0] != "test-ns" || namespaces[1] != ns.Name {
+		t.Errorf("sessionNamespaces() = %v, want [test-ns %s]", namespaces, ns.Name)
+	}
+}
+
+func TestMirrorPodSpecDependencies(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	if _, err := manager.client.CoreV1().Secrets("test-ns").Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-app", Namespace: "test-ns"},
+		Data:       map[string][]byte{"token": []byte("v1")},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:    "agentapi",
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "github-app"}}}},
+		}},
+		Volumes: []corev1.Volume{{
+			Name:         "optional",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}}},
+		}},
+	}
+	secrets, configMaps := podSpecReferences(spec)
+	if len(secrets) != 1 || secrets[0] != "github-app" || len(configMaps) != 1 || configMaps[0] != "missing" {
+		t.Fatalf("podSpecReferences() = %v, %v", secrets, configMaps)
+	}
+
+	if err := manager.mirrorPodSpecDependencies(ctx, "team-ns", spec); err != nil {
+		t.Fatalf("mirrorPodSpecDependencies() error = %v", err)
+	}
+	mirrored, err := manager.client.CoreV1().Secrets("team-ns").Get(ctx, "github-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected Secret to be mirrored: %v", err)
+	}
+	if string(mirrored.Data["token"]) != "v1" || mirrored.Labels[mirroredFromLabel] != "test-ns" {
+		t.Errorf("mirrored Secret = %v labels %v", mirrored.Data, mirrored.Labels)
+	}
+
+	// Later mirrors pick up changes of the source
+	source, _ := manager.client.CoreV1().Secrets("test-ns").Get(ctx, "github-app", metav1.GetOptions{})
+	source.Data["token"] = []byte("v2")
+	if _, err := manager.client.CoreV1().Secrets("test-ns").Update(ctx, source, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.mirrorPodSpecDependencies(ctx, "team-ns", spec); err != nil {
+		t.Fatalf("mirrorPodSpecDependencies() error = %v", err)
+	}
+	mirrored, _ = manager.client.CoreV1().Secrets("team-ns").Get(ctx, "github-app", metav1.GetOptions{})
+	if string(mirrored.Data["token"]) != "v2" {
+		t.Errorf("mirrored token = %q, want v2", mirrored.Data["token"])
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+const (
+	// PostSessionReportName is the artifact name of the report written after
+	// the post-session hooks of a session have run.
+	PostSessionReportName = "report.json"
+	// maxPostSessionHookOutput caps the stored output of one hook.
+	maxPostSessionHookOutput = 1 << 20
+)
+
+var unsafeArtifactNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// PostSessionHookPrefix is the artifact key prefix of the post-session hook
+// output of a session.
+func PostSessionHookPrefix(sessionID string) string {
+	return "post-session-hooks/" + sessionID + "/"
+}
+
+// SetArtifactStore configures where post-session hook output is stored.
+// Without a store post-session hooks are not run.
+func (m *KubernetesSessionManager) SetArtifactStore(store ArtifactStore) {
+	m.artifactStore = store
+}
+
+// runPostSessionHooksBeforeDelete runs the post-session hooks of a oneshot
+// session. Failures are logged and never prevent the deletion. Job sessions
+// are skipped: their Pod has exited by the time they are deleted, and their
+// container logs are captured instead.
+func (m *KubernetesSessionManager) runPostSessionHooksBeforeDelete(session *KubernetesSession) {
+	if m.artifactStore == nil || session.Request() == nil || !session.Request().Oneshot || session.RunsAsJob() {
+		return
+	}
+	ctx := context.Background()
+	hooks := m.postSessionHooks(ctx, session)
+	if len(hooks) == 0 {
+		return
+	}
+	log.Printf("[K8S_SESSION] Running %d post-session hooks for session %s", len(hooks), session.id)
+	report := runPostSessionHooks(ctx, session, hooks, m.execInSession, m.artifactStore)
+	log.Printf("[K8S_SESSION] Post-session hooks for session %s finished (%d hooks)", session.id, len(report.Hooks))
+}
+
+// postSessionHooks returns the hooks from the session request, or from the
+// settings Secret for sessions restored after a proxy restart.
+func (m *KubernetesSessionManager) postSessionHooks(ctx context.Context, session *KubernetesSession) []sessionsettings.SetupHook {
+	if hooks := session.Request().PostSessionHooks; len(hooks) > 0 {
+		return SetupHookSettings(hooks)
+	}
+	settingsSecretName := strings.TrimSuffix(session.ServiceName(), "-svc") + "-settings"
+	secret, err := m.client.CoreV1().Secrets(session.Namespace()).Get(ctx, settingsSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	settings, err := sessionsettings.LoadSettingsFromBytes(secret.Data["settings.yaml"])
+	if err != nil {
+		return nil
+	}
+	return settings.PostSessionHooks
+}
+
+// runPostSessionHooks runs hooks one after another in the main container,
+// stores the output of each as an artifact and finally stores the report.
+func runPostSessionHooks(ctx context.Context, session entities.Session, hooks []sessionsettings.SetupHook, exec sessionExecFunc, store ArtifactStore) entities.PostSessionReport {
+	report := entities.PostSessionReport{
+		SessionID: session.ID(),
+		UserID:    session.UserID(),
+		Scope:     session.Scope(),
+		TeamID:    session.TeamID(),
+		StartedAt: time.Now().UTC(),
+		Hooks:     make([]entities.PostSessionHookResult, 0, len(hooks)),
+	}
+	prefix := PostSessionHookPrefix(session.ID())
+	for i, hook := range hooks {
+		result := entities.PostSessionHookResult{
+			Name: hook.Name,
+			Log:  fmt.Sprintf("%02d-%s.log", i+1, unsafeArtifactNameChars.ReplaceAllString(hook.Name, "-")),
+		}
+		timeout := time.Duration(hook.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = entities.DefaultSetupHookTimeoutSeconds * time.Second
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		out := &cappedBuffer{limit: maxPostSessionHookOutput}
+		start := time.Now()
+		exitCode, err := exec(hookCtx, session.ID(), entities.SessionExecRequest{
+			Command: []string{"sh", "-c", hook.Command},
+		}, ExecStreams{Stdout: out, Stderr: out})
+		if hookCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		cancel()
+		result.ExitCode = exitCode
+		result.DurationMS = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("[K8S_SESSION] Post-session hook %q of session %s failed: %v", hook.Name, session.ID(), err)
+		}
+		if err := store.PutArtifact(ctx, prefix+result.Log, "text/plain; charset=utf-8", bytes.NewReader(out.Bytes())); err != nil {
+			log.Printf("[K8S_SESSION] Failed to store output of post-session hook %q of session %s: %v", hook.Name, session.ID(), err)
+		}
+		report.Hooks = append(report.Hooks, result)
+	}
+
+	data, err := json.Marshal(report)
+	if err == nil {
+		err = store.PutArtifact(ctx, prefix+PostSessionReportName, "application/json", bytes.NewReader(data))
+	}
+	if err != nil {
+		log.Printf("[K8S_SESSION] Failed to store post-session report of session %s: %v", session.ID(), err)
+	}
+	return report
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty hook cannot exhaust the proxy memory.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
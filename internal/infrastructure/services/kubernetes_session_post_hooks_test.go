@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+func readArtifact(t *testing.T, store ArtifactStore, key string) string {
+	t.Helper()
+	body, err := store.OpenArtifact(context.Background(), key)
+	if err != nil {
+		t.Fatalf("OpenArtifact(%s) error = %v", key, err)
+	}
+	defer func() { _ = body.Close() }()
+	data, _ := io.ReadAll(body)
+	return string(data)
+}
+
+func TestRunPostSessionHooksStoresOutputAndReport(t *testing.T) {
+	store := NewFilesystemAssetStore(t.TempDir(), "")
+	session := newWorkloadTestSession()
+	var commands []string
+	exec := func(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams ExecStreams) (int, error) {
+		commands = append(commands, strings.Join(req.Command, " "))
+		switch req.Command[2] {
+		case "go test ./...":
+			_, _ = fmt.Fprint(streams.Stdout, "ok  \texample.com/pkg\n")
+			return 0, nil
+		case "make report":
+			_, _ = fmt.Fprint(streams.Stderr, "make: *** No rule to make target\n")
+			return 2, nil
+		}
+		return 0, errors.New("pod is gone")
+	}
+
+	report := runPostSessionHooks(context.Background(), session, []sessionsettings.SetupHook{
+		{Name: "tests", Command: "go test ./..."},
+		{Name: "make report", Command: "make report"},
+		{Name: "upload", Command: "upload-coverage"},
+	}, exec, store)
+
+	if len(commands) != 3 || commands[0] != "sh -c go test ./..." {
+		t.Fatalf("commands = %q", commands)
+	}
+	if report.UserID != "test-user" || len(report.Hooks) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if h := report.Hooks[1]; h.ExitCode != 2 || h.Log != "02-make-report.log" || h.Error != "" {
+		t.Errorf("second hook result = %+v", h)
+	}
+	if h := report.Hooks[2]; h.Error != "pod is gone" {
+		t.Errorf("third hook result = %+v", h)
+	}
+
+	prefix := PostSessionHookPrefix(session.ID())
+	if got := readArtifact(t, store, prefix+"01-tests.log"); !strings.Contains(got, "example.com/pkg") {
+		t.Errorf("tests log = %q", got)
+	}
+	if got := readArtifact(t, store, prefix+"02-make-report.log"); !strings.Contains(got, "No rule") {
+		t.Errorf("make report log = %q", got)
+	}
+	var stored entities.PostSessionReport
+	if err := json.Unmarshal([]byte(readArtifact(t, store, prefix+PostSessionReportName)), &stored); err != nil {
+		t.Fatalf("stored report is not JSON: %v", err)
+	}
+	if stored.SessionID != session.ID() || len(stored.Hooks) != 3 {
+		t.Errorf("stored report = %+v", stored)
+	}
+}
+
+func TestPostSessionHooksOnlyRunForOneshotSessions(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	store := NewFilesystemAssetStore(t.TempDir(), "")
+	manager.SetArtifactStore(store)
+	session := newWorkloadTestSession()
+	session.request.PostSessionHooks = []entities.SetupHook{{Name: "tests", Command: "go test ./..."}}
+
+	manager.runPostSessionHooksBeforeDelete(session)
+	if _, err := store.OpenArtifact(context.Background(), PostSessionHookPrefix(session.ID())+PostSessionReportName); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("report of a non-oneshot session: err = %v, want ErrArtifactNotFound", err)
+	}
+
+	if hooks := manager.postSessionHooks(context.Background(), session); len(hooks) != 1 || hooks[0].TimeoutSeconds != entities.DefaultSetupHookTimeoutSeconds {
+		t.Errorf("postSessionHooks() = %+v", hooks)
+	}
+}
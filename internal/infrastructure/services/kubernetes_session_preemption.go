@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	// preemptedByAnnotation names the session a paused session was preempted
+	// for. It is removed again by ResumeSession.
+	preemptedByAnnotation = "agentapi.proxy/preempted-by"
+
+	// defaultPreemptionMinIdle is how long a session must have been idle to
+	// be preempted when kubernetes_session.preemption.min_idle is unset
+	defaultPreemptionMinIdle = 5 * time.Minute
+)
+
+// validatePreemption rejects a malformed kubernetes_session.preemption.
+func validatePreemption(preemption *config.SessionPreemptionConfig) error {
+	if preemption.MinIdle != "" {
+		if d, err := time.ParseDuration(preemption.MinIdle); err != nil || d < 0 {
+			return fmt.Errorf("kubernetes_session.preemption.min_idle: invalid duration %q", preemption.MinIdle)
+		}
+	}
+	for i, rule := range preemption.Priorities {
+		switch rule.Source {
+		case "", config.SessionSourceInteractive, config.SessionSourceWebhook, config.SessionSourceSchedule:
+		default:
+			return fmt.Errorf("kubernetes_session.preemption.priorities[%d]: unknown source %q", i, rule.Source)
+		}
+	}
+	return nil
+}
+
+// preemptionMinIdle returns kubernetes_session.preemption.min_idle.
+func preemptionMinIdle(preemption *config.SessionPreemptionConfig) time.Duration {
+	if d, err := time.ParseDuration(preemption.MinIdle); err == nil && d >= 0 {
+		return d
+	}
+	return defaultPreemptionMinIdle
+}
+
+// sessionSource returns where a session with tags comes from. Webhooks and
+// schedules tag the sessions they start.
+func sessionSource(tags map[string]string) string {
+	switch {
+	case tags["webhook_id"] != "":
+		return config.SessionSourceWebhook
+	case tags["schedule_id"] != "":
+		return config.SessionSourceSchedule
+	}
+	return config.SessionSourceInteractive
+}
+
+// sessionPriority returns the priority of a session of teamID with tags
+// under the preemption policy.
+func sessionPriority(preemption *config.SessionPreemptionConfig, teamID string, tags map[string]string) int {
+	rules := preemption.Priorities
+	if len(rules) == 0 {
+		rules = config.DefaultSessionPriorities
+	}
+	source := sessionSource(tags)
+	for _, rule := range rules {
+		if rule.Source != "" && rule.Source != source {
+			continue
+		}
+		if rule.TeamID != "" && rule.TeamID != teamID {
+			continue
+		}
+		matched := true
+		for key, value := range rule.Tags {
+			if tags[key] != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.Priority
+		}
+	}
+	return 0
+}
+
+// preemptionCandidate is a running session that may be paused for a new one.
+type preemptionCandidate struct {
+	session  *KubernetesSession
+	priority int
+	idleFor  time.Duration
+}
+
+// ensureSessionCapacity is checkSessionCapacity that, with
+// kubernetes_session.preemption, pauses the lowest-priority idle session of
+// a lower priority than the new session when that frees a slot for it. Among
+// sessions of the same priority the longest idle one is paused. Every
+// preemption is recorded in the audit log.
+func (m *KubernetesSessionManager) ensureSessionCapacity(ctx context.Context, id string, req *entities.RunServerRequest) error {
+	err := m.checkSessionCapacity(id, req)
+	if err == nil || !errors.Is(err, entities.ErrSessionCapacityExhausted) {
+		return err
+	}
+	preemption := m.liveConfig().Preemption
+	if !preemption.Enabled || !m.isPVCEnabled() {
+		return err
+	}
+
+	m.preemptMu.Lock()
+	defer m.preemptMu.Unlock()
+	// Another preemption may have freed a slot meanwhile
+	if err = m.checkSessionCapacity(id, req); err == nil {
+		return nil
+	}
+
+	priority := sessionPriority(&preemption, req.TeamID, req.Tags)
+	victim := m.findPreemptionCandidate(ctx, id, req, &preemption, priority)
+	if victim == nil {
+		log.Printf("[K8S_SESSION] No session of a priority below %d idle for %s to preempt for session %s",
+			priority, preemptionMinIdle(&preemption), id)
+		return err
+	}
+
+	detail := fmt.Sprintf("paused for session %s of %s (priority %d over %d) after %s idle",
+		id, req.UserID, priority, victim.priority, victim.idleFor.Round(time.Second))
+	if pauseErr := m.PauseSession(ctx, victim.session.ID()); pauseErr != nil {
+		log.Printf("[K8S_SESSION] Failed to preempt session %s for session %s: %v", victim.session.ID(), id, pauseErr)
+		m.recordPreemption(ctx, victim.session.ID(), entities.AuditOutcomeFailure, detail+": "+pauseErr.Error())
+		return err
+	}
+	if patchErr := m.patchServiceAnnotations(ctx, victim.session.Namespace(), victim.session.ServiceName(), map[string]interface{}{
+		preemptedByAnnotation: id,
+	}); patchErr != nil {
+		log.Printf("[K8S_SESSION] Failed to mark session %s as preempted: %v", victim.session.ID(), patchErr)
+	}
+	log.Printf("[K8S_SESSION] Preempted session %s: %s", victim.session.ID(), detail)
+	m.recordPreemption(ctx, victim.session.ID(), entities.AuditOutcomeSuccess, detail)
+	return m.checkSessionCapacity(id, req)
+}
+
+// findPreemptionCandidate returns the session to pause for the new session
+// id of priority, or nil. Only running sessions of a lower priority that
+// have been idle for min_idle and whose slot the new session may use are
+// candidates.
+func (m *KubernetesSessionManager) findPreemptionCandidate(ctx context.Context, id string, req *entities.RunServerRequest, preemption *config.SessionPreemptionConfig, priority int) *preemptionCandidate {
+	m.mutex.RLock()
+	sessions := make([]*KubernetesSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	m.mutex.RUnlock()
+
+	minIdle := preemptionMinIdle(preemption)
+	now := time.Now()
+	var best *preemptionCandidate
+	for _, session := range sessions {
+		if session.ID() == id || session.RunsAsJob() {
+			continue
+		}
+		if status := session.Status(); status != "active" && status != "running" {
+			continue
+		}
+		sessionPriority := sessionPriority(preemption, session.TeamID(), session.Tags())
+		if sessionPriority >= priority {
+			continue
+		}
+		if best != nil && sessionPriority > best.priority {
+			continue
+		}
+		if m.checkSessionCapacityWithout(id, req, session.ID()) != nil {
+			continue
+		}
+		idleFor := now.Sub(m.sessionLastActivity(ctx, session))
+		if idleFor < minIdle {
+			continue
+		}
+		if best == nil || sessionPriority < best.priority || idleFor > best.idleFor {
+			best = &preemptionCandidate{session: session, priority: sessionPriority, idleFor: idleFor}
+		}
+	}
+	return best
+}
+
+// sessionLastActivity returns the latest of the session start, its last
+// message and the activity timestamps on its Service.
+func (m *KubernetesSessionManager) sessionLastActivity(ctx context.Context, session *KubernetesSession) time.Time {
+	latest := session.StartedAt()
+	if t := session.LastMessageAt(); t.After(latest) {
+		latest = t
+	}
+	svc, err := m.client.CoreV1().Services(session.Namespace()).Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		// Unknown activity never counts as idle
+		log.Printf("[K8S_SESSION] Failed to get service of session %s: %v", session.ID(), err)
+		return time.Now()
+	}
+	for _, key := range []string{"agentapi.proxy/created-at", "agentapi.proxy/last-message-at", lastActivityAtAnnotation} {
+		if t, err := time.Parse(time.RFC3339, svc.Annotations[key]); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// recordPreemption writes a preempted session to the audit log.
+func (m *KubernetesSessionManager) recordPreemption(ctx context.Context, sessionID, outcome, detail string) {
+	log.Printf("[AUDIT] session_preempted session=%s outcome=%s detail=%q", sessionID, outcome, detail)
+	if m.auditRepo == nil {
+		return
+	}
+	err := m.auditRepo.RecordAuditEvent(ctx, entities.AuditEvent{
+		Timestamp: time.Now().UTC(),
+		Category:  entities.AuditCategoryAdminAction,
+		Action:    "session_preempted",
+		Operation: entities.AuditOpSessionPreempt,
+		Actor:     "system",
+		Resource:  "session/" + sessionID,
+		Outcome:   outcome,
+		Detail:    detail,
+	})
+	if err != nil {
+		log.Printf("[AUDIT] Failed to record preemption of session %s: %v", sessionID, err)
+	}
+}
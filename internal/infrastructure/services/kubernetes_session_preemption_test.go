@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestSessionPriority(t *testing.T) {
+	preemption := &config.SessionPreemptionConfig{
+		Priorities: []config.SessionPriorityRule{
+			{TeamID: "org/oncall", Priority: 200},
+			{Tags: map[string]string{"priority": "low"}, Priority: -10},
+			{Source: config.SessionSourceInteractive, Priority: 100},
+			{Source: config.SessionSourceSchedule, Priority: 50},
+		},
+	}
+	for name, tc := range map[string]struct {
+		teamID string
+		tags   map[string]string
+		want   int
+	}{
+		"team":              {teamID: "org/oncall", tags: map[string]string{"webhook_id": "wh"}, want: 200},
+		"tag":               {tags: map[string]string{"priority": "low"}, want: -10},
+		"interactive":       {want: 100},
+		"schedule":          {tags: map[string]string{"schedule_id": "s"}, want: 50},
+		"webhook unmatched": {tags: map[string]string{"webhook_id": "wh"}, want: 0},
+	} {
+		if got := sessionPriority(preemption, tc.teamID, tc.tags); got != tc.want {
+			t.Errorf("%s: priority = %d, want %d", name, got, tc.want)
+		}
+	}
+
+	defaults := &config.SessionPreemptionConfig{}
+	if got := sessionPriority(defaults, "", nil); got != 100 {
+		t.Errorf("default interactive priority = %d, want 100", got)
+	}
+	if got := sessionPriority(defaults, "", map[string]string{"webhook_id": "wh"}); got != 0 {
+		t.Errorf("default webhook priority = %d, want 0", got)
+	}
+}
+
+func TestValidatePreemption(t *testing.T) {
+	for name, preemption := range map[string]config.SessionPreemptionConfig{
+		"bad min_idle": {MinIdle: "soon"},
+		"bad source":   {Priorities: []config.SessionPriorityRule{{Source: "cron"}}},
+	} {
+		if err := validatePreemption(&preemption); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// newPreemptionTestManager returns a manager with one slot taken by a
+// running webhook session that has been idle for idleFor.
+func newPreemptionTestManager(t *testing.T, idleFor time.Duration) (*KubernetesSessionManager, *KubernetesSession, *recordingAuditRepository) {
+	t.Helper()
+	manager := newWorkloadTestManager(t, true)
+	manager.k8sConfig.SessionCapacity = 1
+	manager.k8sConfig.Preemption = config.SessionPreemptionConfig{Enabled: true, MinIdle: "10m"}
+	audit := &recordingAuditRepository{}
+	manager.SetAuditRepository(audit)
+
+	session := NewKubernetesSession(
+		"batch",
+		&entities.RunServerRequest{UserID: "bot", Tags: map[string]string{"webhook_id": "wh-1"}},
+		"agentapi-session-batch",
+		"agentapi-session-batch-svc",
+		"agentapi-session-batch-pvc",
+		"test-ns",
+		9000,
+		nil,
+		nil,
+	)
+	session.startedAt = time.Now().Add(-idleFor)
+	session.lastMessageAt = session.startedAt
+	ctx := context.Background()
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := manager.createSessionWorkload(ctx, session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	session.SetStatus("active")
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+	return manager, session, audit
+}
+
+func TestEnsureSessionCapacityPreemptsIdleLowPrioritySession(t *testing.T) {
+	manager, batch, audit := newPreemptionTestManager(t, time.Hour)
+	ctx := context.Background()
+
+	interactive := &entities.RunServerRequest{UserID: "alice", Scope: entities.ScopeUser}
+	if err := manager.ensureSessionCapacity(ctx, "interactive-1", interactive); err != nil {
+		t.Fatalf("ensureSessionCapacity() error = %v", err)
+	}
+	if batch.Status() != "paused" {
+		t.Fatalf("Expected the webhook session to be paused, got %s", batch.Status())
+	}
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, batch.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if got := svc.Annotations[preemptedByAnnotation]; got != "interactive-1" {
+		t.Fatalf("preempted-by = %q, want interactive-1", got)
+	}
+	if len(audit.events) != 1 {
+		t.Fatalf("Expected one audit event, got %+v", audit.events)
+	}
+	event := audit.events[0]
+	if event.Operation != entities.AuditOpSessionPreempt || event.Resource != "session/batch" || event.Outcome != entities.AuditOutcomeSuccess {
+		t.Fatalf("Unexpected audit event %+v", event)
+	}
+
+	if err := manager.ResumeSession(ctx, batch.ID()); err != nil {
+		t.Fatalf("ResumeSession() error = %v", err)
+	}
+	svc, err = manager.client.CoreV1().Services("test-ns").Get(ctx, batch.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if _, ok := svc.Annotations[preemptedByAnnotation]; ok {
+		t.Fatal("Expected preempted-by to be removed on resume")
+	}
+}
+
+func TestEnsureSessionCapacityDoesNotPreempt(t *testing.T) {
+	interactive := &entities.RunServerRequest{UserID: "alice", Scope: entities.ScopeUser}
+	webhook := &entities.RunServerRequest{UserID: "bot", Tags: map[string]string{"webhook_id": "wh-2"}}
+	for name, tc := range map[string]struct {
+		idleFor  time.Duration
+		disabled bool
+		req      *entities.RunServerRequest
+	}{
+		"same priority": {idleFor: time.Hour, req: webhook},
+		"not idle":      {idleFor: time.Minute, req: interactive},
+		"disabled":      {idleFor: time.Hour, disabled: true, req: interactive},
+	} {
+		t.Run(name, func(t *testing.T) {
+			manager, batch, audit := newPreemptionTestManager(t, tc.idleFor)
+			manager.k8sConfig.Preemption.Enabled = !tc.disabled
+
+			err := manager.ensureSessionCapacity(context.Background(), "new", tc.req)
+			if !errors.Is(err, entities.ErrSessionCapacityExhausted) {
+				t.Fatalf("ensureSessionCapacity() error = %v, want capacity exhausted", err)
+			}
+			if batch.Status() != "active" {
+				t.Fatalf("Expected the session to keep running, got %s", batch.Status())
+			}
+			if len(audit.events) != 0 {
+				t.Fatalf("Expected no audit events, got %+v", audit.events)
+			}
+		})
+	}
+}
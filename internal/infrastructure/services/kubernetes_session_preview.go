@@ -0,0 +1,368 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+// previewAnnotation stores the preview environment of a session as JSON on
+// its Service.
+const previewAnnotation = "agentapi.proxy/preview"
+
+// previewHookClient calls the deploy hook. Deploys are bounded by
+// kubernetes_session.preview_deploy_timeout through the request context.
+var previewHookClient = &http.Client{}
+
+// previewHookRequest is the JSON body posted to the deploy hook. Action is
+// "deploy" or "destroy". A deploy answers with previewHookResponse.
+type previewHookRequest struct {
+	Action     string `json:"action"`
+	SessionID  string `json:"session_id"`
+	Namespace  string `json:"namespace"`
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch"`
+	UserID     string `json:"user_id"`
+	Scope      string `json:"scope"`
+	TeamID     string `json:"team_id,omitempty"`
+}
+
+type previewHookResponse struct {
+	URL string `json:"url"`
+}
+
+// restorePreviewFromService reads the preview environment of a restored session.
+func restorePreviewFromService(svc *corev1.Service) *entities.PreviewEnvironment {
+	value := svc.Annotations[previewAnnotation]
+	if value == "" {
+		return nil
+	}
+	var preview entities.PreviewEnvironment
+	if err := json.Unmarshal([]byte(value), &preview); err != nil {
+		log.Printf("[PREVIEW] Ignoring invalid preview annotation on Service %s: %v", svc.Name, err)
+		return nil
+	}
+	return &preview
+}
+
+// Preview returns a copy of the preview environment of the session, or nil.
+func (s *KubernetesSession) Preview() *entities.PreviewEnvironment {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.preview == nil {
+		return nil
+	}
+	preview := *s.preview
+	return &preview
+}
+
+// SetPreview sets the preview environment of the session.
+func (s *KubernetesSession) SetPreview(preview *entities.PreviewEnvironment) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.preview = preview
+}
+
+// startPreview records preview as the session's preview environment unless a
+// deploy started less than staleAfter ago is still running.
+func (s *KubernetesSession) startPreview(preview *entities.PreviewEnvironment, staleAfter time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if cur := s.preview; cur != nil && cur.Status == entities.PreviewStatusDeploying && time.Since(cur.UpdatedAt) < staleAfter {
+		return false
+	}
+	if s.preview != nil {
+		preview.CreatedAt = s.preview.CreatedAt
+	}
+	s.preview = preview
+	return true
+}
+
+func (m *KubernetesSessionManager) previewEnabled() bool {
+	return m.k8sConfig != nil && m.k8sConfig.PreviewDeployHookURL != ""
+}
+
+func (m *KubernetesSessionManager) previewDeployTimeout() time.Duration {
+	if d, err := time.ParseDuration(m.k8sConfig.PreviewDeployTimeout); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Minute
+}
+
+// previewNamespace returns the namespace of the preview environment of sessionID.
+func (m *KubernetesSessionManager) previewNamespace(sessionID string) string {
+	name := strings.ToLower(defaultIfEmpty(m.k8sConfig.PreviewNamespacePrefix, "agentapi-preview-") + sessionID)
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	return name
+}
+
+func (m *KubernetesSessionManager) kubernetesSession(sessionID string) (*KubernetesSession, error) {
+	ks, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || ks == nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	return ks, nil
+}
+
+// GetPreviewEnvironment returns the preview environment of a session.
+func (m *KubernetesSessionManager) GetPreviewEnvironment(sessionID string) (*entities.PreviewEnvironment, error) {
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	preview := ks.Preview()
+	if preview == nil {
+		return nil, entities.ErrPreviewNotFound
+	}
+	return preview, nil
+}
+
+// CreatePreviewEnvironment creates the preview namespace of a session and
+// asks the deploy hook to deploy the requested branch into it. The hook runs
+// in the background; the returned environment is in the deploying state and
+// the session's preview is updated with the URL once the hook answers.
+// Requesting a preview again redeploys into the same namespace.
+func (m *KubernetesSessionManager) CreatePreviewEnvironment(ctx context.Context, sessionID string, req entities.CreatePreviewRequest) (*entities.PreviewEnvironment, error) {
+	if !m.previewEnabled() {
+		return nil, entities.ErrPreviewDisabled
+	}
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := req.Branch
+	var repository string
+	if r := ks.Request(); r != nil && r.RepoInfo != nil {
+		repository = r.RepoInfo.FullName
+		if branch == "" {
+			branch = r.RepoInfo.Branch
+		}
+	}
+	if branch == "" {
+		return nil, entities.ErrPreviewBranchRequired
+	}
+
+	now := time.Now().UTC()
+	preview := &entities.PreviewEnvironment{
+		Namespace:  m.previewNamespace(sessionID),
+		Repository: repository,
+		Branch:     branch,
+		Status:     entities.PreviewStatusDeploying,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if !ks.startPreview(preview, m.previewDeployTimeout()) {
+		return nil, entities.ErrPreviewDeploying
+	}
+
+	if err := m.ensurePreviewNamespace(ctx, ks, preview.Namespace); err != nil {
+		ks.SetPreview(nil)
+		return nil, err
+	}
+	if err := m.savePreview(ctx, ks, preview); err != nil {
+		return nil, err
+	}
+	log.Printf("[PREVIEW] Deploying %s@%s for session %s into namespace %s", repository, branch, sessionID, preview.Namespace)
+
+	deploy := *preview
+	go m.deployPreview(ks, &deploy)
+	return preview, nil
+}
+
+// deployPreview calls the deploy hook and records its outcome.
+func (m *KubernetesSessionManager) deployPreview(ks *KubernetesSession, preview *entities.PreviewEnvironment) {
+	started := preview.UpdatedAt
+	ctx, cancel := context.WithTimeout(context.Background(), m.previewDeployTimeout())
+	var resp previewHookResponse
+	err := m.callPreviewHook(ctx, "deploy", ks, preview, &resp)
+	cancel()
+
+	preview.UpdatedAt = time.Now().UTC()
+	if err != nil {
+		log.Printf("[PREVIEW] Deploy failed for session %s: %v", ks.ID(), err)
+		preview.Status = entities.PreviewStatusFailed
+		preview.Message = err.Error()
+	} else {
+		log.Printf("[PREVIEW] Deployed preview for session %s at %s", ks.ID(), resp.URL)
+		preview.Status = entities.PreviewStatusReady
+		preview.URL = resp.URL
+	}
+
+	// The preview may have been deleted, with or without its session, or
+	// redeployed while the hook was running.
+	if cur := ks.Preview(); cur == nil || !cur.UpdatedAt.Equal(started) {
+		return
+	}
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer saveCancel()
+	if err := m.savePreview(saveCtx, ks, preview); err != nil {
+		log.Printf("[PREVIEW] Failed to record preview of session %s: %v", ks.ID(), err)
+	}
+}
+
+// DeletePreviewEnvironment tears down the preview environment of a session.
+func (m *KubernetesSessionManager) DeletePreviewEnvironment(ctx context.Context, sessionID string) error {
+	ks, err := m.kubernetesSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if ks.Preview() == nil {
+		return entities.ErrPreviewNotFound
+	}
+	if err := m.teardownPreview(ctx, ks); err != nil {
+		return err
+	}
+	return m.savePreview(ctx, ks, nil)
+}
+
+// teardownPreview asks the deploy hook to destroy the preview environment of
+// ks and deletes its namespace. The namespace is not owned by the session
+// Service, so it is not garbage collected with the other session resources.
+func (m *KubernetesSessionManager) teardownPreview(ctx context.Context, ks *KubernetesSession) error {
+	preview := ks.Preview()
+	if preview == nil {
+		return nil
+	}
+	ks.SetPreview(nil)
+
+	if m.previewEnabled() {
+		if err := m.callPreviewHook(ctx, "destroy", ks, preview, nil); err != nil {
+			log.Printf("[PREVIEW] Destroy hook failed for session %s, deleting namespace anyway: %v", ks.ID(), err)
+		}
+	}
+	err := m.client.CoreV1().Namespaces().Delete(ctx, preview.Namespace, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete preview namespace %s: %w", preview.Namespace, err)
+	}
+	log.Printf("[PREVIEW] Deleted preview namespace %s of session %s", preview.Namespace, ks.ID())
+	return nil
+}
+
+// ensurePreviewNamespace creates the preview namespace, or reuses it when it
+// already belongs to the session.
+func (m *KubernetesSessionManager) ensurePreviewNamespace(ctx context.Context, ks *KubernetesSession, name string) error {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+				"agentapi.proxy/preview":       "true",
+				"agentapi.proxy/session-id":    ks.ID(),
+			},
+			Annotations: map[string]string{
+				"agentapi.proxy/user-id": ks.UserID(),
+			},
+		},
+	}
+	_, err := m.client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create preview namespace %s: %w", name, err)
+	}
+	existing, err := m.client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get preview namespace %s: %w", name, err)
+	}
+	if existing.Labels["agentapi.proxy/session-id"] != ks.ID() {
+		return fmt.Errorf("namespace %s already exists and does not belong to session %s", name, ks.ID())
+	}
+	return nil
+}
+
+// savePreview stores preview on the session Service (removing it when nil)
+// and on the in-memory session.
+func (m *KubernetesSessionManager) savePreview(ctx context.Context, ks *KubernetesSession, preview *entities.PreviewEnvironment) error {
+	var value interface{}
+	if preview != nil {
+		data, err := json.Marshal(preview)
+		if err != nil {
+			return fmt.Errorf("failed to encode preview: %w", err)
+		}
+		value = string(data)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{previewAnnotation: value},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	if _, err := m.client.CoreV1().Services(ks.Namespace()).Patch(ctx, ks.ServiceName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update preview annotation: %w", err)
+	}
+
+	ks.SetPreview(preview)
+	if m.sessionListCacheRepo != nil {
+		if err := m.sessionListCacheRepo.InvalidateSessionListCache(ctx, m.namespace); err != nil {
+			log.Printf("[PREVIEW] Failed to invalidate session list cache for %s: %v", ks.ID(), err)
+		}
+	}
+	return nil
+}
+
+// callPreviewHook posts action for the preview environment of ks to the
+// deploy hook and decodes the response into out when it is non-nil.
+func (m *KubernetesSessionManager) callPreviewHook(ctx context.Context, action string, ks *KubernetesSession, preview *entities.PreviewEnvironment, out interface{}) error {
+	body, err := json.Marshal(previewHookRequest{
+		Action:     action,
+		SessionID:  ks.ID(),
+		Namespace:  preview.Namespace,
+		Repository: preview.Repository,
+		Branch:     preview.Branch,
+		UserID:     ks.UserID(),
+		Scope:      string(ks.Scope()),
+		TeamID:     ks.TeamID(),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.k8sConfig.PreviewDeployHookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid deploy hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := m.k8sConfig.PreviewDeployHookSecret; secret != "" {
+		ts := hmacutil.NowTimestamp()
+		req.Header.Set(hmacutil.TimestampHeader, ts)
+		req.Header.Set("X-Hub-Signature-256", hmacutil.Sign([]byte(secret), hmacutil.BuildMessage(req.Method, req.URL.RequestURI(), ts, body)))
+	}
+
+	resp, err := previewHookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("deploy hook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read deploy hook response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("deploy hook returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("invalid deploy hook response: %w", err)
+		}
+	}
+	return nil
+}
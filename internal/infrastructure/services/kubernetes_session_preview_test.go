@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+func TestPreviewEnvironmentLifecycle(t *testing.T) {
+	var mu sync.Mutex
+	var actions []previewHookRequest
+	release := make(chan struct{})
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		msg := hmacutil.BuildMessage(r.Method, r.URL.RequestURI(), r.Header.Get(hmacutil.TimestampHeader), body)
+		if !hmacutil.Verify([]byte("hook-secret"), msg, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var req previewHookRequest
+		_ = json.Unmarshal(body, &req)
+		mu.Lock()
+		actions = append(actions, req)
+		mu.Unlock()
+		if req.Action == "deploy" {
+			<-release
+			_, _ = w.Write([]byte(`{"url":"https://` + req.Namespace + `.preview.example.com"}`))
+		}
+	}))
+	defer hook.Close()
+
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+	session.request.RepoInfo = &entities.RepositoryInfo{FullName: "org/app", Branch: "feature-x"}
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService() error = %v", err)
+	}
+	manager.sessions[session.ID()] = session
+
+	if _, err := manager.CreatePreviewEnvironment(ctx, session.ID(), entities.CreatePreviewRequest{}); !errors.Is(err, entities.ErrPreviewDisabled) {
+		t.Fatalf("CreatePreviewEnvironment() without hook error = %v, want ErrPreviewDisabled", err)
+	}
+	manager.k8sConfig.PreviewDeployHookURL = hook.URL
+	manager.k8sConfig.PreviewDeployHookSecret = "hook-secret"
+
+	preview, err := manager.CreatePreviewEnvironment(ctx, session.ID(), entities.CreatePreviewRequest{})
+	if err != nil {
+		t.Fatalf("CreatePreviewEnvironment() error = %v", err)
+	}
+	if preview.Status != entities.PreviewStatusDeploying || preview.Branch != "feature-x" || preview.Namespace != "agentapi-preview-test-session" {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if _, err := manager.CreatePreviewEnvironment(ctx, session.ID(), entities.CreatePreviewRequest{}); !errors.Is(err, entities.ErrPreviewDeploying) {
+		t.Fatalf("second CreatePreviewEnvironment() error = %v, want ErrPreviewDeploying", err)
+	}
+	ns, err := manager.client.CoreV1().Namespaces().Get(ctx, preview.Namespace, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("preview namespace not created: %v", err)
+	}
+	if ns.Labels["agentapi.proxy/session-id"] != session.ID() {
+		t.Errorf("namespace labels = %v", ns.Labels)
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		preview, err = manager.GetPreviewEnvironment(session.ID())
+		if err != nil {
+			t.Fatalf("GetPreviewEnvironment() error = %v", err)
+		}
+		if preview.Status != entities.PreviewStatusDeploying || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if preview.Status != entities.PreviewStatusReady || preview.URL != "https://agentapi-preview-test-session.preview.example.com" {
+		t.Fatalf("preview after deploy = %+v", preview)
+	}
+
+	// The preview survives a proxy restart through the Service annotation.
+	svc, err := manager.client.CoreV1().Services(manager.namespace).Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if restored := restorePreviewFromService(svc); restored == nil || restored.URL != preview.URL {
+		t.Fatalf("restorePreviewFromService() = %+v", restored)
+	}
+
+	if err := manager.DeletePreviewEnvironment(ctx, session.ID()); err != nil {
+		t.Fatalf("DeletePreviewEnvironment() error = %v", err)
+	}
+	if _, err := manager.client.CoreV1().Namespaces().Get(ctx, preview.Namespace, metav1.GetOptions{}); err == nil {
+		t.Error("preview namespace should be deleted")
+	}
+	svc, _ = manager.client.CoreV1().Services(manager.namespace).Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if _, ok := svc.Annotations[previewAnnotation]; ok {
+		t.Error("preview annotation should be removed")
+	}
+	if _, err := manager.GetPreviewEnvironment(session.ID()); !errors.Is(err, entities.ErrPreviewNotFound) {
+		t.Errorf("GetPreviewEnvironment() after delete error = %v, want ErrPreviewNotFound", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(actions) != 2 || actions[0].Action != "deploy" || actions[1].Action != "destroy" || actions[0].Repository != "org/app" {
+		t.Errorf("hook calls = %+v", actions)
+	}
+}
+
+func TestPreviewNamespaceIsTruncated(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	if name := manager.previewNamespace(id); len(name) > 63 {
+		t.Errorf("previewNamespace() = %q is longer than 63 characters", name)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// liveConfig returns the kubernetes_session config of the last reload, or
+// the startup config. Only the fields applied by ReloadConfig may be read
+// from it; everything else keeps its startup value until a restart.
+func (m *KubernetesSessionManager) liveConfig() *config.KubernetesSessionConfig {
+	if live := m.reloadedConfig.Load(); live != nil {
+		return live
+	}
+	return m.k8sConfig
+}
+
+// ReloadConfig applies the safe-to-change kubernetes_session settings of
+// next to new sessions: agent_images, team_images, allowed_images,
+// allowed_request_mcp_servers, cpu_request, cpu_limit, memory_request, memory_limit,
+// max_session_replicas and preemption. Running sessions keep their Pods.
+// next is kept and must not be modified afterwards.
+func (m *KubernetesSessionManager) ReloadConfig(next *config.KubernetesSessionConfig) error {
+	if err := validateImageConfig(next); err != nil {
+		return err
+	}
+	for _, q := range []struct{ name, value string }{
+		{"cpu_request", next.CPURequest},
+		{"cpu_limit", next.CPULimit},
+		{"memory_request", next.MemoryRequest},
+		{"memory_limit", next.MemoryLimit},
+	} {
+		if _, err := resource.ParseQuantity(q.value); err != nil {
+			return fmt.Errorf("kubernetes_session.%s: %w", q.name, err)
+		}
+	}
+	if next.MaxSessionReplicas < 0 {
+		return fmt.Errorf("kubernetes_session.max_session_replicas must not be negative")
+	}
+	if err := validatePreemption(&next.Preemption); err != nil {
+		return err
+	}
+	m.reloadedConfig.Store(next)
+	log.Printf("[K8S_SESSION] Reloaded session images and resource limits (%d agent images, %d team images)",
+		len(next.AgentImages), len(next.TeamImages))
+	return nil
+}
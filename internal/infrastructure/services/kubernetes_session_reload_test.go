@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestReloadConfig(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.Image = "ghcr.io/example/default:1"
+	manager.k8sConfig.MaxSessionReplicas = 2
+	goose := &entities.RunServerRequest{AgentType: "goose"}
+
+	next := *manager.k8sConfig
+	next.Image = "ghcr.io/example/default:2"
+	next.AgentImages = map[string]string{"goose": "ghcr.io/example/goose:1"}
+	next.MaxSessionReplicas = 4
+	if err := manager.ReloadConfig(&next); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	if got := manager.sessionImage(goose); got != "ghcr.io/example/goose:1" {
+		t.Errorf("sessionImage = %q, want the reloaded agent image", got)
+	}
+	// The default image of stock sessions needs a restart.
+	if got := manager.sessionImage(&entities.RunServerRequest{}); got != "ghcr.io/example/default:1" {
+		t.Errorf("default image = %q, want the startup image", got)
+	}
+	if got := manager.maxSessionReplicas(); got != 4 {
+		t.Errorf("maxSessionReplicas = %d, want 4", got)
+	}
+
+	invalid := next
+	invalid.AgentImages = nil
+	invalid.CPULimit = "two cores"
+	if err := manager.ReloadConfig(&invalid); err == nil {
+		t.Fatal("ReloadConfig accepted an invalid cpu_limit")
+	}
+	if got := manager.sessionImage(goose); got != "ghcr.io/example/goose:1" {
+		t.Errorf("sessionImage = %q after a rejected reload", got)
+	}
+	if err := manager.ReloadConfig(&config.KubernetesSessionConfig{TeamImages: []config.TeamImage{{Image: "x"}}}); err == nil {
+		t.Error("ReloadConfig accepted a team image without team_id")
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// replicasAnnotation records the Pod count of multi-replica sessions so it
+// survives proxy restarts.
+const replicasAnnotation = "agentapi.proxy/replicas"
+
+// maxSessionReplicas returns kubernetes_session.max_session_replicas, at least 1.
+func (m *KubernetesSessionManager) maxSessionReplicas() int {
+	live := m.liveConfig()
+	if live == nil || live.MaxSessionReplicas < 1 {
+		return 1
+	}
+	return live.MaxSessionReplicas
+}
+
+// checkReplicas rejects a replica count above 1 unless the agent type is
+// configured as stateless and the session can run as a Deployment. Replicas
+// share no workdir, so Docker, whose image cache may live on the session
+// PVC, is not supported either.
+func (m *KubernetesSessionManager) checkReplicas(req *entities.RunServerRequest) error {
+	if req.Replicas < 0 {
+		return fmt.Errorf("%w: must not be negative", entities.ErrInvalidReplicas)
+	}
+	if req.Replicas <= 1 {
+		return nil
+	}
+	agentType := supportedAgentTypeOrDefault(req.AgentType)
+	if m.k8sConfig == nil || !slices.Contains(m.k8sConfig.StatelessAgentTypes, agentType) {
+		return fmt.Errorf("%w: agent type %q is not stateless", entities.ErrInvalidReplicas, agentType)
+	}
+	if limit := m.maxSessionReplicas(); req.Replicas > limit {
+		return fmt.Errorf("%w: %d exceeds the maximum of %d", entities.ErrInvalidReplicas, req.Replicas, limit)
+	}
+	if !m.isPVCEnabled() {
+		return fmt.Errorf("%w: multiple replicas require Deployment workloads (kubernetes_session.pvc_enabled)", entities.ErrInvalidReplicas)
+	}
+	if req.Docker != nil && req.Docker.Enabled {
+		return fmt.Errorf("%w: docker is not supported with multiple replicas", entities.ErrInvalidReplicas)
+	}
+	if req.Oneshot && m.oneshotJobEnabled() {
+		return fmt.Errorf("%w: oneshot sessions run as a single Job Pod", entities.ErrInvalidReplicas)
+	}
+	return nil
+}
+
+// restoreReplicasFromService reads the replica count of a restored session.
+func restoreReplicasFromService(svc *corev1.Service) int {
+	n, err := strconv.Atoi(svc.Annotations[replicasAnnotation])
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// Replicas returns the number of Pods of the session, at least 1.
+func (s *KubernetesSession) Replicas() int {
+	if s.request == nil || s.request.Replicas < 1 {
+		return 1
+	}
+	return s.request.Replicas
+}
+
+// AddrFor returns the address of the replica serving affinityKey. The same
+// key keeps reaching the same replica while it is ready, and only keys of a
+// removed replica move when the replica set changes. Single-replica sessions
+// and sessions whose endpoints are not known yet use Addr.
+func (s *KubernetesSession) AddrFor(affinityKey string) string {
+	s.mutex.RLock()
+	endpoints := s.endpoints
+	s.mutex.RUnlock()
+	if host := rendezvousHost(endpoints, affinityKey); host != "" {
+		return fmt.Sprintf("%s:%d", host, s.servicePort)
+	}
+	return s.Addr()
+}
+
+func (s *KubernetesSession) setEndpoints(hosts []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.endpoints = hosts
+}
+
+// rendezvousHost picks the host with the highest hash of key and host
+// (rendezvous hashing), or "" when there are no hosts.
+func rendezvousHost(hosts []string, key string) string {
+	var (
+		best      string
+		bestScore uint64
+	)
+	for _, host := range hosts {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(host))
+		if score := mix64(h.Sum64()); best == "" || score > bestScore {
+			best, bestScore = host, score
+		}
+	}
+	return best
+}
+
+// mix64 spreads the bits of FNV hashes of similar inputs, such as Pod IPs
+// that differ only in the last octet (the splitmix64 finalizer).
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func newReplicasTestManager(t *testing.T, pvcEnabled bool) *KubernetesSessionManager {
+	t.Helper()
+	manager := newWorkloadTestManager(t, pvcEnabled)
+	manager.k8sConfig.StatelessAgentTypes = []string{"pi-ollama"}
+	manager.k8sConfig.MaxSessionReplicas = 4
+	return manager
+}
+
+func TestCheckReplicas(t *testing.T) {
+	manager := newReplicasTestManager(t, true)
+	for _, req := range []*entities.RunServerRequest{
+		{},
+		{Replicas: 1, AgentType: "codex-acp"},
+		{Replicas: 3, AgentType: "pi-ollama"},
+	} {
+		if err := manager.checkReplicas(req); err != nil {
+			t.Errorf("checkReplicas(%d, %q) = %v", req.Replicas, req.AgentType, err)
+		}
+	}
+
+	for name, req := range map[string]*entities.RunServerRequest{
+		"negative":       {Replicas: -1},
+		"stateful agent": {Replicas: 2, AgentType: "codex-acp"},
+		"over maximum":   {Replicas: 5, AgentType: "pi-ollama"},
+		"docker":         {Replicas: 2, AgentType: "pi-ollama", Docker: &entities.DockerParams{Enabled: true}},
+	} {
+		if err := manager.checkReplicas(req); !errors.Is(err, entities.ErrInvalidReplicas) {
+			t.Errorf("%s: checkReplicas() = %v, want ErrInvalidReplicas", name, err)
+		}
+	}
+
+	podManager := newReplicasTestManager(t, false)
+	if err := podManager.checkReplicas(&entities.RunServerRequest{Replicas: 2, AgentType: "pi-ollama"}); !errors.Is(err, entities.ErrInvalidReplicas) {
+		t.Errorf("checkReplicas() without PVC = %v, want ErrInvalidReplicas", err)
+	}
+}
+
+func TestMultiReplicaDeploymentUsesEmptyDirWorkdir(t *testing.T) {
+	manager := newReplicasTestManager(t, true)
+	session := newWorkloadTestSession()
+	session.Request().Replicas = 3
+
+	deployment, err := manager.buildDeployment(context.Background(), session, session.Request())
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	if got := *deployment.Spec.Replicas; got != 3 {
+		t.Errorf("replicas = %d, want 3", got)
+	}
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		if v.Name == "workdir" && v.EmptyDir == nil {
+			t.Errorf("workdir volume = %+v, want EmptyDir", v.VolumeSource)
+		}
+	}
+}
+
+func TestRendezvousHostIsStable(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	picked := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		host := rendezvousHost(hosts, key)
+		if again := rendezvousHost([]string{hosts[3], hosts[1], hosts[0], hosts[2]}, key); again != host {
+			t.Fatalf("key %s moved from %s to %s when hosts were reordered", key, host, again)
+		}
+		picked[key] = host
+		counts[host]++
+	}
+	for _, host := range hosts {
+		if counts[host] < 150 {
+			t.Errorf("host %s got %d of 1000 keys, want an even spread", host, counts[host])
+		}
+	}
+
+	// Removing a replica only moves the keys it served
+	remaining := hosts[:3]
+	for key, host := range picked {
+		got := rendezvousHost(remaining, key)
+		if host != hosts[3] && got != host {
+			t.Fatalf("key %s moved from %s to %s although its replica remained", key, host, got)
+		}
+	}
+
+	if got := rendezvousHost(nil, "user-1"); got != "" {
+		t.Errorf("rendezvousHost(nil) = %q, want empty", got)
+	}
+}
+
+func TestAddrForUsesReadyEndpoints(t *testing.T) {
+	manager := newReplicasTestManager(t, true)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+
+	// Without known endpoints the Service address is used
+	if got := session.AddrFor("user-1"); got != session.Addr() {
+		t.Errorf("AddrFor() before refresh = %q, want %q", got, session.Addr())
+	}
+
+	session.Request().Replicas = 2
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      session.ServiceName() + "-abc",
+			Namespace: "test-ns",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: session.ServiceName()},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}},
+			{Addresses: []string{"10.0.0.2"}},
+		},
+	}
+	if _, err := manager.client.DiscoveryV1().EndpointSlices("test-ns").Create(ctx, slice, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create endpoint slice: %v", err)
+	}
+	manager.refreshRouteHost(ctx, session)
+
+	want := rendezvousHost([]string{"10.0.0.1", "10.0.0.2"}, "user-1") + ":9000"
+	if got := session.AddrFor("user-1"); got != want {
+		t.Errorf("AddrFor() = %q, want %q", got, want)
+	}
+	// Service routing still addresses the session itself by its Service
+	if got := session.Host(); got != session.ServiceDNS() {
+		t.Errorf("Host() = %q, want %q", got, session.ServiceDNS())
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// validateReservations rejects malformed kubernetes_session reservations and
+// reservations that do not fit into the session capacity.
+func validateReservations(k8sConfig *config.KubernetesSessionConfig) error {
+	if k8sConfig.SessionCapacity < 0 {
+		return fmt.Errorf("kubernetes_session.session_capacity must not be negative")
+	}
+	reserved := 0
+	seen := make(map[string]bool, len(k8sConfig.Reservations))
+	for i, reservation := range k8sConfig.Reservations {
+		if reservation.TeamID == "" {
+			return fmt.Errorf("kubernetes_session.reservations[%d]: team_id is required", i)
+		}
+		if seen[reservation.TeamID] {
+			return fmt.Errorf("kubernetes_session.reservations[%d]: duplicate team_id %q", i, reservation.TeamID)
+		}
+		seen[reservation.TeamID] = true
+		if reservation.Sessions < 0 {
+			return fmt.Errorf("kubernetes_session.reservations[%d]: sessions must not be negative", i)
+		}
+		if _, err := parseResourceList(reservation.Quota); err != nil {
+			return fmt.Errorf("kubernetes_session.reservations[%d]: quota: %w", i, err)
+		}
+		reserved += reservation.Sessions
+	}
+	if k8sConfig.SessionCapacity > 0 && reserved > k8sConfig.SessionCapacity {
+		return fmt.Errorf("kubernetes_session.reservations reserve %d sessions, more than the session_capacity of %d",
+			reserved, k8sConfig.SessionCapacity)
+	}
+	return nil
+}
+
+// teamReservation returns the reservation of teamID, or nil.
+func (m *KubernetesSessionManager) teamReservation(teamID string) *config.SessionReservation {
+	if m.k8sConfig == nil || teamID == "" {
+		return nil
+	}
+	for i := range m.k8sConfig.Reservations {
+		if m.k8sConfig.Reservations[i].TeamID == teamID {
+			return &m.k8sConfig.Reservations[i]
+		}
+	}
+	return nil
+}
+
+// sessionReservation returns the reservation of the team of a team-scoped
+// session, or nil.
+func (m *KubernetesSessionManager) sessionReservation(req *entities.RunServerRequest) *config.SessionReservation {
+	if req == nil || req.Scope != entities.ScopeTeam {
+		return nil
+	}
+	return m.teamReservation(req.TeamID)
+}
+
+// occupiesSessionSlot reports whether a session in status holds a slot of
+// the session capacity. Stopped and paused sessions run no Pod.
+func occupiesSessionSlot(status string) bool {
+	switch status {
+	case "stopped", "paused":
+		return false
+	}
+	return true
+}
+
+// checkSessionCapacity rejects a new session when it would take a slot
+// reserved for another team. A session of a team with a reservation uses a
+// reserved slot while one is free, and an unreserved slot otherwise. Queued
+// allocations count as sessions, so the allocation queue never hands out
+// reserved slots either.
+func (m *KubernetesSessionManager) checkSessionCapacity(id string, req *entities.RunServerRequest) error {
+	return m.checkSessionCapacityWithout(id, req, "")
+}
+
+// checkSessionCapacityWithout is checkSessionCapacity as if the session
+// without did not hold a slot. "" counts every session.
+func (m *KubernetesSessionManager) checkSessionCapacityWithout(id string, req *entities.RunServerRequest, without string) error {
+	if m.k8sConfig == nil || m.k8sConfig.SessionCapacity <= 0 {
+		return nil
+	}
+	reserved := 0
+	for _, reservation := range m.k8sConfig.Reservations {
+		reserved += reservation.Sessions
+	}
+
+	used := make(map[string]int)
+	shared := 0
+	for _, session := range m.ListSessions(entities.SessionFilter{}) {
+		if session.ID() == id || session.ID() == without || !occupiesSessionSlot(session.Status()) {
+			continue
+		}
+		if session.Scope() == entities.ScopeTeam && m.teamReservation(session.TeamID()) != nil {
+			used[session.TeamID()]++
+			continue
+		}
+		shared++
+	}
+	for teamID, n := range used {
+		if extra := n - m.teamReservation(teamID).Sessions; extra > 0 {
+			shared += extra
+		}
+	}
+
+	if reservation := m.sessionReservation(req); reservation != nil && used[reservation.TeamID] < reservation.Sessions {
+		return nil
+	}
+	if free := m.k8sConfig.SessionCapacity - reserved; shared < free {
+		return nil
+	}
+	return fmt.Errorf("%w: all %d unreserved of %d sessions are in use",
+		entities.ErrSessionCapacityExhausted, m.k8sConfig.SessionCapacity-reserved, m.k8sConfig.SessionCapacity)
+}
+
+// applyGuaranteedQoS gives the Pod of a session of a team with guaranteed_qos
+// the Guaranteed QoS class: every container requests exactly its CPU and
+// memory limits. A missing limit is set to the request.
+func (m *KubernetesSessionManager) applyGuaranteedQoS(req *entities.RunServerRequest, spec *corev1.PodSpec) {
+	reservation := m.sessionReservation(req)
+	if reservation == nil || !reservation.GuaranteedQoS {
+		return
+	}
+	guarantee := func(containers []corev1.Container) {
+		for i := range containers {
+			resources := &containers[i].Resources
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				limit, hasLimit := resources.Limits[name]
+				request, hasRequest := resources.Requests[name]
+				switch {
+				case hasLimit:
+					if resources.Requests == nil {
+						resources.Requests = corev1.ResourceList{}
+					}
+					resources.Requests[name] = limit
+				case hasRequest:
+					if resources.Limits == nil {
+						resources.Limits = corev1.ResourceList{}
+					}
+					resources.Limits[name] = request
+				}
+			}
+		}
+	}
+	guarantee(spec.InitContainers)
+	guarantee(spec.Containers)
+}
+
+// reservedTeamQuota raises the entries of the team namespace quota hard to
+// the quota reserved for teamID. Resources the quota does not limit are left
+// unlimited.
+func (m *KubernetesSessionManager) reservedTeamQuota(teamID string, hard corev1.ResourceList) (corev1.ResourceList, error) {
+	reservation := m.teamReservation(teamID)
+	if reservation == nil || len(reservation.Quota) == 0 {
+		return hard, nil
+	}
+	quota, err := parseResourceList(reservation.Quota)
+	if err != nil {
+		return nil, fmt.Errorf("reserved quota of %s: %w", teamID, err)
+	}
+	for name, quantity := range quota {
+		if current, ok := hard[name]; ok && current.Cmp(quantity) < 0 {
+			hard[name] = quantity
+		}
+	}
+	return hard, nil
+}
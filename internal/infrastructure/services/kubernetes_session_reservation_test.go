@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/logger"
+)
+
+func TestValidateReservations(t *testing.T) {
+	valid := &config.KubernetesSessionConfig{
+		SessionCapacity: 3,
+		Reservations: []config.SessionReservation{
+			{TeamID: "org/critical", Sessions: 2, Quota: map[string]string{"requests.cpu": "8"}},
+		},
+	}
+	if err := validateReservations(valid); err != nil {
+		t.Fatalf("validateReservations = %v", err)
+	}
+	for name, cfg := range map[string]*config.KubernetesSessionConfig{
+		"over capacity": {SessionCapacity: 1, Reservations: []config.SessionReservation{{TeamID: "org/a", Sessions: 2}}},
+		"duplicate":     {Reservations: []config.SessionReservation{{TeamID: "org/a"}, {TeamID: "org/a"}}},
+		"missing team":  {Reservations: []config.SessionReservation{{Sessions: 1}}},
+		"bad quota":     {Reservations: []config.SessionReservation{{TeamID: "org/a", Quota: map[string]string{"pods": "many"}}}},
+	} {
+		if err := validateReservations(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestCheckSessionCapacityKeepsReservedSlots(t *testing.T) {
+	t.Setenv("LOG_DIR", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.KubernetesSession.Namespace = "test-ns"
+	cfg.KubernetesSession.SessionCapacity = 2
+	cfg.KubernetesSession.Reservations = []config.SessionReservation{{TeamID: "org/critical", Sessions: 1}}
+	manager, err := NewKubernetesSessionManagerWithClient(cfg, false, logger.NewLogger(), fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatalf("NewKubernetesSessionManagerWithClient() error = %v", err)
+	}
+	// Queued allocations hold their slot.
+	manager.SetSessionAllocatorEnabled(true)
+	ctx := context.Background()
+	user := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeUser}
+	critical := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeTeam, TeamID: "org/critical"}
+
+	if _, err := manager.CreateSession(ctx, "user-1", user, nil); err != nil {
+		t.Fatalf("first user session: %v", err)
+	}
+	if _, err := manager.CreateSession(ctx, "user-2", user, nil); !errors.Is(err, entities.ErrSessionCapacityExhausted) {
+		t.Fatalf("second user session took the reserved slot: %v", err)
+	}
+	// The allocator re-checks a queued session without counting it twice.
+	if err := manager.checkSessionCapacity("user-1", user); err != nil {
+		t.Fatalf("queued session rejected by the allocator: %v", err)
+	}
+	if _, err := manager.CreateSession(ctx, "critical-1", critical, nil); err != nil {
+		t.Fatalf("reserved session: %v", err)
+	}
+	if _, err := manager.CreateSession(ctx, "critical-2", critical, nil); !errors.Is(err, entities.ErrSessionCapacityExhausted) {
+		t.Fatalf("session beyond the reservation and capacity: %v", err)
+	}
+}
+
+func TestApplyGuaranteedQoS(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.Reservations = []config.SessionReservation{{TeamID: "org/critical", GuaranteedQoS: true}}
+	session := newWorkloadTestSession()
+
+	req := &entities.RunServerRequest{UserID: "test-user", Scope: entities.ScopeTeam, TeamID: "org/critical"}
+	deployment, err := manager.buildDeployment(context.Background(), session, req)
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			limit, hasLimit := container.Resources.Limits[name]
+			request, hasRequest := container.Resources.Requests[name]
+			if hasLimit != hasRequest || hasLimit && limit.Cmp(request) != 0 {
+				t.Errorf("%s %s: request %v != limit %v", container.Name, name, request.String(), limit.String())
+			}
+		}
+	}
+
+	// Sessions of other teams keep burstable resources.
+	deployment, err = manager.buildDeployment(context.Background(), session, &entities.RunServerRequest{UserID: "test-user"})
+	if err != nil {
+		t.Fatalf("buildDeployment: %v", err)
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != mainContainerName {
+			continue
+		}
+		if container.Resources.Requests.Cpu().Cmp(*container.Resources.Limits.Cpu()) == 0 {
+			t.Error("unreserved session got guaranteed cpu")
+		}
+	}
+}
+
+func TestReservedTeamNamespaceQuota(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.TeamNamespaceQuota = map[string]string{"requests.cpu": "4", "pods": "10"}
+	manager.k8sConfig.Reservations = []config.SessionReservation{
+		{TeamID: "org/critical", Quota: map[string]string{"requests.cpu": "16", "requests.memory": "64Gi"}},
+	}
+	ctx := context.Background()
+	if err := manager.ensureTeamNamespace(ctx, "agentapi-team-org-critical", "org/critical"); err != nil {
+		t.Fatalf("ensureTeamNamespace: %v", err)
+	}
+	quota, err := manager.client.CoreV1().ResourceQuotas("agentapi-team-org-critical").Get(ctx, teamResourceQuotaName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hard := quota.Spec.Hard
+	if got := hard[corev1.ResourceRequestsCPU]; got.String() != "16" {
+		t.Errorf("requests.cpu = %s, want the reserved 16", got.String())
+	}
+	if got := hard[corev1.ResourcePods]; got.String() != "10" {
+		t.Errorf("pods = %s, want 10", got.String())
+	}
+	if _, ok := hard[corev1.ResourceRequestsMemory]; ok {
+		t.Error("reservation limited memory that the team quota leaves unlimited")
+	}
+}
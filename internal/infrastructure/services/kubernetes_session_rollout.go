@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	// imageRolloutConfigMap keeps the current image rollout so that every
+	// replica reports it and the leader can continue it after a restart
+	imageRolloutConfigMap = "agentapi-image-rollout"
+	// imageRolloutDataKey is the ConfigMap key holding the rollout JSON
+	imageRolloutDataKey = "rollout.json"
+
+	defaultImageRolloutMinIdle        = 10 * time.Minute
+	defaultImageRolloutInterval       = 30 * time.Second
+	defaultImageRolloutUpgradeTimeout = 10 * time.Minute
+)
+
+// errImageRolloutConflict is returned when the stored rollout changed since
+// it was loaded
+var errImageRolloutConflict = errors.New("image rollout was modified concurrently")
+
+// validateImageRollout checks kubernetes_session.image_rollout
+func validateImageRollout(rollout *config.SessionImageRolloutConfig) error {
+	for _, d := range []struct{ name, value string }{
+		{"min_idle", rollout.MinIdle},
+		{"interval", rollout.Interval},
+		{"upgrade_timeout", rollout.UpgradeTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(d.value); err != nil || parsed < 0 {
+			return fmt.Errorf("kubernetes_session.image_rollout.%s: invalid duration %q", d.name, d.value)
+		}
+	}
+	if rollout.MaxConcurrent < 0 {
+		return fmt.Errorf("kubernetes_session.image_rollout.max_concurrent must not be negative")
+	}
+	return nil
+}
+
+// imageRolloutDuration parses value, or returns fallback when it is unset
+// or malformed
+func imageRolloutDuration(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
+	}
+	return fallback
+}
+
+// StartImageRollout starts moving running sessions to the image the config
+// currently gives them. Only one rollout runs at a time; the leader carries
+// it out with RunImageRollouts.
+func (m *KubernetesSessionManager) StartImageRollout(ctx context.Context, req entities.ImageRolloutRequest, actor string) (*entities.ImageRollout, error) {
+	if !m.isPVCEnabled() {
+		return nil, entities.ErrImageRolloutUnsupported
+	}
+	policy := m.k8sConfig.ImageRollout
+	if req.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("%w: max_concurrent must not be negative", entities.ErrInvalidImageRollout)
+	}
+	if req.MinIdle != "" {
+		if d, err := time.ParseDuration(req.MinIdle); err != nil || d < 0 {
+			return nil, fmt.Errorf("%w: invalid min_idle %q", entities.ErrInvalidImageRollout, req.MinIdle)
+		}
+	}
+
+	current, stored, err := m.loadImageRollout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.State == entities.ImageRolloutRunning {
+		return nil, entities.ErrImageRolloutInProgress
+	}
+
+	now := time.Now().UTC()
+	rollout := &entities.ImageRollout{
+		ID:            uuid.New().String(),
+		State:         entities.ImageRolloutRunning,
+		StartedBy:     actor,
+		StartedAt:     now,
+		MaxConcurrent: req.MaxConcurrent,
+		MinIdle:       req.MinIdle,
+		PinnedTeams:   req.PinnedTeams,
+		Sessions:      []entities.ImageRolloutSession{},
+	}
+	if rollout.MaxConcurrent == 0 {
+		rollout.MaxConcurrent = policy.MaxConcurrent
+	}
+	if rollout.MaxConcurrent == 0 {
+		rollout.MaxConcurrent = 1
+	}
+	if rollout.MinIdle == "" {
+		rollout.MinIdle = imageRolloutDuration(policy.MinIdle, defaultImageRolloutMinIdle).String()
+	}
+	// Plan without upgrading so that the response lists the outdated
+	// sessions; the leader upgrades them on its next step
+	m.stepImageRollout(ctx, rollout, now, false)
+
+	if err := m.saveImageRollout(ctx, rollout, stored); err != nil {
+		if errors.Is(err, errImageRolloutConflict) {
+			return nil, entities.ErrImageRolloutInProgress
+		}
+		return nil, err
+	}
+	log.Printf("[IMAGE_ROLLOUT] %s started rollout %s: %d outdated session(s), %d at a time, min idle %s",
+		actor, rollout.ID, len(rollout.Sessions), rollout.MaxConcurrent, rollout.MinIdle)
+	return rollout, nil
+}
+
+// GetImageRollout returns the current or last image rollout
+func (m *KubernetesSessionManager) GetImageRollout(ctx context.Context) (*entities.ImageRollout, error) {
+	rollout, _, err := m.loadImageRollout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rollout == nil {
+		return nil, entities.ErrImageRolloutNotFound
+	}
+	return rollout, nil
+}
+
+// CancelImageRollout stops the running image rollout. Sessions already
+// upgraded keep their new image.
+func (m *KubernetesSessionManager) CancelImageRollout(ctx context.Context, actor string) (*entities.ImageRollout, error) {
+	for attempt := 0; ; attempt++ {
+		rollout, stored, err := m.loadImageRollout(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if rollout == nil {
+			return nil, entities.ErrImageRolloutNotFound
+		}
+		if rollout.State != entities.ImageRolloutRunning {
+			return rollout, nil
+		}
+		now := time.Now().UTC()
+		rollout.State = entities.ImageRolloutCancelled
+		rollout.UpdatedAt, rollout.FinishedAt = now, &now
+		err = m.saveImageRollout(ctx, rollout, stored)
+		if errors.Is(err, errImageRolloutConflict) && attempt < 3 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[IMAGE_ROLLOUT] %s cancelled rollout %s", actor, rollout.ID)
+		return rollout, nil
+	}
+}
+
+// RunImageRollouts advances the running image rollout every
+// kubernetes_session.image_rollout.interval until ctx is done. Run it on the
+// leader only.
+func (m *KubernetesSessionManager) RunImageRollouts(ctx context.Context) {
+	ticker := time.NewTicker(imageRolloutDuration(m.k8sConfig.ImageRollout.Interval, defaultImageRolloutInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		rollout, stored, err := m.loadImageRollout(ctx)
+		if err != nil {
+			log.Printf("[IMAGE_ROLLOUT] %v", err)
+			continue
+		}
+		if rollout == nil || rollout.State != entities.ImageRolloutRunning {
+			continue
+		}
+		m.stepImageRollout(ctx, rollout, time.Now().UTC(), true)
+		if err := m.saveImageRollout(ctx, rollout, stored); err != nil {
+			log.Printf("[IMAGE_ROLLOUT] Failed to store rollout %s: %v", rollout.ID, err)
+			continue
+		}
+		if rollout.State == entities.ImageRolloutCompleted {
+			log.Printf("[IMAGE_ROLLOUT] Rollout %s completed: %v", rollout.ID, rollout.Counts)
+		}
+	}
+}
+
+// rolloutCandidate is an outdated session that may be upgraded now
+type rolloutCandidate struct {
+	session *KubernetesSession
+	entry   *entities.ImageRolloutSession
+	idleFor time.Duration
+}
+
+// stepImageRollout updates the sessions of rollout and, with upgrade,
+// upgrades the longest idle outdated sessions up to its max_concurrent.
+// Sessions running an agent task, or not idle for min_idle, wait. The
+// rollout completes when no session waits or upgrades anymore.
+func (m *KubernetesSessionManager) stepImageRollout(ctx context.Context, rollout *entities.ImageRollout, now time.Time, upgrade bool) {
+	live := m.liveConfig()
+	pinned := make(map[string]bool)
+	for _, team := range live.TeamImages {
+		if team.Pinned {
+			pinned[team.TeamID] = true
+		}
+	}
+	for _, team := range rollout.PinnedTeams {
+		pinned[team] = true
+	}
+	minIdle := imageRolloutDuration(rollout.MinIdle, defaultImageRolloutMinIdle)
+	timeout := imageRolloutDuration(m.k8sConfig.ImageRollout.UpgradeTimeout, defaultImageRolloutUpgradeTimeout)
+
+	entries := make(map[string]*entities.ImageRolloutSession, len(rollout.Sessions))
+	order := make([]string, 0, len(rollout.Sessions))
+	for i := range rollout.Sessions {
+		entry := rollout.Sessions[i]
+		entries[entry.SessionID] = &entry
+		order = append(order, entry.SessionID)
+	}
+	set := func(entry *entities.ImageRolloutSession, state entities.ImageRolloutSessionState, reason string) {
+		if entry.State != state || entry.Reason != reason {
+			entry.State, entry.Reason, entry.UpdatedAt = state, reason, now
+		}
+	}
+
+	m.mutex.RLock()
+	sessions := make([]*KubernetesSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	m.mutex.RUnlock()
+
+	present := make(map[string]bool, len(sessions))
+	var candidates []rolloutCandidate
+	upgrading := 0
+	for _, session := range sessions {
+		if session.IsStock() || session.RunsAsJob() {
+			continue
+		}
+		deployment, err := m.client.AppsV1().Deployments(session.Namespace()).Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				log.Printf("[IMAGE_ROLLOUT] Failed to get deployment of session %s: %v", session.ID(), err)
+				present[session.ID()] = entries[session.ID()] != nil
+			}
+			continue
+		}
+		current := agentContainerImage(&deployment.Spec.Template.Spec)
+		entry := entries[session.ID()]
+
+		if entry != nil && entry.State == entities.ImageRolloutSessionUpgrading {
+			present[session.ID()] = true
+			switch {
+			case current == entry.ToImage && deploymentRolledOut(deployment):
+				set(entry, entities.ImageRolloutSessionUpgraded, "")
+				m.recordEvent(session.ID(), entities.SessionEventImageUpgraded, "Upgraded from %s to %s", entry.FromImage, entry.ToImage)
+			case now.Sub(entry.UpdatedAt) > timeout:
+				set(entry, entities.ImageRolloutSessionFailed, fmt.Sprintf("not ready %s after the upgrade", timeout))
+			default:
+				upgrading++
+			}
+			continue
+		}
+
+		target := m.sessionImage(session.Request())
+		if current == "" || current == target {
+			// Up to date: keep the outcome of an earlier upgrade only
+			present[session.ID()] = entry != nil && entry.State != entities.ImageRolloutSessionWaiting && entry.State != entities.ImageRolloutSessionSkipped
+			continue
+		}
+		present[session.ID()] = true
+		if entry == nil {
+			entry = &entities.ImageRolloutSession{SessionID: session.ID(), UserID: session.UserID(), UpdatedAt: now}
+			if session.Scope() == entities.ScopeTeam {
+				entry.TeamID = session.TeamID()
+			}
+			entries[session.ID()] = entry
+			order = append(order, session.ID())
+		}
+		if entry.State == entities.ImageRolloutSessionFailed {
+			continue
+		}
+		entry.FromImage, entry.ToImage = current, target
+
+		status := session.Status()
+		switch {
+		case entry.TeamID != "" && pinned[entry.TeamID]:
+			set(entry, entities.ImageRolloutSessionSkipped, fmt.Sprintf("team %s is pinned", entry.TeamID))
+		case status == "running":
+			set(entry, entities.ImageRolloutSessionWaiting, "the agent is running a task")
+		case status == "paused":
+			// Paused sessions have no Pod to disturb and go first
+			set(entry, entities.ImageRolloutSessionWaiting, "ready to upgrade")
+			candidates = append(candidates, rolloutCandidate{session: session, entry: entry, idleFor: time.Duration(1<<63 - 1)})
+		case status != "active":
+			set(entry, entities.ImageRolloutSessionWaiting, fmt.Sprintf("the session is %s", status))
+		default:
+			idleFor := now.Sub(m.sessionLastActivity(ctx, session))
+			if idleFor < minIdle {
+				set(entry, entities.ImageRolloutSessionWaiting, fmt.Sprintf("idle for %s of %s", idleFor.Round(time.Second), minIdle))
+				continue
+			}
+			set(entry, entities.ImageRolloutSessionWaiting, "ready to upgrade")
+			candidates = append(candidates, rolloutCandidate{session: session, entry: entry, idleFor: idleFor})
+		}
+	}
+
+	if upgrade {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].idleFor > candidates[j].idleFor })
+		for _, c := range candidates {
+			if upgrading >= rollout.MaxConcurrent {
+				break
+			}
+			if err := m.setSessionImage(ctx, c.session, c.entry.ToImage); err != nil {
+				log.Printf("[IMAGE_ROLLOUT] Failed to upgrade session %s to %s: %v", c.session.ID(), c.entry.ToImage, err)
+				set(c.entry, entities.ImageRolloutSessionFailed, err.Error())
+				continue
+			}
+			log.Printf("[IMAGE_ROLLOUT] Upgrading session %s from %s to %s", c.session.ID(), c.entry.FromImage, c.entry.ToImage)
+			set(c.entry, entities.ImageRolloutSessionUpgrading, "")
+			upgrading++
+		}
+	}
+
+	// Sessions deleted while waiting drop out; finished ones are kept
+	rollout.Sessions = rollout.Sessions[:0]
+	pending := false
+	for _, id := range order {
+		entry := entries[id]
+		if !present[id] {
+			if entry.State != entities.ImageRolloutSessionUpgraded && entry.State != entities.ImageRolloutSessionFailed {
+				continue
+			}
+		}
+		if entry.State == entities.ImageRolloutSessionWaiting || entry.State == entities.ImageRolloutSessionUpgrading {
+			pending = true
+		}
+		rollout.Sessions = append(rollout.Sessions, *entry)
+	}
+	rollout.UpdatedAt = now
+	rollout.Count()
+	if upgrade && !pending {
+		rollout.State = entities.ImageRolloutCompleted
+		rollout.FinishedAt = &now
+	}
+}
+
+// agentContainerImage returns the image of the agentapi container of spec
+func agentContainerImage(spec *corev1.PodSpec) string {
+	for _, c := range spec.Containers {
+		if c.Name == "agentapi" {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+// deploymentRolledOut reports whether every Pod of deployment runs its
+// current template and is available
+func deploymentRolledOut(deployment *appsv1.Deployment) bool {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == desired &&
+		status.Replicas == desired &&
+		status.AvailableReplicas == desired
+}
+
+// setSessionImage changes the image of the agentapi container of the
+// session's Deployment, which replaces its Pods. The workdir on the PVC is
+// kept.
+func (m *KubernetesSessionManager) setSessionImage(ctx context.Context, session *KubernetesSession, image string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []map[string]string{{"name": "agentapi", "image": image}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.client.AppsV1().Deployments(session.Namespace()).Patch(ctx, session.DeploymentName(), types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// loadImageRollout returns the stored rollout and its ConfigMap, or nil when
+// no rollout was started
+func (m *KubernetesSessionManager) loadImageRollout(ctx context.Context) (*entities.ImageRollout, *corev1.ConfigMap, error) {
+	cm, err := m.client.CoreV1().ConfigMaps(m.namespace).Get(ctx, imageRolloutConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read image rollout: %w", err)
+	}
+	data := cm.Data[imageRolloutDataKey]
+	if data == "" {
+		return nil, cm, nil
+	}
+	var rollout entities.ImageRollout
+	if err := json.Unmarshal([]byte(data), &rollout); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse image rollout: %w", err)
+	}
+	return &rollout, cm, nil
+}
+
+// saveImageRollout stores rollout in stored, the ConfigMap it was loaded
+// from, or a new ConfigMap when stored is nil. A concurrent change fails
+// with errImageRolloutConflict.
+func (m *KubernetesSessionManager) saveImageRollout(ctx context.Context, rollout *entities.ImageRollout, stored *corev1.ConfigMap) error {
+	data, err := json.Marshal(rollout)
+	if err != nil {
+		return err
+	}
+	cms := m.client.CoreV1().ConfigMaps(m.namespace)
+	if stored == nil {
+		_, err = cms.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      imageRolloutConfigMap,
+				Namespace: m.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy"},
+			},
+			Data: map[string]string{imageRolloutDataKey: string(data)},
+		}, metav1.CreateOptions{})
+	} else {
+		cm := stored.DeepCopy()
+		cm.Data = map[string]string{imageRolloutDataKey: string(data)}
+		_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return errImageRolloutConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store image rollout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateImageRollout(t *testing.T) {
+	if err := validateImageRollout(&config.SessionImageRolloutConfig{MinIdle: "10m", MaxConcurrent: 2}); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for name, rollout := range map[string]config.SessionImageRolloutConfig{
+		"bad min_idle":        {MinIdle: "later"},
+		"bad upgrade_timeout": {UpgradeTimeout: "-1m"},
+		"negative":            {MaxConcurrent: -1},
+	} {
+		if err := validateImageRollout(&rollout); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// addRolloutTestSession creates a running session with its Service and
+// Deployment, idle for idleFor.
+func addRolloutTestSession(t *testing.T, manager *KubernetesSessionManager, id string, req *entities.RunServerRequest, status string, idleFor time.Duration) *KubernetesSession {
+	t.Helper()
+	session := NewKubernetesSession(id, req, "agentapi-session-"+id, "agentapi-session-"+id+"-svc", "agentapi-session-"+id+"-pvc", "test-ns", 9000, nil, nil)
+	session.startedAt = time.Now().Add(-idleFor)
+	session.lastMessageAt = session.startedAt
+	ctx := context.Background()
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := manager.createSessionWorkload(ctx, session, req); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	session.SetStatus(status)
+	manager.mutex.Lock()
+	manager.sessions[id] = session
+	manager.mutex.Unlock()
+	return session
+}
+
+// stepStoredRollout advances the stored rollout once like RunImageRollouts
+func stepStoredRollout(t *testing.T, manager *KubernetesSessionManager) *entities.ImageRollout {
+	t.Helper()
+	ctx := context.Background()
+	rollout, stored, err := manager.loadImageRollout(ctx)
+	if err != nil || rollout == nil {
+		t.Fatalf("loadImageRollout() = %v, %v", rollout, err)
+	}
+	manager.stepImageRollout(ctx, rollout, time.Now().UTC(), true)
+	if err := manager.saveImageRollout(ctx, rollout, stored); err != nil {
+		t.Fatalf("saveImageRollout() error = %v", err)
+	}
+	return rollout
+}
+
+func rolloutStates(rollout *entities.ImageRollout) map[string]entities.ImageRolloutSessionState {
+	states := make(map[string]entities.ImageRolloutSessionState, len(rollout.Sessions))
+	for _, s := range rollout.Sessions {
+		states[s.SessionID] = s.State
+	}
+	return states
+}
+
+func TestImageRolloutUpgradesIdleSessionsFirst(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	manager.k8sConfig.ImageRollout = config.SessionImageRolloutConfig{MinIdle: "10m"}
+	manager.k8sConfig.TeamImages = []config.TeamImage{{TeamID: "acme/ml", Pinned: true}}
+	ctx := context.Background()
+
+	addRolloutTestSession(t, manager, "idle-2h", &entities.RunServerRequest{UserID: "alice"}, "active", 2*time.Hour)
+	addRolloutTestSession(t, manager, "idle-30m", &entities.RunServerRequest{UserID: "bob"}, "active", 30*time.Minute)
+	addRolloutTestSession(t, manager, "busy", &entities.RunServerRequest{UserID: "carol"}, "running", 2*time.Hour)
+	addRolloutTestSession(t, manager, "recent", &entities.RunServerRequest{UserID: "dave"}, "active", time.Minute)
+	addRolloutTestSession(t, manager, "pinned", &entities.RunServerRequest{UserID: "erin", Scope: entities.ScopeTeam, TeamID: "acme/ml"}, "active", 2*time.Hour)
+	addRolloutTestSession(t, manager, "requested", &entities.RunServerRequest{UserID: "frank", Image: "custom:1"}, "active", 2*time.Hour)
+
+	// The image is updated; a new session already gets it and the session
+	// that requested its image keeps it
+	manager.k8sConfig.Image = "test-image:v2"
+	addRolloutTestSession(t, manager, "fresh", &entities.RunServerRequest{UserID: "grace"}, "active", 2*time.Hour)
+
+	if _, err := manager.GetImageRollout(ctx); !errors.Is(err, entities.ErrImageRolloutNotFound) {
+		t.Fatalf("GetImageRollout() before a rollout = %v", err)
+	}
+	rollout, err := manager.StartImageRollout(ctx, entities.ImageRolloutRequest{}, "admin")
+	if err != nil {
+		t.Fatalf("StartImageRollout() error = %v", err)
+	}
+	want := map[string]entities.ImageRolloutSessionState{
+		"idle-2h":  entities.ImageRolloutSessionWaiting,
+		"idle-30m": entities.ImageRolloutSessionWaiting,
+		"busy":     entities.ImageRolloutSessionWaiting,
+		"recent":   entities.ImageRolloutSessionWaiting,
+		"pinned":   entities.ImageRolloutSessionSkipped,
+	}
+	if got := rolloutStates(rollout); len(got) != len(want) {
+		t.Fatalf("planned sessions = %v", got)
+	}
+	for id, state := range want {
+		if got := rolloutStates(rollout)[id]; got != state {
+			t.Errorf("planned state of %s = %q, want %q", id, got, state)
+		}
+	}
+	if _, err := manager.StartImageRollout(ctx, entities.ImageRolloutRequest{}, "admin"); !errors.Is(err, entities.ErrImageRolloutInProgress) {
+		t.Fatalf("second StartImageRollout() = %v", err)
+	}
+
+	deploymentImage := func(id string) string {
+		deployment, err := manager.client.AppsV1().Deployments("test-ns").Get(ctx, "agentapi-session-"+id, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return agentContainerImage(&deployment.Spec.Template.Spec)
+	}
+	markRolledOut := func(id string) {
+		deployment, _ := manager.client.AppsV1().Deployments("test-ns").Get(ctx, "agentapi-session-"+id, metav1.GetOptions{})
+		deployment.Status.Replicas, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas = 1, 1, 1
+		if _, err := manager.client.AppsV1().Deployments("test-ns").UpdateStatus(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The longest idle session goes first, one at a time
+	rollout = stepStoredRollout(t, manager)
+	if got := rolloutStates(rollout)["idle-2h"]; got != entities.ImageRolloutSessionUpgrading {
+		t.Fatalf("idle-2h = %q after the first step", got)
+	}
+	if deploymentImage("idle-2h") != "test-image:v2" || deploymentImage("idle-30m") != "test-image:latest" {
+		t.Fatalf("images after the first step: %s, %s", deploymentImage("idle-2h"), deploymentImage("idle-30m"))
+	}
+	rollout = stepStoredRollout(t, manager)
+	if got := rolloutStates(rollout)["idle-30m"]; got != entities.ImageRolloutSessionWaiting {
+		t.Fatalf("idle-30m upgraded while idle-2h is upgrading: %q", got)
+	}
+
+	markRolledOut("idle-2h")
+	rollout = stepStoredRollout(t, manager)
+	states := rolloutStates(rollout)
+	if states["idle-2h"] != entities.ImageRolloutSessionUpgraded || states["idle-30m"] != entities.ImageRolloutSessionUpgrading {
+		t.Fatalf("states after idle-2h rolled out = %v", states)
+	}
+	if states["busy"] != entities.ImageRolloutSessionWaiting || states["recent"] != entities.ImageRolloutSessionWaiting {
+		t.Fatalf("busy or recent sessions upgraded: %v", states)
+	}
+	if deploymentImage("pinned") != "test-image:latest" || deploymentImage("requested") != "custom:1" {
+		t.Fatal("pinned or requested images were changed")
+	}
+	if rollout.Counts[entities.ImageRolloutSessionUpgraded] != 1 || rollout.State != entities.ImageRolloutRunning {
+		t.Fatalf("rollout = %s %v", rollout.State, rollout.Counts)
+	}
+
+	cancelled, err := manager.CancelImageRollout(ctx, "admin")
+	if err != nil || cancelled.State != entities.ImageRolloutCancelled || cancelled.FinishedAt == nil {
+		t.Fatalf("CancelImageRollout() = %+v, %v", cancelled, err)
+	}
+	if _, err := manager.StartImageRollout(ctx, entities.ImageRolloutRequest{MaxConcurrent: 5}, "admin"); err != nil {
+		t.Fatalf("StartImageRollout() after cancel = %v", err)
+	}
+}
+
+func TestImageRolloutCompletes(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	ctx := context.Background()
+	addRolloutTestSession(t, manager, "paused", &entities.RunServerRequest{UserID: "alice"}, "paused", time.Minute)
+	manager.k8sConfig.Image = "test-image:v2"
+
+	if _, err := manager.StartImageRollout(ctx, entities.ImageRolloutRequest{}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	// Paused sessions are upgraded however recently they were used
+	rollout := stepStoredRollout(t, manager)
+	if got := rolloutStates(rollout)["paused"]; got != entities.ImageRolloutSessionUpgrading {
+		t.Fatalf("paused = %q", got)
+	}
+	deployment, _ := manager.client.AppsV1().Deployments("test-ns").Get(ctx, "agentapi-session-paused", metav1.GetOptions{})
+	deployment.Status.Replicas, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas = 1, 1, 1
+	if _, err := manager.client.AppsV1().Deployments("test-ns").UpdateStatus(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rollout = stepStoredRollout(t, manager)
+	if rollout.State != entities.ImageRolloutCompleted || rollout.FinishedAt == nil {
+		t.Fatalf("rollout = %s, sessions %+v", rollout.State, rollout.Sessions)
+	}
+}
+
+func TestStartImageRolloutNeedsDeployments(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	if _, err := manager.StartImageRollout(context.Background(), entities.ImageRolloutRequest{}, "admin"); !errors.Is(err, entities.ErrImageRolloutUnsupported) {
+		t.Fatalf("StartImageRollout() without PVCs = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Session routing modes select how the proxy addresses session Pods.
+const (
+	// RoutingService addresses sessions by the DNS name of their ClusterIP Service.
+	RoutingService = "service"
+	// RoutingEndpoints addresses sessions by the IP of a ready endpoint of
+	// their Service, so routing does not depend on cluster DNS.
+	RoutingEndpoints = "endpoints"
+	// RoutingHeadless creates headless Services, whose DNS name resolves
+	// directly to the session Pod instead of a virtual IP.
+	RoutingHeadless = "headless"
+)
+
+// defaultClusterDomain is the DNS suffix of Kubernetes clusters that do not
+// configure their own.
+const defaultClusterDomain = "cluster.local"
+
+// validateRoutingMode rejects unknown kubernetes_session.routing values.
+func validateRoutingMode(mode string) error {
+	switch mode {
+	case "", RoutingService, RoutingEndpoints, RoutingHeadless:
+		return nil
+	}
+	return fmt.Errorf("unknown kubernetes_session.routing %q: must be %q, %q or %q",
+		mode, RoutingService, RoutingEndpoints, RoutingHeadless)
+}
+
+// clusterDomain returns the configured cluster DNS suffix.
+func (m *KubernetesSessionManager) clusterDomain() string {
+	if m.k8sConfig != nil && m.k8sConfig.ClusterDomain != "" {
+		return m.k8sConfig.ClusterDomain
+	}
+	return defaultClusterDomain
+}
+
+// routingMode returns the configured routing mode, RoutingService by default.
+func (m *KubernetesSessionManager) routingMode() string {
+	if m.k8sConfig != nil && m.k8sConfig.Routing != "" {
+		return m.k8sConfig.Routing
+	}
+	return RoutingService
+}
+
+// serviceHost returns the DNS name of a Service in the manager's namespace.
+func (m *KubernetesSessionManager) serviceHost(serviceName string) string {
+	return serviceDNSName(serviceName, m.namespace, m.clusterDomain())
+}
+
+// sessionHost returns the host the proxy uses to reach a session. Sessions
+// not held by this replica are addressed by their Service DNS name.
+func (m *KubernetesSessionManager) sessionHost(id string) string {
+	m.mutex.RLock()
+	session, ok := m.sessions[id]
+	m.mutex.RUnlock()
+	if ok {
+		return session.Host()
+	}
+	return m.serviceHost(fmt.Sprintf("agentapi-session-%s-svc", id))
+}
+
+// refreshRouteHost points the session at a ready endpoint of its Service when
+// routing by endpoints, and records all ready endpoints of multi-replica
+// sessions for AddrFor. While no endpoint is ready the last known addresses
+// are kept, or the Service DNS name is used when none is known yet.
+func (m *KubernetesSessionManager) refreshRouteHost(ctx context.Context, session *KubernetesSession) {
+	byEndpoints := m.routingMode() == RoutingEndpoints
+	multiReplica := session.Replicas() > 1
+	if !byEndpoints && !multiReplica {
+		return
+	}
+	slices, err := m.client.DiscoveryV1().EndpointSlices(session.Namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + session.ServiceName(),
+	})
+	if err != nil {
+		log.Printf("[K8S_SESSION] Failed to list endpoints of session %s: %v", session.id, err)
+		return
+	}
+	if multiReplica {
+		if ips := readyEndpointIPs(slices.Items); len(ips) > 0 {
+			session.setEndpoints(ips)
+		}
+	}
+	if !byEndpoints {
+		return
+	}
+	if ip := readyEndpointIP(slices.Items); ip != "" && ip != session.routeHostValue() {
+		session.setRouteHost(ip)
+		log.Printf("[K8S_SESSION] Routing session %s to endpoint %s", session.id, ip)
+	}
+}
+
+// readyEndpointIP returns the first address of a ready endpoint, or "".
+// IPv6 addresses are bracketed so they can be joined with a port.
+func readyEndpointIP(slices []discoveryv1.EndpointSlice) string {
+	if ips := readyEndpointIPs(slices); len(ips) > 0 {
+		return ips[0]
+	}
+	return ""
+}
+
+// readyEndpointIPs returns the first address of every ready endpoint,
+// formatted like readyEndpointIP.
+func readyEndpointIPs(slices []discoveryv1.EndpointSlice) []string {
+	var ips []string
+	for _, slice := range slices {
+		if slice.AddressType != discoveryv1.AddressTypeIPv4 && slice.AddressType != discoveryv1.AddressTypeIPv6 {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			// A nil Ready condition means ready, as documented by the API.
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			if len(ep.Addresses) == 0 {
+				continue
+			}
+			if slice.AddressType == discoveryv1.AddressTypeIPv6 {
+				ips = append(ips, "["+ep.Addresses[0]+"]")
+			} else {
+				ips = append(ips, ep.Addresses[0])
+			}
+		}
+	}
+	return ips
+}
+
+// serviceDNSName builds the in-cluster DNS name of a Service.
+func serviceDNSName(serviceName, namespace, clusterDomain string) string {
+	if clusterDomain == "" {
+		clusterDomain = defaultClusterDomain
+	}
+	return serviceName + "." + namespace + ".svc." + clusterDomain
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSessionHostUsesClusterDomain(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.ClusterDomain = "corp.internal"
+
+	session := newWorkloadTestSession()
+	session.clusterDomain = manager.clusterDomain()
+	if got, want := session.Addr(), "agentapi-session-test-session-svc.test-ns.svc.corp.internal:9000"; got != want {
+		t.Errorf("Addr() = %q, want %q", got, want)
+	}
+	if got, want := manager.sessionHost("other"), "agentapi-session-other-svc.test-ns.svc.corp.internal"; got != want {
+		t.Errorf("sessionHost() = %q, want %q", got, want)
+	}
+	if got, want := manager.provisionerProxyURL(), "http://agentapi-proxy.test-ns.svc.corp.internal:8080"; got != want {
+		t.Errorf("provisionerProxyURL() = %q, want %q", got, want)
+	}
+}
+
+func TestHeadlessRoutingCreatesHeadlessService(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.Routing = RoutingHeadless
+	ctx := context.Background()
+
+	session := newWorkloadTestSession()
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService: %v", err)
+	}
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("ClusterIP = %q, want None", svc.Spec.ClusterIP)
+	}
+}
+
+func TestEndpointsRoutingUsesReadyEndpoint(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	ctx := context.Background()
+	session := newWorkloadTestSession()
+
+	notReady, ready := false, true
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      session.ServiceName() + "-abc",
+			Namespace: "test-ns",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: session.ServiceName()},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+			{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}},
+		},
+	}
+	if _, err := manager.client.DiscoveryV1().EndpointSlices("test-ns").Create(ctx, slice, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create endpoint slice: %v", err)
+	}
+
+	// Service routing ignores endpoints
+	manager.refreshRouteHost(ctx, session)
+	if got := session.Host(); got != session.ServiceDNS() {
+		t.Errorf("Host() with service routing = %q, want %q", got, session.ServiceDNS())
+	}
+
+	manager.k8sConfig.Routing = RoutingEndpoints
+	manager.refreshRouteHost(ctx, session)
+	if got, want := session.Addr(), "10.0.0.2:9000"; got != want {
+		t.Errorf("Addr() = %q, want %q", got, want)
+	}
+
+	// The last known endpoint is kept while none is ready
+	if err := manager.client.DiscoveryV1().EndpointSlices("test-ns").Delete(ctx, slice.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete endpoint slice: %v", err)
+	}
+	manager.refreshRouteHost(ctx, session)
+	if got := session.Host(); got != "10.0.0.2" {
+		t.Errorf("Host() after endpoints vanished = %q, want 10.0.0.2", got)
+	}
+}
+
+func TestReadyEndpointIPBracketsIPv6(t *testing.T) {
+	got := readyEndpointIP([]discoveryv1.EndpointSlice{{
+		AddressType: discoveryv1.AddressTypeIPv6,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"fd00::1"}}},
+	}})
+	if got != "[fd00::1]" {
+		t.Errorf("readyEndpointIP() = %q, want [fd00::1]", got)
+	}
+}
+
+func TestValidateRoutingMode(t *testing.T) {
+	for _, mode := range []string{"", RoutingService, RoutingEndpoints, RoutingHeadless} {
+		if err := validateRoutingMode(mode); err != nil {
+			t.Errorf("validateRoutingMode(%q) = %v", mode, err)
+		}
+	}
+	if err := validateRoutingMode("ingress"); err == nil {
+		t.Error("validateRoutingMode(ingress) succeeded, want error")
+	}
+}
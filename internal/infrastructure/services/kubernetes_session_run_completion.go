@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// completionCallbackAnnotation records the completion callback URL of a
+// session so that restored sessions still notify it.
+const completionCallbackAnnotation = "agentapi.proxy/completion-callback-url"
+
+// awaitsRunCompletion reports whether the session is completed once the agent
+// has finished its initial message. Job sessions complete when the Job ends.
+func (s *KubernetesSession) awaitsRunCompletion() bool {
+	if s.RunsAsJob() || s.Completion() != nil {
+		return false
+	}
+	return s.request != nil && s.request.InitialMessage != ""
+}
+
+// CompletionCallbackURL returns the URL notified when the session completes,
+// or "".
+func (s *KubernetesSession) CompletionCallbackURL() string {
+	if s.request == nil {
+		return ""
+	}
+	return s.request.CompletionCallbackURL
+}
+
+// agentStable applies an agentapi "stable" status. The first time the agent
+// goes from running to stable after the initial message the session is
+// completed; a completed session stays completed until the agent runs again.
+func (m *KubernetesSessionManager) agentStable(ctx context.Context, session *KubernetesSession) {
+	switch status := session.Status(); {
+	case status == entities.SessionStatusCompleted:
+	case status == "running" && session.awaitsRunCompletion():
+		m.completeRun(ctx, session)
+	default:
+		session.SetStatus("active")
+	}
+}
+
+// completeRun records that the agent has finished the initial message of a
+// session and marks the session completed.
+func (m *KubernetesSessionManager) completeRun(ctx context.Context, session *KubernetesSession) {
+	completion := &entities.SessionCompletion{
+		SessionID:  session.id,
+		UserID:     session.UserID(),
+		Scope:      session.Scope(),
+		TeamID:     session.TeamID(),
+		Succeeded:  true,
+		FinishedAt: time.Now().UTC(),
+	}
+	// The annotation keeps restored sessions from completing a second time.
+	if data, err := json.Marshal(completion); err == nil {
+		if err := m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+			completionAnnotation: string(data),
+		}); err != nil {
+			log.Printf("[K8S_SESSION] Failed to record completion of session %s: %v", session.id, err)
+		}
+	}
+
+	session.setCompletion(completion)
+	session.SetStatus(entities.SessionStatusCompleted)
+	m.recordEvent(session.id, entities.SessionEventCompleted, "Agent finished the initial message")
+	log.Printf("[K8S_SESSION] Session %s completed its initial message", session.id)
+}
+
+// restoreRunCompletionFromService picks up the completion of a restored
+// non-Job session. An idle completed session is reported as completed again.
+func restoreRunCompletionFromService(session *KubernetesSession, svc *corev1.Service) {
+	if svc.Annotations[workloadAnnotation] == workloadJob || svc.Annotations[completionAnnotation] == "" {
+		return
+	}
+	var completion entities.SessionCompletion
+	if err := json.Unmarshal([]byte(svc.Annotations[completionAnnotation]), &completion); err != nil {
+		log.Printf("[K8S_SESSION] Ignoring invalid completion annotation on Service %s: %v", svc.Name, err)
+		return
+	}
+	session.setCompletion(&completion)
+	if session.Status() == "active" {
+		session.SetStatus(entities.SessionStatusCompleted)
+	}
+}
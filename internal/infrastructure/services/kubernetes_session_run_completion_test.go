@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestAgentStableCompletesInitialRun(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	session.Request().InitialMessage = "Fix the flaky test"
+	session.Request().CompletionCallbackURL = "https://example.com/callback"
+	ctx := context.Background()
+
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService: %v", err)
+	}
+
+	// Becoming stable before the agent has run does not complete the session
+	manager.agentStable(ctx, session)
+	if session.Status() != "active" || session.Completion() != nil {
+		t.Fatalf("status = %q, completion = %+v, want active without completion", session.Status(), session.Completion())
+	}
+
+	session.SetStatus("running")
+	manager.agentStable(ctx, session)
+	completion := session.Completion()
+	if session.Status() != entities.SessionStatusCompleted || completion == nil || !completion.Succeeded {
+		t.Fatalf("status = %q, completion = %+v, want a successful completion", session.Status(), completion)
+	}
+
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if svc.Annotations[completionCallbackAnnotation] != "https://example.com/callback" || svc.Annotations[completionAnnotation] == "" {
+		t.Errorf("annotations = %v, want the callback URL and completion", svc.Annotations)
+	}
+
+	// A restored session keeps its completion and does not complete again
+	restored := newWorkloadTestSession()
+	restored.Request().InitialMessage = "Fix the flaky test"
+	restored.SetStatus("active")
+	restoreRunCompletionFromService(restored, svc)
+	if restored.Status() != entities.SessionStatusCompleted || restored.awaitsRunCompletion() {
+		t.Errorf("restored status = %q, awaits completion = %t", restored.Status(), restored.awaitsRunCompletion())
+	}
+
+	// Follow-up runs only switch between running and active
+	session.SetStatus("running")
+	manager.agentStable(ctx, session)
+	if session.Status() != "active" {
+		t.Errorf("status after a follow-up run = %q, want active", session.Status())
+	}
+}
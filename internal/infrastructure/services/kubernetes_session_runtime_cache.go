@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// runtimeCacheMountPath is where the shared package manager cache is mounted
+// in the main container.
+const runtimeCacheMountPath = "/home/agentapi/.cache/agentapi-runtime"
+
+// runtimeCacheLabel marks the shared runtime cache PVCs.
+const runtimeCacheLabel = "agentapi.proxy/runtime-cache"
+
+// runtimeCacheEnv points package managers at the shared cache. All of them
+// tolerate concurrent use of their cache by several sessions.
+var runtimeCacheEnv = map[string]string{
+	"npm_config_cache":     "npm",
+	"npm_config_store_dir": "pnpm",
+	"YARN_CACHE_FOLDER":    "yarn",
+	"PIP_CACHE_DIR":        "pip",
+	"UV_CACHE_DIR":         "uv",
+	"GOMODCACHE":           "go/mod",
+	"GOCACHE":              "go/build",
+}
+
+// runtimeCacheOwner returns who shares the runtime cache of req: the team of
+// team-scoped sessions and the user otherwise. Stock sessions have no owner
+// yet and get no cache.
+func runtimeCacheOwner(req *entities.RunServerRequest) string {
+	if req == nil || req.UserID == "" {
+		return ""
+	}
+	if req.Scope == entities.ScopeTeam && req.TeamID != "" {
+		return "team:" + req.TeamID
+	}
+	return "user:" + req.UserID
+}
+
+// runtimeCachePVCName returns the name of the runtime cache PVC of owner.
+func runtimeCachePVCName(owner string) string {
+	return "agentapi-runtime-cache-" + hashTeamID(owner)[:16]
+}
+
+func (m *KubernetesSessionManager) runtimeCacheEnabled(req *entities.RunServerRequest) bool {
+	return m.k8sConfig.RuntimeCacheEnabled && runtimeCacheOwner(req) != ""
+}
+
+// ensureRuntimeCachePVC creates the ReadWriteMany cache PVC shared by the
+// sessions of the owner of req in namespace. The PVC is not owned by any
+// session, so the cache outlives them.
+func (m *KubernetesSessionManager) ensureRuntimeCachePVC(ctx context.Context, namespace string, req *entities.RunServerRequest) error {
+	if !m.runtimeCacheEnabled(req) {
+		return nil
+	}
+	owner := runtimeCacheOwner(req)
+	size, err := resource.ParseQuantity(defaultIfEmpty(m.k8sConfig.RuntimeCacheStorageSize, "20Gi"))
+	if err != nil {
+		return fmt.Errorf("invalid runtime cache storage size: %w", err)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runtimeCachePVCName(owner),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+				runtimeCacheLabel:              "true",
+			},
+			Annotations: map[string]string{
+				"agentapi.proxy/runtime-cache-owner": owner,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+	if m.k8sConfig.RuntimeCacheStorageClass != "" {
+		pvc.Spec.StorageClassName = &m.k8sConfig.RuntimeCacheStorageClass
+	}
+
+	_, err = m.client.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[K8S_SESSION] Created runtime cache PVC %s for %s", pvc.Name, owner)
+	return nil
+}
+
+// applyRuntimeCache mounts the shared runtime cache PVC into the main
+// container and points npm, pnpm, yarn, pip, uv and Go at it.
+func (m *KubernetesSessionManager) applyRuntimeCache(req *entities.RunServerRequest, container *corev1.Container) []corev1.Volume {
+	if !m.runtimeCacheEnabled(req) {
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(runtimeCacheEnv)) {
+		container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: runtimeCacheMountPath + "/" + runtimeCacheEnv[name]})
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      "runtime-cache",
+		MountPath: runtimeCacheMountPath,
+	})
+	return []corev1.Volume{{
+		Name: "runtime-cache",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: runtimeCachePVCName(runtimeCacheOwner(req)),
+			},
+		},
+	}}
+}
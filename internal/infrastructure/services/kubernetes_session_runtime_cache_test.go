@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestRuntimeCacheSharedPerTeam(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.RuntimeCacheEnabled = true
+	manager.k8sConfig.RuntimeCacheStorageClass = "nfs"
+	ctx := context.Background()
+
+	alice := &entities.RunServerRequest{UserID: "alice", Scope: entities.ScopeTeam, TeamID: "org/team"}
+	bob := &entities.RunServerRequest{UserID: "bob", Scope: entities.ScopeTeam, TeamID: "org/team"}
+	for _, req := range []*entities.RunServerRequest{alice, bob} {
+		if err := manager.ensureRuntimeCachePVC(ctx, "test-ns", req); err != nil {
+			t.Fatalf("ensureRuntimeCachePVC() error = %v", err)
+		}
+	}
+	pvcs, err := manager.client.CoreV1().PersistentVolumeClaims("test-ns").List(ctx, metav1.ListOptions{LabelSelector: runtimeCacheLabel + "=true"})
+	if err != nil {
+		t.Fatalf("failed to list PVCs: %v", err)
+	}
+	if len(pvcs.Items) != 1 {
+		t.Fatalf("got %d runtime cache PVCs, want one shared by the team", len(pvcs.Items))
+	}
+	pvc := pvcs.Items[0]
+	if pvc.Spec.AccessModes[0] != corev1.ReadWriteMany || *pvc.Spec.StorageClassName != "nfs" || len(pvc.OwnerReferences) != 0 {
+		t.Errorf("unexpected PVC spec %+v", pvc.Spec)
+	}
+
+	session := newWorkloadTestSession()
+	session.request = alice
+	if err := manager.createSessionWorkload(ctx, session, alice); err != nil {
+		t.Fatalf("createSessionWorkload() error = %v", err)
+	}
+	pod, err := manager.client.CoreV1().Pods("test-ns").Get(ctx, session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get pod: %v", err)
+	}
+	var claim string
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == "runtime-cache" && v.PersistentVolumeClaim != nil {
+			claim = v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	if claim != pvc.Name {
+		t.Errorf("runtime cache volume claim = %q, want %q", claim, pvc.Name)
+	}
+	env := map[string]string{}
+	for _, e := range pod.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["GOMODCACHE"] != runtimeCacheMountPath+"/go/mod" || env["npm_config_cache"] != runtimeCacheMountPath+"/npm" {
+		t.Errorf("cache env not set: GOMODCACHE=%q npm_config_cache=%q", env["GOMODCACHE"], env["npm_config_cache"])
+	}
+
+	// User-scoped sessions get a cache of their own; stock sessions none.
+	if runtimeCachePVCName(runtimeCacheOwner(&entities.RunServerRequest{UserID: "alice"})) == pvc.Name {
+		t.Error("user-scoped sessions should not share the team cache")
+	}
+	if manager.runtimeCacheEnabled(&entities.RunServerRequest{}) {
+		t.Error("stock sessions should not get a runtime cache")
+	}
+}
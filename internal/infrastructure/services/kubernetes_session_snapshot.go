@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+const (
+	defaultSnapshotKeep              = 3
+	defaultSnapshotRetainAfterDelete = 7 * 24 * time.Hour
+	// snapshotSweepInterval is how often scheduled snapshots are taken and
+	// old snapshots pruned
+	snapshotSweepInterval = 5 * time.Minute
+	// cloneSnapshotGrace keeps the snapshot of a clone while the session
+	// created from it may not have its PVC yet
+	cloneSnapshotGrace = 10 * time.Minute
+
+	snapshotKindLabel         = "agentapi.proxy/snapshot-kind"
+	snapshotUserAnnotation    = "agentapi.proxy/user-id"
+	snapshotScopeAnnotation   = "agentapi.proxy/scope"
+	snapshotTeamAnnotation    = "agentapi.proxy/team-id"
+	snapshotTakenAtAnnotation = "agentapi.proxy/snapshot-taken-at"
+	snapshotCloneAnnotation   = "agentapi.proxy/clone-session-id"
+	snapshotRequestAnnotation = "agentapi.proxy/snapshot-request"
+)
+
+// volumeSnapshotGVR is the resource of CSI VolumeSnapshots
+var volumeSnapshotGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+
+// snapshotRequest is the part of the request of a session stored with its
+// snapshots to restore it. Credentials and environment variables are not
+// stored; the restored session gets those of its new owner.
+type snapshotRequest struct {
+	AgentType  string                   `json:"agent_type,omitempty"`
+	Tags       map[string]string        `json:"tags,omitempty"`
+	Teams      []string                 `json:"teams,omitempty"`
+	RepoInfo   *entities.RepositoryInfo `json:"repo_info,omitempty"`
+	Image      string                   `json:"image,omitempty"`
+	SessionTTL string                   `json:"session_ttl,omitempty"`
+}
+
+// validateSessionSnapshots checks kubernetes_session.snapshots
+func validateSessionSnapshots(snapshots *config.SessionSnapshotConfig) error {
+	for _, d := range []struct{ name, value string }{
+		{"schedule", snapshots.Schedule},
+		{"retain_after_delete", snapshots.RetainAfterDelete},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(d.value); err != nil || parsed <= 0 {
+			return fmt.Errorf("kubernetes_session.snapshots.%s: invalid duration %q", d.name, d.value)
+		}
+	}
+	if snapshots.Keep < 0 {
+		return fmt.Errorf("kubernetes_session.snapshots.keep must not be negative")
+	}
+	return nil
+}
+
+// snapshotsEnabled reports whether session workdirs can be snapshotted
+func (m *KubernetesSessionManager) snapshotsEnabled() bool {
+	return m.k8sConfig.Snapshots.Enabled && m.isPVCEnabled() && m.dynamicClient != nil
+}
+
+// CloneSession snapshots the workdir of the session sourceID and creates the
+// session newID, owned by userID, from the snapshot. The new session runs
+// with the settings of the source session.
+func (m *KubernetesSessionManager) CloneSession(ctx context.Context, sourceID, newID, userID string, req entities.CloneSessionRequest) (entities.Session, error) {
+	if !m.snapshotsEnabled() {
+		return nil, entities.ErrSnapshotsDisabled
+	}
+	source, ok := m.GetSession(sourceID).(*KubernetesSession)
+	if !ok {
+		return nil, entities.ErrSessionNotSnapshottable
+	}
+	snapshot, err := m.snapshotSession(ctx, source, entities.SessionSnapshotClone, newID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	runReq := *source.Request()
+	runReq.UserID = userID
+	runReq.InitialMessage = req.InitialMessage
+	runReq.Tags = withoutSlugTag(runReq.Tags)
+	runReq.MemoryKey = nil
+	runReq.ProvisionSettings = nil
+	runReq.RestoreSnapshot = snapshot.Name
+	session, err := m.CreateSession(ctx, newID, &runReq, nil)
+	if err != nil {
+		return nil, err
+	}
+	m.recordEvent(newID, entities.SessionEventRestored, "Cloned from session %s (snapshot %s)", sourceID, snapshot.Name)
+	return session, nil
+}
+
+// ListSessionSnapshots returns the snapshots of session workdirs in every
+// session namespace, newest first.
+func (m *KubernetesSessionManager) ListSessionSnapshots(ctx context.Context) ([]entities.SessionSnapshot, error) {
+	if !m.snapshotsEnabled() {
+		return nil, entities.ErrSnapshotsDisabled
+	}
+	objects, err := m.listVolumeSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]entities.SessionSnapshot, 0, len(objects))
+	for i := range objects {
+		snapshots = append(snapshots, *sessionSnapshotFrom(&objects[i]))
+	}
+	return snapshots, nil
+}
+
+// GetSessionSnapshot returns the snapshot name.
+func (m *KubernetesSessionManager) GetSessionSnapshot(ctx context.Context, name string) (*entities.SessionSnapshot, error) {
+	if !m.snapshotsEnabled() {
+		return nil, entities.ErrSnapshotsDisabled
+	}
+	obj, err := m.getVolumeSnapshot(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return sessionSnapshotFrom(obj), nil
+}
+
+// RestoreSessionSnapshot creates the session newID, owned by userID, from the
+// snapshot name, e.g. to recover a session deleted by accident.
+func (m *KubernetesSessionManager) RestoreSessionSnapshot(ctx context.Context, name, newID, userID string, req entities.CloneSessionRequest) (entities.Session, error) {
+	if !m.snapshotsEnabled() {
+		return nil, entities.ErrSnapshotsDisabled
+	}
+	obj, err := m.getVolumeSnapshot(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := sessionSnapshotFrom(obj)
+	if snapshot.Error != "" {
+		return nil, fmt.Errorf("%w: %s", entities.ErrSessionSnapshotNotReady, snapshot.Error)
+	}
+
+	var stored snapshotRequest
+	if raw := obj.GetAnnotations()[snapshotRequestAnnotation]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			log.Printf("[SESSION_SNAPSHOT] Ignoring the unreadable request of snapshot %s: %v", name, err)
+		}
+	}
+	runReq := &entities.RunServerRequest{
+		UserID:          userID,
+		Scope:           snapshot.Scope,
+		TeamID:          snapshot.TeamID,
+		Teams:           stored.Teams,
+		AgentType:       stored.AgentType,
+		Tags:            withoutSlugTag(stored.Tags),
+		RepoInfo:        stored.RepoInfo,
+		Image:           stored.Image,
+		SessionTTL:      stored.SessionTTL,
+		InitialMessage:  req.InitialMessage,
+		RestoreSnapshot: name,
+	}
+	if namespace := m.placementNamespace(runReq); namespace != obj.GetNamespace() {
+		return nil, fmt.Errorf("snapshot %s is in namespace %s but the session would be placed in %s", name, obj.GetNamespace(), namespace)
+	}
+	session, err := m.CreateSession(ctx, newID, runReq, nil)
+	if err != nil {
+		return nil, err
+	}
+	m.recordEvent(newID, entities.SessionEventRestored, "Restored from snapshot %s of session %s", name, snapshot.SessionID)
+	return session, nil
+}
+
+// RunSessionSnapshots takes the scheduled snapshots of sessions and prunes
+// old snapshots until ctx is done. Run it on the leader only.
+func (m *KubernetesSessionManager) RunSessionSnapshots(ctx context.Context) {
+	if !m.snapshotsEnabled() {
+		return
+	}
+	ticker := time.NewTicker(snapshotSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.sweepSessionSnapshots(ctx, time.Now().UTC())
+	}
+}
+
+// sweepSessionSnapshots snapshots the sessions used since their last
+// scheduled snapshot, keeps the newest kubernetes_session.snapshots.keep
+// scheduled snapshots of each session, deletes those of deleted sessions
+// after retain_after_delete and the snapshots of clones once the clone has
+// its PVC.
+func (m *KubernetesSessionManager) sweepSessionSnapshots(ctx context.Context, now time.Time) {
+	cfg := m.k8sConfig.Snapshots
+	objects, err := m.listVolumeSnapshots(ctx)
+	if err != nil {
+		log.Printf("[SESSION_SNAPSHOT] %v", err)
+		return
+	}
+	// Scheduled snapshots by session, newest first
+	scheduled := make(map[string][]*unstructured.Unstructured)
+	for i := range objects {
+		obj := &objects[i]
+		switch entities.SessionSnapshotKind(obj.GetLabels()[snapshotKindLabel]) {
+		case entities.SessionSnapshotScheduled:
+			sessionID := obj.GetLabels()["agentapi.proxy/session-id"]
+			scheduled[sessionID] = append(scheduled[sessionID], obj)
+		case entities.SessionSnapshotClone:
+			cloneID := obj.GetAnnotations()[snapshotCloneAnnotation]
+			if now.Sub(snapshotTakenAt(obj)) > cloneSnapshotGrace && m.cloneProvisioned(ctx, obj.GetNamespace(), cloneID) {
+				m.deleteVolumeSnapshot(ctx, obj.GetNamespace(), obj.GetName())
+			}
+		}
+	}
+
+	if interval, err := time.ParseDuration(cfg.Schedule); err == nil && interval > 0 {
+		m.mutex.RLock()
+		sessions := make([]*KubernetesSession, 0, len(m.sessions))
+		for _, session := range m.sessions {
+			sessions = append(sessions, session)
+		}
+		m.mutex.RUnlock()
+		for _, session := range sessions {
+			if session.IsStock() || session.RunsAsJob() || session.Replicas() > 1 || session.Status() == "creating" {
+				continue
+			}
+			var last time.Time
+			if existing := scheduled[session.ID()]; len(existing) > 0 {
+				last = snapshotTakenAt(existing[0])
+			}
+			// Sessions not used since their last snapshot are not snapshotted again
+			if now.Sub(last) < interval || (!last.IsZero() && !m.sessionLastActivity(ctx, session).After(last)) {
+				continue
+			}
+			if _, err := m.snapshotSession(ctx, session, entities.SessionSnapshotScheduled, "", now); err != nil {
+				log.Printf("[SESSION_SNAPSHOT] Failed to snapshot session %s: %v", session.ID(), err)
+				continue
+			}
+			// The new snapshot counts towards keep at the next sweep
+		}
+	}
+
+	keep := cfg.Keep
+	if keep <= 0 {
+		keep = defaultSnapshotKeep
+	}
+	retain := defaultSnapshotRetainAfterDelete
+	if d, err := time.ParseDuration(cfg.RetainAfterDelete); err == nil && d > 0 {
+		retain = d
+	}
+	for sessionID, snapshots := range scheduled {
+		deleted := !m.sessionServiceExists(ctx, sessionID)
+		for i, obj := range snapshots {
+			if i >= keep || (deleted && now.Sub(snapshotTakenAt(obj)) > retain) {
+				m.deleteVolumeSnapshot(ctx, obj.GetNamespace(), obj.GetName())
+			}
+		}
+	}
+}
+
+// sessionServiceExists reports whether the session still exists. Errors
+// other than not found count as existing so that no snapshot is pruned by
+// mistake.
+func (m *KubernetesSessionManager) sessionServiceExists(ctx context.Context, sessionID string) bool {
+	_, err := m.getSessionService(ctx, sessionID, fmt.Sprintf("agentapi-session-%s-svc", sessionID))
+	return !apierrors.IsNotFound(err)
+}
+
+// cloneProvisioned reports whether the session cloned from a snapshot no
+// longer needs it: its PVC is bound or the session is gone.
+func (m *KubernetesSessionManager) cloneProvisioned(ctx context.Context, namespace, sessionID string) bool {
+	if sessionID == "" {
+		return true
+	}
+	pvc, err := m.client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, fmt.Sprintf("agentapi-session-%s-pvc", sessionID), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+	return err == nil && pvc.Status.Phase == corev1.ClaimBound
+}
+
+// snapshotSession takes a VolumeSnapshot of the workdir PVC of session.
+// cloneID is the session created from a clone snapshot.
+func (m *KubernetesSessionManager) snapshotSession(ctx context.Context, session *KubernetesSession, kind entities.SessionSnapshotKind, cloneID string, now time.Time) (*entities.SessionSnapshot, error) {
+	if session.IsStock() || session.RunsAsJob() || session.Replicas() > 1 {
+		return nil, entities.ErrSessionNotSnapshottable
+	}
+	req := session.Request()
+	stored, err := json.Marshal(snapshotRequest{
+		AgentType:  req.AgentType,
+		Tags:       req.Tags,
+		Teams:      req.Teams,
+		RepoInfo:   req.RepoInfo,
+		Image:      req.Image,
+		SessionTTL: req.SessionTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	scope := req.Scope
+	if scope == "" {
+		scope = entities.ScopeUser
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("snapshot.storage.k8s.io/v1")
+	obj.SetKind("VolumeSnapshot")
+	obj.SetName(fmt.Sprintf("agentapi-snap-%s-%d", session.ID(), now.Unix()))
+	obj.SetNamespace(session.Namespace())
+	// Snapshots are not owned by the session so that they outlive it
+	obj.SetLabels(map[string]string{
+		"app.kubernetes.io/managed-by": "agentapi-proxy",
+		"agentapi.proxy/session-id":    session.ID(),
+		snapshotKindLabel:              string(kind),
+	})
+	annotations := map[string]string{
+		snapshotUserAnnotation:    req.UserID,
+		snapshotScopeAnnotation:   string(scope),
+		snapshotTakenAtAnnotation: now.Format(time.RFC3339),
+		snapshotRequestAnnotation: string(stored),
+	}
+	if req.TeamID != "" {
+		annotations[snapshotTeamAnnotation] = req.TeamID
+	}
+	if cloneID != "" {
+		annotations[snapshotCloneAnnotation] = cloneID
+	}
+	obj.SetAnnotations(annotations)
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{"persistentVolumeClaimName": session.PVCName()},
+	}
+	if class := m.k8sConfig.Snapshots.VolumeSnapshotClass; class != "" {
+		spec["volumeSnapshotClassName"] = class
+	}
+	obj.Object["spec"] = spec
+
+	created, err := m.dynamicClient.Resource(volumeSnapshotGVR).Namespace(session.Namespace()).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VolumeSnapshot: %w", err)
+	}
+	log.Printf("[SESSION_SNAPSHOT] Took %s snapshot %s of session %s", kind, created.GetName(), session.ID())
+	m.recordEvent(session.ID(), entities.SessionEventSnapshotted, "Took %s snapshot %s", kind, created.GetName())
+	return sessionSnapshotFrom(created), nil
+}
+
+// listVolumeSnapshots returns the session snapshots of every session
+// namespace, newest first
+func (m *KubernetesSessionManager) listVolumeSnapshots(ctx context.Context) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, namespace := range m.sessionNamespaces(ctx) {
+		list, err := m.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: snapshotKindLabel})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VolumeSnapshots in %s: %w", namespace, err)
+		}
+		objects = append(objects, list.Items...)
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return snapshotTakenAt(&objects[i]).After(snapshotTakenAt(&objects[j]))
+	})
+	return objects, nil
+}
+
+// getVolumeSnapshot finds the session snapshot name in the session
+// namespaces
+func (m *KubernetesSessionManager) getVolumeSnapshot(ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	for _, namespace := range m.sessionNamespaces(ctx) {
+		obj, err := m.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VolumeSnapshot %s: %w", name, err)
+		}
+		if _, ok := obj.GetLabels()[snapshotKindLabel]; ok {
+			return obj, nil
+		}
+	}
+	return nil, entities.ErrSessionSnapshotNotFound
+}
+
+func (m *KubernetesSessionManager) deleteVolumeSnapshot(ctx context.Context, namespace, name string) {
+	err := m.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Printf("[SESSION_SNAPSHOT] Failed to delete snapshot %s: %v", name, err)
+		return
+	}
+	log.Printf("[SESSION_SNAPSHOT] Deleted snapshot %s", name)
+}
+
+// snapshotTakenAt returns when the snapshot was taken
+func snapshotTakenAt(obj *unstructured.Unstructured) time.Time {
+	if t, err := time.Parse(time.RFC3339, obj.GetAnnotations()[snapshotTakenAtAnnotation]); err == nil {
+		return t
+	}
+	return obj.GetCreationTimestamp().UTC()
+}
+
+// sessionSnapshotFrom converts a VolumeSnapshot to a SessionSnapshot
+func sessionSnapshotFrom(obj *unstructured.Unstructured) *entities.SessionSnapshot {
+	annotations := obj.GetAnnotations()
+	snapshot := &entities.SessionSnapshot{
+		Name:      obj.GetName(),
+		SessionID: obj.GetLabels()["agentapi.proxy/session-id"],
+		UserID:    annotations[snapshotUserAnnotation],
+		Scope:     entities.ResourceScope(annotations[snapshotScopeAnnotation]),
+		TeamID:    annotations[snapshotTeamAnnotation],
+		Kind:      entities.SessionSnapshotKind(obj.GetLabels()[snapshotKindLabel]),
+		CreatedAt: snapshotTakenAt(obj),
+	}
+	snapshot.ReadyToUse, _, _ = unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	snapshot.Error, _, _ = unstructured.NestedString(obj.Object, "status", "error", "message")
+	return snapshot
+}
+
+// withoutSlugTag copies tags without the slug, which stays with the session
+// it was given to
+func withoutSlugTag(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if k != entities.SessionSlugTag {
+			copied[k] = v
+		}
+	}
+	return copied
+}
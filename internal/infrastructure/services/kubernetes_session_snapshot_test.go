@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestValidateSessionSnapshots(t *testing.T) {
+	if err := validateSessionSnapshots(&config.SessionSnapshotConfig{Schedule: "6h", RetainAfterDelete: "168h", Keep: 3}); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for name, snapshots := range map[string]config.SessionSnapshotConfig{
+		"bad schedule":            {Schedule: "daily"},
+		"bad retain_after_delete": {RetainAfterDelete: "0s"},
+		"negative keep":           {Keep: -1},
+	} {
+		if err := validateSessionSnapshots(&snapshots); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// newSnapshotTestManager returns a PVC-backed manager with snapshots enabled
+func newSnapshotTestManager(t *testing.T) *KubernetesSessionManager {
+	t.Helper()
+	t.Setenv("LOG_DIR", t.TempDir())
+	manager := newWorkloadTestManager(t, true)
+	manager.k8sConfig.Snapshots = config.SessionSnapshotConfig{Enabled: true, VolumeSnapshotClass: "csi-snapclass"}
+	manager.dynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{volumeSnapshotGVR: "VolumeSnapshotList"})
+	return manager
+}
+
+func TestCloneSession(t *testing.T) {
+	manager := newSnapshotTestManager(t)
+	ctx := context.Background()
+	addRolloutTestSession(t, manager, "source", &entities.RunServerRequest{
+		UserID: "alice",
+		Tags:   map[string]string{"repo": "acme/app", entities.SessionSlugTag: "fix-login"},
+	}, "active", time.Hour)
+
+	clone, err := manager.CloneSession(ctx, "source", "clone", "alice", entities.CloneSessionRequest{InitialMessage: "try another approach"})
+	if err != nil {
+		t.Fatalf("CloneSession() error = %v", err)
+	}
+	defer func() { _ = manager.DeleteSession("clone") }()
+
+	snapshots, err := manager.ListSessionSnapshots(ctx)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("ListSessionSnapshots() = %v, %v", snapshots, err)
+	}
+	snapshot := snapshots[0]
+	if snapshot.SessionID != "source" || snapshot.UserID != "alice" || snapshot.Kind != entities.SessionSnapshotClone {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+	obj, err := manager.dynamicClient.Resource(volumeSnapshotGVR).Namespace("test-ns").Get(ctx, snapshot.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeSnapshotClassName"); class != "csi-snapclass" {
+		t.Errorf("volumeSnapshotClassName = %q", class)
+	}
+	if pvc, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "persistentVolumeClaimName"); pvc != "agentapi-session-source-pvc" {
+		t.Errorf("source PVC = %q", pvc)
+	}
+
+	pvc, err := manager.client.CoreV1().PersistentVolumeClaims("test-ns").Get(ctx, "agentapi-session-clone-pvc", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pvc.Spec.DataSource == nil || pvc.Spec.DataSource.Kind != "VolumeSnapshot" || pvc.Spec.DataSource.Name != snapshot.Name {
+		t.Fatalf("PVC data source = %+v", pvc.Spec.DataSource)
+	}
+	tags := clone.Tags()
+	if tags["repo"] != "acme/app" || tags[entities.SessionSlugTag] != "" {
+		t.Errorf("clone tags = %v", tags)
+	}
+	if clone.(*KubernetesSession).Request().InitialMessage != "try another approach" {
+		t.Error("initial message of the clone not set")
+	}
+
+	if _, err := manager.CloneSession(ctx, "missing", "clone-2", "alice", entities.CloneSessionRequest{}); !errors.Is(err, entities.ErrSessionNotSnapshottable) {
+		t.Errorf("CloneSession() of a missing session = %v", err)
+	}
+}
+
+func TestSweepSessionSnapshots(t *testing.T) {
+	manager := newSnapshotTestManager(t)
+	manager.k8sConfig.Snapshots.Schedule = "1h"
+	manager.k8sConfig.Snapshots.Keep = 2
+	manager.k8sConfig.Snapshots.RetainAfterDelete = "24h"
+	ctx := context.Background()
+	session := addRolloutTestSession(t, manager, "live", &entities.RunServerRequest{UserID: "alice"}, "active", time.Hour)
+
+	scheduled := func() []entities.SessionSnapshot {
+		t.Helper()
+		snapshots, err := manager.ListSessionSnapshots(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []entities.SessionSnapshot
+		for _, s := range snapshots {
+			if s.Kind == entities.SessionSnapshotScheduled {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	}
+
+	now := time.Now().UTC()
+	manager.sweepSessionSnapshots(ctx, now)
+	if got := scheduled(); len(got) != 1 {
+		t.Fatalf("snapshots after the first sweep = %v", got)
+	}
+	// Not due yet, then due but unused since the last snapshot
+	manager.sweepSessionSnapshots(ctx, now.Add(30*time.Minute))
+	manager.sweepSessionSnapshots(ctx, now.Add(2*time.Hour))
+	if got := scheduled(); len(got) != 1 {
+		t.Fatalf("idle session snapshotted again: %v", got)
+	}
+	// Used again: snapshotted, and only the newest two are kept
+	for i := 1; i <= 3; i++ {
+		at := now.Add(time.Duration(i) * 2 * time.Hour)
+		session.lastMessageAt = at.Add(-time.Minute)
+		manager.sweepSessionSnapshots(ctx, at)
+	}
+	manager.sweepSessionSnapshots(ctx, now.Add(7*time.Hour))
+	got := scheduled()
+	if len(got) != 2 || !got[0].CreatedAt.Equal(now.Add(6*time.Hour).Truncate(time.Second)) {
+		t.Fatalf("snapshots after pruning = %v", got)
+	}
+
+	// Snapshots of a deleted session are kept for retain_after_delete
+	if err := manager.client.CoreV1().Services("test-ns").Delete(ctx, "agentapi-session-live-svc", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	manager.mutex.Lock()
+	delete(manager.sessions, "live")
+	manager.mutex.Unlock()
+	manager.sweepSessionSnapshots(ctx, now.Add(12*time.Hour))
+	if got := scheduled(); len(got) != 2 {
+		t.Fatalf("snapshots of the deleted session pruned early: %v", got)
+	}
+	manager.sweepSessionSnapshots(ctx, now.Add(31*time.Hour))
+	if got := scheduled(); len(got) != 0 {
+		t.Fatalf("snapshots of the deleted session kept: %v", got)
+	}
+}
+
+func TestRestoreSessionSnapshot(t *testing.T) {
+	manager := newSnapshotTestManager(t)
+	ctx := context.Background()
+	session := addRolloutTestSession(t, manager, "deleted", &entities.RunServerRequest{
+		UserID:    "alice",
+		AgentType: "claude-agentapi",
+		RepoInfo:  &entities.RepositoryInfo{FullName: "acme/app"},
+	}, "active", time.Hour)
+	snapshot, err := manager.snapshotSession(ctx, session, entities.SessionSnapshotScheduled, "", time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := manager.RestoreSessionSnapshot(ctx, snapshot.Name, "restored", "alice", entities.CloneSessionRequest{})
+	if err != nil {
+		t.Fatalf("RestoreSessionSnapshot() error = %v", err)
+	}
+	defer func() { _ = manager.DeleteSession("restored") }()
+	req := restored.(*KubernetesSession).Request()
+	if req.RepoInfo == nil || req.RepoInfo.FullName != "acme/app" || req.RestoreSnapshot != snapshot.Name {
+		t.Fatalf("restored request = %+v", req)
+	}
+
+	if _, err := manager.RestoreSessionSnapshot(ctx, "agentapi-snap-unknown", "other", "alice", entities.CloneSessionRequest{}); !errors.Is(err, entities.ErrSessionSnapshotNotFound) {
+		t.Errorf("RestoreSessionSnapshot() of an unknown snapshot = %v", err)
+	}
+}
+
+func TestSnapshotsDisabled(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	if _, err := manager.ListSessionSnapshots(context.Background()); !errors.Is(err, entities.ErrSnapshotsDisabled) {
+		t.Fatalf("ListSessionSnapshots() without a dynamic client = %v", err)
+	}
+}
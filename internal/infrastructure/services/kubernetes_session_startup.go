@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+// startupTemplates holds the built-in startup template of each agent type,
+// named <agent type>.yaml. The default agent type uses claude.yaml.
+//
+//go:embed startup_templates/*.yaml
+var startupTemplates embed.FS
+
+const defaultStartupTemplateAgentType = "claude"
+
+// StartupTemplateData is the data available to startup templates.
+type StartupTemplateData struct {
+	AgentType  string
+	SessionID  string
+	UserID     string
+	TeamID     string
+	Port       int
+	Repository string
+	Branch     string
+	// Env is the session environment, including team and user settings.
+	Env map[string]string
+}
+
+// startupConfig renders the startup command of the session agent type. A
+// <agent type>.yaml key of the kubernetes_session.startup_templates_configmap
+// ConfigMap replaces the built-in template; the provisioner then runs the
+// rendered command instead of its own. An override that cannot be rendered
+// is logged and the built-in template is used.
+func (m *KubernetesSessionManager) startupConfig(ctx context.Context, session *KubernetesSession, req *entities.RunServerRequest, env map[string]string) sessionsettings.StartupConfig {
+	agentType := req.AgentType
+	if agentType == "" {
+		agentType = defaultStartupTemplateAgentType
+	}
+	data := StartupTemplateData{
+		AgentType: agentType,
+		SessionID: session.id,
+		UserID:    req.UserID,
+		TeamID:    req.TeamID,
+		Port:      m.k8sConfig.BasePort,
+		Env:       env,
+	}
+	if req.RepoInfo != nil {
+		data.Repository = req.RepoInfo.FullName
+		data.Branch = req.RepoInfo.Branch
+	}
+
+	if override := m.startupTemplateOverride(ctx, agentType); override != "" {
+		startup, err := renderStartupTemplate(agentType, override, data)
+		if err == nil {
+			startup.Override = true
+			log.Printf("[K8S_SESSION] Using startup template override for agent type %s in session %s", agentType, session.id)
+			return startup
+		}
+		log.Printf("[K8S_SESSION] Warning: ignoring startup template override for agent type %s: %v", agentType, err)
+	}
+
+	builtin, err := startupTemplates.ReadFile("startup_templates/" + agentType + ".yaml")
+	if err != nil {
+		builtin, _ = startupTemplates.ReadFile("startup_templates/" + defaultStartupTemplateAgentType + ".yaml")
+	}
+	startup, err := renderStartupTemplate(agentType, string(builtin), data)
+	if err != nil {
+		// Built-in templates are covered by tests, so this is a programming error.
+		log.Printf("[K8S_SESSION] Error: failed to render built-in startup template for agent type %s: %v", agentType, err)
+	}
+	return startup
+}
+
+// startupTemplateOverride returns the operator template of agentType, or ""
+// when there is none.
+func (m *KubernetesSessionManager) startupTemplateOverride(ctx context.Context, agentType string) string {
+	name := m.k8sConfig.StartupTemplatesConfigMap
+	if name == "" {
+		return ""
+	}
+	cm, err := m.client.CoreV1().ConfigMaps(m.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("[K8S_SESSION] Warning: failed to read startup templates ConfigMap %s: %v", name, err)
+		}
+		return ""
+	}
+	return cm.Data[agentType+".yaml"]
+}
+
+// renderStartupTemplate executes every string of a startup template. Unknown
+// variables are errors so that typos do not silently start a broken agent.
+func renderStartupTemplate(name, text string, data StartupTemplateData) (sessionsettings.StartupConfig, error) {
+	var startup sessionsettings.StartupConfig
+	if err := yaml.Unmarshal([]byte(text), &startup); err != nil {
+		return sessionsettings.StartupConfig{}, fmt.Errorf("invalid startup template: %w", err)
+	}
+	if len(startup.Command) == 0 {
+		return sessionsettings.StartupConfig{}, fmt.Errorf("startup template has no command")
+	}
+
+	render := func(s string) (string, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+	for _, list := range [][]string{startup.Command, startup.Args} {
+		for i, s := range list {
+			rendered, err := render(s)
+			if err != nil {
+				return sessionsettings.StartupConfig{}, err
+			}
+			list[i] = rendered
+		}
+	}
+	preScript, err := render(startup.PreScript)
+	if err != nil {
+		return sessionsettings.StartupConfig{}, err
+	}
+	startup.PreScript = preScript
+	return startup, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestBuiltinStartupTemplatesRender(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	for _, agentType := range []string{"", "claude-acp", "codex-acp", "pi-ollama", "cursor"} {
+		req := &entities.RunServerRequest{UserID: "test-user", AgentType: agentType}
+		startup := manager.startupConfig(context.Background(), session, req, nil)
+		if len(startup.Command) == 0 || startup.Override {
+			t.Errorf("%q: startup = %+v, want a built-in command", agentType, startup)
+		}
+		if !slices.Contains(startup.Args, "9000") {
+			t.Errorf("%q: args = %v, want the port", agentType, startup.Args)
+		}
+	}
+
+	req := &entities.RunServerRequest{AgentType: "pi-ollama"}
+	if startup := manager.startupConfig(context.Background(), session, req, nil); !strings.Contains(startup.PreScript, "pi install npm:pi-ollama-cloud") {
+		t.Errorf("pi-ollama pre-script = %q", startup.PreScript)
+	}
+}
+
+func TestStartupTemplateOverride(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	manager.k8sConfig.StartupTemplatesConfigMap = "startup-templates"
+	session := newWorkloadTestSession()
+	ctx := context.Background()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "startup-templates", Namespace: "test-ns"},
+		Data: map[string]string{
+			"codex-acp.yaml": `command: ["agentapi-proxy"]
+args: ["acp-server", "--port", "{{ .Port }}", "--", "codex", "--profile", "{{ .Env.CODEX_PROFILE }}"]
+pre_script: echo {{ .SessionID }} {{ .Repository }}`,
+			"cursor.yaml": `command: ["agent"]
+args: ["{{ .Unknown }}"]`,
+		},
+	}
+	if _, err := manager.client.CoreV1().ConfigMaps("test-ns").Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create configmap: %v", err)
+	}
+
+	req := &entities.RunServerRequest{
+		AgentType: "codex-acp",
+		RepoInfo:  &entities.RepositoryInfo{FullName: "org/repo"},
+	}
+	startup := manager.startupConfig(ctx, session, req, map[string]string{"CODEX_PROFILE": "ci"})
+	if !startup.Override {
+		t.Fatalf("startup = %+v, want the override", startup)
+	}
+	want := []string{"acp-server", "--port", "9000", "--", "codex", "--profile", "ci"}
+	if !slices.Equal(startup.Args, want) {
+		t.Errorf("args = %v, want %v", startup.Args, want)
+	}
+	if startup.PreScript != "echo test-session org/repo" {
+		t.Errorf("pre-script = %q", startup.PreScript)
+	}
+
+	// An override with an unknown variable falls back to the built-in template
+	startup = manager.startupConfig(ctx, session, &entities.RunServerRequest{AgentType: "cursor"}, nil)
+	if startup.Override || startup.Command[0] != "agentapi-proxy" {
+		t.Errorf("startup = %+v, want the built-in cursor command", startup)
+	}
+
+	// Agent types without an override keep the built-in template
+	startup = manager.startupConfig(ctx, session, &entities.RunServerRequest{AgentType: "claude-acp"}, nil)
+	if startup.Override {
+		t.Errorf("startup = %+v, want the built-in claude-acp command", startup)
+	}
+}
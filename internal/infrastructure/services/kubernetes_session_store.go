@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// sessionStoreTimeout bounds session store calls made on the request path.
+const sessionStoreTimeout = 5 * time.Second
+
+// SetSessionRepository injects a SessionRepository that keeps the full state
+// of every session. Restored sessions then get their complete launch request
+// and webhook payload back instead of what Service metadata can hold. If repo
+// is nil the call is ignored.
+func (m *KubernetesSessionManager) SetSessionRepository(repo portrepos.SessionRepository) {
+	if repo == nil {
+		return
+	}
+	m.sessionRepo = repo
+	log.Printf("[K8S_SESSION] SessionRepository configured")
+}
+
+// saveSessionRecord persists the state of a newly created session. Failures
+// are logged only: the session keeps running on Kubernetes metadata.
+func (m *KubernetesSessionManager) saveSessionRecord(id string, req *entities.RunServerRequest, webhookPayload []byte) {
+	if m.sessionRepo == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	if err := m.sessionRepo.Save(ctx, &portrepos.SessionRecord{
+		ID:             id,
+		Request:        req,
+		WebhookPayload: webhookPayload,
+	}); err != nil {
+		log.Printf("[K8S_SESSION] Warning: failed to persist session %s: %v", id, err)
+	}
+}
+
+// loadSessionRecord returns the persisted state of a session, or nil when no
+// session store is configured or the session is not in it.
+func (m *KubernetesSessionManager) loadSessionRecord(id string) *portrepos.SessionRecord {
+	if m.sessionRepo == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	record, err := m.sessionRepo.Get(ctx, id)
+	if err != nil {
+		log.Printf("[K8S_SESSION] Warning: failed to load session %s from store: %v", id, err)
+		return nil
+	}
+	if record == nil || record.Request == nil {
+		return nil
+	}
+	return record
+}
+
+// applySessionRecord replaces a request reconstructed from Service metadata
+// with the persisted one, when available. The Service remains authoritative
+// for the owner, so a record that disagrees with it is ignored.
+func (m *KubernetesSessionManager) applySessionRecord(id string, req *entities.RunServerRequest) (*entities.RunServerRequest, []byte) {
+	record := m.loadSessionRecord(id)
+	if record == nil {
+		return req, nil
+	}
+	if record.Request.UserID != req.UserID {
+		log.Printf("[K8S_SESSION] Warning: stored owner of session %s does not match its Service, ignoring stored state", id)
+		return req, nil
+	}
+	return record.Request, record.WebhookPayload
+}
+
+// deleteSessionRecord removes the persisted state of a deleted session.
+func (m *KubernetesSessionManager) deleteSessionRecord(ctx context.Context, id string) {
+	if m.sessionRepo == nil {
+		return
+	}
+	if err := m.sessionRepo.Delete(ctx, id); err != nil {
+		log.Printf("[K8S_SESSION] Warning: failed to delete session %s from store: %v", id, err)
+	}
+}
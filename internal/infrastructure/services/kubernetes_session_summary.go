@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// summaryMessagesAnnotation records how many messages the summary of a
+// session was generated from, so that unchanged sessions are not summarized
+// again
+const summaryMessagesAnnotation = "agentapi.proxy/summary-message-count"
+
+// SummaryMessageCount returns the number of messages the summary of the
+// session was generated from, or 0 when it has no summary.
+func (s *KubernetesSession) SummaryMessageCount() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.summaryMessages
+}
+
+func (s *KubernetesSession) setSummary(summary string, messageCount int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.annotations.Summary = summary
+	s.summaryMessages = messageCount
+}
+
+// SetSessionSummary stores the generated summary of a session and the number
+// of messages it was generated from on its Service.
+func (m *KubernetesSessionManager) SetSessionSummary(ctx context.Context, sessionID, summary string, messageCount int) error {
+	session, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || session == nil {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	err := m.patchServiceAnnotations(ctx, session.Namespace(), session.ServiceName(), map[string]interface{}{
+		sessionAnnotationSummary:  summary,
+		summaryMessagesAnnotation: strconv.Itoa(messageCount),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store summary: %w", err)
+	}
+	session.setSummary(summary, messageCount)
+	m.invalidateSessionListCache("summary update")
+	return nil
+}
+
+func restoreSummaryFromService(session *KubernetesSession, svc *corev1.Service) {
+	count, _ := strconv.Atoi(svc.Annotations[summaryMessagesAnnotation])
+	session.setSummary(svc.Annotations[sessionAnnotationSummary], count)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetSessionSummary(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	ctx := context.Background()
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService: %v", err)
+	}
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	if err := manager.SetSessionSummary(ctx, session.ID(), "Fix the flaky login test", 4); err != nil {
+		t.Fatalf("SetSessionSummary: %v", err)
+	}
+	if session.Annotations().Summary != "Fix the flaky login test" || session.SummaryMessageCount() != 4 {
+		t.Errorf("summary = %q from %d messages", session.Annotations().Summary, session.SummaryMessageCount())
+	}
+
+	// The summary survives a restore from the Service
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	restored := newWorkloadTestSession()
+	restored.SetAnnotations(sessionAnnotationsFromMap(svc.Annotations))
+	restoreSummaryFromService(restored, svc)
+	if restored.Annotations().Summary != "Fix the flaky login test" || restored.SummaryMessageCount() != 4 {
+		t.Errorf("restored summary = %q from %d messages", restored.Annotations().Summary, restored.SummaryMessageCount())
+	}
+
+	if err := manager.SetSessionSummary(ctx, "missing", "x", 1); err == nil {
+		t.Error("summarizing an unknown session did not fail")
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// TerminalPort is the port ttyd listens on in sessions with the terminal
+// sidecar. The proxy reaches it through the session Service.
+const TerminalPort = 7681
+
+// terminalCapabilityLabel marks session Services whose Pod runs the terminal sidecar.
+const terminalCapabilityLabel = "agentapi.proxy/capability-terminal"
+
+func terminalEnabled(req *entities.RunServerRequest) bool {
+	return req != nil && req.Terminal != nil && req.Terminal.Enabled
+}
+
+// restoreTerminalFromService rebuilds the terminal params of a restored session.
+func restoreTerminalFromService(svc *corev1.Service) *entities.TerminalParams {
+	if svc.Labels[terminalCapabilityLabel] != "true" {
+		return nil
+	}
+	return &entities.TerminalParams{Enabled: true}
+}
+
+// TerminalEnabled reports whether the session Pod runs the terminal sidecar.
+func (s *KubernetesSession) TerminalEnabled() bool {
+	return terminalEnabled(s.Request())
+}
+
+// buildTerminalContainer returns the ttyd sidecar and its volumes when the
+// session requests one. The shell starts in the shared workdir and ttyd runs
+// without its own authentication: access control is enforced by the proxy,
+// which is the only intended client of the terminal Service port.
+func (m *KubernetesSessionManager) buildTerminalContainer(req *entities.RunServerRequest) (*corev1.Container, []corev1.Volume) {
+	if !terminalEnabled(req) {
+		return nil, nil
+	}
+
+	image := m.k8sConfig.TerminalImage
+	if image == "" {
+		image = config.DefaultTerminalImage
+	}
+	falseVal := false
+
+	sidecar := corev1.Container{
+		Name:            "terminal",
+		Image:           image,
+		ImagePullPolicy: corev1.PullPolicy(m.k8sConfig.ImagePullPolicy),
+		Args: []string{
+			"ttyd",
+			"--writable",
+			"--port", fmt.Sprintf("%d", TerminalPort),
+			"--cwd", "/home/agentapi/workdir",
+			"bash",
+		},
+		Env: []corev1.EnvVar{
+			// The Pod runs as UID 999, which has no home directory in the image.
+			{Name: "HOME", Value: "/home/agentapi"},
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "terminal", ContainerPort: TerminalPort, Protocol: corev1.ProtocolTCP},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/", Port: intstr.FromInt(TerminalPort)},
+			},
+			PeriodSeconds: 10,
+		},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &falseVal,
+		},
+		Resources: buildResourceRequirements(
+			defaultIfEmpty(m.k8sConfig.TerminalCPURequest, "50m"),
+			defaultIfEmpty(m.k8sConfig.TerminalCPULimit, "500m"),
+			defaultIfEmpty(m.k8sConfig.TerminalMemoryRequest, "64Mi"),
+			defaultIfEmpty(m.k8sConfig.TerminalMemoryLimit, "256Mi"),
+		),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "terminal-home", MountPath: "/home/agentapi"},
+			{Name: "workdir", MountPath: "/home/agentapi/workdir"},
+		},
+	}
+
+	volumes := []corev1.Volume{
+		{
+			Name:         "terminal-home",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	return &sidecar, volumes
+}
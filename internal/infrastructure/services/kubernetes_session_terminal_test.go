@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func TestCreateSessionWorkloadWithTerminalSidecar(t *testing.T) {
+	manager := newWorkloadTestManager(t, true)
+	session := newWorkloadTestSession()
+	session.Request().Terminal = &entities.TerminalParams{Enabled: true}
+
+	if err := manager.createSessionWorkload(context.Background(), session, session.Request()); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	deployment, err := manager.client.AppsV1().Deployments("test-ns").Get(context.Background(), session.DeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected deployment to be created: %v", err)
+	}
+
+	var terminal *corev1.Container
+	for i := range deployment.Spec.Template.Spec.Containers {
+		if c := &deployment.Spec.Template.Spec.Containers[i]; c.Name == "terminal" {
+			terminal = c
+		}
+	}
+	if terminal == nil {
+		t.Fatal("Expected terminal sidecar container")
+	}
+	if terminal.Image != config.DefaultTerminalImage {
+		t.Errorf("Expected default terminal image, got %q", terminal.Image)
+	}
+	mountsWorkdir := false
+	for _, vm := range terminal.VolumeMounts {
+		if vm.Name == "workdir" && vm.MountPath == "/home/agentapi/workdir" {
+			mountsWorkdir = true
+		}
+	}
+	if !mountsWorkdir {
+		t.Errorf("Expected terminal to share the workdir, got %+v", terminal.VolumeMounts)
+	}
+
+	hasPort := false
+	for _, p := range manager.buildServicePorts(session) {
+		if p.Name == "terminal" && p.Port == TerminalPort {
+			hasPort = true
+		}
+	}
+	if !hasPort {
+		t.Error("Expected terminal port on the session Service")
+	}
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: manager.buildLabels(session)}}
+	if restored := restoreTerminalFromService(svc); restored == nil || !restored.Enabled {
+		t.Error("Expected terminal params to be restored from Service labels")
+	}
+}
+
+func TestBuildTerminalContainerDisabled(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	if sidecar, volumes := manager.buildTerminalContainer(newWorkloadTestSession().Request()); sidecar != nil || volumes != nil {
+		t.Fatal("Terminal sidecar must be opt-in")
+	}
+}
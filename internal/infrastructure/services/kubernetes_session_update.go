@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// sessionTagLabelPrefix prefixes the labels holding the sanitized tags of a
+// session, which label selectors match
+const sessionTagLabelPrefix = "agentapi.proxy/tag-"
+
+// sessionTagsAnnotation holds the tags of a session as JSON once they have
+// been updated. Labels only hold sanitized keys and values, so the annotation
+// keeps the original ones.
+const sessionTagsAnnotation = "agentapi.proxy/tags"
+
+// UpdateSessionMetadata updates the tags, description and observer mode of a
+// session. The tag labels of its Service are rewritten so that label
+// selectors and tag filters keep matching the session; the scope and
+// team-id-hash labels are left untouched.
+func (m *KubernetesSessionManager) UpdateSessionMetadata(ctx context.Context, sessionID string, req entities.UpdateSessionRequest) (entities.Session, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ks, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || ks == nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	svc, err := m.client.CoreV1().Services(ks.Namespace()).Get(ctx, ks.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	if svc.Labels == nil {
+		svc.Labels = make(map[string]string)
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = make(map[string]string)
+	}
+
+	// Another replica may have updated the tags since this one loaded them
+	current := ks.Tags()
+	if tags, ok := updatedTagsFromService(svc); ok {
+		current = tags
+	}
+	tags := current
+	if req.HasTagChanges() {
+		tags = req.ApplyTags(current)
+		labels, err := sessionTagLabels(tags)
+		if err != nil {
+			return nil, err
+		}
+		for k := range svc.Labels {
+			if strings.HasPrefix(k, sessionTagLabelPrefix) {
+				delete(svc.Labels, k)
+			}
+		}
+		for k, v := range labels {
+			svc.Labels[k] = v
+		}
+		encoded, err := json.Marshal(tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tags: %w", err)
+		}
+		svc.Annotations[sessionTagsAnnotation] = string(encoded)
+	}
+
+	annotations := applySessionAnnotationPatch(sessionAnnotationsFromMap(svc.Annotations), entities.UpdateSessionAnnotationsRequest{Description: req.Description})
+	setSessionAnnotationValue(svc.Annotations, sessionAnnotationDescription, annotations.Description)
+	if req.ObserverMode != nil {
+		annotations.ObserverMode = *req.ObserverMode
+		observer := ""
+		if *req.ObserverMode {
+			observer = "true"
+		}
+		setSessionAnnotationValue(svc.Annotations, sessionAnnotationObserver, observer)
+	}
+
+	if _, err := m.client.CoreV1().Services(ks.Namespace()).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to update service: %w", err)
+	}
+
+	ks.setTags(tags)
+	ks.SetAnnotations(annotations)
+	m.invalidateSessionListCache("metadata update")
+	log.Printf("[K8S_SESSION] Updated metadata of session %s", sessionID)
+	return ks, nil
+}
+
+// SessionObserverMode reports whether a session is in observer mode. The
+// Service is read so that a mode changed through another replica applies at
+// once; the session held in memory answers when it cannot be read.
+func (m *KubernetesSessionManager) SessionObserverMode(ctx context.Context, sessionID string) bool {
+	ks, ok := m.GetSession(sessionID).(*KubernetesSession)
+	if !ok || ks == nil {
+		return false
+	}
+	svc, err := m.client.CoreV1().Services(ks.Namespace()).Get(ctx, ks.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		log.Printf("[K8S_SESSION] Failed to read observer mode of session %s, using the cached mode: %v", sessionID, err)
+		return ks.Annotations().ObserverMode
+	}
+	annotations := sessionAnnotationsFromMap(svc.Annotations)
+	ks.SetAnnotations(annotations)
+	return annotations.ObserverMode
+}
+
+// sessionTagLabels returns the labels of tags. Tags whose keys are empty or
+// collide once sanitized are rejected, since their labels could not be told
+// apart.
+func sessionTagLabels(tags map[string]string) (map[string]string, error) {
+	labels := make(map[string]string, len(tags))
+	owners := make(map[string]string, len(tags))
+	for k, v := range tags {
+		key := sanitizeLabelKey(k)
+		if key == "" {
+			return nil, fmt.Errorf("%w: tag key %q has no valid label characters", entities.ErrInvalidSessionUpdate, k)
+		}
+		if other, ok := owners[key]; ok {
+			return nil, fmt.Errorf("%w: tags %q and %q map to the same label", entities.ErrInvalidSessionUpdate, other, k)
+		}
+		owners[key] = k
+		labels[sessionTagLabelPrefix+key] = sanitizeLabelValue(v)
+	}
+	return labels, nil
+}
+
+// updatedTagsFromService returns the tags stored by UpdateSessionMetadata,
+// if the tags of the session have been updated
+func updatedTagsFromService(svc *corev1.Service) (map[string]string, bool) {
+	raw, ok := svc.Annotations[sessionTagsAnnotation]
+	if !ok {
+		return nil, false
+	}
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		log.Printf("[K8S_SESSION] Ignoring invalid %s annotation on service %s: %v", sessionTagsAnnotation, svc.Name, err)
+		return nil, false
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return tags, true
+}
+
+// restoreTagsFromService returns the tags of a restored session: the updated
+// tags when they have been updated, else the (sanitized) tag labels
+func restoreTagsFromService(svc *corev1.Service) map[string]string {
+	if tags, ok := updatedTagsFromService(svc); ok {
+		return tags
+	}
+	tags := make(map[string]string)
+	for k, v := range svc.Labels {
+		if strings.HasPrefix(k, sessionTagLabelPrefix) {
+			tags[strings.TrimPrefix(k, sessionTagLabelPrefix)] = v
+		}
+	}
+	return tags
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestUpdateSessionMetadata(t *testing.T) {
+	manager := newWorkloadTestManager(t, false)
+	session := newWorkloadTestSession()
+	session.Request().Scope = entities.ScopeTeam
+	session.Request().TeamID = "acme/platform"
+	session.Request().Tags = map[string]string{"env": "dev", "owner": "alice"}
+	ctx := context.Background()
+	if err := manager.createService(ctx, session); err != nil {
+		t.Fatalf("createService: %v", err)
+	}
+	manager.mutex.Lock()
+	manager.sessions[session.ID()] = session
+	manager.mutex.Unlock()
+
+	description := "Release checklist"
+	updated, err := manager.UpdateSessionMetadata(ctx, session.ID(), entities.UpdateSessionRequest{
+		AddTags:     map[string]string{"env": "prod", "release": "v1.2/rc"},
+		RemoveTags:  []string{"owner"},
+		Description: &description,
+	})
+	if err != nil {
+		t.Fatalf("UpdateSessionMetadata: %v", err)
+	}
+	wantTags := map[string]string{"env": "prod", "release": "v1.2/rc"}
+	if !reflect.DeepEqual(updated.Tags(), wantTags) || session.Annotations().Description != description {
+		t.Errorf("tags = %v, description = %q", updated.Tags(), session.Annotations().Description)
+	}
+
+	svc, err := manager.client.CoreV1().Services("test-ns").Get(ctx, session.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if svc.Labels["agentapi.proxy/tag-env"] != "prod" || svc.Labels["agentapi.proxy/tag-release"] != sanitizeLabelValue("v1.2/rc") {
+		t.Errorf("tag labels = %v", svc.Labels)
+	}
+	if _, ok := svc.Labels["agentapi.proxy/tag-owner"]; ok {
+		t.Error("the label of the removed tag was kept")
+	}
+	if svc.Labels["agentapi.proxy/team-id-hash"] != hashTeamID("acme/platform") || svc.Labels["agentapi.proxy/scope"] != "team" {
+		t.Errorf("scope labels = %v", svc.Labels)
+	}
+	if svc.Annotations[sessionAnnotationDescription] != description {
+		t.Errorf("description annotation = %q", svc.Annotations[sessionAnnotationDescription])
+	}
+
+	// Restored sessions get the original tag values rather than the labels
+	if got := restoreTagsFromService(svc); !reflect.DeepEqual(got, wantTags) {
+		t.Errorf("restored tags = %v, want %v", got, wantTags)
+	}
+
+	observer := true
+	if _, err := manager.UpdateSessionMetadata(ctx, session.ID(), entities.UpdateSessionRequest{ObserverMode: &observer}); err != nil {
+		t.Fatalf("UpdateSessionMetadata: %v", err)
+	}
+	session.SetAnnotations(entities.SessionAnnotations{})
+	if !manager.SessionObserverMode(ctx, session.ID()) || !session.Annotations().ObserverMode {
+		t.Error("the observer mode was not read from the service")
+	}
+	if session.Annotations().Description != description || !reflect.DeepEqual(session.Tags(), wantTags) {
+		t.Errorf("enabling observer mode changed the session: %+v, %v", session.Annotations(), session.Tags())
+	}
+
+	// Tags whose labels collide are rejected
+	_, err = manager.UpdateSessionMetadata(ctx, session.ID(), entities.UpdateSessionRequest{
+		Tags: map[string]string{"a/b": "1", "a-b": "2"},
+	})
+	if !errors.Is(err, entities.ErrInvalidSessionUpdate) {
+		t.Errorf("colliding tags: err = %v, want ErrInvalidSessionUpdate", err)
+	}
+}
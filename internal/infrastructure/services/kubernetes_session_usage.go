@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// defaultOtelCollectorExporterPort is where the OpenTelemetry Collector of a
+// session Pod exposes its Prometheus metrics by default
+const defaultOtelCollectorExporterPort = 9090
+
+// BudgetCheck returns entities.ErrBudgetExceeded, wrapped, when the session
+// requested by req would exceed a usage budget.
+type BudgetCheck func(ctx context.Context, req *entities.RunServerRequest) error
+
+// SetBudgetCheck configures the usage budget check of new sessions
+func (m *KubernetesSessionManager) SetBudgetCheck(check BudgetCheck) {
+	m.budgetCheck = check
+}
+
+// checkBudget runs the usage budget check, if any
+func (m *KubernetesSessionManager) checkBudget(ctx context.Context, req *entities.RunServerRequest) error {
+	if m.budgetCheck == nil {
+		return nil
+	}
+	return m.budgetCheck(ctx, req)
+}
+
+// SessionMetricsURL returns the URL of the Prometheus metrics exported by
+// the OpenTelemetry Collector of the session's newest Pod.
+func (m *KubernetesSessionManager) SessionMetricsURL(ctx context.Context, id string) (string, error) {
+	if !m.k8sConfig.OtelCollectorEnabled {
+		return "", fmt.Errorf("the OpenTelemetry Collector of sessions is disabled")
+	}
+	m.mutex.RLock()
+	session, ok := m.sessions[id]
+	m.mutex.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("session not found: %s", id)
+	}
+	pod, err := m.sessionPod(ctx, session)
+	if err != nil {
+		return "", err
+	}
+	if pod.Status.PodIP == "" {
+		return "", entities.ErrSessionPodUnavailable
+	}
+	port := defaultOtelCollectorExporterPort
+	if m.k8sConfig.OtelCollectorExporterPort > 0 {
+		port = m.k8sConfig.OtelCollectorExporterPort
+	}
+	return fmt.Sprintf("http://%s:%d/metrics", pod.Status.PodIP, port), nil
+}
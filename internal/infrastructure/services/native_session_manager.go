@@ -21,6 +21,7 @@ import (
 
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 // NativeSessionManager runs one agent-provisioner process per session on the host.
@@ -313,6 +314,28 @@ func (m *NativeSessionManager) UpdateProvisionRequestStatus(_ context.Context, s
 	return nil
 }
 
+// UpdateSessionExtensions records the extension report sent by the native session.
+func (m *NativeSessionManager) UpdateSessionExtensions(_ context.Context, sessionID string, report *startup.ExtensionReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.provisionRequests[sessionID]
+	if p == nil {
+		return fmt.Errorf("provision request for session %s not found", sessionID)
+	}
+	p.Extensions = report
+	return nil
+}
+
+// GetSessionExtensions returns the last extension report of the session.
+func (m *NativeSessionManager) GetSessionExtensions(_ context.Context, sessionID string) (*startup.ExtensionReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p := m.provisionRequests[sessionID]; p != nil {
+		return p.Extensions, nil
+	}
+	return nil, nil
+}
+
 func (m *NativeSessionManager) GetSession(id string) entities.Session {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
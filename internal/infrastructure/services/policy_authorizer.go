@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portservices "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/services"
+)
+
+var _ portservices.Authorizer = (*PolicyAuthorizer)(nil)
+
+// PolicyAuthorizer authorizes actions with a role policy. Team-scoped
+// resources are checked against the team's override of the base policy.
+type PolicyAuthorizer struct {
+	base  *entities.RBACPolicy
+	teams map[string]*entities.RBACPolicy
+}
+
+// NewPolicyAuthorizer creates a PolicyAuthorizer. teams maps team IDs
+// ("org/team-slug") to role overrides applied on top of base.
+func NewPolicyAuthorizer(base *entities.RBACPolicy, teams map[string]*entities.RBACPolicy) *PolicyAuthorizer {
+	merged := make(map[string]*entities.RBACPolicy, len(teams))
+	for teamID, override := range teams {
+		merged[teamID] = base.Override(override)
+	}
+	return &PolicyAuthorizer{base: base, teams: merged}
+}
+
+// Authorize checks the role the user holds for the resource against the policy
+func (a *PolicyAuthorizer) Authorize(_ context.Context, user *entities.User, action entities.Action, resource entities.AuthzResource) error {
+	if user == nil {
+		return fmt.Errorf("%w: authentication required", entities.ErrActionNotAllowed)
+	}
+
+	policy := a.base
+	teamID := ""
+	if resource.Scope == entities.ScopeTeam {
+		teamID = resource.TeamID
+		if p, ok := a.teams[teamID]; ok {
+			policy = p
+		}
+	}
+
+	role := user.RoleFor(teamID)
+	if role == "" {
+		return fmt.Errorf("%w: %s is not a member of team %s", entities.ErrActionNotAllowed, user.ID(), teamID)
+	}
+	if !policy.Allows(role, action) {
+		return fmt.Errorf("%w: role %s may not %s", entities.ErrActionNotAllowed, role, action)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestPolicyAuthorizer_TeamOverride(t *testing.T) {
+	authorizer := NewPolicyAuthorizer(entities.DefaultRBACPolicy(), map[string]*entities.RBACPolicy{
+		"org/prod": entities.NewRBACPolicy(map[entities.Role][]entities.Action{
+			entities.RoleMember: {entities.ActionSessionList, entities.ActionSessionLogs},
+		}),
+	})
+	user := entities.NewUser("alice", entities.UserTypeGitHub, "alice")
+	user.SetGitHubInfo(entities.NewGitHubUserInfo(1, "alice", "", "", "", "", ""), []entities.GitHubTeamMembership{
+		{Organization: "org", TeamSlug: "dev", Role: "member"},
+		{Organization: "org", TeamSlug: "prod", Role: "member"},
+	})
+	ctx := context.Background()
+
+	dev := entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: "org/dev"}
+	if err := authorizer.Authorize(ctx, user, entities.ActionSessionExec, dev); err != nil {
+		t.Errorf("member exec in org/dev: %v", err)
+	}
+	prod := entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: "org/prod"}
+	if err := authorizer.Authorize(ctx, user, entities.ActionSessionExec, prod); !errors.Is(err, entities.ErrActionNotAllowed) {
+		t.Errorf("member exec in org/prod: err = %v, want ErrActionNotAllowed", err)
+	}
+	if err := authorizer.Authorize(ctx, user, entities.ActionSessionLogs, prod); err != nil {
+		t.Errorf("member logs in org/prod: %v", err)
+	}
+	other := entities.AuthzResource{Scope: entities.ScopeTeam, TeamID: "org/other"}
+	if err := authorizer.Authorize(ctx, user, entities.ActionSessionList, other); !errors.Is(err, entities.ErrActionNotAllowed) {
+		t.Errorf("non-member list: err = %v, want ErrActionNotAllowed", err)
+	}
+	if err := authorizer.Authorize(ctx, user, entities.ActionTeamConfigManage, dev); !errors.Is(err, entities.ErrActionNotAllowed) {
+		t.Errorf("member team config: err = %v, want ErrActionNotAllowed", err)
+	}
+}
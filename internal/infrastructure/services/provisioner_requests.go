@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,12 +17,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 const (
 	provisionRequestType       = "provision"
 	provisionerTokenSecretName = "agentapi-provisioner-token"
 	provisionerTokenSecretKey  = "token"
+	// The session token key never leaves the proxy, unlike the provisioner
+	// token every session Pod is started with.
+	sessionTokenKeySecretName = "agentapi-provisioner-session-key"
+	sessionTokenKeySecretKey  = "key"
 )
 
 // ProvisionerConnectRequest is sent by a session Pod when agent-provisioner starts.
@@ -48,6 +57,17 @@ type ProvisionRequest struct {
 	Message   string                           `json:"message,omitempty"`
 	ClaimedBy string                           `json:"claimed_by,omitempty"`
 	UpdatedAt time.Time                        `json:"updated_at"`
+	// Extensions is the last marketplace/plugin registration report of the session Pod.
+	Extensions *startup.ExtensionReport `json:"extensions,omitempty"`
+}
+
+// ProvisionerToken returns the token session Pods are started with, which
+// their provisioner requires for the workspace and changes APIs.
+func (m *KubernetesSessionManager) ProvisionerToken() string {
+	if m.k8sConfig == nil {
+		return ""
+	}
+	return m.k8sConfig.ProvisionerToken
 }
 
 func (m *KubernetesSessionManager) ValidateProvisionerToken(token string) bool {
@@ -113,6 +133,82 @@ func (m *KubernetesSessionManager) loadProvisionerToken(ctx context.Context) (st
 	return token, nil
 }
 
+// SessionToken returns the credential of sessionID, an HMAC of the session ID
+// keyed with a key only the proxy holds. Session Pods are started with it, so
+// a Pod can authenticate as its own session but not as another one. It is
+// empty when the manager has no session token key.
+func (m *KubernetesSessionManager) SessionToken(sessionID string) string {
+	if len(m.sessionTokenKey) == 0 || sessionID == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, m.sessionTokenKey)
+	mac.Write([]byte(sessionID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateSessionToken reports whether token is the credential of sessionID.
+func (m *KubernetesSessionManager) ValidateSessionToken(sessionID, token string) bool {
+	want := m.SessionToken(sessionID)
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// ensureSessionTokenKey loads the session token key from its Secret, creating
+// the Secret on first start, so session credentials survive proxy restarts.
+func (m *KubernetesSessionManager) ensureSessionTokenKey(ctx context.Context) error {
+	key, err := m.loadSessionTokenKey(ctx)
+	if err == nil {
+		m.sessionTokenKey = key
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	generated, err := generateProvisionerToken()
+	if err != nil {
+		return err
+	}
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sessionTokenKeySecretName,
+			Namespace: m.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "agentapi-proxy",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			sessionTokenKeySecretKey: []byte(generated),
+		},
+	}
+	if _, err := m.client.CoreV1().Secrets(m.namespace).Create(ctx, sec, metav1.CreateOptions{}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			key, err = m.loadSessionTokenKey(ctx)
+			if err != nil {
+				return err
+			}
+			m.sessionTokenKey = key
+			return nil
+		}
+		return err
+	}
+	m.sessionTokenKey = []byte(generated)
+	log.Printf("[K8S_SESSION] Generated session token key Secret %s/%s", m.namespace, sessionTokenKeySecretName)
+	return nil
+}
+
+func (m *KubernetesSessionManager) loadSessionTokenKey(ctx context.Context) ([]byte, error) {
+	sec, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, sessionTokenKeySecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	key := sec.Data[sessionTokenKeySecretKey]
+	if len(key) == 0 {
+		return nil, fmt.Errorf("session token key Secret %s/%s has no %q key", m.namespace, sessionTokenKeySecretName, sessionTokenKeySecretKey)
+	}
+	return key, nil
+}
+
 func generateProvisionerToken() (string, error) {
 	var b [32]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -220,6 +316,29 @@ func (m *KubernetesSessionManager) UpdateProvisionRequestStatus(ctx context.Cont
 	return nil
 }
 
+// UpdateSessionExtensions records the extension report sent by the session Pod.
+func (m *KubernetesSessionManager) UpdateSessionExtensions(ctx context.Context, sessionID string, report *startup.ExtensionReport) error {
+	provisionReq, err := m.getProvisionRequest(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	provisionReq.Extensions = report
+	return m.saveProvisionRequest(ctx, provisionReq)
+}
+
+// GetSessionExtensions returns the last extension report of the session, or
+// nil when the session Pod has not reported one.
+func (m *KubernetesSessionManager) GetSessionExtensions(ctx context.Context, sessionID string) (*startup.ExtensionReport, error) {
+	provisionReq, err := m.getProvisionRequest(ctx, sessionID)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return provisionReq.Extensions, nil
+}
+
 func (m *KubernetesSessionManager) waitForPullProvisioner(ctx context.Context, session *KubernetesSession) error {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -273,7 +392,7 @@ func (m *KubernetesSessionManager) saveProvisionRequest(ctx context.Context, req
 		"agentapi.proxy/session-id":        req.SessionID,
 		"agentapi.proxy/provision-request": "true",
 	}
-	ownerReferences := m.sessionServiceOwnerReferences(ctx, req.SessionID)
+	ownerReferences := m.sessionServiceOwnerReferences(ctx, m.namespace, req.SessionID)
 	sec, err := m.client.CoreV1().Secrets(m.namespace).Get(ctx, name, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
 		sec = &corev1.Secret{
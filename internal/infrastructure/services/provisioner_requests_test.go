@@ -10,6 +10,8 @@ import (
 
 	"github.com/takutakahashi/agentapi-proxy/pkg/config"
 	"github.com/takutakahashi/agentapi-proxy/pkg/logger"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 func TestEnsureProvisionerTokenCreatesSecret(t *testing.T) {
@@ -56,3 +58,39 @@ func TestEnsureProvisionerTokenReusesExistingSecret(t *testing.T) {
 		t.Fatalf("ProvisionerToken = %q, want existing-token", manager.k8sConfig.ProvisionerToken)
 	}
 }
+
+func TestSessionExtensionsAreStoredWithProvisionRequest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.KubernetesSession.Namespace = "test-ns"
+	manager, err := NewKubernetesSessionManagerWithClient(cfg, false, logger.NewLogger(), fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatalf("NewKubernetesSessionManagerWithClient() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if report, err := manager.GetSessionExtensions(ctx, "session-1"); err != nil || report != nil {
+		t.Fatalf("GetSessionExtensions() before provisioning = %+v, %v; want nil", report, err)
+	}
+	if err := manager.CreateProvisionRequestFromSettings(ctx, "session-1", &sessionsettings.SessionSettings{}); err != nil {
+		t.Fatalf("CreateProvisionRequestFromSettings() error = %v", err)
+	}
+
+	report := &startup.ExtensionReport{
+		Marketplaces: []startup.ExtensionStatus{{Name: "team-tools", Version: "1.2.0", Registered: true}},
+		Plugins:      []startup.ExtensionStatus{{Name: "lint@team-tools", Error: "install failed"}},
+	}
+	if err := manager.UpdateSessionExtensions(ctx, "session-1", report); err != nil {
+		t.Fatalf("UpdateSessionExtensions() error = %v", err)
+	}
+	if err := manager.UpdateProvisionRequestStatus(ctx, "session-1", "session-1-provision-1", ProvisionRequestStatusUpdate{Status: "ready"}); err != nil {
+		t.Fatalf("UpdateProvisionRequestStatus() error = %v", err)
+	}
+
+	got, err := manager.GetSessionExtensions(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("GetSessionExtensions() error = %v", err)
+	}
+	if got == nil || len(got.Marketplaces) != 1 || got.Marketplaces[0].Version != "1.2.0" || len(got.Plugins) != 1 || got.Plugins[0].Error != "install failed" {
+		t.Fatalf("GetSessionExtensions() = %+v, want the reported extensions", got)
+	}
+}
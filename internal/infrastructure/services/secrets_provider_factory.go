@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+// SecretsProviderFactory creates the SecretsProvider selected by environment variables:
+//
+//	<prefix>_PROVIDER                "vault" or "aws-secrets-manager"; unset keeps secrets in Kubernetes Secrets
+//	<prefix>_VAULT_ADDR              Vault address
+//	<prefix>_VAULT_TOKEN             static Vault token
+//	<prefix>_VAULT_KUBERNETES_ROLE   Vault Kubernetes auth role (used instead of a static token)
+//	<prefix>_VAULT_KUBERNETES_MOUNT  Vault Kubernetes auth mount (default: "kubernetes")
+//	<prefix>_VAULT_NAMESPACE         Vault Enterprise namespace
+//	<prefix>_VAULT_MOUNT             KV version 2 mount (default: "secret")
+//	<prefix>_AWS_REGION              AWS Secrets Manager region
+//	<prefix>_PATH_PREFIX             path prefix of stored secrets (default: "agentapi")
+type SecretsProviderFactory struct {
+	prefix string
+}
+
+// NewSecretsProviderFactory creates a new SecretsProviderFactory.
+// If prefix is empty, "AGENTAPI_SECRETS" is used.
+func NewSecretsProviderFactory(prefix string) *SecretsProviderFactory {
+	if prefix == "" {
+		prefix = "AGENTAPI_SECRETS"
+	}
+	return &SecretsProviderFactory{prefix: prefix}
+}
+
+// Create returns the configured SecretsProvider, or nil if none is configured
+func (f *SecretsProviderFactory) Create() (services.SecretsProvider, error) {
+	env := func(name string) string { return os.Getenv(f.prefix + "_" + name) }
+
+	switch provider := env("PROVIDER"); provider {
+	case "", "kubernetes":
+		return nil, nil
+	case "vault":
+		p, err := NewVaultSecretsProvider(VaultConfig{
+			Address:             env("VAULT_ADDR"),
+			Namespace:           env("VAULT_NAMESPACE"),
+			Mount:               env("VAULT_MOUNT"),
+			Prefix:              env("PATH_PREFIX"),
+			Token:               env("VAULT_TOKEN"),
+			KubernetesRole:      env("VAULT_KUBERNETES_ROLE"),
+			KubernetesAuthMount: env("VAULT_KUBERNETES_MOUNT"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[%s] Using HashiCorp Vault secrets provider (address: %s)", f.prefix, p.cfg.Address)
+		return p, nil
+	case "aws-secrets-manager":
+		p, err := NewAWSSecretsManagerProvider(env("AWS_REGION"), env("PATH_PREFIX"))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[%s] Using AWS Secrets Manager secrets provider (region: %s)", f.prefix, p.region)
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", provider)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	sessionallocation "github.com/takutakahashi/agentapi-proxy/internal/core/sessionallocation"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -24,20 +25,85 @@ const (
 // CreateSession creates a session by submitting a SessionAllocationRequest when
 // the leader-elected allocator is enabled. Tests and non-server usage fall back
 // to direct allocation when the allocator has not been started.
-func (m *KubernetesSessionManager) CreateSession(ctx context.Context, id string, req *entities.RunServerRequest, webhookPayload []byte) (entities.Session, error) {
+func (m *KubernetesSessionManager) CreateSession(ctx context.Context, id string, req *entities.RunServerRequest, webhookPayload []byte) (session entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "CreateSession", sessionSpanAttributes(id, req)...)
+	defer func() { span.End(err) }()
+
+	if m.shuttingDown.Load() {
+		return nil, entities.ErrShuttingDown
+	}
+	if err := m.checkReplicas(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkAccelerator(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkImage(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkRequestMCPServers(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkBudget(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := m.ensureSessionCapacity(ctx, id, req); err != nil {
+		return nil, err
+	}
+	if err := m.checkCapabilityPolicy(ctx, id, req); err != nil {
+		return nil, err
+	}
 	if !m.isSessionAllocatorEnabled() {
 		return m.allocateSessionDirect(ctx, id, req, webhookPayload)
 	}
+	span.SetAttributes(tracing.Bool("session.allocator", true))
 	return m.submitSessionAllocation(ctx, id, req, webhookPayload)
 }
 
 // CreateSessionDirect allocates a session on this manager without submitting it
 // to the cluster-wide allocator. External session manager workers use this to
 // ensure the remote session ID they report matches the concrete local session.
-func (m *KubernetesSessionManager) CreateSessionDirect(ctx context.Context, id string, req *entities.RunServerRequest, webhookPayload []byte) (entities.Session, error) {
+func (m *KubernetesSessionManager) CreateSessionDirect(ctx context.Context, id string, req *entities.RunServerRequest, webhookPayload []byte) (session entities.Session, err error) {
+	ctx, span := tracing.Start(ctx, "CreateSessionDirect", sessionSpanAttributes(id, req)...)
+	defer func() { span.End(err) }()
+
+	if m.shuttingDown.Load() {
+		return nil, entities.ErrShuttingDown
+	}
+	if err := m.checkReplicas(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkAccelerator(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkImage(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkRequestMCPServers(req); err != nil {
+		return nil, err
+	}
+	if err := m.checkBudget(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := m.ensureSessionCapacity(ctx, id, req); err != nil {
+		return nil, err
+	}
+	if err := m.checkCapabilityPolicy(ctx, id, req); err != nil {
+		return nil, err
+	}
 	return m.allocateSessionDirect(ctx, id, req, webhookPayload)
 }
 
+func sessionSpanAttributes(id string, req *entities.RunServerRequest) []tracing.Attribute {
+	return []tracing.Attribute{
+		tracing.String("session.id", id),
+		tracing.String("session.user_id", req.UserID),
+		tracing.String("session.scope", string(req.Scope)),
+		tracing.String("session.team_id", req.TeamID),
+		tracing.String("session.agent_type", req.AgentType),
+	}
+}
+
 func (m *KubernetesSessionManager) submitSessionAllocation(ctx context.Context, id string, req *entities.RunServerRequest, webhookPayload []byte) (entities.Session, error) {
 	allocation := &sessionallocation.AllocationRequest{
 		SessionID:      id,
@@ -45,6 +111,7 @@ func (m *KubernetesSessionManager) submitSessionAllocation(ctx context.Context,
 		WebhookPayload: webhookPayload,
 		Status:         sessionallocation.StatusPending,
 		Requirements:   sessionRequirements(req),
+		TraceParent:    tracing.TraceParent(ctx),
 		UpdatedAt:      time.Now().UTC(),
 	}
 	if err := m.saveSessionAllocation(ctx, allocation); err != nil {
@@ -396,7 +463,7 @@ func (m *KubernetesSessionManager) allocationProxyURL() string {
 	if proxyURL != "" {
 		return proxyURL
 	}
-	return fmt.Sprintf("http://agentapi-proxy.%s.svc.cluster.local:8080", m.namespace)
+	return fmt.Sprintf("http://%s:8080", m.serviceHost("agentapi-proxy"))
 }
 
 func (m *KubernetesSessionManager) AllocationProxyURL() string {
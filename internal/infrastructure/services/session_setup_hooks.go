@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+// SetupHookSettings converts setup hooks into the provision payload form with
+// names, timeouts and failure policies filled in, so the provisioner does not
+// need to know the defaults.
+func SetupHookSettings(hooks []entities.SetupHook) []sessionsettings.SetupHook {
+	if len(hooks) == 0 {
+		return nil
+	}
+	out := make([]sessionsettings.SetupHook, 0, len(hooks))
+	for i, h := range hooks {
+		name := h.Name
+		if name == "" {
+			name = fmt.Sprintf("hook-%d", i+1)
+		}
+		timeout := h.TimeoutSeconds
+		if timeout <= 0 {
+			timeout = entities.DefaultSetupHookTimeoutSeconds
+		}
+		timeout = min(timeout, entities.MaxSetupHookTimeoutSeconds)
+		onFailure := h.OnFailure
+		if onFailure == "" {
+			onFailure = entities.SetupHookFailSession
+		}
+		out = append(out, sessionsettings.SetupHook{
+			Name:           name,
+			Command:        h.Command,
+			TimeoutSeconds: timeout,
+			OnFailure:      string(onFailure),
+		})
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// activeSessionWatchers counts the sessions whose workload and agent status
+// are being watched by this replica.
+var activeSessionWatchers = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "agentapi_proxy",
+	Name:      "session_watchers_active",
+	Help:      "Number of sessions with active status watchers.",
+})
+
+// sessionWatch is the set of watcher goroutines of one session
+type sessionWatch struct {
+	cancel context.CancelFunc
+}
+
+// sessionWatchers is the registry of session watcher goroutines, keyed by
+// session ID. It keeps at most one watch per session: starting a watch for a
+// session that is already watched replaces the old one, and stopping a
+// session cancels its watch, so watchers neither pile up when sessions are
+// restored repeatedly nor outlive deleted sessions. The zero value is ready
+// to use.
+type sessionWatchers struct {
+	mu      sync.Mutex
+	watches map[string]*sessionWatch
+}
+
+// start runs watch in a goroutine with a context derived from parent. The
+// context is cancelled when the session is stopped, replaced by a new watch,
+// or when watch returns, which also ends any goroutines it spawned.
+func (w *sessionWatchers) start(parent context.Context, id string, watch func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(parent)
+	entry := &sessionWatch{cancel: cancel}
+
+	w.mu.Lock()
+	if w.watches == nil {
+		w.watches = make(map[string]*sessionWatch)
+	}
+	if old, ok := w.watches[id]; ok {
+		log.Printf("[K8S_SESSION] Replacing existing watchers of session %s", id)
+		old.cancel()
+	} else {
+		activeSessionWatchers.Inc()
+	}
+	w.watches[id] = entry
+	w.mu.Unlock()
+
+	go func() {
+		defer w.finish(id, entry)
+		watch(ctx)
+	}()
+}
+
+// finish removes entry once its watch has returned, unless it was replaced
+func (w *sessionWatchers) finish(id string, entry *sessionWatch) {
+	entry.cancel()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watches[id] == entry {
+		delete(w.watches, id)
+		activeSessionWatchers.Dec()
+	}
+}
+
+// stop cancels the watch of the session, if any
+func (w *sessionWatchers) stop(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if entry, ok := w.watches[id]; ok {
+		entry.cancel()
+		delete(w.watches, id)
+		activeSessionWatchers.Dec()
+	}
+}
+
+// stopAll cancels every watch
+func (w *sessionWatchers) stopAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, entry := range w.watches {
+		entry.cancel()
+		delete(w.watches, id)
+		activeSessionWatchers.Dec()
+	}
+}
+
+// count returns the number of watched sessions
+func (w *sessionWatchers) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.watches)
+}
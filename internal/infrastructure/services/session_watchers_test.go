@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func waitDone(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch was not cancelled")
+	}
+}
+
+func TestSessionWatchersReplaceDuplicate(t *testing.T) {
+	var w sessionWatchers
+	first := make(chan struct{})
+	w.start(context.Background(), "s1", func(ctx context.Context) {
+		<-ctx.Done()
+		close(first)
+	})
+	second := make(chan struct{})
+	w.start(context.Background(), "s1", func(ctx context.Context) {
+		<-ctx.Done()
+		close(second)
+	})
+
+	waitDone(t, first)
+	if got := w.count(); got != 1 {
+		t.Fatalf("count = %d, want 1", got)
+	}
+
+	w.stop("s1")
+	waitDone(t, second)
+	if got := w.count(); got != 0 {
+		t.Fatalf("count after stop = %d, want 0", got)
+	}
+}
+
+func TestSessionWatchersFinishOnReturn(t *testing.T) {
+	var w sessionWatchers
+	spawned := make(chan struct{})
+	w.start(context.Background(), "s1", func(ctx context.Context) {
+		go func() {
+			<-ctx.Done()
+			close(spawned)
+		}()
+	})
+
+	// Goroutines spawned by the watch end with it
+	waitDone(t, spawned)
+	deadline := time.Now().Add(2 * time.Second)
+	for w.count() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("finished watch was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionWatchersStopAll(t *testing.T) {
+	var w sessionWatchers
+	done := []chan struct{}{make(chan struct{}), make(chan struct{})}
+	for i, id := range []string{"s1", "s2"} {
+		ch := done[i]
+		w.start(context.Background(), id, func(ctx context.Context) {
+			<-ctx.Done()
+			close(ch)
+		})
+	}
+
+	w.stopAll()
+	for _, ch := range done {
+		waitDone(t, ch)
+	}
+	if got := w.count(); got != 0 {
+		t.Fatalf("count = %d, want 0", got)
+	}
+	// Stopping an unknown session is a no-op
+	w.stop("missing")
+}
@@ -39,6 +39,9 @@ type SimpleAuthService struct {
 	apiTokenRepo     repositories.APITokenRepository
 	githubProvider   *auth.GitHubAuthProvider
 	githubAuthConfig *config.GitHubAuthConfig
+	// staticKeys are the apiKeys entries loaded from auth.static, replaced
+	// by SetStaticAuthConfig
+	staticKeys map[string]struct{}
 
 	// shadowedSecrets records every plaintext secret that has ever been
 	// registered as a named API token (via LoadAPIToken or ReconcileAPITokens).
@@ -63,6 +66,7 @@ func NewSimpleAuthService() *SimpleAuthService {
 		keyToUserID:     make(map[string]entities.UserID),
 		apiTokens:       make(map[string]*apiTokenRecord),
 		shadowedSecrets: make(map[string]struct{}),
+		staticKeys:      make(map[string]struct{}),
 	}
 }
 
@@ -154,14 +158,75 @@ func (s *SimpleAuthService) ReconcileAPITokens(ctx context.Context) error {
 }
 
 // SetGitHubAuthConfig sets the GitHub authentication configuration.
-// If a provider has already been set via SetGitHubProvider, it is preserved.
+// If a provider has already been set via SetGitHubProvider, it is preserved
+// and switched to the config, so that a config reload keeps its caches.
 // Otherwise a new GitHubAuthProvider is created from the config.
 func (s *SimpleAuthService) SetGitHubAuthConfig(cfg *config.GitHubAuthConfig) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.githubAuthConfig = cfg
-	if cfg != nil && cfg.Enabled && s.githubProvider == nil {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	if s.githubProvider == nil {
 		s.githubProvider = auth.NewGitHubAuthProvider(cfg)
+		return
+	}
+	s.githubProvider.SetConfig(cfg)
+}
+
+// SetStaticAuthConfig replaces the API keys loaded from auth.static (and its
+// keys_file) with those of cfg, so that keys added, changed or removed by a
+// config reload apply to later requests. Static keys are dropped when static
+// auth is disabled. Each key authenticates as its user_id with its role and
+// permissions.
+func (s *SimpleAuthService) SetStaticAuthConfig(cfg *config.StaticAuthConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.staticKeys {
+		delete(s.apiKeys, key)
+		delete(s.keyToUserID, key)
+	}
+	s.staticKeys = make(map[string]struct{})
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+
+	for _, k := range cfg.APIKeys {
+		if k.Key == "" || k.UserID == "" {
+			continue
+		}
+		userID := entities.UserID(k.UserID)
+		user := entities.NewUser(userID, entities.UserTypeAPIKey, k.UserID)
+		if k.Role != "" {
+			if err := user.SetRoles([]entities.Role{entities.Role(k.Role)}); err != nil {
+				log.Printf("[AUTH] Warning: ignoring role of static API key for user %s: %v", k.UserID, err)
+			}
+		}
+		if len(k.Permissions) > 0 {
+			permissions := make([]entities.Permission, 0, len(k.Permissions))
+			for _, p := range k.Permissions {
+				permissions = append(permissions, entities.Permission(p))
+			}
+			user.SetPermissions(permissions)
+		}
+
+		var expiresAt *string
+		if k.ExpiresAt != "" {
+			expires := k.ExpiresAt
+			expiresAt = &expires
+		}
+		s.apiKeys[k.Key] = &services.APIKey{
+			Key:         k.Key,
+			UserID:      userID,
+			Permissions: user.Permissions(),
+			ExpiresAt:   expiresAt,
+			CreatedAt:   k.CreatedAt,
+		}
+		s.keyToUserID[k.Key] = userID
+		s.users[userID] = user
+		s.staticKeys[k.Key] = struct{}{}
 	}
 }
 
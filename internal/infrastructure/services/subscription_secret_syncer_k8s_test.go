@@ -71,6 +71,10 @@ func (m *mockStorage) GetNotificationHistory(userID string, limit, offset int, f
 	return nil, 0, nil
 }
 
+func (m *mockStorage) UpdateNotificationHistory(userID string, notificationID string, update func(*notification.NotificationHistory)) error {
+	return nil
+}
+
 func TestKubernetesSubscriptionSecretSyncer_Sync_CreateNew(t *testing.T) {
 	// Setup fake client
 	clientset := fake.NewSimpleClientset()
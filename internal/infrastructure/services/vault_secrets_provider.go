@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+// defaultVaultServiceAccountTokenPath is where Kubernetes mounts the Pod's
+// service account token used for Vault's Kubernetes auth method.
+const defaultVaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures a VaultSecretsProvider
+type VaultConfig struct {
+	Address   string // Vault address, e.g. "https://vault.example.com:8200"
+	Namespace string // Vault Enterprise namespace (optional)
+	Mount     string // KV version 2 mount path (default: "secret")
+	Prefix    string // Path prefix under the mount (default: "agentapi")
+
+	// Token is a static Vault token. It is used when KubernetesRole is empty.
+	Token string
+
+	// KubernetesRole enables the Kubernetes auth method with this role. The
+	// client token is renewed by logging in again before its lease expires.
+	KubernetesRole          string
+	KubernetesAuthMount     string // default: "kubernetes"
+	ServiceAccountTokenPath string // default: the in-cluster service account token
+}
+
+// VaultSecretsProvider stores secrets in a HashiCorp Vault KV version 2 engine
+type VaultSecretsProvider struct {
+	cfg        VaultConfig
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenRenewAt time.Time // zero for static tokens
+}
+
+// NewVaultSecretsProvider creates a new VaultSecretsProvider
+func NewVaultSecretsProvider(cfg VaultConfig) (*VaultSecretsProvider, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if cfg.Token == "" && cfg.KubernetesRole == "" {
+		return nil, fmt.Errorf("vault token or kubernetes role is required")
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "agentapi"
+	}
+	if cfg.KubernetesAuthMount == "" {
+		cfg.KubernetesAuthMount = "kubernetes"
+	}
+	if cfg.ServiceAccountTokenPath == "" {
+		cfg.ServiceAccountTokenPath = defaultVaultServiceAccountTokenPath
+	}
+	return &VaultSecretsProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		token:      cfg.Token,
+	}, nil
+}
+
+// Name returns "vault"
+func (p *VaultSecretsProvider) Name() string {
+	return "vault"
+}
+
+// Get returns the latest version of the secret stored under key
+func (p *VaultSecretsProvider) Get(ctx context.Context, key string) (map[string]string, error) {
+	var resp struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	status, err := p.do(ctx, http.MethodGet, p.path("data", key), nil, &resp)
+	if status == http.StatusNotFound {
+		return nil, services.ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", key, err)
+	}
+	if resp.Data.Data == nil {
+		// The latest version was deleted
+		return nil, services.ErrSecretNotFound
+	}
+	return resp.Data.Data, nil
+}
+
+// Put writes a new version of the secret stored under key
+func (p *VaultSecretsProvider) Put(ctx context.Context, key string, data map[string]string) error {
+	if _, err := p.do(ctx, http.MethodPost, p.path("data", key), map[string]interface{}{"data": data}, nil); err != nil {
+		return fmt.Errorf("failed to write vault secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes all versions of the secret stored under key
+func (p *VaultSecretsProvider) Delete(ctx context.Context, key string) error {
+	status, err := p.do(ctx, http.MethodDelete, p.path("metadata", key), nil, nil)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("failed to delete vault secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// path returns the API path of key under the KV version 2 endpoint kind ("data" or "metadata")
+func (p *VaultSecretsProvider) path(kind, key string) string {
+	return fmt.Sprintf("/v1/%s/%s/%s/%s", p.cfg.Mount, kind, p.cfg.Prefix, strings.TrimLeft(key, "/"))
+}
+
+// do sends an authenticated request, logging in again once if Vault rejects
+// the token. It returns the HTTP status code alongside any error.
+func (p *VaultSecretsProvider) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	token, err := p.clientToken(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	status, err := p.send(ctx, method, path, token, body, out)
+	if status == http.StatusForbidden && p.cfg.KubernetesRole != "" {
+		if token, err = p.clientToken(ctx, true); err != nil {
+			return 0, err
+		}
+		status, err = p.send(ctx, method, path, token, body, out)
+	}
+	return status, err
+}
+
+func (p *VaultSecretsProvider) send(ctx context.Context, method, path, token string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.cfg.Address+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return resp.StatusCode, fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode vault response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// clientToken returns the Vault token, logging in with the Kubernetes auth
+// method when there is no token yet, its lease is about to expire, or force is set.
+func (p *VaultSecretsProvider) clientToken(ctx context.Context, force bool) (string, error) {
+	if p.cfg.KubernetesRole == "" {
+		return p.cfg.Token, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !force && p.token != "" && time.Now().Before(p.tokenRenewAt) {
+		return p.token, nil
+	}
+
+	jwt, err := os.ReadFile(p.cfg.ServiceAccountTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	loginPath := fmt.Sprintf("/v1/auth/%s/login", p.cfg.KubernetesAuthMount)
+	body := map[string]string{"role": p.cfg.KubernetesRole, "jwt": strings.TrimSpace(string(jwt))}
+	if _, err := p.send(ctx, http.MethodPost, loginPath, "", body, &resp); err != nil {
+		return "", fmt.Errorf("vault kubernetes login failed: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault kubernetes login returned no client token")
+	}
+
+	p.token = resp.Auth.ClientToken
+	// Log in again once three quarters of the lease have passed
+	lease := time.Duration(resp.Auth.LeaseDuration) * time.Second
+	if lease <= 0 {
+		// Non-expiring token; still log in periodically to pick up role changes
+		lease = time.Hour
+	}
+	p.tokenRenewAt = time.Now().Add(lease * 3 / 4)
+	return p.token, nil
+}
+
+var _ services.SecretsProvider = (*VaultSecretsProvider)(nil)
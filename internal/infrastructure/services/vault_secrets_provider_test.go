@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/services"
+)
+
+// fakeVault is a minimal KV version 2 server with Kubernetes auth
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]string
+	token   string
+	logins  int
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth/kubernetes/login" {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role"] != "agentapi" || body["jwt"] != "sa-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		v.logins++
+		v.token = "token-" + string(rune('0'+v.logins))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": v.token, "lease_duration": 3600},
+		})
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := v.secrets[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		case http.MethodPost:
+			var body struct {
+				Data map[string]string `json:"data"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			v.secrets[key] = body.Data
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": 1}})
+		}
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/") && r.Method == http.MethodDelete:
+		delete(v.secrets, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultSecretsProvider(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]map[string]string{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewVaultSecretsProvider(VaultConfig{
+		Address:                 server.URL,
+		KubernetesRole:          "agentapi",
+		ServiceAccountTokenPath: tokenPath,
+	})
+	if err != nil {
+		t.Fatalf("NewVaultSecretsProvider() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := provider.Get(ctx, "settings/alice"); !errors.Is(err, services.ErrSecretNotFound) {
+		t.Fatalf("Get() of a missing secret error = %v, want ErrSecretNotFound", err)
+	}
+	if err := provider.Put(ctx, "settings/alice", map[string]string{"env.FOO": "bar"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := vault.secrets["agentapi/settings/alice"]; !ok {
+		t.Fatalf("secret not stored under the path prefix: %v", vault.secrets)
+	}
+
+	// A revoked token is replaced by logging in again
+	vault.mu.Lock()
+	vault.token = "revoked"
+	vault.mu.Unlock()
+	data, err := provider.Get(ctx, "settings/alice")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if data["env.FOO"] != "bar" {
+		t.Errorf("Get() = %v, want env.FOO=bar", data)
+	}
+	if vault.logins != 2 {
+		t.Errorf("logins = %d, want 2 (initial login and re-login after revocation)", vault.logins)
+	}
+
+	if err := provider.Delete(ctx, "settings/alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := provider.Get(ctx, "settings/alice"); !errors.Is(err, services.ErrSecretNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestNewVaultSecretsProviderRequiresCredentials(t *testing.T) {
+	if _, err := NewVaultSecretsProvider(VaultConfig{Address: "https://vault.example.com"}); err == nil {
+		t.Error("expected an error without a token or kubernetes role")
+	}
+	if _, err := NewVaultSecretsProvider(VaultConfig{Token: "t"}); err == nil {
+		t.Error("expected an error without an address")
+	}
+}
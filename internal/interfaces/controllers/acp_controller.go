@@ -203,7 +203,7 @@ func (c *ACPController) handleSessionNew(ctx echo.Context, req acpRequest) error
 	startReq.TeamID = resolvedTeamID
 
 	sessionID := uuid.New().String()
-	session, err := c.sessionCreator.CreateSession(sessionID, startReq, userID, userRole, teams)
+	session, err := c.sessionCreator.CreateSession(ctx.Request().Context(), sessionID, startReq, userID, userRole, teams)
 	if err != nil {
 		log.Printf("[ACP] session/new failed: %v", err)
 		return ctx.JSON(http.StatusOK, acpErrResp(req.ID, -32603, "failed to create session: "+err.Error()))
@@ -318,6 +318,9 @@ func (c *ACPController) handleSessionClose(ctx echo.Context, req acpRequest) err
 	if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
 		return ctx.JSON(http.StatusOK, acpErrResp(req.ID, -32603, "permission denied"))
 	}
+	if c.observerModeBlocks(ctx, session) {
+		return ctx.JSON(http.StatusOK, acpErrResp(req.ID, -32603, errSessionObserverMode.Error()))
+	}
 
 	if err := c.sessionCreator.DeleteSessionByID(params.SessionId); err != nil {
 		log.Printf("[ACP] session/close failed: %v", err)
@@ -450,6 +453,9 @@ func (c *ACPController) proxyToBridge(ctx echo.Context, req acpRequest) error {
 	if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
 		return ctx.JSON(http.StatusOK, acpErrResp(req.ID, -32603, "permission denied"))
 	}
+	if c.observerModeBlocks(ctx, session) {
+		return ctx.JSON(http.StatusOK, acpErrResp(req.ID, -32603, errSessionObserverMode.Error()))
+	}
 
 	addr := session.Addr()
 	if addr == "" {
@@ -522,6 +528,9 @@ func (c *ACPController) proxyResultToBridge(ctx echo.Context, req acpRequest) er
 	if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
 		return ctx.JSON(http.StatusForbidden, map[string]string{"message": "permission denied"})
 	}
+	if c.observerModeBlocks(ctx, session) {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"message": errSessionObserverMode.Error()})
+	}
 
 	addr := session.Addr()
 	if addr == "" {
@@ -545,6 +554,12 @@ func (c *ACPController) proxyResultToBridge(ctx echo.Context, req acpRequest) er
 	return ctx.JSON(http.StatusOK, map[string]interface{}{})
 }
 
+// observerModeBlocks reports whether observer mode keeps the caller from
+// prompting or controlling session. Streaming its updates stays allowed.
+func (c *ACPController) observerModeBlocks(ctx echo.Context, session entities.Session) bool {
+	return observerModeBlocks(ctx.Request().Context(), c.sessionManagerProvider.GetSessionManager(), auth.GetUserFromContext(ctx), session)
+}
+
 // ----------------------------------------------------------------------------
 // HandleSessionSSE – GET /acp
 // ----------------------------------------------------------------------------
@@ -52,6 +52,7 @@ func (s *fakeSession) Cancel()                       {}
 // fakeSessionManager implements repositories.SessionManager for tests.
 type fakeSessionManager struct {
 	sessions map[string]*fakeSession
+	observed map[string]bool // sessions in observer mode
 }
 
 func (m *fakeSessionManager) GetSession(id string) entities.Session {
@@ -68,11 +69,23 @@ func (m *fakeSessionManager) ListSessions(filter entities.SessionFilter) []entit
 		if filter.UserID != "" && s.userID != filter.UserID {
 			continue
 		}
+		if !matchesFakeTags(s.tags, filter.Tags) {
+			continue
+		}
 		out = append(out, s)
 	}
 	return out
 }
 
+func matchesFakeTags(tags, want map[string]string) bool {
+	for k, v := range want {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
 // Stubs to satisfy repositories.SessionManager interface.
 func (m *fakeSessionManager) CreateSession(_ context.Context, _ string, _ *entities.RunServerRequest, _ []byte) (entities.Session, error) {
 	return nil, nil
@@ -86,6 +99,9 @@ func (m *fakeSessionManager) GetMessages(_ context.Context, _ string) ([]reposit
 	return nil, nil
 }
 func (m *fakeSessionManager) Shutdown(_ time.Duration) error { return nil }
+func (m *fakeSessionManager) SessionObserverMode(_ context.Context, id string) bool {
+	return m.observed[id]
+}
 
 // testSessionManagerProvider adapts fakeSessionManager to controllers.SessionManagerProvider.
 type testSessionManagerProvider struct {
@@ -118,7 +134,7 @@ func (r *fakeACPRouteRepo) List(context.Context, string) ([]*repositories.Sessio
 }
 func (r *fakeACPRouteRepo) Delete(context.Context, string) error { return nil }
 
-func (c *fakeSessionCreator) CreateSession(sessionID string, req entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error) {
+func (c *fakeSessionCreator) CreateSession(_ context.Context, sessionID string, req entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error) {
 	c.created = append(c.created, sessionID)
 	return &fakeSession{id: sessionID, userID: userID, scope: req.Scope, status: "running"}, nil
 }
@@ -327,6 +343,41 @@ func TestACPController_SessionClose_OK(t *testing.T) {
 	}
 }
 
+func TestACPController_SessionClose_ObserverMode(t *testing.T) {
+	for _, observed := range []bool{false, true} {
+		mgr := &fakeSessionManager{
+			sessions: map[string]*fakeSession{
+				"sess-team": {id: "sess-team", userID: "owner", scope: entities.ScopeTeam, teamID: "org/qa", status: "running"},
+			},
+			observed: map[string]bool{"sess-team": observed},
+		}
+		creator := &fakeSessionCreator{}
+		ctrl := controllers.NewACPController(&testSessionManagerProvider{mgr: mgr}, creator)
+		c, rec := setupEchoContext(echo.New(), http.MethodPost, "/acp",
+			rpcBody("session/close", map[string]string{"sessionId": "sess-team"}), "user1")
+		c.Set("authz_context", &auth.AuthorizationContext{
+			PersonalScope: auth.PersonalScopeAuth{UserID: "user1"},
+			TeamScope: auth.TeamScopeAuth{
+				Teams:           []string{"org/qa"},
+				TeamPermissions: map[string]auth.TeamPermissions{"org/qa": {CanRead: true}},
+			},
+		})
+		c.Set("internal_user", entities.NewUser("user1", entities.UserTypeRegular, "user1"))
+
+		if err := ctrl.HandleRPC(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp := parseRPCResponse(t, rec)
+		if observed {
+			if resp["error"] == nil || len(creator.deleted) != 0 {
+				t.Errorf("observer mode: response = %v, deleted = %v, want an error and no deletion", resp, creator.deleted)
+			}
+		} else if resp["error"] != nil || len(creator.deleted) != 1 {
+			t.Errorf("response = %v, deleted = %v, want the session closed", resp, creator.deleted)
+		}
+	}
+}
+
 func TestACPController_SessionClose_NotFound(t *testing.T) {
 	e, ctrl, _ := newEchoWithACP(nil)
 	c, rec := setupEchoContext(e, http.MethodPost, "/acp",
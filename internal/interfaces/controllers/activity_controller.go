@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+const (
+	// defaultActivityWindow is how far back GET /me/activity looks without ?since=
+	defaultActivityWindow = 7 * 24 * time.Hour
+	defaultActivityLimit  = 50
+	maxActivityLimit      = 200
+)
+
+// ActivityController serves the activity feed of the caller
+type ActivityController struct {
+	feed *activity.Feed
+}
+
+// NewActivityController creates a new ActivityController
+func NewActivityController(feed *activity.Feed) *ActivityController {
+	return &ActivityController{feed: feed}
+}
+
+// GetName returns the name of this controller for logging
+func (c *ActivityController) GetName() string {
+	return "ActivityController"
+}
+
+// ActivityResponse is the response of GET /me/activity
+type ActivityResponse struct {
+	Items []activity.Item `json:"items"`
+	// FailedSources names the sources that could not be read; the feed is
+	// incomplete when it is set
+	FailedSources []string `json:"failed_sources,omitempty"`
+}
+
+// GetActivity handles GET /me/activity?since=&kind=&limit=.
+// It returns the caller's recent sessions, deleted sessions, approvals,
+// schedule runs and notifications, newest first.
+func (c *ActivityController) GetActivity(ctx echo.Context) error {
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || authzCtx.PersonalScope.UserID == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	q := activity.Query{
+		UserID: authzCtx.PersonalScope.UserID,
+		Since:  time.Now().Add(-defaultActivityWindow),
+		Limit:  defaultActivityLimit,
+	}
+	if since := ctx.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		}
+		q.Since = t
+	}
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxActivityLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxActivityLimit))
+		}
+		q.Limit = n
+	}
+	if kinds := ctx.QueryParam("kind"); kinds != "" {
+		for _, kind := range strings.Split(kinds, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(activity.Kinds, kind) {
+				return echo.NewHTTPError(http.StatusBadRequest, "unknown kind "+strconv.Quote(kind)+": must be one of "+strings.Join(activity.Kinds, ", "))
+			}
+			q.Kinds = append(q.Kinds, kind)
+		}
+	}
+
+	result := c.feed.Collect(ctx.Request().Context(), q)
+	resp := ActivityResponse{Items: result.Items, FailedSources: result.Failed}
+	if resp.Items == nil {
+		resp.Items = []activity.Item{}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
+)
+
+func TestActivityController_GetActivity(t *testing.T) {
+	feed := activity.NewFeed()
+	feed.Add("sessions", activity.SourceFunc(func(_ context.Context, q activity.Query) ([]activity.Item, error) {
+		return []activity.Item{{Kind: activity.KindSession, SessionID: "sess-of-" + q.UserID, Timestamp: time.Now()}}, nil
+	}), activity.KindSession)
+	controller := NewActivityController(feed)
+
+	c, rec := makeWaitEchoContext(t, "", map[string]string{"kind": "session"}, "user-1")
+	require.NoError(t, controller.GetActivity(c))
+	var resp ActivityResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "sess-of-user-1", resp.Items[0].SessionID)
+
+	c, rec = makeWaitEchoContext(t, "", map[string]string{"kind": "notification"}, "user-1")
+	require.NoError(t, controller.GetActivity(c))
+	assert.JSONEq(t, `{"items":[]}`, rec.Body.String())
+
+	c, _ = makeWaitEchoContext(t, "", map[string]string{"kind": "unknown"}, "user-1")
+	assertHTTPError(t, controller.GetActivity(c), http.StatusBadRequest)
+
+	c, _ = makeWaitEchoContext(t, "", map[string]string{"since": "yesterday"}, "user-1")
+	assertHTTPError(t, controller.GetActivity(c), http.StatusBadRequest)
+
+	c, _ = makeWaitEchoContext(t, "", map[string]string{"limit": "1000"}, "user-1")
+	assertHTTPError(t, controller.GetActivity(c), http.StatusBadRequest)
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+)
+
+// BillingLedger provides the metered team usage of each month
+type BillingLedger interface {
+	Month(ctx context.Context, month string) (*showback.Month, error)
+	Months(ctx context.Context) ([]string, error)
+}
+
+// BillingController serves showback statements to administrators
+type BillingController struct {
+	ledger BillingLedger
+	rates  showback.Rates
+}
+
+// NewBillingController creates a new BillingController pricing usage with rates
+func NewBillingController(ledger BillingLedger, rates showback.Rates) *BillingController {
+	return &BillingController{ledger: ledger, rates: rates}
+}
+
+// GetName returns the name of this controller for logging
+func (c *BillingController) GetName() string {
+	return "BillingController"
+}
+
+// BillingResponse is the response of GET /admin/billing
+type BillingResponse struct {
+	Month    string `json:"month"`
+	Currency string `json:"currency"`
+	// Final is false while the month is still in progress
+	Final      bool                  `json:"final"`
+	Total      float64               `json:"total"`
+	Statements []*showback.Statement `json:"statements"`
+	// Months lists the months with recorded usage
+	Months []string `json:"months"`
+}
+
+// GetBilling handles GET /admin/billing. It returns the statements of every
+// team for ?month=YYYY-MM, the current month by default.
+func (c *BillingController) GetBilling(ctx echo.Context) error {
+	now := time.Now()
+	month, start, err := c.month(ctx, now)
+	if err != nil {
+		return err
+	}
+	reqCtx := ctx.Request().Context()
+	m, err := c.ledger.Month(reqCtx, month)
+	if err != nil {
+		log.Printf("[SHOWBACK] Failed to load usage of %s: %v", month, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load usage")
+	}
+	months, err := c.ledger.Months(reqCtx)
+	if err != nil {
+		log.Printf("[SHOWBACK] Failed to list months: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load usage")
+	}
+	statements, err := showback.Statements(m, c.rates, now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	resp := BillingResponse{
+		Month:      month,
+		Currency:   c.rates.Currency,
+		Final:      !now.Before(start.AddDate(0, 1, 0)),
+		Total:      showback.Total(statements),
+		Statements: statements,
+		Months:     months,
+	}
+	if resp.Months == nil {
+		resp.Months = []string{}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// GetStatement handles GET /admin/billing/statement?team=&month=&format=.
+// The statement is returned as JSON, or as a PDF document with format=pdf.
+func (c *BillingController) GetStatement(ctx echo.Context) error {
+	teamID := ctx.QueryParam("team")
+	if teamID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "team is required")
+	}
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "pdf" {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be json or pdf")
+	}
+	month, _, err := c.month(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m, err := c.ledger.Month(ctx.Request().Context(), month)
+	if err != nil {
+		log.Printf("[SHOWBACK] Failed to load usage of %s: %v", month, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load usage")
+	}
+	usage, ok := m.Teams[teamID]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("No usage recorded for team %s in %s", teamID, month))
+	}
+	st, err := showback.BuildStatement(month, *usage, c.rates, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if format == "json" {
+		return ctx.JSON(http.StatusOK, st)
+	}
+
+	var pdf bytes.Buffer
+	if err := showback.WritePDF(&pdf, st); err != nil {
+		log.Printf("[SHOWBACK] Failed to render statement of %s for %s: %v", teamID, month, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render statement")
+	}
+	filename := fmt.Sprintf("showback-%s-%s.pdf", strings.ReplaceAll(teamID, "/", "-"), month)
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return ctx.Blob(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+// month returns the validated ?month= parameter and its first instant,
+// defaulting to the month of now
+func (c *BillingController) month(ctx echo.Context, now time.Time) (string, time.Time, error) {
+	month := ctx.QueryParam("month")
+	if month == "" {
+		month = showback.MonthOf(now)
+	}
+	start, err := showback.ParseMonth(month)
+	if err != nil {
+		return "", time.Time{}, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return month, start, nil
+}
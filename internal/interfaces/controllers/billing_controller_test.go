@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+)
+
+type stubBillingLedger struct {
+	months map[string]*showback.Month
+}
+
+func (l *stubBillingLedger) Month(_ context.Context, month string) (*showback.Month, error) {
+	if m, ok := l.months[month]; ok {
+		return m, nil
+	}
+	return &showback.Month{Month: month, Teams: map[string]*showback.TeamUsage{}}, nil
+}
+
+func (l *stubBillingLedger) Months(context.Context) ([]string, error) {
+	return []string{"2026-09"}, nil
+}
+
+func newTestBillingController() *BillingController {
+	ledger := &stubBillingLedger{months: map[string]*showback.Month{
+		"2026-09": {Month: "2026-09", Teams: map[string]*showback.TeamUsage{
+			"acme/dev": {TeamID: "acme/dev", SessionHours: 10},
+			"acme/ops": {TeamID: "acme/ops", SessionHours: 4},
+		}},
+	}}
+	return NewBillingController(ledger, showback.Rates{Currency: "USD", SessionHour: 0.5})
+}
+
+func TestBillingController_GetBilling(t *testing.T) {
+	controller := newTestBillingController()
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/admin/billing?month=2026-09", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetBilling(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp BillingResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Final)
+	assert.Equal(t, 7.0, resp.Total)
+	require.Len(t, resp.Statements, 2)
+	assert.Equal(t, "acme/dev", resp.Statements[0].TeamID)
+	assert.Equal(t, []string{"2026-09"}, resp.Months)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/admin/billing?month=september", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetBilling(c), http.StatusBadRequest)
+}
+
+func TestBillingController_GetStatement(t *testing.T) {
+	controller := newTestBillingController()
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/admin/billing/statement?team=acme/dev&month=2026-09", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetStatement(c))
+	var st showback.Statement
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+	assert.Equal(t, 5.0, st.Total)
+
+	c, rec = makeMemoryEchoContext(t, http.MethodGet, "/admin/billing/statement?team=acme/dev&month=2026-09&format=pdf", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetStatement(c))
+	assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "showback-acme-dev-2026-09.pdf")
+	assert.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF-")))
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/admin/billing/statement?team=acme/qa&month=2026-09", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetStatement(c), http.StatusNotFound)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/admin/billing/statement?month=2026-09", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetStatement(c), http.StatusBadRequest)
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// BrowserController proxies the noVNC display of a session's headless browser
+// sidecar. The display runs without a password, so every request is
+// authorized here before it is forwarded.
+type BrowserController struct {
+	sessionManagerProvider SessionManagerProvider
+	// browserURL resolves the noVNC base URL of a session. Overridden in tests.
+	browserURL func(entities.Session) (string, bool)
+}
+
+// NewBrowserController creates a new BrowserController
+func NewBrowserController(sessionManagerProvider SessionManagerProvider) *BrowserController {
+	return &BrowserController{
+		sessionManagerProvider: sessionManagerProvider,
+		browserURL:             kubernetesBrowserURL,
+	}
+}
+
+// GetName returns the name of this controller for logging
+func (c *BrowserController) GetName() string {
+	return "BrowserController"
+}
+
+func kubernetesBrowserURL(session entities.Session) (string, bool) {
+	ks, ok := session.(*services.KubernetesSession)
+	if !ok || !ks.BrowserEnabled() {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s:%d", ks.Host(), services.BrowserVNCPort), true
+}
+
+// ProxyBrowser handles /sessions/:sessionId/browser and everything below it,
+// including the noVNC WebSocket.
+func (c *BrowserController) ProxyBrowser(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	baseURL, ok := c.browserURL(session)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Browser is not enabled for this session")
+	}
+	target, err := url.Parse(baseURL)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Invalid browser URL: %v", err))
+	}
+
+	// noVNC builds its WebSocket URL from the host and the path parameter, so
+	// the viewer is opened with a path that points back through this proxy.
+	prefix := "/sessions/" + sessionID + "/browser"
+	if path := ctx.Request().URL.Path; path == prefix || path == prefix+"/" {
+		query := url.Values{}
+		query.Set("autoconnect", "1")
+		query.Set("resize", "scale")
+		query.Set("path", strings.TrimPrefix(prefix, "/")+"/websockify")
+		return ctx.Redirect(http.StatusFound, prefix+"/vnc.html?"+query.Encode())
+	}
+
+	return proxyToSidecar(ctx, c.sessionManagerProvider.GetSessionManager(), session, prefix, target, "Browser")
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func newTestBrowserController(baseURL string) *BrowserController {
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	c := NewBrowserController(&mockWaitProvider{manager: newMockWaitSessionManager(session)})
+	c.browserURL = func(entities.Session) (string, bool) { return baseURL, baseURL != "" }
+	return c
+}
+
+func TestBrowserController_ProxiesToSidecar(t *testing.T) {
+	vnc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app/ui.js", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer vnc.Close()
+
+	c := newTestBrowserController(vnc.URL)
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/browser/app/ui.js", "sess-1", "alice")
+	ctx.Request().Header.Set("Authorization", "Bearer secret")
+	require.NoError(t, c.ProxyBrowser(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestBrowserController_RedirectsToViewer(t *testing.T) {
+	c := newTestBrowserController("http://browser.invalid")
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/browser/", "sess-1", "alice")
+	require.NoError(t, c.ProxyBrowser(ctx))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/sessions/sess-1/browser/vnc.html?autoconnect=1&path=sessions%2Fsess-1%2Fbrowser%2Fwebsockify&resize=scale",
+		rec.Header().Get("Location"))
+}
+
+func TestBrowserController_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		sessionID  string
+		userID     string
+		wantStatus int
+	}{
+		{"unknown session", "http://browser.invalid", "missing", "alice", http.StatusNotFound},
+		{"other user", "http://browser.invalid", "sess-1", "bob", http.StatusForbidden},
+		{"browser disabled", "", "sess-1", "alice", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestBrowserController(tt.baseURL)
+			ctx, _ := makeWorkspaceEchoContext("/sessions/"+tt.sessionID+"/browser/vnc.html", tt.sessionID, tt.userID)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, c.ProxyBrowser(ctx), &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/capacity"
+)
+
+// CapacityForecaster forecasts session capacity from recorded history
+type CapacityForecaster interface {
+	Forecast(ctx context.Context, now time.Time) (*capacity.Report, error)
+}
+
+// CapacityController serves capacity forecasts to administrators
+type CapacityController struct {
+	forecaster CapacityForecaster
+}
+
+// NewCapacityController creates a new CapacityController
+func NewCapacityController(forecaster CapacityForecaster) *CapacityController {
+	return &CapacityController{forecaster: forecaster}
+}
+
+// GetName returns the name of this controller for logging
+func (c *CapacityController) GetName() string {
+	return "CapacityController"
+}
+
+// GetForecast handles GET /admin/capacity/forecast. It forecasts peak
+// concurrent sessions, node count and warm pool size for the next 7 days.
+func (c *CapacityController) GetForecast(ctx echo.Context) error {
+	report, err := c.forecaster.Forecast(ctx.Request().Context(), time.Now())
+	if err != nil {
+		log.Printf("[CAPACITY] Failed to forecast capacity: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to forecast capacity")
+	}
+	return ctx.JSON(http.StatusOK, report)
+}
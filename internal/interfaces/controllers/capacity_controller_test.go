@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/capacity"
+)
+
+type stubCapacityForecaster struct {
+	buckets []capacity.Bucket
+	err     error
+}
+
+func (f *stubCapacityForecaster) Forecast(_ context.Context, now time.Time) (*capacity.Report, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return capacity.Forecast(f.buckets, now, capacity.ForecastOptions{}), nil
+}
+
+func TestCapacityController_GetForecast(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-7*24*time.Hour + time.Hour)
+	controller := NewCapacityController(&stubCapacityForecaster{
+		buckets: []capacity.Bucket{{Hour: hour, PeakActive: 6, Created: 4}},
+	})
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/admin/capacity/forecast", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetForecast(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report capacity.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 6, report.PeakConcurrent)
+	assert.Len(t, report.Hours, 168)
+
+	controller = NewCapacityController(&stubCapacityForecaster{err: errors.New("disk full")})
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/admin/capacity/forecast", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetForecast(c), http.StatusInternalServerError)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/compliance"
+)
+
+// defaultCompliancePeriod is the report period when no from/to is given
+const defaultCompliancePeriod = 30 * 24 * time.Hour
+
+// defaultAuditEventsPeriod is the query period when no from/to is given
+const defaultAuditEventsPeriod = 7 * 24 * time.Hour
+
+// ComplianceController serves the audit log and compliance reports built from it
+type ComplianceController struct {
+	reportUC *compliance.ReportUseCase
+	eventsUC *compliance.EventsUseCase
+}
+
+// NewComplianceController creates a new ComplianceController
+func NewComplianceController(reportUC *compliance.ReportUseCase, eventsUC *compliance.EventsUseCase) *ComplianceController {
+	return &ComplianceController{reportUC: reportUC, eventsUC: eventsUC}
+}
+
+// GetName returns the name of this controller for logging
+func (c *ComplianceController) GetName() string {
+	return "ComplianceController"
+}
+
+// ListAuditEvents handles GET /admin/audit-events. Events are returned newest
+// first and filtered by the user (actor ID) and action (route or operation)
+// query parameters; from/to default to the last 7 days.
+func (c *ComplianceController) ListAuditEvents(ctx echo.Context) error {
+	from, to, err := parsePeriod(ctx, defaultAuditEventsPeriod)
+	if err != nil {
+		return err
+	}
+	limit := 0
+	if v := ctx.QueryParam("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > compliance.MaxEventsLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", compliance.MaxEventsLimit))
+		}
+	}
+
+	result, err := c.eventsUC.Execute(ctx.Request().Context(), compliance.EventsQuery{
+		From:   from,
+		To:     to,
+		Actor:  ctx.QueryParam("user"),
+		Action: ctx.QueryParam("action"),
+		Limit:  limit,
+	})
+	if err != nil {
+		log.Printf("Failed to query audit events: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to query audit events")
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// GetComplianceReport handles GET /admin/reports/compliance. The period is
+// given by the from/to query parameters (RFC 3339 or YYYY-MM-DD) and
+// defaults to the last 30 days; format selects json, csv or pdf.
+func (c *ComplianceController) GetComplianceReport(ctx echo.Context) error {
+	from, to, err := parsePeriod(ctx, defaultCompliancePeriod)
+	if err != nil {
+		return err
+	}
+
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "pdf" {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be one of json, csv, pdf")
+	}
+
+	report, err := c.reportUC.Execute(ctx.Request().Context(), from, to)
+	if err != nil {
+		log.Printf("Failed to generate compliance report: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate compliance report")
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		err = compliance.WriteCSV(&buf, report)
+	case "pdf":
+		contentType = "application/pdf"
+		err = compliance.WritePDF(&buf, report)
+	default:
+		return ctx.JSON(http.StatusOK, report)
+	}
+	if err != nil {
+		log.Printf("Failed to export compliance report as %s: %v", format, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export compliance report")
+	}
+
+	filename := fmt.Sprintf("compliance-report-%s-%s.%s", report.From.Format("20060102"), report.To.Format("20060102"), format)
+	ctx.Response().Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return ctx.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// parsePeriod reads the from/to query parameters. to defaults to now and
+// from to period before to.
+func parsePeriod(ctx echo.Context, period time.Duration) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if v := ctx.QueryParam("to"); v != "" {
+		if to, err = parseReportTime(v); err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "Invalid to: "+err.Error())
+		}
+	}
+	from = to.Add(-period)
+	if v := ctx.QueryParam("from"); v != "" {
+		if from, err = parseReportTime(v); err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "Invalid from: "+err.Error())
+		}
+	}
+	if !from.Before(to) {
+		return from, to, echo.NewHTTPError(http.StatusBadRequest, "from must be before to")
+	}
+	return from, to, nil
+}
+
+// parseReportTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date (UTC midnight)
+func parseReportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	return t, nil
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// ConfigReloader reloads the config file and applies its safe-to-change
+// settings
+type ConfigReloader interface {
+	ReloadConfig(ctx context.Context, actor string) (config.ReloadResult, error)
+}
+
+// ConfigReloadController handles config reloads requested by administrators
+type ConfigReloadController struct {
+	reloader ConfigReloader
+}
+
+// NewConfigReloadController creates a new ConfigReloadController
+func NewConfigReloadController(reloader ConfigReloader) *ConfigReloadController {
+	return &ConfigReloadController{reloader: reloader}
+}
+
+// GetName returns the name of this controller for logging
+func (c *ConfigReloadController) GetName() string {
+	return "ConfigReloadController"
+}
+
+// ReloadConfig handles POST /admin/config/reload. It responds with the
+// changed settings that were applied and those that need a restart, or 422
+// with the current config kept when the config cannot be loaded or is
+// invalid.
+func (c *ConfigReloadController) ReloadConfig(ctx echo.Context) error {
+	actor := "anonymous"
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		actor = string(user.ID())
+	}
+	result, err := c.reloader.ReloadConfig(ctx.Request().Context(), actor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Config reload failed: "+err.Error())
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
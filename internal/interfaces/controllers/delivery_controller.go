@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+)
+
+// DeliveryController serves the dead-letter list of the outbound delivery
+// queue and manual redelivery
+type DeliveryController struct {
+	queue *delivery.Queue
+}
+
+// NewDeliveryController creates a new DeliveryController
+func NewDeliveryController(queue *delivery.Queue) *DeliveryController {
+	return &DeliveryController{queue: queue}
+}
+
+// GetName returns the name of this controller for logging
+func (c *DeliveryController) GetName() string {
+	return "DeliveryController"
+}
+
+// ListDeadLetters handles GET /admin/deliveries/dead. Deliveries are
+// returned most recently dead first and filtered by the kind query parameter.
+func (c *DeliveryController) ListDeadLetters(ctx echo.Context) error {
+	dead, err := c.queue.DeadLetters(ctx.Request().Context())
+	if err != nil {
+		log.Printf("Failed to list dead-lettered deliveries: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list dead-lettered deliveries")
+	}
+	if kind := ctx.QueryParam("kind"); kind != "" {
+		filtered := make([]*delivery.Delivery, 0, len(dead))
+		for _, d := range dead {
+			if d.Kind == kind {
+				filtered = append(filtered, d)
+			}
+		}
+		dead = filtered
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries": dead,
+		"total":      len(dead),
+	})
+}
+
+// Redeliver handles POST /admin/deliveries/:id/redeliver. The delivery is
+// moved back to the queue with a fresh set of attempts.
+func (c *DeliveryController) Redeliver(ctx echo.Context) error {
+	d, err := c.queue.Redeliver(ctx.Request().Context(), ctx.Param("id"))
+	if errors.Is(err, delivery.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Dead-lettered delivery not found")
+	}
+	if err != nil {
+		log.Printf("Failed to redeliver delivery %s: %v", ctx.Param("id"), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to redeliver delivery")
+	}
+	return ctx.JSON(http.StatusAccepted, d)
+}
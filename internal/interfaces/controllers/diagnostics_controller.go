@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+)
+
+// DiagnosticsController runs live checks of the proxy's dependencies
+type DiagnosticsController struct {
+	checks []diagnostics.Check
+}
+
+// NewDiagnosticsController creates a new DiagnosticsController
+func NewDiagnosticsController(checks []diagnostics.Check) *DiagnosticsController {
+	return &DiagnosticsController{checks: checks}
+}
+
+// GetName returns the name of this controller for logging
+func (c *DiagnosticsController) GetName() string {
+	return "DiagnosticsController"
+}
+
+// RunDiagnostics handles GET /admin/diagnostics. The check query parameter
+// takes a comma-separated list of check names to run; all checks run by
+// default. The report is returned with 200 whatever its status.
+func (c *DiagnosticsController) RunDiagnostics(ctx echo.Context) error {
+	checks := c.checks
+	if names := ctx.QueryParam("check"); names != "" {
+		wanted := make(map[string]bool)
+		for _, name := range strings.Split(names, ",") {
+			wanted[strings.TrimSpace(name)] = true
+		}
+		checks = nil
+		for _, check := range c.checks {
+			if wanted[check.Name] {
+				checks = append(checks, check)
+				delete(wanted, check.Name)
+			}
+		}
+		if len(wanted) > 0 {
+			unknown := make([]string, 0, len(wanted))
+			for name := range wanted {
+				unknown = append(unknown, name)
+			}
+			sort.Strings(unknown)
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown check: "+strings.Join(unknown, ", "))
+		}
+	}
+	return ctx.JSON(http.StatusOK, diagnostics.Run(ctx.Request().Context(), checks, diagnostics.DefaultTimeout))
+}
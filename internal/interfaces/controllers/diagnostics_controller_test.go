@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+)
+
+func TestDiagnosticsController_RunDiagnostics(t *testing.T) {
+	controller := NewDiagnosticsController([]diagnostics.Check{
+		diagnostics.Func("kubernetes", "", func(context.Context) (string, error) { return "connected", nil }),
+		diagnostics.Func("redis", "check redis.addr", func(context.Context) (string, error) { return "", errors.New("connection refused") }),
+	})
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/admin/diagnostics", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.RunDiagnostics(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report diagnostics.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, diagnostics.StatusFail, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, "check redis.addr", report.Checks[1].Remediation)
+
+	c, rec = makeMemoryEchoContext(t, http.MethodGet, "/admin/diagnostics?check=kubernetes", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.RunDiagnostics(c))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, diagnostics.StatusPass, report.Status)
+	assert.Len(t, report.Checks, 1)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/admin/diagnostics?check=smtp", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.RunDiagnostics(c), http.StatusBadRequest)
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// EditorController proxies the in-browser editor (code-server) sidecar of a
+// session. The sidecar runs without its own authentication, so every request
+// is authorized here before it is forwarded.
+type EditorController struct {
+	sessionManagerProvider SessionManagerProvider
+	// editorURL resolves the editor base URL of a session. Overridden in tests.
+	editorURL func(entities.Session) (string, bool)
+}
+
+// NewEditorController creates a new EditorController
+func NewEditorController(sessionManagerProvider SessionManagerProvider) *EditorController {
+	return &EditorController{
+		sessionManagerProvider: sessionManagerProvider,
+		editorURL:              kubernetesEditorURL,
+	}
+}
+
+// GetName returns the name of this controller for logging
+func (c *EditorController) GetName() string {
+	return "EditorController"
+}
+
+func kubernetesEditorURL(session entities.Session) (string, bool) {
+	ks, ok := session.(*services.KubernetesSession)
+	if !ok || !ks.EditorEnabled() {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s:%d", ks.Host(), services.EditorPort), true
+}
+
+// ProxyEditor handles /sessions/:sessionId/editor and everything below it,
+// including the WebSocket connections code-server relies on.
+func (c *EditorController) ProxyEditor(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	baseURL, ok := c.editorURL(session)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Editor is not enabled for this session")
+	}
+	target, err := url.Parse(baseURL)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Invalid editor URL: %v", err))
+	}
+
+	// code-server serves assets with relative URLs, so the editor root must
+	// end with a slash.
+	prefix := "/sessions/" + sessionID + "/editor"
+	if ctx.Request().URL.Path == prefix {
+		location := prefix + "/"
+		if ctx.Request().URL.RawQuery != "" {
+			location += "?" + ctx.Request().URL.RawQuery
+		}
+		return ctx.Redirect(http.StatusFound, location)
+	}
+
+	return proxyToSidecar(ctx, c.sessionManagerProvider.GetSessionManager(), session, prefix, target, "Editor")
+}
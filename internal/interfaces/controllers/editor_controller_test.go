@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func newTestEditorController(baseURL string) *EditorController {
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	c := NewEditorController(&mockWaitProvider{manager: newMockWaitSessionManager(session)})
+	c.editorURL = func(entities.Session) (string, bool) { return baseURL, baseURL != "" }
+	return c
+}
+
+func TestEditorController_ProxiesToSidecar(t *testing.T) {
+	editor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/static/app.js", r.URL.Path)
+		assert.Equal(t, "v=1", r.URL.RawQuery)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("X-API-Key"))
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer editor.Close()
+
+	c := newTestEditorController(editor.URL)
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/editor/static/app.js?v=1", "sess-1", "alice")
+	ctx.Request().Header.Set("Authorization", "Bearer secret")
+	ctx.Request().Header.Set("X-API-Key", "secret")
+	require.NoError(t, c.ProxyEditor(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestEditorController_RedirectsToTrailingSlash(t *testing.T) {
+	c := newTestEditorController("http://editor.invalid")
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/editor?folder=/tmp", "sess-1", "alice")
+	require.NoError(t, c.ProxyEditor(ctx))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "/sessions/sess-1/editor/?folder=/tmp", rec.Header().Get("Location"))
+}
+
+func TestEditorController_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		sessionID  string
+		userID     string
+		wantStatus int
+	}{
+		{"unknown session", "http://editor.invalid", "missing", "alice", http.StatusNotFound},
+		{"other user", "http://editor.invalid", "sess-1", "bob", http.StatusForbidden},
+		{"editor disabled", "", "sess-1", "alice", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestEditorController(tt.baseURL)
+			ctx, _ := makeWorkspaceEchoContext("/sessions/"+tt.sessionID+"/editor/", tt.sessionID, tt.userID)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, c.ProxyEditor(ctx), &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
@@ -32,7 +32,9 @@ type ESMHeartbeatRequest struct {
 	ActiveSessions int    `json:"active_sessions,omitempty"`
 }
 
-type esmRegistrationResponse struct {
+// ESMRegistrationResponse is the response of a registration, which tells
+// whether the manager was created or updated
+type ESMRegistrationResponse struct {
 	ExternalSessionManagerResponse
 	Created bool `json:"created"`
 }
@@ -95,7 +97,7 @@ func (c *SettingsController) RegisterExternalSessionManager(ctx echo.Context) er
 	if err := c.repo.Save(ctx.Request().Context(), settings); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save external session manager")
 	}
-	return ctx.JSON(http.StatusOK, esmRegistrationResponse{ExternalSessionManagerResponse: esmResponse(*manager, connectionToken), Created: created})
+	return ctx.JSON(http.StatusOK, ESMRegistrationResponse{ExternalSessionManagerResponse: esmResponse(*manager, connectionToken), Created: created})
 }
 
 func (c *SettingsController) ListExternalSessionManagers(ctx echo.Context) error {
@@ -40,14 +40,14 @@ func TestExternalSessionManagerRegistrationIsIdempotentAndHeartbeatUsesToken(t *
 	ctx, rec := esmTestContext(e, http.MethodPost, "/external-session-managers", body, "user1")
 	require.NoError(t, controller.RegisterExternalSessionManager(ctx))
 	require.Equal(t, http.StatusOK, rec.Code)
-	var created esmRegistrationResponse
+	var created ESMRegistrationResponse
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
 	require.True(t, created.Created)
 	require.NotEmpty(t, created.ConnectionToken)
 
 	ctx, rec = esmTestContext(e, http.MethodPost, "/external-session-managers", body, "user1")
 	require.NoError(t, controller.RegisterExternalSessionManager(ctx))
-	var repeated esmRegistrationResponse
+	var repeated ESMRegistrationResponse
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &repeated))
 	require.False(t, repeated.Created)
 	require.Equal(t, created.ID, repeated.ID)
@@ -74,7 +74,7 @@ func TestExternalSessionManagerHeartbeatRejectsUnreachablePublicURL(t *testing.T
 	body := ESMRegistrationRequest{InstanceID: "machine-2", Name: "native-2"}
 	ctx, rec := esmTestContext(e, http.MethodPost, "/external-session-managers", body, "user1")
 	require.NoError(t, controller.RegisterExternalSessionManager(ctx))
-	var created esmRegistrationResponse
+	var created ESMRegistrationResponse
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
 
 	heartbeat := ESMHeartbeatRequest{PublicURL: "http://127.0.0.1:1"}
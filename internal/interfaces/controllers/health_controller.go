@@ -7,11 +7,14 @@ import (
 )
 
 // HealthController handles health check endpoints
-type HealthController struct{}
+type HealthController struct {
+	draining func() bool
+}
 
-// NewHealthController creates a new HealthController instance
-func NewHealthController() *HealthController {
-	return &HealthController{}
+// NewHealthController creates a new HealthController instance. draining
+// reports whether the server is shutting down; it may be nil.
+func NewHealthController(draining func() bool) *HealthController {
+	return &HealthController{draining: draining}
 }
 
 // GetName returns the name of this controller for logging
@@ -25,3 +28,17 @@ func (c *HealthController) HealthCheck(ctx echo.Context) error {
 		"status": "ok",
 	})
 }
+
+// ReadinessCheck handles GET /ready requests. It fails once the server is
+// shutting down so that load balancers stop routing new requests to it while
+// in-flight requests complete.
+func (c *HealthController) ReadinessCheck(ctx echo.Context) error {
+	if c.draining != nil && c.draining() {
+		return ctx.JSON(http.StatusServiceUnavailable, map[string]string{
+			"status": "draining",
+		})
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
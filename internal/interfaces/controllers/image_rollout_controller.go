@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// ImageRolloutManager starts, reports and cancels image rollouts
+type ImageRolloutManager interface {
+	StartImageRollout(ctx context.Context, req entities.ImageRolloutRequest, actor string) (*entities.ImageRollout, error)
+	GetImageRollout(ctx context.Context) (*entities.ImageRollout, error)
+	CancelImageRollout(ctx context.Context, actor string) (*entities.ImageRollout, error)
+}
+
+// ImageRolloutController handles the rollout of updated images to running
+// sessions requested by administrators
+type ImageRolloutController struct {
+	manager ImageRolloutManager
+}
+
+// NewImageRolloutController creates a new ImageRolloutController
+func NewImageRolloutController(manager ImageRolloutManager) *ImageRolloutController {
+	return &ImageRolloutController{manager: manager}
+}
+
+// GetName returns the name of this controller for logging
+func (c *ImageRolloutController) GetName() string {
+	return "ImageRolloutController"
+}
+
+// StartImageRollout handles POST /admin/image-rollout. It responds 202 with
+// the outdated sessions the rollout will upgrade, or 409 while another
+// rollout is running.
+func (c *ImageRolloutController) StartImageRollout(ctx echo.Context) error {
+	var req entities.ImageRolloutRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	rollout, err := c.manager.StartImageRollout(ctx.Request().Context(), req, rolloutActor(ctx))
+	if err != nil {
+		return imageRolloutError(err)
+	}
+	return ctx.JSON(http.StatusAccepted, rollout)
+}
+
+// GetImageRollout handles GET /admin/image-rollout. It returns the state of
+// the current or last rollout and of each of its sessions.
+func (c *ImageRolloutController) GetImageRollout(ctx echo.Context) error {
+	rollout, err := c.manager.GetImageRollout(ctx.Request().Context())
+	if err != nil {
+		return imageRolloutError(err)
+	}
+	return ctx.JSON(http.StatusOK, rollout)
+}
+
+// CancelImageRollout handles DELETE /admin/image-rollout. Sessions already
+// upgraded keep their new image.
+func (c *ImageRolloutController) CancelImageRollout(ctx echo.Context) error {
+	rollout, err := c.manager.CancelImageRollout(ctx.Request().Context(), rolloutActor(ctx))
+	if err != nil {
+		return imageRolloutError(err)
+	}
+	return ctx.JSON(http.StatusOK, rollout)
+}
+
+// rolloutActor returns the user starting or cancelling a rollout
+func rolloutActor(ctx echo.Context) string {
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		return string(user.ID())
+	}
+	return "anonymous"
+}
+
+// imageRolloutError maps a rollout error to its HTTP error
+func imageRolloutError(err error) error {
+	switch {
+	case errors.Is(err, entities.ErrInvalidImageRollout):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, entities.ErrImageRolloutNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "No image rollout was started")
+	case errors.Is(err, entities.ErrImageRolloutInProgress):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, entities.ErrImageRolloutUnsupported):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	log.Printf("Image rollout failed: %v", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "Image rollout failed")
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+type fakeImageRolloutManager struct {
+	rollout *entities.ImageRollout
+	req     entities.ImageRolloutRequest
+}
+
+func (f *fakeImageRolloutManager) StartImageRollout(_ context.Context, req entities.ImageRolloutRequest, actor string) (*entities.ImageRollout, error) {
+	if req.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("%w: max_concurrent must not be negative", entities.ErrInvalidImageRollout)
+	}
+	if f.rollout != nil && f.rollout.State == entities.ImageRolloutRunning {
+		return nil, entities.ErrImageRolloutInProgress
+	}
+	f.req = req
+	f.rollout = &entities.ImageRollout{ID: "r1", State: entities.ImageRolloutRunning, StartedBy: actor, MaxConcurrent: req.MaxConcurrent}
+	return f.rollout, nil
+}
+
+func (f *fakeImageRolloutManager) GetImageRollout(context.Context) (*entities.ImageRollout, error) {
+	if f.rollout == nil {
+		return nil, entities.ErrImageRolloutNotFound
+	}
+	return f.rollout, nil
+}
+
+func (f *fakeImageRolloutManager) CancelImageRollout(context.Context, string) (*entities.ImageRollout, error) {
+	if f.rollout == nil {
+		return nil, entities.ErrImageRolloutNotFound
+	}
+	f.rollout.State = entities.ImageRolloutCancelled
+	return f.rollout, nil
+}
+
+func TestImageRolloutController(t *testing.T) {
+	manager := &fakeImageRolloutManager{}
+	controller := NewImageRolloutController(manager)
+	admin := newTestAdminUser("admin")
+
+	c, _ := makeMemoryEchoContext(t, http.MethodGet, "/admin/image-rollout", nil, admin)
+	assertHTTPError(t, controller.GetImageRollout(c), http.StatusNotFound)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodPost, "/admin/image-rollout", map[string]interface{}{"max_concurrent": -1}, admin)
+	assertHTTPError(t, controller.StartImageRollout(c), http.StatusBadRequest)
+
+	body := map[string]interface{}{"max_concurrent": 2, "pinned_teams": []string{"acme/ml"}}
+	c, rec := makeMemoryEchoContext(t, http.MethodPost, "/admin/image-rollout", body, admin)
+	require.NoError(t, controller.StartImageRollout(c))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, []string{"acme/ml"}, manager.req.PinnedTeams)
+	var resp entities.ImageRollout
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "admin", resp.StartedBy)
+	assert.Equal(t, 2, resp.MaxConcurrent)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodPost, "/admin/image-rollout", nil, admin)
+	assertHTTPError(t, controller.StartImageRollout(c), http.StatusConflict)
+
+	c, rec = makeMemoryEchoContext(t, http.MethodDelete, "/admin/image-rollout", nil, admin)
+	require.NoError(t, controller.CancelImageRollout(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	resp = entities.ImageRollout{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, entities.ImageRolloutCancelled, resp.State)
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/llmproxy"
+)
+
+// LLMProxyController exposes the egress LLM proxy to session Pods.
+// Pods authenticate with their session API key; the controller verifies the
+// caller can access the session before forwarding to the upstream provider.
+type LLMProxyController struct {
+	sessionManagerProvider SessionManagerProvider
+	proxy                  *llmproxy.Proxy
+}
+
+// LLMUsageResponse is the response body for GET /sessions/:sessionId/llm-usage.
+type LLMUsageResponse struct {
+	SessionID string `json:"session_id"`
+	llmproxy.SessionUsage
+	TotalTokens int64 `json:"total_tokens"`
+}
+
+// NewLLMProxyController creates a new LLMProxyController
+func NewLLMProxyController(sessionManagerProvider SessionManagerProvider, proxy *llmproxy.Proxy) *LLMProxyController {
+	return &LLMProxyController{
+		sessionManagerProvider: sessionManagerProvider,
+		proxy:                  proxy,
+	}
+}
+
+// GetName returns the name of this controller for logging
+func (c *LLMProxyController) GetName() string {
+	return "LLMProxyController"
+}
+
+// ProxyRequest handles ANY /llm-proxy/:sessionId/:provider/*
+func (c *LLMProxyController) ProxyRequest(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	providerType := llmproxy.ProviderType(ctx.Param("provider"))
+
+	if !c.proxy.HasProvider(providerType) {
+		return echo.NewHTTPError(http.StatusNotFound, "LLM provider not configured")
+	}
+
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	info := llmproxy.RequestInfo{SessionID: sessionID, UserID: session.UserID()}
+	if user := auth.GetUserFromContext(ctx); user != nil {
+		info.UserID = string(user.ID())
+	}
+
+	c.proxy.Forward(ctx.Response(), ctx.Request(), providerType, "/"+ctx.Param("*"), info)
+	return nil
+}
+
+// GetSessionUsage handles GET /sessions/:sessionId/llm-usage
+func (c *LLMProxyController) GetSessionUsage(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	usage, _ := c.proxy.Usage().Get(sessionID)
+	return ctx.JSON(http.StatusOK, LLMUsageResponse{
+		SessionID:    sessionID,
+		SessionUsage: usage,
+		TotalTokens:  usage.TotalTokens(),
+	})
+}
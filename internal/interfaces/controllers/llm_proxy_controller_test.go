@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/llmproxy"
+)
+
+func newTestLLMProxyController(t *testing.T, upstreamURL string) *LLMProxyController {
+	t.Helper()
+	proxy, err := llmproxy.New(llmproxy.Options{
+		Providers: []llmproxy.Provider{
+			{Name: "primary", Type: llmproxy.ProviderAnthropic, BaseURL: upstreamURL, APIKey: "upstream-key"},
+		},
+		Recorder: func(llmproxy.Record) {},
+	})
+	require.NoError(t, err)
+
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	provider := &mockWaitProvider{manager: newMockWaitSessionManager(session)}
+	return NewLLMProxyController(provider, proxy)
+}
+
+func makeLLMProxyEchoContext(sessionID, providerType, path, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/llm-proxy/"+sessionID+"/"+providerType+"/"+path, strings.NewReader(`{"model":"claude"}`))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sessionId", "provider", "*")
+	c.SetParamValues(sessionID, providerType, path)
+	c.Set("authz_context", &auth.AuthorizationContext{
+		PersonalScope: auth.PersonalScopeAuth{UserID: userID, CanRead: true},
+		TeamScope:     auth.TeamScopeAuth{TeamPermissions: make(map[string]auth.TeamPermissions)},
+	})
+	return c, rec
+}
+
+func TestLLMProxyController_ProxyRequest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "upstream-key", r.Header.Get("x-api-key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"usage":{"input_tokens":3,"output_tokens":2}}`)
+	}))
+	defer upstream.Close()
+
+	c := newTestLLMProxyController(t, upstream.URL)
+
+	ctx, rec := makeLLMProxyEchoContext("sess-1", "anthropic", "v1/messages", "alice")
+	require.NoError(t, c.ProxyRequest(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	usageCtx, usageRec := makeLLMProxyEchoContext("sess-1", "", "", "alice")
+	require.NoError(t, c.GetSessionUsage(usageCtx))
+	var resp LLMUsageResponse
+	require.NoError(t, json.Unmarshal(usageRec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Requests)
+	assert.Equal(t, int64(5), resp.TotalTokens)
+}
+
+func TestLLMProxyController_ProxyRequest_Errors(t *testing.T) {
+	c := newTestLLMProxyController(t, "http://127.0.0.1:1")
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		provider   string
+		userID     string
+		wantStatus int
+	}{
+		{"unknown provider", "sess-1", "openai", "alice", http.StatusNotFound},
+		{"unknown session", "missing", "anthropic", "alice", http.StatusNotFound},
+		{"other user", "sess-1", "anthropic", "bob", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := makeLLMProxyEchoContext(tt.sessionID, tt.provider, "v1/messages", tt.userID)
+			err := c.ProxyRequest(ctx)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
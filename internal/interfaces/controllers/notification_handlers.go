@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
@@ -44,6 +46,13 @@ func (h *NotificationHandlers) Subscribe(c echo.Context) error {
 	if req.Endpoint == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Endpoint is required")
 	}
+	switch req.Type {
+	case "", notification.SubscriptionTypeWebPush:
+	case notification.SubscriptionTypeDiscord, notification.SubscriptionTypeTeams:
+		return h.subscribeWebhook(c, user, req)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported subscription type: %s", req.Type))
+	}
 	if req.Keys == nil || req.Keys["p256dh"] == "" || req.Keys["auth"] == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Keys with p256dh and auth are required")
 	}
@@ -63,6 +72,23 @@ func (h *NotificationHandlers) Subscribe(c echo.Context) error {
 	})
 }
 
+// subscribeWebhook subscribes the Discord or Microsoft Teams webhook of a
+// POST /notification/subscribe request
+func (h *NotificationHandlers) subscribeWebhook(c echo.Context, user *entities.User, req notification.SubscribeRequest) error {
+	sub, err := h.service.SubscribeWebhook(user, req.Type, req.Endpoint)
+	if err != nil {
+		if errors.Is(err, notification.ErrInvalidWebhookURL) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create subscription")
+	}
+
+	return c.JSON(http.StatusOK, notification.SubscribeResponse{
+		Success:        true,
+		SubscriptionID: sub.ID,
+	})
+}
+
 // GetSubscriptions handles GET /notification/subscribe
 func (h *NotificationHandlers) GetSubscriptions(c echo.Context) error {
 	user := auth.GetUserFromContext(c)
@@ -78,6 +104,37 @@ func (h *NotificationHandlers) GetSubscriptions(c echo.Context) error {
 	return c.JSON(http.StatusOK, subscriptions)
 }
 
+// UpdateSubscription handles PATCH /notification/subscribe. It changes the
+// sessions and notification types the subscription with the given endpoint
+// receives.
+func (h *NotificationHandlers) UpdateSubscription(c echo.Context) error {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	var req notification.UpdateSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if req.Endpoint == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Endpoint is required")
+	}
+
+	sub, err := h.service.UpdateSubscription(string(user.ID()), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, notification.ErrUnknownNotificationType):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, notification.ErrSubscriptionNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Subscription not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update subscription")
+	}
+
+	return c.JSON(http.StatusOK, sub)
+}
+
 // DeleteSubscription handles DELETE /notification/subscribe
 func (h *NotificationHandlers) DeleteSubscription(c echo.Context) error {
 	user := auth.GetUserFromContext(c)
@@ -264,6 +321,9 @@ func (h *NotificationHandlers) GetHistory(c echo.Context) error {
 	if notificationType := c.QueryParam("type"); notificationType != "" {
 		filters["type"] = notificationType
 	}
+	if status := c.QueryParam("status"); status != "" {
+		filters["status"] = status
+	}
 
 	// Get history
 	history, err := h.service.GetNotificationHistory(string(user.ID()), limit, offset, filters)
@@ -273,3 +333,57 @@ func (h *NotificationHandlers) GetHistory(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, history)
 }
+
+// PreviewTemplates handles POST /notifications/templates/preview
+func (h *NotificationHandlers) PreviewTemplates(c echo.Context) error {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	var req notification.TemplatePreviewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	previews, err := h.service.PreviewTemplates(string(user.ID()), req)
+	if err != nil {
+		if errors.Is(err, notification.ErrUnknownEventType) || errors.Is(err, notification.ErrUnsupportedLocale) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render notification templates")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"previews": previews,
+	})
+}
+
+// SendTestNotification handles POST /notifications/test
+func (h *NotificationHandlers) SendTestNotification(c echo.Context) error {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	var req notification.TestNotificationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if req.Type != "" && !slices.Contains(notification.SubscriptionTypes, req.Type) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unsupported subscription type: %s", req.Type))
+	}
+
+	resp, err := h.service.SendTestNotification(string(user.ID()), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, notification.ErrUnknownEventType), errors.Is(err, notification.ErrUnsupportedLocale):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, notification.ErrNoMatchingSubscription):
+			return echo.NewHTTPError(http.StatusNotFound, "No matching subscription")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to send test notification: %v", err))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// errSessionObserverMode is reported for sessions observer mode keeps the
+// caller from changing
+var errSessionObserverMode = errors.New("the session is in observer mode; only its owner can change it")
+
+// sessionObserverModeReader reads the observer mode of a session from its
+// store
+type sessionObserverModeReader interface {
+	SessionObserverMode(ctx context.Context, sessionID string) bool
+}
+
+// observerModeBlocks reports whether observer mode keeps user from changing
+// session. Like the observer mode middleware of the /sessions routes, it
+// leaves the owner and admins alone.
+func observerModeBlocks(ctx context.Context, manager repositories.SessionManager, user *entities.User, session entities.Session) bool {
+	if user == nil || user.CanAccessSession(entities.UserID(session.UserID())) {
+		return false
+	}
+	reader, ok := manager.(sessionObserverModeReader)
+	return ok && reader.SessionObserverMode(ctx, session.ID())
+}
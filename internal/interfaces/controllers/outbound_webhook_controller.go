@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/outboundwebhook"
+)
+
+// OutboundWebhookController manages webhooks that receive session lifecycle
+// events and exposes their delivery logs
+type OutboundWebhookController struct {
+	store      outboundwebhook.Store
+	dispatcher *outboundwebhook.Dispatcher
+}
+
+// NewOutboundWebhookController creates a new OutboundWebhookController
+func NewOutboundWebhookController(store outboundwebhook.Store, dispatcher *outboundwebhook.Dispatcher) *OutboundWebhookController {
+	return &OutboundWebhookController{store: store, dispatcher: dispatcher}
+}
+
+// GetName returns the name of this controller for logging
+func (c *OutboundWebhookController) GetName() string {
+	return "OutboundWebhookController"
+}
+
+// OutboundWebhookRequest is the request body for creating and updating
+// outbound webhooks. On update, omitted fields are left unchanged.
+type OutboundWebhookRequest struct {
+	Name   *string                `json:"name,omitempty"`
+	URL    *string                `json:"url,omitempty"`
+	Secret *string                `json:"secret,omitempty"`
+	Events []string               `json:"events,omitempty"`
+	Scope  entities.ResourceScope `json:"scope,omitempty"`
+	TeamID string                 `json:"team_id,omitempty"`
+	Active *bool                  `json:"active,omitempty"`
+}
+
+// OutboundWebhookResponse is an outbound webhook without its secret. The
+// secret is only returned when the webhook is created.
+type OutboundWebhookResponse struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	URL       string                 `json:"url"`
+	Secret    string                 `json:"secret,omitempty"`
+	Events    []string               `json:"events"`
+	Scope     entities.ResourceScope `json:"scope"`
+	UserID    string                 `json:"user_id"`
+	TeamID    string                 `json:"team_id,omitempty"`
+	Active    bool                   `json:"active"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+func toOutboundWebhookResponse(w *outboundwebhook.Webhook) OutboundWebhookResponse {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return OutboundWebhookResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    events,
+		Scope:     w.Scope,
+		UserID:    w.UserID,
+		TeamID:    w.TeamID,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+// CreateWebhook handles POST /outbound-webhooks
+func (c *OutboundWebhookController) CreateWebhook(ctx echo.Context) error {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	var req OutboundWebhookRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if req.Name == nil || *req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+	if req.URL == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "URL is required")
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = entities.ScopeUser
+	}
+	switch scope {
+	case outboundwebhook.ScopeGlobal:
+		if !user.IsAdmin() {
+			return echo.NewHTTPError(http.StatusForbidden, "Only administrators can create global webhooks")
+		}
+	case entities.ScopeTeam:
+		if req.TeamID == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "team_id is required when scope is 'team'")
+		}
+		if authzCtx := auth.GetAuthorizationContext(ctx); !user.IsAdmin() && (authzCtx == nil || !authzCtx.CanCreateInTeam(req.TeamID)) {
+			return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this team")
+		}
+	case entities.ScopeUser:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid scope: %s", scope))
+	}
+
+	now := time.Now().UTC()
+	w := &outboundwebhook.Webhook{
+		ID:        uuid.New().String(),
+		Name:      *req.Name,
+		Scope:     scope,
+		UserID:    string(user.ID()),
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if scope == entities.ScopeTeam {
+		w.TeamID = req.TeamID
+	}
+	if err := applyOutboundWebhookRequest(w, req); err != nil {
+		return err
+	}
+	if w.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate webhook secret")
+		}
+		w.Secret = secret
+	}
+
+	if err := c.store.Create(ctx.Request().Context(), w); err != nil {
+		log.Printf("Failed to create outbound webhook: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create outbound webhook")
+	}
+	log.Printf("Created outbound webhook %s (%s scope) for user %s", w.ID, w.Scope, w.UserID)
+
+	resp := toOutboundWebhookResponse(w)
+	resp.Secret = w.Secret
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+// ListWebhooks handles GET /outbound-webhooks. Users see their own and
+// their teams' webhooks; administrators also see global webhooks.
+func (c *OutboundWebhookController) ListWebhooks(ctx echo.Context) error {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	webhooks, err := c.store.List(ctx.Request().Context())
+	if err != nil {
+		log.Printf("Failed to list outbound webhooks: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list outbound webhooks")
+	}
+	visible := make([]OutboundWebhookResponse, 0, len(webhooks))
+	for _, w := range webhooks {
+		if canAccessOutboundWebhook(user, w) {
+			visible = append(visible, toOutboundWebhookResponse(w))
+		}
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"webhooks": visible,
+		"total":    len(visible),
+	})
+}
+
+// GetWebhook handles GET /outbound-webhooks/:id
+func (c *OutboundWebhookController) GetWebhook(ctx echo.Context) error {
+	w, err := c.accessibleWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, toOutboundWebhookResponse(w))
+}
+
+// UpdateWebhook handles PUT /outbound-webhooks/:id. The scope of a webhook
+// cannot be changed.
+func (c *OutboundWebhookController) UpdateWebhook(ctx echo.Context) error {
+	w, err := c.accessibleWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req OutboundWebhookRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if (req.Scope != "" && req.Scope != w.Scope) || (req.TeamID != "" && req.TeamID != w.TeamID) {
+		return echo.NewHTTPError(http.StatusBadRequest, "The scope of a webhook cannot be changed")
+	}
+	if req.Name != nil {
+		if *req.Name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+		}
+		w.Name = *req.Name
+	}
+	if err := applyOutboundWebhookRequest(w, req); err != nil {
+		return err
+	}
+	w.UpdatedAt = time.Now().UTC()
+
+	if err := c.store.Update(ctx.Request().Context(), w); err != nil {
+		log.Printf("Failed to update outbound webhook %s: %v", w.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update outbound webhook")
+	}
+	return ctx.JSON(http.StatusOK, toOutboundWebhookResponse(w))
+}
+
+// DeleteWebhook handles DELETE /outbound-webhooks/:id
+func (c *OutboundWebhookController) DeleteWebhook(ctx echo.Context) error {
+	w, err := c.accessibleWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.store.Delete(ctx.Request().Context(), w.ID); err != nil && !errors.Is(err, outboundwebhook.ErrNotFound) {
+		log.Printf("Failed to delete outbound webhook %s: %v", w.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete outbound webhook")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /outbound-webhooks/:id/deliveries. Attempts are
+// returned newest first; limit defaults to 50 and is capped at
+// outboundwebhook.MaxDeliveryLogs.
+func (c *OutboundWebhookController) ListDeliveries(ctx echo.Context) error {
+	w, err := c.accessibleWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 50
+	if limitStr := ctx.QueryParam("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= outboundwebhook.MaxDeliveryLogs {
+			limit = l
+		}
+	}
+	logs, err := c.store.ListLogs(ctx.Request().Context(), w.ID, limit)
+	if err != nil {
+		log.Printf("Failed to list deliveries of outbound webhook %s: %v", w.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list deliveries")
+	}
+	if logs == nil {
+		logs = []outboundwebhook.DeliveryLog{}
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries": logs,
+		"total":      len(logs),
+	})
+}
+
+// TestWebhook handles POST /outbound-webhooks/:id/test. A ping event is sent
+// right away, even to a disabled webhook, and the attempt is returned.
+func (c *OutboundWebhookController) TestWebhook(ctx echo.Context) error {
+	w, err := c.accessibleWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := outboundwebhook.Event{
+		ID:        uuid.New().String(),
+		Type:      outboundwebhook.EventPing,
+		Timestamp: time.Now().UTC(),
+		Message:   "Test delivery from agentapi-proxy",
+	}
+	_ = c.dispatcher.Send(ctx.Request().Context(), w, event)
+
+	logs, err := c.store.ListLogs(ctx.Request().Context(), w.ID, 1)
+	if err != nil || len(logs) == 0 || logs[0].EventID != event.ID {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to record test delivery")
+	}
+	return ctx.JSON(http.StatusOK, logs[0])
+}
+
+// accessibleWebhook loads the webhook named by the id parameter and checks
+// that the current user may manage it
+func (c *OutboundWebhookController) accessibleWebhook(ctx echo.Context) (*outboundwebhook.Webhook, error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+	w, err := c.store.Get(ctx.Request().Context(), ctx.Param("id"))
+	if errors.Is(err, outboundwebhook.ErrNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Outbound webhook not found")
+	}
+	if err != nil {
+		log.Printf("Failed to get outbound webhook %s: %v", ctx.Param("id"), err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get outbound webhook")
+	}
+	if !canAccessOutboundWebhook(user, w) {
+		// Do not reveal webhooks of other users
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Outbound webhook not found")
+	}
+	return w, nil
+}
+
+func canAccessOutboundWebhook(user *entities.User, w *outboundwebhook.Webhook) bool {
+	if w.Scope == outboundwebhook.ScopeGlobal {
+		return user.IsAdmin()
+	}
+	return user.CanAccessResource(entities.UserID(w.UserID), string(w.Scope), w.TeamID)
+}
+
+// applyOutboundWebhookRequest copies the URL, secret, events and active flag
+// of req to w after validating them
+func applyOutboundWebhookRequest(w *outboundwebhook.Webhook, req OutboundWebhookRequest) error {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "URL must be an absolute http or https URL")
+		}
+		w.URL = *req.URL
+	}
+	if req.Secret != nil {
+		w.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		for _, e := range req.Events {
+			if !isOutboundEventType(e) {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown event type: %s", e))
+			}
+		}
+		w.Events = req.Events
+	}
+	if req.Active != nil {
+		w.Active = *req.Active
+	}
+	return nil
+}
+
+func isOutboundEventType(eventType string) bool {
+	for _, e := range outboundwebhook.EventTypes {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
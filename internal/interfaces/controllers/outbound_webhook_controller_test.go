@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/outboundwebhook"
+)
+
+func newTestOutboundWebhookController(t *testing.T) *OutboundWebhookController {
+	t.Helper()
+	store, err := outboundwebhook.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	return NewOutboundWebhookController(store, outboundwebhook.NewDispatcher(store, nil))
+}
+
+func createTestOutboundWebhook(t *testing.T, controller *OutboundWebhookController, body map[string]interface{}) OutboundWebhookResponse {
+	t.Helper()
+	c, rec := makeMemoryEchoContext(t, http.MethodPost, "/outbound-webhooks", body, newTestGitHubUser("alice", "acme", "dev"))
+	require.NoError(t, controller.CreateWebhook(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp OutboundWebhookResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestOutboundWebhookController_Create(t *testing.T) {
+	controller := newTestOutboundWebhookController(t)
+
+	resp := createTestOutboundWebhook(t, controller, map[string]interface{}{
+		"name":   "ci",
+		"url":    "https://example.com/hook",
+		"events": []string{outboundwebhook.EventSessionFailed},
+	})
+	assert.Equal(t, "alice", resp.UserID)
+	assert.Equal(t, "user", string(resp.Scope))
+	assert.True(t, resp.Active)
+	assert.Len(t, resp.Secret, 64, "a secret is generated and returned once")
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/outbound-webhooks", nil, newTestGitHubUser("alice", "acme", "dev"))
+	require.NoError(t, controller.ListWebhooks(c))
+	assert.NotContains(t, rec.Body.String(), resp.Secret)
+}
+
+func TestOutboundWebhookController_Create_Validation(t *testing.T) {
+	controller := newTestOutboundWebhookController(t)
+	user := newTestGitHubUser("alice", "acme", "dev")
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		wantCode int
+	}{
+		{name: "Missing URL", body: map[string]interface{}{"name": "x"}, wantCode: http.StatusBadRequest},
+		{name: "Relative URL", body: map[string]interface{}{"name": "x", "url": "/hook"}, wantCode: http.StatusBadRequest},
+		{name: "Unknown event", body: map[string]interface{}{"name": "x", "url": "https://example.com", "events": []string{"session.exploded"}}, wantCode: http.StatusBadRequest},
+		{name: "Global scope without admin", body: map[string]interface{}{"name": "x", "url": "https://example.com", "scope": "global"}, wantCode: http.StatusForbidden},
+		{name: "Other team", body: map[string]interface{}{"name": "x", "url": "https://example.com", "scope": "team", "team_id": "acme/ops"}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := makeMemoryEchoContext(t, http.MethodPost, "/outbound-webhooks", tt.body, user)
+			assertHTTPError(t, controller.CreateWebhook(c), tt.wantCode)
+		})
+	}
+}
+
+func TestOutboundWebhookController_Access(t *testing.T) {
+	controller := newTestOutboundWebhookController(t)
+	created := createTestOutboundWebhook(t, controller, map[string]interface{}{"name": "mine", "url": "https://example.com/hook"})
+
+	c, _ := makeMemoryEchoContext(t, http.MethodGet, "/outbound-webhooks/"+created.ID, nil, newTestGitHubUser("bob", "acme", "dev"))
+	c.SetParamNames("id")
+	c.SetParamValues(created.ID)
+	assertHTTPError(t, controller.GetWebhook(c), http.StatusNotFound)
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/outbound-webhooks", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.ListWebhooks(c))
+	assert.Contains(t, rec.Body.String(), `"total":0`, "admins do not see other users' personal webhooks")
+
+	c, rec = makeMemoryEchoContext(t, http.MethodPut, "/outbound-webhooks/"+created.ID, map[string]interface{}{"active": false}, newTestGitHubUser("alice", "acme", "dev"))
+	c.SetParamNames("id")
+	c.SetParamValues(created.ID)
+	require.NoError(t, controller.UpdateWebhook(c))
+	assert.Contains(t, rec.Body.String(), `"active":false`)
+}
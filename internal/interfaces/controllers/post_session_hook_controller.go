@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// PostSessionHookController serves the post-session hook output of oneshot
+// sessions. The session is usually deleted by then, so access is authorized
+// against the owner recorded in the report instead of the live session.
+type PostSessionHookController struct {
+	artifactStore services.ArtifactStore
+}
+
+// NewPostSessionHookController creates a new PostSessionHookController.
+// artifactStore may be nil when the asset backend has no artifact support.
+func NewPostSessionHookController(artifactStore services.ArtifactStore) *PostSessionHookController {
+	return &PostSessionHookController{artifactStore: artifactStore}
+}
+
+// GetName returns the name of this controller for logging
+func (c *PostSessionHookController) GetName() string {
+	return "PostSessionHookController"
+}
+
+// authorizedReport loads the report of the session and checks that the
+// caller may access the session it belongs to.
+func (c *PostSessionHookController) authorizedReport(ctx echo.Context) (*entities.PostSessionReport, error) {
+	if c.artifactStore == nil {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "Post-session hooks are not configured")
+	}
+	sessionID := ctx.Param("sessionId")
+	body, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), services.PostSessionHookPrefix(sessionID)+services.PostSessionReportName)
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "No post-session hook report for this session")
+	}
+	if err != nil {
+		log.Printf("Failed to open post-session report of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read post-session report")
+	}
+	defer func() { _ = body.Close() }()
+	var report entities.PostSessionReport
+	if err := json.NewDecoder(body).Decode(&report); err != nil {
+		log.Printf("Failed to decode post-session report of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read post-session report")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(report.UserID, string(report.Scope), report.TeamID) {
+		// Do not reveal whether the session had hooks.
+		return nil, echo.NewHTTPError(http.StatusNotFound, "No post-session hook report for this session")
+	}
+	return &report, nil
+}
+
+// GetReport handles GET /sessions/:sessionId/post-session-hooks.
+func (c *PostSessionHookController) GetReport(ctx echo.Context) error {
+	report, err := c.authorizedReport(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// GetHookLog handles GET /sessions/:sessionId/post-session-hooks/:name and
+// returns the output of one hook as plain text.
+func (c *PostSessionHookController) GetHookLog(ctx echo.Context) error {
+	report, err := c.authorizedReport(ctx)
+	if err != nil {
+		return err
+	}
+	// Only names listed in the report are served, which also rules out paths.
+	name := ctx.Param("name")
+	if !slices.ContainsFunc(report.Hooks, func(h entities.PostSessionHookResult) bool { return h.Log == name }) {
+		return echo.NewHTTPError(http.StatusNotFound, "Hook log not found")
+	}
+
+	body, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), services.PostSessionHookPrefix(report.SessionID)+name)
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Hook log not found")
+	}
+	if err != nil {
+		log.Printf("Failed to open post-session hook log %s of session %s: %v", name, report.SessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read hook log")
+	}
+	defer func() { _ = body.Close() }()
+	ctx.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	ctx.Response().Header().Set(echo.HeaderContentType, "text/plain; charset=utf-8")
+	ctx.Response().WriteHeader(http.StatusOK)
+	_, err = io.Copy(ctx.Response(), body)
+	return err
+}
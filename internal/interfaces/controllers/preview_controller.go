@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// previewManager is implemented by session managers that can deploy preview
+// environments from session branches.
+type previewManager interface {
+	CreatePreviewEnvironment(ctx context.Context, sessionID string, req entities.CreatePreviewRequest) (*entities.PreviewEnvironment, error)
+	GetPreviewEnvironment(sessionID string) (*entities.PreviewEnvironment, error)
+	DeletePreviewEnvironment(ctx context.Context, sessionID string) error
+}
+
+// PreviewController handles the preview environment of a session.
+type PreviewController struct {
+	sessionManagerProvider SessionManagerProvider
+}
+
+// NewPreviewController creates a new PreviewController.
+func NewPreviewController(sessionManagerProvider SessionManagerProvider) *PreviewController {
+	return &PreviewController{sessionManagerProvider: sessionManagerProvider}
+}
+
+// GetName returns the name of this controller for logging
+func (c *PreviewController) GetName() string {
+	return "PreviewController"
+}
+
+// previewSession returns the session and the preview manager after checking
+// that the caller may access (or, with modify, change) the session.
+func (c *PreviewController) previewSession(ctx echo.Context, modify bool) (entities.Session, previewManager, error) {
+	manager := c.sessionManagerProvider.GetSessionManager()
+	session := manager.GetSession(ctx.Param("sessionId"))
+	if session == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+	if modify && !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this session")
+	}
+	if !modify && !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+	previews, ok := manager.(previewManager)
+	if !ok {
+		return nil, nil, echo.NewHTTPError(http.StatusNotImplemented, "Preview environments are not supported")
+	}
+	return session, previews, nil
+}
+
+func previewError(sessionID string, err error) error {
+	switch {
+	case errors.Is(err, entities.ErrPreviewDisabled):
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	case errors.Is(err, entities.ErrPreviewNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, entities.ErrPreviewBranchRequired):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, entities.ErrPreviewDeploying):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	log.Printf("[PREVIEW] Preview request for session %s failed: %v", sessionID, err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "Failed to manage preview environment")
+}
+
+// CreatePreview handles POST /sessions/:sessionId/preview. The deploy runs in
+// the background; poll GetPreview until the status is ready or failed.
+func (c *PreviewController) CreatePreview(ctx echo.Context) error {
+	session, previews, err := c.previewSession(ctx, true)
+	if err != nil {
+		return err
+	}
+	var req entities.CreatePreviewRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	preview, err := previews.CreatePreviewEnvironment(ctx.Request().Context(), session.ID(), req)
+	if err != nil {
+		return previewError(session.ID(), err)
+	}
+	return ctx.JSON(http.StatusAccepted, preview)
+}
+
+// GetPreview handles GET /sessions/:sessionId/preview.
+func (c *PreviewController) GetPreview(ctx echo.Context) error {
+	session, previews, err := c.previewSession(ctx, false)
+	if err != nil {
+		return err
+	}
+	preview, err := previews.GetPreviewEnvironment(session.ID())
+	if err != nil {
+		return previewError(session.ID(), err)
+	}
+	return ctx.JSON(http.StatusOK, preview)
+}
+
+// DeletePreview handles DELETE /sessions/:sessionId/preview.
+func (c *PreviewController) DeletePreview(ctx echo.Context) error {
+	session, previews, err := c.previewSession(ctx, true)
+	if err != nil {
+		return err
+	}
+	if err := previews.DeletePreviewEnvironment(ctx.Request().Context(), session.ID()); err != nil {
+		return previewError(session.ID(), err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
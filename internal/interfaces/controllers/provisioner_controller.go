@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"crypto/subtle"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,6 +14,8 @@ import (
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 type ProvisionerController struct {
@@ -27,8 +30,21 @@ type ProvisionerManager interface {
 	ConnectProvisioner(ctx context.Context, req services.ProvisionerConnectRequest) error
 	ClaimProvisionRequest(ctx context.Context, sessionID, podName string) (*services.ProvisionRequest, bool, error)
 	UpdateProvisionRequestStatus(ctx context.Context, sessionID, requestID string, req services.ProvisionRequestStatusUpdate) error
+	UpdateSessionExtensions(ctx context.Context, sessionID string, report *startup.ExtensionReport) error
 }
 
+// GitHubTokenIssuer is implemented by provisioner managers that can hand out
+// fresh GitHub App installation tokens to session Pods. Every Pod holds the
+// provisioner token, so a Pod proves it is the session it asks a token for
+// with its own session token.
+type GitHubTokenIssuer interface {
+	SessionGitHubToken(ctx context.Context, sessionID string) (*github_pkg.InstallationToken, error)
+	ValidateSessionToken(sessionID, token string) bool
+}
+
+// sessionTokenHeader carries the per-session credential of a session Pod
+const sessionTokenHeader = "X-Session-Token"
+
 func NewProvisionerController(manager ProvisionerManager, allocationQueue sessionallocation.Queue, settingsRepo repositories.SettingsRepository, sessionRouteRepo repositories.SessionRouteRepository) *ProvisionerController {
 	return &ProvisionerController{manager: manager, allocationQueue: allocationQueue, settingsRepo: settingsRepo, sessionRouteRepo: sessionRouteRepo}
 }
@@ -95,6 +111,46 @@ func (pc *ProvisionerController) UpdateProvisionRequestStatus(c echo.Context) er
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// ReportExtensions records which marketplaces and plugins the session Pod
+// registered during setup.
+func (pc *ProvisionerController) ReportExtensions(c echo.Context) error {
+	if !pc.authorized(c) {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	var report startup.ExtensionReport
+	if err := c.Bind(&report); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := pc.manager.UpdateSessionExtensions(c.Request().Context(), c.Param("sessionId"), &report); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// GetGitHubToken returns a current GitHub App installation token for the
+// session, so a long-running session can replace its token before it expires.
+func (pc *ProvisionerController) GetGitHubToken(c echo.Context) error {
+	if !pc.authorized(c) {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	issuer, ok := pc.manager.(GitHubTokenIssuer)
+	if !ok {
+		return c.NoContent(http.StatusNoContent)
+	}
+	sessionID := c.Param("sessionId")
+	if !issuer.ValidateSessionToken(sessionID, c.Request().Header.Get(sessionTokenHeader)) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	token, err := issuer.SessionGitHubToken(c.Request().Context(), sessionID)
+	if errors.Is(err, services.ErrGitHubTokenUnavailable) {
+		return c.NoContent(http.StatusNoContent)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, token)
+}
+
 func (pc *ProvisionerController) GetNextSessionAllocation(c echo.Context) error {
 	if !pc.authorized(c) {
 		return c.NoContent(http.StatusUnauthorized)
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
+)
+
+type fakeGitHubTokenManager struct {
+	issued []string
+}
+
+func (m *fakeGitHubTokenManager) ValidateProvisionerToken(token string) bool {
+	return token == "prov-token"
+}
+
+func (m *fakeGitHubTokenManager) ConnectProvisioner(context.Context, services.ProvisionerConnectRequest) error {
+	return nil
+}
+
+func (m *fakeGitHubTokenManager) ClaimProvisionRequest(context.Context, string, string) (*services.ProvisionRequest, bool, error) {
+	return nil, false, nil
+}
+
+func (m *fakeGitHubTokenManager) UpdateProvisionRequestStatus(context.Context, string, string, services.ProvisionRequestStatusUpdate) error {
+	return nil
+}
+
+func (m *fakeGitHubTokenManager) UpdateSessionExtensions(context.Context, string, *startup.ExtensionReport) error {
+	return nil
+}
+
+func (m *fakeGitHubTokenManager) ValidateSessionToken(sessionID, token string) bool {
+	return token == "token-of-"+sessionID
+}
+
+func (m *fakeGitHubTokenManager) SessionGitHubToken(_ context.Context, sessionID string) (*github_pkg.InstallationToken, error) {
+	m.issued = append(m.issued, sessionID)
+	return &github_pkg.InstallationToken{Token: "ghs_" + sessionID, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func TestGetGitHubToken_RequiresTheTokenOfTheSession(t *testing.T) {
+	manager := &fakeGitHubTokenManager{}
+	controller := NewProvisionerController(manager, nil, nil, nil)
+	e := echo.New()
+
+	get := func(sessionID, sessionToken string) int {
+		req := httptest.NewRequest(http.MethodGet, "/internal/session-provisioners/"+sessionID+"/github-token", nil)
+		req.Header.Set("Authorization", "Bearer prov-token")
+		if sessionToken != "" {
+			req.Header.Set(sessionTokenHeader, sessionToken)
+		}
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("sessionId")
+		ctx.SetParamValues(sessionID)
+		require.NoError(t, controller.GetGitHubToken(ctx))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("sess-a", "token-of-sess-a"))
+	assert.Equal(t, http.StatusForbidden, get("sess-b", "token-of-sess-a"), "another session's token")
+	assert.Equal(t, http.StatusForbidden, get("sess-b", ""), "provisioner token only")
+	assert.Equal(t, []string{"sess-a"}, manager.issued)
+}
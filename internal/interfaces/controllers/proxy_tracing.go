@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
+)
+
+// proxyTrace is the span of one request proxied to a session backend.
+type proxyTrace struct {
+	span  *tracing.Span
+	start time.Time
+}
+
+// startProxyTrace starts the span of a request proxied to sessionID,
+// continuing the caller's trace when it sent a traceparent header. backend
+// is "local" or "external". The returned request carries the span.
+func startProxyTrace(req *http.Request, sessionID, backend string) (*http.Request, *proxyTrace) {
+	ctx, span := tracing.Start(tracing.Extract(req.Context(), req.Header), "ProxySession",
+		tracing.String("session.id", sessionID),
+		tracing.String("session.backend", backend),
+		tracing.String("http.request.method", req.Method),
+		tracing.String("url.path", req.URL.Path),
+	)
+	return req.WithContext(ctx), &proxyTrace{span: span, start: time.Now()}
+}
+
+// instrument propagates the trace to the backend through the traceparent
+// header and records the backend status and latency (time until the response
+// headers arrive). Call it after the Director, ModifyResponse and
+// ErrorHandler of p are set.
+func (t *proxyTrace) instrument(p *httputil.ReverseProxy) {
+	director := p.Director
+	p.Director = func(req *http.Request) {
+		director(req)
+		tracing.Inject(req.Context(), req.Header)
+	}
+
+	modifyResponse := p.ModifyResponse
+	p.ModifyResponse = func(resp *http.Response) error {
+		t.span.SetAttributes(
+			tracing.Int("http.response.status_code", resp.StatusCode),
+			tracing.Float("backend.latency_ms", float64(time.Since(t.start).Microseconds())/1000),
+		)
+		if modifyResponse != nil {
+			return modifyResponse(resp)
+		}
+		return nil
+	}
+
+	errorHandler := p.ErrorHandler
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		t.span.SetAttributes(tracing.Float("backend.latency_ms", float64(time.Since(t.start).Microseconds())/1000))
+		t.span.End(err)
+		if errorHandler != nil {
+			errorHandler(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+}
+
+// end finishes the span once the response, including any stream, is done.
+func (t *proxyTrace) end() {
+	t.span.End(nil)
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
+)
+
+type recordingSpanExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingSpanExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestProxyTracePropagatesTraceParent(t *testing.T) {
+	exporter := &recordingSpanExporter{}
+	tracer := tracing.NewTracer(exporter, tracing.Options{SampleRatio: 1})
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	var received string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(tracing.TraceParentHeader)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	incoming := httptest.NewRequest(http.MethodGet, "/session-1/status", nil)
+	incoming.Header.Set(tracing.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req, trace := startProxyTrace(incoming, "session-1", "local")
+	p := httputil.NewSingleHostReverseProxy(target)
+	trace.instrument(p)
+	p.ServeHTTP(httptest.NewRecorder(), req)
+	trace.end()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if len(exporter.spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(exporter.spans))
+	}
+	span := exporter.spans[0]
+	if want := span.SpanContext.TraceParent(); received != want {
+		t.Errorf("backend traceparent = %q, want %q", received, want)
+	}
+	if got := span.SpanContext.TraceParent()[3:35]; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("span trace ID = %s, want the caller's trace", got)
+	}
+	var status any
+	hasLatency := false
+	for _, a := range span.Attributes {
+		switch a.Key {
+		case "http.response.status_code":
+			status = a.Value
+		case "backend.latency_ms":
+			hasLatency = true
+		}
+	}
+	if status != int64(http.StatusTeapot) || !hasLatency {
+		t.Errorf("span attributes = %+v", span.Attributes)
+	}
+}
+
+func TestProxyTraceRecordsBackendErrors(t *testing.T) {
+	exporter := &recordingSpanExporter{}
+	tracer := tracing.NewTracer(exporter, tracing.Options{SampleRatio: 1})
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	target, _ := url.Parse("http://127.0.0.1:1")
+	req, trace := startProxyTrace(httptest.NewRequest(http.MethodGet, "/session-1/status", nil), "session-1", "local")
+	p := httputil.NewSingleHostReverseProxy(target)
+	trace.instrument(p)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	trace.end()
+
+	_ = tracer.Shutdown(context.Background())
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	if len(exporter.spans) != 1 || exporter.spans[0].Err == "" {
+		t.Errorf("expected one failed span, got %+v", exporter.spans)
+	}
+}
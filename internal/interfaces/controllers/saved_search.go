@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// SavedSearchesResponse is the response of GET /saved-searches
+type SavedSearchesResponse struct {
+	SavedSearches []entities.SavedSearch `json:"saved_searches"`
+	// NotificationSavedSearch is the saved search that routes the caller's
+	// notifications, if any
+	NotificationSavedSearch string `json:"notification_saved_search,omitempty"`
+}
+
+// savedSearchUserID returns the caller's user ID for the saved search endpoints
+func (c *SessionController) savedSearchUserID(ctx echo.Context) (string, error) {
+	if c.settingsRepo == nil {
+		return "", echo.NewHTTPError(http.StatusNotImplemented, "Saved searches require the settings repository")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || authzCtx.PersonalScope.UserID == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+	return authzCtx.PersonalScope.UserID, nil
+}
+
+// ListSavedSearches handles GET /saved-searches
+func (c *SessionController) ListSavedSearches(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+	userID, err := c.savedSearchUserID(ctx)
+	if err != nil {
+		return err
+	}
+	settings, err := c.loadUserSettings(ctx.Request().Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load settings of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load saved searches")
+	}
+	resp := SavedSearchesResponse{
+		SavedSearches:           settings.SavedSearches(),
+		NotificationSavedSearch: settings.NotificationSavedSearch(),
+	}
+	if resp.SavedSearches == nil {
+		resp.SavedSearches = []entities.SavedSearch{}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// PutSavedSearch handles PUT /saved-searches/:name and creates or replaces
+// the caller's saved search of that name
+func (c *SessionController) PutSavedSearch(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+	userID, err := c.savedSearchUserID(ctx)
+	if err != nil {
+		return err
+	}
+	var search entities.SavedSearch
+	if err := ctx.Bind(&search); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	search.Name = ctx.Param("name")
+	search.Query = strings.TrimSpace(search.Query)
+	if err := search.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	settings, err := c.loadUserSettings(ctx.Request().Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load settings of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load saved searches")
+	}
+	searches := slices.Clone(settings.SavedSearches())
+	if i := slices.IndexFunc(searches, func(s entities.SavedSearch) bool { return s.Name == search.Name }); i >= 0 {
+		searches[i] = search
+	} else {
+		if len(searches) >= entities.MaxSavedSearches {
+			return echo.NewHTTPError(http.StatusBadRequest, "Too many saved searches")
+		}
+		searches = append(searches, search)
+	}
+	settings.SetSavedSearches(searches)
+	if err := c.settingsRepo.Save(ctx.Request().Context(), settings); err != nil {
+		log.Printf("Failed to save saved searches of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save saved search")
+	}
+	return ctx.JSON(http.StatusOK, search)
+}
+
+// DeleteSavedSearch handles DELETE /saved-searches/:name. Notifications that
+// were routed by the search are sent for all sessions again.
+func (c *SessionController) DeleteSavedSearch(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+	userID, err := c.savedSearchUserID(ctx)
+	if err != nil {
+		return err
+	}
+	name := ctx.Param("name")
+	settings, err := c.loadUserSettings(ctx.Request().Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load settings of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load saved searches")
+	}
+	if settings.SavedSearch(name) == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Saved search not found")
+	}
+	settings.SetSavedSearches(slices.DeleteFunc(slices.Clone(settings.SavedSearches()), func(s entities.SavedSearch) bool {
+		return s.Name == name
+	}))
+	if settings.NotificationSavedSearch() == name {
+		settings.SetNotificationSavedSearch("")
+	}
+	if err := c.settingsRepo.Save(ctx.Request().Context(), settings); err != nil {
+		log.Printf("Failed to save saved searches of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete saved search")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// callerSavedSearch returns the caller's saved search called name for GET /search
+func (c *SessionController) callerSavedSearch(ctx echo.Context, userID, name string) (*entities.SavedSearch, error) {
+	if c.settingsRepo == nil {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "Saved searches require the settings repository")
+	}
+	settings, err := c.settingsRepo.FindByName(ctx.Request().Context(), userID)
+	if err != nil || settings == nil || settings.SavedSearch(name) == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown saved search "+name)
+	}
+	return settings.SavedSearch(name), nil
+}
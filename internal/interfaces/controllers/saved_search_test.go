@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+func makeSavedSearchContext(t *testing.T, method, name string, body interface{}, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	c, rec := makeMemoryEchoContext(t, method, "/saved-searches/"+name, body, nil)
+	c.SetParamNames("name")
+	c.SetParamValues(name)
+	c.Set("authz_context", &auth.AuthorizationContext{PersonalScope: auth.PersonalScopeAuth{UserID: userID, CanRead: true}})
+	return c, rec
+}
+
+func TestSavedSearches_PutListDelete(t *testing.T) {
+	repo := newMockSettingsRepository()
+	controller := NewSessionController(&mockWaitProvider{}, nil, WithSettingsRepository(repo))
+
+	c, rec := makeSavedSearchContext(t, http.MethodPut, "prod", entities.SavedSearch{Status: "active", Tags: map[string]string{"env": "prod"}, Query: " login "}, "user-1")
+	require.NoError(t, controller.PutSavedSearch(c))
+	assert.JSONEq(t, `{"name":"prod","status":"active","tags":{"env":"prod"},"query":"login"}`, rec.Body.String())
+
+	c, _ = makeSavedSearchContext(t, http.MethodPut, "bad!name", entities.SavedSearch{}, "user-1")
+	assertHTTPError(t, controller.PutSavedSearch(c), http.StatusBadRequest)
+
+	repo.settings["user-1"].SetNotificationSavedSearch("prod")
+	c, rec = makeSavedSearchContext(t, http.MethodGet, "", nil, "user-1")
+	require.NoError(t, controller.ListSavedSearches(c))
+	var resp SavedSearchesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.SavedSearches, 1)
+	assert.Equal(t, "prod", resp.NotificationSavedSearch)
+
+	c, _ = makeSavedSearchContext(t, http.MethodDelete, "prod", nil, "user-1")
+	require.NoError(t, controller.DeleteSavedSearch(c))
+	assert.Empty(t, repo.settings["user-1"].SavedSearches())
+	assert.Empty(t, repo.settings["user-1"].NotificationSavedSearch())
+
+	c, _ = makeSavedSearchContext(t, http.MethodDelete, "prod", nil, "user-1")
+	assertHTTPError(t, controller.DeleteSavedSearch(c), http.StatusNotFound)
+}
+
+func TestSavedSearches_RequireSettingsRepository(t *testing.T) {
+	controller := NewSessionController(&mockWaitProvider{}, nil)
+	c, _ := makeSavedSearchContext(t, http.MethodGet, "", nil, "user-1")
+	assertHTTPError(t, controller.ListSavedSearches(c), http.StatusNotImplemented)
+}
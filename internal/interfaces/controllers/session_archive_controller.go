@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+)
+
+// SessionArchiveController serves the logs and artifacts archived to object
+// storage when sessions were deleted. Access is authorized against the owner
+// recorded in the archive manifest.
+type SessionArchiveController struct {
+	archive *sessionarchive.Archive
+}
+
+// NewSessionArchiveController creates a new SessionArchiveController.
+// archive may be nil when session archiving is disabled.
+func NewSessionArchiveController(archive *sessionarchive.Archive) *SessionArchiveController {
+	return &SessionArchiveController{archive: archive}
+}
+
+// GetName returns the name of this controller for logging
+func (c *SessionArchiveController) GetName() string {
+	return "SessionArchiveController"
+}
+
+// ArchivedSessionsResponse is the response of GET /archived-sessions
+type ArchivedSessionsResponse struct {
+	Sessions []*sessionarchive.Manifest `json:"sessions"`
+}
+
+// ListArchivedSessions handles GET /archived-sessions?team_id=.
+// It returns the archived sessions the caller may access, most recently
+// archived first.
+func (c *SessionArchiveController) ListArchivedSessions(ctx echo.Context) error {
+	if c.archive == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Session archiving is not configured")
+	}
+	manifests, err := c.archive.List(ctx.Request().Context())
+	if err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to list archived sessions: %v", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to list archived sessions")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	teamID := ctx.QueryParam("team_id")
+	resp := ArchivedSessionsResponse{Sessions: make([]*sessionarchive.Manifest, 0, len(manifests))}
+	for _, m := range manifests {
+		if teamID != "" && m.TeamID != teamID {
+			continue
+		}
+		if authzCtx != nil && authzCtx.CanAccessResource(m.UserID, string(m.Scope), m.TeamID) {
+			resp.Sessions = append(resp.Sessions, m)
+		}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// authorizedManifest loads the manifest of the archived session and checks
+// that the caller may access the session it belongs to.
+func (c *SessionArchiveController) authorizedManifest(ctx echo.Context) (*sessionarchive.Manifest, error) {
+	if c.archive == nil {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "Session archiving is not configured")
+	}
+	sessionID := ctx.Param("sessionId")
+	notFound := echo.NewHTTPError(http.StatusNotFound, "Session is not archived")
+	m, err := c.archive.Get(ctx.Request().Context(), sessionID)
+	if errors.Is(err, sessionarchive.ErrNotFound) {
+		return nil, notFound
+	}
+	if err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to read archive manifest of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to read archived session")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(m.UserID, string(m.Scope), m.TeamID) {
+		// Do not reveal whether the session was archived.
+		return nil, notFound
+	}
+	return m, nil
+}
+
+// GetArchive handles GET /sessions/:sessionId/archive and returns the
+// manifest of the archived session.
+func (c *SessionArchiveController) GetArchive(ctx echo.Context) error {
+	m, err := c.authorizedManifest(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, m)
+}
+
+// GetArchivedFile handles GET /sessions/:sessionId/archive/:name and
+// downloads one archived file, e.g. pod.log or artifacts.tar.gz.
+func (c *SessionArchiveController) GetArchivedFile(ctx echo.Context) error {
+	m, err := c.authorizedManifest(ctx)
+	if err != nil {
+		return err
+	}
+	name := ctx.Param("name")
+	file, ok := m.File(name)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Archived file not found")
+	}
+	body, err := c.archive.Open(ctx.Request().Context(), m, name)
+	if errors.Is(err, sessionarchive.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Archived file not found")
+	}
+	if err != nil {
+		log.Printf("[SESSION_ARCHIVE] Failed to open archived %s of session %s: %v", name, m.SessionID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to read archived file")
+	}
+	defer func() { _ = body.Close() }()
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+	ctx.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`"`)
+	ctx.Response().Header().Set(echo.HeaderContentType, contentType)
+	ctx.Response().WriteHeader(http.StatusOK)
+	_, err = io.Copy(ctx.Response(), body)
+	return err
+}
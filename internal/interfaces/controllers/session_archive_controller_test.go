@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+)
+
+// memoryArchiveStore is an in-memory sessionarchive.Store
+type memoryArchiveStore struct {
+	objects map[string][]byte
+}
+
+func (s *memoryArchiveStore) Put(_ context.Context, key, _ string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	s.objects[key] = data
+	return err
+}
+
+func (s *memoryArchiveStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, sessionarchive.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryArchiveStore) List(_ context.Context, prefix string) ([]sessionarchive.Object, error) {
+	var objects []sessionarchive.Object
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, sessionarchive.Object{Key: key})
+		}
+	}
+	return objects, nil
+}
+
+func (s *memoryArchiveStore) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func newTestSessionArchive(t *testing.T) *sessionarchive.Archive {
+	t.Helper()
+	archive := sessionarchive.New(&memoryArchiveStore{objects: map[string][]byte{}}, 0)
+	for _, m := range []*sessionarchive.Manifest{
+		{SessionID: "sess-1", UserID: "user-1", Scope: entities.ScopeUser, ArchivedAt: time.Now()},
+		{SessionID: "sess-2", UserID: "user-2", Scope: entities.ScopeUser, ArchivedAt: time.Now()},
+	} {
+		require.NoError(t, archive.Save(context.Background(), m, []sessionarchive.Entry{
+			{File: sessionarchive.File{Name: "pod.log", ContentType: "text/plain; charset=utf-8"}, Data: []byte("log of " + m.SessionID)},
+		}))
+	}
+	return archive
+}
+
+func TestSessionArchiveController_GetArchivedFile(t *testing.T) {
+	controller := NewSessionArchiveController(newTestSessionArchive(t))
+
+	c, rec := makeWaitEchoContext(t, "sess-1", nil, "user-1")
+	require.NoError(t, controller.GetArchive(c))
+	var manifest sessionarchive.Manifest
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &manifest))
+	assert.Len(t, manifest.Files, 1)
+
+	c, rec = makeWaitEchoContext(t, "sess-1", nil, "user-1")
+	c.SetParamNames("sessionId", "name")
+	c.SetParamValues("sess-1", "pod.log")
+	require.NoError(t, controller.GetArchivedFile(c))
+	assert.Equal(t, "log of sess-1", rec.Body.String())
+
+	c, _ = makeWaitEchoContext(t, "sess-1", nil, "user-1")
+	c.SetParamNames("sessionId", "name")
+	c.SetParamValues("sess-1", "manifest.json")
+	assertHTTPError(t, controller.GetArchivedFile(c), http.StatusNotFound)
+
+	// Other users cannot tell that the session was archived
+	c, _ = makeWaitEchoContext(t, "sess-1", nil, "user-2")
+	assertHTTPError(t, controller.GetArchive(c), http.StatusNotFound)
+}
+
+func TestSessionArchiveController_ListArchivedSessions(t *testing.T) {
+	controller := NewSessionArchiveController(newTestSessionArchive(t))
+
+	c, rec := makeWaitEchoContext(t, "", nil, "user-2")
+	require.NoError(t, controller.ListArchivedSessions(c))
+	var resp ArchivedSessionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Sessions, 1)
+	assert.Equal(t, "sess-2", resp.Sessions[0].SessionID)
+
+	c, _ = makeWaitEchoContext(t, "", nil, "user-2")
+	assertHTTPError(t, NewSessionArchiveController(nil).ListArchivedSessions(c), http.StatusNotImplemented)
+}
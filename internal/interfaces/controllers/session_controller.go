@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,11 +26,12 @@ import (
 	sessionuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/session"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
 	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+	"github.com/takutakahashi/agentapi-proxy/pkg/proxy"
 )
 
 // SessionCreator is an interface for creating sessions
 type SessionCreator interface {
-	CreateSession(sessionID string, req entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error)
+	CreateSession(ctx context.Context, sessionID string, req entities.StartRequest, userID, userRole string, teams []string) (entities.Session, error)
 	DeleteSessionByID(sessionID string) error
 }
 
@@ -40,6 +45,10 @@ type sessionAnnotationUpdater interface {
 	UpdateSessionAnnotations(ctx context.Context, sessionID string, patch entities.UpdateSessionAnnotationsRequest) (entities.SessionAnnotations, error)
 }
 
+type sessionMetadataUpdater interface {
+	UpdateSessionMetadata(ctx context.Context, sessionID string, req entities.UpdateSessionRequest) (entities.Session, error)
+}
+
 type sessionAnnotationsProvider interface {
 	Annotations() entities.SessionAnnotations
 }
@@ -52,6 +61,10 @@ type SessionController struct {
 	sessionRouteRepo       repositories.SessionRouteRepository
 	settingsRepo           repositories.SettingsRepository
 	sessionProfileRepo     repositories.SessionProfileRepository
+	// autoResume resumes paused sessions when a request is proxied to them.
+	autoResume bool
+	// proxy builds the reverse proxies used to reach session backends.
+	proxy *proxy.Proxy
 }
 
 // NewSessionController creates a new SessionController instance
@@ -64,6 +77,7 @@ func NewSessionController(
 		sessionManagerProvider: sessionManagerProvider,
 		sessionCreator:         sessionCreator,
 		validateTeamUC:         sessionuc.NewValidateTeamAccessUseCase(),
+		proxy:                  proxy.New(proxy.Options{}),
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -95,6 +109,20 @@ func WithSessionProfileRepository(repo repositories.SessionProfileRepository) Se
 	}
 }
 
+// WithAutoResume enables resuming paused sessions on proxied requests
+func WithAutoResume(enabled bool) SessionControllerOption {
+	return func(c *SessionController) {
+		c.autoResume = enabled
+	}
+}
+
+// WithProxyOptions configures streaming and idle timeouts of proxied requests
+func WithProxyOptions(opts proxy.Options) SessionControllerOption {
+	return func(c *SessionController) {
+		c.proxy = proxy.New(opts)
+	}
+}
+
 // getSessionManager returns the current session manager
 func (c *SessionController) getSessionManager() repositories.SessionManager {
 	return c.sessionManagerProvider.GetSessionManager()
@@ -111,6 +139,7 @@ func (c *SessionController) RegisterRoutes(e *echo.Echo) error {
 	e.POST("/start", c.StartSession)
 	e.GET("/search", c.SearchSessions)
 	e.PATCH("/sessions/:sessionId/annotations", c.UpdateSessionAnnotations)
+	e.PATCH("/sessions/:sessionId", c.UpdateSession)
 	e.DELETE("/sessions/:sessionId", c.DeleteSession)
 
 	// Session proxy route
@@ -186,102 +215,75 @@ func (c *SessionController) StartSession(ctx echo.Context) error {
 	// Resolve session profile: merge profile config into startReq fields.
 	// When SessionProfileID is set, use that profile. Otherwise fall back to the
 	// user/team's default profile. The profile is the base; explicit request fields override.
+	// The default profiles of the session's teams fill in what both leave unset.
 	if c.sessionProfileRepo != nil {
 		profile := c.resolveSessionProfile(ctx.Request().Context(), startReq.SessionProfileID, userID, startReq.Scope, startReq.TeamID, startReq.Tags)
 		if profile != nil {
 			cfg := profile.Config()
 			startReq.ProfileMCPServers = cfg.MCPServers()
+			applySessionProfileConfig(cfg, &startReq, explicitSandbox, explicitDocker)
+		}
+		teamDefaults := sessionuc.TeamDefaultProfiles(ctx.Request().Context(), c.sessionProfileRepo, sessionuc.ResolveTeams(startReq.Scope, startReq.TeamID, teams))
+		for i := len(teamDefaults) - 1; i >= 0; i-- {
+			applySessionProfileConfig(teamDefaults[i].Config(), &startReq, explicitSandbox, explicitDocker)
+		}
+		startReq.TeamProfileMCPServers = sessionuc.TeamDefaultMCPServers(teamDefaults)
+	}
 
-			// Environment: profile is base, request keys override
-			if len(cfg.Environment()) > 0 {
-				merged := make(map[string]string, len(cfg.Environment()))
-				for k, v := range cfg.Environment() {
-					merged[k] = v
-				}
-				for k, v := range startReq.Environment {
-					merged[k] = v
-				}
-				startReq.Environment = merged
-			}
-
-			// Tags: profile is base, request keys override
-			if len(cfg.Tags()) > 0 {
-				merged := make(map[string]string, len(cfg.Tags()))
-				for k, v := range cfg.Tags() {
-					merged[k] = v
-				}
-				for k, v := range startReq.Tags {
-					merged[k] = v
-				}
-				startReq.Tags = merged
-			}
-
-			// Params: profile is base, request fields override per-field
-			if cfg.Params() != nil {
-				if startReq.Params == nil {
-					startReq.Params = cfg.Params()
-				} else {
-					startReq.Params = mergeSessionParams(cfg.Params(), startReq.Params)
-				}
-			}
-			if containsAllocatorSelector(startReq.Tags) {
-				removeImplicitAllocatorCapabilities(startReq.Params, explicitSandbox, explicitDocker)
-			}
-
-			// MemoryKey: profile is base, request keys override
-			if len(cfg.MemoryKey()) > 0 {
-				merged := make(map[string]string, len(cfg.MemoryKey()))
-				for k, v := range cfg.MemoryKey() {
-					merged[k] = v
-				}
-				for k, v := range startReq.MemoryKey {
-					merged[k] = v
-				}
-				startReq.MemoryKey = merged
-			}
-
-			// SandboxPolicyID: apply profile's policy when request does not already specify one.
-			if startReq.Params == nil {
-				startReq.Params = &entities.SessionParams{}
-			}
-			// Native allocator sessions intentionally do not support sandboxing.
-			// Do not let a profile's implicit sandbox default turn an otherwise valid
-			// allocator.* request into an unsupported-capability request. An explicit
-			// sandbox in the request remains intact and is rejected by the allocator
-			// selection layer.
-			if !containsAllocatorSelector(startReq.Tags) {
-				applyProfileSandboxDefaults(cfg, startReq.Params)
-			}
+	if err := c.applySessionSlug(ctx.Request().Context(), userID, &startReq); err != nil {
+		return err
+	}
+	if err := applySessionGroup(&startReq); err != nil {
+		return err
+	}
 
-			// SessionTTL: apply profile's TTL when request does not already specify one.
-			if cfg.SessionTTL() != "" {
-				if startReq.Params == nil {
-					startReq.Params = &entities.SessionParams{}
-				}
-				if startReq.Params.SessionTTL == "" {
-					startReq.Params.SessionTTL = cfg.SessionTTL()
-				}
-			}
-			if len(cfg.UnsyncedFilePaths()) > 0 {
-				if startReq.Params == nil {
-					startReq.Params = &entities.SessionParams{}
-				}
-				if len(startReq.Params.UnsyncedFilePaths) == 0 {
-					startReq.Params.UnsyncedFilePaths = cfg.UnsyncedFilePaths()
-				}
-			}
+	if startReq.Params != nil && startReq.Params.Docker != nil {
+		if err := startReq.Params.Docker.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if startReq.Params != nil {
+		if err := entities.ValidateSetupHooks(startReq.Params.SetupHooks); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
+		if err := entities.ValidateSetupHooks(startReq.Params.PostSessionHooks); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if err := entities.ValidateCompletionCallbackURL(startReq.Params.CompletionCallbackURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	requestMCPServers, err := entities.RequestMCPServersSettings(startReq.MCPServers)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	startReq.RequestMCPServers = requestMCPServers
 
-	session, err := c.sessionCreator.CreateSession(sessionID, startReq, userID, userRole, teams)
+	session, err := c.sessionCreator.CreateSession(ctx.Request().Context(), sessionID, startReq, userID, userRole, teams)
 	if err != nil {
 		log.Printf("Failed to create session: %v", err)
+		if errors.Is(err, entities.ErrCapabilityNotAllowed) || errors.Is(err, entities.ErrAcceleratorNotAllowed) ||
+			errors.Is(err, entities.ErrImageNotAllowed) || errors.Is(err, entities.ErrBudgetExceeded) ||
+			errors.Is(err, entities.ErrMCPServerNotAllowed) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, entities.ErrInvalidReplicas) || errors.Is(err, entities.ErrInvalidAccelerator) ||
+			errors.Is(err, entities.ErrInvalidImage) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, entities.ErrSessionCapacityExhausted) || errors.Is(err, entities.ErrShuttingDown) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create session")
 	}
 
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"session_id": session.ID(),
-	})
+	}
+	if startReq.Slug != "" {
+		resp["slug"] = startReq.Slug
+	}
+	return ctx.JSON(http.StatusOK, resp)
 }
 
 func containsAllocatorSelector(tags map[string]string) bool {
@@ -309,6 +311,11 @@ func removeImplicitAllocatorCapabilities(params *entities.SessionParams, explici
 func (c *SessionController) SearchSessions(ctx echo.Context) error {
 	c.setCORSHeaders(ctx)
 
+	listOpts, err := parseSessionListOptions(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Get authorization context from middleware (guaranteed to be non-nil by AuthMiddleware)
 	authzCtx := auth.GetAuthorizationContext(ctx)
 	status := ctx.QueryParam("status")
@@ -318,6 +325,26 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 	userID := authzCtx.PersonalScope.UserID
 	userTeamIDs := authzCtx.TeamScope.Teams
 
+	// A saved search fills in the filters that are not given explicitly
+	var saved *entities.SavedSearch
+	if name := ctx.QueryParam("saved"); name != "" {
+		if saved, err = c.callerSavedSearch(ctx, userID, name); err != nil {
+			return err
+		}
+		if status == "" {
+			status = saved.Status
+		}
+		if scopeFilter == "" && saved.EffectiveScope() == entities.ScopeTeam {
+			scopeFilter = string(entities.ScopeTeam)
+		}
+		if listOpts.query == "" {
+			listOpts.query = strings.ToLower(saved.Query)
+		}
+	}
+	inSavedTeams := func(teamID string) bool {
+		return saved == nil || len(saved.TeamIDs) == 0 || slices.Contains(saved.TeamIDs, teamID)
+	}
+
 	// Service accounts cannot use user scope.
 	// Automatically route to the service account's team scope when scope is not explicitly "team".
 	{
@@ -336,6 +363,13 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 			tagFilters[tagKey] = paramValues[0]
 		}
 	}
+	if saved != nil {
+		for k, v := range saved.Tags {
+			if _, ok := tagFilters[k]; !ok {
+				tagFilters[k] = v
+			}
+		}
+	}
 
 	// Build filter
 	filter := entities.SessionFilter{
@@ -370,6 +404,10 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 			}
 		}
 
+		if !inSavedTeams(session.TeamID()) {
+			continue
+		}
+
 		// Check authorization using pre-resolved context
 		// Admin bypasses are handled within CanAccessResource for team-scoped resources only
 		if authzCtx.CanAccessResource(session.UserID(), string(sessionScope), session.TeamID()) {
@@ -389,7 +427,6 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 	var routes []*repositories.SessionRoute
 	allocatedSessions := make(map[string]entities.Session)
 	if c.sessionRouteRepo != nil {
-		var err error
 		routes, err = c.sessionRouteRepo.List(ctx.Request().Context(), userID)
 		if err != nil {
 			log.Printf("[SEARCH] Failed to list session routes: %v", err)
@@ -400,12 +437,7 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 		}
 	}
 
-	// Sort by start time (newest first)
-	sort.Slice(matchingSessions, func(i, j int) bool {
-		return matchingSessions[i].StartedAt().After(matchingSessions[j].StartedAt())
-	})
-
-	filteredSessions := make([]map[string]interface{}, 0, len(matchingSessions))
+	entries := make([]sessionListEntry, 0, len(matchingSessions)+len(routes))
 	// Track session IDs already present to avoid duplicates from route-based sessions
 	localSessionIDs := make(map[string]struct{}, len(matchingSessions))
 	for _, session := range matchingSessions {
@@ -419,10 +451,7 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 		initialMessage := session.Description()
 
 		annotations := getSessionAnnotations(session)
-		description := initialMessage
-		if annotations.Description != "" {
-			description = annotations.Description
-		}
+		description := annotations.DisplayDescription(initialMessage)
 		sessionData := map[string]interface{}{
 			"session_id":      session.ID(),
 			"user_id":         session.UserID(),
@@ -443,8 +472,29 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 			if req := ks.Request(); req != nil && req.Sandbox != nil {
 				sessionData["sandbox_policy_id"] = req.Sandbox.PolicyID
 			}
+			if caps := ks.Capabilities(); len(caps) > 0 {
+				sessionData["capabilities"] = caps
+			}
+			if preview := ks.Preview(); preview != nil {
+				sessionData["preview"] = preview
+			}
+			if completion := ks.Completion(); completion != nil {
+				sessionData["completion"] = completion
+			}
+			if report := ks.GitHubReport(); report != nil {
+				sessionData["github_report"] = report
+			}
+			if health := ks.Health(); health != nil {
+				sessionData["health"] = health
+			}
 		}
-		filteredSessions = append(filteredSessions, sessionData)
+		entries = append(entries, sessionListEntry{
+			data:      sessionData,
+			startedAt: session.StartedAt(),
+			updatedAt: session.UpdatedAt(),
+			status:    session.Status(),
+			text:      []string{description, initialMessage, annotations.Summary},
+		})
 	}
 
 	// Include ESM-created sessions from session routes
@@ -460,7 +510,7 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 		if scopeFilter != string(entities.ScopeTeam) && route.Scope == string(entities.ScopeTeam) {
 			continue
 		}
-		if !authzCtx.CanAccessResource(route.UserID, route.Scope, route.TeamID) {
+		if !authzCtx.CanAccessResource(route.UserID, route.Scope, route.TeamID) || !inSavedTeams(route.TeamID) {
 			continue
 		}
 		tags := route.Tags
@@ -479,28 +529,48 @@ func (c *SessionController) SearchSessions(ctx echo.Context) error {
 			continue
 		}
 		status := routedSessionStatus(route, allocatedSessions)
-		filteredSessions = append(filteredSessions, map[string]interface{}{
-			"session_id":           route.SessionID,
-			"allocated_session_id": route.RemoteSessionID,
-			"user_id":              route.UserID,
-			"scope":                route.Scope,
-			"team_id":              route.TeamID,
-			"status":               status,
-			"started_at":           route.StartedAt,
-			"updated_at":           route.StartedAt,
-			"last_message_at":      route.StartedAt,
-			"addr":                 "",
-			"tags":                 tags,
-			"annotations":          entities.SessionAnnotations{},
-			"metadata": map[string]interface{}{
-				"description": route.InitialMessage,
-			},
-		})
-	}
-
-	return ctx.JSON(http.StatusOK, map[string]interface{}{
-		"sessions": filteredSessions,
-	})
+		entries = append(entries, sessionListEntry{
+			startedAt: route.StartedAt,
+			updatedAt: route.StartedAt,
+			status:    status,
+			text:      []string{route.InitialMessage},
+			data: map[string]interface{}{
+				"session_id":           route.SessionID,
+				"allocated_session_id": route.RemoteSessionID,
+				"user_id":              route.UserID,
+				"scope":                route.Scope,
+				"team_id":              route.TeamID,
+				"status":               status,
+				"started_at":           route.StartedAt,
+				"updated_at":           route.StartedAt,
+				"last_message_at":      route.StartedAt,
+				"addr":                 "",
+				"tags":                 tags,
+				"annotations":          entities.SessionAnnotations{},
+				"metadata": map[string]interface{}{
+					"description": route.InitialMessage,
+				},
+			}})
+	}
+
+	// Reflect the caller's pinned sessions and folders
+	org := c.callerSessionOrganization(ctx.Request().Context(), userID)
+	for i := range entries {
+		entries[i].organize(org, entries[i].data["session_id"].(string))
+	}
+
+	page, total := listOpts.apply(entries)
+	response := map[string]interface{}{
+		"sessions": page,
+		"total":    total,
+	}
+	if folders := org.FolderNames(); len(folders) > 0 {
+		response["folders"] = folders
+	}
+	if next := listOpts.offset + len(page); listOpts.limit > 0 && next < total {
+		response["next_offset"] = next
+	}
+	return ctx.JSON(http.StatusOK, response)
 }
 
 func routedSessionStatus(route *repositories.SessionRoute, allocatedSessions map[string]entities.Session) string {
@@ -598,6 +668,62 @@ func (c *SessionController) UpdateSessionAnnotations(ctx echo.Context) error {
 	})
 }
 
+// UpdateSession handles PATCH /sessions/:sessionId, which updates the tags,
+// description and observer mode of a session.
+func (c *SessionController) UpdateSession(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	sessionID := ctx.Param("sessionId")
+	if sessionID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Session ID is required")
+	}
+
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this session")
+	}
+
+	var req entities.UpdateSessionRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := req.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.ObserverMode != nil && !auth.UserOwnsSession(ctx, session.UserID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the session owner can change observer mode")
+	}
+
+	updater, ok := c.getSessionManager().(sessionMetadataUpdater)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Session updates are not supported")
+	}
+
+	updated, err := updater.UpdateSessionMetadata(ctx.Request().Context(), sessionID, req)
+	if err != nil {
+		if errors.Is(err, entities.ErrInvalidSessionUpdate) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		log.Printf("Failed to update session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update session")
+	}
+
+	annotations := getSessionAnnotations(updated)
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"session_id":  sessionID,
+		"tags":        updated.Tags(),
+		"annotations": annotations,
+		"metadata": map[string]interface{}{
+			"description": annotations.DisplayDescription(updated.Description()),
+		},
+	})
+}
+
 // DeleteSession handles DELETE /sessions/:sessionId requests to terminate a session
 func (c *SessionController) DeleteSession(ctx echo.Context) error {
 	c.setCORSHeaders(ctx)
@@ -656,6 +782,137 @@ func (c *SessionController) DeleteSession(ctx echo.Context) error {
 	})
 }
 
+// sessionPauser is implemented by session managers that can scale a session
+// down to zero and back without losing its workdir.
+type sessionPauser interface {
+	PauseSession(ctx context.Context, sessionID string) error
+	ResumeSession(ctx context.Context, sessionID string) error
+}
+
+// PauseSession handles POST /sessions/:sessionId/pause
+func (c *SessionController) PauseSession(ctx echo.Context) error {
+	return c.changePauseState(ctx, true)
+}
+
+// ResumeSession handles POST /sessions/:sessionId/resume
+func (c *SessionController) ResumeSession(ctx echo.Context) error {
+	return c.changePauseState(ctx, false)
+}
+
+func (c *SessionController) changePauseState(ctx echo.Context, pause bool) error {
+	c.setCORSHeaders(ctx)
+
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this session")
+	}
+
+	pauser, ok := c.getSessionManager().(sessionPauser)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Pausing sessions is not supported")
+	}
+
+	var err error
+	if pause {
+		err = pauser.PauseSession(ctx.Request().Context(), sessionID)
+	} else {
+		err = pauser.ResumeSession(ctx.Request().Context(), sessionID)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotPausable) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		log.Printf("Failed to change pause state of session %s (pause=%t): %v", sessionID, pause, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update session")
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"session_id": sessionID,
+		"status":     session.Status(),
+	})
+}
+
+// routeToPausedSession answers a proxied request for a paused session. With
+// auto-resume enabled the session is resumed and the client is asked to
+// retry once the Pod is back.
+func (c *SessionController) routeToPausedSession(ctx echo.Context, session entities.Session) error {
+	pauser, ok := c.getSessionManager().(sessionPauser)
+	if !c.autoResume || !ok {
+		return echo.NewHTTPError(http.StatusConflict, "Session is paused")
+	}
+	if err := pauser.ResumeSession(ctx.Request().Context(), session.ID()); err != nil {
+		log.Printf("[ROUTE] Failed to auto-resume session %s: %v", session.ID(), err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Failed to resume session")
+	}
+	log.Printf("[ROUTE] Auto-resuming paused session %s", session.ID())
+	ctx.Response().Header().Set("Retry-After", "5")
+	return echo.NewHTTPError(http.StatusServiceUnavailable, "Session is resuming")
+}
+
+// sessionActivityRecorder is implemented by session managers that track the
+// last proxied request for idle session reaping.
+type sessionActivityRecorder interface {
+	RecordActivity(sessionID string)
+}
+
+// sessionAffinityHeader selects the replica of a multi-replica session that
+// serves a request. Requests without it are pinned by user, then client IP.
+const sessionAffinityHeader = "X-Session-Affinity-Key"
+
+// affinityAddresser is implemented by sessions that may be served by
+// several replicas.
+type affinityAddresser interface {
+	AddrFor(affinityKey string) string
+}
+
+// sessionTargetAddr returns the address the request is proxied to.
+func sessionTargetAddr(ctx echo.Context, session entities.Session) string {
+	s, ok := session.(affinityAddresser)
+	if !ok {
+		return session.Addr()
+	}
+	key := ctx.Request().Header.Get(sessionAffinityHeader)
+	if key == "" {
+		if user := auth.GetUserFromContext(ctx); user != nil {
+			key = string(user.ID())
+		}
+	}
+	if key == "" {
+		key = ctx.RealIP()
+	}
+	return s.AddrFor(key)
+}
+
+// SessionRestartingResponse is returned with 503 while the circuit breaker
+// of a session is open because its backend could not be reached
+type SessionRestartingResponse struct {
+	Error             string `json:"error"`
+	Message           string `json:"message"`
+	SessionID         string `json:"session_id"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+// sessionRestarting responds 503 with a Retry-After header and a structured body
+func sessionRestarting(ctx echo.Context, sessionID string, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return ctx.JSON(http.StatusServiceUnavailable, SessionRestartingResponse{
+		Error:             "session_restarting",
+		Message:           "The session backend is unreachable, it may be restarting. Retry later.",
+		SessionID:         sessionID,
+		RetryAfterSeconds: seconds,
+	})
+}
+
 // RouteToSession routes requests to the appropriate agentapi server instance
 func (c *SessionController) RouteToSession(ctx echo.Context) error {
 	sessionID := ctx.Param("sessionId")
@@ -693,10 +950,16 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 			log.Printf("User does not have access to session %s", sessionID)
 			return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
 		}
+		if session.Status() == "paused" {
+			return c.routeToPausedSession(ctx, session)
+		}
+		if recorder, ok := c.getSessionManager().(sessionActivityRecorder); ok {
+			recorder.RecordActivity(session.ID())
+		}
 	}
 
 	// Determine target URL using session address
-	targetURL := fmt.Sprintf("http://%s", session.Addr())
+	targetURL := fmt.Sprintf("http://%s", sessionTargetAddr(ctx, session))
 	target, err := url.Parse(targetURL)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Invalid target URL: %v", err))
@@ -708,14 +971,22 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 		c.updateSessionTimestamp(ctx, session)
 	}
 
-	req := ctx.Request()
+	// Stop hammering a backend that keeps failing, e.g. while its Pod restarts
+	breaker := c.proxy.Breaker()
+	if retryAfter, ok := breaker.Allow(session.ID()); !ok {
+		return sessionRestarting(ctx, sessionID, retryAfter)
+	}
+
+	req, trace := startProxyTrace(ctx.Request(), sessionID, "local")
+	defer trace.end()
 	w := ctx.Response()
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
-	proxy.FlushInterval = time.Millisecond * 100
+	// SSE responses are streamed unbuffered and WebSocket upgrades are passed
+	// through by the streaming proxy.
+	sessionProxy := c.proxy.ReverseProxy(target)
 
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
+	originalDirector := sessionProxy.Director
+	sessionProxy.Director = func(req *http.Request) {
 		originalDirector(req)
 
 		// Remove session ID from path before forwarding
@@ -739,21 +1010,15 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 		}
 	}
 
-	originalModifyResponse := proxy.ModifyResponse
-	proxy.ModifyResponse = func(resp *http.Response) error {
+	originalModifyResponse := sessionProxy.ModifyResponse
+	sessionProxy.ModifyResponse = func(resp *http.Response) error {
 		// Set CORS headers
 		resp.Header.Set("Access-Control-Allow-Origin", "*")
 		resp.Header.Set("Access-Control-Allow-Methods", "GET, HEAD, PUT, PATCH, POST, DELETE, OPTIONS")
 		resp.Header.Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-Forwarded-For, X-Forwarded-Proto, X-Forwarded-Host, X-API-Key")
 		resp.Header.Set("Access-Control-Allow-Credentials", "true")
 		resp.Header.Set("Access-Control-Max-Age", "86400")
-
-		// Handle SSE streams
-		if resp.Header.Get("Content-Type") == "text/event-stream" {
-			resp.Header.Set("Cache-Control", "no-cache")
-			resp.Header.Set("Connection", "keep-alive")
-			resp.Header.Del("Content-Length")
-		}
+		breaker.Success(session.ID())
 
 		if originalModifyResponse != nil {
 			return originalModifyResponse(resp)
@@ -761,8 +1026,11 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 		return nil
 	}
 
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+	sessionProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
 		log.Printf("Proxy error for session %s: %v", sessionID, err)
+		if !errors.Is(err, context.Canceled) {
+			breaker.Failure(session.ID())
+		}
 
 		// When the request is for the agent's /status endpoint and agentapi is
 		// unreachable, check the provisioner's own /status to distinguish a
@@ -770,7 +1038,7 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 		// startup delay (provisioner still pending/provisioning → HTTP 502).
 		if strings.HasSuffix(r.URL.Path, "/status") {
 			if ks, ok := session.(*services.KubernetesSession); ok {
-				provisionerURL := fmt.Sprintf("http://%s:%d/status", ks.ServiceDNS(), services.ProvisionerPort)
+				provisionerURL := fmt.Sprintf("http://%s:%d/status", ks.Host(), services.ProvisionerPort)
 				provClient := &http.Client{Timeout: 2 * time.Second}
 				provResp, provErr := provClient.Get(provisionerURL)
 				if provErr == nil {
@@ -789,8 +1057,9 @@ func (c *SessionController) RouteToSession(ctx echo.Context) error {
 
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 	}
+	trace.instrument(sessionProxy)
 
-	proxy.ServeHTTP(w, req)
+	sessionProxy.ServeHTTP(w, req)
 	return nil
 }
 
@@ -903,7 +1172,10 @@ func (c *SessionController) routeToRemoteSession(ctx echo.Context, route *reposi
 		log.Printf("[REMOTE_ROUTE] Failed to proxy External Session Manager session %s: %v", sessionID, proxyErr)
 		http.Error(w, "Failed to reach external session manager", http.StatusBadGateway)
 	}
-	proxy.ServeHTTP(ctx.Response(), ctx.Request())
+	req, trace := startProxyTrace(ctx.Request(), sessionID, "external")
+	defer trace.end()
+	trace.instrument(proxy)
+	proxy.ServeHTTP(ctx.Response(), req)
 	return nil
 }
 
@@ -1034,6 +1306,7 @@ func (c *SessionController) updateSessionTimestamp(ctx echo.Context, session ent
 				if err := manager.UpdateServiceAnnotation(updateCtx, session.ID(), "agentapi.proxy/last-message-at", lastMessageAt); err != nil {
 					log.Printf("[SESSION] Failed to update last-message-at annotation for session %s: %v", session.ID(), err)
 				}
+				manager.RecordMessageSent(session.ID())
 			}
 		}()
 	}
@@ -1059,6 +1332,89 @@ func (c *SessionController) setCORSHeaders(ctx echo.Context) {
 	ctx.Response().Header().Set("Access-Control-Max-Age", "86400")
 }
 
+// applySessionProfileConfig merges a session profile config into req. The
+// profile is the base; fields already set in req override it.
+func applySessionProfileConfig(cfg entities.SessionProfileConfig, req *entities.StartRequest, explicitSandbox, explicitDocker bool) {
+	// Environment: profile is base, request keys override
+	if len(cfg.Environment()) > 0 {
+		merged := make(map[string]string, len(cfg.Environment()))
+		for k, v := range cfg.Environment() {
+			merged[k] = v
+		}
+		for k, v := range req.Environment {
+			merged[k] = v
+		}
+		req.Environment = merged
+	}
+
+	// Tags: profile is base, request keys override
+	if len(cfg.Tags()) > 0 {
+		merged := make(map[string]string, len(cfg.Tags()))
+		for k, v := range cfg.Tags() {
+			merged[k] = v
+		}
+		for k, v := range req.Tags {
+			merged[k] = v
+		}
+		req.Tags = merged
+	}
+
+	// Params: profile is base, request fields override per-field
+	if cfg.Params() != nil {
+		if req.Params == nil {
+			req.Params = cfg.Params()
+		} else {
+			req.Params = mergeSessionParams(cfg.Params(), req.Params)
+		}
+	}
+	if containsAllocatorSelector(req.Tags) {
+		removeImplicitAllocatorCapabilities(req.Params, explicitSandbox, explicitDocker)
+	}
+
+	// MemoryKey: profile is base, request keys override
+	if len(cfg.MemoryKey()) > 0 {
+		merged := make(map[string]string, len(cfg.MemoryKey()))
+		for k, v := range cfg.MemoryKey() {
+			merged[k] = v
+		}
+		for k, v := range req.MemoryKey {
+			merged[k] = v
+		}
+		req.MemoryKey = merged
+	}
+
+	// SandboxPolicyID: apply profile's policy when request does not already specify one.
+	if req.Params == nil {
+		req.Params = &entities.SessionParams{}
+	}
+	// Native allocator sessions intentionally do not support sandboxing.
+	// Do not let a profile's implicit sandbox default turn an otherwise valid
+	// allocator.* request into an unsupported-capability request. An explicit
+	// sandbox in the request remains intact and is rejected by the allocator
+	// selection layer.
+	if !containsAllocatorSelector(req.Tags) {
+		applyProfileSandboxDefaults(cfg, req.Params)
+	}
+
+	// SessionTTL: apply profile's TTL when request does not already specify one.
+	if cfg.SessionTTL() != "" {
+		if req.Params == nil {
+			req.Params = &entities.SessionParams{}
+		}
+		if req.Params.SessionTTL == "" {
+			req.Params.SessionTTL = cfg.SessionTTL()
+		}
+	}
+	if len(cfg.UnsyncedFilePaths()) > 0 {
+		if req.Params == nil {
+			req.Params = &entities.SessionParams{}
+		}
+		if len(req.Params.UnsyncedFilePaths) == 0 {
+			req.Params.UnsyncedFilePaths = cfg.UnsyncedFilePaths()
+		}
+	}
+}
+
 // mergeSessionParams merges base (profile) params with override (request) params.
 // For each field: if the override field is the zero value, the base value is used.
 func mergeSessionParams(base, override *entities.SessionParams) *entities.SessionParams {
@@ -1099,6 +1455,15 @@ func mergeSessionParams(base, override *entities.SessionParams) *entities.Sessio
 	if override.Docker != nil {
 		merged.Docker = override.Docker
 	}
+	if override.Editor != nil {
+		merged.Editor = override.Editor
+	}
+	if override.Browser != nil {
+		merged.Browser = override.Browser
+	}
+	if override.Terminal != nil {
+		merged.Terminal = override.Terminal
+	}
 	if override.AuthProxy != nil {
 		merged.AuthProxy = override.AuthProxy
 	}
@@ -1111,6 +1476,21 @@ func mergeSessionParams(base, override *entities.SessionParams) *entities.Sessio
 	if override.CredentialSource != "" {
 		merged.CredentialSource = override.CredentialSource
 	}
+	if len(override.SetupHooks) > 0 {
+		merged.SetupHooks = append([]entities.SetupHook(nil), override.SetupHooks...)
+	}
+	if len(override.PostSessionHooks) > 0 {
+		merged.PostSessionHooks = append([]entities.SetupHook(nil), override.PostSessionHooks...)
+	}
+	if override.Replicas > 0 {
+		merged.Replicas = override.Replicas
+	}
+	if override.Accelerator != nil {
+		merged.Accelerator = override.Accelerator
+	}
+	if override.Image != "" {
+		merged.Image = override.Image
+	}
 	return &merged
 }
 
@@ -1163,7 +1543,7 @@ func (c *SessionController) GetSessionSandboxDomains(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotImplemented, "Sandbox domains not available for this session type")
 	}
 
-	provisionerURL := fmt.Sprintf("http://%s:%d/sandbox-domains", ks.ServiceDNS(), services.ProvisionerPort)
+	provisionerURL := fmt.Sprintf("http://%s:%d/sandbox-domains", ks.Host(), services.ProvisionerPort)
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Get(provisionerURL)
 	if err != nil {
@@ -1216,6 +1596,7 @@ func (c *SessionController) resolveSessionProfile(ctx context.Context, profileID
 		log.Printf("[SESSION] Warning: could not list session profiles for default lookup: %v", err)
 		return nil
 	}
+	profiles = sessionuc.WithoutTeamDefaults(profiles)
 	if profile := selectSessionProfileByTags(profiles, tags); profile != nil {
 		log.Printf("[SESSION] Applying tag-selected session profile %q (%s) for user %s", profile.ID(), profile.Name(), userID)
 		return profile
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// Types of the events of GET /events
+const (
+	lifecycleEventCreated       = "created"
+	lifecycleEventStatusChanged = "status-changed"
+	lifecycleEventDeleted       = "deleted"
+)
+
+// lifecycleHeartbeatInterval is how often an idle lifecycle stream sends a
+// heartbeat comment
+const lifecycleHeartbeatInterval = 30 * time.Second
+
+// SessionLifecycleWatcher is implemented by session managers that publish
+// the lifecycle events and status changes of every session, including those
+// of other replicas. KubernetesSessionManager implements this.
+type SessionLifecycleWatcher interface {
+	SubscribeSessionEvents() (<-chan entities.SessionEvent, func())
+	SubscribeStatusEvents() (<-chan services.SessionStatusEvent, func())
+}
+
+// sessionLifecycleEvent is the payload of a lifecycle stream event
+type sessionLifecycleEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	Status    string    `json:"status,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// lifecycleStream forwards the lifecycle events a caller may see. Deleted
+// sessions may be gone by the time their event arrives, so the stream
+// remembers which sessions the caller could see.
+type lifecycleStream struct {
+	manager interface {
+		GetSession(id string) entities.Session
+	}
+	authzCtx  *auth.AuthorizationContext
+	sessionID string // only this session when set
+	visible   map[string]bool
+}
+
+// visibleSession reports whether the caller may see the session, checking
+// sessions not seen before
+func (s *lifecycleStream) visibleSession(sessionID string) bool {
+	if s.sessionID != "" && sessionID != s.sessionID {
+		return false
+	}
+	if visible, ok := s.visible[sessionID]; ok {
+		return visible
+	}
+	session := s.manager.GetSession(sessionID)
+	if session == nil {
+		return false
+	}
+	visible := s.authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID())
+	s.visible[sessionID] = visible
+	return visible
+}
+
+// sessionEvent converts a timeline event of a visible session. Only the
+// created and deleted events of the timeline are streamed.
+func (s *lifecycleStream) sessionEvent(evt entities.SessionEvent) (sessionLifecycleEvent, bool) {
+	var eventType string
+	switch evt.Type {
+	case entities.SessionEventCreated:
+		eventType = lifecycleEventCreated
+	case entities.SessionEventDeleted:
+		eventType = lifecycleEventDeleted
+	default:
+		return sessionLifecycleEvent{}, false
+	}
+	if !s.visibleSession(evt.SessionID) {
+		return sessionLifecycleEvent{}, false
+	}
+	if eventType == lifecycleEventDeleted {
+		delete(s.visible, evt.SessionID)
+	}
+	return sessionLifecycleEvent{
+		Type:      eventType,
+		SessionID: evt.SessionID,
+		Message:   evt.Message,
+		Timestamp: evt.Timestamp,
+	}, true
+}
+
+// statusEvent converts a status change of a visible session
+func (s *lifecycleStream) statusEvent(evt services.SessionStatusEvent) (sessionLifecycleEvent, bool) {
+	if !s.visibleSession(evt.SessionID) {
+		return sessionLifecycleEvent{}, false
+	}
+	return sessionLifecycleEvent{
+		Type:      lifecycleEventStatusChanged,
+		SessionID: evt.SessionID,
+		Status:    evt.Status,
+		Timestamp: evt.Timestamp,
+	}, true
+}
+
+// StreamSessionLifecycle handles GET /events. It opens a Server-Sent Events
+// stream of the created, status-changed and deleted events of every session
+// the caller can access, so that dashboards can update without polling
+// /search.
+func (c *SessionController) StreamSessionLifecycle(ctx echo.Context) error {
+	return c.streamSessionLifecycle(ctx, "")
+}
+
+// StreamSessionEvents handles GET /sessions/:sessionId/events/stream. It
+// streams the lifecycle events of one session like GET /events and ends
+// once the session is deleted.
+func (c *SessionController) StreamSessionEvents(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+	return c.streamSessionLifecycle(ctx, session.ID())
+}
+
+func (c *SessionController) streamSessionLifecycle(ctx echo.Context, sessionID string) error {
+	manager := c.getSessionManager()
+	watcher, ok := manager.(SessionLifecycleWatcher)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "event streaming not supported by this session manager")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	stream := &lifecycleStream{
+		manager:   manager,
+		authzCtx:  authzCtx,
+		sessionID: sessionID,
+		visible:   make(map[string]bool),
+	}
+
+	sessionEvents, cancelSessionEvents := watcher.SubscribeSessionEvents()
+	defer cancelSessionEvents()
+	statusEvents, cancelStatusEvents := watcher.SubscribeStatusEvents()
+	defer cancelStatusEvents()
+
+	log.Printf("[SSE] Client connected to %s from %s (user: %s)", ctx.Path(), ctx.RealIP(), authzCtx.PersonalScope.UserID)
+	defer log.Printf("[SSE] Client disconnected from %s (user: %s)", ctx.Path(), authzCtx.PersonalScope.UserID)
+
+	r := ctx.Response()
+	r.Header().Set("Content-Type", "text/event-stream")
+	r.Header().Set("Cache-Control", "no-cache")
+	r.Header().Set("Connection", "keep-alive")
+	r.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	r.WriteHeader(http.StatusOK)
+	flusher, hasFlusher := r.Writer.(http.Flusher)
+	if hasFlusher {
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(lifecycleHeartbeatInterval)
+	defer heartbeat.Stop()
+	reqCtx := ctx.Request().Context()
+
+	for {
+		var (
+			evt  sessionLifecycleEvent
+			send bool
+		)
+		select {
+		case <-reqCtx.Done():
+			return nil
+		case e, open := <-sessionEvents:
+			if !open {
+				return nil
+			}
+			evt, send = stream.sessionEvent(e)
+		case e, open := <-statusEvents:
+			if !open {
+				return nil
+			}
+			evt, send = stream.statusEvent(e)
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(r, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			if hasFlusher {
+				flusher.Flush()
+			}
+		}
+		if !send {
+			continue
+		}
+		if err := writeLifecycleSSEEvent(r, evt); err != nil {
+			return nil
+		}
+		if hasFlusher {
+			flusher.Flush()
+		}
+		if sessionID != "" && evt.Type == lifecycleEventDeleted {
+			return nil
+		}
+	}
+}
+
+// writeLifecycleSSEEvent writes evt as an SSE event named after its type
+func writeLifecycleSSEEvent(w *echo.Response, evt sessionLifecycleEvent) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, payload)
+	return err
+}
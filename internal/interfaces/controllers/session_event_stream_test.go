@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+type mockLifecycleSessionManager struct {
+	*mockWaitSessionManager
+	mu            sync.Mutex
+	sessions      map[string]entities.Session
+	sessionEvents chan entities.SessionEvent
+	statusEvents  chan services.SessionStatusEvent
+}
+
+func (m *mockLifecycleSessionManager) GetSession(id string) entities.Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[id]
+}
+
+func (m *mockLifecycleSessionManager) removeSession(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+}
+
+func (m *mockLifecycleSessionManager) SubscribeSessionEvents() (<-chan entities.SessionEvent, func()) {
+	return m.sessionEvents, func() {}
+}
+
+func (m *mockLifecycleSessionManager) SubscribeStatusEvents() (<-chan services.SessionStatusEvent, func()) {
+	return m.statusEvents, func() {}
+}
+
+func makeLifecycleEchoContext(ctx context.Context, path, sessionID, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if sessionID != "" {
+		c.SetParamNames("sessionId")
+		c.SetParamValues(sessionID)
+	}
+	c.Set("authz_context", &auth.AuthorizationContext{
+		PersonalScope: auth.PersonalScopeAuth{UserID: userID, CanRead: true},
+		TeamScope:     auth.TeamScopeAuth{TeamPermissions: make(map[string]auth.TeamPermissions)},
+	})
+	return c, rec
+}
+
+func TestStreamSessionLifecycle_FiltersByVisibility(t *testing.T) {
+	mine := &mockWaitSession{id: "mine", userID: "alice"}
+	manager := &mockLifecycleSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(nil),
+		sessions: map[string]entities.Session{
+			"mine":   mine,
+			"theirs": &mockWaitSession{id: "theirs", userID: "bob"},
+		},
+		sessionEvents: make(chan entities.SessionEvent, 10),
+		statusEvents:  make(chan services.SessionStatusEvent, 10),
+	}
+	c := NewSessionController(&mockWaitProvider{manager: manager}, nil)
+
+	now := time.Now()
+	manager.sessionEvents <- entities.SessionEvent{SessionID: "theirs", Type: entities.SessionEventCreated, Timestamp: now}
+	manager.sessionEvents <- entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventCreated, Message: "Session created", Timestamp: now}
+	manager.sessionEvents <- entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventMessageSent, Timestamp: now}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx, rec := makeLifecycleEchoContext(reqCtx, "/events", "", "alice")
+	done := make(chan error, 1)
+	go func() { done <- c.StreamSessionLifecycle(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	manager.statusEvents <- services.SessionStatusEvent{SessionID: "mine", Status: "running", Timestamp: now}
+	manager.statusEvents <- services.SessionStatusEvent{SessionID: "theirs", Status: "running", Timestamp: now}
+	// The deleted session is gone, but the stream remembers it was visible
+	manager.removeSession("mine")
+	manager.sessionEvents <- entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventDeleted, Timestamp: now}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	body := rec.Body.String()
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Contains(t, body, "event: created\ndata: {\"type\":\"created\",\"session_id\":\"mine\",\"message\":\"Session created\"")
+	assert.Contains(t, body, "event: status-changed\ndata: {\"type\":\"status-changed\",\"session_id\":\"mine\",\"status\":\"running\"")
+	assert.Contains(t, body, "event: deleted\ndata: {\"type\":\"deleted\",\"session_id\":\"mine\"")
+	assert.NotContains(t, body, "theirs")
+	assert.NotContains(t, body, "message-sent")
+}
+
+func TestStreamSessionEvents_EndsWhenDeleted(t *testing.T) {
+	session := &mockWaitSession{id: "mine", userID: "alice"}
+	manager := &mockLifecycleSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(nil),
+		sessions:               map[string]entities.Session{"mine": session, "other": &mockWaitSession{id: "other", userID: "alice"}},
+		sessionEvents:          make(chan entities.SessionEvent, 10),
+		statusEvents:           make(chan services.SessionStatusEvent, 10),
+	}
+	c := NewSessionController(&mockWaitProvider{manager: manager}, nil)
+
+	manager.statusEvents <- services.SessionStatusEvent{SessionID: "other", Status: "running"}
+	manager.statusEvents <- services.SessionStatusEvent{SessionID: "mine", Status: "stopped"}
+	manager.sessionEvents <- entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventDeleted}
+
+	ctx, rec := makeLifecycleEchoContext(context.Background(), "/sessions/mine/events/stream", "mine", "alice")
+	require.NoError(t, c.StreamSessionEvents(ctx))
+
+	body := rec.Body.String()
+	assert.NotContains(t, body, "other")
+	assert.True(t, strings.HasSuffix(body, "\n\n"))
+	assert.Contains(t, body, "event: deleted")
+
+	ctx, _ = makeLifecycleEchoContext(context.Background(), "/sessions/mine/events/stream", "mine", "mallory")
+	err := c.StreamSessionEvents(ctx)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// sessionEventLister is implemented by session managers that keep a
+// lifecycle event timeline per session.
+type sessionEventLister interface {
+	ListSessionEvents(ctx context.Context, sessionID string) ([]entities.SessionEvent, error)
+}
+
+// SessionEventsResponse is the response of GET /sessions/:sessionId/events.
+type SessionEventsResponse struct {
+	SessionID string                  `json:"session_id"`
+	Events    []entities.SessionEvent `json:"events"`
+}
+
+// GetSessionEvents handles GET /sessions/:sessionId/events.
+// Events are returned oldest first.
+func (c *SessionController) GetSessionEvents(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	lister, ok := c.getSessionManager().(sessionEventLister)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "event timeline not supported by this session manager")
+	}
+	events, err := lister.ListSessionEvents(ctx.Request().Context(), sessionID)
+	if err != nil {
+		log.Printf("Failed to list events of session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list session events")
+	}
+	return ctx.JSON(http.StatusOK, SessionEventsResponse{SessionID: sessionID, Events: events})
+}
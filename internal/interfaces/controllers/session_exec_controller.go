@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+const (
+	// defaultExecTimeout and maxExecTimeout bound non-interactive execs.
+	defaultExecTimeout = time.Minute
+	maxExecTimeout     = 10 * time.Minute
+	// maxExecOutput caps stdout and stderr of a non-interactive exec.
+	maxExecOutput = 1 << 20
+)
+
+// sessionExecutor is implemented by session managers that can exec into
+// session containers.
+type sessionExecutor interface {
+	ExecInSession(ctx context.Context, sessionID string, req entities.SessionExecRequest, streams services.ExecStreams) (int, error)
+}
+
+// execUpgrader keeps the default same-origin check so that pages on other
+// sites cannot open an exec with the user's cookies.
+var execUpgrader = websocket.Upgrader{ReadBufferSize: 32 * 1024, WriteBufferSize: 32 * 1024}
+
+// ExecCommandRequest is the body of a non-interactive POST /sessions/:sessionId/exec.
+type ExecCommandRequest struct {
+	entities.SessionExecRequest
+	// Stdin is written to the standard input of the command.
+	Stdin string `json:"stdin,omitempty"`
+	// TimeoutSeconds defaults to 60 and is capped at 600.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// ExecCommandResponse is the result of a non-interactive exec.
+type ExecCommandResponse struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// execControlMessage is a text frame sent by WebSocket exec clients.
+type execControlMessage struct {
+	Resize *entities.TerminalSize `json:"resize,omitempty"`
+}
+
+// ExecSession handles /sessions/:sessionId/exec. A WebSocket upgrade opens an
+// interactive exec (binary frames carry stdin and output, text frames carry
+// JSON control messages); a plain POST runs a command and returns its output.
+// Only the session owner and admins may exec, only into sessions granted the
+// exec capability, and every exec is audited.
+func (c *SessionController) ExecSession(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	user := auth.GetUserFromContext(ctx)
+	if user == nil || (string(user.ID()) != session.UserID() && !user.IsAdmin()) {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the session owner or an admin can exec into this session")
+	}
+	executor, ok := c.getSessionManager().(sessionExecutor)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "exec not supported by this session manager")
+	}
+
+	if websocket.IsWebSocketUpgrade(ctx.Request()) {
+		return c.execInteractive(ctx, executor, sessionID, string(user.ID()))
+	}
+	if ctx.Request().Method != http.MethodPost {
+		return echo.NewHTTPError(http.StatusMethodNotAllowed, "Use POST or a WebSocket upgrade")
+	}
+
+	var req ExecCommandRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Command) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "command is required")
+	}
+	req.TTY = false
+	timeout := defaultExecTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(req.TimeoutSeconds)*time.Second, maxExecTimeout)
+	}
+	execCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	stdout.limit, stderr.limit = maxExecOutput, maxExecOutput
+	streams := services.ExecStreams{Stdout: &stdout, Stderr: &stderr}
+	if req.Stdin != "" {
+		streams.Stdin = bytes.NewReader([]byte(req.Stdin))
+	}
+
+	auditExecStart(sessionID, string(user.ID()), ctx.RealIP(), req.SessionExecRequest)
+	start := time.Now()
+	exitCode, err := executor.ExecInSession(execCtx, sessionID, req.SessionExecRequest, streams)
+	auditExecEnd(sessionID, string(user.ID()), exitCode, time.Since(start), err)
+	if err != nil {
+		return execError(sessionID, err)
+	}
+	return ctx.JSON(http.StatusOK, ExecCommandResponse{
+		ExitCode: exitCode,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	})
+}
+
+// execInteractive bridges a WebSocket to an exec with a terminal. Query
+// parameters: container, command (repeatable) and tty (default true).
+func (c *SessionController) execInteractive(ctx echo.Context, executor sessionExecutor, sessionID, userID string) error {
+	req := entities.SessionExecRequest{
+		Container: ctx.QueryParam("container"),
+		Command:   ctx.QueryParams()["command"],
+		TTY:       ctx.QueryParam("tty") != "false",
+	}
+	conn, err := execUpgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		// Upgrade has already written the error response.
+		return nil
+	}
+	defer func() { _ = conn.Close() }()
+
+	execCtx, cancel := context.WithCancel(ctx.Request().Context())
+	defer cancel()
+	stdinR, stdinW := io.Pipe()
+	resize := make(chan entities.TerminalSize, 4)
+	go func() {
+		defer cancel()
+		defer close(resize)
+		defer func() { _ = stdinW.Close() }()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType == websocket.BinaryMessage {
+				if _, err := stdinW.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+			var msg execControlMessage
+			if json.Unmarshal(data, &msg) == nil && msg.Resize != nil {
+				select {
+				case resize <- *msg.Resize:
+				default:
+				}
+			}
+		}
+	}()
+
+	out := &wsBinaryWriter{conn: conn}
+	auditExecStart(sessionID, userID, ctx.RealIP(), req)
+	start := time.Now()
+	exitCode, err := executor.ExecInSession(execCtx, sessionID, req, services.ExecStreams{
+		Stdin:  stdinR,
+		Stdout: out,
+		Stderr: out,
+		Resize: resize,
+	})
+	auditExecEnd(sessionID, userID, exitCode, time.Since(start), err)
+	_ = stdinR.Close()
+
+	result := map[string]interface{}{"exit_code": exitCode}
+	if err != nil {
+		result = map[string]interface{}{"error": err.Error()}
+	}
+	data, _ := json.Marshal(result)
+	out.mu.Lock()
+	_ = conn.WriteMessage(websocket.TextMessage, data)
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	out.mu.Unlock()
+	return nil
+}
+
+func execError(sessionID string, err error) error {
+	switch {
+	case errors.Is(err, entities.ErrExecDisabled):
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	case errors.Is(err, entities.ErrCapabilityNotAllowed):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, entities.ErrUnknownContainer):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, entities.ErrSessionPodUnavailable):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	log.Printf("Exec in session %s failed: %v", sessionID, err)
+	return echo.NewHTTPError(http.StatusBadGateway, "Exec failed")
+}
+
+func auditExecStart(sessionID, userID, clientIP string, req entities.SessionExecRequest) {
+	log.Printf("[AUDIT] session_exec session=%s user=%s client_ip=%s container=%q tty=%v command=%q",
+		sessionID, userID, clientIP, req.Container, req.TTY, req.Command)
+}
+
+func auditExecEnd(sessionID, userID string, exitCode int, duration time.Duration, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	log.Printf("[AUDIT] session_exec_end session=%s user=%s exit_code=%d duration=%s error=%q",
+		sessionID, userID, exitCode, duration.Round(time.Millisecond), errMsg)
+}
+
+// wsBinaryWriter writes exec output as binary WebSocket frames. gorilla
+// connections allow one concurrent writer, hence the mutex.
+type wsBinaryWriter struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (w *wsBinaryWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest. The buffer is not embedded so that its WriteString and ReadFrom
+// cannot bypass the limit.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
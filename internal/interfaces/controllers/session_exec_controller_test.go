@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+type mockExecSessionManager struct {
+	*mockWaitSessionManager
+	requests []entities.SessionExecRequest
+	err      error
+}
+
+func (m *mockExecSessionManager) ExecInSession(_ context.Context, _ string, req entities.SessionExecRequest, streams services.ExecStreams) (int, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return 0, m.err
+	}
+	var stdin []byte
+	if streams.Stdin != nil {
+		stdin, _ = io.ReadAll(streams.Stdin)
+	}
+	_, _ = io.WriteString(streams.Stdout, "got "+string(stdin))
+	_, _ = io.WriteString(streams.Stderr, strings.Repeat("e", maxExecOutput+10))
+	return 3, nil
+}
+
+func makeExecEchoContext(body string, user *entities.User) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/sessions/sess-1/exec", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sessionId")
+	c.SetParamValues("sess-1")
+	if user != nil {
+		c.Set("internal_user", user)
+	}
+	return c, rec
+}
+
+func TestSessionController_ExecSession(t *testing.T) {
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	manager := &mockExecSessionManager{mockWaitSessionManager: newMockWaitSessionManager(session)}
+	c := NewSessionController(&mockWaitProvider{manager: manager}, nil)
+
+	ctx, rec := makeExecEchoContext(`{"command":["cat"],"stdin":"input","tty":true}`, entities.NewUser("alice", entities.UserTypeRegular, "alice"))
+	require.NoError(t, c.ExecSession(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"exit_code":3`)
+	assert.Contains(t, rec.Body.String(), `"stdout":"got input"`)
+	require.Len(t, manager.requests, 1)
+	assert.False(t, manager.requests[0].TTY, "non-interactive execs never get a terminal")
+	assert.NotContains(t, rec.Body.String(), strings.Repeat("e", maxExecOutput+1), "stderr should be capped")
+
+	// Team members who are not the owner cannot exec, admins can.
+	ctx, _ = makeExecEchoContext(`{"command":["id"]}`, entities.NewUser("bob", entities.UserTypeRegular, "bob"))
+	err := c.ExecSession(ctx)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok, "expected HTTPError, got %v", err)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	admin := entities.NewUser("carol", entities.UserTypeRegular, "carol")
+	require.NoError(t, admin.SetRoles([]entities.Role{entities.RoleAdmin}))
+	ctx, rec = makeExecEchoContext(`{"command":["id"]}`, admin)
+	require.NoError(t, c.ExecSession(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Sessions whose capability policy does not allow exec.
+	manager.err = fmt.Errorf("%w: exec not approved", entities.ErrCapabilityNotAllowed)
+	ctx, _ = makeExecEchoContext(`{"command":["id"]}`, entities.NewUser("alice", entities.UserTypeRegular, "alice"))
+	err = c.ExecSession(ctx)
+	httpErr, ok = err.(*echo.HTTPError)
+	require.True(t, ok, "expected HTTPError, got %v", err)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	manager.err = nil
+
+	ctx, _ = makeExecEchoContext(`{}`, entities.NewUser("alice", entities.UserTypeRegular, "alice"))
+	err = c.ExecSession(ctx)
+	httpErr, ok = err.(*echo.HTTPError)
+	require.True(t, ok, "expected HTTPError, got %v", err)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
+)
+
+// sessionExtensionsGetter is implemented by session managers that record the
+// marketplaces and plugins reported by session Pods.
+type sessionExtensionsGetter interface {
+	GetSessionExtensions(ctx context.Context, sessionID string) (*startup.ExtensionReport, error)
+}
+
+// SessionExtensionsResponse is the response of GET /sessions/:sessionId/extensions.
+type SessionExtensionsResponse struct {
+	SessionID string `json:"session_id"`
+	// Reported is false until the session Pod has finished its marketplace setup.
+	Reported     bool                      `json:"reported"`
+	Marketplaces []startup.ExtensionStatus `json:"marketplaces"`
+	Plugins      []startup.ExtensionStatus `json:"plugins"`
+	UpdatedAt    string                    `json:"updated_at,omitempty"`
+}
+
+// GetSessionExtensions handles GET /sessions/:sessionId/extensions.
+func (c *SessionController) GetSessionExtensions(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	getter, ok := c.getSessionManager().(sessionExtensionsGetter)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "extension reports not supported by this session manager")
+	}
+	report, err := getter.GetSessionExtensions(ctx.Request().Context(), sessionID)
+	if err != nil {
+		log.Printf("Failed to get extensions of session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get session extensions")
+	}
+
+	resp := SessionExtensionsResponse{
+		SessionID:    sessionID,
+		Marketplaces: []startup.ExtensionStatus{},
+		Plugins:      []startup.ExtensionStatus{},
+	}
+	if report != nil {
+		resp.Reported = true
+		if report.Marketplaces != nil {
+			resp.Marketplaces = report.Marketplaces
+		}
+		if report.Plugins != nil {
+			resp.Plugins = report.Plugins
+		}
+		if !report.UpdatedAt.IsZero() {
+			resp.UpdatedAt = report.UpdatedAt.Format(time.RFC3339)
+		}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
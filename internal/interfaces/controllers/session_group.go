@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// errSessionGroupForbidden is reported for the members of a group the caller
+// may see but not modify
+var errSessionGroupForbidden = errors.New("you don't have permission to update this session")
+
+// SessionGroupMember is the status and the artifacts of one session of a
+// group
+type SessionGroupMember struct {
+	SessionID   string                      `json:"session_id"`
+	UserID      string                      `json:"user_id"`
+	Scope       entities.ResourceScope      `json:"scope"`
+	TeamID      string                      `json:"team_id,omitempty"`
+	Status      string                      `json:"status"`
+	StartedAt   time.Time                   `json:"started_at"`
+	UpdatedAt   time.Time                   `json:"updated_at"`
+	Description string                      `json:"description,omitempty"`
+	Tags        map[string]string           `json:"tags,omitempty"`
+	Annotations entities.SessionAnnotations `json:"annotations"`
+	// Completion is the outcome of a finished oneshot session
+	Completion *entities.SessionCompletion `json:"completion,omitempty"`
+}
+
+// SessionGroupResponse is the response of GET /session-groups/:group
+type SessionGroupResponse struct {
+	Group   string               `json:"group"`
+	Total   int                  `json:"total"`
+	Members []SessionGroupMember `json:"members"`
+	// Statuses counts the members per status
+	Statuses map[string]int `json:"statuses"`
+}
+
+// SessionGroupMessageRequest is the body of POST /session-groups/:group/messages
+type SessionGroupMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// SessionGroupResult is the outcome of a group operation for one member
+type SessionGroupResult struct {
+	SessionID string `json:"session_id"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SessionGroupResultsResponse is the response of the group operations that
+// act on every member
+type SessionGroupResultsResponse struct {
+	Group     string               `json:"group"`
+	Results   []SessionGroupResult `json:"results"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+}
+
+// add records the outcome of the operation for one member
+func (r *SessionGroupResultsResponse) add(sessionID string, err error) {
+	result := SessionGroupResult{SessionID: sessionID, OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// applySessionGroup validates the group of a new session and stores it in
+// the group tag
+func applySessionGroup(req *entities.StartRequest) error {
+	group := req.Group
+	if group == "" {
+		group = req.Tags[entities.SessionGroupTag]
+	}
+	if group == "" {
+		return nil
+	}
+	if err := entities.ValidateSessionGroup(group); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	tags := make(map[string]string, len(req.Tags)+1)
+	for k, v := range req.Tags {
+		tags[k] = v
+	}
+	tags[entities.SessionGroupTag] = group
+	req.Tags = tags
+	req.Group = group
+	return nil
+}
+
+// sessionGroupMembers returns the group from the path and the sessions of the
+// group the caller may access, oldest first. A group without such sessions is
+// not found.
+func (c *SessionController) sessionGroupMembers(ctx echo.Context) (string, []entities.Session, error) {
+	group := ctx.Param("group")
+	if err := entities.ValidateSessionGroup(group); err != nil {
+		return "", nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil {
+		return "", nil, echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	var members []entities.Session
+	filter := entities.SessionFilter{Tags: map[string]string{entities.SessionGroupTag: group}}
+	for _, session := range c.getSessionManager().ListSessions(filter) {
+		if authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+			members = append(members, session)
+		}
+	}
+	if len(members) == 0 {
+		return "", nil, echo.NewHTTPError(http.StatusNotFound, "Session group not found")
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].StartedAt().Equal(members[j].StartedAt()) {
+			return members[i].StartedAt().Before(members[j].StartedAt())
+		}
+		return members[i].ID() < members[j].ID()
+	})
+	return group, members, nil
+}
+
+// GetSessionGroup handles GET /session-groups/:group and returns the status,
+// annotations and completion of every session of the group
+func (c *SessionController) GetSessionGroup(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	group, members, err := c.sessionGroupMembers(ctx)
+	if err != nil {
+		return err
+	}
+	resp := SessionGroupResponse{
+		Group:    group,
+		Total:    len(members),
+		Members:  make([]SessionGroupMember, 0, len(members)),
+		Statuses: make(map[string]int),
+	}
+	for _, session := range members {
+		annotations := getSessionAnnotations(session)
+		member := SessionGroupMember{
+			SessionID:   session.ID(),
+			UserID:      session.UserID(),
+			Scope:       session.Scope(),
+			TeamID:      session.TeamID(),
+			Status:      session.Status(),
+			StartedAt:   session.StartedAt(),
+			UpdatedAt:   session.UpdatedAt(),
+			Description: session.Description(),
+			Tags:        session.Tags(),
+			Annotations: annotations,
+		}
+		member.Description = annotations.DisplayDescription(member.Description)
+		if ks, ok := session.(*services.KubernetesSession); ok {
+			member.Completion = ks.Completion()
+		}
+		resp.Members = append(resp.Members, member)
+		resp.Statuses[member.Status]++
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// SendSessionGroupMessage handles POST /session-groups/:group/messages and
+// sends the message to every session of the group. Sessions the caller may
+// not modify, including sessions of other users in observer mode, are
+// reported as failed.
+func (c *SessionController) SendSessionGroupMessage(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	var req SessionGroupMessageRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "content is required")
+	}
+	group, members, err := c.sessionGroupMembers(ctx)
+	if err != nil {
+		return err
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	user := auth.GetUserFromContext(ctx)
+	manager := c.getSessionManager()
+	resp := SessionGroupResultsResponse{Group: group, Results: []SessionGroupResult{}}
+	for _, session := range members {
+		if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+			resp.add(session.ID(), errSessionGroupForbidden)
+			continue
+		}
+		if observerModeBlocks(ctx.Request().Context(), manager, user, session) {
+			resp.add(session.ID(), errSessionObserverMode)
+			continue
+		}
+		err := manager.SendMessage(ctx.Request().Context(), session.ID(), req.Content)
+		if err != nil {
+			log.Printf("[SESSION_GROUP] Failed to send message to session %s of group %s: %v", session.ID(), group, err)
+		}
+		resp.add(session.ID(), err)
+	}
+	log.Printf("[SESSION_GROUP] Sent message to %d/%d sessions of group %s", resp.Succeeded, len(members), group)
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// DeleteSessionGroup handles DELETE /session-groups/:group and deletes every
+// session of the group. Sessions the caller may not modify, including
+// sessions of other users in observer mode, are kept and reported as failed.
+func (c *SessionController) DeleteSessionGroup(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	group, members, err := c.sessionGroupMembers(ctx)
+	if err != nil {
+		return err
+	}
+	if c.sessionCreator == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Session deletion is not available")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	user := auth.GetUserFromContext(ctx)
+	manager := c.getSessionManager()
+	resp := SessionGroupResultsResponse{Group: group, Results: []SessionGroupResult{}}
+	for _, session := range members {
+		if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+			resp.add(session.ID(), errSessionGroupForbidden)
+			continue
+		}
+		if observerModeBlocks(ctx.Request().Context(), manager, user, session) {
+			resp.add(session.ID(), errSessionObserverMode)
+			continue
+		}
+		err := c.sessionCreator.DeleteSessionByID(session.ID())
+		if err != nil {
+			log.Printf("[SESSION_GROUP] Failed to delete session %s of group %s: %v", session.ID(), group, err)
+		}
+		resp.add(session.ID(), err)
+	}
+	log.Printf("[SESSION_GROUP] Deleted %d/%d sessions of group %s", resp.Succeeded, len(members), group)
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,194 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// groupSessionManager records the messages sent to sessions.
+type groupSessionManager struct {
+	*fakeSessionManager
+	sent map[string]string
+}
+
+func (m *groupSessionManager) SendMessage(_ context.Context, id, content string) error {
+	if id == "broken" {
+		return errors.New("agent unreachable")
+	}
+	m.sent[id] = content
+	return nil
+}
+
+type groupSessionProvider struct{ mgr *groupSessionManager }
+
+func (p *groupSessionProvider) GetSessionManager() repositories.SessionManager { return p.mgr }
+
+// groupSessionCreator records deleted sessions.
+type groupSessionCreator struct {
+	deleted []string
+}
+
+func (c *groupSessionCreator) CreateSession(context.Context, string, entities.StartRequest, string, string, []string) (entities.Session, error) {
+	return nil, nil
+}
+
+func (c *groupSessionCreator) DeleteSessionByID(id string) error {
+	c.deleted = append(c.deleted, id)
+	return nil
+}
+
+func newSessionGroupTest() (*controllers.SessionController, *groupSessionManager, *groupSessionCreator) {
+	base := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	group := map[string]string{entities.SessionGroupTag: "release-42"}
+	mgr := &groupSessionManager{
+		fakeSessionManager: &fakeSessionManager{sessions: map[string]*fakeSession{
+			"a":      {id: "a", userID: "alice", scope: entities.ScopeUser, tags: group, status: "active", startedAt: base},
+			"broken": {id: "broken", userID: "alice", scope: entities.ScopeUser, tags: group, status: "stable", startedAt: base.Add(time.Minute)},
+			"team":   {id: "team", userID: "bob", scope: entities.ScopeTeam, teamID: "org/qa", tags: group, status: "active", startedAt: base.Add(2 * time.Minute)},
+			"bob":    {id: "bob", userID: "bob", scope: entities.ScopeUser, tags: group, status: "active", startedAt: base},
+			"other":  {id: "other", userID: "alice", scope: entities.ScopeUser, tags: map[string]string{entities.SessionGroupTag: "other"}},
+		}},
+		sent: map[string]string{},
+	}
+	creator := &groupSessionCreator{}
+	return controllers.NewSessionController(&groupSessionProvider{mgr: mgr}, creator), mgr, creator
+}
+
+func sessionGroupContext(method, group, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/session-groups/"+group, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("group")
+	c.SetParamValues(group)
+	c.Set("authz_context", &auth.AuthorizationContext{
+		PersonalScope: auth.PersonalScopeAuth{UserID: "alice"},
+		TeamScope: auth.TeamScopeAuth{
+			Teams:           []string{"org/qa"},
+			TeamPermissions: map[string]auth.TeamPermissions{"org/qa": {CanRead: true}},
+		},
+	})
+	return c, rec
+}
+
+func TestGetSessionGroup(t *testing.T) {
+	controller, _, _ := newSessionGroupTest()
+
+	c, rec := sessionGroupContext(http.MethodGet, "release-42", "")
+	if err := controller.GetSessionGroup(c); err != nil {
+		t.Fatalf("GetSessionGroup: %v", err)
+	}
+	var resp controllers.SessionGroupResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, member := range resp.Members {
+		ids = append(ids, member.SessionID)
+	}
+	// Personal sessions of other users are not members for the caller.
+	if strings.Join(ids, ",") != "a,broken,team" {
+		t.Errorf("members = %v, want a, broken and team oldest first", ids)
+	}
+	if resp.Statuses["active"] != 2 || resp.Statuses["stable"] != 1 {
+		t.Errorf("statuses = %v", resp.Statuses)
+	}
+
+	c, _ = sessionGroupContext(http.MethodGet, "missing", "")
+	if err := controller.GetSessionGroup(c); !isHTTPStatus(err, http.StatusNotFound) {
+		t.Errorf("unknown group: err = %v, want 404", err)
+	}
+	c, _ = sessionGroupContext(http.MethodGet, "Bad_Group", "")
+	if err := controller.GetSessionGroup(c); !isHTTPStatus(err, http.StatusBadRequest) {
+		t.Errorf("invalid group: err = %v, want 400", err)
+	}
+}
+
+func TestSendSessionGroupMessage(t *testing.T) {
+	controller, mgr, _ := newSessionGroupTest()
+
+	c, rec := sessionGroupContext(http.MethodPost, "release-42", `{"content":"run the release checks"}`)
+	if err := controller.SendSessionGroupMessage(c); err != nil {
+		t.Fatalf("SendSessionGroupMessage: %v", err)
+	}
+	var resp controllers.SessionGroupResultsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 || resp.Results[1].SessionID != "broken" || resp.Results[1].OK {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(mgr.sent) != 2 || mgr.sent["team"] != "run the release checks" {
+		t.Errorf("sent = %v", mgr.sent)
+	}
+
+	c, _ = sessionGroupContext(http.MethodPost, "release-42", `{"content":" "}`)
+	if err := controller.SendSessionGroupMessage(c); !isHTTPStatus(err, http.StatusBadRequest) {
+		t.Errorf("empty content: err = %v, want 400", err)
+	}
+}
+
+func TestDeleteSessionGroup(t *testing.T) {
+	controller, _, creator := newSessionGroupTest()
+
+	c, rec := sessionGroupContext(http.MethodDelete, "release-42", "")
+	if err := controller.DeleteSessionGroup(c); err != nil {
+		t.Fatalf("DeleteSessionGroup: %v", err)
+	}
+	if rec.Code != http.StatusOK || strings.Join(creator.deleted, ",") != "a,broken,team" {
+		t.Errorf("deleted = %v", creator.deleted)
+	}
+}
+
+func TestSessionGroupObserverMode(t *testing.T) {
+	controller, mgr, creator := newSessionGroupTest()
+	mgr.observed = map[string]bool{"a": true, "team": true}
+
+	// alice owns "a", so only bob's team session is kept from her.
+	c, rec := sessionGroupContext(http.MethodPost, "release-42", `{"content":"run the release checks"}`)
+	c.Set("internal_user", entities.NewUser("alice", entities.UserTypeRegular, "alice"))
+	if err := controller.SendSessionGroupMessage(c); err != nil {
+		t.Fatalf("SendSessionGroupMessage: %v", err)
+	}
+	var resp controllers.SessionGroupResultsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Results[2].SessionID != "team" || resp.Results[2].OK || !strings.Contains(resp.Results[2].Error, "observer mode") {
+		t.Errorf("team result = %+v, want an observer mode failure", resp.Results[2])
+	}
+	if _, ok := mgr.sent["team"]; ok || mgr.sent["a"] == "" {
+		t.Errorf("sent = %v, want a but not team", mgr.sent)
+	}
+
+	c, rec = sessionGroupContext(http.MethodDelete, "release-42", "")
+	c.Set("internal_user", entities.NewUser("alice", entities.UserTypeRegular, "alice"))
+	if err := controller.DeleteSessionGroup(c); err != nil {
+		t.Fatalf("DeleteSessionGroup: %v", err)
+	}
+	resp = controllers.SessionGroupResultsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(creator.deleted, ",") != "a,broken" || resp.Failed != 1 {
+		t.Errorf("deleted = %v, response = %+v, want the team session kept", creator.deleted, resp)
+	}
+}
+
+func isHTTPStatus(err error, status int) bool {
+	var httpErr *echo.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Code == status
+}
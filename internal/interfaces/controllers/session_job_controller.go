@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// SessionJobController serves the completion and captured container logs of
+// oneshot sessions run as Jobs. Like PostSessionHookController it authorizes
+// against the owner recorded in the completion, as the session is deleted
+// once the Job has finished.
+type SessionJobController struct {
+	artifactStore services.ArtifactStore
+}
+
+// NewSessionJobController creates a new SessionJobController.
+// artifactStore may be nil when the asset backend has no artifact support.
+func NewSessionJobController(artifactStore services.ArtifactStore) *SessionJobController {
+	return &SessionJobController{artifactStore: artifactStore}
+}
+
+// GetName returns the name of this controller for logging
+func (c *SessionJobController) GetName() string {
+	return "SessionJobController"
+}
+
+// authorizedCompletion loads the completion of the session and checks that
+// the caller may access the session it belongs to.
+func (c *SessionJobController) authorizedCompletion(ctx echo.Context) (*entities.SessionCompletion, error) {
+	if c.artifactStore == nil {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, "Session job results are not configured")
+	}
+	sessionID := ctx.Param("sessionId")
+	body, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), services.SessionJobPrefix(sessionID)+services.SessionCompletionName)
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "No job result for this session")
+	}
+	if err != nil {
+		log.Printf("Failed to open job result of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read job result")
+	}
+	defer func() { _ = body.Close() }()
+	var completion entities.SessionCompletion
+	if err := json.NewDecoder(body).Decode(&completion); err != nil {
+		log.Printf("Failed to decode job result of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read job result")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(completion.UserID, string(completion.Scope), completion.TeamID) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "No job result for this session")
+	}
+	return &completion, nil
+}
+
+// GetResult handles GET /sessions/:sessionId/job-result.
+func (c *SessionJobController) GetResult(ctx echo.Context) error {
+	completion, err := c.authorizedCompletion(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, completion)
+}
+
+// GetLog handles GET /sessions/:sessionId/job-result/logs/:name and returns
+// the captured log of one container as plain text.
+func (c *SessionJobController) GetLog(ctx echo.Context) error {
+	completion, err := c.authorizedCompletion(ctx)
+	if err != nil {
+		return err
+	}
+	// Only names listed in the completion are served, which also rules out paths.
+	name := ctx.Param("name")
+	if !slices.Contains(completion.Logs, name) {
+		return echo.NewHTTPError(http.StatusNotFound, "Container log not found")
+	}
+
+	body, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), services.SessionJobPrefix(completion.SessionID)+name)
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Container log not found")
+	}
+	if err != nil {
+		log.Printf("Failed to open job log %s of session %s: %v", name, completion.SessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read container log")
+	}
+	defer func() { _ = body.Close() }()
+	ctx.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	ctx.Response().Header().Set(echo.HeaderContentType, "text/plain; charset=utf-8")
+	ctx.Response().WriteHeader(http.StatusOK)
+	_, err = io.Copy(ctx.Response(), body)
+	return err
+}
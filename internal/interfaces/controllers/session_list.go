@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// maxSessionListLimit caps the limit query parameter of GET /search
+const maxSessionListLimit = 500
+
+// sessionListOptions are the search, sort and pagination parameters of
+// GET /search. A zero limit returns every matching session.
+type sessionListOptions struct {
+	query  string
+	sortBy string
+	desc   bool
+	limit  int
+	offset int
+	// favorite and folder filter by the caller's pinned sessions and folders
+	favorite *bool
+	folder   string
+}
+
+// parseSessionListOptions reads q, sort, order, limit, offset, favorite and folder
+func parseSessionListOptions(ctx echo.Context) (sessionListOptions, error) {
+	opts := sessionListOptions{
+		query:  strings.ToLower(strings.TrimSpace(ctx.QueryParam("q"))),
+		sortBy: ctx.QueryParam("sort"),
+		desc:   true,
+	}
+	switch opts.sortBy {
+	case "":
+		opts.sortBy = "created_at"
+	case "created_at", "updated_at", "status", "favorite":
+	default:
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "sort must be one of created_at, updated_at, status, favorite")
+	}
+	switch ctx.QueryParam("order") {
+	case "", "desc":
+	case "asc":
+		opts.desc = false
+	default:
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "order must be asc or desc")
+	}
+	if v := ctx.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSessionListLimit {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxSessionListLimit))
+		}
+		opts.limit = limit
+	}
+	if v := ctx.QueryParam("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "offset must be a non-negative integer")
+		}
+		opts.offset = offset
+	}
+	if v := ctx.QueryParam("favorite"); v != "" {
+		favorite, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "favorite must be true or false")
+		}
+		opts.favorite = &favorite
+	}
+	opts.folder = strings.TrimSpace(ctx.QueryParam("folder"))
+	return opts, nil
+}
+
+// sessionListEntry is a session of the /search response with the fields it
+// is searched and sorted by
+type sessionListEntry struct {
+	data      map[string]interface{}
+	startedAt time.Time
+	updatedAt time.Time
+	status    string
+	// text holds the description, initial message and summary matched by q
+	text []string
+	// favorite and folder are the caller's organization of the session
+	favorite bool
+	folder   string
+}
+
+// organize records the caller's organization of the session in the entry
+func (e *sessionListEntry) organize(org *entities.SessionOrganization, sessionID string) {
+	e.favorite = org.IsFavorite(sessionID)
+	e.folder = org.Folder(sessionID)
+	e.data["favorite"] = e.favorite
+	if e.folder != "" {
+		e.data["folder"] = e.folder
+	}
+}
+
+// apply filters entries by q, sorts them and returns the requested page
+// along with the number of matching sessions
+func (o sessionListOptions) apply(entries []sessionListEntry) ([]map[string]interface{}, int) {
+	if o.favorite != nil || o.folder != "" {
+		matched := entries[:0]
+		for _, e := range entries {
+			if (o.favorite == nil || e.favorite == *o.favorite) && (o.folder == "" || e.folder == o.folder) {
+				matched = append(matched, e)
+			}
+		}
+		entries = matched
+	}
+	if o.query != "" {
+		matched := entries[:0]
+		for _, e := range entries {
+			for _, text := range e.text {
+				if strings.Contains(strings.ToLower(text), o.query) {
+					matched = append(matched, e)
+					break
+				}
+			}
+		}
+		entries = matched
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		// Pinned sessions are listed first, then newest first by default
+		if o.sortBy == "favorite" && a.favorite != b.favorite {
+			return a.favorite
+		}
+		if o.sortBy == "status" && a.status != b.status {
+			if o.desc {
+				return a.status > b.status
+			}
+			return a.status < b.status
+		}
+		at, bt := a.startedAt, b.startedAt
+		if o.sortBy == "updated_at" {
+			at, bt = a.updatedAt, b.updatedAt
+		}
+		// Sessions with the same status are listed newest first
+		if o.desc || o.sortBy == "status" {
+			return at.After(bt)
+		}
+		return at.Before(bt)
+	})
+
+	total := len(entries)
+	if o.offset >= total {
+		entries = nil
+	} else {
+		entries = entries[o.offset:]
+	}
+	if o.limit > 0 && len(entries) > o.limit {
+		entries = entries[:o.limit]
+	}
+	page := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		page = append(page, e.data)
+	}
+	return page, total
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func newSessionListEntries() []sessionListEntry {
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(id, status string, started, updated int, text ...string) sessionListEntry {
+		return sessionListEntry{
+			data:      map[string]interface{}{"session_id": id},
+			startedAt: base.Add(time.Duration(started) * time.Hour),
+			updatedAt: base.Add(time.Duration(updated) * time.Hour),
+			status:    status,
+			text:      text,
+		}
+	}
+	return []sessionListEntry{
+		entry("a", "active", 1, 5, "Fix the flaky login test"),
+		entry("b", "stopped", 3, 3, "", "Bump dependencies"),
+		entry("c", "active", 2, 9, "Write release notes"),
+	}
+}
+
+func sessionIDs(page []map[string]interface{}) []string {
+	ids := make([]string, 0, len(page))
+	for _, s := range page {
+		ids = append(ids, s["session_id"].(string))
+	}
+	return ids
+}
+
+func TestSessionListOptions_Apply(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      sessionListOptions
+		wantIDs   []string
+		wantTotal int
+	}{
+		{name: "newest first by default", opts: sessionListOptions{sortBy: "created_at", desc: true}, wantIDs: []string{"b", "c", "a"}, wantTotal: 3},
+		{name: "updated ascending", opts: sessionListOptions{sortBy: "updated_at"}, wantIDs: []string{"b", "a", "c"}, wantTotal: 3},
+		{name: "status then newest", opts: sessionListOptions{sortBy: "status"}, wantIDs: []string{"c", "a", "b"}, wantTotal: 3},
+		{name: "search matches initial message", opts: sessionListOptions{sortBy: "created_at", desc: true, query: "dependencies"}, wantIDs: []string{"b"}, wantTotal: 1},
+		{name: "search is case-insensitive", opts: sessionListOptions{sortBy: "created_at", desc: true, query: "flaky"}, wantIDs: []string{"a"}, wantTotal: 1},
+		{name: "page", opts: sessionListOptions{sortBy: "created_at", desc: true, limit: 1, offset: 1}, wantIDs: []string{"c"}, wantTotal: 3},
+		{name: "offset past the end", opts: sessionListOptions{sortBy: "created_at", desc: true, offset: 5}, wantIDs: []string{}, wantTotal: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total := tt.opts.apply(newSessionListEntries())
+			assert.Equal(t, tt.wantIDs, sessionIDs(page))
+			assert.Equal(t, tt.wantTotal, total)
+		})
+	}
+}
+
+func TestSessionListOptions_Organization(t *testing.T) {
+	org := &entities.SessionOrganization{Favorites: []string{"a"}, Folders: map[string]string{"a": "infra", "c": "infra"}}
+	organized := func() []sessionListEntry {
+		entries := newSessionListEntries()
+		for i := range entries {
+			entries[i].organize(org, entries[i].data["session_id"].(string))
+		}
+		return entries
+	}
+	pinned := true
+
+	page, _ := sessionListOptions{sortBy: "favorite", desc: true}.apply(organized())
+	assert.Equal(t, []string{"a", "b", "c"}, sessionIDs(page))
+	assert.Equal(t, true, page[0]["favorite"])
+	assert.Equal(t, "infra", page[0]["folder"])
+
+	page, total := sessionListOptions{sortBy: "created_at", desc: true, favorite: &pinned}.apply(organized())
+	assert.Equal(t, []string{"a"}, sessionIDs(page))
+	assert.Equal(t, 1, total)
+
+	page, _ = sessionListOptions{sortBy: "created_at", desc: true, folder: "infra"}.apply(organized())
+	assert.Equal(t, []string{"c", "a"}, sessionIDs(page))
+}
+
+func TestParseSessionListOptions(t *testing.T) {
+	c, _ := makeMemoryEchoContext(t, http.MethodGet, "/search?q=+Login+&sort=status&order=asc&limit=10&offset=20", nil, newTestAdminUser("admin"))
+	opts, err := parseSessionListOptions(c)
+	require.NoError(t, err)
+	assert.Equal(t, sessionListOptions{query: "login", sortBy: "status", limit: 10, offset: 20}, opts)
+
+	for _, query := range []string{"sort=name", "order=up", "limit=0", "limit=501", "offset=-1", "favorite=maybe"} {
+		c, _ := makeMemoryEchoContext(t, http.MethodGet, "/search?"+query, nil, newTestAdminUser("admin"))
+		_, err := parseSessionListOptions(c)
+		assertHTTPError(t, err, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// sessionLogStreamer is implemented by session managers that can stream the
+// container logs of a session.
+type sessionLogStreamer interface {
+	StreamSessionLogs(ctx context.Context, sessionID string, opts entities.SessionLogOptions) (io.ReadCloser, error)
+}
+
+// parseSessionLogOptions reads the query parameters of GET /sessions/:sessionId/logs.
+func parseSessionLogOptions(ctx echo.Context) (entities.SessionLogOptions, error) {
+	opts := entities.SessionLogOptions{
+		Container: ctx.QueryParam("container"),
+		Follow:    ctx.QueryParam("follow") == "true",
+		Previous:  ctx.QueryParam("previous") == "true",
+	}
+	if v := ctx.QueryParam("tail_lines"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "tail_lines must be a non-negative integer")
+		}
+		opts.TailLines = &n
+	}
+	if v := ctx.QueryParam("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "since must be a positive duration such as 10m")
+		}
+		seconds := int64((d + time.Second - 1) / time.Second)
+		opts.SinceSeconds = &seconds
+	}
+	return opts, nil
+}
+
+// StreamSessionLogs handles GET /sessions/:sessionId/logs.
+// It streams the logs of the main container, or of the sidecar selected with
+// ?container=, as plain text. ?follow=true keeps the stream open.
+func (c *SessionController) StreamSessionLogs(ctx echo.Context) error {
+	sessionID := ctx.Param("sessionId")
+	session := c.getSessionManager().GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	streamer, ok := c.getSessionManager().(sessionLogStreamer)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "log streaming not supported by this session manager")
+	}
+	opts, err := parseSessionLogOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := streamer.StreamSessionLogs(ctx.Request().Context(), sessionID, opts)
+	switch {
+	case errors.Is(err, entities.ErrUnknownContainer):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, entities.ErrSessionPodUnavailable):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case err != nil:
+		log.Printf("Failed to stream logs of session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to stream session logs")
+	}
+	defer func() { _ = stream.Close() }()
+
+	r := ctx.Response()
+	r.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	r.Header().Set("Cache-Control", "no-cache")
+	r.Header().Set("X-Content-Type-Options", "nosniff")
+	r.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	r.WriteHeader(http.StatusOK)
+	flusher, _ := r.Writer.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, werr := r.Write(buf[:n]); werr != nil {
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Request().Context().Err() == nil {
+				log.Printf("Log stream of session %s ended: %v", sessionID, err)
+			}
+			return nil
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
 )
 
@@ -50,14 +54,7 @@ func (c *SessionController) WaitSessionMessages(ctx echo.Context) error {
 	}
 
 	// Parse the optional since parameter (Unix ms or RFC3339).
-	var since time.Time
-	if s := ctx.QueryParam("since"); s != "" {
-		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
-			since = time.UnixMilli(ms)
-		} else if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
-			since = t
-		}
-	}
+	since, _ := parseSinceParam(ctx.QueryParam("since"))
 
 	watcher, ok := manager.(ProxyMessageWatcher)
 	if !ok {
@@ -113,3 +110,157 @@ func (c *SessionController) WaitSessionMessages(ctx echo.Context) error {
 		}
 	}
 }
+
+// parseSinceParam parses a since query parameter given as Unix milliseconds
+// or an RFC3339 timestamp. An empty parameter yields the zero time.
+func parseSinceParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be Unix milliseconds or an RFC3339 timestamp")
+	}
+	return t, nil
+}
+
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 500
+)
+
+// SessionMessagesResponse is a page of the conversation history of a session
+type SessionMessagesResponse struct {
+	SessionID string              `json:"session_id"`
+	Messages  []portrepos.Message `json:"messages"`
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// SendSessionMessageRequest is the body of POST /sessions/:sessionId/messages
+type SendSessionMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// GetSessionMessages handles GET /sessions/:sessionId/messages.
+// It returns the conversation history of the session from the agent, oldest
+// first, for every session manager backend.
+//
+// Query parameters:
+//   - role: comma-separated roles to include (e.g. "user" or "agent"); all by default
+//   - since: Unix timestamp in milliseconds or RFC3339 string; only messages
+//     after it are returned
+//   - cursor: next_cursor of the previous page
+//   - limit: page size (default 50, max 500)
+func (c *SessionController) GetSessionMessages(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	sessionID := ctx.Param("sessionId")
+	manager := c.getSessionManager()
+	session := manager.GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+
+	since, err := parseSinceParam(ctx.QueryParam("since"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	after := -1
+	if cursor := ctx.QueryParam("cursor"); cursor != "" {
+		if after, err = strconv.Atoi(cursor); err != nil || after < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+	}
+	limit := defaultMessagesLimit
+	if l := ctx.QueryParam("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(limit, maxMessagesLimit)
+	}
+	var roles []string
+	if r := ctx.QueryParam("role"); r != "" {
+		for _, role := range strings.Split(r, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	messages, err := manager.GetMessages(ctx.Request().Context(), sessionID)
+	if err != nil {
+		log.Printf("[SESSION_MESSAGES] Failed to get messages of session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to get messages from the session agent")
+	}
+
+	page, hasMore := pageMessages(messages, roles, since, after, limit)
+	resp := SessionMessagesResponse{SessionID: sessionID, Messages: page, HasMore: hasMore}
+	if hasMore {
+		resp.NextCursor = strconv.Itoa(page[len(page)-1].ID)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// pageMessages returns up to limit messages with an ID greater than after,
+// a role in roles (any role when empty) and a timestamp after since, and
+// whether more such messages follow
+func pageMessages(messages []portrepos.Message, roles []string, since time.Time, after, limit int) ([]portrepos.Message, bool) {
+	page := make([]portrepos.Message, 0, min(len(messages), limit))
+	for _, msg := range messages {
+		if msg.ID <= after {
+			continue
+		}
+		if len(roles) > 0 && !slices.Contains(roles, msg.Role) {
+			continue
+		}
+		if !since.IsZero() && !msg.Timestamp.After(since) {
+			continue
+		}
+		if len(page) == limit {
+			return page, true
+		}
+		page = append(page, msg)
+	}
+	return page, false
+}
+
+// SendSessionMessage handles POST /sessions/:sessionId/messages.
+// It sends a user message to the agent of the session through the session
+// manager, so it works the same for every backend and agent type.
+func (c *SessionController) SendSessionMessage(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	sessionID := ctx.Param("sessionId")
+	manager := c.getSessionManager()
+	session := manager.GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this session")
+	}
+
+	var req SendSessionMessageRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "content is required")
+	}
+
+	if err := manager.SendMessage(ctx.Request().Context(), sessionID, req.Content); err != nil {
+		log.Printf("[SESSION_MESSAGES] Failed to send message to session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("Failed to send message: %v", err))
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"ok": true})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -213,3 +214,83 @@ func TestWaitSessionMessages_NoSince_ReceivesEvent(t *testing.T) {
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
 	assert.Equal(t, true, resp["updated"])
 }
+
+// mockMessagesSessionManager returns a fixed conversation and records sent messages
+type mockMessagesSessionManager struct {
+	*mockWaitSessionManager
+	messages []portrepos.Message
+	sent     []string
+}
+
+func (m *mockMessagesSessionManager) GetMessages(_ context.Context, _ string) ([]portrepos.Message, error) {
+	return m.messages, nil
+}
+
+func (m *mockMessagesSessionManager) SendMessage(_ context.Context, _ string, message string) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestGetSessionMessages_FiltersAndPaginates(t *testing.T) {
+	base := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	mgr := &mockMessagesSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(&mockWaitSession{id: "sess-1", userID: "user-1"}),
+	}
+	for i, role := range []string{"agent", "user", "agent", "user", "agent"} {
+		mgr.messages = append(mgr.messages, portrepos.Message{ID: i, Role: role, Content: fmt.Sprintf("m%d", i), Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+	controller := NewSessionController(&mockWaitProvider{manager: mgr}, nil)
+
+	get := func(params map[string]string) SessionMessagesResponse {
+		t.Helper()
+		c, rec := makeWaitEchoContext(t, "sess-1", params, "user-1")
+		require.NoError(t, controller.GetSessionMessages(c))
+		var resp SessionMessagesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	first := get(map[string]string{"role": "agent", "limit": "2"})
+	require.Len(t, first.Messages, 2)
+	assert.Equal(t, []int{0, 2}, []int{first.Messages[0].ID, first.Messages[1].ID})
+	assert.True(t, first.HasMore)
+	assert.Equal(t, "2", first.NextCursor)
+
+	second := get(map[string]string{"role": "agent", "limit": "2", "cursor": first.NextCursor})
+	require.Len(t, second.Messages, 1)
+	assert.Equal(t, 4, second.Messages[0].ID)
+	assert.False(t, second.HasMore)
+	assert.Empty(t, second.NextCursor)
+
+	recent := get(map[string]string{"since": base.Add(2 * time.Minute).Format(time.RFC3339)})
+	require.Len(t, recent.Messages, 2)
+	assert.Equal(t, 3, recent.Messages[0].ID)
+
+	c, _ := makeWaitEchoContext(t, "sess-1", map[string]string{"cursor": "abc"}, "user-1")
+	assertHTTPError(t, controller.GetSessionMessages(c), http.StatusBadRequest)
+	c, _ = makeWaitEchoContext(t, "sess-1", nil, "user-2")
+	assertHTTPError(t, controller.GetSessionMessages(c), http.StatusForbidden)
+}
+
+func TestSendSessionMessage(t *testing.T) {
+	mgr := &mockMessagesSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(&mockWaitSession{id: "sess-1", userID: "user-1"}),
+	}
+	controller := NewSessionController(&mockWaitProvider{manager: mgr}, nil)
+
+	send := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		c, rec := makeWaitEchoContext(t, "sess-1", nil, "user-1")
+		req := httptest.NewRequest(http.MethodPost, "/sessions/sess-1/messages", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.SetRequest(req)
+		return c, rec
+	}
+
+	c, rec := send(`{"content":"run the tests"}`)
+	require.NoError(t, controller.SendSessionMessage(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"run the tests"}, mgr.sent)
+
+	c, _ = send(`{"content":"  "}`)
+	assertHTTPError(t, controller.SendSessionMessage(c), http.StatusBadRequest)
+}
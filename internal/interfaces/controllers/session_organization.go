@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// SessionFolderRequest is the body of PUT /sessions/:sessionId/folder
+type SessionFolderRequest struct {
+	// Folder is the folder to file the session into; "" removes it from its folder
+	Folder string `json:"folder"`
+}
+
+// SessionOrganizationResponse is the organization of one session for the caller
+type SessionOrganizationResponse struct {
+	SessionID string `json:"session_id"`
+	Favorite  bool   `json:"favorite"`
+	Folder    string `json:"folder,omitempty"`
+}
+
+// loadUserSettings returns the settings of the user, or new settings when the
+// user has none yet
+func (c *SessionController) loadUserSettings(ctx context.Context, userID string) (*entities.Settings, error) {
+	settings, err := c.settingsRepo.FindByName(ctx, userID)
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+		settings = entities.NewSettings(userID)
+	}
+	return settings, nil
+}
+
+// loadSessionOrganization returns the settings of the user with their pinned
+// sessions and folders. Settings are created when the user has none yet.
+func (c *SessionController) loadSessionOrganization(ctx context.Context, userID string) (*entities.Settings, *entities.SessionOrganization, error) {
+	settings, err := c.loadUserSettings(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	org := settings.SessionOrganization()
+	if org == nil {
+		org = &entities.SessionOrganization{}
+	}
+	return settings, org, nil
+}
+
+// sessionOrganizationUserID checks that the session exists and the caller may
+// access it, and returns the caller's user ID
+func (c *SessionController) sessionOrganizationUserID(ctx echo.Context, sessionID string) (string, error) {
+	if c.settingsRepo == nil {
+		return "", echo.NewHTTPError(http.StatusNotImplemented, "Session organization requires the settings repository")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+	if session := c.getSessionManager().GetSession(sessionID); session != nil {
+		if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+			return "", echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+		}
+		return authzCtx.PersonalScope.UserID, nil
+	}
+	if c.sessionRouteRepo != nil {
+		route, err := c.sessionRouteRepo.Get(ctx.Request().Context(), sessionID)
+		if err == nil && route != nil {
+			if !authzCtx.CanAccessResource(route.UserID, route.Scope, route.TeamID) {
+				return "", echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+			}
+			return authzCtx.PersonalScope.UserID, nil
+		}
+	}
+	return "", echo.NewHTTPError(http.StatusNotFound, "Session not found")
+}
+
+// updateSessionOrganization applies update to the caller's organization of
+// the session and saves it in their settings. Unless adding is set, the
+// session may be gone, so that deleted sessions can be unpinned and unfiled.
+func (c *SessionController) updateSessionOrganization(ctx echo.Context, adding bool, update func(org *entities.SessionOrganization, sessionID string)) error {
+	c.setCORSHeaders(ctx)
+	sessionID := ctx.Param("sessionId")
+	var userID string
+	if adding {
+		var err error
+		if userID, err = c.sessionOrganizationUserID(ctx, sessionID); err != nil {
+			return err
+		}
+	} else {
+		authzCtx := auth.GetAuthorizationContext(ctx)
+		if c.settingsRepo == nil {
+			return echo.NewHTTPError(http.StatusNotImplemented, "Session organization requires the settings repository")
+		}
+		if authzCtx == nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+		}
+		userID = authzCtx.PersonalScope.UserID
+	}
+
+	settings, org, err := c.loadSessionOrganization(ctx.Request().Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load settings of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load session organization")
+	}
+	update(org, sessionID)
+	settings.SetSessionOrganization(org)
+	if err := c.settingsRepo.Save(ctx.Request().Context(), settings); err != nil {
+		log.Printf("Failed to save session organization of user %s: %v", userID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save session organization")
+	}
+	return ctx.JSON(http.StatusOK, SessionOrganizationResponse{
+		SessionID: sessionID,
+		Favorite:  org.IsFavorite(sessionID),
+		Folder:    org.Folder(sessionID),
+	})
+}
+
+// FavoriteSession handles PUT /sessions/:sessionId/favorite and pins the
+// session for the caller
+func (c *SessionController) FavoriteSession(ctx echo.Context) error {
+	return c.updateSessionOrganization(ctx, true, func(org *entities.SessionOrganization, sessionID string) {
+		org.SetFavorite(sessionID, true)
+	})
+}
+
+// UnfavoriteSession handles DELETE /sessions/:sessionId/favorite
+func (c *SessionController) UnfavoriteSession(ctx echo.Context) error {
+	return c.updateSessionOrganization(ctx, false, func(org *entities.SessionOrganization, sessionID string) {
+		org.SetFavorite(sessionID, false)
+	})
+}
+
+// SetSessionFolder handles PUT /sessions/:sessionId/folder and files the
+// session into a folder of the caller
+func (c *SessionController) SetSessionFolder(ctx echo.Context) error {
+	var req SessionFolderRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	folder := strings.TrimSpace(req.Folder)
+	if folder != "" {
+		if err := entities.ValidateSessionFolderName(folder); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	return c.updateSessionOrganization(ctx, folder != "", func(org *entities.SessionOrganization, sessionID string) {
+		org.SetFolder(sessionID, folder)
+	})
+}
+
+// callerSessionOrganization returns the organization of the caller for
+// GET /search; nil when they have none or it cannot be read
+func (c *SessionController) callerSessionOrganization(ctx context.Context, userID string) *entities.SessionOrganization {
+	if c.settingsRepo == nil || userID == "" {
+		return nil
+	}
+	settings, err := c.settingsRepo.FindByName(ctx, userID)
+	if err != nil || settings == nil {
+		return nil
+	}
+	return settings.SessionOrganization()
+}
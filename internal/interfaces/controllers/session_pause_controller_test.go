@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+type mockPauseSession struct {
+	*mockWaitSession
+	status string
+}
+
+func (s *mockPauseSession) Status() string { return s.status }
+
+type mockPauseSessionManager struct {
+	*mockWaitSessionManager
+	session *mockPauseSession
+	err     error
+	resumed []string
+}
+
+func (m *mockPauseSessionManager) PauseSession(_ context.Context, _ string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.session.status = "paused"
+	return nil
+}
+
+func (m *mockPauseSessionManager) ResumeSession(_ context.Context, id string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.resumed = append(m.resumed, id)
+	m.session.status = "resuming"
+	return nil
+}
+
+func newPauseTestController(status string, err error, opts ...SessionControllerOption) (*SessionController, *mockPauseSessionManager) {
+	session := &mockPauseSession{mockWaitSession: &mockWaitSession{id: "sess-1", userID: "alice"}, status: status}
+	manager := &mockPauseSessionManager{mockWaitSessionManager: newMockWaitSessionManager(session), session: session, err: err}
+	return NewSessionController(&mockWaitProvider{manager: manager}, nil, opts...), manager
+}
+
+func TestSessionController_PauseAndResume(t *testing.T) {
+	c, _ := newPauseTestController("active", nil)
+
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/pause", "sess-1", "alice")
+	require.NoError(t, c.PauseSession(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"session_id":"sess-1","status":"paused"}`, rec.Body.String())
+
+	ctx, rec = makeWorkspaceEchoContext("/sessions/sess-1/resume", "sess-1", "alice")
+	require.NoError(t, c.ResumeSession(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"session_id":"sess-1","status":"resuming"}`, rec.Body.String())
+}
+
+func TestSessionController_PauseErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionID  string
+		userID     string
+		err        error
+		wantStatus int
+	}{
+		{"unknown session", "missing", "alice", nil, http.StatusNotFound},
+		{"other user", "sess-1", "bob", nil, http.StatusForbidden},
+		{"no persistent workdir", "sess-1", "alice", services.ErrSessionNotPausable, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newPauseTestController("active", tt.err)
+			ctx, _ := makeWorkspaceEchoContext("/sessions/"+tt.sessionID+"/pause", tt.sessionID, tt.userID)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, c.PauseSession(ctx), &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
+
+func TestSessionController_RouteToPausedSession(t *testing.T) {
+	c, manager := newPauseTestController("paused", nil)
+	ctx, _ := makeWorkspaceEchoContext("/sess-1/status", "sess-1", "alice")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, c.RouteToSession(ctx), &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+	assert.Empty(t, manager.resumed)
+
+	c, manager = newPauseTestController("paused", nil, WithAutoResume(true))
+	ctx, rec := makeWorkspaceEchoContext("/sess-1/status", "sess-1", "alice")
+	require.ErrorAs(t, c.RouteToSession(ctx), &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+	assert.Equal(t, []string{"sess-1"}, manager.resumed)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -73,12 +74,21 @@ type SessionProfileResponse struct {
 	Scope        entities.ResourceScope       `json:"scope,omitempty"`
 	TeamID       string                       `json:"team_id,omitempty"`
 	IsDefault    bool                         `json:"is_default,omitempty"`
+	TeamDefault  bool                         `json:"team_default,omitempty"`
 	SelectorTags map[string]string            `json:"selector_tags,omitempty"`
 	Config       SessionProfileConfigResponse `json:"config"`
 	CreatedAt    string                       `json:"created_at"`
 	UpdatedAt    string                       `json:"updated_at"`
 }
 
+// TeamDefaultProfileRequest is the request body for setting the default
+// profile of a team
+type TeamDefaultProfileRequest struct {
+	Name        string                      `json:"name,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Config      SessionProfileConfigRequest `json:"config"`
+}
+
 // SessionProfileConfigResponse represents session profile config in responses
 type SessionProfileConfigResponse struct {
 	Environment            map[string]string            `json:"environment,omitempty"`
@@ -274,6 +284,14 @@ func (c *SessionProfileController) UpdateSessionProfile(ctx echo.Context) error
 }
 
 func validateSessionProfileConfig(config entities.SessionProfileConfig) error {
+	if params := config.Params(); params != nil {
+		if err := entities.ValidateSetupHooks(params.SetupHooks); err != nil {
+			return err
+		}
+		if err := entities.ValidateSetupHooks(params.PostSessionHooks); err != nil {
+			return err
+		}
+	}
 	if config.MCPServers() != nil {
 		return config.MCPServers().Validate()
 	}
@@ -312,8 +330,120 @@ func (c *SessionProfileController) DeleteSessionProfile(ctx echo.Context) error
 	return ctx.NoContent(http.StatusNoContent)
 }
 
+// ListTeamDefaultProfiles handles GET /admin/team-default-profiles. ?team_id=
+// selects the default profile of one team.
+func (c *SessionProfileController) ListTeamDefaultProfiles(ctx echo.Context) error {
+	profiles, err := c.teamDefaultProfiles(ctx.Request().Context(), ctx.QueryParam("team_id"))
+	if err != nil {
+		log.Printf("Failed to list team default profiles: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list team default profiles")
+	}
+
+	responses := make([]SessionProfileResponse, 0, len(profiles))
+	for _, p := range profiles {
+		responses = append(responses, c.toResponse(p))
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"session_profiles": responses,
+	})
+}
+
+// PutTeamDefaultProfile handles PUT /admin/team-default-profiles?team_id=.
+// It creates or replaces the default profile of the team.
+func (c *SessionProfileController) PutTeamDefaultProfile(ctx echo.Context) error {
+	teamID := ctx.QueryParam("team_id")
+	if teamID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "team_id is required")
+	}
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+	}
+
+	var req TeamDefaultProfileRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if req.Name == "" {
+		req.Name = teamID + " default"
+	}
+	config := c.requestToConfig(req.Config)
+	if err := validateSessionProfileConfig(config); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	existing, err := c.teamDefaultProfiles(ctx.Request().Context(), teamID)
+	if err != nil {
+		log.Printf("Failed to look up the default profile of team %s: %v", teamID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to set team default profile")
+	}
+	status := http.StatusOK
+	var profile *entities.SessionProfile
+	if len(existing) > 0 {
+		profile = existing[0]
+	} else {
+		profile = entities.NewSessionProfile(uuid.New().String(), req.Name, string(user.ID()))
+		profile.SetOwnership(entities.ScopeTeam, string(user.ID()), teamID)
+		profile.SetIsTeamDefault(true)
+		status = http.StatusCreated
+	}
+	profile.SetName(req.Name)
+	profile.SetDescription(req.Description)
+	profile.SetConfig(config)
+
+	if status == http.StatusCreated {
+		err = c.repo.Create(ctx.Request().Context(), profile)
+	} else {
+		err = c.repo.Update(ctx.Request().Context(), profile)
+	}
+	if err != nil {
+		log.Printf("Failed to save the default profile of team %s: %v", teamID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to set team default profile")
+	}
+	return ctx.JSON(status, c.toResponse(profile))
+}
+
+// DeleteTeamDefaultProfile handles DELETE /admin/team-default-profiles?team_id=
+func (c *SessionProfileController) DeleteTeamDefaultProfile(ctx echo.Context) error {
+	teamID := ctx.QueryParam("team_id")
+	if teamID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "team_id is required")
+	}
+	profiles, err := c.teamDefaultProfiles(ctx.Request().Context(), teamID)
+	if err != nil {
+		log.Printf("Failed to look up the default profile of team %s: %v", teamID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete team default profile")
+	}
+	if len(profiles) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "team default profile not found")
+	}
+	for _, p := range profiles {
+		if err := c.repo.Delete(ctx.Request().Context(), p.ID()); err != nil {
+			log.Printf("Failed to delete team default profile %s: %v", p.ID(), err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete team default profile")
+		}
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
 // --- Helpers ---
 
+// teamDefaultProfiles returns the team default profiles, of every team or of
+// teamID only
+func (c *SessionProfileController) teamDefaultProfiles(ctx context.Context, teamID string) ([]*entities.SessionProfile, error) {
+	profiles, err := c.repo.List(ctx, repositories.SessionProfileFilter{Scope: entities.ScopeTeam, TeamID: teamID})
+	if err != nil {
+		return nil, err
+	}
+	var result []*entities.SessionProfile
+	for _, p := range profiles {
+		if p.IsTeamDefault() {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
 func (c *SessionProfileController) canAccess(ctx echo.Context, user *entities.User, profile *entities.SessionProfile) bool {
 	if user.IsAdmin() {
 		return true
@@ -325,6 +455,10 @@ func (c *SessionProfileController) canAccess(ctx echo.Context, user *entities.Us
 }
 
 func (c *SessionProfileController) canModify(ctx echo.Context, user *entities.User, profile *entities.SessionProfile) bool {
+	// Team defaults are managed by admins through /admin/team-default-profiles
+	if profile.IsTeamDefault() && !user.IsAdmin() {
+		return false
+	}
 	return c.canAccess(ctx, user, profile)
 }
 
@@ -383,6 +517,7 @@ func (c *SessionProfileController) toResponse(p *entities.SessionProfile) Sessio
 		Scope:        p.Scope(),
 		TeamID:       p.TeamID(),
 		IsDefault:    p.IsDefault(),
+		TeamDefault:  p.IsTeamDefault(),
 		SelectorTags: p.SelectorTags(),
 		Config: SessionProfileConfigResponse{
 			Environment:            cfg.Environment(),
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// sessionSlugAttempts is how often a slug is generated from slug_prefix
+// before giving up because all candidates are taken
+const sessionSlugAttempts = 5
+
+// findSessionIDsBySlug returns the IDs of the sessions of userID, or of all
+// users when userID is empty, whose slug is slug. Sessions on an External
+// Session Manager are returned with their user-facing ID.
+func findSessionIDsBySlug(ctx context.Context, manager repositories.SessionManager, routeRepo repositories.SessionRouteRepository, userID, slug string) []string {
+	var ids, remoteIDs []string
+	if routeRepo != nil {
+		routes, err := routeRepo.List(ctx, userID)
+		if err != nil {
+			log.Printf("[SESSION_SLUG] Failed to list session routes: %v", err)
+		}
+		for _, route := range routes {
+			if route.RemoteSessionID != "" {
+				remoteIDs = append(remoteIDs, route.RemoteSessionID)
+			}
+			if route.Tags[entities.SessionSlugTag] == slug {
+				ids = append(ids, route.SessionID)
+			}
+		}
+	}
+	if manager != nil {
+		filter := entities.SessionFilter{UserID: userID, Tags: map[string]string{entities.SessionSlugTag: slug}}
+		for _, session := range manager.ListSessions(filter) {
+			if !slices.Contains(remoteIDs, session.ID()) && !slices.Contains(ids, session.ID()) {
+				ids = append(ids, session.ID())
+			}
+		}
+	}
+	return ids
+}
+
+// applySessionSlug validates the slug of a new session of userID, generates
+// it from slug_prefix if needed and stores it in the slug tag
+func (c *SessionController) applySessionSlug(ctx context.Context, userID string, req *entities.StartRequest) error {
+	slug := req.Slug
+	if slug == "" {
+		slug = req.Tags[entities.SessionSlugTag]
+	}
+	if slug == "" && req.SlugPrefix == "" {
+		return nil
+	}
+	if slug != "" {
+		if err := entities.ValidateSessionSlug(slug); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if len(findSessionIDsBySlug(ctx, c.getSessionManager(), c.sessionRouteRepo, userID, slug)) > 0 {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("%s: %s", entities.ErrSessionSlugTaken, slug))
+		}
+	} else {
+		for attempt := 0; slug == "" && attempt < sessionSlugAttempts; attempt++ {
+			candidate, err := entities.GenerateSessionSlug(req.SlugPrefix)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			if len(findSessionIDsBySlug(ctx, c.getSessionManager(), c.sessionRouteRepo, userID, candidate)) == 0 {
+				slug = candidate
+			}
+		}
+		if slug == "" {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("%s: no free slug for prefix %s", entities.ErrSessionSlugTaken, req.SlugPrefix))
+		}
+	}
+
+	tags := make(map[string]string, len(req.Tags)+1)
+	for k, v := range req.Tags {
+		tags[k] = v
+	}
+	tags[entities.SessionSlugTag] = slug
+	req.Tags = tags
+	req.Slug = slug
+	return nil
+}
+
+// SessionSlugMiddleware replaces a slug in the :sessionId path parameter
+// with the ID of the session. The caller's own sessions are looked up first,
+// then the sessions of all users when the slug is unambiguous among them.
+// Access to the session is checked by the handlers as for the session ID.
+func SessionSlugMiddleware(provider SessionManagerProvider, routeRepo repositories.SessionRouteRepository) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			slug := c.Param("sessionId")
+			if slug == "" || !entities.IsSessionSlug(slug) {
+				return next(c)
+			}
+			manager := provider.GetSessionManager()
+			if manager != nil && manager.GetSession(slug) != nil {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			var ids []string
+			if authzCtx := auth.GetAuthorizationContext(c); authzCtx != nil && authzCtx.PersonalScope.UserID != "" {
+				ids = findSessionIDsBySlug(ctx, manager, routeRepo, authzCtx.PersonalScope.UserID, slug)
+			}
+			if len(ids) == 0 {
+				ids = findSessionIDsBySlug(ctx, manager, routeRepo, "", slug)
+			}
+			if len(ids) == 1 {
+				values := c.ParamValues()
+				for i, name := range c.ParamNames() {
+					if name == "sessionId" {
+						values[i] = ids[0]
+					}
+				}
+				c.SetParamValues(values...)
+			}
+			return next(c)
+		}
+	}
+}
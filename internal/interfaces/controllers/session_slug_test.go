@@ -0,0 +1,58 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+func TestSessionSlugMiddleware(t *testing.T) {
+	const ownID = "0b7c1f7e-8f9a-4a55-9d7e-5f0a7c9d1e01"
+	mgr := &fakeSessionManager{sessions: map[string]*fakeSession{
+		ownID:   {id: ownID, userID: "alice", tags: map[string]string{entities.SessionSlugTag: "pr-review"}},
+		"bob-1": {id: "bob-1", userID: "bob", tags: map[string]string{entities.SessionSlugTag: "pr-review"}},
+		"bob-2": {id: "bob-2", userID: "bob", tags: map[string]string{entities.SessionSlugTag: "nightly"}},
+		"carl":  {id: "carl", userID: "carl", tags: map[string]string{entities.SessionSlugTag: "shared"}},
+		"dave":  {id: "dave", userID: "dave", tags: map[string]string{entities.SessionSlugTag: "shared"}},
+	}}
+	middleware := controllers.SessionSlugMiddleware(&testSessionManagerProvider{mgr: mgr}, nil)
+
+	resolve := func(userID, param string) string {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/sessions/"+param+"/events", nil), httptest.NewRecorder())
+		c.SetParamNames("sessionId")
+		c.SetParamValues(param)
+		c.Set("authz_context", &auth.AuthorizationContext{PersonalScope: auth.PersonalScopeAuth{UserID: userID}})
+		var got string
+		if err := middleware(func(c echo.Context) error {
+			got = c.Param("sessionId")
+			return nil
+		})(c); err != nil {
+			t.Fatalf("middleware error = %v", err)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name, userID, param, want string
+	}{
+		{"own slug wins", "alice", "pr-review", ownID},
+		{"own slug of other user", "bob", "pr-review", "bob-1"},
+		{"unambiguous slug of another user", "alice", "nightly", "bob-2"},
+		{"ambiguous slug is kept", "alice", "shared", "shared"},
+		{"unknown slug is kept", "alice", "missing", "missing"},
+		{"session ID is kept", "bob", ownID, ownID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolve(tt.userID, tt.param); got != tt.want {
+				t.Errorf("sessionId = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// SessionSnapshotManager clones sessions and restores them from snapshots of
+// their workdir
+type SessionSnapshotManager interface {
+	GetSession(id string) entities.Session
+	CloneSession(ctx context.Context, sourceID, newID, userID string, req entities.CloneSessionRequest) (entities.Session, error)
+	ListSessionSnapshots(ctx context.Context) ([]entities.SessionSnapshot, error)
+	GetSessionSnapshot(ctx context.Context, name string) (*entities.SessionSnapshot, error)
+	RestoreSessionSnapshot(ctx context.Context, name, newID, userID string, req entities.CloneSessionRequest) (entities.Session, error)
+}
+
+// SessionSnapshotController handles session clones and the snapshots kept to
+// restore deleted sessions
+type SessionSnapshotController struct {
+	manager SessionSnapshotManager
+}
+
+// NewSessionSnapshotController creates a new SessionSnapshotController
+func NewSessionSnapshotController(manager SessionSnapshotManager) *SessionSnapshotController {
+	return &SessionSnapshotController{manager: manager}
+}
+
+// GetName returns the name of this controller for logging
+func (c *SessionSnapshotController) GetName() string {
+	return "SessionSnapshotController"
+}
+
+// CloneSession handles POST /sessions/:sessionId/clone. It snapshots the
+// workdir of the session and starts a new session, owned by the caller, from
+// the snapshot.
+func (c *SessionSnapshotController) CloneSession(ctx echo.Context) error {
+	sourceID := ctx.Param("sessionId")
+	source := c.manager.GetSession(sourceID)
+	if source == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	if err := authorizeRestore(ctx, source.UserID(), source.Scope(), source.TeamID()); err != nil {
+		return err
+	}
+
+	var req entities.CloneSessionRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	session, err := c.manager.CloneSession(ctx.Request().Context(), sourceID, uuid.New().String(), callerID(ctx), req)
+	if err != nil {
+		return sessionSnapshotError(err)
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"session_id":        session.ID(),
+		"source_session_id": sourceID,
+	})
+}
+
+// ListSnapshots handles GET /snapshots and returns the snapshots of the
+// sessions the caller may access, newest first. ?session_id= selects the
+// snapshots of one session.
+func (c *SessionSnapshotController) ListSnapshots(ctx echo.Context) error {
+	snapshots, err := c.manager.ListSessionSnapshots(ctx.Request().Context())
+	if err != nil {
+		return sessionSnapshotError(err)
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	sessionID := ctx.QueryParam("session_id")
+	visible := make([]entities.SessionSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if sessionID != "" && snapshot.SessionID != sessionID {
+			continue
+		}
+		if authzCtx == nil || !authzCtx.CanAccessResource(snapshot.UserID, string(snapshot.Scope), snapshot.TeamID) {
+			continue
+		}
+		visible = append(visible, snapshot)
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"snapshots": visible})
+}
+
+// RestoreSnapshot handles POST /snapshots/:name/restore. It starts a new
+// session, owned by the caller, from the snapshot, e.g. to recover a session
+// deleted by accident.
+func (c *SessionSnapshotController) RestoreSnapshot(ctx echo.Context) error {
+	name := ctx.Param("name")
+	snapshot, err := c.manager.GetSessionSnapshot(ctx.Request().Context(), name)
+	if err != nil {
+		return sessionSnapshotError(err)
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(snapshot.UserID, string(snapshot.Scope), snapshot.TeamID) {
+		// Do not reveal whether the snapshot exists.
+		return sessionSnapshotError(entities.ErrSessionSnapshotNotFound)
+	}
+	if err := authorizeRestore(ctx, snapshot.UserID, snapshot.Scope, snapshot.TeamID); err != nil {
+		return err
+	}
+
+	var req entities.CloneSessionRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	session, err := c.manager.RestoreSessionSnapshot(ctx.Request().Context(), name, uuid.New().String(), callerID(ctx), req)
+	if err != nil {
+		return sessionSnapshotError(err)
+	}
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"session_id":        session.ID(),
+		"source_session_id": snapshot.SessionID,
+		"snapshot":          name,
+	})
+}
+
+// authorizeRestore checks that the caller may start a session from the
+// workdir of a session. Personal sessions are restored by their owner only,
+// because their workdir may hold the owner's credentials.
+func authorizeRestore(ctx echo.Context, userID string, scope entities.ResourceScope, teamID string) error {
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanModifyResource(userID, string(scope), teamID) {
+		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to clone this session")
+	}
+	if scope != entities.ScopeTeam && userID != callerID(ctx) {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the owner can clone a personal session")
+	}
+	return nil
+}
+
+// callerID returns the ID of the user making the request
+func callerID(ctx echo.Context) string {
+	if authzCtx := auth.GetAuthorizationContext(ctx); authzCtx != nil {
+		return authzCtx.PersonalScope.UserID
+	}
+	return ""
+}
+
+// sessionSnapshotError maps a clone or restore error to its HTTP error
+func sessionSnapshotError(err error) error {
+	switch {
+	case errors.Is(err, entities.ErrSnapshotsDisabled):
+		return echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	case errors.Is(err, entities.ErrSessionSnapshotNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Snapshot not found")
+	case errors.Is(err, entities.ErrSessionNotSnapshottable):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, entities.ErrSessionSnapshotNotReady):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, entities.ErrCapabilityNotAllowed), errors.Is(err, entities.ErrAcceleratorNotAllowed),
+		errors.Is(err, entities.ErrImageNotAllowed), errors.Is(err, entities.ErrBudgetExceeded):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, entities.ErrSessionCapacityExhausted), errors.Is(err, entities.ErrShuttingDown):
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+	log.Printf("Session clone failed: %v", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create session")
+}
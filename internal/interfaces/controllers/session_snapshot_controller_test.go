@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+type fakeSessionSnapshotManager struct {
+	sessions  map[string]entities.Session
+	snapshots []entities.SessionSnapshot
+	owner     string
+}
+
+func (f *fakeSessionSnapshotManager) GetSession(id string) entities.Session {
+	return f.sessions[id]
+}
+
+func (f *fakeSessionSnapshotManager) CloneSession(_ context.Context, _, newID, userID string, _ entities.CloneSessionRequest) (entities.Session, error) {
+	f.owner = userID
+	return entities.NewProxySessionWithStatus(newID, userID, entities.ScopeUser, "", nil, time.Now(), "creating"), nil
+}
+
+func (f *fakeSessionSnapshotManager) ListSessionSnapshots(context.Context) ([]entities.SessionSnapshot, error) {
+	return f.snapshots, nil
+}
+
+func (f *fakeSessionSnapshotManager) GetSessionSnapshot(_ context.Context, name string) (*entities.SessionSnapshot, error) {
+	for i := range f.snapshots {
+		if f.snapshots[i].Name == name {
+			return &f.snapshots[i], nil
+		}
+	}
+	return nil, entities.ErrSessionSnapshotNotFound
+}
+
+func (f *fakeSessionSnapshotManager) RestoreSessionSnapshot(_ context.Context, _, newID, userID string, _ entities.CloneSessionRequest) (entities.Session, error) {
+	f.owner = userID
+	return entities.NewProxySessionWithStatus(newID, userID, entities.ScopeUser, "", nil, time.Now(), "creating"), nil
+}
+
+func snapshotContext(method, path string, userID string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if len(params) == 2 {
+		c.SetParamNames(params[0])
+		c.SetParamValues(params[1])
+	}
+	c.Set("authz_context", &auth.AuthorizationContext{
+		PersonalScope: auth.PersonalScopeAuth{UserID: userID, CanRead: true, CanCreate: true},
+		TeamScope: auth.TeamScopeAuth{
+			Teams:           []string{"acme/ml"},
+			TeamPermissions: map[string]auth.TeamPermissions{"acme/ml": {CanRead: true, CanCreate: true}},
+		},
+	})
+	return c, rec
+}
+
+func TestSessionSnapshotController(t *testing.T) {
+	manager := &fakeSessionSnapshotManager{
+		sessions: map[string]entities.Session{
+			"mine":   entities.NewProxySessionWithStatus("mine", "alice", entities.ScopeUser, "", nil, time.Now(), "active"),
+			"theirs": entities.NewProxySessionWithStatus("theirs", "bob", entities.ScopeUser, "", nil, time.Now(), "active"),
+			"team":   entities.NewProxySessionWithStatus("team", "bob", entities.ScopeTeam, "acme/ml", nil, time.Now(), "active"),
+		},
+		snapshots: []entities.SessionSnapshot{
+			{Name: "snap-mine", SessionID: "gone", UserID: "alice", Scope: entities.ScopeUser, Kind: entities.SessionSnapshotScheduled},
+			{Name: "snap-theirs", SessionID: "other", UserID: "bob", Scope: entities.ScopeUser, Kind: entities.SessionSnapshotScheduled},
+		},
+	}
+	controller := NewSessionSnapshotController(manager)
+
+	c, _ := snapshotContext(http.MethodPost, "/sessions/missing/clone", "alice", "sessionId", "missing")
+	assertHTTPError(t, controller.CloneSession(c), http.StatusNotFound)
+	c, _ = snapshotContext(http.MethodPost, "/sessions/theirs/clone", "alice", "sessionId", "theirs")
+	assertHTTPError(t, controller.CloneSession(c), http.StatusForbidden)
+
+	for _, id := range []string{"mine", "team"} {
+		c, rec := snapshotContext(http.MethodPost, "/sessions/"+id+"/clone", "alice", "sessionId", id)
+		require.NoError(t, controller.CloneSession(c))
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, id, resp["source_session_id"])
+		assert.NotEmpty(t, resp["session_id"])
+		assert.Equal(t, "alice", manager.owner)
+	}
+
+	c, rec := snapshotContext(http.MethodGet, "/snapshots", "alice")
+	require.NoError(t, controller.ListSnapshots(c))
+	var list struct {
+		Snapshots []entities.SessionSnapshot `json:"snapshots"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Snapshots, 1)
+	assert.Equal(t, "snap-mine", list.Snapshots[0].Name)
+
+	c, _ = snapshotContext(http.MethodPost, "/snapshots/snap-theirs/restore", "alice", "name", "snap-theirs")
+	assertHTTPError(t, controller.RestoreSnapshot(c), http.StatusNotFound)
+	c, rec = snapshotContext(http.MethodPost, "/snapshots/snap-mine/restore", "alice", "name", "snap-mine")
+	require.NoError(t, controller.RestoreSnapshot(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"source_session_id":"gone"`)
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+// States GET /sessions/:sessionId/wait can wait for
+const (
+	// WaitForStable waits until the agent is idle and accepts a message
+	WaitForStable = "stable"
+	// WaitForStopped waits until the session has ended or is deleted
+	WaitForStopped = "stopped"
+)
+
+const (
+	defaultSessionWaitTimeout = 300 * time.Second
+	maxSessionWaitTimeout     = time.Hour
+)
+
+// sessionWaitRecheckInterval is how often the wait endpoint reads the
+// session again. Status events wake it up earlier; the re-check notices
+// deleted sessions and works with managers that do not publish status events.
+var sessionWaitRecheckInterval = 5 * time.Second
+
+// SessionWaitResponse is the response of GET /sessions/:sessionId/wait
+type SessionWaitResponse struct {
+	SessionID string `json:"session_id"`
+	For       string `json:"for"`
+	// Status is the last status of the session; "deleted" once it is gone
+	Status string `json:"status"`
+	// Reached is true when the session reached the state waited for
+	Reached bool `json:"reached"`
+	// TimedOut is true when the timeout elapsed first
+	TimedOut bool `json:"timed_out"`
+	// Waited is how long the request waited, in milliseconds
+	Waited int64 `json:"waited_ms"`
+}
+
+// sessionEnded reports whether a session in status will not run its agent
+// again. Completed oneshot sessions have ended; other completed sessions
+// still accept messages.
+func sessionEnded(session entities.Session, status string) bool {
+	switch status {
+	case "stopped", "error", "timeout", "evicted", entities.SessionStatusFailed:
+		return true
+	case entities.SessionStatusCompleted:
+		ks, ok := session.(*services.KubernetesSession)
+		return ok && ks.RunsAsJob()
+	}
+	return false
+}
+
+// sessionWaitOutcome reports whether the wait for a session in status is
+// over and whether the session reached the state waited for. A session that
+// has ended is never going to become stable.
+func sessionWaitOutcome(waitFor string, session entities.Session, status string) (done, reached bool) {
+	ended := sessionEnded(session, status)
+	switch waitFor {
+	case WaitForStopped:
+		return ended, ended
+	default:
+		if ended {
+			return true, false
+		}
+		stable := status == "active" || status == entities.SessionStatusCompleted
+		return stable, stable
+	}
+}
+
+// parseSessionWaitTimeout parses the timeout query parameter given as a
+// duration ("300s", "5m") or in seconds, clamped to [1s, 1h]
+func parseSessionWaitTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultSessionWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			return 0, err
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	if timeout > maxSessionWaitTimeout {
+		timeout = maxSessionWaitTimeout
+	}
+	return timeout, nil
+}
+
+// WaitSession handles GET /sessions/:sessionId/wait.
+// It blocks until the session reaches the state given by the for query
+// parameter or the timeout elapses, so that automation does not have to poll
+// the session status itself.
+//
+// Query parameters:
+//   - for: "stable" (default) waits until the agent is idle; "stopped" waits
+//     until the session has ended or is deleted
+//   - timeout: max wait time as a duration or in seconds (default 300s, max 1h)
+//
+// The response is 200 whether or not the state was reached; reached and
+// timed_out tell the outcomes apart. Waiting for "stable" ends with
+// reached=false when the session ends first.
+func (c *SessionController) WaitSession(ctx echo.Context) error {
+	c.setCORSHeaders(ctx)
+
+	sessionID := ctx.Param("sessionId")
+	authzCtx := auth.GetAuthorizationContext(ctx)
+
+	waitFor := ctx.QueryParam("for")
+	if waitFor == "" {
+		waitFor = WaitForStable
+	}
+	if waitFor != WaitForStable && waitFor != WaitForStopped {
+		return echo.NewHTTPError(http.StatusBadRequest, `for must be "stable" or "stopped"`)
+	}
+	timeout, err := parseSessionWaitTimeout(ctx.QueryParam("timeout"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "timeout must be a duration such as 300s or a number of seconds")
+	}
+
+	manager := c.getSessionManager()
+	session := manager.GetSession(sessionID)
+	if session == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "session not found")
+	}
+	if !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return echo.NewHTTPError(http.StatusForbidden, "access denied")
+	}
+
+	// Subscribe before reading the status so that a change in between is
+	// not missed.
+	var eventCh <-chan services.SessionStatusEvent
+	if watcher, ok := manager.(ProxyStatusWatcher); ok {
+		ch, cancel := watcher.SubscribeStatusEvents()
+		defer cancel()
+		eventCh = ch
+	}
+
+	start := time.Now()
+	respond := func(status string, reached, timedOut bool) error {
+		log.Printf("[SESSION_WAIT] Session %s: for=%s status=%s reached=%t timed_out=%t", sessionID, waitFor, status, reached, timedOut)
+		return ctx.JSON(http.StatusOK, SessionWaitResponse{
+			SessionID: sessionID,
+			For:       waitFor,
+			Status:    status,
+			Reached:   reached,
+			TimedOut:  timedOut,
+			Waited:    time.Since(start).Milliseconds(),
+		})
+	}
+	// check reads the session again and reports whether the wait is over
+	check := func() (bool, error) {
+		session := manager.GetSession(sessionID)
+		if session == nil {
+			return true, respond("deleted", waitFor == WaitForStopped, false)
+		}
+		status := session.Status()
+		if done, reached := sessionWaitOutcome(waitFor, session, status); done {
+			return true, respond(status, reached, false)
+		}
+		return false, nil
+	}
+
+	if done, err := check(); done {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	recheck := time.NewTicker(sessionWaitRecheckInterval)
+	defer recheck.Stop()
+
+	reqCtx := ctx.Request().Context()
+	for {
+		select {
+		case <-reqCtx.Done():
+			return nil
+
+		case evt, open := <-eventCh:
+			if !open {
+				// Manager shutting down; keep waiting on the re-check
+				eventCh = nil
+				continue
+			}
+			if evt.SessionID != sessionID {
+				continue
+			}
+			if done, err := check(); done {
+				return err
+			}
+
+		case <-recheck.C:
+			if done, err := check(); done {
+				return err
+			}
+
+		case <-timer.C:
+			status := "deleted"
+			if session := manager.GetSession(sessionID); session != nil {
+				status = session.Status()
+			}
+			return respond(status, false, true)
+		}
+	}
+}
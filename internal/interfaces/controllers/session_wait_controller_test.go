@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+type mockStatusSession struct {
+	*mockWaitSession
+	mu     sync.Mutex
+	status string
+}
+
+func (s *mockStatusSession) Status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *mockStatusSession) setStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// mockStatusSessionManager publishes status events and can forget its session
+type mockStatusSessionManager struct {
+	*mockWaitSessionManager
+	mu       sync.Mutex
+	deleted  bool
+	statusCh chan services.SessionStatusEvent
+}
+
+func (m *mockStatusSessionManager) GetSession(id string) entities.Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.deleted {
+		return nil
+	}
+	return m.mockWaitSessionManager.GetSession(id)
+}
+
+func (m *mockStatusSessionManager) SubscribeStatusEvents() (<-chan services.SessionStatusEvent, func()) {
+	return m.statusCh, func() {}
+}
+
+func newStatusTestController(status string) (*SessionController, *mockStatusSessionManager, *mockStatusSession) {
+	session := &mockStatusSession{mockWaitSession: &mockWaitSession{id: "sess-1", userID: "user-1"}, status: status}
+	manager := &mockStatusSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(session),
+		statusCh:               make(chan services.SessionStatusEvent, 4),
+	}
+	return NewSessionController(&mockWaitProvider{manager: manager}, nil), manager, session
+}
+
+func waitSession(t *testing.T, c *SessionController, sessionID string, params map[string]string) SessionWaitResponse {
+	t.Helper()
+	ctx, rec := makeWaitEchoContext(t, sessionID, params, "user-1")
+	require.NoError(t, c.WaitSession(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp SessionWaitResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestWaitSession_AlreadyStable(t *testing.T) {
+	c, _, _ := newStatusTestController("active")
+
+	resp := waitSession(t, c, "sess-1", nil)
+	assert.Equal(t, SessionWaitResponse{SessionID: "sess-1", For: "stable", Status: "active", Reached: true, Waited: resp.Waited}, resp)
+}
+
+func TestWaitSession_StableAfterStatusEvent(t *testing.T) {
+	c, manager, session := newStatusTestController("running")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		manager.statusCh <- services.SessionStatusEvent{SessionID: "other", Status: "active"}
+		session.setStatus("active")
+		manager.statusCh <- services.SessionStatusEvent{SessionID: "sess-1", Status: "active"}
+	}()
+
+	resp := waitSession(t, c, "sess-1", map[string]string{"for": "stable", "timeout": "5s"})
+	assert.True(t, resp.Reached)
+	assert.False(t, resp.TimedOut)
+	assert.Equal(t, "active", resp.Status)
+}
+
+func TestWaitSession_StoppedWhenDeleted(t *testing.T) {
+	old := sessionWaitRecheckInterval
+	sessionWaitRecheckInterval = 20 * time.Millisecond
+	defer func() { sessionWaitRecheckInterval = old }()
+
+	c, manager, _ := newStatusTestController("running")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		manager.mu.Lock()
+		manager.deleted = true
+		manager.mu.Unlock()
+	}()
+
+	resp := waitSession(t, c, "sess-1", map[string]string{"for": "stopped", "timeout": "5"})
+	assert.True(t, resp.Reached)
+	assert.Equal(t, "deleted", resp.Status)
+}
+
+func TestWaitSession_StableNeverReachedWhenSessionEnds(t *testing.T) {
+	c, _, _ := newStatusTestController("error")
+
+	resp := waitSession(t, c, "sess-1", map[string]string{"for": "stable"})
+	assert.False(t, resp.Reached)
+	assert.False(t, resp.TimedOut)
+	assert.Equal(t, "error", resp.Status)
+}
+
+func TestWaitSession_Timeout(t *testing.T) {
+	c, _, _ := newStatusTestController("active")
+
+	resp := waitSession(t, c, "sess-1", map[string]string{"for": "stopped", "timeout": "1s"})
+	assert.False(t, resp.Reached)
+	assert.True(t, resp.TimedOut)
+	assert.Equal(t, "active", resp.Status)
+	assert.GreaterOrEqual(t, resp.Waited, int64(1000))
+}
+
+func TestWaitSession_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionID  string
+		userID     string
+		params     map[string]string
+		wantStatus int
+	}{
+		{"unknown state", "sess-1", "user-1", map[string]string{"for": "running"}, http.StatusBadRequest},
+		{"invalid timeout", "sess-1", "user-1", map[string]string{"timeout": "soon"}, http.StatusBadRequest},
+		{"unknown session", "missing", "user-1", nil, http.StatusNotFound},
+		{"other user", "sess-1", "user-2", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newStatusTestController("running")
+			ctx, _ := makeWaitEchoContext(t, tt.sessionID, tt.params, tt.userID)
+			err := c.WaitSession(ctx)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
+
+func TestParseSessionWaitTimeout(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"":      300 * time.Second,
+		"300s":  300 * time.Second,
+		"5m":    5 * time.Minute,
+		"90":    90 * time.Second,
+		"100ms": time.Second,
+		"3h":    time.Hour,
+	} {
+		got, err := parseSessionWaitTimeout(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+}
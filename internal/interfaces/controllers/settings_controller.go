@@ -15,6 +15,7 @@ import (
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
 	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
 	"github.com/takutakahashi/agentapi-proxy/pkg/urlutil"
 )
@@ -22,6 +23,15 @@ import (
 // BaseSettingsName is the reserved name for global base settings (admin-only)
 const BaseSettingsName = "base"
 
+// notificationChannelTypes maps the names of notification_channels to the
+// subscription types they enable
+var notificationChannelTypes = map[string]string{
+	"web":     notification.SubscriptionTypeWebPush,
+	"slack":   notification.SubscriptionTypeSlack,
+	"discord": notification.SubscriptionTypeDiscord,
+	"teams":   notification.SubscriptionTypeTeams,
+}
+
 // SettingsController handles settings-related HTTP requests
 type SettingsController struct {
 	repo             repositories.SettingsRepository
@@ -64,6 +74,7 @@ type MCPServerRequest struct {
 	Args    []string          `json:"args,omitempty"`    // for stdio
 	Env     map[string]string `json:"env,omitempty"`     // environment variables
 	Headers map[string]string `json:"headers,omitempty"` // for http/sse
+	Enabled *bool             `json:"enabled,omitempty"` // default true
 }
 
 // MarketplaceRequest is the request body for a single marketplace
@@ -109,10 +120,27 @@ type UpdateSettingsRequest struct {
 	EnvVars                 map[string]string                `json:"env_vars,omitempty"`                   // Custom environment variables
 	PreferredTeamID         *string                          `json:"preferred_team_id,omitempty"`          // "org/team-slug" format; "" to clear
 	SlackUserID             *string                          `json:"slack_user_id,omitempty"`              // Slack DM notification user ID
-	NotificationChannels    *[]string                        `json:"notification_channels,omitempty"`      // Active notification channels (e.g. ["web", "slack"])
+	NotificationChannels    *[]string                        `json:"notification_channels,omitempty"`      // Active notification channels ("web", "slack", "discord", "teams")
+	Locale                  *string                          `json:"locale,omitempty"`                     // Language of messages and notifications ("en", "ja"); "" to clear
+	NotificationSavedSearch *string                          `json:"notification_saved_search,omitempty"`  // Only notify about sessions matching this saved search; "" to clear
+	NotificationTypes       *[]string                        `json:"notification_types,omitempty"`         // Notification types to receive (e.g. ["run_completed"]); empty for all
 	ExternalSessionManagers *[]ExternalSessionManagerRequest `json:"external_session_managers,omitempty"`  // External session managers (External Session Manager registrations)
 	GitSync                 *GitSyncConfigRequest            `json:"git_sync,omitempty"`                   // GitHub sync configuration
 	DefaultSessionProfileID *string                          `json:"default_session_profile_id,omitempty"` // Default session profile ID for this settings scope
+	CapabilityPolicy        *CapabilityPolicyRequest         `json:"capability_policy,omitempty"`          // Risky session capabilities approved by a team admin
+}
+
+// CapabilityPolicyRequest is the request body for the capability policy.
+// Only team admins may change it.
+type CapabilityPolicyRequest struct {
+	AllowedCapabilities []string `json:"allowed_capabilities"` // "terminal", "editor", "docker", "exec"
+}
+
+// CapabilityPolicyResponse is the response body for the capability policy
+type CapabilityPolicyResponse struct {
+	AllowedCapabilities []string `json:"allowed_capabilities"`
+	UpdatedBy           string   `json:"updated_by,omitempty"`
+	UpdatedAt           string   `json:"updated_at"`
 }
 
 // ExternalSessionManagerRequest represents a single external session manager registration
@@ -143,6 +171,7 @@ type MCPServerResponse struct {
 	Args       []string `json:"args,omitempty"`
 	EnvKeys    []string `json:"env_keys,omitempty"`    // only keys, not values
 	HeaderKeys []string `json:"header_keys,omitempty"` // only keys, not values
+	Enabled    bool     `json:"enabled"`
 }
 
 // MarketplaceResponse is the response body for a single marketplace
@@ -163,9 +192,13 @@ type SettingsResponse struct {
 	PreferredTeamID         string                           `json:"preferred_team_id,omitempty"`          // "org/team-slug" format
 	SlackUserID             string                           `json:"slack_user_id,omitempty"`              // Slack DM notification user ID
 	NotificationChannels    []string                         `json:"notification_channels,omitempty"`      // Active notification channels
+	Locale                  string                           `json:"locale,omitempty"`                     // Language of messages and notifications
+	NotificationSavedSearch string                           `json:"notification_saved_search,omitempty"`  // Saved search that routes notifications
+	NotificationTypes       []string                         `json:"notification_types,omitempty"`         // Notification types the user receives
 	ExternalSessionManagers []ExternalSessionManagerResponse `json:"external_session_managers,omitempty"`  // Registered external session managers
 	GitSync                 *GitSyncConfigResponse           `json:"git_sync,omitempty"`                   // GitHub sync configuration (token redacted)
 	DefaultSessionProfileID string                           `json:"default_session_profile_id,omitempty"` // Default session profile ID for this settings scope
+	CapabilityPolicy        *CapabilityPolicyResponse        `json:"capability_policy,omitempty"`          // Risky session capabilities approved by a team admin
 	CreatedAt               string                           `json:"created_at"`
 	UpdatedAt               string                           `json:"updated_at"`
 }
@@ -296,6 +329,21 @@ func (c *SettingsController) UpdateSettings(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
+	// The capability policy is a security review gate: plain team members
+	// may edit the rest of their team settings but not this.
+	if req.CapabilityPolicy != nil && !c.canModifyCapabilityPolicy(user, name) {
+		return echo.NewHTTPError(http.StatusForbidden, "Only team admins can change the capability policy")
+	}
+
+	locale := ""
+	if req.Locale != nil && *req.Locale != "" {
+		parsed, ok := i18n.Parse(*req.Locale)
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unsupported locale")
+		}
+		locale = string(parsed)
+	}
+
 	// Get existing settings or create new one
 	settings, err := c.repo.FindByName(ctx.Request().Context(), name)
 	isNewSettings := err != nil
@@ -368,6 +416,9 @@ func (c *SettingsController) UpdateSettings(ctx echo.Context) error {
 				}
 			}
 			server.SetHeaders(headers)
+			if serverReq.Enabled != nil {
+				server.SetEnabled(*serverReq.Enabled)
+			}
 
 			mcpServers.SetServer(serverName, server)
 		}
@@ -437,6 +488,27 @@ func (c *SettingsController) UpdateSettings(ctx echo.Context) error {
 		}
 	}
 
+	// Update locale (empty string "" clears the setting)
+	if req.Locale != nil {
+		settings.SetLocale(locale)
+	}
+
+	// Route notifications by one of the user's saved searches ("" clears it)
+	if req.NotificationSavedSearch != nil {
+		if name := *req.NotificationSavedSearch; name != "" && settings.SavedSearch(name) == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown saved search "+name)
+		}
+		settings.SetNotificationSavedSearch(*req.NotificationSavedSearch)
+	}
+
+	// Update the notification types the user receives (empty list for all)
+	if req.NotificationTypes != nil {
+		if err := notification.ValidateNotificationTypes(*req.NotificationTypes); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		settings.SetNotificationTypes(*req.NotificationTypes)
+	}
+
 	// Update notification channels — toggle Active on existing subscriptions instead of deleting them
 	if req.NotificationChannels != nil {
 		settings.SetNotificationChannels(*req.NotificationChannels)
@@ -444,15 +516,12 @@ func (c *SettingsController) UpdateSettings(ctx echo.Context) error {
 		if c.notificationSvc != nil {
 			userID := string(user.ID())
 			channels := *req.NotificationChannels
-			// len == 0 means all channels enabled (backward compat)
-			slackEnabled := len(channels) == 0 || containsString(channels, "slack")
-			webEnabled := len(channels) == 0 || containsString(channels, "web")
-
-			if err := c.notificationSvc.SetSubscriptionTypeActive(userID, notification.SubscriptionTypeSlack, slackEnabled); err != nil {
-				log.Printf("[SETTINGS] Failed to update Slack subscription active state: %v", err)
-			}
-			if err := c.notificationSvc.SetSubscriptionTypeActive(userID, notification.SubscriptionTypeWebPush, webEnabled); err != nil {
-				log.Printf("[SETTINGS] Failed to update WebPush subscription active state: %v", err)
+			for channel, subType := range notificationChannelTypes {
+				// len == 0 means all channels enabled (backward compat)
+				enabled := len(channels) == 0 || containsString(channels, channel)
+				if err := c.notificationSvc.SetSubscriptionTypeActive(userID, subType, enabled); err != nil {
+					log.Printf("[SETTINGS] Failed to update %s subscription active state: %v", subType, err)
+				}
 			}
 		}
 	}
@@ -566,6 +635,18 @@ func (c *SettingsController) UpdateSettings(ctx echo.Context) error {
 		settings.SetDefaultSessionProfileID(*req.DefaultSessionProfileID)
 	}
 
+	// Update capability policy
+	if req.CapabilityPolicy != nil {
+		allowed := make([]entities.SessionCapability, 0, len(req.CapabilityPolicy.AllowedCapabilities))
+		for _, capability := range req.CapabilityPolicy.AllowedCapabilities {
+			allowed = append(allowed, entities.SessionCapability(capability))
+		}
+		policy := entities.NewCapabilityPolicy(allowed)
+		policy.SetUpdatedBy(string(user.ID()))
+		settings.SetCapabilityPolicy(policy)
+		log.Printf("[AUDIT] capability_policy_updated settings=%s user=%s allowed=%q", name, user.ID(), entities.JoinCapabilities(policy.Allowed()))
+	}
+
 	// Determine and set auth_mode
 	authMode := c.determineAuthMode(settings, req.AuthMode)
 	settings.SetAuthMode(authMode)
@@ -752,6 +833,28 @@ func (c *SettingsController) canModify(user *entities.User, name string) bool {
 	return false
 }
 
+// canModifyCapabilityPolicy checks if the user can change the capability
+// policy stored in the named settings. The policy only has an effect on the
+// base settings (user-scoped sessions) and on team settings, so it cannot be
+// set on personal settings.
+func (c *SettingsController) canModifyCapabilityPolicy(user *entities.User, name string) bool {
+	if user.IsAdmin() {
+		return true
+	}
+	if name == BaseSettingsName || user.GitHubInfo() == nil {
+		return false
+	}
+
+	sanitizedInputName := c.sanitizeName(name)
+	for _, team := range user.GitHubInfo().Teams() {
+		teamID := team.Organization + "/" + team.TeamSlug
+		if c.sanitizeName(teamID) == sanitizedInputName {
+			return user.IsTeamAdmin(teamID)
+		}
+	}
+	return false
+}
+
 // sanitizeName sanitizes a name for comparison
 func (c *SettingsController) sanitizeName(s string) string {
 	// Convert to lowercase
@@ -824,14 +927,7 @@ func (c *SettingsController) toResponseWithESMTokens(settings *entities.Settings
 	if mcpServers := settings.MCPServers(); mcpServers != nil && !mcpServers.IsEmpty() {
 		resp.MCPServers = make(map[string]*MCPServerResponse)
 		for name, server := range mcpServers.Servers() {
-			resp.MCPServers[name] = &MCPServerResponse{
-				Type:       server.Type(),
-				URL:        server.URL(),
-				Command:    server.Command(),
-				Args:       server.Args(),
-				EnvKeys:    server.EnvKeys(),
-				HeaderKeys: server.HeaderKeys(),
-			}
+			resp.MCPServers[name] = toMCPServerResponse(server)
 		}
 	}
 
@@ -855,8 +951,23 @@ func (c *SettingsController) toResponseWithESMTokens(settings *entities.Settings
 	resp.PreferredTeamID = settings.PreferredTeamID()
 	resp.SlackUserID = settings.SlackUserID()
 	resp.NotificationChannels = settings.NotificationChannels()
+	resp.Locale = settings.Locale()
+	resp.NotificationSavedSearch = settings.NotificationSavedSearch()
+	resp.NotificationTypes = settings.NotificationTypes()
 	resp.DefaultSessionProfileID = settings.DefaultSessionProfileID()
 
+	if policy := settings.CapabilityPolicy(); policy != nil {
+		allowed := make([]string, 0, len(policy.Allowed()))
+		for _, capability := range policy.Allowed() {
+			allowed = append(allowed, string(capability))
+		}
+		resp.CapabilityPolicy = &CapabilityPolicyResponse{
+			AllowedCapabilities: allowed,
+			UpdatedBy:           policy.UpdatedBy(),
+			UpdatedAt:           policy.UpdatedAt().Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
 	if gs := settings.GitSync(); gs != nil {
 		resp.GitSync = &GitSyncConfigResponse{
 			Enabled:        gs.Enabled,
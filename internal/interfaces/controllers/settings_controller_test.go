@@ -284,6 +284,57 @@ func TestUpdateSettings_DefaultSessionProfileID(t *testing.T) {
 	assert.Equal(t, "profile-1", resp.DefaultSessionProfileID)
 }
 
+func TestUpdateSettings_CapabilityPolicyRequiresTeamAdmin(t *testing.T) {
+	newTeamUser := func(id, role string) *entities.User {
+		user := entities.NewUser(entities.UserID(id), entities.UserTypeGitHub, id)
+		info := entities.NewGitHubUserInfo(1, id, id, id+"@example.com", "", "", "")
+		user.SetGitHubInfo(info, []entities.GitHubTeamMembership{{Organization: "org", TeamSlug: "team", Role: role}})
+		return user
+	}
+	update := func(repo *mockSettingsRepository, user *entities.User) (*httptest.ResponseRecorder, error) {
+		body, err := json.Marshal(UpdateSettingsRequest{
+			CapabilityPolicy: &CapabilityPolicyRequest{AllowedCapabilities: []string{"terminal"}},
+		})
+		require.NoError(t, err)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/settings/org%2Fteam", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("name")
+		c.SetParamValues("org/team")
+		c.Set("internal_user", user)
+		return rec, NewSettingsController(repo, nil, "", "").UpdateSettings(c)
+	}
+
+	repo := newMockSettingsRepository()
+	_, err := update(repo, newTeamUser("member", "member"))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	_, saved := repo.settings["org/team"]
+	assert.False(t, saved, "settings must not be saved when the policy change is rejected")
+
+	rec, err := update(repo, newTeamUser("maintainer", "maintainer"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	savedSettings, err := repo.FindByName(context.Background(), "org/team")
+	require.NoError(t, err)
+	require.NotNil(t, savedSettings.CapabilityPolicy())
+	assert.True(t, savedSettings.CapabilityPolicy().Allows(entities.CapabilityTerminal))
+	assert.Equal(t, "maintainer", savedSettings.CapabilityPolicy().UpdatedBy())
+
+	var resp SettingsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.CapabilityPolicy)
+	assert.Equal(t, []string{"terminal"}, resp.CapabilityPolicy.AllowedCapabilities)
+
+	// Capability policies have no effect on personal settings.
+	assert.False(t, NewSettingsController(repo, nil, "", "").canModifyCapabilityPolicy(newTeamUser("maintainer", "maintainer"), "maintainer"))
+}
+
 func TestMergeSecrets(t *testing.T) {
 	ctrl := &SettingsController{}
 
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/urlutil"
+)
+
+// MCPServerEntryResponse is a single MCP server of the registry
+type MCPServerEntryResponse struct {
+	Name string `json:"name"`
+	*MCPServerResponse
+}
+
+// MCPServerListResponse is the response of the MCP server registry listing
+type MCPServerListResponse struct {
+	Settings string                    `json:"settings"`
+	Servers  []*MCPServerEntryResponse `json:"servers"`
+}
+
+// ListMCPServers handles GET /settings/:name/mcp-servers.
+// The settings name selects the scope: "base", a team ID or a user ID.
+func (c *SettingsController) ListMCPServers(ctx echo.Context) error {
+	name, err := c.mcpServerSettingsName(ctx, c.canAccess)
+	if err != nil {
+		return err
+	}
+
+	resp := &MCPServerListResponse{Settings: name, Servers: []*MCPServerEntryResponse{}}
+	settings, err := c.repo.FindByName(ctx.Request().Context(), name)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ctx.JSON(http.StatusOK, resp)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get settings")
+	}
+	if servers := settings.MCPServers(); servers != nil {
+		for _, serverName := range servers.ServerNames() {
+			resp.Servers = append(resp.Servers, &MCPServerEntryResponse{
+				Name:              serverName,
+				MCPServerResponse: toMCPServerResponse(servers.GetServer(serverName)),
+			})
+		}
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// GetMCPServer handles GET /settings/:name/mcp-servers/:server
+func (c *SettingsController) GetMCPServer(ctx echo.Context) error {
+	name, err := c.mcpServerSettingsName(ctx, c.canAccess)
+	if err != nil {
+		return err
+	}
+
+	_, server, err := c.findMCPServer(ctx, name, ctx.Param("server"))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, &MCPServerEntryResponse{Name: server.Name(), MCPServerResponse: toMCPServerResponse(server)})
+}
+
+// PutMCPServer handles PUT /settings/:name/mcp-servers/:server. It creates
+// or replaces one server; as in PUT /settings/:name, empty env and header
+// values keep the stored secret and omitted keys are kept as well.
+func (c *SettingsController) PutMCPServer(ctx echo.Context) error {
+	name, err := c.mcpServerSettingsName(ctx, c.canModify)
+	if err != nil {
+		return err
+	}
+
+	serverName := ctx.Param("server")
+	var req MCPServerRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	settings, err := c.repo.FindByName(ctx.Request().Context(), name)
+	if err != nil {
+		settings = entities.NewSettings(name)
+	}
+	servers := settings.MCPServers()
+	if servers == nil {
+		servers = entities.NewMCPServersSettings()
+	}
+	existing := servers.GetServer(serverName)
+	server, err := c.newMCPServer(serverName, &req, existing)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	servers.SetServer(serverName, server)
+	settings.SetMCPServers(servers)
+	if err := c.repo.Save(ctx.Request().Context(), settings); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save settings")
+	}
+
+	status := http.StatusOK
+	if existing == nil {
+		status = http.StatusCreated
+	}
+	return ctx.JSON(status, &MCPServerEntryResponse{Name: serverName, MCPServerResponse: toMCPServerResponse(server)})
+}
+
+// DeleteMCPServer handles DELETE /settings/:name/mcp-servers/:server
+func (c *SettingsController) DeleteMCPServer(ctx echo.Context) error {
+	name, err := c.mcpServerSettingsName(ctx, c.canModify)
+	if err != nil {
+		return err
+	}
+
+	serverName := ctx.Param("server")
+	settings, _, err := c.findMCPServer(ctx, name, serverName)
+	if err != nil {
+		return err
+	}
+	servers := settings.MCPServers()
+	servers.RemoveServer(serverName)
+	settings.SetMCPServers(servers)
+	if err := c.repo.Save(ctx.Request().Context(), settings); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save settings")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// newMCPServer builds a server from its request. When it replaces existing,
+// env and header values are merged with mergeSecrets; new servers must have a
+// valid name.
+func (c *SettingsController) newMCPServer(name string, req *MCPServerRequest, existing *entities.MCPServer) (*entities.MCPServer, error) {
+	if existing == nil {
+		if err := entities.ValidateMCPServerName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	server := entities.NewMCPServer(name, req.Type)
+	server.SetURL(req.URL)
+	server.SetCommand(req.Command)
+	server.SetArgs(req.Args)
+	env, headers := req.Env, req.Headers
+	if existing != nil {
+		env = c.mergeSecrets(existing.Env(), req.Env)
+		headers = c.mergeSecrets(existing.Headers(), req.Headers)
+	}
+	server.SetEnv(env)
+	server.SetHeaders(headers)
+	if req.Enabled != nil {
+		server.SetEnabled(*req.Enabled)
+	}
+	if err := server.Validate(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// mcpServerSettingsName returns the settings name of an MCP server registry
+// request after checking the caller's permission on it
+func (c *SettingsController) mcpServerSettingsName(ctx echo.Context, allowed func(*entities.User, string) bool) (string, error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	name := urlutil.DecodeSlashParam(ctx.Param("name"))
+	if name == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+	if !allowed(user, name) {
+		return "", echo.NewHTTPError(http.StatusForbidden, "Access denied")
+	}
+	return name, nil
+}
+
+// findMCPServer returns the named settings and one of their servers, or a
+// 404 error
+func (c *SettingsController) findMCPServer(ctx echo.Context, name, serverName string) (*entities.Settings, *entities.MCPServer, error) {
+	settings, err := c.repo.FindByName(ctx.Request().Context(), name)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil, echo.NewHTTPError(http.StatusNotFound, "MCP server not found")
+		}
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get settings")
+	}
+	if settings.MCPServers() == nil || settings.MCPServers().GetServer(serverName) == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "MCP server not found")
+	}
+	return settings, settings.MCPServers().GetServer(serverName), nil
+}
+
+// toMCPServerResponse converts an MCP server to its response; env and
+// header values are secrets, so only their keys are returned
+func toMCPServerResponse(server *entities.MCPServer) *MCPServerResponse {
+	return &MCPServerResponse{
+		Type:       server.Type(),
+		URL:        server.URL(),
+		Command:    server.Command(),
+		Args:       server.Args(),
+		EnvKeys:    server.EnvKeys(),
+		HeaderKeys: server.HeaderKeys(),
+		Enabled:    server.Enabled(),
+	}
+}
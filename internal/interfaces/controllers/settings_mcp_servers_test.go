@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestMCPServerRegistry(t *testing.T) {
+	repo := newMockSettingsRepository()
+	h := NewSettingsController(repo, nil, "", "")
+	e := echo.New()
+	call := func(handler echo.HandlerFunc, method, name, server string, body interface{}, user *entities.User) (*httptest.ResponseRecorder, error) {
+		var raw []byte
+		if body != nil {
+			var err error
+			raw, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req := httptest.NewRequest(method, "/settings/"+name+"/mcp-servers/"+server, bytes.NewReader(raw))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("name", "server")
+		c.SetParamValues(name, server)
+		c.Set("internal_user", user)
+		return rec, handler(c)
+	}
+	owner := createTestUser("alice", false)
+	statusOf := func(err error) int {
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		return httpErr.Code
+	}
+
+	// Schema validation
+	_, err := call(h.PutMCPServer, http.MethodPut, "alice", "github", MCPServerRequest{Type: "http", URL: "ftp://example.com"}, owner)
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	_, err = call(h.PutMCPServer, http.MethodPut, "alice", "-bad", MCPServerRequest{Type: "stdio", Command: "mcp"}, owner)
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+
+	rec, err := call(h.PutMCPServer, http.MethodPut, "alice", "github", MCPServerRequest{
+		Type: "stdio", Command: "github-mcp", Env: map[string]string{"GITHUB_TOKEN": "secret"},
+	}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Disabling keeps the stored secret
+	disabled := false
+	rec, err = call(h.PutMCPServer, http.MethodPut, "alice", "github", MCPServerRequest{
+		Type: "stdio", Command: "github-mcp", Env: map[string]string{"GITHUB_TOKEN": ""}, Enabled: &disabled,
+	}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	settings, err := repo.FindByName(context.Background(), "alice")
+	require.NoError(t, err)
+	server := settings.MCPServers().GetServer("github")
+	assert.False(t, server.Enabled())
+	assert.Empty(t, server.Env())
+
+	rec, err = call(h.ListMCPServers, http.MethodGet, "alice", "", nil, owner)
+	require.NoError(t, err)
+	var list MCPServerListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Servers, 1)
+	assert.Equal(t, "github", list.Servers[0].Name)
+	assert.False(t, list.Servers[0].Enabled)
+
+	// Other users' and base settings are off limits
+	_, err = call(h.PutMCPServer, http.MethodPut, "base", "docs", MCPServerRequest{Type: "http", URL: "https://docs.example.com/mcp"}, owner)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	_, err = call(h.GetMCPServer, http.MethodGet, "alice", "github", nil, createTestUser("bob", false))
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+
+	rec, err = call(h.DeleteMCPServer, http.MethodDelete, "alice", "github", nil, owner)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	_, err = call(h.GetMCPServer, http.MethodGet, "alice", "github", nil, owner)
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+}
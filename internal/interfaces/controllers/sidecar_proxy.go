@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// proxyToSidecar forwards a request below prefix to a session sidecar that
+// runs without its own authentication. The caller must have authorized the
+// request. WebSocket upgrades are passed through. name is used in logs and
+// error responses, e.g. "Editor".
+func proxyToSidecar(ctx echo.Context, manager repositories.SessionManager, session entities.Session, prefix string, target *url.URL, name string) error {
+	return proxyToSidecarWith(ctx, manager, session, prefix, target, name, nil)
+}
+
+// proxyToSidecarWith is proxyToSidecar with a hook to adjust the reverse proxy
+// before the request is served.
+func proxyToSidecarWith(ctx echo.Context, manager repositories.SessionManager, session entities.Session, prefix string, target *url.URL, name string, configure func(*httputil.ReverseProxy)) error {
+	if recorder, ok := manager.(sessionActivityRecorder); ok {
+		recorder.RecordActivity(session.ID())
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.FlushInterval = 100 * time.Millisecond
+	originalDirector := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		originalDirector(req)
+		req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		req.URL.RawPath = ""
+		// Proxy credentials are never forwarded to the sidecar.
+		req.Header.Del("Authorization")
+		req.Header.Del("X-API-Key")
+		req.Header.Set("X-Forwarded-Prefix", prefix)
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("[SIDECAR] %s proxy error for session %s: %v", name, session.ID(), err)
+		http.Error(w, name+" not available", http.StatusBadGateway)
+	}
+	if configure != nil {
+		configure(proxy)
+	}
+
+	proxy.ServeHTTP(ctx.Response(), ctx.Request())
+	return nil
+}
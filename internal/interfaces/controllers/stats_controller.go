@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionstats"
+)
+
+// StatsProvider provides aggregate session statistics
+type StatsProvider interface {
+	Stats(now time.Time) *sessionstats.Stats
+}
+
+// StatsController serves aggregate session statistics to administrators
+type StatsController struct {
+	provider StatsProvider
+}
+
+// NewStatsController creates a new StatsController
+func NewStatsController(provider StatsProvider) *StatsController {
+	return &StatsController{provider: provider}
+}
+
+// GetName returns the name of this controller for logging
+func (c *StatsController) GetName() string {
+	return "StatsController"
+}
+
+// GetStats handles GET /admin/stats. It returns the counts of current
+// sessions by status, team and agent type, the creation and failure rates
+// and average startup time of the last hour and day, and the top users.
+func (c *StatsController) GetStats(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, c.provider.Stats(time.Now()))
+}
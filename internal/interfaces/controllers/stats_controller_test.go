@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionstats"
+)
+
+func TestStatsController_GetStats(t *testing.T) {
+	stats := sessionstats.NewAggregator(func() []sessionstats.Session {
+		return []sessionstats.Session{
+			{ID: "s1", UserID: "alice", TeamID: "acme/dev", Status: "active"},
+			{ID: "s2", UserID: "bob", Status: "creating"},
+		}
+	})
+	now := time.Now()
+	stats.Refresh(now)
+	stats.Created(sessionstats.Session{ID: "s2", UserID: "bob"}, now)
+	controller := NewStatsController(stats)
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/admin/stats", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetStats(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp sessionstats.Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Sessions)
+	assert.Equal(t, map[string]int{"active": 1, "creating": 1}, resp.ByStatus)
+	assert.Equal(t, map[string]int{"acme/dev": 1}, resp.ByTeam)
+	assert.Equal(t, 1, resp.LastHour.Created)
+	require.Len(t, resp.TopUsers, 2)
+	assert.Equal(t, "bob", resp.TopUsers[0].UserID)
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/urlutil"
+)
+
+// MaskedEnvVarValue replaces env var values in team config responses. Sending
+// it back in PUT /teams/:id/config keeps the stored value.
+const MaskedEnvVarValue = "********"
+
+// TeamServiceAccountEnsurer creates the service account of a team unless it
+// already exists
+type TeamServiceAccountEnsurer interface {
+	EnsureServiceAccount(ctx context.Context, teamID string) error
+}
+
+// TeamConfigController handles the team configuration API: the env vars and
+// service account injected into team sessions, and the MCP servers of the
+// team settings
+type TeamConfigController struct {
+	repo     repositories.TeamConfigRepository
+	settings *SettingsController
+	ensurer  TeamServiceAccountEnsurer
+}
+
+// NewTeamConfigController creates a new TeamConfigController. ensurer may be
+// nil, in which case ensure_service_account requests are rejected.
+func NewTeamConfigController(repo repositories.TeamConfigRepository, settings *SettingsController, ensurer TeamServiceAccountEnsurer) *TeamConfigController {
+	return &TeamConfigController{
+		repo:     repo,
+		settings: settings,
+		ensurer:  ensurer,
+	}
+}
+
+// TeamConfigRequest is the request body of PUT /teams/:id/config.
+// Env var values follow PUT /settings/:name: an empty value deletes the key,
+// omitted keys and MaskedEnvVarValue keep the stored value.
+type TeamConfigRequest struct {
+	EnvVars              map[string]string            `json:"env_vars,omitempty"`
+	EnsureServiceAccount bool                         `json:"ensure_service_account,omitempty"` // create the team's service account if missing
+	MCPServers           map[string]*MCPServerRequest `json:"mcp_servers,omitempty"`            // null removes the server
+}
+
+// TeamServiceAccountResponse describes the service account of a team; its
+// API key is never returned
+type TeamServiceAccountResponse struct {
+	UserID      string    `json:"user_id"`
+	Permissions []string  `json:"permissions"`
+	HasAPIKey   bool      `json:"has_api_key"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TeamConfigResponse is the response body of the team config API
+type TeamConfigResponse struct {
+	TeamID         string                      `json:"team_id"`
+	EnvVars        map[string]string           `json:"env_vars"` // values are masked
+	ServiceAccount *TeamServiceAccountResponse `json:"service_account,omitempty"`
+	Settings       *SettingsResponse           `json:"settings,omitempty"` // team settings, including MCP servers
+}
+
+// GetTeamConfig handles GET /teams/:id/config. Team members and admins may
+// read it.
+func (c *TeamConfigController) GetTeamConfig(ctx echo.Context) error {
+	teamID, err := c.teamID(ctx, (*entities.User).IsMemberOfTeam)
+	if err != nil {
+		return err
+	}
+
+	config, err := c.findConfig(ctx.Request().Context(), teamID)
+	if err != nil {
+		return err
+	}
+	return c.respond(ctx, http.StatusOK, config)
+}
+
+// UpdateTeamConfig handles PUT /teams/:id/config. Only team admins (GitHub
+// maintainers) and admins may change it.
+func (c *TeamConfigController) UpdateTeamConfig(ctx echo.Context) error {
+	teamID, err := c.teamID(ctx, (*entities.User).IsTeamAdmin)
+	if err != nil {
+		return err
+	}
+
+	var req TeamConfigRequest
+	if err := ctx.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if req.EnsureServiceAccount && c.ensurer == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Service accounts are not available")
+	}
+
+	reqCtx := ctx.Request().Context()
+	config, err := c.findConfig(reqCtx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if req.EnvVars != nil {
+		envVars := make(map[string]string, len(req.EnvVars))
+		for k, v := range req.EnvVars {
+			if v == MaskedEnvVarValue {
+				continue
+			}
+			envVars[k] = v
+		}
+		config.SetEnvVars(c.settings.mergeSecrets(config.EnvVars(), envVars))
+		if err := config.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if err := c.repo.Save(reqCtx, config); err != nil {
+			log.Printf("[TEAM_CONFIG] Failed to save config of team %s: %v", teamID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save team config")
+		}
+	}
+
+	if len(req.MCPServers) > 0 {
+		if err := c.updateMCPServers(reqCtx, teamID, req.MCPServers); err != nil {
+			return err
+		}
+	}
+
+	if req.EnsureServiceAccount && config.ServiceAccount() == nil {
+		if err := c.ensurer.EnsureServiceAccount(reqCtx, teamID); err != nil {
+			log.Printf("[TEAM_CONFIG] Failed to create service account of team %s: %v", teamID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create service account")
+		}
+		if config, err = c.findConfig(reqCtx, teamID); err != nil {
+			return err
+		}
+	}
+
+	return c.respond(ctx, http.StatusOK, config)
+}
+
+// updateMCPServers applies the MCP servers of a team config request to the
+// team settings, as PUT and DELETE /settings/:name/mcp-servers/:server would
+func (c *TeamConfigController) updateMCPServers(ctx context.Context, teamID string, reqs map[string]*MCPServerRequest) error {
+	settings, err := c.settings.repo.FindByName(ctx, teamID)
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get settings")
+		}
+		settings = entities.NewSettings(teamID)
+	}
+	servers := settings.MCPServers()
+	if servers == nil {
+		servers = entities.NewMCPServersSettings()
+	}
+
+	for name, req := range reqs {
+		if req == nil {
+			servers.RemoveServer(name)
+			continue
+		}
+		server, err := c.settings.newMCPServer(name, req, servers.GetServer(name))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		servers.SetServer(name, server)
+	}
+
+	settings.SetMCPServers(servers)
+	if err := c.settings.repo.Save(ctx, settings); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save settings")
+	}
+	return nil
+}
+
+// teamID returns the team of a request after checking the caller's
+// permission on it
+func (c *TeamConfigController) teamID(ctx echo.Context, allowed func(*entities.User, string) bool) (string, error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	teamID := urlutil.DecodeSlashParam(ctx.Param("id"))
+	if teamID == "" || !strings.Contains(teamID, "/") {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Team ID must be in the form org/team-slug")
+	}
+	if !user.IsAdmin() && !allowed(user, teamID) {
+		return "", echo.NewHTTPError(http.StatusForbidden, "Access denied")
+	}
+	return teamID, nil
+}
+
+// findConfig returns the config of a team, or an empty one when the team has
+// none yet
+func (c *TeamConfigController) findConfig(ctx context.Context, teamID string) (*entities.TeamConfig, error) {
+	config, err := c.repo.FindByTeamID(ctx, teamID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return entities.NewTeamConfig(teamID, nil, nil), nil
+		}
+		log.Printf("[TEAM_CONFIG] Failed to get config of team %s: %v", teamID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get team config")
+	}
+	return config, nil
+}
+
+// respond writes the masked response of a team config along with the team
+// settings
+func (c *TeamConfigController) respond(ctx echo.Context, status int, config *entities.TeamConfig) error {
+	resp := &TeamConfigResponse{
+		TeamID:  config.TeamID(),
+		EnvVars: make(map[string]string, len(config.EnvVars())),
+	}
+	for k := range config.EnvVars() {
+		resp.EnvVars[k] = MaskedEnvVarValue
+	}
+	if sa := config.ServiceAccount(); sa != nil {
+		permissions := make([]string, 0, len(sa.Permissions()))
+		for _, p := range sa.Permissions() {
+			permissions = append(permissions, string(p))
+		}
+		sort.Strings(permissions)
+		resp.ServiceAccount = &TeamServiceAccountResponse{
+			UserID:      string(sa.UserID()),
+			Permissions: permissions,
+			HasAPIKey:   sa.APIKey() != "",
+			CreatedAt:   sa.CreatedAt(),
+			UpdatedAt:   sa.UpdatedAt(),
+		}
+	}
+	if settings, err := c.settings.repo.FindByName(ctx.Request().Context(), config.TeamID()); err == nil {
+		resp.Settings = c.settings.toResponse(settings)
+	}
+	return ctx.JSON(status, resp)
+}
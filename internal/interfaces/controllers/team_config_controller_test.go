@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+type mockTeamConfigRepository struct {
+	configs map[string]*entities.TeamConfig
+}
+
+func (m *mockTeamConfigRepository) Save(_ context.Context, config *entities.TeamConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	m.configs[config.TeamID()] = config
+	return nil
+}
+
+func (m *mockTeamConfigRepository) FindByTeamID(_ context.Context, teamID string) (*entities.TeamConfig, error) {
+	config, ok := m.configs[teamID]
+	if !ok {
+		return nil, fmt.Errorf("team config not found for team %s", teamID)
+	}
+	return config, nil
+}
+
+func (m *mockTeamConfigRepository) Delete(_ context.Context, teamID string) error {
+	delete(m.configs, teamID)
+	return nil
+}
+
+func (m *mockTeamConfigRepository) Exists(_ context.Context, teamID string) (bool, error) {
+	_, ok := m.configs[teamID]
+	return ok, nil
+}
+
+func (m *mockTeamConfigRepository) List(_ context.Context) ([]*entities.TeamConfig, error) {
+	var configs []*entities.TeamConfig
+	for _, config := range m.configs {
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+type mockServiceAccountEnsurer struct {
+	repo *mockTeamConfigRepository
+}
+
+func (m *mockServiceAccountEnsurer) EnsureServiceAccount(ctx context.Context, teamID string) error {
+	config, err := m.repo.FindByTeamID(ctx, teamID)
+	if err != nil {
+		config = entities.NewTeamConfig(teamID, nil, nil)
+	}
+	config.SetServiceAccount(entities.NewServiceAccount(teamID, "sa-acme-ml", "sa-key", []entities.Permission{entities.PermissionSessionCreate}))
+	return m.repo.Save(ctx, config)
+}
+
+func TestTeamConfigController(t *testing.T) {
+	repo := &mockTeamConfigRepository{configs: map[string]*entities.TeamConfig{}}
+	h := NewTeamConfigController(repo, NewSettingsController(newMockSettingsRepository(), nil, "", ""), &mockServiceAccountEnsurer{repo: repo})
+	e := echo.New()
+	call := func(handler echo.HandlerFunc, method string, body interface{}, user *entities.User) (*TeamConfigResponse, error) {
+		var raw []byte
+		if body != nil {
+			var err error
+			raw, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req := httptest.NewRequest(method, "/teams/acme%2Fml/config", bytes.NewReader(raw))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("acme/ml")
+		c.Set("internal_user", user)
+		if err := handler(c); err != nil {
+			return nil, err
+		}
+		var resp TeamConfigResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return &resp, nil
+	}
+	teamUser := func(id, role string) *entities.User {
+		user := createTestUser(id, false)
+		user.SetGitHubInfo(entities.NewGitHubUserInfo(1, id, id, "", "", "", ""), []entities.GitHubTeamMembership{{Organization: "acme", TeamSlug: "ml", Role: role}})
+		return user
+	}
+	maintainer, member := teamUser("alice", "maintainer"), teamUser("bob", "member")
+	statusOf := func(err error) int {
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		return httpErr.Code
+	}
+
+	resp, err := call(h.UpdateTeamConfig, http.MethodPut, TeamConfigRequest{
+		EnvVars:              map[string]string{"DB_PASSWORD": "hunter2", "REGION": "us-east-1"},
+		EnsureServiceAccount: true,
+		MCPServers:           map[string]*MCPServerRequest{"docs": {Type: "http", URL: "https://docs.example.com/mcp"}},
+	}, maintainer)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_PASSWORD": MaskedEnvVarValue, "REGION": MaskedEnvVarValue}, resp.EnvVars)
+	require.NotNil(t, resp.ServiceAccount)
+	assert.True(t, resp.ServiceAccount.HasAPIKey)
+	require.NotNil(t, resp.Settings)
+	assert.Contains(t, resp.Settings.MCPServers, "docs")
+
+	// Masked values keep the stored value, empty values delete the key
+	_, err = call(h.UpdateTeamConfig, http.MethodPut, TeamConfigRequest{
+		EnvVars: map[string]string{"DB_PASSWORD": MaskedEnvVarValue, "REGION": ""},
+	}, maintainer)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_PASSWORD": "hunter2"}, repo.configs["acme/ml"].EnvVars())
+	assert.NotNil(t, repo.configs["acme/ml"].ServiceAccount())
+
+	// Members may read but not change the config
+	resp, err = call(h.GetTeamConfig, http.MethodGet, nil, member)
+	require.NoError(t, err)
+	assert.Equal(t, MaskedEnvVarValue, resp.EnvVars["DB_PASSWORD"])
+	_, err = call(h.UpdateTeamConfig, http.MethodPut, TeamConfigRequest{EnvVars: map[string]string{"X": "y"}}, member)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	_, err = call(h.GetTeamConfig, http.MethodGet, nil, createTestUser("carol", false))
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+
+	_, err = call(h.UpdateTeamConfig, http.MethodPut, TeamConfigRequest{EnvVars: map[string]string{"BAD-NAME": "x"}}, maintainer)
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+}
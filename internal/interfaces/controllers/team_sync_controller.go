@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+// maxTeamSyncEventSize limits the size of a GitHub webhook payload
+const maxTeamSyncEventSize = 1 << 20
+
+// TeamSyncEventHandler applies GitHub membership, organization and team
+// webhook events to the synced team memberships
+type TeamSyncEventHandler interface {
+	HandleEvent(ctx context.Context, eventType string, payload []byte) error
+}
+
+// TeamSyncController receives the GitHub webhook events of the team
+// membership sync
+type TeamSyncController struct {
+	handler TeamSyncEventHandler
+	secret  string
+}
+
+// NewTeamSyncController creates a new TeamSyncController. Events must be
+// signed with secret.
+func NewTeamSyncController(handler TeamSyncEventHandler, secret string) *TeamSyncController {
+	return &TeamSyncController{
+		handler: handler,
+		secret:  secret,
+	}
+}
+
+// Webhook handles POST /webhooks/github/team-sync
+func (c *TeamSyncController) Webhook(ctx echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxTeamSyncEventSize))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if !hmacutil.Verify([]byte(c.secret), body, ctx.Request().Header.Get("X-Hub-Signature-256")) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid signature")
+	}
+
+	eventType := ctx.Request().Header.Get("X-GitHub-Event")
+	if err := c.handler.HandleEvent(ctx.Request().Context(), eventType, body); err != nil {
+		log.Printf("[TEAM_SYNC] Failed to handle %s event: %v", eventType, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to handle event")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
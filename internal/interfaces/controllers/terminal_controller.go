@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/termrec"
+)
+
+// terminalRecordingUploadTimeout bounds the upload of a finished recording.
+const terminalRecordingUploadTimeout = 2 * time.Minute
+
+// TerminalController proxies the web terminal (ttyd) sidecar of a session and
+// records terminal sessions into the artifact store. ttyd runs without its own
+// authentication, so every request is authorized here before it is forwarded.
+type TerminalController struct {
+	sessionManagerProvider SessionManagerProvider
+	// artifactStore receives terminal recordings. nil disables recording.
+	artifactStore services.ArtifactStore
+	// terminalURL resolves the ttyd base URL of a session. Overridden in tests.
+	terminalURL func(entities.Session) (string, bool)
+}
+
+// NewTerminalController creates a new TerminalController. Terminal sessions
+// are recorded only when artifactStore is non-nil.
+func NewTerminalController(sessionManagerProvider SessionManagerProvider, artifactStore services.ArtifactStore) *TerminalController {
+	return &TerminalController{
+		sessionManagerProvider: sessionManagerProvider,
+		artifactStore:          artifactStore,
+		terminalURL:            kubernetesTerminalURL,
+	}
+}
+
+// GetName returns the name of this controller for logging
+func (c *TerminalController) GetName() string {
+	return "TerminalController"
+}
+
+func kubernetesTerminalURL(session entities.Session) (string, bool) {
+	ks, ok := session.(*services.KubernetesSession)
+	if !ok || !ks.TerminalEnabled() {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s:%d", ks.Host(), services.TerminalPort), true
+}
+
+// TerminalRecording is a recorded terminal session in asciicast v2 format.
+type TerminalRecording struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TerminalRecordingsResponse is returned by ListRecordings.
+type TerminalRecordingsResponse struct {
+	Recordings []TerminalRecording `json:"recordings"`
+}
+
+func terminalRecordingPrefix(sessionID string) string {
+	return "terminal-recordings/" + sessionID + "/"
+}
+
+// authorizedSession returns the session if the caller may access it.
+func (c *TerminalController) authorizedSession(ctx echo.Context) (entities.Session, error) {
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(ctx.Param("sessionId"))
+	if session == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+	return session, nil
+}
+
+// ProxyTerminal handles /sessions/:sessionId/terminal and everything below
+// it, including the ttyd WebSocket.
+func (c *TerminalController) ProxyTerminal(ctx echo.Context) error {
+	session, err := c.authorizedSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	baseURL, ok := c.terminalURL(session)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Terminal is not enabled for this session")
+	}
+	target, err := url.Parse(baseURL)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Invalid terminal URL: %v", err))
+	}
+
+	var configure func(*httputil.ReverseProxy)
+	if c.artifactStore != nil {
+		userID := ""
+		if user := auth.GetUserFromContext(ctx); user != nil {
+			userID = string(user.ID())
+		}
+		configure = func(p *httputil.ReverseProxy) { c.recordUpgrades(p, session.ID(), userID) }
+	}
+
+	prefix := "/sessions/" + session.ID() + "/terminal"
+	return proxyToSidecarWith(ctx, c.sessionManagerProvider.GetSessionManager(), session, prefix, target, "Terminal", configure)
+}
+
+// recordUpgrades makes p record the ttyd WebSocket connections it proxies.
+func (c *TerminalController) recordUpgrades(p *httputil.ReverseProxy, sessionID, userID string) {
+	director := p.Director
+	p.Director = func(req *http.Request) {
+		director(req)
+		// Compressed frames cannot be recorded, so compression is never
+		// negotiated with ttyd.
+		req.Header.Del("Sec-WebSocket-Extensions")
+	}
+	p.ModifyResponse = func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil
+		}
+		conn, ok := resp.Body.(io.ReadWriteCloser)
+		if !ok {
+			return nil
+		}
+		file, err := os.CreateTemp("", "terminal-*.cast")
+		if err != nil {
+			log.Printf("[TERMINAL] Failed to start recording for session %s: %v", sessionID, err)
+			return nil
+		}
+		startedAt := time.Now().UTC()
+		recorder := termrec.NewRecorder(file, "session "+sessionID)
+		resp.Body = termrec.NewTTYDConn(conn, recorder, func() {
+			go c.storeRecording(sessionID, userID, startedAt, file, recorder)
+		})
+		log.Printf("[TERMINAL] Recording terminal of session %s (user: %s)", sessionID, userID)
+		return nil
+	}
+}
+
+// storeRecording uploads a finished recording and removes its temporary file.
+func (c *TerminalController) storeRecording(sessionID, userID string, startedAt time.Time, file *os.File, recorder *termrec.Recorder) {
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+	if recorder.Empty() {
+		return
+	}
+	if err := recorder.Err(); err != nil {
+		log.Printf("[TERMINAL] Recording of session %s is incomplete: %v", sessionID, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Printf("[TERMINAL] Failed to read recording of session %s: %v", sessionID, err)
+		return
+	}
+
+	key := fmt.Sprintf("%s%s-%s.cast", terminalRecordingPrefix(sessionID), startedAt.Format("20060102T150405Z"), uuid.New().String()[:8])
+	ctx, cancel := context.WithTimeout(context.Background(), terminalRecordingUploadTimeout)
+	defer cancel()
+	if err := c.artifactStore.PutArtifact(ctx, key, termrec.ContentType, file); err != nil {
+		log.Printf("[TERMINAL] Failed to store recording of session %s: %v", sessionID, err)
+		return
+	}
+	log.Printf("[TERMINAL] Stored terminal recording %s (user: %s)", key, userID)
+}
+
+// ListRecordings handles GET /sessions/:sessionId/terminal-recordings.
+func (c *TerminalController) ListRecordings(ctx echo.Context) error {
+	session, err := c.authorizedSession(ctx)
+	if err != nil {
+		return err
+	}
+	if c.artifactStore == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Terminal recording is not configured")
+	}
+
+	artifacts, err := c.artifactStore.ListArtifacts(ctx.Request().Context(), terminalRecordingPrefix(session.ID()))
+	if err != nil {
+		log.Printf("[TERMINAL] Failed to list recordings of session %s: %v", session.ID(), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list terminal recordings")
+	}
+	resp := TerminalRecordingsResponse{Recordings: make([]TerminalRecording, 0, len(artifacts))}
+	for _, a := range artifacts {
+		resp.Recordings = append(resp.Recordings, TerminalRecording{
+			Name:      path.Base(a.Key),
+			Size:      a.Size,
+			UpdatedAt: a.UpdatedAt,
+		})
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// GetRecording handles GET /sessions/:sessionId/terminal-recordings/:name and
+// returns the asciicast file, which can be replayed with asciinema.
+func (c *TerminalController) GetRecording(ctx echo.Context) error {
+	session, err := c.authorizedSession(ctx)
+	if err != nil {
+		return err
+	}
+	if c.artifactStore == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Terminal recording is not configured")
+	}
+
+	name := ctx.Param("name")
+	if !strings.HasSuffix(name, ".cast") || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recording name")
+	}
+	body, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), terminalRecordingPrefix(session.ID())+name)
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Recording not found")
+	}
+	if err != nil {
+		log.Printf("[TERMINAL] Failed to open recording %s of session %s: %v", name, session.ID(), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read terminal recording")
+	}
+	defer func() { _ = body.Close() }()
+	return ctx.Stream(http.StatusOK, termrec.ContentType, body)
+}
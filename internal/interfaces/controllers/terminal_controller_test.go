@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+)
+
+// memoryArtifactStore is an in-memory services.ArtifactStore.
+type memoryArtifactStore struct {
+	files map[string]string
+}
+
+func (s *memoryArtifactStore) PutArtifact(_ context.Context, key, _ string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.files[key] = string(data)
+	return nil
+}
+
+func (s *memoryArtifactStore) ListArtifacts(_ context.Context, prefix string) ([]services.Artifact, error) {
+	var artifacts []services.Artifact
+	for key, data := range s.files {
+		if strings.HasPrefix(key, prefix) {
+			artifacts = append(artifacts, services.Artifact{Key: key, Size: int64(len(data)), UpdatedAt: time.Unix(0, 0)})
+		}
+	}
+	return artifacts, nil
+}
+
+func (s *memoryArtifactStore) OpenArtifact(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.files[key]
+	if !ok {
+		return nil, services.ErrArtifactNotFound
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func newTestTerminalController(baseURL string, store services.ArtifactStore) *TerminalController {
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	c := NewTerminalController(&mockWaitProvider{manager: newMockWaitSessionManager(session)}, store)
+	c.terminalURL = func(entities.Session) (string, bool) { return baseURL, baseURL != "" }
+	return c
+}
+
+func TestTerminalController_ProxiesToSidecar(t *testing.T) {
+	ttyd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/token", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, `{"token":""}`)
+	}))
+	defer ttyd.Close()
+
+	c := newTestTerminalController(ttyd.URL, &memoryArtifactStore{files: map[string]string{}})
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/terminal/token", "sess-1", "alice")
+	ctx.Request().Header.Set("Authorization", "Bearer secret")
+	require.NoError(t, c.ProxyTerminal(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"token":""}`, rec.Body.String())
+}
+
+func TestTerminalController_Recordings(t *testing.T) {
+	store := &memoryArtifactStore{files: map[string]string{
+		"terminal-recordings/sess-1/20260101T000000Z-abcd1234.cast": "{\"version\":2}\n",
+		"terminal-recordings/sess-2/20260101T000000Z-ffff0000.cast": "{\"version\":2}\n",
+	}}
+	c := newTestTerminalController("http://terminal.invalid", store)
+
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/terminal-recordings", "sess-1", "alice")
+	require.NoError(t, c.ListRecordings(ctx))
+	var list TerminalRecordingsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Recordings, 1)
+	assert.Equal(t, "20260101T000000Z-abcd1234.cast", list.Recordings[0].Name)
+
+	ctx, rec = makeWorkspaceEchoContext("/sessions/sess-1/terminal-recordings/x", "sess-1", "alice")
+	ctx.SetParamNames("sessionId", "name")
+	ctx.SetParamValues("sess-1", "20260101T000000Z-abcd1234.cast")
+	require.NoError(t, c.GetRecording(ctx))
+	assert.Equal(t, "application/x-asciicast", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"version\":2}\n", rec.Body.String())
+
+	for name, want := range map[string]int{
+		"20260101T000000Z-ffff0000.cast": http.StatusNotFound,
+		"../sess-2/x.cast":               http.StatusBadRequest,
+		"notes.txt":                      http.StatusBadRequest,
+	} {
+		ctx, _ = makeWorkspaceEchoContext("/sessions/sess-1/terminal-recordings/x", "sess-1", "alice")
+		ctx.SetParamNames("sessionId", "name")
+		ctx.SetParamValues("sess-1", name)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, c.GetRecording(ctx), &httpErr, name)
+		assert.Equal(t, want, httpErr.Code, name)
+	}
+}
+
+func TestTerminalController_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		store      services.ArtifactStore
+		call       func(*TerminalController, echo.Context) error
+		sessionID  string
+		userID     string
+		wantStatus int
+	}{
+		{"unknown session", "http://terminal.invalid", nil, (*TerminalController).ProxyTerminal, "missing", "alice", http.StatusNotFound},
+		{"other user", "http://terminal.invalid", nil, (*TerminalController).ProxyTerminal, "sess-1", "bob", http.StatusForbidden},
+		{"terminal disabled", "", nil, (*TerminalController).ProxyTerminal, "sess-1", "alice", http.StatusNotFound},
+		{"recording not configured", "http://terminal.invalid", nil, (*TerminalController).ListRecordings, "sess-1", "alice", http.StatusNotImplemented},
+		{"recordings of other user", "http://terminal.invalid", &memoryArtifactStore{}, (*TerminalController).ListRecordings, "sess-1", "bob", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestTerminalController(tt.baseURL, tt.store)
+			ctx, _ := makeWorkspaceEchoContext("/sessions/"+tt.sessionID+"/terminal/", tt.sessionID, tt.userID)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, tt.call(c, ctx), &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
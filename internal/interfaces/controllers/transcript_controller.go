@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/transcript"
+)
+
+// TranscriptController exports the conversation of a session as a
+// downloadable transcript. Transcripts archived when a session was deleted
+// are served from the artifact store.
+type TranscriptController struct {
+	sessionManagerProvider SessionManagerProvider
+	artifactStore          services.ArtifactStore
+}
+
+// NewTranscriptController creates a new TranscriptController.
+// artifactStore may be nil when the asset backend has no artifact support.
+func NewTranscriptController(sessionManagerProvider SessionManagerProvider, artifactStore services.ArtifactStore) *TranscriptController {
+	return &TranscriptController{sessionManagerProvider: sessionManagerProvider, artifactStore: artifactStore}
+}
+
+// GetName returns the name of this controller for logging
+func (c *TranscriptController) GetName() string {
+	return "TranscriptController"
+}
+
+// ExportSession handles GET /sessions/:sessionId/export?format=json|markdown|html.
+// It renders the message history and metadata of the session, markdown by
+// default, as an attachment.
+func (c *TranscriptController) ExportSession(ctx echo.Context) error {
+	format := transcript.FormatMarkdown
+	if f := ctx.QueryParam("format"); f != "" {
+		parsed, err := transcript.ParseFormat(f)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		format = parsed
+	}
+
+	sessionID := ctx.Param("sessionId")
+	var t *transcript.Transcript
+	var err error
+	if session := c.sessionManagerProvider.GetSessionManager().GetSession(sessionID); session != nil {
+		authzCtx := auth.GetAuthorizationContext(ctx)
+		if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+			return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+		}
+		messages, err := c.sessionManagerProvider.GetSessionManager().GetMessages(ctx.Request().Context(), sessionID)
+		if err != nil {
+			log.Printf("[TRANSCRIPT] Failed to get messages of session %s: %v", sessionID, err)
+			return echo.NewHTTPError(http.StatusBadGateway, "Failed to get messages from the session agent")
+		}
+		t = transcript.FromSession(session, messages, time.Now())
+	} else if t, err = c.archived(ctx, sessionID); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := t.Write(&body, format); err != nil {
+		log.Printf("[TRANSCRIPT] Failed to render transcript of session %s: %v", sessionID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render transcript")
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", t.Filename(format)))
+	return ctx.Blob(http.StatusOK, format.ContentType(), body.Bytes())
+}
+
+// archived loads the transcript archived for a deleted session and checks
+// that the caller may access the session it belongs to
+func (c *TranscriptController) archived(ctx echo.Context, sessionID string) (*transcript.Transcript, error) {
+	notFound := echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	if c.artifactStore == nil {
+		return nil, notFound
+	}
+	r, err := c.artifactStore.OpenArtifact(ctx.Request().Context(), transcript.ArchiveKey(sessionID))
+	if errors.Is(err, services.ErrArtifactNotFound) {
+		return nil, notFound
+	}
+	if err != nil {
+		log.Printf("[TRANSCRIPT] Failed to open archived transcript of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read archived transcript")
+	}
+	defer func() { _ = r.Close() }()
+	var t transcript.Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		log.Printf("[TRANSCRIPT] Failed to decode archived transcript of session %s: %v", sessionID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to read archived transcript")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(t.UserID, string(t.Scope), t.TeamID) {
+		// Do not reveal whether the session existed.
+		return nil, notFound
+	}
+	return &t, nil
+}
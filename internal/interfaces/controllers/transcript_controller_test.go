@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/transcript"
+)
+
+func TestTranscriptController_ExportsLiveSession(t *testing.T) {
+	mgr := &mockMessagesSessionManager{
+		mockWaitSessionManager: newMockWaitSessionManager(&mockWaitSession{id: "sess-1", userID: "user-1"}),
+		messages: []portrepos.Message{
+			{ID: 0, Role: "user", Content: "Run the tests", Timestamp: time.Now()},
+			{ID: 1, Role: "agent", Content: "All green", Timestamp: time.Now()},
+		},
+	}
+	controller := NewTranscriptController(&mockWaitProvider{manager: mgr}, nil)
+
+	c, rec := makeWaitEchoContext(t, "sess-1", nil, "user-1")
+	require.NoError(t, controller.ExportSession(c))
+	assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "session-sess-1-transcript.md")
+	assert.Contains(t, rec.Body.String(), "## Agent")
+	assert.Contains(t, rec.Body.String(), "All green")
+
+	c, _ = makeWaitEchoContext(t, "sess-1", map[string]string{"format": "pdf"}, "user-1")
+	assertHTTPError(t, controller.ExportSession(c), http.StatusBadRequest)
+
+	c, _ = makeWaitEchoContext(t, "sess-1", nil, "user-2")
+	assertHTTPError(t, controller.ExportSession(c), http.StatusForbidden)
+}
+
+func TestTranscriptController_ServesArchivedTranscript(t *testing.T) {
+	var archived bytes.Buffer
+	tr := &transcript.Transcript{SessionID: "gone", UserID: "user-1", Scope: "user", Messages: []portrepos.Message{{Role: "agent", Content: "Bye"}}}
+	require.NoError(t, tr.Write(&archived, transcript.FormatJSON))
+	store := &memoryArtifactStore{files: map[string]string{transcript.ArchiveKey("gone"): archived.String()}}
+	controller := NewTranscriptController(&mockWaitProvider{manager: newMockWaitSessionManager(nil)}, store)
+
+	c, rec := makeWaitEchoContext(t, "gone", map[string]string{"format": "html"}, "user-1")
+	require.NoError(t, controller.ExportSession(c))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "Bye")
+
+	// Other users cannot tell that the session existed
+	c, _ = makeWaitEchoContext(t, "gone", nil, "user-2")
+	assertHTTPError(t, controller.ExportSession(c), http.StatusNotFound)
+}
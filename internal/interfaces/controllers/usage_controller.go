@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+)
+
+// maxUsageRangeDays bounds the days of one usage query
+const maxUsageRangeDays = 366
+
+// UsageLedger provides the recorded token usage of sessions
+type UsageLedger interface {
+	Records(ctx context.Context, q usage.Query) ([]*usage.Record, error)
+	TeamMonth(ctx context.Context, teamID string, at time.Time) (usage.Counters, error)
+}
+
+// UsageController serves the token usage and cost of sessions
+type UsageController struct {
+	ledger  UsageLedger
+	budgets usage.Budgets
+}
+
+// NewUsageController creates a new UsageController reporting the budgets of
+// teams along with their usage
+func NewUsageController(ledger UsageLedger, budgets usage.Budgets) *UsageController {
+	return &UsageController{ledger: ledger, budgets: budgets}
+}
+
+// GetName returns the name of this controller for logging
+func (c *UsageController) GetName() string {
+	return "UsageController"
+}
+
+// UsageResponse is the response of GET /usage
+type UsageResponse struct {
+	*usage.Report
+	// Budget is the budget status of ?team in the current month, if the team
+	// has a budget
+	Budget *usage.BudgetStatus `json:"budget,omitempty"`
+}
+
+// GetUsage handles GET /usage. It reports usage per session, user and team
+// from ?from to ?to (YYYY-MM-DD, inclusive; the current month by default),
+// optionally limited to ?team or ?user. Non-admin users can only query their
+// own usage or the usage of their teams.
+func (c *UsageController) GetUsage(ctx echo.Context) error {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+
+	now := time.Now().UTC()
+	q := usage.Query{
+		From:   time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		TeamID: ctx.QueryParam("team"),
+		UserID: ctx.QueryParam("user"),
+	}
+	var err error
+	if from := ctx.QueryParam("from"); from != "" {
+		if q.From, err = usage.ParseDate(from); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "from: "+err.Error())
+		}
+	}
+	if to := ctx.QueryParam("to"); to != "" {
+		if q.To, err = usage.ParseDate(to); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "to: "+err.Error())
+		}
+	}
+	if q.To.Before(q.From) {
+		return echo.NewHTTPError(http.StatusBadRequest, "from must not be after to")
+	}
+	if q.To.Sub(q.From) >= maxUsageRangeDays*24*time.Hour {
+		return echo.NewHTTPError(http.StatusBadRequest, "the range must not exceed 366 days")
+	}
+
+	if !user.IsAdmin() {
+		switch {
+		case q.TeamID != "":
+			if !user.IsMemberOfTeam(q.TeamID) {
+				return echo.NewHTTPError(http.StatusForbidden, "Not a member of team "+q.TeamID)
+			}
+		case q.UserID != "" && q.UserID != string(user.ID()):
+			return echo.NewHTTPError(http.StatusForbidden, "Cannot view the usage of other users")
+		default:
+			q.UserID = string(user.ID())
+		}
+	}
+
+	reqCtx := ctx.Request().Context()
+	records, err := c.ledger.Records(reqCtx, q)
+	if err != nil {
+		log.Printf("[USAGE] Failed to load usage: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load usage")
+	}
+	resp := UsageResponse{Report: usage.Summarize(records, q.From, q.To)}
+	if budget, ok := c.budgets.For(q.TeamID); ok && q.TeamID != "" {
+		used, err := c.ledger.TeamMonth(reqCtx, q.TeamID, now)
+		if err != nil {
+			log.Printf("[USAGE] Failed to load usage of team %s: %v", q.TeamID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load usage")
+		}
+		resp.Budget = budget.Status(used, now)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
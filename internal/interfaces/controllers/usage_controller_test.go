@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+)
+
+type stubUsageLedger struct {
+	records []*usage.Record
+	queries []usage.Query
+}
+
+func (l *stubUsageLedger) Records(_ context.Context, q usage.Query) ([]*usage.Record, error) {
+	l.queries = append(l.queries, q)
+	var out []*usage.Record
+	for _, r := range l.records {
+		if (q.TeamID == "" || r.TeamID == q.TeamID) && (q.UserID == "" || r.UserID == q.UserID) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (l *stubUsageLedger) TeamMonth(_ context.Context, teamID string, _ time.Time) (usage.Counters, error) {
+	var total usage.Counters
+	for _, r := range l.records {
+		if r.TeamID == teamID {
+			total.InputTokens += r.TotalTokens()
+			total.CostUSD += r.CostUSD
+		}
+	}
+	return total, nil
+}
+
+func newTestUsageController() (*UsageController, *stubUsageLedger) {
+	ledger := &stubUsageLedger{records: []*usage.Record{
+		{Date: "2026-09-01", Session: usage.Session{ID: "s1", UserID: "alice", TeamID: "acme/dev"}, Counters: usage.Counters{InputTokens: 700, CostUSD: 2}},
+		{Date: "2026-09-02", Session: usage.Session{ID: "s2", UserID: "bob", TeamID: "acme/dev"}, Counters: usage.Counters{OutputTokens: 400, CostUSD: 1}},
+		{Date: "2026-09-02", Session: usage.Session{ID: "s3", UserID: "bob"}, Counters: usage.Counters{OutputTokens: 10}},
+	}}
+	return NewUsageController(ledger, usage.Budgets{"acme/dev": {MonthlyTokens: 1000}}), ledger
+}
+
+func TestUsageController_GetUsage(t *testing.T) {
+	controller, ledger := newTestUsageController()
+
+	c, rec := makeMemoryEchoContext(t, http.MethodGet, "/usage?team=acme/dev&from=2026-09-01&to=2026-09-30", nil, newTestGitHubUser("alice", "acme", "dev"))
+	require.NoError(t, controller.GetUsage(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp UsageResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1100), resp.TotalTokens)
+	assert.Equal(t, 3.0, resp.Total.CostUSD)
+	require.Len(t, resp.Sessions, 2)
+	assert.Equal(t, "s1", resp.Sessions[0].ID)
+	require.Len(t, resp.Teams, 1)
+	assert.Equal(t, 2, resp.Teams[0].Sessions)
+	require.NotNil(t, resp.Budget)
+	assert.True(t, resp.Budget.Exceeded)
+	assert.Equal(t, int64(1000), resp.Budget.MonthlyTokens)
+
+	// Without a team, users see their own usage only
+	c, rec = makeMemoryEchoContext(t, http.MethodGet, "/usage?from=2026-09-01&to=2026-09-30", nil, newTestAPIKeyUser("bob"))
+	require.NoError(t, controller.GetUsage(c))
+	resp = UsageResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "bob", ledger.queries[len(ledger.queries)-1].UserID)
+	assert.Len(t, resp.Sessions, 2)
+	assert.Nil(t, resp.Budget)
+
+	// Administrators see everything
+	c, rec = makeMemoryEchoContext(t, http.MethodGet, "/usage?from=2026-09-01&to=2026-09-30", nil, newTestAdminUser("admin"))
+	require.NoError(t, controller.GetUsage(c))
+	resp = UsageResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Users, 2)
+}
+
+func TestUsageController_GetUsageRejectsInvalidQueries(t *testing.T) {
+	controller, _ := newTestUsageController()
+
+	c, _ := makeMemoryEchoContext(t, http.MethodGet, "/usage?team=acme/ops", nil, newTestGitHubUser("alice", "acme", "dev"))
+	assertHTTPError(t, controller.GetUsage(c), http.StatusForbidden)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/usage?user=alice", nil, newTestAPIKeyUser("bob"))
+	assertHTTPError(t, controller.GetUsage(c), http.StatusForbidden)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/usage?from=2026-09", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetUsage(c), http.StatusBadRequest)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/usage?from=2026-09-30&to=2026-09-01", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetUsage(c), http.StatusBadRequest)
+
+	c, _ = makeMemoryEchoContext(t, http.MethodGet, "/usage?from=2024-01-01&to=2026-09-01", nil, newTestAdminUser("admin"))
+	assertHTTPError(t, controller.GetUsage(c), http.StatusBadRequest)
+}
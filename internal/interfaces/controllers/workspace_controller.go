@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+//go:embed workspace_ui.html
+var workspaceUIHTML []byte
+
+// workspaceForwardHeaders are copied from the provisioner response to the client.
+var workspaceForwardHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Disposition",
+	"Content-Range",
+	"Content-Security-Policy",
+	"Accept-Ranges",
+	"Last-Modified",
+	"X-Content-Type-Options",
+}
+
+// WorkspaceController serves a file browser, file downloads and uploads and
+// the uncommitted changes of session workspaces. Data comes from the
+// agent-provisioner /workspace and /changes APIs running inside the session
+// Pod.
+type WorkspaceController struct {
+	sessionManagerProvider SessionManagerProvider
+	httpClient             *http.Client
+	// provisionerBaseURL resolves the provisioner base URL of a session.
+	// Overridden in tests.
+	provisionerBaseURL func(entities.Session) (string, bool)
+	// provisionerToken returns the token the provisioner requires for
+	// every workspace request. Overridden in tests.
+	provisionerToken func() string
+}
+
+// NewWorkspaceController creates a new WorkspaceController
+func NewWorkspaceController(sessionManagerProvider SessionManagerProvider) *WorkspaceController {
+	c := &WorkspaceController{
+		sessionManagerProvider: sessionManagerProvider,
+		httpClient:             &http.Client{},
+		provisionerBaseURL:     kubernetesProvisionerBaseURL,
+	}
+	c.provisionerToken = c.kubernetesProvisionerToken
+	return c
+}
+
+// GetName returns the name of this controller for logging
+func (c *WorkspaceController) GetName() string {
+	return "WorkspaceController"
+}
+
+func kubernetesProvisionerBaseURL(session entities.Session) (string, bool) {
+	ks, ok := session.(*services.KubernetesSession)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s:%d", ks.Host(), services.ProvisionerPort), true
+}
+
+func (c *WorkspaceController) kubernetesProvisionerToken() string {
+	manager, ok := c.sessionManagerProvider.GetSessionManager().(*services.KubernetesSessionManager)
+	if !ok {
+		return ""
+	}
+	return manager.ProvisionerToken()
+}
+
+// ServeUI handles GET /sessions/:sessionId/workspace
+func (c *WorkspaceController) ServeUI(ctx echo.Context) error {
+	if _, err := c.authorizedSession(ctx); err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	return ctx.HTMLBlob(http.StatusOK, workspaceUIHTML)
+}
+
+// ListFiles handles GET /sessions/:sessionId/workspace/files
+func (c *WorkspaceController) ListFiles(ctx echo.Context) error {
+	query := url.Values{}
+	query.Set("path", ctx.QueryParam("path"))
+	return c.forward(ctx, http.MethodGet, "/workspace/files", query)
+}
+
+// GetFileContent handles GET /sessions/:sessionId/workspace/raw
+func (c *WorkspaceController) GetFileContent(ctx echo.Context) error {
+	query := url.Values{}
+	query.Set("path", ctx.QueryParam("path"))
+	if ctx.QueryParam("download") == "true" {
+		query.Set("download", "true")
+	}
+	return c.forward(ctx, http.MethodGet, "/workspace/raw", query)
+}
+
+// DownloadFile handles GET /sessions/:sessionId/files/download and downloads
+// a file of the workspace as an attachment.
+func (c *WorkspaceController) DownloadFile(ctx echo.Context) error {
+	query := url.Values{}
+	query.Set("path", ctx.QueryParam("path"))
+	query.Set("download", "true")
+	return c.forward(ctx, http.MethodGet, "/workspace/raw", query)
+}
+
+// UploadFile handles PUT /sessions/:sessionId/files. The request body
+// replaces the file at path; its parent directory must exist. It responds
+// 201 with the entry of the written file.
+func (c *WorkspaceController) UploadFile(ctx echo.Context) error {
+	if ctx.QueryParam("path") == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "path is required")
+	}
+	query := url.Values{}
+	query.Set("path", ctx.QueryParam("path"))
+	return c.forward(ctx, http.MethodPut, "/workspace/raw", query)
+}
+
+// GetChanges handles GET /sessions/:sessionId/changes
+// It returns structured per-file diffs of the uncommitted changes in the
+// session repository. The optional mode query parameter selects "staged" or
+// "unstaged" changes; both are returned by default.
+func (c *WorkspaceController) GetChanges(ctx echo.Context) error {
+	query := url.Values{}
+	switch mode := ctx.QueryParam("mode"); mode {
+	case "":
+	case "staged", "unstaged":
+		query.Set("mode", mode)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be staged or unstaged")
+	}
+	return c.forward(ctx, http.MethodGet, "/changes", query)
+}
+
+func (c *WorkspaceController) authorizedSession(ctx echo.Context) (entities.Session, error) {
+	session := c.sessionManagerProvider.GetSessionManager().GetSession(ctx.Param("sessionId"))
+	if session == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Session not found")
+	}
+
+	authzCtx := auth.GetAuthorizationContext(ctx)
+	if authzCtx == nil || !authzCtx.CanAccessResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to access this session")
+	}
+	if ctx.Request().Method == http.MethodPut && !authzCtx.CanModifyResource(session.UserID(), string(session.Scope()), session.TeamID()) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this session")
+	}
+	return session, nil
+}
+
+// forward sends the request to the provisioner of the session with method,
+// passing the request body of uploads through, and copies the response back.
+func (c *WorkspaceController) forward(ctx echo.Context, method, path string, query url.Values) error {
+	session, err := c.authorizedSession(ctx)
+	if err != nil {
+		return err
+	}
+	baseURL, ok := c.provisionerBaseURL(session)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Workspace browsing not available for this session type")
+	}
+
+	var body io.Reader
+	if method == http.MethodPut {
+		body = ctx.Request().Body
+	}
+	req, err := http.NewRequestWithContext(ctx.Request().Context(), method, baseURL+path+"?"+query.Encode(), body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build workspace request")
+	}
+	if method == http.MethodPut {
+		req.ContentLength = ctx.Request().ContentLength
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if token := c.provisionerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if rng := ctx.Request().Header.Get("Range"); rng != "" {
+		req.Header.Set("Range", rng)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[WORKSPACE] Failed to reach provisioner for session %s: %v", session.ID(), err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Session workspace not available")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	header := ctx.Response().Header()
+	for _, name := range workspaceForwardHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	header.Set("Cache-Control", "no-store")
+	ctx.Response().WriteHeader(resp.StatusCode)
+	_, err = io.Copy(ctx.Response(), resp.Body)
+	return err
+}
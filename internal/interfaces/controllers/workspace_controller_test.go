@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+)
+
+func newTestWorkspaceController(baseURL string) *WorkspaceController {
+	session := &mockWaitSession{id: "sess-1", userID: "alice"}
+	c := NewWorkspaceController(&mockWaitProvider{manager: newMockWaitSessionManager(session)})
+	c.provisionerBaseURL = func(entities.Session) (string, bool) { return baseURL, baseURL != "" }
+	c.provisionerToken = func() string { return "provisioner-token" }
+	return c
+}
+
+func makeWorkspaceEchoContext(target, sessionID, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sessionId")
+	c.SetParamValues(sessionID)
+	c.Set("authz_context", &auth.AuthorizationContext{
+		PersonalScope: auth.PersonalScopeAuth{UserID: userID, CanRead: true},
+		TeamScope:     auth.TeamScopeAuth{TeamPermissions: make(map[string]auth.TeamPermissions)},
+	})
+	return c, rec
+}
+
+func TestWorkspaceController_ForwardsToProvisioner(t *testing.T) {
+	provisioner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/workspace/raw", r.URL.Path)
+		assert.Equal(t, "repo/main.go", r.URL.Query().Get("path"))
+		assert.Equal(t, "true", r.URL.Query().Get("download"))
+		assert.Equal(t, "Bearer provisioner-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Disposition", "attachment; filename=main.go")
+		w.Header().Set("X-Internal", "secret")
+		_, _ = io.WriteString(w, "package main\n")
+	}))
+	defer provisioner.Close()
+
+	c := newTestWorkspaceController(provisioner.URL)
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/workspace/raw?path=repo/main.go&download=true", "sess-1", "alice")
+	require.NoError(t, c.GetFileContent(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "package main\n", rec.Body.String())
+	assert.Equal(t, "attachment; filename=main.go", rec.Header().Get("Content-Disposition"))
+	assert.Empty(t, rec.Header().Get("X-Internal"))
+}
+
+func TestWorkspaceController_GetChanges(t *testing.T) {
+	provisioner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/changes", r.URL.Path)
+		assert.Equal(t, "staged", r.URL.Query().Get("mode"))
+		assert.Equal(t, "Bearer provisioner-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"staged":[],"truncated":false}`)
+	}))
+	defer provisioner.Close()
+
+	c := newTestWorkspaceController(provisioner.URL)
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/changes?mode=staged", "sess-1", "alice")
+	require.NoError(t, c.GetChanges(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"staged":[],"truncated":false}`, rec.Body.String())
+
+	ctx, _ = makeWorkspaceEchoContext("/sessions/sess-1/changes?mode=bogus", "sess-1", "alice")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, c.GetChanges(ctx), &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestWorkspaceController_ServeUI(t *testing.T) {
+	c := newTestWorkspaceController("")
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/workspace", "sess-1", "alice")
+	require.NoError(t, c.ServeUI(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<title>Workspace</title>")
+}
+
+func TestWorkspaceController_Errors(t *testing.T) {
+	c := newTestWorkspaceController("")
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		userID     string
+		wantStatus int
+	}{
+		{"unknown session", "missing", "alice", http.StatusNotFound},
+		{"other user", "sess-1", "bob", http.StatusForbidden},
+		{"non-kubernetes session", "sess-1", "alice", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := makeWorkspaceEchoContext("/sessions/"+tt.sessionID+"/workspace/files", tt.sessionID, tt.userID)
+			err := c.ListFiles(ctx)
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.wantStatus, httpErr.Code)
+		})
+	}
+}
+
+func TestWorkspaceController_UploadFile(t *testing.T) {
+	provisioner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/workspace/raw", r.URL.Path)
+		assert.Equal(t, "out/report.txt", r.URL.Query().Get("path"))
+		assert.Equal(t, "Bearer provisioner-token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "artifact", string(body))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"name":"report.txt","path":"out/report.txt","type":"file","size":8}`)
+	}))
+	defer provisioner.Close()
+
+	c := newTestWorkspaceController(provisioner.URL)
+	ctx, rec := makeWorkspaceEchoContext("/sessions/sess-1/files?path=out/report.txt", "sess-1", "alice")
+	ctx.SetRequest(httptest.NewRequest(http.MethodPut, "/sessions/sess-1/files?path=out/report.txt", strings.NewReader("artifact")))
+	require.NoError(t, c.UploadFile(ctx))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"path":"out/report.txt"`)
+
+	ctx, _ = makeWorkspaceEchoContext("/sessions/sess-1/files", "sess-1", "alice")
+	ctx.SetRequest(httptest.NewRequest(http.MethodPut, "/sessions/sess-1/files", strings.NewReader("artifact")))
+	assertHTTPError(t, c.UploadFile(ctx), http.StatusBadRequest)
+
+	ctx, _ = makeWorkspaceEchoContext("/sessions/sess-1/files?path=out/report.txt", "sess-1", "mallory")
+	ctx.SetRequest(httptest.NewRequest(http.MethodPut, "/sessions/sess-1/files?path=out/report.txt", strings.NewReader("artifact")))
+	assertHTTPError(t, c.UploadFile(ctx), http.StatusForbidden)
+}
@@ -0,0 +1,34 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
+)
+
+// NewActivitySource returns the latest runs of the schedules a user created,
+// for the activity feed.
+func NewActivitySource(manager Manager) activity.Source {
+	return activity.SourceFunc(func(ctx context.Context, q activity.Query) ([]activity.Item, error) {
+		schedules, err := manager.List(ctx, ScheduleFilter{UserID: q.UserID})
+		if err != nil {
+			return nil, err
+		}
+		var items []activity.Item
+		for _, s := range schedules {
+			if s.LastExecution == nil {
+				continue
+			}
+			items = append(items, activity.Item{
+				Kind:      activity.KindScheduleRun,
+				Timestamp: s.LastExecution.ExecutedAt,
+				SessionID: s.LastExecution.SessionID,
+				Title:     s.Name,
+				Status:    s.LastExecution.Status,
+				Detail:    s.LastExecution.Error,
+				Ref:       s.ID,
+			})
+		}
+		return items, nil
+	})
+}
@@ -0,0 +1,195 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DraftRequest is the input to a DraftParser
+type DraftRequest struct {
+	// Text is the user's natural language description of the schedule
+	Text string
+	// Timezone is the IANA timezone the user is speaking in
+	Timezone string
+	// Now is the reference time for relative expressions such as "tomorrow"
+	Now time.Time
+}
+
+// ScheduleDraft is the structured schedule a DraftParser extracted from natural language.
+// It is not persisted; the user reviews it and submits it to POST /schedules.
+type ScheduleDraft struct {
+	// Name is a short human-readable name for the schedule
+	Name string `json:"name"`
+	// CronExpr is a 5-field cron expression for recurring schedules
+	CronExpr string `json:"cron_expr,omitempty"`
+	// ScheduledAt is the execution time for one-time schedules
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	// Timezone is the IANA timezone the cron expression is evaluated in
+	Timezone string `json:"timezone,omitempty"`
+	// Message is the initial message sent to the session
+	Message string `json:"message"`
+	// Repository is the repository URL the session should work on, if any
+	Repository string `json:"repository,omitempty"`
+	// Notes explains assumptions or ambiguities the user should double-check
+	Notes string `json:"notes,omitempty"`
+}
+
+// DraftParser converts natural language into a schedule draft
+type DraftParser interface {
+	ParseDraft(ctx context.Context, req DraftRequest) (*ScheduleDraft, error)
+}
+
+const (
+	anthropicVersion      = "2023-06-01"
+	draftParserMaxTokens  = 1024
+	maxDraftResponseBytes = 1 << 20
+)
+
+const draftParserSystemPrompt = `You convert a user's description of a recurring or one-time automation into a schedule for an AI coding agent.
+Reply with a single JSON object and nothing else, using these fields:
+  "name": short name for the schedule (max 60 characters)
+  "cron_expr": 5-field cron expression (minute hour day-of-month month day-of-week) for recurring schedules, otherwise ""
+  "scheduled_at": RFC 3339 timestamp for one-time schedules, otherwise ""
+  "timezone": IANA timezone the schedule is evaluated in
+  "message": the instruction the agent should receive when the schedule fires
+  "repository": repository URL such as "https://github.com/org/repo" if one is mentioned, otherwise ""
+  "notes": assumptions you made or anything ambiguous, otherwise ""
+Exactly one of cron_expr and scheduled_at must be set. Use the user's timezone unless they name another.`
+
+// AnthropicDraftParser drafts schedules with the Anthropic Messages API
+type AnthropicDraftParser struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewAnthropicDraftParser creates a new AnthropicDraftParser
+func NewAnthropicDraftParser(baseURL, apiKey, model string, timeout time.Duration) *AnthropicDraftParser {
+	return &AnthropicDraftParser{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicMessagesRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessagesResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// rawDraft mirrors ScheduleDraft but keeps scheduled_at as a string so that
+// an empty value from the model does not fail to decode.
+type rawDraft struct {
+	Name        string `json:"name"`
+	CronExpr    string `json:"cron_expr"`
+	ScheduledAt string `json:"scheduled_at"`
+	Timezone    string `json:"timezone"`
+	Message     string `json:"message"`
+	Repository  string `json:"repository"`
+	Notes       string `json:"notes"`
+}
+
+// ParseDraft asks the configured model for a schedule draft
+func (p *AnthropicDraftParser) ParseDraft(ctx context.Context, req DraftRequest) (*ScheduleDraft, error) {
+	userPrompt := fmt.Sprintf("Current time: %s\nUser timezone: %s\n\n%s",
+		req.Now.Format(time.RFC3339), req.Timezone, req.Text)
+
+	body, err := json.Marshal(anthropicMessagesRequest{
+		Model:     p.model,
+		MaxTokens: draftParserMaxTokens,
+		System:    draftParserSystemPrompt,
+		Messages:  []anthropicMessage{{Role: "user", Content: userPrompt}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode model request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build model request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("model request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxDraftResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model returned status %d", resp.StatusCode)
+	}
+
+	var msg anthropicMessagesResponse
+	if err := json.Unmarshal(respBody, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode model response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return decodeDraft(text.String())
+}
+
+// decodeDraft extracts the JSON object from the model output, tolerating
+// surrounding prose or Markdown code fences.
+func decodeDraft(text string) (*ScheduleDraft, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("model response did not contain a JSON object")
+	}
+
+	var raw rawDraft
+	if err := json.Unmarshal([]byte(text[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule draft: %w", err)
+	}
+
+	draft := &ScheduleDraft{
+		Name:       strings.TrimSpace(raw.Name),
+		CronExpr:   strings.TrimSpace(raw.CronExpr),
+		Timezone:   strings.TrimSpace(raw.Timezone),
+		Message:    strings.TrimSpace(raw.Message),
+		Repository: strings.TrimSpace(raw.Repository),
+		Notes:      strings.TrimSpace(raw.Notes),
+	}
+	if s := strings.TrimSpace(raw.ScheduledAt); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduled_at in draft: %w", err)
+		}
+		draft.ScheduledAt = &t
+	}
+	return draft, nil
+}
@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestAnthropicDraftParser_ParseDraft(t *testing.T) {
+	var gotReq anthropicMessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		text := "Here is the schedule:\n```json\n" +
+			`{"name":"Weekday triage","cron_expr":"0 9 * * 1-5","scheduled_at":"","timezone":"Asia/Tokyo",` +
+			`"message":"Triage new issues","repository":"https://github.com/org/repo","notes":""}` +
+			"\n```"
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": text}},
+		})
+	}))
+	defer server.Close()
+
+	parser := NewAnthropicDraftParser(server.URL+"/", "test-key", "test-model", 5*time.Second)
+	draft, err := parser.ParseDraft(context.Background(), DraftRequest{
+		Text:     "every weekday at 9am run triage on org/repo",
+		Timezone: "Asia/Tokyo",
+		Now:      time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("ParseDraft() error = %v", err)
+	}
+
+	if gotReq.Model != "test-model" {
+		t.Errorf("model = %q, want test-model", gotReq.Model)
+	}
+	if len(gotReq.Messages) != 1 || !strings.Contains(gotReq.Messages[0].Content, "every weekday at 9am") {
+		t.Errorf("user message not forwarded: %+v", gotReq.Messages)
+	}
+	if draft.CronExpr != "0 9 * * 1-5" {
+		t.Errorf("CronExpr = %q", draft.CronExpr)
+	}
+	if draft.ScheduledAt != nil {
+		t.Errorf("ScheduledAt = %v, want nil", draft.ScheduledAt)
+	}
+	if draft.Repository != "https://github.com/org/repo" {
+		t.Errorf("Repository = %q", draft.Repository)
+	}
+}
+
+func TestAnthropicDraftParser_UpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	parser := NewAnthropicDraftParser(server.URL, "k", "m", 5*time.Second)
+	if _, err := parser.ParseDraft(context.Background(), DraftRequest{Text: "x", Now: time.Now()}); err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+}
+
+func TestDecodeDraft_Invalid(t *testing.T) {
+	for _, text := range []string{"no json here", `{"name":"x","scheduled_at":"tomorrow"}`} {
+		if _, err := decodeDraft(text); err == nil {
+			t.Errorf("decodeDraft(%q) expected error", text)
+		}
+	}
+}
+
+type fakeDraftParser struct {
+	draft *ScheduleDraft
+	err   error
+}
+
+func (f *fakeDraftParser) ParseDraft(_ context.Context, _ DraftRequest) (*ScheduleDraft, error) {
+	return f.draft, f.err
+}
+
+func TestHandlers_ParseSchedule(t *testing.T) {
+	tests := []struct {
+		name       string
+		parser     DraftParser
+		body       string
+		wantStatus int
+	}{
+		{
+			name: "recurring draft",
+			parser: &fakeDraftParser{draft: &ScheduleDraft{
+				Name:       "Weekday triage",
+				CronExpr:   "0 9 * * 1-5",
+				Message:    "Triage new issues",
+				Repository: "https://github.com/org/repo",
+			}},
+			body:       `{"text":"every weekday at 9am run triage on org/repo","timezone":"UTC"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "parser not configured",
+			body:       `{"text":"every day"}`,
+			wantStatus: http.StatusNotImplemented,
+		},
+		{
+			name:       "empty text",
+			parser:     &fakeDraftParser{},
+			body:       `{"text":"  "}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "model failure",
+			parser:     &fakeDraftParser{err: errors.New("boom")},
+			body:       `{"text":"every day"}`,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "invalid drafted cron",
+			parser:     &fakeDraftParser{draft: &ScheduleDraft{Name: "x", CronExpr: "every day"}},
+			body:       `{"text":"every day"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "no timing in draft",
+			parser:     &fakeDraftParser{draft: &ScheduleDraft{Name: "x"}},
+			body:       `{"text":"run triage"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := NewHandlers(nil, nil, nil, nil)
+			if tt.parser != nil {
+				handlers.WithDraftParser(tt.parser)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/schedules/parse", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handlers.ParseSchedule(c)
+			status := rec.Code
+			if err != nil {
+				var he *echo.HTTPError
+				if !errors.As(err, &he) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				status = he.Code
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp ParseScheduleResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Draft.CronExpr != "0 9 * * 1-5" || resp.Draft.Timezone != "UTC" {
+				t.Errorf("unexpected draft timing: %+v", resp.Draft)
+			}
+			if resp.Draft.SessionConfig.Params == nil || resp.Draft.SessionConfig.Params.Message != "Triage new issues" {
+				t.Errorf("message not carried into draft: %+v", resp.Draft.SessionConfig)
+			}
+			if resp.Draft.SessionConfig.Tags["repository"] != "https://github.com/org/repo" {
+				t.Errorf("repository tag = %q", resp.Draft.SessionConfig.Tags["repository"])
+			}
+			if len(resp.NextExecutions) != draftPreviewCount {
+				t.Errorf("len(NextExecutions) = %d, want %d", len(resp.NextExecutions), draftPreviewCount)
+			}
+			for _, at := range resp.NextExecutions {
+				if at.Weekday() == time.Saturday || at.Weekday() == time.Sunday || at.Hour() != 9 {
+					t.Errorf("unexpected execution time %v", at)
+				}
+			}
+		})
+	}
+}
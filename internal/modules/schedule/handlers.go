@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	portservices "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/services"
 	sessionuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/session"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
 )
@@ -19,6 +21,8 @@ type Handlers struct {
 	manager         Manager
 	sessionManager  portrepos.SessionManager
 	launcher        *sessionuc.LaunchUseCase
+	draftParser     DraftParser
+	authorizer      portservices.Authorizer
 	defaultTimezone string
 }
 
@@ -46,6 +50,19 @@ func NewHandlersWithTimezone(manager Manager, sessionManager portrepos.SessionMa
 	}
 }
 
+// WithDraftParser enables POST /schedules/parse using the given parser
+func (h *Handlers) WithDraftParser(parser DraftParser) *Handlers {
+	h.draftParser = parser
+	return h
+}
+
+// WithAuthorizer checks schedule changes against the role policy of the
+// authorizer. Without one, schedule access is governed by ownership only.
+func (h *Handlers) WithAuthorizer(authorizer portservices.Authorizer) *Handlers {
+	h.authorizer = authorizer
+	return h
+}
+
 // GetName returns the name of this handler for logging
 func (h *Handlers) GetName() string {
 	return "ScheduleHandlers"
@@ -57,6 +74,7 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) error {
 
 	g.POST("", h.CreateSchedule)
 	g.GET("", h.ListSchedules)
+	g.POST("/parse", h.ParseSchedule)
 	g.GET("/:id", h.GetSchedule)
 	g.PUT("/:id", h.UpdateSchedule)
 	g.DELETE("/:id", h.DeleteSchedule)
@@ -68,42 +86,50 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) error {
 
 // CreateScheduleRequest represents the request body for creating a schedule
 type CreateScheduleRequest struct {
-	Name          string                 `json:"name"`
-	Scope         entities.ResourceScope `json:"scope,omitempty"`
-	TeamID        string                 `json:"team_id,omitempty"`
-	ScheduledAt   *time.Time             `json:"scheduled_at,omitempty"`
-	CronExpr      string                 `json:"cron_expr,omitempty"`
-	Timezone      string                 `json:"timezone,omitempty"`
-	SessionConfig SessionConfig          `json:"session_config"`
+	Name            string                 `json:"name"`
+	Scope           entities.ResourceScope `json:"scope,omitempty"`
+	TeamID          string                 `json:"team_id,omitempty"`
+	ScheduledAt     *time.Time             `json:"scheduled_at,omitempty"`
+	CronExpr        string                 `json:"cron_expr,omitempty"`
+	Timezone        string                 `json:"timezone,omitempty"`
+	OverlapPolicy   OverlapPolicy          `json:"overlap_policy,omitempty"`
+	MissedRunPolicy MissedRunPolicy        `json:"missed_run_policy,omitempty"`
+	SessionConfig   SessionConfig          `json:"session_config"`
 }
 
 // UpdateScheduleRequest represents the request body for updating a schedule
 type UpdateScheduleRequest struct {
-	Name          *string         `json:"name,omitempty"`
-	Status        *ScheduleStatus `json:"status,omitempty"`
-	ScheduledAt   *time.Time      `json:"scheduled_at,omitempty"`
-	CronExpr      *string         `json:"cron_expr,omitempty"`
-	Timezone      *string         `json:"timezone,omitempty"`
-	SessionConfig *SessionConfig  `json:"session_config,omitempty"`
+	Name            *string          `json:"name,omitempty"`
+	Status          *ScheduleStatus  `json:"status,omitempty"`
+	ScheduledAt     *time.Time       `json:"scheduled_at,omitempty"`
+	CronExpr        *string          `json:"cron_expr,omitempty"`
+	Timezone        *string          `json:"timezone,omitempty"`
+	OverlapPolicy   *OverlapPolicy   `json:"overlap_policy,omitempty"`
+	MissedRunPolicy *MissedRunPolicy `json:"missed_run_policy,omitempty"`
+	SessionConfig   *SessionConfig   `json:"session_config,omitempty"`
 }
 
 // ScheduleResponse represents the response for a schedule
 type ScheduleResponse struct {
-	ID              string                 `json:"id"`
-	Name            string                 `json:"name"`
-	UserID          string                 `json:"user_id"`
-	Scope           entities.ResourceScope `json:"scope,omitempty"`
-	TeamID          string                 `json:"team_id,omitempty"`
-	Status          ScheduleStatus         `json:"status"`
-	ScheduledAt     *time.Time             `json:"scheduled_at,omitempty"`
-	CronExpr        string                 `json:"cron_expr,omitempty"`
-	Timezone        string                 `json:"timezone,omitempty"`
-	SessionConfig   SessionConfig          `json:"session_config"`
-	NextExecutionAt *time.Time             `json:"next_execution_at,omitempty"`
-	ExecutionCount  int                    `json:"execution_count"`
-	LastExecution   *ExecutionRecord       `json:"last_execution,omitempty"`
-	CreatedAt       time.Time              `json:"created_at"`
-	UpdatedAt       time.Time              `json:"updated_at"`
+	ID                string                 `json:"id"`
+	Name              string                 `json:"name"`
+	UserID            string                 `json:"user_id"`
+	Scope             entities.ResourceScope `json:"scope,omitempty"`
+	TeamID            string                 `json:"team_id,omitempty"`
+	Status            ScheduleStatus         `json:"status"`
+	ScheduledAt       *time.Time             `json:"scheduled_at,omitempty"`
+	CronExpr          string                 `json:"cron_expr,omitempty"`
+	Timezone          string                 `json:"timezone,omitempty"`
+	OverlapPolicy     OverlapPolicy          `json:"overlap_policy"`
+	MissedRunPolicy   MissedRunPolicy        `json:"missed_run_policy"`
+	SessionConfig     SessionConfig          `json:"session_config"`
+	NextExecutionAt   *time.Time             `json:"next_execution_at,omitempty"`
+	QueuedSince       *time.Time             `json:"queued_since,omitempty"`
+	ExecutionCount    int                    `json:"execution_count"`
+	LastExecution     *ExecutionRecord       `json:"last_execution,omitempty"`
+	LastSessionStatus string                 `json:"last_session_status,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
 }
 
 // CreateSchedule handles POST /schedules
@@ -130,6 +156,9 @@ func (h *Handlers) CreateSchedule(c echo.Context) error {
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid cron expression: "+err.Error())
 		}
 	}
+	if err := ValidatePolicies(req.OverlapPolicy, req.MissedRunPolicy); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
 	// Use default timezone if not provided
 	timezone := req.Timezone
@@ -172,6 +201,9 @@ func (h *Handlers) CreateSchedule(c echo.Context) error {
 			return echo.NewHTTPError(http.StatusForbidden, "You are not a member of this team")
 		}
 	}
+	if err := h.authorizeManage(c, req.Scope, req.TeamID); err != nil {
+		return err
+	}
 
 	// For user-scoped schedules, auto-populate github_token from auth header if not provided
 	sessionConfig := req.SessionConfig
@@ -201,17 +233,19 @@ func (h *Handlers) CreateSchedule(c echo.Context) error {
 
 	// Create schedule
 	schedule := &Schedule{
-		ID:            uuid.New().String(),
-		Name:          req.Name,
-		UserID:        userID,
-		Scope:         req.Scope,
-		TeamID:        req.TeamID,
-		UserTeams:     userTeams,
-		Status:        ScheduleStatusActive,
-		ScheduledAt:   req.ScheduledAt,
-		CronExpr:      req.CronExpr,
-		Timezone:      timezone,
-		SessionConfig: sessionConfig,
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		UserID:          userID,
+		Scope:           req.Scope,
+		TeamID:          req.TeamID,
+		UserTeams:       userTeams,
+		Status:          ScheduleStatusActive,
+		ScheduledAt:     req.ScheduledAt,
+		CronExpr:        req.CronExpr,
+		Timezone:        timezone,
+		OverlapPolicy:   req.OverlapPolicy,
+		MissedRunPolicy: req.MissedRunPolicy,
+		SessionConfig:   sessionConfig,
 	}
 
 	// Calculate next execution time
@@ -231,6 +265,131 @@ func (h *Handlers) CreateSchedule(c echo.Context) error {
 	return c.JSON(http.StatusCreated, h.toResponse(schedule))
 }
 
+// ParseScheduleRequest represents the request body for drafting a schedule from natural language
+type ParseScheduleRequest struct {
+	Text     string                 `json:"text"`
+	Timezone string                 `json:"timezone,omitempty"`
+	Scope    entities.ResourceScope `json:"scope,omitempty"`
+	TeamID   string                 `json:"team_id,omitempty"`
+}
+
+// ParseScheduleResponse represents a schedule draft awaiting user confirmation.
+// Draft can be submitted as-is to POST /schedules.
+type ParseScheduleResponse struct {
+	Draft          CreateScheduleRequest `json:"draft"`
+	NextExecutions []time.Time           `json:"next_executions,omitempty"`
+	Notes          string                `json:"notes,omitempty"`
+}
+
+// maxParseTextLength bounds the natural language input sent to the model
+const maxParseTextLength = 2000
+
+// draftPreviewCount is the number of upcoming executions returned with a draft
+const draftPreviewCount = 3
+
+// ParseSchedule handles POST /schedules/parse
+func (h *Handlers) ParseSchedule(c echo.Context) error {
+	h.setCORSHeaders(c)
+
+	if h.draftParser == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Schedule parsing is not enabled")
+	}
+
+	var req ParseScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "text is required")
+	}
+	if len(req.Text) > maxParseTextLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("text must be at most %d characters", maxParseTextLength))
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = h.defaultTimezone
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid timezone: "+timezone)
+	}
+
+	now := time.Now()
+	draft, err := h.draftParser.ParseDraft(c.Request().Context(), DraftRequest{
+		Text:     req.Text,
+		Timezone: timezone,
+		Now:      now,
+	})
+	if err != nil {
+		log.Printf("Failed to parse schedule draft: %v", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to draft schedule")
+	}
+
+	if draft.Timezone == "" {
+		draft.Timezone = timezone
+	}
+	if _, err := time.LoadLocation(draft.Timezone); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "drafted timezone is invalid: "+draft.Timezone)
+	}
+	if draft.CronExpr == "" && draft.ScheduledAt == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "could not determine when the schedule should run")
+	}
+	if draft.CronExpr != "" {
+		if err := NewCronParser().Validate(draft.CronExpr); err != nil {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "drafted cron expression is invalid: "+err.Error())
+		}
+	}
+
+	resp := ParseScheduleResponse{
+		Draft: CreateScheduleRequest{
+			Name:        draft.Name,
+			Scope:       req.Scope,
+			TeamID:      req.TeamID,
+			ScheduledAt: draft.ScheduledAt,
+			CronExpr:    draft.CronExpr,
+			Timezone:    draft.Timezone,
+			SessionConfig: SessionConfig{
+				Params: &entities.SessionParams{Message: draft.Message},
+			},
+		},
+		NextExecutions: previewExecutions(draft, now),
+		Notes:          draft.Notes,
+	}
+	if draft.Repository != "" {
+		resp.Draft.SessionConfig.Tags = map[string]string{"repository": draft.Repository}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// previewExecutions returns the next few execution times of a draft so the
+// user can check the interpretation before confirming.
+func previewExecutions(draft *ScheduleDraft, from time.Time) []time.Time {
+	if draft.CronExpr == "" {
+		if draft.ScheduledAt != nil {
+			return []time.Time{draft.ScheduledAt.UTC()}
+		}
+		return nil
+	}
+
+	parser := NewCronParser()
+	next := from
+	if draft.ScheduledAt != nil && from.Before(*draft.ScheduledAt) {
+		next = draft.ScheduledAt.Add(-time.Second)
+	}
+	var times []time.Time
+	for i := 0; i < draftPreviewCount; i++ {
+		t, err := parser.Next(draft.CronExpr, draft.Timezone, next)
+		if err != nil {
+			break
+		}
+		times = append(times, t)
+		next = t
+	}
+	return times
+}
+
 // ListSchedules handles GET /schedules
 func (h *Handlers) ListSchedules(c echo.Context) error {
 	h.setCORSHeaders(c)
@@ -369,6 +528,9 @@ func (h *Handlers) UpdateSchedule(c echo.Context) error {
 	if !h.userCanAccessSchedule(c, schedule) {
 		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to update this schedule")
 	}
+	if err := h.authorizeManage(c, schedule.GetScope(), schedule.TeamID); err != nil {
+		return err
+	}
 
 	var req UpdateScheduleRequest
 	if err := c.Bind(&req); err != nil {
@@ -403,6 +565,15 @@ func (h *Handlers) UpdateSchedule(c echo.Context) error {
 		}
 		schedule.Timezone = *req.Timezone
 	}
+	if req.OverlapPolicy != nil {
+		schedule.OverlapPolicy = *req.OverlapPolicy
+	}
+	if req.MissedRunPolicy != nil {
+		schedule.MissedRunPolicy = *req.MissedRunPolicy
+	}
+	if err := ValidatePolicies(schedule.OverlapPolicy, schedule.MissedRunPolicy); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 	if req.SessionConfig != nil {
 		schedule.SessionConfig = *req.SessionConfig
 	}
@@ -422,6 +593,8 @@ func (h *Handlers) UpdateSchedule(c echo.Context) error {
 			return echo.NewHTTPError(http.StatusBadRequest, "failed to calculate next execution: "+err.Error())
 		}
 		schedule.NextExecutionAt = nextAt
+		// A queued run belongs to the old timing
+		schedule.QueuedSince = nil
 	}
 
 	if err := h.manager.Update(c.Request().Context(), schedule); err != nil {
@@ -455,6 +628,9 @@ func (h *Handlers) DeleteSchedule(c echo.Context) error {
 	if !h.userCanAccessSchedule(c, schedule) {
 		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to delete this schedule")
 	}
+	if err := h.authorizeManage(c, schedule.GetScope(), schedule.TeamID); err != nil {
+		return err
+	}
 
 	if err := h.manager.Delete(c.Request().Context(), id); err != nil {
 		log.Printf("Failed to delete schedule %s: %v", id, err)
@@ -490,6 +666,9 @@ func (h *Handlers) TriggerSchedule(c echo.Context) error {
 	if !h.userCanAccessSchedule(c, schedule) {
 		return echo.NewHTTPError(http.StatusForbidden, "You don't have permission to trigger this schedule")
 	}
+	if err := h.authorizeManage(c, schedule.GetScope(), schedule.TeamID); err != nil {
+		return err
+	}
 
 	// Build the launch request for this manual trigger.
 	// For the Teams field we prefer the live auth context (most up-to-date team
@@ -611,22 +790,39 @@ func (h *Handlers) TriggerSchedule(c echo.Context) error {
 // toResponse converts a Schedule to ScheduleResponse
 func (h *Handlers) toResponse(s *Schedule) ScheduleResponse {
 	return ScheduleResponse{
-		ID:              s.ID,
-		Name:            s.Name,
-		UserID:          s.UserID,
-		Scope:           s.GetScope(), // Use GetScope() to handle default value
-		TeamID:          s.TeamID,
-		Status:          s.Status,
-		ScheduledAt:     s.ScheduledAt,
-		CronExpr:        s.CronExpr,
-		Timezone:        s.Timezone,
-		SessionConfig:   s.SessionConfig,
-		NextExecutionAt: s.NextExecutionAt,
-		ExecutionCount:  s.ExecutionCount,
-		LastExecution:   s.LastExecution,
-		CreatedAt:       s.CreatedAt,
-		UpdatedAt:       s.UpdatedAt,
+		ID:                s.ID,
+		Name:              s.Name,
+		UserID:            s.UserID,
+		Scope:             s.GetScope(), // Use GetScope() to handle default value
+		TeamID:            s.TeamID,
+		Status:            s.Status,
+		ScheduledAt:       s.ScheduledAt,
+		CronExpr:          s.CronExpr,
+		Timezone:          s.Timezone,
+		OverlapPolicy:     s.GetOverlapPolicy(),
+		MissedRunPolicy:   s.GetMissedRunPolicy(),
+		SessionConfig:     s.SessionConfig,
+		NextExecutionAt:   s.NextExecutionAt,
+		QueuedSince:       s.QueuedSince,
+		ExecutionCount:    s.ExecutionCount,
+		LastExecution:     s.LastExecution,
+		LastSessionStatus: h.lastSessionStatus(s),
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+	}
+}
+
+// lastSessionStatus returns the current status of the session started by the
+// last run of a schedule
+func (h *Handlers) lastSessionStatus(s *Schedule) string {
+	if s.LastExecution == nil || s.LastExecution.SessionID == "" || h.sessionManager == nil {
+		return ""
+	}
+	session := h.sessionManager.GetSession(s.LastExecution.SessionID)
+	if session == nil {
+		return "deleted"
 	}
+	return session.Status()
 }
 
 // userCanAccessSchedule checks if the current user can access the schedule
@@ -644,6 +840,20 @@ func (h *Handlers) userCanAccessSchedule(c echo.Context, schedule *Schedule) boo
 	)
 }
 
+// authorizeManage checks that the role of the current user allows managing
+// schedules of the given scope
+func (h *Handlers) authorizeManage(c echo.Context, scope entities.ResourceScope, teamID string) error {
+	if h.authorizer == nil {
+		return nil
+	}
+	resource := entities.AuthzResource{Scope: scope, TeamID: teamID}
+	if err := h.authorizer.Authorize(c.Request().Context(), auth.GetUserFromContext(c), entities.ActionScheduleManage, resource); err != nil {
+		log.Printf("Schedule authorization failed: %v", err)
+		return echo.NewHTTPError(http.StatusForbidden, "Your role does not allow managing schedules")
+	}
+	return nil
+}
+
 // setCORSHeaders sets CORS headers for all schedule endpoints
 func (h *Handlers) setCORSHeaders(c echo.Context) {
 	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
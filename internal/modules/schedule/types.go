@@ -18,6 +18,30 @@ const (
 	ScheduleStatusCompleted ScheduleStatus = "completed"
 )
 
+// OverlapPolicy defines what happens when a run is due while the session of
+// the previous run is still active
+type OverlapPolicy string
+
+const (
+	// OverlapPolicySkip records the run as skipped and waits for the next one
+	OverlapPolicySkip OverlapPolicy = "skip"
+	// OverlapPolicyQueue holds the run until the previous session has ended
+	OverlapPolicyQueue OverlapPolicy = "queue"
+	// OverlapPolicyParallel starts a new session next to the previous one
+	OverlapPolicyParallel OverlapPolicy = "parallel"
+)
+
+// MissedRunPolicy defines what happens to runs that became due while no
+// worker was running, e.g. during a proxy outage
+type MissedRunPolicy string
+
+const (
+	// MissedRunPolicyRunOnce runs once as soon as possible, however many runs were missed
+	MissedRunPolicyRunOnce MissedRunPolicy = "run_once"
+	// MissedRunPolicySkip records the run as missed and waits for the next one
+	MissedRunPolicySkip MissedRunPolicy = "skip"
+)
+
 // Schedule represents a scheduled session configuration
 type Schedule struct {
 	// ID is the unique identifier for the schedule
@@ -51,6 +75,14 @@ type Schedule struct {
 	// Timezone is the IANA timezone for schedule evaluation (default: UTC)
 	Timezone string `json:"timezone,omitempty"`
 
+	// OverlapPolicy applies when a run is due while the previous session is
+	// still active (default: skip). It is ignored when SessionConfig.ReuseSession is set.
+	OverlapPolicy OverlapPolicy `json:"overlap_policy,omitempty"`
+
+	// MissedRunPolicy applies when a run is picked up well after it was due
+	// (default: run_once)
+	MissedRunPolicy MissedRunPolicy `json:"missed_run_policy,omitempty"`
+
 	// SessionConfig contains the configuration for creating sessions
 	SessionConfig SessionConfig `json:"session_config"`
 
@@ -60,6 +92,10 @@ type Schedule struct {
 	// NextExecutionAt is the calculated next execution time
 	NextExecutionAt *time.Time `json:"next_execution_at,omitempty"`
 
+	// QueuedSince is set while a due run waits for the previous session to
+	// end under OverlapPolicyQueue
+	QueuedSince *time.Time `json:"queued_since,omitempty"`
+
 	// ExecutionCount is the total number of executions
 	ExecutionCount int `json:"execution_count"`
 
@@ -99,7 +135,7 @@ type ExecutionRecord struct {
 	ExecutedAt time.Time `json:"executed_at"`
 	// SessionID is the ID of the created or reused session (if successful)
 	SessionID string `json:"session_id,omitempty"`
-	// Status is the result of the execution: "success", "failed", or "skipped"
+	// Status is the result of the execution: "success", "failed", "skipped" or "missed"
 	Status string `json:"status"`
 	// Error contains the error message if execution failed
 	Error string `json:"error,omitempty"`
@@ -115,6 +151,22 @@ func (s *Schedule) GetScope() entities.ResourceScope {
 	return s.Scope
 }
 
+// GetOverlapPolicy returns the overlap policy, defaulting to skip if not set
+func (s *Schedule) GetOverlapPolicy() OverlapPolicy {
+	if s.OverlapPolicy == "" {
+		return OverlapPolicySkip
+	}
+	return s.OverlapPolicy
+}
+
+// GetMissedRunPolicy returns the missed-run policy, defaulting to run_once if not set
+func (s *Schedule) GetMissedRunPolicy() MissedRunPolicy {
+	if s.MissedRunPolicy == "" {
+		return MissedRunPolicyRunOnce
+	}
+	return s.MissedRunPolicy
+}
+
 // IsOneTime returns true if this is a one-time schedule (no recurring)
 func (s *Schedule) IsOneTime() bool {
 	return s.ScheduledAt != nil && s.CronExpr == ""
@@ -152,6 +204,25 @@ func (s *Schedule) Validate() error {
 	if s.ScheduledAt == nil && s.CronExpr == "" {
 		return ErrInvalidSchedule{Field: "schedule", Message: "either scheduled_at or cron_expr must be set"}
 	}
+	if err := ValidatePolicies(s.OverlapPolicy, s.MissedRunPolicy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidatePolicies checks the overlap and missed-run policies. Empty values
+// select the defaults.
+func ValidatePolicies(overlap OverlapPolicy, missed MissedRunPolicy) error {
+	switch overlap {
+	case "", OverlapPolicySkip, OverlapPolicyQueue, OverlapPolicyParallel:
+	default:
+		return ErrInvalidSchedule{Field: "overlap_policy", Message: "must be one of skip, queue or parallel"}
+	}
+	switch missed {
+	case "", MissedRunPolicyRunOnce, MissedRunPolicySkip:
+	default:
+		return ErrInvalidSchedule{Field: "missed_run_policy", Message: "must be one of run_once or skip"}
+	}
 	return nil
 }
 
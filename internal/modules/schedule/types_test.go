@@ -213,3 +213,25 @@ func TestSchedule_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlap OverlapPolicy
+		missed  MissedRunPolicy
+		wantErr bool
+	}{
+		{name: "defaults", wantErr: false},
+		{name: "queue and skip", overlap: OverlapPolicyQueue, missed: MissedRunPolicySkip, wantErr: false},
+		{name: "parallel and run_once", overlap: OverlapPolicyParallel, missed: MissedRunPolicyRunOnce, wantErr: false},
+		{name: "unknown overlap", overlap: "replace", wantErr: true},
+		{name: "unknown missed", missed: "run_all", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePolicies(tt.overlap, tt.missed); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
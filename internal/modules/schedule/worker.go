@@ -139,12 +139,42 @@ func (w *Worker) processSchedules(ctx context.Context) {
 func (w *Worker) executeSchedule(ctx context.Context, schedule *Schedule) {
 	log.Printf("[SCHEDULE_WORKER] Executing schedule %s (%s)", schedule.ID, schedule.Name)
 
+	// A run picked up long after it was due was missed, e.g. while the proxy
+	// was down. A run held back by the queue overlap policy is late on purpose
+	// and is never treated as missed.
+	if schedule.QueuedSince == nil && schedule.GetMissedRunPolicy() == MissedRunPolicySkip && w.isMissed(schedule, time.Now()) {
+		log.Printf("[SCHEDULE_WORKER] Skipping schedule %s: run due at %v was missed",
+			schedule.ID, schedule.NextExecutionAt)
+		w.recordExecution(ctx, schedule, ExecutionRecord{
+			ExecutedAt: time.Now(),
+			Status:     "missed",
+			Error:      "run due at " + schedule.NextExecutionAt.UTC().Format(time.RFC3339) + " was missed",
+		})
+		if schedule.IsRecurring() {
+			w.updateNextExecution(ctx, schedule)
+		} else {
+			w.markCompleted(ctx, schedule.ID)
+		}
+		return
+	}
+
 	// Check if previous session is still active
 	if schedule.LastExecution != nil && schedule.LastExecution.SessionID != "" {
 		if w.isSessionActive(schedule.LastExecution.SessionID) {
-			// When session reuse is enabled, we do NOT skip — the LaunchUseCase will
-			// send the message to the existing active session instead of creating a new one.
-			if !schedule.SessionConfig.ReuseSession {
+			switch {
+			case schedule.SessionConfig.ReuseSession:
+				// When session reuse is enabled, we do NOT skip — the LaunchUseCase will
+				// send the message to the existing active session instead of creating a new one.
+				log.Printf("[SCHEDULE_WORKER] Reusing previous session %s for schedule %s",
+					schedule.LastExecution.SessionID, schedule.ID)
+			case schedule.GetOverlapPolicy() == OverlapPolicyParallel:
+				log.Printf("[SCHEDULE_WORKER] Starting schedule %s alongside active previous session %s",
+					schedule.ID, schedule.LastExecution.SessionID)
+			case schedule.GetOverlapPolicy() == OverlapPolicyQueue:
+				// Leave the run due so that it starts once the previous session ends
+				w.markQueued(ctx, schedule)
+				return
+			default:
 				log.Printf("[SCHEDULE_WORKER] Skipping schedule %s: previous session %s still active",
 					schedule.ID, schedule.LastExecution.SessionID)
 				w.recordExecution(ctx, schedule, ExecutionRecord{
@@ -156,13 +186,14 @@ func (w *Worker) executeSchedule(ctx context.Context, schedule *Schedule) {
 				w.updateNextExecution(ctx, schedule)
 				return
 			}
-			log.Printf("[SCHEDULE_WORKER] Reusing previous session %s for schedule %s",
-				schedule.LastExecution.SessionID, schedule.ID)
 		} else if !schedule.SessionConfig.ReuseSession {
 			// Delete previous session if it's no longer active (only when not using reuse mode)
 			w.deletePreviousSession(schedule.LastExecution.SessionID)
 		}
 	}
+	if schedule.QueuedSince != nil {
+		w.clearQueued(ctx, schedule)
+	}
 
 	// Create session
 	sessionID := uuid.New().String()
@@ -214,18 +245,58 @@ func (w *Worker) executeSchedule(ctx context.Context, schedule *Schedule) {
 				schedule.ID, err)
 		}
 
-		// Then get the updated schedule and mark as completed
-		updated, err := w.manager.Get(ctx, schedule.ID)
-		if err != nil {
-			log.Printf("[SCHEDULE_WORKER] Failed to get schedule %s for completion: %v",
-				schedule.ID, err)
-			return
-		}
-		updated.Status = ScheduleStatusCompleted
-		if err := w.manager.Update(ctx, updated); err != nil {
-			log.Printf("[SCHEDULE_WORKER] Failed to mark schedule %s as completed: %v",
-				schedule.ID, err)
-		}
+		// Then mark the updated schedule as completed
+		w.markCompleted(ctx, schedule.ID)
+	}
+}
+
+// markCompleted marks a one-time schedule as completed
+func (w *Worker) markCompleted(ctx context.Context, id string) {
+	updated, err := w.manager.Get(ctx, id)
+	if err != nil {
+		log.Printf("[SCHEDULE_WORKER] Failed to get schedule %s for completion: %v", id, err)
+		return
+	}
+	updated.Status = ScheduleStatusCompleted
+	if err := w.manager.Update(ctx, updated); err != nil {
+		log.Printf("[SCHEDULE_WORKER] Failed to mark schedule %s as completed: %v", id, err)
+	}
+}
+
+// isMissed reports whether a due run is late by more than two check
+// intervals (at least a minute), which a running worker never is
+func (w *Worker) isMissed(schedule *Schedule, now time.Time) bool {
+	if schedule.NextExecutionAt == nil {
+		return false
+	}
+	grace := 2 * w.config.CheckInterval
+	if grace < time.Minute {
+		grace = time.Minute
+	}
+	return now.Sub(*schedule.NextExecutionAt) > grace
+}
+
+// markQueued records that a due run waits for the previous session to end
+func (w *Worker) markQueued(ctx context.Context, schedule *Schedule) {
+	if schedule.QueuedSince != nil {
+		return
+	}
+	log.Printf("[SCHEDULE_WORKER] Queueing schedule %s until previous session %s ends",
+		schedule.ID, schedule.LastExecution.SessionID)
+	now := time.Now()
+	schedule.QueuedSince = &now
+	if err := w.manager.Update(ctx, schedule); err != nil {
+		log.Printf("[SCHEDULE_WORKER] Failed to queue schedule %s: %v", schedule.ID, err)
+	}
+}
+
+// clearQueued releases a queued run before it starts
+func (w *Worker) clearQueued(ctx context.Context, schedule *Schedule) {
+	log.Printf("[SCHEDULE_WORKER] Starting queued run of schedule %s (queued since %v)",
+		schedule.ID, schedule.QueuedSince)
+	schedule.QueuedSince = nil
+	if err := w.manager.Update(ctx, schedule); err != nil {
+		log.Printf("[SCHEDULE_WORKER] Failed to clear queued run of schedule %s: %v", schedule.ID, err)
 	}
 }
 
@@ -401,3 +401,117 @@ func TestWorker_DeletePreviousInactiveSession(t *testing.T) {
 		t.Errorf("expected status 'success', got %q", updated.LastExecution.Status)
 	}
 }
+
+// newOverlapTestSchedule creates a due recurring schedule whose previous
+// session prev-session is still active
+func newOverlapTestSchedule(t *testing.T, manager Manager, sessionManager *mockProxySessionManager, policy OverlapPolicy) {
+	t.Helper()
+	sessionManager.sessions["prev-session"] = &mockProxySession{
+		id:        "prev-session",
+		userID:    "test-user",
+		status:    "active",
+		startedAt: time.Now(),
+	}
+	due := time.Now().Add(-time.Second)
+	schedule := &Schedule{
+		ID:              "test-schedule",
+		Name:            "Test Schedule",
+		UserID:          "test-user",
+		Status:          ScheduleStatusActive,
+		CronExpr:        "* * * * *",
+		OverlapPolicy:   policy,
+		NextExecutionAt: &due,
+		LastExecution: &ExecutionRecord{
+			ExecutedAt: due.Add(-time.Minute),
+			SessionID:  "prev-session",
+			Status:     "success",
+		},
+	}
+	if err := manager.Create(context.Background(), schedule); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+}
+
+func TestWorker_OverlapPolicyParallel(t *testing.T) {
+	manager := NewKubernetesManager(fake.NewSimpleClientset(), "default")
+	sessionManager := newMockProxySessionManager()
+	newOverlapTestSchedule(t, manager, sessionManager, OverlapPolicyParallel)
+
+	worker := NewWorker(manager, sessionManager, nil, WorkerConfig{CheckInterval: time.Minute, Enabled: true}, nil)
+	worker.processSchedules(context.Background())
+
+	if len(sessionManager.sessions) != 2 {
+		t.Errorf("expected a new session next to prev-session, got %d sessions", len(sessionManager.sessions))
+	}
+	updated, _ := manager.Get(context.Background(), "test-schedule")
+	if updated.LastExecution.Status != "success" || updated.LastExecution.SessionID == "prev-session" {
+		t.Errorf("unexpected last execution %+v", updated.LastExecution)
+	}
+}
+
+func TestWorker_OverlapPolicyQueue(t *testing.T) {
+	ctx := context.Background()
+	manager := NewKubernetesManager(fake.NewSimpleClientset(), "default")
+	sessionManager := newMockProxySessionManager()
+	newOverlapTestSchedule(t, manager, sessionManager, OverlapPolicyQueue)
+
+	worker := NewWorker(manager, sessionManager, nil, WorkerConfig{CheckInterval: time.Minute, Enabled: true}, nil)
+	worker.processSchedules(ctx)
+
+	queued, _ := manager.Get(ctx, "test-schedule")
+	if queued.QueuedSince == nil || !queued.IsDue(time.Now()) {
+		t.Fatalf("expected the run to stay due and queued, got queued_since=%v next=%v", queued.QueuedSince, queued.NextExecutionAt)
+	}
+	if len(sessionManager.sessions) != 1 || queued.LastExecution.SessionID != "prev-session" {
+		t.Errorf("expected no run while the previous session is active")
+	}
+
+	// The queued run starts once the previous session is gone, however late
+	sessionManager.sessions["prev-session"].status = "stopped"
+	queuedSince := time.Now().Add(-time.Hour)
+	queued.QueuedSince = &queuedSince
+	queued.MissedRunPolicy = MissedRunPolicySkip
+	if err := manager.Update(ctx, queued); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	worker.processSchedules(ctx)
+
+	updated, _ := manager.Get(ctx, "test-schedule")
+	if updated.QueuedSince != nil || updated.LastExecution.Status != "success" {
+		t.Errorf("expected the queued run to start, got queued_since=%v last=%+v", updated.QueuedSince, updated.LastExecution)
+	}
+}
+
+func TestWorker_MissedRunPolicySkip(t *testing.T) {
+	ctx := context.Background()
+	manager := NewKubernetesManager(fake.NewSimpleClientset(), "default")
+	sessionManager := newMockProxySessionManager()
+
+	past := time.Now().Add(-time.Hour)
+	schedule := &Schedule{
+		ID:              "test-schedule",
+		Name:            "Test Schedule",
+		UserID:          "test-user",
+		Status:          ScheduleStatusActive,
+		CronExpr:        "0 9 * * *",
+		MissedRunPolicy: MissedRunPolicySkip,
+		NextExecutionAt: &past,
+	}
+	if err := manager.Create(ctx, schedule); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	worker := NewWorker(manager, sessionManager, nil, WorkerConfig{CheckInterval: 30 * time.Second, Enabled: true}, nil)
+	worker.processSchedules(ctx)
+
+	if len(sessionManager.sessions) != 0 {
+		t.Errorf("expected no session for a missed run, got %d", len(sessionManager.sessions))
+	}
+	updated, _ := manager.Get(ctx, "test-schedule")
+	if updated.LastExecution == nil || updated.LastExecution.Status != "missed" {
+		t.Errorf("expected a missed execution, got %+v", updated.LastExecution)
+	}
+	if !updated.NextExecutionAt.After(time.Now()) {
+		t.Errorf("expected next execution in the future, got %v", updated.NextExecutionAt)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	core "github.com/takutakahashi/agentapi-proxy/internal/core/sessionallocation"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 )
 
 type SessionCreator interface {
@@ -84,6 +85,8 @@ func (w *Worker) run(ctx context.Context) {
 func (w *Worker) processOne(ctx context.Context, req *core.AllocationRequest) {
 	log.Printf("[SESSION_ALLOCATOR] Allocating session %s (sandbox=%t dind=%t agent_type=%s)",
 		req.SessionID, req.Requirements.Sandbox, req.Requirements.DinD, req.Requirements.AgentType)
+	// Continue the trace of the request that submitted the allocation.
+	ctx = tracing.ContextWithTraceParent(ctx, req.TraceParent)
 	sess, err := w.creator.CreateSessionDirect(ctx, req.SessionID, req.Request, req.WebhookPayload)
 	if err != nil {
 		_ = w.client.Complete(context.Background(), req.SessionID, core.AllocationResult{Status: core.StatusError, Message: err.Error()})
@@ -11,6 +11,7 @@ import (
 	infrasessionallocation "github.com/takutakahashi/agentapi-proxy/internal/infrastructure/sessionallocation"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 )
 
 type AllocatorWorker struct {
@@ -110,7 +111,7 @@ func (w *AllocatorWorker) process(ctx context.Context, allocation *sessionalloca
 		})
 		return
 	}
-	session, err := w.createLocalSession(ctx, sessionID, req)
+	session, err := w.createLocalSession(tracing.ContextWithTraceParent(ctx, allocation.TraceParent), sessionID, req)
 	if err != nil {
 		log.Printf("[SESSION_MANAGER_ALLOCATOR] Failed to create session for allocation %s: %v", allocation.SessionID, err)
 		_ = w.client.CompleteExternal(context.Background(), allocation.SessionID, sessionallocation.AllocationResult{
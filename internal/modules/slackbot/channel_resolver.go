@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,6 +36,8 @@ type SlackChannelResolver struct {
 	slackAPIBase string // base URL for the Slack API, e.g. "https://slack.com/api"
 	// in-memory cache: channel ID → channel name (cleared on pod restart)
 	cache sync.Map
+	// deliveryQueue retries failed posts; nil disables retries
+	deliveryQueue *delivery.Queue
 }
 
 // NewSlackChannelResolver creates a new SlackChannelResolver
@@ -305,14 +308,27 @@ type postMessageRequest struct {
 type postMessageResponse struct {
 	OK    bool   `json:"ok"`
 	Error string `json:"error,omitempty"`
+	TS    string `json:"ts,omitempty"`
 }
 
 // PostMessage posts a message to a Slack channel, optionally in a thread.
 // If threadTS is non-empty, the message is posted as a thread reply.
 // Requires a bot token with chat:write scope.
 func (r *SlackChannelResolver) PostMessage(ctx context.Context, channel, threadTS, text, botToken string) error {
+	_, err := r.postMessage(ctx, channel, threadTS, text, botToken)
+	return err
+}
+
+// StartThread posts a top-level message to a Slack channel and returns its
+// timestamp, which identifies the thread for subsequent replies.
+func (r *SlackChannelResolver) StartThread(ctx context.Context, channel, text, botToken string) (string, error) {
+	return r.postMessage(ctx, channel, "", text, botToken)
+}
+
+// postMessage calls chat.postMessage and returns the timestamp of the posted message.
+func (r *SlackChannelResolver) postMessage(ctx context.Context, channel, threadTS, text, botToken string) (string, error) {
 	if botToken == "" {
-		return fmt.Errorf("bot token is empty; cannot post message to Slack")
+		return "", fmt.Errorf("bot token is empty; cannot post message to Slack")
 	}
 
 	payload := postMessageRequest{
@@ -323,19 +339,19 @@ func (r *SlackChannelResolver) PostMessage(ctx context.Context, channel, threadT
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message payload: %w", err)
+		return "", fmt.Errorf("failed to marshal message payload: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.slackAPIBase+"/chat.postMessage", bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+botToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("slack API request failed: %w", err)
+		return "", fmt.Errorf("slack API request failed: %w", err)
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
@@ -345,22 +361,22 @@ func (r *SlackChannelResolver) PostMessage(ctx context.Context, channel, threadT
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read Slack API response: %w", err)
+		return "", fmt.Errorf("failed to read Slack API response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack API returned status %d: %s", resp.StatusCode, string(respBody))
+		return "", fmt.Errorf("slack API returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var result postMessageResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return fmt.Errorf("failed to parse Slack API response: %w", err)
+		return "", fmt.Errorf("failed to parse Slack API response: %w", err)
 	}
 
 	if !result.OK {
-		return fmt.Errorf("slack API error: %s", result.Error)
+		return "", &slackAPIError{Code: result.Error}
 	}
 
 	log.Printf("[CHANNEL_RESOLVER] Posted message to channel=%s thread=%s", channel, threadTS)
-	return nil
+	return result.TS, nil
 }
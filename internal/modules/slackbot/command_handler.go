@@ -0,0 +1,346 @@
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/slack-go/slack"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	sessionuc "github.com/takutakahashi/agentapi-proxy/internal/usecases/session"
+)
+
+// slashCommandUsage is returned for unknown or malformed /agent invocations.
+const slashCommandUsage = "Usage:\n" +
+	"• `/agent start owner/repo \"what to do\"` – start a session (status updates are posted in a thread)\n" +
+	"• `/agent stop <session-id>` – stop one of your sessions\n" +
+	"• `/agent status <session-id>` – show the status of a session\n" +
+	"• `/agent list` – list your active sessions"
+
+// SlackCommandHandler handles Slack slash commands (e.g. /agent) received over HTTP.
+// Requests are verified with the Slack signing secret, and the invoking Slack user
+// is mapped to an agentapi user through the slack_user_id of their user settings.
+type SlackCommandHandler struct {
+	sessionManager  repositories.SessionManager
+	settingsRepo    repositories.SettingsRepository
+	launcher        *sessionuc.LaunchUseCase
+	channelResolver *SlackChannelResolver
+	signingSecret   string
+	// Default bot token used to post status updates
+	botTokenSecretName string
+	botTokenSecretKey  string
+	// baseURL is used to construct session URLs; NOTIFICATION_BASE_URL takes precedence.
+	baseURL string
+	dryRun  bool
+}
+
+// NewSlackCommandHandler creates a new SlackCommandHandler
+func NewSlackCommandHandler(
+	sessionManager repositories.SessionManager,
+	settingsRepo repositories.SettingsRepository,
+	channelResolver *SlackChannelResolver,
+	signingSecret string,
+	botTokenSecretName string,
+	botTokenSecretKey string,
+	baseURL string,
+	dryRun bool,
+	memoryRepo repositories.MemoryRepository,
+	sessionProfileRepo repositories.SessionProfileRepository,
+) *SlackCommandHandler {
+	return &SlackCommandHandler{
+		sessionManager: sessionManager,
+		settingsRepo:   settingsRepo,
+		launcher: sessionuc.NewLaunchUseCase(sessionManager).
+			WithMemoryRepository(memoryRepo).
+			WithSessionProfileRepository(sessionProfileRepo),
+		channelResolver:    channelResolver,
+		signingSecret:      signingSecret,
+		botTokenSecretName: botTokenSecretName,
+		botTokenSecretKey:  botTokenSecretKey,
+		baseURL:            baseURL,
+		dryRun:             dryRun,
+	}
+}
+
+// slashCommandResponse is the immediate response to a slash command.
+type slashCommandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func ephemeral(text string) slashCommandResponse {
+	return slashCommandResponse{ResponseType: "ephemeral", Text: text}
+}
+
+// HandleSlashCommand handles POST /slack/commands
+func (h *SlackCommandHandler) HandleSlashCommand(c echo.Context) error {
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	verifier, err := slack.NewSecretsVerifier(req.Header, h.signingSecret)
+	if err != nil {
+		log.Printf("[SLACK_COMMAND] Missing or invalid signature headers: %v", err)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Signature verification failed"})
+	}
+	if _, err := verifier.Write(body); err != nil || verifier.Ensure() != nil {
+		log.Printf("[SLACK_COMMAND] Signature verification failed")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Signature verification failed"})
+	}
+
+	cmd, err := slack.SlashCommandParse(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid slash command payload"})
+	}
+	log.Printf("[SLACK_COMMAND] Received %s from user=%s channel=%s: %q", cmd.Command, cmd.UserID, cmd.ChannelID, cmd.Text)
+
+	userID, err := h.resolveUser(req.Context(), cmd.UserID)
+	if err != nil {
+		log.Printf("[SLACK_COMMAND] Failed to resolve Slack user %s: %v", cmd.UserID, err)
+		return c.JSON(http.StatusOK, ephemeral(":warning: Failed to look up your account. Please try again later."))
+	}
+	if userID == "" {
+		return c.JSON(http.StatusOK, ephemeral(":warning: Your Slack account is not linked. Set `slack_user_id` in your agentapi settings first."))
+	}
+
+	args := splitCommandArgs(cmd.Text)
+	if len(args) == 0 {
+		return c.JSON(http.StatusOK, ephemeral(slashCommandUsage))
+	}
+	switch strings.ToLower(args[0]) {
+	case "start":
+		return c.JSON(http.StatusOK, h.start(userID, cmd, args[1:]))
+	case "stop":
+		return c.JSON(http.StatusOK, h.stop(req.Context(), userID, args[1:]))
+	case "status":
+		return c.JSON(http.StatusOK, h.status(userID, args[1:]))
+	case "list":
+		return c.JSON(http.StatusOK, h.list(userID))
+	default:
+		return c.JSON(http.StatusOK, ephemeral(slashCommandUsage))
+	}
+}
+
+// resolveUser returns the agentapi user whose settings carry the given Slack
+// user ID. Team settings (named "org/team") are not considered.
+func (h *SlackCommandHandler) resolveUser(ctx context.Context, slackUserID string) (string, error) {
+	if h.settingsRepo == nil || slackUserID == "" {
+		return "", nil
+	}
+	all, err := h.settingsRepo.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, settings := range all {
+		if settings.SlackUserID() == slackUserID && !strings.Contains(settings.Name(), "/") {
+			return settings.Name(), nil
+		}
+	}
+	return "", nil
+}
+
+// start creates a session for the repository. A thread is opened in the channel
+// and passed to the session as Slack params, so agent responses and status
+// updates are posted there and replies in the thread reach the session.
+func (h *SlackCommandHandler) start(userID string, cmd slack.SlashCommand, args []string) slashCommandResponse {
+	if len(args) < 2 || !strings.Contains(args[0], "/") {
+		return ephemeral(slashCommandUsage)
+	}
+	repo := args[0]
+	message := strings.TrimSpace(strings.Join(args[1:], " "))
+	if message == "" {
+		return ephemeral(slashCommandUsage)
+	}
+	sessionID := uuid.New().String()
+
+	go func() {
+		ctx := context.Background()
+		botToken := h.botToken(ctx)
+		threadTS := ""
+		if botToken != "" && !h.dryRun {
+			ts, err := h.channelResolver.StartThread(ctx, cmd.ChannelID,
+				fmt.Sprintf("<@%s> started a session for `%s`:\n> %s", cmd.UserID, repo, message), botToken)
+			if err != nil {
+				log.Printf("[SLACK_COMMAND] Failed to open status thread: %v", err)
+			}
+			threadTS = ts
+		}
+
+		var slackParams *entities.SlackParams
+		tags := map[string]string{
+			"repository":    repo,
+			"slack_command": "true",
+			"slack_user_id": cmd.UserID,
+		}
+		if threadTS != "" {
+			slackParams = &entities.SlackParams{Channel: cmd.ChannelID, ThreadTS: threadTS}
+		}
+
+		if h.dryRun {
+			log.Printf("[SLACK_COMMAND] [DRY-RUN] Would create session: id=%s, user=%s, repository=%s", sessionID, userID, repo)
+			return
+		}
+
+		result, err := h.launcher.Launch(ctx, sessionID, sessionuc.LaunchRequest{
+			UserID:         userID,
+			Scope:          entities.ScopeUser,
+			Teams:          sessionuc.ResolveTeams(entities.ScopeUser, "", nil),
+			Tags:           tags,
+			InitialMessage: message,
+			AgentType:      "claude-acp",
+			RepoInfo: &entities.RepositoryInfo{
+				FullName: repo,
+				CloneDir: "/home/agentapi/workdir/repo",
+			},
+			SlackParams: slackParams,
+		})
+		if err != nil {
+			log.Printf("[SLACK_COMMAND] Failed to create session for user %s: %v", userID, err)
+			h.postToThread(ctx, cmd.ChannelID, threadTS, botToken, fmt.Sprintf(":warning: Failed to create session: %v", err))
+			return
+		}
+		log.Printf("[SLACK_COMMAND] Created session %s for user %s (repository=%s)", result.SessionID, userID, repo)
+		h.postToThread(ctx, cmd.ChannelID, threadTS, botToken,
+			fmt.Sprintf("Session created :robot_face:\n%s", h.sessionURL(result.SessionID)))
+	}()
+
+	return ephemeral(fmt.Sprintf("Starting a session for `%s` (id: `%s`)…", repo, sessionID))
+}
+
+// stop deletes one of the user's sessions.
+func (h *SlackCommandHandler) stop(ctx context.Context, userID string, args []string) slashCommandResponse {
+	if len(args) != 1 {
+		return ephemeral(slashCommandUsage)
+	}
+	session := h.ownedSession(userID, args[0])
+	if session == nil {
+		return ephemeral(fmt.Sprintf(":warning: Session `%s` was not found.", args[0]))
+	}
+	if h.dryRun {
+		log.Printf("[SLACK_COMMAND] [DRY-RUN] Would stop session %s", session.ID())
+		return ephemeral(fmt.Sprintf("Stopped session `%s`.", session.ID()))
+	}
+	if err := h.sessionManager.DeleteSession(session.ID()); err != nil {
+		log.Printf("[SLACK_COMMAND] Failed to stop session %s: %v", session.ID(), err)
+		return ephemeral(fmt.Sprintf(":warning: Failed to stop session: %v", err))
+	}
+	if tags := session.Tags(); tags["slack_thread_ts"] != "" {
+		h.postToThread(ctx, tags["slack_channel"], tags["slack_thread_ts"], h.botToken(ctx), "Session stopped :stop_sign:")
+	}
+	return ephemeral(fmt.Sprintf("Stopped session `%s`.", session.ID()))
+}
+
+// status reports the state of one of the user's sessions.
+func (h *SlackCommandHandler) status(userID string, args []string) slashCommandResponse {
+	if len(args) != 1 {
+		return ephemeral(slashCommandUsage)
+	}
+	session := h.ownedSession(userID, args[0])
+	if session == nil {
+		return ephemeral(fmt.Sprintf(":warning: Session `%s` was not found.", args[0]))
+	}
+	return ephemeral(formatSessionLine(session, h.sessionURL(session.ID())))
+}
+
+// list reports the user's active sessions.
+func (h *SlackCommandHandler) list(userID string) slashCommandResponse {
+	sessions := h.sessionManager.ListSessions(entities.SessionFilter{UserID: userID, Status: "active"})
+	if len(sessions) == 0 {
+		return ephemeral("You have no active sessions.")
+	}
+	lines := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		lines = append(lines, formatSessionLine(s, h.sessionURL(s.ID())))
+	}
+	return ephemeral(strings.Join(lines, "\n"))
+}
+
+// ownedSession returns the session when it exists and belongs to the user.
+func (h *SlackCommandHandler) ownedSession(userID, sessionID string) entities.Session {
+	session := h.sessionManager.GetSession(sessionID)
+	if session == nil || session.UserID() != userID {
+		return nil
+	}
+	return session
+}
+
+func (h *SlackCommandHandler) botToken(ctx context.Context) string {
+	if h.channelResolver == nil || h.botTokenSecretName == "" {
+		return ""
+	}
+	token, err := h.channelResolver.GetBotToken(ctx, h.botTokenSecretName, h.botTokenSecretKey)
+	if err != nil {
+		log.Printf("[SLACK_COMMAND] Failed to get bot token: %v", err)
+		return ""
+	}
+	return token
+}
+
+// postToThread posts a status update to the session thread (best-effort).
+func (h *SlackCommandHandler) postToThread(ctx context.Context, channel, threadTS, botToken, message string) {
+	if h.channelResolver == nil || botToken == "" || threadTS == "" {
+		return
+	}
+	if err := h.channelResolver.PostMessage(ctx, channel, threadTS, message, botToken); err != nil {
+		log.Printf("[SLACK_COMMAND] Failed to post status update: channel=%s, err=%v", channel, err)
+		h.channelResolver.QueuePostRetry(channel, threadTS, message, h.botTokenSecretName, h.botTokenSecretKey, err)
+	}
+}
+
+func (h *SlackCommandHandler) sessionURL(sessionID string) string {
+	base := os.Getenv("NOTIFICATION_BASE_URL")
+	if base == "" {
+		base = h.baseURL
+	}
+	if base == "" {
+		return sessionID
+	}
+	return fmt.Sprintf("%s/sessions/%s", strings.TrimRight(base, "/"), sessionID)
+}
+
+func formatSessionLine(s entities.Session, url string) string {
+	line := fmt.Sprintf("• `%s` %s", s.ID(), s.Status())
+	if repo := s.Tags()["repository"]; repo != "" {
+		line += fmt.Sprintf(" (repository: `%s`)", repo)
+	}
+	return line + " " + url
+}
+
+// splitCommandArgs splits slash command text on whitespace while keeping
+// double-quoted sections (including Slack's smart quotes) together.
+func splitCommandArgs(text string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			inQuotes = !inQuotes
+			hasArg = true
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,59 @@
+package slackbot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+type listSettingsRepo struct {
+	portrepos.SettingsRepository
+	settings []*entities.Settings
+}
+
+func (r *listSettingsRepo) List(context.Context) ([]*entities.Settings, error) {
+	return r.settings, nil
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: `start owner/repo "fix the bug"`, want: []string{"start", "owner/repo", "fix the bug"}},
+		{text: "start owner/repo “fix the bug”", want: []string{"start", "owner/repo", "fix the bug"}},
+		{text: "  list  ", want: []string{"list"}},
+		{text: `start owner/repo ""`, want: []string{"start", "owner/repo", ""}},
+		{text: "", want: nil},
+	}
+	for _, tt := range tests {
+		if got := splitCommandArgs(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandArgs(%q) = %#v, want %#v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSlackCommandHandler_ResolveUser(t *testing.T) {
+	team := entities.NewSettings("org/team")
+	team.SetSlackUserID("U123")
+	user := entities.NewSettings("alice")
+	user.SetSlackUserID("U123")
+	other := entities.NewSettings("bob")
+	other.SetSlackUserID("U999")
+
+	h := &SlackCommandHandler{settingsRepo: &listSettingsRepo{settings: []*entities.Settings{team, other, user}}}
+
+	got, err := h.resolveUser(context.Background(), "U123")
+	if err != nil {
+		t.Fatalf("resolveUser() error = %v", err)
+	}
+	if got != "alice" {
+		t.Errorf("expected alice, got %q", got)
+	}
+	if got, _ := h.resolveUser(context.Background(), "U000"); got != "" {
+		t.Errorf("expected no user for unlinked Slack account, got %q", got)
+	}
+}
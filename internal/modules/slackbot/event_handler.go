@@ -591,6 +591,13 @@ func (h *SlackBotEventHandler) getBotToken(ctx context.Context, bot *entities.Sl
 	if h.channelResolver == nil {
 		return "", fmt.Errorf("channel resolver is nil; cannot get bot token")
 	}
+	secretName, secretKey := h.botTokenSecret(bot)
+	return h.channelResolver.GetBotToken(ctx, secretName, secretKey)
+}
+
+// botTokenSecret returns the Secret name and key holding the bot token,
+// preferring the SlackBot's own settings over the defaults.
+func (h *SlackBotEventHandler) botTokenSecret(bot *entities.SlackBot) (string, string) {
 	secretName := h.defaultBotTokenSecretName
 	secretKey := h.defaultBotTokenSecretKey
 	if bot != nil {
@@ -601,7 +608,7 @@ func (h *SlackBotEventHandler) getBotToken(ctx context.Context, bot *entities.Sl
 			secretKey = bot.BotTokenSecretKey()
 		}
 	}
-	return h.channelResolver.GetBotToken(ctx, secretName, secretKey)
+	return secretName, secretKey
 }
 
 // postErrorToSlack posts an error message to the Slack thread where the triggering event occurred.
@@ -796,6 +803,8 @@ func (h *SlackBotEventHandler) postSessionURLToSlack(ctx context.Context, channe
 
 	if err := h.channelResolver.PostMessage(ctx, channel, threadTS, message, botToken); err != nil {
 		log.Printf("[SLACKBOT] Failed to post session URL to Slack thread: %v", err)
+		secretName, secretKey := h.botTokenSecret(bot)
+		h.channelResolver.QueuePostRetry(channel, threadTS, message, secretName, secretKey, err)
 		return
 	}
 
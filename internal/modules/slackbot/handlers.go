@@ -9,7 +9,8 @@ import (
 
 // Handlers provides SlackBot management REST API, implementing app.CustomHandler
 type Handlers struct {
-	controller *SlackBotController
+	controller     *SlackBotController
+	commandHandler *SlackCommandHandler
 }
 
 // NewHandlers creates a new SlackBot Handlers instance (management API only).
@@ -21,6 +22,12 @@ func NewHandlers(repo repositories.SlackBotRepository) *Handlers {
 	}
 }
 
+// WithCommandHandler enables the /slack/commands slash command endpoint.
+func (h *Handlers) WithCommandHandler(commandHandler *SlackCommandHandler) *Handlers {
+	h.commandHandler = commandHandler
+	return h
+}
+
 // GetName returns the name of this handler for logging
 func (h *Handlers) GetName() string {
 	return "SlackBotHandlers"
@@ -37,6 +44,12 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) error {
 	g.PUT("/:id", h.controller.UpdateSlackBot)
 	g.DELETE("/:id", h.controller.DeleteSlackBot)
 
+	// Slash commands are verified with the Slack signing secret instead of API auth
+	if h.commandHandler != nil {
+		e.POST("/slack/commands", h.commandHandler.HandleSlashCommand)
+		log.Printf("Registered Slack slash command route")
+	}
+
 	log.Printf("Registered SlackBot management routes (Socket Mode event handling active)")
 	return nil
 }
@@ -0,0 +1,96 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+)
+
+// SlackPostDeliveryKind is the delivery queue kind of Slack post retries
+const SlackPostDeliveryKind = "slack.post"
+
+// slackAPIError is an error reported by the Slack Web API in an ok=false response
+type slackAPIError struct {
+	Code string
+}
+
+func (e *slackAPIError) Error() string {
+	return fmt.Sprintf("slack API error: %s", e.Code)
+}
+
+// retryableSlackErrors are Slack API error codes that may succeed later.
+// Other codes (channel_not_found, not_in_channel, invalid_auth, ...) need
+// an operator to fix the setup and are dead-lettered on the first failure.
+var retryableSlackErrors = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// slackPostPayload is a Slack post persisted for retry. The bot token is
+// referenced by its Secret rather than stored, so that it is read again
+// when the post is retried.
+type slackPostPayload struct {
+	Channel    string `json:"channel"`
+	ThreadTS   string `json:"thread_ts,omitempty"`
+	Text       string `json:"text"`
+	SecretName string `json:"secret_name"`
+	SecretKey  string `json:"secret_key,omitempty"`
+}
+
+// WithDeliveryQueue sets the queue that retries failed posts queued with
+// QueuePostRetry.
+func (r *SlackChannelResolver) WithDeliveryQueue(queue *delivery.Queue) *SlackChannelResolver {
+	r.deliveryQueue = queue
+	if queue != nil {
+		queue.Register(SlackPostDeliveryKind, r.redeliverPost)
+	}
+	return r
+}
+
+// QueuePostRetry persists a post that failed with cause so that it is
+// retried later using the bot token in the given Secret. It is a no-op
+// without a delivery queue or when cause cannot be fixed by retrying.
+func (r *SlackChannelResolver) QueuePostRetry(channel, threadTS, text, secretName, secretKey string, cause error) {
+	if r.deliveryQueue == nil || secretName == "" {
+		return
+	}
+	if apiErr, ok := cause.(*slackAPIError); ok && !retryableSlackErrors[apiErr.Code] {
+		return
+	}
+	payload := slackPostPayload{
+		Channel:    channel,
+		ThreadTS:   threadTS,
+		Text:       text,
+		SecretName: secretName,
+		SecretKey:  secretKey,
+	}
+	d, err := r.deliveryQueue.Retry(context.Background(), SlackPostDeliveryKind, payload, cause)
+	if err != nil {
+		log.Printf("[CHANNEL_RESOLVER] Failed to queue Slack post retry: channel=%s, err=%v", channel, err)
+		return
+	}
+	log.Printf("[CHANNEL_RESOLVER] Queued Slack post retry %s: channel=%s thread=%s", d.ID, channel, threadTS)
+}
+
+// redeliverPost is the delivery queue handler for Slack post retries
+func (r *SlackChannelResolver) redeliverPost(ctx context.Context, raw json.RawMessage) error {
+	var p slackPostPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return delivery.Permanent(fmt.Errorf("invalid Slack post payload: %w", err))
+	}
+	botToken, err := r.GetBotToken(ctx, p.SecretName, p.SecretKey)
+	if err != nil {
+		return err
+	}
+	_, err = r.postMessage(ctx, p.Channel, p.ThreadTS, p.Text, botToken)
+	if apiErr, ok := err.(*slackAPIError); ok && !retryableSlackErrors[apiErr.Code] {
+		return delivery.Permanent(err)
+	}
+	return err
+}
@@ -4,15 +4,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/core/configrender"
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 	"github.com/takutakahashi/agentapi-proxy/internal/modules/webhook/infra"
 	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
 	"github.com/takutakahashi/agentapi-proxy/pkg/auth"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
 )
 
 // WebhookController handles webhook management HTTP requests
@@ -52,6 +55,8 @@ type CreateWebhookRequest struct {
 	Triggers        []TriggerRequest              `json:"triggers"`
 	SessionConfig   *SessionConfigRequest         `json:"session_config,omitempty"`
 	MaxSessions     int                           `json:"max_sessions,omitempty"`
+	Source          string                        `json:"source,omitempty"`
+	MaxPayloadBytes int64                         `json:"max_payload_bytes,omitempty"`
 }
 
 // GitHubConfigRequest represents GitHub-specific configuration in requests
@@ -93,14 +98,15 @@ type GitHubConditionsRequest struct {
 
 // SessionConfigRequest represents session configuration in requests
 type SessionConfigRequest struct {
-	Environment            map[string]string       `json:"environment,omitempty"`
-	Tags                   map[string]string       `json:"tags,omitempty"`
-	InitialMessageTemplate string                  `json:"initial_message_template,omitempty"`
-	ReuseMessageTemplate   string                  `json:"reuse_message_template,omitempty"`
-	Params                 *entities.SessionParams `json:"params,omitempty"`
-	ReuseSession           bool                    `json:"reuse_session,omitempty"`
-	MountPayload           bool                    `json:"mount_payload,omitempty"`
-	SessionProfileID       string                  `json:"session_profile_id,omitempty"`
+	Environment            map[string]string                `json:"environment,omitempty"`
+	Tags                   map[string]string                `json:"tags,omitempty"`
+	InitialMessageTemplate string                           `json:"initial_message_template,omitempty"`
+	ReuseMessageTemplate   string                           `json:"reuse_message_template,omitempty"`
+	Params                 *entities.SessionParams          `json:"params,omitempty"`
+	ReuseSession           bool                             `json:"reuse_session,omitempty"`
+	MountPayload           bool                             `json:"mount_payload,omitempty"`
+	SessionProfileID       string                           `json:"session_profile_id,omitempty"`
+	PayloadMappings        []entities.WebhookPayloadMapping `json:"payload_mappings,omitempty"`
 }
 
 // UpdateWebhookRequest represents the request body for updating a webhook
@@ -115,6 +121,8 @@ type UpdateWebhookRequest struct {
 	Triggers        []TriggerRequest               `json:"triggers,omitempty"`
 	SessionConfig   *SessionConfigRequest          `json:"session_config,omitempty"`
 	MaxSessions     *int                           `json:"max_sessions,omitempty"`
+	Source          *string                        `json:"source,omitempty"`
+	MaxPayloadBytes *int64                         `json:"max_payload_bytes,omitempty"`
 }
 
 // WebhookResponse represents the response for a webhook
@@ -135,6 +143,8 @@ type WebhookResponse struct {
 	Triggers        []TriggerResponse             `json:"triggers"`
 	SessionConfig   *SessionConfigResponse        `json:"session_config,omitempty"`
 	MaxSessions     int                           `json:"max_sessions"`
+	Source          string                        `json:"source,omitempty"`
+	MaxPayloadBytes int64                         `json:"max_payload_bytes,omitempty"`
 	CreatedAt       string                        `json:"created_at"`
 	UpdatedAt       string                        `json:"updated_at"`
 	LastDelivery    *DeliveryRecordResponse       `json:"last_delivery,omitempty"`
@@ -180,14 +190,15 @@ type GitHubConditionsResponse struct {
 
 // SessionConfigResponse represents session configuration in responses
 type SessionConfigResponse struct {
-	Environment            map[string]string      `json:"environment,omitempty"`
-	Tags                   map[string]string      `json:"tags,omitempty"`
-	InitialMessageTemplate string                 `json:"initial_message_template,omitempty"`
-	ReuseMessageTemplate   string                 `json:"reuse_message_template,omitempty"`
-	Params                 *SessionParamsResponse `json:"params,omitempty"`
-	ReuseSession           bool                   `json:"reuse_session,omitempty"`
-	MountPayload           bool                   `json:"mount_payload,omitempty"`
-	SessionProfileID       string                 `json:"session_profile_id,omitempty"`
+	Environment            map[string]string                `json:"environment,omitempty"`
+	Tags                   map[string]string                `json:"tags,omitempty"`
+	InitialMessageTemplate string                           `json:"initial_message_template,omitempty"`
+	ReuseMessageTemplate   string                           `json:"reuse_message_template,omitempty"`
+	Params                 *SessionParamsResponse           `json:"params,omitempty"`
+	ReuseSession           bool                             `json:"reuse_session,omitempty"`
+	MountPayload           bool                             `json:"mount_payload,omitempty"`
+	SessionProfileID       string                           `json:"session_profile_id,omitempty"`
+	PayloadMappings        []entities.WebhookPayloadMapping `json:"payload_mappings,omitempty"`
 }
 
 // SessionParamsResponse represents session params in responses
@@ -313,6 +324,8 @@ func (c *WebhookController) CreateWebhook(ctx echo.Context) error {
 	if req.MaxSessions > 0 {
 		webhook.SetMaxSessions(req.MaxSessions)
 	}
+	webhook.SetSource(req.Source)
+	webhook.SetMaxPayloadBytes(req.MaxPayloadBytes)
 
 	// Capture creator's team memberships for user-scoped webhooks so that the
 	// webhook handler can inject team-level settings without a live auth context.
@@ -325,6 +338,9 @@ func (c *WebhookController) CreateWebhook(ctx echo.Context) error {
 
 	// Set GitHub config
 	if req.GitHub != nil {
+		if err := validateEnterpriseURL(req.GitHub.EnterpriseURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
 		webhook.SetGitHub(c.requestToGitHubConfig(req.GitHub))
 	}
 
@@ -334,6 +350,10 @@ func (c *WebhookController) CreateWebhook(ctx echo.Context) error {
 		webhook.SetSessionConfig(c.requestToSessionConfig(req.SessionConfig))
 	}
 
+	if err := c.validateGenericSource(ctx, webhook); err != nil {
+		return err
+	}
+
 	if err := c.repo.Create(ctx.Request().Context(), webhook); err != nil {
 		log.Printf("Failed to create webhook: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create webhook")
@@ -502,7 +522,16 @@ func (c *WebhookController) UpdateWebhook(ctx echo.Context) error {
 	if req.MaxSessions != nil && *req.MaxSessions > 0 {
 		webhook.SetMaxSessions(*req.MaxSessions)
 	}
+	if req.Source != nil {
+		webhook.SetSource(*req.Source)
+	}
+	if req.MaxPayloadBytes != nil {
+		webhook.SetMaxPayloadBytes(*req.MaxPayloadBytes)
+	}
 	if req.GitHub != nil {
+		if err := validateEnterpriseURL(req.GitHub.EnterpriseURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
 		webhook.SetGitHub(c.requestToGitHubConfig(req.GitHub))
 	}
 	if req.Triggers != nil {
@@ -520,6 +549,10 @@ func (c *WebhookController) UpdateWebhook(ctx echo.Context) error {
 		}
 	}
 
+	if err := c.validateGenericSource(ctx, webhook); err != nil {
+		return err
+	}
+
 	if err := c.repo.Update(ctx.Request().Context(), webhook); err != nil {
 		log.Printf("Failed to update webhook %s: %v", id, err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update webhook")
@@ -611,6 +644,21 @@ func (c *WebhookController) requestToGitHubConfig(req *GitHubConfigRequest) *ent
 	return gh
 }
 
+// validateEnterpriseURL checks the GitHub Enterprise Server host or URL of a
+// GitHub webhook. Empty means github.com.
+func validateEnterpriseURL(enterpriseURL string) error {
+	if enterpriseURL == "" {
+		return nil
+	}
+	if !strings.Contains(enterpriseURL, "://") {
+		enterpriseURL = "https://" + enterpriseURL
+	}
+	if _, err := github_pkg.NewInstance(enterpriseURL, ""); err != nil {
+		return fmt.Errorf("github.enterprise_url: %w", err)
+	}
+	return nil
+}
+
 // requestToTriggers converts a slice of trigger requests to entities.
 func (c *WebhookController) requestToTriggers(reqs []TriggerRequest) []entities.WebhookTrigger {
 	triggers := make([]entities.WebhookTrigger, 0, len(reqs))
@@ -662,6 +710,7 @@ func (c *WebhookController) requestToSessionConfig(req *SessionConfigRequest) *e
 	config.SetReuseSession(req.ReuseSession)
 	config.SetMountPayload(req.MountPayload)
 	config.SetSessionProfileID(req.SessionProfileID)
+	config.SetPayloadMappings(req.PayloadMappings)
 	if req.Params != nil {
 		config.SetParams(req.Params)
 	}
@@ -684,6 +733,8 @@ func (c *WebhookController) toResponse(ctx echo.Context, w *entities.Webhook) We
 		SignaturePrefix: w.SignaturePrefix(),
 		WebhookURL:      c.getWebhookURL(ctx, w),
 		MaxSessions:     w.MaxSessions(),
+		Source:          w.Source(),
+		MaxPayloadBytes: w.MaxPayloadBytes(),
 		CreatedAt:       w.CreatedAt().Format(time.RFC3339),
 		UpdatedAt:       w.UpdatedAt().Format(time.RFC3339),
 		DeliveryCount:   w.DeliveryCount(),
@@ -766,6 +817,7 @@ func (c *WebhookController) sessionConfigToResponse(sc *entities.WebhookSessionC
 		ReuseSession:           sc.ReuseSession(),
 		MountPayload:           sc.MountPayload(),
 		SessionProfileID:       sc.SessionProfileID(),
+		PayloadMappings:        sc.PayloadMappings(),
 	}
 	// Exclude GithubToken from response since it is sensitive
 	if params := sc.Params(); params != nil {
@@ -778,6 +830,31 @@ func (c *WebhookController) sessionConfigToResponse(sc *entities.WebhookSessionC
 	return resp
 }
 
+// validateGenericSource checks the source name and payload limit of a webhook
+// and that no other webhook is registered under the same source.
+func (c *WebhookController) validateGenericSource(ctx echo.Context, webhook *entities.Webhook) error {
+	if webhook.Source() == "" && webhook.MaxPayloadBytes() == 0 {
+		return nil
+	}
+	if err := webhook.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if webhook.Source() == "" {
+		return nil
+	}
+	existing, err := c.repo.List(ctx.Request().Context(), repositories.WebhookFilter{Source: webhook.Source()})
+	if err != nil {
+		log.Printf("Failed to look up webhook source %s: %v", webhook.Source(), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to validate webhook source")
+	}
+	for _, w := range existing {
+		if w.ID() != webhook.ID() {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("source %q is already used by another webhook", webhook.Source()))
+		}
+	}
+	return nil
+}
+
 func (c *WebhookController) getWebhookURL(ctx echo.Context, w *entities.Webhook) string {
 	baseURL := c.baseURL
 	if baseURL == "" {
@@ -796,9 +873,11 @@ func (c *WebhookController) getWebhookURL(ctx echo.Context, w *entities.Webhook)
 		baseURL = fmt.Sprintf("%s://%s", scheme, host)
 	}
 
-	switch w.WebhookType() {
-	case entities.WebhookTypeGitHub:
+	switch {
+	case w.WebhookType() == entities.WebhookTypeGitHub:
 		return fmt.Sprintf("%s/hooks/github/%s", baseURL, w.ID())
+	case w.Source() != "":
+		return fmt.Sprintf("%s/webhooks/generic/%s", baseURL, w.Source())
 	default:
 		return fmt.Sprintf("%s/hooks/custom/%s", baseURL, w.ID())
 	}
@@ -846,6 +925,11 @@ func (c *WebhookController) validateTemplates(webhookType entities.WebhookType,
 			return fmt.Errorf("webhook session_config.initial_message_template: %w", err)
 		}
 	}
+	if sessionConfig != nil {
+		if err := validatePayloadMappings(sessionConfig.PayloadMappings); err != nil {
+			return fmt.Errorf("webhook session_config.%w", err)
+		}
+	}
 
 	for i, trigger := range triggers {
 		if trigger.Conditions.GoTemplate != "" {
@@ -859,11 +943,29 @@ func (c *WebhookController) validateTemplates(webhookType entities.WebhookType,
 				return fmt.Errorf("trigger[%d] (%s) session_config.initial_message_template: %w", i, trigger.Name, err)
 			}
 		}
+		if trigger.SessionConfig != nil {
+			if err := validatePayloadMappings(trigger.SessionConfig.PayloadMappings); err != nil {
+				return fmt.Errorf("trigger[%d] (%s) session_config.%w", i, trigger.Name, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// validatePayloadMappings checks the JSONPath and target of every payload mapping.
+func validatePayloadMappings(mappings []entities.WebhookPayloadMapping) error {
+	for i, m := range mappings {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("payload_mappings[%d]: %w", i, err)
+		}
+		if err := configrender.ValidateJSONPath(m.Path); err != nil {
+			return fmt.Errorf("payload_mappings[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
 // validateGoTemplateCondition validates a GoTemplate condition string
 func (c *WebhookController) validateGoTemplateCondition(webhookType entities.WebhookType, tmplStr string) error {
 	// Only validate template syntax, not execution with test payload
@@ -259,3 +259,37 @@ func TestValidateWebhookTemplates(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTemplates_PayloadMappings(t *testing.T) {
+	controller := &WebhookController{}
+
+	valid := &SessionConfigRequest{PayloadMappings: []entities.WebhookPayloadMapping{
+		{Path: "$.alert.labels.service", Target: "tags.service"},
+		{Path: "$.alert.id", Target: "env.ALERT_ID"},
+		{Path: "$.repo", Target: "repository"},
+	}}
+	if err := controller.validateTemplates(entities.WebhookTypeCustom, valid, nil); err != nil {
+		t.Fatalf("Expected valid payload mappings, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mapping entities.WebhookPayloadMapping
+	}{
+		{name: "unknown target", mapping: entities.WebhookPayloadMapping{Path: "$.a", Target: "labels.a"}},
+		{name: "empty tag key", mapping: entities.WebhookPayloadMapping{Path: "$.a", Target: "tags."}},
+		{name: "path without root", mapping: entities.WebhookPayloadMapping{Path: "a.b", Target: "tags.a"}},
+		{name: "wildcard", mapping: entities.WebhookPayloadMapping{Path: "$.items[*]", Target: "tags.a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			triggers := []TriggerRequest{{
+				Name:          "t",
+				SessionConfig: &SessionConfigRequest{PayloadMappings: []entities.WebhookPayloadMapping{tt.mapping}},
+			}}
+			if err := controller.validateTemplates(entities.WebhookTypeCustom, nil, triggers); err == nil {
+				t.Fatal("Expected validation error")
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ package webhook
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
@@ -24,6 +26,7 @@ type WebhookCustomController struct {
 	sessionManager      repositories.SessionManager
 	launcher            *sessionuc.LaunchUseCase
 	signatureVerifier   *infra.SignatureVerifier
+	jwtVerifier         *infra.JWTVerifier
 	gotemplateEvaluator *infra.GoTemplateEvaluator
 }
 
@@ -42,6 +45,7 @@ func NewWebhookCustomController(
 			WithMemoryRepository(memoryRepo).
 			WithSessionProfileRepository(sessionProfileRepo),
 		signatureVerifier:   infra.NewSignatureVerifier(),
+		jwtVerifier:         infra.NewJWTVerifier(),
 		gotemplateEvaluator: infra.NewGoTemplateEvaluator(),
 	}
 }
@@ -59,17 +63,6 @@ func (c *WebhookCustomController) HandleCustomWebhook(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing webhook ID"})
 	}
 
-	// Read the raw body for signature verification
-	body, err := io.ReadAll(ctx.Request().Body)
-	if err != nil {
-		log.Printf("[WEBHOOK_CUSTOM] Failed to read request body: %v", err)
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
-	}
-	ctx.Request().Body = io.NopCloser(bytes.NewBuffer(body))
-
-	log.Printf("[WEBHOOK_CUSTOM] Received custom webhook: webhook_id=%s, content_type=%s, body_size=%d",
-		webhookID, ctx.Request().Header.Get("Content-Type"), len(body))
-
 	matchedWebhook, err := c.repo.Get(ctx.Request().Context(), webhookID)
 	if err != nil {
 		log.Printf("[WEBHOOK_CUSTOM] Failed to get webhook %s: %v", webhookID, err)
@@ -82,10 +75,99 @@ func (c *WebhookCustomController) HandleCustomWebhook(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Webhook is not a custom webhook"})
 	}
 
-	// Verify signature
+	return c.processCustomWebhook(ctx, matchedWebhook, matchedWebhook.MaxPayloadBytes())
+}
+
+// HandleGenericWebhook handles POST /webhooks/generic/:source.
+// The custom webhook registered under the source name is authenticated with its
+// configured shared secret, HMAC signature or JWT and evaluated like
+// /hooks/custom/:id. Payloads are limited to DefaultGenericWebhookMaxPayloadBytes
+// unless the webhook sets max_payload_bytes. The API rejects duplicate
+// sources, but webhooks stored by other means may still share one; the
+// delivery then goes to the webhook whose signature it carries.
+func (c *WebhookCustomController) HandleGenericWebhook(ctx echo.Context) error {
+	source := ctx.Param("source")
+	if source == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing webhook source"})
+	}
+
+	webhooks, err := c.repo.List(ctx.Request().Context(), repositories.WebhookFilter{
+		Type:   entities.WebhookTypeCustom,
+		Source: source,
+	})
+	if err != nil {
+		log.Printf("[WEBHOOK_CUSTOM] Failed to look up webhook source %s: %v", source, err)
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to look up webhook"})
+	}
+	if len(webhooks) == 0 {
+		log.Printf("[WEBHOOK_CUSTOM] No webhook registered for source %s", source)
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+	}
+	if len(webhooks) == 1 {
+		return c.processCustomWebhook(ctx, webhooks[0], genericPayloadLimit(webhooks[0]))
+	}
+
+	log.Printf("[WEBHOOK_CUSTOM] %d webhooks share source %s; matching the delivery by signature", len(webhooks), source)
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID() < webhooks[j].ID() })
+	var limit int64
+	for _, w := range webhooks {
+		limit = max(limit, genericPayloadLimit(w))
+	}
+	body, err := readWebhookBody(ctx, limit)
+	if err != nil {
+		return webhookBodyError(ctx, source, limit, err)
+	}
+	for _, w := range webhooks {
+		if c.verifyWebhookSignature(ctx, body, w) != nil {
+			continue
+		}
+		if wLimit := genericPayloadLimit(w); int64(len(body)) > wLimit {
+			return webhookBodyError(ctx, w.ID(), wLimit, errPayloadTooLarge)
+		}
+		return c.handleVerifiedWebhook(ctx, w, body)
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, "Signature verification failed")
+}
+
+// genericPayloadLimit returns the payload limit of a webhook delivered to
+// /webhooks/generic/:source
+func genericPayloadLimit(w *entities.Webhook) int64 {
+	if limit := w.MaxPayloadBytes(); limit > 0 {
+		return limit
+	}
+	return entities.DefaultGenericWebhookMaxPayloadBytes
+}
+
+// processCustomWebhook reads and verifies a delivery for a custom webhook and
+// creates a session when one of its triggers matches. maxPayloadBytes <= 0
+// disables the payload size limit.
+func (c *WebhookCustomController) processCustomWebhook(ctx echo.Context, matchedWebhook *entities.Webhook, maxPayloadBytes int64) error {
+	body, err := readWebhookBody(ctx, maxPayloadBytes)
+	if err != nil {
+		return webhookBodyError(ctx, matchedWebhook.ID(), maxPayloadBytes, err)
+	}
 	if err := c.verifyWebhookSignature(ctx, body, matchedWebhook); err != nil {
 		return err
 	}
+	return c.handleVerifiedWebhook(ctx, matchedWebhook, body)
+}
+
+// webhookBodyError writes the response for a delivery whose body could not
+// be read
+func webhookBodyError(ctx echo.Context, target string, maxPayloadBytes int64, err error) error {
+	if errors.Is(err, errPayloadTooLarge) {
+		log.Printf("[WEBHOOK_CUSTOM] Payload for webhook %s exceeds %d bytes", target, maxPayloadBytes)
+		return ctx.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Payload too large"})
+	}
+	log.Printf("[WEBHOOK_CUSTOM] Failed to read request body: %v", err)
+	return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+}
+
+// handleVerifiedWebhook evaluates the triggers of a custom webhook against a
+// delivery whose signature has been verified
+func (c *WebhookCustomController) handleVerifiedWebhook(ctx echo.Context, matchedWebhook *entities.Webhook, body []byte) error {
+	log.Printf("[WEBHOOK_CUSTOM] Received custom webhook: webhook_id=%s, content_type=%s, body_size=%d",
+		matchedWebhook.ID(), ctx.Request().Header.Get("Content-Type"), len(body))
 
 	// Parse payload as JSON
 	var payload map[string]interface{}
@@ -115,6 +197,9 @@ func (c *WebhookCustomController) HandleCustomWebhook(ctx echo.Context) error {
 		"trigger_id":   matchResult.ID(),
 		"trigger_name": matchResult.Name(),
 	}
+	if matchedWebhook.Source() != "" {
+		tags["webhook_source"] = matchedWebhook.Source()
+	}
 
 	// Create or reuse session via shared service
 	sessionID, sessionReused, err := c.sessionService.CreateSessionFromWebhook(ctx.Request().Context(), SessionCreationParams{
@@ -148,21 +233,28 @@ func (c *WebhookCustomController) HandleCustomWebhook(ctx echo.Context) error {
 }
 
 // verifyWebhookSignature verifies the webhook signature based on the configured type.
-// Returns an echo error response if verification fails, or nil on success.
+// Returns a 401 *echo.HTTPError if verification fails, or nil on success.
 func (c *WebhookCustomController) verifyWebhookSignature(ctx echo.Context, body []byte, wh *entities.Webhook) error {
 	headerName := wh.SignatureHeader()
 	headerValue := ctx.Request().Header.Get(headerName)
 
 	if headerValue == "" {
 		log.Printf("[WEBHOOK_CUSTOM] Missing signature header '%s' for webhook %s", headerName, wh.ID())
-		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing signature header"})
+		return echo.NewHTTPError(http.StatusUnauthorized, "Missing signature header")
 	}
 
 	switch wh.SignatureType() {
+	case entities.WebhookSignatureTypeJWT:
+		if err := c.jwtVerifier.Verify(headerValue, wh.Secret()); err != nil {
+			log.Printf("[WEBHOOK_CUSTOM] JWT verification failed for webhook %s (header: %s): %v", wh.ID(), headerName, err)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Token verification failed")
+		}
+		log.Printf("[WEBHOOK_CUSTOM] JWT verified for webhook %s (%s)", wh.ID(), wh.Name())
+
 	case entities.WebhookSignatureTypeStatic:
 		if headerValue != wh.Secret() {
 			log.Printf("[WEBHOOK_CUSTOM] Token verification failed for webhook %s (header: %s)", wh.ID(), headerName)
-			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Token verification failed"})
+			return echo.NewHTTPError(http.StatusUnauthorized, "Token verification failed")
 		}
 		log.Printf("[WEBHOOK_CUSTOM] Static token verified for webhook %s (%s)", wh.ID(), wh.Name())
 
@@ -176,7 +268,7 @@ func (c *WebhookCustomController) verifyWebhookSignature(ctx echo.Context, body
 		}
 		if !c.signatureVerifier.Verify(body, headerValue, config) {
 			log.Printf("[WEBHOOK_CUSTOM] Signature verification failed for webhook %s (header: %s)", wh.ID(), headerName)
-			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Signature verification failed"})
+			return echo.NewHTTPError(http.StatusUnauthorized, "Signature verification failed")
 		}
 		log.Printf("[WEBHOOK_CUSTOM] HMAC signature verified for webhook %s (%s)", wh.ID(), wh.Name())
 	}
@@ -338,6 +430,31 @@ Please ensure the webhook payload is valid JSON.
 
 // Helper functions
 
+// errPayloadTooLarge is returned by readWebhookBody when the body exceeds the limit.
+var errPayloadTooLarge = errors.New("payload too large")
+
+// readWebhookBody reads the raw request body for signature verification and
+// restores it for later readers. maxBytes <= 0 disables the limit.
+func readWebhookBody(ctx echo.Context, maxBytes int64) ([]byte, error) {
+	req := ctx.Request()
+	var reader io.Reader = req.Body
+	if maxBytes > 0 {
+		if req.ContentLength > maxBytes {
+			return nil, errPayloadTooLarge
+		}
+		reader = io.LimitReader(req.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return nil, errPayloadTooLarge
+	}
+	req.Body = io.NopCloser(bytes.NewBuffer(body))
+	return body, nil
+}
+
 func detectAlgorithm(signatureHeader string) string {
 	switch {
 	case strings.Contains(signatureHeader, "sha1="):
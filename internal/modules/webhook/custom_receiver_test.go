@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// sourceWebhookRepo serves List lookups from a fixed set of webhooks.
+type sourceWebhookRepo struct {
+	repositories.WebhookRepository
+	webhooks  []*entities.Webhook
+	delivered []string
+}
+
+func (r *sourceWebhookRepo) List(_ context.Context, filter repositories.WebhookFilter) ([]*entities.Webhook, error) {
+	var result []*entities.Webhook
+	for _, w := range r.webhooks {
+		if filter.Source != "" && w.Source() != filter.Source {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+func (r *sourceWebhookRepo) RecordDelivery(_ context.Context, id string, _ *entities.WebhookDeliveryRecord) error {
+	r.delivered = append(r.delivered, id)
+	return nil
+}
+
+func TestHandleGenericWebhook(t *testing.T) {
+	wh := entities.NewWebhook("wh-1", "alerts", "user-1", entities.WebhookTypeCustom)
+	wh.SetSource("alerting")
+	wh.SetSecret("s3cret")
+	wh.SetSignatureType(entities.WebhookSignatureTypeJWT)
+	wh.SetSignatureHeader("Authorization")
+	wh.SetMaxPayloadBytes(16)
+	controller := &WebhookCustomController{repo: &sourceWebhookRepo{webhooks: []*entities.Webhook{wh}}}
+
+	tests := []struct {
+		name       string
+		source     string
+		body       string
+		header     string
+		wantStatus int
+	}{
+		{name: "Unknown source", source: "billing", body: `{}`, wantStatus: http.StatusNotFound},
+		{name: "Payload over limit", source: "alerting", body: `{"message":"this payload is too long"}`, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "Missing token", source: "alerting", body: `{}`, wantStatus: http.StatusUnauthorized},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/generic/"+tt.source, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("source")
+			ctx.SetParamValues(tt.source)
+
+			if err := controller.HandleGenericWebhook(ctx); err != nil {
+				e.HTTPErrorHandler(err, ctx)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleGenericWebhookSharedSource(t *testing.T) {
+	newWebhook := func(id, secret string) *entities.Webhook {
+		wh := entities.NewWebhook(id, id, "user-1", entities.WebhookTypeCustom)
+		wh.SetSource("alerting")
+		wh.SetSecret(secret)
+		wh.SetSignatureType(entities.WebhookSignatureTypeStatic)
+		wh.SetSignatureHeader("X-Token")
+		return wh
+	}
+	repo := &sourceWebhookRepo{webhooks: []*entities.Webhook{newWebhook("wh-1", "first"), newWebhook("wh-2", "second")}}
+	controller := &WebhookCustomController{repo: repo, sessionService: &WebhookSessionService{repo: repo}}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantStatus    int
+		wantDelivered []string
+	}{
+		{name: "Second webhook", token: "second", wantStatus: http.StatusOK, wantDelivered: []string{"wh-2"}},
+		{name: "First webhook", token: "first", wantStatus: http.StatusOK, wantDelivered: []string{"wh-1"}},
+		{name: "No webhook", token: "other", wantStatus: http.StatusUnauthorized},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.delivered = nil
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/generic/alerting", strings.NewReader(`{}`))
+			req.Header.Set("X-Token", tt.token)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("source")
+			ctx.SetParamValues("alerting")
+
+			if err := controller.HandleGenericWebhook(ctx); err != nil {
+				e.HTTPErrorHandler(err, ctx)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if strings.Join(repo.delivered, ",") != strings.Join(tt.wantDelivered, ",") {
+				t.Errorf("deliveries = %v, want %v", repo.delivered, tt.wantDelivered)
+			}
+		})
+	}
+}
@@ -52,6 +52,12 @@ type GitHubPayload struct {
 	// Issue specific
 	Issue *GitHubIssue `json:"issue,omitempty"`
 
+	// Comment specific (issue_comment, pull_request_review_comment)
+	Comment *GitHubComment `json:"comment,omitempty"`
+
+	// Label added or removed (labeled/unlabeled actions)
+	Label *GitHubLabel `json:"label,omitempty"`
+
 	// Push specific
 	Commits    []GitHubCommit `json:"commits,omitempty"`
 	HeadCommit *GitHubCommit  `json:"head_commit,omitempty"`
@@ -76,6 +82,7 @@ type GitHubUser struct {
 	ID        int64  `json:"id"`
 	AvatarURL string `json:"avatar_url,omitempty"`
 	HTMLURL   string `json:"html_url,omitempty"`
+	Type      string `json:"type,omitempty"` // "User", "Bot" or "Organization"
 }
 
 // GitHubPullRequest represents a GitHub pull request
@@ -110,6 +117,22 @@ type GitHubIssue struct {
 	HTMLURL string        `json:"html_url"`
 	User    *GitHubUser   `json:"user,omitempty"`
 	Labels  []GitHubLabel `json:"labels,omitempty"`
+	// PullRequest is set when the issue is a pull request
+	PullRequest *GitHubIssuePullRequest `json:"pull_request,omitempty"`
+}
+
+// GitHubIssuePullRequest links an issue to the pull request it represents
+type GitHubIssuePullRequest struct {
+	HTMLURL string `json:"html_url"`
+}
+
+// GitHubComment represents an issue, pull request or review comment
+type GitHubComment struct {
+	ID      int64       `json:"id"`
+	Body    string      `json:"body"`
+	HTMLURL string      `json:"html_url"`
+	User    *GitHubUser `json:"user,omitempty"`
+	Path    string      `json:"path,omitempty"` // review comments only
 }
 
 // GitHubLabel represents a GitHub label
@@ -468,7 +491,7 @@ func extractBranch(event string, payload *GitHubPayload) string {
 			return strings.TrimPrefix(payload.Ref, "refs/heads/")
 		}
 		return payload.Ref
-	case "pull_request":
+	case "pull_request", "pull_request_review", "pull_request_review_comment":
 		if payload.PullRequest != nil && payload.PullRequest.Head != nil {
 			return payload.PullRequest.Head.Ref
 		}
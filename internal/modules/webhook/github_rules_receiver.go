@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/modules/webhook/infra"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// GitHubRulesWebhookID identifies sessions created by the rule-based GitHub
+// receiver in tags and session limits
+const GitHubRulesWebhookID = "github-rules"
+
+// maxGitHubPayloadBytes is the largest payload GitHub sends (25 MB)
+const maxGitHubPayloadBytes = 25 << 20
+
+// GitHubRulesConfig configures the rule-based GitHub receiver
+type GitHubRulesConfig struct {
+	Secret       string
+	UserID       string
+	TeamID       string
+	Repositories []string
+	MaxSessions  int
+	Rules        []GitHubRule
+}
+
+// GitHubRule maps GitHub events to a session. Every non-empty condition must match.
+type GitHubRule struct {
+	Name                   string
+	Events                 []string
+	Actions                []string
+	Command                string
+	Labels                 []string
+	Branches               []string
+	BaseBranches           []string
+	Repositories           []string
+	Senders                []string
+	InitialMessageTemplate string
+	SessionProfileID       string
+	AgentType              string
+	Tags                   map[string]string
+	Environment            map[string]string
+	ReuseSession           bool
+}
+
+// WebhookGitHubRulesController handles POST /webhooks/github, creating
+// sessions from GitHub events according to rules in the proxy config
+type WebhookGitHubRulesController struct {
+	config            GitHubRulesConfig
+	webhook           *entities.Webhook
+	github            *WebhookGitHubController
+	sessionService    *WebhookSessionService
+	signatureVerifier *infra.SignatureVerifier
+}
+
+// NewWebhookGitHubRulesController creates a new rule-based GitHub receiver.
+// Each rule becomes a trigger of a webhook that exists only in memory, so
+// that matching and session creation are shared with API-managed webhooks.
+func NewWebhookGitHubRulesController(cfg GitHubRulesConfig, repo repositories.WebhookRepository, sessionManager repositories.SessionManager, memoryRepo repositories.MemoryRepository, sessionProfileRepo repositories.SessionProfileRepository) (*WebhookGitHubRulesController, error) {
+	if cfg.Secret == "" {
+		return nil, errors.New("webhook.github.secret is required")
+	}
+	if cfg.UserID == "" {
+		return nil, errors.New("webhook.github.user_id is required")
+	}
+
+	wh := entities.NewWebhook(GitHubRulesWebhookID, "GitHub rules", cfg.UserID, entities.WebhookTypeGitHub)
+	if cfg.TeamID != "" {
+		wh.SetOwnership(entities.ScopeTeam, cfg.UserID, cfg.TeamID)
+	}
+	wh.SetSecret(cfg.Secret)
+	wh.SetMaxSessions(cfg.MaxSessions)
+	for i, rule := range cfg.Rules {
+		if len(rule.Events) == 0 {
+			return nil, fmt.Errorf("webhook.github.rules[%d]: events is required", i)
+		}
+		if rule.Name == "" {
+			cfg.Rules[i].Name = "rule-" + strconv.Itoa(i+1)
+		}
+		wh.AddTrigger(ruleTrigger(i, cfg.Rules[i]))
+	}
+
+	return &WebhookGitHubRulesController{
+		config:            cfg,
+		webhook:           wh,
+		github:            NewWebhookGitHubController(repo, sessionManager, memoryRepo, sessionProfileRepo),
+		sessionService:    NewWebhookSessionService(repo, sessionManager, memoryRepo, sessionProfileRepo),
+		signatureVerifier: infra.NewSignatureVerifier(),
+	}, nil
+}
+
+// ruleTrigger converts a rule into a trigger. Labels and Command are not
+// trigger conditions and are checked by matchRule.
+func ruleTrigger(index int, rule GitHubRule) entities.WebhookTrigger {
+	trigger := entities.NewWebhookTrigger(strconv.Itoa(index), rule.Name)
+	trigger.SetPriority(index)
+
+	cond := entities.NewWebhookGitHubConditions()
+	cond.SetEvents(rule.Events)
+	actions := rule.Actions
+	if len(actions) == 0 && rule.Command != "" {
+		actions = []string{"created"}
+	}
+	cond.SetActions(actions)
+	cond.SetBranches(rule.Branches)
+	cond.SetBaseBranches(rule.BaseBranches)
+	cond.SetRepositories(rule.Repositories)
+	cond.SetSender(rule.Senders)
+	var conditions entities.WebhookTriggerConditions
+	conditions.SetGitHub(cond)
+	trigger.SetConditions(conditions)
+
+	sc := entities.NewWebhookSessionConfig()
+	sc.SetInitialMessageTemplate(rule.InitialMessageTemplate)
+	sc.SetSessionProfileID(rule.SessionProfileID)
+	sc.SetTags(rule.Tags)
+	sc.SetEnvironment(rule.Environment)
+	sc.SetReuseSession(rule.ReuseSession)
+	sc.SetMountPayload(true)
+	if rule.AgentType != "" {
+		sc.SetParams(&entities.SessionParams{AgentType: rule.AgentType})
+	}
+	trigger.SetSessionConfig(sc)
+	return trigger
+}
+
+// GetName returns the name of this controller for logging
+func (c *WebhookGitHubRulesController) GetName() string {
+	return "WebhookGitHubRulesController"
+}
+
+// SetSessionReporter reports the sessions created by the rules through reporter
+func (c *WebhookGitHubRulesController) SetSessionReporter(reporter SessionReporter) {
+	c.sessionService.SetSessionReporter(reporter)
+}
+
+// HandleGitHubRulesWebhook handles POST /webhooks/github
+func (c *WebhookGitHubRulesController) HandleGitHubRulesWebhook(ctx echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxGitHubPayloadBytes+1))
+	if err != nil {
+		log.Printf("[WEBHOOK_GITHUB_RULES] Failed to read request body: %v", err)
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+	}
+	if len(body) > maxGitHubPayloadBytes {
+		return ctx.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Payload too large"})
+	}
+
+	event := ctx.Request().Header.Get("X-GitHub-Event")
+	deliveryID := ctx.Request().Header.Get("X-GitHub-Delivery")
+	if event == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Missing required header"})
+	}
+
+	if !c.signatureVerifier.VerifyGitHubSignature(body, ctx.Request().Header.Get("X-Hub-Signature-256"), c.config.Secret) {
+		log.Printf("[WEBHOOK_GITHUB_RULES] Signature verification failed: event=%s, delivery=%s", event, deliveryID)
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Signature verification failed"})
+	}
+
+	if event == "ping" {
+		return ctx.JSON(http.StatusOK, map[string]string{"message": "pong"})
+	}
+
+	var payload GitHubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON payload"})
+	}
+	if err := json.Unmarshal(body, &payload.Raw); err != nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid JSON payload"})
+	}
+	if payload.Repository == nil {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Payload missing repository information"})
+	}
+
+	log.Printf("[WEBHOOK_GITHUB_RULES] Received event=%s, action=%s, repository=%s, delivery=%s",
+		event, payload.Action, payload.Repository.FullName, deliveryID)
+
+	if len(c.config.Repositories) > 0 && !matchAnyRepository(c.config.Repositories, payload.Repository.FullName) {
+		log.Printf("[WEBHOOK_GITHUB_RULES] Repository %s is not in the allowlist", payload.Repository.FullName)
+		return ctx.JSON(http.StatusOK, map[string]string{"message": "Repository not allowed"})
+	}
+
+	trigger, rule, command := c.matchRules(event, &payload)
+	if trigger == nil {
+		return ctx.JSON(http.StatusOK, map[string]string{"message": "No matching rule"})
+	}
+
+	log.Printf("[WEBHOOK_GITHUB_RULES] Rule matched: %s", rule.Name)
+
+	sessionID, sessionReused, err := c.sessionService.CreateSessionFromWebhook(ctx.Request().Context(), SessionCreationParams{
+		Webhook:        c.webhook,
+		Trigger:        trigger,
+		Payload:        payload.Raw,
+		RawPayload:     body,
+		Tags:           c.buildTags(rule, event, &payload),
+		DefaultMessage: c.buildDefaultMessage(event, &payload, command),
+	})
+	if err != nil {
+		log.Printf("[WEBHOOK_GITHUB_RULES] Failed to create session for rule %s: %v", rule.Name, err)
+		if IsSessionLimitError(err) {
+			return ctx.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		}
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create session"})
+	}
+
+	log.Printf("[WEBHOOK_GITHUB_RULES] Session %s (reused=%t) for rule %s", sessionID, sessionReused, rule.Name)
+	return ctx.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Session created",
+		"session_id":     sessionID,
+		"session_reused": sessionReused,
+		"rule":           rule.Name,
+	})
+}
+
+// matchRules returns the trigger and rule of the first matching rule, and
+// the command comment that matched it, if any
+func (c *WebhookGitHubRulesController) matchRules(event string, payload *GitHubPayload) (*entities.WebhookTrigger, *GitHubRule, string) {
+	triggers := c.webhook.Triggers()
+	for i := range triggers {
+		rule := &c.config.Rules[i]
+		if !c.github.matchTrigger(&triggers[i], event, payload) {
+			continue
+		}
+		if !matchRuleLabels(rule.Labels, payload) {
+			continue
+		}
+		command := ""
+		if rule.Command != "" {
+			var ok bool
+			if command, ok = matchCommand(rule.Command, payload); !ok {
+				continue
+			}
+		}
+		return &triggers[i], rule, command
+	}
+	return nil, nil, ""
+}
+
+// matchRuleLabels checks the label just added for labeled events and the
+// labels of the issue or pull request otherwise
+func matchRuleLabels(labels []string, payload *GitHubPayload) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	if payload.Action == "labeled" && payload.Label != nil {
+		return containsString(labels, payload.Label.Name)
+	}
+	return anyStringInSlice(labels, extractLabels(payload))
+}
+
+// matchCommand reports whether the comment's first line starts with command
+// as a whole word and returns that line. Comments from bots never match, so
+// that an agent replying on the thread cannot trigger itself.
+func matchCommand(command string, payload *GitHubPayload) (string, bool) {
+	if payload.Comment == nil {
+		return "", false
+	}
+	if payload.Sender != nil && payload.Sender.Type == "Bot" {
+		return "", false
+	}
+	line := strings.TrimSpace(payload.Comment.Body)
+	if i := strings.IndexAny(line, "\r\n"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if !strings.HasPrefix(line, command) {
+		return "", false
+	}
+	if rest := line[len(command):]; rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", false
+	}
+	return line, true
+}
+
+// buildTags returns the metadata tags of a session created by rule
+func (c *WebhookGitHubRulesController) buildTags(rule *GitHubRule, event string, payload *GitHubPayload) map[string]string {
+	tags := map[string]string{
+		"webhook_id":   GitHubRulesWebhookID,
+		"webhook_name": c.webhook.Name(),
+		"trigger_id":   GitHubRulesWebhookID + "/" + rule.Name,
+		"trigger_name": rule.Name,
+		"github_event": event,
+		"repository":   payload.Repository.FullName,
+	}
+	if payload.Action != "" {
+		tags["github_action"] = payload.Action
+	}
+	if branch := extractBranch(event, payload); branch != "" {
+		tags["branch"] = branch
+	}
+	switch {
+	case payload.PullRequest != nil:
+		tags["pr"] = strconv.Itoa(payload.PullRequest.Number)
+	case payload.Issue != nil && payload.Issue.PullRequest != nil:
+		tags["pr"] = strconv.Itoa(payload.Issue.Number)
+	case payload.Issue != nil:
+		tags["issue"] = strconv.Itoa(payload.Issue.Number)
+	}
+	return tags
+}
+
+// buildDefaultMessage extends the default GitHub message with the comment
+// that triggered the session
+func (c *WebhookGitHubRulesController) buildDefaultMessage(event string, payload *GitHubPayload, command string) string {
+	msg := c.github.buildDefaultInitialMessage(event, payload)
+	if payload.Comment == nil {
+		return msg
+	}
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+	if payload.Issue != nil {
+		fmt.Fprintf(&sb, "\nIssue #%d: %s\nURL: %s\n", payload.Issue.Number, payload.Issue.Title, payload.Issue.HTMLURL)
+	}
+	if payload.PullRequest != nil && event != "pull_request" {
+		fmt.Fprintf(&sb, "\nPull Request #%d: %s\nURL: %s\n", payload.PullRequest.Number, payload.PullRequest.Title, payload.PullRequest.HTMLURL)
+	}
+	if payload.Comment.Path != "" {
+		fmt.Fprintf(&sb, "File: %s\n", payload.Comment.Path)
+	}
+	if command != "" {
+		fmt.Fprintf(&sb, "\nCommand: %s\n", command)
+	}
+	fmt.Fprintf(&sb, "\nComment (%s):\n%s\n", payload.Comment.HTMLURL, payload.Comment.Body)
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newTestRulesController(t *testing.T, rules ...GitHubRule) *WebhookGitHubRulesController {
+	t.Helper()
+	c, err := NewWebhookGitHubRulesController(GitHubRulesConfig{
+		Secret:       "s3cret",
+		UserID:       "user-1",
+		Repositories: []string{"acme/*"},
+		Rules:        rules,
+	}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewWebhookGitHubRulesController() error = %v", err)
+	}
+	return c
+}
+
+func parseGitHubPayload(t *testing.T, body string) *GitHubPayload {
+	t.Helper()
+	var p GitHubPayload
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	_ = json.Unmarshal([]byte(body), &p.Raw)
+	return &p
+}
+
+func TestGitHubRulesMatch(t *testing.T) {
+	c := newTestRulesController(t,
+		GitHubRule{Name: "fix-command", Events: []string{"issue_comment"}, Command: "/agent"},
+		GitHubRule{Name: "labeled", Events: []string{"issues"}, Actions: []string{"labeled"}, Labels: []string{"agent"}},
+		GitHubRule{Name: "review", Events: []string{"pull_request_review_comment"}, Command: "/agent", Branches: []string{"feature/*"}},
+	)
+
+	tests := []struct {
+		name        string
+		event       string
+		body        string
+		wantRule    string
+		wantCommand string
+	}{
+		{
+			name:        "Issue comment command",
+			event:       "issue_comment",
+			body:        `{"action":"created","repository":{"full_name":"acme/app"},"issue":{"number":3},"comment":{"body":"/agent fix the test\nthanks"},"sender":{"login":"alice","type":"User"}}`,
+			wantRule:    "fix-command",
+			wantCommand: "/agent fix the test",
+		},
+		{
+			name:  "Command prefix must be a whole word",
+			event: "issue_comment",
+			body:  `{"action":"created","repository":{"full_name":"acme/app"},"comment":{"body":"/agents are great"},"sender":{"login":"alice"}}`,
+		},
+		{
+			name:  "Bot comments are ignored",
+			event: "issue_comment",
+			body:  `{"action":"created","repository":{"full_name":"acme/app"},"comment":{"body":"/agent fix"},"sender":{"login":"agent[bot]","type":"Bot"}}`,
+		},
+		{
+			name:  "Edited comments are ignored by default",
+			event: "issue_comment",
+			body:  `{"action":"edited","repository":{"full_name":"acme/app"},"comment":{"body":"/agent fix"},"sender":{"login":"alice"}}`,
+		},
+		{
+			name:     "Label just added",
+			event:    "issues",
+			body:     `{"action":"labeled","repository":{"full_name":"acme/app"},"issue":{"number":4,"labels":[{"name":"bug"},{"name":"agent"}]},"label":{"name":"agent"}}`,
+			wantRule: "labeled",
+		},
+		{
+			name:  "Other label added to an already labeled issue",
+			event: "issues",
+			body:  `{"action":"labeled","repository":{"full_name":"acme/app"},"issue":{"number":4,"labels":[{"name":"bug"},{"name":"agent"}]},"label":{"name":"bug"}}`,
+		},
+		{
+			name:        "Review comment on matching branch",
+			event:       "pull_request_review_comment",
+			body:        `{"action":"created","repository":{"full_name":"acme/app"},"pull_request":{"number":9,"head":{"ref":"feature/x"}},"comment":{"body":"/agent address this"},"sender":{"login":"bob"}}`,
+			wantRule:    "review",
+			wantCommand: "/agent address this",
+		},
+		{
+			name:  "Review comment on other branch",
+			event: "pull_request_review_comment",
+			body:  `{"action":"created","repository":{"full_name":"acme/app"},"pull_request":{"number":9,"head":{"ref":"main"}},"comment":{"body":"/agent address this"},"sender":{"login":"bob"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger, rule, command := c.matchRules(tt.event, parseGitHubPayload(t, tt.body))
+			if tt.wantRule == "" {
+				if trigger != nil {
+					t.Fatalf("expected no match, got rule %s", rule.Name)
+				}
+				return
+			}
+			if trigger == nil {
+				t.Fatalf("expected rule %s to match", tt.wantRule)
+			}
+			if rule.Name != tt.wantRule || command != tt.wantCommand {
+				t.Errorf("matched rule=%q command=%q, want rule=%q command=%q", rule.Name, command, tt.wantRule, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestGitHubRulesTags(t *testing.T) {
+	c := newTestRulesController(t, GitHubRule{Name: "fix", Events: []string{"issue_comment"}, Command: "/agent"})
+	payload := parseGitHubPayload(t, `{"action":"created","repository":{"full_name":"acme/app"},"issue":{"number":12,"pull_request":{"html_url":"https://github.com/acme/app/pull/12"}},"comment":{"body":"/agent fix"}}`)
+
+	tags := c.buildTags(&c.config.Rules[0], "issue_comment", payload)
+	if tags["repository"] != "acme/app" || tags["pr"] != "12" || tags["webhook_id"] != GitHubRulesWebhookID || tags["trigger_name"] != "fix" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if _, ok := tags["issue"]; ok {
+		t.Errorf("pull request comment tagged as issue: %v", tags)
+	}
+}
+
+func TestHandleGitHubRulesWebhook_Rejects(t *testing.T) {
+	c := newTestRulesController(t, GitHubRule{Name: "fix", Events: []string{"issue_comment"}, Command: "/agent"})
+	e := echo.New()
+
+	tests := []struct {
+		name       string
+		event      string
+		signature  string
+		wantStatus int
+	}{
+		{name: "Missing event header", signature: "sha256=00", wantStatus: http.StatusBadRequest},
+		{name: "Invalid signature", event: "issue_comment", signature: "sha256=00", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(`{}`))
+			if tt.event != "" {
+				req.Header.Set("X-GitHub-Event", tt.event)
+			}
+			req.Header.Set("X-Hub-Signature-256", tt.signature)
+			rec := httptest.NewRecorder()
+
+			if err := c.HandleGitHubRulesWebhook(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("HandleGitHubRulesWebhook() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewWebhookGitHubRulesController_Validation(t *testing.T) {
+	if _, err := NewWebhookGitHubRulesController(GitHubRulesConfig{UserID: "u"}, nil, nil, nil, nil); err == nil {
+		t.Error("expected error without secret")
+	}
+	if _, err := NewWebhookGitHubRulesController(GitHubRulesConfig{Secret: "s", UserID: "u", Rules: []GitHubRule{{Name: "x"}}}, nil, nil, nil, nil); err == nil {
+		t.Error("expected error for rule without events")
+	}
+}
@@ -16,6 +16,8 @@ type Handlers struct {
 	controller       *WebhookController
 	githubController *WebhookGitHubController
 	customController *WebhookCustomController
+	rulesController  *WebhookGitHubRulesController
+	reporter         SessionReporter
 }
 
 // NewHandlers creates a new Handlers instance
@@ -31,6 +33,24 @@ func NewHandlers(repo repositories.WebhookRepository, sessionManager repositorie
 	}
 }
 
+// WithGitHubRules enables the rule-based GitHub receiver at /webhooks/github
+func (h *Handlers) WithGitHubRules(rulesController *WebhookGitHubRulesController) *Handlers {
+	h.rulesController = rulesController
+	h.rulesController.SetSessionReporter(h.reporter)
+	return h
+}
+
+// WithSessionReporter reports the sessions created from GitHub webhooks on
+// their repository
+func (h *Handlers) WithSessionReporter(reporter SessionReporter) *Handlers {
+	h.reporter = reporter
+	h.githubController.SessionService().SetSessionReporter(reporter)
+	if h.rulesController != nil {
+		h.rulesController.SetSessionReporter(reporter)
+	}
+	return h
+}
+
 // GetName returns the name of this handler for logging
 func (h *Handlers) GetName() string {
 	return "WebhookHandlers"
@@ -48,6 +68,14 @@ func (h *Handlers) RegisterRoutes(e *echo.Echo) error {
 	g.POST("/:id/regenerate-secret", h.controller.RegenerateSecret)
 	g.POST("/:id/trigger", h.TriggerWebhook)
 
+	// Generic receiver: authenticated by the webhook's own secret, not by API auth
+	g.POST("/generic/:source", h.customController.HandleGenericWebhook)
+
+	// Rule-based GitHub receiver: authenticated by the configured webhook secret
+	if h.rulesController != nil {
+		g.POST("/github", h.rulesController.HandleGitHubRulesWebhook)
+	}
+
 	// Receiver endpoints
 	hooks := e.Group("/hooks")
 	hooks.POST("/github/:id", h.githubController.HandleGitHubWebhook)
@@ -0,0 +1,45 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWTVerifier verifies HMAC-signed JSON Web Tokens sent by webhook sources
+type JWTVerifier struct{}
+
+// NewJWTVerifier creates a new JWTVerifier
+func NewJWTVerifier() *JWTVerifier {
+	return &JWTVerifier{}
+}
+
+// Verify checks that the token is signed with the secret using HS256, HS384 or
+// HS512 and that it carries an exp claim that has not passed. A "Bearer "
+// prefix is stripped from the header value. nbf and iat are honoured when set.
+func (v *JWTVerifier) Verify(headerValue, secret string) error {
+	if secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+	tokenString := strings.TrimSpace(headerValue)
+	if len(tokenString) > 7 && strings.EqualFold(tokenString[:7], "Bearer ") {
+		tokenString = strings.TrimSpace(tokenString[7:])
+	}
+	if tokenString == "" {
+		return errors.New("token is empty")
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	if _, err := parser.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}); err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	if !claims.VerifyExpiresAt(jwt.TimeFunc().Unix(), true) {
+		return errors.New("token has no exp claim")
+	}
+	return nil
+}
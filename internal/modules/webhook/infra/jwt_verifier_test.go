@@ -0,0 +1,82 @@
+package infra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func signTestJWT(t *testing.T, method jwt.SigningMethod, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	verifier := NewJWTVerifier()
+	valid := jwt.MapClaims{"iss": "alerting", "exp": time.Now().Add(time.Minute).Unix()}
+
+	tests := []struct {
+		name    string
+		header  string
+		secret  string
+		wantErr bool
+	}{
+		{
+			name:   "Valid bearer token",
+			header: "Bearer " + signTestJWT(t, jwt.SigningMethodHS256, "s3cret", valid),
+			secret: "s3cret",
+		},
+		{
+			name:   "Valid raw HS512 token",
+			header: signTestJWT(t, jwt.SigningMethodHS512, "s3cret", valid),
+			secret: "s3cret",
+		},
+		{
+			name:    "Wrong secret",
+			header:  signTestJWT(t, jwt.SigningMethodHS256, "other", valid),
+			secret:  "s3cret",
+			wantErr: true,
+		},
+		{
+			name:    "Expired token",
+			header:  signTestJWT(t, jwt.SigningMethodHS256, "s3cret", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+			secret:  "s3cret",
+			wantErr: true,
+		},
+		{
+			name:    "Missing exp claim",
+			header:  signTestJWT(t, jwt.SigningMethodHS256, "s3cret", jwt.MapClaims{"iss": "alerting"}),
+			secret:  "s3cret",
+			wantErr: true,
+		},
+		{
+			name: "Unsigned token",
+			header: func() string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return token
+			}(),
+			secret:  "s3cret",
+			wantErr: true,
+		},
+		{
+			name:    "Empty header",
+			header:  "Bearer ",
+			secret:  "s3cret",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(tt.header, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
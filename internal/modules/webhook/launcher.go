@@ -21,6 +21,15 @@ type WebhookSessionService struct {
 	repo           repositories.WebhookRepository
 	sessionManager repositories.SessionManager
 	launcher       *sessionuc.LaunchUseCase
+	reporter       SessionReporter
+}
+
+// SessionReporter reports the work of sessions created from GitHub webhooks
+// on their repository, see githubreport.Reporter
+type SessionReporter interface {
+	// Start prepares the repository for a new session and points req at the
+	// working branch
+	Start(ctx context.Context, sessionID string, req *entities.RunServerRequest) error
 }
 
 // NewWebhookSessionService creates a new WebhookSessionService.
@@ -34,6 +43,11 @@ func NewWebhookSessionService(repo repositories.WebhookRepository, sessionManage
 	}
 }
 
+// SetSessionReporter reports new sessions of GitHub webhooks through reporter
+func (s *WebhookSessionService) SetSessionReporter(reporter SessionReporter) {
+	s.reporter = reporter
+}
+
 // SessionCreationParams holds all parameters needed to create a session from a webhook delivery.
 type SessionCreationParams struct {
 	Webhook        *entities.Webhook
@@ -56,14 +70,20 @@ func (s *WebhookSessionService) CreateSessionFromWebhook(ctx context.Context, pa
 
 	sessionConfig := configrender.MergeSessionConfigs(webhook.SessionConfig(), trigger.SessionConfig())
 
-	env, err := s.renderConfigMap(sessionConfig, params.Payload, func(sc *entities.WebhookSessionConfig) map[string]string {
+	mapped, err := s.applyPayloadMappings(sessionConfig, params.Payload)
+	if err != nil {
+		return "", false, err
+	}
+	templateData := mapped.TemplateData(params.Payload)
+
+	env, err := s.renderConfigMap(sessionConfig, templateData, func(sc *entities.WebhookSessionConfig) map[string]string {
 		return sc.Environment()
 	})
 	if err != nil {
 		return "", false, fmt.Errorf("failed to render environment variables: %w", err)
 	}
 
-	tags, err := s.renderConfigMap(sessionConfig, params.Payload, func(sc *entities.WebhookSessionConfig) map[string]string {
+	tags, err := s.renderConfigMap(sessionConfig, templateData, func(sc *entities.WebhookSessionConfig) map[string]string {
 		return sc.Tags()
 	})
 	if err != nil {
@@ -74,14 +94,15 @@ func (s *WebhookSessionService) CreateSessionFromWebhook(ctx context.Context, pa
 	for k, v := range params.Tags {
 		tags[k] = v
 	}
+	mapped.MergeInto(tags, env)
 
 	// Render session params with template evaluation
-	renderedParams, err := configrender.RenderSessionParams(sessionConfig, params.Payload)
+	renderedParams, err := configrender.RenderSessionParams(sessionConfig, templateData)
 	if err != nil {
 		return "", false, fmt.Errorf("failed to render session params: %w", err)
 	}
 
-	initialMessage, err := s.determineInitialMessage(sessionConfig, renderedParams, params.Payload, params.DefaultMessage)
+	initialMessage, err := s.determineInitialMessage(sessionConfig, renderedParams, templateData, params.DefaultMessage)
 	if err != nil {
 		return "", false, err
 	}
@@ -133,7 +154,7 @@ func (s *WebhookSessionService) CreateSessionFromWebhook(ctx context.Context, pa
 	// Resolve the reuse message (for existing-session route)
 	var reuseMessage string
 	if sessionConfig != nil && sessionConfig.ReuseMessageTemplate() != "" {
-		if rendered, renderErr := configrender.RenderTemplate(sessionConfig.ReuseMessageTemplate(), params.Payload); renderErr == nil {
+		if rendered, renderErr := configrender.RenderTemplate(sessionConfig.ReuseMessageTemplate(), templateData); renderErr == nil {
 			reuseMessage = rendered
 		} else {
 			log.Printf("[WEBHOOK] Failed to render reuse message template: %v", renderErr)
@@ -174,6 +195,7 @@ func (s *WebhookSessionService) CreateSessionFromWebhook(ctx context.Context, pa
 		StopBeforeReuse:          true,
 		MaxSessions:              webhook.MaxSessions(),
 		LimitMatchTags:           map[string]string{"webhook_id": webhook.ID()},
+		BeforeCreate:             s.reportSession(webhook),
 	})
 	if err != nil {
 		return "", false, fmt.Errorf("failed to create session: %w", err)
@@ -182,6 +204,21 @@ func (s *WebhookSessionService) CreateSessionFromWebhook(ctx context.Context, pa
 	return result.SessionID, result.SessionReused, nil
 }
 
+// reportSession returns the hook that starts the GitHub report of a new
+// session of a GitHub webhook, or nil. Reporting failures are logged and do
+// not prevent the session from starting.
+func (s *WebhookSessionService) reportSession(webhook *entities.Webhook) func(context.Context, string, *entities.RunServerRequest) error {
+	if s.reporter == nil || webhook.WebhookType() != entities.WebhookTypeGitHub {
+		return nil
+	}
+	return func(ctx context.Context, sessionID string, req *entities.RunServerRequest) error {
+		if err := s.reporter.Start(ctx, sessionID, req); err != nil {
+			log.Printf("[WEBHOOK] Failed to start GitHub report of session %s: %v", sessionID, err)
+		}
+		return nil
+	}
+}
+
 // RecordDelivery records a webhook delivery event.
 func (s *WebhookSessionService) RecordDelivery(ctx context.Context, webhookID, deliveryID string, status entities.DeliveryStatus, trigger *entities.WebhookTrigger, sessionID string, sessionReused bool, deliveryErr error) {
 	record := entities.NewWebhookDeliveryRecord(deliveryID, status)
@@ -231,14 +268,20 @@ func (s *WebhookSessionService) DryRunSessionConfig(params SessionCreationParams
 
 	sessionConfig := configrender.MergeSessionConfigs(webhook.SessionConfig(), trigger.SessionConfig())
 
-	env, err := s.renderConfigMap(sessionConfig, params.Payload, func(sc *entities.WebhookSessionConfig) map[string]string {
+	mapped, err := s.applyPayloadMappings(sessionConfig, params.Payload)
+	if err != nil {
+		return &DryRunResult{Error: err.Error()}, nil
+	}
+	templateData := mapped.TemplateData(params.Payload)
+
+	env, err := s.renderConfigMap(sessionConfig, templateData, func(sc *entities.WebhookSessionConfig) map[string]string {
 		return sc.Environment()
 	})
 	if err != nil {
 		return &DryRunResult{Error: fmt.Sprintf("failed to render environment variables: %v", err)}, nil
 	}
 
-	tags, err := s.renderConfigMap(sessionConfig, params.Payload, func(sc *entities.WebhookSessionConfig) map[string]string {
+	tags, err := s.renderConfigMap(sessionConfig, templateData, func(sc *entities.WebhookSessionConfig) map[string]string {
 		return sc.Tags()
 	})
 	if err != nil {
@@ -249,13 +292,14 @@ func (s *WebhookSessionService) DryRunSessionConfig(params SessionCreationParams
 	for k, v := range params.Tags {
 		tags[k] = v
 	}
+	mapped.MergeInto(tags, env)
 
-	renderedParams, err := configrender.RenderSessionParams(sessionConfig, params.Payload)
+	renderedParams, err := configrender.RenderSessionParams(sessionConfig, templateData)
 	if err != nil {
 		return &DryRunResult{Error: fmt.Sprintf("failed to render session params: %v", err)}, nil
 	}
 
-	initialMessage, err := s.determineInitialMessage(sessionConfig, renderedParams, params.Payload, params.DefaultMessage)
+	initialMessage, err := s.determineInitialMessage(sessionConfig, renderedParams, templateData, params.DefaultMessage)
 	if err != nil {
 		return &DryRunResult{Error: fmt.Sprintf("failed to render initial message: %v", err)}, nil
 	}
@@ -267,6 +311,19 @@ func (s *WebhookSessionService) DryRunSessionConfig(params SessionCreationParams
 	}, nil
 }
 
+// applyPayloadMappings evaluates the JSONPath payload mappings of the
+// session config. It returns nil when the config has none.
+func (s *WebhookSessionService) applyPayloadMappings(sessionConfig *entities.WebhookSessionConfig, payload map[string]interface{}) (*configrender.MappedValues, error) {
+	if sessionConfig == nil || len(sessionConfig.PayloadMappings()) == 0 {
+		return nil, nil
+	}
+	mapped, err := configrender.ApplyPayloadMappings(sessionConfig.PayloadMappings(), payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply payload mappings: %w", err)
+	}
+	return mapped, nil
+}
+
 // renderConfigMap renders either environment or tags from session config.
 func (s *WebhookSessionService) renderConfigMap(
 	sessionConfig *entities.WebhookSessionConfig,
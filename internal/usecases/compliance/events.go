@@ -0,0 +1,82 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// DefaultEventsLimit is the number of events returned when no limit is given
+const DefaultEventsLimit = 100
+
+// MaxEventsLimit is the largest number of events returned by one query
+const MaxEventsLimit = 1000
+
+// EventsQuery selects audit events
+type EventsQuery struct {
+	// From and To bound the timestamp: From <= timestamp < To
+	From time.Time
+	To   time.Time
+	// Actor keeps events of one user ID when set
+	Actor string
+	// Action keeps events whose action or operation equals it when set
+	Action string
+	// Limit caps the number of events; DefaultEventsLimit when zero
+	Limit int
+}
+
+// EventsResult is a page of audit events, newest first
+type EventsResult struct {
+	Events []entities.AuditEvent `json:"events"`
+	// Truncated is true when more events matched than were returned
+	Truncated bool `json:"truncated"`
+}
+
+// EventsUseCase queries the audit log
+type EventsUseCase struct {
+	auditRepo repositories.AuditRepository
+}
+
+// NewEventsUseCase creates a new EventsUseCase
+func NewEventsUseCase(auditRepo repositories.AuditRepository) *EventsUseCase {
+	return &EventsUseCase{auditRepo: auditRepo}
+}
+
+// Execute returns the newest events matching q
+func (uc *EventsUseCase) Execute(ctx context.Context, q EventsQuery) (*EventsResult, error) {
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultEventsLimit
+	}
+	if limit > MaxEventsLimit {
+		limit = MaxEventsLimit
+	}
+
+	events, err := uc.auditRepo.ListAuditEvents(ctx, q.From, q.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	result := &EventsResult{Events: []entities.AuditEvent{}}
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if q.Actor != "" && e.Actor != q.Actor {
+			continue
+		}
+		if q.Action != "" && e.Action != q.Action && e.Operation != q.Action {
+			continue
+		}
+		if len(result.Events) == limit {
+			result.Truncated = true
+			break
+		}
+		result.Events = append(result.Events, e)
+	}
+	return result, nil
+}
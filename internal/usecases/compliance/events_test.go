@@ -0,0 +1,76 @@
+package compliance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestEventsUseCaseFilters(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeAuditRepository{events: []entities.AuditEvent{
+		{Timestamp: base, Actor: "alice", Action: "POST /start", Operation: entities.AuditOpSessionCreate},
+		{Timestamp: base.Add(time.Hour), Actor: "bob", Action: "POST /start", Operation: entities.AuditOpSessionCreate},
+		{Timestamp: base.Add(2 * time.Hour), Actor: "alice", Action: "DELETE /sessions/:sessionId", Operation: entities.AuditOpSessionDelete},
+		{Timestamp: base.Add(3 * time.Hour), Actor: "alice", Action: "capability_denied"},
+		{Timestamp: base.Add(48 * time.Hour), Actor: "alice", Action: "POST /start", Operation: entities.AuditOpSessionCreate},
+	}}
+	uc := NewEventsUseCase(repo)
+	window := EventsQuery{From: base, To: base.Add(24 * time.Hour)}
+
+	tests := []struct {
+		name    string
+		query   EventsQuery
+		actions []string
+	}{
+		{"all newest first", window, []string{"capability_denied", "DELETE /sessions/:sessionId", "POST /start", "POST /start"}},
+		{"by user", EventsQuery{From: window.From, To: window.To, Actor: "bob"}, []string{"POST /start"}},
+		{"by operation", EventsQuery{From: window.From, To: window.To, Action: entities.AuditOpSessionDelete}, []string{"DELETE /sessions/:sessionId"}},
+		{"by route", EventsQuery{From: window.From, To: window.To, Actor: "alice", Action: "POST /start"}, []string{"POST /start"}},
+		{"by named action", EventsQuery{From: window.From, To: window.To, Action: "capability_denied"}, []string{"capability_denied"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := uc.Execute(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if len(result.Events) != len(tt.actions) {
+				t.Fatalf("got %d events, want %d: %+v", len(result.Events), len(tt.actions), result.Events)
+			}
+			for i, e := range result.Events {
+				if e.Action != tt.actions[i] {
+					t.Errorf("event %d action = %q, want %q", i, e.Action, tt.actions[i])
+				}
+			}
+			if result.Truncated {
+				t.Error("Truncated = true, want false")
+			}
+		})
+	}
+}
+
+func TestEventsUseCaseLimit(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeAuditRepository{}
+	for i := 0; i < 5; i++ {
+		repo.events = append(repo.events, entities.AuditEvent{Timestamp: base.Add(time.Duration(i) * time.Minute), Detail: string(rune('a' + i))})
+	}
+
+	result, err := NewEventsUseCase(repo).Execute(context.Background(), EventsQuery{From: base, To: base.Add(time.Hour), Limit: 2})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(result.Events) != 2 || result.Events[0].Detail != "e" || result.Events[1].Detail != "d" {
+		t.Errorf("events = %+v, want the 2 newest", result.Events)
+	}
+	if !result.Truncated {
+		t.Error("Truncated = false, want true")
+	}
+
+	if _, err := NewEventsUseCase(repo).Execute(context.Background(), EventsQuery{From: base, To: base}); err == nil {
+		t.Error("Execute() with empty period succeeded, want error")
+	}
+}
@@ -0,0 +1,210 @@
+package compliance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// csvHeader lists the columns of the CSV export, one row per audit event
+var csvHeader = []string{"timestamp", "category", "action", "actor", "resource", "outcome", "client_ip", "detail"}
+
+// WriteCSV writes every event of the report as one CSV row
+func WriteCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, section := range report.Sections {
+		for _, e := range section.Events {
+			row := []string{
+				e.Timestamp.UTC().Format(time.RFC3339),
+				string(e.Category),
+				e.Action,
+				e.Actor,
+				e.Resource,
+				e.Outcome,
+				e.ClientIP,
+				e.Detail,
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// reportLines renders the report as plain text lines for the PDF export
+func reportLines(report *Report) []string {
+	lines := []string{
+		"Compliance Report",
+		"",
+		fmt.Sprintf("Period:    %s - %s", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339)),
+		fmt.Sprintf("Generated: %s", report.GeneratedAt.Format(time.RFC3339)),
+		"",
+		"Summary",
+	}
+	for _, section := range report.Sections {
+		lines = append(lines, fmt.Sprintf("  %-18s %6d  %s", section.Category, section.Total, formatOutcomes(section.ByOutcome)))
+	}
+
+	r := report.Retention
+	lines = append(lines, "", "Data retention")
+	if r.RetentionDays > 0 {
+		lines = append(lines, fmt.Sprintf("  Retention period: %d days", r.RetentionDays))
+	} else {
+		lines = append(lines, "  Retention period: unlimited")
+	}
+	if r.OldestEvent != nil {
+		lines = append(lines, "  Oldest event:     "+r.OldestEvent.UTC().Format(time.RFC3339))
+	}
+	status := "adherent"
+	if !r.Adherent {
+		status = "NOT ADHERENT - events older than the retention period are stored"
+	}
+	lines = append(lines,
+		"  Status:           "+status,
+		fmt.Sprintf("  Purges in period: %d (%d events deleted)", r.PurgeRuns, r.EventsPurged),
+	)
+
+	for _, section := range report.Sections {
+		lines = append(lines, "", fmt.Sprintf("%s (%d)", section.Category, section.Total))
+		if len(section.Events) == 0 {
+			lines = append(lines, "  none")
+		}
+		for _, e := range section.Events {
+			line := fmt.Sprintf("  %s  %-7s %s %s", e.Timestamp.UTC().Format(time.RFC3339), e.Outcome, e.Actor, e.Action)
+			if e.Resource != "" {
+				line += " " + e.Resource
+			}
+			if e.Detail != "" {
+				line += " - " + e.Detail
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func formatOutcomes(byOutcome map[string]int) string {
+	outcomes := make([]string, 0, len(byOutcome))
+	for outcome := range byOutcome {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	parts := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		parts = append(parts, fmt.Sprintf("%s=%d", outcome, byOutcome[outcome]))
+	}
+	return strings.Join(parts, " ")
+}
+
+const (
+	pdfPageWidth    = 612 // US Letter, in points
+	pdfPageHeight   = 792
+	pdfMargin       = 40
+	pdfFontSize     = 8
+	pdfLineHeight   = 10
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	pdfLineWidth    = 125 // Courier characters per line at pdfFontSize
+)
+
+// WritePDF writes the report as a plain text PDF document
+func WritePDF(w io.Writer, report *Report) error {
+	var lines []string
+	for _, line := range reportLines(report) {
+		lines = append(lines, wrapLine(line, pdfLineWidth)...)
+	}
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content
+	// stream per page.
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	)
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", escapePDFText(line))
+		}
+		content.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// wrapLine splits line into chunks of at most width characters, indenting
+// continuation lines
+func wrapLine(line string, width int) []string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return []string{line}
+	}
+	lines := []string{string(runes[:width])}
+	for runes = runes[width:]; len(runes) > 0; {
+		n := width - 4
+		if n > len(runes) {
+			n = len(runes)
+		}
+		lines = append(lines, "    "+string(runes[:n]))
+		runes = runes[n:]
+	}
+	return lines
+}
+
+// escapePDFText escapes a string for a PDF literal; characters outside
+// printable ASCII are replaced because the standard fonts cannot show them
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// retentionGrace is how far past the retention period the oldest event may be
+// before the report flags non-adherence; purges run periodically, not continuously.
+const retentionGrace = 24 * time.Hour
+
+// CategorySection summarizes the audit events of one category
+type CategorySection struct {
+	Category  entities.AuditCategory `json:"category"`
+	Total     int                    `json:"total"`
+	ByOutcome map[string]int         `json:"by_outcome"`
+	Events    []entities.AuditEvent  `json:"events"`
+}
+
+// RetentionStatus reports whether audit data is kept no longer than configured
+type RetentionStatus struct {
+	// RetentionDays is the configured retention; 0 means events are kept forever
+	RetentionDays int `json:"retention_days"`
+	// OldestEvent is the timestamp of the oldest stored audit event
+	OldestEvent *time.Time `json:"oldest_event,omitempty"`
+	// Adherent is false when events older than the retention period are still stored
+	Adherent bool `json:"adherent"`
+	// PurgeRuns is the number of retention purges in the report period
+	PurgeRuns int `json:"purge_runs"`
+	// EventsPurged is the number of events deleted by those purges
+	EventsPurged int `json:"events_purged"`
+	// LastPurge is when the last purge in the report period ran
+	LastPurge *time.Time `json:"last_purge,omitempty"`
+}
+
+// Report is a compliance report over a period
+type Report struct {
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Sections    []CategorySection `json:"sections"`
+	Retention   RetentionStatus   `json:"retention"`
+}
+
+// ReportUseCase generates compliance reports from the audit log
+type ReportUseCase struct {
+	auditRepo     repositories.AuditRepository
+	retentionDays int
+	now           func() time.Time
+}
+
+// NewReportUseCase creates a new ReportUseCase
+func NewReportUseCase(auditRepo repositories.AuditRepository, retentionDays int) *ReportUseCase {
+	return &ReportUseCase{
+		auditRepo:     auditRepo,
+		retentionDays: retentionDays,
+		now:           time.Now,
+	}
+}
+
+// Execute builds the report for events with from <= timestamp < to
+func (uc *ReportUseCase) Execute(ctx context.Context, from, to time.Time) (*Report, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	events, err := uc.auditRepo.ListAuditEvents(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	oldest, err := uc.auditRepo.OldestAuditEvent(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find oldest audit event: %w", err)
+	}
+
+	now := uc.now()
+	report := &Report{
+		From:        from.UTC(),
+		To:          to.UTC(),
+		GeneratedAt: now.UTC(),
+		Retention: RetentionStatus{
+			RetentionDays: uc.retentionDays,
+			Adherent:      true,
+		},
+	}
+
+	sections := make(map[entities.AuditCategory]*CategorySection, len(entities.AuditCategories))
+	for _, category := range entities.AuditCategories {
+		report.Sections = append(report.Sections, CategorySection{
+			Category:  category,
+			ByOutcome: map[string]int{},
+			Events:    []entities.AuditEvent{},
+		})
+	}
+	for i := range report.Sections {
+		sections[report.Sections[i].Category] = &report.Sections[i]
+	}
+
+	for _, e := range events {
+		section, ok := sections[e.Category]
+		if !ok {
+			continue
+		}
+		section.Total++
+		section.ByOutcome[e.Outcome]++
+		section.Events = append(section.Events, e)
+
+		if e.Category == entities.AuditCategoryRetention {
+			report.Retention.PurgeRuns++
+			if n, err := strconv.Atoi(e.Detail); err == nil {
+				report.Retention.EventsPurged += n
+			}
+			ts := e.Timestamp
+			report.Retention.LastPurge = &ts
+		}
+	}
+
+	if !oldest.IsZero() {
+		report.Retention.OldestEvent = &oldest
+		if uc.retentionDays > 0 {
+			limit := now.AddDate(0, 0, -uc.retentionDays).Add(-retentionGrace)
+			report.Retention.Adherent = !oldest.Before(limit)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,185 @@
+package compliance
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+type fakeAuditRepository struct {
+	events []entities.AuditEvent
+}
+
+func (f *fakeAuditRepository) RecordAuditEvent(_ context.Context, e entities.AuditEvent) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeAuditRepository) ListAuditEvents(_ context.Context, from, to time.Time) ([]entities.AuditEvent, error) {
+	var result []entities.AuditEvent
+	for _, e := range f.events {
+		if !e.Timestamp.Before(from) && e.Timestamp.Before(to) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeAuditRepository) OldestAuditEvent(_ context.Context) (time.Time, error) {
+	var oldest time.Time
+	for _, e := range f.events {
+		if oldest.IsZero() || e.Timestamp.Before(oldest) {
+			oldest = e.Timestamp
+		}
+	}
+	return oldest, nil
+}
+
+func (f *fakeAuditRepository) PurgeAuditEvents(_ context.Context, _ time.Time) (int, error) {
+	return 0, nil
+}
+
+var reportNow = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+func newTestReport(t *testing.T, retentionDays int, events ...entities.AuditEvent) *Report {
+	t.Helper()
+	uc := NewReportUseCase(&fakeAuditRepository{events: events}, retentionDays)
+	uc.now = func() time.Time { return reportNow }
+	report, err := uc.Execute(context.Background(), reportNow.AddDate(0, 0, -30), reportNow)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	return report
+}
+
+func section(report *Report, category entities.AuditCategory) CategorySection {
+	for _, s := range report.Sections {
+		if s.Category == category {
+			return s
+		}
+	}
+	return CategorySection{}
+}
+
+func TestReportUseCase_Execute(t *testing.T) {
+	day := reportNow.AddDate(0, 0, -1)
+	report := newTestReport(t, 90,
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategoryAccess, Action: "POST /sessions/start", Actor: "alice", Outcome: entities.AuditOutcomeSuccess},
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategoryAccess, Action: "GET /sessions", Outcome: entities.AuditOutcomeDenied},
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategoryAdminAction, Action: "PUT /settings/:name", Actor: "admin", Outcome: entities.AuditOutcomeSuccess},
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategoryPolicyViolation, Action: "capability_denied", Actor: "bob", Outcome: entities.AuditOutcomeDenied},
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategorySecretRotation, Action: "POST /external-session-managers/:id/rotate-token", Actor: "admin", Outcome: entities.AuditOutcomeSuccess},
+		entities.AuditEvent{Timestamp: day, Category: entities.AuditCategoryRetention, Action: "purge", Outcome: entities.AuditOutcomeSuccess, Detail: "12"},
+		entities.AuditEvent{Timestamp: reportNow.AddDate(0, 0, -60), Category: entities.AuditCategoryAccess, Action: "old", Outcome: entities.AuditOutcomeSuccess},
+	)
+
+	if len(report.Sections) != len(entities.AuditCategories) {
+		t.Fatalf("len(Sections) = %d, want %d", len(report.Sections), len(entities.AuditCategories))
+	}
+	access := section(report, entities.AuditCategoryAccess)
+	if access.Total != 2 || access.ByOutcome[entities.AuditOutcomeDenied] != 1 {
+		t.Errorf("access section = %+v, want 2 events (1 denied) within the period", access)
+	}
+	if got := section(report, entities.AuditCategoryPolicyViolation).Total; got != 1 {
+		t.Errorf("policy violations = %d, want 1", got)
+	}
+	if report.Retention.PurgeRuns != 1 || report.Retention.EventsPurged != 12 {
+		t.Errorf("retention = %+v, want 1 purge of 12 events", report.Retention)
+	}
+	if !report.Retention.Adherent {
+		t.Error("expected retention to be adherent with a 60 day old event and 90 day retention")
+	}
+}
+
+func TestReportUseCase_RetentionViolation(t *testing.T) {
+	report := newTestReport(t, 30, entities.AuditEvent{
+		Timestamp: reportNow.AddDate(0, 0, -45),
+		Category:  entities.AuditCategoryAccess,
+		Outcome:   entities.AuditOutcomeSuccess,
+	})
+	if report.Retention.Adherent {
+		t.Error("expected non-adherence when events outlive the retention period")
+	}
+	if report.Retention.OldestEvent == nil {
+		t.Error("expected oldest event to be reported")
+	}
+}
+
+func TestReportUseCase_InvalidPeriod(t *testing.T) {
+	uc := NewReportUseCase(&fakeAuditRepository{}, 0)
+	if _, err := uc.Execute(context.Background(), reportNow, reportNow); err == nil {
+		t.Fatal("expected error for empty period")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := newTestReport(t, 0, entities.AuditEvent{
+		Timestamp: reportNow.AddDate(0, 0, -1),
+		Category:  entities.AuditCategoryAdminAction,
+		Action:    "DELETE /sessions/:sessionId",
+		Actor:     "admin",
+		Resource:  "/sessions/abc",
+		Outcome:   entities.AuditOutcomeSuccess,
+		Detail:    `quoted "detail", with comma`,
+	})
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, report); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("len(rows) = %d, want header + 1 event", len(rows))
+	}
+	if rows[1][1] != "admin_action" || rows[1][7] != `quoted "detail", with comma` {
+		t.Errorf("unexpected row %q", rows[1])
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	var events []entities.AuditEvent
+	for i := 0; i < 200; i++ {
+		events = append(events, entities.AuditEvent{
+			Timestamp: reportNow.Add(-time.Duration(i) * time.Minute),
+			Category:  entities.AuditCategoryAccess,
+			Action:    "POST /sessions/start (test)",
+			Actor:     "user-" + strings.Repeat("x", i%3),
+			Outcome:   entities.AuditOutcomeSuccess,
+		})
+	}
+	report := newTestReport(t, 90, events...)
+
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, report); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	pdf := buf.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatal("output is not a PDF document")
+	}
+	if !strings.Contains(pdf, `POST /sessions/start \(test\)`) {
+		t.Error("expected parentheses in text to be escaped")
+	}
+	if strings.Count(pdf, "/Type /Page ") < 2 {
+		t.Error("expected the report to span multiple pages")
+	}
+
+	// The xref offset must point at the xref table
+	idx := strings.LastIndex(pdf, "startxref\n")
+	var offset int
+	if _, err := fmt.Sscan(pdf[idx+len("startxref\n"):], &offset); err != nil {
+		t.Fatalf("invalid startxref: %v", err)
+	}
+	if !strings.HasPrefix(pdf[offset:], "xref\n") {
+		t.Error("startxref does not point at the xref table")
+	}
+}
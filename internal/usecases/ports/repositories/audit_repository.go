@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// MaxAuditEventsPerDay is the number of audit events kept per UTC day; the
+// oldest events of a day are dropped beyond it.
+const MaxAuditEventsPerDay = 3000
+
+// AuditRepository stores audit events for compliance reporting.
+type AuditRepository interface {
+	// RecordAuditEvent stores event.
+	RecordAuditEvent(ctx context.Context, event entities.AuditEvent) error
+
+	// ListAuditEvents returns events with from <= timestamp < to, oldest first.
+	ListAuditEvents(ctx context.Context, from, to time.Time) ([]entities.AuditEvent, error)
+
+	// OldestAuditEvent returns the timestamp of the oldest stored event, or
+	// the zero time when there are none.
+	OldestAuditEvent(ctx context.Context) (time.Time, error)
+
+	// PurgeAuditEvents deletes events older than before and returns how many
+	// were deleted.
+	PurgeAuditEvents(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// MaxSessionEvents is the number of most recent events kept per session.
+const MaxSessionEvents = 200
+
+// EventRecorder stores the lifecycle event timeline of sessions.
+type EventRecorder interface {
+	// RecordSessionEvent appends event to the timeline of event.SessionID,
+	// dropping the oldest events beyond MaxSessionEvents.
+	RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error
+
+	// ListSessionEvents returns the timeline of a session, oldest first.
+	// Unknown sessions have an empty timeline.
+	ListSessionEvents(ctx context.Context, sessionID string) ([]entities.SessionEvent, error)
+
+	// DeleteSessionEvents removes the timeline of a session.
+	DeleteSessionEvents(ctx context.Context, sessionID string) error
+}
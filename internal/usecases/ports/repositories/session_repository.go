@@ -9,6 +9,8 @@ import (
 
 // Message represents a message in a conversation
 type Message struct {
+	// ID is the position of the message in the conversation, starting at 0
+	ID        int       `json:"id"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
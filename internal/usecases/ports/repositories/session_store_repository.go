@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// SessionRecord is the full persisted state of a session. Unlike the metadata
+// kept on Kubernetes objects it includes the complete launch request (with
+// environment) and the webhook payload.
+type SessionRecord struct {
+	ID             string
+	Request        *entities.RunServerRequest
+	WebhookPayload []byte
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// SessionRepository persists session state so that sessions can be restored
+// without loss after a proxy restart.
+type SessionRepository interface {
+	// Save creates or updates a session record
+	Save(ctx context.Context, record *SessionRecord) error
+	// Get retrieves the record for the given session ID; returns nil, nil if not found
+	Get(ctx context.Context, sessionID string) (*SessionRecord, error)
+	// List retrieves all session records; if userID is non-empty, only records for that user are returned
+	List(ctx context.Context, userID string) ([]*SessionRecord, error)
+	// Delete removes the record for the given session ID
+	Delete(ctx context.Context, sessionID string) error
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
 )
 
 // StatusChangeEvent represents a cross-pod session status change notification.
@@ -40,3 +42,24 @@ type StatusEventRepository interface {
 	// Called during session deletion to free resources.
 	DeleteStatus(ctx context.Context, sessionID string) error
 }
+
+// SharedSessionEvent is a session timeline event recorded by the pod PodID.
+type SharedSessionEvent struct {
+	entities.SessionEvent
+	PodID string `json:"pod_id"`
+}
+
+// SessionEventBroadcaster shares the session timeline events recorded by each
+// pod with all other pods, so that they drop deleted sessions from memory and
+// pass the events on to their own subscribers.
+//
+// Implementations must be safe for concurrent use.
+type SessionEventBroadcaster interface {
+	// PublishSessionEvent broadcasts event to all pods.
+	PublishSessionEvent(ctx context.Context, event SharedSessionEvent) error
+
+	// SubscribeSessionEvents returns a channel that receives the events
+	// published by any pod.  The channel is closed when ctx is cancelled or
+	// the subscription is lost.
+	SubscribeSessionEvents(ctx context.Context) (<-chan SharedSessionEvent, error)
+}
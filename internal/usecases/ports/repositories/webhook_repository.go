@@ -20,6 +20,8 @@ type WebhookFilter struct {
 	TeamIDs []string
 	// Type filters by webhook type
 	Type entities.WebhookType
+	// Source filters by generic source name
+	Source string
 }
 
 // GitHubMatcher defines criteria for finding webhooks that match a GitHub event
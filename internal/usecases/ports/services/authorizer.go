@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// Authorizer decides whether a user may perform an action on a resource.
+// Implementations other than the built-in role policy (e.g. an external
+// policy engine) can be plugged in through Server.SetAuthorizer.
+type Authorizer interface {
+	// Authorize returns nil when the action is allowed, or an error wrapping
+	// entities.ErrActionNotAllowed when it is denied.
+	Authorize(ctx context.Context, user *entities.User, action entities.Action, resource entities.AuthzResource) error
+}
@@ -36,6 +36,9 @@ type LaunchRequest struct {
 	InitialMessageWaitSecond *int
 	Sandbox                  *entities.SandboxParams
 	Docker                   *entities.DockerParams
+	Editor                   *entities.EditorParams
+	Browser                  *entities.BrowserParams
+	Terminal                 *entities.TerminalParams
 	AuthProxy                *bool
 	CycleMessage             string
 	CycleMaxCount            int
@@ -43,6 +46,14 @@ type LaunchRequest struct {
 	UnsyncedFilePaths        []string
 	CredentialSource         string
 	ProfileMCPServers        *entities.MCPServersSettings
+	TeamProfileMCPServers    *entities.MCPServersSettings
+	RequestMCPServers        *entities.MCPServersSettings
+	SetupHooks               []entities.SetupHook
+	PostSessionHooks         []entities.SetupHook
+	Replicas                 int
+	Accelerator              *entities.AcceleratorParams
+	Image                    string
+	CompletionCallbackURL    string
 
 	// Webhook payload to mount in the session filesystem (optional)
 	WebhookPayload []byte
@@ -71,6 +82,11 @@ type LaunchRequest struct {
 	// SessionProfileID is an optional reference to a SessionProfile.
 	// When set, the profile's config is merged as a base; explicit request fields override it.
 	SessionProfileID string
+
+	// BeforeCreate, when set, is called with the RunServerRequest of a new
+	// session right before it is created. It is not called when a session is
+	// reused or the session limit is reached. An error aborts the launch.
+	BeforeCreate func(ctx context.Context, sessionID string, req *entities.RunServerRequest) error
 }
 
 // LaunchResult is returned by LaunchUseCase.Launch.
@@ -133,7 +149,8 @@ func (uc *LaunchUseCase) WithSessionProfileRepository(repo repositories.SessionP
 // Launch creates or reuses a session according to the LaunchRequest.
 //
 // Execution order:
-//  0. Resolve session profile config (explicit ID, or default profile when ID is empty).
+//  0. Resolve session profile config (explicit ID, or default profile when ID is empty),
+//     then the default profiles of the session's teams beneath it.
 //  1. Try to reuse an existing active session (when ReuseSession is true).
 //  2. Check the session limit (when MaxSessions > 0).
 //  3. Create a new session.
@@ -145,6 +162,15 @@ func (uc *LaunchUseCase) Launch(ctx context.Context, sessionID string, req Launc
 		if profile != nil {
 			applyProfileToLaunchRequest(profile.Config(), &req)
 		}
+		// Team defaults fill what the profile and the request leave unset,
+		// the last team first
+		teamDefaults := TeamDefaultProfiles(ctx, uc.sessionProfileRepo, req.Teams)
+		for i := len(teamDefaults) - 1; i >= 0; i-- {
+			cfg := teamDefaults[i].Config()
+			cfg.SetMCPServers(nil)
+			applyProfileToLaunchRequest(cfg, &req)
+		}
+		req.TeamProfileMCPServers = TeamDefaultMCPServers(teamDefaults)
 	}
 
 	// 1. Try session reuse
@@ -191,7 +217,7 @@ func (uc *LaunchUseCase) Launch(ctx context.Context, sessionID string, req Launc
 	runReq := &entities.RunServerRequest{
 		UserID:                   req.UserID,
 		Environment:              req.Environment,
-		Tags:                     req.Tags,
+		Tags:                     slackThreadTags(req.Tags, req.SlackParams),
 		Scope:                    req.Scope,
 		TeamID:                   req.TeamID,
 		Teams:                    req.Teams,
@@ -207,11 +233,28 @@ func (uc *LaunchUseCase) Launch(ctx context.Context, sessionID string, req Launc
 		CycleMaxCount:            req.CycleMaxCount,
 		Sandbox:                  req.Sandbox,
 		Docker:                   req.Docker,
+		Editor:                   req.Editor,
+		Browser:                  req.Browser,
+		Terminal:                 req.Terminal,
 		AuthProxy:                req.AuthProxy,
 		SessionTTL:               req.SessionTTL,
 		UnsyncedFilePaths:        req.UnsyncedFilePaths,
 		CredentialSource:         req.CredentialSource,
 		ProfileMCPServers:        req.ProfileMCPServers,
+		TeamProfileMCPServers:    req.TeamProfileMCPServers,
+		RequestMCPServers:        req.RequestMCPServers,
+		SetupHooks:               req.SetupHooks,
+		PostSessionHooks:         req.PostSessionHooks,
+		Replicas:                 req.Replicas,
+		Accelerator:              req.Accelerator,
+		Image:                    req.Image,
+		CompletionCallbackURL:    req.CompletionCallbackURL,
+	}
+
+	if req.BeforeCreate != nil {
+		if err := req.BeforeCreate(ctx, sessionID, runReq); err != nil {
+			return LaunchResult{}, err
+		}
 	}
 
 	session, err := uc.sessionManager.CreateSession(ctx, sessionID, runReq, req.WebhookPayload)
@@ -298,6 +341,7 @@ func (uc *LaunchUseCase) resolveSessionProfile(ctx context.Context, req LaunchRe
 		log.Printf("[LAUNCH] Warning: could not list session profiles for default lookup: %v", err)
 		return nil
 	}
+	profiles = WithoutTeamDefaults(profiles)
 	if profile := selectProfileByTags(profiles, req.Tags); profile != nil {
 		log.Printf("[LAUNCH] Applying tag-selected session profile %q (%s) for user %s", profile.ID(), profile.Name(), req.UserID)
 		return profile
@@ -396,6 +440,21 @@ func applyProfileToLaunchRequest(cfg entities.SessionProfileConfig, req *LaunchR
 		if req.Docker == nil && cfg.Params().Docker != nil {
 			req.Docker = cfg.Params().Docker
 		}
+		if req.Editor == nil && cfg.Params().Editor != nil {
+			req.Editor = cfg.Params().Editor
+		}
+		if req.Browser == nil && cfg.Params().Browser != nil {
+			req.Browser = cfg.Params().Browser
+		}
+		if req.Terminal == nil && cfg.Params().Terminal != nil {
+			req.Terminal = cfg.Params().Terminal
+		}
+		if req.Accelerator == nil && cfg.Params().Accelerator != nil {
+			req.Accelerator = cfg.Params().Accelerator
+		}
+		if req.Image == "" && cfg.Params().Image != "" {
+			req.Image = cfg.Params().Image
+		}
 		if req.AuthProxy == nil && cfg.Params().AuthProxy != nil {
 			req.AuthProxy = cfg.Params().AuthProxy
 		}
@@ -417,6 +476,12 @@ func applyProfileToLaunchRequest(cfg entities.SessionProfileConfig, req *LaunchR
 		if req.CredentialSource == "" {
 			req.CredentialSource = cfg.Params().CredentialSource
 		}
+		if len(req.SetupHooks) == 0 && len(cfg.Params().SetupHooks) > 0 {
+			req.SetupHooks = append([]entities.SetupHook(nil), cfg.Params().SetupHooks...)
+		}
+		if len(req.PostSessionHooks) == 0 && len(cfg.Params().PostSessionHooks) > 0 {
+			req.PostSessionHooks = append([]entities.SetupHook(nil), cfg.Params().PostSessionHooks...)
+		}
 	}
 	if cfg.SessionTTL() != "" && req.SessionTTL == "" {
 		req.SessionTTL = cfg.SessionTTL()
@@ -444,3 +509,25 @@ func applyProfileSandboxDefaults(cfg entities.SessionProfileConfig, req *LaunchR
 		req.Sandbox.CountMode = true
 	}
 }
+
+// slackThreadTags returns the session tags with slack_channel and
+// slack_thread_ts added for sessions that post into a Slack thread. The
+// slackbot event handler routes replies in a thread to the active session
+// carrying these tags, so users can answer sessions started from the API,
+// webhooks or schedules directly in Slack. Tags set by the caller win.
+func slackThreadTags(tags map[string]string, slack *entities.SlackParams) map[string]string {
+	if slack == nil || slack.Channel == "" || slack.ThreadTS == "" {
+		return tags
+	}
+	result := make(map[string]string, len(tags)+2)
+	for k, v := range tags {
+		result[k] = v
+	}
+	if _, ok := result["slack_channel"]; !ok {
+		result["slack_channel"] = slack.Channel
+	}
+	if _, ok := result["slack_thread_ts"]; !ok {
+		result["slack_thread_ts"] = slack.ThreadTS
+	}
+	return result
+}
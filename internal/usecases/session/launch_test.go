@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -87,10 +88,13 @@ func (r *fakeSessionProfileRepo) Get(_ context.Context, id string) (*entities.Se
 func (r *fakeSessionProfileRepo) List(_ context.Context, filter repositories.SessionProfileFilter) ([]*entities.SessionProfile, error) {
 	var result []*entities.SessionProfile
 	for _, p := range r.profiles {
-		if p.UserID() != filter.UserID || p.Scope() != filter.Scope {
+		if (filter.UserID != "" && p.UserID() != filter.UserID) || p.Scope() != filter.Scope {
 			continue
 		}
-		if filter.Scope == entities.ScopeTeam && p.TeamID() != filter.TeamID {
+		if filter.TeamID != "" && p.TeamID() != filter.TeamID {
+			continue
+		}
+		if len(filter.TeamIDs) > 0 && !slices.Contains(filter.TeamIDs, p.TeamID()) {
 			continue
 		}
 		result = append(result, p)
@@ -164,6 +168,58 @@ func TestLaunchPropagatesProfileMCPServers(t *testing.T) {
 	}
 }
 
+func TestLaunchAppliesTeamDefaultProfilesBeneathUserProfile(t *testing.T) {
+	sessionManager := &recordingSessionManager{}
+	userProfile := entities.NewSessionProfile("profile-1", "mine", "user-1")
+	userProfile.SetIsDefault(true)
+	userCfg := entities.NewSessionProfileConfig()
+	userCfg.SetEnvironment(map[string]string{"EDITOR": "vim"})
+	userProfile.SetConfig(userCfg)
+
+	newTeamDefault := func(id, teamID string, env map[string]string, ttl string) *entities.SessionProfile {
+		p := entities.NewSessionProfile(id, teamID+" default", "admin")
+		p.SetOwnership(entities.ScopeTeam, "admin", teamID)
+		p.SetIsTeamDefault(true)
+		cfg := entities.NewSessionProfileConfig()
+		cfg.SetEnvironment(env)
+		cfg.SetSessionTTL(ttl)
+		servers := entities.NewMCPServersSettings()
+		servers.SetServer("docs", entities.NewMCPServer("docs", "http"))
+		cfg.SetMCPServers(servers)
+		p.SetConfig(cfg)
+		return p
+	}
+	teamA := newTeamDefault("team-a", "acme/a", map[string]string{"EDITOR": "nano", "REGION": "us", "TEAM": "a"}, "24h")
+	teamB := newTeamDefault("team-b", "acme/b", map[string]string{"TEAM": "b"}, "")
+
+	launcher := NewLaunchUseCase(sessionManager).WithSessionProfileRepository(
+		&fakeSessionProfileRepo{profiles: []*entities.SessionProfile{userProfile, teamA, teamB}},
+	)
+	if _, err := launcher.Launch(context.Background(), "session-1", LaunchRequest{
+		UserID:      "user-1",
+		Scope:       entities.ScopeUser,
+		Teams:       []string{"acme/a", "acme/b"},
+		Environment: map[string]string{"REGION": "eu"},
+	}); err != nil {
+		t.Fatalf("Launch() error = %v", err)
+	}
+
+	// request > user profile > later team > earlier team
+	want := map[string]string{"EDITOR": "vim", "REGION": "eu", "TEAM": "b"}
+	if !reflect.DeepEqual(sessionManager.req.Environment, want) {
+		t.Errorf("Environment = %v, want %v", sessionManager.req.Environment, want)
+	}
+	if sessionManager.req.SessionTTL != "24h" {
+		t.Errorf("SessionTTL = %q, want the team default", sessionManager.req.SessionTTL)
+	}
+	if sessionManager.req.ProfileMCPServers != nil {
+		t.Errorf("team default MCP servers leaked into the session profile layer")
+	}
+	if servers := sessionManager.req.TeamProfileMCPServers; servers == nil || servers.GetServer("docs") == nil {
+		t.Errorf("TeamProfileMCPServers = %v, want the team default servers", servers)
+	}
+}
+
 func TestLaunchAppliesDefaultProfileSandbox(t *testing.T) {
 	sessionManager := &recordingSessionManager{}
 	profile := entities.NewSessionProfile("profile-1", "default", "user-1")
@@ -288,6 +344,35 @@ func TestLaunchAppliesProfileCredentialSource(t *testing.T) {
 	}
 }
 
+func TestLaunchAppliesProfileSetupHooks(t *testing.T) {
+	sessionManager := &recordingSessionManager{}
+	profile := entities.NewSessionProfile("profile-1", "team profile", "user-1")
+	profile.SetOwnership(entities.ScopeTeam, "user-1", "org/team-a")
+	profile.SetIsDefault(true)
+	cfg := entities.NewSessionProfileConfig()
+	hooks := []entities.SetupHook{
+		{Name: "deps", Command: "npm ci", TimeoutSeconds: 900},
+		{Name: "seed", Command: "make seed", OnFailure: entities.SetupHookContinue},
+	}
+	cfg.SetParams(&entities.SessionParams{SetupHooks: hooks})
+	profile.SetConfig(cfg)
+
+	launcher := NewLaunchUseCase(sessionManager).
+		WithSessionProfileRepository(&fakeSessionProfileRepo{profiles: []*entities.SessionProfile{profile}})
+
+	_, err := launcher.Launch(context.Background(), "session-1", LaunchRequest{
+		UserID: "user-1",
+		Scope:  entities.ScopeTeam,
+		TeamID: "org/team-a",
+	})
+	if err != nil {
+		t.Fatalf("Launch() error = %v", err)
+	}
+	if !reflect.DeepEqual(sessionManager.req.SetupHooks, hooks) {
+		t.Fatalf("setup hooks = %#v, want %#v", sessionManager.req.SetupHooks, hooks)
+	}
+}
+
 func TestLaunchExplicitUnsyncedFilePathsOverrideProfile(t *testing.T) {
 	sessionManager := &recordingSessionManager{}
 	profile := entities.NewSessionProfile("profile-1", "default", "user-1")
@@ -610,3 +695,29 @@ func TestLaunchReuseReturnsErrorForNonStaleSendFailure(t *testing.T) {
 func boolPointer(v bool) *bool {
 	return &v
 }
+
+func TestLaunchTagsSlackThreadForReplyRouting(t *testing.T) {
+	sessionManager := &recordingSessionManager{}
+	tags := map[string]string{"repository": "org/repo"}
+
+	_, err := NewLaunchUseCase(sessionManager).Launch(context.Background(), "session-1", LaunchRequest{
+		UserID:      "user-1",
+		Scope:       entities.ScopeUser,
+		Tags:        tags,
+		SlackParams: &entities.SlackParams{Channel: "C123", ThreadTS: "1700000000.000100"},
+	})
+	if err != nil {
+		t.Fatalf("Launch() error = %v", err)
+	}
+	want := map[string]string{
+		"repository":      "org/repo",
+		"slack_channel":   "C123",
+		"slack_thread_ts": "1700000000.000100",
+	}
+	if !reflect.DeepEqual(sessionManager.req.Tags, want) {
+		t.Fatalf("expected tags %v, got %v", want, sessionManager.req.Tags)
+	}
+	if len(tags) != 1 {
+		t.Fatalf("caller tags must not be modified, got %v", tags)
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"context"
+	"log"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// TeamDefaultProfiles returns the default profiles of teams in the order of
+// teams, as returned by ResolveTeams. They apply beneath the profile of a
+// session: later teams override earlier ones, and the session's own profile
+// and the request override them all.
+func TeamDefaultProfiles(ctx context.Context, repo repositories.SessionProfileRepository, teams []string) []*entities.SessionProfile {
+	if repo == nil || len(teams) == 0 {
+		return nil
+	}
+	profiles, err := repo.List(ctx, repositories.SessionProfileFilter{Scope: entities.ScopeTeam, TeamIDs: teams})
+	if err != nil {
+		log.Printf("[LAUNCH] Warning: could not list team default session profiles: %v", err)
+		return nil
+	}
+	byTeam := make(map[string]*entities.SessionProfile)
+	for _, p := range profiles {
+		if p.IsTeamDefault() {
+			byTeam[p.TeamID()] = p
+		}
+	}
+	var result []*entities.SessionProfile
+	for _, team := range teams {
+		if p, ok := byTeam[team]; ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// TeamDefaultMCPServers merges the MCP servers of team default profiles,
+// later profiles winning by server name. Returns nil when none has any.
+func TeamDefaultMCPServers(profiles []*entities.SessionProfile) *entities.MCPServersSettings {
+	var merged *entities.MCPServersSettings
+	for _, p := range profiles {
+		cfg := p.Config()
+		if cfg.MCPServers() == nil || cfg.MCPServers().IsEmpty() {
+			continue
+		}
+		if merged == nil {
+			merged = entities.NewMCPServersSettings()
+		}
+		for name, server := range cfg.MCPServers().Servers() {
+			merged.SetServer(name, server)
+		}
+	}
+	return merged
+}
+
+// WithoutTeamDefaults drops team default profiles, which are never selected
+// as the profile of a session
+func WithoutTeamDefaults(profiles []*entities.SessionProfile) []*entities.SessionProfile {
+	result := profiles[:0:0]
+	for _, p := range profiles {
+		if !p.IsTeamDefault() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -24,6 +24,9 @@ func init() {
 	rootCmd.AddCommand(cmd.NativeCmd)
 	rootCmd.AddCommand(cmd.OneshotCmd)
 	rootCmd.AddCommand(cmd.AcpServerCmd)
+	rootCmd.AddCommand(cmd.AdminCmd)
+	rootCmd.AddCommand(cmd.ConfigCmd)
+	rootCmd.AddCommand(cmd.DoctorCmd)
 }
 
 func main() {
@@ -0,0 +1,155 @@
+// Package activity merges what recently happened to a user — their sessions,
+// deleted sessions, capability approvals, schedule runs and notifications —
+// into a single feed, so that clients can render a home view with one request.
+//
+// Each kind of activity is read by a Source. Sources are queried
+// concurrently; a failing source is reported by name and leaves the rest of
+// the feed intact.
+package activity
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Kinds of feed items
+const (
+	// KindSession is a session of the user, at its latest activity
+	KindSession = "session"
+	// KindSessionDeleted is a deleted session of the user
+	KindSessionDeleted = "session_deleted"
+	// KindApproval is a decision on the capabilities a session requested
+	KindApproval = "approval"
+	// KindScheduleRun is the latest run of a schedule of the user
+	KindScheduleRun = "schedule_run"
+	// KindNotification is a notification sent to the user
+	KindNotification = "notification"
+)
+
+// Kinds lists all item kinds
+var Kinds = []string{KindSession, KindSessionDeleted, KindApproval, KindScheduleRun, KindNotification}
+
+// Item is one entry of the feed
+type Item struct {
+	Kind      string    `json:"kind"`
+	Timestamp time.Time `json:"timestamp"`
+	// SessionID is the session the item is about, if any
+	SessionID string `json:"session_id,omitempty"`
+	Title     string `json:"title"`
+	Status    string `json:"status,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	// Ref identifies the object behind the item when it is not a session,
+	// e.g. a schedule or notification ID
+	Ref string `json:"ref,omitempty"`
+}
+
+// Query selects the activity of one user
+type Query struct {
+	UserID string
+	// Since drops items older than it
+	Since time.Time
+	// Kinds keeps only items of these kinds; empty keeps all
+	Kinds []string
+	// Limit caps the number of items; 0 means no limit
+	Limit int
+}
+
+// wants reports whether q includes items of kind
+func (q Query) wants(kind string) bool {
+	return len(q.Kinds) == 0 || slices.Contains(q.Kinds, kind)
+}
+
+// Source reads one kind of activity. Sources may return items outside the
+// query; the feed filters and orders them.
+type Source interface {
+	Activity(ctx context.Context, q Query) ([]Item, error)
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(ctx context.Context, q Query) ([]Item, error)
+
+// Activity calls f
+func (f SourceFunc) Activity(ctx context.Context, q Query) ([]Item, error) {
+	return f(ctx, q)
+}
+
+// Result is the feed of a query
+type Result struct {
+	// Items are ordered newest first
+	Items []Item
+	// Failed names the sources that could not be read
+	Failed []string
+}
+
+type namedSource struct {
+	name   string
+	kinds  []string
+	source Source
+}
+
+// Feed merges the activity of its sources
+type Feed struct {
+	mu      sync.RWMutex
+	sources []namedSource
+}
+
+// NewFeed returns a feed without sources
+func NewFeed() *Feed {
+	return &Feed{}
+}
+
+// Add registers a source under name. Kinds lists the item kinds it returns,
+// so that it is skipped for queries that exclude all of them.
+func (f *Feed) Add(name string, source Source, kinds ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sources = append(f.sources, namedSource{name: name, kinds: kinds, source: source})
+}
+
+// Collect queries the sources and merges their items
+func (f *Feed) Collect(ctx context.Context, q Query) Result {
+	f.mu.RLock()
+	sources := slices.Clone(f.sources)
+	f.mu.RUnlock()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		result Result
+	)
+	for _, s := range sources {
+		if len(s.kinds) > 0 && !slices.ContainsFunc(s.kinds, q.wants) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items, err := s.source.Activity(ctx, q)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("[ACTIVITY] Failed to read %s of user %s: %v", s.name, q.UserID, err)
+				result.Failed = append(result.Failed, s.name)
+				return
+			}
+			for _, item := range items {
+				if q.wants(item.Kind) && !item.Timestamp.Before(q.Since) {
+					result.Items = append(result.Items, item)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortStableFunc(result.Items, func(a, b Item) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	if q.Limit > 0 && len(result.Items) > q.Limit {
+		result.Items = result.Items[:q.Limit]
+	}
+	slices.Sort(result.Failed)
+	return result
+}
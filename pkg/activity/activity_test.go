@@ -0,0 +1,58 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func staticSource(items ...Item) Source {
+	return SourceFunc(func(context.Context, Query) ([]Item, error) { return items, nil })
+}
+
+func TestFeedCollectMergesNewestFirst(t *testing.T) {
+	now := time.Now()
+	feed := NewFeed()
+	feed.Add("sessions", staticSource(
+		Item{Kind: KindSession, SessionID: "s1", Timestamp: now.Add(-3 * time.Hour)},
+		Item{Kind: KindSession, SessionID: "old", Timestamp: now.Add(-48 * time.Hour)},
+	), KindSession)
+	feed.Add("notifications", staticSource(
+		Item{Kind: KindNotification, Ref: "n1", Timestamp: now.Add(-time.Hour)},
+	), KindNotification)
+	feed.Add("schedules", SourceFunc(func(context.Context, Query) ([]Item, error) {
+		return nil, errors.New("unavailable")
+	}), KindScheduleRun)
+
+	result := feed.Collect(context.Background(), Query{UserID: "u", Since: now.Add(-24 * time.Hour)})
+	if len(result.Items) != 2 || result.Items[0].Ref != "n1" || result.Items[1].SessionID != "s1" {
+		t.Fatalf("Collect() items = %+v, want n1 then s1", result.Items)
+	}
+	if len(result.Failed) != 1 || result.Failed[0] != "schedules" {
+		t.Fatalf("Collect() failed = %v, want [schedules]", result.Failed)
+	}
+}
+
+func TestFeedCollectKindsAndLimit(t *testing.T) {
+	now := time.Now()
+	called := false
+	feed := NewFeed()
+	feed.Add("sessions", staticSource(
+		Item{Kind: KindSession, SessionID: "s1", Timestamp: now.Add(-time.Minute)},
+		Item{Kind: KindSession, SessionID: "s2", Timestamp: now.Add(-2 * time.Minute)},
+		Item{Kind: KindSession, SessionID: "s3", Timestamp: now.Add(-3 * time.Minute)},
+	), KindSession)
+	feed.Add("notifications", SourceFunc(func(context.Context, Query) ([]Item, error) {
+		called = true
+		return nil, nil
+	}), KindNotification)
+
+	result := feed.Collect(context.Background(), Query{UserID: "u", Kinds: []string{KindSession}, Limit: 2})
+	if called {
+		t.Error("source of an excluded kind was queried")
+	}
+	if len(result.Items) != 2 || result.Items[0].SessionID != "s1" || result.Items[1].SessionID != "s2" {
+		t.Fatalf("Collect() items = %+v, want s1, s2", result.Items)
+	}
+}
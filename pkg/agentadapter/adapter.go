@@ -0,0 +1,272 @@
+// Package agentadapter describes how each agent type runs in a session: the
+// command of its agent server, the path polled until the server is up, the
+// shape of the message API the proxy talks to and where the agent reads its
+// configuration. The provisioner and the session managers look agent types up
+// here instead of switching on their names.
+package agentadapter
+
+import "sort"
+
+// MessageAPI is the HTTP API the agent server of a session exposes on
+// AGENTAPI_PORT.
+type MessageAPI string
+
+const (
+	// MessageAPIAgentAPI is the API of coder/agentapi: GET /status,
+	// GET /messages and POST /message.
+	MessageAPIAgentAPI MessageAPI = "agentapi"
+	// MessageAPIACP is the API of the acp-server bridge: agentapi-compatible
+	// status and messages, and JSON-RPC 2.0 (Agent Client Protocol) on
+	// POST /rpc.
+	MessageAPIACP MessageAPI = "acp"
+)
+
+// DefaultType is the agent type of sessions that request none: Claude Code
+// behind agentapi. Sessions store it as "".
+const DefaultType = "claude-agentapi"
+
+// ConfigLayout lists where an agent reads its configuration, relative to the
+// home directory. Empty paths are not used by the agent.
+type ConfigLayout struct {
+	// Instructions is the file of global instructions of the agent. The
+	// provisioner copies the managed ~/.claude/CLAUDE.md (with injected
+	// memories) there for agents other than Claude Code.
+	Instructions string
+	// Settings is the settings file of the agent
+	Settings string
+	// Credentials is the credentials file of the agent
+	Credentials string
+}
+
+// CommandOptions are the runtime inputs of the agent server command.
+type CommandOptions struct {
+	// Port is the port the agent server listens on
+	Port string
+	// Resume continues the last conversation of the working directory
+	Resume bool
+	// ClaudeArgs (CLAUDE_ARGS) are appended to the command line of Claude
+	// Code. Other agents ignore them.
+	ClaudeArgs string
+	// HistoryFile is where ACP bridges that support it write the
+	// conversation history for the Slack integration
+	HistoryFile string
+}
+
+// Adapter describes one agent type.
+type Adapter interface {
+	// Type is the agent_type of sessions using the adapter
+	Type() string
+	// Command returns the executable and arguments of the agent server
+	Command(opts CommandOptions) (string, []string)
+	// HealthPath is the path polled until the agent server responds 200
+	HealthPath() string
+	// MessageAPI is the API shape of the agent server
+	MessageAPI() MessageAPI
+	// ConfigLayout is where the agent reads its configuration
+	ConfigLayout() ConfigLayout
+	// CanResume reports whether the agent resumes a checkpointed
+	// conversation when started with CommandOptions.Resume
+	CanResume() bool
+}
+
+// agentAPIAdapter runs an agent CLI behind coder/agentapi, which drives the
+// terminal UI of the CLI. agentapiType selects the screen parser of agentapi
+// ("" keeps the default, Claude Code).
+type agentAPIAdapter struct {
+	agentType    string
+	agentapiType string
+	cli          string
+	resumeArg    string
+	// takesClaudeArgs appends CommandOptions.ClaudeArgs to cli
+	takesClaudeArgs bool
+	layout          ConfigLayout
+}
+
+func (a *agentAPIAdapter) Type() string               { return a.agentType }
+func (a *agentAPIAdapter) HealthPath() string         { return "/status" }
+func (a *agentAPIAdapter) MessageAPI() MessageAPI     { return MessageAPIAgentAPI }
+func (a *agentAPIAdapter) ConfigLayout() ConfigLayout { return a.layout }
+func (a *agentAPIAdapter) CanResume() bool            { return a.resumeArg != "" }
+
+func (a *agentAPIAdapter) Command(opts CommandOptions) (string, []string) {
+	cli := a.cli
+	if a.takesClaudeArgs && opts.ClaudeArgs != "" {
+		cli = cli + " " + opts.ClaudeArgs
+	}
+	if opts.Resume && a.resumeArg != "" {
+		cli = cli + " " + a.resumeArg
+	}
+	args := []string{"server"}
+	if a.agentapiType != "" {
+		args = append(args, "--type", a.agentapiType)
+	}
+	args = append(args,
+		"--allowed-hosts", "*",
+		"--allowed-origins", "*",
+		"--port", opts.Port,
+		"--",
+		"sh", "-c", cli,
+	)
+	return "agentapi", args
+}
+
+// acpAdapter runs an ACP agent over stdio behind the acp-server bridge of
+// agentapi-proxy.
+type acpAdapter struct {
+	agentType string
+	// bridgeArgs are acp-server flags such as --auto-approve
+	bridgeArgs []string
+	// writesHistory passes --output-file to the bridge
+	writesHistory bool
+	command       []string
+	layout        ConfigLayout
+}
+
+func (a *acpAdapter) Type() string               { return a.agentType }
+func (a *acpAdapter) HealthPath() string         { return "/status" }
+func (a *acpAdapter) MessageAPI() MessageAPI     { return MessageAPIACP }
+func (a *acpAdapter) ConfigLayout() ConfigLayout { return a.layout }
+func (a *acpAdapter) CanResume() bool            { return false }
+
+func (a *acpAdapter) Command(opts CommandOptions) (string, []string) {
+	args := []string{"acp-server", "--port", opts.Port}
+	if a.writesHistory && opts.HistoryFile != "" {
+		args = append(args, "--output-file", opts.HistoryFile)
+	}
+	args = append(args, a.bridgeArgs...)
+	args = append(args, "--")
+	args = append(args, a.command...)
+	return "agentapi-proxy", args
+}
+
+var claudeLayout = ConfigLayout{
+	Instructions: ".claude/CLAUDE.md",
+	Settings:     ".claude/settings.json",
+	Credentials:  ".claude/.credentials.json",
+}
+
+var codexLayout = ConfigLayout{
+	Instructions: ".codex/AGENTS.md",
+	Settings:     ".codex/config.toml",
+	Credentials:  ".codex/auth.json",
+}
+
+var adapters = map[string]Adapter{
+	DefaultType: &agentAPIAdapter{
+		agentType:       DefaultType,
+		cli:             "claude",
+		resumeArg:       "-c",
+		takesClaudeArgs: true,
+		layout:          claudeLayout,
+	},
+	"goose": &agentAPIAdapter{
+		agentType:    "goose",
+		agentapiType: "goose",
+		cli:          "goose",
+		layout: ConfigLayout{
+			Instructions: ".config/goose/.goosehints",
+			Settings:     ".config/goose/config.yaml",
+		},
+	},
+	"aider": &agentAPIAdapter{
+		agentType:    "aider",
+		agentapiType: "aider",
+		// aider reads conventions from files passed with --read
+		cli: "aider --read $HOME/.aider/CONVENTIONS.md",
+		layout: ConfigLayout{
+			Instructions: ".aider/CONVENTIONS.md",
+			Settings:     ".aider.conf.yml",
+		},
+	},
+	"codex": &agentAPIAdapter{
+		agentType:    "codex",
+		agentapiType: "codex",
+		cli:          "codex",
+		layout:       codexLayout,
+	},
+	// claude-agent-acp is the official ACP adapter for the Claude Agent SDK:
+	// https://github.com/agentclientprotocol/claude-agent-acp
+	"claude-acp": &acpAdapter{
+		agentType:     "claude-acp",
+		writesHistory: true,
+		command:       []string{"bunx", "@agentclientprotocol/claude-agent-acp"},
+		layout:        claudeLayout,
+	},
+	// codex-acp is the ACP adapter for OpenAI Codex:
+	// https://github.com/agentclientprotocol/codex-acp
+	"codex-acp": &acpAdapter{
+		agentType:  "codex-acp",
+		bridgeArgs: []string{"--auto-approve"},
+		command:    []string{"npx", "-y", "@agentclientprotocol/codex-acp"},
+		layout:     codexLayout,
+	},
+	// pi-acp starts `pi --mode rpc` with the pi-ollama-cloud provider:
+	// https://github.com/svkozak/pi-acp
+	"pi-ollama": &acpAdapter{
+		agentType:  "pi-ollama",
+		bridgeArgs: []string{"--auto-approve"},
+		command:    []string{"npx", "-y", "pi-acp"},
+		layout: ConfigLayout{
+			Instructions: ".pi/agent/AGENTS.md",
+			Settings:     ".pi/agent/settings.json",
+		},
+	},
+	// Cursor Agent CLI has a native ACP server:
+	// https://cursor.com/docs/cli/acp
+	"cursor": &acpAdapter{
+		agentType:  "cursor",
+		bridgeArgs: []string{"--auto-approve", "--raw-json-log"},
+		command:    []string{"agent", "acp"},
+		layout: ConfigLayout{
+			Settings: ".cursor/cli-config.json",
+		},
+	},
+}
+
+// Lookup returns the adapter of agentType. "" is the default agent type.
+func Lookup(agentType string) (Adapter, bool) {
+	if agentType == "" {
+		agentType = DefaultType
+	}
+	adapter, ok := adapters[agentType]
+	return adapter, ok
+}
+
+// Get returns the adapter of agentType, or the adapter of the default agent
+// type when agentType is unknown.
+func Get(agentType string) Adapter {
+	if adapter, ok := Lookup(agentType); ok {
+		return adapter
+	}
+	return adapters[DefaultType]
+}
+
+// Normalize returns the agent type stored for sessions requesting agentType:
+// known agent types other than the default are kept, everything else runs the
+// default agent and is stored as "".
+func Normalize(agentType string) string {
+	if agentType == DefaultType {
+		return ""
+	}
+	if _, ok := adapters[agentType]; ok {
+		return agentType
+	}
+	return ""
+}
+
+// IsACP reports whether sessions of agentType speak ACP through the
+// acp-server bridge.
+func IsACP(agentType string) bool {
+	adapter, ok := Lookup(agentType)
+	return ok && adapter.MessageAPI() == MessageAPIACP
+}
+
+// Types returns the known agent types in order.
+func Types() []string {
+	types := make([]string, 0, len(adapters))
+	for agentType := range adapters {
+		types = append(types, agentType)
+	}
+	sort.Strings(types)
+	return types
+}
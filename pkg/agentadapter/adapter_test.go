@@ -0,0 +1,64 @@
+package agentadapter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultCommand(t *testing.T) {
+	for _, agentType := range []string{"", DefaultType, "unknown-agent"} {
+		cmd, args := Get(agentType).Command(CommandOptions{Port: "8080", Resume: true, ClaudeArgs: "--verbose"})
+		want := []string{"server", "--allowed-hosts", "*", "--allowed-origins", "*", "--port", "8080", "--", "sh", "-c", "claude --verbose -c"}
+		if cmd != "agentapi" || !reflect.DeepEqual(args, want) {
+			t.Errorf("%q: command = %q %#v, want agentapi %#v", agentType, cmd, args, want)
+		}
+	}
+}
+
+func TestACPCommand(t *testing.T) {
+	cmd, args := Get("claude-acp").Command(CommandOptions{Port: "8080", HistoryFile: "/tmp/history.jsonl"})
+	want := []string{"acp-server", "--port", "8080", "--output-file", "/tmp/history.jsonl", "--", "bunx", "@agentclientprotocol/claude-agent-acp"}
+	if cmd != "agentapi-proxy" || !reflect.DeepEqual(args, want) {
+		t.Errorf("command = %q %#v, want agentapi-proxy %#v", cmd, args, want)
+	}
+}
+
+func TestAgentAPIAdapters(t *testing.T) {
+	cmd, args := Get("codex").Command(CommandOptions{Port: "8080", Resume: true, ClaudeArgs: "--verbose"})
+	want := []string{"server", "--type", "codex", "--allowed-hosts", "*", "--allowed-origins", "*", "--port", "8080", "--", "sh", "-c", "codex"}
+	if cmd != "agentapi" || !reflect.DeepEqual(args, want) {
+		t.Errorf("command = %q %#v, want agentapi %#v", cmd, args, want)
+	}
+	for _, agentType := range []string{"goose", "aider", "codex"} {
+		adapter := Get(agentType)
+		if adapter.Type() != agentType || adapter.MessageAPI() != MessageAPIAgentAPI || adapter.CanResume() {
+			t.Errorf("%s: type %q, message API %q, resume %v", agentType, adapter.Type(), adapter.MessageAPI(), adapter.CanResume())
+		}
+		if adapter.ConfigLayout().Instructions == "" {
+			t.Errorf("%s: no instructions file", agentType)
+		}
+	}
+}
+
+func TestIsACPAndNormalize(t *testing.T) {
+	tests := []struct {
+		agentType  string
+		acp        bool
+		normalized string
+	}{
+		{agentType: "", normalized: ""},
+		{agentType: DefaultType, normalized: ""},
+		{agentType: "goose", normalized: "goose"},
+		{agentType: "cursor", acp: true, normalized: "cursor"},
+		{agentType: "pi-ollama", acp: true, normalized: "pi-ollama"},
+		{agentType: "unknown-agent", normalized: ""},
+	}
+	for _, tt := range tests {
+		if got := IsACP(tt.agentType); got != tt.acp {
+			t.Errorf("IsACP(%q) = %v, want %v", tt.agentType, got, tt.acp)
+		}
+		if got := Normalize(tt.agentType); got != tt.normalized {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.agentType, got, tt.normalized)
+		}
+	}
+}
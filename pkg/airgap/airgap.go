@@ -0,0 +1,186 @@
+// Package airgap implements the helpers behind the air-gapped deployment mode:
+// rewriting container image references to internal registry mirrors and
+// detecting configuration values that still point outside the cluster.
+package airgap
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultRegistry is the registry implied by image references without a host
+// (e.g. "busybox:1.36").
+const DefaultRegistry = "docker.io"
+
+// Mirror maps an upstream registry to an internal mirror.
+type Mirror struct {
+	// Registry is the upstream registry host (e.g. "ghcr.io", "docker.io").
+	Registry string
+	// Target is the mirror prefix that replaces Registry (e.g. "registry.corp/ghcr").
+	Target string
+}
+
+// ImageRegistry returns the registry host of an image reference.
+// References without an explicit host resolve to DefaultRegistry.
+func ImageRegistry(image string) string {
+	registry, _ := splitImage(image)
+	return registry
+}
+
+// TargetHost returns the registry host of a mirror target such as
+// "registry.corp/ghcr".
+func TargetHost(target string) string {
+	host, _, _ := strings.Cut(strings.TrimSuffix(target, "/"), "/")
+	return strings.ToLower(host)
+}
+
+// RewriteImage points image at its internal mirror. A mirror whose Registry
+// matches the image registry wins; otherwise defaultTarget (if set) replaces
+// the registry. Images already served from a mirror target are left alone.
+func RewriteImage(image string, mirrors []Mirror, defaultTarget string) string {
+	if image == "" {
+		return image
+	}
+	for _, m := range mirrors {
+		if hasPathPrefix(image, m.Target) {
+			return image
+		}
+	}
+	if defaultTarget != "" && hasPathPrefix(image, defaultTarget) {
+		return image
+	}
+
+	registry, path := splitImage(image)
+	for _, m := range mirrors {
+		if m.Target != "" && strings.EqualFold(m.Registry, registry) {
+			return strings.TrimSuffix(m.Target, "/") + "/" + path
+		}
+	}
+	if defaultTarget != "" {
+		return strings.TrimSuffix(defaultTarget, "/") + "/" + path
+	}
+	return image
+}
+
+// splitImage splits image into its registry host and repository path.
+// Docker Hub official images get the implicit "library/" namespace.
+func splitImage(image string) (string, string) {
+	first, rest, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return strings.ToLower(first), rest
+	}
+	if !found {
+		return DefaultRegistry, "library/" + image
+	}
+	return DefaultRegistry, image
+}
+
+func hasPathPrefix(s, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix != "" && strings.HasPrefix(s, prefix+"/")
+}
+
+// Kind classifies a Reference.
+type Kind string
+
+const (
+	// KindImage is a container image reference.
+	KindImage Kind = "image"
+	// KindURL is an HTTP(S) endpoint.
+	KindURL Kind = "url"
+)
+
+// Reference is a configuration value that may require network access.
+type Reference struct {
+	// Field is the configuration key the value came from (e.g. "scia.session_sidecar_image").
+	Field string
+	Kind  Kind
+	Value string
+}
+
+// Finding is a Reference that points outside the allowed hosts.
+type Finding struct {
+	Reference
+	Host string
+}
+
+// String formats the finding for startup logs.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s=%q reaches external host %s", f.Kind, f.Field, f.Value, f.Host)
+}
+
+// Validate returns the references whose host is not internal. A host is
+// internal when it is a loopback or private IP, ends in ".svc" or
+// ".cluster.local", or matches an allowedHosts entry. Entries match exactly,
+// or as a domain suffix when they start with "." or "*.".
+func Validate(refs []Reference, allowedHosts []string) []Finding {
+	var findings []Finding
+	for _, ref := range refs {
+		if ref.Value == "" {
+			continue
+		}
+		host := referenceHost(ref)
+		if host == "" || IsInternalHost(host, allowedHosts) {
+			continue
+		}
+		findings = append(findings, Finding{Reference: ref, Host: host})
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Field < findings[j].Field })
+	return findings
+}
+
+func referenceHost(ref Reference) string {
+	switch ref.Kind {
+	case KindImage:
+		return ImageRegistry(ref.Value)
+	case KindURL:
+		u, err := url.Parse(ref.Value)
+		if err != nil {
+			return ""
+		}
+		return u.Host
+	}
+	return ""
+}
+
+// IsInternalHost reports whether host (optionally with a port) is reachable
+// without leaving the private network.
+func IsInternalHost(host string, allowedHosts []string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+	}
+	if !strings.Contains(host, ".") || strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".cluster.local") {
+		return true
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if h, _, err := net.SplitHostPort(allowed); err == nil {
+			allowed = h
+		}
+		switch {
+		case allowed == "":
+			continue
+		case strings.HasPrefix(allowed, "*."):
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		case strings.HasPrefix(allowed, "."):
+			if strings.HasSuffix(host, allowed) || host == allowed[1:] {
+				return true
+			}
+		case host == allowed:
+			return true
+		}
+	}
+	return false
+}
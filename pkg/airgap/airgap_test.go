@@ -0,0 +1,66 @@
+package airgap_test
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/airgap"
+)
+
+func TestRewriteImage(t *testing.T) {
+	mirrors := []airgap.Mirror{
+		{Registry: "ghcr.io", Target: "registry.corp/ghcr"},
+	}
+	tests := []struct {
+		image         string
+		defaultTarget string
+		want          string
+	}{
+		{"ghcr.io/takutakahashi/scia:0.17.0", "", "registry.corp/ghcr/takutakahashi/scia:0.17.0"},
+		{"busybox:1.36", "registry.corp/hub", "registry.corp/hub/library/busybox:1.36"},
+		{"gcr.io/istio-release/iptables@sha256:abc", "registry.corp/all/", "registry.corp/all/istio-release/iptables@sha256:abc"},
+		{"docker:dind", "", "docker:dind"},
+		{"registry.corp/ghcr/takutakahashi/nfa:1", "registry.corp/hub", "registry.corp/ghcr/takutakahashi/nfa:1"},
+		{"", "registry.corp/hub", ""},
+	}
+	for _, tt := range tests {
+		if got := airgap.RewriteImage(tt.image, mirrors, tt.defaultTarget); got != tt.want {
+			t.Errorf("RewriteImage(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	for image, want := range map[string]string{
+		"busybox:1.36":             "docker.io",
+		"library/ubuntu":           "docker.io",
+		"ghcr.io/org/app:1":        "ghcr.io",
+		"localhost/app":            "localhost",
+		"registry.corp:5000/x/y:1": "registry.corp:5000",
+	} {
+		if got := airgap.ImageRegistry(image); got != want {
+			t.Errorf("ImageRegistry(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	refs := []airgap.Reference{
+		{Field: "b.image", Kind: airgap.KindImage, Value: "ghcr.io/org/app:1"},
+		{Field: "a.url", Kind: airgap.KindURL, Value: "https://api.github.com"},
+		{Field: "mirror", Kind: airgap.KindImage, Value: "registry.corp/hub/app:1"},
+		{Field: "svc", Kind: airgap.KindURL, Value: "http://agentapi-proxy.ns.svc.cluster.local:8080"},
+		{Field: "ip", Kind: airgap.KindURL, Value: "http://10.0.0.5:9000"},
+		{Field: "ghes", Kind: airgap.KindURL, Value: "https://ghe.internal.corp/api/v3"},
+		{Field: "empty", Kind: airgap.KindURL},
+	}
+	findings := airgap.Validate(refs, []string{"registry.corp", ".internal.corp"})
+	if len(findings) != 2 {
+		t.Fatalf("findings = %v, want 2", findings)
+	}
+	if findings[0].Field != "a.url" || findings[0].Host != "api.github.com" {
+		t.Errorf("findings[0] = %+v", findings[0])
+	}
+	if findings[1].Field != "b.image" || findings[1].Host != "ghcr.io" {
+		t.Errorf("findings[1] = %+v", findings[1])
+	}
+}
@@ -57,6 +57,7 @@ type UserCache struct {
 
 // GitHubAuthProvider handles GitHub OAuth authentication
 type GitHubAuthProvider struct {
+	configMu        sync.RWMutex
 	config          *config.GitHubAuthConfig
 	client          *http.Client
 	userCache       *utils.TTLCache       // token hash → UserCache, TTL 30s
@@ -82,6 +83,21 @@ func NewGitHubAuthProvider(cfg *config.GitHubAuthConfig) *GitHubAuthProvider {
 	}
 }
 
+// cfg returns the current configuration
+func (p *GitHubAuthProvider) cfg() *config.GitHubAuthConfig {
+	p.configMu.RLock()
+	defer p.configMu.RUnlock()
+	return p.config
+}
+
+// SetConfig replaces the configuration on a config reload. Cached users keep
+// their role until their cache entry expires.
+func (p *GitHubAuthProvider) SetConfig(cfg *config.GitHubAuthConfig) {
+	p.configMu.Lock()
+	defer p.configMu.Unlock()
+	p.config = cfg
+}
+
 // SetTeamMappingRepo injects a persistent ConfigMap-backed team mapping repository.
 // When set, team memberships will be read from and written to the ConfigMap as a
 // secondary cache layer (behind the 30-second in-memory teamCache).
@@ -89,6 +105,12 @@ func (p *GitHubAuthProvider) SetTeamMappingRepo(repo TeamMappingRepository) {
 	p.teamMappingRepo = repo
 }
 
+// InvalidateTeams drops the in-memory team memberships of a user, so that
+// the next authentication reads them from the team mapping repository
+func (p *GitHubAuthProvider) InvalidateTeams(username string) {
+	p.teamCache.Delete(username)
+}
+
 // isTestEnvironment detects if running in test environment
 func isTestEnvironment() bool {
 	// Check for test environment indicators
@@ -188,7 +210,7 @@ func (p *GitHubAuthProvider) Authenticate(ctx context.Context, token string) (*U
 
 // getUser retrieves user information from GitHub API without caching
 func (p *GitHubAuthProvider) getUser(ctx context.Context, token string) (*GitHubUserInfo, error) {
-	url := fmt.Sprintf("%s/user", strings.TrimSuffix(p.config.BaseURL, "/"))
+	url := fmt.Sprintf("%s/user", strings.TrimSuffix(p.cfg().BaseURL, "/"))
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -280,8 +302,8 @@ func (p *GitHubAuthProvider) getUserTeamsWithWildcard(ctx context.Context, token
 	}
 
 	// Get all configured patterns
-	patterns := make([]string, 0, len(p.config.UserMapping.TeamRoleMapping))
-	for pattern := range p.config.UserMapping.TeamRoleMapping {
+	patterns := make([]string, 0, len(p.cfg().UserMapping.TeamRoleMapping))
+	for pattern := range p.cfg().UserMapping.TeamRoleMapping {
 		patterns = append(patterns, pattern)
 	}
 
@@ -310,7 +332,7 @@ func (p *GitHubAuthProvider) getUserTeamsWithWildcard(ctx context.Context, token
 func (p *GitHubAuthProvider) getUserTeamsExactMatch(ctx context.Context, token, username string) ([]GitHubTeamMembership, error) {
 	// Extract unique organizations from configured team mappings
 	configuredOrgs := make(map[string][]string) // org -> []teamSlugs
-	for teamKey := range p.config.UserMapping.TeamRoleMapping {
+	for teamKey := range p.cfg().UserMapping.TeamRoleMapping {
 		parts := strings.Split(teamKey, "/")
 		if len(parts) == 2 {
 			org, teamSlug := parts[0], parts[1]
@@ -387,7 +409,7 @@ func (p *GitHubAuthProvider) getUserTeamsExactMatch(ctx context.Context, token,
 // checkTeamMembership checks if user is a member of a specific team without caching
 func (p *GitHubAuthProvider) checkTeamMembership(ctx context.Context, token, org, teamSlug, username string) (bool, string) {
 	url := fmt.Sprintf("%s/orgs/%s/teams/%s/memberships/%s",
-		strings.TrimSuffix(p.config.BaseURL, "/"), org, teamSlug, username)
+		strings.TrimSuffix(p.cfg().BaseURL, "/"), org, teamSlug, username)
 
 	log.Printf("[AUTH_DEBUG] Checking team membership: org=%s, team=%s, user=%s", org, teamSlug, username)
 
@@ -433,7 +455,7 @@ func (p *GitHubAuthProvider) checkTeamMembership(ctx context.Context, token, org
 
 // getUserOrganizations retrieves user's organizations from GitHub API without caching
 func (p *GitHubAuthProvider) getUserOrganizations(ctx context.Context, token string) ([]GitHubOrganization, error) {
-	url := fmt.Sprintf("%s/user/orgs", strings.TrimSuffix(p.config.BaseURL, "/"))
+	url := fmt.Sprintf("%s/user/orgs", strings.TrimSuffix(p.cfg().BaseURL, "/"))
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -468,17 +490,17 @@ func (p *GitHubAuthProvider) getUserOrganizations(ctx context.Context, token str
 // Returns: role, permissions, envFile
 func (p *GitHubAuthProvider) mapUserPermissions(teams []GitHubTeamMembership) (string, []string, string) {
 	log.Printf("[AUTH_DEBUG] Starting mapUserPermissions")
-	log.Printf("[AUTH_DEBUG] Default role: %s", p.config.UserMapping.DefaultRole)
-	log.Printf("[AUTH_DEBUG] Default permissions: %v", p.config.UserMapping.DefaultPermissions)
-	log.Printf("[AUTH_DEBUG] Team role mappings: %+v", p.config.UserMapping.TeamRoleMapping)
+	log.Printf("[AUTH_DEBUG] Default role: %s", p.cfg().UserMapping.DefaultRole)
+	log.Printf("[AUTH_DEBUG] Default permissions: %v", p.cfg().UserMapping.DefaultPermissions)
+	log.Printf("[AUTH_DEBUG] Team role mappings: %+v", p.cfg().UserMapping.TeamRoleMapping)
 	log.Printf("[AUTH_DEBUG] User teams: %+v", teams)
 
-	highestRole := p.config.UserMapping.DefaultRole
+	highestRole := p.cfg().UserMapping.DefaultRole
 	allPermissions := make(map[string]bool)
 	envFile := "" // Track the env file for the highest priority team
 
 	// Add default permissions
-	for _, perm := range p.config.UserMapping.DefaultPermissions {
+	for _, perm := range p.cfg().UserMapping.DefaultPermissions {
 		allPermissions[perm] = true
 		log.Printf("[AUTH_DEBUG] Added default permission: %s", perm)
 	}
@@ -491,7 +513,7 @@ func (p *GitHubAuthProvider) mapUserPermissions(teams []GitHubTeamMembership) (s
 		matchFound := false
 
 		// Check all configured patterns for matches
-		for pattern, rule := range p.config.UserMapping.TeamRoleMapping {
+		for pattern, rule := range p.cfg().UserMapping.TeamRoleMapping {
 			if matchTeamPattern(pattern, team.Organization, team.TeamSlug) {
 				matchFound = true
 				log.Printf("[AUTH_DEBUG] Found matching rule for %s (pattern: %s): role=%s, permissions=%v", teamKey, pattern, rule.Role, rule.Permissions)
@@ -518,7 +540,7 @@ func (p *GitHubAuthProvider) mapUserPermissions(teams []GitHubTeamMembership) (s
 		if !matchFound {
 			log.Printf("[AUTH_DEBUG] No rule found for team: %s", teamKey)
 			log.Printf("[AUTH_DEBUG] Available team mappings:")
-			for availableKey := range p.config.UserMapping.TeamRoleMapping {
+			for availableKey := range p.cfg().UserMapping.TeamRoleMapping {
 				log.Printf("[AUTH_DEBUG]   - %s", availableKey)
 			}
 		}
@@ -634,7 +656,7 @@ func matchTeamPattern(pattern, org, teamSlug string) bool {
 
 // hasWildcardPatterns checks if any team mappings contain wildcard patterns
 func (p *GitHubAuthProvider) hasWildcardPatterns() bool {
-	for teamKey := range p.config.UserMapping.TeamRoleMapping {
+	for teamKey := range p.cfg().UserMapping.TeamRoleMapping {
 		if strings.Contains(teamKey, "*") {
 			return true
 		}
@@ -650,7 +672,7 @@ func (p *GitHubAuthProvider) getAllUserTeams(ctx context.Context, token string)
 
 	for {
 		url := fmt.Sprintf("%s/user/teams?per_page=%d&page=%d",
-			strings.TrimSuffix(p.config.BaseURL, "/"), perPage, page)
+			strings.TrimSuffix(p.cfg().BaseURL, "/"), perPage, page)
 
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
@@ -46,8 +46,8 @@ func AuthMiddleware(cfg *config.Config, authService services.AuthService) echo.M
 				return next(c)
 			}
 
-			// Skip auth for health endpoint
-			if path == "/health" {
+			// Skip auth for health, readiness and metrics endpoints
+			if path == "/health" || path == "/ready" || path == "/metrics" {
 				return next(c)
 			}
 
@@ -57,8 +57,8 @@ func AuthMiddleware(cfg *config.Config, authService services.AuthService) echo.M
 				return next(c)
 			}
 
-			// Skip auth for public static files
-			if strings.HasPrefix(path, "/public") {
+			// Skip auth for public static files and the API documentation
+			if strings.HasPrefix(path, "/public") || path == "/openapi.json" || path == "/docs" {
 				return next(c)
 			}
 
@@ -190,6 +190,13 @@ func GetUserFromContext(c echo.Context) *entities.User {
 	return nil
 }
 
+// GetAPIKeyFingerprint returns a fingerprint of the API key that authenticated
+// the request, or "" when another authentication method was used
+func GetAPIKeyFingerprint(c echo.Context) string {
+	fp, _ := c.Get("api_key_fingerprint").(string)
+	return fp
+}
+
 // GetAuthorizationContext retrieves the pre-built authorization context from Echo context
 func GetAuthorizationContext(c echo.Context) *AuthorizationContext {
 	if authzCtx := c.Get("authz_context"); authzCtx != nil {
@@ -315,6 +322,9 @@ func tryInternalAPIKeyAuth(c echo.Context, cfg *config.Config, authService servi
 		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Invalid API key")
 	}
 
+	// Record a fingerprint (never the key itself) so per-key rate limits can apply
+	c.Set("api_key_fingerprint", hashToken(apiKey))
+
 	return user, nil
 }
 
@@ -380,7 +390,15 @@ func isOAuthEndpoint(path string) bool {
 // These endpoints use HMAC signature verification instead of standard authentication
 func isWebhookReceiverEndpoint(path string) bool {
 	// Webhook receiver endpoints (not management endpoints)
-	if strings.HasPrefix(path, "/hooks/") {
+	if strings.HasPrefix(path, "/hooks/") || strings.HasPrefix(path, "/webhooks/generic/") || path == "/webhooks/github" {
+		return true
+	}
+	// GitHub team membership events — verified with the team sync webhook secret
+	if path == "/webhooks/github/team-sync" {
+		return true
+	}
+	// Slack slash commands — verified with the Slack signing secret
+	if path == "/slack/commands" {
 		return true
 	}
 	// Session manager forwarding endpoint — uses HMAC-SHA256 signature verification
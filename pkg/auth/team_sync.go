@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+)
+
+// TeamDirectory lists GitHub teams and their members independently of any
+// user's token, e.g. through a GitHub App installation
+type TeamDirectory interface {
+	ListTeams(ctx context.Context, org string) ([]github_pkg.OrgTeam, error)
+	ListTeamMembers(ctx context.Context, org, teamSlug string) ([]github_pkg.TeamMember, error)
+	// TeamMembership returns nil when login is not an active member
+	TeamMembership(ctx context.Context, org, teamSlug, login string) (*github_pkg.TeamMember, error)
+}
+
+// TeamMembershipRepository is a TeamMappingRepository that TeamSync fills.
+// Synced entries are authoritative: they do not expire with the cache TTL,
+// and an empty synced entry means the user belongs to no configured team.
+type TeamMembershipRepository interface {
+	TeamMappingRepository
+
+	// SetSynced stores the team memberships of a user found by a sync
+	SetSynced(ctx context.Context, username string, teams []GitHubTeamMembership) error
+
+	// SyncedUsernames returns the users with a synced entry
+	SyncedUsernames(ctx context.Context) ([]string, error)
+}
+
+// TeamSync keeps the team memberships of users in a TeamMembershipRepository
+// in line with GitHub, so that authentication reads them instead of asking
+// GitHub with the user's token. Sync walks all configured teams; the webhook
+// events handled by HandleEvent update single users in between.
+type TeamSync struct {
+	provider  *GitHubAuthProvider
+	directory TeamDirectory
+	repo      TeamMembershipRepository
+
+	mu       sync.Mutex // serializes syncs and event updates
+	lastSync time.Time
+}
+
+// NewTeamSync creates a new TeamSync for the teams configured in provider
+func NewTeamSync(provider *GitHubAuthProvider, directory TeamDirectory, repo TeamMembershipRepository) *TeamSync {
+	return &TeamSync{
+		provider:  provider,
+		directory: directory,
+		repo:      repo,
+	}
+}
+
+// Run syncs immediately and then every interval until ctx is cancelled
+func (s *TeamSync) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			log.Printf("[TEAM_SYNC] Sync failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastSync returns when the last successful sync finished
+func (s *TeamSync) LastSync() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSync
+}
+
+// Sync reads the members of every configured team and stores the teams of
+// each member. Users synced before who left all teams get an empty entry.
+// Nothing is stored when a team cannot be read, so a partial view never
+// drops memberships.
+func (s *TeamSync) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teams, err := s.configuredTeams(ctx)
+	if err != nil {
+		return err
+	}
+	memberships := make(map[string][]GitHubTeamMembership)
+	for _, team := range teams {
+		members, err := s.directory.ListTeamMembers(ctx, team.Organization, team.TeamSlug)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			membership := team
+			membership.Role = m.Role
+			memberships[m.Login] = append(memberships[m.Login], membership)
+		}
+	}
+
+	previous, err := s.repo.SyncedUsernames(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list synced users: %w", err)
+	}
+	for _, username := range previous {
+		if _, ok := memberships[username]; !ok {
+			memberships[username] = []GitHubTeamMembership{}
+		}
+	}
+
+	for username, userTeams := range memberships {
+		if err := s.store(ctx, username, userTeams); err != nil {
+			return err
+		}
+	}
+	s.lastSync = time.Now()
+	log.Printf("[TEAM_SYNC] Synced %d team(s), %d user(s)", len(teams), len(memberships))
+	return nil
+}
+
+// teamSyncEvent holds the fields of the GitHub webhook events TeamSync uses
+type teamSyncEvent struct {
+	Action string `json:"action"`
+	Member *struct {
+		Login string `json:"login"`
+	} `json:"member"`
+	Membership *struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"membership"`
+	Team *struct {
+		Slug string `json:"slug"`
+	} `json:"team"`
+	Organization *struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// HandleEvent applies a GitHub webhook event:
+//   - membership: the teams of the added or removed member are read again
+//   - organization member_added/member_removed: likewise for the member
+//   - team created/edited/deleted: a full sync runs in the background
+//
+// Other events are ignored.
+func (s *TeamSync) HandleEvent(ctx context.Context, eventType string, payload []byte) error {
+	var event teamSyncEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid %s event payload: %w", eventType, err)
+	}
+
+	switch eventType {
+	case "membership":
+		if event.Member == nil || event.Team == nil || event.Organization == nil {
+			return nil
+		}
+		if !s.isConfiguredTeam(event.Organization.Login, event.Team.Slug) {
+			return nil
+		}
+		return s.SyncUser(ctx, event.Member.Login)
+	case "organization":
+		if event.Membership == nil || (event.Action != "member_added" && event.Action != "member_removed") {
+			return nil
+		}
+		return s.SyncUser(ctx, event.Membership.User.Login)
+	case "team":
+		switch event.Action {
+		case "created", "edited", "deleted":
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+				defer cancel()
+				if err := s.Sync(ctx); err != nil {
+					log.Printf("[TEAM_SYNC] Sync after team %s event failed: %v", event.Action, err)
+				}
+			}()
+		}
+	}
+	return nil
+}
+
+// SyncUser reads the memberships of one user in every configured team and
+// stores them
+func (s *TeamSync) SyncUser(ctx context.Context, username string) error {
+	if username == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	teams, err := s.configuredTeams(ctx)
+	if err != nil {
+		return err
+	}
+	userTeams := []GitHubTeamMembership{}
+	for _, team := range teams {
+		member, err := s.directory.TeamMembership(ctx, team.Organization, team.TeamSlug, username)
+		if err != nil {
+			return err
+		}
+		if member != nil {
+			team.Role = member.Role
+			userTeams = append(userTeams, team)
+		}
+	}
+	if err := s.store(ctx, username, userTeams); err != nil {
+		return err
+	}
+	log.Printf("[TEAM_SYNC] Synced user %s (%d team(s))", username, len(userTeams))
+	return nil
+}
+
+// store saves the teams of a user and drops them from the in-memory cache
+// of the provider
+func (s *TeamSync) store(ctx context.Context, username string, teams []GitHubTeamMembership) error {
+	if err := s.repo.SetSynced(ctx, username, teams); err != nil {
+		return fmt.Errorf("failed to store teams of %s: %w", username, err)
+	}
+	s.provider.InvalidateTeams(username)
+	return nil
+}
+
+// configuredTeams returns the teams matching the team_role_mapping patterns,
+// sorted by organization and slug. Patterns with a wildcard organization
+// cannot be listed and are left to per-request lookups.
+func (s *TeamSync) configuredTeams(ctx context.Context) ([]GitHubTeamMembership, error) {
+	var patterns []string
+	orgs := make(map[string]bool)
+	for pattern := range s.provider.cfg().UserMapping.TeamRoleMapping {
+		parts := strings.Split(pattern, "/")
+		if len(parts) != 2 {
+			continue
+		}
+		if strings.Contains(parts[0], "*") {
+			log.Printf("[TEAM_SYNC] Skipping pattern %q: organizations cannot be wildcards", pattern)
+			continue
+		}
+		patterns = append(patterns, pattern)
+		orgs[parts[0]] = true
+	}
+
+	var teams []GitHubTeamMembership
+	for org := range orgs {
+		orgTeams, err := s.directory.ListTeams(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range orgTeams {
+			for _, pattern := range patterns {
+				if matchTeamPattern(pattern, org, t.Slug) {
+					teams = append(teams, GitHubTeamMembership{Organization: org, TeamSlug: t.Slug, TeamName: t.Name})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if teams[i].Organization != teams[j].Organization {
+			return teams[i].Organization < teams[j].Organization
+		}
+		return teams[i].TeamSlug < teams[j].TeamSlug
+	})
+	return teams, nil
+}
+
+// isConfiguredTeam reports whether a team matches a team_role_mapping pattern
+func (s *TeamSync) isConfiguredTeam(org, teamSlug string) bool {
+	for pattern := range s.provider.cfg().UserMapping.TeamRoleMapping {
+		if matchTeamPattern(pattern, org, teamSlug) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+)
+
+type fakeTeamDirectory struct {
+	teams   map[string][]github_pkg.OrgTeam
+	members map[string][]github_pkg.TeamMember // "org/slug" -> members
+}
+
+func (d *fakeTeamDirectory) ListTeams(_ context.Context, org string) ([]github_pkg.OrgTeam, error) {
+	return d.teams[org], nil
+}
+
+func (d *fakeTeamDirectory) ListTeamMembers(_ context.Context, org, teamSlug string) ([]github_pkg.TeamMember, error) {
+	return d.members[org+"/"+teamSlug], nil
+}
+
+func (d *fakeTeamDirectory) TeamMembership(_ context.Context, org, teamSlug, login string) (*github_pkg.TeamMember, error) {
+	for _, m := range d.members[org+"/"+teamSlug] {
+		if m.Login == login {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+type memoryTeamMembershipRepository struct {
+	entries map[string][]GitHubTeamMembership
+	synced  map[string]bool
+}
+
+func (r *memoryTeamMembershipRepository) Get(_ context.Context, username string) ([]GitHubTeamMembership, bool, error) {
+	teams, ok := r.entries[username]
+	return teams, ok, nil
+}
+
+func (r *memoryTeamMembershipRepository) Set(_ context.Context, username string, teams []GitHubTeamMembership) error {
+	r.entries[username] = teams
+	return nil
+}
+
+func (r *memoryTeamMembershipRepository) SetSynced(_ context.Context, username string, teams []GitHubTeamMembership) error {
+	r.entries[username] = teams
+	r.synced[username] = true
+	return nil
+}
+
+func (r *memoryTeamMembershipRepository) SyncedUsernames(_ context.Context) ([]string, error) {
+	var usernames []string
+	for username := range r.synced {
+		usernames = append(usernames, username)
+	}
+	return usernames, nil
+}
+
+func TestTeamSync(t *testing.T) {
+	provider := NewGitHubAuthProvider(&config.GitHubAuthConfig{
+		UserMapping: config.GitHubUserMapping{TeamRoleMapping: map[string]config.TeamRoleRule{
+			"acme/ml":         {Role: "member"},
+			"acme/backend-*":  {Role: "developer"},
+			"*/platform":      {Role: "admin"},
+			"other-org/infra": {Role: "admin"},
+		}},
+	})
+	directory := &fakeTeamDirectory{
+		teams: map[string][]github_pkg.OrgTeam{
+			"acme":      {{Slug: "ml", Name: "ML"}, {Slug: "backend-api", Name: "Backend API"}, {Slug: "design", Name: "Design"}},
+			"other-org": {{Slug: "infra", Name: "Infra"}},
+		},
+		members: map[string][]github_pkg.TeamMember{
+			"acme/ml":          {{Login: "alice", Role: "maintainer"}, {Login: "bob", Role: "member"}},
+			"acme/backend-api": {{Login: "bob", Role: "member"}},
+			"acme/design":      {{Login: "carol", Role: "member"}},
+		},
+	}
+	repo := &memoryTeamMembershipRepository{entries: map[string][]GitHubTeamMembership{}, synced: map[string]bool{"dave": true}}
+	sync := NewTeamSync(provider, directory, repo)
+	ctx := context.Background()
+
+	require.NoError(t, sync.Sync(ctx))
+	assert.Equal(t, []GitHubTeamMembership{{Organization: "acme", TeamSlug: "ml", TeamName: "ML", Role: "maintainer"}}, repo.entries["alice"])
+	assert.Equal(t, []GitHubTeamMembership{
+		{Organization: "acme", TeamSlug: "backend-api", TeamName: "Backend API", Role: "member"},
+		{Organization: "acme", TeamSlug: "ml", TeamName: "ML", Role: "member"},
+	}, repo.entries["bob"])
+	assert.NotContains(t, repo.entries, "carol", "teams outside team_role_mapping are not synced")
+	assert.Equal(t, []GitHubTeamMembership{}, repo.entries["dave"], "users who left all teams get an empty entry")
+	assert.False(t, sync.LastSync().IsZero())
+
+	// bob leaves acme/ml
+	directory.members["acme/ml"] = directory.members["acme/ml"][:1]
+	require.NoError(t, sync.HandleEvent(ctx, "membership", []byte(`{"action":"removed","scope":"team","member":{"login":"bob"},"team":{"slug":"ml"},"organization":{"login":"acme"}}`)))
+	assert.Equal(t, []GitHubTeamMembership{{Organization: "acme", TeamSlug: "backend-api", TeamName: "Backend API", Role: "member"}}, repo.entries["bob"])
+
+	// Events of other teams are ignored
+	require.NoError(t, sync.HandleEvent(ctx, "membership", []byte(`{"action":"added","scope":"team","member":{"login":"carol"},"team":{"slug":"design"},"organization":{"login":"acme"}}`)))
+	assert.NotContains(t, repo.entries, "carol")
+}
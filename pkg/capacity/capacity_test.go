@@ -0,0 +1,102 @@
+package capacity
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecorderSample(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	t0 := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	sessions := []Session{{ID: "old", StartedAt: t0.Add(-time.Hour)}}
+	r := NewRecorder(store, func() []Session { return sessions }, 0)
+
+	// Sessions running at the first sample are not counted as created
+	if err := r.Sample(context.Background(), t0); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	sessions = []Session{
+		{ID: "old", StartedAt: t0.Add(-time.Hour)},
+		{ID: "a", StartedAt: t0.Add(2 * time.Minute)},
+		{ID: "b", StartedAt: t0.Add(3 * time.Minute)},
+	}
+	if err := r.Sample(context.Background(), t0.Add(5*time.Minute)); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	sessions = sessions[2:]
+	if err := r.Sample(context.Background(), t0.Add(70*time.Minute)); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+
+	// A new recorder reads the persisted history
+	buckets, err := NewRecorder(store, nil, 0).Buckets(context.Background())
+	if err != nil {
+		t.Fatalf("Buckets() error = %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("buckets = %+v, want 2 hours", buckets)
+	}
+	if b := buckets[0]; b.PeakActive != 3 || b.Created != 2 || b.Ended != 0 {
+		t.Errorf("first hour = %+v, want peak 3, created 2", b)
+	}
+	if b := buckets[1]; b.PeakActive != 1 || b.Ended != 2 || b.DurationSeconds != (130+68)*60 {
+		t.Errorf("second hour = %+v, want peak 1, 2 ended after 130 and 68 minutes", b)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	start := now.Truncate(time.Hour).Add(-21 * 24 * time.Hour)
+	var buckets []Bucket
+	for h := start; h.Before(now); h = h.Add(time.Hour) {
+		b := Bucket{Hour: h, PeakActive: 2, Created: 1}
+		// Mondays at 10:00 UTC are busy, more so in the most recent week
+		if h.Weekday() == time.Monday && h.Hour() == 10 {
+			b.PeakActive, b.Created = 10, 8
+			if now.Sub(h) < week {
+				b.PeakActive = 12
+			}
+		}
+		buckets = append(buckets, b)
+	}
+
+	report := Forecast(buckets, now, ForecastOptions{
+		SessionCPUMillis:   1000,
+		SessionMemoryBytes: 2 << 30,
+		NodeCPUMillis:      8000,
+		NodeMemoryBytes:    8 << 30,
+		Headroom:           0.25,
+	})
+
+	if report.HistoryDays != 21 || report.Confidence != "medium" {
+		t.Errorf("history = %d days (%s), want 21 days (medium)", report.HistoryDays, report.Confidence)
+	}
+	if report.GrowthFactor != 1 {
+		t.Errorf("growth factor = %v, want 1 with the same number of created sessions", report.GrowthFactor)
+	}
+	if len(report.Hours) != 168 || len(report.Days) != 8 {
+		t.Fatalf("forecast covers %d hours and %d days, want 168 hours over 8 days", len(report.Hours), len(report.Days))
+	}
+	if report.PeakConcurrent != 12 || report.PeakHour == nil || report.PeakHour.Weekday() != time.Monday || report.PeakHour.Hour() != 10 {
+		t.Errorf("peak = %d at %v, want 12 on Monday 10:00", report.PeakConcurrent, report.PeakHour)
+	}
+	if report.WarmPoolSize != 2 {
+		t.Errorf("warm pool = %d, want 2 for 8 sessions created in the busiest hour", report.WarmPoolSize)
+	}
+	// 12 sessions with 25% headroom = 15; memory fits 4 sessions per node
+	c := report.Capacity
+	if c == nil || c.Sessions != 15 || c.SessionsPerNode != 4 || c.LimitedBy != "memory" || c.NodesRequired != 4 {
+		t.Errorf("capacity = %+v, want 4 memory-bound nodes for 15 sessions", c)
+	}
+}
+
+func TestForecastWithoutHistory(t *testing.T) {
+	report := Forecast(nil, time.Now(), ForecastOptions{})
+	if report.PeakConcurrent != 0 || report.Confidence != "low" || report.Capacity != nil {
+		t.Errorf("empty forecast = %+v", report)
+	}
+}
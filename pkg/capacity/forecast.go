@@ -0,0 +1,225 @@
+package capacity
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	week = 7 * 24 * time.Hour
+
+	// minGrowth and maxGrowth bound the week-over-week growth factor so a
+	// single quiet or busy week does not dominate the forecast
+	minGrowth = 0.5
+	maxGrowth = 2.0
+)
+
+// ForecastOptions describes the resources used to turn concurrency into
+// nodes. Capacity is only reported when both the session and node sizes of
+// at least one resource are set.
+type ForecastOptions struct {
+	// SessionCPUMillis and SessionMemoryBytes are the requests of one session
+	SessionCPUMillis   int64
+	SessionMemoryBytes int64
+	// NodeCPUMillis and NodeMemoryBytes are the allocatable resources of one
+	// node available to sessions
+	NodeCPUMillis   int64
+	NodeMemoryBytes int64
+	// Headroom is the extra fraction of capacity on top of the forecast
+	// peak, e.g. 0.2 for 20%
+	Headroom float64
+}
+
+// HourForecast is the forecast of one hour
+type HourForecast struct {
+	Hour time.Time `json:"hour"`
+	// Concurrent is the expected peak of concurrent sessions
+	Concurrent int `json:"concurrent"`
+	// Created is the expected number of sessions started
+	Created int `json:"created"`
+}
+
+// DayForecast summarizes the forecast of one UTC day
+type DayForecast struct {
+	Date           string    `json:"date"`
+	PeakConcurrent int       `json:"peak_concurrent"`
+	PeakHour       time.Time `json:"peak_hour"`
+	Created        int       `json:"created"`
+}
+
+// NodeCapacity is the node count needed for the forecast peak
+type NodeCapacity struct {
+	NodesRequired int `json:"nodes_required"`
+	// Sessions is the forecast peak including headroom
+	Sessions int `json:"sessions"`
+	// SessionsPerNode is how many sessions fit on one node. When it is
+	// zero a session does not fit on a node and NodesRequired is zero.
+	SessionsPerNode int `json:"sessions_per_node"`
+	// LimitedBy is "cpu" or "memory"
+	LimitedBy string  `json:"limited_by"`
+	Headroom  float64 `json:"headroom"`
+}
+
+// Report is the capacity forecast for the week following GeneratedAt
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// HistoryFrom is the oldest hour of recorded history used
+	HistoryFrom *time.Time `json:"history_from,omitempty"`
+	HistoryDays int        `json:"history_days"`
+	// Confidence is "low" with less than a week of history, "medium" with
+	// less than four weeks and "high" otherwise
+	Confidence string `json:"confidence"`
+	// GrowthFactor scales the history by the change in created sessions
+	// between the last two weeks
+	GrowthFactor float64 `json:"growth_factor"`
+	// AverageDurationMinutes is the mean lifetime of ended sessions
+	AverageDurationMinutes float64    `json:"average_duration_minutes"`
+	PeakConcurrent         int        `json:"peak_concurrent"`
+	PeakHour               *time.Time `json:"peak_hour,omitempty"`
+	// WarmPoolSize is the number of pre-warmed sessions covering the
+	// sessions started in the busiest 15 minutes, assuming even arrival
+	// within the busiest hour
+	WarmPoolSize int            `json:"warm_pool_size"`
+	Capacity     *NodeCapacity  `json:"capacity,omitempty"`
+	Days         []DayForecast  `json:"days"`
+	Hours        []HourForecast `json:"hours"`
+}
+
+// Forecast predicts the 168 hours following now from hourly history. Each
+// hour is forecast from the same hour of the week in every recorded week:
+// concurrency takes the highest observed peak and created sessions the
+// mean, both scaled by the growth factor.
+func Forecast(buckets []Bucket, now time.Time, opts ForecastOptions) *Report {
+	now = now.UTC()
+	byHour := make(map[time.Time]Bucket, len(buckets))
+	var (
+		oldest        time.Time
+		ended         int
+		totalDuration float64
+	)
+	for _, b := range buckets {
+		hour := b.Hour.UTC()
+		byHour[hour] = b
+		if oldest.IsZero() || hour.Before(oldest) {
+			oldest = hour
+		}
+		ended += b.Ended
+		totalDuration += b.DurationSeconds
+	}
+
+	report := &Report{GeneratedAt: now, GrowthFactor: 1, Confidence: "low"}
+	if !oldest.IsZero() {
+		report.HistoryFrom = &oldest
+		report.HistoryDays = int(now.Sub(oldest).Hours() / 24)
+	}
+	switch {
+	case report.HistoryDays >= 28:
+		report.Confidence = "high"
+	case report.HistoryDays >= 7:
+		report.Confidence = "medium"
+	}
+	if ended > 0 {
+		report.AverageDurationMinutes = math.Round(totalDuration/float64(ended)/60*10) / 10
+	}
+	report.GrowthFactor = growthFactor(byHour, now, report.HistoryDays)
+
+	start := now.Truncate(time.Hour).Add(time.Hour)
+	maxWeeks := report.HistoryDays/7 + 1
+	busiestCreated := 0
+	for i := 0; i < 7*24; i++ {
+		hour := start.Add(time.Duration(i) * time.Hour)
+		peak, created, weeks := 0, 0, 0
+		for k := 1; k <= maxWeeks; k++ {
+			b, ok := byHour[hour.Add(-time.Duration(k)*week)]
+			if !ok {
+				continue
+			}
+			weeks++
+			created += b.Created
+			if b.PeakActive > peak {
+				peak = b.PeakActive
+			}
+		}
+		f := HourForecast{Hour: hour}
+		if weeks > 0 {
+			f.Concurrent = int(math.Ceil(float64(peak) * report.GrowthFactor))
+			f.Created = int(math.Ceil(float64(created) / float64(weeks) * report.GrowthFactor))
+		}
+		report.Hours = append(report.Hours, f)
+
+		if f.Concurrent > report.PeakConcurrent {
+			report.PeakConcurrent = f.Concurrent
+			peakHour := hour
+			report.PeakHour = &peakHour
+		}
+		if f.Created > busiestCreated {
+			busiestCreated = f.Created
+		}
+
+		date := hour.Format("2006-01-02")
+		if n := len(report.Days); n == 0 || report.Days[n-1].Date != date {
+			report.Days = append(report.Days, DayForecast{Date: date, PeakHour: hour})
+		}
+		day := &report.Days[len(report.Days)-1]
+		day.Created += f.Created
+		if f.Concurrent > day.PeakConcurrent {
+			day.PeakConcurrent = f.Concurrent
+			day.PeakHour = hour
+		}
+	}
+	report.WarmPoolSize = int(math.Ceil(float64(busiestCreated) / 4))
+	report.Capacity = nodeCapacity(report.PeakConcurrent, opts)
+	return report
+}
+
+// growthFactor compares the sessions created in the last seven days with the
+// seven days before. It is 1 with less than two weeks of history.
+func growthFactor(byHour map[time.Time]Bucket, now time.Time, historyDays int) float64 {
+	if historyDays < 14 {
+		return 1
+	}
+	end := now.Truncate(time.Hour)
+	var recent, previous int
+	for h := end.Add(-2 * week); h.Before(end); h = h.Add(time.Hour) {
+		if h.Before(end.Add(-week)) {
+			previous += byHour[h].Created
+		} else {
+			recent += byHour[h].Created
+		}
+	}
+	if recent == 0 || previous == 0 {
+		return 1
+	}
+	growth := float64(recent) / float64(previous)
+	growth = math.Max(minGrowth, math.Min(maxGrowth, growth))
+	return math.Round(growth*100) / 100
+}
+
+func nodeCapacity(peak int, opts ForecastOptions) *NodeCapacity {
+	perNode, limitedBy := 0, ""
+	fit := func(session, node int64, resource string) {
+		if session <= 0 || node <= 0 {
+			return
+		}
+		n := int(node / session)
+		if limitedBy == "" || n < perNode {
+			perNode, limitedBy = n, resource
+		}
+	}
+	fit(opts.SessionCPUMillis, opts.NodeCPUMillis, "cpu")
+	fit(opts.SessionMemoryBytes, opts.NodeMemoryBytes, "memory")
+	if limitedBy == "" {
+		return nil
+	}
+
+	c := &NodeCapacity{
+		Sessions:        int(math.Ceil(float64(peak) * (1 + opts.Headroom))),
+		SessionsPerNode: perNode,
+		LimitedBy:       limitedBy,
+		Headroom:        opts.Headroom,
+	}
+	if perNode > 0 {
+		c.NodesRequired = int(math.Ceil(float64(c.Sessions) / float64(perNode)))
+	}
+	return c
+}
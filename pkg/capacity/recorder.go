@@ -0,0 +1,217 @@
+// Package capacity records how many sessions run over time and forecasts
+// the peak concurrency and node capacity of the coming week from it.
+package capacity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultRetention is how long hourly history is kept
+const DefaultRetention = 8 * 7 * 24 * time.Hour
+
+// Bucket aggregates the sessions of one hour
+type Bucket struct {
+	Hour time.Time `json:"hour"`
+	// PeakActive is the highest number of concurrent sessions sampled
+	PeakActive int `json:"peak_active"`
+	// Created is the number of sessions started in the hour
+	Created int `json:"created"`
+	// Ended is the number of sessions that disappeared in the hour, and
+	// DurationSeconds the sum of their lifetimes
+	Ended           int     `json:"ended"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// History is the persisted state of a Recorder
+type History struct {
+	LastSampleAt time.Time `json:"last_sample_at"`
+	Buckets      []Bucket  `json:"buckets"`
+}
+
+// Store persists the recorded history
+type Store interface {
+	Load(ctx context.Context) (*History, error)
+	Save(ctx context.Context, h *History) error
+}
+
+// FileStore is a Store that keeps the history in one JSON file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a FileStore writing <dir>/history.json, creating dir
+// if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create capacity directory: %w", err)
+	}
+	return &FileStore{path: filepath.Join(dir, "history.json")}, nil
+}
+
+// Load returns the stored history, or an empty one
+func (s *FileStore) Load(_ context.Context) (*History, error) {
+	h := &History{}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capacity history: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse capacity history: %w", err)
+	}
+	return h, nil
+}
+
+// Save replaces the stored history
+func (s *FileStore) Save(_ context.Context, h *History) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to marshal capacity history: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write capacity history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write capacity history: %w", err)
+	}
+	return nil
+}
+
+// Session is the part of a running session the recorder needs
+type Session struct {
+	ID        string
+	StartedAt time.Time
+}
+
+// Recorder samples the running sessions and aggregates them into hourly
+// buckets
+type Recorder struct {
+	store     Store
+	list      func() []Session
+	retention time.Duration
+
+	mu      sync.Mutex
+	history *History
+	// running maps the sessions of the previous sample to their start time
+	running map[string]time.Time
+}
+
+// NewRecorder creates a Recorder listing sessions with list. A zero
+// retention uses DefaultRetention.
+func NewRecorder(store Store, list func() []Session, retention time.Duration) *Recorder {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Recorder{store: store, list: list, retention: retention}
+}
+
+// Run samples every interval until ctx is done
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sample(ctx, time.Now()); err != nil {
+			log.Printf("[CAPACITY] Failed to record sessions: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample records the sessions running at now. Sessions started since the
+// previous sample count as created; sessions gone since the previous sample
+// count as ended, with their lifetime measured up to now.
+func (r *Recorder) Sample(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(ctx); err != nil {
+		return err
+	}
+	sessions := r.list()
+	current := make(map[string]time.Time, len(sessions))
+	for _, s := range sessions {
+		current[s.ID] = s.StartedAt
+		if s.StartedAt.After(r.history.LastSampleAt) && !r.history.LastSampleAt.IsZero() {
+			r.bucketLocked(s.StartedAt).Created++
+		}
+	}
+	// Lifetimes are only known for sessions seen by this process
+	for id, startedAt := range r.running {
+		if _, ok := current[id]; !ok {
+			b := r.bucketLocked(now)
+			b.Ended++
+			b.DurationSeconds += now.Sub(startedAt).Seconds()
+		}
+	}
+	if b := r.bucketLocked(now); len(sessions) > b.PeakActive {
+		b.PeakActive = len(sessions)
+	}
+	r.running = current
+	r.history.LastSampleAt = now
+
+	cutoff := now.Add(-r.retention)
+	kept := r.history.Buckets[:0]
+	for _, b := range r.history.Buckets {
+		if !b.Hour.Before(cutoff) {
+			kept = append(kept, b)
+		}
+	}
+	r.history.Buckets = kept
+	return r.store.Save(ctx, r.history)
+}
+
+// Buckets returns a copy of the recorded history, oldest first
+func (r *Recorder) Buckets(ctx context.Context) ([]Bucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(ctx); err != nil {
+		return nil, err
+	}
+	return append([]Bucket(nil), r.history.Buckets...), nil
+}
+
+func (r *Recorder) loadLocked(ctx context.Context) error {
+	if r.history != nil {
+		return nil
+	}
+	h, err := r.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	r.history = h
+	return nil
+}
+
+// bucketLocked returns the bucket of the hour containing t, inserting it in
+// order when missing
+func (r *Recorder) bucketLocked(t time.Time) *Bucket {
+	hour := t.UTC().Truncate(time.Hour)
+	buckets := r.history.Buckets
+	i := len(buckets)
+	for i > 0 && buckets[i-1].Hour.After(hour) {
+		i--
+	}
+	if i > 0 && buckets[i-1].Hour.Equal(hour) {
+		return &buckets[i-1]
+	}
+	buckets = append(buckets, Bucket{})
+	copy(buckets[i+1:], buckets[i:])
+	buckets[i] = Bucket{Hour: hour}
+	r.history.Buckets = buckets
+	return &r.history.Buckets[i]
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -123,6 +124,25 @@ type SessionInfo struct {
 // SearchResponse represents the response from searching sessions
 type SearchResponse struct {
 	Sessions []SessionInfo `json:"sessions"`
+	// Total is the number of matching sessions across all pages
+	Total int `json:"total"`
+	// NextOffset is set when more sessions follow the returned page
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// SearchOptions filters, sorts and paginates sessions returned by /search
+type SearchOptions struct {
+	Status string
+	Tags   map[string]string
+	// Query matches the description and initial message (case-insensitive)
+	Query string
+	// Sort is created_at (default), updated_at or status
+	Sort string
+	// Order is desc (default) or asc
+	Order string
+	// Limit is the page size; zero returns all sessions
+	Limit  int
+	Offset int
 }
 
 // SessionAnnotations contains user-managed annotations attached to a session.
@@ -287,19 +307,39 @@ func (c *Client) Search(ctx context.Context, status string) (*SearchResponse, er
 
 // SearchWithTags lists and filters sessions with tag support
 func (c *Client) SearchWithTags(ctx context.Context, status string, tags map[string]string) (*SearchResponse, error) {
+	return c.SearchWithOptions(ctx, SearchOptions{Status: status, Tags: tags})
+}
+
+// SearchWithOptions lists sessions with search, sorting and pagination
+func (c *Client) SearchWithOptions(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
 	u, err := url.Parse(c.baseURL + "/search")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	q := u.Query()
-	if status != "" {
-		q.Set("status", status)
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
 	}
 	// Add tag filters
-	for key, value := range tags {
+	for key, value := range opts.Tags {
 		q.Set("tag."+key, value)
 	}
+	if opts.Query != "" {
+		q.Set("q", opts.Query)
+	}
+	if opts.Sort != "" {
+		q.Set("sort", opts.Sort)
+	}
+	if opts.Order != "" {
+		q.Set("order", opts.Order)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
 	u.RawQuery = q.Encode()
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
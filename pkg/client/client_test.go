@@ -172,6 +172,35 @@ func TestClient_Search(t *testing.T) {
 	}
 }
 
+func TestClient_SearchWithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") != "flaky test" || q.Get("sort") != "updated_at" || q.Get("order") != "asc" ||
+			q.Get("limit") != "20" || q.Get("offset") != "40" || q.Get("tag.repo") != "org/repo" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		if _, err := w.Write([]byte(`{"sessions":[{"session_id":"s1"}],"total":61,"next_offset":60}`)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL).SearchWithOptions(context.Background(), SearchOptions{
+		Tags:   map[string]string{"repo": "org/repo"},
+		Query:  "flaky test",
+		Sort:   "updated_at",
+		Order:  "asc",
+		Limit:  20,
+		Offset: 40,
+	})
+	if err != nil {
+		t.Fatalf("SearchWithOptions() error = %v", err)
+	}
+	if resp.Total != 61 || resp.NextOffset == nil || *resp.NextOffset != 60 || len(resp.Sessions) != 1 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
 func TestClient_SendMessage(t *testing.T) {
 	tests := []struct {
 		name           string
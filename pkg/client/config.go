@@ -1,8 +1,12 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds the client configuration
@@ -68,3 +72,54 @@ func NewClientFromEnv() (*Client, *Config, error) {
 	client := NewClient(config.Endpoint, WithAPIKeyAuth(config.APIKey))
 	return client, config, nil
 }
+
+// FileConfig is the client configuration file, which holds the proxy to
+// connect to when it is not given by flags or environment variables:
+//
+//	endpoint: https://agentapi.example.com
+//	api_key: ap_xxxxxxxx
+type FileConfig struct {
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key"`
+}
+
+// DefaultConfigPath returns the path of the client configuration file:
+// $AGENTAPI_CLIENT_CONFIG, else agentapi-proxy/client.yaml in the user
+// configuration directory (~/.config on Linux).
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv("AGENTAPI_CLIENT_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "agentapi-proxy", "client.yaml"), nil
+}
+
+// LoadFileConfig reads the client configuration file at path. When path is
+// empty the file at DefaultConfigPath is read, and its absence yields an
+// empty configuration.
+func LoadFileConfig(path string) (*FileConfig, error) {
+	optional := path == ""
+	if optional {
+		defaultPath, err := DefaultConfigPath()
+		if err != nil {
+			return &FileConfig{}, nil
+		}
+		path = defaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if optional && errors.Is(err, os.ErrNotExist) {
+			return &FileConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read client config: %w", err)
+	}
+	var cfg FileConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse client config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
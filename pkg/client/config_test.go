@@ -1,6 +1,10 @@
 package client
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestEndpointFromEnvPrefersExplicitEndpoint(t *testing.T) {
 	t.Setenv("AGENTAPI_PROXY_ENDPOINT", "https://proxy.example/base")
@@ -29,3 +33,35 @@ func TestEndpointFromEnvFallsBackToServiceEnvironment(t *testing.T) {
 		t.Fatalf("EndpointFromEnv() = %q, want service endpoint", endpoint)
 	}
 }
+
+func TestLoadFileConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.yaml")
+	if err := os.WriteFile(path, []byte("endpoint: https://proxy.example\napi_key: secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFileConfig(path)
+	if err != nil {
+		t.Fatalf("LoadFileConfig() error = %v", err)
+	}
+	if cfg.Endpoint != "https://proxy.example" || cfg.APIKey != "secret" {
+		t.Fatalf("LoadFileConfig() = %+v", cfg)
+	}
+}
+
+func TestLoadFileConfigMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "client.yaml")
+
+	if _, err := LoadFileConfig(missing); err == nil {
+		t.Fatal("LoadFileConfig() with an explicit missing path should fail")
+	}
+
+	t.Setenv("AGENTAPI_CLIENT_CONFIG", missing)
+	cfg, err := LoadFileConfig("")
+	if err != nil {
+		t.Fatalf("LoadFileConfig(\"\") error = %v", err)
+	}
+	if *cfg != (FileConfig{}) {
+		t.Fatalf("LoadFileConfig(\"\") = %+v, want empty config", cfg)
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// MessageUpdate is a message of a session streamed by FollowMessages: the
+// data of an agentapi message_update event
+type MessageUpdate struct {
+	ID      int64     `json:"id"`
+	Role    string    `json:"role"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// FollowMessages streams the messages of a session as they are completed.
+// agentapi keeps rewriting the last message while the agent works, so an
+// agent message is sent once a newer message appears or the agent becomes
+// stable. Messages whose ID is at most afterID are skipped, so that the
+// history can be printed first. Both channels are closed when the stream
+// ends; cancel ctx to stop it.
+func (c *Client) FollowMessages(ctx context.Context, sessionID string, afterID int64) (<-chan MessageUpdate, <-chan error) {
+	messageChan := make(chan MessageUpdate, 32)
+	errorChan := make(chan error, 1)
+
+	go func() {
+		defer close(messageChan)
+		defer close(errorChan)
+
+		follower := &messageFollower{lastID: afterID}
+		eventChan, streamErrors := c.StreamEvents(ctx, sessionID)
+		eventType := ""
+		for line := range eventChan {
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				eventType = strings.TrimSpace(name)
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			for _, msg := range follower.handle(eventType, strings.TrimSpace(data)) {
+				select {
+				case messageChan <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		if err := <-streamErrors; err != nil {
+			errorChan <- err
+		}
+	}()
+
+	return messageChan, errorChan
+}
+
+// messageFollower turns agentapi events into completed messages
+type messageFollower struct {
+	lastID  int64          // last message sent or skipped
+	pending *MessageUpdate // agent message still being written
+}
+
+// handle returns the messages completed by an event
+func (f *messageFollower) handle(eventType, data string) []MessageUpdate {
+	switch eventType {
+	case "message_update":
+		var update MessageUpdate
+		if err := json.Unmarshal([]byte(data), &update); err != nil || update.ID <= f.lastID {
+			return nil
+		}
+		var done []MessageUpdate
+		if f.pending != nil && f.pending.ID != update.ID {
+			done = append(done, f.complete())
+		}
+		if update.Role == "user" {
+			f.lastID = update.ID
+			return append(done, update)
+		}
+		f.pending = &update
+		return done
+	case "status_change":
+		var status struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(data), &status); err != nil || status.Status != "stable" || f.pending == nil {
+			return nil
+		}
+		return []MessageUpdate{f.complete()}
+	}
+	return nil
+}
+
+func (f *messageFollower) complete() MessageUpdate {
+	msg := *f.pending
+	f.pending = nil
+	f.lastID = msg.ID
+	return msg
+}
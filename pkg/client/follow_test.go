@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFollowMessages(t *testing.T) {
+	events := []string{
+		// history printed before following
+		`event: message_update` + "\n" + `data: {"id":0,"role":"agent","message":"welcome"}`,
+		`event: message_update` + "\n" + `data: {"id":1,"role":"user","message":"hello"}`,
+		// the agent rewrites its message until it is stable
+		`event: status_change` + "\n" + `data: {"status":"running"}`,
+		`event: message_update` + "\n" + `data: {"id":2,"role":"agent","message":"Hi"}`,
+		`event: message_update` + "\n" + `data: {"id":2,"role":"agent","message":"Hi there"}`,
+		`event: status_change` + "\n" + `data: {"status":"stable"}`,
+		`event: message_update` + "\n" + `data: {"id":3,"role":"user","message":"bye"}`,
+		`event: message_update` + "\n" + `data: {"id":4,"role":"agent","message":"See"}`,
+		`event: message_update` + "\n" + `data: {"id":4,"role":"agent","message":"See you"}`,
+		`event: message_update` + "\n" + `data: {"id":5,"role":"user","message":"!"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/session-1/events" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "%s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	messages, errs := NewClient(server.URL).FollowMessages(context.Background(), "session-1", 1)
+
+	var got []string
+	for msg := range messages {
+		got = append(got, fmt.Sprintf("%d %s: %s", msg.ID, msg.Role, msg.Message))
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("FollowMessages() error = %v", err)
+	}
+	want := []string{
+		"2 agent: Hi there",
+		"3 user: bye",
+		"4 agent: See you",
+		"5 user: !",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("FollowMessages() = %q, want %q", got, want)
+	}
+}
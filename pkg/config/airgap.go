@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/airgap"
+)
+
+// defaultDinDImage mirrors the fallback used by the Kubernetes session manager
+// when no DinD image is configured.
+const defaultDinDImage = "docker:dind"
+
+// mirrors returns the registry mirrors in airgap form.
+func (c AirGapConfig) mirrors() []airgap.Mirror {
+	mirrors := make([]airgap.Mirror, 0, len(c.RegistryMirrors))
+	for _, m := range c.RegistryMirrors {
+		mirrors = append(mirrors, airgap.Mirror{Registry: m.Registry, Target: m.Target})
+	}
+	return mirrors
+}
+
+// RewriteImage points image at its internal mirror. It returns image unchanged
+// when air-gapped mode is disabled.
+func (c AirGapConfig) RewriteImage(image string) string {
+	if !c.Enabled {
+		return image
+	}
+	return airgap.RewriteImage(image, c.mirrors(), c.ImageRegistry)
+}
+
+// SessionEnv returns the environment variables injected into session Pods in
+// air-gapped mode: package mirrors and switches that stop agents from
+// phoning home for updates and telemetry.
+func (c AirGapConfig) SessionEnv() map[string]string {
+	if !c.Enabled {
+		return nil
+	}
+	env := map[string]string{
+		"CLAUDE_CODE_DISABLE_NONESSENTIAL_TRAFFIC": "1",
+		"DISABLE_AUTOUPDATER":                      "1",
+		"DISABLE_TELEMETRY":                        "1",
+		"DISABLE_ERROR_REPORTING":                  "1",
+	}
+	if c.NPMRegistry != "" {
+		env["NPM_CONFIG_REGISTRY"] = c.NPMRegistry
+	}
+	if c.PyPIIndexURL != "" {
+		env["PIP_INDEX_URL"] = c.PyPIIndexURL
+		env["UV_INDEX_URL"] = c.PyPIIndexURL
+	}
+	if c.GoProxy != "" {
+		env["GOPROXY"] = c.GoProxy
+	}
+	if c.GoSumDB != "" {
+		env["GOSUMDB"] = c.GoSumDB
+	}
+	return env
+}
+
+// applyAirGap rewrites every configured container image to its internal mirror.
+func applyAirGap(config *Config) {
+	if !config.AirGap.Enabled {
+		return
+	}
+	ks := &config.KubernetesSession
+	if ks.DinDImage == "" {
+		ks.DinDImage = defaultDinDImage
+	}
+	if ks.EditorImage == "" {
+		ks.EditorImage = DefaultEditorImage
+	}
+	if ks.BrowserImage == "" {
+		ks.BrowserImage = DefaultBrowserImage
+	}
+	if ks.TerminalImage == "" {
+		ks.TerminalImage = DefaultTerminalImage
+	}
+	if ks.BuildKitImage == "" {
+		ks.BuildKitImage = DefaultBuildKitImage
+	}
+	for _, image := range []*string{
+		&ks.Image,
+		&ks.InitContainerImage,
+		&ks.SandboxInitImage,
+		&ks.NetworkFilterImage,
+		&ks.OtelCollectorImage,
+		&ks.DinDImage,
+		&ks.EditorImage,
+		&ks.BrowserImage,
+		&ks.TerminalImage,
+		&ks.BuildKitImage,
+		&config.Scia.SessionSidecarImage,
+		&config.Scia.SessionSidecarConfigImage,
+	} {
+		*image = config.AirGap.RewriteImage(*image)
+	}
+	for agentType, image := range ks.AgentImages {
+		ks.AgentImages[agentType] = config.AirGap.RewriteImage(image)
+	}
+	for i := range ks.TeamImages {
+		ks.TeamImages[i].Image = config.AirGap.RewriteImage(ks.TeamImages[i].Image)
+		for agentType, image := range ks.TeamImages[i].AgentImages {
+			ks.TeamImages[i].AgentImages[agentType] = config.AirGap.RewriteImage(image)
+		}
+	}
+	log.Printf("[CONFIG] Air-gapped mode enabled (image registry: %q, %d registry mirrors)",
+		config.AirGap.ImageRegistry, len(config.AirGap.RegistryMirrors))
+}
+
+// ExternalReferences lists the configured images and outbound endpoints that
+// require network access from the cluster.
+func (c *Config) ExternalReferences() []airgap.Reference {
+	var refs []airgap.Reference
+	image := func(field, value string) {
+		refs = append(refs, airgap.Reference{Field: field, Kind: airgap.KindImage, Value: value})
+	}
+	endpoint := func(field, value string) {
+		refs = append(refs, airgap.Reference{Field: field, Kind: airgap.KindURL, Value: value})
+	}
+
+	ks := c.KubernetesSession
+	image("kubernetes_session.image", ks.Image)
+	for _, agentType := range sortedKeys(ks.AgentImages) {
+		image("kubernetes_session.agent_images."+agentType, ks.AgentImages[agentType])
+	}
+	for i, team := range ks.TeamImages {
+		if team.Image != "" {
+			image(fmt.Sprintf("kubernetes_session.team_images[%d].image", i), team.Image)
+		}
+		for _, agentType := range sortedKeys(team.AgentImages) {
+			image(fmt.Sprintf("kubernetes_session.team_images[%d].agent_images.%s", i, agentType), team.AgentImages[agentType])
+		}
+	}
+	image("kubernetes_session.init_container_image", ks.InitContainerImage)
+	image("kubernetes_session.network_filter_image", ks.NetworkFilterImage)
+	image("kubernetes_session.dind_image", defaultIfBlank(ks.DinDImage, defaultDinDImage))
+	image("kubernetes_session.editor_image", defaultIfBlank(ks.EditorImage, DefaultEditorImage))
+	image("kubernetes_session.browser_image", defaultIfBlank(ks.BrowserImage, DefaultBrowserImage))
+	image("kubernetes_session.terminal_image", defaultIfBlank(ks.TerminalImage, DefaultTerminalImage))
+	image("kubernetes_session.buildkit_image", defaultIfBlank(ks.BuildKitImage, DefaultBuildKitImage))
+	if c.Scia.Enabled || c.Scia.SessionSidecarEnabled {
+		image("scia.session_sidecar_image", c.Scia.SessionSidecarImage)
+		image("scia.session_sidecar_config_image", c.Scia.SessionSidecarConfigImage)
+		endpoint("scia.proxy_url", c.Scia.ProxyURL)
+	}
+
+	if c.Auth.GitHub != nil && c.Auth.GitHub.Enabled {
+		endpoint("auth.github.base_url", c.Auth.GitHub.BaseURL)
+		if c.Auth.GitHub.OAuth != nil {
+			endpoint("auth.github.oauth.base_url", c.Auth.GitHub.OAuth.BaseURL)
+		}
+	}
+	if c.LLMProxy.Enabled {
+		for i, p := range c.LLMProxy.Providers {
+			endpoint(fmt.Sprintf("llm_proxy.providers[%d].base_url", i), p.BaseURL)
+		}
+	}
+	if c.Asset.Backend == "s3" && c.Asset.S3 != nil {
+		if c.Asset.S3.Endpoint != "" {
+			endpoint("asset.s3.endpoint", c.Asset.S3.Endpoint)
+		} else {
+			endpoint("asset.s3.endpoint", "https://s3.amazonaws.com")
+		}
+	}
+	if c.Slack.AppTokenSecretName != "" || ks.SlackBotTokenSecretName != "" {
+		endpoint("slack", "https://slack.com/api")
+	}
+
+	if c.EventBus.Enabled {
+		switch c.EventBus.Type {
+		case "pubsub":
+			endpoint("event_bus.url", defaultIfBlank(c.EventBus.URL, "https://pubsub.googleapis.com"))
+		case "nats", "kafka":
+			endpoint("event_bus.url", c.EventBus.URL)
+		}
+	}
+
+	endpoint("kubernetes_session.preview_deploy_hook_url", ks.PreviewDeployHookURL)
+	if c.Tracing.Exporter == "otlp" {
+		endpoint("tracing.endpoint", c.Tracing.Endpoint)
+	}
+
+	endpoint("egress_proxy.http_proxy", c.EgressProxy.HTTPProxy)
+	endpoint("egress_proxy.https_proxy", c.EgressProxy.HTTPSProxy)
+	endpoint("egress_proxy.all_proxy", c.EgressProxy.AllProxy)
+	endpoint("air_gap.npm_registry", c.AirGap.NPMRegistry)
+	endpoint("air_gap.pypi_index_url", c.AirGap.PyPIIndexURL)
+	// GOPROXY is a list ("https://goproxy.corp,direct"); only the first entry is a URL.
+	goproxy, _, _ := strings.Cut(c.AirGap.GoProxy, ",")
+	endpoint("air_gap.goproxy", strings.Split(goproxy, "|")[0])
+	return refs
+}
+
+// ValidateAirGap reports configuration values that still point outside the
+// cluster. It returns nil when air-gapped mode is disabled.
+func (c *Config) ValidateAirGap() []airgap.Finding {
+	if !c.AirGap.Enabled {
+		return nil
+	}
+	allowed := append([]string{}, c.AirGap.AllowedHosts...)
+	if c.AirGap.ImageRegistry != "" {
+		allowed = append(allowed, airgap.TargetHost(c.AirGap.ImageRegistry))
+	}
+	for _, m := range c.AirGap.RegistryMirrors {
+		allowed = append(allowed, airgap.TargetHost(m.Target))
+	}
+	return airgap.Validate(c.ExternalReferences(), allowed)
+}
+
+func defaultIfBlank(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// sortedKeys returns the keys of m in order, for a stable list of references.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -19,9 +19,12 @@
 //	AGENTAPI_AUTH_GITHUB_OAUTH_CLIENT_SECRET=your_client_secret
 //	AGENTAPI_AUTH_GITHUB_OAUTH_SCOPE=read:user read:org project
 //	AGENTAPI_AUTH_GITHUB_USER_MAPPING_DEFAULT_ROLE=user
+//	AGENTAPI_AUTH_GITHUB_TEAM_SYNC_ENABLED=true
+//	AGENTAPI_AUTH_GITHUB_TEAM_SYNC_WEBHOOK_SECRET=your_webhook_secret
 //	AGENTAPI_ENABLE_MULTIPLE_USERS=true
 //	AGENTAPI_WEBHOOK_BASE_URL=https://example.com
 //	AGENTAPI_WEBHOOK_GITHUB_ENTERPRISE_HOST=github.enterprise.com
+//	AGENTAPI_GITHUB_ENTERPRISE_URL=https://github.enterprise.com
 //
 // Configuration file search paths:
 //   - Current directory
@@ -44,6 +47,10 @@ import (
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"github.com/takutakahashi/agentapi-proxy/pkg/egressproxy"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
+	"github.com/takutakahashi/agentapi-proxy/pkg/proxy"
+	"github.com/takutakahashi/agentapi-proxy/pkg/tracing"
 	"gopkg.in/yaml.v2"
 )
 
@@ -69,6 +76,22 @@ type GitHubAuthConfig struct {
 	TokenHeader string             `json:"token_header" mapstructure:"token_header"`
 	UserMapping GitHubUserMapping  `json:"user_mapping" mapstructure:"user_mapping"`
 	OAuth       *GitHubOAuthConfig `json:"oauth,omitempty" mapstructure:"oauth"`
+	// TeamSync syncs the members of the team_role_mapping teams in the
+	// background instead of reading them with each user's token
+	TeamSync GitHubTeamSyncConfig `json:"team_sync" mapstructure:"team_sync"`
+}
+
+// GitHubTeamSyncConfig configures the team membership sync. Teams are read
+// with the GitHub App of the GitHub Secret (kubernetes_session.github_secret_name),
+// which needs the organization "Members" read permission.
+type GitHubTeamSyncConfig struct {
+	// Enabled turns on the sync
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Interval is how often all teams are synced (default: 10m)
+	Interval string `json:"interval" mapstructure:"interval"`
+	// WebhookSecret verifies the membership, organization and team events sent
+	// to POST /webhooks/github/team-sync. The endpoint is disabled when empty.
+	WebhookSecret string `json:"webhook_secret" mapstructure:"webhook_secret"`
 }
 
 // GitHubOAuthConfig represents GitHub OAuth2 configuration
@@ -146,6 +169,154 @@ type Toleration struct {
 	TolerationSeconds *int64 `json:"toleration_seconds,omitempty" mapstructure:"toleration_seconds" yaml:"toleration_seconds"`
 }
 
+// AcceleratorAllowance is what the sessions of a team may request with
+// params.accelerator
+type AcceleratorAllowance struct {
+	// TeamID is the team ("org/team-slug") of the team-scoped sessions the
+	// allowance applies to. "*" applies to sessions without an allowance of
+	// their own, including user-scoped sessions.
+	TeamID string `json:"team_id" mapstructure:"team_id" yaml:"team_id"`
+	// ExtendedResources is the most a session may request of each extended
+	// resource, e.g. {"nvidia.com/gpu": "2"}
+	ExtendedResources map[string]string `json:"extended_resources,omitempty" mapstructure:"extended_resources" yaml:"extended_resources"`
+	// RuntimeClassNames are the RuntimeClasses a session may request
+	RuntimeClassNames []string `json:"runtime_class_names,omitempty" mapstructure:"runtime_class_names" yaml:"runtime_class_names"`
+	// NodeLabels are the node label keys a session may require, each with
+	// the allowed values. An empty list allows any value.
+	NodeLabels map[string][]string `json:"node_labels,omitempty" mapstructure:"node_labels" yaml:"node_labels"`
+}
+
+// TeamImage overrides the container images of the team-scoped sessions of
+// a team
+type TeamImage struct {
+	// TeamID is the team ("org/team-slug") the override applies to
+	TeamID string `json:"team_id" mapstructure:"team_id" yaml:"team_id"`
+	// Image is the image of sessions whose agent type has no entry in AgentImages
+	Image string `json:"image,omitempty" mapstructure:"image" yaml:"image"`
+	// AgentImages maps an agent type to its image for the team
+	AgentImages map[string]string `json:"agent_images,omitempty" mapstructure:"agent_images" yaml:"agent_images"`
+	// AllowedImages are further images the sessions of the team may request
+	// with params.image, in addition to kubernetes_session.allowed_images
+	AllowedImages []string `json:"allowed_images,omitempty" mapstructure:"allowed_images" yaml:"allowed_images"`
+	// Pinned keeps the running sessions of the team on their image during
+	// image rollouts. New sessions still get the configured image.
+	Pinned bool `json:"pinned,omitempty" mapstructure:"pinned" yaml:"pinned"`
+}
+
+// SessionReservation is the capacity reserved for the team-scoped sessions
+// of a team
+type SessionReservation struct {
+	// TeamID is the team ("org/team-slug") the capacity is reserved for
+	TeamID string `json:"team_id" mapstructure:"team_id" yaml:"team_id"`
+	// Sessions is the number of session slots of kubernetes_session.session_capacity
+	// reserved for the team. Further sessions of the team share the
+	// unreserved slots with everyone else.
+	Sessions int `json:"sessions" mapstructure:"sessions" yaml:"sessions"`
+	// Quota is the least ResourceQuota of the team namespace with
+	// per-team namespace placement, e.g. {"requests.cpu": "16"}
+	Quota map[string]string `json:"quota,omitempty" mapstructure:"quota" yaml:"quota"`
+	// GuaranteedQoS gives the session Pods of the team the Guaranteed QoS
+	// class by setting the requests of every container to its limits
+	GuaranteedQoS bool `json:"guaranteed_qos" mapstructure:"guaranteed_qos" yaml:"guaranteed_qos"`
+}
+
+// Session sources matched by SessionPriorityRule.Source
+const (
+	// SessionSourceInteractive is a session started by a user or API client
+	SessionSourceInteractive = "interactive"
+	// SessionSourceWebhook is a session started by a webhook
+	SessionSourceWebhook = "webhook"
+	// SessionSourceSchedule is a session started by a schedule
+	SessionSourceSchedule = "schedule"
+)
+
+// SessionPreemptionConfig configures the preemption of idle sessions when
+// kubernetes_session.session_capacity is exhausted
+type SessionPreemptionConfig struct {
+	// Enabled pauses the lowest-priority idle session with a lower priority
+	// than a new session that finds no free slot. Needs a persistent workdir
+	// (kubernetes_session.pvc_enabled) so that paused sessions can resume.
+	Enabled bool `json:"enabled" mapstructure:"enabled" yaml:"enabled"`
+	// MinIdle is how long a session must have been idle to be preempted.
+	// Default: "5m".
+	MinIdle string `json:"min_idle" mapstructure:"min_idle" yaml:"min_idle"`
+	// Priorities assign priorities to sessions; the first matching rule wins
+	// and sessions matching no rule have priority 0. Without rules,
+	// interactive sessions have priority 100 and all others 0.
+	Priorities []SessionPriorityRule `json:"priorities,omitempty" mapstructure:"priorities" yaml:"priorities"`
+}
+
+// SessionHealthProbeConfig configures probing the agentapi server of running
+// sessions beyond the readiness of their Pod
+type SessionHealthProbeConfig struct {
+	// Enabled probes GET /status of every ready session
+	Enabled bool `json:"enabled" mapstructure:"enabled" yaml:"enabled"`
+	// Interval between probes of a session. Default: "30s".
+	Interval string `json:"interval" mapstructure:"interval" yaml:"interval"`
+	// Timeout of one probe. Default: "5s".
+	Timeout string `json:"timeout" mapstructure:"timeout" yaml:"timeout"`
+	// FailureThreshold is the number of consecutive failed probes after
+	// which the session is reported unhealthy. Default: 3.
+	FailureThreshold int `json:"failure_threshold" mapstructure:"failure_threshold" yaml:"failure_threshold"`
+	// RestartThreshold is the number of consecutive failed probes after
+	// which the Deployment of the session is restarted. 0 (the default)
+	// never restarts.
+	RestartThreshold int `json:"restart_threshold" mapstructure:"restart_threshold" yaml:"restart_threshold"`
+}
+
+// SessionImageRolloutConfig configures the rollout of updated images to
+// running sessions started with POST /admin/image-rollout
+type SessionImageRolloutConfig struct {
+	// MinIdle is how long a session must have been idle to be upgraded.
+	// Default: "10m".
+	MinIdle string `json:"min_idle" mapstructure:"min_idle" yaml:"min_idle"`
+	// MaxConcurrent is the number of sessions upgraded at a time. Default: 1.
+	MaxConcurrent int `json:"max_concurrent" mapstructure:"max_concurrent" yaml:"max_concurrent"`
+	// Interval between two steps of a rollout. Default: "30s".
+	Interval string `json:"interval" mapstructure:"interval" yaml:"interval"`
+	// UpgradeTimeout is how long an upgraded session may take to become
+	// ready before it is reported failed. Default: "10m".
+	UpgradeTimeout string `json:"upgrade_timeout" mapstructure:"upgrade_timeout" yaml:"upgrade_timeout"`
+}
+
+// SessionSnapshotConfig configures VolumeSnapshots of the workdir PVCs of
+// sessions, used by POST /sessions/:id/clone and to restore deleted sessions
+type SessionSnapshotConfig struct {
+	// Enabled turns on snapshots. They need pvc_enabled and the
+	// snapshot.storage.k8s.io CRDs with a CSI driver that supports them.
+	Enabled bool `json:"enabled" mapstructure:"enabled" yaml:"enabled"`
+	// VolumeSnapshotClass of the snapshots. Empty uses the default class.
+	VolumeSnapshotClass string `json:"volume_snapshot_class" mapstructure:"volume_snapshot_class" yaml:"volume_snapshot_class"`
+	// Schedule is the interval between two scheduled snapshots of a session,
+	// e.g. "6h". Empty (the default) takes no scheduled snapshots.
+	Schedule string `json:"schedule" mapstructure:"schedule" yaml:"schedule"`
+	// Keep is the number of scheduled snapshots kept per session. Default: 3.
+	Keep int `json:"keep" mapstructure:"keep" yaml:"keep"`
+	// RetainAfterDelete is how long the scheduled snapshots of a deleted
+	// session are kept to restore it. Default: "168h".
+	RetainAfterDelete string `json:"retain_after_delete" mapstructure:"retain_after_delete" yaml:"retain_after_delete"`
+}
+
+// SessionPriorityRule gives the sessions it matches a priority. Empty fields
+// match every session.
+type SessionPriorityRule struct {
+	// Source is where the session comes from: "interactive", "webhook" or
+	// "schedule"
+	Source string `json:"source,omitempty" mapstructure:"source" yaml:"source"`
+	// TeamID matches sessions of the team ("org/team-slug")
+	TeamID string `json:"team_id,omitempty" mapstructure:"team_id" yaml:"team_id"`
+	// Tags match sessions having all of these tags
+	Tags map[string]string `json:"tags,omitempty" mapstructure:"tags" yaml:"tags"`
+	// Priority is the priority of matched sessions; higher preempts lower
+	Priority int `json:"priority" mapstructure:"priority" yaml:"priority"`
+}
+
+// DefaultSessionPriorities are the priorities of sessions when
+// kubernetes_session.preemption.priorities is empty
+var DefaultSessionPriorities = []SessionPriorityRule{
+	{Source: SessionSourceInteractive, Priority: 100},
+}
+
 // ScheduleWorkerConfig represents schedule worker configuration
 type ScheduleWorkerConfig struct {
 	// Enabled enables the schedule worker
@@ -162,6 +333,40 @@ type ScheduleWorkerConfig struct {
 	RetryPeriod string `json:"retry_period" mapstructure:"retry_period"`
 }
 
+// ScheduleParserConfig configures POST /schedules/parse, which turns a natural
+// language request into a schedule draft using an Anthropic Messages API model.
+type ScheduleParserConfig struct {
+	// Enabled turns on the /schedules/parse endpoint
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// BaseURL is the Messages API base URL (default: "https://api.anthropic.com")
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// APIKeyEnv is the name of the environment variable holding the API key (default: "ANTHROPIC_API_KEY")
+	APIKeyEnv string `json:"api_key_env" mapstructure:"api_key_env"`
+	// Model is the model used to draft schedules
+	Model string `json:"model" mapstructure:"model"`
+	// Timeout bounds each model call (e.g., "30s")
+	Timeout string `json:"timeout" mapstructure:"timeout"`
+}
+
+// SessionSummaryConfig configures the one-line summaries of what sessions
+// did. They are generated by an Anthropic Messages API model from the
+// conversation, when a session completes and periodically while it runs.
+type SessionSummaryConfig struct {
+	// Enabled turns on summarization
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// BaseURL is the Messages API base URL (default: "https://api.anthropic.com")
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// APIKeyEnv is the name of the environment variable holding the API key (default: "ANTHROPIC_API_KEY")
+	APIKeyEnv string `json:"api_key_env" mapstructure:"api_key_env"`
+	// Model is the model used to summarize sessions
+	Model string `json:"model" mapstructure:"model"`
+	// Timeout bounds each model call (e.g., "30s")
+	Timeout string `json:"timeout" mapstructure:"timeout"`
+	// Interval is how often running sessions with new messages are
+	// summarized again (default: "10m")
+	Interval string `json:"interval" mapstructure:"interval"`
+}
+
 // SlackbotCleanupWorkerConfig represents Slackbot session cleanup worker configuration.
 // The worker deletes Slackbot sessions whose last message is older than SessionTTL.
 type SlackbotCleanupWorkerConfig struct {
@@ -186,6 +391,196 @@ type SlackbotCleanupWorkerConfig struct {
 	RetryPeriod string `json:"retry_period" mapstructure:"retry_period"`
 }
 
+// IdleReaperConfig represents idle session reaper configuration.
+// The reaper deletes or scales to zero sessions with no proxied request or
+// agent message for longer than IdleTTL. A session can override the TTL with
+// the "idle_ttl" tag (e.g. "2h", or "never" to opt out).
+type IdleReaperConfig struct {
+	// Enabled enables the idle session reaper. Default: false (opt-in).
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// CheckInterval is how often to scan for idle sessions. Default: "5m".
+	CheckInterval string `json:"check_interval" mapstructure:"check_interval"`
+	// IdleTTL is how long a session may stay idle before it is reaped. Default: "24h".
+	IdleTTL string `json:"idle_ttl" mapstructure:"idle_ttl"`
+	// Action is applied to idle sessions: "delete" or "scale_to_zero". Default: "delete".
+	Action string `json:"action" mapstructure:"action"`
+	// DryRun disables the action; idle sessions are only logged.
+	DryRun bool `json:"dry_run" mapstructure:"dry_run"`
+	// LeaseDuration is the duration that non-leader candidates will wait to force acquire leadership
+	LeaseDuration string `json:"lease_duration" mapstructure:"lease_duration"`
+	// RenewDeadline is the duration that the acting master will retry refreshing leadership before giving up
+	RenewDeadline string `json:"renew_deadline" mapstructure:"renew_deadline"`
+	// RetryPeriod is the duration the LeaderElector clients should wait between tries of actions
+	RetryPeriod string `json:"retry_period" mapstructure:"retry_period"`
+}
+
+// LeaderElectionConfig configures the election of the replica that runs the
+// singleton background subsystems of the proxy: the showback sampler and
+// digest, metering export, capacity sampling, the session archive sweep, the
+// sandbox domain collector, audit event and share cleanup and the label schema
+// migration. All replicas serve HTTP. Workers with their own lease settings
+// (schedule_worker, idle_reaper, ...) keep their own Leases.
+type LeaderElectionConfig struct {
+	// Enabled runs the singleton subsystems only on the replica holding the
+	// Lease. When disabled every replica runs them. Default: true.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Namespace is the namespace of the Lease. Falls back to
+	// kubernetes_session.namespace, then the namespace of the proxy Pod.
+	Namespace string `json:"namespace" mapstructure:"namespace"`
+	// LeaseName is the name of the Lease. Default: "agentapi-proxy-leader".
+	LeaseName string `json:"lease_name" mapstructure:"lease_name"`
+	// LeaseDuration is the duration that non-leader candidates will wait to force acquire leadership
+	LeaseDuration string `json:"lease_duration" mapstructure:"lease_duration"`
+	// RenewDeadline is the duration that the acting master will retry refreshing leadership before giving up
+	RenewDeadline string `json:"renew_deadline" mapstructure:"renew_deadline"`
+	// RetryPeriod is the duration the LeaderElector clients should wait between tries of actions
+	RetryPeriod string `json:"retry_period" mapstructure:"retry_period"`
+}
+
+// ShutdownConfig configures the graceful shutdown on SIGTERM. The proxy first
+// reports not ready on GET /ready and refuses new sessions while it keeps
+// serving for DrainDelay, so that load balancers stop routing to it. It then
+// stops accepting connections and waits up to GracePeriod for in-flight
+// requests, flushes buffered events and usage and shuts the session manager
+// down.
+type ShutdownConfig struct {
+	// DrainDelay is how long the proxy keeps serving after SIGTERM. Default: "5s".
+	DrainDelay string `json:"drain_delay" mapstructure:"drain_delay"`
+	// GracePeriod bounds the wait for in-flight requests; streams still open
+	// after it are closed. Default: "20s".
+	GracePeriod string `json:"grace_period" mapstructure:"grace_period"`
+}
+
+// StreamingConfig represents how streaming responses proxied to sessions are
+// handled. Server-Sent Events are always flushed immediately and WebSocket
+// upgrades are always passed through; these settings bound idle streams.
+type StreamingConfig struct {
+	// FlushInterval is the flush interval for regular proxied responses. Default: "100ms".
+	FlushInterval string `json:"flush_interval" mapstructure:"flush_interval"`
+	// SSEIdleTimeout closes an event stream with no data for this long. "0" disables. Default: "30m".
+	SSEIdleTimeout string `json:"sse_idle_timeout" mapstructure:"sse_idle_timeout"`
+	// WebSocketIdleTimeout closes a WebSocket with no traffic for this long. "0" disables. Default: "30m".
+	WebSocketIdleTimeout string `json:"websocket_idle_timeout" mapstructure:"websocket_idle_timeout"`
+}
+
+// ProxyOptions returns the session reverse proxy options. Invalid durations
+// are logged and treated as unset: the default flush interval is used and the
+// idle timeout is disabled.
+func (c StreamingConfig) ProxyOptions() proxy.Options {
+	parse := func(name, value string) time.Duration {
+		if value == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(value)
+		if err == nil && d < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			log.Printf("[CONFIG] Invalid streaming.%s %q, ignoring: %v", name, value, err)
+			return 0
+		}
+		return d
+	}
+	return proxy.Options{
+		FlushInterval:        parse("flush_interval", c.FlushInterval),
+		SSEIdleTimeout:       parse("sse_idle_timeout", c.SSEIdleTimeout),
+		WebSocketIdleTimeout: parse("websocket_idle_timeout", c.WebSocketIdleTimeout),
+	}
+}
+
+// AuditConfig represents the audit event log used by compliance reports
+type AuditConfig struct {
+	// RetentionDays is how long audit events are kept; older events are purged
+	// hourly. "0" keeps events forever. Default: 90.
+	RetentionDays int `json:"retention_days" mapstructure:"retention_days"`
+	// Sinks receive every audit event in addition to the audit log.
+	Sinks []AuditSinkConfig `json:"sinks,omitempty" mapstructure:"sinks"`
+}
+
+// AuditSinkConfig represents a destination audit events are forwarded to
+type AuditSinkConfig struct {
+	// Type is "file", "stdout", "s3" or "webhook".
+	Type string `json:"type" mapstructure:"type"`
+	// Path is the JSON Lines file the file sink appends to.
+	Path string `json:"path,omitempty" mapstructure:"path"`
+	// S3 is the bucket the s3 sink writes one object per event to.
+	S3 *AssetS3Config `json:"s3,omitempty" mapstructure:"s3"`
+	// URL is the endpoint the webhook sink POSTs each event to as JSON.
+	URL string `json:"url,omitempty" mapstructure:"url"`
+	// Headers are added to webhook requests, e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers"`
+}
+
+// BackendProxyConfig represents how requests proxied to session backends
+// cope with unreachable backends, e.g. while a session Pod restarts.
+type BackendProxyConfig struct {
+	// MaxRetries is the number of retries of idempotent requests (GET, HEAD,
+	// OPTIONS, PUT, DELETE) that failed before a response. "0" disables. Default: 2.
+	MaxRetries int `json:"max_retries" mapstructure:"max_retries"`
+	// RetryBackoff is the wait before the first retry; it doubles per retry. Default: "200ms".
+	RetryBackoff string `json:"retry_backoff" mapstructure:"retry_backoff"`
+	// RetryMaxBackoff caps the wait between retries. Default: "2s".
+	RetryMaxBackoff string `json:"retry_max_backoff" mapstructure:"retry_max_backoff"`
+	// CircuitFailureThreshold is the number of consecutive failed requests to a
+	// session that opens its circuit; requests then get 503 until a probe succeeds.
+	// "0" disables. Default: 5.
+	CircuitFailureThreshold int `json:"circuit_failure_threshold" mapstructure:"circuit_failure_threshold"`
+	// CircuitOpenDuration is how long an open circuit rejects requests before a probe. Default: "10s".
+	CircuitOpenDuration string `json:"circuit_open_duration" mapstructure:"circuit_open_duration"`
+}
+
+// ApplyTo sets the retry and circuit breaker options of the session reverse
+// proxy. Invalid durations are logged and treated as zero, which disables
+// the backoff or the breaker respectively.
+func (c BackendProxyConfig) ApplyTo(opts *proxy.Options) {
+	parse := func(name, value string) time.Duration {
+		if value == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(value)
+		if err == nil && d < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			log.Printf("[CONFIG] Invalid backend_proxy.%s %q, ignoring: %v", name, value, err)
+			return 0
+		}
+		return d
+	}
+	opts.Retry = proxy.RetryOptions{
+		MaxRetries:     c.MaxRetries,
+		InitialBackoff: parse("retry_backoff", c.RetryBackoff),
+		MaxBackoff:     parse("retry_max_backoff", c.RetryMaxBackoff),
+	}
+	opts.CircuitBreaker = proxy.CircuitBreakerOptions{
+		FailureThreshold: c.CircuitFailureThreshold,
+		OpenDuration:     parse("circuit_open_duration", c.CircuitOpenDuration),
+	}
+}
+
+// TracingConfig represents OpenTelemetry trace export for session creation
+// and proxied requests.
+type TracingConfig struct {
+	// Exporter is "none" (default), "log" (write spans to the proxy log) or
+	// "otlp" (OTLP/HTTP JSON to Endpoint).
+	Exporter string `json:"exporter" mapstructure:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. "http://otel-collector:4318".
+	// "/v1/traces" is appended when the URL has no path.
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
+	// Headers are sent with every export request, e.g. collector credentials.
+	Headers map[string]string `json:"headers" mapstructure:"headers"`
+	// ServiceName is the service.name resource attribute. Default: "agentapi-proxy".
+	ServiceName string `json:"service_name" mapstructure:"service_name"`
+	// SampleRatio is the fraction of new traces recorded, between 0 and 1.
+	// Requests carrying a traceparent header follow the caller's decision. Default: 1.
+	SampleRatio float64 `json:"sample_ratio" mapstructure:"sample_ratio"`
+}
+
+// Setup installs the configured tracer. It returns nil when tracing is disabled.
+func (c TracingConfig) Setup() (*tracing.Tracer, error) {
+	return tracing.Setup(c.Exporter, c.Endpoint, c.Headers, c.ServiceName, tracing.Options{SampleRatio: c.SampleRatio})
+}
+
 // StockInventoryWorkerConfig represents stock inventory worker configuration.
 // The worker ensures a target number of pre-warmed stock sessions are always available.
 // Note: Sandbox (network filter) and scia sidecar are now always enabled.
@@ -229,6 +624,64 @@ type WebhookConfig struct {
 	// When set, webhooks without explicit enterprise_url will match against this host
 	// Example: "github.enterprise.com" (hostname only, without https://)
 	GitHubEnterpriseHost string `json:"github_enterprise_host" mapstructure:"github_enterprise_host"`
+	// GitHub configures the rule-based GitHub receiver at /webhooks/github
+	GitHub GitHubWebhookRulesConfig `json:"github" mapstructure:"github"`
+}
+
+// GitHubWebhookRulesConfig configures the GitHub receiver at /webhooks/github.
+// Unlike webhooks managed through the API, its rules live in the proxy config
+// and one GitHub App or organization webhook can serve many repositories.
+type GitHubWebhookRulesConfig struct {
+	// Enabled turns on the /webhooks/github endpoint
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Secret is the webhook secret used to verify X-Hub-Signature-256 (required)
+	Secret string `json:"secret" mapstructure:"secret"`
+	// UserID is the user that owns sessions created by the rules
+	UserID string `json:"user_id" mapstructure:"user_id"`
+	// TeamID makes sessions team-scoped ("org/team-slug") when set
+	TeamID string `json:"team_id" mapstructure:"team_id"`
+	// Repositories is the allowlist of repositories ("owner/repo" or "owner/*");
+	// empty allows all repositories
+	Repositories []string `json:"repositories" mapstructure:"repositories"`
+	// MaxSessions limits concurrent sessions created by the rules (default: 10)
+	MaxSessions int `json:"max_sessions" mapstructure:"max_sessions"`
+	// Rules are evaluated in order; the first matching rule creates the session
+	Rules []GitHubWebhookRule `json:"rules" mapstructure:"rules"`
+}
+
+// GitHubWebhookRule maps GitHub events to a session. Every non-empty
+// condition must match.
+type GitHubWebhookRule struct {
+	// Name identifies the rule in logs and session tags
+	Name string `json:"name" mapstructure:"name"`
+	// Events are X-GitHub-Event values, e.g. issue_comment, pull_request_review_comment, issues
+	Events []string `json:"events" mapstructure:"events"`
+	// Actions are payload actions, e.g. created, labeled. Defaults to created when Command is set.
+	Actions []string `json:"actions" mapstructure:"actions"`
+	// Command is a comment prefix such as "/agent"; the comment's first line must start with it
+	Command string `json:"command" mapstructure:"command"`
+	// Labels match the label just added (labeled action) or any label of the issue/PR
+	Labels []string `json:"labels" mapstructure:"labels"`
+	// Branches are glob patterns for the pushed or PR head branch
+	Branches []string `json:"branches" mapstructure:"branches"`
+	// BaseBranches are glob patterns for the PR base branch
+	BaseBranches []string `json:"base_branches" mapstructure:"base_branches"`
+	// Repositories narrows the receiver allowlist for this rule
+	Repositories []string `json:"repositories" mapstructure:"repositories"`
+	// Senders are GitHub logins allowed to trigger the rule
+	Senders []string `json:"senders" mapstructure:"senders"`
+	// InitialMessageTemplate is a Go template rendered with the payload
+	InitialMessageTemplate string `json:"initial_message_template" mapstructure:"initial_message_template"`
+	// SessionProfileID selects the session profile (template) to launch
+	SessionProfileID string `json:"session_profile_id" mapstructure:"session_profile_id"`
+	// AgentType overrides the agent type of the session
+	AgentType string `json:"agent_type" mapstructure:"agent_type"`
+	// Tags are added to the session; values are Go templates
+	Tags map[string]string `json:"tags" mapstructure:"tags"`
+	// Environment is added to the session; values are Go templates
+	Environment map[string]string `json:"environment" mapstructure:"environment"`
+	// ReuseSession sends the event to an existing session with the same tags
+	ReuseSession bool `json:"reuse_session" mapstructure:"reuse_session"`
 }
 
 // SciaConfig represents scia OAuth broker/proxy integration configuration.
@@ -274,6 +727,21 @@ type KubernetesSessionConfig struct {
 	Namespace string `json:"namespace" mapstructure:"namespace"`
 	// Image is the container image for session pods
 	Image string `json:"image" mapstructure:"image"`
+	// AgentImages maps an agent type to the image of its sessions, e.g.
+	// {"codex-acp": "ghcr.io/org/agentapi-codex:v1"}. Sessions without an
+	// agent type use the "claude-agentapi" entry; other agent types use Image.
+	AgentImages map[string]string `json:"agent_images,omitempty" mapstructure:"agent_images" yaml:"agent_images"`
+	// TeamImages override the images of the team-scoped sessions of a team.
+	TeamImages []TeamImage `json:"team_images,omitempty" mapstructure:"team_images" yaml:"team_images"`
+	// AllowedImages are the images sessions may request with params.image.
+	// An entry ending in "*" allows every image starting with the rest, e.g.
+	// "ghcr.io/org/agentapi:*". Without entries params.image is rejected.
+	AllowedImages []string `json:"allowed_images,omitempty" mapstructure:"allowed_images" yaml:"allowed_images"`
+	// AllowedRequestMCPServers are the MCP servers a POST /start request may
+	// attach with mcp_servers: entries match the URL of http/sse servers and
+	// the command of stdio servers, with the "*" suffix of AllowedImages.
+	// Without entries requests with mcp_servers are rejected.
+	AllowedRequestMCPServers []string `json:"allowed_request_mcp_servers,omitempty" mapstructure:"allowed_request_mcp_servers" yaml:"allowed_request_mcp_servers"`
 	// ImagePullPolicy is the image pull policy for session pods
 	ImagePullPolicy string `json:"image_pull_policy" mapstructure:"image_pull_policy"`
 	// ServiceAccount is the service account for session pods
@@ -299,11 +767,52 @@ type KubernetesSessionConfig struct {
 	PodStartTimeout int `json:"pod_start_timeout" mapstructure:"pod_start_timeout"`
 	// PodStopTimeout is the timeout in seconds for pod termination
 	PodStopTimeout int `json:"pod_stop_timeout" mapstructure:"pod_stop_timeout"`
+	// AutoResume resumes a paused session when a request is proxied to it.
+	// The request is answered with 503 and Retry-After while the Pod starts.
+	AutoResume bool `json:"auto_resume" mapstructure:"auto_resume"`
+
+	// RequireCapabilityApproval rejects sessions requesting risky capabilities
+	// (terminal, editor, docker) that are not allowed by the capability policy
+	// of the team settings, or of the base settings for user-scoped sessions.
+	// Exec into sessions whose policy does not allow "exec" is rejected too.
+	// Only team admins can change a capability policy.
+	RequireCapabilityApproval bool `json:"require_capability_approval" mapstructure:"require_capability_approval"`
 	// ProvisionerToken authenticates session Pod calls to the internal
 	// provisioner API.
 	ProvisionerToken string `json:"provisioner_token" mapstructure:"provisioner_token"`
 	// ProvisionerProxyURL is the base URL session Pods use to reach this proxy.
 	ProvisionerProxyURL string `json:"provisioner_proxy_url" mapstructure:"provisioner_proxy_url"`
+	// ClusterDomain is the DNS suffix of the cluster used to build Service
+	// host names. Defaults to "cluster.local".
+	ClusterDomain string `json:"cluster_domain" mapstructure:"cluster_domain"`
+	// Routing selects how the proxy addresses session Pods:
+	// "service" (default) uses the ClusterIP Service DNS name, "endpoints"
+	// uses the IP of a ready endpoint of the Service for clusters with DNS
+	// issues, and "headless" creates headless Services whose DNS name
+	// resolves directly to the Pod.
+	Routing string `json:"routing" mapstructure:"routing"`
+	// StatelessAgentTypes lists the agent types that keep no state in the
+	// session workdir and may therefore run with more than one replica.
+	StatelessAgentTypes []string `json:"stateless_agent_types" mapstructure:"stateless_agent_types"`
+	// MaxSessionReplicas caps params.replicas of a session. Multi-replica
+	// sessions also require PVCEnabled so sessions run as Deployments.
+	MaxSessionReplicas int `json:"max_session_replicas" mapstructure:"max_session_replicas"`
+	// OneshotJobEnabled runs oneshot sessions as Jobs instead of
+	// Deployments or Pods. The Job completes when the agent stops and its
+	// logs are kept as artifacts before the session is cleaned up.
+	OneshotJobEnabled bool `json:"oneshot_job_enabled" mapstructure:"oneshot_job_enabled"`
+	// OneshotJobBackoffLimit is the number of retries of a failed oneshot Job.
+	OneshotJobBackoffLimit int `json:"oneshot_job_backoff_limit" mapstructure:"oneshot_job_backoff_limit"`
+	// OneshotJobActiveDeadlineSeconds bounds the runtime of a oneshot Job.
+	// 0 means no limit.
+	OneshotJobActiveDeadlineSeconds int `json:"oneshot_job_active_deadline_seconds" mapstructure:"oneshot_job_active_deadline_seconds"`
+	// OneshotJobTTLSecondsAfterFinished is how long a finished oneshot session
+	// stays visible with its completion status before it is deleted.
+	OneshotJobTTLSecondsAfterFinished int `json:"oneshot_job_ttl_seconds_after_finished" mapstructure:"oneshot_job_ttl_seconds_after_finished"`
+	// StartupTemplatesConfigMap names a ConfigMap whose <agent type>.yaml keys
+	// replace the built-in startup template of that agent type. It is read
+	// at session creation, so changes apply to new sessions without a restart.
+	StartupTemplatesConfigMap string `json:"startup_templates_configmap" mapstructure:"startup_templates_configmap"`
 	// ClaudeConfigUserConfigMapPrefix is the prefix for user-specific ConfigMap names
 	// Full name will be: {prefix}-{username} (e.g., claude-config-johndoe)
 	ClaudeConfigUserConfigMapPrefix string `json:"claude_config_user_configmap_prefix" mapstructure:"claude_config_user_configmap_prefix"`
@@ -319,6 +828,15 @@ type KubernetesSessionConfig struct {
 	// It is kept separate from GitHubSecretName so that params.github_token can override authentication
 	// without losing Enterprise Server URL settings
 	GitHubConfigSecretName string `json:"github_config_secret_name" mapstructure:"github_config_secret_name"`
+	// GitHubTokenRefreshInterval is how often the proxy checks the GitHub App
+	// installation tokens it serves to sessions and mints new ones before they
+	// expire. Default: "5m".
+	GitHubTokenRefreshInterval string `json:"github_token_refresh_interval" mapstructure:"github_token_refresh_interval"`
+	// LabelSchemaMigrationInterval is how often the labels of existing session
+	// Services and Deployments are upgraded to the current label schema, so
+	// that label-filtered session lists do not miss sessions created by older
+	// versions. "0" disables the migration. Default: "1h".
+	LabelSchemaMigrationInterval string `json:"label_schema_migration_interval" mapstructure:"label_schema_migration_interval"`
 	// ConfigFile is the path to an external configuration file for kubernetes session settings
 	// This file can contain node_selector and tolerations settings
 	ConfigFile string `json:"config_file,omitempty" mapstructure:"config_file"`
@@ -332,6 +850,38 @@ type KubernetesSessionConfig struct {
 	// Tolerations are tolerations for session pods to schedule onto nodes with matching taints
 	Tolerations []Toleration `json:"tolerations,omitempty" mapstructure:"tolerations" yaml:"tolerations"`
 
+	// Accelerators. Sessions can request extended resources such as
+	// nvidia.com/gpu, a RuntimeClass and node labels with params.accelerator,
+	// within the allowance of their team.
+
+	// ExtendedResources are extended resource limits of every session
+	// container, e.g. {"nvidia.com/gpu": "1"}. A session request overrides them.
+	ExtendedResources map[string]string `json:"extended_resources,omitempty" mapstructure:"extended_resources" yaml:"extended_resources"`
+	// RuntimeClassName is the RuntimeClass of session Pods that do not request one.
+	RuntimeClassName string `json:"runtime_class_name" mapstructure:"runtime_class_name"`
+	// AcceleratorAllowances limit what sessions may request. Sessions without
+	// an allowance cannot request accelerators.
+	AcceleratorAllowances []AcceleratorAllowance `json:"accelerator_allowances,omitempty" mapstructure:"accelerator_allowances" yaml:"accelerator_allowances"`
+
+	// SessionCapacity is the most sessions that run at once across all
+	// tenants, including reserved slots (0: unlimited). Stock sessions are
+	// not counted.
+	SessionCapacity int `json:"session_capacity" mapstructure:"session_capacity"`
+	// Reservations guarantee session slots and namespace quota to teams.
+	// Reserved slots are never used by other tenants.
+	Reservations []SessionReservation `json:"reservations,omitempty" mapstructure:"reservations" yaml:"reservations"`
+	// Preemption pauses idle low-priority sessions to make room for
+	// higher-priority ones when the session capacity is exhausted.
+	Preemption SessionPreemptionConfig `json:"preemption" mapstructure:"preemption" yaml:"preemption"`
+	// HealthProbe probes the agentapi server of ready sessions and can
+	// restart sessions whose server stopped answering.
+	HealthProbe SessionHealthProbeConfig `json:"health_probe" mapstructure:"health_probe" yaml:"health_probe"`
+	// ImageRollout paces the rollout of updated images to running sessions.
+	ImageRollout SessionImageRolloutConfig `json:"image_rollout" mapstructure:"image_rollout" yaml:"image_rollout"`
+	// Snapshots takes VolumeSnapshots of session workdirs to clone sessions
+	// and restore deleted ones.
+	Snapshots SessionSnapshotConfig `json:"snapshots" mapstructure:"snapshots" yaml:"snapshots"`
+
 	// SettingsBaseSecret is the single base Kubernetes Secret shared by all sessions.
 	// It contains settings.json in the agentapi settings format (env_vars, auth_mode, bedrock,
 	// mcp_servers, marketplaces, enabled_plugins, hooks) and is merged at the lowest priority
@@ -405,14 +955,194 @@ type KubernetesSessionConfig struct {
 	DinDCPULimit      string `json:"dind_cpu_limit" mapstructure:"dind_cpu_limit"`
 	DinDMemoryRequest string `json:"dind_memory_request" mapstructure:"dind_memory_request"`
 	DinDMemoryLimit   string `json:"dind_memory_limit" mapstructure:"dind_memory_limit"`
+
+	// BuildKit sidecar configuration.
+	// Sessions with docker.mode=buildkit get a rootless BuildKit daemon instead
+	// of the privileged DinD sidecar and BUILDKIT_HOST set in the main container.
+
+	// BuildKitImage is the container image for the BuildKit sidecar. It must be
+	// a rootless variant.
+	// Defaults to DefaultBuildKitImage if not specified.
+	BuildKitImage string `json:"buildkit_image" mapstructure:"buildkit_image"`
+
+	// BuildKit sidecar resource configuration
+	BuildKitCPURequest    string `json:"buildkit_cpu_request" mapstructure:"buildkit_cpu_request"`
+	BuildKitCPULimit      string `json:"buildkit_cpu_limit" mapstructure:"buildkit_cpu_limit"`
+	BuildKitMemoryRequest string `json:"buildkit_memory_request" mapstructure:"buildkit_memory_request"`
+	BuildKitMemoryLimit   string `json:"buildkit_memory_limit" mapstructure:"buildkit_memory_limit"`
+
+	// DockerImageCacheOnPVC stores the DinD/BuildKit image and layer cache on
+	// the session PVC (under .image-cache in the workdir) so it survives Pod
+	// restarts and pause/resume. Ignored when PVCEnabled is false.
+	// Defaults to false.
+	DockerImageCacheOnPVC bool `json:"docker_image_cache_on_pvc" mapstructure:"docker_image_cache_on_pvc"`
+
+	// RuntimeCacheEnabled mounts a ReadWriteMany PVC shared by the sessions of
+	// a team (or of a user, for user-scoped sessions) and points npm, pnpm,
+	// yarn, pip, uv and Go at it, so dependencies are downloaded once.
+	// Sessions adopted from the stock pool have no owner when their Pod is
+	// created and run without the cache. Defaults to false.
+	RuntimeCacheEnabled bool `json:"runtime_cache_enabled" mapstructure:"runtime_cache_enabled"`
+	// RuntimeCacheStorageClass must provide ReadWriteMany volumes (e.g. NFS,
+	// EFS or Filestore). Empty uses the cluster default.
+	RuntimeCacheStorageClass string `json:"runtime_cache_storage_class" mapstructure:"runtime_cache_storage_class"`
+	// RuntimeCacheStorageSize is the size of each cache PVC. Defaults to "20Gi".
+	RuntimeCacheStorageSize string `json:"runtime_cache_storage_size" mapstructure:"runtime_cache_storage_size"`
+
+	// Namespace placement. With "team" placement, team-scoped sessions run in
+	// a namespace of their team, created on first use with a ResourceQuota,
+	// LimitRange and NetworkPolicy, so that noisy teams are isolated and
+	// cluster admins can apply per-team quotas natively. Personal sessions and
+	// stock sessions stay in Namespace.
+
+	// NamespacePlacement is "single" (all sessions in Namespace, the default)
+	// or "team".
+	NamespacePlacement string `json:"namespace_placement" mapstructure:"namespace_placement"`
+	// TeamNamespacePrefix prefixes the sanitized team ID to name team
+	// namespaces. Defaults to "agentapi-team-".
+	TeamNamespacePrefix string `json:"team_namespace_prefix" mapstructure:"team_namespace_prefix"`
+	// TeamNamespaces maps team IDs to namespace names, overriding the prefix.
+	TeamNamespaces map[string]string `json:"team_namespaces" mapstructure:"team_namespaces"`
+	// TeamNamespaceQuota is the hard limits of the ResourceQuota of team
+	// namespaces, e.g. {"requests.cpu": "20", "pods": "50"}. Empty creates no quota.
+	TeamNamespaceQuota map[string]string `json:"team_namespace_quota" mapstructure:"team_namespace_quota"`
+	// TeamNamespaceLimitDefault and TeamNamespaceLimitDefaultRequest are the
+	// container defaults of the LimitRange of team namespaces, e.g.
+	// {"cpu": "2", "memory": "4Gi"}. Empty creates no LimitRange.
+	TeamNamespaceLimitDefault        map[string]string `json:"team_namespace_limit_default" mapstructure:"team_namespace_limit_default"`
+	TeamNamespaceLimitDefaultRequest map[string]string `json:"team_namespace_limit_default_request" mapstructure:"team_namespace_limit_default_request"`
+	// TeamNamespaceNetworkPolicy only admits traffic into team namespaces from
+	// the namespace itself and from Namespace. Defaults to true.
+	TeamNamespaceNetworkPolicy bool `json:"team_namespace_network_policy" mapstructure:"team_namespace_network_policy"`
+
+	// Node drains. Session Pods can be protected by PodDisruptionBudgets, let
+	// the agent checkpoint before the Pod stops, and be recreated and resumed
+	// after they were evicted.
+
+	// DisruptionBudget is "" (no PodDisruptionBudget, the default), "session"
+	// (one PodDisruptionBudget per session) or "shared" (one per namespace
+	// selecting all session Pods).
+	DisruptionBudget string `json:"disruption_budget" mapstructure:"disruption_budget"`
+	// DisruptionBudgetMaxUnavailable is the maxUnavailable of the budgets.
+	// 0 (the default) blocks evictions of session Pods until they are deleted.
+	DisruptionBudgetMaxUnavailable int `json:"disruption_budget_max_unavailable" mapstructure:"disruption_budget_max_unavailable"`
+	// PreStopCheckpoint adds a preStop hook that checkpoints the agent, so that
+	// the next Pod of the session resumes the conversation with "claude -c".
+	PreStopCheckpoint bool `json:"prestop_checkpoint" mapstructure:"prestop_checkpoint"`
+	// RecreateEvictedSessions recreates the Pod of an evicted session without
+	// a PVC. Deployments of PVC-backed sessions recreate their Pod anyway.
+	RecreateEvictedSessions bool `json:"recreate_evicted_sessions" mapstructure:"recreate_evicted_sessions"`
+
+	// Preview environments. A session can request a namespace of its own into
+	// which the deploy hook deploys its branch; the namespace is deleted with
+	// the session.
+
+	// PreviewDeployHookURL receives deploy and destroy requests for preview
+	// environments. Empty disables preview environments.
+	PreviewDeployHookURL string `json:"preview_deploy_hook_url" mapstructure:"preview_deploy_hook_url"`
+	// PreviewDeployHookSecret signs hook requests (X-Hub-Signature-256) when set.
+	PreviewDeployHookSecret string `json:"preview_deploy_hook_secret" mapstructure:"preview_deploy_hook_secret"`
+	// PreviewNamespacePrefix prefixes the session ID to name preview namespaces.
+	// Defaults to "agentapi-preview-".
+	PreviewNamespacePrefix string `json:"preview_namespace_prefix" mapstructure:"preview_namespace_prefix"`
+	// PreviewDeployTimeout bounds a deploy hook call, e.g. "10m". Defaults to "10m".
+	PreviewDeployTimeout string `json:"preview_deploy_timeout" mapstructure:"preview_deploy_timeout"`
+
+	// ExecEnabled allows session owners and admins to exec into session
+	// containers through POST /sessions/{id}/exec. Every exec is written to
+	// the audit log. Defaults to true.
+	ExecEnabled bool `json:"exec_enabled" mapstructure:"exec_enabled"`
+
+	// Editor (code-server) sidecar configuration.
+	// Sessions with editor.enabled=true in their params get a code-server sidecar
+	// serving the session workdir through /sessions/{id}/editor/.
+
+	// EditorImage is the container image for the editor sidecar.
+	// Defaults to DefaultEditorImage if not specified.
+	EditorImage string `json:"editor_image" mapstructure:"editor_image"`
+
+	// Editor sidecar resource configuration
+	EditorCPURequest    string `json:"editor_cpu_request" mapstructure:"editor_cpu_request"`
+	EditorCPULimit      string `json:"editor_cpu_limit" mapstructure:"editor_cpu_limit"`
+	EditorMemoryRequest string `json:"editor_memory_request" mapstructure:"editor_memory_request"`
+	EditorMemoryLimit   string `json:"editor_memory_limit" mapstructure:"editor_memory_limit"`
+
+	// Browser (headless browser + noVNC) sidecar configuration.
+	// Sessions with browser.enabled=true in their params get a browser sidecar
+	// whose display is served through /sessions/{id}/browser/.
+
+	// BrowserImage is the container image for the browser sidecar. It must be
+	// compatible with the Selenium standalone images.
+	// Defaults to DefaultBrowserImage if not specified.
+	BrowserImage string `json:"browser_image" mapstructure:"browser_image"`
+
+	// Browser sidecar resource configuration
+	BrowserCPURequest    string `json:"browser_cpu_request" mapstructure:"browser_cpu_request"`
+	BrowserCPULimit      string `json:"browser_cpu_limit" mapstructure:"browser_cpu_limit"`
+	BrowserMemoryRequest string `json:"browser_memory_request" mapstructure:"browser_memory_request"`
+	BrowserMemoryLimit   string `json:"browser_memory_limit" mapstructure:"browser_memory_limit"`
+
+	// Terminal (ttyd) sidecar configuration.
+	// Sessions with terminal.enabled=true in their params get a web terminal
+	// sidecar served through /sessions/{id}/terminal/.
+
+	// TerminalImage is the container image for the terminal sidecar. It must
+	// provide ttyd and bash.
+	// Defaults to DefaultTerminalImage if not specified.
+	TerminalImage string `json:"terminal_image" mapstructure:"terminal_image"`
+
+	// TerminalRecording records terminal sessions in asciicast format into the
+	// artifact store (see AssetConfig). Defaults to true.
+	TerminalRecording bool `json:"terminal_recording" mapstructure:"terminal_recording"`
+
+	// Terminal sidecar resource configuration
+	TerminalCPURequest    string `json:"terminal_cpu_request" mapstructure:"terminal_cpu_request"`
+	TerminalCPULimit      string `json:"terminal_cpu_limit" mapstructure:"terminal_cpu_limit"`
+	TerminalMemoryRequest string `json:"terminal_memory_request" mapstructure:"terminal_memory_request"`
+	TerminalMemoryLimit   string `json:"terminal_memory_limit" mapstructure:"terminal_memory_limit"`
 }
 
+// DefaultSwaggerUIURL is the base URL of the swagger-ui-dist assets the /docs
+// page loads when AirGapConfig.SwaggerUIURL is empty.
+const DefaultSwaggerUIURL = "https://unpkg.com/swagger-ui-dist@5"
+
+// DefaultEditorImage is the code-server image used for the editor sidecar
+// when KubernetesSessionConfig.EditorImage is empty.
+const DefaultEditorImage = "codercom/code-server:4.96.4"
+
+// DefaultBrowserImage is the image used for the browser sidecar when
+// KubernetesSessionConfig.BrowserImage is empty.
+const DefaultBrowserImage = "selenium/standalone-chromium:4.27.0"
+
+// DefaultTerminalImage is the image used for the terminal sidecar when
+// KubernetesSessionConfig.TerminalImage is empty.
+const DefaultTerminalImage = "tsl0922/ttyd:1.7.7"
+
+// DefaultBuildKitImage is the rootless BuildKit image used for the BuildKit
+// sidecar when KubernetesSessionConfig.BuildKitImage is empty.
+const DefaultBuildKitImage = "moby/buildkit:v0.23.2-rootless"
+
 // MemoryConfig represents memory backend configuration
 type MemoryConfig struct {
 	// Backend is the storage backend type: "kubernetes" (default), "s3", or "external"
 	Backend  string                `json:"backend" mapstructure:"backend"`
 	S3       *MemoryS3Config       `json:"s3,omitempty" mapstructure:"s3"`
 	External *MemoryExternalConfig `json:"external,omitempty" mapstructure:"external"`
+	// Migration moves memories to another backend ("kubernetes" or "s3"),
+	// which uses the same s3 section.
+	Migration StorageMigrationConfig `json:"migration" mapstructure:"migration"`
+}
+
+// StorageMigrationConfig configures the migration of a store to another
+// backend. While TargetBackend is set, every write goes to both backends and
+// reads switch to the target once the migration has been cut over with
+// "agentapi-proxy admin storage-migration cutover".
+type StorageMigrationConfig struct {
+	// TargetBackend is the backend being migrated to; empty disables the migration
+	TargetBackend string `json:"target_backend" mapstructure:"target_backend"`
+	// TargetDSN is the data source name of a SQL target backend.
+	// Typically populated from an environment variable.
+	TargetDSN string `json:"target_dsn" mapstructure:"target_dsn"`
 }
 
 // MemoryExternalConfig represents configuration for the external memory-server backend.
@@ -437,6 +1167,27 @@ type MemoryS3Config struct {
 	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
 }
 
+// SessionStoreConfig represents the session state store configuration.
+type SessionStoreConfig struct {
+	// Backend is the store type: "kubernetes" (default), "postgres", or "sqlite".
+	// With "kubernetes" sessions are restored from Service labels and annotations
+	// only, which drops the webhook payload and environment of each session.
+	// The SQL backends need a binary built with the matching build tag
+	// ("postgres" or "sqlite").
+	Backend string `json:"backend" mapstructure:"backend"`
+	// DSN is the data source name passed to the SQL driver.
+	// Typically populated from the AGENTAPI_SESSION_STORE_DSN environment variable.
+	DSN string `json:"dsn" mapstructure:"dsn"`
+	// ReadReplicaDSN optionally points bulk reads of the SQL backends, such as
+	// reports listing every session, at a read replica or analytical copy of
+	// the same database so they do not contend with session routing.
+	// Typically populated from AGENTAPI_SESSION_STORE_READ_REPLICA_DSN.
+	ReadReplicaDSN string `json:"read_replica_dsn" mapstructure:"read_replica_dsn"`
+	// Migration moves session records to another backend ("postgres" or
+	// "sqlite") given by its target_dsn.
+	Migration StorageMigrationConfig `json:"migration" mapstructure:"migration"`
+}
+
 // AssetConfig represents static asset upload configuration.
 type AssetConfig struct {
 	// Backend is the storage backend type: "nginx" (default) or "s3".
@@ -456,6 +1207,266 @@ type AssetS3Config struct {
 	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
 }
 
+// LLMProxyConfig configures the egress proxy that session Pods use for model API
+// calls. When enabled, Pods receive ANTHROPIC_BASE_URL / OPENAI_BASE_URL pointing
+// at /llm-proxy/{sessionId}/{provider} and authenticate with their session API key;
+// the upstream provider keys stay on the proxy.
+type LLMProxyConfig struct {
+	// Enabled turns on the /llm-proxy endpoint and Pod environment injection.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// PublicURL is the base URL session Pods use to reach this proxy.
+	// Defaults to kubernetes_session.provisioner_proxy_url or the in-cluster service URL.
+	PublicURL string `json:"public_url" mapstructure:"public_url"`
+	// LogBodies includes redacted request and response bodies in the LLM proxy log.
+	LogBodies bool `json:"log_bodies" mapstructure:"log_bodies"`
+	// MaxLoggedBodyBytes caps each logged body (default: 4096).
+	MaxLoggedBodyBytes int `json:"max_logged_body_bytes" mapstructure:"max_logged_body_bytes"`
+	// Providers lists upstream providers in failover order. Providers of the same
+	// type are tried in order until one returns a non-retryable response.
+	Providers []LLMProxyProviderConfig `json:"providers" mapstructure:"providers"`
+}
+
+// LLMProxyProviderConfig describes one upstream model API provider.
+type LLMProxyProviderConfig struct {
+	// Name identifies the provider in logs (e.g. "anthropic-primary").
+	Name string `json:"name" mapstructure:"name"`
+	// Type is the wire protocol: "anthropic" or "openai".
+	Type string `json:"type" mapstructure:"type"`
+	// BaseURL is the upstream base URL (e.g. "https://api.anthropic.com").
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// APIKeyEnv is the name of the environment variable holding the API key.
+	APIKeyEnv string `json:"api_key_env" mapstructure:"api_key_env"`
+}
+
+// HasProviderType reports whether a provider of the given type is configured.
+func (c LLMProxyConfig) HasProviderType(providerType string) bool {
+	for _, p := range c.Providers {
+		if p.Type == providerType {
+			return true
+		}
+	}
+	return false
+}
+
+// EgressProxyConfig configures the corporate HTTP/SOCKS proxy and CA bundle used
+// for outbound traffic from session Pods and from the proxy process itself.
+type EgressProxyConfig struct {
+	// HTTPProxy is the proxy for plain HTTP requests (e.g. "http://proxy.corp:3128").
+	HTTPProxy string `json:"http_proxy" mapstructure:"http_proxy"`
+	// HTTPSProxy is the proxy for HTTPS requests. socks5:// URLs are supported.
+	HTTPSProxy string `json:"https_proxy" mapstructure:"https_proxy"`
+	// AllProxy is exported as ALL_PROXY for clients such as curl and git.
+	AllProxy string `json:"all_proxy" mapstructure:"all_proxy"`
+	// NoProxy is a comma-separated list of hosts, domains and CIDRs that bypass the proxy.
+	NoProxy string `json:"no_proxy" mapstructure:"no_proxy"`
+	// CABundleConfigMap is a ConfigMap in the session namespace holding the
+	// corporate CA certificates (PEM). It is mounted into session Pods and
+	// appended to their trust store.
+	CABundleConfigMap string `json:"ca_bundle_config_map" mapstructure:"ca_bundle_config_map"`
+	// CABundleKey is the ConfigMap key holding the PEM bundle (default: "ca-bundle.crt").
+	CABundleKey string `json:"ca_bundle_key" mapstructure:"ca_bundle_key"`
+	// CABundleFile is a PEM file added to the proxy process's own trust store.
+	CABundleFile string `json:"ca_bundle_file" mapstructure:"ca_bundle_file"`
+	// ApplyToProxy exports the proxy settings into the agentapi-proxy process
+	// environment at startup (variables already set in the environment win).
+	ApplyToProxy bool `json:"apply_to_proxy" mapstructure:"apply_to_proxy"`
+	// Teams holds per-team overrides. Empty fields inherit the global value;
+	// NoProxy entries are appended to the global list.
+	Teams []EgressProxyTeamConfig `json:"teams" mapstructure:"teams"`
+}
+
+// ProxySettings returns the egress proxy settings for teamID with its team
+// override applied. An empty teamID returns the global settings.
+func (c EgressProxyConfig) ProxySettings(teamID string) egressproxy.Settings {
+	base := egressproxy.Settings{
+		HTTPProxy:  c.HTTPProxy,
+		HTTPSProxy: c.HTTPSProxy,
+		AllProxy:   c.AllProxy,
+		NoProxy:    c.NoProxy,
+	}
+	overrides := make([]egressproxy.TeamOverride, 0, len(c.Teams))
+	for _, t := range c.Teams {
+		overrides = append(overrides, egressproxy.TeamOverride{
+			TeamID: t.TeamID,
+			Settings: egressproxy.Settings{
+				HTTPProxy:  t.HTTPProxy,
+				HTTPSProxy: t.HTTPSProxy,
+				AllProxy:   t.AllProxy,
+				NoProxy:    t.NoProxy,
+			},
+		})
+	}
+	return egressproxy.Resolve(base, overrides, teamID)
+}
+
+// CABundleConfigMapFor returns the CA bundle ConfigMap to mount for teamID.
+func (c EgressProxyConfig) CABundleConfigMapFor(teamID string) string {
+	if teamID != "" {
+		for _, t := range c.Teams {
+			if t.TeamID == teamID && t.CABundleConfigMap != "" {
+				return t.CABundleConfigMap
+			}
+		}
+	}
+	return c.CABundleConfigMap
+}
+
+// EgressProxyTeamConfig overrides the egress proxy for sessions of one team.
+type EgressProxyTeamConfig struct {
+	// TeamID is the team identifier in "org/team-slug" format.
+	TeamID     string `json:"team_id" mapstructure:"team_id"`
+	HTTPProxy  string `json:"http_proxy" mapstructure:"http_proxy"`
+	HTTPSProxy string `json:"https_proxy" mapstructure:"https_proxy"`
+	AllProxy   string `json:"all_proxy" mapstructure:"all_proxy"`
+	NoProxy    string `json:"no_proxy" mapstructure:"no_proxy"`
+	// CABundleConfigMap replaces the global CA bundle ConfigMap for this team.
+	CABundleConfigMap string `json:"ca_bundle_config_map" mapstructure:"ca_bundle_config_map"`
+}
+
+// GitHubEnterpriseConfig points sessions, GitHub App token minting and GitHub
+// webhooks at GitHub Enterprise Server instances instead of github.com. It
+// takes precedence over GITHUB_API and GITHUB_URL in the GitHub config Secret.
+type GitHubEnterpriseConfig struct {
+	// URL is the web URL of the default instance (e.g. "https://github.example.com").
+	// Empty means github.com.
+	URL string `json:"url" mapstructure:"url"`
+	// APIURL is the REST API URL of the default instance (default: <url>/api/v3).
+	APIURL string `json:"api_url" mapstructure:"api_url"`
+	// Teams assigns their own instance to the sessions of teams.
+	Teams []GitHubEnterpriseTeamConfig `json:"teams" mapstructure:"teams"`
+}
+
+// GitHubEnterpriseTeamConfig is the GitHub instance of the sessions of one team.
+type GitHubEnterpriseTeamConfig struct {
+	// TeamID is the team identifier in "org/team-slug" format.
+	TeamID string `json:"team_id" mapstructure:"team_id"`
+	URL    string `json:"url" mapstructure:"url"`
+	APIURL string `json:"api_url" mapstructure:"api_url"`
+	// SecretName replaces kubernetes_session.github_secret_name for this team.
+	// It holds the GitHub App (or token) registered on the team's instance.
+	SecretName string `json:"secret_name" mapstructure:"secret_name"`
+}
+
+// Instance returns the GitHub instance for teamID and the GitHub Secret that
+// replaces the default one ("" keeps it). ok is false when neither a default
+// nor a team instance is configured, leaving the GitHub config Secret in
+// charge. An empty teamID returns the default instance.
+func (c GitHubEnterpriseConfig) Instance(teamID string) (instance github_pkg.Instance, secretName string, ok bool) {
+	if teamID != "" {
+		for _, t := range c.Teams {
+			if t.TeamID != teamID {
+				continue
+			}
+			if instance, err := github_pkg.NewInstance(t.URL, t.APIURL); err == nil {
+				return instance, t.SecretName, true
+			}
+		}
+	}
+	if c.URL == "" && c.APIURL == "" {
+		return github_pkg.DefaultInstance(), "", false
+	}
+	instance, err := github_pkg.NewInstance(c.URL, c.APIURL)
+	if err != nil {
+		return github_pkg.DefaultInstance(), "", false
+	}
+	return instance, "", true
+}
+
+// WebhookGitHubEnterpriseHost returns the GitHub Enterprise host that GitHub
+// webhooks without an enterprise_url are matched against:
+// webhook.github_enterprise_host, else the host of the default
+// github_enterprise instance. Empty means github.com.
+func (c *Config) WebhookGitHubEnterpriseHost() string {
+	if c.Webhook.GitHubEnterpriseHost != "" {
+		return c.Webhook.GitHubEnterpriseHost
+	}
+	if instance, _, ok := c.GitHubEnterprise.Instance(""); ok && instance.IsEnterprise() {
+		return instance.Host()
+	}
+	return ""
+}
+
+// Validate returns the first invalid URL or team entry
+func (c GitHubEnterpriseConfig) Validate() error {
+	if _, err := github_pkg.NewInstance(c.URL, c.APIURL); err != nil {
+		return fmt.Errorf("github_enterprise: %w", err)
+	}
+	seen := make(map[string]bool, len(c.Teams))
+	for i, t := range c.Teams {
+		if org, slug, found := strings.Cut(t.TeamID, "/"); !found || org == "" || slug == "" {
+			return fmt.Errorf("github_enterprise.teams[%d]: team_id %q must be in org/team-slug format", i, t.TeamID)
+		}
+		if seen[t.TeamID] {
+			return fmt.Errorf("github_enterprise.teams[%d]: duplicate team_id %q", i, t.TeamID)
+		}
+		seen[t.TeamID] = true
+		if t.URL == "" && t.APIURL == "" {
+			return fmt.Errorf("github_enterprise.teams[%d]: url or api_url is required", i)
+		}
+		if _, err := github_pkg.NewInstance(t.URL, t.APIURL); err != nil {
+			return fmt.Errorf("github_enterprise.teams[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// GitHubReportingConfig configures reporting the work of sessions created
+// from GitHub webhooks on their repository: a working branch and a Check Run
+// are created when the session starts, and a pull request is opened and the
+// Check Run completed once the agent has finished. The GitHub App needs the
+// Contents, Pull requests and Checks write permissions.
+type GitHubReportingConfig struct {
+	// Enabled turns on reporting for sessions with a repository
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// BranchPrefix prefixes the session ID in working branch names (default: agentapi/)
+	BranchPrefix string `json:"branch_prefix" mapstructure:"branch_prefix"`
+	// CheckName is the name of the Check Run (default: agentapi)
+	CheckName string `json:"check_name" mapstructure:"check_name"`
+	// DetailsURL links the Check Run to the session; {session_id} is replaced
+	// with the session ID
+	DetailsURL string `json:"details_url" mapstructure:"details_url"`
+}
+
+// AirGapConfig configures the air-gapped deployment mode. When enabled, the
+// default container images are rewritten to internal registry mirrors, session
+// Pods are pointed at internal package mirrors with non-essential external
+// traffic disabled, and startup validation reports any configuration value that
+// still points outside the cluster.
+type AirGapConfig struct {
+	// Enabled turns on air-gapped mode.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Strict makes startup fail when validation finds external dependencies
+	// instead of only logging them.
+	Strict bool `json:"strict" mapstructure:"strict"`
+	// ImageRegistry is the mirror prefix used for images whose registry has no
+	// entry in RegistryMirrors (e.g. "registry.corp/mirror").
+	ImageRegistry string `json:"image_registry" mapstructure:"image_registry"`
+	// RegistryMirrors maps individual upstream registries to mirror prefixes.
+	RegistryMirrors []AirGapRegistryMirror `json:"registry_mirrors" mapstructure:"registry_mirrors"`
+	// NPMRegistry is exported to session Pods as NPM_CONFIG_REGISTRY.
+	NPMRegistry string `json:"npm_registry" mapstructure:"npm_registry"`
+	// PyPIIndexURL is exported to session Pods as PIP_INDEX_URL and UV_INDEX_URL.
+	PyPIIndexURL string `json:"pypi_index_url" mapstructure:"pypi_index_url"`
+	// GoProxy is exported to session Pods as GOPROXY.
+	GoProxy string `json:"goproxy" mapstructure:"goproxy"`
+	// GoSumDB is exported to session Pods as GOSUMDB (e.g. "off" or an internal sumdb).
+	GoSumDB string `json:"gosumdb" mapstructure:"gosumdb"`
+	// AllowedHosts lists additional internal hosts. Entries starting with "."
+	// or "*." match subdomains. Cluster-local names and private IPs are always allowed.
+	AllowedHosts []string `json:"allowed_hosts" mapstructure:"allowed_hosts"`
+	// SwaggerUIURL is the base URL of the swagger-ui-dist assets the /docs page
+	// loads, e.g. an internal mirror. Applies outside air-gapped mode as well.
+	SwaggerUIURL string `json:"swagger_ui_url" mapstructure:"swagger_ui_url"`
+}
+
+// AirGapRegistryMirror maps an upstream container registry to an internal mirror.
+type AirGapRegistryMirror struct {
+	// Registry is the upstream registry host (e.g. "ghcr.io", "docker.io").
+	Registry string `json:"registry" mapstructure:"registry"`
+	// Target is the mirror prefix that replaces the registry (e.g. "registry.corp/ghcr").
+	Target string `json:"target" mapstructure:"target"`
+}
+
 // SessionManagerConfig holds configuration for the session manager forwarding endpoint.
 // When enabled, External Session Manager (small-cluster mode) accepts pre-built SessionSettings from a
 // trusted upstream proxy (親プロキシ) and creates sessions without requiring local secrets.
@@ -499,6 +1510,311 @@ type RedisConfig struct {
 	WriteTimeout string `json:"write_timeout" mapstructure:"write_timeout"`
 }
 
+// RateLimitRule is a token-bucket rule. A zero RPS disables the rule.
+type RateLimitRule struct {
+	// RPS is the sustained number of requests per second
+	RPS float64 `json:"rps" mapstructure:"rps"`
+	// Burst is the number of requests allowed at once (default: 1)
+	Burst int `json:"burst" mapstructure:"burst"`
+}
+
+// RateLimitRouteConfig applies a per-user rule to a group of routes
+type RateLimitRouteConfig struct {
+	// Name identifies the group in logs and 429 responses (e.g. "sessions")
+	Name string `json:"name" mapstructure:"name"`
+	// PathPrefix matches the route pattern (e.g. "/sessions", or "/:sessionId" for proxied traffic)
+	PathPrefix string `json:"path_prefix" mapstructure:"path_prefix"`
+	// RPS is the sustained number of requests per second
+	RPS float64 `json:"rps" mapstructure:"rps"`
+	// Burst is the number of requests allowed at once (default: 1)
+	Burst int `json:"burst" mapstructure:"burst"`
+}
+
+// RateLimitConfig configures HTTP rate limiting. Requests over a limit get
+// 429 Too Many Requests with a Retry-After header.
+type RateLimitConfig struct {
+	// Enabled turns on rate limiting
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Backend is "memory" (per replica) or "redis" (shared, uses the redis section)
+	Backend string `json:"backend" mapstructure:"backend"`
+	// User limits each user, or each client IP for unauthenticated requests
+	User RateLimitRule `json:"user" mapstructure:"user"`
+	// APIKey limits each API key
+	APIKey RateLimitRule `json:"api_key" mapstructure:"api_key"`
+	// Team limits the combined traffic of each team's members
+	Team RateLimitRule `json:"team" mapstructure:"team"`
+	// Routes limit each user within a route group; the first matching group applies
+	Routes []RateLimitRouteConfig `json:"routes" mapstructure:"routes"`
+}
+
+// ConfigReloadConfig configures hot reloading. POST /admin/config/reload
+// always reloads; Watch also reloads when the config file, the auth config
+// file, the API keys file or the Kubernetes session config file changes.
+// Only rate limits, auth providers, session images and session resource
+// limits are applied without a restart.
+type ConfigReloadConfig struct {
+	// Watch reloads on changes of the config files
+	Watch bool `json:"watch" mapstructure:"watch"`
+	// Debounce is the quiet period after a change before reloading (default: "2s")
+	Debounce string `json:"debounce" mapstructure:"debounce"`
+}
+
+// DeliveryConfig configures the persistent retry queue for outbound
+// notifications and Slack posts. Failed deliveries are retried with
+// exponential backoff and moved to a dead-letter list after MaxAttempts.
+type DeliveryConfig struct {
+	// Enabled turns on the delivery queue
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Backend is "file" (per replica, under Dir) or "redis" (shared, uses the redis section)
+	Backend string `json:"backend" mapstructure:"backend"`
+	// Dir is the file backend directory (default: ~/.agentapi-proxy/deliveries)
+	Dir string `json:"dir" mapstructure:"dir"`
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered (default: 8)
+	MaxAttempts int `json:"max_attempts" mapstructure:"max_attempts"`
+	// BaseBackoff is the wait after the first failure, doubled per attempt (e.g. "30s")
+	BaseBackoff string `json:"base_backoff" mapstructure:"base_backoff"`
+	// MaxBackoff caps the wait between attempts (e.g. "1h")
+	MaxBackoff string `json:"max_backoff" mapstructure:"max_backoff"`
+	// PollInterval is how often due deliveries are attempted (e.g. "10s")
+	PollInterval string `json:"poll_interval" mapstructure:"poll_interval"`
+}
+
+// OutboundWebhookConfig configures webhooks that users and administrators
+// register to receive session lifecycle events. Failed deliveries are
+// retried through the delivery queue, which is enabled with this feature.
+type OutboundWebhookConfig struct {
+	// Enabled turns on outbound webhooks and their API
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Dir is where webhooks and delivery logs are stored (default: ~/.agentapi-proxy/outbound-webhooks)
+	Dir string `json:"dir" mapstructure:"dir"`
+	// AllowPrivateTargets lets webhook URLs resolve to loopback, private and link-local addresses
+	AllowPrivateTargets bool `json:"allow_private_targets" mapstructure:"allow_private_targets"`
+}
+
+// SessionNotificationsConfig configures push notifications that the proxy
+// sends to the owners of sessions when they become ready, fail or finish a
+// run. Recipients, channels and notification types are those of the
+// notification subscriptions and user settings.
+type SessionNotificationsConfig struct {
+	// Enabled turns on notifications on session lifecycle events
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+}
+
+// EventBusConfig configures the publisher of schema-versioned session
+// lifecycle events to a message broker, so that platform automation can react
+// to sessions, relayed messages, capability approvals and policy violations
+// without polling the proxy API.
+type EventBusConfig struct {
+	// Enabled turns on the event bus publisher
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Type is "nats", "kafka" (through a Confluent REST Proxy), "pubsub"
+	// (Google Cloud Pub/Sub) or "stdout".
+	Type string `json:"type" mapstructure:"type"`
+	// URL is the broker: nats://host:4222 for nats, the REST Proxy base URL
+	// for kafka. pubsub defaults to https://pubsub.googleapis.com.
+	URL string `json:"url" mapstructure:"url"`
+	// Topic is the NATS subject prefix (events go to <topic>.<event type>),
+	// the Kafka topic, or the Pub/Sub topic as projects/<project>/topics/<topic>.
+	// Default: "agentapi.events".
+	Topic string `json:"topic" mapstructure:"topic"`
+	// Headers are added to kafka and pubsub requests, e.g. for authentication.
+	// pubsub uses the token of the GCE metadata server without an Authorization header.
+	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers"`
+	// Token authenticates to NATS (auth_token of the CONNECT message)
+	Token string `json:"token,omitempty" mapstructure:"token"`
+	// Events restricts the published event types (e.g. "session.created");
+	// empty publishes all.
+	Events []string `json:"events,omitempty" mapstructure:"events"`
+	// Source identifies this proxy in events. Default: "agentapi-proxy".
+	Source string `json:"source" mapstructure:"source"`
+	// BufferSize is the number of events kept while the broker is slow or
+	// unreachable; newer events are dropped when it is full. Default: 1000.
+	BufferSize int `json:"buffer_size" mapstructure:"buffer_size"`
+}
+
+// CompletionCallbacksConfig configures the callbacks sent to the
+// completion_callback_url of a session when it completes.
+type CompletionCallbacksConfig struct {
+	// Secret signs callback requests (X-AgentAPI-Signature-256) when set
+	Secret string `json:"secret" mapstructure:"secret"`
+	// AllowPrivateTargets lets callback URLs resolve to loopback, private and link-local addresses
+	AllowPrivateTargets bool `json:"allow_private_targets" mapstructure:"allow_private_targets"`
+}
+
+// CapacityForecastConfig configures the session history sampler behind
+// GET /admin/capacity/forecast. Session requests are read from
+// kubernetes_session.cpu_request and memory_request.
+type CapacityForecastConfig struct {
+	// Enabled turns on sampling and the forecast endpoint
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Dir is where the hourly history is stored (default: ~/.agentapi-proxy/capacity)
+	Dir string `json:"dir" mapstructure:"dir"`
+	// SampleInterval is how often running sessions are counted (e.g., "5m")
+	SampleInterval string `json:"sample_interval" mapstructure:"sample_interval"`
+	// RetentionDays is how many days of history are kept
+	RetentionDays int `json:"retention_days" mapstructure:"retention_days"`
+	// NodeCPU and NodeMemory are the allocatable resources of one session
+	// node (e.g., "7500m", "28Gi"). Node counts are reported when set.
+	NodeCPU    string `json:"node_cpu" mapstructure:"node_cpu"`
+	NodeMemory string `json:"node_memory" mapstructure:"node_memory"`
+	// Headroom is the extra capacity on top of the forecast peak (e.g., 0.2 for 20%)
+	Headroom float64 `json:"headroom" mapstructure:"headroom"`
+}
+
+// UsageConfig configures the accounting of the model token usage and cost
+// of sessions behind GET /usage. Usage is read from the OpenTelemetry
+// Collector of session Pods, so kubernetes_session.otel_collector_enabled is
+// required.
+type UsageConfig struct {
+	// Enabled turns on usage accounting
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// CollectInterval is how often the metrics of running sessions are read
+	// (default: "1m")
+	CollectInterval string `json:"collect_interval" mapstructure:"collect_interval"`
+	// Budgets are the monthly limits of teams. New team sessions are refused
+	// once a limit of their team is reached for the month.
+	Budgets []UsageBudgetConfig `json:"budgets" mapstructure:"budgets"`
+}
+
+// UsageBudgetConfig is the monthly limit of a team. Zero limits are not
+// enforced.
+type UsageBudgetConfig struct {
+	// Team is the team ID ("org/team-slug"), or "*" for every team without
+	// a budget of its own
+	Team string `json:"team" mapstructure:"team"`
+	// MonthlyTokens limits the tokens, cache tokens included
+	MonthlyTokens int64 `json:"monthly_tokens" mapstructure:"monthly_tokens"`
+	// MonthlyCostUSD limits the cost reported by the agent
+	MonthlyCostUSD float64 `json:"monthly_cost_usd" mapstructure:"monthly_cost_usd"`
+}
+
+// ShowbackConfig configures usage metering per team and the monthly
+// showback statements behind /admin/billing.
+type ShowbackConfig struct {
+	// Enabled turns on metering and the billing endpoints
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Dir is where monthly usage is stored (default: ~/.agentapi-proxy/showback)
+	Dir string `json:"dir" mapstructure:"dir"`
+	// SampleInterval is how often running sessions are metered (e.g., "5m")
+	SampleInterval string `json:"sample_interval" mapstructure:"sample_interval"`
+	// Rates are the unit prices of statements
+	Rates ShowbackRatesConfig `json:"rates" mapstructure:"rates"`
+	// DigestSlackChannel receives the statements of the previous month at the
+	// start of each month. Requires SLACK_BOT_TOKEN.
+	DigestSlackChannel string `json:"digest_slack_channel" mapstructure:"digest_slack_channel"`
+}
+
+// ShowbackRatesConfig holds the unit prices of showback statements. A zero
+// rate lists the usage without charging it.
+type ShowbackRatesConfig struct {
+	Currency     string  `json:"currency" mapstructure:"currency"`
+	Session      float64 `json:"session" mapstructure:"session"`
+	SessionHour  float64 `json:"session_hour" mapstructure:"session_hour"`
+	StorageHour  float64 `json:"storage_hour" mapstructure:"storage_hour"`
+	InputTokens  float64 `json:"input_tokens" mapstructure:"input_tokens"`   // per million tokens
+	OutputTokens float64 `json:"output_tokens" mapstructure:"output_tokens"` // per million tokens
+	Notification float64 `json:"notification" mapstructure:"notification"`
+}
+
+// MeteringConfig configures the export of team usage to a billing system.
+// It exports the usage metered for showback, so Showback must be enabled.
+type MeteringConfig struct {
+	// Enabled turns on the export
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Provider is "stripe" (billing meter events) or "webhook"
+	Provider string `json:"provider" mapstructure:"provider"`
+	// Interval is how often usage is exported (e.g., "1h")
+	Interval string `json:"interval" mapstructure:"interval"`
+	// Dir is where the reported totals are kept (default: ~/.agentapi-proxy/metering)
+	Dir string `json:"dir" mapstructure:"dir"`
+	// Stripe configures the stripe provider
+	Stripe MeteringStripeConfig `json:"stripe" mapstructure:"stripe"`
+	// Webhook configures the webhook provider
+	Webhook MeteringWebhookConfig `json:"webhook" mapstructure:"webhook"`
+}
+
+// MeteringStripeConfig configures the export to Stripe billing meters
+type MeteringStripeConfig struct {
+	// APIKeyEnv is the name of the environment variable holding the Stripe
+	// secret key (default: "STRIPE_API_KEY")
+	APIKeyEnv string `json:"api_key_env" mapstructure:"api_key_env"`
+	// BaseURL overrides the Stripe API endpoint
+	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	// Events maps metrics (e.g., "session_minutes") to meter event names.
+	// Only metrics listed here are exported.
+	Events map[string]string `json:"events" mapstructure:"events"`
+	// Customers maps teams to Stripe customers. Usage of unmapped teams is
+	// kept until they are mapped.
+	Customers []MeteringCustomer `json:"customers" mapstructure:"customers"`
+}
+
+// MeteringCustomer maps a team to a Stripe customer
+type MeteringCustomer struct {
+	TeamID     string `json:"team_id" mapstructure:"team_id"`
+	CustomerID string `json:"customer_id" mapstructure:"customer_id"`
+}
+
+// MeteringWebhookConfig configures the export to a metering webhook
+type MeteringWebhookConfig struct {
+	// URL receives one POST per usage record
+	URL string `json:"url" mapstructure:"url"`
+	// Secret signs the records with HMAC-SHA256 when set
+	Secret string `json:"secret" mapstructure:"secret"`
+}
+
+// TranscriptArchiveConfig configures archiving the conversation of sessions
+// when they are deleted
+type TranscriptArchiveConfig struct {
+	// Enabled archives the transcript of each deleted session as JSON into
+	// the artifact store (see AssetConfig; S3 with the s3 asset backend), so
+	// that GET /sessions/{id}/export keeps working after deletion
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+}
+
+// SessionArchiveConfig configures archiving the pod logs, agent history and
+// workdir artifacts of sessions to object storage before they are deleted
+type SessionArchiveConfig struct {
+	// Enabled turns on archiving and the /sessions/{id}/archive endpoints
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Provider is "s3" (default) or "gcs". GCS is accessed through its
+	// S3-compatible XML API with HMAC keys as AWS credentials.
+	Provider string `json:"provider" mapstructure:"provider"`
+	// Bucket is the bucket name (required)
+	Bucket string `json:"bucket" mapstructure:"bucket"`
+	// Region is the bucket region (optional, uses the AWS default config if empty)
+	Region string `json:"region" mapstructure:"region"`
+	// Prefix is prepended to the {team}/{session_id}/ keys of archived sessions
+	Prefix string `json:"prefix" mapstructure:"prefix"`
+	// Endpoint is a custom S3-compatible endpoint URL
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
+	// RetentionDays removes archives older than this many days (0 keeps them forever)
+	RetentionDays int `json:"retention_days" mapstructure:"retention_days"`
+	// Artifacts lists shell glob patterns, relative to the session workdir,
+	// of files archived as artifacts.tar.gz, e.g. ["repo/coverage.out", "repo/dist"]
+	Artifacts []string `json:"artifacts" mapstructure:"artifacts"`
+}
+
+// RBACConfig configures role-based authorization of session, log, exec,
+// team configuration and schedule operations. Roles are admin, team-admin,
+// member and viewer; actions are listed in entities.Actions.
+type RBACConfig struct {
+	// Enabled turns on role-based authorization
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Roles maps a role to its actions ("*" for all) and replaces the
+	// built-in assignment of that role. Roles not listed keep the defaults.
+	Roles map[string][]string `json:"roles" mapstructure:"roles"`
+	// Teams overrides role assignments for individual teams
+	Teams []RBACTeamPolicy `json:"teams" mapstructure:"teams"`
+}
+
+// RBACTeamPolicy overrides role assignments within one team
+type RBACTeamPolicy struct {
+	// TeamID is the team in "org/team-slug" format
+	TeamID string `json:"team_id" mapstructure:"team_id"`
+	// Roles maps a role to its actions within the team
+	Roles map[string][]string `json:"roles" mapstructure:"roles"`
+}
+
 // Config represents the proxy configuration
 type Config struct {
 	// Auth represents authentication configuration
@@ -511,8 +1827,24 @@ type Config struct {
 	KubernetesSession KubernetesSessionConfig `json:"kubernetes_session" mapstructure:"kubernetes_session"`
 	// ScheduleWorker is the configuration for the schedule worker
 	ScheduleWorker ScheduleWorkerConfig `json:"schedule_worker" mapstructure:"schedule_worker"`
+	// ScheduleParser is the configuration for natural language schedule drafting
+	ScheduleParser ScheduleParserConfig `json:"schedule_parser" mapstructure:"schedule_parser"`
+	// SessionSummary configures the generated one-line summaries of sessions.
+	SessionSummary SessionSummaryConfig `json:"session_summary" mapstructure:"session_summary"`
 	// SlackbotCleanupWorker is the configuration for the Slackbot session cleanup worker
 	SlackbotCleanupWorker SlackbotCleanupWorkerConfig `json:"slackbot_cleanup_worker" mapstructure:"slackbot_cleanup_worker"`
+	// IdleReaper is the configuration for the idle session reaper
+	IdleReaper IdleReaperConfig `json:"idle_reaper" mapstructure:"idle_reaper"`
+	// LeaderElection elects the replica that runs the singleton background subsystems
+	LeaderElection LeaderElectionConfig `json:"leader_election" mapstructure:"leader_election"`
+	// Shutdown configures connection draining on SIGTERM.
+	Shutdown ShutdownConfig `json:"shutdown" mapstructure:"shutdown"`
+	// Streaming is the configuration for SSE and WebSocket requests proxied to sessions.
+	Streaming StreamingConfig `json:"streaming" mapstructure:"streaming"`
+	// BackendProxy is the retry and circuit breaker configuration for requests proxied to sessions.
+	BackendProxy BackendProxyConfig `json:"backend_proxy" mapstructure:"backend_proxy"`
+	// Tracing is the configuration for OpenTelemetry trace export.
+	Tracing TracingConfig `json:"tracing" mapstructure:"tracing"`
 	// StockInventoryWorker is the configuration for the stock session inventory worker.
 	StockInventoryWorker StockInventoryWorkerConfig `json:"stock_inventory_worker" mapstructure:"stock_inventory_worker"`
 	// Webhook is the configuration for webhook functionality
@@ -521,6 +1853,8 @@ type Config struct {
 	Scia SciaConfig `json:"scia" mapstructure:"scia"`
 	// Memory is the configuration for memory storage backend
 	Memory MemoryConfig `json:"memory" mapstructure:"memory"`
+	// SessionStore is the configuration for the session state store
+	SessionStore SessionStoreConfig `json:"session_store" mapstructure:"session_store"`
 	// Asset is the configuration for static asset upload and serving.
 	Asset AssetConfig `json:"asset" mapstructure:"asset"`
 	// Slack is the configuration for Slack bot inbound webhook functionality
@@ -530,8 +1864,48 @@ type Config struct {
 	// Redis holds optional Redis configuration for cross-pod status synchronisation.
 	// When Redis.Addr is empty the feature is disabled and a no-op fallback is used.
 	Redis RedisConfig `json:"redis" mapstructure:"redis"`
+	// RBAC configures role-based authorization with per-team policy overrides.
+	RBAC RBACConfig `json:"rbac" mapstructure:"rbac"`
+	// Audit configures the audit event log behind compliance reports.
+	Audit AuditConfig `json:"audit" mapstructure:"audit"`
+	// RateLimit configures per-user, per-API-key, per-team and per-route rate limiting.
+	RateLimit RateLimitConfig `json:"rate_limit" mapstructure:"rate_limit"`
+	// Delivery configures the persistent retry queue for outbound deliveries.
+	Delivery DeliveryConfig `json:"delivery" mapstructure:"delivery"`
+	// OutboundWebhooks configures webhooks that receive session lifecycle events.
+	OutboundWebhooks OutboundWebhookConfig `json:"outbound_webhooks" mapstructure:"outbound_webhooks"`
+	// EventBus publishes session lifecycle events to a message broker.
+	EventBus EventBusConfig `json:"event_bus" mapstructure:"event_bus"`
+	// SessionNotifications sends push notifications on session lifecycle events.
+	SessionNotifications SessionNotificationsConfig `json:"session_notifications" mapstructure:"session_notifications"`
+	// CompletionCallbacks configures the completion callbacks of sessions.
+	CompletionCallbacks CompletionCallbacksConfig `json:"completion_callbacks" mapstructure:"completion_callbacks"`
+	// CapacityForecast configures session history sampling and capacity forecasts.
+	CapacityForecast CapacityForecastConfig `json:"capacity_forecast" mapstructure:"capacity_forecast"`
+	// Usage configures per-session token and cost accounting and team budgets.
+	Usage UsageConfig `json:"usage" mapstructure:"usage"`
+	// Showback configures team usage metering and monthly showback statements.
+	Showback ShowbackConfig `json:"showback" mapstructure:"showback"`
+	// Metering configures the export of team usage to Stripe or a metering webhook.
+	Metering MeteringConfig `json:"metering" mapstructure:"metering"`
+	// TranscriptArchive configures archiving session transcripts on deletion.
+	TranscriptArchive TranscriptArchiveConfig `json:"transcript_archive" mapstructure:"transcript_archive"`
+	// SessionArchive configures archiving session logs and artifacts to object storage on deletion.
+	SessionArchive SessionArchiveConfig `json:"session_archive" mapstructure:"session_archive"`
 	// GitSync holds proxy-level GitHub sync settings (e.g. KMS encryption key).
 	GitSync GitSyncProxyConfig `json:"git_sync" mapstructure:"git_sync"`
+	// LLMProxy configures the egress proxy for session Pod model API traffic.
+	LLMProxy LLMProxyConfig `json:"llm_proxy" mapstructure:"llm_proxy"`
+	// EgressProxy configures the corporate HTTP/SOCKS proxy and CA bundle.
+	EgressProxy EgressProxyConfig `json:"egress_proxy" mapstructure:"egress_proxy"`
+	// GitHubEnterprise configures the GitHub Enterprise Server instances.
+	GitHubEnterprise GitHubEnterpriseConfig `json:"github_enterprise" mapstructure:"github_enterprise"`
+	// GitHubReporting reports sessions created from GitHub webhooks as branches, Check Runs and pull requests.
+	GitHubReporting GitHubReportingConfig `json:"github_reporting" mapstructure:"github_reporting"`
+	// AirGap configures the air-gapped deployment mode.
+	AirGap AirGapConfig `json:"air_gap" mapstructure:"air_gap"`
+	// ConfigReload configures reloading the safe-to-change settings without a restart.
+	ConfigReload ConfigReloadConfig `json:"config_reload" mapstructure:"config_reload"`
 }
 
 // GitSyncEncryptionProxyConfig holds proxy-level AWS KMS settings for GitHub sync.
@@ -575,8 +1949,9 @@ type GitSyncProxyConfig struct {
 
 // SlackConfig represents Slack bot (Socket Mode) configuration
 type SlackConfig struct {
-	// SigningSecret is the default Slack App signing secret (kept for backward compatibility,
-	// no longer required for Socket Mode operation).
+	// SigningSecret is the default Slack App signing secret. It is not required for
+	// Socket Mode operation; when set, the /slack/commands slash command endpoint is enabled
+	// and requests to it are verified with this secret.
 	// Set via AGENTAPI_SLACK_SIGNING_SECRET environment variable.
 	SigningSecret string `json:"signing_secret" mapstructure:"signing_secret"`
 	// AppTokenSecretName is the K8s Secret name containing the default App-level token (xapp-...).
@@ -971,6 +2346,18 @@ func initializeConfigStructsFromEnv(config *Config, v *viper.Viper) {
 	if paths := commaSeparatedList(os.Getenv("AGENTAPI_SCIA_TODOIST_PATHS")); len(paths) > 0 {
 		config.Scia.TodoistPaths = paths
 	}
+	if hosts := commaSeparatedList(os.Getenv("AGENTAPI_AIR_GAP_ALLOWED_HOSTS")); len(hosts) > 0 {
+		config.AirGap.AllowedHosts = hosts
+	}
+	if images := commaSeparatedList(os.Getenv("AGENTAPI_K8S_SESSION_ALLOWED_IMAGES")); len(images) > 0 {
+		config.KubernetesSession.AllowedImages = images
+	}
+	if servers := commaSeparatedList(os.Getenv("AGENTAPI_K8S_SESSION_ALLOWED_REQUEST_MCP_SERVERS")); len(servers) > 0 {
+		config.KubernetesSession.AllowedRequestMCPServers = servers
+	}
+	if agentTypes := commaSeparatedList(os.Getenv("AGENTAPI_K8S_SESSION_STATELESS_AGENT_TYPES")); len(agentTypes) > 0 {
+		config.KubernetesSession.StatelessAgentTypes = agentTypes
+	}
 
 	// Override fields if environment variables are set (even if structures already exist)
 	if config.Auth.Static != nil {
@@ -986,6 +2373,15 @@ func initializeConfigStructsFromEnv(config *Config, v *viper.Viper) {
 		if v.IsSet("auth.github.user_mapping.default_permissions") {
 			config.Auth.GitHub.UserMapping.DefaultPermissions = v.GetStringSlice("auth.github.user_mapping.default_permissions")
 		}
+		if v.IsSet("auth.github.team_sync.enabled") {
+			config.Auth.GitHub.TeamSync.Enabled = v.GetBool("auth.github.team_sync.enabled")
+		}
+		if v.IsSet("auth.github.team_sync.interval") {
+			config.Auth.GitHub.TeamSync.Interval = v.GetString("auth.github.team_sync.interval")
+		}
+		if v.IsSet("auth.github.team_sync.webhook_secret") {
+			config.Auth.GitHub.TeamSync.WebhookSecret = v.GetString("auth.github.team_sync.webhook_secret")
+		}
 
 		// Override OAuth settings if already exists
 		if config.Auth.GitHub.OAuth != nil {
@@ -1023,6 +2419,9 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("auth.github.token_header")
 	_ = v.BindEnv("auth.github.user_mapping.default_role")
 	_ = v.BindEnv("auth.github.user_mapping.default_permissions")
+	_ = v.BindEnv("auth.github.team_sync.enabled")
+	_ = v.BindEnv("auth.github.team_sync.interval")
+	_ = v.BindEnv("auth.github.team_sync.webhook_secret")
 
 	// GitHub OAuth configuration
 	_ = v.BindEnv("auth.github.oauth.client_id")
@@ -1080,7 +2479,52 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("kubernetes_session.pvc_storage_size", "AGENTAPI_K8S_SESSION_PVC_STORAGE_SIZE")
 	_ = v.BindEnv("kubernetes_session.pod_start_timeout", "AGENTAPI_K8S_SESSION_POD_START_TIMEOUT")
 	_ = v.BindEnv("kubernetes_session.pod_stop_timeout", "AGENTAPI_K8S_SESSION_POD_STOP_TIMEOUT")
+	_ = v.BindEnv("kubernetes_session.auto_resume", "AGENTAPI_K8S_SESSION_AUTO_RESUME")
+	_ = v.BindEnv("kubernetes_session.require_capability_approval", "AGENTAPI_K8S_SESSION_REQUIRE_CAPABILITY_APPROVAL")
+	_ = v.BindEnv("kubernetes_session.docker_image_cache_on_pvc", "AGENTAPI_K8S_SESSION_DOCKER_IMAGE_CACHE_ON_PVC")
+	_ = v.BindEnv("kubernetes_session.preview_deploy_hook_url", "AGENTAPI_K8S_SESSION_PREVIEW_DEPLOY_HOOK_URL")
+	_ = v.BindEnv("kubernetes_session.preview_deploy_hook_secret", "AGENTAPI_K8S_SESSION_PREVIEW_DEPLOY_HOOK_SECRET")
+	_ = v.BindEnv("kubernetes_session.namespace_placement", "AGENTAPI_K8S_SESSION_NAMESPACE_PLACEMENT")
+	_ = v.BindEnv("kubernetes_session.team_namespace_prefix", "AGENTAPI_K8S_SESSION_TEAM_NAMESPACE_PREFIX")
+	_ = v.BindEnv("kubernetes_session.team_namespace_network_policy", "AGENTAPI_K8S_SESSION_TEAM_NAMESPACE_NETWORK_POLICY")
+	_ = v.BindEnv("kubernetes_session.runtime_class_name", "AGENTAPI_K8S_SESSION_RUNTIME_CLASS_NAME")
+	_ = v.BindEnv("kubernetes_session.session_capacity", "AGENTAPI_K8S_SESSION_CAPACITY")
+	_ = v.BindEnv("kubernetes_session.preemption.enabled", "AGENTAPI_K8S_SESSION_PREEMPTION_ENABLED")
+	_ = v.BindEnv("kubernetes_session.preemption.min_idle", "AGENTAPI_K8S_SESSION_PREEMPTION_MIN_IDLE")
+	_ = v.BindEnv("kubernetes_session.health_probe.enabled", "AGENTAPI_K8S_SESSION_HEALTH_PROBE_ENABLED")
+	_ = v.BindEnv("kubernetes_session.health_probe.interval", "AGENTAPI_K8S_SESSION_HEALTH_PROBE_INTERVAL")
+	_ = v.BindEnv("kubernetes_session.health_probe.timeout", "AGENTAPI_K8S_SESSION_HEALTH_PROBE_TIMEOUT")
+	_ = v.BindEnv("kubernetes_session.health_probe.failure_threshold", "AGENTAPI_K8S_SESSION_HEALTH_PROBE_FAILURE_THRESHOLD")
+	_ = v.BindEnv("kubernetes_session.health_probe.restart_threshold", "AGENTAPI_K8S_SESSION_HEALTH_PROBE_RESTART_THRESHOLD")
+	_ = v.BindEnv("kubernetes_session.image_rollout.min_idle", "AGENTAPI_K8S_SESSION_IMAGE_ROLLOUT_MIN_IDLE")
+	_ = v.BindEnv("kubernetes_session.image_rollout.max_concurrent", "AGENTAPI_K8S_SESSION_IMAGE_ROLLOUT_MAX_CONCURRENT")
+	_ = v.BindEnv("kubernetes_session.image_rollout.interval", "AGENTAPI_K8S_SESSION_IMAGE_ROLLOUT_INTERVAL")
+	_ = v.BindEnv("kubernetes_session.image_rollout.upgrade_timeout", "AGENTAPI_K8S_SESSION_IMAGE_ROLLOUT_UPGRADE_TIMEOUT")
+	_ = v.BindEnv("kubernetes_session.snapshots.enabled", "AGENTAPI_K8S_SESSION_SNAPSHOTS_ENABLED")
+	_ = v.BindEnv("kubernetes_session.snapshots.volume_snapshot_class", "AGENTAPI_K8S_SESSION_SNAPSHOTS_VOLUME_SNAPSHOT_CLASS")
+	_ = v.BindEnv("kubernetes_session.snapshots.schedule", "AGENTAPI_K8S_SESSION_SNAPSHOTS_SCHEDULE")
+	_ = v.BindEnv("kubernetes_session.snapshots.keep", "AGENTAPI_K8S_SESSION_SNAPSHOTS_KEEP")
+	_ = v.BindEnv("kubernetes_session.snapshots.retain_after_delete", "AGENTAPI_K8S_SESSION_SNAPSHOTS_RETAIN_AFTER_DELETE")
+	_ = v.BindEnv("kubernetes_session.disruption_budget", "AGENTAPI_K8S_SESSION_DISRUPTION_BUDGET")
+	_ = v.BindEnv("kubernetes_session.disruption_budget_max_unavailable", "AGENTAPI_K8S_SESSION_DISRUPTION_BUDGET_MAX_UNAVAILABLE")
+	_ = v.BindEnv("kubernetes_session.prestop_checkpoint", "AGENTAPI_K8S_SESSION_PRESTOP_CHECKPOINT")
+	_ = v.BindEnv("kubernetes_session.recreate_evicted_sessions", "AGENTAPI_K8S_SESSION_RECREATE_EVICTED_SESSIONS")
+	_ = v.BindEnv("kubernetes_session.preview_namespace_prefix", "AGENTAPI_K8S_SESSION_PREVIEW_NAMESPACE_PREFIX")
+	_ = v.BindEnv("kubernetes_session.preview_deploy_timeout", "AGENTAPI_K8S_SESSION_PREVIEW_DEPLOY_TIMEOUT")
+	_ = v.BindEnv("kubernetes_session.exec_enabled", "AGENTAPI_K8S_SESSION_EXEC_ENABLED")
+	_ = v.BindEnv("kubernetes_session.runtime_cache_enabled", "AGENTAPI_K8S_SESSION_RUNTIME_CACHE_ENABLED")
+	_ = v.BindEnv("kubernetes_session.runtime_cache_storage_class", "AGENTAPI_K8S_SESSION_RUNTIME_CACHE_STORAGE_CLASS")
+	_ = v.BindEnv("kubernetes_session.runtime_cache_storage_size", "AGENTAPI_K8S_SESSION_RUNTIME_CACHE_STORAGE_SIZE")
+	_ = v.BindEnv("kubernetes_session.terminal_recording", "AGENTAPI_K8S_SESSION_TERMINAL_RECORDING")
 	_ = v.BindEnv("kubernetes_session.provisioner_proxy_url", "AGENTAPI_K8S_SESSION_PROVISIONER_PROXY_URL")
+	_ = v.BindEnv("kubernetes_session.cluster_domain", "AGENTAPI_K8S_SESSION_CLUSTER_DOMAIN")
+	_ = v.BindEnv("kubernetes_session.routing", "AGENTAPI_K8S_SESSION_ROUTING")
+	_ = v.BindEnv("kubernetes_session.max_session_replicas", "AGENTAPI_K8S_SESSION_MAX_SESSION_REPLICAS")
+	_ = v.BindEnv("kubernetes_session.oneshot_job_enabled", "AGENTAPI_K8S_SESSION_ONESHOT_JOB_ENABLED")
+	_ = v.BindEnv("kubernetes_session.oneshot_job_backoff_limit", "AGENTAPI_K8S_SESSION_ONESHOT_JOB_BACKOFF_LIMIT")
+	_ = v.BindEnv("kubernetes_session.oneshot_job_active_deadline_seconds", "AGENTAPI_K8S_SESSION_ONESHOT_JOB_ACTIVE_DEADLINE_SECONDS")
+	_ = v.BindEnv("kubernetes_session.oneshot_job_ttl_seconds_after_finished", "AGENTAPI_K8S_SESSION_ONESHOT_JOB_TTL_SECONDS_AFTER_FINISHED")
+	_ = v.BindEnv("kubernetes_session.startup_templates_configmap", "AGENTAPI_K8S_SESSION_STARTUP_TEMPLATES_CONFIGMAP")
 	_ = v.BindEnv("kubernetes_session.claude_config_user_configmap_prefix", "AGENTAPI_K8S_SESSION_CLAUDE_CONFIG_USER_CONFIGMAP_PREFIX")
 	_ = v.BindEnv("kubernetes_session.init_container_image", "AGENTAPI_K8S_SESSION_INIT_CONTAINER_IMAGE")
 	_ = v.BindEnv("kubernetes_session.sandbox_init_image", "AGENTAPI_K8S_SESSION_SANDBOX_INIT_IMAGE")
@@ -1096,6 +2540,8 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("kubernetes_session.network_filter_init_memory_limit", "AGENTAPI_K8S_SESSION_NETWORK_FILTER_INIT_MEMORY_LIMIT")
 	_ = v.BindEnv("kubernetes_session.github_secret_name", "AGENTAPI_K8S_SESSION_GITHUB_SECRET_NAME")
 	_ = v.BindEnv("kubernetes_session.github_config_secret_name", "AGENTAPI_K8S_SESSION_GITHUB_CONFIG_SECRET_NAME")
+	_ = v.BindEnv("kubernetes_session.github_token_refresh_interval", "AGENTAPI_K8S_SESSION_GITHUB_TOKEN_REFRESH_INTERVAL")
+	_ = v.BindEnv("kubernetes_session.label_schema_migration_interval", "AGENTAPI_K8S_SESSION_LABEL_SCHEMA_MIGRATION_INTERVAL")
 	_ = v.BindEnv("kubernetes_session.config_file", "AGENTAPI_K8S_SESSION_CONFIG_FILE")
 	_ = v.BindEnv("kubernetes_session.session_pod_template_file", "AGENTAPI_K8S_SESSION_POD_TEMPLATE_FILE")
 	// MCP servers configuration
@@ -1127,6 +2573,19 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("schedule_worker.renew_deadline", "AGENTAPI_SCHEDULE_WORKER_RENEW_DEADLINE")
 	_ = v.BindEnv("schedule_worker.retry_period", "AGENTAPI_SCHEDULE_WORKER_RETRY_PERIOD")
 
+	// Schedule parser configuration
+	_ = v.BindEnv("schedule_parser.enabled", "AGENTAPI_SCHEDULE_PARSER_ENABLED")
+	_ = v.BindEnv("schedule_parser.base_url", "AGENTAPI_SCHEDULE_PARSER_BASE_URL")
+	_ = v.BindEnv("schedule_parser.api_key_env", "AGENTAPI_SCHEDULE_PARSER_API_KEY_ENV")
+	_ = v.BindEnv("schedule_parser.model", "AGENTAPI_SCHEDULE_PARSER_MODEL")
+	_ = v.BindEnv("schedule_parser.timeout", "AGENTAPI_SCHEDULE_PARSER_TIMEOUT")
+	_ = v.BindEnv("session_summary.enabled", "AGENTAPI_SESSION_SUMMARY_ENABLED")
+	_ = v.BindEnv("session_summary.base_url", "AGENTAPI_SESSION_SUMMARY_BASE_URL")
+	_ = v.BindEnv("session_summary.api_key_env", "AGENTAPI_SESSION_SUMMARY_API_KEY_ENV")
+	_ = v.BindEnv("session_summary.model", "AGENTAPI_SESSION_SUMMARY_MODEL")
+	_ = v.BindEnv("session_summary.timeout", "AGENTAPI_SESSION_SUMMARY_TIMEOUT")
+	_ = v.BindEnv("session_summary.interval", "AGENTAPI_SESSION_SUMMARY_INTERVAL")
+
 	// Slackbot cleanup worker configuration
 	_ = v.BindEnv("slackbot_cleanup_worker.enabled", "AGENTAPI_SLACKBOT_CLEANUP_WORKER_ENABLED")
 	_ = v.BindEnv("slackbot_cleanup_worker.check_interval", "AGENTAPI_SLACKBOT_CLEANUP_WORKER_CHECK_INTERVAL")
@@ -1137,6 +2596,36 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("slackbot_cleanup_worker.renew_deadline", "AGENTAPI_SLACKBOT_CLEANUP_WORKER_RENEW_DEADLINE")
 	_ = v.BindEnv("slackbot_cleanup_worker.retry_period", "AGENTAPI_SLACKBOT_CLEANUP_WORKER_RETRY_PERIOD")
 
+	// Idle reaper
+	_ = v.BindEnv("idle_reaper.enabled", "AGENTAPI_IDLE_REAPER_ENABLED")
+	_ = v.BindEnv("idle_reaper.check_interval", "AGENTAPI_IDLE_REAPER_CHECK_INTERVAL")
+	_ = v.BindEnv("idle_reaper.idle_ttl", "AGENTAPI_IDLE_REAPER_IDLE_TTL")
+	_ = v.BindEnv("idle_reaper.action", "AGENTAPI_IDLE_REAPER_ACTION")
+	_ = v.BindEnv("idle_reaper.dry_run", "AGENTAPI_IDLE_REAPER_DRY_RUN")
+	_ = v.BindEnv("idle_reaper.lease_duration", "AGENTAPI_IDLE_REAPER_LEASE_DURATION")
+	_ = v.BindEnv("idle_reaper.renew_deadline", "AGENTAPI_IDLE_REAPER_RENEW_DEADLINE")
+	_ = v.BindEnv("idle_reaper.retry_period", "AGENTAPI_IDLE_REAPER_RETRY_PERIOD")
+	_ = v.BindEnv("leader_election.enabled", "AGENTAPI_LEADER_ELECTION_ENABLED")
+	_ = v.BindEnv("leader_election.namespace", "AGENTAPI_LEADER_ELECTION_NAMESPACE")
+	_ = v.BindEnv("leader_election.lease_name", "AGENTAPI_LEADER_ELECTION_LEASE_NAME")
+	_ = v.BindEnv("leader_election.lease_duration", "AGENTAPI_LEADER_ELECTION_LEASE_DURATION")
+	_ = v.BindEnv("leader_election.renew_deadline", "AGENTAPI_LEADER_ELECTION_RENEW_DEADLINE")
+	_ = v.BindEnv("leader_election.retry_period", "AGENTAPI_LEADER_ELECTION_RETRY_PERIOD")
+	_ = v.BindEnv("shutdown.drain_delay", "AGENTAPI_SHUTDOWN_DRAIN_DELAY")
+	_ = v.BindEnv("shutdown.grace_period", "AGENTAPI_SHUTDOWN_GRACE_PERIOD")
+	_ = v.BindEnv("streaming.flush_interval", "AGENTAPI_STREAMING_FLUSH_INTERVAL")
+	_ = v.BindEnv("streaming.sse_idle_timeout", "AGENTAPI_STREAMING_SSE_IDLE_TIMEOUT")
+	_ = v.BindEnv("streaming.websocket_idle_timeout", "AGENTAPI_STREAMING_WEBSOCKET_IDLE_TIMEOUT")
+	_ = v.BindEnv("backend_proxy.max_retries", "AGENTAPI_BACKEND_PROXY_MAX_RETRIES")
+	_ = v.BindEnv("backend_proxy.retry_backoff", "AGENTAPI_BACKEND_PROXY_RETRY_BACKOFF")
+	_ = v.BindEnv("backend_proxy.retry_max_backoff", "AGENTAPI_BACKEND_PROXY_RETRY_MAX_BACKOFF")
+	_ = v.BindEnv("backend_proxy.circuit_failure_threshold", "AGENTAPI_BACKEND_PROXY_CIRCUIT_FAILURE_THRESHOLD")
+	_ = v.BindEnv("backend_proxy.circuit_open_duration", "AGENTAPI_BACKEND_PROXY_CIRCUIT_OPEN_DURATION")
+	_ = v.BindEnv("tracing.exporter", "AGENTAPI_TRACING_EXPORTER")
+	_ = v.BindEnv("tracing.endpoint", "AGENTAPI_TRACING_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	_ = v.BindEnv("tracing.service_name", "AGENTAPI_TRACING_SERVICE_NAME", "OTEL_SERVICE_NAME")
+	_ = v.BindEnv("tracing.sample_ratio", "AGENTAPI_TRACING_SAMPLE_RATIO")
+
 	// Stock inventory worker configuration
 	_ = v.BindEnv("stock_inventory_worker.enabled", "AGENTAPI_STOCK_INVENTORY_WORKER_ENABLED")
 	_ = v.BindEnv("stock_inventory_worker.check_interval", "AGENTAPI_STOCK_INVENTORY_WORKER_CHECK_INTERVAL")
@@ -1158,6 +2647,10 @@ func bindEnvVars(v *viper.Viper) {
 	// Webhook configuration
 	_ = v.BindEnv("webhook.base_url", "AGENTAPI_WEBHOOK_BASE_URL")
 	_ = v.BindEnv("webhook.github_enterprise_host", "AGENTAPI_WEBHOOK_GITHUB_ENTERPRISE_HOST")
+	_ = v.BindEnv("webhook.github.enabled", "AGENTAPI_WEBHOOK_GITHUB_ENABLED")
+	_ = v.BindEnv("webhook.github.secret", "AGENTAPI_WEBHOOK_GITHUB_SECRET")
+	_ = v.BindEnv("webhook.github.user_id", "AGENTAPI_WEBHOOK_GITHUB_USER_ID")
+	_ = v.BindEnv("webhook.github.team_id", "AGENTAPI_WEBHOOK_GITHUB_TEAM_ID")
 
 	// Slack configuration
 	_ = v.BindEnv("slack.signing_secret", "AGENTAPI_SLACK_SIGNING_SECRET")
@@ -1178,6 +2671,14 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("memory.s3.region", "AGENTAPI_MEMORY_S3_REGION")
 	_ = v.BindEnv("memory.s3.prefix", "AGENTAPI_MEMORY_S3_PREFIX")
 	_ = v.BindEnv("memory.s3.endpoint", "AGENTAPI_MEMORY_S3_ENDPOINT")
+	_ = v.BindEnv("memory.migration.target_backend", "AGENTAPI_MEMORY_MIGRATION_TARGET_BACKEND")
+
+	// Session store configuration
+	_ = v.BindEnv("session_store.backend", "AGENTAPI_SESSION_STORE_BACKEND")
+	_ = v.BindEnv("session_store.dsn", "AGENTAPI_SESSION_STORE_DSN")
+	_ = v.BindEnv("session_store.read_replica_dsn", "AGENTAPI_SESSION_STORE_READ_REPLICA_DSN")
+	_ = v.BindEnv("session_store.migration.target_backend", "AGENTAPI_SESSION_STORE_MIGRATION_TARGET_BACKEND")
+	_ = v.BindEnv("session_store.migration.target_dsn", "AGENTAPI_SESSION_STORE_MIGRATION_TARGET_DSN")
 
 	// Asset backend configuration
 	_ = v.BindEnv("asset.backend", "AGENTAPI_ASSET_BACKEND")
@@ -1201,6 +2702,56 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("redis.read_timeout", "AGENTAPI_REDIS_READ_TIMEOUT")
 	_ = v.BindEnv("redis.write_timeout", "AGENTAPI_REDIS_WRITE_TIMEOUT")
 
+	// Rate limit configuration
+	_ = v.BindEnv("rbac.enabled", "AGENTAPI_RBAC_ENABLED")
+	_ = v.BindEnv("audit.retention_days", "AGENTAPI_AUDIT_RETENTION_DAYS")
+	_ = v.BindEnv("rate_limit.enabled", "AGENTAPI_RATE_LIMIT_ENABLED")
+	_ = v.BindEnv("rate_limit.backend", "AGENTAPI_RATE_LIMIT_BACKEND")
+	_ = v.BindEnv("rate_limit.user.rps", "AGENTAPI_RATE_LIMIT_USER_RPS")
+	_ = v.BindEnv("rate_limit.user.burst", "AGENTAPI_RATE_LIMIT_USER_BURST")
+	_ = v.BindEnv("rate_limit.api_key.rps", "AGENTAPI_RATE_LIMIT_API_KEY_RPS")
+	_ = v.BindEnv("rate_limit.api_key.burst", "AGENTAPI_RATE_LIMIT_API_KEY_BURST")
+	_ = v.BindEnv("rate_limit.team.rps", "AGENTAPI_RATE_LIMIT_TEAM_RPS")
+	_ = v.BindEnv("rate_limit.team.burst", "AGENTAPI_RATE_LIMIT_TEAM_BURST")
+	_ = v.BindEnv("delivery.enabled", "AGENTAPI_DELIVERY_ENABLED")
+	_ = v.BindEnv("delivery.backend", "AGENTAPI_DELIVERY_BACKEND")
+	_ = v.BindEnv("delivery.dir", "AGENTAPI_DELIVERY_DIR")
+	_ = v.BindEnv("delivery.max_attempts", "AGENTAPI_DELIVERY_MAX_ATTEMPTS")
+	_ = v.BindEnv("outbound_webhooks.enabled", "AGENTAPI_OUTBOUND_WEBHOOKS_ENABLED")
+	_ = v.BindEnv("outbound_webhooks.dir", "AGENTAPI_OUTBOUND_WEBHOOKS_DIR")
+	_ = v.BindEnv("outbound_webhooks.allow_private_targets", "AGENTAPI_OUTBOUND_WEBHOOKS_ALLOW_PRIVATE_TARGETS")
+	_ = v.BindEnv("session_notifications.enabled", "AGENTAPI_SESSION_NOTIFICATIONS_ENABLED")
+	_ = v.BindEnv("event_bus.enabled", "AGENTAPI_EVENT_BUS_ENABLED")
+	_ = v.BindEnv("event_bus.type", "AGENTAPI_EVENT_BUS_TYPE")
+	_ = v.BindEnv("event_bus.url", "AGENTAPI_EVENT_BUS_URL")
+	_ = v.BindEnv("event_bus.topic", "AGENTAPI_EVENT_BUS_TOPIC")
+	_ = v.BindEnv("event_bus.token", "AGENTAPI_EVENT_BUS_TOKEN")
+	_ = v.BindEnv("event_bus.source", "AGENTAPI_EVENT_BUS_SOURCE")
+	_ = v.BindEnv("event_bus.buffer_size", "AGENTAPI_EVENT_BUS_BUFFER_SIZE")
+	_ = v.BindEnv("completion_callbacks.secret", "AGENTAPI_COMPLETION_CALLBACKS_SECRET")
+	_ = v.BindEnv("completion_callbacks.allow_private_targets", "AGENTAPI_COMPLETION_CALLBACKS_ALLOW_PRIVATE_TARGETS")
+	_ = v.BindEnv("capacity_forecast.enabled", "AGENTAPI_CAPACITY_FORECAST_ENABLED")
+	_ = v.BindEnv("capacity_forecast.dir", "AGENTAPI_CAPACITY_FORECAST_DIR")
+	_ = v.BindEnv("capacity_forecast.node_cpu", "AGENTAPI_CAPACITY_FORECAST_NODE_CPU")
+	_ = v.BindEnv("capacity_forecast.node_memory", "AGENTAPI_CAPACITY_FORECAST_NODE_MEMORY")
+	_ = v.BindEnv("usage.enabled", "AGENTAPI_USAGE_ENABLED")
+	_ = v.BindEnv("usage.collect_interval", "AGENTAPI_USAGE_COLLECT_INTERVAL")
+	_ = v.BindEnv("showback.enabled", "AGENTAPI_SHOWBACK_ENABLED")
+	_ = v.BindEnv("showback.dir", "AGENTAPI_SHOWBACK_DIR")
+	_ = v.BindEnv("showback.digest_slack_channel", "AGENTAPI_SHOWBACK_DIGEST_SLACK_CHANNEL")
+	_ = v.BindEnv("metering.enabled", "AGENTAPI_METERING_ENABLED")
+	_ = v.BindEnv("metering.provider", "AGENTAPI_METERING_PROVIDER")
+	_ = v.BindEnv("metering.webhook.url", "AGENTAPI_METERING_WEBHOOK_URL")
+	_ = v.BindEnv("metering.webhook.secret", "AGENTAPI_METERING_WEBHOOK_SECRET")
+	_ = v.BindEnv("transcript_archive.enabled", "AGENTAPI_TRANSCRIPT_ARCHIVE_ENABLED")
+	_ = v.BindEnv("session_archive.enabled", "AGENTAPI_SESSION_ARCHIVE_ENABLED")
+	_ = v.BindEnv("session_archive.provider", "AGENTAPI_SESSION_ARCHIVE_PROVIDER")
+	_ = v.BindEnv("session_archive.bucket", "AGENTAPI_SESSION_ARCHIVE_BUCKET")
+	_ = v.BindEnv("session_archive.region", "AGENTAPI_SESSION_ARCHIVE_REGION")
+	_ = v.BindEnv("session_archive.prefix", "AGENTAPI_SESSION_ARCHIVE_PREFIX")
+	_ = v.BindEnv("session_archive.endpoint", "AGENTAPI_SESSION_ARCHIVE_ENDPOINT")
+	_ = v.BindEnv("session_archive.retention_days", "AGENTAPI_SESSION_ARCHIVE_RETENTION_DAYS")
+
 	// GitHub sync proxy configuration
 	_ = v.BindEnv("git_sync.sync_interval", "AGENTAPI_GIT_SYNC_SYNC_INTERVAL")
 	_ = v.BindEnv("git_sync.encryption.kms_key_arn", "AGENTAPI_GIT_SYNC_ENCRYPTION_KMS_KEY_ARN")
@@ -1211,6 +2762,42 @@ func bindEnvVars(v *viper.Viper) {
 	_ = v.BindEnv("git_sync.renew_deadline", "AGENTAPI_GIT_SYNC_RENEW_DEADLINE")
 	_ = v.BindEnv("git_sync.retry_period", "AGENTAPI_GIT_SYNC_RETRY_PERIOD")
 
+	// LLM egress proxy configuration
+	_ = v.BindEnv("llm_proxy.enabled", "AGENTAPI_LLM_PROXY_ENABLED")
+	_ = v.BindEnv("llm_proxy.public_url", "AGENTAPI_LLM_PROXY_PUBLIC_URL")
+	_ = v.BindEnv("llm_proxy.log_bodies", "AGENTAPI_LLM_PROXY_LOG_BODIES")
+	_ = v.BindEnv("llm_proxy.max_logged_body_bytes", "AGENTAPI_LLM_PROXY_MAX_LOGGED_BODY_BYTES")
+
+	// Corporate egress proxy configuration
+	_ = v.BindEnv("egress_proxy.http_proxy", "AGENTAPI_EGRESS_HTTP_PROXY")
+	_ = v.BindEnv("egress_proxy.https_proxy", "AGENTAPI_EGRESS_HTTPS_PROXY")
+	_ = v.BindEnv("egress_proxy.all_proxy", "AGENTAPI_EGRESS_ALL_PROXY")
+	_ = v.BindEnv("egress_proxy.no_proxy", "AGENTAPI_EGRESS_NO_PROXY")
+	_ = v.BindEnv("egress_proxy.ca_bundle_config_map", "AGENTAPI_EGRESS_CA_BUNDLE_CONFIG_MAP")
+	_ = v.BindEnv("egress_proxy.ca_bundle_key", "AGENTAPI_EGRESS_CA_BUNDLE_KEY")
+	_ = v.BindEnv("egress_proxy.ca_bundle_file", "AGENTAPI_EGRESS_CA_BUNDLE_FILE")
+	_ = v.BindEnv("egress_proxy.apply_to_proxy", "AGENTAPI_EGRESS_APPLY_TO_PROXY")
+	_ = v.BindEnv("github_enterprise.url", "AGENTAPI_GITHUB_ENTERPRISE_URL")
+	_ = v.BindEnv("github_enterprise.api_url", "AGENTAPI_GITHUB_ENTERPRISE_API_URL")
+	_ = v.BindEnv("github_reporting.enabled", "AGENTAPI_GITHUB_REPORTING_ENABLED")
+	_ = v.BindEnv("github_reporting.branch_prefix", "AGENTAPI_GITHUB_REPORTING_BRANCH_PREFIX")
+	_ = v.BindEnv("github_reporting.check_name", "AGENTAPI_GITHUB_REPORTING_CHECK_NAME")
+	_ = v.BindEnv("github_reporting.details_url", "AGENTAPI_GITHUB_REPORTING_DETAILS_URL")
+
+	// Air-gapped mode configuration
+	_ = v.BindEnv("air_gap.enabled", "AGENTAPI_AIR_GAP_ENABLED")
+	_ = v.BindEnv("air_gap.strict", "AGENTAPI_AIR_GAP_STRICT")
+	_ = v.BindEnv("air_gap.image_registry", "AGENTAPI_AIR_GAP_IMAGE_REGISTRY")
+	_ = v.BindEnv("air_gap.npm_registry", "AGENTAPI_AIR_GAP_NPM_REGISTRY")
+	_ = v.BindEnv("air_gap.pypi_index_url", "AGENTAPI_AIR_GAP_PYPI_INDEX_URL")
+	_ = v.BindEnv("air_gap.goproxy", "AGENTAPI_AIR_GAP_GOPROXY")
+	_ = v.BindEnv("air_gap.gosumdb", "AGENTAPI_AIR_GAP_GOSUMDB")
+	_ = v.BindEnv("air_gap.swagger_ui_url", "AGENTAPI_AIR_GAP_SWAGGER_UI_URL")
+
+	// Config hot reload configuration
+	_ = v.BindEnv("config_reload.watch", "AGENTAPI_CONFIG_RELOAD_WATCH")
+	_ = v.BindEnv("config_reload.debounce", "AGENTAPI_CONFIG_RELOAD_DEBOUNCE")
+
 }
 
 // setDefaults sets default values for viper configuration
@@ -1225,6 +2812,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("auth.github.oauth.client_secret", "")
 	v.SetDefault("auth.github.oauth.scope", "read:user read:org project")
 	v.SetDefault("auth.github.oauth.base_url", "")
+	v.SetDefault("auth.github.team_sync.interval", "10m")
 
 	// AWS auth defaults
 	v.SetDefault("auth.aws.enabled", false)
@@ -1253,7 +2841,52 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("kubernetes_session.pvc_storage_size", "10Gi")
 	v.SetDefault("kubernetes_session.pod_start_timeout", 120)
 	v.SetDefault("kubernetes_session.pod_stop_timeout", 30)
+	v.SetDefault("kubernetes_session.auto_resume", false)
+	v.SetDefault("kubernetes_session.require_capability_approval", false)
+	v.SetDefault("kubernetes_session.docker_image_cache_on_pvc", false)
+	v.SetDefault("kubernetes_session.preview_deploy_hook_url", "")
+	v.SetDefault("kubernetes_session.preview_deploy_hook_secret", "")
+	v.SetDefault("kubernetes_session.namespace_placement", "single")
+	v.SetDefault("kubernetes_session.team_namespace_prefix", "agentapi-team-")
+	v.SetDefault("kubernetes_session.team_namespace_network_policy", true)
+	v.SetDefault("kubernetes_session.runtime_class_name", "")
+	v.SetDefault("kubernetes_session.session_capacity", 0)
+	v.SetDefault("kubernetes_session.preemption.enabled", false)
+	v.SetDefault("kubernetes_session.preemption.min_idle", "5m")
+	v.SetDefault("kubernetes_session.health_probe.enabled", false)
+	v.SetDefault("kubernetes_session.health_probe.interval", "30s")
+	v.SetDefault("kubernetes_session.health_probe.timeout", "5s")
+	v.SetDefault("kubernetes_session.health_probe.failure_threshold", 3)
+	v.SetDefault("kubernetes_session.health_probe.restart_threshold", 0)
+	v.SetDefault("kubernetes_session.image_rollout.min_idle", "10m")
+	v.SetDefault("kubernetes_session.image_rollout.max_concurrent", 1)
+	v.SetDefault("kubernetes_session.image_rollout.interval", "30s")
+	v.SetDefault("kubernetes_session.image_rollout.upgrade_timeout", "10m")
+	v.SetDefault("kubernetes_session.snapshots.enabled", false)
+	v.SetDefault("kubernetes_session.snapshots.volume_snapshot_class", "")
+	v.SetDefault("kubernetes_session.snapshots.schedule", "")
+	v.SetDefault("kubernetes_session.snapshots.keep", 3)
+	v.SetDefault("kubernetes_session.snapshots.retain_after_delete", "168h")
+	v.SetDefault("kubernetes_session.disruption_budget", "")
+	v.SetDefault("kubernetes_session.disruption_budget_max_unavailable", 0)
+	v.SetDefault("kubernetes_session.prestop_checkpoint", false)
+	v.SetDefault("kubernetes_session.recreate_evicted_sessions", false)
+	v.SetDefault("kubernetes_session.preview_namespace_prefix", "agentapi-preview-")
+	v.SetDefault("kubernetes_session.preview_deploy_timeout", "10m")
+	v.SetDefault("kubernetes_session.exec_enabled", true)
+	v.SetDefault("kubernetes_session.runtime_cache_enabled", false)
+	v.SetDefault("kubernetes_session.runtime_cache_storage_class", "")
+	v.SetDefault("kubernetes_session.runtime_cache_storage_size", "20Gi")
+	v.SetDefault("kubernetes_session.terminal_recording", true)
 	v.SetDefault("kubernetes_session.provisioner_proxy_url", "")
+	v.SetDefault("kubernetes_session.cluster_domain", "cluster.local")
+	v.SetDefault("kubernetes_session.routing", "service")
+	v.SetDefault("kubernetes_session.max_session_replicas", 1)
+	v.SetDefault("kubernetes_session.oneshot_job_enabled", false)
+	v.SetDefault("kubernetes_session.oneshot_job_backoff_limit", 0)
+	v.SetDefault("kubernetes_session.oneshot_job_active_deadline_seconds", 21600)
+	v.SetDefault("kubernetes_session.oneshot_job_ttl_seconds_after_finished", 600)
+	v.SetDefault("kubernetes_session.startup_templates_configmap", "")
 	v.SetDefault("kubernetes_session.claude_config_user_configmap_prefix", "claude-config")
 	v.SetDefault("kubernetes_session.init_container_image", "")
 	v.SetDefault("kubernetes_session.sandbox_init_image", "gcr.io/istio-release/iptables@sha256:88626c33372697bd006bbfc61d1e0d7b60ae9a988d1a7cac07cc834b13e5c21a")
@@ -1268,6 +2901,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("kubernetes_session.network_filter_init_memory_request", "32Mi")
 	v.SetDefault("kubernetes_session.network_filter_init_memory_limit", "64Mi")
 	v.SetDefault("kubernetes_session.github_secret_name", "")
+	v.SetDefault("kubernetes_session.github_token_refresh_interval", "5m")
+	v.SetDefault("kubernetes_session.label_schema_migration_interval", "1h")
 
 	// Settings base secret default (single base Secret shared by all sessions,
 	// merged with team/user settings at session settings generation time)
@@ -1281,6 +2916,88 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("schedule_worker.renew_deadline", "10s")
 	v.SetDefault("schedule_worker.retry_period", "2s")
 
+	// Rate limit defaults
+	v.SetDefault("rbac.enabled", false)
+	v.SetDefault("audit.retention_days", 90)
+	v.SetDefault("rate_limit.enabled", false)
+	v.SetDefault("rate_limit.backend", "memory")
+
+	// Delivery queue defaults
+	v.SetDefault("delivery.enabled", false)
+	v.SetDefault("delivery.backend", "file")
+	v.SetDefault("delivery.dir", "")
+	v.SetDefault("delivery.max_attempts", 8)
+	v.SetDefault("delivery.base_backoff", "30s")
+	v.SetDefault("delivery.max_backoff", "1h")
+	v.SetDefault("delivery.poll_interval", "10s")
+
+	// Outbound webhook defaults
+	v.SetDefault("outbound_webhooks.enabled", false)
+	v.SetDefault("outbound_webhooks.dir", "")
+	v.SetDefault("outbound_webhooks.allow_private_targets", false)
+	v.SetDefault("session_notifications.enabled", false)
+	v.SetDefault("event_bus.enabled", false)
+	v.SetDefault("event_bus.type", "")
+	v.SetDefault("event_bus.url", "")
+	v.SetDefault("event_bus.topic", "agentapi.events")
+	v.SetDefault("event_bus.token", "")
+	v.SetDefault("event_bus.source", "agentapi-proxy")
+	v.SetDefault("event_bus.buffer_size", 1000)
+
+	// Completion callback defaults
+	v.SetDefault("completion_callbacks.secret", "")
+	v.SetDefault("completion_callbacks.allow_private_targets", false)
+
+	// Capacity forecast defaults
+	v.SetDefault("capacity_forecast.enabled", false)
+	v.SetDefault("capacity_forecast.dir", "")
+	v.SetDefault("capacity_forecast.sample_interval", "5m")
+	v.SetDefault("capacity_forecast.retention_days", 56)
+	v.SetDefault("capacity_forecast.node_cpu", "")
+	v.SetDefault("capacity_forecast.node_memory", "")
+	v.SetDefault("capacity_forecast.headroom", 0.2)
+
+	// Showback defaults
+	v.SetDefault("usage.enabled", false)
+	v.SetDefault("usage.collect_interval", "1m")
+	v.SetDefault("showback.enabled", false)
+	v.SetDefault("showback.dir", "")
+	v.SetDefault("showback.sample_interval", "5m")
+	v.SetDefault("showback.rates.currency", "USD")
+	v.SetDefault("showback.digest_slack_channel", "")
+
+	// Metering export defaults
+	v.SetDefault("metering.enabled", false)
+	v.SetDefault("metering.provider", "stripe")
+	v.SetDefault("metering.interval", "1h")
+	v.SetDefault("metering.dir", "")
+	v.SetDefault("metering.stripe.api_key_env", "STRIPE_API_KEY")
+	v.SetDefault("metering.webhook.url", "")
+	v.SetDefault("metering.webhook.secret", "")
+
+	// Transcript archive defaults
+	v.SetDefault("transcript_archive.enabled", false)
+
+	// Session archive defaults
+	v.SetDefault("session_archive.enabled", false)
+	v.SetDefault("session_archive.provider", "s3")
+	v.SetDefault("session_archive.bucket", "")
+	v.SetDefault("session_archive.prefix", "")
+	v.SetDefault("session_archive.retention_days", 0)
+
+	// Schedule parser defaults
+	v.SetDefault("schedule_parser.enabled", false)
+	v.SetDefault("schedule_parser.base_url", "https://api.anthropic.com")
+	v.SetDefault("schedule_parser.api_key_env", "ANTHROPIC_API_KEY")
+	v.SetDefault("schedule_parser.model", "claude-sonnet-4-5")
+	v.SetDefault("schedule_parser.timeout", "30s")
+	v.SetDefault("session_summary.enabled", false)
+	v.SetDefault("session_summary.base_url", "https://api.anthropic.com")
+	v.SetDefault("session_summary.api_key_env", "ANTHROPIC_API_KEY")
+	v.SetDefault("session_summary.model", "claude-haiku-4-5")
+	v.SetDefault("session_summary.timeout", "30s")
+	v.SetDefault("session_summary.interval", "10m")
+
 	// Slackbot cleanup worker defaults
 	v.SetDefault("slackbot_cleanup_worker.enabled", false)
 	v.SetDefault("slackbot_cleanup_worker.check_interval", "1h")
@@ -1291,6 +3008,35 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("slackbot_cleanup_worker.renew_deadline", "10s")
 	v.SetDefault("slackbot_cleanup_worker.retry_period", "2s")
 
+	// Idle reaper defaults
+	v.SetDefault("idle_reaper.enabled", false)
+	v.SetDefault("idle_reaper.check_interval", "5m")
+	v.SetDefault("idle_reaper.idle_ttl", "24h")
+	v.SetDefault("idle_reaper.action", "delete")
+	v.SetDefault("idle_reaper.dry_run", false)
+	v.SetDefault("idle_reaper.lease_duration", "15s")
+	v.SetDefault("idle_reaper.renew_deadline", "10s")
+	v.SetDefault("idle_reaper.retry_period", "2s")
+	v.SetDefault("leader_election.enabled", true)
+	v.SetDefault("leader_election.namespace", "")
+	v.SetDefault("leader_election.lease_name", "agentapi-proxy-leader")
+	v.SetDefault("leader_election.lease_duration", "15s")
+	v.SetDefault("leader_election.renew_deadline", "10s")
+	v.SetDefault("leader_election.retry_period", "2s")
+	v.SetDefault("shutdown.drain_delay", "5s")
+	v.SetDefault("shutdown.grace_period", "20s")
+	v.SetDefault("streaming.flush_interval", "100ms")
+	v.SetDefault("streaming.sse_idle_timeout", "30m")
+	v.SetDefault("streaming.websocket_idle_timeout", "30m")
+	v.SetDefault("backend_proxy.max_retries", 2)
+	v.SetDefault("backend_proxy.retry_backoff", "200ms")
+	v.SetDefault("backend_proxy.retry_max_backoff", "2s")
+	v.SetDefault("backend_proxy.circuit_failure_threshold", 5)
+	v.SetDefault("backend_proxy.circuit_open_duration", "10s")
+	v.SetDefault("tracing.exporter", "none")
+	v.SetDefault("tracing.service_name", "agentapi-proxy")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+
 	// Stock inventory worker defaults
 	v.SetDefault("stock_inventory_worker.enabled", false)
 	v.SetDefault("stock_inventory_worker.check_interval", "30s")
@@ -1311,6 +3057,10 @@ func setDefaults(v *viper.Viper) {
 	// Webhook defaults
 	v.SetDefault("webhook.base_url", "")
 	v.SetDefault("webhook.github_enterprise_host", "")
+	v.SetDefault("webhook.github.enabled", false)
+	v.SetDefault("webhook.github.secret", "")
+	v.SetDefault("webhook.github.user_id", "")
+	v.SetDefault("webhook.github.team_id", "")
 
 	// scia defaults
 	v.SetDefault("scia.enabled", false)
@@ -1337,6 +3087,14 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("memory.s3.endpoint", "")
 	v.SetDefault("memory.external.url", "")
 	v.SetDefault("memory.external.admin_token", "")
+	v.SetDefault("memory.migration.target_backend", "")
+
+	// Session store defaults
+	v.SetDefault("session_store.backend", "kubernetes")
+	v.SetDefault("session_store.dsn", "")
+	v.SetDefault("session_store.read_replica_dsn", "")
+	v.SetDefault("session_store.migration.target_backend", "")
+	v.SetDefault("session_store.migration.target_dsn", "")
 
 	// Asset backend defaults
 	v.SetDefault("asset.backend", "nginx")
@@ -1354,6 +3112,28 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("git_sync.renew_deadline", "10s")
 	v.SetDefault("git_sync.retry_period", "2s")
 
+	// LLM egress proxy defaults
+	v.SetDefault("llm_proxy.enabled", false)
+	v.SetDefault("llm_proxy.log_bodies", false)
+	v.SetDefault("llm_proxy.max_logged_body_bytes", 4096)
+
+	// Corporate egress proxy defaults
+	v.SetDefault("egress_proxy.ca_bundle_key", "ca-bundle.crt")
+	v.SetDefault("egress_proxy.apply_to_proxy", true)
+
+	// GitHub reporting defaults
+	v.SetDefault("github_reporting.enabled", false)
+	v.SetDefault("github_reporting.branch_prefix", "agentapi/")
+	v.SetDefault("github_reporting.check_name", "agentapi")
+
+	// Air-gapped mode defaults
+	v.SetDefault("air_gap.enabled", false)
+	v.SetDefault("air_gap.strict", false)
+
+	// Config hot reload defaults
+	v.SetDefault("config_reload.watch", false)
+	v.SetDefault("config_reload.debounce", "2s")
+
 	// Redis defaults (empty addr = disabled)
 	v.SetDefault("redis.addr", "")
 	v.SetDefault("redis.password", "")
@@ -1402,6 +3182,31 @@ func applyConfigDefaults(config *Config) {
 	if config.Asset.S3 != nil && config.Asset.S3.Prefix == "" {
 		config.Asset.S3.Prefix = "agentapi-assets/"
 	}
+	if config.EgressProxy.CABundleKey == "" {
+		config.EgressProxy.CABundleKey = "ca-bundle.crt"
+	}
+	if config.LLMProxy.MaxLoggedBodyBytes <= 0 {
+		config.LLMProxy.MaxLoggedBodyBytes = 4096
+	}
+	for i := range config.LLMProxy.Providers {
+		p := &config.LLMProxy.Providers[i]
+		if p.BaseURL == "" {
+			switch p.Type {
+			case "anthropic":
+				p.BaseURL = "https://api.anthropic.com"
+			case "openai":
+				p.BaseURL = "https://api.openai.com"
+			}
+		}
+		if p.APIKeyEnv == "" {
+			switch p.Type {
+			case "anthropic":
+				p.APIKeyEnv = "ANTHROPIC_API_KEY"
+			case "openai":
+				p.APIKeyEnv = "OPENAI_API_KEY"
+			}
+		}
+	}
 	if config.Scia.NoProxy == "" {
 		config.Scia.NoProxy = "localhost,127.0.0.1,.svc,.cluster.local"
 	}
@@ -1477,6 +3282,10 @@ func postProcessConfig(config *Config) error {
 		}
 	}
 
+	// Rewrite images to internal mirrors last so that values from every
+	// source (file, env, external k8s session config) are covered.
+	applyAirGap(config)
+
 	return nil
 }
 
@@ -1550,6 +3359,17 @@ func DefaultConfig() *Config {
 			RenewDeadline:  "10s",
 			RetryPeriod:    "2s",
 		},
+		LeaderElection: LeaderElectionConfig{
+			Enabled:       true,
+			LeaseName:     "agentapi-proxy-leader",
+			LeaseDuration: "15s",
+			RenewDeadline: "10s",
+			RetryPeriod:   "2s",
+		},
+		Shutdown: ShutdownConfig{
+			DrainDelay:  "5s",
+			GracePeriod: "20s",
+		},
 		Asset: AssetConfig{
 			Backend:     "nginx",
 			StoragePath: "/var/lib/agentapi-assets",
@@ -1714,6 +3534,18 @@ type K8sSessionConfigOverride struct {
 		NodeSelector          map[string]string `json:"node_selector,omitempty" yaml:"node_selector"`
 		Affinity     map[string]interface{} `json:"affinity,omitempty" yaml:"affinity"`
 		Tolerations           []Toleration      `json:"tolerations,omitempty" yaml:"tolerations"`
+		AgentImages           map[string]string      `json:"agent_images,omitempty" yaml:"agent_images"`
+		TeamImages            []TeamImage            `json:"team_images,omitempty" yaml:"team_images"`
+		AllowedImages         []string               `json:"allowed_images,omitempty" yaml:"allowed_images"`
+		AllowedRequestMCPServers []string            `json:"allowed_request_mcp_servers,omitempty" yaml:"allowed_request_mcp_servers"`
+		ExtendedResources     map[string]string      `json:"extended_resources,omitempty" yaml:"extended_resources"`
+		AcceleratorAllowances []AcceleratorAllowance `json:"accelerator_allowances,omitempty" yaml:"accelerator_allowances"`
+		Reservations          []SessionReservation   `json:"reservations,omitempty" yaml:"reservations"`
+		Preemption            *SessionPreemptionConfig `json:"preemption,omitempty" yaml:"preemption"`
+		TeamNamespaces                   map[string]string `json:"team_namespaces,omitempty" yaml:"team_namespaces"`
+		TeamNamespaceQuota               map[string]string `json:"team_namespace_quota,omitempty" yaml:"team_namespace_quota"`
+		TeamNamespaceLimitDefault        map[string]string `json:"team_namespace_limit_default,omitempty" yaml:"team_namespace_limit_default"`
+		TeamNamespaceLimitDefaultRequest map[string]string `json:"team_namespace_limit_default_request,omitempty" yaml:"team_namespace_limit_default_request"`
 	} `json:"kubernetes_session,omitempty" yaml:"kubernetes_session"`
 }
 
@@ -1758,6 +3590,51 @@ func loadK8sSessionConfigFromFile(config *Config, filename string) error {
 			config.KubernetesSession.Tolerations = k8sOverride.KubernetesSession.Tolerations
 			log.Printf("[CONFIG] Applied kubernetes session tolerations: %+v", config.KubernetesSession.Tolerations)
 		}
+		if k8sOverride.KubernetesSession.AgentImages != nil {
+			config.KubernetesSession.AgentImages = k8sOverride.KubernetesSession.AgentImages
+			log.Printf("[CONFIG] Applied kubernetes session agent_images: %v", config.KubernetesSession.AgentImages)
+		}
+		if k8sOverride.KubernetesSession.TeamImages != nil {
+			config.KubernetesSession.TeamImages = k8sOverride.KubernetesSession.TeamImages
+			log.Printf("[CONFIG] Applied %d kubernetes session team images", len(config.KubernetesSession.TeamImages))
+		}
+		if k8sOverride.KubernetesSession.AllowedImages != nil {
+			config.KubernetesSession.AllowedImages = k8sOverride.KubernetesSession.AllowedImages
+			log.Printf("[CONFIG] Applied kubernetes session allowed_images: %v", config.KubernetesSession.AllowedImages)
+		}
+		if k8sOverride.KubernetesSession.AllowedRequestMCPServers != nil {
+			config.KubernetesSession.AllowedRequestMCPServers = k8sOverride.KubernetesSession.AllowedRequestMCPServers
+			log.Printf("[CONFIG] Applied kubernetes session allowed_request_mcp_servers: %v", config.KubernetesSession.AllowedRequestMCPServers)
+		}
+		if k8sOverride.KubernetesSession.ExtendedResources != nil {
+			config.KubernetesSession.ExtendedResources = k8sOverride.KubernetesSession.ExtendedResources
+			log.Printf("[CONFIG] Applied kubernetes session extended_resources: %v", config.KubernetesSession.ExtendedResources)
+		}
+		if k8sOverride.KubernetesSession.AcceleratorAllowances != nil {
+			config.KubernetesSession.AcceleratorAllowances = k8sOverride.KubernetesSession.AcceleratorAllowances
+			log.Printf("[CONFIG] Applied %d kubernetes session accelerator allowances", len(config.KubernetesSession.AcceleratorAllowances))
+		}
+		if k8sOverride.KubernetesSession.Reservations != nil {
+			config.KubernetesSession.Reservations = k8sOverride.KubernetesSession.Reservations
+			log.Printf("[CONFIG] Applied %d kubernetes session reservations", len(config.KubernetesSession.Reservations))
+		}
+		if k8sOverride.KubernetesSession.Preemption != nil {
+			config.KubernetesSession.Preemption = *k8sOverride.KubernetesSession.Preemption
+			log.Printf("[CONFIG] Applied kubernetes session preemption (enabled: %t, %d priority rules)",
+				config.KubernetesSession.Preemption.Enabled, len(config.KubernetesSession.Preemption.Priorities))
+		}
+		if k8sOverride.KubernetesSession.TeamNamespaces != nil {
+			config.KubernetesSession.TeamNamespaces = k8sOverride.KubernetesSession.TeamNamespaces
+		}
+		if k8sOverride.KubernetesSession.TeamNamespaceQuota != nil {
+			config.KubernetesSession.TeamNamespaceQuota = k8sOverride.KubernetesSession.TeamNamespaceQuota
+		}
+		if k8sOverride.KubernetesSession.TeamNamespaceLimitDefault != nil {
+			config.KubernetesSession.TeamNamespaceLimitDefault = k8sOverride.KubernetesSession.TeamNamespaceLimitDefault
+		}
+		if k8sOverride.KubernetesSession.TeamNamespaceLimitDefaultRequest != nil {
+			config.KubernetesSession.TeamNamespaceLimitDefaultRequest = k8sOverride.KubernetesSession.TeamNamespaceLimitDefaultRequest
+		}
 	}
 
 	return nil
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/takutakahashi/agentapi-proxy/pkg/proxy"
 )
 
 // clearAGENTAPIEnvVars clears all AGENTAPI_ environment variables and returns a cleanup function
@@ -743,3 +744,130 @@ func TestInitializeConfigStructsFromEnv_AllSettingsFromEnvironment(t *testing.T)
 	}
 
 }
+
+func TestApplyAirGap_RewritesImages(t *testing.T) {
+	config := &Config{
+		KubernetesSession: KubernetesSessionConfig{
+			Image:              "ghcr.io/takutakahashi/agentapi-proxy:latest",
+			NetworkFilterImage: "ghcr.io/takutakahashi/nfa:0.12.1",
+		},
+		Scia: SciaConfig{SessionSidecarConfigImage: "busybox:1.36"},
+		AirGap: AirGapConfig{
+			Enabled:         true,
+			ImageRegistry:   "registry.corp/hub",
+			RegistryMirrors: []AirGapRegistryMirror{{Registry: "ghcr.io", Target: "registry.corp/ghcr"}},
+		},
+	}
+
+	applyAirGap(config)
+
+	assert.Equal(t, "registry.corp/ghcr/takutakahashi/agentapi-proxy:latest", config.KubernetesSession.Image)
+	assert.Equal(t, "registry.corp/ghcr/takutakahashi/nfa:0.12.1", config.KubernetesSession.NetworkFilterImage)
+	assert.Equal(t, "registry.corp/hub/library/docker:dind", config.KubernetesSession.DinDImage)
+	assert.Equal(t, "registry.corp/hub/codercom/code-server:4.96.4", config.KubernetesSession.EditorImage)
+	assert.Equal(t, "registry.corp/hub/selenium/standalone-chromium:4.27.0", config.KubernetesSession.BrowserImage)
+	assert.Equal(t, "registry.corp/hub/tsl0922/ttyd:1.7.7", config.KubernetesSession.TerminalImage)
+	assert.Equal(t, "registry.corp/hub/moby/buildkit:v0.23.2-rootless", config.KubernetesSession.BuildKitImage)
+	assert.Equal(t, "registry.corp/hub/library/busybox:1.36", config.Scia.SessionSidecarConfigImage)
+	assert.Empty(t, config.ValidateAirGap())
+}
+
+func TestValidateAirGap_ReportsExternalDependencies(t *testing.T) {
+	config := &Config{
+		Auth: AuthConfig{GitHub: &GitHubAuthConfig{Enabled: true, BaseURL: "https://api.github.com"}},
+		AirGap: AirGapConfig{
+			Enabled:      true,
+			GoProxy:      "https://goproxy.internal.corp,direct",
+			AllowedHosts: []string{".internal.corp"},
+		},
+	}
+
+	findings := config.ValidateAirGap()
+	if assert.Len(t, findings, 6) {
+		assert.Equal(t, "auth.github.base_url", findings[0].Field)
+		assert.Equal(t, "kubernetes_session.browser_image", findings[1].Field)
+		assert.Equal(t, "kubernetes_session.buildkit_image", findings[2].Field)
+		assert.Equal(t, "kubernetes_session.dind_image", findings[3].Field)
+		assert.Equal(t, "kubernetes_session.editor_image", findings[4].Field)
+		assert.Equal(t, "kubernetes_session.terminal_image", findings[5].Field)
+	}
+
+	config.AirGap.Enabled = false
+	assert.Nil(t, config.ValidateAirGap())
+}
+
+func TestStreamingConfig_ProxyOptions(t *testing.T) {
+	opts := StreamingConfig{
+		FlushInterval:        "50ms",
+		SSEIdleTimeout:       "0",
+		WebSocketIdleTimeout: "bogus",
+	}.ProxyOptions()
+
+	assert.Equal(t, 50*time.Millisecond, opts.FlushInterval)
+	assert.Zero(t, opts.SSEIdleTimeout)
+	assert.Zero(t, opts.WebSocketIdleTimeout)
+}
+
+func TestBackendProxyConfig_ApplyTo(t *testing.T) {
+	var opts proxy.Options
+	BackendProxyConfig{
+		MaxRetries:              3,
+		RetryBackoff:            "100ms",
+		RetryMaxBackoff:         "bogus",
+		CircuitFailureThreshold: 4,
+		CircuitOpenDuration:     "15s",
+	}.ApplyTo(&opts)
+
+	assert.Equal(t, 3, opts.Retry.MaxRetries)
+	assert.Equal(t, 100*time.Millisecond, opts.Retry.InitialBackoff)
+	assert.Zero(t, opts.Retry.MaxBackoff)
+	assert.Equal(t, 4, opts.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, 15*time.Second, opts.CircuitBreaker.OpenDuration)
+}
+
+func TestLoadConfigTracingFromEnvironment(t *testing.T) {
+	t.Setenv("AGENTAPI_TRACING_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://otel-collector:4318")
+	t.Setenv("AGENTAPI_TRACING_SAMPLE_RATIO", "0.25")
+
+	loadedConfig, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	assert.Equal(t, "otlp", loadedConfig.Tracing.Exporter)
+	assert.Equal(t, "http://otel-collector:4318", loadedConfig.Tracing.Endpoint)
+	assert.Equal(t, "agentapi-proxy", loadedConfig.Tracing.ServiceName)
+	assert.Equal(t, 0.25, loadedConfig.Tracing.SampleRatio)
+}
+
+func TestGitHubEnterpriseConfig(t *testing.T) {
+	c := GitHubEnterpriseConfig{
+		URL: "https://github.example.com/",
+		Teams: []GitHubEnterpriseTeamConfig{
+			{TeamID: "acme/eu", APIURL: "https://github.eu.example.com/api/v3", SecretName: "github-eu"},
+		},
+	}
+	assert.NoError(t, c.Validate())
+
+	instance, secretName, ok := c.Instance("acme/eu")
+	assert.True(t, ok)
+	assert.Equal(t, "https://github.eu.example.com", instance.URL)
+	assert.Equal(t, "github-eu", secretName)
+
+	instance, secretName, ok = c.Instance("acme/ml")
+	assert.True(t, ok)
+	assert.Equal(t, "https://github.example.com/api/v3", instance.APIURL)
+	assert.Empty(t, secretName)
+
+	_, _, ok = GitHubEnterpriseConfig{}.Instance("acme/ml")
+	assert.False(t, ok)
+
+	cfg := &Config{GitHubEnterprise: c}
+	assert.Equal(t, "github.example.com", cfg.WebhookGitHubEnterpriseHost())
+	cfg.Webhook.GitHubEnterpriseHost = "ghe.internal"
+	assert.Equal(t, "ghe.internal", cfg.WebhookGitHubEnterpriseHost())
+
+	assert.Error(t, GitHubEnterpriseConfig{URL: "github.example.com"}.Validate())
+	assert.Error(t, GitHubEnterpriseConfig{Teams: []GitHubEnterpriseTeamConfig{{TeamID: "acme", URL: "https://github.example.com"}}}.Validate())
+	assert.Error(t, GitHubEnterpriseConfig{Teams: []GitHubEnterpriseTeamConfig{{TeamID: "acme/eu"}}}.Validate())
+}
@@ -0,0 +1,28 @@
+package config
+
+import "fmt"
+
+// CheckReferencedFiles reads the files cfg refers to again and returns the
+// errors LoadConfig only logs as warnings: auth_config_file,
+// auth.static.keys_file and kubernetes_session.config_file. cfg is not
+// modified.
+func CheckReferencedFiles(cfg *Config) []error {
+	var errs []error
+	if cfg.AuthConfigFile != "" {
+		if err := loadAuthConfigFromFile(&Config{}, cfg.AuthConfigFile); err != nil {
+			errs = append(errs, fmt.Errorf("auth_config_file %s: %w", cfg.AuthConfigFile, err))
+		}
+	}
+	if cfg.Auth.Static != nil && cfg.Auth.Static.KeysFile != "" {
+		keys := &Config{Auth: AuthConfig{Static: &StaticAuthConfig{KeysFile: cfg.Auth.Static.KeysFile}}}
+		if err := keys.loadAPIKeysFromFile(); err != nil {
+			errs = append(errs, fmt.Errorf("auth.static.keys_file %s: %w", cfg.Auth.Static.KeysFile, err))
+		}
+	}
+	if cfg.KubernetesSession.ConfigFile != "" {
+		if err := loadK8sSessionConfigFromFile(&Config{}, cfg.KubernetesSession.ConfigFile); err != nil {
+			errs = append(errs, fmt.Errorf("kubernetes_session.config_file %s: %w", cfg.KubernetesSession.ConfigFile, err))
+		}
+	}
+	return errs
+}
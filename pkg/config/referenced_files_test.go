@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "keys.json")
+	if err := os.WriteFile(keys, []byte(`{"api_keys": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "k8s.json")
+	if err := os.WriteFile(broken, []byte(`{"kubernetes_session": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		AuthConfigFile:    filepath.Join(dir, "missing.yaml"),
+		Auth:              AuthConfig{Static: &StaticAuthConfig{KeysFile: keys}},
+		KubernetesSession: KubernetesSessionConfig{ConfigFile: broken},
+	}
+	errs := CheckReferencedFiles(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("CheckReferencedFiles() = %v, want 2 errors", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "auth_config_file ") || !strings.HasPrefix(errs[1].Error(), "kubernetes_session.config_file ") {
+		t.Errorf("CheckReferencedFiles() = %v", errs)
+	}
+	if cfg.Auth.Static.APIKeys != nil {
+		t.Error("CheckReferencedFiles() modified the config")
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ReloadableSettings are the settings a config reload applies to the
+// running proxy. Every other setting keeps its startup value until a
+// restart.
+var ReloadableSettings = []string{
+	"auth.static",
+	"auth.github.enabled",
+	"auth.github.base_url",
+	"auth.github.token_header",
+	"auth.github.user_mapping",
+	"auth.aws",
+	"rate_limit.enabled",
+	"rate_limit.user",
+	"rate_limit.api_key",
+	"rate_limit.team",
+	"rate_limit.routes",
+	"kubernetes_session.agent_images",
+	"kubernetes_session.team_images",
+	"kubernetes_session.allowed_images",
+	"kubernetes_session.allowed_request_mcp_servers",
+	"kubernetes_session.cpu_request",
+	"kubernetes_session.cpu_limit",
+	"kubernetes_session.memory_request",
+	"kubernetes_session.memory_limit",
+	"kubernetes_session.max_session_replicas",
+	"kubernetes_session.preemption",
+}
+
+// ReloadResult lists the settings a config reload found changed
+type ReloadResult struct {
+	// Reloaded are the changed settings that were applied
+	Reloaded []string `json:"reloaded"`
+	// RestartRequired are the changed settings that keep their startup value
+	// until a restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// DiffReload compares the current and the reloaded config and sorts the
+// changed settings into those a reload applies and those that need a
+// restart.
+func DiffReload(current, next *Config) ReloadResult {
+	result := ReloadResult{Reloaded: []string{}, RestartRequired: []string{}}
+	diffSettings(reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem(), "", &result)
+	return result
+}
+
+// MergeReload returns a copy of current with the reloadable settings of
+// next. current is not modified.
+func MergeReload(current, next *Config) *Config {
+	merged := *current
+	mergeSettings(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "")
+	return &merged
+}
+
+// settingName returns the config key of a struct field, or "" for fields
+// that are not settings.
+func settingName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	for _, key := range []string{"json", "mapstructure"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(field.Name)
+}
+
+// containsReloadable reports whether settings below path are reloadable
+func containsReloadable(path string) bool {
+	for _, setting := range ReloadableSettings {
+		if strings.HasPrefix(setting, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// structOf returns the struct of a struct or pointer-to-struct value, with
+// nil pointers read as the zero struct.
+func structOf(v reflect.Value) (reflect.Value, bool) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return v, true
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			return reflect.Zero(v.Type().Elem()), true
+		}
+		return v.Elem(), true
+	}
+	return reflect.Value{}, false
+}
+
+func diffSettings(a, b reflect.Value, prefix string, result *ReloadResult) {
+	for i := 0; i < a.NumField(); i++ {
+		name := settingName(a.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		path := prefix + name
+		fa, fb := a.Field(i), b.Field(i)
+		if containsReloadable(path) {
+			sa, okA := structOf(fa)
+			sb, okB := structOf(fb)
+			if okA && okB {
+				diffSettings(sa, sb, path+".", result)
+				continue
+			}
+		}
+		if reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			continue
+		}
+		if slices.Contains(ReloadableSettings, path) {
+			result.Reloaded = append(result.Reloaded, path)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, path)
+		}
+	}
+}
+
+func mergeSettings(dst, src reflect.Value, prefix string) {
+	for i := 0; i < dst.NumField(); i++ {
+		name := settingName(dst.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		path := prefix + name
+		fd, fs := dst.Field(i), src.Field(i)
+		switch {
+		case slices.Contains(ReloadableSettings, path):
+			fd.Set(fs)
+		case !containsReloadable(path):
+		case fd.Kind() == reflect.Struct:
+			mergeSettings(fd, fs, path+".")
+		case fd.Kind() == reflect.Pointer:
+			if fd.IsNil() && fs.IsNil() {
+				continue
+			}
+			// Copy the struct so that the current config is not modified
+			copied := reflect.New(fd.Type().Elem())
+			if !fd.IsNil() {
+				copied.Elem().Set(fd.Elem())
+			}
+			source, _ := structOf(fs)
+			mergeSettings(copied.Elem(), source, path+".")
+			fd.Set(copied)
+		}
+	}
+}
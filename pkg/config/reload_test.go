@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiffReload(t *testing.T) {
+	current := DefaultConfig()
+	current.Auth.GitHub = &GitHubAuthConfig{Enabled: true, BaseURL: "https://api.github.com"}
+	current.KubernetesSession.CPULimit = "2"
+
+	next := DefaultConfig()
+	next.Auth.GitHub = &GitHubAuthConfig{
+		Enabled: true,
+		BaseURL: "https://api.github.com",
+		OAuth:   &GitHubOAuthConfig{ClientID: "id"},
+		UserMapping: GitHubUserMapping{
+			DefaultRole: "member",
+		},
+	}
+	next.Auth.AWS = &AWSAuthConfig{Enabled: true}
+	next.RateLimit.User = RateLimitRule{RPS: 5, Burst: 10}
+	next.RateLimit.Backend = "redis"
+	next.KubernetesSession.CPULimit = "4"
+	next.KubernetesSession.Image = "ghcr.io/example/agent:2"
+	next.Redis.Addr = "redis:6379"
+
+	got := DiffReload(current, next)
+	want := ReloadResult{
+		Reloaded: []string{"auth.github.user_mapping", "auth.aws", "kubernetes_session.cpu_limit", "rate_limit.user"},
+		RestartRequired: []string{
+			"auth.github.oauth", "kubernetes_session.image", "redis", "rate_limit.backend",
+		},
+	}
+	if !reflect.DeepEqual(sorted(got.Reloaded), sorted(want.Reloaded)) {
+		t.Errorf("Reloaded = %v, want %v", got.Reloaded, want.Reloaded)
+	}
+	if !reflect.DeepEqual(sorted(got.RestartRequired), sorted(want.RestartRequired)) {
+		t.Errorf("RestartRequired = %v, want %v", got.RestartRequired, want.RestartRequired)
+	}
+
+	if got := DiffReload(current, current); len(got.Reloaded) != 0 || len(got.RestartRequired) != 0 {
+		t.Errorf("unchanged config: %+v", got)
+	}
+}
+
+func TestMergeReload(t *testing.T) {
+	current := DefaultConfig()
+	current.Auth.GitHub = &GitHubAuthConfig{Enabled: true, TokenHeader: "Authorization"}
+	current.KubernetesSession.Image = "ghcr.io/example/agent:1"
+
+	next := DefaultConfig()
+	next.Auth.GitHub = &GitHubAuthConfig{
+		Enabled:     true,
+		TokenHeader: "X-GitHub-Token",
+		OAuth:       &GitHubOAuthConfig{ClientID: "id"},
+	}
+	next.KubernetesSession.Image = "ghcr.io/example/agent:2"
+	next.KubernetesSession.AgentImages = map[string]string{"goose": "ghcr.io/example/goose:1"}
+	next.RateLimit.Enabled = true
+	next.Redis.Addr = "redis:6379"
+
+	merged := MergeReload(current, next)
+	if merged.Auth.GitHub.TokenHeader != "X-GitHub-Token" || merged.Auth.GitHub.OAuth != nil {
+		t.Errorf("auth.github = %+v, want the new token header and no OAuth", merged.Auth.GitHub)
+	}
+	if merged.KubernetesSession.Image != "ghcr.io/example/agent:1" || merged.KubernetesSession.AgentImages["goose"] == "" {
+		t.Errorf("kubernetes_session image = %q, agent images = %v", merged.KubernetesSession.Image, merged.KubernetesSession.AgentImages)
+	}
+	if !merged.RateLimit.Enabled || merged.Redis.Addr != "" {
+		t.Errorf("rate_limit.enabled = %v, redis.addr = %q", merged.RateLimit.Enabled, merged.Redis.Addr)
+	}
+	// The current config is not modified
+	if current.Auth.GitHub.TokenHeader != "Authorization" || current.RateLimit.Enabled {
+		t.Errorf("current config was modified: %+v", current.Auth.GitHub)
+	}
+}
+
+func sorted(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,88 @@
+// Package delivery provides a persistent retry queue for outbound
+// integrations such as webhooks, Slack posts and notifications. Deliveries
+// that fail are stored and retried with exponential backoff, so a proxy
+// restart does not drop them; deliveries that keep failing are moved to a
+// dead-letter list from which they can be redelivered manually.
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a delivery does not exist
+var ErrNotFound = errors.New("delivery not found")
+
+// Delivery is a pending or dead outbound delivery
+type Delivery struct {
+	// ID uniquely identifies the delivery
+	ID string `json:"id"`
+	// Kind selects the Handler that performs the delivery (e.g. "notification")
+	Kind string `json:"kind"`
+	// Payload is the handler-specific delivery content
+	Payload json.RawMessage `json:"payload"`
+	// Attempts is the number of failed attempts so far
+	Attempts int `json:"attempts"`
+	// MaxAttempts is the number of attempts before the delivery is dead-lettered
+	MaxAttempts int `json:"max_attempts"`
+	// NextAttemptAt is when the delivery is due to be attempted next
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// LastError is the error of the latest failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// CreatedAt is when the delivery was enqueued
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is when the delivery was last attempted or changed
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeadAt is set once the delivery has been moved to the dead-letter list
+	DeadAt *time.Time `json:"dead_at,omitempty"`
+}
+
+// Dead reports whether the delivery is in the dead-letter list
+func (d *Delivery) Dead() bool {
+	return d.DeadAt != nil
+}
+
+// Store persists deliveries. Pending and dead deliveries are kept apart so
+// that due deliveries can be listed without scanning the dead-letter list.
+type Store interface {
+	// Put creates or replaces a delivery. Deliveries with DeadAt set are
+	// stored in the dead-letter list, all others as pending.
+	Put(ctx context.Context, d *Delivery) error
+	// Get returns a pending or dead delivery by ID
+	Get(ctx context.Context, id string) (*Delivery, error)
+	// Delete removes a delivery
+	Delete(ctx context.Context, id string) error
+	// Due returns up to limit pending deliveries whose NextAttemptAt is not after now
+	Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error)
+	// Claim postpones a due pending delivery to until so that no other worker
+	// attempts it concurrently. It returns false if the delivery is no longer
+	// due, e.g. because another worker claimed it first.
+	Claim(ctx context.Context, id string, now, until time.Time) (bool, error)
+	// ListDead returns the dead-letter list, most recently dead first
+	ListDead(ctx context.Context) ([]*Delivery, error)
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the queue dead-letters the delivery
+// immediately instead of retrying it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,220 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store that keeps each delivery as a JSON file under
+// <dir>/pending or <dir>/dead. It is meant for single-replica deployments;
+// use RedisStore when several replicas share the queue.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{"pending", "dead"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create delivery directory: %w", err)
+		}
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(dead bool, id string) string {
+	sub := "pending"
+	if dead {
+		sub = "dead"
+	}
+	return filepath.Join(s.dir, sub, id+".json")
+}
+
+// Put creates or replaces a delivery
+func (s *FileStore) Put(_ context.Context, d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(d)
+}
+
+func (s *FileStore) put(d *Delivery) error {
+	if err := validID(d.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	// Write to a temporary file and rename it so that a crash never leaves a
+	// truncated delivery behind.
+	target := s.path(d.Dead(), d.ID)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write delivery: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write delivery: %w", err)
+	}
+
+	if err := os.Remove(s.path(!d.Dead(), d.ID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move delivery: %w", err)
+	}
+	return nil
+}
+
+// Get returns a pending or dead delivery by ID
+func (s *FileStore) Get(_ context.Context, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id)
+}
+
+func (s *FileStore) get(id string) (*Delivery, error) {
+	if err := validID(id); err != nil {
+		return nil, err
+	}
+	for _, dead := range []bool{false, true} {
+		d, err := readDelivery(s.path(dead, id))
+		if os.IsNotExist(err) {
+			continue
+		}
+		return d, err
+	}
+	return nil, ErrNotFound
+}
+
+// Delete removes a delivery
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validID(id); err != nil {
+		return err
+	}
+	found := false
+	for _, dead := range []bool{false, true} {
+		err := os.Remove(s.path(dead, id))
+		if err == nil {
+			found = true
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete delivery: %w", err)
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Due returns up to limit pending deliveries due at now, oldest first
+func (s *FileStore) Due(_ context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.list(false)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]*Delivery, 0, len(all))
+	for _, d := range all {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Claim postpones a due pending delivery to until
+func (s *FileStore) Claim(_ context.Context, id string, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validID(id); err != nil {
+		return false, err
+	}
+	d, err := readDelivery(s.path(false, id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if d.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	d.NextAttemptAt = until
+	return true, s.put(d)
+}
+
+// ListDead returns the dead-letter list, most recently dead first
+func (s *FileStore) ListDead(_ context.Context) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dead, err := s.list(true)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].DeadAt.After(*dead[j].DeadAt) })
+	return dead, nil
+}
+
+func (s *FileStore) list(dead bool) ([]*Delivery, error) {
+	dir := filepath.Dir(s.path(dead, "x"))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	deliveries := make([]*Delivery, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		d, err := readDelivery(filepath.Join(dir, e.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if dead && d.DeadAt == nil {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func readDelivery(path string) (*Delivery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery %s: %w", filepath.Base(path), err)
+	}
+	return &d, nil
+}
+
+// validID rejects IDs that could escape the store directory
+func validID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return fmt.Errorf("invalid delivery ID %q", id)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Queue defaults
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseBackoff  = 30 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultPollInterval = 10 * time.Second
+
+	// claimLease is how long a claimed delivery is hidden from other workers.
+	// A worker that crashes mid-delivery leaves the delivery to be retried
+	// once the lease expires.
+	claimLease = 5 * time.Minute
+	// batchSize is the maximum number of deliveries attempted per poll
+	batchSize = 100
+)
+
+// Handler performs one delivery attempt of a kind. Returning an error
+// wrapped with Permanent dead-letters the delivery without further retries.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Options configures a Queue
+type Options struct {
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered
+	MaxAttempts int
+	// BaseBackoff is the wait after the first failed attempt; it doubles per attempt
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration
+}
+
+// Queue retries failed deliveries from a Store until they succeed or run out
+// of attempts
+type Queue struct {
+	store       Store
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	now         func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewQueue creates a new Queue. Zero options take the defaults.
+func NewQueue(store Store, opts Options) *Queue {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = DefaultBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Queue{
+		store:       store,
+		maxAttempts: opts.MaxAttempts,
+		baseBackoff: opts.BaseBackoff,
+		maxBackoff:  opts.MaxBackoff,
+		now:         time.Now,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for a delivery kind, replacing any previous one
+func (q *Queue) Register(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+func (q *Queue) handler(kind string) Handler {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.handlers[kind]
+}
+
+// Enqueue stores a delivery to be attempted on the next poll
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload interface{}) (*Delivery, error) {
+	return q.enqueue(ctx, kind, payload, nil)
+}
+
+// Retry stores a delivery whose first attempt has already failed with
+// cause, to be attempted again after the backoff. Permanent causes are
+// dead-lettered right away.
+func (q *Queue) Retry(ctx context.Context, kind string, payload interface{}, cause error) (*Delivery, error) {
+	if cause == nil {
+		cause = fmt.Errorf("delivery failed")
+	}
+	return q.enqueue(ctx, kind, payload, cause)
+}
+
+func (q *Queue) enqueue(ctx context.Context, kind string, payload interface{}, cause error) (*Delivery, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal delivery payload: %w", err)
+	}
+	now := q.now().UTC()
+	d := &Delivery{
+		ID:            uuid.New().String(),
+		Kind:          kind,
+		Payload:       raw,
+		MaxAttempts:   q.maxAttempts,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if cause != nil {
+		q.fail(d, cause, now)
+	}
+	if err := q.store.Put(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// fail records a failed attempt on d and schedules the next one or
+// dead-letters it
+func (q *Queue) fail(d *Delivery, cause error, now time.Time) {
+	d.Attempts++
+	d.LastError = cause.Error()
+	d.UpdatedAt = now
+	if IsPermanent(cause) || d.Attempts >= d.MaxAttempts {
+		d.DeadAt = &now
+		return
+	}
+	d.NextAttemptAt = now.Add(q.backoff(d.Attempts))
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (q *Queue) backoff(attempts int) time.Duration {
+	wait := q.baseBackoff
+	for i := 1; i < attempts && wait < q.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > q.maxBackoff {
+		wait = q.maxBackoff
+	}
+	return wait
+}
+
+// ProcessDue attempts all due deliveries once and returns how many succeeded
+func (q *Queue) ProcessDue(ctx context.Context) (int, error) {
+	now := q.now().UTC()
+	due, err := q.store.Due(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		claimed, err := q.store.Claim(ctx, d.ID, now, now.Add(claimLease))
+		if err != nil {
+			log.Printf("[DELIVERY] Failed to claim delivery %s: %v", d.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if q.attempt(ctx, d) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// attempt runs the handler for a claimed delivery and records the outcome
+func (q *Queue) attempt(ctx context.Context, d *Delivery) bool {
+	var err error
+	if h := q.handler(d.Kind); h == nil {
+		err = fmt.Errorf("no handler registered for delivery kind %q", d.Kind)
+	} else {
+		err = h(ctx, d.Payload)
+	}
+
+	if err == nil {
+		if delErr := q.store.Delete(ctx, d.ID); delErr != nil && delErr != ErrNotFound {
+			log.Printf("[DELIVERY] Delivered %s (%s) but failed to remove it: %v", d.ID, d.Kind, delErr)
+		}
+		return true
+	}
+
+	q.fail(d, err, q.now().UTC())
+	if d.Dead() {
+		log.Printf("[DELIVERY] Delivery %s (%s) moved to dead letters after %d attempts: %v", d.ID, d.Kind, d.Attempts, err)
+	} else {
+		log.Printf("[DELIVERY] Delivery %s (%s) attempt %d failed, retrying at %s: %v", d.ID, d.Kind, d.Attempts, d.NextAttemptAt.Format(time.RFC3339), err)
+	}
+	if putErr := q.store.Put(ctx, d); putErr != nil {
+		log.Printf("[DELIVERY] Failed to save delivery %s: %v", d.ID, putErr)
+	}
+	return false
+}
+
+// Run polls for due deliveries every interval until ctx is done
+func (q *Queue) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := q.ProcessDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[DELIVERY] Failed to process due deliveries: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeadLetters returns the dead-lettered deliveries, most recently dead first
+func (q *Queue) DeadLetters(ctx context.Context) ([]*Delivery, error) {
+	return q.store.ListDead(ctx)
+}
+
+// Redeliver moves a dead-lettered delivery back to the queue with a fresh
+// set of attempts, to be attempted on the next poll. It returns ErrNotFound
+// if no dead-lettered delivery has the ID.
+func (q *Queue) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	d, err := q.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !d.Dead() {
+		return nil, ErrNotFound
+	}
+	now := q.now().UTC()
+	d.DeadAt = nil
+	d.Attempts = 0
+	d.MaxAttempts = q.maxAttempts
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+	if err := q.store.Put(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
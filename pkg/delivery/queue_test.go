@@ -0,0 +1,208 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *FileStore, *time.Time) {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewQueue(store, Options{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+	q.now = func() time.Time { return now }
+	return q, store, &now
+}
+
+func TestQueue_RetryThenDeliver(t *testing.T) {
+	q, store, now := newTestQueue(t)
+	ctx := context.Background()
+
+	var got []string
+	fail := true
+	q.Register("test", func(_ context.Context, payload json.RawMessage) error {
+		var p string
+		_ = json.Unmarshal(payload, &p)
+		got = append(got, p)
+		if fail {
+			return errors.New("endpoint down")
+		}
+		return nil
+	})
+
+	d, err := q.Retry(ctx, "test", "hello", errors.New("endpoint down"))
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if d.Attempts != 1 || !d.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Attempts = %d, NextAttemptAt = %v", d.Attempts, d.NextAttemptAt)
+	}
+
+	// Not due yet
+	if n, _ := q.ProcessDue(ctx); n != 0 || len(got) != 0 {
+		t.Fatalf("delivered %d before backoff elapsed", n)
+	}
+
+	// Second attempt fails and backs off twice as long
+	*now = now.Add(time.Minute)
+	if n, _ := q.ProcessDue(ctx); n != 0 {
+		t.Fatalf("delivered = %d, want 0", n)
+	}
+	stored, err := store.Get(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Attempts != 2 || !stored.NextAttemptAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("Attempts = %d, NextAttemptAt = %v", stored.Attempts, stored.NextAttemptAt)
+	}
+
+	// Third attempt succeeds and removes the delivery
+	fail = false
+	*now = now.Add(2 * time.Minute)
+	if n, _ := q.ProcessDue(ctx); n != 1 {
+		t.Fatalf("delivered = %d, want 1", n)
+	}
+	if len(got) != 2 || got[1] != "hello" {
+		t.Fatalf("handler calls = %v", got)
+	}
+	if _, err := store.Get(ctx, d.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delivery: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueue_DeadLetterAndRedeliver(t *testing.T) {
+	q, _, now := newTestQueue(t)
+	ctx := context.Background()
+
+	calls := 0
+	q.Register("test", func(context.Context, json.RawMessage) error {
+		calls++
+		if calls < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	d, err := q.Retry(ctx, "test", map[string]string{"k": "v"}, errors.New("boom"))
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		*now = now.Add(time.Hour)
+		_, _ = q.ProcessDue(ctx)
+	}
+
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != d.ID || dead[0].Attempts != 3 || dead[0].LastError != "boom" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	// Dead deliveries are not retried automatically
+	*now = now.Add(time.Hour)
+	_, _ = q.ProcessDue(ctx)
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+
+	if _, err := q.Redeliver(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeliver(missing): err = %v", err)
+	}
+	redelivered, err := q.Redeliver(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivered.Dead() || redelivered.Attempts != 0 {
+		t.Fatalf("redelivered = %+v", redelivered)
+	}
+	if n, _ := q.ProcessDue(ctx); n != 1 {
+		t.Fatalf("delivered = %d, want 1", n)
+	}
+	if dead, _ := q.DeadLetters(ctx); len(dead) != 0 {
+		t.Fatalf("dead letters after redelivery = %d", len(dead))
+	}
+}
+
+func TestQueue_PermanentAndUnknownKind(t *testing.T) {
+	q, _, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	d, err := q.Retry(ctx, "test", "x", Permanent(errors.New("gone")))
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if !d.Dead() || d.Attempts != 1 {
+		t.Fatalf("permanent failure not dead-lettered: %+v", d)
+	}
+
+	// Deliveries without a handler fail like any other attempt
+	if _, err := q.Enqueue(ctx, "unknown", "y"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if n, _ := q.ProcessDue(ctx); n != 0 {
+		t.Fatalf("delivered = %d, want 0", n)
+	}
+}
+
+func TestQueue_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewFileStore(dir)
+	q := NewQueue(store, Options{})
+	d, err := q.Enqueue(context.Background(), "test", "persisted")
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// A new queue over the same directory picks up the pending delivery
+	store2, _ := NewFileStore(dir)
+	q2 := NewQueue(store2, Options{})
+	var got json.RawMessage
+	q2.Register("test", func(_ context.Context, payload json.RawMessage) error {
+		got = payload
+		return nil
+	})
+	if n, err := q2.ProcessDue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ProcessDue = %d, %v", n, err)
+	}
+	if string(got) != `"persisted"` {
+		t.Fatalf("payload = %s", got)
+	}
+	if _, err := store2.Get(context.Background(), d.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delivery not removed: %v", err)
+	}
+}
+
+func TestFileStore_Claim(t *testing.T) {
+	store, _ := NewFileStore(t.TempDir())
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Put(ctx, &Delivery{ID: "a", Kind: "test", NextAttemptAt: now}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	ok, err := store.Claim(ctx, "a", now, now.Add(time.Minute))
+	if err != nil || !ok {
+		t.Fatalf("first Claim = %v, %v", ok, err)
+	}
+	if ok, _ := store.Claim(ctx, "a", now, now.Add(time.Minute)); ok {
+		t.Fatal("second Claim succeeded while lease is held")
+	}
+	if due, _ := store.Due(ctx, now, 0); len(due) != 0 {
+		t.Fatalf("claimed delivery still due: %d", len(due))
+	}
+	if ok, _ := store.Claim(ctx, "a", now.Add(time.Minute), now.Add(2*time.Minute)); !ok {
+		t.Fatal("Claim after lease expiry failed")
+	}
+
+	if err := store.Put(ctx, &Delivery{ID: "../escape"}); err == nil {
+		t.Fatal("expected invalid ID to be rejected")
+	}
+}
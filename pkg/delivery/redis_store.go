@@ -0,0 +1,179 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys of the delivery queue. Delivery JSON is kept in one hash; the
+// pending and dead sorted sets are scored by next attempt and dead-letter
+// time in Unix milliseconds.
+const (
+	redisItemsKey   = "agentapi:delivery:items"
+	redisPendingKey = "agentapi:delivery:pending"
+	redisDeadKey    = "agentapi:delivery:dead"
+)
+
+// claimScript moves a due pending delivery's score to ARGV[2] if its score
+// is not after ARGV[1]. Returns 1 when claimed.
+var claimScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[3])
+if not score or tonumber(score) > tonumber(ARGV[1]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+return 1
+`)
+
+// RedisStore is a Store shared by all replicas through Redis
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a new RedisStore
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Put creates or replaces a delivery
+func (s *RedisStore) Put(ctx context.Context, d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisItemsKey, d.ID, data)
+		if d.Dead() {
+			pipe.ZRem(ctx, redisPendingKey, d.ID)
+			pipe.ZAdd(ctx, redisDeadKey, redis.Z{Score: unixMilli(*d.DeadAt), Member: d.ID})
+		} else {
+			pipe.ZRem(ctx, redisDeadKey, d.ID)
+			pipe.ZAdd(ctx, redisPendingKey, redis.Z{Score: unixMilli(d.NextAttemptAt), Member: d.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store delivery: %w", err)
+	}
+	return nil
+}
+
+// Get returns a pending or dead delivery by ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Delivery, error) {
+	data, err := s.client.HGet(ctx, redisItemsKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	var d Delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery %s: %w", id, err)
+	}
+	return &d, nil
+}
+
+// Delete removes a delivery
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	var removed *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.HDel(ctx, redisItemsKey, id)
+		pipe.ZRem(ctx, redisPendingKey, id)
+		pipe.ZRem(ctx, redisDeadKey, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete delivery: %w", err)
+	}
+	if removed.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Due returns up to limit pending deliveries due at now, oldest first
+func (s *RedisStore) Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	opt := &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10)}
+	if limit > 0 {
+		opt.Count = int64(limit)
+	}
+	zs, err := s.client.ZRangeByScoreWithScores(ctx, redisPendingKey, opt).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due deliveries: %w", err)
+	}
+	deliveries, err := s.load(ctx, zs)
+	if err != nil {
+		return nil, err
+	}
+	// The sorted set score is authoritative for the next attempt time since
+	// Claim only updates the score.
+	scores := make(map[string]float64, len(zs))
+	for _, z := range zs {
+		if id, ok := z.Member.(string); ok {
+			scores[id] = z.Score
+		}
+	}
+	for _, d := range deliveries {
+		d.NextAttemptAt = time.UnixMilli(int64(scores[d.ID])).UTC()
+	}
+	return deliveries, nil
+}
+
+// Claim postpones a due pending delivery to until
+func (s *RedisStore) Claim(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	n, err := claimScript.Run(ctx, s.client, []string{redisPendingKey},
+		now.UnixMilli(), until.UnixMilli(), id).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim delivery: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ListDead returns the dead-letter list, most recently dead first
+func (s *RedisStore) ListDead(ctx context.Context) ([]*Delivery, error) {
+	zs, err := s.client.ZRevRangeWithScores(ctx, redisDeadKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead deliveries: %w", err)
+	}
+	return s.load(ctx, zs)
+}
+
+// load fetches the deliveries named by zs in order, skipping members whose
+// JSON has been removed concurrently.
+func (s *RedisStore) load(ctx context.Context, zs []redis.Z) ([]*Delivery, error) {
+	if len(zs) == 0 {
+		return []*Delivery{}, nil
+	}
+	ids := make([]string, len(zs))
+	for i, z := range zs {
+		ids[i], _ = z.Member.(string)
+	}
+	values, err := s.client.HMGet(ctx, redisItemsKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deliveries: %w", err)
+	}
+	deliveries := make([]*Delivery, 0, len(values))
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var d Delivery
+		if err := json.Unmarshal([]byte(str), &d); err != nil {
+			return nil, fmt.Errorf("failed to parse delivery %s: %w", ids[i], err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, nil
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
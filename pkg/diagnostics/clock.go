@@ -0,0 +1,61 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxClockSkew is the clock difference tolerated by ClockSkew. GitHub
+// App JWTs and signed webhook timestamps start failing beyond a few minutes.
+const DefaultMaxClockSkew = 30 * time.Second
+
+// ClockSkew compares the local clock with the Date header returned by url.
+// Skew beyond maxSkew fails the check. The Date header has one second
+// resolution, so differences below two seconds are not reported.
+func ClockSkew(url string, client *http.Client, maxSkew time.Duration) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+	return Check{Name: "clock_skew", Run: func(ctx context.Context) Result {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return Fail("Set a valid reference URL", "invalid reference URL %q: %v", url, err)
+		}
+		sent := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return Warn("Allow outbound HTTPS from the proxy Pod to the reference server, or ignore this check in air-gapped clusters",
+				"could not reach %s to compare clocks: %v", url, err)
+		}
+		_ = resp.Body.Close()
+		received := time.Now()
+
+		remote, err := http.ParseTime(resp.Header.Get("Date"))
+		if err != nil {
+			return Warn("Use a reference server that returns a Date header", "%s returned no usable Date header", url)
+		}
+		// Compare against the middle of the round trip
+		local := sent.Add(received.Sub(sent) / 2)
+		skew := local.Sub(remote).Round(time.Second)
+		if abs(skew) < 2*time.Second {
+			skew = 0
+		}
+		message := fmt.Sprintf("local clock differs from %s by %s", url, skew)
+		if abs(skew) > maxSkew {
+			return Fail("Enable NTP time synchronization on the node running the proxy", "%s (maximum %s)", message, maxSkew)
+		}
+		return Pass("%s", message)
+	}}
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
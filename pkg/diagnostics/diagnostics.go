@@ -0,0 +1,151 @@
+// Package diagnostics runs live checks against the dependencies of the proxy
+// (Kubernetes, storage backends, third-party credentials, the system clock)
+// and reports pass/fail results with remediation hints.
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// StatusPass means the dependency works as configured
+	StatusPass Status = "pass"
+	// StatusWarn means the dependency works but something needs attention
+	StatusWarn Status = "warn"
+	// StatusFail means the dependency is broken
+	StatusFail Status = "fail"
+	// StatusSkip means the dependency is not configured
+	StatusSkip Status = "skip"
+)
+
+// DefaultTimeout bounds each check when no timeout is given to Run
+const DefaultTimeout = 10 * time.Second
+
+// ErrNotConfigured makes Func report a check as skipped
+var ErrNotConfigured = errors.New("not configured")
+
+// Result is the outcome of one check
+type Result struct {
+	Name        string `json:"name"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a diagnostics run. Its status is the worst status
+// of its checks; skipped checks do not count.
+type Report struct {
+	Status    Status    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Check is a named live check
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Pass returns a passing result
+func Pass(format string, args ...interface{}) Result {
+	return Result{Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+// Warn returns a warning with a remediation hint
+func Warn(remediation, format string, args ...interface{}) Result {
+	return Result{Status: StatusWarn, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+// Fail returns a failure with a remediation hint
+func Fail(remediation, format string, args ...interface{}) Result {
+	return Result{Status: StatusFail, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+// Skip returns a skipped result
+func Skip(format string, args ...interface{}) Result {
+	return Result{Status: StatusSkip, Message: fmt.Sprintf(format, args...)}
+}
+
+// Func creates a check from fn. The check passes with the message returned by
+// fn, is skipped when fn returns ErrNotConfigured and fails with remediation
+// on any other error.
+func Func(name, remediation string, fn func(ctx context.Context) (string, error)) Check {
+	return Check{Name: name, Run: func(ctx context.Context) Result {
+		message, err := fn(ctx)
+		switch {
+		case errors.Is(err, ErrNotConfigured):
+			if message == "" {
+				message = err.Error()
+			}
+			return Skip("%s", message)
+		case err != nil:
+			return Fail(remediation, "%v", err)
+		default:
+			return Pass("%s", message)
+		}
+	}}
+}
+
+// Run runs the checks concurrently, each bounded by timeout, and returns
+// their results in the order of checks. A check that panics or exceeds its
+// timeout fails.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check, timeout)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: results, Timestamp: time.Now().UTC()}
+	for _, r := range results {
+		if r.Status == StatusFail {
+			report.Status = StatusFail
+		} else if r.Status == StatusWarn && report.Status == StatusPass {
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Fail("This is a bug in the check; please report it", "check panicked: %v", r)
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Fail("Check network connectivity from the proxy Pod to the dependency", "check did not finish within %s", timeout)
+	}
+	result.Name = check.Name
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
@@ -0,0 +1,77 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunAggregatesStatus(t *testing.T) {
+	checks := []Check{
+		Func("ok", "", func(context.Context) (string, error) { return "fine", nil }),
+		Func("unset", "", func(context.Context) (string, error) { return "", ErrNotConfigured }),
+		{Name: "attention", Run: func(context.Context) Result { return Warn("look", "hmm") }},
+	}
+
+	report := Run(context.Background(), checks, time.Second)
+	if report.Status != StatusWarn {
+		t.Errorf("status = %s, want %s", report.Status, StatusWarn)
+	}
+	want := []Status{StatusPass, StatusSkip, StatusWarn}
+	for i, r := range report.Checks {
+		if r.Name != checks[i].Name || r.Status != want[i] {
+			t.Errorf("check %d = %+v, want %s %s", i, r, checks[i].Name, want[i])
+		}
+	}
+
+	checks = append(checks, Func("broken", "fix it", func(context.Context) (string, error) { return "", errors.New("boom") }))
+	report = Run(context.Background(), checks, time.Second)
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want %s", report.Status, StatusFail)
+	}
+	if last := report.Checks[3]; last.Message != "boom" || last.Remediation != "fix it" {
+		t.Errorf("failed check = %+v", last)
+	}
+}
+
+func TestRunBoundsSlowAndPanickingChecks(t *testing.T) {
+	checks := []Check{
+		{Name: "slow", Run: func(context.Context) Result {
+			time.Sleep(time.Second)
+			return Pass("late")
+		}},
+		{Name: "panics", Run: func(context.Context) Result { panic("oops") }},
+	}
+
+	start := time.Now()
+	report := Run(context.Background(), checks, 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Run() took %s, want it bounded by the timeout", elapsed)
+	}
+	for _, r := range report.Checks {
+		if r.Status != StatusFail {
+			t.Errorf("check %s = %s, want %s", r.Name, r.Status, StatusFail)
+		}
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	offset := time.Duration(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	check := ClockSkew(srv.URL, srv.Client(), 30*time.Second)
+	if r := check.Run(context.Background()); r.Status != StatusPass {
+		t.Errorf("in-sync clock = %+v, want pass", r)
+	}
+
+	offset = 5 * time.Minute
+	if r := check.Run(context.Background()); r.Status != StatusFail || r.Remediation == "" {
+		t.Errorf("skewed clock = %+v, want fail with remediation", r)
+	}
+}
@@ -0,0 +1,164 @@
+// Package egressproxy resolves corporate HTTP/SOCKS proxy settings and CA
+// bundles for environments whose clusters only reach the internet through a
+// corporate proxy.
+//
+// The same Settings are used in two places: they are rendered as environment
+// variables for session Pods (HTTP_PROXY, HTTPS_PROXY, ALL_PROXY, NO_PROXY and
+// their lowercase variants), and applied to the agentapi-proxy process itself
+// via ApplyToProcess so that outbound calls made by the proxy (GitHub, Slack,
+// model APIs) follow the same route and trust the same CA.
+package egressproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Settings describes the proxy route for outbound traffic.
+type Settings struct {
+	// HTTPProxy is used for plain HTTP requests (e.g. "http://proxy.corp:3128").
+	HTTPProxy string
+	// HTTPSProxy is used for HTTPS requests. May be a socks5:// URL.
+	HTTPSProxy string
+	// AllProxy is a fallback for clients that honor ALL_PROXY (curl, git). May be a socks5:// URL.
+	AllProxy string
+	// NoProxy is a comma-separated list of hosts, domains and CIDRs that bypass the proxy.
+	NoProxy string
+}
+
+// TeamOverride replaces non-empty fields of the base Settings for one team.
+type TeamOverride struct {
+	TeamID string
+	Settings
+}
+
+// IsZero reports whether no proxy is configured.
+func (s Settings) IsZero() bool {
+	return s.HTTPProxy == "" && s.HTTPSProxy == "" && s.AllProxy == ""
+}
+
+// Resolve returns base with the override for teamID applied. Fields left empty
+// in the override inherit the base value.
+func Resolve(base Settings, overrides []TeamOverride, teamID string) Settings {
+	if teamID == "" {
+		return base
+	}
+	for _, o := range overrides {
+		if o.TeamID != teamID {
+			continue
+		}
+		if o.HTTPProxy != "" {
+			base.HTTPProxy = o.HTTPProxy
+		}
+		if o.HTTPSProxy != "" {
+			base.HTTPSProxy = o.HTTPSProxy
+		}
+		if o.AllProxy != "" {
+			base.AllProxy = o.AllProxy
+		}
+		if o.NoProxy != "" {
+			base.NoProxy = MergeNoProxy(base.NoProxy, o.NoProxy)
+		}
+		break
+	}
+	return base
+}
+
+// EnvVar is a single environment variable.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// EnvVars renders s as environment variables in a stable order. Both upper-
+// and lowercase names are emitted because tools disagree on which they read.
+func (s Settings) EnvVars() []EnvVar {
+	var vars []EnvVar
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		vars = append(vars,
+			EnvVar{Name: name, Value: value},
+			EnvVar{Name: strings.ToLower(name), Value: value},
+		)
+	}
+	add("HTTP_PROXY", s.HTTPProxy)
+	add("HTTPS_PROXY", s.HTTPSProxy)
+	add("ALL_PROXY", s.AllProxy)
+	add("NO_PROXY", s.NoProxy)
+	return vars
+}
+
+// MergeNoProxy joins comma-separated NO_PROXY lists, dropping blanks and duplicates.
+func MergeNoProxy(lists ...string) string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, list := range lists {
+		for _, part := range strings.Split(list, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" || seen[part] {
+				continue
+			}
+			seen[part] = true
+			values = append(values, part)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// ApplyToProcess exports s into the current process environment and, when
+// caFile is set, adds its certificates to http.DefaultTransport's trust store.
+// Variables already present in the environment are left untouched so that
+// deployment-level settings win over configuration.
+//
+// It must be called before the first outbound HTTP request: net/http caches
+// the proxy environment on first use.
+func ApplyToProcess(s Settings, caFile string) error {
+	for _, v := range s.EnvVars() {
+		if _, ok := os.LookupEnv(v.Name); ok {
+			continue
+		}
+		if err := os.Setenv(v.Name, v.Value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", v.Name, err)
+		}
+	}
+	if caFile == "" {
+		return nil
+	}
+
+	pool, err := LoadCertPool(caFile)
+	if err != nil {
+		return err
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("http.DefaultTransport is %T, cannot install CA bundle", http.DefaultTransport)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return nil
+}
+
+// LoadCertPool returns the system trust store extended with the PEM
+// certificates in caFile.
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", caFile, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+	}
+	return pool, nil
+}
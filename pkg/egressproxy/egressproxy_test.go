@@ -0,0 +1,131 @@
+package egressproxy_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/egressproxy"
+)
+
+func TestResolve(t *testing.T) {
+	base := egressproxy.Settings{
+		HTTPProxy:  "http://proxy.corp:3128",
+		HTTPSProxy: "http://proxy.corp:3128",
+		NoProxy:    "localhost,.corp",
+	}
+	overrides := []egressproxy.TeamOverride{
+		{TeamID: "org/team-a", Settings: egressproxy.Settings{HTTPSProxy: "socks5://socks.corp:1080", NoProxy: ".team-a.corp,localhost"}},
+	}
+
+	got := egressproxy.Resolve(base, overrides, "org/team-a")
+	if got.HTTPProxy != base.HTTPProxy {
+		t.Errorf("HTTPProxy = %q, want inherited %q", got.HTTPProxy, base.HTTPProxy)
+	}
+	if got.HTTPSProxy != "socks5://socks.corp:1080" {
+		t.Errorf("HTTPSProxy = %q", got.HTTPSProxy)
+	}
+	if got.NoProxy != "localhost,.corp,.team-a.corp" {
+		t.Errorf("NoProxy = %q", got.NoProxy)
+	}
+
+	if other := egressproxy.Resolve(base, overrides, "org/team-b"); other != base {
+		t.Errorf("unrelated team should get base settings, got %+v", other)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	s := egressproxy.Settings{HTTPSProxy: "http://p:1", NoProxy: "a"}
+	vars := s.EnvVars()
+	want := []egressproxy.EnvVar{
+		{Name: "HTTPS_PROXY", Value: "http://p:1"},
+		{Name: "https_proxy", Value: "http://p:1"},
+		{Name: "NO_PROXY", Value: "a"},
+		{Name: "no_proxy", Value: "a"},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("got %d vars, want %d: %+v", len(vars), len(want), vars)
+	}
+	for i := range want {
+		if vars[i] != want[i] {
+			t.Errorf("vars[%d] = %+v, want %+v", i, vars[i], want[i])
+		}
+	}
+	if !(egressproxy.Settings{NoProxy: "x"}).IsZero() {
+		t.Error("settings without a proxy should be zero")
+	}
+}
+
+func TestApplyToProcess_KeepsExistingEnv(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://from-env:1")
+	// t.Setenv restores the original values after the test; unset them so
+	// ApplyToProcess sees them as missing.
+	for _, name := range []string{"http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		t.Setenv(name, "")
+		_ = os.Unsetenv(name)
+	}
+
+	err := egressproxy.ApplyToProcess(egressproxy.Settings{HTTPProxy: "http://from-config:1", HTTPSProxy: "http://from-config:2"}, "")
+	if err != nil {
+		t.Fatalf("ApplyToProcess() error = %v", err)
+	}
+	if got := os.Getenv("HTTP_PROXY"); got != "http://from-env:1" {
+		t.Errorf("HTTP_PROXY = %q, want environment value", got)
+	}
+	if got := os.Getenv("HTTPS_PROXY"); got != "http://from-config:2" {
+		t.Errorf("HTTPS_PROXY = %q, want config value", got)
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := egressproxy.LoadCertPool(invalid); err == nil {
+		t.Error("expected error for bundle without certificates")
+	}
+	if _, err := egressproxy.LoadCertPool(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("expected error for missing bundle")
+	}
+
+	valid := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(valid, selfSignedCA(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := egressproxy.LoadCertPool(valid); err != nil {
+		t.Errorf("LoadCertPool() error = %v", err)
+	}
+}
+
+func selfSignedCA(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Corp Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,282 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+const (
+	// defaultBufferSize is the number of queued events when Options.BufferSize is not set
+	defaultBufferSize = 1000
+	// publishTimeout bounds one attempt to publish an event
+	publishTimeout = 10 * time.Second
+	// publishAttempts is how often an event is tried before it is dropped
+	publishAttempts = 3
+)
+
+// publishRetryBackoff is the wait before the first retry; it doubles per retry
+var publishRetryBackoff = time.Second
+
+// publishedEvents counts events by type and result
+var publishedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "agentapi_proxy",
+	Name:      "event_bus_events_total",
+	Help:      "Events handed to the event bus by type and result (published, failed, dropped).",
+}, []string{"type", "result"})
+
+// SessionLookup returns a session by ID, or nil if it does not exist
+type SessionLookup func(id string) entities.Session
+
+// Options configures a Bus
+type Options struct {
+	// Source identifies this proxy in events. Default: "agentapi-proxy".
+	Source string
+	// Events restricts the published event types; empty publishes all
+	Events []string
+	// BufferSize is the number of events queued for the publisher. Default: 1000.
+	BufferSize int
+}
+
+// Bus turns session lifecycle, status, capability and policy events into
+// schema-versioned events and publishes them in the background. Events are
+// queued in memory: a full queue drops new events rather than slowing down
+// the operation that caused them.
+type Bus struct {
+	publisher Publisher
+	session   SessionLookup
+	source    string
+	events    map[string]bool
+	queue     chan Event
+	// pending counts the events queued or being published
+	pending atomic.Int64
+
+	// lastStatus is the last agent status seen per session
+	mu         sync.Mutex
+	lastStatus map[string]string
+}
+
+// New creates a Bus. session is used to describe the session of each event;
+// it may be nil.
+func New(publisher Publisher, session SessionLookup, opts Options) *Bus {
+	if opts.Source == "" {
+		opts.Source = "agentapi-proxy"
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+	var events map[string]bool
+	if len(opts.Events) > 0 {
+		events = make(map[string]bool, len(opts.Events))
+		for _, t := range opts.Events {
+			events[t] = true
+		}
+	}
+	return &Bus{
+		publisher:  publisher,
+		session:    session,
+		source:     opts.Source,
+		events:     events,
+		queue:      make(chan Event, opts.BufferSize),
+		lastStatus: make(map[string]string),
+	}
+}
+
+// Emit queues an event about sessionID ("" for none). The session is
+// described before returning so that deleted sessions are still described.
+func (b *Bus) Emit(eventType, sessionID, message string, data map[string]any, at time.Time) {
+	if b.events != nil && !b.events[eventType] {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	event := Event{
+		SchemaVersion: SchemaVersion,
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		Source:        b.source,
+		Time:          at,
+		Session:       b.describe(sessionID),
+		Message:       message,
+		Data:          data,
+	}
+	b.pending.Add(1)
+	select {
+	case b.queue <- event:
+	default:
+		b.pending.Add(-1)
+		publishedEvents.WithLabelValues(eventType, "dropped").Inc()
+		log.Printf("[EVENT_BUS] Queue full, dropped %s event of session %s", eventType, sessionID)
+	}
+}
+
+func (b *Bus) describe(sessionID string) *EventSession {
+	if sessionID == "" {
+		return nil
+	}
+	described := &EventSession{ID: sessionID}
+	if b.session == nil {
+		return described
+	}
+	if s := b.session(sessionID); s != nil {
+		described.UserID = s.UserID()
+		described.Scope = string(s.Scope())
+		described.TeamID = s.TeamID()
+		described.Status = s.Status()
+		described.Tags = s.Tags()
+	}
+	return described
+}
+
+// Run publishes queued events until ctx is cancelled, then closes the
+// publisher. An event that cannot be published after a few attempts is
+// dropped.
+func (b *Bus) Run(ctx context.Context) {
+	defer func() {
+		if err := b.publisher.Close(); err != nil {
+			log.Printf("[EVENT_BUS] Failed to close publisher: %v", err)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.queue:
+			b.publish(ctx, event)
+			b.pending.Add(-1)
+		}
+	}
+}
+
+// Flush waits until the queued events are published, or dropped after their
+// retries, while Run is running. It returns ctx.Err() if ctx is done first.
+func (b *Bus) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for b.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (b *Bus) publish(ctx context.Context, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[EVENT_BUS] Failed to encode %s event: %v", event.Type, err)
+		publishedEvents.WithLabelValues(event.Type, "failed").Inc()
+		return
+	}
+	backoff := publishRetryBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err = b.publisher.Publish(attemptCtx, event, body)
+		cancel()
+		if err == nil {
+			publishedEvents.WithLabelValues(event.Type, "published").Inc()
+			return
+		}
+		if attempt == publishAttempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	publishedEvents.WithLabelValues(event.Type, "failed").Inc()
+	log.Printf("[EVENT_BUS] Failed to publish %s event %s: %v", event.Type, event.ID, err)
+}
+
+// Recorder wraps a session event recorder so that recorded lifecycle events
+// are also published
+func (b *Bus) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &recorder{EventRecorder: inner, bus: b}
+}
+
+type recorder struct {
+	portrepos.EventRecorder
+	bus *Bus
+}
+
+func (r *recorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	r.bus.HandleSessionEvent(event)
+	return r.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent publishes a session timeline event
+func (b *Bus) HandleSessionEvent(event entities.SessionEvent) {
+	if event.Type == entities.SessionEventDeleted {
+		b.mu.Lock()
+		delete(b.lastStatus, event.SessionID)
+		b.mu.Unlock()
+	}
+	b.Emit(sessionEventType(event.Type), event.SessionID, event.Message, nil, event.Timestamp)
+}
+
+// ObserveStatus publishes session.status_changed when the agent status of a
+// session differs from the last one seen
+func (b *Bus) ObserveStatus(sessionID, status string) {
+	b.mu.Lock()
+	previous, seen := b.lastStatus[sessionID]
+	b.lastStatus[sessionID] = status
+	b.mu.Unlock()
+	if seen && previous == status {
+		return
+	}
+	data := map[string]any{"status": status}
+	if previous != "" {
+		data["previous_status"] = previous
+	}
+	b.Emit(EventSessionStatusChanged, sessionID, "", data, time.Time{})
+}
+
+// CapabilityDecision publishes approval.granted or approval.denied for the
+// risky capabilities requested by a session
+func (b *Bus) CapabilityDecision(sessionID string, granted bool, capabilities []entities.SessionCapability, reason string) {
+	eventType := EventApprovalDenied
+	if granted {
+		eventType = EventApprovalGranted
+	}
+	names := make([]string, 0, len(capabilities))
+	for _, c := range capabilities {
+		names = append(names, string(c))
+	}
+	b.Emit(eventType, sessionID, reason, map[string]any{"capabilities": names}, time.Time{})
+}
+
+// WriteAuditEvent publishes policy.violation for policy violations written
+// to the audit log, which makes the Bus usable as an audit sink. Other audit
+// events are ignored.
+func (b *Bus) WriteAuditEvent(_ context.Context, event entities.AuditEvent) error {
+	if event.Category != entities.AuditCategoryPolicyViolation {
+		return nil
+	}
+	var sessionID string
+	if id, ok := strings.CutPrefix(event.Resource, "session/"); ok {
+		sessionID = id
+	}
+	b.Emit(EventPolicyViolation, sessionID, event.Detail, map[string]any{
+		"action":   event.Action,
+		"actor":    event.Actor,
+		"resource": event.Resource,
+		"outcome":  event.Outcome,
+	}, event.Timestamp)
+	return nil
+}
@@ -0,0 +1,184 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// recordingPublisher keeps published events; the first failures publishes fail
+type recordingPublisher struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	events   []Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, _ Event, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts++
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func (p *recordingPublisher) published() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Event(nil), p.events...)
+}
+
+func waitForEvents(t *testing.T, p *recordingPublisher, n int) []Event {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := p.published(); len(events) >= n {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("published %d events, want %d", len(p.published()), n)
+	return nil
+}
+
+func runBus(t *testing.T, p Publisher, opts Options) *Bus {
+	t.Helper()
+	bus := New(p, nil, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go bus.Run(ctx)
+	return bus
+}
+
+func TestBusPublishesSchemaVersionedEvents(t *testing.T) {
+	p := &recordingPublisher{}
+	bus := runBus(t, p, Options{Source: "proxy-a"})
+
+	bus.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventProvisionFailed, Message: "image pull failed"})
+	bus.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventMessageSent})
+	bus.ObserveStatus("s1", "running")
+	bus.ObserveStatus("s1", "running")
+	bus.ObserveStatus("s1", "active")
+	bus.CapabilityDecision("s1", false, []entities.SessionCapability{"docker"}, "not approved in base settings")
+	_ = bus.WriteAuditEvent(context.Background(), entities.AuditEvent{Category: entities.AuditCategoryAccess, Resource: "session/s1"})
+	_ = bus.WriteAuditEvent(context.Background(), entities.AuditEvent{
+		Category: entities.AuditCategoryPolicyViolation,
+		Action:   "capability_denied",
+		Resource: "session/s1",
+		Outcome:  entities.AuditOutcomeDenied,
+	})
+
+	events := waitForEvents(t, p, 6)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+		if e.SchemaVersion != SchemaVersion || e.Source != "proxy-a" || e.ID == "" || e.SessionID() != "s1" {
+			t.Errorf("event %+v lacks the envelope fields", e)
+		}
+	}
+	want := []string{"session.provision_failed", EventMessageRelayed, EventSessionStatusChanged, EventSessionStatusChanged, EventApprovalDenied, EventPolicyViolation}
+	if len(types) != len(want) {
+		t.Fatalf("types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("types = %v, want %v", types, want)
+		}
+	}
+	if events[3].Data["status"] != "active" || events[3].Data["previous_status"] != "running" {
+		t.Errorf("status_changed data = %v", events[3].Data)
+	}
+	if caps, _ := events[4].Data["capabilities"].([]any); len(caps) != 1 || caps[0] != "docker" {
+		t.Errorf("approval data = %v", events[4].Data)
+	}
+	if events[5].Data["action"] != "capability_denied" {
+		t.Errorf("policy.violation data = %v", events[5].Data)
+	}
+}
+
+func TestBusFiltersEventTypes(t *testing.T) {
+	p := &recordingPublisher{}
+	bus := runBus(t, p, Options{Events: []string{"session.deleted"}})
+
+	bus.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventCreated})
+	bus.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventDeleted})
+
+	events := waitForEvents(t, p, 1)
+	time.Sleep(50 * time.Millisecond)
+	if events = p.published(); len(events) != 1 || events[0].Type != "session.deleted" {
+		t.Errorf("published %+v, want only session.deleted", events)
+	}
+}
+
+func TestBusRetriesFailedPublishes(t *testing.T) {
+	old := publishRetryBackoff
+	publishRetryBackoff = time.Millisecond
+	defer func() { publishRetryBackoff = old }()
+
+	p := &recordingPublisher{failures: 2}
+	bus := runBus(t, p, Options{})
+	bus.Emit(EventSessionStatusChanged, "s1", "", nil, time.Time{})
+
+	waitForEvents(t, p, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", p.attempts)
+	}
+}
+
+func TestBusDropsEventsWhenQueueIsFull(t *testing.T) {
+	p := &recordingPublisher{}
+	bus := New(p, nil, Options{BufferSize: 1})
+	bus.Emit("session.created", "s1", "", nil, time.Time{})
+	bus.Emit("session.created", "s2", "", nil, time.Time{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+	events := waitForEvents(t, p, 1)
+	time.Sleep(50 * time.Millisecond)
+	if events = p.published(); len(events) != 1 || events[0].SessionID() != "s1" {
+		t.Errorf("published %+v, want only the first event", events)
+	}
+}
+
+func TestBusFlushWaitsForQueuedEvents(t *testing.T) {
+	p := &recordingPublisher{}
+	bus := New(p, nil, Options{})
+	bus.Emit("session.created", "s1", "", nil, time.Time{})
+	bus.Emit("session.deleted", "s1", "", nil, time.Time{})
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if err := bus.Flush(short); err == nil {
+		t.Fatal("Flush() without Run succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Run(ctx)
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := bus.Flush(flushCtx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if events := p.published(); len(events) != 2 {
+		t.Errorf("published %d events after Flush, want 2", len(events))
+	}
+}
@@ -0,0 +1,124 @@
+// Package eventbus publishes machine-readable session lifecycle events to a
+// message broker (NATS, Kafka through a REST Proxy, Google Cloud Pub/Sub), so
+// that platform automation can react to sessions, relayed messages,
+// capability approvals and policy violations without polling the proxy API.
+//
+// Every event is a JSON envelope carrying a schema version:
+//
+//	{
+//	  "schema_version": "1",
+//	  "id": "4f6c...",
+//	  "type": "session.created",
+//	  "source": "agentapi-proxy",
+//	  "time": "2026-01-02T03:04:05Z",
+//	  "session": {"id": "...", "user_id": "...", "scope": "user"},
+//	  "message": "Session created",
+//	  "data": {}
+//	}
+//
+// Fields are only ever added within a schema version; a change that removes
+// or redefines a field bumps SchemaVersion.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// SchemaVersion is the version of the event envelope and of the data of
+// every event type
+const SchemaVersion = "1"
+
+// Publisher types of config.EventBusConfig
+const (
+	TypeNATS   = "nats"
+	TypeKafka  = "kafka"
+	TypePubSub = "pubsub"
+	TypeStdout = "stdout"
+)
+
+// Event types that are not session timeline events. Timeline events are
+// published as "session.<timeline type>" (session.created,
+// session.provision_failed, ...), except message-sent, which is
+// message.relayed.
+const (
+	EventSessionStatusChanged = "session.status_changed"
+	EventMessageRelayed       = "message.relayed"
+	EventApprovalGranted      = "approval.granted"
+	EventApprovalDenied       = "approval.denied"
+	EventPolicyViolation      = "policy.violation"
+)
+
+// Event is the envelope of every published event
+type Event struct {
+	SchemaVersion string         `json:"schema_version"`
+	ID            string         `json:"id"`
+	Type          string         `json:"type"`
+	Source        string         `json:"source"`
+	Time          time.Time      `json:"time"`
+	Session       *EventSession  `json:"session,omitempty"`
+	Message       string         `json:"message,omitempty"`
+	Data          map[string]any `json:"data,omitempty"`
+}
+
+// SessionID returns the ID of the session the event is about, or ""
+func (e *Event) SessionID() string {
+	if e.Session == nil {
+		return ""
+	}
+	return e.Session.ID
+}
+
+// EventSession describes the session an event is about
+type EventSession struct {
+	ID     string            `json:"id"`
+	UserID string            `json:"user_id,omitempty"`
+	Scope  string            `json:"scope,omitempty"`
+	TeamID string            `json:"team_id,omitempty"`
+	Status string            `json:"status,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+// sessionEventType returns the event type of a session timeline event
+func sessionEventType(t entities.SessionEventType) string {
+	if t == entities.SessionEventMessageSent {
+		return EventMessageRelayed
+	}
+	return "session." + strings.ReplaceAll(string(t), "-", "_")
+}
+
+// Publisher sends an encoded event to a broker. Publish is not called
+// concurrently.
+type Publisher interface {
+	Publish(ctx context.Context, event Event, body []byte) error
+	Close() error
+}
+
+// NewPublisher creates the publisher configured by cfg. It does not connect
+// to the broker.
+func NewPublisher(cfg config.EventBusConfig) (Publisher, error) {
+	topic := cfg.Topic
+	if topic == "" {
+		topic = "agentapi.events"
+	}
+	switch cfg.Type {
+	case TypeNATS:
+		return NewNATSPublisher(cfg.URL, topic, cfg.Token)
+	case TypeKafka:
+		return NewKafkaPublisher(cfg.URL, topic, cfg.Headers)
+	case TypePubSub:
+		return NewPubSubPublisher(cfg.URL, topic, cfg.Headers)
+	case TypeStdout:
+		return NewWriterPublisher(os.Stdout), nil
+	case "":
+		return nil, fmt.Errorf("event_bus.type is empty (supported: nats, kafka, pubsub, stdout)")
+	default:
+		return nil, fmt.Errorf("event_bus.type %q is unknown (supported: nats, kafka, pubsub, stdout)", cfg.Type)
+	}
+}
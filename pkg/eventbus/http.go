@@ -0,0 +1,232 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPubSubURL is the Pub/Sub API used when event_bus.url is not set
+const defaultPubSubURL = "https://pubsub.googleapis.com"
+
+// pubSubTokenURL is where the token of the service account of the node or
+// workload is read on Google Cloud
+var pubSubTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// postJSON POSTs body and returns the response body of a 2xx response
+func postJSON(ctx context.Context, client *http.Client, target, contentType string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned %d: %s", target, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// KafkaPublisher publishes events to a Kafka topic through the v2 API of a
+// Confluent REST Proxy. Records are keyed by session ID so that the events
+// of a session stay in order within a partition.
+type KafkaPublisher struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewKafkaPublisher creates a KafkaPublisher for the REST Proxy at baseURL
+func NewKafkaPublisher(baseURL, topic string, headers map[string]string) (*KafkaPublisher, error) {
+	if u, err := url.Parse(baseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("event_bus.url %q must be the http(s) URL of a Kafka REST Proxy", baseURL)
+	}
+	return &KafkaPublisher{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		headers:  headers,
+		client:   &http.Client{},
+	}, nil
+}
+
+// Publish produces one record with the event as its value
+func (p *KafkaPublisher) Publish(ctx context.Context, event Event, body []byte) error {
+	record := map[string]any{"value": json.RawMessage(body)}
+	if id := event.SessionID(); id != "" {
+		record["key"] = id
+	}
+	payload, err := json.Marshal(map[string]any{"records": []any{record}})
+	if err != nil {
+		return err
+	}
+	respBody, err := postJSON(ctx, p.client, p.endpoint, "application/vnd.kafka.json.v2+json", p.headers, payload)
+	if err != nil {
+		return err
+	}
+	// A record that was not produced is reported in its offset entry
+	var resp struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if json.Unmarshal(respBody, &resp) == nil {
+		for _, o := range resp.Offsets {
+			if o.ErrorCode != nil || o.Error != "" {
+				return fmt.Errorf("kafka rejected the record: %s", o.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Close does nothing; requests do not keep state
+func (p *KafkaPublisher) Close() error { return nil }
+
+// PubSubPublisher publishes events to a Google Cloud Pub/Sub topic with the
+// REST API. The event type, schema version and session ID are also set as
+// message attributes for subscription filters. Without an Authorization
+// header, requests use the token of the GCE metadata server (Workload
+// Identity on GKE).
+type PubSubPublisher struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewPubSubPublisher creates a PubSubPublisher for topic, given as
+// projects/<project>/topics/<topic>
+func NewPubSubPublisher(baseURL, topic string, headers map[string]string) (*PubSubPublisher, error) {
+	if baseURL == "" {
+		baseURL = defaultPubSubURL
+	}
+	if u, err := url.Parse(baseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("event_bus.url %q must be an http(s) URL", baseURL)
+	}
+	if parts := strings.Split(topic, "/"); len(parts) != 4 || parts[0] != "projects" || parts[2] != "topics" || parts[1] == "" || parts[3] == "" {
+		return nil, fmt.Errorf("event_bus.topic %q must be projects/<project>/topics/<topic> for pubsub", topic)
+	}
+	return &PubSubPublisher{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/v1/" + topic + ":publish",
+		headers:  headers,
+		client:   &http.Client{},
+	}, nil
+}
+
+// Publish publishes one message with the event as its data
+func (p *PubSubPublisher) Publish(ctx context.Context, event Event, body []byte) error {
+	attributes := map[string]string{
+		"type":           event.Type,
+		"schema_version": event.SchemaVersion,
+	}
+	if id := event.SessionID(); id != "" {
+		attributes["session_id"] = id
+	}
+	payload, err := json.Marshal(map[string]any{"messages": []any{map[string]any{
+		"data":       base64.StdEncoding.EncodeToString(body),
+		"attributes": attributes,
+	}}})
+	if err != nil {
+		return err
+	}
+	headers := p.headers
+	if !hasHeader(headers, "Authorization") {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return fmt.Errorf("get Pub/Sub access token: %w", err)
+		}
+		headers = map[string]string{"Authorization": "Bearer " + token}
+		for k, v := range p.headers {
+			headers[k] = v
+		}
+	}
+	_, err = postJSON(ctx, p.client, p.endpoint, "application/json", headers, payload)
+	return err
+}
+
+// accessToken returns the cached metadata server token, refreshed a minute
+// before it expires
+func (p *PubSubPublisher) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pubSubTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned %d", resp.StatusCode)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	p.token = token.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
+
+// Close does nothing; requests do not keep state
+func (p *PubSubPublisher) Close() error { return nil }
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriterPublisher writes events as JSON Lines, e.g. to stdout for a log
+// shipper
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterPublisher creates a WriterPublisher
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// Publish writes the event on its own line
+func (p *WriterPublisher) Publish(_ context.Context, _ Event, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(append(append([]byte{}, body...), '\n'))
+	return err
+}
+
+// Close does nothing
+func (p *WriterPublisher) Close() error { return nil }
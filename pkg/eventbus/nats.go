@@ -0,0 +1,196 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsDialTimeout bounds connecting to the NATS server
+const natsDialTimeout = 10 * time.Second
+
+// NATSPublisher publishes events to <subject prefix>.<event type> with the
+// core NATS protocol. Each publish is followed by a PING, so that Publish only
+// returns once the server has processed the message. The connection is
+// opened on first use and again after an error.
+type NATSPublisher struct {
+	addr    string
+	tls     bool
+	prefix  string
+	connect map[string]any
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATSPublisher creates a NATSPublisher for a nats:// or tls:// URL.
+// Credentials are taken from the user info of the URL, or token.
+func NewNATSPublisher(rawURL, subjectPrefix, token string) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "nats" && u.Scheme != "tls") {
+		return nil, fmt.Errorf("event_bus.url %q must be a nats:// or tls:// URL", rawURL)
+	}
+	if strings.ContainsAny(subjectPrefix, " \t\r\n") {
+		return nil, fmt.Errorf("event_bus.topic %q is not a valid NATS subject", subjectPrefix)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	connect := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "agentapi-proxy",
+		"lang":     "go",
+		"version":  "1",
+		"protocol": 0,
+	}
+	if u.User != nil {
+		connect["user"] = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			connect["pass"] = password
+		}
+	}
+	if token != "" {
+		connect["auth_token"] = token
+	}
+	return &NATSPublisher{addr: addr, tls: u.Scheme == "tls", prefix: subjectPrefix, connect: connect}, nil
+}
+
+// Publish sends event to <prefix>.<event type>
+func (p *NATSPublisher) Publish(ctx context.Context, event Event, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		if err := p.dial(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(ctx, p.prefix+"."+event.Type, body); err != nil {
+		p.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) dial(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: natsDialTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if p.tls {
+		host, _, _ := net.SplitHostPort(p.addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", p.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", p.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to NATS %s: %w", p.addr, err)
+	}
+	p.conn = conn
+	p.r = bufio.NewReader(conn)
+	p.setDeadline(ctx)
+
+	// The server greets with INFO before accepting CONNECT
+	line, err := p.readLine()
+	if err != nil {
+		p.closeLocked()
+		return fmt.Errorf("read NATS INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		p.closeLocked()
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	connect, err := json.Marshal(p.connect)
+	if err != nil {
+		p.closeLocked()
+		return err
+	}
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		p.closeLocked()
+		return fmt.Errorf("send NATS CONNECT: %w", err)
+	}
+	if err := p.awaitPong(); err != nil {
+		p.closeLocked()
+		return fmt.Errorf("NATS CONNECT: %w", err)
+	}
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject string, body []byte) error {
+	p.setDeadline(ctx)
+	w := bufio.NewWriter(p.conn)
+	fmt.Fprintf(w, "PUB %s %d\r\n", subject, len(body))
+	_, _ = w.Write(body)
+	_, _ = w.WriteString("\r\nPING\r\n")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("publish to NATS: %w", err)
+	}
+	if err := p.awaitPong(); err != nil {
+		return fmt.Errorf("publish to NATS: %w", err)
+	}
+	return nil
+}
+
+// awaitPong reads until the PONG answering our PING, answering the PINGs of
+// the server on the way
+func (p *NATSPublisher) awaitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *NATSPublisher) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsDialTimeout)
+	}
+	_ = p.conn.SetDeadline(deadline)
+}
+
+func (p *NATSPublisher) closeLocked() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+		p.conn = nil
+		p.r = nil
+	}
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeLocked()
+	return nil
+}
@@ -0,0 +1,254 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// fakeNATSServer accepts one connection at a time and records the CONNECT
+// options and published messages
+type fakeNATSServer struct {
+	listener net.Listener
+	connects chan map[string]any
+	messages chan [2]string // subject, payload
+}
+
+func newFakeNATSServer(t *testing.T) *fakeNATSServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeNATSServer{listener: l, connects: make(chan map[string]any, 4), messages: make(chan [2]string, 16)}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	_, _ = conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			var opts map[string]any
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts)
+			s.connects <- opts
+		case line == "PING":
+			// Also exercise answering a server PING before our PONG
+			_, _ = conn.Write([]byte("PING\r\nPONG\r\n"))
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var size int
+			if _, err := fmt.Sscanf(line, "PUB %s %d", &subject, &size); err != nil {
+				_, _ = conn.Write([]byte("-ERR 'Unknown Protocol Operation'\r\n"))
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.messages <- [2]string{subject, string(payload[:size])}
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	srv := newFakeNATSServer(t)
+	p, err := NewNATSPublisher("nats://bot:secret@"+srv.listener.Addr().String(), "agentapi.events", "")
+	if err != nil {
+		t.Fatalf("NewNATSPublisher() error = %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+		if err := p.Publish(ctx, Event{Type: "session.created"}, []byte(body)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	if opts := <-srv.connects; opts["user"] != "bot" || opts["pass"] != "secret" || opts["verbose"] != false {
+		t.Errorf("CONNECT options = %v", opts)
+	}
+	for _, want := range []string{`{"n":1}`, `{"n":2}`} {
+		msg := <-srv.messages
+		if msg[0] != "agentapi.events.session.created" || msg[1] != want {
+			t.Errorf("message = %v, want %s on agentapi.events.session.created", msg, want)
+		}
+	}
+	if len(srv.connects) != 0 {
+		t.Errorf("publisher reconnected for the second event")
+	}
+}
+
+func TestNATSPublisherReconnectsAfterError(t *testing.T) {
+	srv := newFakeNATSServer(t)
+	p, err := NewNATSPublisher("nats://"+srv.listener.Addr().String(), "events", "token-1")
+	if err != nil {
+		t.Fatalf("NewNATSPublisher() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := p.Publish(ctx, Event{Type: "a"}, []byte("1")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if opts := <-srv.connects; opts["auth_token"] != "token-1" {
+		t.Errorf("CONNECT options = %v", opts)
+	}
+
+	// A subject with a space is rejected by the server and drops the connection
+	if err := p.Publish(ctx, Event{Type: "bad subject"}, []byte("2")); err == nil {
+		t.Fatal("Publish() with an invalid subject succeeded")
+	}
+	if err := p.Publish(ctx, Event{Type: "b"}, []byte("3")); err != nil {
+		t.Fatalf("Publish() after an error = %v", err)
+	}
+	<-srv.connects
+	<-srv.messages
+	if msg := <-srv.messages; msg[0] != "events.b" {
+		t.Errorf("message = %v, want events.b", msg)
+	}
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	var gotPath, gotType, gotAuth string
+	var got struct {
+		Records []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType, gotAuth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if got.Records[0].Key == "rejected" {
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"Kafka error"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":12}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := NewKafkaPublisher(srv.URL+"/", "agentapi.events", map[string]string{"Authorization": "Basic abc"})
+	if err != nil {
+		t.Fatalf("NewKafkaPublisher() error = %v", err)
+	}
+	event := Event{Type: "session.created", Session: &EventSession{ID: "s1"}}
+	if err := p.Publish(context.Background(), event, []byte(`{"type":"session.created"}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if gotPath != "/topics/agentapi.events" || gotType != "application/vnd.kafka.json.v2+json" || gotAuth != "Basic abc" {
+		t.Errorf("request = %s %s %s", gotPath, gotType, gotAuth)
+	}
+	if got.Records[0].Key != "s1" || string(got.Records[0].Value) != `{"type":"session.created"}` {
+		t.Errorf("records = %+v", got.Records)
+	}
+
+	event.Session.ID = "rejected"
+	if err := p.Publish(context.Background(), event, []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "Kafka error") {
+		t.Errorf("Publish() of a rejected record = %v", err)
+	}
+}
+
+func TestPubSubPublisherUsesMetadataToken(t *testing.T) {
+	tokenRequests := 0
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		tokenRequests++
+		_, _ = w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+	}))
+	defer metadata.Close()
+	old := pubSubTokenURL
+	pubSubTokenURL = metadata.URL
+	defer func() { pubSubTokenURL = old }()
+
+	var gotPath, gotAuth string
+	var got struct {
+		Messages []struct {
+			Data       string            `json:"data"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"messages"`
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer api.Close()
+
+	p, err := NewPubSubPublisher(api.URL, "projects/p1/topics/agentapi", nil)
+	if err != nil {
+		t.Fatalf("NewPubSubPublisher() error = %v", err)
+	}
+	event := Event{Type: "policy.violation", SchemaVersion: SchemaVersion, Session: &EventSession{ID: "s1"}}
+	for i := 0; i < 2; i++ {
+		if err := p.Publish(context.Background(), event, []byte(`{"ok":true}`)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if gotPath != "/v1/projects/p1/topics/agentapi:publish" || gotAuth != "Bearer ya29.token" {
+		t.Errorf("request = %s %s", gotPath, gotAuth)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("token requests = %d, want 1 (cached)", tokenRequests)
+	}
+	data, _ := base64.StdEncoding.DecodeString(got.Messages[0].Data)
+	attrs := got.Messages[0].Attributes
+	if string(data) != `{"ok":true}` || attrs["type"] != "policy.violation" || attrs["session_id"] != "s1" || attrs["schema_version"] != SchemaVersion {
+		t.Errorf("message = %s %v", data, attrs)
+	}
+}
+
+func TestNewPublisherValidatesConfig(t *testing.T) {
+	for _, cfg := range []config.EventBusConfig{
+		{Type: ""},
+		{Type: "sqs"},
+		{Type: "nats", URL: "http://nats:4222"},
+		{Type: "kafka", URL: "kafka:9092"},
+		{Type: "pubsub", Topic: "agentapi.events"},
+	} {
+		if _, err := NewPublisher(cfg); err == nil {
+			t.Errorf("NewPublisher(%+v) succeeded", cfg)
+		}
+	}
+	for _, cfg := range []config.EventBusConfig{
+		{Type: "nats", URL: "nats://nats:4222"},
+		{Type: "kafka", URL: "http://kafka-rest:8082"},
+		{Type: "pubsub", Topic: "projects/p1/topics/t1"},
+		{Type: "stdout"},
+	} {
+		if _, err := NewPublisher(cfg); err != nil {
+			t.Errorf("NewPublisher(%+v) error = %v", cfg, err)
+		}
+	}
+}
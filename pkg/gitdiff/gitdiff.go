@@ -0,0 +1,398 @@
+// Package gitdiff reports the uncommitted changes of a git working tree as
+// structured per-file diffs. The agent-provisioner mounts it under /changes
+// so that UIs can render what the agent changed without a terminal.
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBytes bounds the amount of diff output parsed per request.
+const DefaultMaxBytes = 4 << 20
+
+// Status is the kind of change made to a file.
+type Status string
+
+const (
+	StatusAdded     Status = "added"
+	StatusModified  Status = "modified"
+	StatusDeleted   Status = "deleted"
+	StatusRenamed   Status = "renamed"
+	StatusCopied    Status = "copied"
+	StatusUntracked Status = "untracked"
+)
+
+// LineType is the kind of a diff line.
+type LineType string
+
+const (
+	LineContext LineType = "context"
+	LineAdded   LineType = "added"
+	LineDeleted LineType = "deleted"
+)
+
+// Line is a single line of a hunk. OldLine and NewLine are 1-based and zero
+// when the line does not exist on that side.
+type Line struct {
+	Type    LineType `json:"type"`
+	Content string   `json:"content"`
+	OldLine int      `json:"old_line,omitempty"`
+	NewLine int      `json:"new_line,omitempty"`
+	// NoNewline is set when the line has no trailing newline.
+	NoNewline bool `json:"no_newline,omitempty"`
+}
+
+// Hunk is a contiguous block of changes.
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Header   string `json:"header,omitempty"`
+	Lines    []Line `json:"lines"`
+}
+
+// FileDiff is the diff of one file.
+type FileDiff struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Status    Status `json:"status"`
+	Binary    bool   `json:"binary"`
+	OldMode   string `json:"old_mode,omitempty"`
+	NewMode   string `json:"new_mode,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Hunks     []Hunk `json:"hunks"`
+}
+
+// Parse parses unified diff output of "git diff" produced with the default
+// "a/" and "b/" prefixes.
+func Parse(r io.Reader) ([]FileDiff, error) {
+	var (
+		files []FileDiff
+		file  *FileDiff
+		hunk  *Hunk
+		oldNo int
+		newNo int
+	)
+	flush := func() {
+		if file == nil {
+			return
+		}
+		if file.Hunks == nil {
+			file.Hunks = []Hunk{}
+		}
+		files = append(files, *file)
+		file, hunk = nil, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), DefaultMaxBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			oldPath, newPath := parseGitHeader(strings.TrimPrefix(line, "diff --git "))
+			file = &FileDiff{Path: newPath, OldPath: oldPath, Status: StatusModified}
+			continue
+		}
+		if file == nil {
+			continue
+		}
+
+		if hunk != nil {
+			switch {
+			case strings.HasPrefix(line, " ") || line == "":
+				oldNo++
+				newNo++
+				hunk.Lines = append(hunk.Lines, Line{Type: LineContext, Content: strings.TrimPrefix(line, " "), OldLine: oldNo, NewLine: newNo})
+				continue
+			case strings.HasPrefix(line, "+"):
+				newNo++
+				file.Additions++
+				hunk.Lines = append(hunk.Lines, Line{Type: LineAdded, Content: line[1:], NewLine: newNo})
+				continue
+			case strings.HasPrefix(line, "-"):
+				oldNo++
+				file.Deletions++
+				hunk.Lines = append(hunk.Lines, Line{Type: LineDeleted, Content: line[1:], OldLine: oldNo})
+				continue
+			case strings.HasPrefix(line, `\`):
+				if n := len(hunk.Lines); n > 0 {
+					hunk.Lines[n-1].NoNewline = true
+				}
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, h)
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldNo, newNo = h.OldStart-1, h.NewStart-1
+		case strings.HasPrefix(line, "new file mode "):
+			file.Status = StatusAdded
+			file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.Status = StatusDeleted
+			file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			file.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			file.NewMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = StatusRenamed
+			file.OldPath = unquote(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.Path = unquote(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			file.Status = StatusCopied
+			file.OldPath = unquote(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			file.Path = unquote(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			file.Binary = true
+		case strings.HasPrefix(line, "--- "):
+			if p := stripPrefix(unquote(strings.TrimPrefix(line, "--- ")), "a/"); p != "" {
+				file.OldPath = p
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if p := stripPrefix(unquote(strings.TrimPrefix(line, "+++ ")), "b/"); p != "" {
+				file.Path = p
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+	flush()
+
+	for i := range files {
+		f := &files[i]
+		if f.Status == StatusDeleted && f.Path == "" {
+			f.Path = f.OldPath
+		}
+		if f.Status != StatusRenamed && f.Status != StatusCopied {
+			if f.Path == "" {
+				f.Path = f.OldPath
+			}
+			f.OldPath = ""
+		}
+	}
+	return files, nil
+}
+
+// parseGitHeader extracts the old and new paths from the remainder of a
+// "diff --git" line. The paths are only a fallback: "---", "+++" and
+// rename/copy headers take precedence when present.
+func parseGitHeader(rest string) (string, string) {
+	if strings.HasPrefix(rest, `"`) {
+		oldQuoted, err := strconv.QuotedPrefix(rest)
+		if err == nil {
+			newPart := strings.TrimPrefix(rest[len(oldQuoted):], " ")
+			return stripPrefix(unquote(oldQuoted), "a/"), stripPrefix(unquote(newPart), "b/")
+		}
+	}
+	// Unquoted paths may contain spaces. For the common case where both sides
+	// name the same file the line is "a/<p> b/<p>", so split it in half.
+	if len(rest)%2 == 1 {
+		half := (len(rest) - 1) / 2
+		oldPart, newPart := rest[:half], rest[half+1:]
+		if strings.HasPrefix(oldPart, "a/") && strings.HasPrefix(newPart, "b/") && oldPart[2:] == newPart[2:] {
+			return oldPart[2:], newPart[2:]
+		}
+	}
+	if i := strings.Index(rest, " b/"); i >= 0 {
+		return stripPrefix(rest[:i], "a/"), rest[i+3:]
+	}
+	return "", ""
+}
+
+func parseHunkHeader(line string) (Hunk, error) {
+	// @@ -oldStart[,oldLines] +newStart[,newLines] @@ [section]
+	end := strings.Index(line[3:], " @@")
+	if end < 0 {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+	ranges := strings.Fields(line[3 : 3+end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+	h := Hunk{Header: strings.TrimSpace(line[3+end+3:]), Lines: []Line{}}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(ranges[0][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(ranges[1][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	return h, nil
+}
+
+func parseRange(s string) (int, int, error) {
+	start, count, found := strings.Cut(s, ",")
+	a, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !found {
+		return a, 1, nil
+	}
+	b, err := strconv.Atoi(count)
+	return a, b, err
+}
+
+// unquote decodes a C-style quoted git path; unquoted input is returned as is.
+func unquote(s string) string {
+	s = strings.TrimSuffix(s, "\t")
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+func stripPrefix(p, prefix string) string {
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// Mode selects which changes are reported.
+type Mode string
+
+const (
+	// ModeAll reports staged changes, unstaged changes and untracked files.
+	ModeAll Mode = ""
+	// ModeStaged reports changes staged in the index.
+	ModeStaged Mode = "staged"
+	// ModeUnstaged reports working tree changes not yet staged, and untracked files.
+	ModeUnstaged Mode = "unstaged"
+)
+
+// Changes is the response body of GET /changes.
+type Changes struct {
+	Staged    []FileDiff `json:"staged,omitempty"`
+	Unstaged  []FileDiff `json:"unstaged,omitempty"`
+	Untracked []FileDiff `json:"untracked,omitempty"`
+	// Truncated is set when diff output exceeded the size limit and some
+	// files or hunks were dropped.
+	Truncated bool `json:"truncated"`
+}
+
+// Repo is a git working tree.
+type Repo struct {
+	Dir string
+	// MaxBytes bounds the diff output parsed per diff; DefaultMaxBytes if zero.
+	MaxBytes int
+}
+
+// ErrNotRepository is returned when Dir is not a git working tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Changes returns the uncommitted changes of the repository.
+func (r Repo) Changes(ctx context.Context, mode Mode) (*Changes, error) {
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); err != nil {
+		return nil, ErrNotRepository
+	}
+	if mode != ModeAll && mode != ModeStaged && mode != ModeUnstaged {
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
+	changes := &Changes{}
+	if mode == ModeAll || mode == ModeStaged {
+		files, truncated, err := r.diff(ctx, "--cached")
+		if err != nil {
+			return nil, err
+		}
+		changes.Staged = files
+		changes.Truncated = changes.Truncated || truncated
+	}
+	if mode == ModeAll || mode == ModeUnstaged {
+		files, truncated, err := r.diff(ctx)
+		if err != nil {
+			return nil, err
+		}
+		changes.Unstaged = files
+		changes.Truncated = changes.Truncated || truncated
+
+		untracked, err := r.untracked(ctx)
+		if err != nil {
+			return nil, err
+		}
+		changes.Untracked = untracked
+	}
+	return changes, nil
+}
+
+func (r Repo) diff(ctx context.Context, args ...string) ([]FileDiff, bool, error) {
+	// Pin options that user or system git config could otherwise change.
+	base := []string{
+		"-c", "core.quotePath=false",
+		"diff", "--no-color", "--no-ext-diff", "--no-textconv", "-M",
+		"--src-prefix=a/", "--dst-prefix=b/",
+	}
+	out, err := r.git(ctx, append(base, args...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	limit := r.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+	truncated := false
+	if len(out) > limit {
+		out = out[:limit]
+		// Drop the partial last line.
+		if i := bytes.LastIndexByte(out, '\n'); i >= 0 {
+			out = out[:i+1]
+		}
+		truncated = true
+	}
+	files, err := Parse(bytes.NewReader(out))
+	return files, truncated, err
+}
+
+func (r Repo) untracked(ctx context.Context) ([]FileDiff, error) {
+	out, err := r.git(ctx, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []FileDiff
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p == "" {
+			continue
+		}
+		files = append(files, FileDiff{Path: p, Status: StatusUntracked, Hunks: []Hunk{}})
+	}
+	return files, nil
+}
+
+func (r Repo) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
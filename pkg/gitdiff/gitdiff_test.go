@@ -0,0 +1,189 @@
+package gitdiff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 3b18e51..a0b1c2d 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@ package main
+ package main
+-func old() {}
++func new() {}
++func extra() {}
+ // end
+\ No newline at end of file
+diff --git a/docs/old name.md b/docs/new name.md
+similarity index 90%
+rename from docs/old name.md
+rename to docs/new name.md
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..e69de29
+Binary files /dev/null and b/logo.png differ
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 3b18e51..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+
+func TestParse(t *testing.T) {
+	files, err := Parse(strings.NewReader(sampleDiff))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %d: %+v", len(files), files)
+	}
+
+	mod := files[0]
+	if mod.Path != "main.go" || mod.Status != StatusModified || mod.OldPath != "" {
+		t.Errorf("unexpected modified file: %+v", mod)
+	}
+	if mod.Additions != 2 || mod.Deletions != 1 || len(mod.Hunks) != 1 {
+		t.Fatalf("unexpected counts: %+v", mod)
+	}
+	h := mod.Hunks[0]
+	if h.OldStart != 1 || h.OldLines != 3 || h.NewStart != 1 || h.NewLines != 4 || h.Header != "package main" {
+		t.Errorf("unexpected hunk header: %+v", h)
+	}
+	want := []Line{
+		{Type: LineContext, Content: "package main", OldLine: 1, NewLine: 1},
+		{Type: LineDeleted, Content: "func old() {}", OldLine: 2},
+		{Type: LineAdded, Content: "func new() {}", NewLine: 2},
+		{Type: LineAdded, Content: "func extra() {}", NewLine: 3},
+		{Type: LineContext, Content: "// end", OldLine: 3, NewLine: 4, NoNewline: true},
+	}
+	if len(h.Lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), h.Lines)
+	}
+	for i := range want {
+		if h.Lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, h.Lines[i], want[i])
+		}
+	}
+
+	if r := files[1]; r.Status != StatusRenamed || r.OldPath != "docs/old name.md" || r.Path != "docs/new name.md" {
+		t.Errorf("unexpected rename: %+v", r)
+	}
+	if b := files[2]; b.Status != StatusAdded || !b.Binary || b.Path != "logo.png" || b.NewMode != "100644" {
+		t.Errorf("unexpected binary file: %+v", b)
+	}
+	if d := files[3]; d.Status != StatusDeleted || d.Path != "gone.txt" || d.Deletions != 1 {
+		t.Errorf("unexpected deleted file: %+v", d)
+	}
+}
+
+func TestParseGitHeader(t *testing.T) {
+	tests := []struct {
+		rest, oldPath, newPath string
+	}{
+		{"a/file.go b/file.go", "file.go", "file.go"},
+		{"a/with space b/with space", "with space", "with space"},
+		{"a/x.go b/y.go", "x.go", "y.go"},
+		{`"a/tab\there" "b/tab\there"`, "tab\there", "tab\there"},
+	}
+	for _, tt := range tests {
+		oldPath, newPath := parseGitHeader(tt.rest)
+		if oldPath != tt.oldPath || newPath != tt.newPath {
+			t.Errorf("parseGitHeader(%q) = (%q, %q), want (%q, %q)", tt.rest, oldPath, newPath, tt.oldPath, tt.newPath)
+		}
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestRepoChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, dir, "init", "-q")
+	write("a.txt", "one\ntwo\n")
+	write("b.bin", "\x00\x01\x02")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-qm", "init")
+
+	write("a.txt", "one\nTWO\n")
+	runGit(t, dir, "add", "a.txt")
+	write("b.bin", "\x00\x03")
+	write("new.txt", "hello\n")
+
+	changes, err := Repo{Dir: dir}.Changes(context.Background(), ModeAll)
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(changes.Staged) != 1 || changes.Staged[0].Path != "a.txt" || changes.Staged[0].Additions != 1 {
+		t.Errorf("unexpected staged changes: %+v", changes.Staged)
+	}
+	if len(changes.Unstaged) != 1 || changes.Unstaged[0].Path != "b.bin" || !changes.Unstaged[0].Binary {
+		t.Errorf("unexpected unstaged changes: %+v", changes.Unstaged)
+	}
+	if len(changes.Untracked) != 1 || changes.Untracked[0].Path != "new.txt" {
+		t.Errorf("unexpected untracked files: %+v", changes.Untracked)
+	}
+
+	staged, err := Repo{Dir: dir}.Changes(context.Background(), ModeStaged)
+	if err != nil {
+		t.Fatalf("Changes(staged) error = %v", err)
+	}
+	if len(staged.Unstaged) != 0 || len(staged.Untracked) != 0 || len(staged.Staged) != 1 {
+		t.Errorf("staged mode returned unexpected changes: %+v", staged)
+	}
+
+	rec := httptest.NewRecorder()
+	NewHandler(Repo{Dir: dir}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/changes?mode=unstaged", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handler status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var got Changes
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Staged) != 0 || len(got.Unstaged) != 1 {
+		t.Errorf("unexpected handler response: %+v", got)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/changes?mode=bogus", http.StatusBadRequest},
+		{"/changes", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		NewHandler(Repo{Dir: t.TempDir()}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+	}
+}
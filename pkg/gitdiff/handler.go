@@ -0,0 +1,37 @@
+package gitdiff
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// NewHandler serves GET requests with the changes of repo as JSON. The
+// optional "mode" query parameter is "staged" or "unstaged"; both are
+// reported when it is omitted.
+func NewHandler(repo Repo) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mode := Mode(r.URL.Query().Get("mode"))
+		if mode != ModeAll && mode != ModeStaged && mode != ModeUnstaged {
+			http.Error(rw, "mode must be staged or unstaged", http.StatusBadRequest)
+			return
+		}
+		changes, err := repo.Changes(r.Context(), mode)
+		if errors.Is(err, ErrNotRepository) {
+			http.Error(rw, "repository not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("[CHANGES] Failed to diff %s: %v", repo.Dir, err)
+			http.Error(rw, "failed to compute changes", http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(changes)
+	})
+}
@@ -162,19 +162,18 @@ func (c *InstallationCache) discoverInstallationID(ctx context.Context, appID in
 	}
 
 	// Set base URL if specified (for GitHub Enterprise)
-	if apiBase != "" && apiBase != "https://api.github.com" {
-		transport.BaseURL = apiBase
+	instance, err := NewInstance("", apiBase)
+	if err != nil {
+		return 0, err
+	}
+	if instance.IsEnterprise() {
+		transport.BaseURL = instance.APIURL
 	}
 
 	// Create GitHub client
-	var client *github.Client
-	if apiBase == "" || strings.Contains(apiBase, "https://api.github.com") {
-		client = github.NewClient(&http.Client{Transport: transport})
-	} else {
-		client, err = github.NewClient(&http.Client{Transport: transport}).WithEnterpriseURLs(apiBase, apiBase)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create GitHub Enterprise client: %w", err)
-		}
+	client, err := instance.NewClient(&http.Client{Transport: transport})
+	if err != nil {
+		return 0, err
 	}
 
 	// List installations for the app
@@ -199,15 +198,10 @@ func (c *InstallationCache) discoverInstallationID(ctx context.Context, appID in
 
 		// Create installation client to check repository access
 		installationTransport := ghinstallation.NewFromAppsTransport(transport, installationID)
-		var installationClient *github.Client
-		if apiBase == "" || strings.Contains(apiBase, "https://api.github.com") {
-			installationClient = github.NewClient(&http.Client{Transport: installationTransport})
-		} else {
-			installationClient, err = github.NewClient(&http.Client{Transport: installationTransport}).WithEnterpriseURLs(apiBase, apiBase)
-			if err != nil {
-				log.Printf("[INSTALLATION_CACHE] Warning: failed to create client for installation %d: %v", installationID, err)
-				continue
-			}
+		installationClient, err := instance.NewClient(&http.Client{Transport: installationTransport})
+		if err != nil {
+			log.Printf("[INSTALLATION_CACHE] Warning: failed to create client for installation %d: %v", installationID, err)
+			continue
 		}
 
 		// Try to access the repository with this installation
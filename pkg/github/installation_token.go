@@ -0,0 +1,253 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v57/github"
+)
+
+// AppCredentials identifies a GitHub App installation that tokens are minted for.
+type AppCredentials struct {
+	AppID int64
+	// InstallationID may be 0, in which case it is discovered from the repository.
+	InstallationID int64
+	PEM            []byte
+	// APIBase is the GitHub API URL. Empty means github.com.
+	APIBase string
+	// RestrictToRepository limits minted tokens to the requested repository.
+	RestrictToRepository bool
+}
+
+// InstallationToken is a short-lived GitHub App installation token.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MintFunc creates a new installation token for the installation that has
+// access to repoFullName.
+type MintFunc func(ctx context.Context, creds AppCredentials, repoFullName string) (*InstallationToken, error)
+
+// InstallationTokenSource mints GitHub App installation tokens and keeps them
+// fresh. Tokens are cached per installation and repository and minted again
+// once they come within RefreshBefore of their expiry, so callers always get a
+// token that stays valid for a while.
+type InstallationTokenSource struct {
+	// RefreshBefore is how long before expiry a cached token is replaced. Default: 15m.
+	RefreshBefore time.Duration
+	// IdleTTL drops cached tokens nobody asked for in this long. Default: 2h.
+	IdleTTL time.Duration
+
+	mint  MintFunc
+	cache *InstallationCache
+
+	mu     sync.Mutex
+	tokens map[string]*installationTokenEntry
+}
+
+type installationTokenEntry struct {
+	creds        AppCredentials
+	repoFullName string
+	token        *InstallationToken
+	lastUsed     time.Time
+}
+
+// NewInstallationTokenSource creates a token source. A nil mint uses the GitHub API.
+func NewInstallationTokenSource(mint MintFunc) *InstallationTokenSource {
+	s := &InstallationTokenSource{
+		RefreshBefore: 15 * time.Minute,
+		IdleTTL:       2 * time.Hour,
+		mint:          mint,
+		cache:         NewInstallationCache(),
+		tokens:        make(map[string]*installationTokenEntry),
+	}
+	if s.mint == nil {
+		s.mint = s.mintFromAPI
+	}
+	return s
+}
+
+// Token returns a cached installation token for the repository, minting a new
+// one when none is cached or the cached one is about to expire.
+func (s *InstallationTokenSource) Token(ctx context.Context, creds AppCredentials, repoFullName string) (*InstallationToken, error) {
+	key := tokenCacheKey(creds, repoFullName)
+
+	s.mu.Lock()
+	entry, ok := s.tokens[key]
+	if ok {
+		entry.lastUsed = time.Now()
+		if s.fresh(entry.token) {
+			token := *entry.token
+			s.mu.Unlock()
+			return &token, nil
+		}
+	}
+	s.mu.Unlock()
+
+	token, err := s.mint(ctx, creds, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.tokens[key] = &installationTokenEntry{
+		creds:        creds,
+		repoFullName: repoFullName,
+		token:        token,
+		lastUsed:     time.Now(),
+	}
+	s.mu.Unlock()
+
+	result := *token
+	return &result, nil
+}
+
+// Refresh mints new tokens for cached entries that are about to expire and
+// drops entries that have not been requested within IdleTTL.
+func (s *InstallationTokenSource) Refresh(ctx context.Context) {
+	now := time.Now()
+	var stale []*installationTokenEntry
+
+	s.mu.Lock()
+	for key, entry := range s.tokens {
+		if now.Sub(entry.lastUsed) > s.IdleTTL {
+			delete(s.tokens, key)
+			continue
+		}
+		if !s.fresh(entry.token) {
+			stale = append(stale, entry)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, entry := range stale {
+		token, err := s.mint(ctx, entry.creds, entry.repoFullName)
+		if err != nil {
+			log.Printf("[GITHUB_TOKEN] Failed to refresh installation token for %q: %v", entry.repoFullName, err)
+			continue
+		}
+		s.mu.Lock()
+		entry.token = token
+		s.mu.Unlock()
+	}
+}
+
+// Run calls Refresh every interval until ctx is cancelled.
+func (s *InstallationTokenSource) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Refresh(ctx)
+		}
+	}
+}
+
+func (s *InstallationTokenSource) fresh(token *InstallationToken) bool {
+	return token != nil && time.Until(token.ExpiresAt) > s.RefreshBefore
+}
+
+func tokenCacheKey(creds AppCredentials, repoFullName string) string {
+	return fmt.Sprintf("%s|%d|%d|%t|%s", creds.APIBase, creds.AppID, creds.InstallationID, creds.RestrictToRepository, strings.ToLower(repoFullName))
+}
+
+// mintFromAPI creates an installation token through the GitHub API. When the
+// installation ID is unknown it is discovered from the repository.
+func (s *InstallationTokenSource) mintFromAPI(ctx context.Context, creds AppCredentials, repoFullName string) (*InstallationToken, error) {
+	installationID := creds.InstallationID
+	if installationID == 0 {
+		if repoFullName == "" {
+			return nil, fmt.Errorf("installation ID is not configured and no repository is available for discovery")
+		}
+		id, err := s.cache.GetInstallationID(ctx, creds.AppID, creds.PEM, repoFullName, creds.APIBase)
+		if err != nil {
+			return nil, err
+		}
+		installationID = id
+	}
+
+	client, err := appClient(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts *github.InstallationTokenOptions
+	if parts := strings.Split(repoFullName, "/"); creds.RestrictToRepository && len(parts) == 2 && parts[1] != "" {
+		opts = &github.InstallationTokenOptions{Repositories: []string{strings.TrimSuffix(parts[1], ".git")}}
+	}
+	token, _, err := client.Apps.CreateInstallationToken(ctx, installationID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token: %w", err)
+	}
+	return &InstallationToken{
+		Token:     token.GetToken(),
+		ExpiresAt: token.GetExpiresAt().Time,
+	}, nil
+}
+
+// VerifyApp authenticates as the GitHub App and, when an installation ID is
+// configured, checks that the installation exists. It returns the App slug
+// and the account the installation belongs to.
+func VerifyApp(ctx context.Context, creds AppCredentials) (slug, account string, err error) {
+	client, err := appClient(creds)
+	if err != nil {
+		return "", "", err
+	}
+	app, _, err := client.Apps.Get(ctx, "")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to authenticate as GitHub App %d: %w", creds.AppID, err)
+	}
+	if creds.InstallationID == 0 {
+		return app.GetSlug(), "", nil
+	}
+	installation, _, err := client.Apps.GetInstallation(ctx, creds.InstallationID)
+	if err != nil {
+		return app.GetSlug(), "", fmt.Errorf("failed to get installation %d: %w", creds.InstallationID, err)
+	}
+	return app.GetSlug(), installation.GetAccount().GetLogin(), nil
+}
+
+// appClient returns a GitHub client authenticated as the App itself
+func appClient(creds AppCredentials) (*github.Client, error) {
+	transport, err := ghinstallation.NewAppsTransport(http.DefaultTransport, creds.AppID, creds.PEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub App transport: %w", err)
+	}
+	instance, err := NewInstance("", creds.APIBase)
+	if err != nil {
+		return nil, err
+	}
+	if instance.IsEnterprise() {
+		transport.BaseURL = instance.APIURL
+	}
+	return instance.NewClient(&http.Client{Transport: transport})
+}
+
+// Client returns a REST API client authenticated with an installation token
+// for the repository. The token is not refreshed, so the client is meant for
+// a few calls in a row.
+func (s *InstallationTokenSource) Client(ctx context.Context, creds AppCredentials, repoFullName string) (*github.Client, error) {
+	token, err := s.Token(ctx, creds, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := NewInstance("", creds.APIBase)
+	if err != nil {
+		return nil, err
+	}
+	client, err := instance.NewClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.WithAuthToken(token.Token), nil
+}
@@ -0,0 +1,76 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func countingMint(calls *int, lifetime time.Duration) MintFunc {
+	return func(ctx context.Context, creds AppCredentials, repoFullName string) (*InstallationToken, error) {
+		*calls++
+		return &InstallationToken{
+			Token:     fmt.Sprintf("token-%d", *calls),
+			ExpiresAt: time.Now().Add(lifetime),
+		}, nil
+	}
+}
+
+func TestInstallationTokenSource_CachesFreshTokens(t *testing.T) {
+	calls := 0
+	source := NewInstallationTokenSource(countingMint(&calls, time.Hour))
+	creds := AppCredentials{AppID: 1, InstallationID: 2}
+
+	first, err := source.Token(context.Background(), creds, "org/repo")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	second, err := source.Token(context.Background(), creds, "org/repo")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if calls != 1 || first.Token != second.Token {
+		t.Fatalf("expected cached token, got %d mints (%s, %s)", calls, first.Token, second.Token)
+	}
+
+	if _, err := source.Token(context.Background(), creds, "org/other"); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected a separate token per repository, got %d mints", calls)
+	}
+}
+
+func TestInstallationTokenSource_RefreshReplacesExpiringTokens(t *testing.T) {
+	calls := 0
+	source := NewInstallationTokenSource(countingMint(&calls, 10*time.Minute))
+	creds := AppCredentials{AppID: 1, InstallationID: 2}
+
+	if _, err := source.Token(context.Background(), creds, "org/repo"); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	source.Refresh(context.Background())
+	if calls != 2 {
+		t.Fatalf("expected Refresh to mint a replacement for a token expiring within RefreshBefore, got %d mints", calls)
+	}
+}
+
+func TestInstallationTokenSource_RefreshDropsIdleEntries(t *testing.T) {
+	calls := 0
+	source := NewInstallationTokenSource(countingMint(&calls, 10*time.Minute))
+	source.IdleTTL = 0
+	creds := AppCredentials{AppID: 1, InstallationID: 2}
+
+	if _, err := source.Token(context.Background(), creds, "org/repo"); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	source.Refresh(context.Background())
+	if calls != 1 {
+		t.Fatalf("expected idle entry to be dropped without minting, got %d mints", calls)
+	}
+	if len(source.tokens) != 0 {
+		t.Fatalf("expected idle entry to be removed, got %d entries", len(source.tokens))
+	}
+}
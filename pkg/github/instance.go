@@ -0,0 +1,147 @@
+package github
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// DefaultURL is the web URL of github.com
+	DefaultURL = "https://github.com"
+	// DefaultAPIURL is the REST API URL of github.com
+	DefaultAPIURL = "https://api.github.com"
+
+	enterpriseAPIPath = "/api/v3"
+)
+
+// Instance is a GitHub installation: github.com or a GitHub Enterprise
+// Server. Both URLs are normalized, without a trailing slash.
+type Instance struct {
+	// URL is the web URL, e.g. https://github.example.com
+	URL string
+	// APIURL is the REST API URL, e.g. https://github.example.com/api/v3
+	APIURL string
+}
+
+// DefaultInstance returns github.com
+func DefaultInstance() Instance {
+	return Instance{URL: DefaultURL, APIURL: DefaultAPIURL}
+}
+
+// NewInstance validates and normalizes the URLs of a GitHub instance. Either
+// may be empty: the API URL of a GitHub Enterprise Server defaults to
+// <url>/api/v3, and the web URL is derived from the API URL. Both empty means
+// github.com.
+func NewInstance(webURL, apiURL string) (Instance, error) {
+	webURL, apiURL = strings.TrimSpace(webURL), strings.TrimSpace(apiURL)
+	if webURL == "" && apiURL == "" {
+		return DefaultInstance(), nil
+	}
+
+	var web, api *url.URL
+	var err error
+	if webURL != "" {
+		if web, err = parseBaseURL(webURL); err != nil {
+			return Instance{}, fmt.Errorf("invalid GitHub URL %q: %w", webURL, err)
+		}
+		if web.Path != "" {
+			return Instance{}, fmt.Errorf("invalid GitHub URL %q: must not have a path", webURL)
+		}
+	}
+	if apiURL != "" {
+		if api, err = parseBaseURL(apiURL); err != nil {
+			return Instance{}, fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+		}
+		// A bare GitHub Enterprise Server host is accepted as its API URL
+		if api.Path == "" && api.String() != DefaultAPIURL {
+			api.Path = enterpriseAPIPath
+		}
+	}
+
+	switch {
+	case web == nil && api.String() == DefaultAPIURL:
+		return DefaultInstance(), nil
+	case web == nil:
+		web = &url.URL{Scheme: api.Scheme, Host: api.Host}
+	case api == nil && web.String() == DefaultURL:
+		return DefaultInstance(), nil
+	case api == nil:
+		api = &url.URL{Scheme: web.Scheme, Host: web.Host, Path: enterpriseAPIPath}
+	}
+	return Instance{URL: web.String(), APIURL: api.String()}, nil
+}
+
+// parseBaseURL parses an absolute http(s) URL without credentials, query or
+// fragment, and strips its trailing slash
+func parseBaseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("scheme must be https or http")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("host is missing")
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("must not have credentials, a query or a fragment")
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u, nil
+}
+
+// EnvInstance returns the instance of the GITHUB_URL and GITHUB_API
+// environment variables. Invalid values are logged and used as they are.
+func EnvInstance() Instance {
+	webURL, apiURL := os.Getenv("GITHUB_URL"), os.Getenv("GITHUB_API")
+	instance, err := NewInstance(webURL, apiURL)
+	if err != nil {
+		log.Printf("[GITHUB] Warning: %v", err)
+		instance = DefaultInstance()
+		if webURL != "" {
+			instance.URL = webURL
+		}
+		if apiURL != "" {
+			instance.APIURL = apiURL
+		}
+	}
+	return instance
+}
+
+// IsEnterprise reports whether the instance is a GitHub Enterprise Server
+func (i Instance) IsEnterprise() bool {
+	return i.APIURL != "" && i.APIURL != DefaultAPIURL
+}
+
+// Host returns the host (with port, if any) of the web URL, as used by gh
+// (GH_HOST) and in X-GitHub-Enterprise-Host
+func (i Instance) Host() string {
+	return ExtractHostname(i.URL)
+}
+
+// CloneURL returns the HTTPS clone URL of a repository ("owner/repo")
+func (i Instance) CloneURL(repoFullName string) string {
+	return fmt.Sprintf("%s/%s.git", i.URL, repoFullName)
+}
+
+// NewClient returns a REST API client for the instance
+func (i Instance) NewClient(httpClient *http.Client) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if !i.IsEnterprise() {
+		return client, nil
+	}
+	client, err := client.WithEnterpriseURLs(i.APIURL, i.URL+"/api/uploads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub Enterprise client for %s: %w", i.URL, err)
+	}
+	return client, nil
+}
@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// OrgTeam is a team of a GitHub organization
+type OrgTeam struct {
+	Slug string
+	Name string
+}
+
+// TeamMember is a member of a GitHub team. Role is "member" or "maintainer".
+type TeamMember struct {
+	Login string
+	Role  string
+}
+
+// CredentialsFunc returns the GitHub App credentials to act with. It is
+// called for every new installation token, so rotated credentials are picked
+// up.
+type CredentialsFunc func(ctx context.Context) (*AppCredentials, error)
+
+// AppTeamDirectory lists the teams of organizations and their members through
+// the GitHub App installed on each organization. The App needs the
+// organization "Members" read permission.
+type AppTeamDirectory struct {
+	credentials CredentialsFunc
+
+	mu      sync.Mutex
+	clients map[string]*orgClient // org -> installation client
+}
+
+type orgClient struct {
+	client    *github.Client
+	expiresAt time.Time
+}
+
+// NewAppTeamDirectory creates a new AppTeamDirectory
+func NewAppTeamDirectory(credentials CredentialsFunc) *AppTeamDirectory {
+	return &AppTeamDirectory{
+		credentials: credentials,
+		clients:     make(map[string]*orgClient),
+	}
+}
+
+// ListTeams returns all teams of org
+func (d *AppTeamDirectory) ListTeams(ctx context.Context, org string) ([]OrgTeam, error) {
+	client, err := d.client(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	var teams []OrgTeam
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Teams.ListTeams(ctx, org, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list teams of %s: %w", org, err)
+		}
+		for _, t := range page {
+			teams = append(teams, OrgTeam{Slug: t.GetSlug(), Name: t.GetName()})
+		}
+		if resp.NextPage == 0 {
+			return teams, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListTeamMembers returns the members of a team, including the members of
+// its child teams, with their role
+func (d *AppTeamDirectory) ListTeamMembers(ctx context.Context, org, teamSlug string) ([]TeamMember, error) {
+	client, err := d.client(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	maintainers, err := listTeamMembers(ctx, client, org, teamSlug, "maintainer")
+	if err != nil {
+		return nil, err
+	}
+	all, err := listTeamMembers(ctx, client, org, teamSlug, "all")
+	if err != nil {
+		return nil, err
+	}
+	isMaintainer := make(map[string]bool, len(maintainers))
+	for _, login := range maintainers {
+		isMaintainer[login] = true
+	}
+	members := make([]TeamMember, 0, len(all))
+	for _, login := range all {
+		role := "member"
+		if isMaintainer[login] {
+			role = "maintainer"
+		}
+		members = append(members, TeamMember{Login: login, Role: role})
+	}
+	return members, nil
+}
+
+// TeamMembership returns the active membership of login in a team, or nil
+// when login is not a member
+func (d *AppTeamDirectory) TeamMembership(ctx context.Context, org, teamSlug, login string) (*TeamMember, error) {
+	client, err := d.client(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	membership, _, err := client.Teams.GetTeamMembershipBySlug(ctx, org, teamSlug, login)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get membership of %s in %s/%s: %w", login, org, teamSlug, err)
+	}
+	if membership.GetState() != "active" {
+		return nil, nil
+	}
+	return &TeamMember{Login: login, Role: membership.GetRole()}, nil
+}
+
+func listTeamMembers(ctx context.Context, client *github.Client, org, teamSlug, role string) ([]string, error) {
+	var logins []string
+	opts := &github.TeamListTeamMembersOptions{Role: role, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := client.Teams.ListTeamMembersBySlug(ctx, org, teamSlug, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list members of %s/%s: %w", org, teamSlug, err)
+		}
+		for _, u := range page {
+			logins = append(logins, u.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			return logins, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// client returns a client authenticated as the App installation of org. It
+// is reused until its token comes within 5 minutes of expiry.
+func (d *AppTeamDirectory) client(ctx context.Context, org string) (*github.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok := d.clients[org]; ok && time.Until(c.expiresAt) > 5*time.Minute {
+		return c.client, nil
+	}
+
+	creds, err := d.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	app, err := appClient(*creds)
+	if err != nil {
+		return nil, err
+	}
+	// The configured installation may belong to another organization, so the
+	// installation of org is looked up first
+	installationID := creds.InstallationID
+	installation, _, err := app.Apps.FindOrganizationInstallation(ctx, org)
+	if err == nil {
+		installationID = installation.GetID()
+	} else if installationID == 0 {
+		return nil, fmt.Errorf("GitHub App is not installed on %s: %w", org, err)
+	}
+	token, _, err := app.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token for %s: %w", org, err)
+	}
+
+	instance, err := NewInstance("", creds.APIBase)
+	if err != nil {
+		return nil, err
+	}
+	client, err := instance.NewClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	client = client.WithAuthToken(token.GetToken())
+	d.clients[org] = &orgClient{client: client, expiresAt: token.GetExpiresAt().Time}
+	return client, nil
+}
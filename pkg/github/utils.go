@@ -144,20 +144,12 @@ func GetRepositoryFromGitRemote() string {
 
 // GetAPIBase returns the GitHub API base URL (supports enterprise)
 func GetAPIBase() string {
-	apiBase := os.Getenv("GITHUB_API")
-	if apiBase == "" {
-		apiBase = "https://api.github.com"
-	}
-	return apiBase
+	return EnvInstance().APIURL
 }
 
 // GetGitHubURL returns the GitHub URL (supports enterprise)
 func GetGitHubURL() string {
-	githubURL := os.Getenv("GITHUB_URL")
-	if githubURL == "" {
-		githubURL = "https://github.com"
-	}
-	return githubURL
+	return EnvInstance().URL
 }
 
 // ExtractHostname extracts hostname from GitHub URL
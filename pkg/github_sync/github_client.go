@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/google/go-github/v57/github"
+	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
 	"golang.org/x/oauth2"
 )
 
@@ -20,8 +20,8 @@ type GitHubSyncClient struct {
 
 // NewGitHubSyncClient creates a client authenticated with the given PAT.
 // repoFullName must be "owner/repo".
-// When the GITHUB_API environment variable is set, an Enterprise client is created
-// using that URL so that operators who configure GitHub Enterprise in Helm do not
+// When the GITHUB_URL or GITHUB_API environment variable is set, an Enterprise client is
+// created for that instance so that operators who configure GitHub Enterprise in Helm do not
 // need separate git-sync settings.
 func NewGitHubSyncClient(ctx context.Context, token, repoFullName string) (*GitHubSyncClient, error) {
 	parts := strings.SplitN(repoFullName, "/", 2)
@@ -32,18 +32,9 @@ func NewGitHubSyncClient(ctx context.Context, token, repoFullName string) (*GitH
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
 
-	var ghClient *github.Client
-	if enterpriseURL := os.Getenv("GITHUB_API"); enterpriseURL != "" {
-		if !strings.HasSuffix(enterpriseURL, "/") {
-			enterpriseURL += "/"
-		}
-		var err error
-		ghClient, err = github.NewClient(tc).WithEnterpriseURLs(enterpriseURL, enterpriseURL)
-		if err != nil {
-			return nil, fmt.Errorf("create enterprise GitHub client for %s: %w", enterpriseURL, err)
-		}
-	} else {
-		ghClient = github.NewClient(tc)
+	ghClient, err := github_pkg.EnvInstance().NewClient(tc)
+	if err != nil {
+		return nil, err
 	}
 
 	return &GitHubSyncClient{
@@ -0,0 +1,372 @@
+// Package githubreport reports the work of sessions created from GitHub
+// webhooks on their repository: a working branch and an in-progress Check
+// Run when the session starts, a pull request and the Check Run result once
+// the agent has finished.
+package githubreport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v57/github"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// Defaults of Config
+const (
+	DefaultBranchPrefix = "agentapi/"
+	DefaultCheckName    = "agentapi"
+)
+
+// maxTitleLength is the length pull request titles derived from the initial
+// message are cut to
+const maxTitleLength = 72
+
+// Config configures a Reporter
+type Config struct {
+	// BranchPrefix prefixes the session ID in working branch names
+	BranchPrefix string
+	// CheckName is the name of the Check Run
+	CheckName string
+	// DetailsURL links the Check Run to the session; {session_id} is
+	// replaced with the session ID
+	DetailsURL string
+}
+
+// Sessions gives a Reporter access to the sessions it reports on and to
+// GitHub
+type Sessions interface {
+	GetSession(id string) entities.Session
+	// GitHubRepositoryClient returns a client authenticated as the GitHub
+	// App installation of a repository, on the GitHub instance of teamID
+	GitHubRepositoryClient(ctx context.Context, teamID, repoFullName string) (*github.Client, error)
+	// SetGitHubReport stores the report of a session
+	SetGitHubReport(ctx context.Context, sessionID string, report *entities.GitHubReport) error
+}
+
+// reportedSession is a session that carries a GitHub report
+type reportedSession interface {
+	GitHubReport() *entities.GitHubReport
+}
+
+// finishEvents maps the timeline events that end the initial run of a
+// session to the conclusion of its Check Run
+var finishEvents = map[entities.SessionEventType]string{
+	entities.SessionEventCompleted:    "success",
+	entities.SessionEventJobCompleted: "success",
+	entities.SessionEventJobFailed:    "failure",
+	entities.SessionEventDeleted:      "cancelled",
+}
+
+// Reporter creates the working branch and Check Run of sessions and finishes
+// them when the session ends
+type Reporter struct {
+	cfg      Config
+	sessions Sessions
+
+	mu        sync.Mutex
+	finishing map[string]bool // sessions whose report is being finished
+}
+
+// NewReporter creates a new Reporter. Empty config values get their defaults.
+func NewReporter(cfg Config, sessions Sessions) *Reporter {
+	if cfg.BranchPrefix == "" {
+		cfg.BranchPrefix = DefaultBranchPrefix
+	}
+	if cfg.CheckName == "" {
+		cfg.CheckName = DefaultCheckName
+	}
+	return &Reporter{
+		cfg:       cfg,
+		sessions:  sessions,
+		finishing: make(map[string]bool),
+	}
+}
+
+// Start creates the working branch of a new session from the branch of its
+// repository (or the default branch) and an in-progress Check Run on it, and
+// points req at the branch. Sessions without a repository and sessions
+// working on a pull request are left alone.
+func (r *Reporter) Start(ctx context.Context, sessionID string, req *entities.RunServerRequest) error {
+	if req.RepoInfo == nil || req.RepoInfo.FullName == "" || req.RepoInfo.PR != "" {
+		return nil
+	}
+	repoFullName := req.RepoInfo.FullName
+	owner, repo, ok := splitRepository(repoFullName)
+	if !ok {
+		return fmt.Errorf("invalid repository %q", repoFullName)
+	}
+	client, err := r.sessions.GitHubRepositoryClient(ctx, teamID(req.Scope, req.TeamID), repoFullName)
+	if err != nil {
+		return err
+	}
+
+	base := req.RepoInfo.Branch
+	if base == "" {
+		repository, _, err := client.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return fmt.Errorf("failed to get repository %s: %w", repoFullName, err)
+		}
+		base = repository.GetDefaultBranch()
+	}
+	baseRef, _, err := client.Git.GetRef(ctx, owner, repo, "heads/"+base)
+	if err != nil {
+		return fmt.Errorf("failed to get branch %s of %s: %w", base, repoFullName, err)
+	}
+	sha := baseRef.GetObject().GetSHA()
+
+	branch := r.cfg.BranchPrefix + sessionID
+	_, _, err = client.Git.CreateRef(ctx, owner, repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: github.String(sha)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create branch %s in %s: %w", branch, repoFullName, err)
+	}
+
+	checkRun, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:       r.cfg.CheckName,
+		HeadSHA:    sha,
+		DetailsURL: r.detailsURL(sessionID),
+		ExternalID: github.String(sessionID),
+		Status:     github.String("in_progress"),
+		StartedAt:  &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   github.String("Agent working…"),
+			Summary: github.String(fmt.Sprintf("Session `%s` is working on branch `%s`.", sessionID, branch)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create check run in %s: %w", repoFullName, err)
+	}
+
+	req.RepoInfo.Branch = branch
+	req.GitHubReport = &entities.GitHubReport{
+		Repository: repoFullName,
+		BaseBranch: base,
+		Branch:     branch,
+		HeadSHA:    sha,
+		CheckRunID: checkRun.GetID(),
+		Title:      pullRequestTitle(req.InitialMessage, sessionID),
+		Status:     entities.GitHubReportInProgress,
+	}
+	log.Printf("[GITHUB_REPORT] Session %s works on %s:%s (check run %d)", sessionID, repoFullName, branch, checkRun.GetID())
+	return nil
+}
+
+// Recorder wraps a session event recorder so that sessions ending their
+// initial run finish their GitHub report
+func (r *Reporter) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &reportRecorder{EventRecorder: inner, reporter: r}
+}
+
+type reportRecorder struct {
+	portrepos.EventRecorder
+	reporter *Reporter
+}
+
+func (rr *reportRecorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	rr.reporter.HandleSessionEvent(event)
+	return rr.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent finishes the report of a session in the background when
+// it completes, fails or is deleted while its report is in progress. Other
+// events and sessions without a report are ignored.
+func (r *Reporter) HandleSessionEvent(event entities.SessionEvent) {
+	conclusion, ok := finishEvents[event.Type]
+	if !ok {
+		return
+	}
+	if event.Type == entities.SessionEventDeleted {
+		r.mu.Lock()
+		finishing := r.finishing[event.SessionID]
+		delete(r.finishing, event.SessionID)
+		r.mu.Unlock()
+		if finishing {
+			return
+		}
+	}
+	session := r.sessions.GetSession(event.SessionID)
+	if session == nil {
+		return
+	}
+	rs, ok := session.(reportedSession)
+	if !ok {
+		return
+	}
+	report := rs.GitHubReport()
+	if report == nil || report.Status != entities.GitHubReportInProgress {
+		return
+	}
+
+	r.mu.Lock()
+	if r.finishing[event.SessionID] {
+		r.mu.Unlock()
+		return
+	}
+	if event.Type != entities.SessionEventDeleted {
+		r.finishing[event.SessionID] = true
+	}
+	r.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := r.Finish(ctx, session, report, conclusion, event.Message); err != nil {
+			log.Printf("[GITHUB_REPORT] Failed to finish report of session %s: %v", event.SessionID, err)
+		}
+	}()
+}
+
+// Finish opens a pull request from the working branch of a session when it
+// has commits (a draft when the session failed) and completes its Check Run,
+// then stores the updated report. A successful session without changes
+// concludes neutral.
+func (r *Reporter) Finish(ctx context.Context, session entities.Session, report *entities.GitHubReport, conclusion, message string) error {
+	owner, repo, ok := splitRepository(report.Repository)
+	if !ok {
+		return fmt.Errorf("invalid repository %q", report.Repository)
+	}
+	client, err := r.sessions.GitHubRepositoryClient(ctx, teamID(session.Scope(), session.TeamID()), report.Repository)
+	if err != nil {
+		return err
+	}
+
+	if conclusion != "cancelled" {
+		if err := r.openPullRequest(ctx, client, owner, repo, session.ID(), report, conclusion != "success", message); err != nil {
+			report.Error = err.Error()
+			conclusion = "failure"
+		} else if report.PullRequestNumber == 0 && conclusion == "success" {
+			conclusion = "neutral"
+		}
+	}
+	report.Status = entities.GitHubReportCompleted
+	report.Conclusion = conclusion
+	now := time.Now()
+	report.CompletedAt = &now
+
+	_, _, err = client.Checks.UpdateCheckRun(ctx, owner, repo, report.CheckRunID, github.UpdateCheckRunOptions{
+		Name:        r.cfg.CheckName,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &github.Timestamp{Time: now},
+		Output:      checkRunOutput(session.ID(), report, message),
+	})
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to update check run: %v", err)
+	}
+
+	if storeErr := r.sessions.SetGitHubReport(ctx, session.ID(), report); storeErr != nil {
+		log.Printf("[GITHUB_REPORT] Failed to store report of session %s: %v", session.ID(), storeErr)
+	}
+	if report.Error != "" {
+		return fmt.Errorf("%s", report.Error)
+	}
+	log.Printf("[GITHUB_REPORT] Finished report of session %s: %s", session.ID(), conclusion)
+	return nil
+}
+
+// openPullRequest opens a pull request from the working branch when it is
+// ahead of the base branch, and records it and the new head in report
+func (r *Reporter) openPullRequest(ctx context.Context, client *github.Client, owner, repo, sessionID string, report *entities.GitHubReport, draft bool, message string) error {
+	comparison, _, err := client.Repositories.CompareCommits(ctx, owner, repo, report.BaseBranch, report.Branch, nil)
+	if err != nil {
+		return fmt.Errorf("failed to compare %s with %s: %w", report.Branch, report.BaseBranch, err)
+	}
+	if comparison.GetAheadBy() == 0 {
+		return nil
+	}
+	if commits := comparison.Commits; len(commits) > 0 {
+		report.HeadSHA = commits[len(commits)-1].GetSHA()
+	}
+
+	body := fmt.Sprintf("Opened by agentapi-proxy for session `%s`.", sessionID)
+	if message != "" {
+		body += "\n\n" + message
+	}
+	pr, _, err := client.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: github.String(report.Title),
+		Head:  github.String(report.Branch),
+		Base:  github.String(report.BaseBranch),
+		Body:  github.String(body),
+		Draft: github.Bool(draft),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open pull request from %s: %w", report.Branch, err)
+	}
+	report.PullRequestNumber = pr.GetNumber()
+	report.PullRequestURL = pr.GetHTMLURL()
+	return nil
+}
+
+func (r *Reporter) detailsURL(sessionID string) *string {
+	if r.cfg.DetailsURL == "" {
+		return nil
+	}
+	return github.String(strings.ReplaceAll(r.cfg.DetailsURL, "{session_id}", sessionID))
+}
+
+// checkRunOutput describes the result of a session in its Check Run
+func checkRunOutput(sessionID string, report *entities.GitHubReport, message string) *github.CheckRunOutput {
+	var title string
+	switch report.Conclusion {
+	case "success":
+		title = fmt.Sprintf("Opened pull request #%d", report.PullRequestNumber)
+	case "neutral":
+		title = "Agent finished without changes"
+	case "cancelled":
+		title = "Session was deleted"
+	default:
+		title = "Agent failed"
+	}
+	summary := fmt.Sprintf("Session `%s` worked on branch `%s`.", sessionID, report.Branch)
+	if report.PullRequestURL != "" {
+		summary += fmt.Sprintf("\n\nPull request: %s", report.PullRequestURL)
+	}
+	if report.Error != "" {
+		summary += "\n\n" + report.Error
+	} else if message != "" {
+		summary += "\n\n" + message
+	}
+	return &github.CheckRunOutput{Title: github.String(title), Summary: github.String(summary)}
+}
+
+// pullRequestTitle returns the first line of the initial message, cut to
+// maxTitleLength, or a title naming the session
+func pullRequestTitle(initialMessage, sessionID string) string {
+	for _, line := range strings.Split(initialMessage, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "# "))
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > maxTitleLength {
+			line = string([]rune(line)[:maxTitleLength-1]) + "…"
+		}
+		return line
+	}
+	return "Changes from agentapi session " + sessionID
+}
+
+// teamID returns the team whose GitHub instance a session uses
+func teamID(scope entities.ResourceScope, teamID string) string {
+	if scope == entities.ScopeTeam {
+		return teamID
+	}
+	return ""
+}
+
+func splitRepository(fullName string) (owner, repo string, ok bool) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
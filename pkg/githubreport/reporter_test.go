@@ -0,0 +1,244 @@
+package githubreport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// fakeGitHub records the requests of a Reporter and answers them like the
+// GitHub REST API
+type fakeGitHub struct {
+	mu       sync.Mutex
+	requests map[string]map[string]interface{} // "METHOD path" -> JSON body
+	aheadBy  int
+}
+
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var payload map[string]interface{}
+	_ = json.Unmarshal(body, &payload)
+	g.mu.Lock()
+	g.requests[r.Method+" "+r.URL.Path] = payload
+	g.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "GET /repos/acme/app":
+		fmt.Fprint(w, `{"default_branch":"main"}`)
+	case "GET /repos/acme/app/git/ref/heads/main":
+		fmt.Fprint(w, `{"ref":"refs/heads/main","object":{"sha":"base-sha"}}`)
+	case "POST /repos/acme/app/git/refs":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	case "POST /repos/acme/app/check-runs":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":42}`)
+	case "GET /repos/acme/app/compare/main...agentapi/s1":
+		fmt.Fprintf(w, `{"ahead_by":%d,"commits":[{"sha":"head-sha"}]}`, g.aheadBy)
+	case "POST /repos/acme/app/pulls":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number":7,"html_url":"https://github.com/acme/app/pull/7"}`)
+	case "PATCH /repos/acme/app/check-runs/42":
+		fmt.Fprint(w, `{"id":42}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	}
+}
+
+func (g *fakeGitHub) request(key string) (map[string]interface{}, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	payload, ok := g.requests[key]
+	return payload, ok
+}
+
+type fakeSession struct {
+	entities.Session
+	id     string
+	report *entities.GitHubReport
+}
+
+func (s *fakeSession) ID() string                    { return s.id }
+func (s *fakeSession) Scope() entities.ResourceScope { return entities.ScopeUser }
+func (s *fakeSession) TeamID() string                { return "" }
+func (s *fakeSession) GitHubReport() *entities.GitHubReport {
+	if s.report == nil {
+		return nil
+	}
+	report := *s.report
+	return &report
+}
+
+type fakeSessions struct {
+	client   *github.Client
+	mu       sync.Mutex
+	sessions map[string]*fakeSession
+	stored   chan *entities.GitHubReport
+}
+
+func (f *fakeSessions) GetSession(id string) entities.Session {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[id]; ok {
+		return s
+	}
+	return nil
+}
+
+func (f *fakeSessions) GitHubRepositoryClient(context.Context, string, string) (*github.Client, error) {
+	return f.client, nil
+}
+
+func (f *fakeSessions) SetGitHubReport(_ context.Context, sessionID string, report *entities.GitHubReport) error {
+	f.mu.Lock()
+	f.sessions[sessionID].report = report
+	f.mu.Unlock()
+	f.stored <- report
+	return nil
+}
+
+func newTestReporter(t *testing.T, gh *fakeGitHub) (*Reporter, *fakeSessions) {
+	t.Helper()
+	srv := httptest.NewServer(gh)
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	sessions := &fakeSessions{client: client, sessions: map[string]*fakeSession{}, stored: make(chan *entities.GitHubReport, 1)}
+	return NewReporter(Config{DetailsURL: "https://proxy.example.com/sessions/{session_id}"}, sessions), sessions
+}
+
+func waitForReport(t *testing.T, sessions *fakeSessions) *entities.GitHubReport {
+	t.Helper()
+	select {
+	case report := <-sessions.stored:
+		return report
+	case <-time.After(5 * time.Second):
+		t.Fatal("the report was not stored")
+		return nil
+	}
+}
+
+func TestReporterOpensPullRequest(t *testing.T) {
+	gh := &fakeGitHub{requests: map[string]map[string]interface{}{}, aheadBy: 2}
+	reporter, sessions := newTestReporter(t, gh)
+
+	req := &entities.RunServerRequest{
+		InitialMessage: "## Fix the login redirect\n\nSee issue #12",
+		RepoInfo:       &entities.RepositoryInfo{FullName: "acme/app", CloneDir: "s1"},
+	}
+	if err := reporter.Start(context.Background(), "s1", req); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if req.RepoInfo.Branch != "agentapi/s1" {
+		t.Errorf("branch = %q, expected agentapi/s1", req.RepoInfo.Branch)
+	}
+	want := &entities.GitHubReport{
+		Repository: "acme/app",
+		BaseBranch: "main",
+		Branch:     "agentapi/s1",
+		HeadSHA:    "base-sha",
+		CheckRunID: 42,
+		Title:      "Fix the login redirect",
+		Status:     entities.GitHubReportInProgress,
+	}
+	if *req.GitHubReport != *want {
+		t.Errorf("report = %+v, expected %+v", req.GitHubReport, want)
+	}
+	ref, _ := gh.request("POST /repos/acme/app/git/refs")
+	if ref["ref"] != "refs/heads/agentapi/s1" {
+		t.Errorf("created ref = %v", ref)
+	}
+	check, _ := gh.request("POST /repos/acme/app/check-runs")
+	if check["status"] != "in_progress" || check["head_sha"] != "base-sha" || check["details_url"] != "https://proxy.example.com/sessions/s1" {
+		t.Errorf("created check run = %v", check)
+	}
+
+	sessions.sessions["s1"] = &fakeSession{id: "s1", report: req.GitHubReport}
+	reporter.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventMessageSent})
+	reporter.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventCompleted, Message: "Agent finished"})
+	report := waitForReport(t, sessions)
+
+	if report.Status != entities.GitHubReportCompleted || report.Conclusion != "success" {
+		t.Errorf("status = %s, conclusion = %s", report.Status, report.Conclusion)
+	}
+	if report.PullRequestNumber != 7 || report.PullRequestURL != "https://github.com/acme/app/pull/7" || report.HeadSHA != "head-sha" {
+		t.Errorf("report = %+v", report)
+	}
+	pr, _ := gh.request("POST /repos/acme/app/pulls")
+	if pr["head"] != "agentapi/s1" || pr["base"] != "main" || pr["draft"] != false {
+		t.Errorf("pull request = %v", pr)
+	}
+	update, _ := gh.request("PATCH /repos/acme/app/check-runs/42")
+	if update["status"] != "completed" || update["conclusion"] != "success" {
+		t.Errorf("check run update = %v", update)
+	}
+
+	// A finished report is not finished again
+	reporter.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventDeleted})
+	select {
+	case <-sessions.stored:
+		t.Error("deleting a finished session updated its report")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReporterWithoutChanges(t *testing.T) {
+	gh := &fakeGitHub{requests: map[string]map[string]interface{}{}}
+	reporter, sessions := newTestReporter(t, gh)
+	sessions.sessions["s1"] = &fakeSession{id: "s1", report: &entities.GitHubReport{
+		Repository: "acme/app",
+		BaseBranch: "main",
+		Branch:     "agentapi/s1",
+		CheckRunID: 42,
+		Status:     entities.GitHubReportInProgress,
+	}}
+
+	reporter.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventJobCompleted})
+	report := waitForReport(t, sessions)
+	if report.Conclusion != "neutral" || report.PullRequestNumber != 0 {
+		t.Errorf("report = %+v, expected a neutral conclusion without pull request", report)
+	}
+	if _, ok := gh.request("POST /repos/acme/app/pulls"); ok {
+		t.Error("a pull request was opened for a branch without commits")
+	}
+}
+
+func TestReporterSkipsPullRequestSessions(t *testing.T) {
+	gh := &fakeGitHub{requests: map[string]map[string]interface{}{}}
+	reporter, _ := newTestReporter(t, gh)
+
+	req := &entities.RunServerRequest{RepoInfo: &entities.RepositoryInfo{FullName: "acme/app", Branch: "feature", PR: "3"}}
+	if err := reporter.Start(context.Background(), "s1", req); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if req.GitHubReport != nil || req.RepoInfo.Branch != "feature" || len(gh.requests) != 0 {
+		t.Errorf("a pull request session was reported: %+v", req.GitHubReport)
+	}
+}
+
+func TestPullRequestTitle(t *testing.T) {
+	long := "Refactor the session manager so that every restore path shares one helper function"
+	tests := map[string]string{
+		"":                       "Changes from agentapi session s1",
+		"\n  \n# Add retries\nx": "Add retries",
+		long:                     long[:71] + "…",
+	}
+	for message, want := range tests {
+		if got := pullRequestTitle(message, "s1"); got != want {
+			t.Errorf("pullRequestTitle(%q) = %q, expected %q", message, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package i18n provides the message catalog used to localize user-facing
+// texts such as API error messages and notifications.
+//
+// Messages are looked up by ID. Notification texts use dotted IDs
+// (e.g. "notification.message_received.title"); API error messages use the
+// English message itself as the ID, so only translations need catalog entries
+// and untranslated messages are returned unchanged.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale is a supported language code (ISO 639-1).
+type Locale string
+
+const (
+	English  Locale = "en"
+	Japanese Locale = "ja"
+
+	// DefaultLocale is used when no locale is selected or a message has no
+	// translation in the selected locale.
+	DefaultLocale = English
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalog maps each supported locale to its messages.
+var catalog = mustLoadCatalog()
+
+func mustLoadCatalog() map[Locale]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read locales: %v", err))
+	}
+	c := make(map[Locale]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", entry.Name(), err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", entry.Name(), err))
+		}
+		c[Locale(strings.TrimSuffix(entry.Name(), ".json"))] = messages
+	}
+	return c
+}
+
+// Supported returns the supported locales in sorted order.
+func Supported() []Locale {
+	locales := make([]Locale, 0, len(catalog))
+	for l := range catalog {
+		locales = append(locales, l)
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+	return locales
+}
+
+// Parse normalizes a language tag such as "ja", "ja-JP" or "EN_us" to a
+// supported locale. It reports false if the language is not supported.
+func Parse(tag string) (Locale, bool) {
+	lang := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalog[Locale(lang)]; !ok || lang == "" {
+		return "", false
+	}
+	return Locale(lang), true
+}
+
+// FromAcceptLanguage returns the supported locale with the highest quality
+// value in an Accept-Language header. It reports false if none is supported.
+func FromAcceptLanguage(header string) (Locale, bool) {
+	var best Locale
+	bestQ := 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		locale, ok := Parse(tag)
+		if ok && q > bestQ {
+			best, bestQ = locale, q
+		}
+	}
+	return best, best != ""
+}
+
+// T returns the message with the given ID in locale, falling back to
+// DefaultLocale and then to the ID itself. When args are given the message
+// is used as a fmt format string.
+func T(locale Locale, id string, args ...any) string {
+	msg, ok := catalog[locale][id]
+	if !ok {
+		msg, ok = catalog[DefaultLocale][id]
+	}
+	if !ok {
+		msg = id
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
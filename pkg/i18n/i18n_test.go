@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Locale
+		ok   bool
+	}{
+		{"ja", Japanese, true},
+		{"ja-JP", Japanese, true},
+		{"EN_us", English, true},
+		{" en ", English, true},
+		{"fr", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.tag)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %q, %v; want %q, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Locale
+		ok     bool
+	}{
+		{"ja-JP,ja;q=0.9,en-US;q=0.8", Japanese, true},
+		{"fr-FR,en;q=0.5,ja;q=0.7", Japanese, true},
+		{"en-US,en;q=0.9", English, true},
+		{"fr-FR,de;q=0.5", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FromAcceptLanguage(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FromAcceptLanguage(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(Japanese, "Session not found"); got != "セッションが見つかりません" {
+		t.Errorf("T(ja, error) = %q", got)
+	}
+	if got := T(English, "Session not found"); got != "Session not found" {
+		t.Errorf("T(en, error) = %q, want the ID unchanged", got)
+	}
+	if got := T("", "notification.error.title"); got != "Error occurred" {
+		t.Errorf("T(\"\", notification) = %q, want the default locale message", got)
+	}
+	if got := T(Japanese, "missing %s", "id"); got != "missing id" {
+		t.Errorf("T() with args = %q", got)
+	}
+}
+
+// Every dotted message ID must be translated in every supported locale, so
+// notifications never fall back to another language.
+func TestCatalogsDefineSameMessageIDs(t *testing.T) {
+	for id := range catalog[DefaultLocale] {
+		for _, locale := range Supported() {
+			if _, ok := catalog[locale][id]; !ok && strings.Contains(id, ".") {
+				t.Errorf("locale %s is missing message %q", locale, id)
+			}
+		}
+	}
+	for _, locale := range Supported() {
+		for id := range catalog[locale] {
+			if _, ok := catalog[DefaultLocale][id]; !ok && strings.HasPrefix(id, "notification.") {
+				t.Errorf("message %q in locale %s is missing from %s", id, locale, DefaultLocale)
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package idle_reaper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/modules/schedule"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// sessionIDLabel is the label key holding the session ID on Kubernetes Services.
+	sessionIDLabel = "agentapi.proxy/session-id"
+
+	// idleTTLTagLabel is the label generated from the "idle_ttl" session tag.
+	// It overrides Config.IdleTTL for one session; "0", "off" and "never"
+	// exempt the session from idle reaping.
+	idleTTLTagLabel = "agentapi.proxy/tag-idle_ttl"
+
+	// idleTTLTag is the session tag key carrying the per-session idle TTL.
+	idleTTLTag = "idle_ttl"
+
+	// createdAtAnnotation, lastMessageAtAnnotation and lastActivityAtAnnotation
+	// are the RFC3339 timestamps used to determine when a session was last used.
+	createdAtAnnotation      = "agentapi.proxy/created-at"
+	lastMessageAtAnnotation  = "agentapi.proxy/last-message-at"
+	lastActivityAtAnnotation = "agentapi.proxy/last-activity-at"
+
+	// PausedAtAnnotation marks paused sessions, whether paused by the reaper
+	// or through the pause API. Such sessions are skipped until resumed.
+	PausedAtAnnotation = "agentapi.proxy/paused-at"
+
+	// sessionSelector matches user sessions and excludes pre-warmed stock Pods.
+	sessionSelector = "app.kubernetes.io/managed-by=agentapi-proxy,app.kubernetes.io/name=agentapi-session,!agentapi.proxy/stock"
+)
+
+// Action is what the reaper does with an idle session.
+type Action string
+
+const (
+	// ActionDelete deletes the session and all of its resources.
+	ActionDelete Action = "delete"
+	// ActionScaleToZero pauses the session: its Deployment is scaled to zero
+	// replicas and the Service, Secrets and PVC are kept so it can be resumed.
+	ActionScaleToZero Action = "scale_to_zero"
+)
+
+// SessionPauser is implemented by session managers that can pause a session
+// without deleting it.
+type SessionPauser interface {
+	PauseSession(ctx context.Context, sessionID string) error
+}
+
+// Config holds configuration for the idle session reaper.
+type Config struct {
+	// CheckInterval is how often the reaper scans for idle sessions.
+	// Default: 5m
+	CheckInterval time.Duration
+	// IdleTTL is how long a session may go without a proxied request or agent
+	// message before it is reaped.
+	// Default: 24h
+	IdleTTL time.Duration
+	// Action is applied to idle sessions. Default: ActionDelete
+	Action Action
+	// DryRun disables the action; idle sessions are only logged.
+	DryRun bool
+}
+
+// DefaultConfig returns the default configuration.
+func DefaultConfig() Config {
+	return Config{
+		CheckInterval: 5 * time.Minute,
+		IdleTTL:       24 * time.Hour,
+		Action:        ActionDelete,
+	}
+}
+
+// Reaper periodically deletes or scales to zero sessions that have been idle
+// longer than their TTL. Activity is the latest of the session creation time,
+// the last message sent to the agent, the last agent message and the last
+// request proxied to the session.
+type Reaper struct {
+	sessionManager portrepos.SessionManager
+	k8sClient      kubernetes.Interface
+	namespace      string
+	config         Config
+	now            func() time.Time
+
+	stopCh  chan struct{}
+	running bool
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewReaper creates a new Reaper.
+func NewReaper(
+	sessionManager portrepos.SessionManager,
+	k8sClient kubernetes.Interface,
+	namespace string,
+	config Config,
+) *Reaper {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultConfig().CheckInterval
+	}
+	if config.Action == "" {
+		config.Action = ActionDelete
+	}
+	return &Reaper{
+		sessionManager: sessionManager,
+		k8sClient:      k8sClient,
+		namespace:      namespace,
+		config:         config,
+		now:            time.Now,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start begins the reaper loop. It is safe to call from multiple goroutines.
+func (r *Reaper) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = true
+	r.stopCh = make(chan struct{})
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go r.run(ctx)
+
+	dryRunNote := ""
+	if r.config.DryRun {
+		dryRunNote = " (dry-run mode: no sessions will be changed)"
+	}
+	log.Printf("[IDLE_REAPER] Started with check interval %v, idle TTL %v, action %s%s",
+		r.config.CheckInterval, r.config.IdleTTL, r.config.Action, dryRunNote)
+	return nil
+}
+
+// Stop gracefully stops the reaper.
+func (r *Reaper) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	close(r.stopCh)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	log.Printf("[IDLE_REAPER] Stopped")
+}
+
+func (r *Reaper) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+
+	r.reap(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[IDLE_REAPER] Context cancelled, stopping")
+			return
+		case <-r.stopCh:
+			log.Printf("[IDLE_REAPER] Stop signal received")
+			return
+		case <-ticker.C:
+			r.reap(ctx)
+		}
+	}
+}
+
+// reap scans all sessions once and applies the configured action to the idle ones.
+func (r *Reaper) reap(ctx context.Context) {
+	now := r.now()
+	dryRunPrefix := ""
+	if r.config.DryRun {
+		dryRunPrefix = "[DRY-RUN] "
+	}
+
+	svcList, err := r.k8sClient.CoreV1().Services(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: sessionSelector,
+	})
+	if err != nil {
+		log.Printf("[IDLE_REAPER] %sFailed to list sessions: %v", dryRunPrefix, err)
+		return
+	}
+
+	reaped := 0
+	for _, svc := range svcList.Items {
+		sessionID := svc.Labels[sessionIDLabel]
+		if sessionID == "" {
+			continue
+		}
+		if svc.Annotations[PausedAtAnnotation] != "" {
+			continue
+		}
+
+		var inMemory time.Time
+		tagTTL := svc.Labels[idleTTLTagLabel]
+		if session := r.sessionManager.GetSession(sessionID); session != nil {
+			inMemory = session.LastMessageAt()
+			if v, ok := session.Tags()[idleTTLTag]; ok {
+				tagTTL = v
+			}
+		}
+
+		ttl, enabled, err := EffectiveTTL(tagTTL, r.config.IdleTTL)
+		if err != nil {
+			log.Printf("[IDLE_REAPER] %sSession %s: %v, using default %v", dryRunPrefix, sessionID, err, r.config.IdleTTL)
+		}
+		if !enabled {
+			continue
+		}
+
+		lastActivity := LastActivity(svc.Annotations, inMemory)
+		if lastActivity.IsZero() {
+			log.Printf("[IDLE_REAPER] %sSession %s: no activity timestamp found, skipping", dryRunPrefix, sessionID)
+			continue
+		}
+		idleFor := now.Sub(lastActivity)
+		if idleFor < ttl {
+			continue
+		}
+
+		if r.config.DryRun {
+			log.Printf("[IDLE_REAPER] [DRY-RUN] Would %s session %s (idle for %s, last activity %s, ttl %s)",
+				r.config.Action, sessionID, idleFor.Round(time.Second), lastActivity.Format(time.RFC3339), ttl)
+			reaped++
+			continue
+		}
+
+		log.Printf("[IDLE_REAPER] Applying %s to session %s (idle for %s, last activity %s, ttl %s)",
+			r.config.Action, sessionID, idleFor.Round(time.Second), lastActivity.Format(time.RFC3339), ttl)
+		if err := r.apply(ctx, sessionID); err != nil {
+			log.Printf("[IDLE_REAPER] Failed to %s session %s: %v", r.config.Action, sessionID, err)
+			continue
+		}
+		reaped++
+	}
+
+	if reaped > 0 {
+		log.Printf("[IDLE_REAPER] %sReaped %d idle session(s)", dryRunPrefix, reaped)
+	}
+}
+
+func (r *Reaper) apply(ctx context.Context, sessionID string) error {
+	switch r.config.Action {
+	case ActionScaleToZero:
+		pauser, ok := r.sessionManager.(SessionPauser)
+		if !ok {
+			return fmt.Errorf("session manager does not support pausing sessions")
+		}
+		return pauser.PauseSession(ctx, sessionID)
+	default:
+		return r.sessionManager.DeleteSession(sessionID)
+	}
+}
+
+// EffectiveTTL resolves the idle TTL for a session from its idle_ttl tag.
+// An empty tag yields the default; "0", "off", "never" and "false" disable
+// reaping. An unparsable tag falls back to the default and returns an error
+// describing the bad value.
+func EffectiveTTL(tag string, defaultTTL time.Duration) (time.Duration, bool, error) {
+	tag = strings.TrimSpace(tag)
+	switch strings.ToLower(tag) {
+	case "":
+		return defaultTTL, defaultTTL > 0, nil
+	case "0", "off", "never", "false":
+		return 0, false, nil
+	}
+	ttl, err := time.ParseDuration(tag)
+	if err != nil || ttl <= 0 {
+		return defaultTTL, defaultTTL > 0, fmt.Errorf("invalid %s tag %q", idleTTLTag, tag)
+	}
+	return ttl, true, nil
+}
+
+// LastActivity returns the most recent activity timestamp from the Service
+// annotations and the in-memory last message time.
+func LastActivity(annotations map[string]string, inMemory time.Time) time.Time {
+	latest := inMemory
+	for _, key := range []string{createdAtAnnotation, lastMessageAtAnnotation, lastActivityAtAnnotation} {
+		v, ok := annotations[key]
+		if !ok || v == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// LeaderReaper combines leader election with the idle reaper so that only one
+// replica reaps sessions at a time.
+type LeaderReaper struct {
+	reaper  *Reaper
+	elector *schedule.LeaderElector
+}
+
+// NewLeaderReaper creates a new LeaderReaper.
+func NewLeaderReaper(
+	sessionManager portrepos.SessionManager,
+	k8sClient kubernetes.Interface,
+	namespace string,
+	config Config,
+	electionConfig schedule.LeaderElectionConfig,
+) *LeaderReaper {
+	// Use a distinct lease name so this worker does not compete with other workers.
+	electionConfig.LeaseName = "agentapi-idle-reaper"
+
+	return &LeaderReaper{
+		reaper:  NewReaper(sessionManager, k8sClient, namespace, config),
+		elector: schedule.NewLeaderElector(k8sClient, electionConfig),
+	}
+}
+
+// Run starts the leader election loop. Only the leader runs the reaper.
+func (lr *LeaderReaper) Run(ctx context.Context) {
+	lr.elector.Run(ctx,
+		func(leaderCtx context.Context) {
+			if err := lr.reaper.Start(leaderCtx); err != nil {
+				log.Printf("[IDLE_REAPER] Failed to start reaper: %v", err)
+			}
+		},
+		func() {
+			lr.reaper.Stop()
+		},
+	)
+}
+
+// Stop gracefully stops the leader reaper.
+func (lr *LeaderReaper) Stop() {
+	lr.reaper.Stop()
+}
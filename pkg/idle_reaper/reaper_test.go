@@ -0,0 +1,175 @@
+package idle_reaper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// mockSessionManager is a minimal SessionManager for testing.
+type mockSessionManager struct {
+	deletedIDs []string
+	scaledIDs  []string
+}
+
+func (m *mockSessionManager) CreateSession(_ context.Context, id string, _ *entities.RunServerRequest, _ []byte) (entities.Session, error) {
+	return nil, nil
+}
+func (m *mockSessionManager) GetSession(_ string) entities.Session                     { return nil }
+func (m *mockSessionManager) ListSessions(_ entities.SessionFilter) []entities.Session { return nil }
+func (m *mockSessionManager) SendMessage(_ context.Context, _ string, _ string) error  { return nil }
+func (m *mockSessionManager) StopAgent(_ context.Context, _ string) error              { return nil }
+func (m *mockSessionManager) GetMessages(_ context.Context, _ string) ([]portrepos.Message, error) {
+	return nil, nil
+}
+func (m *mockSessionManager) Shutdown(_ time.Duration) error { return nil }
+func (m *mockSessionManager) DeleteSession(id string) error {
+	m.deletedIDs = append(m.deletedIDs, id)
+	return nil
+}
+func (m *mockSessionManager) PauseSession(_ context.Context, id string) error {
+	m.scaledIDs = append(m.scaledIDs, id)
+	return nil
+}
+
+func sessionService(sessionID string, labels, annotations map[string]string) corev1.Service {
+	l := map[string]string{
+		"app.kubernetes.io/managed-by": "agentapi-proxy",
+		"app.kubernetes.io/name":       "agentapi-session",
+		sessionIDLabel:                 sessionID,
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "agentapi-session-" + sessionID + "-svc",
+			Namespace:   "test",
+			Labels:      l,
+			Annotations: annotations,
+		},
+	}
+}
+
+func newTestReaper(mgr portrepos.SessionManager, config Config, svcs ...corev1.Service) *Reaper {
+	objects := make([]k8sruntime.Object, len(svcs))
+	for i := range svcs {
+		svc := svcs[i]
+		objects[i] = &svc
+	}
+	return NewReaper(mgr, fake.NewSimpleClientset(objects...), "test", config)
+}
+
+func ago(d time.Duration) string {
+	return time.Now().Add(-d).Format(time.RFC3339)
+}
+
+func TestReap_DeletesIdleSessions(t *testing.T) {
+	idle := sessionService("idle", nil, map[string]string{
+		createdAtAnnotation:     ago(72 * time.Hour),
+		lastMessageAtAnnotation: ago(30 * time.Hour),
+	})
+	recentlyProxied := sessionService("proxied", nil, map[string]string{
+		createdAtAnnotation:      ago(72 * time.Hour),
+		lastMessageAtAnnotation:  ago(30 * time.Hour),
+		lastActivityAtAnnotation: ago(time.Hour),
+	})
+	exempt := sessionService("exempt", map[string]string{idleTTLTagLabel: "never"}, map[string]string{
+		createdAtAnnotation: ago(72 * time.Hour),
+	})
+	shortTTL := sessionService("short", map[string]string{idleTTLTagLabel: "30m"}, map[string]string{
+		createdAtAnnotation: ago(time.Hour),
+	})
+
+	mgr := &mockSessionManager{}
+	r := newTestReaper(mgr, Config{IdleTTL: 24 * time.Hour}, idle, recentlyProxied, exempt, shortTTL)
+	r.reap(context.Background())
+
+	if len(mgr.deletedIDs) != 2 {
+		t.Fatalf("expected 2 deletions, got %v", mgr.deletedIDs)
+	}
+	deleted := map[string]bool{}
+	for _, id := range mgr.deletedIDs {
+		deleted[id] = true
+	}
+	if !deleted["idle"] || !deleted["short"] {
+		t.Errorf("expected idle and short to be deleted, got %v", mgr.deletedIDs)
+	}
+}
+
+func TestReap_ScaleToZero(t *testing.T) {
+	idle := sessionService("idle", nil, map[string]string{createdAtAnnotation: ago(48 * time.Hour)})
+	alreadyScaled := sessionService("scaled", nil, map[string]string{
+		createdAtAnnotation: ago(48 * time.Hour),
+		PausedAtAnnotation:  ago(time.Hour),
+	})
+
+	mgr := &mockSessionManager{}
+	r := newTestReaper(mgr, Config{IdleTTL: time.Hour, Action: ActionScaleToZero}, idle, alreadyScaled)
+	r.reap(context.Background())
+
+	if len(mgr.deletedIDs) != 0 {
+		t.Errorf("scale_to_zero must not delete sessions, got %v", mgr.deletedIDs)
+	}
+	if len(mgr.scaledIDs) != 1 || mgr.scaledIDs[0] != "idle" {
+		t.Errorf("expected only idle to be scaled, got %v", mgr.scaledIDs)
+	}
+}
+
+func TestReap_DryRun(t *testing.T) {
+	idle := sessionService("idle", nil, map[string]string{createdAtAnnotation: ago(48 * time.Hour)})
+
+	mgr := &mockSessionManager{}
+	r := newTestReaper(mgr, Config{IdleTTL: time.Hour, DryRun: true}, idle)
+	r.reap(context.Background())
+
+	if len(mgr.deletedIDs) != 0 {
+		t.Errorf("dry-run must not delete sessions, got %v", mgr.deletedIDs)
+	}
+}
+
+func TestEffectiveTTL(t *testing.T) {
+	tests := []struct {
+		tag         string
+		wantTTL     time.Duration
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{"", 24 * time.Hour, true, false},
+		{"2h", 2 * time.Hour, true, false},
+		{"never", 0, false, false},
+		{"0", 0, false, false},
+		{"soon", 24 * time.Hour, true, true},
+		{"-1h", 24 * time.Hour, true, true},
+	}
+	for _, tt := range tests {
+		ttl, enabled, err := EffectiveTTL(tt.tag, 24*time.Hour)
+		if ttl != tt.wantTTL || enabled != tt.wantEnabled || (err != nil) != tt.wantErr {
+			t.Errorf("EffectiveTTL(%q) = (%v, %v, %v), want (%v, %v, err=%v)",
+				tt.tag, ttl, enabled, err, tt.wantTTL, tt.wantEnabled, tt.wantErr)
+		}
+	}
+
+	if _, enabled, _ := EffectiveTTL("", 0); enabled {
+		t.Error("a zero default TTL should disable reaping for untagged sessions")
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	inMemory := time.Now().Add(-time.Minute).Truncate(time.Second)
+	got := LastActivity(map[string]string{
+		createdAtAnnotation:      ago(time.Hour),
+		lastActivityAtAnnotation: "not-a-time",
+	}, inMemory)
+	if !got.Equal(inMemory) {
+		t.Errorf("LastActivity() = %v, want in-memory time %v", got, inMemory)
+	}
+}
@@ -195,6 +195,8 @@ func (e *Exporter) convertWebhookToImport(ctx context.Context, w *entities.Webho
 		SignatureType:   string(w.SignatureType()),
 		SignaturePrefix: w.SignaturePrefix(),
 		MaxSessions:     w.MaxSessions(),
+		Source:          w.Source(),
+		MaxPayloadBytes: w.MaxPayloadBytes(),
 	}
 
 	// Always include secret (encrypt if encryption service is available)
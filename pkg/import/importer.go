@@ -525,6 +525,10 @@ func (i *Importer) convertWebhookImport(ctx context.Context, webhookImport Webho
 		webhookEntity.SetMaxSessions(webhookImport.MaxSessions)
 	}
 
+	// Set generic receiver configuration
+	webhookEntity.SetSource(webhookImport.Source)
+	webhookEntity.SetMaxPayloadBytes(webhookImport.MaxPayloadBytes)
+
 	// Set GitHub config
 	if webhookImport.GitHub != nil {
 		githubConfig := entities.NewWebhookGitHubConfig()
@@ -45,6 +45,8 @@ type WebhookImport struct {
 	SignatureType   string                 `yaml:"signature_type,omitempty" toml:"signature_type,omitempty" json:"signature_type,omitempty"`
 	SignaturePrefix string                 `yaml:"signature_prefix,omitempty" toml:"signature_prefix,omitempty" json:"signature_prefix,omitempty"`
 	MaxSessions     int                    `yaml:"max_sessions,omitempty" toml:"max_sessions,omitempty" json:"max_sessions,omitempty"`
+	Source          string                 `yaml:"source,omitempty" toml:"source,omitempty" json:"source,omitempty"`
+	MaxPayloadBytes int64                  `yaml:"max_payload_bytes,omitempty" toml:"max_payload_bytes,omitempty" json:"max_payload_bytes,omitempty"`
 	GitHub          *GitHubConfigImport    `yaml:"github,omitempty" toml:"github,omitempty" json:"github,omitempty"`
 	Triggers        []WebhookTriggerImport `yaml:"triggers" toml:"triggers" json:"triggers"`
 	SessionConfig   *SessionConfigImport   `yaml:"session_config,omitempty" toml:"session_config,omitempty" json:"session_config,omitempty"`
@@ -0,0 +1,195 @@
+// Package leader runs the singleton background subsystems of the proxy on one
+// replica at a time. Replicas compete for a coordination.k8s.io Lease; the
+// replica holding it runs every registered task and the others wait to take
+// over.
+package leader
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// isLeader is 1 while this replica runs the singleton tasks
+var isLeader = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "agentapi_proxy",
+	Name:      "leader",
+	Help:      "1 while this replica holds the leader Lease and runs the singleton background subsystems.",
+})
+
+// Config contains the Lease settings of a Runner
+type Config struct {
+	// Namespace is the namespace of the Lease
+	Namespace string
+	// LeaseName is the name of the Lease
+	LeaseName string
+	// LeaseDuration is the duration that non-leader candidates will wait to force acquire leadership
+	LeaseDuration time.Duration
+	// RenewDeadline is the duration that the acting master will retry refreshing leadership before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod is the duration the LeaderElector clients should wait between tries of actions
+	RetryPeriod time.Duration
+}
+
+// task is a registered singleton subsystem
+type task struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Runner runs registered tasks while this replica holds the Lease. Tasks get
+// a context that is cancelled when the replica loses the Lease, and are
+// started again when it takes the Lease back. Without a Kubernetes client
+// there is no election and the tasks run as soon as Run is called.
+type Runner struct {
+	client   kubernetes.Interface
+	config   Config
+	identity string
+
+	mu      sync.Mutex
+	tasks   []task
+	leadCtx context.Context // non-nil while leading
+	leader  string          // identity of the current leader
+}
+
+// NewRunner creates a Runner. A nil client disables the election.
+func NewRunner(client kubernetes.Interface, config Config) *Runner {
+	hostname, _ := os.Hostname()
+	return &Runner{
+		client:   client,
+		config:   config,
+		identity: hostname + "_" + uuid.New().String()[:8],
+	}
+}
+
+// Go registers a singleton task. It is started right away when this replica
+// is leading, and otherwise once it becomes the leader. run must return when
+// its context is cancelled.
+func (r *Runner) Go(name string, run func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, task{name: name, run: run})
+	if r.leadCtx != nil {
+		r.start(r.leadCtx, task{name: name, run: run})
+	}
+}
+
+// Run takes part in the election until ctx is cancelled. The Lease is
+// released on cancellation so that another replica takes over at once.
+func (r *Runner) Run(ctx context.Context) {
+	if r.client == nil {
+		log.Printf("[LEADER] Leader election disabled, running singleton tasks on this replica")
+		r.lead(ctx)
+		<-ctx.Done()
+		r.stop()
+		return
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      r.config.LeaseName,
+			Namespace: r.config.Namespace,
+		},
+		Client: r.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: r.identity,
+		},
+	}
+	log.Printf("[LEADER] Starting leader election for Lease %s/%s with identity %s", r.config.Namespace, r.config.LeaseName, r.identity)
+
+	// RunOrDie returns when the Lease is lost; stand for election again
+	// until the proxy shuts down.
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   r.config.LeaseDuration,
+			RenewDeadline:   r.config.RenewDeadline,
+			RetryPeriod:     r.config.RetryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leadCtx context.Context) {
+					log.Printf("[LEADER] Became leader, starting singleton tasks")
+					r.lead(leadCtx)
+				},
+				OnStoppedLeading: func() {
+					log.Printf("[LEADER] Lost leadership, singleton tasks stopped")
+					r.stop()
+				},
+				OnNewLeader: func(identity string) {
+					r.mu.Lock()
+					r.leader = identity
+					r.mu.Unlock()
+					if identity != r.identity {
+						log.Printf("[LEADER] New leader elected: %s", identity)
+					}
+				},
+			},
+		})
+	}
+}
+
+// lead starts every registered task with ctx
+func (r *Runner) lead(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leadCtx = ctx
+	r.leader = r.identity
+	isLeader.Set(1)
+	for _, t := range r.tasks {
+		r.start(ctx, t)
+	}
+}
+
+// stop records that the tasks were stopped; their context is already
+// cancelled
+func (r *Runner) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leadCtx = nil
+	isLeader.Set(0)
+}
+
+func (r *Runner) start(ctx context.Context, t task) {
+	log.Printf("[LEADER] Starting %s", t.name)
+	go t.run(ctx)
+}
+
+// IsLeader reports whether this replica currently runs the singleton tasks
+func (r *Runner) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leadCtx != nil
+}
+
+// Leader returns the identity of the current leader, or "" before one is known
+func (r *Runner) Leader() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+// Identity returns the identity of this replica in the election
+func (r *Runner) Identity() string {
+	return r.identity
+}
+
+// Tasks returns the names of the registered tasks
+func (r *Runner) Tasks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		names = append(names, t.name)
+	}
+	return names
+}
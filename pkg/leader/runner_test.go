@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countingTask counts its running instances
+func countingTask(running *int32) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		atomic.AddInt32(running, 1)
+		<-ctx.Done()
+		atomic.AddInt32(running, -1)
+	}
+}
+
+func TestRunnerWithoutElection(t *testing.T) {
+	var early, late int32
+	runner := NewRunner(nil, Config{})
+	runner.Go("early", countingTask(&early))
+	if runner.IsLeader() || atomic.LoadInt32(&early) != 0 {
+		t.Fatal("tasks must not run before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	waitFor(t, "early task", func() bool { return atomic.LoadInt32(&early) == 1 })
+
+	runner.Go("late", countingTask(&late))
+	waitFor(t, "task registered while leading", func() bool { return atomic.LoadInt32(&late) == 1 })
+	if !runner.IsLeader() || runner.Leader() != runner.Identity() {
+		t.Errorf("IsLeader() = %t, Leader() = %q, want this replica", runner.IsLeader(), runner.Leader())
+	}
+
+	cancel()
+	<-done
+	waitFor(t, "tasks to stop", func() bool { return atomic.LoadInt32(&early)+atomic.LoadInt32(&late) == 0 })
+	if runner.IsLeader() {
+		t.Error("IsLeader() = true after Run returned")
+	}
+}
+
+func TestRunnerElectsOneReplica(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	config := Config{
+		Namespace:     "agentapi",
+		LeaseName:     "agentapi-proxy-leader",
+		LeaseDuration: 2 * time.Second,
+		RenewDeadline: time.Second,
+		RetryPeriod:   50 * time.Millisecond,
+	}
+
+	var running int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replicas := []*Runner{NewRunner(client, config), NewRunner(client, config)}
+	for _, r := range replicas {
+		r.Go("singleton", countingTask(&running))
+		go r.Run(ctx)
+	}
+
+	waitFor(t, "a leader", func() bool { return replicas[0].IsLeader() || replicas[1].IsLeader() })
+	leaderIdx := 0
+	if replicas[1].IsLeader() {
+		leaderIdx = 1
+	}
+	standby := replicas[1-leaderIdx]
+	waitFor(t, "the standby to see the leader", func() bool { return standby.Leader() == replicas[leaderIdx].Identity() })
+	time.Sleep(300 * time.Millisecond)
+	if n := atomic.LoadInt32(&running); n != 1 {
+		t.Fatalf("%d instances of the singleton task are running, want 1", n)
+	}
+	if standby.IsLeader() {
+		t.Fatal("both replicas lead")
+	}
+}
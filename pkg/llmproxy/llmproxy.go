@@ -0,0 +1,388 @@
+// Package llmproxy implements the egress proxy that session Pods use for
+// outbound model API traffic (Anthropic, OpenAI and compatible providers).
+//
+// Session Pods never receive the upstream provider credentials. Instead they
+// authenticate to agentapi-proxy with their own session API key and the proxy:
+//
+//   - strips the inbound credentials and injects the configured provider key,
+//   - forwards the request to the first healthy provider of the requested type,
+//     failing over to the next provider on connection errors and retryable
+//     status codes (429, 5xx, 529),
+//   - extracts token usage from JSON and SSE responses and accumulates it per
+//     session, and
+//   - emits one Record per request, optionally including redacted bodies.
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProviderType identifies the wire protocol of an upstream provider.
+type ProviderType string
+
+const (
+	// ProviderAnthropic authenticates with the x-api-key header.
+	ProviderAnthropic ProviderType = "anthropic"
+	// ProviderOpenAI authenticates with an "Authorization: Bearer" header.
+	ProviderOpenAI ProviderType = "openai"
+)
+
+// DefaultMaxRequestBytes caps the request body buffered for failover.
+const DefaultMaxRequestBytes = 32 << 20
+
+// DefaultMaxLoggedBodyBytes caps the redacted body size included in a Record.
+const DefaultMaxLoggedBodyBytes = 4096
+
+// Provider is a single upstream endpoint.
+type Provider struct {
+	// Name is a human readable identifier used in logs (e.g. "anthropic-primary").
+	Name string
+	// Type selects the authentication scheme and the route the provider serves.
+	Type ProviderType
+	// BaseURL is the upstream base URL (e.g. "https://api.anthropic.com").
+	BaseURL string
+	// APIKey is injected into every forwarded request.
+	APIKey string
+}
+
+// Record describes a single proxied request.
+type Record struct {
+	SessionID    string        `json:"session_id"`
+	UserID       string        `json:"user_id,omitempty"`
+	Provider     string        `json:"provider"`
+	Type         ProviderType  `json:"type"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	Model        string        `json:"model,omitempty"`
+	StatusCode   int           `json:"status_code"`
+	Attempts     int           `json:"attempts"`
+	Duration     time.Duration `json:"duration"`
+	Usage        Usage         `json:"usage"`
+	Error        string        `json:"error,omitempty"`
+	RequestBody  string        `json:"request_body,omitempty"`
+	ResponseBody string        `json:"response_body,omitempty"`
+}
+
+// RequestInfo identifies the session a proxied request belongs to.
+type RequestInfo struct {
+	SessionID string
+	UserID    string
+}
+
+// Options configures a Proxy.
+type Options struct {
+	// Providers lists upstream providers in failover order.
+	Providers []Provider
+	// LogBodies includes redacted request/response bodies in each Record.
+	LogBodies bool
+	// MaxLoggedBodyBytes caps logged bodies. Defaults to DefaultMaxLoggedBodyBytes.
+	MaxLoggedBodyBytes int
+	// MaxRequestBytes caps the buffered request body. Defaults to DefaultMaxRequestBytes.
+	MaxRequestBytes int64
+	// Transport is used for upstream requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Recorder receives a Record after each request. Defaults to log.Printf.
+	Recorder func(Record)
+}
+
+// Proxy forwards model API requests to configured upstream providers.
+type Proxy struct {
+	opts      Options
+	providers map[ProviderType][]Provider
+	usage     *UsageTracker
+}
+
+// New validates opts and returns a Proxy.
+func New(opts Options) (*Proxy, error) {
+	if opts.MaxLoggedBodyBytes <= 0 {
+		opts.MaxLoggedBodyBytes = DefaultMaxLoggedBodyBytes
+	}
+	if opts.MaxRequestBytes <= 0 {
+		opts.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+	if opts.Recorder == nil {
+		opts.Recorder = logRecord
+	}
+
+	providers := make(map[ProviderType][]Provider)
+	for i, p := range opts.Providers {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("%s-%d", p.Type, i)
+		}
+		switch p.Type {
+		case ProviderAnthropic, ProviderOpenAI:
+		default:
+			return nil, fmt.Errorf("provider %q: unsupported type %q", p.Name, p.Type)
+		}
+		u, err := url.Parse(p.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("provider %q: invalid base_url %q", p.Name, p.BaseURL)
+		}
+		if p.APIKey == "" {
+			return nil, fmt.Errorf("provider %q: api key is empty", p.Name)
+		}
+		p.BaseURL = strings.TrimRight(p.BaseURL, "/")
+		providers[p.Type] = append(providers[p.Type], p)
+	}
+
+	return &Proxy{
+		opts:      opts,
+		providers: providers,
+		usage:     NewUsageTracker(),
+	}, nil
+}
+
+// Usage returns the per-session usage tracker.
+func (p *Proxy) Usage() *UsageTracker {
+	return p.usage
+}
+
+// HasProvider reports whether at least one provider of type t is configured.
+func (p *Proxy) HasProvider(t ProviderType) bool {
+	return len(p.providers[t]) > 0
+}
+
+// Forward proxies r to the providers of type t. path is the upstream path
+// (e.g. "/v1/messages"); the query string of r is preserved.
+func (p *Proxy) Forward(w http.ResponseWriter, r *http.Request, t ProviderType, path string, info RequestInfo) {
+	start := time.Now()
+	rec := Record{
+		SessionID: info.SessionID,
+		UserID:    info.UserID,
+		Type:      t,
+		Method:    r.Method,
+		Path:      path,
+	}
+	defer func() {
+		rec.Duration = time.Since(start)
+		p.usage.Add(info.SessionID, rec.Usage)
+		p.opts.Recorder(rec)
+	}()
+
+	candidates := p.providers[t]
+	if len(candidates) == 0 {
+		rec.StatusCode = http.StatusNotFound
+		rec.Error = "no provider configured"
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no %s provider configured", t))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, p.opts.MaxRequestBytes+1))
+	if err != nil {
+		rec.StatusCode = http.StatusBadRequest
+		rec.Error = err.Error()
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	if int64(len(body)) > p.opts.MaxRequestBytes {
+		rec.StatusCode = http.StatusRequestEntityTooLarge
+		rec.Error = "request body too large"
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	rec.Model = extractModel(body)
+	if p.opts.LogBodies {
+		rec.RequestBody = RedactBody(body, p.opts.MaxLoggedBodyBytes)
+	}
+
+	var lastErr error
+	for i, provider := range candidates {
+		rec.Attempts = i + 1
+		rec.Provider = provider.Name
+
+		req, err := p.buildUpstreamRequest(r, provider, path, body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := p.opts.Transport.RoundTrip(req)
+		if err != nil {
+			lastErr = err
+			log.Printf("[LLM_PROXY] Provider %s failed for session %s: %v", provider.Name, info.SessionID, err)
+			continue
+		}
+		if isRetryableStatus(resp.StatusCode) && i < len(candidates)-1 {
+			lastErr = fmt.Errorf("upstream returned %d", resp.StatusCode)
+			log.Printf("[LLM_PROXY] Provider %s returned %d for session %s, failing over", provider.Name, resp.StatusCode, info.SessionID)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			continue
+		}
+
+		rec.StatusCode = resp.StatusCode
+		p.copyResponse(w, resp, &rec)
+		return
+	}
+
+	rec.StatusCode = http.StatusBadGateway
+	if lastErr != nil {
+		rec.Error = lastErr.Error()
+	}
+	writeError(w, http.StatusBadGateway, "all upstream providers failed")
+}
+
+func (p *Proxy) buildUpstreamRequest(r *http.Request, provider Provider, path string, body []byte) (*http.Request, error) {
+	target := provider.BaseURL + "/" + strings.TrimLeft(path, "/")
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range r.Header {
+		if isHopByHopHeader(k) || isCredentialHeader(k) {
+			continue
+		}
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	// Let the transport negotiate compression so usage can be parsed from plain bodies.
+	req.Header.Del("Accept-Encoding")
+	req.ContentLength = int64(len(body))
+
+	switch provider.Type {
+	case ProviderAnthropic:
+		req.Header.Set("x-api-key", provider.APIKey)
+	case ProviderOpenAI:
+		req.Header.Set("Authorization", "Bearer "+provider.APIKey)
+	}
+	return req, nil
+}
+
+func (p *Proxy) copyResponse(w http.ResponseWriter, resp *http.Response, rec *Record) {
+	defer func() { _ = resp.Body.Close() }()
+
+	for k, vs := range resp.Header {
+		if isHopByHopHeader(k) || strings.EqualFold(k, "Content-Length") {
+			continue
+		}
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	scanner := newUsageScanner(resp.Header.Get("Content-Type"))
+	var logged bytes.Buffer
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			scanner.Write(chunk)
+			if p.opts.LogBodies && logged.Len() < p.opts.MaxLoggedBodyBytes {
+				logged.Write(chunk)
+			}
+			if _, werr := w.Write(chunk); werr != nil {
+				rec.Error = werr.Error()
+				break
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				rec.Error = err.Error()
+			}
+			break
+		}
+	}
+
+	rec.Usage = scanner.Usage()
+	if rec.Model == "" {
+		rec.Model = scanner.Model()
+	}
+	if p.opts.LogBodies {
+		rec.ResponseBody = RedactBody(logged.Bytes(), p.opts.MaxLoggedBodyBytes)
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529: // Anthropic "overloaded"
+		return true
+	}
+	return false
+}
+
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Host":                true,
+}
+
+func isHopByHopHeader(name string) bool {
+	return hopByHopHeaders[http.CanonicalHeaderKey(name)]
+}
+
+func isCredentialHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "X-Api-Key", "Api-Key", "Cookie":
+		return true
+	}
+	return false
+}
+
+func extractModel(body []byte) string {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.Model
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type": "error",
+		"error": map[string]string{
+			"type":    "llm_proxy_error",
+			"message": message,
+		},
+	})
+}
+
+func logRecord(rec Record) {
+	log.Printf("[LLM_PROXY] session=%s provider=%s model=%s %s %s status=%d attempts=%d duration=%s input_tokens=%d output_tokens=%d",
+		rec.SessionID, rec.Provider, rec.Model, rec.Method, rec.Path, rec.StatusCode, rec.Attempts,
+		rec.Duration.Round(time.Millisecond), rec.Usage.InputTokens, rec.Usage.OutputTokens)
+	if rec.Error != "" {
+		log.Printf("[LLM_PROXY] session=%s error: %s", rec.SessionID, rec.Error)
+	}
+	if rec.RequestBody != "" {
+		log.Printf("[LLM_PROXY] session=%s request: %s", rec.SessionID, rec.RequestBody)
+	}
+	if rec.ResponseBody != "" {
+		log.Printf("[LLM_PROXY] session=%s response: %s", rec.SessionID, rec.ResponseBody)
+	}
+}
@@ -0,0 +1,43 @@
+package llmproxy
+
+import "regexp"
+
+const redacted = "[REDACTED]"
+
+var secretPatterns = []*regexp.Regexp{
+	// Anthropic / OpenAI style keys (sk-..., sk-ant-..., sk-proj-...)
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`),
+	// GitHub tokens
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{20,}`),
+	// AWS access key IDs
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	// Slack tokens
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9\-]{10,}`),
+	// Bearer credentials embedded in text
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=\-]{8,}`),
+}
+
+// secretFieldPattern matches JSON string fields whose name suggests a secret.
+var secretFieldPattern = regexp.MustCompile(`(?i)("(?:[a-z0-9_\-]*(?:api[_\-]?key|secret|password|passwd|token|authorization)[a-z0-9_\-]*)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// RedactBody masks credentials in body and truncates it to maxBytes.
+// Token counters such as "input_tokens" are numbers and are left untouched.
+func RedactBody(body []byte, maxBytes int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	truncated := false
+	if maxBytes > 0 && len(body) > maxBytes {
+		body = body[:maxBytes]
+		truncated = true
+	}
+	s := secretFieldPattern.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, redacted)
+	}
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
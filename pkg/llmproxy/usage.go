@@ -0,0 +1,234 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// maxBufferedResponseBytes caps the non-streaming response body kept for usage parsing.
+const maxBufferedResponseBytes = 4 << 20
+
+// Usage is the token usage reported by a provider.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
+}
+
+// TotalTokens returns the sum of all token counters.
+func (u Usage) TotalTokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// IsZero reports whether no tokens were recorded.
+func (u Usage) IsZero() bool {
+	return u.TotalTokens() == 0
+}
+
+func (u *Usage) add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheCreationInputTokens += o.CacheCreationInputTokens
+	u.CacheReadInputTokens += o.CacheReadInputTokens
+}
+
+// mergeMax keeps the largest value of each counter. Streaming providers report
+// cumulative counters, so the maximum across events is the final value.
+func (u *Usage) mergeMax(o Usage) {
+	u.InputTokens = max(u.InputTokens, o.InputTokens)
+	u.OutputTokens = max(u.OutputTokens, o.OutputTokens)
+	u.CacheCreationInputTokens = max(u.CacheCreationInputTokens, o.CacheCreationInputTokens)
+	u.CacheReadInputTokens = max(u.CacheReadInputTokens, o.CacheReadInputTokens)
+}
+
+// rawUsage covers both the Anthropic and the OpenAI usage objects.
+type rawUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	PromptTokens             int64 `json:"prompt_tokens"`
+	CompletionTokens         int64 `json:"completion_tokens"`
+}
+
+func (r *rawUsage) toUsage() Usage {
+	if r == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:              max(r.InputTokens, r.PromptTokens),
+		OutputTokens:             max(r.OutputTokens, r.CompletionTokens),
+		CacheCreationInputTokens: r.CacheCreationInputTokens,
+		CacheReadInputTokens:     r.CacheReadInputTokens,
+	}
+}
+
+// usagePayload matches the places providers put usage and model in a response
+// or stream event: top-level (Anthropic messages, OpenAI chat completions),
+// "message" (Anthropic message_start) and "response" (OpenAI Responses API).
+type usagePayload struct {
+	Model   string    `json:"model"`
+	Usage   *rawUsage `json:"usage"`
+	Message *struct {
+		Model string    `json:"model"`
+		Usage *rawUsage `json:"usage"`
+	} `json:"message"`
+	Response *struct {
+		Model string    `json:"model"`
+		Usage *rawUsage `json:"usage"`
+	} `json:"response"`
+}
+
+// ExtractUsage parses token usage and model from a single JSON document.
+func ExtractUsage(data []byte) (Usage, string) {
+	var p usagePayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Usage{}, ""
+	}
+	var u Usage
+	model := p.Model
+	u.mergeMax(p.Usage.toUsage())
+	if p.Message != nil {
+		u.mergeMax(p.Message.Usage.toUsage())
+		if model == "" {
+			model = p.Message.Model
+		}
+	}
+	if p.Response != nil {
+		u.mergeMax(p.Response.Usage.toUsage())
+		if model == "" {
+			model = p.Response.Model
+		}
+	}
+	return u, model
+}
+
+// usageScanner incrementally extracts usage from a response body. SSE
+// streams are parsed line by line; other bodies are buffered (up to a cap)
+// and parsed once complete.
+type usageScanner struct {
+	sse     bool
+	pending []byte
+	body    bytes.Buffer
+	usage   Usage
+	model   string
+}
+
+func newUsageScanner(contentType string) *usageScanner {
+	return &usageScanner{sse: strings.HasPrefix(strings.ToLower(contentType), "text/event-stream")}
+}
+
+func (s *usageScanner) Write(p []byte) {
+	if !s.sse {
+		if s.body.Len()+len(p) <= maxBufferedResponseBytes {
+			s.body.Write(p)
+		}
+		return
+	}
+	s.pending = append(s.pending, p...)
+	for {
+		idx := bytes.IndexByte(s.pending, '\n')
+		if idx < 0 {
+			return
+		}
+		s.scanLine(s.pending[:idx])
+		s.pending = s.pending[idx+1:]
+	}
+}
+
+func (s *usageScanner) scanLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+	u, model := ExtractUsage(data)
+	s.usage.mergeMax(u)
+	if s.model == "" {
+		s.model = model
+	}
+}
+
+func (s *usageScanner) finish() {
+	if s.sse {
+		if len(s.pending) > 0 {
+			s.scanLine(s.pending)
+			s.pending = nil
+		}
+		return
+	}
+	if s.body.Len() > 0 {
+		s.usage, s.model = ExtractUsage(s.body.Bytes())
+		s.body.Reset()
+	}
+}
+
+// Usage returns the usage collected so far, flushing any buffered data.
+func (s *usageScanner) Usage() Usage {
+	s.finish()
+	return s.usage
+}
+
+// Model returns the model reported by the response, if any.
+func (s *usageScanner) Model() string {
+	s.finish()
+	return s.model
+}
+
+// SessionUsage is the accumulated usage for one session.
+type SessionUsage struct {
+	Requests int64 `json:"requests"`
+	Usage
+}
+
+// UsageTracker accumulates token usage per session. It is safe for concurrent use.
+type UsageTracker struct {
+	mu       sync.RWMutex
+	sessions map[string]*SessionUsage
+}
+
+// NewUsageTracker returns an empty UsageTracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{sessions: make(map[string]*SessionUsage)}
+}
+
+// Add records one request with usage u for sessionID.
+func (t *UsageTracker) Add(sessionID string, u Usage) {
+	if sessionID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	su, ok := t.sessions[sessionID]
+	if !ok {
+		su = &SessionUsage{}
+		t.sessions[sessionID] = su
+	}
+	su.Requests++
+	su.add(u)
+}
+
+// Get returns the accumulated usage for sessionID.
+func (t *UsageTracker) Get(sessionID string) (SessionUsage, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	su, ok := t.sessions[sessionID]
+	if !ok {
+		return SessionUsage{}, false
+	}
+	return *su, true
+}
+
+// Delete drops the usage recorded for sessionID.
+func (t *UsageTracker) Delete(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, sessionID)
+}
@@ -0,0 +1,289 @@
+// Package metering exports the team usage metered for showback to a billing
+// system, such as Stripe meter events or a generic metering webhook.
+//
+// Usage is aggregated per tenant (team) and metric. Each export reports the
+// growth of the monthly totals since the previous export. A record is
+// identified by its month, tenant, metric and the total it brings the metric
+// to, so a record that is re-sent after a failure has the same identifier
+// and is counted once by the receiver.
+package metering
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+)
+
+// Metric names. Durations are exported in whole minutes because billing
+// systems meter integer quantities.
+const (
+	MetricSessions       = "sessions"
+	MetricSessionMinutes = "session_minutes"
+	MetricStorageMinutes = "storage_minutes"
+	MetricLLMRequests    = "llm_requests"
+	MetricInputTokens    = "input_tokens"
+	MetricOutputTokens   = "output_tokens"
+	MetricNotifications  = "notifications"
+)
+
+// Metrics lists every metric
+var Metrics = []string{
+	MetricSessions,
+	MetricSessionMinutes,
+	MetricStorageMinutes,
+	MetricLLMRequests,
+	MetricInputTokens,
+	MetricOutputTokens,
+	MetricNotifications,
+}
+
+// ErrTenantNotMapped is returned by a Sink that cannot bill a tenant. The
+// usage of the tenant stays pending until it is mapped.
+var ErrTenantNotMapped = errors.New("tenant is not mapped to a billing customer")
+
+// metricValue returns the monthly total of metric in usage
+func metricValue(u showback.TeamUsage, metric string) (int64, bool) {
+	switch metric {
+	case MetricSessions:
+		return int64(u.Sessions), true
+	case MetricSessionMinutes:
+		return int64(math.Floor(u.SessionHours * 60)), true
+	case MetricStorageMinutes:
+		return int64(math.Floor(u.StorageHours * 60)), true
+	case MetricLLMRequests:
+		return u.LLMRequests, true
+	case MetricInputTokens:
+		return u.InputTokens, true
+	case MetricOutputTokens:
+		return u.OutputTokens, true
+	case MetricNotifications:
+		return int64(u.Notifications), true
+	}
+	return 0, false
+}
+
+// ValidateMetric returns an error when metric is not a known metric
+func ValidateMetric(metric string) error {
+	if _, ok := metricValue(showback.TeamUsage{}, metric); !ok {
+		return fmt.Errorf("unknown metering metric %q (valid: %s)", metric, strings.Join(Metrics, ", "))
+	}
+	return nil
+}
+
+// Record is one usage record: the growth of a metric of a tenant
+type Record struct {
+	// ID is the idempotency key of the record
+	ID     string `json:"id"`
+	Tenant string `json:"tenant"`
+	Metric string `json:"metric"`
+	// Quantity is the usage since the previous record of the metric
+	Quantity int64 `json:"quantity"`
+	// Total is the usage of the month including this record
+	Total     int64     `json:"total"`
+	Month     string    `json:"month"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// recordID derives the idempotency key of a record
+func recordID(month, tenant, metric string, total int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", month, tenant, metric, total)))
+	return "agentapi-" + hex.EncodeToString(sum[:16])
+}
+
+// Sink receives usage records
+type Sink interface {
+	Report(ctx context.Context, record Record) error
+}
+
+// UsageSource provides the metered usage of a month
+type UsageSource interface {
+	Month(ctx context.Context, month string) (*showback.Month, error)
+}
+
+// Exporter reports the usage of a UsageSource to a Sink
+type Exporter struct {
+	source  UsageSource
+	sink    Sink
+	metrics []string
+	state   *stateFile
+
+	mu sync.Mutex
+}
+
+// NewExporter creates an Exporter reporting metrics, keeping the reported
+// totals in dir
+func NewExporter(source UsageSource, sink Sink, metrics []string, dir string) (*Exporter, error) {
+	for _, metric := range metrics {
+		if err := ValidateMetric(metric); err != nil {
+			return nil, err
+		}
+	}
+	state, err := loadState(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{source: source, sink: sink, metrics: metrics, state: state}, nil
+}
+
+// Run exports every interval until ctx is done
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := e.Export(ctx, time.Now()); err != nil {
+			log.Printf("[METERING] Export failed after %d record(s): %v", n, err)
+		} else if n > 0 {
+			log.Printf("[METERING] Exported %d usage record(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export reports the usage of the previous and the current month that has
+// not been reported yet and returns the number of records sent. Records of
+// tenants the sink cannot bill are left pending.
+func (e *Exporter) Export(ctx context.Context, now time.Time) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now = now.UTC()
+	months := []string{showback.MonthOf(now.AddDate(0, 0, -now.Day())), showback.MonthOf(now)}
+	sent := 0
+	var firstErr error
+	for _, month := range months {
+		usage, err := e.source.Month(ctx, month)
+		if err != nil {
+			return sent, err
+		}
+		// Usage of a month that has ended is dated at its last second, so
+		// that it falls in the billing period of that month
+		at := now
+		if start, err := showback.ParseMonth(month); err == nil {
+			if end := start.AddDate(0, 1, 0); !now.Before(end) {
+				at = end.Add(-time.Second)
+			}
+		}
+		for _, tenant := range usage.TeamIDs() {
+			for _, metric := range e.metrics {
+				total, _ := metricValue(*usage.Teams[tenant], metric)
+				reported := e.state.reported(month, tenant, metric)
+				if total <= reported {
+					continue
+				}
+				record := Record{
+					ID:        recordID(month, tenant, metric, total),
+					Tenant:    tenant,
+					Metric:    metric,
+					Quantity:  total - reported,
+					Total:     total,
+					Month:     month,
+					Timestamp: at,
+				}
+				if err := e.sink.Report(ctx, record); err != nil {
+					if errors.Is(err, ErrTenantNotMapped) {
+						break
+					}
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to report %s of %s: %w", metric, tenant, err)
+					}
+					continue
+				}
+				e.state.setReported(month, tenant, metric, total)
+				sent++
+			}
+		}
+	}
+	e.state.prune(months)
+	if err := e.state.save(); err != nil {
+		return sent, err
+	}
+	return sent, firstErr
+}
+
+// stateFile keeps the reported monthly totals in <dir>/metering-state.json
+type stateFile struct {
+	path string
+	// Reported maps month → tenant → metric → reported total
+	Reported map[string]map[string]map[string]int64 `json:"reported"`
+}
+
+func loadState(dir string) (*stateFile, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create metering directory: %w", err)
+	}
+	s := &stateFile{path: filepath.Join(dir, "metering-state.json"), Reported: make(map[string]map[string]map[string]int64)}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metering state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse metering state: %w", err)
+	}
+	if s.Reported == nil {
+		s.Reported = make(map[string]map[string]map[string]int64)
+	}
+	return s, nil
+}
+
+func (s *stateFile) reported(month, tenant, metric string) int64 {
+	return s.Reported[month][tenant][metric]
+}
+
+func (s *stateFile) setReported(month, tenant, metric string, total int64) {
+	tenants, ok := s.Reported[month]
+	if !ok {
+		tenants = make(map[string]map[string]int64)
+		s.Reported[month] = tenants
+	}
+	metrics, ok := tenants[tenant]
+	if !ok {
+		metrics = make(map[string]int64)
+		tenants[tenant] = metrics
+	}
+	metrics[metric] = total
+}
+
+// prune drops the months other than keep
+func (s *stateFile) prune(keep []string) {
+	for month := range s.Reported {
+		if !slices.Contains(keep, month) {
+			delete(s.Reported, month)
+		}
+	}
+}
+
+func (s *stateFile) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metering state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write metering state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write metering state: %w", err)
+	}
+	return nil
+}
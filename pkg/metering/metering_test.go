@@ -0,0 +1,136 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+)
+
+type stubSource struct {
+	months map[string]*showback.Month
+}
+
+func (s *stubSource) Month(_ context.Context, month string) (*showback.Month, error) {
+	if m, ok := s.months[month]; ok {
+		return m, nil
+	}
+	return &showback.Month{Month: month}, nil
+}
+
+type recordingSink struct {
+	records []Record
+	fail    error
+}
+
+func (s *recordingSink) Report(_ context.Context, record Record) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	if record.Tenant == "acme/unbilled" {
+		return ErrTenantNotMapped
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestExporterReportsGrowthOnce(t *testing.T) {
+	usage := &showback.TeamUsage{TeamID: "acme/dev", Sessions: 2, SessionHours: 1.5}
+	source := &stubSource{months: map[string]*showback.Month{
+		"2026-10": {Month: "2026-10", Teams: map[string]*showback.TeamUsage{
+			"acme/dev":      usage,
+			"acme/unbilled": {TeamID: "acme/unbilled", Sessions: 1},
+		}},
+	}}
+	sink := &recordingSink{fail: errors.New("unavailable")}
+	dir := t.TempDir()
+	exporter, err := NewExporter(source, sink, []string{MetricSessions, MetricSessionMinutes}, dir)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if _, err := exporter.Export(ctx, now); err == nil {
+		t.Fatal("expected the failed reports to be returned")
+	}
+	sink.fail = nil
+	n, err := exporter.Export(ctx, now.Add(time.Minute))
+	if err != nil || n != 2 {
+		t.Fatalf("Export() = %d, %v; want 2 records", n, err)
+	}
+	if r := sink.records[1]; r.Metric != MetricSessionMinutes || r.Quantity != 90 || r.Total != 90 {
+		t.Errorf("record = %+v, want 90 session minutes", r)
+	}
+
+	// Only the growth is reported, and the state survives a restart
+	usage.Sessions = 3
+	exporter, err = NewExporter(source, sink, []string{MetricSessions, MetricSessionMinutes}, dir)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	if n, err := exporter.Export(ctx, now.Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("Export() = %d, %v; want 1 record", n, err)
+	}
+	last := sink.records[2]
+	if last.Quantity != 1 || last.Total != 3 || last.ID != recordID("2026-10", "acme/dev", MetricSessions, 3) {
+		t.Errorf("record = %+v, want 1 more session", last)
+	}
+	if last.ID == sink.records[0].ID {
+		t.Error("records of different totals must have different IDs")
+	}
+}
+
+func TestStripeSink(t *testing.T) {
+	var form map[string]string
+	var idempotencyKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/billing/meter_events" || r.Header.Get("Authorization") != "Bearer sk_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = r.ParseForm()
+		form = map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		idempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+		_, _ = w.Write([]byte(`{"object":"billing.meter_event"}`))
+	}))
+	defer server.Close()
+
+	sink := NewStripeSink("sk_test", map[string]string{MetricInputTokens: "llm_input_tokens"}, map[string]string{"acme/dev": "cus_123"}).
+		WithBaseURL(server.URL)
+	if got := sink.Metrics(); len(got) != 1 || got[0] != MetricInputTokens {
+		t.Errorf("Metrics() = %v, want only input_tokens", got)
+	}
+
+	record := Record{ID: "agentapi-1", Tenant: "acme/dev", Metric: MetricInputTokens, Quantity: 1200, Timestamp: time.Unix(1760000000, 0)}
+	if err := sink.Report(context.Background(), record); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	want := map[string]string{
+		"event_name":                  "llm_input_tokens",
+		"identifier":                  "agentapi-1",
+		"timestamp":                   "1760000000",
+		"payload[stripe_customer_id]": "cus_123",
+		"payload[value]":              "1200",
+	}
+	for k, v := range want {
+		if form[k] != v {
+			t.Errorf("%s = %q, want %q", k, form[k], v)
+		}
+	}
+	if idempotencyKey != "agentapi-1" {
+		t.Errorf("Idempotency-Key = %q", idempotencyKey)
+	}
+
+	record.Tenant = "acme/ops"
+	if err := sink.Report(context.Background(), record); !errors.Is(err, ErrTenantNotMapped) {
+		t.Errorf("Report() error = %v, want ErrTenantNotMapped", err)
+	}
+}
@@ -0,0 +1,147 @@
+package metering
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+const (
+	// DefaultStripeBaseURL is the Stripe API endpoint
+	DefaultStripeBaseURL = "https://api.stripe.com"
+
+	// SignatureHeader carries the HMAC-SHA256 signature of webhook records
+	SignatureHeader = "X-AgentAPI-Signature-256"
+	// IdempotencyKeyHeader carries the record ID
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	defaultTimeout  = 10 * time.Second
+	maxResponseBody = 4096
+)
+
+// StripeSink reports records as Stripe billing meter events. Each metric is
+// sent to the meter with the configured event name, and each tenant is billed
+// to its Stripe customer.
+type StripeSink struct {
+	apiKey    string
+	baseURL   string
+	events    map[string]string
+	customers map[string]string
+	client    *http.Client
+}
+
+// NewStripeSink creates a StripeSink. events maps metrics to meter event
+// names and customers maps tenants to Stripe customer IDs.
+func NewStripeSink(apiKey string, events, customers map[string]string) *StripeSink {
+	return &StripeSink{
+		apiKey:    apiKey,
+		baseURL:   DefaultStripeBaseURL,
+		events:    events,
+		customers: customers,
+		client:    &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// WithBaseURL sends meter events to baseURL instead of the Stripe API
+func (s *StripeSink) WithBaseURL(baseURL string) *StripeSink {
+	s.baseURL = strings.TrimSuffix(baseURL, "/")
+	return s
+}
+
+// Metrics returns the metrics that have a meter event name, in the order of
+// Metrics
+func (s *StripeSink) Metrics() []string {
+	var metrics []string
+	for _, metric := range Metrics {
+		if s.events[metric] != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// Report creates a meter event for record. The record ID is the event
+// identifier, which Stripe deduplicates.
+func (s *StripeSink) Report(ctx context.Context, record Record) error {
+	customer := s.customers[record.Tenant]
+	if customer == "" {
+		return fmt.Errorf("%w: %s", ErrTenantNotMapped, record.Tenant)
+	}
+	eventName := s.events[record.Metric]
+	if eventName == "" {
+		return fmt.Errorf("no Stripe meter event configured for %s", record.Metric)
+	}
+	form := url.Values{
+		"event_name":                  {eventName},
+		"identifier":                  {record.ID},
+		"timestamp":                   {strconv.FormatInt(record.Timestamp.Unix(), 10)},
+		"payload[stripe_customer_id]": {customer},
+		"payload[value]":              {strconv.FormatInt(record.Quantity, 10)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/billing/meter_events", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(IdempotencyKeyHeader, record.ID)
+	return do(s.client, req, "Stripe")
+}
+
+// WebhookSink posts each record as JSON to a metering webhook, signed with
+// an HMAC-SHA256 of the body when a secret is set
+type WebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookSink creates a WebhookSink posting to url
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{url: url, secret: secret, client: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Report posts record. Receivers should deduplicate records by their ID,
+// which is also sent in the Idempotency-Key header.
+func (s *WebhookSink) Report(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agentapi-proxy-metering")
+	req.Header.Set(IdempotencyKeyHeader, record.ID)
+	if s.secret != "" {
+		req.Header.Set(SignatureHeader, hmacutil.Sign([]byte(s.secret), body))
+	}
+	return do(s.client, req, "metering webhook")
+}
+
+// do sends req and turns a non-2xx response into an error
+func do(client *http.Client, req *http.Request, target string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", target, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return fmt.Errorf("%s responded with status %d: %s", target, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Channel delivers notifications to the subscriptions of one subscription
+// type, e.g. Web Push or Slack DMs
+type Channel interface {
+	SendNotification(sub Subscription, title, body string, data map[string]interface{}) error
+}
+
+// ErrInvalidWebhookURL is returned when a Discord or Microsoft Teams
+// subscription does not point at a webhook of that service
+var ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+
+// discordWebhookHosts are the hosts Discord serves channel webhooks from
+var discordWebhookHosts = []string{"discord.com", "discordapp.com", "canary.discord.com", "ptb.discord.com"}
+
+// teamsWebhookHostSuffixes are the hosts of Teams incoming webhooks: Office
+// 365 connectors and Power Automate workflows
+var teamsWebhookHostSuffixes = []string{".webhook.office.com", ".logic.azure.com", ".api.powerplatform.com"}
+
+// ValidateWebhookURL checks that endpoint is a webhook URL of the service of
+// subType. Only the services' own hosts are accepted so that subscriptions
+// cannot make the proxy post to arbitrary URLs.
+func ValidateWebhookURL(subType, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" {
+		return fmt.Errorf("%w: %s webhooks must be https URLs", ErrInvalidWebhookURL, subType)
+	}
+	host := strings.ToLower(u.Hostname())
+	switch subType {
+	case SubscriptionTypeDiscord:
+		if slices.Contains(discordWebhookHosts, host) && strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return nil
+		}
+	case SubscriptionTypeTeams:
+		for _, suffix := range teamsWebhookHostSuffixes {
+			if strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %s subscriptions have no webhook", ErrInvalidWebhookURL, subType)
+	}
+	return fmt.Errorf("%w: not a %s webhook", ErrInvalidWebhookURL, subType)
+}
+
+// notificationLink returns the session link and the initial message of the
+// session carried in notification data
+func notificationLink(data map[string]interface{}) (url, initialMessage string) {
+	url, _ = data["url"].(string)
+	initialMessage, _ = data["initial_message"].(string)
+	return url, initialMessage
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "..."
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		subType  string
+		endpoint string
+		valid    bool
+	}{
+		{SubscriptionTypeDiscord, "https://discord.com/api/webhooks/123/token", true},
+		{SubscriptionTypeDiscord, "https://discordapp.com/api/webhooks/123/token", true},
+		{SubscriptionTypeDiscord, "http://discord.com/api/webhooks/123/token", false},
+		{SubscriptionTypeDiscord, "https://discord.com/channels/123", false},
+		{SubscriptionTypeDiscord, "https://discord.com.example.com/api/webhooks/123/token", false},
+		{SubscriptionTypeTeams, "https://acme.webhook.office.com/webhookb2/abc", true},
+		{SubscriptionTypeTeams, "https://prod-01.westus.logic.azure.com/workflows/abc", true},
+		{SubscriptionTypeTeams, "https://internal.example.com/webhook.office.com", false},
+		{SubscriptionTypeTeams, "https://acme.webhook.office.com:8443/webhookb2/abc", false},
+		{SubscriptionTypeSlack, "https://hooks.slack.com/services/abc", false},
+	}
+	for _, tt := range tests {
+		err := ValidateWebhookURL(tt.subType, tt.endpoint)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateWebhookURL(%s, %s) error = %v", tt.subType, tt.endpoint, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidWebhookURL) {
+			t.Errorf("ValidateWebhookURL(%s, %s) error = %v, want ErrInvalidWebhookURL", tt.subType, tt.endpoint, err)
+		}
+	}
+}
+
+func TestWebhookChannels(t *testing.T) {
+	var received map[string]interface{}
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sub := Subscription{Endpoint: server.URL}
+	data := map[string]interface{}{"url": "https://agentapi.example.com/sessions/s1", "initial_message": "fix the build"}
+
+	if err := NewDiscordService().SendNotification(sub, "Session ready", "Ready for input", data); err != nil {
+		t.Fatalf("Discord SendNotification() error = %v", err)
+	}
+	embeds, _ := received["embeds"].([]interface{})
+	if len(embeds) != 1 || embeds[0].(map[string]interface{})["url"] != data["url"] {
+		t.Errorf("Discord payload = %v", received)
+	}
+
+	status = http.StatusOK
+	if err := NewTeamsService().SendNotification(sub, "Session ready", "Ready for input", data); err != nil {
+		t.Fatalf("Teams SendNotification() error = %v", err)
+	}
+	if attachments, _ := received["attachments"].([]interface{}); len(attachments) != 1 {
+		t.Errorf("Teams payload = %v", received)
+	}
+
+	// A deleted webhook is not retried
+	status = http.StatusNotFound
+	if err := NewDiscordService().SendNotification(sub, "t", "b", nil); !errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("SendNotification() to a deleted webhook error = %v, want ErrSubscriptionGone", err)
+	}
+}
+
+type fakeChannel struct {
+	sent []Subscription
+}
+
+func (f *fakeChannel) SendNotification(sub Subscription, _, _ string, _ map[string]interface{}) error {
+	f.sent = append(f.sent, sub)
+	return nil
+}
+
+func TestSubscribeWebhook(t *testing.T) {
+	svc := newTestService(t)
+	discord := &fakeChannel{}
+	svc.SetChannel(SubscriptionTypeDiscord, discord)
+	user := entities.NewUser("user-1", entities.UserTypeAPIKey, "user-1")
+
+	if _, err := svc.SubscribeWebhook(user, SubscriptionTypeDiscord, "https://example.com/hook"); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Fatalf("SubscribeWebhook() with a foreign URL error = %v", err)
+	}
+	first, err := svc.SubscribeWebhook(user, SubscriptionTypeDiscord, "https://discord.com/api/webhooks/1/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := svc.SubscribeWebhook(user, SubscriptionTypeDiscord, "https://discord.com/api/webhooks/1/a")
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("resubscribing the same webhook = %+v, %v; want ID %s", again, err, first.ID)
+	}
+	if _, err := svc.SubscribeWebhook(user, SubscriptionTypeDiscord, "https://discord.com/api/webhooks/2/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.NotifySessionEvent("user-1", "session-1", TypeRunCompleted, nil); err != nil {
+		t.Fatalf("NotifySessionEvent() error = %v", err)
+	}
+	if len(discord.sent) != 2 {
+		t.Errorf("Discord channel received %d notifications, want 2", len(discord.sent))
+	}
+}
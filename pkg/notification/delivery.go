@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+)
+
+// DeliveryKind is the delivery queue kind of notification retries
+const DeliveryKind = "notification"
+
+// deliveryPayload is a notification send persisted for retry
+type deliveryPayload struct {
+	// HistoryID is the history entry updated with the outcome of the retry
+	HistoryID        string                 `json:"history_id,omitempty"`
+	Subscription     Subscription           `json:"subscription"`
+	Title            string                 `json:"title"`
+	Body             string                 `json:"body"`
+	NotificationType string                 `json:"notification_type"`
+	Data             map[string]interface{} `json:"data,omitempty"`
+}
+
+// SetDeliveryQueue sets the queue that retries failed sends. Without a queue
+// a failed send is only recorded in the notification history.
+func (s *Service) SetDeliveryQueue(queue *delivery.Queue) {
+	s.deliveryQueue = queue
+	queue.Register(DeliveryKind, s.redeliver)
+}
+
+// queueRetry persists a failed send for retry and reports whether it was
+// queued
+func (s *Service) queueRetry(sub Subscription, historyID, title, body, notificationType string, data map[string]interface{}, sendErr error) bool {
+	if s.deliveryQueue == nil {
+		return false
+	}
+	if errors.Is(sendErr, ErrSubscriptionGone) {
+		// The push service will never accept this subscription again
+		return false
+	}
+	payload := deliveryPayload{
+		HistoryID:        historyID,
+		Subscription:     sub,
+		Title:            title,
+		Body:             body,
+		NotificationType: notificationType,
+		Data:             data,
+	}
+	d, err := s.deliveryQueue.Retry(context.Background(), DeliveryKind, payload, sendErr)
+	if err != nil {
+		log.Printf("[NOTIFICATION_SERVICE] Failed to queue notification retry for user %s: %v", sub.UserID, err)
+		return false
+	}
+	log.Printf("[NOTIFICATION_SERVICE] Queued notification retry %s for user %s", d.ID, sub.UserID)
+	return true
+}
+
+// redeliver is the delivery queue handler for notification retries
+func (s *Service) redeliver(_ context.Context, raw json.RawMessage) error {
+	var p deliveryPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return delivery.Permanent(fmt.Errorf("invalid notification payload: %w", err))
+	}
+
+	sendErr := s.deliver(p.Subscription, p.Title, p.Body, p.Data)
+	if p.HistoryID != "" {
+		s.recordRetry(p, sendErr)
+	} else if sendErr == nil {
+		// Retries queued before history entries were updated add an entry
+		s.recordRedelivered(p)
+	}
+	if errors.Is(sendErr, ErrSubscriptionGone) {
+		return delivery.Permanent(sendErr)
+	}
+	return sendErr
+}
+
+// recordRetry updates the history entry of a retried send with its outcome.
+// The entry may be missing when another replica made the first attempt; a
+// successful retry is then recorded as a new entry.
+func (s *Service) recordRetry(p deliveryPayload, sendErr error) {
+	err := s.storage.UpdateNotificationHistory(p.Subscription.UserID, p.HistoryID, func(h *NotificationHistory) {
+		h.Attempts++
+		switch {
+		case sendErr == nil:
+			h.Delivered = true
+			h.Status = DeliveryStatusDelivered
+			h.ErrorMessage = nil
+		case errors.Is(sendErr, ErrSubscriptionGone):
+			h.Status = DeliveryStatusFailed
+		}
+		if sendErr != nil {
+			errMsg := sendErr.Error()
+			h.ErrorMessage = &errMsg
+		}
+	})
+	if err != nil && sendErr == nil {
+		s.recordRedelivered(p)
+	}
+}
+
+// recordRedelivered adds a history entry for a successful retry
+func (s *Service) recordRedelivered(p deliveryPayload) {
+	history := NotificationHistory{
+		UserID:         p.Subscription.UserID,
+		SubscriptionID: p.Subscription.ID,
+		Title:          p.Title,
+		Body:           p.Body,
+		Type:           p.NotificationType,
+		SessionID:      getSessionIDFromData(p.Data),
+		Data:           p.Data,
+		SentAt:         time.Now(),
+		Delivered:      true,
+		Status:         DeliveryStatusDelivered,
+	}
+	if histErr := s.storage.AddNotificationHistory(p.Subscription.UserID, history); histErr != nil {
+		log.Printf("[NOTIFICATION_SERVICE] Failed to save notification history: %v", histErr)
+	}
+}
@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// discordEmbedColor is the sidebar color of notification embeds
+const discordEmbedColor = 0x5865F2
+
+// DiscordService posts notifications to Discord channel webhooks. The
+// webhook URL is the endpoint of the subscription.
+type DiscordService struct {
+	client *http.Client
+}
+
+// NewDiscordService creates a new Discord webhook service
+func NewDiscordService() *DiscordService {
+	return &DiscordService{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// SendNotification posts a notification to the webhook of sub
+func (s *DiscordService) SendNotification(sub Subscription, title, body string, data map[string]interface{}) error {
+	url, initialMessage := notificationLink(data)
+	return postWebhook(s.client, sub.Endpoint, discordPayload(title, body, url, initialMessage))
+}
+
+// discordPayload builds the webhook message of a notification: one embed
+// linking to the session, with the initial message as its footer
+func discordPayload(title, body, url, initialMessage string) map[string]interface{} {
+	embed := map[string]interface{}{
+		"title":       title,
+		"description": body,
+		"color":       discordEmbedColor,
+	}
+	if url != "" {
+		embed["url"] = url
+	}
+	if initialMessage != "" {
+		embed["footer"] = map[string]interface{}{"text": "💬 " + truncateRunes(initialMessage, 100)}
+	}
+	return map[string]interface{}{
+		"embeds": []interface{}{embed},
+		// Notifications never ping anyone, whatever the texts contain
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	}
+}
+
+// postWebhook posts payload as JSON to a channel webhook. A webhook that no
+// longer exists (404/410) is reported as ErrSubscriptionGone so that it is
+// not retried.
+func postWebhook(client *http.Client, webhookURL string, payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("webhook rejected with status %d: %w", resp.StatusCode, ErrSubscriptionGone)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
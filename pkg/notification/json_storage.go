@@ -110,7 +110,7 @@ func (s *JSONStorage) AddSubscription(userID string, sub Subscription) error {
 
 	// Generate ID if not provided
 	if sub.ID == "" {
-		sub.ID = fmt.Sprintf("sub_%s", uuid.New().String())
+		sub.ID = newSubscriptionID()
 	}
 
 	// Set defaults
@@ -127,6 +127,16 @@ func (s *JSONStorage) AddSubscription(userID string, sub Subscription) error {
 	return s.saveSubscriptions(userID, subscriptions)
 }
 
+// newSubscriptionID returns a new subscription ID
+func newSubscriptionID() string {
+	return fmt.Sprintf("sub_%s", uuid.New().String())
+}
+
+// newNotificationID returns a new notification history ID
+func newNotificationID() string {
+	return fmt.Sprintf("notif_%d_%s", time.Now().Unix(), uuid.New().String()[:8])
+}
+
 // isSameDevice checks if two DeviceInfo objects represent the same device
 func (s *JSONStorage) isSameDevice(device1, device2 *DeviceInfo) bool {
 	// If both are nil, consider them the same (legacy compatibility)
@@ -366,7 +376,7 @@ func (s *JSONStorage) AddNotificationHistory(userID string, notification Notific
 
 	// Generate ID if not provided
 	if notification.ID == "" {
-		notification.ID = fmt.Sprintf("notif_%d_%s", time.Now().Unix(), uuid.New().String()[:8])
+		notification.ID = newNotificationID()
 	}
 
 	// Load existing history
@@ -381,6 +391,25 @@ func (s *JSONStorage) AddNotificationHistory(userID string, notification Notific
 	return s.saveNotificationHistory(userID, history)
 }
 
+// UpdateNotificationHistory applies update to the history entry with the
+// given ID, e.g. to record the outcome of a retried delivery
+func (s *JSONStorage) UpdateNotificationHistory(userID string, notificationID string, update func(*NotificationHistory)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.loadNotificationHistory(userID)
+	if err != nil {
+		return err
+	}
+	for i := range history {
+		if history[i].ID == notificationID {
+			update(&history[i])
+			return s.saveNotificationHistory(userID, history)
+		}
+	}
+	return fmt.Errorf("notification not found: %s", notificationID)
+}
+
 // GetNotificationHistory retrieves notification history with pagination and filtering
 func (s *JSONStorage) GetNotificationHistory(userID string, limit, offset int, filters map[string]string) ([]NotificationHistory, int, error) {
 	s.mu.RLock()
@@ -401,6 +430,9 @@ func (s *JSONStorage) GetNotificationHistory(userID string, limit, offset int, f
 		if notificationType := filters["type"]; notificationType != "" && notification.Type != notificationType {
 			continue
 		}
+		if status := filters["status"]; status != "" && notification.Status != status {
+			continue
+		}
 
 		filteredNotifications = append(filteredNotifications, notification)
 	}
@@ -1,42 +1,136 @@
 package notification
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
 )
 
 // Service provides notification functionality
 type Service struct {
 	storage            Storage
-	webpush            *WebPushService
-	slack              *SlackService            // Optional, for Slack DM notifications
+	channels           map[string]Channel       // Delivery channels by subscription type
 	secretSyncer       SubscriptionSecretSyncer // Optional, for syncing subscriptions to K8s Secrets (legacy)
 	subscriptionReader SubscriptionReader       // Optional, for reading subscriptions from K8s Secrets
 	subscriptionWriter SubscriptionWriter       // Optional, for writing subscriptions directly to K8s Secrets
+	localeResolver     LocaleResolver           // Optional, for localizing notifications per recipient
+	defaultLocale      i18n.Locale              // Locale for recipients without a locale setting
+	deliveryQueue      *delivery.Queue          // Optional, for retrying failed sends
+	deliveredHook      func(sessionID string)   // Optional, called for each notification delivered for a session
+	sessionRouter      SessionRouter            // Optional, decides which users are notified about a session
+	typePreference     TypePreference           // Optional, the notification types each user wants
 }
 
+// LocaleResolver returns the locale selected in a user's profile, or "" if
+// the user has not selected one.
+type LocaleResolver func(userID string) i18n.Locale
+
+// SessionRouter reports whether a user wants notifications about a session,
+// e.g. because the session matches the saved search they route
+// notifications by.
+type SessionRouter func(userID, sessionID string) bool
+
+// TypePreference returns the notification types a user has chosen to
+// receive, or nil if the user has not chosen (all types).
+type TypePreference func(userID string) []string
+
+// ErrSubscriptionNotFound is returned when a user has no subscription with
+// the given endpoint
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrUnknownNotificationType is returned when a subscription or preference
+// names a notification type that does not exist
+var ErrUnknownNotificationType = errors.New("unknown notification type")
+
+// legacyNotificationTypes is the type filter every subscription was created
+// with before the proxy sent session notifications. It is treated as no
+// filter so that those subscriptions receive the newer types too.
+var legacyNotificationTypes = []string{TypeMessage, TypeStatusChange, TypeSessionUpdate, TypeError}
+
 // NewService creates a new notification service
 func NewService(baseDir string) (*Service, error) {
 	storage := NewJSONLStorage(baseDir)
 
+	// Discord and Teams webhooks need no configuration of the proxy
+	channels := map[string]Channel{
+		SubscriptionTypeDiscord: NewDiscordService(),
+		SubscriptionTypeTeams:   NewTeamsService(),
+	}
+
 	// WebPush service is optional - notifications can be stored without sending
-	webpush, _ := NewWebPushService()
+	if webpush, err := NewWebPushService(); err == nil {
+		channels[SubscriptionTypeWebPush] = webpush
+	}
 
 	// Slack service is optional - only available when SLACK_BOT_TOKEN is set
-	slackSvc, _ := NewSlackService()
+	if slackSvc, err := NewSlackService(); err == nil {
+		channels[SubscriptionTypeSlack] = slackSvc
+	}
 
 	return &Service{
-		storage: storage,
-		webpush: webpush,
-		slack:   slackSvc,
+		storage:  storage,
+		channels: channels,
+		// Notifications were Japanese-only before locale support; keep that
+		// for users who have not selected a locale.
+		defaultLocale: i18n.Japanese,
 	}, nil
 }
 
+// SetChannel sets the channel that delivers notifications to subscriptions
+// of subType; nil removes it, failing those deliveries.
+func (s *Service) SetChannel(subType string, channel Channel) {
+	if channel == nil {
+		delete(s.channels, subType)
+		return
+	}
+	s.channels[subType] = channel
+}
+
+// SetLocaleResolver sets the resolver used to localize event notifications
+// for each recipient.
+func (s *Service) SetLocaleResolver(resolver LocaleResolver) {
+	s.localeResolver = resolver
+}
+
+// localeForUser returns the locale to render a user's notifications in.
+func (s *Service) localeForUser(userID string) i18n.Locale {
+	if s.localeResolver != nil {
+		if locale := s.localeResolver(userID); locale != "" {
+			return locale
+		}
+	}
+	if s.defaultLocale != "" {
+		return s.defaultLocale
+	}
+	return i18n.DefaultLocale
+}
+
+// SetSessionRouter sets the router that filters the recipients of session
+// notifications. Notifications sent to a user directly are not filtered.
+func (s *Service) SetSessionRouter(router SessionRouter) {
+	s.sessionRouter = router
+}
+
+// SetTypePreference sets the function that returns the notification types
+// each user wants. Manual notifications are not filtered.
+func (s *Service) SetTypePreference(preference TypePreference) {
+	s.typePreference = preference
+}
+
+// SetSessionDeliveredHook sets a function called for each notification
+// successfully delivered to a subscriber of a session.
+func (s *Service) SetSessionDeliveredHook(hook func(sessionID string)) {
+	s.deliveredHook = hook
+}
+
 // SetSecretSyncer sets the secret syncer for syncing subscriptions to K8s Secrets
 // This is optional and only used when Kubernetes mode is enabled
 func (s *Service) SetSecretSyncer(syncer SubscriptionSecretSyncer) {
@@ -114,21 +208,16 @@ func (s *Service) GetStorage() Storage {
 
 // Subscribe creates a new push notification subscription
 func (s *Service) Subscribe(user *entities.User, endpoint string, keys map[string]string, deviceInfo *DeviceInfo) (*Subscription, error) {
-	// Get username from GitHub user info if available, otherwise use UserID
-	username := string(user.ID())
-	if user.UserType() == entities.UserTypeGitHub && user.GitHubInfo() != nil {
-		username = user.GitHubInfo().Login()
-	}
-
 	now := time.Now()
 	newSub := Subscription{
+		ID:                newSubscriptionID(),
 		UserID:            string(user.ID()),
 		UserType:          string(user.UserType()),
-		Username:          username,
+		Username:          subscriptionUsername(user),
 		Endpoint:          endpoint,
 		Keys:              keys,
 		SessionIDs:        []string{},
-		NotificationTypes: []string{"message", "status_change", "session_update", "error"},
+		NotificationTypes: append([]string(nil), NotificationTypes...),
 		DeviceInfo:        deviceInfo,
 		CreatedAt:         now,
 		UpdatedAt:         now,
@@ -144,6 +233,9 @@ func (s *Service) Subscribe(user *entities.User, endpoint string, keys map[strin
 	replaced := false
 	for _, sub := range current {
 		if sub.Endpoint == endpoint {
+			if sub.ID != "" {
+				newSub.ID = sub.ID
+			}
 			updated = append(updated, newSub)
 			replaced = true
 		} else {
@@ -167,24 +259,20 @@ func (s *Service) SubscribeSlack(user *entities.User, slackUserID string) (*Subs
 		return nil, fmt.Errorf("slack user ID is required")
 	}
 
-	username := string(user.ID())
-	if user.UserType() == entities.UserTypeGitHub && user.GitHubInfo() != nil {
-		username = user.GitHubInfo().Login()
-	}
-
 	userID := string(user.ID())
 	current, _ := s.readCurrentSubscriptions(userID)
 
 	now := time.Now()
 	newSub := Subscription{
+		ID:                newSubscriptionID(),
 		UserID:            userID,
 		UserType:          string(user.UserType()),
-		Username:          username,
+		Username:          subscriptionUsername(user),
 		Type:              SubscriptionTypeSlack,
 		Endpoint:          slackUserID,
 		Keys:              map[string]string{},
 		SessionIDs:        []string{},
-		NotificationTypes: []string{"message", "status_change", "session_update", "error"},
+		NotificationTypes: append([]string(nil), NotificationTypes...),
 		CreatedAt:         now,
 		UpdatedAt:         now,
 		LastUsed:          now,
@@ -207,6 +295,69 @@ func (s *Service) SubscribeSlack(user *entities.User, slackUserID string) (*Subs
 	return &newSub, nil
 }
 
+// SubscribeWebhook creates or updates a Discord or Microsoft Teams
+// subscription of a user to the channel of the incoming webhook URL endpoint.
+// A user may subscribe several channels.
+func (s *Service) SubscribeWebhook(user *entities.User, subType, endpoint string) (*Subscription, error) {
+	if err := ValidateWebhookURL(subType, endpoint); err != nil {
+		return nil, err
+	}
+
+	userID := string(user.ID())
+	current, _ := s.readCurrentSubscriptions(userID)
+
+	now := time.Now()
+	newSub := Subscription{
+		ID:                newSubscriptionID(),
+		UserID:            userID,
+		UserType:          string(user.UserType()),
+		Username:          subscriptionUsername(user),
+		Type:              subType,
+		Endpoint:          endpoint,
+		Keys:              map[string]string{},
+		SessionIDs:        []string{},
+		NotificationTypes: append([]string(nil), NotificationTypes...),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		LastUsed:          now,
+		Active:            true,
+	}
+
+	// Replace an existing sub with the same webhook, or append.
+	updated := make([]Subscription, 0, len(current)+1)
+	replaced := false
+	for _, sub := range current {
+		if sub.Type == subType && sub.Endpoint == endpoint {
+			newSub.ID = sub.ID
+			newSub.SessionIDs = sub.SessionIDs
+			newSub.NotificationTypes = sub.NotificationTypes
+			newSub.CreatedAt = sub.CreatedAt
+			updated = append(updated, newSub)
+			replaced = true
+		} else {
+			updated = append(updated, sub)
+		}
+	}
+	if !replaced {
+		updated = append(updated, newSub)
+	}
+
+	if err := s.persistSubscriptions(userID, updated); err != nil {
+		return nil, fmt.Errorf("failed to save %s subscription: %w", subType, err)
+	}
+
+	return &newSub, nil
+}
+
+// subscriptionUsername returns the GitHub login of user if available,
+// otherwise the user ID
+func subscriptionUsername(user *entities.User) string {
+	if user.UserType() == entities.UserTypeGitHub && user.GitHubInfo() != nil {
+		return user.GitHubInfo().Login()
+	}
+	return string(user.ID())
+}
+
 // DeleteSlackSubscription removes the Slack subscription for a user
 func (s *Service) DeleteSlackSubscription(userID string) error {
 	current, err := s.readCurrentSubscriptions(userID)
@@ -224,7 +375,49 @@ func (s *Service) DeleteSlackSubscription(userID string) error {
 
 // GetSubscriptions returns all active subscriptions for a user
 func (s *Service) GetSubscriptions(userID string) ([]Subscription, error) {
-	return s.storage.GetSubscriptions(userID)
+	return s.readCurrentSubscriptions(userID)
+}
+
+// UpdateSubscription changes the sessions and notification types the
+// subscription of userID with req.Endpoint receives
+func (s *Service) UpdateSubscription(userID string, req UpdateSubscriptionRequest) (*Subscription, error) {
+	if req.NotificationTypes != nil {
+		if err := ValidateNotificationTypes(*req.NotificationTypes); err != nil {
+			return nil, err
+		}
+	}
+	current, err := s.readCurrentSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range current {
+		if current[i].Endpoint != req.Endpoint {
+			continue
+		}
+		if req.SessionIDs != nil {
+			current[i].SessionIDs = append([]string{}, *req.SessionIDs...)
+		}
+		if req.NotificationTypes != nil {
+			current[i].NotificationTypes = append([]string{}, *req.NotificationTypes...)
+		}
+		current[i].UpdatedAt = time.Now()
+		if err := s.persistSubscriptions(userID, current); err != nil {
+			return nil, fmt.Errorf("failed to save subscription: %w", err)
+		}
+		return &current[i], nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, req.Endpoint)
+}
+
+// ValidateNotificationTypes checks that every entry of types is one of
+// NotificationTypes
+func ValidateNotificationTypes(types []string) error {
+	for _, t := range types {
+		if !slices.Contains(NotificationTypes, t) {
+			return fmt.Errorf("%w: %s", ErrUnknownNotificationType, t)
+		}
+	}
+	return nil
 }
 
 // DeleteSubscription removes a subscription by endpoint
@@ -280,74 +473,23 @@ func (s *Service) SendNotificationToUser(userID string, title, body, notificatio
 
 	var lastError error
 	successCount := 0
+	wanted := s.userWants(userID, notificationType)
 
 	for _, sub := range subscriptions {
 		// Skip inactive subscriptions (channel disabled by user)
 		if !sub.Active {
 			continue
 		}
-		// Check if subscription wants this notification type
-		if !s.shouldSendNotification(sub, notificationType, data) {
+		// Check if the user and the subscription want this notification type
+		if !wanted || !s.shouldSendNotification(sub, notificationType, data) {
 			continue
 		}
 
-		var sendErr error
-		subType := sub.Type
-		if subType == "" {
-			subType = SubscriptionTypeWebPush // backward compat
-		}
-
-		switch subType {
-		case SubscriptionTypeWebPush:
-			if s.webpush == nil {
-				sendErr = fmt.Errorf("web push service not configured")
-			} else {
-				sendErr = s.webpush.SendNotification(sub, title, body, data)
-			}
-		case SubscriptionTypeSlack:
-			if s.slack == nil {
-				sendErr = fmt.Errorf("slack service not configured")
-			} else {
-				url := ""
-				if u, ok := data["url"].(string); ok {
-					url = u
-				}
-				initialMessage := ""
-				if im, ok := data["initial_message"].(string); ok {
-					initialMessage = im
-				}
-				sendErr = s.slack.SendDM(sub.Endpoint, title, body, url, initialMessage)
-			}
-		default:
-			sendErr = fmt.Errorf("unsupported subscription type: %s", subType)
-		}
-
-		// Record history
-		history := NotificationHistory{
-			UserID:         userID,
-			SubscriptionID: sub.ID,
-			Title:          title,
-			Body:           body,
-			Type:           notificationType,
-			SessionID:      getSessionIDFromData(data),
-			Data:           data,
-			SentAt:         time.Now(),
-			Delivered:      sendErr == nil,
-		}
-
-		if sendErr != nil {
-			errMsg := sendErr.Error()
-			history.ErrorMessage = &errMsg
+		if sendErr := s.send(sub, title, body, notificationType, data); sendErr != nil {
 			lastError = sendErr
 		} else {
 			successCount++
 		}
-
-		// Save to history
-		if histErr := s.storage.AddNotificationHistory(userID, history); histErr != nil {
-			// Log but don't fail the notification send
-			fmt.Printf("Failed to save notification history: %v\n", histErr)
-		}
 	}
 
 	if successCount == 0 && lastError != nil {
@@ -359,19 +501,45 @@ func (s *Service) SendNotificationToUser(userID string, title, body, notificatio
 
 // SendNotificationToSession sends a notification to all users subscribed to a session
 func (s *Service) SendNotificationToSession(sessionID string, title, body, notificationType string, data map[string]interface{}) error {
+	return s.sendToSession(sessionID, "", notificationType, data, func(string) (string, string) {
+		return title, body
+	})
+}
+
+// sendLocalizedToSession sends the catalog messages titleID and bodyID to the
+// users subscribed to a session, each in the user's locale. userID restricts
+// the recipients to one user; "" sends to all subscribers.
+func (s *Service) sendLocalizedToSession(sessionID, userID, titleID, bodyID, notificationType string, data map[string]interface{}) error {
+	return s.sendToSession(sessionID, userID, notificationType, data, func(userID string) (string, string) {
+		locale := s.localeForUser(userID)
+		return i18n.T(locale, titleID), i18n.T(locale, bodyID)
+	})
+}
+
+// sendToSession sends a notification rendered by render for each recipient
+// to the users subscribed to a session: userID, or all users when "".
+func (s *Service) sendToSession(sessionID, userID, notificationType string, data map[string]interface{}, render func(userID string) (title, body string)) error {
 	// Add session ID to data
 	if data == nil {
 		data = make(map[string]interface{})
 	}
 	data["session_id"] = sessionID
 
-	subscriptions, err := s.getAllSubscriptions()
+	var subscriptions []Subscription
+	var err error
+	if userID != "" {
+		subscriptions, err = s.getSubscriptionsForUser(userID)
+	} else {
+		subscriptions, err = s.getAllSubscriptions()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get subscriptions: %w", err)
 	}
 
 	var lastError error
 	successCount := 0
+	routed := map[string]bool{}
+	wanted := map[string]bool{}
 
 	for _, sub := range subscriptions {
 		// Skip inactive subscriptions (channel disabled by user)
@@ -382,68 +550,37 @@ func (s *Service) SendNotificationToSession(sessionID string, title, body, notif
 		if !s.isSubscribedToSession(sub, sessionID) {
 			continue
 		}
-
-		// Check if subscription wants this notification type
-		if !s.shouldSendNotification(sub, notificationType, data) {
-			continue
-		}
-
-		var sendErr error
-		subType := sub.Type
-		if subType == "" {
-			subType = SubscriptionTypeWebPush // backward compat
-		}
-
-		switch subType {
-		case SubscriptionTypeWebPush:
-			if s.webpush == nil {
-				sendErr = fmt.Errorf("web push service not configured")
-			} else {
-				sendErr = s.webpush.SendNotification(sub, title, body, data)
+		// Check if the user's routing wants this session
+		if s.sessionRouter != nil {
+			wanted, ok := routed[sub.UserID]
+			if !ok {
+				wanted = s.sessionRouter(sub.UserID, sessionID)
+				routed[sub.UserID] = wanted
 			}
-		case SubscriptionTypeSlack:
-			if s.slack == nil {
-				sendErr = fmt.Errorf("slack service not configured")
-			} else {
-				url := ""
-				if u, ok := data["url"].(string); ok {
-					url = u
-				}
-				initialMessage := ""
-				if im, ok := data["initial_message"].(string); ok {
-					initialMessage = im
-				}
-				sendErr = s.slack.SendDM(sub.Endpoint, title, body, url, initialMessage)
+			if !wanted {
+				continue
 			}
-		default:
-			sendErr = fmt.Errorf("unsupported subscription type: %s", subType)
 		}
 
-		// Record history
-		history := NotificationHistory{
-			UserID:         sub.UserID,
-			SubscriptionID: sub.ID,
-			Title:          title,
-			Body:           body,
-			Type:           notificationType,
-			SessionID:      sessionID,
-			Data:           data,
-			SentAt:         time.Now(),
-			Delivered:      sendErr == nil,
+		// Check if the user and the subscription want this notification type
+		userWants, ok := wanted[sub.UserID]
+		if !ok {
+			userWants = s.userWants(sub.UserID, notificationType)
+			wanted[sub.UserID] = userWants
 		}
+		if !userWants || !s.shouldSendNotification(sub, notificationType, data) {
+			continue
+		}
+
+		title, body := render(sub.UserID)
 
-		if sendErr != nil {
-			errMsg := sendErr.Error()
-			history.ErrorMessage = &errMsg
+		if sendErr := s.send(sub, title, body, notificationType, data); sendErr != nil {
 			lastError = sendErr
 		} else {
 			successCount++
-		}
-
-		// Save to history
-		if histErr := s.storage.AddNotificationHistory(sub.UserID, history); histErr != nil {
-			// Log but don't fail the notification send
-			fmt.Printf("Failed to save notification history: %v\n", histErr)
+			if s.deliveredHook != nil {
+				s.deliveredHook(sessionID)
+			}
 		}
 	}
 
@@ -454,53 +591,94 @@ func (s *Service) SendNotificationToSession(sessionID string, title, body, notif
 	return nil
 }
 
+// send delivers a notification to one subscription and records the outcome
+// in the history of its user. A failed send queued for retry is recorded as
+// retrying and updated when the retry completes.
+func (s *Service) send(sub Subscription, title, body, notificationType string, data map[string]interface{}) error {
+	history := NotificationHistory{
+		ID:             newNotificationID(),
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		Title:          title,
+		Body:           body,
+		Type:           notificationType,
+		SessionID:      getSessionIDFromData(data),
+		Data:           data,
+		SentAt:         time.Now(),
+		Attempts:       1,
+	}
+
+	sendErr := s.deliver(sub, title, body, data)
+	switch {
+	case sendErr == nil:
+		history.Delivered = true
+		history.Status = DeliveryStatusDelivered
+	case s.queueRetry(sub, history.ID, title, body, notificationType, data, sendErr):
+		history.Status = DeliveryStatusRetrying
+	default:
+		history.Status = DeliveryStatusFailed
+	}
+	if sendErr != nil {
+		errMsg := sendErr.Error()
+		history.ErrorMessage = &errMsg
+	}
+
+	if histErr := s.storage.AddNotificationHistory(sub.UserID, history); histErr != nil {
+		// Log but don't fail the notification send
+		log.Printf("[NOTIFICATION_SERVICE] Failed to save notification history: %v", histErr)
+	}
+	return sendErr
+}
+
+// deliver sends a notification to one subscription
+func (s *Service) deliver(sub Subscription, title, body string, data map[string]interface{}) error {
+	subType := subscriptionType(sub)
+	if !slices.Contains(SubscriptionTypes, subType) {
+		return fmt.Errorf("unsupported subscription type: %s", subType)
+	}
+	channel, ok := s.channels[subType]
+	if !ok {
+		return fmt.Errorf("%s channel not configured", subType)
+	}
+	return channel.SendNotification(sub, title, body, data)
+}
+
 // ProcessWebhook handles incoming webhooks from agentapi
 func (s *Service) ProcessWebhook(webhook WebhookRequest) error {
-	// Map event types to notification parameters
-	var title, body, notificationType string
 	data := webhook.Data
 	if data == nil {
 		data = make(map[string]interface{})
 	}
 	// Set URL only if not already provided (e.g., enriched by the webhook handler)
 	if _, exists := data["url"]; !exists {
-		if baseURL := os.Getenv("NOTIFICATION_BASE_URL"); baseURL != "" {
-			data["url"] = baseURL + "/sessions/" + webhook.SessionID
-		} else {
-			data["url"] = fmt.Sprintf("/sessions/%s", webhook.SessionID)
-		}
+		data["url"] = sessionURL(webhook.SessionID)
 	}
 
-	switch webhook.EventType {
-	case "message_received":
-		title = "新しいメッセージ"
-		body = "Claude からの返答が到着しました"
-		notificationType = "message"
-	case "status_change":
-		status, _ := data["status"].(string)
-		if status == "running" {
-			title = "ステータス変更"
-			body = "エージェントが応答中です"
-		} else {
-			title = "ステータス変更"
-			body = "エージェントの応答が完了しました"
-		}
-		notificationType = "status_change"
-	case "session_update":
-		title = "セッション更新"
-		body = "セッションが更新されました"
-		notificationType = "session_update"
-	case "error":
-		title = "エラー発生"
-		body = "セッションでエラーが発生しました"
-		notificationType = "error"
-	default:
+	titleID, bodyID, notificationType, ok := eventMessageIDs(webhook.EventType, data)
+	if !ok {
 		// Unknown event type, skip
 		return nil
 	}
 
-	// Send notification to all users subscribed to this session
-	return s.SendNotificationToSession(webhook.SessionID, title, body, notificationType, data)
+	// Send notification to all users subscribed to this session, each in their locale
+	return s.sendLocalizedToSession(webhook.SessionID, "", titleID, bodyID, notificationType, data)
+}
+
+// NotifySessionEvent sends the notification of eventType (e.g.
+// "run_completed") about a session to the subscriptions of userID that
+// follow the session, in the user's locale
+func (s *Service) NotifySessionEvent(userID, sessionID, eventType string, data map[string]interface{}) error {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if _, exists := data["url"]; !exists {
+		data["url"] = sessionURL(sessionID)
+	}
+	titleID, bodyID, notificationType, ok := eventMessageIDs(eventType, data)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+	return s.sendLocalizedToSession(sessionID, userID, titleID, bodyID, notificationType, data)
 }
 
 // GetNotificationHistory retrieves notification history for a user
@@ -523,27 +701,28 @@ func (s *Service) GetNotificationHistory(userID string, limit, offset int, filte
 func (s *Service) shouldSendNotification(sub Subscription, notificationType string, data map[string]interface{}) bool {
 	// "manual" notifications (explicitly triggered via API/CLI) are always delivered
 	// regardless of the subscription's notification type filter.
-	if notificationType == "manual" {
+	if notificationType == TypeManual {
 		return true
 	}
 
 	// Check if subscription wants this notification type
-	if len(sub.NotificationTypes) > 0 {
-		found := false
-		for _, nt := range sub.NotificationTypes {
-			if nt == notificationType {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
+	if len(sub.NotificationTypes) > 0 && !slices.Equal(sub.NotificationTypes, legacyNotificationTypes) {
+		return slices.Contains(sub.NotificationTypes, notificationType)
 	}
 
 	return true
 }
 
+// userWants reports whether userID's preference includes notificationType.
+// Users without a preference receive all types.
+func (s *Service) userWants(userID, notificationType string) bool {
+	if notificationType == TypeManual || s.typePreference == nil {
+		return true
+	}
+	types := s.typePreference(userID)
+	return len(types) == 0 || slices.Contains(types, notificationType)
+}
+
 // isSubscribedToSession checks if a subscription is for a specific session
 func (s *Service) isSubscribedToSession(sub Subscription, sessionID string) bool {
 	// Empty session_ids means subscribed to all sessions
@@ -561,6 +740,40 @@ func (s *Service) isSubscribedToSession(sub Subscription, sessionID string) bool
 	return false
 }
 
+// sessionURL returns the link to a session shown in notifications
+func sessionURL(sessionID string) string {
+	if baseURL := os.Getenv("NOTIFICATION_BASE_URL"); baseURL != "" {
+		return baseURL + "/sessions/" + sessionID
+	}
+	return fmt.Sprintf("/sessions/%s", sessionID)
+}
+
+// eventMessageIDs maps an agentapi event type to the catalog IDs of its
+// notification title and body. It reports false for unknown event types.
+func eventMessageIDs(eventType string, data map[string]interface{}) (titleID, bodyID, notificationType string, ok bool) {
+	switch eventType {
+	case "message_received":
+		return "notification.message_received.title", "notification.message_received.body", "message", true
+	case "status_change":
+		status, _ := data["status"].(string)
+		if status == "running" {
+			return "notification.status_change.title", "notification.status_change.running.body", "status_change", true
+		}
+		return "notification.status_change.title", "notification.status_change.stable.body", "status_change", true
+	case "session_update":
+		return "notification.session_update.title", "notification.session_update.body", "session_update", true
+	case "error":
+		return "notification.error.title", "notification.error.body", "error", true
+	case TypeSessionReady:
+		return "notification.session_ready.title", "notification.session_ready.body", TypeSessionReady, true
+	case TypeSessionFailed:
+		return "notification.session_failed.title", "notification.session_failed.body", TypeSessionFailed, true
+	case TypeRunCompleted:
+		return "notification.run_completed.title", "notification.run_completed.body", TypeRunCompleted, true
+	}
+	return "", "", "", false
+}
+
 // getSessionIDFromData extracts session ID from notification data
 func getSessionIDFromData(data map[string]interface{}) string {
 	if data == nil {
@@ -600,9 +813,9 @@ func (s *Service) SendNotification(req SendNotificationRequest) (*SendNotificati
 
 	var err error
 	if req.SessionID != "" {
-		err = s.SendNotificationToSession(req.SessionID, req.Title, req.Body, "manual", data)
+		err = s.SendNotificationToSession(req.SessionID, req.Title, req.Body, TypeManual, data)
 	} else {
-		err = s.SendNotificationToUser(req.UserID, req.Title, req.Body, "manual", data)
+		err = s.SendNotificationToUser(req.UserID, req.Title, req.Body, TypeManual, data)
 	}
 
 	if err != nil {
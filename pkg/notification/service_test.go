@@ -0,0 +1,218 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
+)
+
+// newTestService creates a Service in a temporary directory. Storage
+// updates the last used time of subscriptions in the background, so failing
+// to remove the directory is not an error as it is with t.TempDir().
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	tmpDir, err := os.MkdirTemp("", "notification_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Logf("Failed to remove temp dir: %v", err)
+		}
+	})
+	svc, err := NewService(tmpDir)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc
+}
+
+func TestProcessWebhookLocalizesPerRecipient(t *testing.T) {
+	svc := newTestService(t)
+	// Without a web push service every delivery fails, but history still
+	// records the rendered texts.
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	svc.SetLocaleResolver(func(userID string) i18n.Locale {
+		if userID == "english-user" {
+			return i18n.English
+		}
+		return ""
+	})
+
+	for _, userID := range []string{"english-user", "default-user"} {
+		sub := Subscription{
+			ID:       userID + "-sub",
+			UserID:   userID,
+			Type:     SubscriptionTypeWebPush,
+			Endpoint: "https://push.example.com/" + userID,
+			Active:   true,
+		}
+		if err := svc.storage.AddSubscription(userID, sub); err != nil {
+			t.Fatalf("AddSubscription() error = %v", err)
+		}
+	}
+
+	_ = svc.ProcessWebhook(WebhookRequest{SessionID: "session-1", EventType: "message_received"})
+
+	want := map[string]string{
+		"english-user": "New message",
+		"default-user": "新しいメッセージ",
+	}
+	for userID, title := range want {
+		history, _, err := svc.storage.GetNotificationHistory(userID, 10, 0, nil)
+		if err != nil {
+			t.Fatalf("GetNotificationHistory(%s) error = %v", userID, err)
+		}
+		if len(history) != 1 || history[0].Title != title {
+			t.Errorf("history of %s = %+v, want one notification titled %q", userID, history, title)
+		}
+	}
+}
+
+func TestProcessWebhookSkipsUsersRejectedBySessionRouter(t *testing.T) {
+	svc := newTestService(t)
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	svc.SetSessionRouter(func(userID, sessionID string) bool {
+		return userID == "routed-user" && sessionID == "session-1"
+	})
+
+	for _, userID := range []string{"routed-user", "filtered-user"} {
+		sub := Subscription{
+			ID:       userID + "-sub",
+			UserID:   userID,
+			Type:     SubscriptionTypeWebPush,
+			Endpoint: "https://push.example.com/" + userID,
+			Active:   true,
+		}
+		if err := svc.storage.AddSubscription(userID, sub); err != nil {
+			t.Fatalf("AddSubscription() error = %v", err)
+		}
+	}
+
+	_ = svc.ProcessWebhook(WebhookRequest{SessionID: "session-1", EventType: "message_received"})
+
+	for userID, want := range map[string]int{"routed-user": 1, "filtered-user": 0} {
+		history, _, err := svc.storage.GetNotificationHistory(userID, 10, 0, nil)
+		if err != nil {
+			t.Fatalf("GetNotificationHistory(%s) error = %v", userID, err)
+		}
+		if len(history) != want {
+			t.Errorf("history of %s has %d notifications, want %d", userID, len(history), want)
+		}
+	}
+}
+
+func TestTypePreferenceAndLegacySubscriptions(t *testing.T) {
+	svc := newTestService(t)
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	svc.SetTypePreference(func(userID string) []string {
+		if userID == "picky-user" {
+			return []string{TypeSessionFailed}
+		}
+		return nil
+	})
+
+	for _, userID := range []string{"picky-user", "legacy-user"} {
+		sub := Subscription{
+			ID:                userID + "-sub",
+			UserID:            userID,
+			Endpoint:          "https://push.example.com/" + userID,
+			NotificationTypes: legacyNotificationTypes,
+			Active:            true,
+		}
+		if err := svc.storage.AddSubscription(userID, sub); err != nil {
+			t.Fatalf("AddSubscription() error = %v", err)
+		}
+	}
+
+	for _, userID := range []string{"picky-user", "legacy-user"} {
+		_ = svc.NotifySessionEvent(userID, "session-1", TypeRunCompleted, nil)
+	}
+
+	for userID, want := range map[string]int{"picky-user": 0, "legacy-user": 1} {
+		history, _, err := svc.storage.GetNotificationHistory(userID, 10, 0, map[string]string{"type": TypeRunCompleted})
+		if err != nil {
+			t.Fatalf("GetNotificationHistory(%s) error = %v", userID, err)
+		}
+		if len(history) != want {
+			t.Errorf("history of %s has %d run_completed notifications, want %d", userID, len(history), want)
+		}
+	}
+	// Manual notifications ignore the preference
+	if err := svc.SendNotificationToUser("picky-user", "Hi", "Manual", TypeManual, nil); err == nil {
+		t.Error("SendNotificationToUser() without a web push service succeeded")
+	}
+	if _, total, _ := svc.storage.GetNotificationHistory("picky-user", 10, 0, nil); total != 1 {
+		t.Errorf("manual notification filtered by preference: %d entries", total)
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	svc := newTestService(t)
+	sub := Subscription{ID: "sub-1", UserID: "user-1", Endpoint: "https://push.example.com/1", Active: true}
+	if err := svc.storage.AddSubscription("user-1", sub); err != nil {
+		t.Fatal(err)
+	}
+
+	types := []string{TypeRunCompleted, TypeSessionFailed}
+	sessions := []string{"session-1"}
+	updated, err := svc.UpdateSubscription("user-1", UpdateSubscriptionRequest{
+		Endpoint: sub.Endpoint, NotificationTypes: &types, SessionIDs: &sessions,
+	})
+	if err != nil {
+		t.Fatalf("UpdateSubscription() error = %v", err)
+	}
+	if len(updated.NotificationTypes) != 2 || len(updated.SessionIDs) != 1 {
+		t.Fatalf("updated subscription = %+v", updated)
+	}
+	subs, _ := svc.GetSubscriptions("user-1")
+	if len(subs) != 1 || len(subs[0].NotificationTypes) != 2 {
+		t.Fatalf("stored subscriptions = %+v", subs)
+	}
+
+	bogus := []string{"everything"}
+	if _, err := svc.UpdateSubscription("user-1", UpdateSubscriptionRequest{Endpoint: sub.Endpoint, NotificationTypes: &bogus}); !errors.Is(err, ErrUnknownNotificationType) {
+		t.Errorf("UpdateSubscription() with an unknown type = %v", err)
+	}
+	if _, err := svc.UpdateSubscription("user-1", UpdateSubscriptionRequest{Endpoint: "https://push.example.com/2"}); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("UpdateSubscription() of an unknown endpoint = %v", err)
+	}
+}
+
+func TestDeliveryStatusTracksRetries(t *testing.T) {
+	svc := newTestService(t)
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	store, err := delivery.NewFileStore(filepath.Join(t.TempDir(), "deliveries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetDeliveryQueue(delivery.NewQueue(store, delivery.Options{}))
+	sub := Subscription{ID: "sub-1", UserID: "user-1", Endpoint: "https://push.example.com/1", Active: true}
+	if err := svc.storage.AddSubscription("user-1", sub); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = svc.NotifySessionEvent("user-1", "session-1", TypeSessionReady, nil)
+	history, _, _ := svc.storage.GetNotificationHistory("user-1", 10, 0, map[string]string{"status": DeliveryStatusRetrying})
+	if len(history) != 1 || history[0].Attempts != 1 || history[0].Delivered {
+		t.Fatalf("history after a failed send = %+v", history)
+	}
+
+	// A retry that fails again is counted on the same entry
+	payload, err := json.Marshal(deliveryPayload{HistoryID: history[0].ID, Subscription: sub, NotificationType: TypeSessionReady})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.redeliver(t.Context(), payload); err == nil {
+		t.Fatal("redeliver() without a web push service succeeded")
+	}
+	history, total, _ := svc.storage.GetNotificationHistory("user-1", 10, 0, nil)
+	if total != 1 || history[0].Attempts != 2 || history[0].Status != DeliveryStatusRetrying {
+		t.Fatalf("history after a failed retry = %+v", history)
+	}
+}
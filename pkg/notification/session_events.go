@@ -0,0 +1,117 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// sessionEventTypes maps session timeline events to the notification event
+// types sent for them. Timeline events without an entry do not notify.
+var sessionEventTypes = map[entities.SessionEventType]string{
+	entities.SessionEventProvisioned:     TypeSessionReady,
+	entities.SessionEventProvisionFailed: TypeSessionFailed,
+	entities.SessionEventStartupTimeout:  TypeSessionFailed,
+	entities.SessionEventCrashed:         TypeSessionFailed,
+	entities.SessionEventEvicted:         TypeSessionFailed,
+	entities.SessionEventJobFailed:       TypeSessionFailed,
+	entities.SessionEventCompleted:       TypeRunCompleted,
+	entities.SessionEventJobCompleted:    TypeRunCompleted,
+}
+
+// SessionLookup returns a session by ID, or nil if it does not exist
+type SessionLookup func(id string) entities.Session
+
+// SessionNotifier sends push notifications from the proxy when sessions
+// become ready, fail or finish a run, without relying on the agent in the
+// session Pod to call the notification API.
+type SessionNotifier struct {
+	service *Service
+	session SessionLookup
+
+	// lastStatus is the last agent status seen per session, used to detect
+	// completed runs
+	mu         sync.Mutex
+	lastStatus map[string]string
+}
+
+// NewSessionNotifier creates a SessionNotifier. session is used to find the
+// owner of the session of each event.
+func NewSessionNotifier(service *Service, session SessionLookup) *SessionNotifier {
+	return &SessionNotifier{
+		service:    service,
+		session:    session,
+		lastStatus: make(map[string]string),
+	}
+}
+
+// Recorder wraps a session event recorder so that recorded lifecycle events
+// also notify the owners of the sessions
+func (n *SessionNotifier) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &recorder{EventRecorder: inner, notifier: n}
+}
+
+type recorder struct {
+	portrepos.EventRecorder
+	notifier *SessionNotifier
+}
+
+func (r *recorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	r.notifier.HandleSessionEvent(event)
+	return r.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent notifies the owner of the session of a timeline event
+// in the background
+func (n *SessionNotifier) HandleSessionEvent(event entities.SessionEvent) {
+	if event.Type == entities.SessionEventDeleted {
+		n.mu.Lock()
+		delete(n.lastStatus, event.SessionID)
+		n.mu.Unlock()
+		return
+	}
+	eventType, ok := sessionEventTypes[event.Type]
+	if !ok {
+		return
+	}
+	n.notifyAsync(event.SessionID, eventType, event.Message)
+}
+
+// ObserveStatus tracks the agent status of a session and notifies its owner
+// when the agent goes from running back to idle ("active")
+func (n *SessionNotifier) ObserveStatus(sessionID, status string) {
+	n.mu.Lock()
+	previous := n.lastStatus[sessionID]
+	n.lastStatus[sessionID] = status
+	n.mu.Unlock()
+
+	if previous == "running" && status == "active" {
+		n.notifyAsync(sessionID, TypeRunCompleted, "")
+	}
+}
+
+// notifyAsync sends the notification to the owner of a user-scoped session.
+// Team-scoped sessions do not trigger push notifications, like notifications
+// sent through the API.
+func (n *SessionNotifier) notifyAsync(sessionID, eventType, message string) {
+	session := n.session(sessionID)
+	if session == nil || session.Scope() == entities.ScopeTeam {
+		return
+	}
+	userID := session.UserID()
+	data := map[string]interface{}{}
+	if desc := session.Description(); desc != "" {
+		data["initial_message"] = desc
+	}
+	if message != "" {
+		data["message"] = message
+	}
+	go func() {
+		if err := n.service.NotifySessionEvent(userID, sessionID, eventType, data); err != nil {
+			log.Printf("[NOTIFICATION_SERVICE] Failed to notify %s of %s for session %s: %v", userID, eventType, sessionID, err)
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// waitForHistory waits until userID has want notifications of type
+// notificationType; notifications of session events are sent in the
+// background
+func waitForHistory(t *testing.T, svc *Service, userID, notificationType string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, total, err := svc.storage.GetNotificationHistory(userID, 10, 0, map[string]string{"type": notificationType})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d %s notifications, want %d", userID, total, notificationType, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionNotifier(t *testing.T) {
+	svc := newTestService(t)
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	for _, userID := range []string{"alice", "bob"} {
+		sub := Subscription{ID: userID + "-sub", UserID: userID, Endpoint: "https://push.example.com/" + userID, Active: true}
+		if err := svc.storage.AddSubscription(userID, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessions := map[string]entities.Session{
+		"mine": entities.NewProxySessionWithStatus("mine", "alice", entities.ScopeUser, "", nil, time.Now(), "active"),
+		"team": entities.NewProxySessionWithStatus("team", "bob", entities.ScopeTeam, "acme/ml", nil, time.Now(), "active"),
+	}
+	notifier := NewSessionNotifier(svc, func(id string) entities.Session { return sessions[id] })
+
+	notifier.HandleSessionEvent(entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventProvisioned})
+	notifier.HandleSessionEvent(entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventCrashed, Message: "OOMKilled"})
+	notifier.HandleSessionEvent(entities.SessionEvent{SessionID: "mine", Type: entities.SessionEventMessageSent})
+	waitForHistory(t, svc, "alice", TypeSessionReady, 1)
+	waitForHistory(t, svc, "alice", TypeSessionFailed, 1)
+
+	// A run completes when the agent goes from running back to idle
+	notifier.ObserveStatus("mine", "active")
+	notifier.ObserveStatus("mine", "running")
+	notifier.ObserveStatus("mine", "active")
+	waitForHistory(t, svc, "alice", TypeRunCompleted, 1)
+
+	// Team-scoped and unknown sessions do not notify
+	notifier.HandleSessionEvent(entities.SessionEvent{SessionID: "team", Type: entities.SessionEventProvisioned})
+	notifier.HandleSessionEvent(entities.SessionEvent{SessionID: "gone", Type: entities.SessionEventProvisioned})
+	time.Sleep(50 * time.Millisecond)
+	waitForHistory(t, svc, "bob", TypeSessionReady, 0)
+}
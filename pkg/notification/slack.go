@@ -1,6 +1,7 @@
 package notification
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
@@ -36,6 +37,51 @@ func (s *SlackService) SendDM(slackUserID, title, body, url, initialMessage stri
 		return fmt.Errorf("failed to open DM channel with user %s: %w", slackUserID, err)
 	}
 
+	blocks := slackDMBlocks(title, body, url, initialMessage)
+
+	_, _, err = s.client.PostMessage(
+		channel.ID,
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionText(title+"\n"+body, false),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send Slack DM: %w", err)
+	}
+
+	return nil
+}
+
+// SendNotification sends a notification as a DM to the Slack user ID of sub
+func (s *SlackService) SendNotification(sub Subscription, title, body string, data map[string]interface{}) error {
+	url, initialMessage := notificationLink(data)
+	return s.SendDM(sub.Endpoint, title, body, url, initialMessage)
+}
+
+// PostToChannel posts a plain text message to a Slack channel
+func (s *SlackService) PostToChannel(channel, text string) error {
+	if _, _, err := s.client.PostMessage(channel, slack.MsgOptionText(text, false)); err != nil {
+		return fmt.Errorf("failed to post to Slack channel %s: %w", channel, err)
+	}
+	return nil
+}
+
+// UploadToChannel uploads a file to a Slack channel
+func (s *SlackService) UploadToChannel(channel, filename, title string, data []byte) error {
+	_, err := s.client.UploadFile(slack.UploadFileParameters{
+		Reader:   bytes.NewReader(data),
+		FileSize: len(data),
+		Filename: filename,
+		Title:    title,
+		Channel:  channel,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to Slack channel %s: %w", filename, channel, err)
+	}
+	return nil
+}
+
+// slackDMBlocks builds the Block Kit layout of a notification DM
+func slackDMBlocks(title, body, url, initialMessage string) []slack.Block {
 	// Build content text
 	contentText := fmt.Sprintf("*%s*\n%s", title, body)
 
@@ -74,14 +120,5 @@ func (s *SlackService) SendDM(slackUserID, title, body, url, initialMessage stri
 		))
 	}
 
-	_, _, err = s.client.PostMessage(
-		channel.ID,
-		slack.MsgOptionBlocks(blocks...),
-		slack.MsgOptionText(title+"\n"+body, false),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to send Slack DM: %w", err)
-	}
-
-	return nil
+	return blocks
 }
@@ -12,6 +12,7 @@ type Storage interface {
 	// History methods
 	AddNotificationHistory(userID string, notification NotificationHistory) error
 	GetNotificationHistory(userID string, limit, offset int, filters map[string]string) ([]NotificationHistory, int, error)
+	UpdateNotificationHistory(userID string, notificationID string, update func(*NotificationHistory)) error
 	RotateNotificationHistory(userID string, maxEntries int) error
 }
 
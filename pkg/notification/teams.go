@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"net/http"
+	"time"
+)
+
+// TeamsService posts notifications to Microsoft Teams incoming webhooks
+// (Office 365 connectors or Power Automate workflows). The webhook URL is
+// the endpoint of the subscription.
+type TeamsService struct {
+	client *http.Client
+}
+
+// NewTeamsService creates a new Microsoft Teams webhook service
+func NewTeamsService() *TeamsService {
+	return &TeamsService{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// SendNotification posts a notification to the webhook of sub
+func (s *TeamsService) SendNotification(sub Subscription, title, body string, data map[string]interface{}) error {
+	url, initialMessage := notificationLink(data)
+	return postWebhook(s.client, sub.Endpoint, teamsPayload(title, body, url, initialMessage))
+}
+
+// teamsPayload builds the webhook message of a notification: an Adaptive
+// Card, which both connectors and workflows accept, with a button opening
+// the session
+func teamsPayload(title, body, url, initialMessage string) map[string]interface{} {
+	cardBody := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true},
+		map[string]interface{}{"type": "TextBlock", "text": body, "wrap": true},
+	}
+	if initialMessage != "" {
+		cardBody = append(cardBody, map[string]interface{}{
+			"type": "TextBlock", "text": "💬 " + truncateRunes(initialMessage, 100),
+			"isSubtle": true, "size": "Small", "wrap": true, "separator": true,
+		})
+	}
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    cardBody,
+	}
+	if url != "" {
+		card["actions"] = []interface{}{
+			map[string]interface{}{"type": "Action.OpenUrl", "title": "Open session", "url": url},
+		}
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			},
+		},
+	}
+}
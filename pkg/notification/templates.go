@@ -0,0 +1,216 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
+)
+
+// TemplateEvents lists the agentapi and session event types that have
+// notification templates
+var TemplateEvents = []string{
+	"message_received", "status_change", "session_update", "error",
+	TypeSessionReady, TypeSessionFailed, TypeRunCompleted,
+}
+
+// sampleSessionID is the session referenced by previews and test sends
+const sampleSessionID = "sample-session"
+
+var (
+	// ErrUnknownEventType is returned when a preview or test send names an
+	// event type without a template
+	ErrUnknownEventType = errors.New("unknown event type")
+	// ErrUnsupportedLocale is returned when a preview or test send names a
+	// locale without a message catalog
+	ErrUnsupportedLocale = errors.New("unsupported locale")
+	// ErrNoMatchingSubscription is returned when a test send matches none
+	// of the user's subscriptions
+	ErrNoMatchingSubscription = errors.New("no matching subscription")
+)
+
+// TemplatePreviewRequest represents the request body for previewing
+// notification templates. All fields are optional: without event_type every
+// template is rendered, without locale the caller's locale is used, and data
+// is merged over the sample data.
+type TemplatePreviewRequest struct {
+	EventType string                 `json:"event_type,omitempty"`
+	Locale    string                 `json:"locale,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// TemplatePreview is a notification template rendered with sample data, as
+// each channel would receive it
+type TemplatePreview struct {
+	EventType        string                 `json:"event_type"`
+	NotificationType string                 `json:"notification_type"`
+	Locale           i18n.Locale            `json:"locale"`
+	Title            string                 `json:"title"`
+	Body             string                 `json:"body"`
+	Data             map[string]interface{} `json:"data"`
+	WebPush          map[string]interface{} `json:"webpush"`
+	Slack            []slack.Block          `json:"slack"`
+	Discord          map[string]interface{} `json:"discord"`
+	Teams            map[string]interface{} `json:"teams"`
+}
+
+// TestNotificationRequest represents the request body for sending a test
+// notification to the caller's own subscriptions. subscription_id selects a
+// single subscription (even a disabled one) and type selects every active
+// subscription of a channel type; without either all active subscriptions
+// receive the test.
+type TestNotificationRequest struct {
+	SubscriptionID string                 `json:"subscription_id,omitempty"`
+	Type           string                 `json:"type,omitempty"`
+	EventType      string                 `json:"event_type,omitempty"`
+	Locale         string                 `json:"locale,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+}
+
+// TestDeliveryResult is the outcome of a test send to one subscription
+type TestDeliveryResult struct {
+	SubscriptionID string `json:"subscription_id"`
+	Type           string `json:"type"`
+	Delivered      bool   `json:"delivered"`
+	Error          string `json:"error,omitempty"`
+}
+
+// TestNotificationResponse represents the response for a test send
+type TestNotificationResponse struct {
+	Preview TemplatePreview      `json:"preview"`
+	Results []TestDeliveryResult `json:"results"`
+}
+
+// PreviewTemplates renders the notification templates for userID with
+// sample data, without sending anything
+func (s *Service) PreviewTemplates(userID string, req TemplatePreviewRequest) ([]TemplatePreview, error) {
+	locale, err := s.previewLocale(userID, req.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	events := TemplateEvents
+	if req.EventType != "" {
+		events = []string{req.EventType}
+	}
+
+	previews := make([]TemplatePreview, 0, len(events))
+	for _, eventType := range events {
+		preview, err := renderPreview(eventType, locale, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, *preview)
+	}
+	return previews, nil
+}
+
+// SendTestNotification renders a template with sample data and sends it to
+// the selected subscriptions of userID. Test sends bypass notification type
+// filters, are not retried and are not recorded in the history, so the result
+// of each delivery is reported directly.
+func (s *Service) SendTestNotification(userID string, req TestNotificationRequest) (*TestNotificationResponse, error) {
+	locale, err := s.previewLocale(userID, req.Locale)
+	if err != nil {
+		return nil, err
+	}
+	eventType := req.EventType
+	if eventType == "" {
+		eventType = "message_received"
+	}
+	preview, err := renderPreview(eventType, locale, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	preview.Data["test"] = true
+
+	subscriptions, err := s.getSubscriptionsForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	results := make([]TestDeliveryResult, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		if !matchesTestTarget(sub, req) {
+			continue
+		}
+		result := TestDeliveryResult{SubscriptionID: sub.ID, Type: subscriptionType(sub)}
+		if err := s.deliver(sub, preview.Title, preview.Body, preview.Data); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Delivered = true
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, ErrNoMatchingSubscription
+	}
+
+	return &TestNotificationResponse{Preview: *preview, Results: results}, nil
+}
+
+// previewLocale parses tag, falling back to the locale of userID
+func (s *Service) previewLocale(userID, tag string) (i18n.Locale, error) {
+	if tag == "" {
+		return s.localeForUser(userID), nil
+	}
+	locale, ok := i18n.Parse(tag)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedLocale, tag)
+	}
+	return locale, nil
+}
+
+// renderPreview renders the template of eventType in locale with the sample
+// data overridden by data
+func renderPreview(eventType string, locale i18n.Locale, data map[string]interface{}) (*TemplatePreview, error) {
+	sample := map[string]interface{}{
+		"session_id":      sampleSessionID,
+		"url":             sessionURL(sampleSessionID),
+		"initial_message": i18n.T(locale, "notification.sample.initial_message"),
+	}
+	for k, v := range data {
+		sample[k] = v
+	}
+
+	titleID, bodyID, notificationType, ok := eventMessageIDs(eventType, sample)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+	title, body := i18n.T(locale, titleID), i18n.T(locale, bodyID)
+	url, _ := sample["url"].(string)
+	initialMessage, _ := sample["initial_message"].(string)
+
+	return &TemplatePreview{
+		EventType:        eventType,
+		NotificationType: notificationType,
+		Locale:           locale,
+		Title:            title,
+		Body:             body,
+		Data:             sample,
+		WebPush:          webPushPayload(title, body, sample),
+		Slack:            slackDMBlocks(title, body, url, initialMessage),
+		Discord:          discordPayload(title, body, url, initialMessage),
+		Teams:            teamsPayload(title, body, url, initialMessage),
+	}, nil
+}
+
+// matchesTestTarget reports whether a test send selects sub
+func matchesTestTarget(sub Subscription, req TestNotificationRequest) bool {
+	if req.SubscriptionID != "" {
+		return sub.ID == req.SubscriptionID
+	}
+	if !sub.Active {
+		return false
+	}
+	return req.Type == "" || subscriptionType(sub) == req.Type
+}
+
+// subscriptionType returns the channel type of sub
+func subscriptionType(sub Subscription) string {
+	if sub.Type == "" {
+		return SubscriptionTypeWebPush // backward compat
+	}
+	return sub.Type
+}
@@ -0,0 +1,120 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/i18n"
+)
+
+func TestPreviewTemplates(t *testing.T) {
+	svc, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	previews, err := svc.PreviewTemplates("user-1", TemplatePreviewRequest{})
+	if err != nil {
+		t.Fatalf("PreviewTemplates() error = %v", err)
+	}
+	if len(previews) != len(TemplateEvents) {
+		t.Fatalf("got %d previews, want one per event type", len(previews))
+	}
+	if previews[0].Locale != i18n.Japanese || previews[0].Title != "新しいメッセージ" {
+		t.Errorf("preview without locale = %+v, want the default locale", previews[0])
+	}
+
+	previews, err = svc.PreviewTemplates("user-1", TemplatePreviewRequest{
+		EventType: "status_change",
+		Locale:    "en-US",
+		Data:      map[string]interface{}{"status": "running", "url": "https://example.com/s/1"},
+	})
+	if err != nil {
+		t.Fatalf("PreviewTemplates() error = %v", err)
+	}
+	p := previews[0]
+	if p.Body != "The agent is responding" || p.NotificationType != "status_change" {
+		t.Errorf("preview = %+v, want the running status body", p)
+	}
+	if p.WebPush["title"] != "Status changed" || p.Data["url"] != "https://example.com/s/1" || p.Data["session_id"] != sampleSessionID {
+		t.Errorf("sample data not merged: %+v", p)
+	}
+	if len(p.Slack) != 4 {
+		t.Errorf("got %d Slack blocks, want section, divider, context and button", len(p.Slack))
+	}
+
+	if _, err := svc.PreviewTemplates("user-1", TemplatePreviewRequest{EventType: "unknown"}); !errors.Is(err, ErrUnknownEventType) {
+		t.Errorf("unknown event type error = %v", err)
+	}
+	if _, err := svc.PreviewTemplates("user-1", TemplatePreviewRequest{Locale: "fr"}); !errors.Is(err, ErrUnsupportedLocale) {
+		t.Errorf("unsupported locale error = %v", err)
+	}
+}
+
+func TestSendTestNotification(t *testing.T) {
+	svc := newTestService(t)
+	// Without channel services every delivery fails, and the error is
+	// reported per subscription.
+	svc.SetChannel(SubscriptionTypeWebPush, nil)
+	for _, sub := range []Subscription{
+		{ID: "push", UserID: "user-1", Endpoint: "https://push.example.com/1", Active: true, NotificationTypes: []string{"error"}},
+		{ID: "slack", UserID: "user-1", Type: SubscriptionTypeSlack, Endpoint: "U123", Active: true},
+	} {
+		if err := svc.storage.AddSubscription("user-1", sub); err != nil {
+			t.Fatalf("AddSubscription() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     TestNotificationRequest
+		wantIDs []string
+		wantErr error
+	}{
+		{name: "All active subscriptions", req: TestNotificationRequest{}, wantIDs: []string{"push", "slack"}},
+		{name: "By type", req: TestNotificationRequest{Type: SubscriptionTypeSlack}, wantIDs: []string{"slack"}},
+		{name: "By ID", req: TestNotificationRequest{SubscriptionID: "push", EventType: "error"}, wantIDs: []string{"push"}},
+		{name: "Unknown subscription", req: TestNotificationRequest{SubscriptionID: "missing"}, wantErr: ErrNoMatchingSubscription},
+		{name: "Unknown event type", req: TestNotificationRequest{EventType: "unknown"}, wantErr: ErrUnknownEventType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.SendTestNotification("user-1", tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SendTestNotification() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendTestNotification() error = %v", err)
+			}
+			if len(resp.Results) != len(tt.wantIDs) {
+				t.Fatalf("results = %+v, want %v", resp.Results, tt.wantIDs)
+			}
+			got := make(map[string]bool)
+			for _, r := range resp.Results {
+				if r.Delivered || r.Error == "" {
+					t.Errorf("result %+v, want a failed delivery", r)
+				}
+				got[r.SubscriptionID] = true
+			}
+			for _, id := range tt.wantIDs {
+				if !got[id] {
+					t.Errorf("results = %+v, want a delivery to %s", resp.Results, id)
+				}
+			}
+			if resp.Preview.Data["test"] != true {
+				t.Errorf("test sends must be marked in data: %+v", resp.Preview.Data)
+			}
+		})
+	}
+
+	history, _, err := svc.storage.GetNotificationHistory("user-1", 10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetNotificationHistory() error = %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("test sends recorded in history: %+v", history)
+	}
+}
@@ -7,12 +7,50 @@ import (
 const (
 	SubscriptionTypeWebPush = "webpush"
 	SubscriptionTypeSlack   = "slack"
+	SubscriptionTypeDiscord = "discord"
+	SubscriptionTypeTeams   = "teams"
 )
 
-// SubscribeRequest represents the request body for subscribing to push notifications
+// SubscriptionTypes lists the channels notifications can be delivered to
+var SubscriptionTypes = []string{
+	SubscriptionTypeWebPush, SubscriptionTypeSlack, SubscriptionTypeDiscord, SubscriptionTypeTeams,
+}
+
+// Notification types that subscriptions and users filter by. The first four
+// come from agentapi webhooks; the session types are sent by the proxy itself
+// on session lifecycle events.
+const (
+	TypeMessage       = "message"
+	TypeStatusChange  = "status_change"
+	TypeSessionUpdate = "session_update"
+	TypeError         = "error"
+	TypeSessionReady  = "session_ready"
+	TypeSessionFailed = "session_failed"
+	TypeRunCompleted  = "run_completed"
+	TypeManual        = "manual"
+)
+
+// NotificationTypes lists the notification types users can choose to
+// receive. Manual notifications are always delivered.
+var NotificationTypes = []string{
+	TypeMessage, TypeStatusChange, TypeSessionUpdate, TypeError,
+	TypeSessionReady, TypeSessionFailed, TypeRunCompleted,
+}
+
+// Delivery statuses of notification history entries
+const (
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusRetrying  = "retrying"
+	DeliveryStatusFailed    = "failed"
+)
+
+// SubscribeRequest represents the request body for subscribing to push notifications.
+// Type "discord" or "teams" subscribes the incoming webhook URL in Endpoint
+// instead, without keys.
 type SubscribeRequest struct {
+	Type     string            `json:"type,omitempty"`
 	Endpoint string            `json:"endpoint" validate:"required"`
-	Keys     map[string]string `json:"keys" validate:"required"`
+	Keys     map[string]string `json:"keys"`
 }
 
 // SubscribeResponse represents the response for a successful subscription
@@ -27,7 +65,7 @@ type Subscription struct {
 	UserID            string            `json:"user_id"`
 	UserType          string            `json:"user_type"`
 	Username          string            `json:"username"`
-	Type              string            `json:"type"` // "webpush" (default), "slack"
+	Type              string            `json:"type"` // "webpush" (default), "slack", "discord", "teams"
 	Endpoint          string            `json:"endpoint"`
 	Keys              map[string]string `json:"keys"`
 	SessionIDs        []string          `json:"session_ids"`
@@ -71,6 +109,10 @@ type NotificationHistory struct {
 	Delivered      bool                   `json:"delivered"`
 	Clicked        bool                   `json:"clicked"`
 	ErrorMessage   *string                `json:"error_message"`
+	// Status is "delivered", "retrying" (queued for another attempt) or
+	// "failed"; Attempts counts the sends so far
+	Status   string `json:"status,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
 }
 
 // HistoryResponse represents the response for notification history endpoint
@@ -80,6 +122,15 @@ type HistoryResponse struct {
 	HasMore       bool                  `json:"has_more"`
 }
 
+// UpdateSubscriptionRequest represents the request body for changing which
+// sessions and notification types a subscription receives. Omitted fields
+// are left unchanged; empty lists mean all sessions or all types.
+type UpdateSubscriptionRequest struct {
+	Endpoint          string    `json:"endpoint" validate:"required"`
+	SessionIDs        *[]string `json:"session_ids,omitempty"`
+	NotificationTypes *[]string `json:"notification_types,omitempty"`
+}
+
 // DeleteSubscriptionRequest represents the request body for deleting a subscription
 type DeleteSubscriptionRequest struct {
 	Endpoint string `json:"endpoint" validate:"required"`
@@ -2,12 +2,18 @@ package notification
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/SherClockHolmes/webpush-go"
 )
 
+// ErrSubscriptionGone is returned when the push service reports that a
+// subscription has expired or been unsubscribed
+var ErrSubscriptionGone = errors.New("push subscription is no longer valid")
+
 // WebPushService handles sending web push notifications
 type WebPushService struct {
 	vapidPublicKey    string
@@ -34,15 +40,7 @@ func NewWebPushService() (*WebPushService, error) {
 
 // SendNotification sends a push notification to a subscription
 func (s *WebPushService) SendNotification(sub Subscription, title, body string, data map[string]interface{}) error {
-	// Create notification payload
-	payload := map[string]interface{}{
-		"title": title,
-		"body":  body,
-		"icon":  "/icon-192x192.png",
-		"data":  data,
-	}
-
-	payloadBytes, err := json.Marshal(payload)
+	payloadBytes, err := json.Marshal(webPushPayload(title, body, data))
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
@@ -76,6 +74,9 @@ func (s *WebPushService) SendNotification(sub Subscription, title, body string,
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("notification rejected with status %d: %w", resp.StatusCode, ErrSubscriptionGone)
+	}
 	if resp.StatusCode >= 400 {
 		return fmt.Errorf("notification rejected with status %d", resp.StatusCode)
 	}
@@ -141,9 +142,22 @@ func (s *WebPushService) SendNotificationWithOptions(sub Subscription, title, bo
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("notification rejected with status %d: %w", resp.StatusCode, ErrSubscriptionGone)
+	}
 	if resp.StatusCode >= 400 {
 		return fmt.Errorf("notification rejected with status %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// webPushPayload builds the payload delivered to the service worker
+func webPushPayload(title, body string, data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"title": title,
+		"body":  body,
+		"icon":  "/icon-192x192.png",
+		"data":  data,
+	}
+}
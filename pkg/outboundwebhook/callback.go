@@ -0,0 +1,146 @@
+package outboundwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+)
+
+// CallbackDeliveryKind is the delivery queue kind of completion callback retries
+const CallbackDeliveryKind = "webhook.callback"
+
+// callbackEventTypes maps the timeline events that end the initial run of a
+// session to the event sent to its completion callback
+var callbackEventTypes = map[entities.SessionEventType]string{
+	entities.SessionEventCompleted:    EventRunCompleted,
+	entities.SessionEventJobCompleted: EventRunCompleted,
+	entities.SessionEventJobFailed:    EventSessionFailed,
+}
+
+// CallbackLookup returns the completion callback URL of a session, or ""
+type CallbackLookup func(sessionID string) string
+
+// Callbacks sends the completion of a session to the callback URL given when
+// the session was created. Unlike webhooks, callbacks are not registered and
+// have no delivery log; they are signed with one proxy-wide secret.
+type Callbacks struct {
+	callbackURL CallbackLookup
+	session     SessionLookup
+	secret      string
+	client      *http.Client
+	queue       *delivery.Queue
+}
+
+// NewCallbacks creates Callbacks. callbackURL selects the sessions that are
+// notified and session describes them in the event. Requests are signed with
+// secret unless it is empty.
+func NewCallbacks(callbackURL CallbackLookup, session SessionLookup, secret string) *Callbacks {
+	return &Callbacks{
+		callbackURL: callbackURL,
+		session:     session,
+		secret:      secret,
+		client:      newClient(false),
+	}
+}
+
+// AllowPrivateTargets lets callbacks reach loopback, private and link-local
+// addresses
+func (c *Callbacks) AllowPrivateTargets() *Callbacks {
+	c.client = newClient(true)
+	return c
+}
+
+// WithHTTPClient sets the client used for callbacks
+func (c *Callbacks) WithHTTPClient(client *http.Client) *Callbacks {
+	c.client = client
+	return c
+}
+
+// WithDeliveryQueue retries failed callbacks through queue
+func (c *Callbacks) WithDeliveryQueue(queue *delivery.Queue) *Callbacks {
+	c.queue = queue
+	queue.Register(CallbackDeliveryKind, c.redeliver)
+	return c
+}
+
+// Recorder wraps a session event recorder so that recorded completions are
+// also sent to completion callbacks
+func (c *Callbacks) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &callbackRecorder{EventRecorder: inner, callbacks: c}
+}
+
+type callbackRecorder struct {
+	portrepos.EventRecorder
+	callbacks *Callbacks
+}
+
+func (r *callbackRecorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	r.callbacks.HandleSessionEvent(event)
+	return r.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent sends the completion of a session to its callback URL in
+// the background. Other events and sessions without a callback are ignored.
+func (c *Callbacks) HandleSessionEvent(event entities.SessionEvent) {
+	eventType, ok := callbackEventTypes[event.Type]
+	if !ok {
+		return
+	}
+	url := c.callbackURL(event.SessionID)
+	if url == "" {
+		return
+	}
+	e := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: event.Timestamp,
+		Message:   event.Message,
+		Session:   describeSession(c.session, event.SessionID),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := c.Send(ctx, url, e); err != nil {
+			log.Printf("[OUTBOUND_WEBHOOK] Completion callback of session %s failed: %v", event.SessionID, err)
+			c.queueRetry(url, e, err)
+		}
+	}()
+}
+
+// Send delivers event to a callback URL
+func (c *Callbacks) Send(ctx context.Context, url string, event Event) error {
+	_, _, err := postEvent(ctx, c.client, url, c.secret, event)
+	return err
+}
+
+// callbackPayload is a failed callback persisted for retry
+type callbackPayload struct {
+	URL   string `json:"url"`
+	Event Event  `json:"event"`
+}
+
+func (c *Callbacks) queueRetry(url string, event Event, sendErr error) {
+	if c.queue == nil || delivery.IsPermanent(sendErr) {
+		return
+	}
+	if _, err := c.queue.Retry(context.Background(), CallbackDeliveryKind, callbackPayload{URL: url, Event: event}, sendErr); err != nil {
+		log.Printf("[OUTBOUND_WEBHOOK] Failed to queue retry of completion callback for session %s: %v", event.Session.ID, err)
+	}
+}
+
+// redeliver is the delivery queue handler for completion callback retries
+func (c *Callbacks) redeliver(ctx context.Context, raw json.RawMessage) error {
+	var p callbackPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return delivery.Permanent(fmt.Errorf("invalid completion callback payload: %w", err))
+	}
+	return c.Send(ctx, p.URL, p.Event)
+}
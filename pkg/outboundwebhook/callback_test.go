@@ -0,0 +1,46 @@
+package outboundwebhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+func TestCallbacksSendCompletion(t *testing.T) {
+	rcv := &receiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	urls := map[string]string{"s1": srv.URL}
+	c := NewCallbacks(func(id string) string { return urls[id] }, nil, "s3cret").AllowPrivateTargets()
+
+	now := time.Now().UTC()
+	c.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventMessageSent, Timestamp: now})
+	c.HandleSessionEvent(entities.SessionEvent{SessionID: "s2", Type: entities.SessionEventCompleted, Timestamp: now})
+	c.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventCompleted, Message: "done", Timestamp: now})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rcv.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if rcv.count() != 1 {
+		t.Fatalf("got %d callbacks, want only the completion of the session with a callback URL", rcv.count())
+	}
+	req, body := rcv.requests[0], rcv.bodies[0]
+	if req.Header.Get(EventHeader) != EventRunCompleted {
+		t.Errorf("event = %s, want %s", req.Header.Get(EventHeader), EventRunCompleted)
+	}
+	if want := hmacutil.Sign([]byte("s3cret"), body); req.Header.Get(SignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get(SignatureHeader), want)
+	}
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil || got.Session.ID != "s1" || got.Message != "done" {
+		t.Errorf("body = %s, err = %v", body, err)
+	}
+}
@@ -0,0 +1,343 @@
+package outboundwebhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+// DeliveryKind is the delivery queue kind of outbound webhook retries
+const DeliveryKind = "webhook.outbound"
+
+// Request headers of outbound webhook deliveries
+const (
+	EventHeader     = "X-AgentAPI-Event"
+	DeliveryHeader  = "X-AgentAPI-Delivery"
+	SignatureHeader = "X-AgentAPI-Signature-256"
+)
+
+const (
+	// defaultTimeout bounds one delivery request
+	defaultTimeout = 10 * time.Second
+	// maxResponseBody is how much of a response body is kept in the delivery log
+	maxResponseBody = 1024
+)
+
+// sessionEventTypes maps session timeline events to outbound event types.
+// Timeline events without an entry are not sent.
+var sessionEventTypes = map[entities.SessionEventType]string{
+	entities.SessionEventCreated:         EventSessionCreated,
+	entities.SessionEventProvisioned:     EventSessionActive,
+	entities.SessionEventProvisionFailed: EventSessionFailed,
+	entities.SessionEventStartupTimeout:  EventSessionFailed,
+	entities.SessionEventJobFailed:       EventSessionFailed,
+	entities.SessionEventCrashed:         EventSessionCrashed,
+	entities.SessionEventEvicted:         EventSessionEvicted,
+	entities.SessionEventDeleted:         EventSessionDeleted,
+	entities.SessionEventJobCompleted:    EventRunCompleted,
+	entities.SessionEventCompleted:       EventRunCompleted,
+}
+
+// SessionLookup returns a session by ID, or nil if it does not exist
+type SessionLookup func(id string) entities.Session
+
+// Dispatcher sends session lifecycle events to the matching webhooks
+type Dispatcher struct {
+	store   Store
+	session SessionLookup
+	client  *http.Client
+	queue   *delivery.Queue
+
+	// lastStatus is the last agent status seen per session, used to detect
+	// completed runs
+	mu         sync.Mutex
+	lastStatus map[string]string
+}
+
+// NewDispatcher creates a Dispatcher. session is used to describe the
+// session of each event and to select the webhooks that may receive it.
+func NewDispatcher(store Store, session SessionLookup) *Dispatcher {
+	return &Dispatcher{
+		store:      store,
+		session:    session,
+		client:     newClient(false),
+		lastStatus: make(map[string]string),
+	}
+}
+
+// AllowPrivateTargets lets webhooks reach loopback, private and link-local
+// addresses, which are refused by default so that users cannot make the
+// proxy call internal services
+func (d *Dispatcher) AllowPrivateTargets() *Dispatcher {
+	d.client = newClient(true)
+	return d
+}
+
+// WithHTTPClient sets the client used for deliveries
+func (d *Dispatcher) WithHTTPClient(client *http.Client) *Dispatcher {
+	d.client = client
+	return d
+}
+
+// WithDeliveryQueue retries failed deliveries through queue. Without a queue
+// a failed delivery is only recorded in the delivery log.
+func (d *Dispatcher) WithDeliveryQueue(queue *delivery.Queue) *Dispatcher {
+	d.queue = queue
+	queue.Register(DeliveryKind, d.redeliver)
+	return d
+}
+
+// Recorder wraps a session event recorder so that recorded lifecycle events
+// are also sent to outbound webhooks
+func (d *Dispatcher) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &recorder{EventRecorder: inner, dispatcher: d}
+}
+
+type recorder struct {
+	portrepos.EventRecorder
+	dispatcher *Dispatcher
+}
+
+func (r *recorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	r.dispatcher.HandleSessionEvent(event)
+	return r.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent sends a session timeline event to the matching
+// webhooks in the background. The session is described before returning so
+// that deleted sessions can still be matched.
+func (d *Dispatcher) HandleSessionEvent(event entities.SessionEvent) {
+	eventType, ok := sessionEventTypes[event.Type]
+	if !ok {
+		return
+	}
+	if eventType == EventSessionDeleted {
+		d.mu.Lock()
+		delete(d.lastStatus, event.SessionID)
+		d.mu.Unlock()
+	}
+	d.dispatchAsync(eventType, event.SessionID, event.Message, event.Timestamp)
+}
+
+// ObserveStatus tracks the session status and sends run.completed when the
+// agent goes from running back to idle ("active"). The run that completes a
+// session is sent for its timeline event instead.
+func (d *Dispatcher) ObserveStatus(sessionID, status string) {
+	d.mu.Lock()
+	previous := d.lastStatus[sessionID]
+	d.lastStatus[sessionID] = status
+	d.mu.Unlock()
+
+	if previous == "running" && status == "active" {
+		d.dispatchAsync(EventRunCompleted, sessionID, "The agent has finished responding", time.Now().UTC())
+	}
+}
+
+func (d *Dispatcher) dispatchAsync(eventType, sessionID, message string, timestamp time.Time) {
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: timestamp,
+		Message:   message,
+		Session:   d.describeSession(sessionID),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		d.Dispatch(ctx, event)
+	}()
+}
+
+func (d *Dispatcher) describeSession(sessionID string) *EventSession {
+	return describeSession(d.session, sessionID)
+}
+
+func describeSession(lookup SessionLookup, sessionID string) *EventSession {
+	described := &EventSession{ID: sessionID}
+	if lookup == nil {
+		return described
+	}
+	session := lookup(sessionID)
+	if session == nil {
+		return described
+	}
+	described.UserID = session.UserID()
+	described.Scope = string(session.Scope())
+	described.TeamID = session.TeamID()
+	described.Status = session.Status()
+	described.Tags = session.Tags()
+	return described
+}
+
+// Dispatch sends event to every active webhook that subscribes to it and
+// may receive events of its session
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
+	webhooks, err := d.store.List(ctx)
+	if err != nil {
+		log.Printf("[OUTBOUND_WEBHOOK] Failed to list webhooks for %s event: %v", event.Type, err)
+		return
+	}
+	for _, w := range webhooks {
+		if !w.Active || !w.Subscribes(event.Type) || !w.Receives(event.Session) {
+			continue
+		}
+		if err := d.Send(ctx, w, event); err != nil {
+			d.queueRetry(w, event, err)
+		}
+	}
+}
+
+// Send delivers event to one webhook and records the attempt in its
+// delivery log. Client errors other than 408 and 429 are permanent.
+func (d *Dispatcher) Send(ctx context.Context, w *Webhook, event Event) error {
+	return d.send(ctx, w, event, false)
+}
+
+func (d *Dispatcher) send(ctx context.Context, w *Webhook, event Event, retry bool) error {
+	entry := DeliveryLog{
+		ID:        uuid.New().String(),
+		WebhookID: w.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Retry:     retry,
+		Timestamp: time.Now().UTC(),
+	}
+	err := d.post(ctx, w, event, &entry)
+	entry.DurationMs = time.Since(entry.Timestamp).Milliseconds()
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := d.store.AppendLog(ctx, entry); logErr != nil {
+		log.Printf("[OUTBOUND_WEBHOOK] Failed to record delivery to webhook %s: %v", w.ID, logErr)
+	}
+	return err
+}
+
+func (d *Dispatcher) post(ctx context.Context, w *Webhook, event Event, entry *DeliveryLog) error {
+	statusCode, respBody, err := postEvent(ctx, d.client, w.URL, w.Secret, event)
+	entry.StatusCode = statusCode
+	entry.ResponseBody = respBody
+	return err
+}
+
+// postEvent sends event to url, signed with secret when it is set, and
+// returns the response status and the start of the response body. Client
+// errors other than 408 and 429 are permanent.
+func postEvent(ctx context.Context, client *http.Client, url, secret string, event Event) (int, string, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, "", delivery.Permanent(fmt.Errorf("failed to marshal event: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", delivery.Permanent(fmt.Errorf("invalid webhook URL: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agentapi-proxy-webhook")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, hmacutil.Sign([]byte(secret), body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, string(respBody), nil
+	}
+	statusErr := fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return resp.StatusCode, string(respBody), delivery.Permanent(statusErr)
+	}
+	return resp.StatusCode, string(respBody), statusErr
+}
+
+// newClient returns the HTTP client for deliveries. Unless allowPrivate is
+// set, connections to non-public addresses are refused after DNS resolution
+// so that redirects and rebinding cannot bypass the check.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: defaultTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to deliver to non-public address %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: defaultTimeout, Transport: transport}
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// retryPayload is a failed delivery persisted for retry. The webhook is
+// looked up again on each attempt so that edits and deletions apply.
+type retryPayload struct {
+	WebhookID string `json:"webhook_id"`
+	Event     Event  `json:"event"`
+}
+
+func (d *Dispatcher) queueRetry(w *Webhook, event Event, sendErr error) {
+	if d.queue == nil || delivery.IsPermanent(sendErr) {
+		return
+	}
+	queued, err := d.queue.Retry(context.Background(), DeliveryKind, retryPayload{WebhookID: w.ID, Event: event}, sendErr)
+	if err != nil {
+		log.Printf("[OUTBOUND_WEBHOOK] Failed to queue retry of %s event for webhook %s: %v", event.Type, w.ID, err)
+		return
+	}
+	log.Printf("[OUTBOUND_WEBHOOK] Queued retry %s of %s event for webhook %s", queued.ID, event.Type, w.ID)
+}
+
+// redeliver is the delivery queue handler for outbound webhook retries
+func (d *Dispatcher) redeliver(ctx context.Context, raw json.RawMessage) error {
+	var p retryPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return delivery.Permanent(fmt.Errorf("invalid outbound webhook payload: %w", err))
+	}
+	w, err := d.store.Get(ctx, p.WebhookID)
+	if errors.Is(err, ErrNotFound) {
+		return delivery.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if !w.Active {
+		return delivery.Permanent(fmt.Errorf("outbound webhook %s is disabled", w.ID))
+	}
+	return d.send(ctx, w, p.Event, true)
+}
@@ -0,0 +1,230 @@
+package outboundwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/hmacutil"
+)
+
+// receiver records the requests of a test webhook endpoint
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newTestDispatcher(t *testing.T, webhooks ...*Webhook) (*Dispatcher, *FileStore) {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	for _, w := range webhooks {
+		if err := store.Create(context.Background(), w); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	return NewDispatcher(store, nil).AllowPrivateTargets(), store
+}
+
+func TestDispatchSignsAndFilters(t *testing.T) {
+	rcv := &receiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	d, store := newTestDispatcher(t,
+		&Webhook{ID: "mine", URL: srv.URL, Secret: "s3cret", Scope: entities.ScopeUser, UserID: "alice", Active: true},
+		&Webhook{ID: "other-user", URL: srv.URL, Scope: entities.ScopeUser, UserID: "bob", Active: true},
+		&Webhook{ID: "team", URL: srv.URL, Scope: entities.ScopeTeam, TeamID: "acme/dev", Active: true},
+		&Webhook{ID: "deleted-only", URL: srv.URL, Scope: ScopeGlobal, Events: []string{EventSessionDeleted}, Active: true},
+		&Webhook{ID: "disabled", URL: srv.URL, Scope: ScopeGlobal},
+	)
+
+	event := Event{ID: "evt-1", Type: EventSessionCreated, Session: &EventSession{ID: "s1", UserID: "alice", Scope: "user"}}
+	d.Dispatch(context.Background(), event)
+
+	if rcv.count() != 1 {
+		t.Fatalf("got %d deliveries, want only the owner's webhook", rcv.count())
+	}
+	req, body := rcv.requests[0], rcv.bodies[0]
+	if req.Header.Get(EventHeader) != EventSessionCreated || req.Header.Get(DeliveryHeader) != "evt-1" {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+	if want := hmacutil.Sign([]byte("s3cret"), body); req.Header.Get(SignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get(SignatureHeader), want)
+	}
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil || got.Session.ID != "s1" {
+		t.Errorf("body = %s, err = %v", body, err)
+	}
+
+	logs, err := store.ListLogs(context.Background(), "mine", 10)
+	if err != nil {
+		t.Fatalf("ListLogs() error = %v", err)
+	}
+	if len(logs) != 1 || !logs[0].Success || logs[0].StatusCode != http.StatusOK || logs[0].EventID != "evt-1" {
+		t.Errorf("delivery log = %+v", logs)
+	}
+}
+
+func TestSendRefusesPrivateTargets(t *testing.T) {
+	rcv := &receiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	d := NewDispatcher(store, nil)
+	if err := d.Send(context.Background(), &Webhook{ID: "w1", URL: srv.URL}, Event{ID: "evt-1", Type: EventPing}); err == nil {
+		t.Error("expected delivery to a loopback address to be refused")
+	}
+	if rcv.count() != 0 {
+		t.Errorf("request reached the loopback server")
+	}
+}
+
+func TestDispatchRetriesServerErrors(t *testing.T) {
+	rcv := &receiver{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	queueStore, err := delivery.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("delivery.NewFileStore() error = %v", err)
+	}
+	queue := delivery.NewQueue(queueStore, delivery.Options{BaseBackoff: time.Millisecond})
+	d, store := newTestDispatcher(t, &Webhook{ID: "global", URL: srv.URL, Scope: ScopeGlobal, Active: true})
+	d.WithDeliveryQueue(queue)
+
+	d.Dispatch(context.Background(), Event{ID: "evt-1", Type: EventSessionFailed, Session: &EventSession{ID: "s1"}})
+	if rcv.count() != 1 {
+		t.Fatalf("got %d deliveries, want 1", rcv.count())
+	}
+
+	rcv.mu.Lock()
+	rcv.status = http.StatusNoContent
+	rcv.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	if _, err := queue.ProcessDue(context.Background()); err != nil {
+		t.Fatalf("ProcessDue() error = %v", err)
+	}
+	if rcv.count() != 2 {
+		t.Fatalf("got %d deliveries, want the retry", rcv.count())
+	}
+
+	logs, _ := store.ListLogs(context.Background(), "global", 10)
+	if len(logs) != 2 || !logs[0].Success || !logs[0].Retry || logs[1].Success || logs[1].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("delivery log = %+v, want the successful retry first", logs)
+	}
+}
+
+func TestDispatchDoesNotRetryClientErrors(t *testing.T) {
+	rcv := &receiver{status: http.StatusNotFound}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	queueStore, err := delivery.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("delivery.NewFileStore() error = %v", err)
+	}
+	queue := delivery.NewQueue(queueStore, delivery.Options{})
+	d, _ := newTestDispatcher(t, &Webhook{ID: "global", URL: srv.URL, Scope: ScopeGlobal, Active: true})
+	d.WithDeliveryQueue(queue)
+
+	d.Dispatch(context.Background(), Event{ID: "evt-1", Type: EventSessionFailed})
+	due, err := queueStore.Due(context.Background(), time.Now().Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Due() error = %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("client error queued for retry: %+v", due)
+	}
+}
+
+func TestObserveStatusSendsRunCompleted(t *testing.T) {
+	rcv := &receiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	d, _ := newTestDispatcher(t, &Webhook{ID: "global", URL: srv.URL, Scope: ScopeGlobal, Events: []string{EventRunCompleted}, Active: true})
+
+	d.ObserveStatus("s1", "active")
+	d.ObserveStatus("s1", "running")
+	d.ObserveStatus("s1", "active")
+	// The run that completes a session is sent for its timeline event
+	d.ObserveStatus("s1", "running")
+	d.ObserveStatus("s1", entities.SessionStatusCompleted)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rcv.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if rcv.count() != 1 {
+		t.Fatalf("got %d deliveries, want one run.completed", rcv.count())
+	}
+	if rcv.requests[0].Header.Get(EventHeader) != EventRunCompleted {
+		t.Errorf("event = %s, want %s", rcv.requests[0].Header.Get(EventHeader), EventRunCompleted)
+	}
+}
+
+func TestFileStoreKeepsRecentLogs(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Create(ctx, &Webhook{ID: "w1"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for i := 0; i < MaxDeliveryLogs+5; i++ {
+		if err := store.AppendLog(ctx, DeliveryLog{WebhookID: "w1", StatusCode: i}); err != nil {
+			t.Fatalf("AppendLog() error = %v", err)
+		}
+	}
+
+	logs, err := store.ListLogs(ctx, "w1", 0)
+	if err != nil {
+		t.Fatalf("ListLogs() error = %v", err)
+	}
+	if len(logs) != MaxDeliveryLogs || logs[0].StatusCode != MaxDeliveryLogs+4 {
+		t.Errorf("got %d logs starting at %d, want the %d newest", len(logs), logs[0].StatusCode, MaxDeliveryLogs)
+	}
+
+	if err := store.Delete(ctx, "w1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if logs, _ := store.ListLogs(ctx, "w1", 0); len(logs) != 0 {
+		t.Errorf("logs kept after delete: %d", len(logs))
+	}
+	if _, err := store.Get(ctx, "w1"); err != ErrNotFound {
+		t.Errorf("Get() after delete error = %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package outboundwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxDeliveryLogs is the number of most recent delivery attempts kept per webhook
+const MaxDeliveryLogs = 100
+
+// Store persists outbound webhooks and their delivery logs
+type Store interface {
+	// Create adds a new webhook
+	Create(ctx context.Context, w *Webhook) error
+	// Get returns a webhook by ID, or ErrNotFound
+	Get(ctx context.Context, id string) (*Webhook, error)
+	// List returns all webhooks, oldest first
+	List(ctx context.Context) ([]*Webhook, error)
+	// Update replaces an existing webhook, or returns ErrNotFound
+	Update(ctx context.Context, w *Webhook) error
+	// Delete removes a webhook and its delivery log, or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+	// AppendLog records a delivery attempt, dropping the oldest attempts
+	// beyond MaxDeliveryLogs
+	AppendLog(ctx context.Context, entry DeliveryLog) error
+	// ListLogs returns up to limit delivery attempts of a webhook, newest first
+	ListLogs(ctx context.Context, webhookID string, limit int) ([]DeliveryLog, error)
+}
+
+// FileStore is a Store that keeps the webhooks in <dir>/webhooks.json and
+// the delivery log of each webhook in <dir>/logs/<id>.json
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "logs"), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create outbound webhook directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Create adds a new webhook
+func (s *FileStore) Create(_ context.Context, w *Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validID(w.ID); err != nil {
+		return err
+	}
+	webhooks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, exists := webhooks[w.ID]; exists {
+		return fmt.Errorf("outbound webhook %s already exists", w.ID)
+	}
+	webhooks[w.ID] = w
+	return s.save(webhooks)
+}
+
+// Get returns a webhook by ID
+func (s *FileStore) Get(_ context.Context, id string) (*Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	w, ok := webhooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return w, nil
+}
+
+// List returns all webhooks, oldest first
+func (s *FileStore) List(_ context.Context) ([]*Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// Update replaces an existing webhook
+func (s *FileStore) Update(_ context.Context, w *Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := webhooks[w.ID]; !ok {
+		return ErrNotFound
+	}
+	webhooks[w.ID] = w
+	return s.save(webhooks)
+}
+
+// Delete removes a webhook and its delivery log
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(webhooks, id)
+	if err := s.save(webhooks); err != nil {
+		return err
+	}
+	if err := os.Remove(s.logPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete delivery log: %w", err)
+	}
+	return nil
+}
+
+// AppendLog records a delivery attempt
+func (s *FileStore) AppendLog(_ context.Context, entry DeliveryLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validID(entry.WebhookID); err != nil {
+		return err
+	}
+	logs, err := s.loadLogs(entry.WebhookID)
+	if err != nil {
+		return err
+	}
+	logs = append(logs, entry)
+	if len(logs) > MaxDeliveryLogs {
+		logs = logs[len(logs)-MaxDeliveryLogs:]
+	}
+	return writeJSON(s.logPath(entry.WebhookID), logs)
+}
+
+// ListLogs returns up to limit delivery attempts of a webhook, newest first
+func (s *FileStore) ListLogs(_ context.Context, webhookID string, limit int) ([]DeliveryLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validID(webhookID); err != nil {
+		return nil, err
+	}
+	logs, err := s.loadLogs(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	newest := make([]DeliveryLog, 0, len(logs))
+	for i := len(logs) - 1; i >= 0 && (limit <= 0 || len(newest) < limit); i-- {
+		newest = append(newest, logs[i])
+	}
+	return newest, nil
+}
+
+func (s *FileStore) load() (map[string]*Webhook, error) {
+	webhooks := make(map[string]*Webhook)
+	data, err := os.ReadFile(filepath.Join(s.dir, "webhooks.json"))
+	if os.IsNotExist(err) {
+		return webhooks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbound webhooks: %w", err)
+	}
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse outbound webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (s *FileStore) save(webhooks map[string]*Webhook) error {
+	return writeJSON(filepath.Join(s.dir, "webhooks.json"), webhooks)
+}
+
+func (s *FileStore) logPath(webhookID string) string {
+	return filepath.Join(s.dir, "logs", webhookID+".json")
+}
+
+func (s *FileStore) loadLogs(webhookID string) ([]DeliveryLog, error) {
+	var logs []DeliveryLog
+	data, err := os.ReadFile(s.logPath(webhookID))
+	if os.IsNotExist(err) {
+		return logs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery log: %w", err)
+	}
+	return logs, nil
+}
+
+// writeJSON writes v to a temporary file and renames it so that a crash
+// never leaves a truncated file behind
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// validID rejects IDs that could escape the store directory
+func validID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid outbound webhook id %q", id)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package outboundwebhook sends session lifecycle events to webhook URLs
+// registered by users and administrators, and the completion of a session to
+// the callback URL given when it was created.
+//
+// Each request carries the JSON event as its body, signed with the secret of
+// the webhook:
+//
+//	X-AgentAPI-Event:         session.created
+//	X-AgentAPI-Delivery:      <event id>
+//	X-AgentAPI-Signature-256: sha256=<hex HMAC-SHA256 of the body>
+//
+// Failed requests are retried with backoff by the delivery queue and every
+// attempt is kept in a per-webhook delivery log for debugging.
+package outboundwebhook
+
+import (
+	"errors"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+// Event types sent to outbound webhooks
+const (
+	EventSessionCreated = "session.created"
+	EventSessionActive  = "session.active"
+	EventSessionFailed  = "session.failed"
+	EventSessionCrashed = "session.crashed"
+	EventSessionEvicted = "session.evicted"
+	EventSessionDeleted = "session.deleted"
+	EventRunCompleted   = "run.completed"
+	// EventPing is only sent by the test endpoint
+	EventPing = "ping"
+)
+
+// EventTypes lists the event types a webhook can subscribe to
+var EventTypes = []string{
+	EventSessionCreated,
+	EventSessionActive,
+	EventSessionFailed,
+	EventSessionCrashed,
+	EventSessionEvicted,
+	EventSessionDeleted,
+	EventRunCompleted,
+}
+
+// ScopeGlobal is the scope of administrator webhooks, which receive the
+// events of every session
+const ScopeGlobal entities.ResourceScope = "global"
+
+// ErrNotFound is returned when a webhook does not exist
+var ErrNotFound = errors.New("outbound webhook not found")
+
+// Webhook is a registered outbound webhook. A user-scoped webhook receives
+// the events of the user's own sessions, a team-scoped webhook those of the
+// team's sessions and a global webhook those of all sessions.
+type Webhook struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	URL       string                 `json:"url"`
+	Secret    string                 `json:"secret"`
+	Events    []string               `json:"events,omitempty"`
+	Scope     entities.ResourceScope `json:"scope"`
+	UserID    string                 `json:"user_id"`
+	TeamID    string                 `json:"team_id,omitempty"`
+	Active    bool                   `json:"active"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of eventType. A
+// webhook without event types receives all events.
+func (w *Webhook) Subscribes(eventType string) bool {
+	if eventType == EventPing || len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Receives reports whether the webhook may receive events of session
+func (w *Webhook) Receives(session *EventSession) bool {
+	switch w.Scope {
+	case ScopeGlobal:
+		return true
+	case entities.ScopeTeam:
+		return session != nil && session.Scope == string(entities.ScopeTeam) && session.TeamID == w.TeamID
+	default:
+		return session != nil && session.Scope != string(entities.ScopeTeam) && session.UserID == w.UserID
+	}
+}
+
+// Event is the JSON body sent to outbound webhooks
+type Event struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	Timestamp time.Time     `json:"timestamp"`
+	Message   string        `json:"message,omitempty"`
+	Session   *EventSession `json:"session,omitempty"`
+}
+
+// EventSession describes the session an event is about
+type EventSession struct {
+	ID     string            `json:"id"`
+	UserID string            `json:"user_id,omitempty"`
+	Scope  string            `json:"scope,omitempty"`
+	TeamID string            `json:"team_id,omitempty"`
+	Status string            `json:"status,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+// DeliveryLog is one attempt to deliver an event to a webhook
+type DeliveryLog struct {
+	ID           string    `json:"id"`
+	WebhookID    string    `json:"webhook_id"`
+	EventID      string    `json:"event_id"`
+	EventType    string    `json:"event_type"`
+	Retry        bool      `json:"retry"`
+	Success      bool      `json:"success"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+	Timestamp    time.Time `json:"timestamp"`
+}
@@ -0,0 +1,94 @@
+package provisioner
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/agentadapter"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+// claudeProjectsPath holds the conversation transcripts of Claude Code. It is
+// on the dot-claude EmptyDir, so it does not outlive the Pod.
+var claudeProjectsPath = filepath.Join(runtimeHome, ".claude", "projects")
+
+// checkpointDir is where a checkpoint of the conversation is kept: in the
+// workdir, which is on the session PVC when one is used.
+func checkpointDir() string {
+	return filepath.Join(workspaceRoot(), ".agentapi-checkpoint")
+}
+
+// handleCheckpoint saves the agent conversation so that the next Pod of the
+// session can resume it. It is called by the preStop hook of the session
+// Pod. Only requests from inside the Pod are accepted.
+func (s *Server) handleCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err := saveCheckpoint(claudeProjectsPath, checkpointDir()); err != nil {
+		log.Printf("[PROVISIONER] Failed to checkpoint the conversation: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[PROVISIONER] Conversation checkpointed to %s", checkpointDir())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveCheckpoint copies the transcripts in projectsDir to dir, replacing an
+// older checkpoint only once the copy is complete.
+func saveCheckpoint(projectsDir, dir string) error {
+	if _, err := os.Stat(projectsDir); err != nil {
+		return fmt.Errorf("no conversation to checkpoint: %w", err)
+	}
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.CopyFS(filepath.Join(tmp, "projects"), os.DirFS(projectsDir)); err != nil {
+		_ = os.RemoveAll(tmp)
+		return fmt.Errorf("failed to copy the conversation: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// restoreCheckpoint moves the conversation checkpointed in dir back into
+// projectsDir. It reports whether there was a checkpoint to restore; the
+// checkpoint is consumed either way so that a broken one is not retried.
+func restoreCheckpoint(dir, projectsDir string) bool {
+	saved := filepath.Join(dir, "projects")
+	if _, err := os.Stat(saved); err != nil {
+		return false
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	if err := os.RemoveAll(projectsDir); err != nil {
+		log.Printf("[PROVISIONER] Failed to restore the checkpointed conversation: %v", err)
+		return false
+	}
+	if err := os.CopyFS(projectsDir, os.DirFS(saved)); err != nil {
+		log.Printf("[PROVISIONER] Failed to restore the checkpointed conversation: %v", err)
+		return false
+	}
+	return true
+}
+
+// resumesWithClaudeCLI reports whether the agent of settings is the default
+// Claude Code CLI, which can resume a checkpointed conversation with -c.
+func resumesWithClaudeCLI(settings *sessionsettings.SessionSettings) bool {
+	if settings.Startup.Override && len(settings.Startup.Command) > 0 {
+		return false
+	}
+	return agentadapter.Get(settings.Session.AgentType).CanResume()
+}
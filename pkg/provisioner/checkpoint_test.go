@@ -0,0 +1,74 @@
+package provisioner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	projects := filepath.Join(root, "claude", "projects")
+	dir := filepath.Join(root, "workdir", ".agentapi-checkpoint")
+	if err := os.MkdirAll(filepath.Join(projects, "-home-agentapi-workdir-repo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	transcript := filepath.Join("-home-agentapi-workdir-repo", "conversation.jsonl")
+	if err := os.WriteFile(filepath.Join(projects, transcript), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if restoreCheckpoint(dir, projects) {
+		t.Fatal("restored a checkpoint that was never saved")
+	}
+	// Saving twice replaces the older checkpoint.
+	for i := 0; i < 2; i++ {
+		if err := saveCheckpoint(projects, dir); err != nil {
+			t.Fatalf("saveCheckpoint: %v", err)
+		}
+	}
+
+	// The next Pod starts with an empty dot-claude volume.
+	if err := os.RemoveAll(projects); err != nil {
+		t.Fatal(err)
+	}
+	if !restoreCheckpoint(dir, projects) {
+		t.Fatal("checkpoint not restored")
+	}
+	if data, err := os.ReadFile(filepath.Join(projects, transcript)); err != nil || string(data) != "{}\n" {
+		t.Fatalf("restored transcript = %q, %v", data, err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint not consumed: %v", err)
+	}
+}
+
+func TestHandleCheckpointRejectsRemoteRequests(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/checkpoint", nil)
+	req.RemoteAddr = "10.0.0.5:40000"
+	resp := httptest.NewRecorder()
+	(&Server{}).handleCheckpoint(resp, req)
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("remote status = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestBuildAgentCommandResumesConversation(t *testing.T) {
+	settings := &sessionsettings.SessionSettings{}
+	if !resumesWithClaudeCLI(settings) {
+		t.Fatal("default agent should resume with claude -c")
+	}
+	_, args := (&Server{resumeConversation: true}).buildAgentCommand(settings, nil)
+	if got := args[len(args)-1]; got != "claude -c" {
+		t.Fatalf("agent command = %q, want %q", got, "claude -c")
+	}
+
+	settings.Session.AgentType = "codex-acp"
+	if resumesWithClaudeCLI(settings) {
+		t.Fatal("codex-acp cannot resume with claude -c")
+	}
+}
@@ -0,0 +1,40 @@
+package provisioner
+
+import (
+	"log"
+	"net"
+	"net/http"
+)
+
+// Done is closed when the agent reported that the session is complete. The
+// agent-provisioner then exits successfully, which completes the Job of a
+// oneshot session.
+func (s *Server) Done() <-chan struct{} {
+	return s.doneChan()
+}
+
+func (s *Server) doneChan() chan struct{} {
+	s.doneOnce.Do(func() {
+		s.done = make(chan struct{})
+	})
+	return s.done
+}
+
+// handleComplete marks the session complete. Only requests from inside the
+// Pod are accepted, since the provisioner port is reachable cluster-wide.
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	s.completeOnce.Do(func() {
+		log.Printf("[PROVISIONER] Session completed")
+		close(s.doneChan())
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
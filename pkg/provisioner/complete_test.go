@@ -0,0 +1,40 @@
+package provisioner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleComplete(t *testing.T) {
+	server := &Server{}
+
+	remote := httptest.NewRequest(http.MethodPost, "/complete", nil)
+	remote.RemoteAddr = "10.0.0.5:40000"
+	resp := httptest.NewRecorder()
+	server.handleComplete(resp, remote)
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("remote status = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+	select {
+	case <-server.Done():
+		t.Fatal("remote request completed the session")
+	default:
+	}
+
+	// Completing twice is harmless
+	for i := 0; i < 2; i++ {
+		local := httptest.NewRequest(http.MethodPost, "/complete", nil)
+		local.RemoteAddr = "127.0.0.1:40000"
+		resp = httptest.NewRecorder()
+		server.handleComplete(resp, local)
+		if resp.Code != http.StatusNoContent {
+			t.Fatalf("local status = %d, want %d", resp.Code, http.StatusNoContent)
+		}
+	}
+	select {
+	case <-server.Done():
+	default:
+		t.Fatal("Done() not closed after /complete")
+	}
+}
@@ -0,0 +1,36 @@
+package provisioner
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
+)
+
+// extensionReportPath is where session setup records the marketplaces and
+// plugins it registered.
+var extensionReportPath = filepath.Join(runtimeHome, ".session", "extensions.json")
+
+// SetExtensionsReporter installs a callback invoked with the extension report
+// after every setup that ran the marketplaces phase.
+func (s *Server) SetExtensionsReporter(fn func(*startup.ExtensionReport)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extensionsReporter = fn
+}
+
+// publishExtensionReport hands the report written by the last setup to the
+// extensions reporter.
+func (s *Server) publishExtensionReport() {
+	report, err := startup.ReadExtensionReport(extensionReportPath)
+	if err != nil {
+		log.Printf("[PROVISIONER] Warning: no extension report to publish: %v", err)
+		return
+	}
+	s.mu.RLock()
+	reporter := s.extensionsReporter
+	s.mu.RUnlock()
+	if reporter != nil {
+		reporter(report)
+	}
+}
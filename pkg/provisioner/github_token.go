@@ -0,0 +1,110 @@
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
+)
+
+const (
+	// githubTokenMaxWait bounds the time between two token fetches.
+	githubTokenMaxWait = 30 * time.Minute
+	// githubTokenMinWait keeps a misbehaving proxy from being polled in a loop.
+	githubTokenMinWait = time.Minute
+	// githubTokenRenewBefore is how long before expiry the token is replaced.
+	githubTokenRenewBefore = 10 * time.Minute
+)
+
+type githubTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// runGitHubTokenRefresher keeps the gh CLI token of a session authenticated
+// with the proxy's GitHub App fresh. The proxy mints installation tokens,
+// which expire after an hour; without this loop git and gh stop working in
+// long-running sessions. Sessions started with an explicit GITHUB_TOKEN have
+// nothing to refresh and return immediately.
+func runGitHubTokenRefresher(ctx context.Context, client *http.Client, cfg PullClientConfig, settings *sessionsettings.SessionSettings) {
+	if settings.Env["GITHUB_TOKEN"] != "" {
+		return
+	}
+	env := mergeEnv(os.Environ(), settings.Env)
+	wait := githubTokenMaxWait
+	for {
+		sleepOrDone(ctx, wait)
+		if ctx.Err() != nil {
+			return
+		}
+		token, ok, err := fetchGitHubToken(ctx, client, cfg)
+		switch {
+		case err != nil:
+			log.Printf("[PROVISIONER] Failed to fetch GitHub token: %v", err)
+			wait = githubTokenMinWait
+			continue
+		case !ok:
+			log.Printf("[PROVISIONER] Proxy does not issue GitHub App tokens for this session, stopping token refresh")
+			return
+		}
+		if err := startup.RefreshGitHubCLIToken(settings.Env["GITHUB_API"], token.Token, env); err != nil {
+			log.Printf("[PROVISIONER] Failed to install refreshed GitHub token: %v", err)
+			wait = githubTokenMinWait
+			continue
+		}
+		log.Printf("[PROVISIONER] Refreshed GitHub token (expires at %s)", token.ExpiresAt.Format(time.RFC3339))
+		wait = nextGitHubTokenRefresh(token.ExpiresAt, time.Now())
+	}
+}
+
+// nextGitHubTokenRefresh returns how long to wait before fetching a
+// replacement for a token expiring at expiresAt.
+func nextGitHubTokenRefresh(expiresAt, now time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return githubTokenMaxWait
+	}
+	wait := expiresAt.Sub(now) - githubTokenRenewBefore
+	if wait > githubTokenMaxWait {
+		return githubTokenMaxWait
+	}
+	if wait < githubTokenMinWait {
+		return githubTokenMinWait
+	}
+	return wait
+}
+
+func fetchGitHubToken(ctx context.Context, client *http.Client, cfg PullClientConfig) (*githubTokenResponse, bool, error) {
+	path := "/internal/session-provisioners/" + url.PathEscape(cfg.SessionID) + "/github-token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.ProxyURL+path, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	authorizePullRequest(req, cfg)
+	req.Header.Set("X-Session-Token", cfg.SessionToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("GET %s returned HTTP %d", path, resp.StatusCode)
+	}
+	var token githubTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, false, err
+	}
+	if token.Token == "" {
+		return nil, false, fmt.Errorf("GET %s returned an empty token", path)
+	}
+	return &token, true, nil
+}
@@ -0,0 +1,73 @@
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNextGitHubTokenRefresh(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      time.Duration
+	}{
+		{name: "unknown expiry", expiresAt: time.Time{}, want: githubTokenMaxWait},
+		{name: "long-lived token is capped", expiresAt: now.Add(2 * time.Hour), want: githubTokenMaxWait},
+		{name: "renews before expiry", expiresAt: now.Add(30 * time.Minute), want: 20 * time.Minute},
+		{name: "nearly expired token waits the minimum", expiresAt: now.Add(5 * time.Minute), want: githubTokenMinWait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextGitHubTokenRefresh(tt.expiresAt, now); got != tt.want {
+				t.Errorf("nextGitHubTokenRefresh() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/session-provisioners/sess-1/github-token" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer prov-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Session-Token") != "sess-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"token": "ghs_new", "expires_at": expiresAt})
+	}))
+	defer srv.Close()
+
+	cfg := PullClientConfig{ProxyURL: srv.URL, Token: "prov-token", SessionID: "sess-1", SessionToken: "sess-token"}
+	token, ok, err := fetchGitHubToken(context.Background(), srv.Client(), cfg)
+	if err != nil || !ok {
+		t.Fatalf("fetchGitHubToken() = %v, %v", ok, err)
+	}
+	if token.Token != "ghs_new" || !token.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected token %+v", token)
+	}
+}
+
+func TestFetchGitHubToken_NoContentMeansNotIssued(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := PullClientConfig{ProxyURL: srv.URL, Token: "prov-token", SessionID: "sess-1"}
+	token, ok, err := fetchGitHubToken(context.Background(), srv.Client(), cfg)
+	if err != nil || ok || token != nil {
+		t.Fatalf("fetchGitHubToken() = %+v, %v, %v; want nil, false, nil", token, ok, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -18,6 +19,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/takutakahashi/agentapi-proxy/pkg/agentadapter"
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -131,6 +133,21 @@ func normalizeNativeSettings(settings *sessionsettings.SessionSettings) {
 	settings.Sandbox = nil
 }
 
+// provisionSetupOptions returns the session setup options for the settings
+// written to settingsPath.
+func provisionSetupOptions(settingsPath string) sessionsettings.SetupOptions {
+	compileOpts := sessionsettings.DefaultCompileOptions()
+	compileOpts.InputPath = settingsPath
+	return sessionsettings.SetupOptions{
+		InputPath:                 settingsPath,
+		CompileOptions:            compileOpts,
+		NotificationSubscriptions: nativeRuntimePath("notification-subscriptions-source", "/notification-subscriptions-source"),
+		NotificationsDir:          filepath.Join(runtimeHome, "notifications"),
+		RegisterMarketplaces:      true,
+		ExtensionReportPath:       extensionReportPath,
+	}
+}
+
 // runProvision executes the full provisioning sequence and then supervises
 // the agentapi subprocess.
 //
@@ -139,7 +156,7 @@ func normalizeNativeSettings(settings *sessionsettings.SessionSettings) {
 //  2. Run sessionsettings.Setup() (write-pem, clone-repo, compile, sync-extra)
 //  3. Load the generated session env file
 //  4. Fetch memory from the proxy and inject into CLAUDE.md
-//  5. cd into the cloned repo if present
+//  5. cd into the cloned repo if present and run the setup hooks
 //  6. Start agentapi (or an ACP bridge) as a subprocess
 //  7. Wait for agentapi to become ready
 //  8. Send the initial message if specified in settings
@@ -186,16 +203,15 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 	// ── Step 2: run setup ─────────────────────────────────────────────────────
 	// Override CompileOptions.InputPath to use the temp file written above,
 	// not the default /session-settings/settings.yaml (which is no longer mounted).
-	compileOpts := sessionsettings.DefaultCompileOptions()
-	compileOpts.InputPath = provisionTempSettings
+	opts := provisionSetupOptions(provisionTempSettings)
+	s.setProvisionedSettings(settings)
+	// The repository clone inside Setup may go through a TLS-intercepting
+	// corporate proxy, so the corporate CA bundle must exist beforehand.
+	prepareExtraCABundle(map[string]string{
+		"AGENTAPI_EXTRA_CA_FILE": os.Getenv("AGENTAPI_EXTRA_CA_FILE"),
+		"SSL_CERT_FILE":          os.Getenv("SSL_CERT_FILE"),
+	})
 
-	opts := sessionsettings.SetupOptions{
-		InputPath:                 provisionTempSettings,
-		CompileOptions:            compileOpts,
-		NotificationSubscriptions: nativeRuntimePath("notification-subscriptions-source", "/notification-subscriptions-source"),
-		NotificationsDir:          filepath.Join(runtimeHome, "notifications"),
-		RegisterMarketplaces:      true,
-	}
 	s.setPhase("provision:session-setup")
 	log.Printf("[PROVISIONER] Running session setup")
 	if err := sessionsettings.Setup(opts); err != nil {
@@ -203,11 +219,19 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 		return
 	}
 	log.Printf("[PROVISIONER] Session setup complete")
+	s.publishExtensionReport()
 
 	// ── Step 2.3: docker login for DinD registries ───────────────────────────
 	s.setPhase("provision:post-setup")
 	if settings.Docker != nil && settings.Docker.Enabled {
-		go s.runDockerLogins(ctx, settings.Docker)
+		if settings.Docker.Mode == sessionsettings.DockerModeBuildKit {
+			configPath := filepath.Join(runtimeHome, ".docker", "config.json")
+			if err := writeDockerAuthConfig(configPath, dockerRegistryConfigPath, settings.Docker); err != nil {
+				log.Printf("[PROVISIONER] Warning: failed to write docker registry credentials: %v", err)
+			}
+		} else {
+			go s.runDockerLogins(ctx, settings.Docker)
+		}
 	}
 
 	// ── Step 2.5: restore managed files from provision payload ───────────────
@@ -223,7 +247,7 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 		// This preserves user customisations while ensuring oneshot/cycle/managed
 		// hooks injected by the proxy are always present.
 		if settings.Claude.SettingsJSON != nil {
-			claudeSettingsPath := filepath.Join(compileOpts.OutputDir, ".claude", "settings.json")
+			claudeSettingsPath := filepath.Join(opts.CompileOptions.OutputDir, ".claude", "settings.json")
 			if err := mergeHooksIntoSettingsFile(claudeSettingsPath, settings.Claude.SettingsJSON); err != nil {
 				log.Printf("[PROVISIONER] Warning: failed to re-apply hooks to settings.json: %v", err)
 			}
@@ -258,16 +282,22 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 	envMap := loadEnvFile(sessionEnvFile)
 	log.Printf("[PROVISIONER] Loaded %d env vars from session env file", len(envMap))
 	prepareSciaCABundle(ctx, envMap)
+	prepareExtraCABundle(envMap)
 
 	// ── Step 4: fetch memory from proxy → inject into CLAUDE.md ──────────────
 	s.setPhase("provision:fetch-memory")
 	s.fetchAndInjectMemory(envMap)
 
-	// ── Step 4.5: expose managed instructions and MCP servers to Pi ──────────
+	// ── Step 4.5: expose managed instructions and MCP servers to other agents ─
 	s.setPhase("provision:write-agent-files")
-	if err := writePiAgentInstructions(claudeMDPath, piAgentInstructionsPath); err != nil {
+	if err := writeAgentInstructions(claudeMDPath, piAgentInstructionsPath); err != nil {
 		log.Printf("[PROVISIONER] Warning: failed to write Pi AGENTS.md: %v", err)
 	}
+	if path := agentInstructionsPath(settings.Session.AgentType); path != "" {
+		if err := writeAgentInstructions(claudeMDPath, path); err != nil {
+			log.Printf("[PROVISIONER] Warning: failed to write %s: %v", path, err)
+		}
+	}
 	if err := writePiMCPServers(piSettingsPath, piMCPServers(settings)); err != nil {
 		log.Printf("[PROVISIONER] Warning: failed to write Pi MCP settings: %v", err)
 	}
@@ -288,6 +318,15 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 		}
 	}
 
+	// ── Step 5.3: run setup hooks ────────────────────────────────────────────
+	// Setup hooks come from the session profile (e.g. "npm ci") and run in the
+	// cloned repository. A failing hook fails the session unless its policy is
+	// "continue".
+	if err := s.runSetupHooks(ctx, settings.SetupHooks, envMap); err != nil {
+		s.setStatus(StatusError, err.Error())
+		return
+	}
+
 	// ── Step 5.5: run pre-script ─────────────────────────────────────────────
 	// Executes the optional shell pre-script before starting the agent.
 	// Pre-scripts are used for setup tasks such as pre-fetching npm/bun packages.
@@ -311,6 +350,12 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 
 	// ── Step 7: build and start the agent subprocess ──────────────────────────
 	s.setPhase("provision:start-agent")
+	// A conversation checkpointed by the preStop hook of the previous Pod is
+	// resumed instead of sending the initial message again.
+	if resumesWithClaudeCLI(settings) && restoreCheckpoint(checkpointDir(), claudeProjectsPath) {
+		log.Printf("[PROVISIONER] Resuming the checkpointed conversation")
+		s.resumeConversation = true
+	}
 	agentCmd, agentArgs := s.buildAgentCommand(settings, envMap)
 	log.Printf("[PROVISIONER] Starting agent: %s %v", agentCmd, agentArgs)
 
@@ -334,7 +379,8 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 	agentapiURL := fmt.Sprintf("http://localhost:%s", agentapiPort)
 
 	log.Printf("[PROVISIONER] Waiting for agentapi to be ready at %s", agentapiURL)
-	if err := waitForAgentAPI(ctx, agentapiURL, 120); err != nil {
+	healthPath := agentadapter.Get(settings.Session.AgentType).HealthPath()
+	if err := waitForAgentAPI(ctx, agentapiURL, healthPath, 120); err != nil {
 		s.setStatus(StatusError, fmt.Sprintf("agentapi not ready: %v", err))
 		_ = cmd.Process.Kill()
 		return
@@ -348,7 +394,7 @@ func (s *Server) runProvision(ctx context.Context, settings *sessionsettings.Ses
 	enableNetworkFilterPolicy()
 
 	// ── Step 9: send initial message ─────────────────────────────────────────
-	if settings.InitialMessage != "" {
+	if settings.InitialMessage != "" && !s.resumeConversation {
 		s.setPhase("provision:send-initial-message")
 		log.Printf("[PROVISIONER] Sending initial message")
 		agentType := settings.Session.AgentType
@@ -479,6 +525,79 @@ func (s *Server) runDockerLogins(ctx context.Context, docker *sessionsettings.Do
 	}
 }
 
+// dockerRegistryConfigPath is where the session manager mounts a registry
+// Secret in the main container of BuildKit sessions.
+const dockerRegistryConfigPath = "/etc/agentapi/docker-registry/config.json"
+
+// writeDockerAuthConfig merges registry credentials into the docker config at
+// configPath for sessions running BuildKit, which have no daemon to
+// "docker login" against: buildctl reads the credentials from that file.
+// Auths from the mounted registry Secret at secretPath are merged first, then
+// inline credentials, which win on conflict.
+func writeDockerAuthConfig(configPath, secretPath string, docker *sessionsettings.DockerConfig) error {
+	auths := make(map[string]interface{})
+	mergeAuths := func(path string) error {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var cfg struct {
+			Auths map[string]interface{} `json:"auths"`
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		for server, auth := range cfg.Auths {
+			auths[server] = auth
+		}
+		return nil
+	}
+
+	// Keep any other settings of an existing config file.
+	config := make(map[string]interface{})
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("parse %s: %w", configPath, err)
+		}
+		if err := mergeAuths(configPath); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := mergeAuths(secretPath); err != nil {
+		return err
+	}
+	for _, reg := range docker.Registries {
+		if reg.Username == "" || reg.Password == "" {
+			continue
+		}
+		server := reg.Server
+		if server == "" {
+			server = "https://index.docker.io/v1/"
+		}
+		auths[server] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(reg.Username + ":" + reg.Password)),
+		}
+	}
+	if len(auths) == 0 {
+		return nil
+	}
+	config["auths"] = auths
+
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, out, 0o600)
+}
+
 // runPreScript executes the pre-script shell snippet before the agent process starts.
 // It inherits the session environment and streams stdout/stderr to the provisioner log.
 func (s *Server) runPreScript(ctx context.Context, script string, envMap map[string]string) error {
@@ -1059,84 +1178,36 @@ service:
 }
 
 // buildAgentCommand returns the executable and arguments for the agent
-// process, mirroring the logic in BuildRemoteProvisionSettings().
+// process, mirroring the logic in BuildRemoteProvisionSettings(). An
+// overridden startup command from the session settings takes precedence.
 func (s *Server) buildAgentCommand(settings *sessionsettings.SessionSettings, envMap map[string]string) (string, []string) {
 	agentType := settings.Session.AgentType
+	if startup := settings.Startup; startup.Override && len(startup.Command) > 0 {
+		// Operator startup template for this agent type
+		if agentType == "pi-ollama" {
+			ensurePiOllamaEnv(envMap)
+		}
+		args := append(append([]string(nil), startup.Command[1:]...), startup.Args...)
+		return startup.Command[0], args
+	}
 
 	agentapiPort := os.Getenv("AGENTAPI_PORT")
 	if agentapiPort == "" {
 		agentapiPort = "8080"
 	}
 
-	switch agentType {
-	case "claude-acp":
-		// Start the acp-server bridge that wraps claude-agent-acp (ACP agent) via stdio.
-		// The bridge exposes an agentapi-compatible HTTP server on AGENTAPI_PORT.
-		// --output-file writes conversation history in acp-posts JSONL format for Slack integration.
-		// claude-agent-acp is the official ACP adapter for the Claude Agent SDK:
-		// https://github.com/agentclientprotocol/claude-agent-acp
-		return "agentapi-proxy", []string{
-			"acp-server",
-			"--port", agentapiPort,
-			"--output-file", acpHistoryPath,
-			"--",
-			"bunx", "@agentclientprotocol/claude-agent-acp",
-		}
-
-	case "codex-acp":
-		// Start the acp-server bridge that wraps codex-acp (ACP adapter for OpenAI Codex) via stdio.
-		// https://github.com/agentclientprotocol/codex-acp
-		// --auto-approve bypasses the UI permission modal at the ACP bridge layer.
-		return "agentapi-proxy", []string{
-			"acp-server",
-			"--port", agentapiPort,
-			"--auto-approve",
-			"--",
-			"npx", "-y", "@agentclientprotocol/codex-acp",
-		}
-
-	case "pi-ollama":
-		// Start the acp-server bridge that wraps pi-acp via stdio. pi-acp starts
-		// `pi --mode rpc`; the image/pre-script install pi-ollama-cloud so Pi can
-		// talk directly to Ollama Cloud without a local Ollama daemon.
-		// https://github.com/svkozak/pi-acp
+	if agentType == "pi-ollama" {
+		// pi-acp starts `pi --mode rpc`; the image/pre-script install
+		// pi-ollama-cloud so Pi can talk directly to Ollama Cloud without a
+		// local Ollama daemon.
 		ensurePiOllamaEnv(envMap)
-		return "agentapi-proxy", []string{
-			"acp-server",
-			"--port", agentapiPort,
-			"--auto-approve",
-			"--",
-			"npx", "-y", "pi-acp",
-		}
-
-	case "cursor":
-		// Start the acp-server bridge that wraps Cursor Agent CLI's native ACP server via stdio.
-		// https://cursor.com/docs/cli/acp
-		// --auto-approve bypasses the UI permission modal at the ACP bridge layer.
-		return "agentapi-proxy", []string{
-			"acp-server",
-			"--port", agentapiPort,
-			"--auto-approve",
-			"--raw-json-log",
-			"--",
-			"agent", "acp",
-		}
-
-	default:
-		// Default: agentapi server wrapping claude
-		claudeCmd := "claude"
-		if claudeArgs := os.Getenv("CLAUDE_ARGS"); claudeArgs != "" {
-			claudeCmd = claudeCmd + " " + claudeArgs
-		}
-		return "agentapi", []string{
-			"server",
-			"--allowed-hosts", "*",
-			"--allowed-origins", "*",
-			"--port", agentapiPort,
-			"--",
-			"sh", "-c", claudeCmd,
-		}
 	}
+	return agentadapter.Get(agentType).Command(agentadapter.CommandOptions{
+		Port:        agentapiPort,
+		Resume:      s.resumeConversation,
+		ClaudeArgs:  os.Getenv("CLAUDE_ARGS"),
+		HistoryFile: acpHistoryPath,
+	})
 }
 
 func ensurePiOllamaEnv(envMap map[string]string) {
@@ -1221,7 +1292,24 @@ func symlinkCodexSkillsForPi(codexPath, piPath string) error {
 	return fmt.Errorf("pi skills path already exists and is not a directory or symlink: %s", piPath)
 }
 
-func writePiAgentInstructions(sourcePath, destPath string) error {
+// agentInstructionsPath returns where the agent of agentType reads its global
+// instructions when that is not the managed CLAUDE.md or the Pi AGENTS.md,
+// which are always written, or "" when there is nothing more to write.
+func agentInstructionsPath(agentType string) string {
+	instructions := agentadapter.Get(agentType).ConfigLayout().Instructions
+	if instructions == "" {
+		return ""
+	}
+	path := filepath.Join(runtimeHome, instructions)
+	if path == claudeMDPath || path == piAgentInstructionsPath {
+		return ""
+	}
+	return path
+}
+
+// writeAgentInstructions copies the managed instructions at sourcePath to the
+// instructions file of another agent. A missing source is skipped.
+func writeAgentInstructions(sourcePath, destPath string) error {
 	if strings.TrimSpace(sourcePath) == "" || strings.TrimSpace(destPath) == "" {
 		return fmt.Errorf("source and destination paths must be set")
 	}
@@ -1233,12 +1321,12 @@ func writePiAgentInstructions(sourcePath, destPath string) error {
 		return fmt.Errorf("read source instructions: %w", err)
 	}
 	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-		return fmt.Errorf("create Pi agent dir: %w", err)
+		return fmt.Errorf("create agent instructions dir: %w", err)
 	}
 	if err := os.WriteFile(destPath, content, 0o644); err != nil {
-		return fmt.Errorf("write Pi instructions: %w", err)
+		return fmt.Errorf("write agent instructions: %w", err)
 	}
-	log.Printf("[PROVISIONER] Wrote Pi instructions to %s", destPath)
+	log.Printf("[PROVISIONER] Wrote agent instructions to %s", destPath)
 	return nil
 }
 
@@ -1290,11 +1378,11 @@ func writePiMCPServers(settingsPath string, mcpServers map[string]interface{}) e
 	return nil
 }
 
-// waitForAgentAPI polls agentapiURL/status until it responds 200 or the
+// waitForAgentAPI polls agentapiURL + healthPath until it responds 200 or the
 // context is cancelled.  maxRetries × 0.5 s = total wait time.
-func waitForAgentAPI(ctx context.Context, agentapiURL string, maxRetries int) error {
+func waitForAgentAPI(ctx context.Context, agentapiURL, healthPath string, maxRetries int) error {
 	client := &http.Client{Timeout: 3 * time.Second}
-	statusURL := agentapiURL + "/status"
+	statusURL := agentapiURL + healthPath
 
 	for i := 0; i < maxRetries; i++ {
 		select {
@@ -1352,29 +1440,60 @@ func waitForSciaProxy(ctx context.Context, proxyURL string, timeout time.Duratio
 	log.Printf("[PROVISIONER] Warning: scia proxy did not become ready before timeout: %s", proxyURL)
 }
 
-func prepareSciaCABundle(ctx context.Context, envMap map[string]string) {
-	proxyURL := envMap["AGENTAPI_SCIA_PROXY_URL"]
-	if proxyURL == "" {
+// extraCABundlePath is the bundle the proxy points SSL_CERT_FILE at when a
+// corporate CA is configured without scia.
+const extraCABundlePath = "/tmp/agentapi-ca-bundle.pem"
+
+// prepareExtraCABundle adds the corporate CA (AGENTAPI_EXTRA_CA_FILE) to the
+// bundle named by SSL_CERT_FILE. With scia the bundle written by
+// prepareSciaCABundle is extended and NODE_EXTRA_CA_CERTS is switched to it so
+// Node trusts both CAs; otherwise the bundle is rebuilt from the system store.
+// The bundle is written even when the corporate CA cannot be read so that
+// SSL_CERT_FILE never points at a missing file.
+func prepareExtraCABundle(envMap map[string]string) {
+	extraCAPath := envMap["AGENTAPI_EXTRA_CA_FILE"]
+	if extraCAPath == "" {
 		return
 	}
-	waitForSciaProxy(ctx, proxyURL, 15*time.Second)
-
-	sciaCAPath := envMap["NODE_EXTRA_CA_CERTS"]
-	if sciaCAPath == "" {
-		sciaCAPath = "/etc/scia/mitm/ca.pem"
-	}
 	bundlePath := envMap["SSL_CERT_FILE"]
-	if bundlePath == "" || bundlePath == sciaCAPath {
+	sciaEnabled := envMap["AGENTAPI_SCIA_PROXY_URL"] != ""
+	if bundlePath != extraCABundlePath && !sciaEnabled {
+		return
+	}
+	if sciaEnabled && (bundlePath == "" || bundlePath == envMap["NODE_EXTRA_CA_CERTS"]) {
 		return
 	}
 
-	sciaCA, err := os.ReadFile(sciaCAPath)
+	var bundle []byte
+	if sciaEnabled {
+		existing, err := os.ReadFile(bundlePath)
+		if err != nil {
+			log.Printf("[PROVISIONER] Warning: failed to read CA bundle %s: %v", bundlePath, err)
+			return
+		}
+		bundle = existing
+	} else {
+		bundle = readSystemCABundle()
+	}
+
+	extraCA, err := os.ReadFile(extraCAPath)
 	if err != nil {
-		log.Printf("[PROVISIONER] Warning: failed to read scia CA %s: %v", sciaCAPath, err)
+		log.Printf("[PROVISIONER] Warning: failed to read corporate CA %s: %v", extraCAPath, err)
+	} else {
+		bundle = appendPEM(bundle, extraCA)
+	}
+	if err := os.WriteFile(bundlePath, bundle, 0o600); err != nil {
+		log.Printf("[PROVISIONER] Warning: failed to write CA bundle %s: %v", bundlePath, err)
 		return
 	}
+	if sciaEnabled {
+		envMap["NODE_EXTRA_CA_CERTS"] = bundlePath
+	}
+	log.Printf("[PROVISIONER] Wrote corporate CA bundle to %s", bundlePath)
+}
 
-	var bundle []byte
+// readSystemCABundle returns the first system CA bundle found on the host.
+func readSystemCABundle() []byte {
 	for _, systemPath := range []string{
 		"/etc/ssl/certs/ca-certificates.crt",
 		"/etc/pki/tls/certs/ca-bundle.crt",
@@ -1382,17 +1501,47 @@ func prepareSciaCABundle(ctx context.Context, envMap map[string]string) {
 	} {
 		systemCA, err := os.ReadFile(systemPath)
 		if err == nil && len(systemCA) > 0 {
-			bundle = append(bundle, systemCA...)
-			if !bytes.HasSuffix(bundle, []byte("\n")) {
-				bundle = append(bundle, '\n')
-			}
-			break
+			return appendPEM(nil, systemCA)
 		}
 	}
-	bundle = append(bundle, sciaCA...)
+	return nil
+}
+
+// appendPEM appends pem to bundle, keeping certificates newline-separated.
+func appendPEM(bundle, pem []byte) []byte {
+	if len(bundle) > 0 && !bytes.HasSuffix(bundle, []byte("\n")) {
+		bundle = append(bundle, '\n')
+	}
+	bundle = append(bundle, pem...)
 	if !bytes.HasSuffix(bundle, []byte("\n")) {
 		bundle = append(bundle, '\n')
 	}
+	return bundle
+}
+
+func prepareSciaCABundle(ctx context.Context, envMap map[string]string) {
+	proxyURL := envMap["AGENTAPI_SCIA_PROXY_URL"]
+	if proxyURL == "" {
+		return
+	}
+	waitForSciaProxy(ctx, proxyURL, 15*time.Second)
+
+	sciaCAPath := envMap["NODE_EXTRA_CA_CERTS"]
+	if sciaCAPath == "" {
+		sciaCAPath = "/etc/scia/mitm/ca.pem"
+	}
+	bundlePath := envMap["SSL_CERT_FILE"]
+	if bundlePath == "" || bundlePath == sciaCAPath {
+		return
+	}
+
+	sciaCA, err := os.ReadFile(sciaCAPath)
+	if err != nil {
+		log.Printf("[PROVISIONER] Warning: failed to read scia CA %s: %v", sciaCAPath, err)
+		return
+	}
+
+	bundle := appendPEM(readSystemCABundle(), sciaCA)
 	if err := os.WriteFile(bundlePath, bundle, 0o600); err != nil {
 		log.Printf("[PROVISIONER] Warning: failed to write scia CA bundle %s: %v", bundlePath, err)
 		return
@@ -1430,7 +1579,7 @@ type acpMessagesResponse struct {
 // started, replicating the logic of initialMessageSenderScript.
 func sendInitialMessage(ctx context.Context, agentapiURL, message, agentType string, waitSec int) {
 	// ACP sessions use a different transport (JSON-RPC 2.0 over POST /rpc).
-	if agentType == "claude-acp" || agentType == "codex-acp" || agentType == "pi-ollama" || agentType == "cursor" {
+	if agentadapter.IsACP(agentType) {
 		sendACPInitialMessage(ctx, agentapiURL, message, waitSec)
 		return
 	}
@@ -53,6 +53,62 @@ func TestBuildAgentCommandCursor(t *testing.T) {
 	}
 }
 
+func TestBuildAgentCommandGoose(t *testing.T) {
+	t.Setenv("AGENTAPI_PORT", "9000")
+	t.Setenv("CLAUDE_ARGS", "--dangerously-skip-permissions")
+
+	cmd, args := (&Server{resumeConversation: true}).buildAgentCommand(&sessionsettings.SessionSettings{
+		Session: sessionsettings.SessionMeta{AgentType: "goose"},
+	}, nil)
+
+	if cmd != "agentapi" {
+		t.Fatalf("command = %q, want agentapi", cmd)
+	}
+	// CLAUDE_ARGS and -c only apply to Claude Code.
+	want := []string{"server", "--type", "goose", "--allowed-hosts", "*", "--allowed-origins", "*", "--port", "9000", "--", "sh", "-c", "goose"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %#v, want %#v", args, want)
+	}
+}
+
+func TestAgentInstructionsPath(t *testing.T) {
+	if got, want := agentInstructionsPath("codex"), filepath.Join(runtimeHome, ".codex", "AGENTS.md"); got != want {
+		t.Errorf("codex = %q, want %q", got, want)
+	}
+	// The managed CLAUDE.md and the Pi AGENTS.md are written anyway.
+	for _, agentType := range []string{"", "claude-acp", "pi-ollama", "cursor"} {
+		if got := agentInstructionsPath(agentType); got != "" {
+			t.Errorf("%q = %q, want none", agentType, got)
+		}
+	}
+}
+
+func TestBuildAgentCommandStartupOverride(t *testing.T) {
+	t.Setenv("AGENTAPI_PORT", "9000")
+
+	settings := &sessionsettings.SessionSettings{
+		Session: sessionsettings.SessionMeta{AgentType: "codex-acp"},
+		Startup: sessionsettings.StartupConfig{
+			Command: []string{"agentapi-proxy", "acp-server"},
+			Args:    []string{"--port", "9000", "--", "my-codex"},
+		},
+	}
+	// Without override the built-in command is used
+	if _, args := (&Server{}).buildAgentCommand(settings, nil); args[len(args)-1] != "@agentclientprotocol/codex-acp" {
+		t.Fatalf("args = %#v, want the built-in codex-acp command", args)
+	}
+
+	settings.Startup.Override = true
+	cmd, args := (&Server{}).buildAgentCommand(settings, nil)
+	if cmd != "agentapi-proxy" {
+		t.Fatalf("command = %q, want agentapi-proxy", cmd)
+	}
+	want := []string{"acp-server", "--port", "9000", "--", "my-codex"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %#v, want %#v", args, want)
+	}
+}
+
 func TestBuildAgentCommandPiOllama(t *testing.T) {
 	t.Setenv("AGENTAPI_PORT", "9000")
 	origCommandPath := piOllamaCommandPath
@@ -175,7 +231,7 @@ func TestSymlinkCodexSkillsForPiPreservesNonEmptyDir(t *testing.T) {
 	}
 }
 
-func TestWritePiAgentInstructionsCopiesClaudeMD(t *testing.T) {
+func TestWriteAgentInstructionsCopiesClaudeMD(t *testing.T) {
 	dir := t.TempDir()
 	sourcePath := filepath.Join(dir, ".claude", "CLAUDE.md")
 	destPath := filepath.Join(dir, ".pi", "agent", "AGENTS.md")
@@ -187,8 +243,8 @@ func TestWritePiAgentInstructionsCopiesClaudeMD(t *testing.T) {
 		t.Fatalf("write source: %v", err)
 	}
 
-	if err := writePiAgentInstructions(sourcePath, destPath); err != nil {
-		t.Fatalf("writePiAgentInstructions: %v", err)
+	if err := writeAgentInstructions(sourcePath, destPath); err != nil {
+		t.Fatalf("writeAgentInstructions: %v", err)
 	}
 
 	got, err := os.ReadFile(destPath)
@@ -200,11 +256,11 @@ func TestWritePiAgentInstructionsCopiesClaudeMD(t *testing.T) {
 	}
 }
 
-func TestWritePiAgentInstructionsSkipsMissingSource(t *testing.T) {
+func TestWriteAgentInstructionsSkipsMissingSource(t *testing.T) {
 	destPath := filepath.Join(t.TempDir(), ".pi", "agent", "AGENTS.md")
 
-	if err := writePiAgentInstructions("/does/not/exist/CLAUDE.md", destPath); err != nil {
-		t.Fatalf("writePiAgentInstructions should skip missing source: %v", err)
+	if err := writeAgentInstructions("/does/not/exist/CLAUDE.md", destPath); err != nil {
+		t.Fatalf("writeAgentInstructions should skip missing source: %v", err)
 	}
 	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
 		t.Fatalf("Pi AGENTS.md should not be created, stat err=%v", err)
@@ -531,3 +587,113 @@ func TestWriteCodexRequirementsToPath(t *testing.T) {
 		t.Fatalf("expected %q, got %q", content, string(got))
 	}
 }
+
+func TestPrepareExtraCABundle_ExtendsSciaBundle(t *testing.T) {
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "scia-ca-bundle.pem")
+	extraPath := filepath.Join(dir, "corp.pem")
+	if err := os.WriteFile(bundlePath, []byte("SYSTEM\nSCIA"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(extraPath, []byte("CORP\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	envMap := map[string]string{
+		"AGENTAPI_EXTRA_CA_FILE":  extraPath,
+		"AGENTAPI_SCIA_PROXY_URL": "http://127.0.0.1:18081",
+		"SSL_CERT_FILE":           bundlePath,
+		"NODE_EXTRA_CA_CERTS":     filepath.Join(dir, "scia-ca.pem"),
+	}
+	prepareExtraCABundle(envMap)
+
+	got, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "SYSTEM\nSCIA\nCORP\n" {
+		t.Errorf("bundle = %q", got)
+	}
+	if envMap["NODE_EXTRA_CA_CERTS"] != bundlePath {
+		t.Errorf("NODE_EXTRA_CA_CERTS = %q, want %q", envMap["NODE_EXTRA_CA_CERTS"], bundlePath)
+	}
+}
+
+func TestPrepareExtraCABundle_SkipsForeignBundle(t *testing.T) {
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "custom.pem")
+	envMap := map[string]string{
+		"AGENTAPI_EXTRA_CA_FILE": filepath.Join(dir, "corp.pem"),
+		"SSL_CERT_FILE":          bundlePath,
+	}
+	prepareExtraCABundle(envMap)
+	if _, err := os.Stat(bundlePath); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be left alone, stat err = %v", bundlePath, err)
+	}
+}
+
+func TestWriteDockerAuthConfigMergesSecretAndInlineCredentials(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".docker", "config.json")
+	secretPath := filepath.Join(dir, "secret", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"credsStore":"","auths":{"old.example.com":{"auth":"b2xk"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(secretPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secretPath, []byte(`{"auths":{"ghcr.io":{"auth":"c2VjcmV0"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeDockerAuthConfig(configPath, secretPath, &sessionsettings.DockerConfig{
+		Enabled: true,
+		Mode:    sessionsettings.DockerModeBuildKit,
+		Registries: []sessionsettings.RegistryConfig{
+			{Server: "registry.example.com", Username: "bot", Password: "token"},
+			{Server: "ghcr.io", SecretName: "ghcr-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("writeDockerAuthConfig() error = %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		CredsStore *string                      `json:"credsStore"`
+		Auths      map[string]map[string]string `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CredsStore == nil {
+		t.Error("expected existing settings to be preserved")
+	}
+	want := map[string]string{
+		"old.example.com":      "b2xk",
+		"ghcr.io":              "c2VjcmV0",
+		"registry.example.com": "Ym90OnRva2Vu",
+	}
+	for server, auth := range want {
+		if got.Auths[server]["auth"] != auth {
+			t.Errorf("auths[%q] = %v, want %q", server, got.Auths[server], auth)
+		}
+	}
+}
+
+func TestWriteDockerAuthConfigSkipsWithoutCredentials(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ".docker", "config.json")
+	err := writeDockerAuthConfig(configPath, filepath.Join(t.TempDir(), "missing.json"), &sessionsettings.DockerConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("writeDockerAuthConfig() error = %v", err)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("expected no docker config to be written, stat error = %v", err)
+	}
+}
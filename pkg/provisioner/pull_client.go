@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 type PullClientConfig struct {
@@ -22,9 +23,12 @@ type PullClientConfig struct {
 	Token             string
 	UpstreamAuthToken string
 	SessionID         string
-	PodName           string
-	Namespace         string
-	CAFile            string
+	// SessionToken proves to the proxy that requests about SessionID come
+	// from the Pod of that session, unlike Token, which all Pods share.
+	SessionToken string
+	PodName      string
+	Namespace    string
+	CAFile       string
 }
 
 type pullProvisionRequest struct {
@@ -82,8 +86,16 @@ func RunPullClient(ctx context.Context, srv *Server, cfg PullClientConfig) error
 				}
 			}()
 		})
+		srv.SetExtensionsReporter(func(report *startup.ExtensionReport) {
+			go func() {
+				if err := reportExtensions(context.Background(), client, cfg, report); err != nil {
+					log.Printf("[PROVISIONER] Failed to report extensions: %v", err)
+				}
+			}()
+		})
 		srv.setStatus(StatusProvisioning, "")
 		srv.runProvision(ctx, provisionReq.Settings)
+		go runGitHubTokenRefresher(ctx, client, cfg, provisionReq.Settings)
 		<-ctx.Done()
 		return ctx.Err()
 	}
@@ -160,6 +172,10 @@ func reportProvisionRequestStatus(ctx context.Context, client *http.Client, cfg
 	})
 }
 
+func reportExtensions(ctx context.Context, client *http.Client, cfg PullClientConfig, report *startup.ExtensionReport) error {
+	return postJSON(ctx, client, cfg, "/internal/session-provisioners/"+url.PathEscape(cfg.SessionID)+"/extensions", report)
+}
+
 func postJSON(ctx context.Context, client *http.Client, cfg PullClientConfig, path string, body interface{}) error {
 	data, err := json.Marshal(body)
 	if err != nil {
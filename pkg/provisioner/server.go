@@ -12,11 +12,13 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
 )
 
 // defaultStartupScript is run on every Pod start regardless of agent type.
@@ -98,6 +100,19 @@ type Server struct {
 	phaseTime time.Time
 	serverCtx context.Context // long-lived context for provisioning goroutines
 	reporter  func(Status, string)
+
+	extensionsReporter func(*startup.ExtensionReport)
+
+	doneOnce     sync.Once
+	completeOnce sync.Once
+	done         chan struct{} // closed by POST /complete
+
+	provisioned *sessionsettings.SessionSettings // settings of the last provisioning, for POST /setup
+	setupMu     sync.Mutex                       // serializes setup re-runs
+
+	resumeConversation bool // a checkpointed conversation was restored; start claude with -c
+
+	workspaceToken string // bearer token required for /workspace uploads from outside the Pod
 }
 
 // New creates a new Server.
@@ -152,6 +167,11 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/status", s.handleStatus)
 	mux.HandleFunc("/sandbox-domains", s.handleSandboxDomains)
 	mux.HandleFunc("/sandbox-policy", s.handleSandboxPolicy)
+	mux.HandleFunc("/complete", s.handleComplete)
+	mux.HandleFunc("/checkpoint", s.handleCheckpoint)
+	mux.HandleFunc("/setup", s.handleSetup)
+	mux.Handle("/workspace/", s.workspaceHandler())
+	mux.Handle("/changes", s.changesHandler())
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
@@ -291,6 +311,12 @@ func (s *Server) handleSandboxPolicy(w http.ResponseWriter, r *http.Request) {
 	_, _ = io.Copy(w, resp.Body)
 }
 
+// workspaceRoot is the directory exposed under /workspace: the
+// parent of the repository clone, so sibling checkouts are visible too.
+func workspaceRoot() string {
+	return envPath("AGENTAPI_WORKDIR", filepath.Dir(workdirRepoPath))
+}
+
 func (s *Server) client() *http.Client {
 	if s.httpClient != nil {
 		return s.httpClient
@@ -0,0 +1,109 @@
+package provisioner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+const (
+	// defaultSetupHookTimeout applies when the proxy sent no timeout.
+	defaultSetupHookTimeout = 10 * time.Minute
+	// setupHookTailBytes is how much of a failed hook's output is reported in
+	// the provision status message.
+	setupHookTailBytes = 2048
+)
+
+// setupHookLogDir keeps the full output of every setup hook so that it can be
+// inspected from the session terminal after a failure.
+var setupHookLogDir = filepath.Join(runtimeHome, ".session", "setup-hooks")
+
+var unsafeLogNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// runSetupHooks runs the setup hooks in order in the current directory (the
+// cloned repository). A failing hook with on_failure "continue" is logged and
+// skipped; any other failure stops the sequence and is returned.
+func (s *Server) runSetupHooks(ctx context.Context, hooks []sessionsettings.SetupHook, envMap map[string]string) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(setupHookLogDir, 0o755); err != nil {
+		log.Printf("[PROVISIONER] Warning: failed to create setup hook log directory: %v", err)
+	}
+	for i, hook := range hooks {
+		s.setPhase("provision:setup-hook:" + hook.Name)
+		logPath := filepath.Join(setupHookLogDir, fmt.Sprintf("%02d-%s.log", i+1, unsafeLogNameChars.ReplaceAllString(hook.Name, "-")))
+		log.Printf("[PROVISIONER] Running setup hook %q (log: %s)", hook.Name, logPath)
+		start := time.Now()
+		tail, err := runSetupHook(ctx, hook, envMap, logPath)
+		if err == nil {
+			log.Printf("[PROVISIONER] Setup hook %q complete in %s", hook.Name, time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		if hook.OnFailure == "continue" {
+			log.Printf("[PROVISIONER] Warning: setup hook %q failed (continuing): %v", hook.Name, err)
+			continue
+		}
+		return fmt.Errorf("setup hook %q failed: %v; output: %s", hook.Name, err, tail)
+	}
+	return nil
+}
+
+// runSetupHook runs one hook, writing its output to the provisioner log and
+// to logPath, and returns the end of the output.
+func runSetupHook(ctx context.Context, hook sessionsettings.SetupHook, envMap map[string]string, logPath string) (string, error) {
+	timeout := defaultSetupHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tail := &tailBuffer{limit: setupHookTailBytes}
+	out := io.MultiWriter(os.Stdout, tail)
+	if f, err := os.Create(logPath); err != nil {
+		log.Printf("[PROVISIONER] Warning: failed to create setup hook log %s: %v", logPath, err)
+	} else {
+		defer func() { _ = f.Close() }()
+		out = io.MultiWriter(os.Stdout, tail, f)
+	}
+
+	cmd := exec.CommandContext(hookCtx, "sh", "-c", hook.Command)
+	cmd.Env = mergeEnv(os.Environ(), envMap)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Do not wait forever for grandchildren that keep the output pipes open
+	// after the shell has been killed.
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+	if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return strings.TrimSpace(tail.String()), err
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string { return string(b.buf) }
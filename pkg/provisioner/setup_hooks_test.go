@@ -0,0 +1,60 @@
+package provisioner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+func TestRunSetupHooks(t *testing.T) {
+	orig := setupHookLogDir
+	setupHookLogDir = t.TempDir()
+	defer func() { setupHookLogDir = orig }()
+	marker := filepath.Join(t.TempDir(), "ran")
+
+	s := &Server{}
+	err := s.runSetupHooks(context.Background(), []sessionsettings.SetupHook{
+		{Name: "optional", Command: "echo optional; exit 3", OnFailure: "continue"},
+		{Name: "bootstrap", Command: `echo "$GREETING" > ` + marker},
+	}, map[string]string{"GREETING": "hello"})
+	if err != nil {
+		t.Fatalf("runSetupHooks() error = %v", err)
+	}
+	if got, _ := os.ReadFile(marker); strings.TrimSpace(string(got)) != "hello" {
+		t.Errorf("bootstrap hook output = %q, want hello", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(setupHookLogDir, "01-optional.log")); strings.TrimSpace(string(got)) != "optional" {
+		t.Errorf("optional hook log = %q", got)
+	}
+
+	err = s.runSetupHooks(context.Background(), []sessionsettings.SetupHook{
+		{Name: "broken", Command: "echo npm ERR! missing lockfile; exit 1", OnFailure: "fail"},
+		{Name: "never", Command: "touch " + marker + ".never"},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) || !strings.Contains(err.Error(), "missing lockfile") {
+		t.Fatalf("runSetupHooks() error = %v, want failure of broken with its output", err)
+	}
+	if _, statErr := os.Stat(marker + ".never"); statErr == nil {
+		t.Error("hooks after a failing hook must not run")
+	}
+}
+
+func TestRunSetupHookTimeout(t *testing.T) {
+	_, err := runSetupHook(context.Background(), sessionsettings.SetupHook{Name: "slow", Command: "exec sleep 30", TimeoutSeconds: 1}, nil, filepath.Join(t.TempDir(), "slow.log"))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("runSetupHook() error = %v, want timeout", err)
+	}
+}
+
+func TestTailBufferKeepsEnd(t *testing.T) {
+	b := &tailBuffer{limit: 4}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("defg"))
+	if b.String() != "defg" {
+		t.Errorf("tailBuffer = %q, want defg", b.String())
+	}
+}
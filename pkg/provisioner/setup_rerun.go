@@ -0,0 +1,105 @@
+package provisioner
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+// SetupResponse is the JSON body returned by POST /setup.
+type SetupResponse struct {
+	Phases []string `json:"phases"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func (s *Server) setProvisionedSettings(settings *sessionsettings.SessionSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provisioned = settings
+}
+
+// handleSetup re-runs phases of the session setup with the settings of the
+// last provisioning, e.g. POST /setup?phase=clone after a transient clone
+// failure. Without phase parameters every phase runs. Like /complete it only
+// accepts requests from inside the Pod.
+func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	phases := r.URL.Query()["phase"]
+	if err := sessionsettings.ValidateSetupPhases(phases); err != nil {
+		writeSetupResponse(w, http.StatusBadRequest, SetupResponse{Phases: phases, Error: err.Error()})
+		return
+	}
+
+	s.mu.RLock()
+	settings, status := s.provisioned, s.status
+	s.mu.RUnlock()
+	switch {
+	case settings == nil:
+		writeSetupResponse(w, http.StatusConflict, SetupResponse{Phases: phases, Error: "session has not been provisioned"})
+		return
+	case status == StatusProvisioning:
+		writeSetupResponse(w, http.StatusConflict, SetupResponse{Phases: phases, Error: "provisioning is in progress"})
+		return
+	}
+
+	if !s.setupMu.TryLock() {
+		writeSetupResponse(w, http.StatusConflict, SetupResponse{Phases: phases, Error: "a setup re-run is in progress"})
+		return
+	}
+	defer s.setupMu.Unlock()
+
+	log.Printf("[PROVISIONER] Re-running setup phases %v", phases)
+	if err := runSetupPhases(settings, phases); err != nil {
+		log.Printf("[PROVISIONER] Setup re-run failed: %v", err)
+		writeSetupResponse(w, http.StatusInternalServerError, SetupResponse{Phases: phases, Error: err.Error()})
+		return
+	}
+	if len(phases) == 0 || slices.Contains(phases, sessionsettings.SetupPhaseMarketplaces) {
+		s.publishExtensionReport()
+	}
+	writeSetupResponse(w, http.StatusOK, SetupResponse{Phases: phases})
+}
+
+// runSetupPhases writes settings to a temporary file and runs the given
+// setup phases from it.
+func runSetupPhases(settings *sessionsettings.SessionSettings, phases []string) error {
+	data, err := sessionsettings.MarshalYAML(settings)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "agentapi-setup-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	opts := provisionSetupOptions(f.Name())
+	opts.Phases = phases
+	return sessionsettings.Setup(opts)
+}
+
+func writeSetupResponse(w http.ResponseWriter, code int, resp SetupResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,47 @@
+package provisioner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionsettings"
+)
+
+func TestHandleSetupRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		remoteAddr  string
+		provisioned bool
+		status      Status
+		want        int
+	}{
+		{name: "remote", target: "/setup", remoteAddr: "10.0.0.5:40000", provisioned: true, want: http.StatusForbidden},
+		{name: "unknown phase", target: "/setup?phase=nope", remoteAddr: "127.0.0.1:40000", provisioned: true, want: http.StatusBadRequest},
+		{name: "not provisioned", target: "/setup?phase=clone", remoteAddr: "127.0.0.1:40000", want: http.StatusConflict},
+		{name: "provisioning", target: "/setup?phase=clone", remoteAddr: "127.0.0.1:40000", provisioned: true, status: StatusProvisioning, want: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{status: tt.status}
+			if tt.provisioned {
+				server.setProvisionedSettings(&sessionsettings.SessionSettings{})
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			req.RemoteAddr = tt.remoteAddr
+			resp := httptest.NewRecorder()
+			server.handleSetup(resp, req)
+			if resp.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", resp.Code, tt.want, resp.Body.String())
+			}
+			if tt.want != http.StatusForbidden {
+				var body SetupResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+					t.Errorf("body = %+v (%v), want an error message", body, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package provisioner
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/gitdiff"
+	"github.com/takutakahashi/agentapi-proxy/pkg/workspacefs"
+)
+
+// SetWorkspaceToken sets the provisioner token the proxy presents as a bearer
+// token to read and upload files through /workspace and /changes.
+func (s *Server) SetWorkspaceToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workspaceToken = token
+}
+
+// workspaceHandler serves the workspace file API under /workspace.
+func (s *Server) workspaceHandler() http.Handler {
+	return s.requireWorkspaceToken(http.StripPrefix("/workspace", workspacefs.NewHandler(workspacefs.FS{Root: workspaceRoot()})))
+}
+
+// changesHandler serves the git diff of the workdir under /changes.
+func (s *Server) changesHandler() http.Handler {
+	return s.requireWorkspaceToken(gitdiff.NewHandler(gitdiff.Repo{Dir: workdirRepoPath}))
+}
+
+// requireWorkspaceToken rejects requests that do not carry the provisioner
+// token. The workspace and its diff are reachable from the whole Pod network,
+// so reads are as sensitive as uploads.
+func (s *Server) requireWorkspaceToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.workspaceAuthorized(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) workspaceAuthorized(r *http.Request) bool {
+	s.mu.RLock()
+	token := s.workspaceToken
+	s.mu.RUnlock()
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
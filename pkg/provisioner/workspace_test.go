@@ -0,0 +1,80 @@
+package provisioner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceHandlerAuth(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("AGENTAPI_WORKDIR", root)
+
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		auth       string
+		want       int
+	}{
+		{name: "read without token", method: http.MethodGet, remoteAddr: "10.0.0.5:40000", want: http.StatusForbidden},
+		{name: "read with token", method: http.MethodGet, remoteAddr: "10.0.0.5:40000", auth: "Bearer secret", want: http.StatusOK},
+		{name: "upload without token", method: http.MethodPut, remoteAddr: "10.0.0.5:40000", want: http.StatusForbidden},
+		{name: "upload with wrong token", method: http.MethodPut, remoteAddr: "10.0.0.5:40000", auth: "Bearer nope", want: http.StatusForbidden},
+		{name: "upload with token", method: http.MethodPut, remoteAddr: "10.0.0.5:40000", auth: "Bearer secret", want: http.StatusCreated},
+		{name: "local upload without token", method: http.MethodPut, remoteAddr: "127.0.0.1:40000", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			server := &Server{}
+			server.SetWorkspaceToken("secret")
+			req := httptest.NewRequest(tt.method, "/workspace/raw?path=a.txt", strings.NewReader("new"))
+			req.RemoteAddr = tt.remoteAddr
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp := httptest.NewRecorder()
+			server.workspaceHandler().ServeHTTP(resp, req)
+			if resp.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", resp.Code, tt.want, resp.Body.String())
+			}
+			data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantData := "old"
+			if tt.want == http.StatusCreated {
+				wantData = "new"
+			}
+			if string(data) != wantData {
+				t.Errorf("file = %q, want %q", data, wantData)
+			}
+		})
+	}
+}
+
+func TestChangesHandlerAuth(t *testing.T) {
+	server := &Server{}
+	server.SetWorkspaceToken("secret")
+
+	resp := httptest.NewRecorder()
+	server.changesHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/changes", nil))
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("status without token = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+
+	server.SetWorkspaceToken("")
+	req := httptest.NewRequest(http.MethodGet, "/changes", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	resp = httptest.NewRecorder()
+	server.changesHandler().ServeHTTP(resp, req)
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("status without a configured token = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,173 @@
+// Package proxy provides the reverse proxy used to reach agentapi backends.
+// Regular responses are flushed periodically, Server-Sent Events are streamed
+// without buffering and WebSocket upgrades are passed through. Streaming
+// connections are closed after a configurable period without traffic.
+// Idempotent requests are retried while a backend is unreachable, and a
+// per-session circuit breaker stops traffic to backends that keep failing.
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultFlushInterval is used for regular responses when
+// Options.FlushInterval is zero.
+const DefaultFlushInterval = 100 * time.Millisecond
+
+// Options configures a Proxy.
+type Options struct {
+	// FlushInterval is the flush interval for regular responses. Event
+	// streams are always flushed after every write.
+	FlushInterval time.Duration
+	// SSEIdleTimeout closes an event stream when the backend sent nothing for
+	// this long. Zero disables the timeout.
+	SSEIdleTimeout time.Duration
+	// WebSocketIdleTimeout closes an upgraded connection when no data flowed
+	// in either direction for this long. Zero disables the timeout.
+	WebSocketIdleTimeout time.Duration
+	// Retry retries idempotent requests whose backend could not be reached.
+	Retry RetryOptions
+	// CircuitBreaker stops sending requests to a session whose backend keeps failing.
+	CircuitBreaker CircuitBreakerOptions
+}
+
+// Proxy builds reverse proxies that share one set of transports.
+type Proxy struct {
+	flushInterval time.Duration
+	transport     http.RoundTripper
+	breaker       *Breaker
+}
+
+// New creates a Proxy.
+func New(opts Options) *Proxy {
+	flushInterval := opts.FlushInterval
+	if flushInterval == 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	return &Proxy{
+		flushInterval: flushInterval,
+		transport: newRetryTransport(&streamTransport{
+			base: http.DefaultTransport,
+			sse:  newIdleTimeoutTransport(opts.SSEIdleTimeout),
+			ws:   newIdleTimeoutTransport(opts.WebSocketIdleTimeout),
+		}, opts.Retry),
+		breaker: NewBreaker(opts.CircuitBreaker),
+	}
+}
+
+// Breaker returns the per-session circuit breaker; it is nil when disabled,
+// and a nil Breaker allows every request.
+func (p *Proxy) Breaker() *Breaker {
+	return p.breaker
+}
+
+// ReverseProxy returns a reverse proxy to target. Callers may wrap Director,
+// ModifyResponse and ErrorHandler; ModifyResponse must be chained so that
+// event stream headers keep being set.
+func (p *Proxy) ReverseProxy(target *url.URL) *httputil.ReverseProxy {
+	rp := httputil.NewSingleHostReverseProxy(target)
+	rp.Transport = p.transport
+	rp.FlushInterval = p.flushInterval
+	rp.ModifyResponse = func(resp *http.Response) error {
+		if IsEventStreamResponse(resp) {
+			resp.Header.Set("Cache-Control", "no-cache")
+			// Ask buffering ingresses such as nginx to pass events through.
+			resp.Header.Set("X-Accel-Buffering", "no")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+		}
+		return nil
+	}
+	return rp
+}
+
+// IsWebSocketRequest reports whether r asks for a WebSocket upgrade.
+func IsWebSocketRequest(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// IsEventStreamRequest reports whether r accepts a Server-Sent Events stream.
+func IsEventStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// IsEventStreamResponse reports whether resp is a Server-Sent Events stream.
+func IsEventStreamResponse(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamTransport sends streaming requests over connections with an idle
+// timeout and everything else over the shared keep-alive transport.
+type streamTransport struct {
+	base, sse, ws http.RoundTripper
+}
+
+func (t *streamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case IsWebSocketRequest(req):
+		return t.ws.RoundTrip(req)
+	case IsEventStreamRequest(req):
+		return t.sse.RoundTrip(req)
+	default:
+		return t.base.RoundTrip(req)
+	}
+}
+
+// newIdleTimeoutTransport returns a transport whose connections fail once they
+// have been idle for timeout. Connections are not reused because a pooled
+// connection would carry the deadline of its previous request.
+func newIdleTimeoutTransport(timeout time.Duration) http.RoundTripper {
+	if timeout <= 0 {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &idleTimeoutConn{Conn: conn, timeout: timeout}, nil
+	}
+	return transport
+}
+
+// idleTimeoutConn pushes its deadline forward on every read and write, so a
+// blocked read fails only after no data flowed in either direction.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	return n, err
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFrontend(t *testing.T, backend *httptest.Server, opts Options) *httptest.Server {
+	t.Helper()
+	target, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontend := httptest.NewServer(New(opts).ReverseProxy(target))
+	t.Cleanup(frontend.Close)
+	return frontend
+}
+
+func TestEventStreamIsNotBuffered(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	// A long flush interval must not delay events.
+	frontend := newFrontend(t, backend, Options{FlushInterval: time.Hour})
+	req, _ := http.NewRequest(http.MethodGet, frontend.URL+"/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if got := resp.Header.Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("X-Accel-Buffering = %q, want no", got)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "data: first\n" {
+		t.Errorf("unexpected first line %q", line)
+	}
+}
+
+func TestEventStreamIdleTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: only\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+
+	frontend := newFrontend(t, backend, Options{SSEIdleTimeout: 200 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, frontend.URL+"/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	done := make(chan string, 1)
+	go func() {
+		body, _ := io.ReadAll(resp.Body)
+		done <- string(body)
+	}()
+	select {
+	case body := <-done:
+		if !strings.Contains(body, "data: only") {
+			t.Errorf("expected event before the stream closed, got %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle event stream was not closed")
+	}
+}
+
+// echoUpgradeHandler accepts any upgrade and echoes raw bytes back.
+func echoUpgradeHandler(w http.ResponseWriter, r *http.Request) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	_, _ = io.Copy(conn, buf)
+}
+
+func dialUpgrade(t *testing.T, frontend *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(frontend.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_, _ = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	return conn, reader
+}
+
+func TestWebSocketPassThrough(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(echoUpgradeHandler))
+	defer backend.Close()
+
+	conn, reader := dialUpgrade(t, newFrontend(t, backend, Options{WebSocketIdleTimeout: time.Minute}))
+	_, _ = io.WriteString(conn, "ping\n")
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ping\n" {
+		t.Errorf("echo = %q, want ping", line)
+	}
+}
+
+func TestWebSocketIdleTimeout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(echoUpgradeHandler))
+	defer backend.Close()
+
+	conn, reader := dialUpgrade(t, newFrontend(t, backend, Options{WebSocketIdleTimeout: 200 * time.Millisecond}))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("expected the idle connection to be closed, got %v", err)
+	}
+}
+
+func TestIsWebSocketRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+	if !IsWebSocketRequest(req) {
+		t.Error("expected WebSocket request")
+	}
+	req.Header.Set("Upgrade", "h2c")
+	if IsWebSocketRequest(req) {
+		t.Error("h2c upgrade must not be treated as WebSocket")
+	}
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryOptions configures retries of idempotent requests whose backend could
+// not be reached, e.g. while a session Pod is restarting.
+type RetryOptions struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry; it doubles on each retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries.
+	MaxBackoff time.Duration
+}
+
+// CircuitBreakerOptions configures the per-session circuit breaker.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests that opens
+	// the circuit. Zero disables the breaker.
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before a probe request
+	// is let through.
+	OpenDuration time.Duration
+}
+
+// isIdempotent reports whether a request with this method may be sent twice.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryTransport retries idempotent requests that failed before a response
+// was received. Requests with a body are only retried when it can be replayed.
+type retryTransport struct {
+	next  http.RoundTripper
+	opts  RetryOptions
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryTransport(next http.RoundTripper, opts RetryOptions) http.RoundTripper {
+	if opts.MaxRetries <= 0 {
+		return next
+	}
+	return &retryTransport{next: next, opts: opts, sleep: sleepContext}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if !isIdempotent(req.Method) || IsWebSocketRequest(req) || (hasBody && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+
+	backoff := t.opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil || attempt >= t.opts.MaxRetries || req.Context().Err() != nil ||
+			errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return resp, err
+		}
+
+		log.Printf("[PROXY] Retrying %s %s in %v (retry %d/%d): %v",
+			req.Method, req.URL.Path, backoff, attempt+1, t.opts.MaxRetries, err)
+		if sleepErr := t.sleep(req.Context(), backoff); sleepErr != nil {
+			return nil, err
+		}
+
+		if hasBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		backoff *= 2
+		if t.opts.MaxBackoff > 0 && backoff > t.opts.MaxBackoff {
+			backoff = t.opts.MaxBackoff
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// circuit is the breaker state of one session.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	// probeAt is when the half-open probe was let through; zero when none is in flight.
+	probeAt time.Time
+}
+
+// Breaker tracks backend failures per key (session ID) and rejects requests
+// while a backend is considered down. A nil or disabled Breaker allows everything.
+type Breaker struct {
+	opts CircuitBreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewBreaker creates a Breaker. It returns nil when opts disable the breaker.
+func NewBreaker(opts CircuitBreakerOptions) *Breaker {
+	if opts.FailureThreshold <= 0 || opts.OpenDuration <= 0 {
+		return nil
+	}
+	return &Breaker{opts: opts, now: time.Now, circuits: make(map[string]*circuit)}
+}
+
+// Allow reports whether a request for key may be sent. When it may not,
+// retryAfter is the time until the next probe is let through.
+func (b *Breaker) Allow(key string) (retryAfter time.Duration, ok bool) {
+	if b == nil {
+		return 0, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[key]
+	if c == nil || c.failures < b.opts.FailureThreshold {
+		return 0, true
+	}
+
+	now := b.now()
+	if now.Before(c.openUntil) {
+		return c.openUntil.Sub(now), false
+	}
+	// Half-open: let a single probe through. A probe that never reports back
+	// (e.g. the client went away) is replaced after another OpenDuration.
+	if !c.probeAt.IsZero() && now.Sub(c.probeAt) < b.opts.OpenDuration {
+		return b.opts.OpenDuration - now.Sub(c.probeAt), false
+	}
+	c.probeAt = now
+	return 0, true
+}
+
+// Success records that the backend for key answered, closing its circuit.
+func (b *Breaker) Success(key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, key)
+}
+
+// Failure records that the backend for key could not be reached.
+func (b *Breaker) Failure(key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[key]
+	if c == nil {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	c.failures++
+	c.probeAt = time.Time{}
+	if c.failures >= b.opts.FailureThreshold {
+		c.openUntil = b.now().Add(b.opts.OpenDuration)
+		if c.failures == b.opts.FailureThreshold {
+			log.Printf("[PROXY] Circuit opened for %s after %d consecutive failures", key, c.failures)
+		}
+	}
+}
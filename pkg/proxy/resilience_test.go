@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flakyTransport struct {
+	failures int
+	calls    int
+	bodies   []string
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.calls++
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		f.bodies = append(f.bodies, string(b))
+	}
+	if f.calls <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func newTestRetryTransport(next http.RoundTripper, maxRetries int) (*retryTransport, *[]time.Duration) {
+	var waits []time.Duration
+	return &retryTransport{
+		next: next,
+		opts: RetryOptions{MaxRetries: maxRetries, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond},
+		sleep: func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		},
+	}, &waits
+}
+
+func TestRetryTransport_RetriesIdempotentRequests(t *testing.T) {
+	flaky := &flakyTransport{failures: 2}
+	rt, waits := newTestRetryTransport(flaky, 3)
+
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://backend/status", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || flaky.calls != 3 {
+		t.Errorf("status = %d, calls = %d, want 200 after 3 calls", resp.StatusCode, flaky.calls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}
+	if len(*waits) != len(want) || (*waits)[0] != want[0] || (*waits)[1] != want[1] {
+		t.Errorf("backoffs = %v, want %v", *waits, want)
+	}
+}
+
+func TestRetryTransport_GivesUpAfterMaxRetries(t *testing.T) {
+	flaky := &flakyTransport{failures: 10}
+	rt, _ := newTestRetryTransport(flaky, 2)
+
+	if _, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://backend/status", nil)); err == nil {
+		t.Fatal("expected error after retries are exhausted")
+	}
+	if flaky.calls != 3 {
+		t.Errorf("calls = %d, want 3", flaky.calls)
+	}
+}
+
+func TestRetryTransport_DoesNotRetryUnsafeRequests(t *testing.T) {
+	flaky := &flakyTransport{failures: 1}
+	rt, _ := newTestRetryTransport(flaky, 3)
+
+	req := httptest.NewRequest(http.MethodPost, "http://backend/message", strings.NewReader("{}"))
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected POST failure to be returned")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("calls = %d, want 1", flaky.calls)
+	}
+}
+
+func TestRetryTransport_ReplaysBody(t *testing.T) {
+	flaky := &flakyTransport{failures: 1}
+	rt, _ := newTestRetryTransport(flaky, 1)
+
+	req, _ := http.NewRequest(http.MethodPut, "http://backend/config", strings.NewReader("payload"))
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if len(flaky.bodies) != 2 || flaky.bodies[1] != "payload" {
+		t.Errorf("bodies = %q, want payload replayed", flaky.bodies)
+	}
+}
+
+func TestRetryTransport_StopsWhenContextCanceled(t *testing.T) {
+	flaky := &flakyTransport{failures: 10}
+	rt, _ := newTestRetryTransport(flaky, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://backend/status", nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected error")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("calls = %d, want 1", flaky.calls)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(CircuitBreakerOptions{FailureThreshold: 2, OpenDuration: 10 * time.Second})
+	b.now = func() time.Time { return now }
+
+	b.Failure("s1")
+	if _, ok := b.Allow("s1"); !ok {
+		t.Fatal("circuit should stay closed below the threshold")
+	}
+	b.Failure("s1")
+	retryAfter, ok := b.Allow("s1")
+	if ok || retryAfter != 10*time.Second {
+		t.Fatalf("Allow() = %v, %v; want open for 10s", retryAfter, ok)
+	}
+	if _, ok := b.Allow("s2"); !ok {
+		t.Fatal("other sessions must not be affected")
+	}
+
+	// After OpenDuration a single probe is let through
+	now = now.Add(10 * time.Second)
+	if _, ok := b.Allow("s1"); !ok {
+		t.Fatal("expected probe to be allowed")
+	}
+	if _, ok := b.Allow("s1"); ok {
+		t.Fatal("only one probe may be in flight")
+	}
+
+	// A failed probe reopens the circuit
+	b.Failure("s1")
+	if _, ok := b.Allow("s1"); ok {
+		t.Fatal("expected circuit to reopen after failed probe")
+	}
+
+	// A successful probe closes it
+	now = now.Add(10 * time.Second)
+	if _, ok := b.Allow("s1"); !ok {
+		t.Fatal("expected probe to be allowed")
+	}
+	b.Success("s1")
+	if _, ok := b.Allow("s1"); !ok {
+		t.Fatal("expected circuit to close after success")
+	}
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := NewBreaker(CircuitBreakerOptions{})
+	if b != nil {
+		t.Fatal("expected nil breaker when disabled")
+	}
+	for i := 0; i < 10; i++ {
+		b.Failure("s")
+	}
+	if _, ok := b.Allow("s"); !ok {
+		t.Fatal("nil breaker must allow requests")
+	}
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Identity identifies who a request is charged to
+type Identity struct {
+	// Principal is "user:<id>" for authenticated requests or "ip:<addr>" otherwise
+	Principal string
+	// APIKey is a fingerprint of the API key used, empty for other auth methods
+	APIKey string
+	// Teams are the teams the principal belongs to
+	Teams []string
+}
+
+// RouteGroup applies a per-principal rule to routes whose Echo path pattern
+// starts with PathPrefix (e.g. "/sessions" or "/:sessionId" for proxied traffic).
+type RouteGroup struct {
+	Name       string
+	PathPrefix string
+	Rule       Rule
+}
+
+// matches reports whether the route pattern belongs to the group
+func (g RouteGroup) matches(path string) bool {
+	if strings.HasSuffix(g.PathPrefix, "/") {
+		return strings.HasPrefix(path, g.PathPrefix)
+	}
+	return path == g.PathPrefix || strings.HasPrefix(path, g.PathPrefix+"/")
+}
+
+// MiddlewareConfig configures Middleware
+type MiddlewareConfig struct {
+	// User limits each principal across all routes
+	User Rule
+	// APIKey limits each API key across all routes
+	APIKey Rule
+	// Team limits the combined traffic of each team's members
+	Team Rule
+	// Routes limit each principal within a route group; the first match applies
+	Routes []RouteGroup
+	// Identify extracts the request identity
+	Identify func(c echo.Context) Identity
+	// Skipper skips rate limiting when it returns true
+	Skipper func(c echo.Context) bool
+}
+
+// check is one bucket a request is charged against
+type check struct {
+	scope string
+	key   string
+	rule  Rule
+}
+
+// Middleware returns Echo middleware that responds 429 with Retry-After when
+// any applicable bucket is empty. Limiter errors are logged and the request
+// is allowed, so a Redis outage does not take the API down.
+func Middleware(limiter Limiter, cfg MiddlewareConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Method == http.MethodOptions {
+				return next(c)
+			}
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+
+			id := cfg.Identify(c)
+			if id.Principal == "" {
+				return next(c)
+			}
+
+			var denied *check
+			var retryAfter float64
+			for _, chk := range cfg.checks(c.Path(), id) {
+				res, err := limiter.Allow(c.Request().Context(), chk.key, chk.rule)
+				if err != nil {
+					log.Printf("[RATE_LIMIT] Limiter error for %s, allowing request: %v", chk.key, err)
+					continue
+				}
+				if !res.Allowed && res.RetryAfter.Seconds() >= retryAfter {
+					chk := chk
+					denied = &chk
+					retryAfter = res.RetryAfter.Seconds()
+				}
+			}
+
+			if denied != nil {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter)))))
+				log.Printf("[RATE_LIMIT] Rejected %s %s for %s (%s limit)", c.Request().Method, c.Path(), id.Principal, denied.scope)
+				return echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded (%s)", denied.scope))
+			}
+			return next(c)
+		}
+	}
+}
+
+// checks lists the buckets a request to path is charged against
+func (cfg MiddlewareConfig) checks(path string, id Identity) []check {
+	var checks []check
+	if cfg.User.Enabled() {
+		checks = append(checks, check{scope: "user", key: "principal:" + id.Principal, rule: cfg.User})
+	}
+	if cfg.APIKey.Enabled() && id.APIKey != "" {
+		checks = append(checks, check{scope: "api_key", key: "apikey:" + id.APIKey, rule: cfg.APIKey})
+	}
+	if cfg.Team.Enabled() {
+		for _, team := range id.Teams {
+			checks = append(checks, check{scope: "team", key: "team:" + team, rule: cfg.Team})
+		}
+	}
+	for _, group := range cfg.Routes {
+		if group.Rule.Enabled() && group.matches(path) {
+			checks = append(checks, check{scope: group.Name, key: "route:" + group.Name + ":" + id.Principal, rule: group.Rule})
+			break
+		}
+	}
+	return checks
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newTestEcho(limiter Limiter, cfg MiddlewareConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(Middleware(limiter, cfg))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/sessions", ok)
+	e.GET("/:sessionId/*", ok)
+	e.GET("/health", ok)
+	return e
+}
+
+func doRequest(e *echo.Echo, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestMiddleware_UserLimit(t *testing.T) {
+	e := newTestEcho(NewMemoryLimiter(), MiddlewareConfig{
+		User:     Rule{RPS: 0.5, Burst: 2},
+		Identify: func(echo.Context) Identity { return Identity{Principal: "user:alice"} },
+	})
+
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(e, "/sessions"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d", i, rec.Code)
+		}
+	}
+	rec := doRequest(e, "/sessions")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
+
+func TestMiddleware_RouteGroups(t *testing.T) {
+	e := newTestEcho(NewMemoryLimiter(), MiddlewareConfig{
+		Routes: []RouteGroup{
+			{Name: "sessions", PathPrefix: "/sessions", Rule: Rule{RPS: 1, Burst: 1}},
+			{Name: "proxy", PathPrefix: "/:sessionId", Rule: Rule{RPS: 100, Burst: 100}},
+		},
+		Identify: func(echo.Context) Identity { return Identity{Principal: "user:alice"} },
+	})
+
+	if rec := doRequest(e, "/sessions"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if rec := doRequest(e, "/sessions"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	// Proxied traffic has its own, larger bucket
+	for i := 0; i < 10; i++ {
+		if rec := doRequest(e, "/abc/status"); rec.Code != http.StatusOK {
+			t.Fatalf("proxied request %d: status = %d", i, rec.Code)
+		}
+	}
+}
+
+func TestMiddleware_TeamAndAPIKey(t *testing.T) {
+	users := []string{"user:alice", "user:bob"}
+	n := 0
+	e := newTestEcho(NewMemoryLimiter(), MiddlewareConfig{
+		Team: Rule{RPS: 1, Burst: 2},
+		Identify: func(echo.Context) Identity {
+			id := Identity{Principal: users[n%2], Teams: []string{"org/team"}}
+			n++
+			return id
+		},
+	})
+
+	// Two different users share the team bucket
+	doRequest(e, "/sessions")
+	doRequest(e, "/sessions")
+	if rec := doRequest(e, "/sessions"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 from team limit", rec.Code)
+	}
+
+	e = newTestEcho(NewMemoryLimiter(), MiddlewareConfig{
+		APIKey:   Rule{RPS: 1, Burst: 1},
+		Identify: func(echo.Context) Identity { return Identity{Principal: "user:svc", APIKey: "fp"} },
+	})
+	doRequest(e, "/sessions")
+	if rec := doRequest(e, "/sessions"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 from API key limit", rec.Code)
+	}
+}
+
+func TestMiddleware_SkipperAndOptions(t *testing.T) {
+	e := newTestEcho(NewMemoryLimiter(), MiddlewareConfig{
+		User:     Rule{RPS: 1, Burst: 1},
+		Identify: func(echo.Context) Identity { return Identity{Principal: "ip:1.2.3.4"} },
+		Skipper:  func(c echo.Context) bool { return c.Request().URL.Path == "/health" },
+	})
+
+	for i := 0; i < 5; i++ {
+		if rec := doRequest(e, "/health"); rec.Code != http.StatusOK {
+			t.Fatalf("skipped request %d: status = %d", i, rec.Code)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/sessions", nil))
+		if rec.Code == http.StatusTooManyRequests {
+			t.Fatal("OPTIONS request should not be rate limited")
+		}
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, Rule) (Result, error) {
+	return Result{}, errors.New("redis down")
+}
+
+func TestMiddleware_FailsOpen(t *testing.T) {
+	e := newTestEcho(failingLimiter{}, MiddlewareConfig{
+		User:     Rule{RPS: 1, Burst: 1},
+		Identify: func(echo.Context) Identity { return Identity{Principal: "user:alice"} },
+	})
+	for i := 0; i < 3; i++ {
+		if rec := doRequest(e, "/sessions"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 when limiter fails", rec.Code)
+		}
+	}
+}
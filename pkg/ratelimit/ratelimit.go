@@ -0,0 +1,117 @@
+// Package ratelimit provides token-bucket rate limiting for the HTTP server,
+// with an in-memory limiter for single replicas and a Redis-backed one for
+// multi-replica deployments.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule is a token-bucket rule: RPS tokens are added per second up to Burst.
+// A rule with non-positive RPS is disabled.
+type Rule struct {
+	RPS   float64
+	Burst int
+}
+
+// Enabled reports whether the rule limits anything
+func (r Rule) Enabled() bool {
+	return r.RPS > 0
+}
+
+// burst returns the bucket capacity, which is at least one token
+func (r Rule) burst() float64 {
+	if r.Burst < 1 {
+		return 1
+	}
+	return float64(r.Burst)
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	// Allowed is true when a token was available
+	Allowed bool
+	// RetryAfter is how long until a token becomes available when not allowed
+	RetryAfter time.Duration
+}
+
+// Limiter takes tokens from named buckets
+type Limiter interface {
+	// Allow takes one token from the bucket identified by key under rule
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// memoryIdleSweepInterval is how often idle buckets are evicted from a MemoryLimiter
+const memoryIdleSweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// MemoryLimiter is an in-process Limiter. Buckets are not shared between replicas.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes one token from the bucket identified by key
+func (l *MemoryLimiter) Allow(_ context.Context, key string, rule Rule) (Result, error) {
+	if !rule.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok || b.rule != rule {
+		b = &bucket{tokens: rule.burst(), last: now, rule: rule}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(rule.burst(), b.tokens+elapsed*rule.RPS)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true}, nil
+	}
+	wait := (1 - b.tokens) / rule.RPS
+	return Result{RetryAfter: time.Duration(wait * float64(time.Second))}, nil
+}
+
+// sweep evicts buckets that have refilled completely, since they are
+// indistinguishable from new ones. Callers must hold l.mu.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memoryIdleSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		refill := time.Duration(b.rule.burst() / b.rule.RPS * float64(time.Second))
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	rule := Rule{RPS: 1, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(context.Background(), "k", rule)
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d: Allowed = %v, err = %v", i, res.Allowed, err)
+		}
+	}
+
+	res, _ := l.Allow(context.Background(), "k", rule)
+	if res.Allowed {
+		t.Fatal("expected request over burst to be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", res.RetryAfter)
+	}
+
+	// Other keys have their own bucket
+	if res, _ := l.Allow(context.Background(), "other", rule); !res.Allowed {
+		t.Error("expected independent bucket for another key")
+	}
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	rule := Rule{RPS: 2, Burst: 1}
+
+	if res, _ := l.Allow(context.Background(), "k", rule); !res.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+	if res, _ := l.Allow(context.Background(), "k", rule); res.Allowed {
+		t.Fatal("second request should be rejected")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if res, _ := l.Allow(context.Background(), "k", rule); !res.Allowed {
+		t.Fatal("request after refill should be allowed")
+	}
+}
+
+func TestMemoryLimiter_DisabledRule(t *testing.T) {
+	l := NewMemoryLimiter()
+	for i := 0; i < 100; i++ {
+		if res, _ := l.Allow(context.Background(), "k", Rule{}); !res.Allowed {
+			t.Fatal("disabled rule should always allow")
+		}
+	}
+}
+
+func TestMemoryLimiter_SweepsIdleBuckets(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+
+	_, _ = l.Allow(context.Background(), "idle", Rule{RPS: 10, Burst: 5})
+	now = now.Add(2 * memoryIdleSweepInterval)
+	_, _ = l.Allow(context.Background(), "active", Rule{RPS: 10, Burst: 5})
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("expected idle bucket to be evicted")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("expected active bucket to be kept")
+	}
+}
@@ -0,0 +1,92 @@
+package rbaccheck
+
+import (
+	"bytes"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestOptions names the generated objects and the ServiceAccount they
+// are bound to
+type ManifestOptions struct {
+	// Name of the Role, ClusterRole and their bindings
+	Name string
+	// ServiceAccount and ServiceAccountNamespace identify the proxy. The
+	// namespace defaults to the session namespace.
+	ServiceAccount          string
+	ServiceAccountNamespace string
+}
+
+// Manifest renders a Role and RoleBinding for the namespaced rules of reqs,
+// plus a ClusterRole and ClusterRoleBinding when cluster-scoped rules are
+// required, as a multi-document YAML stream
+func Manifest(reqs Requirements, opts ManifestOptions) ([]byte, error) {
+	if opts.ServiceAccountNamespace == "" {
+		opts.ServiceAccountNamespace = reqs.Namespace
+	}
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      opts.ServiceAccount,
+		Namespace: opts.ServiceAccountNamespace,
+	}}
+
+	objects := []interface{}{
+		&rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+			ObjectMeta: metav1.ObjectMeta{Name: opts.Name, Namespace: reqs.Namespace},
+			Rules:      policyRules(reqs.Namespaced),
+		},
+		&rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: opts.Name, Namespace: reqs.Namespace},
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: opts.Name},
+		},
+	}
+	if len(reqs.Cluster) > 0 {
+		objects = append(objects,
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: opts.Name},
+				Rules:      policyRules(reqs.Cluster),
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: opts.Name},
+				Subjects:   subjects,
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: opts.Name},
+			})
+	}
+
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render manifest: %w", err)
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+func policyRules(rules []Rule) []rbacv1.PolicyRule {
+	policy := make([]rbacv1.PolicyRule, 0, len(rules))
+	for _, r := range rules {
+		resource := r.Resource
+		if r.Subresource != "" {
+			resource += "/" + r.Subresource
+		}
+		policy = append(policy, rbacv1.PolicyRule{
+			APIGroups: []string{r.APIGroup},
+			Resources: []string{resource},
+			Verbs:     r.Verbs,
+		})
+	}
+	return policy
+}
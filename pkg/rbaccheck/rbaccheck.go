@@ -0,0 +1,167 @@
+// Package rbaccheck lists the Kubernetes permissions the proxy needs for its
+// configured features, checks them against the current ServiceAccount and
+// generates least-privilege Role and ClusterRole manifests.
+package rbaccheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+// Rule is a set of verbs the proxy needs on one resource
+type Rule struct {
+	APIGroup    string
+	Resource    string
+	Subresource string
+	Verbs       []string
+	// Reason names the feature or code path that needs the rule
+	Reason string
+}
+
+// Name returns the resource in kubectl notation, e.g. "pods/log" or
+// "deployments.apps"
+func (r Rule) Name() string {
+	name := r.Resource
+	if r.Subresource != "" {
+		name += "/" + r.Subresource
+	}
+	if r.APIGroup != "" {
+		name += "." + r.APIGroup
+	}
+	return name
+}
+
+// Requirements are the rules needed by a configuration. Namespaced rules
+// apply to the session namespace, cluster rules to cluster-scoped resources.
+type Requirements struct {
+	Namespace  string
+	Namespaced []Rule
+	Cluster    []Rule
+}
+
+// Required returns the rules the proxy uses with cfg. namespace is the
+// session namespace the proxy runs against.
+func Required(cfg *config.Config, namespace string) Requirements {
+	k8s := cfg.KubernetesSession
+	reqs := Requirements{Namespace: namespace}
+	add := func(r Rule) { reqs.Namespaced = append(reqs.Namespaced, r) }
+
+	add(Rule{Resource: "pods", Verbs: []string{"get", "list", "watch", "create", "update", "delete"},
+		Reason: "session and stock Pods (update: stock session adoption)"})
+	add(Rule{Resource: "pods", Subresource: "log", Verbs: []string{"get"},
+		Reason: "session logs"})
+	if k8s.ExecEnabled {
+		add(Rule{Resource: "pods", Subresource: "exec", Verbs: []string{"get", "create"},
+			Reason: "kubernetes_session.exec_enabled (get: WebSocket exec, create: SPDY exec)"})
+	}
+	add(Rule{Resource: "services", Verbs: []string{"get", "list", "create", "delete", "patch", "update"},
+		Reason: "session Services and their annotations"})
+	add(Rule{Resource: "persistentvolumeclaims", Verbs: []string{"get", "list", "create", "delete"},
+		Reason: "session workdir volumes"})
+	add(Rule{APIGroup: "apps", Resource: "deployments", Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+		Reason: "sessions with a PVC and stock session adoption"})
+	if k8s.Routing == "endpoints" {
+		add(Rule{APIGroup: "discovery.k8s.io", Resource: "endpointslices", Verbs: []string{"list"},
+			Reason: "kubernetes_session.routing=endpoints"})
+	}
+	if k8s.OneshotJobEnabled {
+		add(Rule{APIGroup: "batch", Resource: "jobs", Verbs: []string{"get", "list", "create", "delete"},
+			Reason: "kubernetes_session.oneshot_job_enabled"})
+	}
+	add(Rule{Resource: "secrets", Verbs: []string{"get", "list", "create", "update", "delete", "patch"},
+		Reason: "session settings, credentials and Secret backed stores"})
+	add(Rule{Resource: "configmaps", Verbs: []string{"get", "list", "create", "update", "patch", "delete"},
+		Reason: "schedules, webhooks and ConfigMap backed stores"})
+
+	var electing []string
+	if cfg.LeaderElection.Enabled {
+		electing = append(electing, "leader_election")
+	}
+	if cfg.ScheduleWorker.Enabled {
+		electing = append(electing, "schedule_worker")
+	}
+	if cfg.SlackbotCleanupWorker.Enabled {
+		electing = append(electing, "slackbot_cleanup_worker")
+	}
+	if interval := cfg.GitSync.SyncInterval; interval != "" && interval != "0" {
+		electing = append(electing, "git_sync.sync_interval")
+	}
+	if len(electing) > 0 {
+		add(Rule{APIGroup: "coordination.k8s.io", Resource: "leases", Verbs: []string{"get", "create", "update"},
+			Reason: "leader election of " + strings.Join(electing, ", ")})
+	}
+
+	if k8s.PreviewDeployHookURL != "" {
+		reqs.Cluster = append(reqs.Cluster, Rule{Resource: "namespaces", Verbs: []string{"get", "create", "delete"},
+			Reason: "preview environments (kubernetes_session.preview_deploy_hook_url)"})
+	}
+	return reqs
+}
+
+// Missing is a verb the ServiceAccount is not allowed to use
+type Missing struct {
+	Rule Rule
+	Verb string
+	// Namespace is empty for cluster-scoped rules
+	Namespace string
+}
+
+func (m Missing) String() string {
+	if m.Namespace == "" {
+		return fmt.Sprintf("%s %s (cluster)", m.Verb, m.Rule.Name())
+	}
+	return fmt.Sprintf("%s %s", m.Verb, m.Rule.Name())
+}
+
+// Result is the outcome of Check
+type Result struct {
+	Checked int
+	Missing []Missing
+}
+
+// Check asks the API server with SelfSubjectAccessReviews whether the
+// current credentials may use every verb of reqs
+func Check(ctx context.Context, client kubernetes.Interface, reqs Requirements) (*Result, error) {
+	result := &Result{}
+	review := func(rule Rule, namespace string) error {
+		for _, verb := range rule.Verbs {
+			resp, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   namespace,
+						Verb:        verb,
+						Group:       rule.APIGroup,
+						Resource:    rule.Resource,
+						Subresource: rule.Subresource,
+					},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to review %s %s: %w", verb, rule.Name(), err)
+			}
+			result.Checked++
+			if !resp.Status.Allowed {
+				result.Missing = append(result.Missing, Missing{Rule: rule, Verb: verb, Namespace: namespace})
+			}
+		}
+		return nil
+	}
+	for _, rule := range reqs.Namespaced {
+		if err := review(rule, reqs.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range reqs.Cluster {
+		if err := review(rule, ""); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
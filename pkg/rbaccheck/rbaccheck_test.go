@@ -0,0 +1,97 @@
+package rbaccheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+)
+
+func hasRule(rules []Rule, name string) bool {
+	for _, r := range rules {
+		if r.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequiredFollowsFeatures(t *testing.T) {
+	cfg := &config.Config{}
+	reqs := Required(cfg, "agentapi")
+	for _, name := range []string{"pods/exec", "jobs.batch", "leases.coordination.k8s.io", "endpointslices.discovery.k8s.io"} {
+		if hasRule(reqs.Namespaced, name) {
+			t.Errorf("%s required without its feature", name)
+		}
+	}
+	if len(reqs.Cluster) != 0 {
+		t.Errorf("cluster rules = %v, want none", reqs.Cluster)
+	}
+
+	cfg.KubernetesSession.ExecEnabled = true
+	cfg.KubernetesSession.OneshotJobEnabled = true
+	cfg.KubernetesSession.Routing = "endpoints"
+	cfg.KubernetesSession.PreviewDeployHookURL = "https://deploy.example.com/hook"
+	cfg.ScheduleWorker.Enabled = true
+	reqs = Required(cfg, "agentapi")
+	for _, name := range []string{"pods/exec", "jobs.batch", "leases.coordination.k8s.io", "endpointslices.discovery.k8s.io"} {
+		if !hasRule(reqs.Namespaced, name) {
+			t.Errorf("%s not required with its feature enabled", name)
+		}
+	}
+	if !hasRule(reqs.Cluster, "namespaces") {
+		t.Errorf("namespaces not required for preview environments")
+	}
+}
+
+func TestCheckReportsDeniedVerbs(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = !(attrs.Resource == "services" && attrs.Verb == "patch")
+		return true, review, nil
+	})
+
+	reqs := Requirements{
+		Namespace: "agentapi",
+		Namespaced: []Rule{
+			{Resource: "services", Verbs: []string{"get", "patch"}},
+			{Resource: "configmaps", Verbs: []string{"get"}},
+		},
+	}
+	result, err := Check(context.Background(), client, reqs)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.Checked != 3 {
+		t.Errorf("checked = %d, want 3", result.Checked)
+	}
+	if len(result.Missing) != 1 || result.Missing[0].String() != "patch services" {
+		t.Errorf("missing = %v, want [patch services]", result.Missing)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.KubernetesSession.PreviewDeployHookURL = "https://deploy.example.com/hook"
+	out, err := Manifest(Required(cfg, "agentapi"), ManifestOptions{Name: "agentapi-proxy", ServiceAccount: "agentapi-proxy"})
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	manifest := string(out)
+	for _, want := range []string{"kind: Role\n", "kind: RoleBinding\n", "kind: ClusterRole\n", "kind: ClusterRoleBinding\n", "pods/log", "namespace: agentapi"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest does not contain %q:\n%s", want, manifest)
+		}
+	}
+	if strings.Count(manifest, "---\n") != 3 {
+		t.Errorf("manifest should have 4 documents:\n%s", manifest)
+	}
+}
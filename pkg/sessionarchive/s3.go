@@ -0,0 +1,154 @@
+package sessionarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// GCSEndpoint is the S3-compatible XML API endpoint of Google Cloud Storage.
+const GCSEndpoint = "https://storage.googleapis.com"
+
+// S3Options configures an S3Store.
+type S3Options struct {
+	// Provider is "s3" (default) or "gcs". GCS is reached through its
+	// S3-compatible XML API with HMAC keys passed as AWS credentials.
+	Provider string
+	Bucket   string
+	Region   string
+	// Prefix is prepended to every key, e.g. "agentapi-archive".
+	Prefix string
+	// Endpoint is a custom S3-compatible endpoint URL.
+	Endpoint string
+}
+
+// S3Store implements Store on Amazon S3, Google Cloud Storage or another
+// S3-compatible service.
+type S3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates an S3Store with the default AWS credential chain.
+func NewS3Store(ctx context.Context, opts S3Options) (*S3Store, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	endpoint, region := opts.Endpoint, opts.Region
+	gcs := false
+	switch opts.Provider {
+	case "", "s3":
+	case "gcs":
+		gcs = true
+		if endpoint == "" {
+			endpoint = GCSEndpoint
+		}
+		if region == "" {
+			region = "auto"
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider %q (valid: s3, gcs)", opts.Provider)
+	}
+
+	loadOpts := []func(*awsconfig.LoadOptions) error{}
+	if region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+		if gcs {
+			// The GCS XML API rejects the checksum trailers sent by default.
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		}
+	})
+	return &S3Store{client: client, bucket: opts.Bucket, prefix: strings.Trim(opts.Prefix, "/")}, nil
+}
+
+func (s *S3Store) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// Put uploads body under key.
+func (s *S3Store) Put(ctx context.Context, key, contentType string, body io.Reader) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.objectKey(key)),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// Get downloads the object stored under key.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// List returns the objects whose key starts with prefix.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	base := s.objectKey("")
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(base + prefix),
+	})
+	var objects []Object
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list archive objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+			o := Object{Key: strings.TrimPrefix(*obj.Key, base)}
+			if obj.Size != nil {
+				o.Size = *obj.Size
+			}
+			if obj.LastModified != nil {
+				o.UpdatedAt = *obj.LastModified
+			}
+			objects = append(objects, o)
+		}
+	}
+	return objects, nil
+}
+
+// Delete removes the object stored under key.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	return err
+}
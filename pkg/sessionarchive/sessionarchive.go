@@ -0,0 +1,275 @@
+// Package sessionarchive keeps the pod logs, agent history and selected
+// workdir artifacts of deleted sessions in object storage.
+//
+// The files of a session are stored under {team}/{session_id}/, or
+// _users/{user_id}/{session_id}/ for personal sessions, next to a
+// manifest.json that describes them. A copy of each manifest is kept under
+// _index/{session_id}.json so that archived sessions can be found by ID.
+package sessionarchive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+const (
+	// ManifestName is the name of the manifest stored with the files of a session.
+	ManifestName = "manifest.json"
+
+	indexPrefix = "_index/"
+	usersPrefix = "_users/"
+)
+
+// ErrNotFound is returned for sessions and files that are not archived.
+var ErrNotFound = errors.New("not archived")
+
+// Object describes a stored object.
+type Object struct {
+	Key       string
+	Size      int64
+	UpdatedAt time.Time
+}
+
+// Store is the object storage holding the archive. Keys are relative to the
+// configured bucket prefix.
+type Store interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader) error
+	// Get returns ErrNotFound for unknown keys.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// File describes an archived file of a session.
+type File struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Truncated is set when the file was cut off at the size limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Entry is a file to archive with its content.
+type Entry struct {
+	File
+	Data []byte
+}
+
+// Manifest describes the archive of one session.
+type Manifest struct {
+	SessionID  string                 `json:"session_id"`
+	UserID     string                 `json:"user_id"`
+	Scope      entities.ResourceScope `json:"scope"`
+	TeamID     string                 `json:"team_id,omitempty"`
+	Tags       map[string]string      `json:"tags,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	ArchivedAt time.Time              `json:"archived_at"`
+	// ExpiresAt is when the archive is removed; nil keeps it forever.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Files     []File     `json:"files"`
+}
+
+// NewManifest returns the manifest of session archived at now.
+func NewManifest(session entities.Session, now time.Time) *Manifest {
+	return &Manifest{
+		SessionID:  session.ID(),
+		UserID:     session.UserID(),
+		Scope:      session.Scope(),
+		TeamID:     session.TeamID(),
+		Tags:       session.Tags(),
+		StartedAt:  session.StartedAt(),
+		ArchivedAt: now.UTC(),
+	}
+}
+
+// Prefix returns the key prefix of the files of the session.
+func (m *Manifest) Prefix() string {
+	if m.Scope == entities.ScopeTeam && m.TeamID != "" {
+		return m.TeamID + "/" + m.SessionID + "/"
+	}
+	return usersPrefix + m.UserID + "/" + m.SessionID + "/"
+}
+
+// File returns the archived file called name.
+func (m *Manifest) File(name string) (File, bool) {
+	i := slices.IndexFunc(m.Files, func(f File) bool { return f.Name == name })
+	if i < 0 {
+		return File{}, false
+	}
+	return m.Files[i], true
+}
+
+// validKeyPart rejects values that could escape their place in a key.
+func validKeyPart(s string) bool {
+	return s != "" && !strings.Contains(s, "..") && !strings.HasPrefix(s, "/") && !strings.HasSuffix(s, "/")
+}
+
+// Archive stores and retrieves archived sessions.
+type Archive struct {
+	store     Store
+	retention time.Duration
+}
+
+// New creates an Archive on store. Archives older than retention are removed
+// by Sweep; a zero retention keeps them forever.
+func New(store Store, retention time.Duration) *Archive {
+	return &Archive{store: store, retention: retention}
+}
+
+// Save stores the entries and the manifest of a session. The entries are
+// recorded in m.Files. Files that cannot be stored are left out of the
+// manifest; an error is returned only when the manifest cannot be stored.
+func (a *Archive) Save(ctx context.Context, m *Manifest, entries []Entry) error {
+	if !validKeyPart(m.SessionID) || strings.Contains(m.SessionID, "/") || !validKeyPart(m.UserID) ||
+		(m.Scope == entities.ScopeTeam && m.TeamID != "" && !validKeyPart(m.TeamID)) {
+		return fmt.Errorf("session %q cannot be archived: invalid owner or ID", m.SessionID)
+	}
+	if a.retention > 0 {
+		expires := m.ArchivedAt.Add(a.retention)
+		m.ExpiresAt = &expires
+	}
+	prefix := m.Prefix()
+	m.Files = make([]File, 0, len(entries))
+	for _, e := range entries {
+		if !validKeyPart(e.Name) || strings.Contains(e.Name, "/") || e.Name == ManifestName {
+			log.Printf("[SESSION_ARCHIVE] Skipping file with invalid name %q of session %s", e.Name, m.SessionID)
+			continue
+		}
+		if err := a.store.Put(ctx, prefix+e.Name, e.ContentType, bytes.NewReader(e.Data)); err != nil {
+			log.Printf("[SESSION_ARCHIVE] Failed to archive %s of session %s: %v", e.Name, m.SessionID, err)
+			continue
+		}
+		f := e.File
+		f.Size = int64(len(e.Data))
+		m.Files = append(m.Files, f)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := a.store.Put(ctx, prefix+ManifestName, "application/json", bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to store manifest: %w", err)
+	}
+	if err := a.store.Put(ctx, indexPrefix+m.SessionID+".json", "application/json", bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to store index: %w", err)
+	}
+	return nil
+}
+
+// Get returns the manifest of an archived session.
+func (a *Archive) Get(ctx context.Context, sessionID string) (*Manifest, error) {
+	if !validKeyPart(sessionID) || strings.Contains(sessionID, "/") {
+		return nil, ErrNotFound
+	}
+	return a.readManifest(ctx, indexPrefix+sessionID+".json")
+}
+
+func (a *Archive) readManifest(ctx context.Context, key string) (*Manifest, error) {
+	r, err := a.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", key, err)
+	}
+	return &m, nil
+}
+
+// Open returns the content of an archived file of the session.
+func (a *Archive) Open(ctx context.Context, m *Manifest, name string) (io.ReadCloser, error) {
+	// Only names listed in the manifest are served, which also rules out paths.
+	if _, ok := m.File(name); !ok {
+		return nil, ErrNotFound
+	}
+	return a.store.Get(ctx, m.Prefix()+name)
+}
+
+// List returns the manifests of all archived sessions, most recently
+// archived first. Manifests that cannot be read are skipped.
+func (a *Archive) List(ctx context.Context) ([]*Manifest, error) {
+	objects, err := a.store.List(ctx, indexPrefix)
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]*Manifest, 0, len(objects))
+	for _, o := range objects {
+		m, err := a.readManifest(ctx, o.Key)
+		if err != nil {
+			log.Printf("[SESSION_ARCHIVE] Skipping unreadable manifest %s: %v", o.Key, err)
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	slices.SortFunc(manifests, func(x, y *Manifest) int { return y.ArchivedAt.Compare(x.ArchivedAt) })
+	return manifests, nil
+}
+
+// Sweep removes the archives that expired before now and returns how many
+// sessions were removed.
+func (a *Archive) Sweep(ctx context.Context, now time.Time) (int, error) {
+	manifests, err := a.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, m := range manifests {
+		if m.ExpiresAt == nil || m.ExpiresAt.After(now) {
+			continue
+		}
+		if err := a.remove(ctx, m); err != nil {
+			log.Printf("[SESSION_ARCHIVE] Failed to remove expired archive of session %s: %v", m.SessionID, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// remove deletes the files of the session, then its index entry, so a
+// failed removal is retried by the next sweep.
+func (a *Archive) remove(ctx context.Context, m *Manifest) error {
+	objects, err := a.store.List(ctx, m.Prefix())
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		if err := a.store.Delete(ctx, o.Key); err != nil {
+			return err
+		}
+	}
+	return a.store.Delete(ctx, indexPrefix+m.SessionID+".json")
+}
+
+// Run sweeps expired archives every interval until ctx is cancelled.
+func (a *Archive) Run(ctx context.Context, interval time.Duration) {
+	if a.retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := a.Sweep(ctx, time.Now()); err != nil {
+			log.Printf("[SESSION_ARCHIVE] Failed to sweep expired archives: %v", err)
+		} else if n > 0 {
+			log.Printf("[SESSION_ARCHIVE] Removed %d expired session archive(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package sessionarchive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+)
+
+type memoryStore struct {
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: map[string][]byte{}}
+}
+
+func (s *memoryStore) Put(_ context.Context, key, _ string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	s.objects[key] = data
+	return err
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStore) List(_ context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: int64(len(data))})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func TestSaveAndOpen(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	archive := New(store, 24*time.Hour)
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	m := &Manifest{SessionID: "sess-1", UserID: "alice", Scope: entities.ScopeTeam, TeamID: "acme/dev", ArchivedAt: at}
+	err := archive.Save(ctx, m, []Entry{
+		{File: File{Name: "pod.log", ContentType: "text/plain"}, Data: []byte("started\n")},
+		{File: File{Name: "../escape"}, Data: []byte("x")},
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, ok := store.objects["acme/dev/sess-1/pod.log"]; !ok {
+		t.Fatalf("pod.log not stored under the team prefix: %v", store.objects)
+	}
+
+	got, err := archive.Get(ctx, "sess-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got.Files) != 1 || got.Files[0].Size != 8 || got.ExpiresAt == nil || !got.ExpiresAt.Equal(at.Add(24*time.Hour)) {
+		t.Errorf("manifest = %+v", got)
+	}
+	r, err := archive.Open(ctx, got, "pod.log")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	if string(data) != "started\n" {
+		t.Errorf("pod.log = %q", data)
+	}
+	if _, err := archive.Open(ctx, got, ManifestName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(manifest) error = %v, want ErrNotFound", err)
+	}
+	if _, err := archive.Get(ctx, "../sess-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(path) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPersonalSessionPrefix(t *testing.T) {
+	m := &Manifest{SessionID: "sess-2", UserID: "bob", Scope: entities.ScopeUser}
+	if got := m.Prefix(); got != "_users/bob/sess-2/" {
+		t.Errorf("Prefix() = %q", got)
+	}
+}
+
+func TestSweepRemovesExpiredArchives(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	archive := New(store, time.Hour)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for id, archivedAt := range map[string]time.Time{"old": now.Add(-2 * time.Hour), "new": now.Add(-time.Minute)} {
+		m := &Manifest{SessionID: id, UserID: "alice", Scope: entities.ScopeUser, ArchivedAt: archivedAt}
+		if err := archive.Save(ctx, m, []Entry{{File: File{Name: "pod.log"}, Data: []byte("log")}}); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+
+	removed, err := archive.Sweep(ctx, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Sweep() = %d, %v; want 1", removed, err)
+	}
+	if _, err := archive.Get(ctx, "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired archive still indexed: %v", err)
+	}
+	for key := range store.objects {
+		if strings.Contains(key, "/old/") {
+			t.Errorf("expired object %s not removed", key)
+		}
+	}
+	list, err := archive.List(ctx)
+	if err != nil || len(list) != 1 || list[0].SessionID != "new" {
+		t.Errorf("List() = %v, %v", list, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	github_pkg "github.com/takutakahashi/agentapi-proxy/pkg/github"
@@ -33,6 +34,10 @@ type SetupOptions struct {
 	// RegisterMarketplaces registers cloned marketplace repos via claude CLI.
 	RegisterMarketplaces bool
 
+	// ExtensionReportPath is where the marketplaces phase writes which
+	// marketplaces and plugins it registered (optional).
+	ExtensionReportPath string
+
 	// SettingsFile is the path to the user settings.json (from claude-config-user ConfigMap).
 	// Contains marketplace and plugin configuration. Optional.
 	SettingsFile string
@@ -40,6 +45,35 @@ type SetupOptions struct {
 	// PEMOutputPath is where GITHUB_APP_PEM content is written.
 	// Defaults to /tmp/github-app/app.pem.
 	PEMOutputPath string
+
+	// Phases limits Setup to these phases, e.g. to retry a failed clone in a
+	// live Pod. Empty runs every phase. Every phase can be re-run.
+	Phases []string
+}
+
+// Setup phases, in the order Setup runs them.
+const (
+	SetupPhaseWritePEM        = "write-pem"
+	SetupPhaseClone           = "clone"
+	SetupPhaseCompileSettings = "compile-settings"
+	SetupPhaseMarketplaces    = "marketplaces"
+)
+
+// SetupPhases lists every setup phase in order.
+var SetupPhases = []string{SetupPhaseWritePEM, SetupPhaseClone, SetupPhaseCompileSettings, SetupPhaseMarketplaces}
+
+// ValidateSetupPhases returns an error for names that are not setup phases.
+func ValidateSetupPhases(phases []string) error {
+	for _, phase := range phases {
+		if !slices.Contains(SetupPhases, phase) {
+			return fmt.Errorf("unknown setup phase %q (valid: %s)", phase, strings.Join(SetupPhases, ", "))
+		}
+	}
+	return nil
+}
+
+func (o SetupOptions) runsPhase(phase string) bool {
+	return len(o.Phases) == 0 || slices.Contains(o.Phases, phase)
 }
 
 // DefaultSetupOptions returns the default Setup options.
@@ -52,11 +86,17 @@ func DefaultSetupOptions() SetupOptions {
 }
 
 // Setup runs the full init-container setup sequence for a session Pod:
-//  1. write-pem  : writes GITHUB_APP_PEM env var to a file on disk
-//  2. clone-repo : clones the repository (if session.repository is set)
-//  3. compile    : generates all Claude config files from settings.yaml
-//  4. sync-extra : copies credentials, notification subscriptions
+//  1. write-pem        : writes GITHUB_APP_PEM env var to a file on disk
+//  2. clone            : clones the repository (if session.repository is set)
+//  3. compile-settings : generates all Claude config files from settings.yaml
+//  4. marketplaces     : registers marketplaces, copies credentials and
+//     notification subscriptions
+//
+// opts.Phases selects a subset of the phases.
 func Setup(opts SetupOptions) error {
+	if err := ValidateSetupPhases(opts.Phases); err != nil {
+		return err
+	}
 	if opts.InputPath == "" {
 		opts.InputPath = DefaultSetupOptions().InputPath
 	}
@@ -70,46 +110,52 @@ func Setup(opts SetupOptions) error {
 		return fmt.Errorf("failed to load session settings: %w", err)
 	}
 
+	outputDir := opts.CompileOptions.OutputDir
+	if outputDir == "" {
+		outputDir = DefaultCompileOptions().OutputDir
+	}
+
 	// 1. Write GitHub App PEM to disk so git/gh can use it
-	if err := writePEM(settings, opts.PEMOutputPath); err != nil {
-		// Non-fatal: not all sessions use GitHub App auth
-		log.Printf("[SETUP] Warning: write-pem skipped: %v", err)
+	if opts.runsPhase(SetupPhaseWritePEM) {
+		if err := writePEM(settings, opts.PEMOutputPath); err != nil {
+			// Non-fatal: not all sessions use GitHub App auth
+			log.Printf("[SETUP] Warning: write-pem skipped: %v", err)
+		}
 	}
 
 	// 2. Clone repository if configured
-	if settings.Repository != nil && settings.Repository.FullName != "" {
+	if opts.runsPhase(SetupPhaseClone) && settings.Repository != nil && settings.Repository.FullName != "" {
 		if err := cloneRepo(settings); err != nil {
 			return fmt.Errorf("clone-repo failed: %w", err)
 		}
 	}
 
 	// 3. Compile settings.yaml → config files
-	if err := Compile(opts.CompileOptions); err != nil {
-		return fmt.Errorf("compile failed: %w", err)
-	}
-
-	outputDir := opts.CompileOptions.OutputDir
-	if outputDir == "" {
-		outputDir = DefaultCompileOptions().OutputDir
-	}
-	if shouldRefreshPiOllamaCloud(settings) {
-		if err := refreshPiOllamaCloudCache(settings.Env, outputDir); err != nil {
-			// Non-fatal: pi-ollama-cloud can continue with its bundled or existing cache.
-			log.Printf("[SETUP] Warning: failed to refresh Ollama Cloud models: %v", err)
+	if opts.runsPhase(SetupPhaseCompileSettings) {
+		if err := Compile(opts.CompileOptions); err != nil {
+			return fmt.Errorf("compile failed: %w", err)
+		}
+		if shouldRefreshPiOllamaCloud(settings) {
+			if err := refreshPiOllamaCloudCache(settings.Env, outputDir); err != nil {
+				// Non-fatal: pi-ollama-cloud can continue with its bundled or existing cache.
+				log.Printf("[SETUP] Warning: failed to refresh Ollama Cloud models: %v", err)
+			}
 		}
 	}
 
-	// 4. Copy credentials, CLAUDE.md, notification subscriptions
-	if err := syncExtra(settings, opts); err != nil {
-		return fmt.Errorf("sync-extra failed: %w", err)
-	}
+	if opts.runsPhase(SetupPhaseMarketplaces) {
+		// 4. Copy credentials, CLAUDE.md, notification subscriptions
+		if err := syncExtra(settings, opts); err != nil {
+			return fmt.Errorf("sync-extra failed: %w", err)
+		}
 
-	// 5. Re-patch ~/.claude.json to ensure onboarding fields are set.
-	//    The sync step (marketplace clone / plugin install) may trigger Claude
-	//    CLI which rewrites ~/.claude.json and drops bypassPermissionsModeAccepted,
-	//    causing the "Welcome to Claude Code" screen on next launch.
-	if err := patchClaudeJSON(outputDir, settings.Claude.ClaudeJSON); err != nil {
-		log.Printf("[SETUP] Warning: failed to re-patch .claude.json: %v", err)
+		// 5. Re-patch ~/.claude.json to ensure onboarding fields are set.
+		//    The sync step (marketplace clone / plugin install) may trigger Claude
+		//    CLI which rewrites ~/.claude.json and drops bypassPermissionsModeAccepted,
+		//    causing the "Welcome to Claude Code" screen on next launch.
+		if err := patchClaudeJSON(outputDir, settings.Claude.ClaudeJSON); err != nil {
+			log.Printf("[SETUP] Warning: failed to re-patch .claude.json: %v", err)
+		}
 	}
 
 	log.Printf("[SETUP] Setup completed successfully")
@@ -165,6 +211,9 @@ func cloneRepo(settings *SessionSettings) error {
 	if _, err := os.Stat(filepath.Join(cloneDir, ".git")); err == nil {
 		log.Printf("[SETUP] Repository already cloned at %s, skipping", cloneDir)
 	} else {
+		if err := startup.RemovePartialClone(cloneDir); err != nil {
+			return err
+		}
 		if err := os.MkdirAll(filepath.Dir(cloneDir), 0755); err != nil {
 			return fmt.Errorf("failed to create parent dir for clone: %w", err)
 		}
@@ -176,8 +225,7 @@ func cloneRepo(settings *SessionSettings) error {
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			// Fallback: try GITHUB_URL-based git clone for non-GitHub hosts
-			githubURL := github_pkg.GetGitHubURL()
-			cloneURL := fmt.Sprintf("%s/%s.git", githubURL, repo.FullName)
+			cloneURL := github_pkg.EnvInstance().CloneURL(repo.FullName)
 			log.Printf("[SETUP] gh repo clone failed, falling back to git clone: %s", cloneURL)
 			cmd2 := exec.Command("git", "clone", "--depth", "1", cloneURL, cloneDir)
 			cmd2.Env = githubCommandEnv()
@@ -200,10 +248,8 @@ func cloneRepo(settings *SessionSettings) error {
 
 func githubCommandEnv() []string {
 	env := os.Environ()
-	if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" && githubAPI != "https://api.github.com" {
-		githubHost := strings.TrimPrefix(githubAPI, "https://")
-		githubHost = strings.TrimPrefix(githubHost, "http://")
-		githubHost = strings.TrimSuffix(githubHost, "/api/v3")
+	if instance := github_pkg.EnvInstance(); instance.IsEnterprise() {
+		githubHost := instance.Host()
 		env = append(env, fmt.Sprintf("GH_HOST=%s", githubHost))
 		log.Printf("[SETUP] GHE detected, setting GH_HOST=%s for gh/git", githubHost)
 	}
@@ -289,6 +335,7 @@ func syncExtra(settings *SessionSettings, opts SetupOptions) error {
 		NotificationSubscriptions: opts.NotificationSubscriptions,
 		NotificationsDir:          opts.NotificationsDir,
 		RegisterMarketplaces:      opts.RegisterMarketplaces,
+		ExtensionReportPath:       opts.ExtensionReportPath,
 	}
 
 	return startup.Sync(syncOpts)
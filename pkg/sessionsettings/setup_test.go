@@ -51,3 +51,15 @@ func TestRepositoryCheckoutTarget(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSetupPhases(t *testing.T) {
+	if err := ValidateSetupPhases(nil); err != nil {
+		t.Errorf("no phases: %v", err)
+	}
+	if err := ValidateSetupPhases([]string{SetupPhaseClone, SetupPhaseMarketplaces}); err != nil {
+		t.Errorf("known phases: %v", err)
+	}
+	if err := ValidateSetupPhases([]string{SetupPhaseClone, "checkout"}); err == nil {
+		t.Error("unknown phase: want an error")
+	}
+}
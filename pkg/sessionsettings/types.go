@@ -146,9 +146,15 @@ func SandboxAllowedDomains(allowed, denied []string) []string {
 // DOCKER_HOST is set so the main container can communicate with the docker daemon.
 type DockerConfig struct {
 	Enabled    bool             `yaml:"enabled"              json:"enabled"`
+	Mode       string           `yaml:"mode,omitempty"       json:"mode,omitempty"` // "dind" (default) or "buildkit"
 	Registries []RegistryConfig `yaml:"registries,omitempty" json:"registries,omitempty"`
 }
 
+// DockerModeBuildKit is the DockerConfig.Mode of sessions running the rootless
+// BuildKit sidecar. Those sessions have no Docker daemon, so registry
+// credentials are written to the docker config file read by buildctl.
+const DockerModeBuildKit = "buildkit"
+
 // RegistryConfig holds authentication configuration for a container registry.
 type RegistryConfig struct {
 	// Server is the registry server address (e.g., "ghcr.io", "registry.example.com")
@@ -193,6 +199,12 @@ type SessionSettings struct {
 	// was introduced.  The provisioner falls back to this field when Files is empty.
 	Credentials       string   `yaml:"credentials,omitempty" json:"credentials,omitempty"`
 	UnsyncedFilePaths []string `yaml:"unsynced_file_paths,omitempty" json:"unsynced_file_paths,omitempty"`
+	// SetupHooks are run by the provisioner in the cloned repository before the
+	// agent starts.
+	SetupHooks []SetupHook `yaml:"setup_hooks,omitempty" json:"setup_hooks,omitempty"`
+	// PostSessionHooks are run by the proxy in the session container before a
+	// oneshot session is deleted.
+	PostSessionHooks []SetupHook `yaml:"post_session_hooks,omitempty" json:"post_session_hooks,omitempty"`
 }
 
 // OtelCollectorConfig holds OpenTelemetry Collector configuration for in-process mode.
@@ -281,6 +293,18 @@ type StartupConfig struct {
 	Command   []string `yaml:"command,omitempty"    json:"command,omitempty"`
 	Args      []string `yaml:"args,omitempty"       json:"args,omitempty"`
 	PreScript string   `yaml:"pre_script,omitempty" json:"pre_script,omitempty"`
+	// Override is set when Command and Args come from an operator startup
+	// template. The provisioner then runs them instead of its built-in command.
+	Override bool `yaml:"override,omitempty" json:"override,omitempty"`
+}
+
+// SetupHook is a setup command run before the agent starts.
+// OnFailure is "fail" (the session fails) or "continue".
+type SetupHook struct {
+	Name           string `yaml:"name,omitempty"            json:"name,omitempty"`
+	Command        string `yaml:"command"                   json:"command"`
+	TimeoutSeconds int    `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+	OnFailure      string `yaml:"on_failure,omitempty"      json:"on_failure,omitempty"`
 }
 
 // GithubConfig holds GitHub authentication configuration reference info.
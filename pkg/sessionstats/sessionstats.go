@@ -0,0 +1,302 @@
+// Package sessionstats keeps aggregate statistics of sessions for the admin
+// dashboard.
+//
+// Counts of the current sessions are taken from a periodic snapshot, while
+// creations, failures and startup times are accumulated from lifecycle
+// events in per-minute buckets. Reading the statistics therefore costs the
+// same however many sessions exist, and clients can poll them cheaply.
+package sessionstats
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// bucketCount is the number of per-minute buckets kept, one day
+	bucketCount = 24 * 60
+	// pendingStartTTL is how long the creation of a session is remembered
+	// while waiting for it to start
+	pendingStartTTL = time.Hour
+	// topUserCount is the number of users in Stats.TopUsers
+	topUserCount = 10
+)
+
+// Session is what the statistics know of a session
+type Session struct {
+	ID        string
+	UserID    string
+	TeamID    string
+	AgentType string
+	Status    string
+}
+
+// bucket accumulates the events of one minute
+type bucket struct {
+	minute  int64
+	created int
+	started int
+	failed  int
+	// timed counts the started sessions whose startup time is known
+	timed        int
+	startupTotal time.Duration
+	createdBy    map[string]int
+}
+
+// Aggregator accumulates session statistics. It is safe for concurrent use.
+type Aggregator struct {
+	list func() []Session
+
+	mu          sync.Mutex
+	buckets     [bucketCount]bucket
+	pending     map[string]time.Time // session → creation, until it starts
+	snapshot    snapshot
+	refreshedAt time.Time
+}
+
+// snapshot counts the current sessions
+type snapshot struct {
+	total       int
+	byStatus    map[string]int
+	byTeam      map[string]int
+	byAgentType map[string]int
+	byUser      map[string]int
+}
+
+// NewAggregator creates an Aggregator counting the sessions returned by list
+func NewAggregator(list func() []Session) *Aggregator {
+	return &Aggregator{list: list, pending: make(map[string]time.Time)}
+}
+
+// Run refreshes the counts of current sessions every interval until ctx is
+// done
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration) {
+	a.Refresh(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.Refresh(now)
+		}
+	}
+}
+
+// Refresh counts the current sessions
+func (a *Aggregator) Refresh(now time.Time) {
+	s := snapshot{
+		byStatus:    make(map[string]int),
+		byTeam:      make(map[string]int),
+		byAgentType: make(map[string]int),
+		byUser:      make(map[string]int),
+	}
+	for _, session := range a.list() {
+		s.total++
+		s.byStatus[session.Status]++
+		if session.TeamID != "" {
+			s.byTeam[session.TeamID]++
+		}
+		agentType := session.AgentType
+		if agentType == "" {
+			agentType = "default"
+		}
+		s.byAgentType[agentType]++
+		if session.UserID != "" {
+			s.byUser[session.UserID]++
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snapshot = s
+	a.refreshedAt = now
+}
+
+// bucketAt returns the bucket of the minute of at, reset if it held an
+// older minute. Must be called with a.mu held.
+func (a *Aggregator) bucketAt(at time.Time) *bucket {
+	minute := at.Unix() / 60
+	b := &a.buckets[minute%bucketCount]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	return b
+}
+
+// Created records that session was created at at
+func (a *Aggregator) Created(session Session, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := a.bucketAt(at)
+	b.created++
+	if session.UserID != "" {
+		if b.createdBy == nil {
+			b.createdBy = make(map[string]int)
+		}
+		b.createdBy[session.UserID]++
+	}
+	a.pending[session.ID] = at
+	for id, created := range a.pending {
+		if at.Sub(created) > pendingStartTTL {
+			delete(a.pending, id)
+		}
+	}
+}
+
+// Started records that the session sessionID became ready at at. The
+// startup time is known when its creation was recorded.
+func (a *Aggregator) Started(sessionID string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := a.bucketAt(at)
+	b.started++
+	if created, ok := a.pending[sessionID]; ok {
+		delete(a.pending, sessionID)
+		if d := at.Sub(created); d >= 0 {
+			b.timed++
+			b.startupTotal += d
+		}
+	}
+}
+
+// Failed records that the session sessionID failed to start or crashed at at
+func (a *Aggregator) Failed(sessionID string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucketAt(at).failed++
+	delete(a.pending, sessionID)
+}
+
+// Window is the activity of a period ending now
+type Window struct {
+	Created int `json:"created"`
+	Started int `json:"started"`
+	Failed  int `json:"failed"`
+	// CreatedPerHour is the average creation rate over the window
+	CreatedPerHour float64 `json:"created_per_hour"`
+	// FailureRate is Failed divided by Created, 0 without creations
+	FailureRate float64 `json:"failure_rate"`
+	// AvgStartupSeconds is the mean time from creation to ready of the
+	// sessions started in the window whose creation was seen
+	AvgStartupSeconds float64 `json:"avg_startup_seconds"`
+}
+
+// UserStats is a user in Stats.TopUsers
+type UserStats struct {
+	UserID string `json:"user_id"`
+	// Sessions is the number of current sessions
+	Sessions int `json:"sessions"`
+	// CreatedLast24h is the number of sessions created in the last day
+	CreatedLast24h int `json:"created_last_24h"`
+}
+
+// Stats are the aggregate statistics of sessions
+type Stats struct {
+	// Sessions is the number of current sessions
+	Sessions    int            `json:"sessions"`
+	ByStatus    map[string]int `json:"by_status"`
+	ByTeam      map[string]int `json:"by_team"`
+	ByAgentType map[string]int `json:"by_agent_type"`
+	LastHour    Window         `json:"last_hour"`
+	Last24h     Window         `json:"last_24h"`
+	// TopUsers are the users with the most current sessions, then the most
+	// sessions created in the last day
+	TopUsers []UserStats `json:"top_users"`
+	// RefreshedAt is when the current sessions were counted
+	RefreshedAt time.Time `json:"refreshed_at"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Stats returns the statistics at now
+func (a *Aggregator) Stats(now time.Time) *Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := &Stats{
+		Sessions:    a.snapshot.total,
+		ByStatus:    copyCounts(a.snapshot.byStatus),
+		ByTeam:      copyCounts(a.snapshot.byTeam),
+		ByAgentType: copyCounts(a.snapshot.byAgentType),
+		RefreshedAt: a.refreshedAt,
+		GeneratedAt: now,
+	}
+	stats.LastHour, _ = a.window(now, 60)
+	var createdBy map[string]int
+	stats.Last24h, createdBy = a.window(now, bucketCount)
+
+	users := make(map[string]*UserStats)
+	user := func(id string) *UserStats {
+		u, ok := users[id]
+		if !ok {
+			u = &UserStats{UserID: id}
+			users[id] = u
+		}
+		return u
+	}
+	for id, n := range a.snapshot.byUser {
+		user(id).Sessions = n
+	}
+	for id, n := range createdBy {
+		user(id).CreatedLast24h = n
+	}
+	stats.TopUsers = make([]UserStats, 0, len(users))
+	for _, u := range users {
+		stats.TopUsers = append(stats.TopUsers, *u)
+	}
+	sort.Slice(stats.TopUsers, func(i, j int) bool {
+		a, b := stats.TopUsers[i], stats.TopUsers[j]
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		if a.CreatedLast24h != b.CreatedLast24h {
+			return a.CreatedLast24h > b.CreatedLast24h
+		}
+		return a.UserID < b.UserID
+	})
+	if len(stats.TopUsers) > topUserCount {
+		stats.TopUsers = stats.TopUsers[:topUserCount]
+	}
+	return stats
+}
+
+// window sums the buckets of the minutes minutes up to now and the sessions
+// created per user in them. Must be called with a.mu held.
+func (a *Aggregator) window(now time.Time, minutes int) (Window, map[string]int) {
+	var w Window
+	var timed int
+	var startupTotal time.Duration
+	createdBy := make(map[string]int)
+	last := now.Unix() / 60
+	for minute := last - int64(minutes) + 1; minute <= last; minute++ {
+		b := &a.buckets[minute%bucketCount]
+		if b.minute != minute {
+			continue
+		}
+		w.Created += b.created
+		w.Started += b.started
+		w.Failed += b.failed
+		timed += b.timed
+		startupTotal += b.startupTotal
+		for id, n := range b.createdBy {
+			createdBy[id] += n
+		}
+	}
+	w.CreatedPerHour = float64(w.Created) / (float64(minutes) / 60)
+	if w.Created > 0 {
+		w.FailureRate = float64(w.Failed) / float64(w.Created)
+	}
+	if timed > 0 {
+		w.AvgStartupSeconds = startupTotal.Seconds() / float64(timed)
+	}
+	return w, createdBy
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	out := make(map[string]int, len(counts))
+	for k, v := range counts {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package sessionstats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregatorCountsCurrentSessions(t *testing.T) {
+	sessions := []Session{
+		{ID: "s1", UserID: "alice", TeamID: "acme/dev", AgentType: "claude", Status: "active"},
+		{ID: "s2", UserID: "alice", TeamID: "acme/dev", Status: "paused"},
+		{ID: "s3", UserID: "bob", AgentType: "goose", Status: "active"},
+	}
+	a := NewAggregator(func() []Session { return sessions })
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	a.Refresh(now)
+
+	stats := a.Stats(now)
+	if stats.Sessions != 3 || stats.ByStatus["active"] != 2 || stats.ByStatus["paused"] != 1 {
+		t.Errorf("sessions = %d, by status = %v", stats.Sessions, stats.ByStatus)
+	}
+	if len(stats.ByTeam) != 1 || stats.ByTeam["acme/dev"] != 2 {
+		t.Errorf("by team = %v", stats.ByTeam)
+	}
+	if stats.ByAgentType["default"] != 1 || stats.ByAgentType["claude"] != 1 || stats.ByAgentType["goose"] != 1 {
+		t.Errorf("by agent type = %v", stats.ByAgentType)
+	}
+	if len(stats.TopUsers) != 2 || stats.TopUsers[0].UserID != "alice" || stats.TopUsers[0].Sessions != 2 {
+		t.Errorf("top users = %+v", stats.TopUsers)
+	}
+	if !stats.RefreshedAt.Equal(now) {
+		t.Errorf("refreshed at = %v", stats.RefreshedAt)
+	}
+
+	// The snapshot is only replaced by the next refresh
+	sessions = sessions[:1]
+	if got := a.Stats(now).Sessions; got != 3 {
+		t.Errorf("sessions before refresh = %d", got)
+	}
+}
+
+func TestAggregatorWindows(t *testing.T) {
+	a := NewAggregator(func() []Session { return nil })
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// Two hours ago: one session created and started in 30s
+	earlier := now.Add(-2 * time.Hour)
+	a.Created(Session{ID: "old", UserID: "carol"}, earlier)
+	a.Started("old", earlier.Add(30*time.Second))
+
+	// Last hour: three sessions, one failed, one started in 10s, one started
+	// without its creation being seen
+	a.Created(Session{ID: "s1", UserID: "alice"}, now.Add(-10*time.Minute))
+	a.Started("s1", now.Add(-10*time.Minute+10*time.Second))
+	a.Created(Session{ID: "s2", UserID: "alice"}, now.Add(-5*time.Minute))
+	a.Failed("s2", now.Add(-4*time.Minute))
+	a.Created(Session{ID: "s3", UserID: "bob"}, now.Add(-time.Minute))
+	a.Started("unknown", now)
+
+	stats := a.Stats(now)
+	hour := stats.LastHour
+	if hour.Created != 3 || hour.Started != 2 || hour.Failed != 1 {
+		t.Errorf("last hour = %+v", hour)
+	}
+	if hour.CreatedPerHour != 3 || hour.FailureRate != 1.0/3 || hour.AvgStartupSeconds != 10 {
+		t.Errorf("last hour rates = %+v", hour)
+	}
+	day := stats.Last24h
+	if day.Created != 4 || day.Started != 3 || day.AvgStartupSeconds != 20 || day.CreatedPerHour != 4.0/24 {
+		t.Errorf("last 24h = %+v", day)
+	}
+	if len(stats.TopUsers) != 3 || stats.TopUsers[0].UserID != "alice" || stats.TopUsers[0].CreatedLast24h != 2 {
+		t.Errorf("top users = %+v", stats.TopUsers)
+	}
+
+	// A day later the buckets have expired
+	later := now.Add(25 * time.Hour)
+	if w := a.Stats(later).Last24h; w.Created != 0 || w.Started != 0 {
+		t.Errorf("last 24h a day later = %+v", w)
+	}
+	// and reused buckets do not carry old counts
+	a.Created(Session{ID: "s4"}, later)
+	if w := a.Stats(later).LastHour; w.Created != 1 || w.Started != 0 {
+		t.Errorf("last hour after reuse = %+v", w)
+	}
+}
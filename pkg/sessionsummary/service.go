@@ -0,0 +1,190 @@
+package sessionsummary
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// summarizeTimeout bounds summarizing one session
+const summarizeTimeout = time.Minute
+
+// Sessions gives a Service access to the sessions it summarizes
+type Sessions interface {
+	ListSessions(filter entities.SessionFilter) []entities.Session
+	GetSession(id string) entities.Session
+	GetMessages(ctx context.Context, id string) ([]portrepos.Message, error)
+	// SetSessionSummary stores the summary of a session and the number of
+	// messages it was generated from
+	SetSessionSummary(ctx context.Context, sessionID, summary string, messageCount int) error
+}
+
+// summarizedSession is a session that remembers what its summary was
+// generated from
+type summarizedSession interface {
+	SummaryMessageCount() int
+}
+
+// periodicStatuses are the statuses of sessions summarized by Run
+var periodicStatuses = map[string]bool{
+	"active":                        true,
+	"running":                       true,
+	entities.SessionStatusCompleted: true,
+}
+
+// completionEvents are the timeline events after which a session is
+// summarized right away
+var completionEvents = map[entities.SessionEventType]bool{
+	entities.SessionEventCompleted:    true,
+	entities.SessionEventJobCompleted: true,
+	entities.SessionEventJobFailed:    true,
+}
+
+// Service keeps the summaries of sessions up to date: sessions are
+// summarized when they complete and, while they run, whenever they have new
+// messages at the next periodic pass
+type Service struct {
+	summarizer Summarizer
+	sessions   Sessions
+
+	mu      sync.Mutex
+	running map[string]bool // sessions being summarized
+}
+
+// NewService creates a new Service
+func NewService(summarizer Summarizer, sessions Sessions) *Service {
+	return &Service{
+		summarizer: summarizer,
+		sessions:   sessions,
+		running:    make(map[string]bool),
+	}
+}
+
+// Run summarizes the sessions with new messages every interval until ctx is
+// cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SummarizeAll(ctx)
+		}
+	}
+}
+
+// SummarizeAll summarizes every running or completed session with new
+// messages since its last summary, one at a time
+func (s *Service) SummarizeAll(ctx context.Context) {
+	summarized := 0
+	for _, session := range s.sessions.ListSessions(entities.SessionFilter{}) {
+		if ctx.Err() != nil {
+			return
+		}
+		if !periodicStatuses[session.Status()] {
+			continue
+		}
+		ok, err := s.SummarizeSession(ctx, session.ID())
+		if err != nil {
+			log.Printf("[SESSION_SUMMARY] Failed to summarize session %s: %v", session.ID(), err)
+			continue
+		}
+		if ok {
+			summarized++
+		}
+	}
+	if summarized > 0 {
+		log.Printf("[SESSION_SUMMARY] Summarized %d session(s)", summarized)
+	}
+}
+
+// SummarizeSession summarizes a session when it has messages its summary
+// was not generated from. It reports whether a new summary was stored.
+func (s *Service) SummarizeSession(ctx context.Context, sessionID string) (bool, error) {
+	if !s.begin(sessionID) {
+		return false, nil
+	}
+	defer s.end(sessionID)
+
+	session := s.sessions.GetSession(sessionID)
+	if session == nil {
+		return false, fmt.Errorf("session not found")
+	}
+	tracked, ok := session.(summarizedSession)
+	if !ok {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+	defer cancel()
+	messages, err := s.sessions.GetMessages(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	// A summary needs at least one answer of the agent
+	if len(messages) < 2 || len(messages) == tracked.SummaryMessageCount() {
+		return false, nil
+	}
+	summary, err := s.summarizer.Summarize(ctx, messages)
+	if err != nil {
+		return false, err
+	}
+	if err := s.sessions.SetSessionSummary(ctx, sessionID, summary, len(messages)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Recorder wraps a session event recorder so that sessions are summarized
+// when they complete
+func (s *Service) Recorder(inner portrepos.EventRecorder) portrepos.EventRecorder {
+	return &summaryRecorder{EventRecorder: inner, service: s}
+}
+
+type summaryRecorder struct {
+	portrepos.EventRecorder
+	service *Service
+}
+
+func (r *summaryRecorder) RecordSessionEvent(ctx context.Context, event entities.SessionEvent) error {
+	r.service.HandleSessionEvent(event)
+	return r.EventRecorder.RecordSessionEvent(ctx, event)
+}
+
+// HandleSessionEvent summarizes a session in the background when it
+// completes. Other events are ignored.
+func (s *Service) HandleSessionEvent(event entities.SessionEvent) {
+	if !completionEvents[event.Type] {
+		return
+	}
+	go func() {
+		if _, err := s.SummarizeSession(context.Background(), event.SessionID); err != nil {
+			log.Printf("[SESSION_SUMMARY] Failed to summarize completed session %s: %v", event.SessionID, err)
+		}
+	}()
+}
+
+// begin marks a session as being summarized. It returns false when it
+// already is.
+func (s *Service) begin(sessionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[sessionID] {
+		return false
+	}
+	s.running[sessionID] = true
+	return true
+}
+
+func (s *Service) end(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, sessionID)
+}
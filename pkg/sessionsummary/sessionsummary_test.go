@@ -0,0 +1,231 @@
+package sessionsummary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// fakeAnthropic answers Messages API requests with reply and counts them
+type fakeAnthropic struct {
+	mu       sync.Mutex
+	reply    string
+	requests []anthropicMessagesRequest
+}
+
+func (a *fakeAnthropic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req anthropicMessagesRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	a.mu.Lock()
+	a.requests = append(a.requests, req)
+	a.mu.Unlock()
+	reply, _ := json.Marshal(a.reply)
+	fmt.Fprintf(w, `{"content":[{"type":"text","text":%s}]}`, reply)
+}
+
+func (a *fakeAnthropic) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.requests)
+}
+
+type fakeSession struct {
+	entities.Session
+	id       string
+	status   string
+	messages int
+}
+
+func (s *fakeSession) ID() string               { return s.id }
+func (s *fakeSession) Status() string           { return s.status }
+func (s *fakeSession) SummaryMessageCount() int { return s.messages }
+
+type fakeSessions struct {
+	mu        sync.Mutex
+	sessions  map[string]*fakeSession
+	messages  map[string][]portrepos.Message
+	summaries map[string]string
+	stored    chan string
+}
+
+func newFakeSessions() *fakeSessions {
+	return &fakeSessions{
+		sessions:  map[string]*fakeSession{},
+		messages:  map[string][]portrepos.Message{},
+		summaries: map[string]string{},
+		stored:    make(chan string, 10),
+	}
+}
+
+func (f *fakeSessions) add(id, status string, contents ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions[id] = &fakeSession{id: id, status: status}
+	for i, content := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "agent"
+		}
+		f.messages[id] = append(f.messages[id], portrepos.Message{Role: role, Content: content})
+	}
+}
+
+func (f *fakeSessions) ListSessions(entities.SessionFilter) []entities.Session {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sessions []entities.Session
+	for _, s := range f.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+func (f *fakeSessions) GetSession(id string) entities.Session {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[id]; ok {
+		return s
+	}
+	return nil
+}
+
+func (f *fakeSessions) GetMessages(_ context.Context, id string) ([]portrepos.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.messages[id], nil
+}
+
+func (f *fakeSessions) SetSessionSummary(_ context.Context, sessionID, summary string, messageCount int) error {
+	f.mu.Lock()
+	f.summaries[sessionID] = summary
+	f.sessions[sessionID].messages = messageCount
+	f.mu.Unlock()
+	f.stored <- sessionID
+	return nil
+}
+
+func (f *fakeSessions) summary(id string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.summaries[id]
+}
+
+func newTestService(t *testing.T, reply string) (*Service, *fakeAnthropic, *fakeSessions) {
+	t.Helper()
+	api := &fakeAnthropic{reply: reply}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	sessions := newFakeSessions()
+	summarizer := NewAnthropicSummarizer(srv.URL+"/", "test-key", "test-model", 5*time.Second)
+	return NewService(summarizer, sessions), api, sessions
+}
+
+func TestSummarizeSession(t *testing.T) {
+	service, api, sessions := newTestService(t, "\"Fix the flaky login test; PR opened\"\nMore details")
+	sessions.add("s1", "active", "Fix the login test", "Done, opened #3")
+
+	ok, err := service.SummarizeSession(context.Background(), "s1")
+	if err != nil || !ok {
+		t.Fatalf("SummarizeSession() = %v, %v", ok, err)
+	}
+	if got := sessions.summary("s1"); got != "Fix the flaky login test; PR opened" {
+		t.Errorf("summary = %q", got)
+	}
+	req := api.requests[0]
+	if req.Model != "test-model" || len(req.Messages) != 1 || !strings.Contains(req.Messages[0].Content, "[agent]\nDone, opened #3") {
+		t.Errorf("model request = %+v", req)
+	}
+
+	// Without new messages the session is not summarized again
+	ok, err = service.SummarizeSession(context.Background(), "s1")
+	if err != nil || ok || api.count() != 1 {
+		t.Errorf("unchanged session summarized again: %v, %v, %d requests", ok, err, api.count())
+	}
+}
+
+func TestSummarizeSessionWithoutAnswer(t *testing.T) {
+	service, api, sessions := newTestService(t, "Something")
+	sessions.add("s1", "active", "Fix the login test")
+
+	ok, err := service.SummarizeSession(context.Background(), "s1")
+	if err != nil || ok || api.count() != 0 {
+		t.Errorf("session without answer summarized: %v, %v", ok, err)
+	}
+	if _, err := service.SummarizeSession(context.Background(), "missing"); err == nil {
+		t.Error("summarizing an unknown session did not fail")
+	}
+}
+
+func TestSummarizeAll(t *testing.T) {
+	service, _, sessions := newTestService(t, "Add retries")
+	sessions.add("running", "active", "Add retries", "Working on it")
+	sessions.add("stopped", "stopped", "Add retries", "Working on it")
+
+	service.SummarizeAll(context.Background())
+	if sessions.summary("running") != "Add retries" {
+		t.Error("the active session was not summarized")
+	}
+	if sessions.summary("stopped") != "" {
+		t.Error("a stopped session was summarized")
+	}
+}
+
+func TestRecorderSummarizesCompletedSessions(t *testing.T) {
+	service, _, sessions := newTestService(t, "Add retries")
+	sessions.add("s1", "completed", "Add retries", "Added")
+
+	service.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventMessageSent})
+	service.HandleSessionEvent(entities.SessionEvent{SessionID: "s1", Type: entities.SessionEventCompleted})
+	select {
+	case id := <-sessions.stored:
+		if id != "s1" || sessions.summary("s1") != "Add retries" {
+			t.Errorf("stored summary of %s = %q", id, sessions.summary("s1"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the completed session was not summarized")
+	}
+}
+
+func TestTranscriptKeepsFirstAndRecentMessages(t *testing.T) {
+	messages := []portrepos.Message{{Role: "user", Content: "The task"}}
+	for i := 0; i < 20; i++ {
+		messages = append(messages, portrepos.Message{Role: "agent", Content: fmt.Sprintf("step %d %s", i, strings.Repeat("x", 2000))})
+	}
+	transcript := Transcript(messages)
+	if !strings.HasPrefix(transcript, "[user]\nThe task") {
+		t.Errorf("the first message was dropped: %.40q", transcript)
+	}
+	if !strings.Contains(transcript, "step 19 ") || strings.Contains(transcript, "step 0 ") {
+		t.Error("the transcript does not keep the most recent messages")
+	}
+	if !strings.Contains(transcript, "messages omitted") || len(transcript) > maxTranscriptLength {
+		t.Errorf("transcript of %d bytes does not mark omitted messages", len(transcript))
+	}
+}
+
+func TestCleanSummary(t *testing.T) {
+	tests := map[string]string{
+		"":                            "",
+		"\n\n  `Add retries`  \nmore": "Add retries",
+		"# **Fix the build**":         "Fix the build",
+		strings.Repeat("a", 300):      strings.Repeat("a", MaxSummaryLength-1) + "…",
+	}
+	for text, want := range tests {
+		if got := CleanSummary(text); got != want {
+			t.Errorf("CleanSummary(%q) = %q, expected %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package sessionsummary generates one-line summaries of what sessions did
+// from their conversation, so that session lists are readable without
+// opening each session.
+package sessionsummary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+const (
+	anthropicVersion        = "2023-06-01"
+	summarizerMaxTokens     = 100
+	maxSummaryResponseBytes = 1 << 20
+
+	// MaxSummaryLength is the length summaries are cut to
+	MaxSummaryLength = 200
+	// maxTranscriptLength bounds the conversation sent to the model. The
+	// first message is kept and the most recent messages fill the rest.
+	maxTranscriptLength = 16000
+	maxMessageLength    = 4000
+)
+
+const summarizerSystemPrompt = `You summarize the conversation between a user and an AI coding agent for a session list.
+Reply with one line of at most 100 characters describing what the session did or is doing, and nothing else.
+Write it like a commit subject: start with a verb, no trailing period, no quotes, no Markdown.
+Mention the outcome when the conversation shows it (e.g. "Fix flaky login test; PR opened").`
+
+// Summarizer turns the conversation of a session into a one-line summary
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []portrepos.Message) (string, error)
+}
+
+// AnthropicSummarizer summarizes sessions with the Anthropic Messages API
+type AnthropicSummarizer struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewAnthropicSummarizer creates a new AnthropicSummarizer
+func NewAnthropicSummarizer(baseURL, apiKey, model string, timeout time.Duration) *AnthropicSummarizer {
+	return &AnthropicSummarizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicMessagesRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessagesResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// Summarize asks the configured model for a one-line summary of messages
+func (s *AnthropicSummarizer) Summarize(ctx context.Context, messages []portrepos.Message) (string, error) {
+	body, err := json.Marshal(anthropicMessagesRequest{
+		Model:     s.model,
+		MaxTokens: summarizerMaxTokens,
+		System:    summarizerSystemPrompt,
+		Messages:  []anthropicMessage{{Role: "user", Content: Transcript(messages)}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode model request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build model request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", s.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("model request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxSummaryResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read model response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("model returned status %d", resp.StatusCode)
+	}
+
+	var msg anthropicMessagesResponse
+	if err := json.Unmarshal(respBody, &msg); err != nil {
+		return "", fmt.Errorf("failed to decode model response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	summary := CleanSummary(text.String())
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// Transcript renders messages as the conversation sent to the model. The
+// first message (the task) is always kept; older messages after it are
+// dropped when the conversation is longer than maxTranscriptLength.
+func Transcript(messages []portrepos.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	render := func(m portrepos.Message) string {
+		return fmt.Sprintf("[%s]\n%s\n\n", m.Role, truncate(strings.TrimSpace(m.Content), maxMessageLength))
+	}
+
+	first := render(messages[0])
+	budget := maxTranscriptLength - len(first)
+	var recent []string
+	for i := len(messages) - 1; i > 0; i-- {
+		part := render(messages[i])
+		if len(part) > budget {
+			break
+		}
+		budget -= len(part)
+		recent = append(recent, part)
+	}
+
+	var b strings.Builder
+	b.WriteString(first)
+	if skipped := len(messages) - 1 - len(recent); skipped > 0 {
+		fmt.Fprintf(&b, "[... %d messages omitted ...]\n\n", skipped)
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		b.WriteString(recent[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// CleanSummary reduces model output to a single line of at most
+// MaxSummaryLength characters without surrounding quotes or Markdown
+func CleanSummary(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "#*_-`\"' ")
+		if line != "" {
+			return truncate(line, MaxSummaryLength)
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -84,9 +84,19 @@ func Materialize(resolved SettingsPatch) (MaterializedSettings, error) {
 		result.EnvVars["CLAUDE_CODE_OAUTH_TOKEN"] = resolved.OAuthToken
 	}
 
-	// 4. MCP servers — serialize the typed map to map[string]interface{}.
-	if len(resolved.MCPServers) > 0 {
-		raw, err := json.Marshal(resolved.MCPServers)
+	// 4. MCP servers — serialize the typed map to map[string]interface{},
+	//    leaving out disabled servers.
+	enabledServers := make(map[string]*MCPServerPatch, len(resolved.MCPServers))
+	for name, server := range resolved.MCPServers {
+		if server == nil || server.IsDisabled() {
+			continue
+		}
+		s := *server
+		s.Enabled = nil
+		enabledServers[name] = &s
+	}
+	if len(enabledServers) > 0 {
+		raw, err := json.Marshal(enabledServers)
 		if err == nil {
 			var servers map[string]interface{}
 			if err := json.Unmarshal(raw, &servers); err == nil {
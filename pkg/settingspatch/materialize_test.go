@@ -166,6 +166,27 @@ func TestMaterialize_MCPServers(t *testing.T) {
 		assert.Contains(t, m.MCPServers, "my-server")
 	})
 
+	t.Run("a disabled server in a higher layer turns the server off", func(t *testing.T) {
+		disabled := false
+		team := SettingsPatch{
+			MCPServers: map[string]*MCPServerPatch{
+				"github": {Type: "http", URL: "http://github-mcp:8080"},
+				"docs":   {Type: "http", URL: "http://docs-mcp:8080"},
+			},
+		}
+		user := SettingsPatch{
+			MCPServers: map[string]*MCPServerPatch{
+				"github": {Type: "http", URL: "http://github-mcp:8080", Enabled: &disabled},
+			},
+		}
+
+		m, err := Materialize(Resolve(team, user))
+		require.NoError(t, err)
+		assert.NotContains(t, m.MCPServers, "github")
+		assert.Contains(t, m.MCPServers, "docs")
+		assert.NotContains(t, m.MCPServers["docs"], "enabled")
+	})
+
 	t.Run("no mcp servers: MCPServers is nil", func(t *testing.T) {
 		resolved := SettingsPatch{}
 
@@ -36,7 +36,8 @@ type SettingsPatch struct {
 	// MCPServers is the MCP server configuration map.
 	// Absent key = inherit from lower layer.
 	// nil value = explicitly delete server.
-	// Non-nil value = override/add server.
+	// Non-nil value = override/add server; a disabled server overrides
+	// the lower layers too but is dropped by Materialize().
 	MCPServers map[string]*MCPServerPatch `json:"mcp_servers,omitempty"`
 
 	// EnvVars are custom environment variables.
@@ -85,6 +86,13 @@ type MCPServerPatch struct {
 	Args    []string          `json:"args,omitempty"`
 	Env     map[string]string `json:"env,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	// Enabled = false keeps the server out of sessions. nil = enabled.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// IsDisabled reports whether the server is explicitly disabled
+func (p *MCPServerPatch) IsDisabled() bool {
+	return p.Enabled != nil && !*p.Enabled
 }
 
 // MarketplacePatch represents a single plugin marketplace configuration.
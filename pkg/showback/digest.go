@@ -0,0 +1,60 @@
+package showback
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Publisher delivers the final statements of a month
+type Publisher func(ctx context.Context, month string, statements []*Statement) error
+
+// Digest delivers the statements of the previous month once it has ended
+type Digest struct {
+	meter   *Meter
+	rates   Rates
+	publish Publisher
+}
+
+// NewDigest creates a Digest pricing usage with rates
+func NewDigest(meter *Meter, rates Rates, publish Publisher) *Digest {
+	return &Digest{meter: meter, rates: rates, publish: publish}
+}
+
+// Run delivers due statements every interval until ctx is done
+func (d *Digest) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.DeliverDue(ctx, time.Now()); err != nil {
+			log.Printf("[SHOWBACK] Failed to deliver statements: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue publishes the statements of the month before now unless they
+// were already delivered. Months without usage are skipped.
+func (d *Digest) DeliverDue(ctx context.Context, now time.Time) error {
+	month := MonthOf(now.UTC().AddDate(0, 0, -now.UTC().Day()))
+	m, err := d.meter.Month(ctx, month)
+	if err != nil {
+		return err
+	}
+	if m.DeliveredAt != nil || len(m.Teams) == 0 {
+		return nil
+	}
+	statements, err := Statements(m, d.rates, now)
+	if err != nil {
+		return err
+	}
+	if err := d.publish(ctx, month, statements); err != nil {
+		return err
+	}
+	log.Printf("[SHOWBACK] Delivered %d statement(s) for %s", len(statements), month)
+	return d.meter.MarkDelivered(ctx, month, now)
+}
@@ -0,0 +1,285 @@
+package showback
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Tokens is the model token usage of a team. InputTokens includes cache
+// tokens.
+type Tokens struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// TokenSource returns the token usage of each team in month. It is backed by
+// the usage ledger, so that statements and budgets count the same tokens.
+type TokenSource func(ctx context.Context, month string) (map[string]Tokens, error)
+
+// Meter accumulates team usage in memory and writes the changed months to a
+// Store on Flush. Token usage is not metered; it is read from the
+// TokenSource whenever a month is read.
+type Meter struct {
+	store  Store
+	tokens TokenSource // optional; nil = no token usage
+
+	mu     sync.Mutex
+	months map[string]*Month
+	dirty  map[string]bool
+}
+
+// NewMeter creates a Meter backed by store
+func NewMeter(store Store) *Meter {
+	return &Meter{store: store, months: make(map[string]*Month), dirty: make(map[string]bool)}
+}
+
+// SetTokenSource configures where the token usage of teams is read from
+func (m *Meter) SetTokenSource(source TokenSource) {
+	m.tokens = source
+}
+
+// AddNotification records a notification delivered for a session of teamID
+func (m *Meter) AddNotification(ctx context.Context, teamID string, at time.Time) error {
+	return m.record(ctx, teamID, at, func(u *TeamUsage) { u.Notifications++ })
+}
+
+// record applies fn to the usage of teamID in the month of at. Usage without
+// a team is dropped.
+func (m *Meter) record(ctx context.Context, teamID string, at time.Time, fn func(*TeamUsage)) error {
+	if teamID == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	month, err := m.loadLocked(ctx, MonthOf(at))
+	if err != nil {
+		return err
+	}
+	fn(month.team(teamID))
+	m.dirty[month.Month] = true
+	return nil
+}
+
+// Month returns a copy of the usage of month, with the token usage of the
+// TokenSource
+func (m *Meter) Month(ctx context.Context, month string) (*Month, error) {
+	out, err := m.copyMonth(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	if m.tokens == nil {
+		return out, nil
+	}
+	tokens, err := m.tokens(ctx, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token usage of %s: %w", month, err)
+	}
+	for _, u := range out.Teams {
+		u.InputTokens, u.OutputTokens = 0, 0
+	}
+	for teamID, t := range tokens {
+		u := out.team(teamID)
+		u.InputTokens, u.OutputTokens = t.InputTokens, t.OutputTokens
+	}
+	return out, nil
+}
+
+func (m *Meter) copyMonth(ctx context.Context, month string) (*Month, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	loaded, err := m.loadLocked(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	out := *loaded
+	out.Teams = make(map[string]*TeamUsage, len(loaded.Teams))
+	for id, u := range loaded.Teams {
+		copied := *u
+		out.Teams[id] = &copied
+	}
+	return &out, nil
+}
+
+// Months lists the metered months, oldest first
+func (m *Meter) Months(ctx context.Context) ([]string, error) {
+	if err := m.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return m.store.Months(ctx)
+}
+
+// MarkDelivered records that the statements of month have been delivered
+func (m *Meter) MarkDelivered(ctx context.Context, month string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	loaded, err := m.loadLocked(ctx, month)
+	if err != nil {
+		return err
+	}
+	at = at.UTC()
+	loaded.DeliveredAt = &at
+	m.dirty[month] = true
+	return m.flushLocked(ctx, at)
+}
+
+// Flush saves the months changed since the previous flush
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flushLocked(ctx, time.Now().UTC())
+}
+
+func (m *Meter) flushLocked(ctx context.Context, now time.Time) error {
+	for month := range m.dirty {
+		loaded := m.months[month]
+		loaded.UpdatedAt = now
+		if err := m.store.Save(ctx, loaded); err != nil {
+			return err
+		}
+		delete(m.dirty, month)
+	}
+	// Keep only the current and the previous month in memory
+	current := MonthOf(now)
+	previous := MonthOf(now.AddDate(0, -1, 0))
+	for month := range m.months {
+		if month != current && month != previous {
+			delete(m.months, month)
+		}
+	}
+	return nil
+}
+
+func (m *Meter) loadLocked(ctx context.Context, month string) (*Month, error) {
+	if loaded, ok := m.months[month]; ok {
+		return loaded, nil
+	}
+	loaded, err := m.store.Load(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	m.months[month] = loaded
+	return loaded, nil
+}
+
+// Session is the part of a session the sampler meters
+type Session struct {
+	ID     string
+	TeamID string
+	// StartedAt is when the session was created
+	StartedAt time.Time
+	// Paused sessions only use storage
+	Paused bool
+	// Storage is true when the session has a workdir volume
+	Storage bool
+	// LLMRequests is the cumulative number of LLM proxy requests of the
+	// session
+	LLMRequests int64
+}
+
+// Sampler meters the running sessions of a Meter at intervals
+type Sampler struct {
+	meter *Meter
+	list  func() []Session
+	// maxGap caps the time metered between two samples, so that the time
+	// the proxy was down is not charged
+	maxGap time.Duration
+
+	mu         sync.Mutex
+	lastSample time.Time
+	seen       map[string]Session
+}
+
+// NewSampler creates a Sampler listing sessions with list. The time metered
+// per sample is capped at maxGap.
+func NewSampler(meter *Meter, list func() []Session, maxGap time.Duration) *Sampler {
+	return &Sampler{meter: meter, list: list, maxGap: maxGap, seen: make(map[string]Session)}
+}
+
+// Run samples and flushes every interval until ctx is done
+func (s *Sampler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sample(ctx, time.Now()); err != nil {
+			log.Printf("[SHOWBACK] Failed to meter sessions: %v", err)
+		}
+		if err := s.meter.Flush(ctx); err != nil {
+			log.Printf("[SHOWBACK] Failed to save usage: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample meters the sessions running at now: their time since the previous
+// sample, their LLM requests since the previous sample and, for sessions
+// started since the previous sample, their creation.
+func (s *Sampler) Sample(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := s.list()
+	current := make(map[string]Session, len(sessions))
+	for _, session := range sessions {
+		if session.TeamID == "" {
+			continue
+		}
+		current[session.ID] = session
+		if err := s.meterLocked(ctx, session, now); err != nil {
+			return err
+		}
+	}
+	s.seen = current
+	s.lastSample = now
+	return nil
+}
+
+// Forget meters the usage of a deleted session since the previous sample
+// and stops tracking it
+func (s *Sampler) Forget(ctx context.Context, session Session, now time.Time) error {
+	if session.TeamID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.meterLocked(ctx, session, now)
+	delete(s.seen, session.ID)
+	return err
+}
+
+func (s *Sampler) meterLocked(ctx context.Context, session Session, now time.Time) error {
+	prev, seen := s.seen[session.ID]
+	from := s.lastSample
+	created := !seen && !s.lastSample.IsZero() && session.StartedAt.After(s.lastSample)
+	if created {
+		from = session.StartedAt
+	}
+	var elapsed time.Duration
+	if (seen || created) && now.After(from) {
+		elapsed = min(now.Sub(from), s.maxGap)
+	}
+	// The LLM proxy counters restart with the proxy
+	requests := session.LLMRequests
+	if seen && requests >= prev.LLMRequests {
+		requests -= prev.LLMRequests
+	}
+
+	return s.meter.record(ctx, session.TeamID, now, func(u *TeamUsage) {
+		if created {
+			u.Sessions++
+		}
+		hours := elapsed.Hours()
+		if !session.Paused {
+			u.SessionHours += hours
+		}
+		if session.Storage {
+			u.StorageHours += hours
+		}
+		u.LLMRequests += requests
+	})
+}
@@ -0,0 +1,99 @@
+package showback
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WritePDF renders st as a single-page PDF. The statement is laid out in a
+// monospaced font so that no font metrics are needed; characters outside
+// ASCII are replaced with '?'.
+func WritePDF(w io.Writer, st *Statement) error {
+	lines := []string{
+		"Showback statement",
+		"",
+		"Team:       " + st.TeamID,
+		fmt.Sprintf("Period:     %s to %s", st.PeriodStart.Format("2006-01-02"), st.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02")),
+		"Generated:  " + st.GeneratedAt.Format("2006-01-02 15:04 MST"),
+	}
+	if !st.Final {
+		lines = append(lines, "Status:     preliminary (month in progress)")
+	}
+	lines = append(lines, "",
+		fmt.Sprintf("%-16s %14s  %-13s %12s %12s", "Item", "Quantity", "Unit", "Unit price", "Amount"),
+		strings.Repeat("-", 71),
+	)
+	for _, l := range st.Lines {
+		lines = append(lines, fmt.Sprintf("%-16s %14s  %-13s %12s %12s",
+			l.Item, formatNumber(l.Quantity, 4), l.Unit, formatNumber(l.UnitPrice, 4), fmt.Sprintf("%.2f", l.Amount)))
+	}
+	lines = append(lines,
+		strings.Repeat("-", 71),
+		fmt.Sprintf("%-58s %12.2f", strings.TrimSpace("Total "+st.Currency), st.Total),
+	)
+
+	var content bytes.Buffer
+	content.WriteString("BT\n/F1 10 Tf\n12 TL\n50 790 Td\n")
+	for i, line := range lines {
+		if i == 0 {
+			content.WriteString("/F1 14 Tf\n")
+		}
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+		if i == 0 {
+			content.WriteString("/F1 10 Tf\n")
+		}
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// formatNumber formats v with up to places decimals, trimming trailing zeros
+func formatNumber(v float64, places int) string {
+	s := fmt.Sprintf("%.*f", places, v)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+// Package showback meters the resources used by team sessions and turns a
+// month of usage into a showback statement per team, as JSON or PDF.
+//
+// Usage is attributed to the team of team-scoped sessions; personal sessions
+// are not metered.
+package showback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MonthLayout is the format of month keys, e.g. "2026-10"
+const MonthLayout = "2006-01"
+
+var monthPattern = regexp.MustCompile(`^\d{4}-\d{2}$`)
+
+// MonthOf returns the UTC month key of t
+func MonthOf(t time.Time) string {
+	return t.UTC().Format(MonthLayout)
+}
+
+// ParseMonth validates a month key and returns the first instant of the month
+func ParseMonth(month string) (time.Time, error) {
+	if !monthPattern.MatchString(month) {
+		return time.Time{}, fmt.Errorf("invalid month %q: must be YYYY-MM", month)
+	}
+	t, err := time.Parse(MonthLayout, month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q: must be YYYY-MM", month)
+	}
+	return t, nil
+}
+
+// TeamUsage is the metered usage of one team in one month
+type TeamUsage struct {
+	TeamID string `json:"team_id"`
+	// Sessions is the number of sessions created
+	Sessions int `json:"sessions"`
+	// SessionHours is the time sessions were running, paused sessions excluded
+	SessionHours float64 `json:"session_hours"`
+	// StorageHours is the time session workdir volumes existed, paused
+	// sessions included
+	StorageHours float64 `json:"storage_hours"`
+	// LLMRequests is the number of model API requests of the sessions
+	// through the LLM proxy
+	LLMRequests int64 `json:"llm_requests"`
+	// InputTokens and OutputTokens are the model token usage of the
+	// sessions, read from the usage ledger. InputTokens includes cache tokens.
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// Notifications is the number of notifications delivered for the
+	// sessions
+	Notifications int `json:"notifications"`
+}
+
+// Month is the usage of every team in one month
+type Month struct {
+	Month     string                `json:"month"`
+	Teams     map[string]*TeamUsage `json:"teams"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	// DeliveredAt is set once the statements of the month have been delivered
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// TeamIDs returns the teams with usage in the month, sorted
+func (m *Month) TeamIDs() []string {
+	ids := make([]string, 0, len(m.Teams))
+	for id := range m.Teams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *Month) team(teamID string) *TeamUsage {
+	if m.Teams == nil {
+		m.Teams = make(map[string]*TeamUsage)
+	}
+	u, ok := m.Teams[teamID]
+	if !ok {
+		u = &TeamUsage{TeamID: teamID}
+		m.Teams[teamID] = u
+	}
+	return u
+}
+
+// Store persists metered months
+type Store interface {
+	// Load returns a month, or an empty one when nothing was recorded
+	Load(ctx context.Context, month string) (*Month, error)
+	Save(ctx context.Context, m *Month) error
+	// Months lists the recorded months, oldest first
+	Months(ctx context.Context) ([]string, error)
+}
+
+// FileStore is a Store that keeps each month in <dir>/usage-<month>.json
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore, creating dir if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create showback directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(month string) string {
+	return filepath.Join(s.dir, "usage-"+month+".json")
+}
+
+// Load returns the stored month, or an empty one
+func (s *FileStore) Load(_ context.Context, month string) (*Month, error) {
+	m := &Month{Month: month, Teams: make(map[string]*TeamUsage)}
+	data, err := os.ReadFile(s.path(month))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage of %s: %w", month, err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse usage of %s: %w", month, err)
+	}
+	return m, nil
+}
+
+// Save replaces the stored month
+func (s *FileStore) Save(_ context.Context, m *Month) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage of %s: %w", m.Month, err)
+	}
+	path := s.path(m.Month)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write usage of %s: %w", m.Month, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write usage of %s: %w", m.Month, err)
+	}
+	return nil
+}
+
+// Months lists the stored months, oldest first
+func (s *FileStore) Months(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	var months []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "usage-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		month := strings.TrimSuffix(strings.TrimPrefix(name, "usage-"), ".json")
+		if monthPattern.MatchString(month) {
+			months = append(months, month)
+		}
+	}
+	sort.Strings(months)
+	return months, nil
+}
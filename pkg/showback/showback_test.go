@@ -0,0 +1,148 @@
+package showback
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSamplerMetersTeamSessions(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	meter := NewMeter(store)
+	t0 := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	sessions := []Session{
+		{ID: "running", TeamID: "acme/dev", StartedAt: t0.Add(-time.Hour), Storage: true},
+		{ID: "personal", StartedAt: t0.Add(-time.Hour), Storage: true},
+	}
+	sampler := NewSampler(meter, func() []Session { return sessions }, time.Hour)
+	ctx := context.Background()
+
+	// The first sample only starts tracking
+	if err := sampler.Sample(ctx, t0); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	sessions = []Session{
+		{ID: "running", TeamID: "acme/dev", StartedAt: t0.Add(-time.Hour), Storage: true, LLMRequests: 2},
+		{ID: "new", TeamID: "acme/dev", StartedAt: t0.Add(30 * time.Minute), Paused: true, Storage: true},
+		{ID: "personal", StartedAt: t0.Add(-time.Hour), Storage: true},
+	}
+	if err := sampler.Sample(ctx, t0.Add(time.Hour)); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	// A deleted session is metered up to its deletion
+	ended := sessions[0]
+	ended.LLMRequests = 3
+	if err := sampler.Forget(ctx, ended, t0.Add(90*time.Minute)); err != nil {
+		t.Fatalf("Forget() error = %v", err)
+	}
+	if err := meter.AddNotification(ctx, "acme/dev", t0); err != nil {
+		t.Fatalf("AddNotification() error = %v", err)
+	}
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// Token usage is read from the token source, not metered
+	reader := NewMeter(store)
+	reader.SetTokenSource(func(_ context.Context, month string) (map[string]Tokens, error) {
+		if month != "2026-10" {
+			t.Errorf("token source month = %q", month)
+		}
+		return map[string]Tokens{"acme/dev": {InputTokens: 1500, OutputTokens: 300}}, nil
+	})
+	month, err := reader.Month(ctx, "2026-10")
+	if err != nil {
+		t.Fatalf("Month() error = %v", err)
+	}
+	if ids := month.TeamIDs(); len(ids) != 1 || ids[0] != "acme/dev" {
+		t.Fatalf("teams = %v, want only acme/dev", ids)
+	}
+	want := TeamUsage{
+		TeamID:        "acme/dev",
+		Sessions:      1,
+		SessionHours:  1.5,
+		StorageHours:  2,
+		LLMRequests:   3,
+		InputTokens:   1500,
+		OutputTokens:  300,
+		Notifications: 1,
+	}
+	if got := *month.Teams["acme/dev"]; got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildStatement(t *testing.T) {
+	usage := TeamUsage{TeamID: "acme/dev", Sessions: 10, SessionHours: 20, StorageHours: 30, InputTokens: 2_000_000, OutputTokens: 500_000, Notifications: 40}
+	rates := Rates{Currency: "USD", SessionHour: 0.5, StorageHour: 0.01, InputTokens: 3, OutputTokens: 15}
+
+	st, err := BuildStatement("2026-09", usage, rates, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("BuildStatement() error = %v", err)
+	}
+	// 20*0.5 + 30*0.01 + 2*3 + 0.5*15
+	if st.Total != 23.8 || !st.Final || len(st.Lines) != 6 {
+		t.Errorf("statement = %+v, want a final total of 23.8 over 6 lines", st)
+	}
+	if _, err := BuildStatement("2026-9", usage, rates, time.Now()); err == nil {
+		t.Error("expected an invalid month to be rejected")
+	}
+
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, st); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	pdf := buf.String()
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4")) || !bytes.HasSuffix(buf.Bytes(), []byte("%%EOF\n")) {
+		t.Errorf("output is not a PDF document")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("(Team:       acme/dev)")) || !bytes.Contains(buf.Bytes(), []byte("23.80")) {
+		t.Errorf("PDF does not contain the statement:\n%s", pdf)
+	}
+}
+
+func TestDigestDeliversPreviousMonthOnce(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	meter := NewMeter(store)
+	ctx := context.Background()
+	if err := meter.AddNotification(ctx, "acme/dev", time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("AddNotification() error = %v", err)
+	}
+
+	var published []string
+	digest := NewDigest(meter, Rates{Currency: "USD"}, func(_ context.Context, month string, statements []*Statement) error {
+		for _, st := range statements {
+			if !st.Final {
+				t.Errorf("statement of %s is not final", st.TeamID)
+			}
+			published = append(published, month+" "+st.TeamID)
+		}
+		return nil
+	})
+	for _, now := range []time.Time{
+		time.Date(2026, 10, 1, 1, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC),
+	} {
+		if err := digest.DeliverDue(ctx, now); err != nil {
+			t.Fatalf("DeliverDue() error = %v", err)
+		}
+	}
+	if len(published) != 1 || published[0] != "2026-09 acme/dev" {
+		t.Errorf("published = %v, want the September statement of acme/dev once", published)
+	}
+
+	month, err := NewMeter(store).Month(ctx, "2026-09")
+	if err != nil {
+		t.Fatalf("Month() error = %v", err)
+	}
+	if month.DeliveredAt == nil {
+		t.Error("expected the delivery to be saved")
+	}
+}
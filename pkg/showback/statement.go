@@ -0,0 +1,106 @@
+package showback
+
+import (
+	"math"
+	"time"
+)
+
+// Rates are the unit prices of a statement. A zero rate lists the usage
+// without charging it.
+type Rates struct {
+	Currency string `json:"currency"`
+	// Session is charged per created session
+	Session     float64 `json:"session"`
+	SessionHour float64 `json:"session_hour"`
+	StorageHour float64 `json:"storage_hour"`
+	// InputTokens and OutputTokens are charged per million tokens
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+	Notification float64 `json:"notification"`
+}
+
+// Line is one item of a statement
+type Line struct {
+	Item      string  `json:"item"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit"`
+	UnitPrice float64 `json:"unit_price"`
+	Amount    float64 `json:"amount"`
+}
+
+// Statement is the showback statement of one team for one month
+type Statement struct {
+	TeamID      string    `json:"team_id"`
+	Month       string    `json:"month"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	// Final is false while the month is still in progress
+	Final       bool      `json:"final"`
+	Currency    string    `json:"currency"`
+	Usage       TeamUsage `json:"usage"`
+	Lines       []Line    `json:"lines"`
+	Total       float64   `json:"total"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// BuildStatement prices the usage of a team in month. The statement is
+// final once now is past the end of the month.
+func BuildStatement(month string, usage TeamUsage, rates Rates, now time.Time) (*Statement, error) {
+	start, err := ParseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 1, 0)
+	st := &Statement{
+		TeamID:      usage.TeamID,
+		Month:       month,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Final:       !now.Before(end),
+		Currency:    rates.Currency,
+		Usage:       usage,
+		GeneratedAt: now.UTC(),
+	}
+	add := func(item string, quantity float64, unit string, unitPrice float64) {
+		line := Line{Item: item, Quantity: round(quantity, 4), Unit: unit, UnitPrice: unitPrice}
+		line.Amount = round(quantity*unitPrice, 2)
+		st.Lines = append(st.Lines, line)
+		st.Total += line.Amount
+	}
+	add("Sessions", float64(usage.Sessions), "session", rates.Session)
+	add("Session hours", usage.SessionHours, "hour", rates.SessionHour)
+	add("Storage hours", usage.StorageHours, "hour", rates.StorageHour)
+	add("Input tokens", float64(usage.InputTokens)/1e6, "1M tokens", rates.InputTokens)
+	add("Output tokens", float64(usage.OutputTokens)/1e6, "1M tokens", rates.OutputTokens)
+	add("Notifications", float64(usage.Notifications), "notification", rates.Notification)
+	st.Total = round(st.Total, 2)
+	return st, nil
+}
+
+// Statements builds the statement of every team with usage in m, sorted by
+// team
+func Statements(m *Month, rates Rates, now time.Time) ([]*Statement, error) {
+	statements := make([]*Statement, 0, len(m.Teams))
+	for _, id := range m.TeamIDs() {
+		st, err := BuildStatement(m.Month, *m.Teams[id], rates, now)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, st)
+	}
+	return statements, nil
+}
+
+// Total sums the totals of statements
+func Total(statements []*Statement) float64 {
+	var total float64
+	for _, st := range statements {
+		total += st.Total
+	}
+	return round(total, 2)
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
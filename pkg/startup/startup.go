@@ -381,20 +381,15 @@ func GenerateGitHubAppTokenForRepository(appIDStr, installationIDStr, pemPath, r
 	}
 
 	// Set base URL if specified (for GitHub Enterprise)
-	if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" && githubAPI != "https://api.github.com" {
-		transport.BaseURL = githubAPI
+	instance := github_pkg.EnvInstance()
+	if instance.IsEnterprise() {
+		transport.BaseURL = instance.APIURL
 	}
 
 	// Create GitHub client
-	client := github.NewClient(&http.Client{Transport: transport})
-
-	// Handle GitHub Enterprise
-	if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" {
-		var err error
-		client, err = github.NewClient(&http.Client{Transport: transport}).WithEnterpriseURLs(githubAPI, githubAPI)
-		if err != nil {
-			return "", fmt.Errorf("failed to create GitHub Enterprise client: %w", err)
-		}
+	client, err := instance.NewClient(&http.Client{Transport: transport})
+	if err != nil {
+		return "", err
 	}
 
 	// Create installation token
@@ -457,10 +452,7 @@ func AutoDiscoverInstallationID(appIDStr, pemPath, repoFullName string) (string,
 	}
 
 	// Get API base URL
-	apiBase := os.Getenv("GITHUB_API")
-	if apiBase == "" {
-		apiBase = "https://api.github.com"
-	}
+	apiBase := github_pkg.GetAPIBase()
 
 	ctx := context.Background()
 	installationID, err := installationCache.GetInstallationID(ctx, appID, pemData, repoFullName, apiBase)
@@ -503,10 +495,8 @@ func setupRepository(repoURL, token, cloneDir string) error {
 		env = append(env, fmt.Sprintf("GITHUB_TOKEN=%s", token))
 
 		// Set GitHub Enterprise host if applicable
-		if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" && githubAPI != "https://api.github.com" {
-			githubHost := strings.TrimPrefix(githubAPI, "https://")
-			githubHost = strings.TrimPrefix(githubHost, "http://")
-			githubHost = strings.TrimSuffix(githubHost, "/api/v3")
+		if instance := github_pkg.EnvInstance(); instance.IsEnterprise() {
+			githubHost := instance.Host()
 			env = append(env, fmt.Sprintf("GH_HOST=%s", githubHost))
 
 			// Authenticate gh CLI for GitHub Enterprise Server
@@ -564,6 +554,17 @@ func AuthenticateGHCLI(githubHost, token string, env []string) error {
 	return nil
 }
 
+// RefreshGitHubCLIToken replaces the token stored by gh CLI, which git also
+// uses through the credential helper set up by gh auth setup-git. githubAPI
+// selects the GitHub Enterprise Server host; empty means github.com.
+func RefreshGitHubCLIToken(githubAPI, token string, env []string) error {
+	instance, err := github_pkg.NewInstance("", githubAPI)
+	if err != nil {
+		return err
+	}
+	return performGHAuthLogin(instance.Host(), token, env)
+}
+
 // extractRepoName extracts owner/repo from various GitHub URL formats
 func extractRepoName(repoURL string) (string, error) {
 	// Handle various URL formats
@@ -589,14 +590,7 @@ func extractRepoName(repoURL string) (string, error) {
 
 // getGitHubURL returns the GitHub URL (supports enterprise)
 func getGitHubURL() string {
-	if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" {
-		// For enterprise GitHub, convert API URL to web URL
-		if strings.HasSuffix(githubAPI, "/api/v3") {
-			return strings.TrimSuffix(githubAPI, "/api/v3")
-		}
-		return githubAPI
-	}
-	return "https://github.com"
+	return github_pkg.GetGitHubURL()
 }
 
 // SetupGitHubAuth sets up GitHub authentication using gh CLI
@@ -729,13 +723,7 @@ func normalizeGitHubAuthStep(step string) string {
 }
 
 func getGitHubAuthHost() string {
-	githubHost := "github.com"
-	if githubAPI := os.Getenv("GITHUB_API"); githubAPI != "" && githubAPI != "https://api.github.com" {
-		githubHost = strings.TrimPrefix(githubAPI, "https://")
-		githubHost = strings.TrimPrefix(githubHost, "http://")
-		githubHost = strings.TrimSuffix(githubHost, "/api/v3")
-	}
-	return githubHost
+	return github_pkg.EnvInstance().Host()
 }
 
 // performGHAuthLogin performs gh auth login
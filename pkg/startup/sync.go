@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,6 +22,29 @@ type SyncOptions struct {
 	NotificationSubscriptions string // Path to notification subscriptions directory (optional)
 	NotificationsDir          string // Path to notifications output directory (optional)
 	RegisterMarketplaces      bool   // Register cloned marketplaces using claude CLI
+	ExtensionReportPath       string // Path to write the marketplace/plugin registration report to (optional)
+}
+
+// ExtensionReport records which marketplaces and plugins the sync registered
+// for the agent, and which failed.
+type ExtensionReport struct {
+	Marketplaces []ExtensionStatus `json:"marketplaces"`
+	Plugins      []ExtensionStatus `json:"plugins"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// ExtensionStatus is the registration result of one marketplace or plugin.
+type ExtensionStatus struct {
+	// Name is the marketplace name, or plugin@marketplace for plugins.
+	Name string `json:"name"`
+	// Alias is the configured marketplace key when it differs from Name.
+	Alias string `json:"alias,omitempty"`
+	// Source is the marketplace URL or repository.
+	Source string `json:"source,omitempty"`
+	// Version is the version from marketplace.json, or the cloned commit.
+	Version    string `json:"version,omitempty"`
+	Registered bool   `json:"registered"`
+	Error      string `json:"error,omitempty"`
 }
 
 // settingsJSON represents the structure of settings.json from Settings Secret
@@ -62,7 +86,14 @@ type marketplaceJSON struct {
 
 // marketplacePluginJSON represents .claude-plugin/marketplace.json in a marketplace repository
 type marketplacePluginJSON struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	Metadata struct {
+		Version string `json:"version"`
+	} `json:"metadata"`
+	Plugins []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"plugins"`
 }
 
 // Sync synchronizes settings from Settings Secret to Claude configuration files.
@@ -77,9 +108,15 @@ func Sync(opts SyncOptions) error {
 		settings = nil
 	}
 
-	if err := syncMarketplaces(opts, settings); err != nil {
+	report, err := syncMarketplaces(opts, settings)
+	if err != nil {
 		return fmt.Errorf("failed to sync marketplaces: %w", err)
 	}
+	if opts.ExtensionReportPath != "" {
+		if err := writeExtensionReport(opts.ExtensionReportPath, report); err != nil {
+			log.Printf("[SYNC] Warning: failed to write extension report: %v", err)
+		}
+	}
 
 	if opts.CredentialsFile != "" {
 		if err := syncCredentials(opts.CredentialsFile, opts.OutputDir); err != nil {
@@ -148,13 +185,19 @@ func generateClaudeJSON(outputDir string) error {
 
 // syncMarketplaces clones custom marketplace repositories and registers marketplaces
 // and plugins via claude CLI. settings.json is assumed to already be written by
-// the compile step; this function does NOT overwrite it.
-func syncMarketplaces(opts SyncOptions, settings *settingsJSON) error {
+// the compile step; this function does NOT overwrite it. Failures of single
+// marketplaces and plugins are not errors; they are recorded in the report.
+func syncMarketplaces(opts SyncOptions, settings *settingsJSON) (*ExtensionReport, error) {
 	claudeDir := filepath.Join(opts.OutputDir, ".claude")
 	marketplacesDir := filepath.Join(claudeDir, "plugins", "marketplaces")
 	if err := os.MkdirAll(marketplacesDir, 0755); err != nil {
-		return fmt.Errorf("failed to create marketplaces directory: %w", err)
+		return nil, fmt.Errorf("failed to create marketplaces directory: %w", err)
+	}
+	report := &ExtensionReport{
+		Marketplaces: []ExtensionStatus{},
+		Plugins:      []ExtensionStatus{},
 	}
+	defer func() { report.UpdatedAt = time.Now().UTC() }()
 
 	nameMapping := make(map[string]string)
 
@@ -181,17 +224,33 @@ func syncMarketplaces(opts SyncOptions, settings *settingsJSON) error {
 	}
 
 	// Clone custom marketplace repositories
-	for aliasKey, marketplace := range mergedMarketplaces {
+	cloned := make(map[string]ExtensionStatus) // by real name
+	aliases := make([]string, 0, len(mergedMarketplaces))
+	for aliasKey := range mergedMarketplaces {
+		aliases = append(aliases, aliasKey)
+	}
+	sort.Strings(aliases)
+	for _, aliasKey := range aliases {
+		marketplace := mergedMarketplaces[aliasKey]
 		if marketplace.URL == "" {
 			log.Printf("[SYNC] Skipping marketplace %s: no URL configured", aliasKey)
 			continue
 		}
+		failed := func(err error) {
+			report.Marketplaces = append(report.Marketplaces, ExtensionStatus{Name: aliasKey, Source: marketplace.URL, Error: err.Error()})
+		}
 
 		tempDir := filepath.Join(marketplacesDir, ".tmp-"+aliasKey)
 		log.Printf("[SYNC] Cloning marketplace %s from %s", aliasKey, marketplace.URL)
 
+		// A previous interrupted run may have left the temp dir behind
+		if err := RemovePartialClone(tempDir); err != nil {
+			log.Printf("[SYNC] Warning: %v", err)
+			continue
+		}
 		if err := cloneMarketplace(marketplace.URL, tempDir); err != nil {
 			log.Printf("[SYNC] Warning: failed to clone marketplace %s: %v", aliasKey, err)
+			failed(err)
 			continue
 		}
 
@@ -199,6 +258,7 @@ func syncMarketplaces(opts SyncOptions, settings *settingsJSON) error {
 		if err != nil {
 			log.Printf("[SYNC] Error: failed to read marketplace name for %s: %v", aliasKey, err)
 			removeTempDir(tempDir)
+			failed(err)
 			continue
 		}
 
@@ -206,31 +266,64 @@ func syncMarketplaces(opts SyncOptions, settings *settingsJSON) error {
 		if err := os.Rename(tempDir, targetDir); err != nil {
 			log.Printf("[SYNC] Error: failed to rename marketplace dir from %s to %s: %v", tempDir, targetDir, err)
 			removeTempDir(tempDir)
+			failed(err)
 			continue
 		}
 
 		nameMapping[aliasKey] = realName
+		status := ExtensionStatus{Name: realName, Source: marketplace.URL, Version: marketplaceVersion(targetDir)}
+		if aliasKey != realName {
+			status.Alias = aliasKey
+		}
+		cloned[realName] = status
 		log.Printf("[SYNC] Cloned marketplace %s (alias: %s) at %s", realName, aliasKey, targetDir)
 	}
 
 	if !opts.RegisterMarketplaces {
-		return nil
+		for _, name := range sortedKeys(cloned) {
+			report.Marketplaces = append(report.Marketplaces, cloned[name])
+		}
+		return report, nil
 	}
 
+	official := ExtensionStatus{Name: officialMarketplace, Source: officialMarketplace, Registered: true}
 	if err := registerOfficialMarketplace(opts.OutputDir); err != nil {
 		log.Printf("[SYNC] Warning: failed to register official marketplace: %v", err)
+		official.Registered, official.Error = false, err.Error()
 	}
+	report.Marketplaces = append(report.Marketplaces, official)
 
-	if err := registerMarketplaces(opts.OutputDir, marketplacesDir); err != nil {
+	registered, err := registerMarketplaces(opts.OutputDir, marketplacesDir)
+	if err != nil {
 		log.Printf("[SYNC] Warning: failed to register marketplaces: %v", err)
 	}
+	for _, name := range sortedKeys(registered) {
+		status, ok := cloned[name]
+		if !ok {
+			status = ExtensionStatus{Name: name, Version: marketplaceVersion(filepath.Join(marketplacesDir, name))}
+		}
+		if regErr := registered[name]; regErr != nil {
+			status.Error = regErr.Error()
+		} else {
+			status.Registered = true
+		}
+		delete(cloned, name)
+		report.Marketplaces = append(report.Marketplaces, status)
+	}
+	// Cloned marketplaces that registration did not see
+	for _, name := range sortedKeys(cloned) {
+		report.Marketplaces = append(report.Marketplaces, cloned[name])
+	}
 
 	// Install enabled plugins from the compile-generated settings.json
 	for _, plugin := range compileSettings.EnabledPlugins {
 		resolvedPlugin := resolvePluginName(plugin, nameMapping)
+		status := ExtensionStatus{Name: resolvedPlugin, Version: pluginVersion(marketplacesDir, resolvedPlugin), Registered: true}
 		if err := installPlugin(opts.OutputDir, resolvedPlugin); err != nil {
 			log.Printf("[SYNC] Warning: failed to install plugin %s: %v", resolvedPlugin, err)
+			status.Registered, status.Error = false, err.Error()
 		}
+		report.Plugins = append(report.Plugins, status)
 	}
 
 	// Aggregate SKILL.md files from installed marketplaces into ~/.codex/instructions.md
@@ -239,9 +332,81 @@ func syncMarketplaces(opts SyncOptions, settings *settingsJSON) error {
 		log.Printf("[SYNC] Warning: failed to sync Codex skills: %v", err)
 	}
 
+	return report, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// marketplaceVersion returns the metadata version of the marketplace in dir,
+// falling back to the short commit of its clone.
+func marketplaceVersion(dir string) string {
+	if mp, err := readMarketplaceJSON(dir); err == nil && mp.Metadata.Version != "" {
+		return mp.Metadata.Version
+	}
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pluginVersion returns the version a marketplace lists for a
+// plugin@marketplace identifier, or "" when it is not known.
+func pluginVersion(marketplacesDir, pluginIdentifier string) string {
+	pluginName, marketplace, ok := strings.Cut(pluginIdentifier, "@")
+	if !ok {
+		return ""
+	}
+	mp, err := readMarketplaceJSON(filepath.Join(marketplacesDir, marketplace))
+	if err != nil {
+		return ""
+	}
+	for _, p := range mp.Plugins {
+		if p.Name == pluginName {
+			return p.Version
+		}
+	}
+	return ""
+}
+
+// writeExtensionReport writes report as JSON to path.
+func writeExtensionReport(path string, report *ExtensionReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal extension report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create extension report directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write extension report: %w", err)
+	}
+	log.Printf("[SYNC] Wrote extension report to %s", path)
 	return nil
 }
 
+// ReadExtensionReport reads a report written by Sync.
+func ReadExtensionReport(path string) (*ExtensionReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report ExtensionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse extension report: %w", err)
+	}
+	return &report, nil
+}
+
 // removeTempDir removes a temporary directory, logging a warning on failure.
 func removeTempDir(dir string) {
 	if err := os.RemoveAll(dir); err != nil {
@@ -271,18 +436,15 @@ func readClaudeSettingsJSON(settingsPath string) (*claudeSettingsJSON, error) {
 	return &content, nil
 }
 
-// getGHESHost returns the GitHub Enterprise Server hostname extracted from
-// the GITHUB_API environment variable. Returns an empty string when running
-// against github.com (i.e. GITHUB_API is unset or equals "https://api.github.com").
+// getGHESHost returns the GitHub Enterprise Server hostname of the GITHUB_URL
+// and GITHUB_API environment variables. Returns an empty string when running
+// against github.com (i.e. both are unset or GITHUB_API equals "https://api.github.com").
 func getGHESHost() string {
-	githubAPI := os.Getenv("GITHUB_API")
-	if githubAPI == "" || githubAPI == "https://api.github.com" {
+	instance := github_pkg.EnvInstance()
+	if !instance.IsEnterprise() {
 		return ""
 	}
-	host := strings.TrimPrefix(githubAPI, "https://")
-	host = strings.TrimPrefix(host, "http://")
-	host = strings.TrimSuffix(host, "/api/v3")
-	return host
+	return instance.Host()
 }
 
 // buildGitHubEnvForHost builds environment variables for GitHub CLI/git commands.
@@ -366,6 +528,23 @@ func cloneMarketplace(url, targetDir string) error {
 	return nil
 }
 
+// RemovePartialClone removes dir when it exists without a .git directory,
+// which is what an interrupted clone leaves behind. Removing it lets a re-run
+// of the setup clone again instead of failing on the non-empty directory.
+func RemovePartialClone(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return nil
+	}
+	log.Printf("[SYNC] Removing partial clone at %s", dir)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove partial clone %s: %w", dir, err)
+	}
+	return nil
+}
+
 // cloneGitHubDotComMarketplace clones a marketplace from github.com in a GHES
 // environment without setting GH_HOST to the enterprise server.
 //
@@ -406,23 +585,32 @@ func cloneGitHubDotComMarketplace(repoFullName, targetDir string) error {
 
 // readMarketplaceName reads the real marketplace name from .claude-plugin/marketplace.json
 func readMarketplaceName(targetDir string) (string, error) {
+	mp, err := readMarketplaceJSON(targetDir)
+	if err != nil {
+		return "", err
+	}
+
+	if mp.Name == "" {
+		return "", fmt.Errorf("marketplace.json has empty name field")
+	}
+
+	return mp.Name, nil
+}
+
+// readMarketplaceJSON reads .claude-plugin/marketplace.json of a marketplace repository
+func readMarketplaceJSON(targetDir string) (*marketplacePluginJSON, error) {
 	jsonPath := filepath.Join(targetDir, ".claude-plugin", "marketplace.json")
 
 	data, err := os.ReadFile(jsonPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read marketplace.json: %w", err)
+		return nil, fmt.Errorf("failed to read marketplace.json: %w", err)
 	}
 
 	var mp marketplacePluginJSON
 	if err := json.Unmarshal(data, &mp); err != nil {
-		return "", fmt.Errorf("failed to parse marketplace.json: %w", err)
+		return nil, fmt.Errorf("failed to parse marketplace.json: %w", err)
 	}
-
-	if mp.Name == "" {
-		return "", fmt.Errorf("marketplace.json has empty name field")
-	}
-
-	return mp.Name, nil
+	return &mp, nil
 }
 
 // resolvePluginName replaces alias marketplace name with real name in plugin identifier
@@ -473,13 +661,16 @@ func registerOfficialMarketplace(outputDir string) error {
 	return nil
 }
 
-// registerMarketplaces uses claude CLI to register cloned marketplaces
-func registerMarketplaces(outputDir string, marketplacesDir string) error {
+// registerMarketplaces uses claude CLI to register cloned marketplaces.
+// It returns the registration error of each marketplace directory, nil for
+// the registered ones.
+func registerMarketplaces(outputDir string, marketplacesDir string) (map[string]error, error) {
 	entries, err := os.ReadDir(marketplacesDir)
 	if err != nil {
-		return fmt.Errorf("failed to read marketplaces directory: %w", err)
+		return nil, fmt.Errorf("failed to read marketplaces directory: %w", err)
 	}
 
+	results := make(map[string]error)
 	for _, entry := range entries {
 		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
 			continue
@@ -489,11 +680,13 @@ func registerMarketplaces(outputDir string, marketplacesDir string) error {
 		log.Printf("[SYNC] Registering marketplace at %s", marketplacePath)
 		if err := runClaudeCLI(outputDir, "plugin", "marketplace", "add", marketplacePath); err != nil {
 			log.Printf("[SYNC] Warning: failed to register marketplace at %s: %v", marketplacePath, err)
+			results[entry.Name()] = err
 			continue
 		}
+		results[entry.Name()] = nil
 		log.Printf("[SYNC] Successfully registered marketplace at %s", marketplacePath)
 	}
-	return nil
+	return results, nil
 }
 
 // installPlugin installs a plugin using claude CLI.
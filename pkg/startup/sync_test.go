@@ -646,7 +646,7 @@ func TestSyncMarketplaces(t *testing.T) {
 			OutputDir: outputDir,
 		}
 
-		err := syncMarketplaces(opts, nil)
+		_, err := syncMarketplaces(opts, nil)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -680,7 +680,7 @@ func TestSyncMarketplaces(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -706,7 +706,7 @@ func TestSyncMarketplaces(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -741,7 +741,7 @@ func TestSyncMarketplaces(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -779,7 +779,7 @@ func TestSyncMarketplaces(t *testing.T) {
 		opts := SyncOptions{OutputDir: outputDir}
 
 		// Should not fail even though some marketplaces fail
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -814,7 +814,7 @@ func TestSyncMarketplaces(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -832,7 +832,7 @@ func TestSyncMarketplaces(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, nil)
+		_, err := syncMarketplaces(opts, nil)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1181,7 +1181,7 @@ func TestSyncMarketplaces_EnabledPlugins(t *testing.T) {
 			OutputDir: outputDir,
 		}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1207,7 +1207,7 @@ func TestSyncMarketplaces_EnabledPlugins(t *testing.T) {
 		}
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1266,7 +1266,7 @@ func TestSyncMarketplaces_EnabledPlugins(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1498,7 +1498,7 @@ func TestSyncMarketplaces_WithRealMarketplaceName(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1542,7 +1542,7 @@ func TestSyncMarketplaces_WithRealMarketplaceName(t *testing.T) {
 		opts := SyncOptions{OutputDir: outputDir}
 
 		// Should not fail, but marketplace should be skipped (no marketplace.json)
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1589,7 +1589,7 @@ func TestSyncMarketplaces_WithRealMarketplaceName(t *testing.T) {
 
 		opts := SyncOptions{OutputDir: outputDir}
 
-		err := syncMarketplaces(opts, settings)
+		_, err := syncMarketplaces(opts, settings)
 		if err != nil {
 			t.Fatalf("syncMarketplaces failed: %v", err)
 		}
@@ -1783,3 +1783,84 @@ func TestCloneMarketplace_GHESWithLocalURL(t *testing.T) {
 		}
 	})
 }
+
+func TestRemovePartialClone(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	partial := filepath.Join(tmpDir, "partial")
+	if err := os.MkdirAll(partial, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := RemovePartialClone(partial); err != nil {
+		t.Fatalf("RemovePartialClone failed: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("Expected partial clone to be removed, got %v", err)
+	}
+
+	complete := filepath.Join(tmpDir, "complete")
+	if err := os.MkdirAll(filepath.Join(complete, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := RemovePartialClone(complete); err != nil {
+		t.Fatalf("RemovePartialClone failed: %v", err)
+	}
+	if _, err := os.Stat(complete); err != nil {
+		t.Errorf("Expected clone to be kept, got %v", err)
+	}
+
+	if err := RemovePartialClone(filepath.Join(tmpDir, "missing")); err != nil {
+		t.Errorf("RemovePartialClone on a missing dir failed: %v", err)
+	}
+}
+
+func TestSyncExtensionReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "output")
+
+	goodDir := filepath.Join(tmpDir, "good-source")
+	createTestGitRepo(t, goodDir, map[string]string{
+		".claude-plugin/marketplace.json": `{"name": "team-tools", "metadata": {"version": "1.2.0"}, "plugins": [{"name": "lint", "version": "0.3.1"}]}`,
+	})
+	badDir := filepath.Join(tmpDir, "bad-source")
+	createTestGitRepo(t, badDir, map[string]string{"README.md": "# No marketplace.json"})
+
+	settingsFile := filepath.Join(tmpDir, "settings.json")
+	settingsData, _ := json.Marshal(settingsJSON{
+		Name: "test-user",
+		Marketplaces: map[string]*marketplaceJSON{
+			"tools":  {URL: goodDir},
+			"broken": {URL: badDir},
+		},
+	})
+	if err := os.WriteFile(settingsFile, settingsData, 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+
+	reportPath := filepath.Join(outputDir, ".session", "extensions.json")
+	if err := Sync(SyncOptions{SettingsFile: settingsFile, OutputDir: outputDir, ExtensionReportPath: reportPath}); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	report, err := ReadExtensionReport(reportPath)
+	if err != nil {
+		t.Fatalf("ReadExtensionReport failed: %v", err)
+	}
+	if len(report.Marketplaces) != 2 {
+		t.Fatalf("Expected 2 marketplaces, got %+v", report.Marketplaces)
+	}
+	broken, tools := report.Marketplaces[0], report.Marketplaces[1]
+	if broken.Name != "broken" || broken.Registered || !strings.Contains(broken.Error, "marketplace.json") {
+		t.Errorf("Expected a failure for the broken marketplace, got %+v", broken)
+	}
+	if tools.Name != "team-tools" || tools.Alias != "tools" || tools.Version != "1.2.0" || tools.Error != "" {
+		t.Errorf("Unexpected status for the cloned marketplace: %+v", tools)
+	}
+	if report.UpdatedAt.IsZero() {
+		t.Error("Expected updated_at to be set")
+	}
+
+	if got := pluginVersion(filepath.Join(outputDir, ".claude", "plugins", "marketplaces"), "lint@team-tools"); got != "0.3.1" {
+		t.Errorf("pluginVersion = %q, want 0.3.1", got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package termrec records terminal sessions in the asciicast v2 format
+// (https://docs.asciinema.org/manual/asciicast/v2/) by tapping the WebSocket
+// traffic of a ttyd web terminal.
+package termrec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of asciicast v2 recordings.
+const ContentType = "application/x-asciicast"
+
+// Default terminal size used when the client never reports one.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// Header is the first line of an asciicast v2 recording.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes asciicast v2 events to an io.Writer. The header is written
+// lazily with the first event so that the initial terminal size reported by
+// the client ends up in the header instead of a resize event. A Recorder is
+// safe for concurrent use.
+type Recorder struct {
+	mu            sync.Mutex
+	w             io.Writer
+	header        Header
+	start         time.Time
+	headerWritten bool
+	closed        bool
+	err           error
+	now           func() time.Time
+	// Incomplete UTF-8 sequences held back until the rest arrives.
+	pendingOutput []byte
+	pendingInput  []byte
+}
+
+// NewRecorder creates a Recorder writing to w. title is stored in the header.
+func NewRecorder(w io.Writer, title string) *Recorder {
+	return newRecorder(w, title, time.Now)
+}
+
+func newRecorder(w io.Writer, title string, now func() time.Time) *Recorder {
+	start := now()
+	return &Recorder{
+		w: w,
+		header: Header{
+			Version:   2,
+			Width:     DefaultWidth,
+			Height:    DefaultHeight,
+			Timestamp: start.Unix(),
+			Title:     title,
+			Env:       map[string]string{"TERM": "xterm-256color"},
+		},
+		start: start,
+		now:   now,
+	}
+}
+
+// Output records data written by the terminal.
+func (r *Recorder) Output(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var text string
+	text, r.pendingOutput = completeUTF8(r.pendingOutput, data)
+	r.eventLocked("o", text)
+}
+
+// Input records data typed by the user.
+func (r *Recorder) Input(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var text string
+	text, r.pendingInput = completeUTF8(r.pendingInput, data)
+	r.eventLocked("i", text)
+}
+
+// Resize records a terminal size change.
+func (r *Recorder) Resize(width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.headerWritten {
+		r.header.Width, r.header.Height = width, height
+		return
+	}
+	r.eventLocked("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Err returns the first error encountered while writing, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close stops the recording. Events recorded afterwards are dropped, so the
+// underlying writer may be read once Close returns.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+// Empty reports whether nothing has been recorded yet.
+func (r *Recorder) Empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.headerWritten
+}
+
+func (r *Recorder) eventLocked(kind, data string) {
+	if r.err != nil || r.closed || data == "" {
+		return
+	}
+	if !r.headerWritten {
+		if r.err = writeJSONLine(r.w, r.header); r.err != nil {
+			return
+		}
+		r.headerWritten = true
+	}
+	elapsed := r.now().Sub(r.start).Seconds()
+	// Round to microseconds like asciinema does; JSON encodes the float as is.
+	elapsed = float64(int64(elapsed*1e6)) / 1e6
+	r.err = writeJSONLine(r.w, []interface{}{elapsed, kind, data})
+}
+
+// completeUTF8 appends data to pending and splits off a trailing incomplete
+// UTF-8 sequence, so that multi-byte characters split across WebSocket
+// messages are not recorded as replacement characters.
+func completeUTF8(pending, data []byte) (string, []byte) {
+	buf := append(pending, data...)
+	cut := len(buf)
+	// A UTF-8 sequence is at most utf8.UTFMax bytes long.
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	rest := append([]byte(nil), buf[cut:]...)
+	return string(buf[:cut]), rest
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,194 @@
+package termrec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame encodes a single WebSocket frame. Client frames are masked.
+func frame(opcode byte, fin bool, payload []byte, masked bool) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	out := []byte{b0}
+	var b1 byte
+	if masked {
+		b1 = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		out = append(out, b1|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		out = append(out, b1|126, 0, 0)
+		binary.BigEndian.PutUint16(out[2:], uint16(len(payload)))
+	default:
+		out = append(out, b1|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(out[2:], uint64(len(payload)))
+	}
+	if !masked {
+		return append(out, payload...)
+	}
+	mask := []byte{1, 2, 3, 4}
+	out = append(out, mask...)
+	for i, c := range payload {
+		out = append(out, c^mask[i%4])
+	}
+	return out
+}
+
+// fakeConn plays upstream bytes on Read and collects client bytes on Write.
+type fakeConn struct {
+	io.Reader
+	written bytes.Buffer
+	closed  bool
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) { return c.written.Write(p) }
+func (c *fakeConn) Close() error                { c.closed = true; return nil }
+
+func fakeClock() func() time.Time {
+	t := time.Unix(1700000000, 0)
+	return func() time.Time {
+		now := t
+		t = t.Add(500 * time.Millisecond)
+		return now
+	}
+}
+
+func decodeCast(t *testing.T, data string) (Header, [][]interface{}) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	var header Header
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("invalid header %q: %v", lines[0], err)
+	}
+	var events [][]interface{}
+	for _, line := range lines[1:] {
+		var ev []interface{}
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return header, events
+}
+
+func TestTTYDConnRecordsSession(t *testing.T) {
+	var upstream []byte
+	upstream = append(upstream, frame(0x2, true, []byte("0$ "), false)...)
+	upstream = append(upstream, frame(0x9, true, []byte("ping"), false)...)
+	// A fragmented message.
+	upstream = append(upstream, frame(0x2, false, []byte("0ab"), false)...)
+	upstream = append(upstream, frame(0x0, true, []byte("cd"), false)...)
+	// A UTF-8 character split across two messages.
+	upstream = append(upstream, frame(0x2, true, []byte("0h\xe3\x81"), false)...)
+	upstream = append(upstream, frame(0x2, true, []byte("0\x82i\r\n"), false)...)
+	upstream = append(upstream, frame(0x2, true, []byte("1title"), false)...)
+
+	var cast bytes.Buffer
+	rec := newRecorder(&cast, "sess-1", fakeClock())
+	closed := 0
+	conn := &fakeConn{Reader: bytes.NewReader(upstream)}
+	c := NewTTYDConn(conn, rec, func() { closed++ })
+
+	clientFrames := [][]byte{
+		frame(0x1, true, []byte(`{"AuthToken":"","columns":120,"rows":40}`), true),
+		frame(0x2, true, []byte("0ls\r"), true),
+		frame(0x2, true, []byte(`1{"columns":100,"rows":30}`), true),
+	}
+	for _, f := range clientFrames {
+		if _, err := c.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Read upstream in small chunks to exercise partial frames.
+	buf := make([]byte, 3)
+	for {
+		if _, err := c.Read(buf); err == io.EOF {
+			break
+		}
+	}
+	_ = c.Close()
+	_ = c.Close()
+
+	if closed != 1 || !conn.closed {
+		t.Fatalf("close callback ran %d times, conn closed = %v", closed, conn.closed)
+	}
+	var wantWritten []byte
+	for _, f := range clientFrames {
+		wantWritten = append(wantWritten, f...)
+	}
+	if !bytes.Equal(conn.written.Bytes(), wantWritten) {
+		t.Fatal("client frames were modified on their way upstream")
+	}
+
+	header, events := decodeCast(t, cast.String())
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Title != "sess-1" {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	want := [][2]string{
+		{"i", "ls\r"},
+		{"r", "100x30"},
+		{"o", "$ "},
+		{"o", "abcd"},
+		{"o", "h"},
+		{"o", "あi\r\n"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i, w := range want {
+		if events[i][1] != w[0] || events[i][2] != w[1] {
+			t.Errorf("event %d = %v, want %v", i, events[i], w)
+		}
+	}
+}
+
+func TestTTYDConnStopsOnCompressedStream(t *testing.T) {
+	compressed := frame(0x2, true, []byte("0data"), false)
+	compressed[0] |= 0x40 // RSV1: permessage-deflate
+	upstream := append(compressed, frame(0x2, true, []byte("0after"), false)...)
+
+	var cast bytes.Buffer
+	rec := NewRecorder(&cast, "")
+	c := NewTTYDConn(&fakeConn{Reader: bytes.NewReader(upstream)}, rec, nil)
+	got, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, upstream) {
+		t.Fatal("upstream bytes were modified")
+	}
+	if !rec.Empty() {
+		t.Fatalf("expected nothing recorded, got %q", cast.String())
+	}
+}
+
+func TestFrameDecoderLengths(t *testing.T) {
+	for _, size := range []int{10, 300, 70000} {
+		payload := bytes.Repeat([]byte("x"), size)
+		var got []byte
+		d := frameDecoder{onMessage: func(m []byte) { got = append([]byte(nil), m...) }}
+		_, _ = d.Write(frame(0x2, true, payload, true))
+		if !bytes.Equal(got, payload) {
+			t.Errorf("size %d: decoded %d bytes", size, len(got))
+		}
+	}
+}
+
+func TestRecorderDropsEventsAfterClose(t *testing.T) {
+	var cast bytes.Buffer
+	rec := NewRecorder(&cast, "")
+	rec.Output([]byte("before"))
+	rec.Close()
+	rec.Output([]byte("after"))
+	if strings.Contains(cast.String(), "after") || !strings.Contains(cast.String(), "before") {
+		t.Fatalf("unexpected recording: %q", cast.String())
+	}
+}
@@ -0,0 +1,207 @@
+package termrec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ttyd message types, the first byte of every WebSocket message.
+// See https://github.com/tsl0922/ttyd/blob/main/src/server.h.
+const (
+	// Server to client.
+	ttydOutput = '0'
+
+	// Client to server.
+	ttydInput      = '0'
+	ttydResize     = '1'
+	ttydInitialize = '{'
+)
+
+// maxFrameSize bounds the memory used for a single WebSocket message. ttyd
+// messages are small; anything larger means the stream is not ttyd traffic.
+const maxFrameSize = 16 << 20
+
+var errStreamNotSupported = errors.New("termrec: unsupported WebSocket stream")
+
+// frameDecoder incrementally decodes WebSocket frames (RFC 6455) from one
+// direction of a connection and reports complete data messages. Once the
+// stream turns out to be undecodable, e.g. because of a negotiated
+// compression extension, the decoder stops without affecting the connection.
+type frameDecoder struct {
+	buf       []byte
+	message   []byte
+	onMessage func([]byte)
+	failed    bool
+}
+
+func (d *frameDecoder) Write(p []byte) (int, error) {
+	if d.failed {
+		return len(p), nil
+	}
+	d.buf = append(d.buf, p...)
+	consumed := 0
+	for {
+		n, err := d.decodeFrame(d.buf[consumed:])
+		if err != nil {
+			d.failed = true
+			d.buf, d.message = nil, nil
+			return len(p), nil
+		}
+		if n == 0 {
+			break
+		}
+		consumed += n
+	}
+	d.buf = append(d.buf[:0], d.buf[consumed:]...)
+	return len(p), nil
+}
+
+// decodeFrame decodes the frame at the start of b and returns its length, or
+// 0 when b does not hold a complete frame yet.
+func (d *frameDecoder) decodeFrame(b []byte) (int, error) {
+	if len(b) < 2 {
+		return 0, nil
+	}
+	fin := b[0]&0x80 != 0
+	if b[0]&0x70 != 0 {
+		return 0, errStreamNotSupported
+	}
+	opcode := b[0] & 0x0f
+	masked := b[1]&0x80 != 0
+	length := uint64(b[1] & 0x7f)
+	offset := 2
+	switch length {
+	case 126:
+		if len(b) < offset+2 {
+			return 0, nil
+		}
+		length = uint64(binary.BigEndian.Uint16(b[offset:]))
+		offset += 2
+	case 127:
+		if len(b) < offset+8 {
+			return 0, nil
+		}
+		length = binary.BigEndian.Uint64(b[offset:])
+		offset += 8
+	}
+	if length > maxFrameSize {
+		return 0, errStreamNotSupported
+	}
+	var mask []byte
+	if masked {
+		if len(b) < offset+4 {
+			return 0, nil
+		}
+		mask = b[offset : offset+4]
+		offset += 4
+	}
+	end := offset + int(length)
+	if len(b) < end {
+		return 0, nil
+	}
+	payload := b[offset:end]
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	switch {
+	case opcode >= 0x8:
+		// Control frames (close, ping, pong) carry no terminal data.
+	case opcode == 0x0:
+		d.message = append(d.message, payload...)
+	default:
+		d.message = append(d.message[:0], payload...)
+	}
+	if fin && opcode < 0x8 {
+		if len(d.message) > maxFrameSize {
+			return 0, errStreamNotSupported
+		}
+		d.onMessage(d.message)
+		d.message = d.message[:0]
+	}
+	return end, nil
+}
+
+// TTYDConn wraps the upstream side of a ttyd WebSocket connection and records
+// the terminal traffic flowing through it. Reads carry ttyd output to the
+// client, writes carry client input to ttyd. The wrapped bytes are passed
+// through unchanged.
+type TTYDConn struct {
+	io.ReadWriteCloser
+	recorder *Recorder
+	output   frameDecoder
+	input    frameDecoder
+	once     sync.Once
+	onClose  func()
+}
+
+// NewTTYDConn returns a TTYDConn recording conn into recorder. onClose, when
+// non-nil, is called once after the connection is closed.
+func NewTTYDConn(conn io.ReadWriteCloser, recorder *Recorder, onClose func()) *TTYDConn {
+	c := &TTYDConn{ReadWriteCloser: conn, recorder: recorder, onClose: onClose}
+	c.output.onMessage = c.handleOutput
+	c.input.onMessage = c.handleInput
+	return c
+}
+
+func (c *TTYDConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if n > 0 {
+		// The decoder unmasks in place, so it gets its own copy.
+		_, _ = c.output.Write(append([]byte(nil), p[:n]...))
+	}
+	return n, err
+}
+
+func (c *TTYDConn) Write(p []byte) (int, error) {
+	_, _ = c.input.Write(append([]byte(nil), p...))
+	return c.ReadWriteCloser.Write(p)
+}
+
+// Close closes the wrapped connection, stops the recording and runs the
+// onClose callback once.
+func (c *TTYDConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.once.Do(func() {
+		c.recorder.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return err
+}
+
+func (c *TTYDConn) handleOutput(msg []byte) {
+	if len(msg) > 0 && msg[0] == ttydOutput {
+		c.recorder.Output(msg[1:])
+	}
+}
+
+func (c *TTYDConn) handleInput(msg []byte) {
+	if len(msg) == 0 {
+		return
+	}
+	switch msg[0] {
+	case ttydInput:
+		c.recorder.Input(msg[1:])
+	case ttydResize:
+		c.recordSize(msg[1:])
+	case ttydInitialize:
+		c.recordSize(msg)
+	}
+}
+
+func (c *TTYDConn) recordSize(data []byte) {
+	var size struct {
+		Columns int `json:"columns"`
+		Rows    int `json:"rows"`
+	}
+	if json.Unmarshal(data, &size) == nil {
+		c.recorder.Resize(size.Columns, size.Rows)
+	}
+}
@@ -0,0 +1,458 @@
+// Package tracing records OpenTelemetry-compatible trace spans for session
+// creation and proxied requests.
+//
+// Spans carry W3C Trace Context identifiers, so traces started here join
+// traces of callers that send a traceparent header and continue in the
+// agentapi backend through the header injected into proxied requests.
+// Finished spans are batched and handed to an Exporter; the OTLP exporter
+// sends them to any OpenTelemetry collector.
+//
+// Tracing is disabled until SetTracer is called. Start then returns a nil
+// *Span, whose methods are no-ops, so call sites need no checks.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceParentHeader is the W3C Trace Context propagation header.
+const TraceParentHeader = "traceparent"
+
+// Attribute is a key/value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Float returns a floating point attribute.
+func Float(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both identifiers are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats sc as a traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceParent parses a traceparent header value. Unknown future
+// versions are accepted as long as the version 00 fields are readable.
+func ParseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+	return sc, sc.IsValid()
+}
+
+// SpanData is the immutable record of a finished span handed to exporters.
+type SpanData struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID [8]byte
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	// Err is the error the span ended with, if any.
+	Err string
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Span is an operation being traced. A nil *Span is valid and does nothing.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the identifiers of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// End finishes the span. A non-nil err marks the span as failed. Only the
+// first call has an effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Err = err.Error()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+// Options configures a Tracer.
+type Options struct {
+	// SampleRatio is the fraction of new traces that are recorded. Spans
+	// whose parent came with a traceparent header follow its sampled flag.
+	SampleRatio float64
+	// BatchSize is the number of spans sent per export. Defaults to 256.
+	BatchSize int
+	// FlushInterval is how often partial batches are exported. Defaults to 5s.
+	FlushInterval time.Duration
+	// QueueSize bounds the spans waiting for export; more are dropped.
+	// Defaults to 2048.
+	QueueSize int
+}
+
+// Tracer creates spans and exports them in batches.
+type Tracer struct {
+	exporter Exporter
+	opts     Options
+	queue    chan SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	stopped  sync.WaitGroup
+	dropped  atomic.Int64
+	shutdown sync.Once
+}
+
+// NewTracer returns a tracer exporting through exporter and starts its
+// export loop. Call Shutdown to flush pending spans.
+func NewTracer(exporter Exporter, opts Options) *Tracer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 256
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	t := &Tracer{
+		exporter: exporter,
+		opts:     opts,
+		queue:    make(chan SpanData, opts.QueueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	t.stopped.Add(1)
+	go t.run()
+	return t
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case t.queue <- data:
+	default:
+		if t.dropped.Add(1)%100 == 1 {
+			log.Printf("[TRACING] Export queue full, dropping spans")
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	defer t.stopped.Done()
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.opts.BatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := t.exporter.Export(ctx, batch); err != nil {
+			log.Printf("[TRACING] Failed to export %d spans: %v", len(batch), err)
+		}
+		cancel()
+		batch = make([]SpanData, 0, t.opts.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+				if len(batch) >= t.opts.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.opts.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flush:
+			drain()
+			close(ack)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+// ForceFlush exports every span finished so far.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports pending spans and stops the export loop.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.shutdown.Do(func() { close(t.done) })
+	stopped := make(chan struct{})
+	go func() {
+		t.stopped.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start begins a span named name as a child of the span in ctx, or of the
+// remote parent extracted into ctx.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, data: SpanData{Name: name, Start: time.Now(), Attributes: attrs}}
+	sc := &span.data.SpanContext
+	if parent := spanContextFromContext(ctx); parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		span.data.ParentSpanID = parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = sampled(sc.TraceID, t.opts.SampleRatio)
+	}
+	sc.SpanID = newSpanID()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sampled makes the sampling decision from the trace ID so that every
+// process seeing the same trace decides the same way.
+func sampled(traceID [16]byte, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	var x uint64
+	for _, b := range traceID[8:] {
+		x = x<<8 | uint64(b)
+	}
+	return x>>1 < uint64(ratio*(1<<63))
+}
+
+func newTraceID() [16]byte {
+	var id [16]byte
+	for id == ([16]byte{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() [8]byte {
+	var id [8]byte
+	for id == ([8]byte{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+var global atomic.Pointer[Tracer]
+
+// SetTracer installs t as the tracer used by Start. Passing nil disables
+// tracing.
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+// Enabled reports whether a tracer is installed.
+func Enabled() bool {
+	return global.Load() != nil
+}
+
+// Start begins a span using the installed tracer. It returns a nil span when
+// tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, attrs...)
+}
+
+// Run traces fn as a span named name and records the error it returns.
+func Run(ctx context.Context, name string, fn func(ctx context.Context) error, attrs ...Attribute) error {
+	ctx, span := Start(ctx, name, attrs...)
+	err := fn(ctx)
+	span.End(err)
+	return err
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext returns the current span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func spanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithTraceParent returns ctx with the remote parent described by a
+// traceparent value. Invalid values leave ctx unchanged.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	sc, ok := ParseTraceParent(traceParent)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// TraceParent returns the traceparent value of the current span in ctx, or
+// "" when there is none.
+func TraceParent(ctx context.Context) string {
+	sc := spanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceParent()
+}
+
+// Extract returns ctx with the remote parent from the traceparent header of h.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return ContextWithTraceParent(ctx, h.Get(TraceParentHeader))
+}
+
+// Inject sets the traceparent header of h to the current span in ctx. The
+// header is left untouched when ctx carries no trace, so a caller's own
+// traceparent still reaches the backend while tracing is disabled.
+func Inject(ctx context.Context, h http.Header) {
+	if tp := TraceParent(ctx); tp != "" {
+		h.Set(TraceParentHeader, tp)
+	}
+}
+
+// Setup installs a tracer exporting through the named exporter ("log" or
+// "otlp"). It returns a nil tracer and leaves tracing disabled for "" and
+// "none".
+func Setup(exporter, endpoint string, headers map[string]string, serviceName string, opts Options) (*Tracer, error) {
+	var exp Exporter
+	switch exporter {
+	case "", "none":
+		return nil, nil
+	case "log":
+		exp = LogExporter{}
+	case "otlp":
+		if endpoint == "" {
+			return nil, fmt.Errorf("tracing: otlp exporter requires an endpoint")
+		}
+		exp = NewOTLPExporter(endpoint, headers, serviceName)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", exporter)
+	}
+	t := NewTracer(exp, opts)
+	SetTracer(t)
+	return t, nil
+}
@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || !sc.Sampled {
+		t.Fatalf("ParseTraceParent() = %+v, %v", sc, ok)
+	}
+	if got := sc.TraceParent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("TraceParent() = %q", got)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceParent(invalid); ok {
+			t.Errorf("ParseTraceParent(%q) accepted an invalid value", invalid)
+		}
+	}
+}
+
+func TestTracerPropagation(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, Options{SampleRatio: 0})
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	h := http.Header{}
+	h.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, parent := Start(Extract(context.Background(), h), "proxy")
+	err := Run(ctx, "backend", func(ctx context.Context) error {
+		out := http.Header{}
+		Inject(ctx, out)
+		sc, ok := ParseTraceParent(out.Get(TraceParentHeader))
+		if !ok || sc.SpanID != SpanFromContext(ctx).SpanContext().SpanID {
+			t.Errorf("Inject() = %q, want the current span", out.Get(TraceParentHeader))
+		}
+		return errors.New("boom")
+	}, String("session.id", "s1"))
+	if err == nil {
+		t.Fatal("Run() should return the error of fn")
+	}
+	parent.End(nil)
+	parent.End(nil)
+
+	// The remote parent was sampled, so the zero sample ratio is ignored.
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if len(exporter.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(exporter.spans))
+	}
+	child, root := exporter.spans[0], exporter.spans[1]
+	if child.Err != "boom" || child.ParentSpanID != root.SpanContext.SpanID {
+		t.Errorf("unexpected child span %+v", child)
+	}
+	if got := root.SpanContext.TraceParent()[3:35]; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("root span trace ID = %s, want the remote trace", got)
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, Options{SampleRatio: 0})
+	ctx, span := tracer.Start(context.Background(), "dropped")
+	span.End(nil)
+	if TraceParent(ctx)[53:] != "00" {
+		t.Errorf("TraceParent() = %q, want unsampled flag", TraceParent(ctx))
+	}
+	_ = tracer.Shutdown(context.Background())
+	if len(exporter.spans) != 0 {
+		t.Errorf("exported %d unsampled spans", len(exporter.spans))
+	}
+
+	// Without a tracer spans are nil and every call is a no-op.
+	ctx, span = Start(context.Background(), "disabled")
+	span.SetAttributes(Int("n", 1))
+	span.End(nil)
+	if span != nil || TraceParent(ctx) != "" {
+		t.Error("Start() without a tracer should not create spans")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got otlpRequest
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	exporter := NewOTLPExporter(srv.URL, map[string]string{"Authorization": "Bearer x"}, "")
+	tracer := NewTracer(exporter, Options{SampleRatio: 1, FlushInterval: time.Hour})
+	_, span := tracer.Start(context.Background(), "CreateSession", Int("attempt", 2), Bool("pvc", true))
+	span.End(errors.New("quota exceeded"))
+	if err := tracer.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+	_ = tracer.Shutdown(context.Background())
+
+	if path != "/v1/traces" || auth != "Bearer x" {
+		t.Errorf("request path=%q auth=%q", path, auth)
+	}
+	if len(got.ResourceSpans) != 1 || *got.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "agentapi-proxy" {
+		t.Fatalf("unexpected resource spans %+v", got.ResourceSpans)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.Name != "CreateSession" || len(s.TraceID) != 32 || len(s.SpanID) != 16 || s.ParentSpanID != "" {
+		t.Errorf("unexpected span %+v", s)
+	}
+	if s.Status.Code != otlpStatusError || s.Status.Message != "quota exceeded" {
+		t.Errorf("status = %+v", s.Status)
+	}
+	if *s.Attributes[0].Value.IntValue != "2" || !*s.Attributes[1].Value.BoolValue {
+		t.Errorf("attributes = %+v", s.Attributes)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer SetTracer(nil)
+	if tracer, err := Setup("none", "", nil, "", Options{}); tracer != nil || err != nil || Enabled() {
+		t.Errorf("Setup(none) = %v, %v", tracer, err)
+	}
+	if _, err := Setup("otlp", "", nil, "", Options{}); err == nil {
+		t.Error("Setup(otlp) without endpoint should fail")
+	}
+	if _, err := Setup("zipkin", "", nil, "", Options{}); err == nil {
+		t.Error("Setup() should reject unknown exporters")
+	}
+	tracer, err := Setup("log", "", nil, "", Options{})
+	if err != nil || !Enabled() {
+		t.Fatalf("Setup(log) error = %v", err)
+	}
+	_ = tracer.Shutdown(context.Background())
+}
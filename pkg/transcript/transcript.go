@@ -0,0 +1,231 @@
+// Package transcript renders the conversation of a session, with its
+// metadata, as a downloadable JSON, Markdown or HTML document.
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+// Format is a transcript document format
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+// ParseFormat validates a format name. "md" is accepted for markdown.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("invalid format %q: must be json, markdown or html", s)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Extension returns the file extension of the format
+func (f Format) Extension() string {
+	if f == FormatMarkdown {
+		return "md"
+	}
+	return string(f)
+}
+
+// ArchiveKey is the artifact key under which the transcript of a deleted
+// session is archived as JSON
+func ArchiveKey(sessionID string) string {
+	return "transcripts/" + sessionID + "/transcript.json"
+}
+
+// Transcript is the conversation of a session with its metadata
+type Transcript struct {
+	SessionID   string                 `json:"session_id"`
+	UserID      string                 `json:"user_id"`
+	Scope       entities.ResourceScope `json:"scope"`
+	TeamID      string                 `json:"team_id,omitempty"`
+	Status      string                 `json:"status"`
+	Description string                 `json:"description,omitempty"`
+	Repository  string                 `json:"repository,omitempty"`
+	Branch      string                 `json:"branch,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	StartedAt   time.Time              `json:"started_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	ExportedAt  time.Time              `json:"exported_at"`
+	Messages    []portrepos.Message    `json:"messages"`
+}
+
+// sessionRequest is implemented by sessions that keep their creation request
+type sessionRequest interface {
+	Request() *entities.RunServerRequest
+}
+
+// FromSession builds the transcript of session from its messages
+func FromSession(session entities.Session, messages []portrepos.Message, now time.Time) *Transcript {
+	t := &Transcript{
+		SessionID:   session.ID(),
+		UserID:      session.UserID(),
+		Scope:       session.Scope(),
+		TeamID:      session.TeamID(),
+		Status:      session.Status(),
+		Description: session.Description(),
+		Tags:        session.Tags(),
+		StartedAt:   session.StartedAt(),
+		UpdatedAt:   session.UpdatedAt(),
+		ExportedAt:  now.UTC(),
+		Messages:    messages,
+	}
+	if t.Messages == nil {
+		t.Messages = []portrepos.Message{}
+	}
+	if sr, ok := session.(sessionRequest); ok {
+		if req := sr.Request(); req != nil && req.RepoInfo != nil {
+			t.Repository = req.RepoInfo.FullName
+			t.Branch = req.RepoInfo.Branch
+		}
+	}
+	if t.Repository == "" {
+		t.Repository = t.Tags["repository"]
+	}
+	return t
+}
+
+// Filename returns the download file name of the transcript in format
+func (t *Transcript) Filename(format Format) string {
+	return fmt.Sprintf("session-%s-transcript.%s", t.SessionID, format.Extension())
+}
+
+// Write renders the transcript to w in format
+func (t *Transcript) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return t.writeMarkdown(w)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, t)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// sortedTags returns the tags as "key=value", sorted by key
+func (t *Transcript) sortedTags() []string {
+	tags := make([]string, 0, len(t.Tags))
+	for k, v := range t.Tags {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (t *Transcript) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", t.SessionID)
+	if t.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", t.Description)
+	}
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	row := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "| %s | %s |\n", name, strings.ReplaceAll(value, "|", "\\|"))
+		}
+	}
+	row("User", t.UserID)
+	row("Team", t.TeamID)
+	row("Status", t.Status)
+	row("Repository", t.Repository)
+	row("Branch", t.Branch)
+	row("Tags", strings.Join(t.sortedTags(), ", "))
+	row("Started", formatTime(t.StartedAt))
+	row("Updated", formatTime(t.UpdatedAt))
+	row("Exported", formatTime(t.ExportedAt))
+
+	for _, msg := range t.Messages {
+		fmt.Fprintf(&b, "\n## %s", roleTitle(msg.Role))
+		if !msg.Timestamp.IsZero() {
+			fmt.Fprintf(&b, " · %s", formatTime(msg.Timestamp))
+		}
+		fmt.Fprintf(&b, "\n\n%s\n", strings.TrimRight(msg.Content, "\n"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// roleTitle returns the heading of a message role, e.g. "Agent" for "agent"
+func roleTitle(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": formatTime,
+	"role": roleTitle,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Session {{.SessionID}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 4px 12px 4px 0; vertical-align: top; }
+th { color: #59636e; font-weight: normal; }
+.message { border: 1px solid #d1d9e0; border-radius: 6px; margin: 1em 0; }
+.message header { background: #f6f8fa; padding: 6px 12px; border-bottom: 1px solid #d1d9e0; font-size: 0.9em; }
+.message.user header { background: #ddf4ff; }
+.message pre { margin: 0; padding: 12px; white-space: pre-wrap; word-wrap: break-word; font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Session {{.SessionID}}</h1>
+{{with .Description}}<p>{{.}}</p>{{end}}
+<table>
+{{with .UserID}}<tr><th>User</th><td>{{.}}</td></tr>{{end}}
+{{with .TeamID}}<tr><th>Team</th><td>{{.}}</td></tr>{{end}}
+{{with .Status}}<tr><th>Status</th><td>{{.}}</td></tr>{{end}}
+{{with .Repository}}<tr><th>Repository</th><td>{{.}}</td></tr>{{end}}
+{{with .Branch}}<tr><th>Branch</th><td>{{.}}</td></tr>{{end}}
+{{range $k, $v := .Tags}}<tr><th>Tag {{$k}}</th><td>{{$v}}</td></tr>{{end}}
+<tr><th>Started</th><td>{{time .StartedAt}}</td></tr>
+<tr><th>Exported</th><td>{{time .ExportedAt}}</td></tr>
+</table>
+{{range .Messages}}<section class="message {{.Role}}">
+<header><strong>{{role .Role}}</strong>{{if not .Timestamp.IsZero}} · {{time .Timestamp}}{{end}}</header>
+<pre>{{.Content}}</pre>
+</section>
+{{end}}</body>
+</html>
+`))
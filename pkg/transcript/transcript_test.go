@@ -0,0 +1,76 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	portrepos "github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+)
+
+func testTranscript() *Transcript {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	return &Transcript{
+		SessionID:  "sess-1",
+		UserID:     "alice",
+		Status:     "active",
+		Repository: "acme/app",
+		Tags:       map[string]string{"env": "dev", "repository": "acme/app"},
+		StartedAt:  at,
+		ExportedAt: at.Add(time.Hour),
+		Messages: []portrepos.Message{
+			{ID: 0, Role: "user", Content: "Fix <script> in the | table", Timestamp: at},
+			{ID: 1, Role: "agent", Content: "Done.\n", Timestamp: at.Add(time.Minute)},
+		},
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	tr := testTranscript()
+
+	var md bytes.Buffer
+	if err := tr.Write(&md, FormatMarkdown); err != nil {
+		t.Fatalf("Write(markdown) error = %v", err)
+	}
+	for _, want := range []string{"# Session sess-1", "| Repository | acme/app |", "| Tags | env=dev, repository=acme/app |", "## User · 2026-10-01T09:00:00Z", "## Agent · 2026-10-01T09:01:00Z\n\nDone.\n"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, md.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := tr.Write(&html, FormatHTML); err != nil {
+		t.Fatalf("Write(html) error = %v", err)
+	}
+	if strings.Contains(html.String(), "<script>") || !strings.Contains(html.String(), "Fix &lt;script&gt;") {
+		t.Errorf("message content is not escaped in HTML:\n%s", html.String())
+	}
+
+	var js bytes.Buffer
+	if err := tr.Write(&js, FormatJSON); err != nil {
+		t.Fatalf("Write(json) error = %v", err)
+	}
+	var decoded Transcript
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.SessionID != "sess-1" || len(decoded.Messages) != 2 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"json": FormatJSON, "md": FormatMarkdown, "Markdown": FormatMarkdown, "html": FormatHTML} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("expected pdf to be rejected")
+	}
+	if got := testTranscript().Filename(FormatMarkdown); got != "session-sess-1-transcript.md" {
+		t.Errorf("Filename() = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnyTeam is the team of the budget applied to teams without their own
+const AnyTeam = "*"
+
+// Budget is the monthly limit of a team. Zero limits are not enforced.
+type Budget struct {
+	MonthlyTokens  int64   `json:"monthly_tokens,omitempty"`
+	MonthlyCostUSD float64 `json:"monthly_cost_usd,omitempty"`
+}
+
+// Budgets maps team IDs, or AnyTeam, to their budget
+type Budgets map[string]Budget
+
+// For returns the budget of teamID
+func (b Budgets) For(teamID string) (Budget, bool) {
+	if budget, ok := b[teamID]; ok {
+		return budget, true
+	}
+	budget, ok := b[AnyTeam]
+	return budget, ok
+}
+
+// BudgetStatus is the use of a team budget in a month
+type BudgetStatus struct {
+	Month string `json:"month"`
+	Budget
+	UsedTokens  int64   `json:"used_tokens"`
+	UsedCostUSD float64 `json:"used_cost_usd"`
+	Exceeded    bool    `json:"exceeded"`
+	// Reason explains which limit is exceeded
+	Reason string `json:"reason,omitempty"`
+}
+
+// Status compares the usage of a month with the budget
+func (b Budget) Status(used Counters, at time.Time) *BudgetStatus {
+	status := &BudgetStatus{
+		Month:       at.UTC().Format(MonthLayout),
+		Budget:      b,
+		UsedTokens:  used.TotalTokens(),
+		UsedCostUSD: used.CostUSD,
+	}
+	switch {
+	case b.MonthlyTokens > 0 && status.UsedTokens >= b.MonthlyTokens:
+		status.Exceeded = true
+		status.Reason = fmt.Sprintf("%d of %d tokens used in %s", status.UsedTokens, b.MonthlyTokens, status.Month)
+	case b.MonthlyCostUSD > 0 && status.UsedCostUSD >= b.MonthlyCostUSD:
+		status.Exceeded = true
+		status.Reason = fmt.Sprintf("$%.2f of $%.2f used in %s", status.UsedCostUSD, b.MonthlyCostUSD, status.Month)
+	}
+	return status
+}
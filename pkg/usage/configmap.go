@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// configMapPrefix prefixes the ConfigMap of each month, e.g.
+	// agentapi-usage-2026-10
+	configMapPrefix = "agentapi-usage-"
+	// cursorsConfigMap keeps the last counters of each session
+	cursorsConfigMap = "agentapi-usage-cursors"
+	// dataKey is the ConfigMap key holding the JSON document
+	dataKey = "usage.json"
+)
+
+// ConfigMapStore keeps each month in a ConfigMap, shared by every replica
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapStore creates a ConfigMapStore in namespace
+func NewConfigMapStore(client kubernetes.Interface, namespace string) *ConfigMapStore {
+	return &ConfigMapStore{client: client, namespace: namespace}
+}
+
+// Load returns the stored month, or an empty one
+func (s *ConfigMapStore) Load(ctx context.Context, month string) (*Month, error) {
+	m := &Month{Month: month, Records: []*Record{}}
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, configMapPrefix+month, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage of %s: %w", month, err)
+	}
+	if data := cm.Data[dataKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), m); err != nil {
+			return nil, fmt.Errorf("failed to parse usage of %s: %w", month, err)
+		}
+	}
+	m.Version, m.stored = cm.ResourceVersion, true
+	return m, nil
+}
+
+// Save stores m. The ResourceVersion of the loaded ConfigMap makes a
+// concurrent save fail with ErrConflict.
+func (s *ConfigMapStore) Save(ctx context.Context, m *Month) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage of %s: %w", m.Month, err)
+	}
+	return s.put(ctx, configMapPrefix+m.Month, m.stored, m.Version, string(data))
+}
+
+// LoadCursors returns the stored cursors
+func (s *ConfigMapStore) LoadCursors(ctx context.Context) (map[string]Counters, error) {
+	cursors := make(map[string]Counters)
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, cursorsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data := cm.Data[dataKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &cursors); err != nil {
+			return nil, fmt.Errorf("failed to parse usage cursors: %w", err)
+		}
+	}
+	return cursors, nil
+}
+
+// SaveCursors replaces the stored cursors
+func (s *ConfigMapStore) SaveCursors(ctx context.Context, cursors map[string]Counters) error {
+	data, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	err = s.put(ctx, cursorsConfigMap, false, "", string(data))
+	if errors.Is(err, ErrConflict) {
+		err = s.put(ctx, cursorsConfigMap, true, "", string(data))
+	}
+	return err
+}
+
+// put updates the ConfigMap name when it exists, or creates it. A non-empty
+// version must match the stored ConfigMap.
+func (s *ConfigMapStore) put(ctx context.Context, name string, exists bool, version, data string) error {
+	cms := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       s.namespace,
+			ResourceVersion: version,
+			Labels:          map[string]string{"app.kubernetes.io/managed-by": "agentapi-proxy", "agentapi.proxy/usage": "true"},
+		},
+		Data: map[string]string{dataKey: data},
+	}
+	var err error
+	if exists {
+		_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	} else {
+		_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	return nil
+}
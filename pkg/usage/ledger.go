@@ -0,0 +1,228 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// saveAttempts bounds the retries of a conflicting Save
+const saveAttempts = 5
+
+// Ledger adds usage to the records of a Store. Usage is kept in memory
+// until Flush, which adds it to the stored months, so that every replica
+// can record usage without overwriting the usage recorded by the others.
+type Ledger struct {
+	store Store
+
+	mu      sync.Mutex
+	pending map[string]map[string]*Record // month → record key → usage not yet stored
+}
+
+// NewLedger creates a Ledger backed by store
+func NewLedger(store Store) *Ledger {
+	return &Ledger{store: store, pending: make(map[string]map[string]*Record)}
+}
+
+// Add records usage of session at at
+func (l *Ledger) Add(session Session, at time.Time, c Counters) {
+	if c.IsZero() {
+		return
+	}
+	at = at.UTC()
+	r := &Record{Date: at.Format(DateLayout), Session: session}
+	month := at.Format(MonthLayout)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records, ok := l.pending[month]
+	if !ok {
+		records = make(map[string]*Record)
+		l.pending[month] = records
+	}
+	if existing, ok := records[r.key()]; ok {
+		existing.add(c)
+		return
+	}
+	r.Counters = c
+	records[r.key()] = r
+}
+
+// Flush adds the pending usage to the stored months. Usage that could not
+// be stored stays pending.
+func (l *Ledger) Flush(ctx context.Context) error {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = make(map[string]map[string]*Record)
+	l.mu.Unlock()
+
+	var errs []error
+	for month, records := range pending {
+		list := make([]*Record, 0, len(records))
+		for _, r := range records {
+			list = append(list, r)
+		}
+		if err := l.save(ctx, month, list); err != nil {
+			errs = append(errs, err)
+			for _, r := range list {
+				l.Add(r.Session, mustParseDate(r.Date), r.Counters)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Ledger) save(ctx context.Context, month string, records []*Record) error {
+	for attempt := 1; ; attempt++ {
+		stored, err := l.store.Load(ctx, month)
+		if err != nil {
+			return err
+		}
+		stored.merge(records)
+		err = l.store.Save(ctx, stored)
+		if err == nil || !errors.Is(err, ErrConflict) || attempt == saveAttempts {
+			return err
+		}
+	}
+}
+
+// Records returns the records matching q, pending usage included
+func (l *Ledger) Records(ctx context.Context, q Query) ([]*Record, error) {
+	var out []*Record
+	for _, month := range q.months() {
+		stored, err := l.store.Load(ctx, month)
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		pending := make([]*Record, 0, len(l.pending[month]))
+		for _, r := range l.pending[month] {
+			pending = append(pending, r)
+		}
+		stored.merge(pending)
+		l.mu.Unlock()
+		for _, r := range stored.Records {
+			if q.matches(r) {
+				out = append(out, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+// TeamMonth returns the usage of teamID in the month of at
+func (l *Ledger) TeamMonth(ctx context.Context, teamID string, at time.Time) (Counters, error) {
+	records, err := l.Records(ctx, monthQuery(at, teamID))
+	if err != nil {
+		return Counters{}, err
+	}
+	var total Counters
+	for _, r := range records {
+		total.add(r.Counters)
+	}
+	return total, nil
+}
+
+// TeamsMonth returns the usage of every team in the month of at. Usage of
+// sessions without a team is left out.
+func (l *Ledger) TeamsMonth(ctx context.Context, at time.Time) (map[string]Counters, error) {
+	records, err := l.Records(ctx, monthQuery(at, ""))
+	if err != nil {
+		return nil, err
+	}
+	teams := make(map[string]Counters)
+	for _, r := range records {
+		if r.TeamID == "" {
+			continue
+		}
+		total := teams[r.TeamID]
+		total.add(r.Counters)
+		teams[r.TeamID] = total
+	}
+	return teams, nil
+}
+
+// monthQuery selects the records of teamID (every team when empty) in the
+// month of at
+func monthQuery(at time.Time, teamID string) Query {
+	at = at.UTC()
+	first := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Query{From: first, To: first.AddDate(0, 1, -1), TeamID: teamID}
+}
+
+func mustParseDate(date string) time.Time {
+	t, _ := time.Parse(DateLayout, date)
+	return t
+}
+
+// Collector reads the cumulative counters of running sessions and adds their
+// increase since the previous read to a Ledger. The last counters of each
+// session are stored so that a restarted or newly elected collector does
+// not count them again.
+type Collector struct {
+	ledger *Ledger
+	store  Store
+	list   func() []Session
+	scrape func(ctx context.Context, sessionID string) (Counters, error)
+
+	mu      sync.Mutex
+	cursors map[string]Counters // nil until loaded
+}
+
+// NewCollector creates a Collector of the sessions returned by list
+func NewCollector(ledger *Ledger, list func() []Session, scrape func(ctx context.Context, sessionID string) (Counters, error)) *Collector {
+	return &Collector{ledger: ledger, store: ledger.store, list: list, scrape: scrape}
+}
+
+// Run collects every interval and flushes the ledger until ctx is done
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.Collect(ctx, time.Now()); err != nil {
+			log.Printf("[USAGE] Failed to collect usage: %v", err)
+		}
+		if err := c.ledger.Flush(ctx); err != nil {
+			log.Printf("[USAGE] Failed to store usage: %v", err)
+		}
+	}
+}
+
+// Collect reads the counters of the running sessions and records their
+// increase at now. Sessions whose counters cannot be read are retried on
+// the next collection; sessions no longer running are forgotten.
+func (c *Collector) Collect(ctx context.Context, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cursors == nil {
+		cursors, err := c.store.LoadCursors(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load cursors: %w", err)
+		}
+		c.cursors = cursors
+	}
+
+	running := make(map[string]bool)
+	for _, session := range c.list() {
+		running[session.ID] = true
+		counters, err := c.scrape(ctx, session.ID)
+		if err != nil {
+			continue
+		}
+		c.ledger.Add(session, now, counters.since(c.cursors[session.ID]))
+		c.cursors[session.ID] = counters
+	}
+	for id := range c.cursors {
+		if !running[id] {
+			delete(c.cursors, id)
+		}
+	}
+	return c.store.SaveCursors(ctx, c.cursors)
+}
@@ -0,0 +1,146 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prometheus names of the Claude Code metrics, as exported by the
+// OpenTelemetry Collector of session Pods: claude_code.token.usage with a
+// "type" attribute and claude_code.cost.usage in USD.
+const (
+	tokenUsageMetric = "claude_code_token_usage"
+	costUsageMetric  = "claude_code_cost_usage"
+)
+
+// ParseMetrics sums the Claude Code token and cost counters of a Prometheus
+// text exposition. Samples of every model and agent session are added up.
+func ParseMetrics(r io.Reader) (Counters, error) {
+	var c Counters
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, labels, value, ok := parseSample(line)
+		if !ok || strings.HasSuffix(name, "_created") {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, tokenUsageMetric):
+			tokens := int64(value)
+			switch labels["type"] {
+			case "input":
+				c.InputTokens += tokens
+			case "output":
+				c.OutputTokens += tokens
+			case "cacheRead":
+				c.CacheReadTokens += tokens
+			case "cacheCreation":
+				c.CacheCreationTokens += tokens
+			}
+		case strings.HasPrefix(name, costUsageMetric):
+			c.CostUSD += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Counters{}, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return c, nil
+}
+
+// parseSample splits `name{label="value",...} value [timestamp]`
+func parseSample(line string) (string, map[string]string, float64, bool) {
+	labels := map[string]string{}
+	name, rest := line, ""
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", nil, 0, false
+		}
+		name, rest = line[:i], line[j+1:]
+		for _, pair := range splitLabels(line[i+1 : j]) {
+			k, v, found := strings.Cut(pair, "=")
+			if !found {
+				continue
+			}
+			if unquoted, err := strconv.Unquote(strings.TrimSpace(v)); err == nil {
+				labels[strings.TrimSpace(k)] = unquoted
+			}
+		}
+	} else if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name, rest = line[:i], line[i:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, false
+	}
+	return name, labels, value, true
+}
+
+// splitLabels splits label pairs on the commas outside quoted values
+func splitLabels(s string) []string {
+	var pairs []string
+	inQuotes, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == ',' && !inQuotes:
+			pairs = append(pairs, s[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		pairs = append(pairs, s[start:])
+	}
+	return pairs
+}
+
+// HTTPScraper reads the counters of a session from the metrics endpoint
+// returned by url
+type HTTPScraper struct {
+	url    func(ctx context.Context, sessionID string) (string, error)
+	client *http.Client
+}
+
+// NewHTTPScraper creates an HTTPScraper
+func NewHTTPScraper(url func(ctx context.Context, sessionID string) (string, error)) *HTTPScraper {
+	return &HTTPScraper{url: url, client: &http.Client{}}
+}
+
+// Scrape returns the cumulative counters of the session
+func (s *HTTPScraper) Scrape(ctx context.Context, sessionID string) (Counters, error) {
+	target, err := s.url(ctx, sessionID)
+	if err != nil {
+		return Counters{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return Counters{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Counters{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return Counters{}, fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return ParseMetrics(resp.Body)
+}
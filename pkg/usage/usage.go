@@ -0,0 +1,261 @@
+// Package usage accounts the model token usage and cost of sessions per day
+// and reports it per session, user and team.
+//
+// The agents report cumulative counters (the Claude Code metrics exported by
+// the OpenTelemetry Collector of session Pods). A Collector reads them
+// periodically and adds the increase since the previous read to the Ledger,
+// which keeps one record per session and day in a Store.
+//
+// The Ledger is the only record of token usage: budgets, the usage report,
+// showback statements and metering exports all read it.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DateLayout is the format of record dates, e.g. "2026-10-16"
+const DateLayout = "2006-01-02"
+
+// MonthLayout is the format of month keys, e.g. "2026-10"
+const MonthLayout = "2006-01"
+
+// ErrConflict is returned by Store.Save when the month was changed since it
+// was loaded
+var ErrConflict = errors.New("usage was changed concurrently")
+
+// Counters are token counts and cost
+type Counters struct {
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CostUSD             float64 `json:"cost_usd"`
+}
+
+// TotalTokens returns the sum of all token counts
+func (c Counters) TotalTokens() int64 {
+	return c.InputTokens + c.OutputTokens + c.CacheReadTokens + c.CacheCreationTokens
+}
+
+// IsZero reports whether nothing was counted
+func (c Counters) IsZero() bool {
+	return c.TotalTokens() == 0 && c.CostUSD == 0
+}
+
+func (c *Counters) add(o Counters) {
+	c.InputTokens += o.InputTokens
+	c.OutputTokens += o.OutputTokens
+	c.CacheReadTokens += o.CacheReadTokens
+	c.CacheCreationTokens += o.CacheCreationTokens
+	c.CostUSD += o.CostUSD
+}
+
+// since returns the increase of cumulative counters c over prev. Counters
+// restart from zero with the agent, in which case c is the increase.
+func (c Counters) since(prev Counters) Counters {
+	if c.TotalTokens() < prev.TotalTokens() || c.CostUSD < prev.CostUSD {
+		return c
+	}
+	return Counters{
+		InputTokens:         max(c.InputTokens-prev.InputTokens, 0),
+		OutputTokens:        max(c.OutputTokens-prev.OutputTokens, 0),
+		CacheReadTokens:     max(c.CacheReadTokens-prev.CacheReadTokens, 0),
+		CacheCreationTokens: max(c.CacheCreationTokens-prev.CacheCreationTokens, 0),
+		CostUSD:             max(c.CostUSD-prev.CostUSD, 0),
+	}
+}
+
+// Session identifies the session usage is attributed to
+type Session struct {
+	ID     string `json:"session_id"`
+	UserID string `json:"user_id"`
+	TeamID string `json:"team_id,omitempty"`
+}
+
+// Record is the usage of one session on one UTC day
+type Record struct {
+	Date string `json:"date"`
+	Session
+	Counters
+}
+
+func (r *Record) key() string {
+	return r.Date + "/" + r.ID
+}
+
+// Month is the usage recorded in one UTC month
+type Month struct {
+	Month   string    `json:"month"`
+	Records []*Record `json:"records"`
+	// Version identifies the stored revision for Store.Save; empty for a
+	// month not stored yet
+	Version string `json:"-"`
+	stored  bool
+}
+
+// merge adds the counters of records to the month
+func (m *Month) merge(records []*Record) {
+	index := make(map[string]*Record, len(m.Records))
+	for _, r := range m.Records {
+		index[r.key()] = r
+	}
+	for _, r := range records {
+		if existing, ok := index[r.key()]; ok {
+			existing.add(r.Counters)
+			continue
+		}
+		copied := *r
+		m.Records = append(m.Records, &copied)
+		index[copied.key()] = &copied
+	}
+}
+
+// Store persists months of usage
+type Store interface {
+	// Load returns a month, or an empty one when nothing was recorded
+	Load(ctx context.Context, month string) (*Month, error)
+	// Save stores m, failing with ErrConflict when the month was saved since
+	// m was loaded
+	Save(ctx context.Context, m *Month) error
+	// LoadCursors returns the counters of each session at the last collection
+	LoadCursors(ctx context.Context) (map[string]Counters, error)
+	// SaveCursors replaces the cursors
+	SaveCursors(ctx context.Context, cursors map[string]Counters) error
+}
+
+// Query selects usage records. Empty fields match everything.
+type Query struct {
+	// From and To are the first and last day, inclusive
+	From, To time.Time
+	TeamID   string
+	UserID   string
+}
+
+// months returns the month keys of the days of q
+func (q Query) months() []string {
+	var months []string
+	first := time.Date(q.From.Year(), q.From.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for m := first; !m.After(q.To); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format(MonthLayout))
+	}
+	return months
+}
+
+func (q Query) matches(r *Record) bool {
+	date := r.Date
+	return date >= q.From.Format(DateLayout) && date <= q.To.Format(DateLayout) &&
+		(q.TeamID == "" || r.TeamID == q.TeamID) &&
+		(q.UserID == "" || r.UserID == q.UserID)
+}
+
+// ParseDate parses a YYYY-MM-DD day as UTC
+func ParseDate(date string) (time.Time, error) {
+	t, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: must be YYYY-MM-DD", date)
+	}
+	return t, nil
+}
+
+// SessionUsage is the usage of one session in a report
+type SessionUsage struct {
+	Session
+	Counters
+	TotalTokens int64 `json:"total_tokens"`
+}
+
+// GroupUsage is the usage of one user or team in a report
+type GroupUsage struct {
+	ID          string `json:"id"`
+	Sessions    int    `json:"sessions"`
+	TotalTokens int64  `json:"total_tokens"`
+	Counters
+}
+
+// Report sums usage records per session, user and team
+type Report struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	Total       Counters       `json:"total"`
+	TotalTokens int64          `json:"total_tokens"`
+	Sessions    []SessionUsage `json:"sessions"`
+	Users       []GroupUsage   `json:"users"`
+	Teams       []GroupUsage   `json:"teams"`
+}
+
+// Summarize builds the report of records over [from, to]. Sessions, users
+// and teams are sorted by decreasing cost, then tokens.
+func Summarize(records []*Record, from, to time.Time) *Report {
+	report := &Report{
+		From:     from.Format(DateLayout),
+		To:       to.Format(DateLayout),
+		Sessions: []SessionUsage{},
+		Users:    []GroupUsage{},
+		Teams:    []GroupUsage{},
+	}
+	sessions := make(map[string]*SessionUsage)
+	users := make(map[string]*GroupUsage)
+	teams := make(map[string]*GroupUsage)
+	group := func(groups map[string]*GroupUsage, id, sessionID string, seen map[string]bool, c Counters) {
+		g, ok := groups[id]
+		if !ok {
+			g = &GroupUsage{ID: id}
+			groups[id] = g
+		}
+		if !seen[id+"/"+sessionID] {
+			seen[id+"/"+sessionID] = true
+			g.Sessions++
+		}
+		g.add(c)
+	}
+	userSessions, teamSessions := make(map[string]bool), make(map[string]bool)
+	for _, r := range records {
+		report.Total.add(r.Counters)
+		s, ok := sessions[r.ID]
+		if !ok {
+			s = &SessionUsage{Session: r.Session}
+			sessions[r.ID] = s
+		}
+		s.add(r.Counters)
+		group(users, r.UserID, r.ID, userSessions, r.Counters)
+		if r.TeamID != "" {
+			group(teams, r.TeamID, r.ID, teamSessions, r.Counters)
+		}
+	}
+	report.TotalTokens = report.Total.TotalTokens()
+	for _, s := range sessions {
+		s.TotalTokens = s.Counters.TotalTokens()
+		report.Sessions = append(report.Sessions, *s)
+	}
+	sort.Slice(report.Sessions, func(i, j int) bool {
+		return heavier(report.Sessions[i].Counters, report.Sessions[j].Counters, report.Sessions[i].ID, report.Sessions[j].ID)
+	})
+	report.Users = sortedGroups(users)
+	report.Teams = sortedGroups(teams)
+	return report
+}
+
+func sortedGroups(groups map[string]*GroupUsage) []GroupUsage {
+	out := make([]GroupUsage, 0, len(groups))
+	for _, g := range groups {
+		g.TotalTokens = g.Counters.TotalTokens()
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return heavier(out[i].Counters, out[j].Counters, out[i].ID, out[j].ID) })
+	return out
+}
+
+func heavier(a, b Counters, idA, idB string) bool {
+	if a.CostUSD != b.CostUSD {
+		return a.CostUSD > b.CostUSD
+	}
+	if a.TotalTokens() != b.TotalTokens() {
+		return a.TotalTokens() > b.TotalTokens()
+	}
+	return idA < idB
+}
@@ -0,0 +1,197 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const sampleMetrics = `# HELP claude_code_token_usage_tokens_total Number of tokens used
+# TYPE claude_code_token_usage_tokens_total counter
+claude_code_token_usage_tokens_total{agentapi_session_id="s1",model="claude-sonnet",type="input"} 1200
+claude_code_token_usage_tokens_total{agentapi_session_id="s1",model="claude-haiku",type="input"} 300
+claude_code_token_usage_tokens_total{agentapi_session_id="s1",model="claude-sonnet",type="output"} 800
+claude_code_token_usage_tokens_total{agentapi_session_id="s1",model="claude-sonnet",type="cacheRead"} 5000
+claude_code_token_usage_tokens_total{agentapi_session_id="s1",model="claude-sonnet",type="cacheCreation"} 700
+claude_code_cost_usage_USD_total{agentapi_session_id="s1",model="claude-sonnet",note="a, \"quoted\" value"} 0.25
+claude_code_cost_usage_USD_total{agentapi_session_id="s1",model="claude-haiku"} 0.01
+claude_code_session_count_total{agentapi_session_id="s1"} 1
+`
+
+func TestParseMetrics(t *testing.T) {
+	c, err := ParseMetrics(strings.NewReader(sampleMetrics))
+	if err != nil {
+		t.Fatalf("ParseMetrics() error = %v", err)
+	}
+	want := Counters{InputTokens: 1500, OutputTokens: 800, CacheReadTokens: 5000, CacheCreationTokens: 700}
+	if c.InputTokens != want.InputTokens || c.OutputTokens != want.OutputTokens ||
+		c.CacheReadTokens != want.CacheReadTokens || c.CacheCreationTokens != want.CacheCreationTokens {
+		t.Errorf("ParseMetrics() = %+v, want %+v", c, want)
+	}
+	if math.Abs(c.CostUSD-0.26) > 1e-9 {
+		t.Errorf("CostUSD = %v, want 0.26", c.CostUSD)
+	}
+}
+
+// fakeSessions serves the cumulative counters of sessions
+type fakeSessions struct {
+	sessions []Session
+	counters map[string]Counters
+	failing  map[string]bool
+}
+
+func (f *fakeSessions) list() []Session { return f.sessions }
+
+func (f *fakeSessions) scrape(_ context.Context, id string) (Counters, error) {
+	if f.failing[id] {
+		return Counters{}, errors.New("connection refused")
+	}
+	return f.counters[id], nil
+}
+
+func TestCollectorRecordsIncreasePerSessionAndDay(t *testing.T) {
+	ctx := context.Background()
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "agentapi")
+	ledger := NewLedger(store)
+	sessions := &fakeSessions{
+		sessions: []Session{{ID: "s1", UserID: "alice", TeamID: "org/a"}, {ID: "s2", UserID: "bob", TeamID: "org/b"}},
+		counters: map[string]Counters{"s1": {InputTokens: 100, CostUSD: 1}, "s2": {OutputTokens: 50}},
+		failing:  map[string]bool{},
+	}
+	collector := NewCollector(ledger, sessions.list, sessions.scrape)
+
+	day1 := time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	if err := collector.Collect(ctx, day1); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	sessions.counters["s1"] = Counters{InputTokens: 160, CostUSD: 1.5}
+	sessions.failing["s2"] = true
+	if err := collector.Collect(ctx, day2); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if err := ledger.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// A new collector, e.g. on another replica, continues from the stored cursors
+	sessions.failing["s2"] = false
+	sessions.counters["s2"] = Counters{OutputTokens: 70}
+	if err := NewCollector(ledger, sessions.list, sessions.scrape).Collect(ctx, day2); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if err := ledger.Flush(ctx); err != nil {
+		t.Fatalf("second Flush() error = %v", err)
+	}
+
+	records, err := ledger.Records(ctx, Query{From: day1, To: day2})
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	got := map[string]Counters{}
+	for _, r := range records {
+		got[r.Date+"/"+r.ID] = r.Counters
+	}
+	want := map[string]Counters{
+		"2026-10-15/s1": {InputTokens: 100, CostUSD: 1},
+		"2026-10-15/s2": {OutputTokens: 50},
+		"2026-10-16/s1": {InputTokens: 60, CostUSD: 0.5},
+		"2026-10-16/s2": {OutputTokens: 20},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("records = %+v, want %+v", got, want)
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("record %s = %+v, want %+v", k, got[k], w)
+		}
+	}
+
+	team, err := ledger.TeamMonth(ctx, "org/a", day2)
+	if err != nil || team.InputTokens != 160 || team.CostUSD != 1.5 {
+		t.Errorf("TeamMonth(org/a) = %+v, %v", team, err)
+	}
+	teams, err := ledger.TeamsMonth(ctx, day1)
+	if err != nil || len(teams) != 2 || teams["org/a"] != team || teams["org/b"].OutputTokens != 70 {
+		t.Errorf("TeamsMonth() = %+v, %v", teams, err)
+	}
+}
+
+func TestCollectorHandlesCounterResets(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewLedger(NewConfigMapStore(fake.NewSimpleClientset(), "agentapi"))
+	sessions := &fakeSessions{
+		sessions: []Session{{ID: "s1", UserID: "alice"}},
+		counters: map[string]Counters{"s1": {InputTokens: 500}},
+	}
+	collector := NewCollector(ledger, sessions.list, sessions.scrape)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	_ = collector.Collect(ctx, now)
+	// The agent restarted and counts from zero again
+	sessions.counters["s1"] = Counters{InputTokens: 40}
+	_ = collector.Collect(ctx, now)
+
+	records, _ := ledger.Records(ctx, Query{From: now, To: now})
+	if len(records) != 1 || records[0].InputTokens != 540 {
+		t.Errorf("records = %+v, want 540 input tokens", records)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []*Record{
+		{Date: "2026-10-01", Session: Session{ID: "s1", UserID: "alice", TeamID: "org/a"}, Counters: Counters{InputTokens: 10, CostUSD: 0.1}},
+		{Date: "2026-10-02", Session: Session{ID: "s1", UserID: "alice", TeamID: "org/a"}, Counters: Counters{InputTokens: 20, CostUSD: 0.2}},
+		{Date: "2026-10-02", Session: Session{ID: "s2", UserID: "alice"}, Counters: Counters{OutputTokens: 5, CostUSD: 1}},
+		{Date: "2026-10-02", Session: Session{ID: "s3", UserID: "bob", TeamID: "org/a"}, Counters: Counters{OutputTokens: 1}},
+	}
+	from, _ := ParseDate("2026-10-01")
+	to, _ := ParseDate("2026-10-31")
+	report := Summarize(records, from, to)
+
+	if report.TotalTokens != 36 || math.Abs(report.Total.CostUSD-1.3) > 1e-9 {
+		t.Errorf("total = %d tokens, %v USD", report.TotalTokens, report.Total.CostUSD)
+	}
+	if len(report.Sessions) != 3 || report.Sessions[0].ID != "s2" || report.Sessions[1].TotalTokens != 30 {
+		t.Errorf("sessions = %+v", report.Sessions)
+	}
+	if len(report.Users) != 2 || report.Users[0].ID != "alice" || report.Users[0].Sessions != 2 || report.Users[0].TotalTokens != 35 {
+		t.Errorf("users = %+v", report.Users)
+	}
+	if len(report.Teams) != 1 || report.Teams[0].ID != "org/a" || report.Teams[0].Sessions != 2 {
+		t.Errorf("teams = %+v", report.Teams)
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	budgets := Budgets{
+		"org/a": {MonthlyTokens: 1000},
+		AnyTeam: {MonthlyCostUSD: 5},
+	}
+	at := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	a, ok := budgets.For("org/a")
+	if !ok || a.MonthlyTokens != 1000 {
+		t.Fatalf("For(org/a) = %+v, %v", a, ok)
+	}
+	if status := a.Status(Counters{InputTokens: 999, CostUSD: 100}, at); status.Exceeded {
+		t.Errorf("Status() below the token limit = %+v", status)
+	}
+	if status := a.Status(Counters{InputTokens: 600, OutputTokens: 400}, at); !status.Exceeded || status.Reason != "1000 of 1000 tokens used in 2026-10" {
+		t.Errorf("Status() at the token limit = %+v", status)
+	}
+	other, ok := budgets.For("org/b")
+	if !ok || other.MonthlyCostUSD != 5 {
+		t.Fatalf("For(org/b) = %+v, %v", other, ok)
+	}
+	if status := other.Status(Counters{CostUSD: 5.5}, at); !status.Exceeded {
+		t.Errorf("Status() over the cost limit = %+v", status)
+	}
+	if _, ok := (Budgets{"org/a": {MonthlyTokens: 1}}).For("org/b"); ok {
+		t.Error("For() without a default budget found one")
+	}
+}
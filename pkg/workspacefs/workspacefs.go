@@ -0,0 +1,320 @@
+// Package workspacefs serves a session workspace directory over HTTP. The
+// agent-provisioner mounts it under /workspace so that the proxy can offer
+// directory listings, previews, downloads and uploads without attaching a
+// terminal to the session.
+package workspacefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxUploadBytes limits uploaded files when FS.MaxUploadBytes is 0.
+const DefaultMaxUploadBytes = 100 << 20
+
+var (
+	// ErrOutsideRoot is returned when a path resolves outside the workspace root.
+	ErrOutsideRoot = errors.New("path is outside the workspace")
+	// ErrIsDir is returned when a file is uploaded over a directory.
+	ErrIsDir = errors.New("path is a directory")
+)
+
+// EntryType is the kind of a workspace entry.
+type EntryType string
+
+const (
+	// TypeDir is a directory.
+	TypeDir EntryType = "dir"
+	// TypeFile is a regular file.
+	TypeFile EntryType = "file"
+	// TypeSymlink is a symbolic link. Links are listed but never followed
+	// outside the workspace.
+	TypeSymlink EntryType = "symlink"
+)
+
+// Entry describes a file or directory inside the workspace.
+type Entry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Type    EntryType `json:"type"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Listing is the response body of GET /files.
+type Listing struct {
+	Entry
+	// Entries holds the directory contents; empty for files.
+	Entries []Entry `json:"entries,omitempty"`
+}
+
+// FS is a workspace rooted at Root.
+type FS struct {
+	Root string
+	// MaxUploadBytes limits the size of uploaded files; 0 means
+	// DefaultMaxUploadBytes.
+	MaxUploadBytes int64
+}
+
+// Resolve maps a slash-separated workspace path to an absolute file path.
+// Symlinks are resolved and must stay inside Root.
+func (w FS) Resolve(rel string) (string, error) {
+	root, err := filepath.EvalSymlinks(w.Root)
+	if err != nil {
+		return "", err
+	}
+	clean := path.Clean("/" + rel)
+	full := filepath.Join(root, filepath.FromSlash(clean))
+	resolved, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return "", ErrOutsideRoot
+	}
+	return resolved, nil
+}
+
+// Stat returns the listing for rel. Directories include their entries sorted
+// with directories first, then by name.
+func (w FS) Stat(rel string) (*Listing, error) {
+	full, err := w.Resolve(rel)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	clean := strings.TrimPrefix(path.Clean("/"+rel), "/")
+	listing := &Listing{Entry: newEntry(clean, info)}
+	if !info.IsDir() {
+		return listing, nil
+	}
+
+	dirEntries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+	listing.Entries = make([]Entry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		listing.Entries = append(listing.Entries, newEntry(path.Join(clean, de.Name()), info))
+	}
+	sort.Slice(listing.Entries, func(i, j int) bool {
+		a, b := listing.Entries[i], listing.Entries[j]
+		if (a.Type == TypeDir) != (b.Type == TypeDir) {
+			return a.Type == TypeDir
+		}
+		return a.Name < b.Name
+	})
+	return listing, nil
+}
+
+// Write replaces the file rel with the contents of r. The parent directory
+// must exist inside the workspace. The file is written to a temporary file
+// first so that a failed upload leaves the old contents in place.
+func (w FS) Write(rel string, r io.Reader) (*Entry, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if clean == "" {
+		return nil, ErrIsDir
+	}
+	parent, err := w.Resolve(path.Dir(clean))
+	if err != nil {
+		return nil, err
+	}
+	target := filepath.Join(parent, path.Base(clean))
+	if info, err := os.Lstat(target); err == nil {
+		if info.IsDir() {
+			return nil, ErrIsDir
+		}
+		// Writing through a symlink must stay inside the workspace
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if target, err = w.Resolve(clean); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	entry := newEntry(clean, info)
+	return &entry, nil
+}
+
+func newEntry(rel string, info fs.FileInfo) Entry {
+	e := Entry{
+		Name:    info.Name(),
+		Path:    rel,
+		Type:    TypeFile,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UTC(),
+	}
+	switch {
+	case info.IsDir():
+		e.Type = TypeDir
+		e.Size = 0
+	case info.Mode()&fs.ModeSymlink != 0:
+		e.Type = TypeSymlink
+	}
+	if rel == "" {
+		e.Name = ""
+	}
+	return e
+}
+
+// NewHandler returns an http.Handler serving the workspace:
+//
+//	GET /files?path=<rel>                  directory listing or file metadata (JSON)
+//	GET /raw?path=<rel>[&download=true]    file contents
+//	PUT /raw?path=<rel>                    upload a file (JSON entry)
+//
+// Previews are served with a content type that browsers will not execute:
+// text is always text/plain and only raster images keep their type.
+func NewHandler(w FS) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files", w.handleFiles)
+	mux.HandleFunc("/raw", w.handleRaw)
+	return mux
+}
+
+func (w FS) handleFiles(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listing, err := w.Stat(r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(listing)
+}
+
+func (w FS) handleRaw(rw http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		w.handleUpload(rw, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	full, err := w.Resolve(r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	defer f.Close() //nolint:errcheck
+	info, err := f.Stat()
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if info.IsDir() {
+		http.Error(rw, "path is a directory", http.StatusBadRequest)
+		return
+	}
+
+	h := rw.Header()
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	if r.URL.Query().Get("download") == "true" {
+		h.Set("Content-Type", "application/octet-stream")
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+	} else {
+		h.Set("Content-Type", previewContentType(f, info.Name()))
+	}
+	http.ServeContent(rw, r, info.Name(), info.ModTime(), f)
+}
+
+func (w FS) handleUpload(rw http.ResponseWriter, r *http.Request) {
+	limit := w.MaxUploadBytes
+	if limit <= 0 {
+		limit = DefaultMaxUploadBytes
+	}
+	entry, err := w.Write(r.URL.Query().Get("path"), http.MaxBytesReader(rw, r.Body, limit))
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(rw).Encode(entry)
+}
+
+// previewContentType returns a safe inline content type for name.
+func previewContentType(f *os.File, name string) string {
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	_, _ = f.Seek(0, 0)
+	sniffed := http.DetectContentType(buf[:n])
+	switch {
+	case strings.HasPrefix(sniffed, "image/png"), strings.HasPrefix(sniffed, "image/jpeg"),
+		strings.HasPrefix(sniffed, "image/gif"), strings.HasPrefix(sniffed, "image/webp"):
+		return sniffed
+	case strings.HasPrefix(sniffed, "text/") || n == 0:
+		return "text/plain; charset=utf-8"
+	case strings.HasSuffix(sniffed, "json") || strings.Contains(mime.TypeByExtension(filepath.Ext(name)), "text"):
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+func writeError(rw http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(rw, fmt.Sprintf("file is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrIsDir):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrOutsideRoot):
+		http.Error(rw, err.Error(), http.StatusForbidden)
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(rw, "not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(rw, "permission denied", http.StatusForbidden)
+	default:
+		http.Error(rw, fmt.Sprintf("failed to access workspace: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,154 @@
+package workspacefs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/agentapi-proxy/pkg/workspacefs"
+)
+
+func newWorkspace(t *testing.T) workspacefs.FS {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "workdir")
+	mustWrite(t, filepath.Join(root, "repo", "main.go"), "package main\n")
+	mustWrite(t, filepath.Join(root, "repo", "index.html"), "<script>alert(1)</script>")
+	mustWrite(t, filepath.Join(root, "README.md"), "# hi\n")
+	mustWrite(t, filepath.Join(base, "secret.txt"), "top secret")
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	return workspacefs.FS{Root: root}
+}
+
+func mustWrite(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestFiles_ListsDirectory(t *testing.T) {
+	ws := newWorkspace(t)
+	rec := get(t, workspacefs.NewHandler(ws), "/files?path=")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var listing workspacefs.Listing
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	if listing.Type != workspacefs.TypeDir || len(listing.Entries) != 3 {
+		t.Fatalf("unexpected listing: %+v", listing)
+	}
+	if listing.Entries[0].Name != "repo" || listing.Entries[0].Type != workspacefs.TypeDir {
+		t.Errorf("directories should sort first, got %+v", listing.Entries[0])
+	}
+	if e := listing.Entries[2]; e.Name != "escape" || e.Type != workspacefs.TypeSymlink {
+		t.Errorf("symlink should be listed, got %+v", e)
+	}
+}
+
+func TestFiles_RejectsEscapes(t *testing.T) {
+	ws := newWorkspace(t)
+	h := workspacefs.NewHandler(ws)
+
+	if rec := get(t, h, "/files?path=escape"); rec.Code != http.StatusForbidden {
+		t.Errorf("symlink escape: status = %d", rec.Code)
+	}
+	// ".." is clamped to the root rather than escaping it.
+	rec := get(t, h, "/files?path=../../")
+	var listing workspacefs.Listing
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil || listing.Path != "" {
+		t.Errorf("dot-dot should resolve to root, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get(t, h, "/raw?path=missing.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing file: status = %d", rec.Code)
+	}
+}
+
+func TestRaw_PreviewAndDownload(t *testing.T) {
+	ws := newWorkspace(t)
+	h := workspacefs.NewHandler(ws)
+
+	rec := get(t, h, "/raw?path=repo/index.html")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("HTML preview must not be served as HTML, got %q", ct)
+	}
+	if rec.Body.String() != "<script>alert(1)</script>" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+
+	rec = get(t, h, "/raw?path=repo/main.go&download=true")
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename=main.go` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if rec := get(t, h, "/raw?path=repo"); rec.Code != http.StatusBadRequest {
+		t.Errorf("directory raw: status = %d", rec.Code)
+	}
+}
+
+func put(t *testing.T, h http.Handler, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestRaw_Upload(t *testing.T) {
+	ws := newWorkspace(t)
+	ws.MaxUploadBytes = 16
+	h := workspacefs.NewHandler(ws)
+
+	rec := put(t, h, "/raw?path=repo/out.txt", "artifact")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry workspacefs.Entry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil || entry.Path != "repo/out.txt" || entry.Size != 8 {
+		t.Fatalf("entry = %+v, %v", entry, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(ws.Root, "repo", "out.txt")); string(b) != "artifact" {
+		t.Errorf("written content = %q", b)
+	}
+
+	if rec := put(t, h, "/raw?path=repo/main.go", "package other\n"); rec.Code != http.StatusCreated {
+		t.Errorf("overwrite: status = %d", rec.Code)
+	}
+	if rec := put(t, h, "/raw?path=repo", "x"); rec.Code != http.StatusBadRequest {
+		t.Errorf("directory: status = %d", rec.Code)
+	}
+	if rec := put(t, h, "/raw?path=missing/out.txt", "x"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing parent: status = %d", rec.Code)
+	}
+	if rec := put(t, h, "/raw?path=escape", "x"); rec.Code != http.StatusForbidden {
+		t.Errorf("symlink escape: status = %d", rec.Code)
+	}
+	if b, _ := os.ReadFile(filepath.Join(filepath.Dir(ws.Root), "secret.txt")); string(b) != "top secret" {
+		t.Errorf("file outside the workspace overwritten: %q", b)
+	}
+	if rec := put(t, h, "/raw?path=big.bin", strings.Repeat("x", 17)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload: status = %d", rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(ws.Root, "big.bin")); !os.IsNotExist(err) {
+		t.Errorf("oversized upload left a file: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+// Command gen regenerates spec/schemas.gen.json from the handler layer
+// types listed in schemagen.Types. It is run by go generate in spec/.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/takutakahashi/agentapi-proxy/spec/schemagen"
+)
+
+func main() {
+	root := flag.String("root", "..", "directory of the module")
+	out := flag.String("out", "schemas.gen.json", "file to write the schemas to")
+	flag.Parse()
+
+	schemas, err := schemagen.Generate(*root, schemagen.Types)
+	if err != nil {
+		log.Fatalf("failed to generate schemas: %v", err)
+	}
+	data, err := schemagen.Marshal(schemas)
+	if err != nil {
+		log.Fatalf("failed to encode schemas: %v", err)
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+}
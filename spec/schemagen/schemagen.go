@@ -0,0 +1,341 @@
+// Package schemagen generates the OpenAPI schemas of the request and
+// response structs of the handler layer, so that the schemas served in
+// /openapi.json follow the Go types instead of being maintained by hand.
+//
+// Schemas are derived from the json tags of the structs. Descriptions come
+// from the doc comments of the types and fields, and the values of string
+// types come from their constants, both read from the Go source.
+package schemagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modulePath is the import path of the module the generated types live in
+const modulePath = "github.com/takutakahashi/agentapi-proxy"
+
+// Schema names the OpenAPI schema of a Go type
+type Schema struct {
+	Name string
+	Type reflect.Type
+}
+
+// Generate returns the schemas of types, keyed by name. root is the
+// directory of the module, where the sources of the types are read for
+// their doc comments. Every named struct reachable from types must be part
+// of types, so that each schema has a deliberate name.
+func Generate(root string, types []Schema) (map[string]any, error) {
+	g := &generator{
+		root:     root,
+		names:    make(map[reflect.Type]string, len(types)),
+		packages: make(map[string]*packageDocs),
+	}
+	for _, s := range types {
+		if s.Type.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("schema %s: %s is not a struct", s.Name, s.Type)
+		}
+		if other, ok := g.names[s.Type]; ok {
+			return nil, fmt.Errorf("schema %s: %s is already named %s", s.Name, s.Type, other)
+		}
+		g.names[s.Type] = s.Name
+	}
+
+	schemas := make(map[string]any, len(types))
+	for _, s := range types {
+		if _, ok := schemas[s.Name]; ok {
+			return nil, fmt.Errorf("schema %s is defined twice", s.Name)
+		}
+		schema, err := g.structSchema(s.Type)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", s.Name, err)
+		}
+		schemas[s.Name] = schema
+	}
+	return schemas, nil
+}
+
+// Marshal encodes schemas the way they are stored in spec/schemas.gen.json
+func Marshal(schemas map[string]any) ([]byte, error) {
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+type generator struct {
+	root     string
+	names    map[reflect.Type]string
+	packages map[string]*packageDocs
+}
+
+// structSchema returns the object schema of a registered struct
+func (g *generator) structSchema(t reflect.Type) (map[string]any, error) {
+	properties := make(map[string]any)
+	var required []string
+	if err := g.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	docs, err := g.docs(t)
+	if err != nil {
+		return nil, err
+	}
+	if text := docs.types[t.Name()]; text != "" {
+		schema["description"] = text
+	}
+	return schema, nil
+}
+
+// addFields adds the properties of the fields of t, flattening embedded
+// structs like encoding/json does. Fields that are always encoded are
+// required.
+func (g *generator) addFields(t reflect.Type, properties map[string]any, required *[]string) error {
+	docs, err := g.docs(t)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := g.addFields(embedded, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var schema map[string]any
+		if hasOption(opts, "string") {
+			schema = map[string]any{"type": "string"}
+		} else {
+			schema, err = g.typeSchema(field.Type)
+			if err != nil {
+				return fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+			}
+		}
+		if text := docs.fields[t.Name()+"."+field.Name]; text != "" {
+			if _, isRef := schema["$ref"]; isRef {
+				// Siblings of $ref are ignored in OpenAPI 3.0
+				schema = map[string]any{"allOf": []any{schema}, "description": text}
+			} else {
+				schema["description"] = text
+			}
+		}
+		properties[name] = schema
+		if !hasOption(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// typeSchema returns the schema of a field type
+func (g *generator) typeSchema(t reflect.Type) (map[string]any, error) {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]any{}, nil
+	}
+	if name, ok := g.names[t]; ok {
+		return map[string]any{"$ref": "#/components/schemas/" + name}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		schema := map[string]any{"type": "string"}
+		if t.PkgPath() != "" {
+			docs, err := g.docs(t)
+			if err != nil {
+				return nil, err
+			}
+			if values := docs.enums[t.Name()]; len(values) > 0 {
+				schema["enum"] = values
+			}
+		}
+		return schema, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}, nil
+		}
+		items, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		values, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Struct:
+		return nil, fmt.Errorf("struct %s has no schema name", t)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// packageDocs are the doc comments and string constants of a package
+type packageDocs struct {
+	types  map[string]string   // type name -> doc
+	fields map[string]string   // "Type.Field" -> doc
+	enums  map[string][]string // string type name -> constant values
+}
+
+// docs returns the docs of the package of t, read from its source under
+// root. Types of other modules have none.
+func (g *generator) docs(t reflect.Type) (*packageDocs, error) {
+	pkgPath := t.PkgPath()
+	if docs, ok := g.packages[pkgPath]; ok {
+		return docs, nil
+	}
+	docs := &packageDocs{
+		types:  make(map[string]string),
+		fields: make(map[string]string),
+		enums:  make(map[string][]string),
+	}
+	g.packages[pkgPath] = docs
+	if pkgPath != modulePath && !strings.HasPrefix(pkgPath, modulePath+"/") {
+		return docs, nil
+	}
+
+	dir := filepath.Join(g.root, filepath.FromSlash(strings.TrimPrefix(pkgPath, modulePath)))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the source of %s: %w", pkgPath, err)
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		docs.addFile(file)
+	}
+	return docs, nil
+}
+
+func (d *packageDocs) addFile(file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				comment := spec.Doc
+				if comment == nil && len(gen.Specs) == 1 {
+					comment = gen.Doc
+				}
+				d.types[spec.Name.Name] = commentText(comment)
+				st, ok := spec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					comment := field.Doc
+					if comment == nil {
+						comment = field.Comment
+					}
+					for _, name := range field.Names {
+						d.fields[spec.Name.Name+"."+name.Name] = commentText(comment)
+					}
+				}
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST {
+					continue
+				}
+				ident, ok := spec.Type.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				for _, value := range spec.Values {
+					lit, ok := value.(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					if s, err := strconv.Unquote(lit.Value); err == nil {
+						d.enums[ident.Name] = append(d.enums[ident.Name], s)
+					}
+				}
+			}
+		}
+	}
+}
+
+// commentText returns a doc comment as one line
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
@@ -0,0 +1,68 @@
+package schemagen
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+type testResponse struct {
+	testBase
+	Count     int               `json:"count"`
+	Note      string            `json:"note,omitempty"`
+	Limit     *int              `json:"limit"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Items     []testItem        `json:"items"`
+	CreatedAt time.Time         `json:"created_at"`
+	Size      int64             `json:"size,string"`
+	Ignored   string            `json:"-"`
+}
+
+func TestGenerate(t *testing.T) {
+	schemas, err := Generate("../..", []Schema{
+		{"TestResponse", reflect.TypeOf(testResponse{})},
+		{"TestItem", reflect.TypeOf(testItem{})},
+	})
+	require.NoError(t, err)
+
+	schema := schemas["TestResponse"].(map[string]any)
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"count", "created_at", "id", "items", "size"}, schema["required"])
+	properties := schema["properties"].(map[string]any)
+	assert.Len(t, properties, 8)
+	assert.Equal(t, map[string]any{"type": "string"}, properties["id"])
+	assert.Equal(t, map[string]any{"type": "integer"}, properties["limit"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}, properties["labels"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/TestItem"}}, properties["items"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, properties["created_at"])
+	assert.Equal(t, map[string]any{"type": "string"}, properties["size"])
+}
+
+func TestGenerate_UnnamedStruct(t *testing.T) {
+	_, err := Generate("../..", []Schema{{"TestResponse", reflect.TypeOf(testResponse{})}})
+	assert.ErrorContains(t, err, "has no schema name")
+}
+
+// TestGeneratedSchemasUpToDate fails when the handler layer types changed
+// without running go generate in spec/
+func TestGeneratedSchemasUpToDate(t *testing.T) {
+	schemas, err := Generate("../..", Types)
+	require.NoError(t, err)
+	want, err := Marshal(schemas)
+	require.NoError(t, err)
+	got, err := os.ReadFile("../schemas.gen.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "spec/schemas.gen.json is out of date; run go generate ./spec")
+}
@@ -0,0 +1,251 @@
+package schemagen
+
+import (
+	"reflect"
+
+	"github.com/takutakahashi/agentapi-proxy/internal/domain/entities"
+	"github.com/takutakahashi/agentapi-proxy/internal/infrastructure/services"
+	"github.com/takutakahashi/agentapi-proxy/internal/interfaces/controllers"
+	"github.com/takutakahashi/agentapi-proxy/internal/modules/schedule"
+	"github.com/takutakahashi/agentapi-proxy/internal/modules/slackbot"
+	"github.com/takutakahashi/agentapi-proxy/internal/modules/webhook"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/compliance"
+	"github.com/takutakahashi/agentapi-proxy/internal/usecases/ports/repositories"
+	resourcetransfer "github.com/takutakahashi/agentapi-proxy/internal/usecases/resource_transfer"
+	"github.com/takutakahashi/agentapi-proxy/pkg/activity"
+	"github.com/takutakahashi/agentapi-proxy/pkg/capacity"
+	"github.com/takutakahashi/agentapi-proxy/pkg/config"
+	"github.com/takutakahashi/agentapi-proxy/pkg/delivery"
+	"github.com/takutakahashi/agentapi-proxy/pkg/diagnostics"
+	"github.com/takutakahashi/agentapi-proxy/pkg/gitdiff"
+	githubsync "github.com/takutakahashi/agentapi-proxy/pkg/github_sync"
+	"github.com/takutakahashi/agentapi-proxy/pkg/notification"
+	"github.com/takutakahashi/agentapi-proxy/pkg/outboundwebhook"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionarchive"
+	"github.com/takutakahashi/agentapi-proxy/pkg/sessionstats"
+	"github.com/takutakahashi/agentapi-proxy/pkg/showback"
+	"github.com/takutakahashi/agentapi-proxy/pkg/startup"
+	"github.com/takutakahashi/agentapi-proxy/pkg/transcript"
+	"github.com/takutakahashi/agentapi-proxy/pkg/usage"
+	"github.com/takutakahashi/agentapi-proxy/pkg/workspacefs"
+)
+
+// Types are the request and response structs of the handler layer whose
+// schemas are generated into spec/schemas.gen.json. Schemas written by hand
+// in spec/openapi.json must not use these names.
+var Types = []Schema{
+	{"SessionAnnotations", reflect.TypeOf(entities.SessionAnnotations{})},
+	{"UpdateSessionAnnotationsRequest", reflect.TypeOf(entities.UpdateSessionAnnotationsRequest{})},
+	{"UpdateSessionRequest", reflect.TypeOf(entities.UpdateSessionRequest{})},
+	{"CloneSessionRequest", reflect.TypeOf(entities.CloneSessionRequest{})},
+	{"SessionSnapshot", reflect.TypeOf(entities.SessionSnapshot{})},
+	{"ImageRolloutRequest", reflect.TypeOf(entities.ImageRolloutRequest{})},
+	{"ImageRollout", reflect.TypeOf(entities.ImageRollout{})},
+	{"ImageRolloutSession", reflect.TypeOf(entities.ImageRolloutSession{})},
+	{"SessionStats", reflect.TypeOf(sessionstats.Stats{})},
+	{"SessionStatsWindow", reflect.TypeOf(sessionstats.Window{})},
+	{"SessionStatsUser", reflect.TypeOf(sessionstats.UserStats{})},
+
+	{"AcceleratorParams", reflect.TypeOf(entities.AcceleratorParams{})},
+	{"AuditEvent", reflect.TypeOf(entities.AuditEvent{})},
+	{"BrowserParams", reflect.TypeOf(entities.BrowserParams{})},
+	{"CreatePreviewRequest", reflect.TypeOf(entities.CreatePreviewRequest{})},
+	{"DockerParams", reflect.TypeOf(entities.DockerParams{})},
+	{"DockerRegistry", reflect.TypeOf(entities.DockerRegistry{})},
+	{"EditorParams", reflect.TypeOf(entities.EditorParams{})},
+	{"PostSessionHookResult", reflect.TypeOf(entities.PostSessionHookResult{})},
+	{"PostSessionReport", reflect.TypeOf(entities.PostSessionReport{})},
+	{"PreviewEnvironment", reflect.TypeOf(entities.PreviewEnvironment{})},
+	{"RequestMCPServer", reflect.TypeOf(entities.RequestMCPServer{})},
+	{"SandboxParams", reflect.TypeOf(entities.SandboxParams{})},
+	{"SavedSearch", reflect.TypeOf(entities.SavedSearch{})},
+	{"SessionCompletion", reflect.TypeOf(entities.SessionCompletion{})},
+	{"SessionEvent", reflect.TypeOf(entities.SessionEvent{})},
+	{"SessionParams", reflect.TypeOf(entities.SessionParams{})},
+	{"SetupHook", reflect.TypeOf(entities.SetupHook{})},
+	{"SlackParams", reflect.TypeOf(entities.SlackParams{})},
+	{"StartRequest", reflect.TypeOf(entities.StartRequest{})},
+	{"TerminalParams", reflect.TypeOf(entities.TerminalParams{})},
+	{"WebhookPayloadMapping", reflect.TypeOf(entities.WebhookPayloadMapping{})},
+
+	{"APITokenListResponse", reflect.TypeOf(controllers.APITokenListResponse{})},
+	{"APITokenMetadata", reflect.TypeOf(controllers.APITokenMetadata{})},
+	{"APITokenWithSecret", reflect.TypeOf(controllers.CreateAPITokenResponse{})},
+	{"ActivityResponse", reflect.TypeOf(controllers.ActivityResponse{})},
+	{"ArchivedSessionsResponse", reflect.TypeOf(controllers.ArchivedSessionsResponse{})},
+	{"BedrockSettingsRequest", reflect.TypeOf(controllers.BedrockSettingsRequest{})},
+	{"BedrockSettingsResponse", reflect.TypeOf(controllers.BedrockSettingsResponse{})},
+	{"BillingResponse", reflect.TypeOf(controllers.BillingResponse{})},
+	{"CapabilityPolicyRequest", reflect.TypeOf(controllers.CapabilityPolicyRequest{})},
+	{"CapabilityPolicyResponse", reflect.TypeOf(controllers.CapabilityPolicyResponse{})},
+	{"CreateAPITokenRequest", reflect.TypeOf(controllers.CreateAPITokenRequest{})},
+	{"CreateFileRequest", reflect.TypeOf(controllers.CreateFileRequest{})},
+	{"CreateMemoryRequest", reflect.TypeOf(controllers.CreateMemoryRequest{})},
+	{"CreateSandboxPolicyRequest", reflect.TypeOf(controllers.CreateSandboxPolicyRequest{})},
+	{"CreateSessionProfileRequest", reflect.TypeOf(controllers.CreateSessionProfileRequest{})},
+	{"CreateTaskGroupRequest", reflect.TypeOf(controllers.CreateTaskGroupRequest{})},
+	{"CreateTaskRequest", reflect.TypeOf(controllers.CreateTaskRequest{})},
+	{"CredentialsFileInfo", reflect.TypeOf(controllers.CredentialsFileInfo{})},
+	{"CredentialsResponse", reflect.TypeOf(controllers.CredentialsResponse{})},
+	{"ExecCommandRequest", reflect.TypeOf(controllers.ExecCommandRequest{})},
+	{"ExecCommandResponse", reflect.TypeOf(controllers.ExecCommandResponse{})},
+	{"ExternalSessionManager", reflect.TypeOf(controllers.ExternalSessionManagerResponse{})},
+	{"ExternalSessionManagerHeartbeat", reflect.TypeOf(controllers.ESMHeartbeatRequest{})},
+	{"ExternalSessionManagerRegistration", reflect.TypeOf(controllers.ESMRegistrationResponse{})},
+	{"ExternalSessionManagerRegistrationRequest", reflect.TypeOf(controllers.ESMRegistrationRequest{})},
+	{"ExternalSessionManagerRequest", reflect.TypeOf(controllers.ExternalSessionManagerRequest{})},
+	{"FileListResponse", reflect.TypeOf(controllers.ListFilesResponse{})},
+	{"GitSyncConfigRequest", reflect.TypeOf(controllers.GitSyncConfigRequest{})},
+	{"GitSyncConfigResponse", reflect.TypeOf(controllers.GitSyncConfigResponse{})},
+	{"LLMUsageResponse", reflect.TypeOf(controllers.LLMUsageResponse{})},
+	{"MCPServerEntry", reflect.TypeOf(controllers.MCPServerEntryResponse{})},
+	{"MCPServerList", reflect.TypeOf(controllers.MCPServerListResponse{})},
+	{"MCPServerRequest", reflect.TypeOf(controllers.MCPServerRequest{})},
+	{"MCPServerResponse", reflect.TypeOf(controllers.MCPServerResponse{})},
+	{"MarketplaceRequest", reflect.TypeOf(controllers.MarketplaceRequest{})},
+	{"MarketplaceResponse", reflect.TypeOf(controllers.MarketplaceResponse{})},
+	{"Memory", reflect.TypeOf(controllers.MemoryResponse{})},
+	{"MemoryListResponse", reflect.TypeOf(controllers.MemoryListResponse{})},
+	{"OutboundWebhookRequest", reflect.TypeOf(controllers.OutboundWebhookRequest{})},
+	{"OutboundWebhookResponse", reflect.TypeOf(controllers.OutboundWebhookResponse{})},
+	{"SandboxPolicy", reflect.TypeOf(controllers.SandboxPolicyResponse{})},
+	{"SandboxPolicyListResponse", reflect.TypeOf(controllers.SandboxPolicyListResponse{})},
+	{"SavedSearchesResponse", reflect.TypeOf(controllers.SavedSearchesResponse{})},
+	{"SessionExtensionsResponse", reflect.TypeOf(controllers.SessionExtensionsResponse{})},
+	{"SessionGroupResult", reflect.TypeOf(controllers.SessionGroupResult{})},
+	{"SessionGroupResults", reflect.TypeOf(controllers.SessionGroupResultsResponse{})},
+	{"SessionOrganization", reflect.TypeOf(controllers.SessionOrganizationResponse{})},
+	{"SessionProfileConfigRequest", reflect.TypeOf(controllers.SessionProfileConfigRequest{})},
+	{"SessionProfileConfigResponse", reflect.TypeOf(controllers.SessionProfileConfigResponse{})},
+	{"SessionProfileResponse", reflect.TypeOf(controllers.SessionProfileResponse{})},
+	{"SessionRestartingResponse", reflect.TypeOf(controllers.SessionRestartingResponse{})},
+	{"SessionWaitResponse", reflect.TypeOf(controllers.SessionWaitResponse{})},
+	{"SettingsResponse", reflect.TypeOf(controllers.SettingsResponse{})},
+	{"SyncEncryptionResponse", reflect.TypeOf(controllers.GitSyncEncryptionResponse{})},
+	{"Task", reflect.TypeOf(controllers.TaskResponse{})},
+	{"TaskGroup", reflect.TypeOf(controllers.TaskGroupResponse{})},
+	{"TaskGroupListResponse", reflect.TypeOf(controllers.TaskGroupListResponse{})},
+	{"TaskLinkRequest", reflect.TypeOf(controllers.TaskLinkRequest{})},
+	{"TaskLinkResponse", reflect.TypeOf(controllers.TaskLinkResponse{})},
+	{"TaskListResponse", reflect.TypeOf(controllers.TaskListResponse{})},
+	{"TeamConfigRequest", reflect.TypeOf(controllers.TeamConfigRequest{})},
+	{"TeamConfigResponse", reflect.TypeOf(controllers.TeamConfigResponse{})},
+	{"TeamDefaultProfileRequest", reflect.TypeOf(controllers.TeamDefaultProfileRequest{})},
+	{"TeamServiceAccount", reflect.TypeOf(controllers.TeamServiceAccountResponse{})},
+	{"TerminalRecording", reflect.TypeOf(controllers.TerminalRecording{})},
+	{"TerminalRecordingsResponse", reflect.TypeOf(controllers.TerminalRecordingsResponse{})},
+	{"TransferResourceRequest", reflect.TypeOf(controllers.TransferResourceRequest{})},
+	{"UpdateFileRequest", reflect.TypeOf(controllers.UpdateFileRequest{})},
+	{"UpdateMemoryRequest", reflect.TypeOf(controllers.UpdateMemoryRequest{})},
+	{"UpdateSandboxPolicyRequest", reflect.TypeOf(controllers.UpdateSandboxPolicyRequest{})},
+	{"UpdateSessionProfileRequest", reflect.TypeOf(controllers.UpdateSessionProfileRequest{})},
+	{"UpdateSettingsRequest", reflect.TypeOf(controllers.UpdateSettingsRequest{})},
+	{"UpdateTaskGroupRequest", reflect.TypeOf(controllers.UpdateTaskGroupRequest{})},
+	{"UpdateTaskRequest", reflect.TypeOf(controllers.UpdateTaskRequest{})},
+	{"UsageResponse", reflect.TypeOf(controllers.UsageResponse{})},
+	{"UserFile", reflect.TypeOf(controllers.FileResponse{})},
+	{"UserInfoResponse", reflect.TypeOf(controllers.UserInfoResponse{})},
+
+	{"CreateScheduleRequest", reflect.TypeOf(schedule.CreateScheduleRequest{})},
+	{"ExecutionRecord", reflect.TypeOf(schedule.ExecutionRecord{})},
+	{"ParseScheduleRequest", reflect.TypeOf(schedule.ParseScheduleRequest{})},
+	{"ParseScheduleResponse", reflect.TypeOf(schedule.ParseScheduleResponse{})},
+	{"ScheduleResponse", reflect.TypeOf(schedule.ScheduleResponse{})},
+	{"SessionConfig", reflect.TypeOf(schedule.SessionConfig{})},
+	{"UpdateScheduleRequest", reflect.TypeOf(schedule.UpdateScheduleRequest{})},
+
+	{"CreateSlackBotRequest", reflect.TypeOf(slackbot.CreateSlackBotRequest{})},
+	{"SlackBotResponse", reflect.TypeOf(slackbot.SlackBotResponse{})},
+	{"SlackBotSessionConfig", reflect.TypeOf(slackbot.SlackBotSessionConfig{})},
+	{"SlackBotSessionParams", reflect.TypeOf(slackbot.SlackBotSessionParams{})},
+	{"UpdateSlackBotRequest", reflect.TypeOf(slackbot.UpdateSlackBotRequest{})},
+
+	{"CreateWebhookRequest", reflect.TypeOf(webhook.CreateWebhookRequest{})},
+	{"TriggerWebhookRequest", reflect.TypeOf(webhook.TriggerWebhookRequest{})},
+	{"TriggerWebhookResponse", reflect.TypeOf(webhook.TriggerWebhookResponse{})},
+	{"UpdateWebhookRequest", reflect.TypeOf(webhook.UpdateWebhookRequest{})},
+	{"WebhookDeliveryRecordResponse", reflect.TypeOf(webhook.DeliveryRecordResponse{})},
+	{"WebhookGitHubConditionsRequest", reflect.TypeOf(webhook.GitHubConditionsRequest{})},
+	{"WebhookGitHubConditionsResponse", reflect.TypeOf(webhook.GitHubConditionsResponse{})},
+	{"WebhookGitHubConfigRequest", reflect.TypeOf(webhook.GitHubConfigRequest{})},
+	{"WebhookGitHubConfigResponse", reflect.TypeOf(webhook.GitHubConfigResponse{})},
+	{"WebhookMatchedTrigger", reflect.TypeOf(webhook.TriggerMatchedTriggerInfo{})},
+	{"WebhookResponse", reflect.TypeOf(webhook.WebhookResponse{})},
+	{"WebhookSessionConfigRequest", reflect.TypeOf(webhook.SessionConfigRequest{})},
+	{"WebhookSessionConfigResponse", reflect.TypeOf(webhook.SessionConfigResponse{})},
+	{"WebhookSessionParamsResponse", reflect.TypeOf(webhook.SessionParamsResponse{})},
+	{"WebhookTriggerConditionsRequest", reflect.TypeOf(webhook.TriggerConditionsRequest{})},
+	{"WebhookTriggerConditionsResponse", reflect.TypeOf(webhook.TriggerConditionsResponse{})},
+	{"WebhookTriggerRequest", reflect.TypeOf(webhook.TriggerRequest{})},
+	{"WebhookTriggerResponse", reflect.TypeOf(webhook.TriggerResponse{})},
+
+	{"SessionStatusEvent", reflect.TypeOf(services.SessionStatusEvent{})},
+
+	{"ComplianceCategorySection", reflect.TypeOf(compliance.CategorySection{})},
+	{"ComplianceReport", reflect.TypeOf(compliance.Report{})},
+	{"ComplianceRetentionStatus", reflect.TypeOf(compliance.RetentionStatus{})},
+
+	{"SessionMessage", reflect.TypeOf(repositories.Message{})},
+
+	{"TransferEndpoint", reflect.TypeOf(resourcetransfer.Endpoint{})},
+	{"TransferResourceResponse", reflect.TypeOf(resourcetransfer.Result{})},
+
+	{"ActivityItem", reflect.TypeOf(activity.Item{})},
+
+	{"CapacityDayForecast", reflect.TypeOf(capacity.DayForecast{})},
+	{"CapacityForecast", reflect.TypeOf(capacity.Report{})},
+	{"CapacityHourForecast", reflect.TypeOf(capacity.HourForecast{})},
+	{"CapacityNode", reflect.TypeOf(capacity.NodeCapacity{})},
+
+	{"ConfigReloadResult", reflect.TypeOf(config.ReloadResult{})},
+
+	{"Delivery", reflect.TypeOf(delivery.Delivery{})},
+
+	{"DiagnosticsReport", reflect.TypeOf(diagnostics.Report{})},
+	{"DiagnosticsResult", reflect.TypeOf(diagnostics.Result{})},
+
+	{"DiffHunk", reflect.TypeOf(gitdiff.Hunk{})},
+	{"DiffLine", reflect.TypeOf(gitdiff.Line{})},
+	{"FileDiff", reflect.TypeOf(gitdiff.FileDiff{})},
+	{"SessionChanges", reflect.TypeOf(gitdiff.Changes{})},
+
+	{"PullResponse", reflect.TypeOf(githubsync.PullResponse{})},
+	{"PushResponse", reflect.TypeOf(githubsync.PushResponse{})},
+	{"RotateKeyResponse", reflect.TypeOf(githubsync.RotateKeyResponse{})},
+	{"SyncAllResponse", reflect.TypeOf(githubsync.SyncAllResponse{})},
+	{"SyncAllResult", reflect.TypeOf(githubsync.SyncAllResult{})},
+	{"SyncSummary", reflect.TypeOf(githubsync.SyncSummary{})},
+
+	{"Notification", reflect.TypeOf(notification.NotificationHistory{})},
+	{"NotificationDeviceInfo", reflect.TypeOf(notification.DeviceInfo{})},
+	{"NotificationHistoryResponse", reflect.TypeOf(notification.HistoryResponse{})},
+	{"NotificationSubscription", reflect.TypeOf(notification.Subscription{})},
+	{"NotificationTemplatePreview", reflect.TypeOf(notification.TemplatePreview{})},
+	{"NotificationTemplatePreviewRequest", reflect.TypeOf(notification.TemplatePreviewRequest{})},
+	{"NotificationTestDeliveryResult", reflect.TypeOf(notification.TestDeliveryResult{})},
+	{"SendNotificationRequest", reflect.TypeOf(notification.SendNotificationRequest{})},
+	{"SendNotificationResponse", reflect.TypeOf(notification.SendNotificationResponse{})},
+	{"TestNotificationRequest", reflect.TypeOf(notification.TestNotificationRequest{})},
+	{"TestNotificationResponse", reflect.TypeOf(notification.TestNotificationResponse{})},
+
+	{"OutboundWebhookDelivery", reflect.TypeOf(outboundwebhook.DeliveryLog{})},
+
+	{"SessionArchive", reflect.TypeOf(sessionarchive.Manifest{})},
+	{"SessionArchiveFile", reflect.TypeOf(sessionarchive.File{})},
+
+	{"ShowbackLine", reflect.TypeOf(showback.Line{})},
+	{"ShowbackStatement", reflect.TypeOf(showback.Statement{})},
+	{"TeamUsage", reflect.TypeOf(showback.TeamUsage{})},
+
+	{"ExtensionStatus", reflect.TypeOf(startup.ExtensionStatus{})},
+
+	{"SessionTranscript", reflect.TypeOf(transcript.Transcript{})},
+
+	{"GroupUsage", reflect.TypeOf(usage.GroupUsage{})},
+	{"SessionUsage", reflect.TypeOf(usage.SessionUsage{})},
+	{"UsageBudgetStatus", reflect.TypeOf(usage.BudgetStatus{})},
+	{"UsageCounters", reflect.TypeOf(usage.Counters{})},
+
+	{"WorkspaceEntry", reflect.TypeOf(workspacefs.Entry{})},
+	{"WorkspaceListing", reflect.TypeOf(workspacefs.Listing{})},
+}
@@ -1,14 +1,75 @@
 package spec
 
 import (
+	"bytes"
 	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
 	"io/fs"
+	"strings"
 )
 
+//go:generate go run ./gen
+
 //go:embed openapi.json
 var embeddedFS embed.FS
 
+//go:embed schemas.gen.json
+var generatedSchemas []byte
+
+//go:embed swagger-ui.html
+var swaggerUIPage string
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(swaggerUIPage))
+
+// SwaggerUI returns the page served at /docs, which renders /openapi.json
+// with the swagger-ui-dist assets under assetsURL
+func SwaggerUI(assetsURL string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := swaggerUITemplate.Execute(&buf, strings.TrimRight(assetsURL, "/")); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // FS returns the embedded spec filesystem containing openapi.json.
 func FS() fs.FS {
 	return embeddedFS
 }
+
+// Document returns the OpenAPI document served at /openapi.json:
+// openapi.json, whose paths and schemas are written by hand, with the
+// schemas generated from the handler layer into schemas.gen.json.
+func Document() ([]byte, error) {
+	raw, err := fs.ReadFile(embeddedFS, "openapi.json")
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi.json: %w", err)
+	}
+	var generated map[string]any
+	if err := json.Unmarshal(generatedSchemas, &generated); err != nil {
+		return nil, fmt.Errorf("invalid schemas.gen.json: %w", err)
+	}
+
+	components, _ := doc["components"].(map[string]any)
+	if components == nil {
+		components = make(map[string]any)
+		doc["components"] = components
+	}
+	schemas, _ := components["schemas"].(map[string]any)
+	if schemas == nil {
+		schemas = make(map[string]any)
+		components["schemas"] = schemas
+	}
+	for name, schema := range generated {
+		if _, ok := schemas[name]; ok {
+			return nil, fmt.Errorf("schema %s is both in openapi.json and schemas.gen.json", name)
+		}
+		schemas[name] = schema
+	}
+	return json.Marshal(doc)
+}